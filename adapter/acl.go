@@ -0,0 +1,30 @@
+package adapter
+
+import "net/netip"
+
+// InboundACL restricts the source addresses accepted by an inbound listener.
+type InboundACL interface {
+	Allow(source netip.Addr) bool
+	Entries() InboundACLEntries
+	// AddRuntime adds prefixes to the runtime lists, which are kept until
+	// the instance is reloaded.
+	AddRuntime(allow []netip.Prefix, deny []netip.Prefix)
+	RemoveRuntime(allow []netip.Prefix, deny []netip.Prefix)
+}
+
+type InboundACLEntries struct {
+	Allow        []netip.Prefix `json:"allow"`
+	Deny         []netip.Prefix `json:"deny"`
+	RuntimeAllow []netip.Prefix `json:"runtime_allow"`
+	RuntimeDeny  []netip.Prefix `json:"runtime_deny"`
+	AllowRuleSet []string       `json:"allow_rule_set,omitempty"`
+	DenyRuleSet  []string       `json:"deny_rule_set,omitempty"`
+}
+
+// InboundACLManager tracks the ACLs of started inbound listeners by inbound tag.
+type InboundACLManager interface {
+	Register(inbound string, acl InboundACL)
+	Unregister(inbound string, acl InboundACL)
+	Get(inbound string) (InboundACL, bool)
+	List() map[string]InboundACL
+}
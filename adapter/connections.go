@@ -11,4 +11,14 @@ type ConnectionManager interface {
 	Lifecycle
 	NewConnection(ctx context.Context, this N.Dialer, conn net.Conn, metadata InboundContext, onClose N.CloseHandlerFunc)
 	NewPacketConnection(ctx context.Context, this N.Dialer, conn N.PacketConn, metadata InboundContext, onClose N.CloseHandlerFunc)
+	UDPSessionTables() []UDPSessionTableStats
+}
+
+// UDPSessionTableStats describes a UDP session table. Inbound is empty for
+// the global table.
+type UDPSessionTableStats struct {
+	Inbound string
+	Limit   int
+	Active  int
+	Evicted uint64
 }
@@ -92,3 +92,27 @@ type DNSTransportManager interface {
 	Remove(tag string) error
 	Create(ctx context.Context, logger log.ContextLogger, tag string, outboundType string, options any) error
 }
+
+// DNSTrace records how the DNS router handled a query, for debugging tools.
+type DNSTrace struct {
+	Matches   []DNSTraceMatch
+	Transport string
+	Cached    bool
+}
+
+type DNSTraceMatch struct {
+	Index  int
+	Rule   string
+	Action string
+}
+
+type dnsTraceKey struct{}
+
+func WithDNSTrace(ctx context.Context, trace *DNSTrace) context.Context {
+	return context.WithValue(ctx, (*dnsTraceKey)(nil), trace)
+}
+
+func DNSTraceFromContext(ctx context.Context) *DNSTrace {
+	trace, _ := ctx.Value((*dnsTraceKey)(nil)).(*DNSTrace)
+	return trace
+}
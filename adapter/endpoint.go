@@ -2,6 +2,8 @@ package adapter
 
 import (
 	"context"
+	"net/netip"
+	"time"
 
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
@@ -14,6 +16,27 @@ type Endpoint interface {
 	Outbound
 }
 
+// WireGuardEndpoint is implemented by WireGuard endpoints, whose peers can be
+// added, replaced and removed at runtime.
+type WireGuardEndpoint interface {
+	Endpoint
+	Peers() []WireGuardPeerStatus
+	// SetPeer adds the peer or replaces the peer with the same public key.
+	SetPeer(peer option.WireGuardPeer) error
+	RemovePeer(publicKey string) error
+}
+
+type WireGuardPeerStatus struct {
+	Name                        string
+	PublicKey                   string
+	Endpoint                    string
+	AllowedIPs                  []netip.Prefix
+	PersistentKeepaliveInterval uint16
+	LastHandshake               time.Time
+	ReceivedBytes               uint64
+	SentBytes                   uint64
+}
+
 type EndpointRegistry interface {
 	option.EndpointOptionsRegistry
 	Create(ctx context.Context, router Router, logger log.ContextLogger, tag string, endpointType string, options any) (Endpoint, error)
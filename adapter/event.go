@@ -0,0 +1,93 @@
+package adapter
+
+import "time"
+
+const (
+	EventConnectionOpen   = "connection_open"
+	EventRuleMatched      = "rule_matched"
+	EventOutboundSelected = "outbound_selected"
+	EventConnectionClosed = "connection_closed"
+	EventDNSAnswered      = "dns_answered"
+	EventOutboundHealth   = "outbound_health"
+
+	EventCertificateRenewed  = "certificate_renewed"
+	EventCertificateExpiring = "certificate_expiring"
+	EventQuotaExceeded       = "quota_exceeded"
+	EventBanApplied          = "ban_applied"
+	EventReloadFailed        = "reload_failed"
+
+	EventTaskStarted  = "task_started"
+	EventTaskProgress = "task_progress"
+	EventTaskFinished = "task_finished"
+)
+
+// Background tasks reporting progress with task events.
+const (
+	TaskRuleSet      = "rule_set"
+	TaskFile         = "file"
+	TaskSubscription = "subscription"
+	TaskCertificate  = "certificate"
+	TaskDrain        = "drain"
+)
+
+const (
+	TaskStateSucceeded   = "succeeded"
+	TaskStateNotModified = "not_modified"
+	TaskStateFailed      = "failed"
+)
+
+const (
+	OutboundStateHealthy   = "healthy"
+	OutboundStateUnhealthy = "unhealthy"
+)
+
+// Event is emitted to subscribers of the EventBus, fields not related to
+// its type are left empty.
+type Event struct {
+	Type         string     `json:"type"`
+	Time         time.Time  `json:"time"`
+	ConnectionID uint32     `json:"connection_id,omitempty"`
+	Network      string     `json:"network,omitempty"`
+	Inbound      string     `json:"inbound,omitempty"`
+	InboundType  string     `json:"inbound_type,omitempty"`
+	User         string     `json:"user,omitempty"`
+	Source       string     `json:"source,omitempty"`
+	Destination  string     `json:"destination,omitempty"`
+	Domain       string     `json:"domain,omitempty"`
+	Protocol     string     `json:"protocol,omitempty"`
+	Rule         string     `json:"rule,omitempty"`
+	Action       string     `json:"action,omitempty"`
+	Outbound     string     `json:"outbound,omitempty"`
+	Chain        []string   `json:"chain,omitempty"`
+	Upload       int64      `json:"upload,omitempty"`
+	Download     int64      `json:"download,omitempty"`
+	Duration     int64      `json:"duration,omitempty"`
+	QueryType    string     `json:"query_type,omitempty"`
+	Transport    string     `json:"transport,omitempty"`
+	Answers      []string   `json:"answers,omitempty"`
+	RCode        string     `json:"rcode,omitempty"`
+	Group        string     `json:"group,omitempty"`
+	State        string     `json:"state,omitempty"`
+	Delay        uint16     `json:"delay,omitempty"`
+	Package      string     `json:"package,omitempty"`
+	Limit        int64      `json:"limit,omitempty"`
+	Used         int64      `json:"used,omitempty"`
+	ExpireAt     *time.Time `json:"expire_at,omitempty"`
+	Task         string     `json:"task,omitempty"`
+	Tag          string     `json:"tag,omitempty"`
+	Current      int64      `json:"current,omitempty"`
+	Total        int64      `json:"total,omitempty"`
+	Error        string     `json:"error,omitempty"`
+}
+
+// EventBus delivers events to subscribers without blocking the emitter,
+// events are dropped for subscribers that do not keep up.
+type EventBus interface {
+	// Subscribed reports if any subscriber receives events of eventType,
+	// so that emitters can skip building them.
+	Subscribed(eventType string) bool
+	Emit(event Event)
+	// Subscribe returns a channel receiving events of eventTypes, or all
+	// events if none is given, until cancel is called.
+	Subscribe(bufferSize int, eventTypes ...string) (events <-chan Event, cancel func())
+}
@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"context"
 	"encoding/binary"
+	"net/netip"
 	"time"
 
+	M "github.com/sagernet/sing/common/metadata"
 	"github.com/sagernet/sing/common/varbin"
 )
 
@@ -52,6 +54,29 @@ type CacheFile interface {
 	StoreGroupExpand(group string, expand bool) error
 	LoadRuleSet(tag string) *SavedBinary
 	SaveRuleSet(tag string, set *SavedBinary) error
+	LoadSubscription(tag string) *SavedBinary
+	SaveSubscription(tag string, subscription *SavedBinary) error
+
+	StoreUDPSession() bool
+	// LoadUDPSessions returns and removes the saved UDP sessions.
+	LoadUDPSessions() []SavedUDPSession
+	SaveUDPSessions(sessions []SavedUDPSession) error
+	LoadWireGuardPeerEndpoints(tag string) map[string]netip.AddrPort
+	SaveWireGuardPeerEndpoints(tag string, endpoints map[string]netip.AddrPort) error
+	LoadSSHHostKey(host string) []byte
+	SaveSSHHostKey(host string, hostKey []byte) error
+}
+
+// SavedUDPSession is a UDP NAT session kept across reloads, so that the
+// session can be recreated with the same outbound local port.
+type SavedUDPSession struct {
+	Inbound     string
+	Source      M.Socksaddr
+	Destination M.Socksaddr
+	Outbound    string
+	LocalPort   uint16
+	LastActive  time.Time
+	Timeout     time.Duration
 }
 
 type SavedBinary struct {
@@ -5,6 +5,7 @@ import (
 	"net/netip"
 	"time"
 
+	"github.com/sagernet/sing-box/common/chaos"
 	"github.com/sagernet/sing-box/common/process"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
@@ -28,6 +29,23 @@ type UDPInjectableInbound interface {
 	PacketConnectionHandlerEx
 }
 
+type TunInbound interface {
+	Inbound
+	RouteOptions() option.TunRouteOptions
+	UpdateRouteOptions(options option.TunRouteOptions) error
+}
+
+// ManagedUserInbound is implemented by inbounds whose users can be added,
+// replaced and removed at runtime. Users are decoded from JSON in the user
+// format of the inbound options.
+type ManagedUserInbound interface {
+	Inbound
+	// InboundOptions returns the inbound options with the current users.
+	InboundOptions() option.Inbound
+	SetUser(content []byte) (option.InboundUser, error)
+	RemoveUser(name string) error
+}
+
 type InboundRegistry interface {
 	option.InboundOptionsRegistry
 	Create(ctx context.Context, router Router, logger log.ContextLogger, tag string, inboundType string, options any) (Inbound, error)
@@ -59,6 +77,9 @@ type InboundContext struct {
 	SniffContext any
 	SnifferNames []string
 	SniffError   error
+	// QUICShortHeader is the start of the first packet of a UDP connection
+	// that looks like a QUIC short header packet but is not sniffed.
+	QUICShortHeader []byte
 
 	// cache
 
@@ -75,9 +96,33 @@ type InboundContext struct {
 	UDPDisableDomainUnmapping bool
 	UDPConnect                bool
 	UDPTimeout                time.Duration
-	TLSFragment               bool
-	TLSFragmentFallbackDelay  time.Duration
-	TLSRecordFragment         bool
+	UDPNATMapping             string
+	UDPNATFiltering           string
+	UDPOverTCP                string
+	Multiplex                 string
+	MultiplexPadding          bool
+	// UDPLocalPort is the outbound local port of a UDP session restored
+	// after reload.
+	UDPLocalPort             uint16
+	TLSFragment              bool
+	TLSFragmentFallbackDelay time.Duration
+	TLSRecordFragment        bool
+	TTL                      uint8
+	RoutingMark              uint32
+	DSCP                     uint8
+	// TCPKeepAlive overrides the keep alive idle of TCP sockets of the
+	// connection, negative values disable keep alive.
+	TCPKeepAlive         time.Duration
+	TCPKeepAliveInterval time.Duration
+	// TCPIdleTimeout closes the connection after no data is relayed for the
+	// duration.
+	TCPIdleTimeout time.Duration
+	// TCPDisableHalfClose closes the connection once either direction is
+	// finished, and TCPHalfCloseTimeout after the duration.
+	TCPDisableHalfClose bool
+	TCPHalfCloseTimeout time.Duration
+	// Chaos degrades the outbound connection for testing.
+	Chaos *chaos.Config
 
 	NetworkStrategy     *C.NetworkStrategy
 	NetworkType         []C.InterfaceType
@@ -0,0 +1,141 @@
+package inbound
+
+import (
+	"sync"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+)
+
+// UserList holds the users of an inbound that can be changed at runtime,
+// in the user type of the inbound options. Each user is assigned an ID,
+// which is kept while a user with the same name is in the list, so that
+// services keyed by it do not mistake users of connections authenticated
+// before an update. The update function is called with the new list and
+// IDs under the lock, and the list is only replaced if it succeeds.
+type UserList[U any] struct {
+	access sync.RWMutex
+	users  []U
+	ids    []int
+	names  map[int]string
+	nextID int
+	user   func(U) option.InboundUser
+	update func(ids []int, users []U) error
+}
+
+func NewUserList[U any](user func(U) option.InboundUser) *UserList[U] {
+	return &UserList[U]{user: user}
+}
+
+// Initialize sets the function that updates the service of the inbound, and
+// the initial users.
+func (l *UserList[U]) Initialize(users []U, update func(ids []int, users []U) error) error {
+	l.update = update
+	return l.SetUsers(users)
+}
+
+func (l *UserList[U]) Users() []U {
+	l.access.RLock()
+	defer l.access.RUnlock()
+	users := make([]U, len(l.users))
+	copy(users, l.users)
+	return users
+}
+
+func (l *UserList[U]) SetUsers(users []U) error {
+	l.access.Lock()
+	defer l.access.Unlock()
+	return l.updateLocked(users)
+}
+
+// updateLocked assigns the IDs of current users to users with the same
+// name, and new IDs to the others.
+func (l *UserList[U]) updateLocked(users []U) error {
+	currentIDs := make(map[string][]int, len(l.users))
+	for i, user := range l.users {
+		name := l.user(user).Name
+		if name != "" {
+			currentIDs[name] = append(currentIDs[name], l.ids[i])
+		}
+	}
+	nextID := l.nextID
+	ids := make([]int, len(users))
+	names := make(map[int]string, len(users))
+	for i, user := range users {
+		name := l.user(user).Name
+		if userIDs := currentIDs[name]; len(userIDs) > 0 {
+			ids[i] = userIDs[0]
+			currentIDs[name] = userIDs[1:]
+		} else {
+			ids[i] = nextID
+			nextID++
+		}
+		names[ids[i]] = name
+	}
+	err := l.update(ids, users)
+	if err != nil {
+		return err
+	}
+	l.users = users
+	l.ids = ids
+	l.names = names
+	l.nextID = nextID
+	return nil
+}
+
+// Name returns the name of the user with the ID, or an empty string if the
+// user has been removed.
+func (l *UserList[U]) Name(id int) string {
+	l.access.RLock()
+	defer l.access.RUnlock()
+	return l.names[id]
+}
+
+// SetUser adds the user decoded from content, or replaces the user with the
+// same name.
+func (l *UserList[U]) SetUser(content []byte) (option.InboundUser, error) {
+	user, err := json.UnmarshalExtended[U](content)
+	if err != nil {
+		return option.InboundUser{}, E.Cause(err, "decode user")
+	}
+	inboundUser := l.user(user)
+	if inboundUser.Name == "" {
+		return option.InboundUser{}, E.New("missing user name")
+	}
+	l.access.Lock()
+	defer l.access.Unlock()
+	users := make([]U, 0, len(l.users)+1)
+	var replaced bool
+	for _, it := range l.users {
+		if l.user(it).Name == inboundUser.Name {
+			users = append(users, user)
+			replaced = true
+		} else {
+			users = append(users, it)
+		}
+	}
+	if !replaced {
+		users = append(users, user)
+	}
+	err = l.updateLocked(users)
+	if err != nil {
+		return option.InboundUser{}, err
+	}
+	return inboundUser, nil
+}
+
+func (l *UserList[U]) RemoveUser(name string) error {
+	l.access.Lock()
+	defer l.access.Unlock()
+	users := make([]U, 0, len(l.users))
+	for _, it := range l.users {
+		if l.user(it).Name != name {
+			users = append(users, it)
+		}
+	}
+	if len(users) == len(l.users) {
+		return E.New("user ", name, " not found")
+	}
+	return l.updateLocked(users)
+}
@@ -0,0 +1,71 @@
+package inbound
+
+import (
+	"testing"
+
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserListStableID(t *testing.T) {
+	t.Parallel()
+	var serviceUsers map[int]string
+	userList := NewUserList(option.TrojanUser.InboundUser)
+	err := userList.Initialize([]option.TrojanUser{
+		{Name: "a", Password: "a"},
+		{Name: "b", Password: "b"},
+		{Name: "c", Password: "c"},
+	}, func(ids []int, users []option.TrojanUser) error {
+		serviceUsers = make(map[int]string)
+		for i, user := range users {
+			serviceUsers[ids[i]] = user.Password
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	idOf := func(password string) int {
+		for id, it := range serviceUsers {
+			if it == password {
+				return id
+			}
+		}
+		t.Fatal("missing user ", password)
+		return -1
+	}
+	bID, cID := idOf("b"), idOf("c")
+	require.Equal(t, "c", userList.Name(cID))
+
+	require.NoError(t, userList.RemoveUser("a"))
+	require.Equal(t, cID, idOf("c"))
+	require.Equal(t, "b", userList.Name(bID))
+	require.Equal(t, "c", userList.Name(cID))
+
+	_, err = userList.SetUser([]byte(`{"name":"b","password":"b2"}`))
+	require.NoError(t, err)
+	require.Equal(t, bID, idOf("b2"))
+
+	_, err = userList.SetUser([]byte(`{"name":"a","password":"a"}`))
+	require.NoError(t, err)
+	aID := idOf("a")
+	require.NotContains(t, []int{bID, cID}, aID)
+	require.Equal(t, "a", userList.Name(aID))
+}
+
+func TestUserListDuplicateName(t *testing.T) {
+	t.Parallel()
+	var serviceIDs []int
+	userList := NewUserList(option.TrojanUser.InboundUser)
+	update := func(ids []int, users []option.TrojanUser) error {
+		serviceIDs = ids
+		return nil
+	}
+	users := []option.TrojanUser{{Name: "a"}, {Name: "a"}, {}}
+	require.NoError(t, userList.Initialize(users, update))
+	firstIDs := serviceIDs
+	require.Len(t, firstIDs, 3)
+	require.NotEqual(t, firstIDs[0], firstIDs[1])
+	require.NoError(t, userList.SetUsers(users))
+	require.Equal(t, firstIDs[:2], serviceIDs[:2])
+	require.NotEqual(t, firstIDs[2], serviceIDs[2])
+}
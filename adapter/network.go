@@ -1,6 +1,7 @@
 package adapter
 
 import (
+	"net/netip"
 	"time"
 
 	C "github.com/sagernet/sing-box/constant"
@@ -27,6 +28,9 @@ type NetworkManager interface {
 	WIFIState() WIFIState
 	ResetNetwork()
 	UpdateWIFIState()
+	RegisterPortMapper(mapper PortMapper)
+	UnregisterPortMapper(mapper PortMapper)
+	PortMappings() []PortMapping
 }
 
 type NetworkOptions struct {
@@ -56,3 +60,17 @@ type NetworkInterface struct {
 	Expensive   bool
 	Constrained bool
 }
+
+type PortMapper interface {
+	PortMapping() PortMapping
+}
+
+type PortMapping struct {
+	Network         string
+	Listen          netip.AddrPort
+	Protocol        string
+	Gateway         netip.Addr
+	ExternalAddress netip.AddrPort
+	Expire          time.Time
+	Error           string
+}
@@ -32,6 +32,22 @@ type DirectRouteOutbound interface {
 	NewDirectRouteConnection(metadata InboundContext, routeContext tun.DirectRouteContext, timeout time.Duration) (tun.DirectRouteDestination, error)
 }
 
+const (
+	LazyStateIdle         = "idle"
+	LazyStateInitializing = "initializing"
+	LazyStateReady        = "ready"
+	LazyStateFailed       = "failed"
+)
+
+// LazyOutbound is implemented by outbounds that can defer expensive
+// initialization until they are first used or selected.
+// LazyState returns an empty state if deferring is not enabled.
+type LazyOutbound interface {
+	Outbound
+	LazyState() (state string, err error)
+	Initialize() error
+}
+
 type OutboundRegistry interface {
 	option.OutboundOptionsRegistry
 	CreateOutbound(ctx context.Context, router Router, logger log.ContextLogger, tag string, outboundType string, options any) (Outbound, error)
@@ -0,0 +1,68 @@
+package outbound
+
+import (
+	"sync"
+
+	"github.com/sagernet/sing-box/adapter"
+)
+
+// Lazy defers the initialization of an outbound to its first use when
+// enabled. A failed initialization is retried on the next use.
+type Lazy struct {
+	enabled    bool
+	initialize func() error
+	access     sync.Mutex
+	stateLock  sync.RWMutex
+	state      string
+	err        error
+}
+
+func NewLazy(enabled bool, initialize func() error) *Lazy {
+	return &Lazy{
+		enabled:    enabled,
+		initialize: initialize,
+		state:      adapter.LazyStateIdle,
+	}
+}
+
+func (l *Lazy) Enabled() bool {
+	return l.enabled
+}
+
+func (l *Lazy) Initialize() error {
+	if !l.enabled {
+		return nil
+	}
+	if state, _ := l.LazyState(); state == adapter.LazyStateReady {
+		return nil
+	}
+	l.access.Lock()
+	defer l.access.Unlock()
+	if state, _ := l.LazyState(); state == adapter.LazyStateReady {
+		return nil
+	}
+	l.setState(adapter.LazyStateInitializing, nil)
+	err := l.initialize()
+	if err != nil {
+		l.setState(adapter.LazyStateFailed, err)
+		return err
+	}
+	l.setState(adapter.LazyStateReady, nil)
+	return nil
+}
+
+func (l *Lazy) LazyState() (state string, err error) {
+	if !l.enabled {
+		return "", nil
+	}
+	l.stateLock.RLock()
+	defer l.stateLock.RUnlock()
+	return l.state, l.err
+}
+
+func (l *Lazy) setState(state string, err error) {
+	l.stateLock.Lock()
+	defer l.stateLock.Unlock()
+	l.state = state
+	l.err = err
+}
@@ -0,0 +1,36 @@
+package adapter
+
+import (
+	"context"
+	"time"
+
+	"github.com/sagernet/sing/common/x/list"
+)
+
+// PowerState is the state of the device, reported by the platform or read
+// from the operating system.
+type PowerState struct {
+	OnBattery     bool
+	PowerSaveMode bool
+	Idle          bool
+}
+
+// PowerManager switches to the low power mode when enabled by `power_saving`
+// and the device is idle or on battery, where keepalive intervals are
+// lengthened and periodic tasks such as health checks are coalesced.
+type PowerManager interface {
+	LifecycleService
+	LowPower() bool
+	UpdatePowerState(state PowerState)
+	// KeepAliveInterval returns interval lengthened in the low power mode.
+	KeepAliveInterval(interval time.Duration) time.Duration
+	// WaitWindow blocks in the low power mode until the next timer window,
+	// so that periodic tasks wake the device together.
+	WaitWindow(ctx context.Context)
+	RegisterCallback(callback PowerCallback) *list.Element[PowerCallback]
+	UnregisterCallback(element *list.Element[PowerCallback])
+}
+
+// PowerCallback is called with the manager locked when the low power mode is
+// changed, so only LowPower and KeepAliveInterval may be called from it.
+type PowerCallback = func(lowPower bool)
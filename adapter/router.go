@@ -3,12 +3,14 @@ package adapter
 import (
 	"context"
 	"crypto/tls"
+	"io"
 	"net"
 	"net/http"
 	"sync"
 	"time"
 
 	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing-tun"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
@@ -29,10 +31,53 @@ type Router interface {
 	Rules() []Rule
 	AppendTracker(tracker ConnectionTracker)
 	ResetNetwork()
+	Budgets() []BudgetStats
+	Users() []UserStatus
+	UpdateUser(inbound string, name string, update UserStatusUpdate) error
+	SetInboundUser(inbound string, content []byte) (option.InboundUser, error)
+	RemoveInboundUser(inbound string, name string) error
+	InboundUserLink(inbound string, name string, server string) (string, error)
+	// Drain rejects new connections of the inbounds, or of all inbounds if
+	// none is given, until routed ones are closed or the timeout elapses.
+	Drain(inbounds []string, timeout time.Duration) error
+	DrainStatus() DrainStatus
+	// RegisterDrainListener registers a listener of the inbound to close when
+	// the inbound is drained, the returned function unregisters it.
+	RegisterDrainListener(inbound string, listener io.Closer) (unregister func())
 
 	Reload()
 }
 
+const (
+	BudgetInboundConnections = "inbound_connections"
+	BudgetInboundSniffBuffer = "inbound_sniff_buffer"
+	BudgetUserConnections    = "user_connections"
+	BudgetSniffBuffer        = "sniff_buffer"
+)
+
+// BudgetStats describes the usage of a subsystem budget. Key is the inbound
+// tag or user name the budget belongs to, if any.
+type BudgetStats struct {
+	Subsystem string
+	Key       string
+	Limit     int64
+	Used      int64
+	Peak      int64
+	Rejected  uint64
+}
+
+// DrainStatus describes a drain started by Router.Drain, Inbounds is empty
+// if all inbounds are drained.
+type DrainStatus struct {
+	Draining  bool
+	Finished  bool
+	Inbounds  []string
+	Active    int
+	Total     int
+	StartedAt time.Time
+	Deadline  time.Time
+}
+
 type ConnectionTracker interface {
 	RoutedConnection(ctx context.Context, conn net.Conn, metadata InboundContext, matchedRule Rule, matchOutbound Outbound) net.Conn
 	RoutedPacketConnection(ctx context.Context, conn N.PacketConn, metadata InboundContext, matchedRule Rule, matchOutbound Outbound) N.PacketConn
@@ -70,6 +115,25 @@ type RuleSet interface {
 	HeadlessRule
 }
 
+// MutableRuleSet is a RuleSet whose rules can be replaced at runtime,
+// implemented by local and inline rule-sets.
+type MutableRuleSet interface {
+	RuleSet
+	SetRules(rules []option.HeadlessRule) error
+}
+
+// ManagedRuleSet is a RuleSet whose updates can be scheduled by a service,
+// implemented by remote rule-sets.
+type ManagedRuleSet interface {
+	RuleSet
+	// SetManaged stops the own update schedule of the rule-set, and checks
+	// downloaded content with verify before loading it if not nil.
+	// It must be called before the rule-set is started.
+	SetManaged(verify RuleSetVerifyFunc)
+}
+
+type RuleSetVerifyFunc func(ctx context.Context, url string, content []byte) error
+
 type RuleSetUpdateCallback func(it RuleSet)
 
 type RuleSetMetadata struct {
@@ -77,6 +141,18 @@ type RuleSetMetadata struct {
 	ContainsWIFIRule    bool
 	ContainsIPCIDRRule  bool
 }
+type offlineKey struct{}
+
+// WithOffline marks the context to start without network access, remote
+// resources failing to download are then logged instead of failing the start.
+func WithOffline(ctx context.Context) context.Context {
+	return context.WithValue(ctx, offlineKey{}, true)
+}
+
+func IsOffline(ctx context.Context) bool {
+	return ctx.Value(offlineKey{}) != nil
+}
+
 type HTTPStartContext struct {
 	ctx             context.Context
 	access          sync.Mutex
@@ -1,7 +1,12 @@
 package adapter
 
 import (
+	"context"
+	"net/netip"
+
 	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing/common/json"
 )
 
 type HeadlessRule interface {
@@ -22,6 +27,27 @@ type DNSRule interface {
 	MatchAddressLimit(metadata *InboundContext) bool
 }
 
+// RuleItemRegistry creates the rule items configured in the `custom` field
+// of route and DNS rules, by their registered type.
+type RuleItemRegistry interface {
+	CreateRuleItem(ctx context.Context, logger log.ContextLogger, itemType string, rawOptions json.RawMessage) (HeadlessRule, error)
+}
+
+// ProxyBypassRule is implemented by route rules that can be written as a
+// proxy bypass list, such as of a PAC file.
+type ProxyBypassRule interface {
+	ProxyBypass() (bypass ProxyBypass, loaded bool)
+}
+
+// ProxyBypass is a route rule only matching destination domains or
+// addresses, routed to Outbound.
+type ProxyBypass struct {
+	Outbound     string
+	Domain       []string
+	DomainSuffix []string
+	IPCIDR       []netip.Prefix
+}
+
 type RuleAction interface {
 	Type() string
 	String() string
@@ -0,0 +1,24 @@
+package adapter
+
+import (
+	"context"
+	"time"
+)
+
+type Subscription interface {
+	Service
+	Outbounds() []string
+	Groups() []string
+	Update(ctx context.Context) error
+	UpdatedAt() time.Time
+	LastError() error
+	Info() *SubscriptionInfo
+}
+
+// SubscriptionInfo is the traffic and expiry information reported by the subscription-userinfo header.
+type SubscriptionInfo struct {
+	Upload   uint64 `json:"Upload"`
+	Download uint64 `json:"Download"`
+	Total    uint64 `json:"Total"`
+	Expire   int64  `json:"Expire"`
+}
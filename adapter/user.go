@@ -0,0 +1,20 @@
+package adapter
+
+import "time"
+
+// UserStatus is the status of a user of a multi-user inbound. Connections is
+// the number of routed connections of the user.
+type UserStatus struct {
+	Inbound     string
+	Name        string
+	Enabled     bool
+	ExpireAt    time.Time
+	Connections int
+}
+
+// UserStatusUpdate changes the status of a user, nil fields are kept and a
+// zero ExpireAt removes the expiry.
+type UserStatusUpdate struct {
+	Enabled  *bool
+	ExpireAt *time.Time
+}
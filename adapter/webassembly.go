@@ -0,0 +1,27 @@
+package adapter
+
+import "context"
+
+// WASMManager provides the sandboxed WebAssembly modules declared in
+// experimental.wasm by tag.
+type WASMManager interface {
+	LifecycleService
+	Module(tag string) (WASMModule, bool)
+}
+
+type WASMModule interface {
+	Tag() string
+	// Sniff runs the sniffer exported by the module on payload, returning
+	// nil if the protocol is not recognized.
+	Sniff(ctx context.Context, payload []byte, isPacket bool) (*WASMSniffResult, error)
+	// Match runs the rule evaluation function exported by the module with
+	// metadata.
+	Match(ctx context.Context, function string, metadata *InboundContext) (bool, error)
+}
+
+type WASMSniffResult struct {
+	NeedMoreData bool
+	Protocol     string
+	Domain       string
+	Client       string
+}
@@ -13,9 +13,14 @@ import (
 	"github.com/sagernet/sing-box/adapter/inbound"
 	"github.com/sagernet/sing-box/adapter/outbound"
 	boxService "github.com/sagernet/sing-box/adapter/service"
+	"github.com/sagernet/sing-box/common/acl"
 	"github.com/sagernet/sing-box/common/certificate"
 	"github.com/sagernet/sing-box/common/dialer"
+	"github.com/sagernet/sing-box/common/eventbus"
+	"github.com/sagernet/sing-box/common/power"
+	"github.com/sagernet/sing-box/common/privilege"
 	"github.com/sagernet/sing-box/common/taskmonitor"
+	"github.com/sagernet/sing-box/common/timesync"
 	"github.com/sagernet/sing-box/common/tls"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/dns"
@@ -38,6 +43,7 @@ import (
 var _ adapter.SimpleLifecycle = (*Box)(nil)
 
 type Box struct {
+	ctx             context.Context
 	createdAt       time.Time
 	logFactory      log.Factory
 	logger          log.ContextLogger
@@ -50,15 +56,28 @@ type Box struct {
 	dnsRouter       *dns.Router
 	connection      *route.ConnectionManager
 	router          *route.Router
+	eventBus        adapter.EventBus
 	internalService []adapter.LifecycleService
-	reloadChan     chan struct{}
+	privilege       option.PrivilegeOptions
+	reloadCaps      []string
+	reloadChan      chan struct{}
 	done            chan struct{}
+	cancel          context.CancelFunc
+	options         Options
 }
 
 type Options struct {
 	option.Options
 	Context           context.Context
 	PlatformLogWriter log.PlatformWriter
+
+	// set by functional options, see Create
+	config     []byte
+	logWriter  io.Writer
+	services   []ServiceFactory
+	startHooks []StartHook
+	closeHooks []CloseHook
+	eventBus   adapter.EventBus
 }
 
 func Context(
@@ -95,14 +114,23 @@ func Context(
 	return ctx
 }
 
-func New(options Options) (*Box, error) {
+func New(options Options) (_ *Box, err error) {
 	createdAt := time.Now()
 	reloadChan := make(chan struct{}, 1)
 	ctx := options.Context
 	if ctx == nil {
 		ctx = context.Background()
 	}
-	ctx = service.ContextWithDefaultRegistry(ctx)
+	ctx, cancel := context.WithCancel(ctx)
+	defer func() {
+		if err != nil {
+			cancel()
+		}
+	}()
+	// services of the instance are registered to a registry of its own, so
+	// that instances created from the same context, such as by Reload, do
+	// not find services of each other.
+	ctx = service.ContextWithRegistry(ctx, newChildRegistry(service.RegistryFromContext(ctx)))
 
 	endpointRegistry := service.FromContext[adapter.EndpointRegistry](ctx)
 	inboundRegistry := service.FromContext[adapter.InboundRegistry](ctx)
@@ -143,7 +171,9 @@ func New(options Options) (*Box, error) {
 	}
 	platformInterface := service.FromContext[platform.Interface](ctx)
 	var defaultLogWriter io.Writer
-	if platformInterface != nil {
+	if options.logWriter != nil {
+		defaultLogWriter = options.logWriter
+	} else if platformInterface != nil {
 		defaultLogWriter = io.Discard
 	}
 	logFactory, err := log.New(log.Options{
@@ -157,6 +187,7 @@ func New(options Options) (*Box, error) {
 	if err != nil {
 		return nil, E.Cause(err, "create log factory")
 	}
+	service.MustRegister[log.Factory](ctx, logFactory)
 
 	C.URLTestUnifiedDelay = experimentalOptions.URLTestUnifiedDelay
 
@@ -173,6 +204,12 @@ func New(options Options) (*Box, error) {
 		service.MustRegister[adapter.CertificateStore](ctx, certificateStore)
 		internalServices = append(internalServices, certificateStore)
 	}
+	powerManager, err := power.NewManager(ctx, logFactory.NewLogger("power"), common.PtrValueOrDefault(options.PowerSaving))
+	if err != nil {
+		return nil, E.Cause(err, "initialize power manager")
+	}
+	service.MustRegister[adapter.PowerManager](ctx, powerManager)
+	internalServices = append(internalServices, powerManager)
 
 	routeOptions := common.PtrValueOrDefault(options.Route)
 	dnsOptions := common.PtrValueOrDefault(options.DNS)
@@ -186,6 +223,23 @@ func New(options Options) (*Box, error) {
 	service.MustRegister[adapter.OutboundManager](ctx, outboundManager)
 	service.MustRegister[adapter.DNSTransportManager](ctx, dnsTransportManager)
 	service.MustRegister[adapter.ServiceManager](ctx, serviceManager)
+	service.MustRegister[adapter.InboundACLManager](ctx, acl.NewManager())
+	eventBus := options.eventBus
+	if eventBus == nil {
+		eventBus = service.FromContext[adapter.EventBus](ctx)
+	}
+	if eventBus == nil {
+		eventBus = eventbus.New()
+	}
+	service.MustRegister[adapter.EventBus](ctx, eventBus)
+	if experimentalOptions.WASM != nil && len(experimentalOptions.WASM.Modules) > 0 {
+		wasmManager, err := experimental.NewWASMManager(ctx, logFactory.NewLogger("wasm"), common.PtrValueOrDefault(experimentalOptions.WASM))
+		if err != nil {
+			return nil, E.Cause(err, "create wasm manager")
+		}
+		service.MustRegister[adapter.WASMManager](ctx, wasmManager)
+		internalServices = append(internalServices, wasmManager)
+	}
 	dnsRouter := dns.NewRouter(ctx, logFactory, dnsOptions)
 	service.MustRegister[adapter.DNSRouter](ctx, dnsRouter)
 	networkManager, err := route.NewNetworkManager(ctx, logFactory.NewLogger("network"), routeOptions)
@@ -193,10 +247,14 @@ func New(options Options) (*Box, error) {
 		return nil, E.Cause(err, "initialize network manager")
 	}
 	service.MustRegister[adapter.NetworkManager](ctx, networkManager)
-	connectionManager := route.NewConnectionManager(logFactory.NewLogger("connection"))
+	connectionManager, err := route.NewConnectionManager(ctx, logFactory.NewLogger("connection"), common.PtrValueOrDefault(experimentalOptions.IOURing), common.PtrValueOrDefault(routeOptions.UDPSession))
+	if err != nil {
+		return nil, E.Cause(err, "initialize connection manager")
+	}
 	service.MustRegister[adapter.ConnectionManager](ctx, connectionManager)
 	router := route.NewRouter(ctx, logFactory, routeOptions, dnsOptions, reloadChan)
 	service.MustRegister[adapter.Router](ctx, router)
+	router.AppendTracker(eventbus.NewTracker(eventBus, outboundManager))
 	err = router.Initialize(routeOptions.Rules, routeOptions.RuleSet)
 	if err != nil {
 		return nil, E.Cause(err, "initialize router")
@@ -273,6 +331,9 @@ func New(options Options) (*Box, error) {
 		if err != nil {
 			return nil, E.Cause(err, "initialize inbound[", i, "]")
 		}
+		if multiUserOptions, isMultiUser := inboundOptions.Options.(option.MultiUserInboundOptions); isMultiUser {
+			router.RegisterUsers(tag, multiUserOptions.InboundUsers())
+		}
 	}
 	for i, outboundOptions := range options.Outbounds {
 		var tag string
@@ -373,18 +434,30 @@ func New(options Options) (*Box, error) {
 		if err != nil {
 			return nil, E.Cause(err, "create NTP service")
 		}
-		ntpService := ntp.NewService(ntp.Options{
+		ntpService := timesync.NewService(timesync.Options{
 			Context:       ctx,
 			Dialer:        ntpDialer,
 			Logger:        logFactory.NewLogger("ntp"),
 			Server:        ntpOptions.ServerOptions.Build(),
 			Interval:      time.Duration(ntpOptions.Interval),
 			WriteToSystem: ntpOptions.WriteToSystem,
+			Listen: option.ListenOptions{
+				Listen:     ntpOptions.Listen,
+				ListenPort: ntpOptions.ListenPort,
+			},
 		})
 		timeService.TimeService = ntpService
 		internalServices = append(internalServices, adapter.NewLifecycleService(ntpService, "ntp service"))
 	}
+	for i, serviceFactory := range options.services {
+		lifecycleService, err := serviceFactory(ctx)
+		if err != nil {
+			return nil, E.Cause(err, "create embedded service[", i, "]")
+		}
+		internalServices = append(internalServices, lifecycleService)
+	}
 	return &Box{
+		ctx:             ctx,
 		network:         networkManager,
 		endpoint:        endpointManager,
 		inbound:         inboundManager,
@@ -394,12 +467,17 @@ func New(options Options) (*Box, error) {
 		dnsRouter:       dnsRouter,
 		connection:      connectionManager,
 		router:          router,
+		eventBus:        eventBus,
 		createdAt:       createdAt,
 		logFactory:      logFactory,
 		logger:          logFactory.Logger(),
 		internalService: internalServices,
-		reloadChan:     reloadChan,
+		privilege:       common.PtrValueOrDefault(options.Privilege),
+		reloadCaps:      privilege.ReloadCapabilities(options.Options),
+		reloadChan:      reloadChan,
 		done:            make(chan struct{}),
+		cancel:          cancel,
+		options:         options,
 	}, nil
 }
 
@@ -438,9 +516,26 @@ func (s *Box) Start() error {
 		return err
 	}
 	s.logger.Info("sing-box started (", F.Seconds(time.Since(s.createdAt).Seconds()), "s)")
+	for _, hook := range s.options.startHooks {
+		err = hook(s)
+		if err != nil {
+			s.Close()
+			return E.Cause(err, "start hook")
+		}
+	}
 	return nil
 }
 
+// PostStart runs the remaining start stages of the services started by PreStart,
+// without starting inbounds, endpoints and services, for tools which only dial.
+func (s *Box) PostStart() error {
+	err := adapter.Start(adapter.StartStatePostStart, s.outbound, s.network, s.dnsTransport, s.dnsRouter, s.connection, s.router)
+	if err != nil {
+		return err
+	}
+	return adapter.Start(adapter.StartStateStarted, s.network, s.dnsTransport, s.dnsRouter, s.connection, s.router, s.outbound)
+}
+
 func (s *Box) preStart() error {
 	monitor := taskmonitor.New(s.logger, C.StartTimeout)
 	monitor.Start("start logger")
@@ -493,6 +588,10 @@ func (s *Box) start() error {
 	if err != nil {
 		return err
 	}
+	err = privilege.Drop(s.logger, s.privilege, s.reloadCaps)
+	if err != nil {
+		return E.Cause(err, "drop privileges")
+	}
 	return nil
 }
 
@@ -503,6 +602,10 @@ func (s *Box) Close() error {
 	default:
 		close(s.done)
 	}
+	for _, hook := range s.options.closeHooks {
+		hook(s)
+	}
+	s.connection.SaveUDPSessions()
 	err := common.Close(
 		s.service, s.endpoint, s.inbound, s.outbound, s.router, s.connection, s.dnsRouter, s.dnsTransport, s.network,
 	)
@@ -514,9 +617,16 @@ func (s *Box) Close() error {
 	err = E.Append(err, s.logFactory.Close(), func(err error) error {
 		return E.Cause(err, "close logger")
 	})
+	s.cancel()
 	return err
 }
 
+// Context returns the context of the instance, which carries its services,
+// such as adapter.CacheFile and adapter.ClashServer.
+func (s *Box) Context() context.Context {
+	return s.ctx
+}
+
 func (s *Box) Network() adapter.NetworkManager {
 	return s.network
 }
@@ -525,6 +635,14 @@ func (s *Box) Router() adapter.Router {
 	return s.router
 }
 
+func (s *Box) DNSRouter() adapter.DNSRouter {
+	return s.dnsRouter
+}
+
+func (s *Box) DNSTransport() adapter.DNSTransportManager {
+	return s.dnsTransport
+}
+
 func (s *Box) Inbound() adapter.InboundManager {
 	return s.inbound
 }
@@ -533,6 +651,49 @@ func (s *Box) Outbound() adapter.OutboundManager {
 	return s.outbound
 }
 
+// EventBus returns the bus of connection, DNS and outbound health events,
+// it is kept across reloads so that subscriptions stay valid.
+func (s *Box) EventBus() adapter.EventBus {
+	return s.eventBus
+}
+
 func (s *Box) ReloadChan() <-chan struct{} {
 	return s.reloadChan
 }
+
+// Drain rejects new connections of the inbounds, or of the inbounds of the
+// drain options if none is given, DrainDone is closed once existing ones
+// are closed or timeout elapsed.
+func (s *Box) Drain(inbounds []string, timeout time.Duration) error {
+	return s.router.Drain(inbounds, timeout)
+}
+
+func (s *Box) DrainDone() <-chan struct{} {
+	return s.router.DrainDone()
+}
+
+// childRegistry holds the services of an instance, and falls back to the
+// registry of the parent context for services provided by the caller, such
+// as protocol registries and the platform interface.
+type childRegistry struct {
+	service.Registry
+	parent service.Registry
+}
+
+func newChildRegistry(parent service.Registry) service.Registry {
+	if parent == nil {
+		return service.NewRegistry()
+	}
+	return &childRegistry{
+		Registry: service.NewRegistry(),
+		parent:   parent,
+	}
+}
+
+func (r *childRegistry) Get(serviceType any) any {
+	registered := r.Registry.Get(serviceType)
+	if registered != nil {
+		return registered
+	}
+	return r.parent.Get(serviceType)
+}
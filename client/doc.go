@@ -0,0 +1,13 @@
+// Package client holds protocol clients that can be imported without the
+// rest of sing-box: each one is built from a N.Dialer of sing and a TLS
+// configuration, implements N.Dialer itself, and imports no sing-box
+// package outside this directory.
+//
+//   - client/hysteria2
+//   - client/tuic
+//   - client/vless
+//   - client/shadowsocks
+//   - client/http3, an HTTP/3 CONNECT and connect-udp proxy client
+//   - client/reality, a REALITY TLS configuration usable by client/vless
+//   - client/tls, TLS configurations and helpers shared by the clients
+package client
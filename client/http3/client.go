@@ -0,0 +1,252 @@
+// Package http3 provides an HTTP/3 CONNECT proxy client that depends only on
+// a dialer and a TLS configuration. UDP is proxied with connect-udp requests
+// (RFC 9298) and HTTP Datagrams.
+package http3
+
+import (
+	"context"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sagernet/quic-go"
+	"github.com/sagernet/quic-go/http3"
+	"github.com/sagernet/sing-quic"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	aTLS "github.com/sagernet/sing/common/tls"
+)
+
+var _ N.Dialer = (*Client)(nil)
+
+type Options struct {
+	// Dialer dials the UDP connection to the server, N.SystemDialer is used
+	// if nil.
+	Dialer   N.Dialer
+	Server   M.Socksaddr
+	Username string
+	Password string
+	// Headers are sent with each request, Host overrides the authority of
+	// connect-udp requests.
+	Headers          http.Header
+	ZeroRTTHandshake bool
+	TLSConfig        aTLS.Config
+}
+
+type Client struct {
+	ctx        context.Context
+	dialer     N.Dialer
+	server     M.Socksaddr
+	authority  string
+	headers    http.Header
+	zeroRTT    bool
+	tlsConfig  aTLS.Config
+	quicConfig *quic.Config
+	transport  *http3.Transport
+	access     sync.Mutex
+	conn       *clientConn
+}
+
+type clientConn struct {
+	quicConn *quic.Conn
+	rawConn  net.Conn
+	conn     *http3.ClientConn
+}
+
+func (c *clientConn) closeWithError(err error) {
+	_ = c.quicConn.CloseWithError(0, err.Error())
+	_ = c.rawConn.Close()
+}
+
+func NewClient(ctx context.Context, options Options) (*Client, error) {
+	if options.TLSConfig == nil {
+		return nil, E.New("missing TLS config")
+	}
+	if options.Dialer == nil {
+		options.Dialer = N.SystemDialer
+	}
+	if len(options.TLSConfig.NextProtos()) == 0 {
+		options.TLSConfig.SetNextProtos([]string{http3.NextProtoH3})
+	}
+	headers := options.Headers.Clone()
+	if headers == nil {
+		headers = make(http.Header)
+	}
+	authority := headers.Get("Host")
+	if authority != "" {
+		headers.Del("Host")
+	} else {
+		authority = options.TLSConfig.ServerName()
+		if authority == "" {
+			authority = options.Server.AddrString()
+		}
+		if options.Server.Port != 443 {
+			authority = net.JoinHostPort(authority, strconv.Itoa(int(options.Server.Port)))
+		}
+	}
+	if options.Username != "" {
+		headers.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(options.Username+":"+options.Password)))
+	}
+	return &Client{
+		ctx:       ctx,
+		dialer:    options.Dialer,
+		server:    options.Server,
+		authority: authority,
+		headers:   headers,
+		zeroRTT:   options.ZeroRTTHandshake,
+		tlsConfig: options.TLSConfig,
+		quicConfig: &quic.Config{
+			EnableDatagrams: true,
+			KeepAlivePeriod: 25 * time.Second,
+		},
+		transport: &http3.Transport{
+			EnableDatagrams: true,
+		},
+	}, nil
+}
+
+func (c *Client) offer(ctx context.Context) (*clientConn, error) {
+	c.access.Lock()
+	defer c.access.Unlock()
+	if c.conn != nil && c.conn.quicConn.Context().Err() == nil {
+		return c.conn, nil
+	}
+	rawConn, err := c.dialer.DialContext(ctx, N.NetworkUDP, c.server)
+	if err != nil {
+		return nil, err
+	}
+	packetConn := bufio.NewUnbindPacketConn(rawConn)
+	var quicConn *quic.Conn
+	if c.zeroRTT {
+		quicConn, err = qtls.DialEarly(ctx, packetConn, rawConn.RemoteAddr(), c.tlsConfig, c.quicConfig)
+	} else {
+		quicConn, err = qtls.Dial(ctx, packetConn, rawConn.RemoteAddr(), c.tlsConfig, c.quicConfig)
+	}
+	if err != nil {
+		rawConn.Close()
+		return nil, E.Cause(err, "open connection")
+	}
+	c.conn = &clientConn{
+		quicConn: quicConn,
+		rawConn:  rawConn,
+		conn:     c.transport.NewClientConn(quicConn),
+	}
+	return c.conn, nil
+}
+
+func (c *Client) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	switch N.NetworkName(network) {
+	case N.NetworkTCP:
+		conn, err := c.offer(ctx)
+		if err != nil {
+			return nil, err
+		}
+		request := &http.Request{
+			Method: http.MethodConnect,
+			Host:   destination.String(),
+			URL:    &url.URL{Host: destination.String()},
+			Header: c.headers.Clone(),
+		}
+		stream, err := c.roundTrip(ctx, conn, request)
+		if err != nil {
+			return nil, err
+		}
+		return &streamConn{RequestStream: stream, quicConn: conn.quicConn}, nil
+	case N.NetworkUDP:
+		packetConn, err := c.ListenPacket(ctx, destination)
+		if err != nil {
+			return nil, err
+		}
+		return bufio.NewBindPacketConn(packetConn, destination), nil
+	default:
+		return nil, E.Extend(N.ErrUnknownNetwork, network)
+	}
+}
+
+// ListenPacket opens a connect-udp request to destination, packets to
+// other destinations are sent to it as well.
+func (c *Client) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	conn, err := c.offer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case <-conn.conn.ReceivedSettings():
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-conn.quicConn.Context().Done():
+		return nil, context.Cause(conn.quicConn.Context())
+	}
+	settings := conn.conn.Settings()
+	if !settings.EnableExtendedConnect || !settings.EnableDatagrams {
+		return nil, E.New("connect-udp: HTTP Datagrams or extended CONNECT disabled by server")
+	}
+	// the host is percent-encoded as required by the URI template
+	host := strings.ReplaceAll(url.PathEscape(destination.AddrString()), ":", "%3A")
+	requestURL, err := url.Parse("https://" + c.authority + "/.well-known/masque/udp/" + host + "/" + strconv.Itoa(int(destination.Port)) + "/")
+	if err != nil {
+		return nil, err
+	}
+	request := &http.Request{
+		Method: http.MethodConnect,
+		Proto:  "connect-udp",
+		Host:   c.authority,
+		URL:    requestURL,
+		Header: c.headers.Clone(),
+	}
+	request.Header.Set("Capsule-Protocol", "?1")
+	stream, err := c.roundTrip(ctx, conn, request)
+	if err != nil {
+		return nil, err
+	}
+	return bufio.NewNetPacketConn(newDatagramConn(stream, conn.quicConn, destination)), nil
+}
+
+func (c *Client) roundTrip(ctx context.Context, conn *clientConn, request *http.Request) (*http3.RequestStream, error) {
+	stream, err := conn.conn.OpenRequestStream(ctx)
+	if err != nil {
+		return nil, E.Cause(err, "open request stream")
+	}
+	err = stream.SendRequestHeader(request)
+	if err != nil {
+		stream.CancelRead(quic.StreamErrorCode(http3.ErrCodeRequestCanceled))
+		stream.CancelWrite(quic.StreamErrorCode(http3.ErrCodeRequestCanceled))
+		return nil, E.Cause(err, "send request")
+	}
+	response, err := stream.ReadResponse()
+	if err != nil {
+		stream.CancelRead(quic.StreamErrorCode(http3.ErrCodeRequestCanceled))
+		stream.CancelWrite(quic.StreamErrorCode(http3.ErrCodeRequestCanceled))
+		return nil, E.Cause(err, "read response")
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		stream.CancelRead(quic.StreamErrorCode(http3.ErrCodeNoError))
+		stream.CancelWrite(quic.StreamErrorCode(http3.ErrCodeNoError))
+		return nil, E.New("unexpected status: ", response.Status)
+	}
+	return stream, nil
+}
+
+// CloseWithError closes the current QUIC connection, a new one is created
+// by the next dial.
+func (c *Client) CloseWithError(err error) error {
+	c.access.Lock()
+	defer c.access.Unlock()
+	if c.conn != nil {
+		c.conn.closeWithError(err)
+		c.conn = nil
+	}
+	return nil
+}
+
+func (c *Client) Close() error {
+	return c.CloseWithError(os.ErrClosed)
+}
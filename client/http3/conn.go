@@ -0,0 +1,150 @@
+package http3
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sagernet/quic-go"
+	"github.com/sagernet/quic-go/http3"
+	"github.com/sagernet/sing/common/buf"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/pipe"
+)
+
+type streamConn struct {
+	*http3.RequestStream
+	quicConn *quic.Conn
+}
+
+func (c *streamConn) Close() error {
+	c.RequestStream.CancelRead(quic.StreamErrorCode(http3.ErrCodeNoError))
+	return c.RequestStream.Close()
+}
+
+func (c *streamConn) LocalAddr() net.Addr {
+	return c.quicConn.LocalAddr()
+}
+
+func (c *streamConn) RemoteAddr() net.Addr {
+	return c.quicConn.RemoteAddr()
+}
+
+func (c *streamConn) Upstream() any {
+	return c.RequestStream
+}
+
+const datagramQueueSize = 64
+
+var _ N.PacketConn = (*datagramConn)(nil)
+
+// datagramConn carries packets in HTTP Datagrams with context ID 0, and is
+// closed with the request stream.
+type datagramConn struct {
+	stream       *http3.RequestStream
+	quicConn     *quic.Conn
+	destination  M.Socksaddr
+	ctx          context.Context
+	cancel       context.CancelFunc
+	datagrams    chan []byte
+	readDeadline pipe.Deadline
+	closeOnce    sync.Once
+}
+
+func newDatagramConn(stream *http3.RequestStream, quicConn *quic.Conn, destination M.Socksaddr) *datagramConn {
+	ctx, cancel := context.WithCancel(quicConn.Context())
+	conn := &datagramConn{
+		stream:       stream,
+		quicConn:     quicConn,
+		destination:  destination,
+		ctx:          ctx,
+		cancel:       cancel,
+		datagrams:    make(chan []byte, datagramQueueSize),
+		readDeadline: pipe.MakeDeadline(),
+	}
+	go conn.loopReceive()
+	go conn.waitStream()
+	return conn
+}
+
+func (c *datagramConn) loopReceive() {
+	for {
+		datagram, err := c.stream.ReceiveDatagram(c.ctx)
+		if err != nil {
+			c.Close()
+			return
+		}
+		select {
+		case c.datagrams <- datagram:
+		default:
+		}
+	}
+}
+
+func (c *datagramConn) waitStream() {
+	_, _ = io.Copy(io.Discard, c.stream)
+	c.Close()
+}
+
+func (c *datagramConn) ReadPacket(buffer *buf.Buffer) (destination M.Socksaddr, err error) {
+	for {
+		var datagram []byte
+		select {
+		case datagram = <-c.datagrams:
+		case <-c.ctx.Done():
+			return M.Socksaddr{}, net.ErrClosed
+		case <-c.readDeadline.Wait():
+			return M.Socksaddr{}, os.ErrDeadlineExceeded
+		}
+		// only UDP payloads with context ID 0 are supported
+		if len(datagram) == 0 || datagram[0] != 0 {
+			continue
+		}
+		_, err = buffer.Write(datagram[1:])
+		if err != nil {
+			return M.Socksaddr{}, err
+		}
+		return c.destination, nil
+	}
+}
+
+func (c *datagramConn) WritePacket(buffer *buf.Buffer, destination M.Socksaddr) error {
+	defer buffer.Release()
+	buffer.ExtendHeader(1)[0] = 0
+	return c.stream.SendDatagram(buffer.Bytes())
+}
+
+func (c *datagramConn) FrontHeadroom() int {
+	return 1
+}
+
+func (c *datagramConn) LocalAddr() net.Addr {
+	return c.quicConn.LocalAddr()
+}
+
+func (c *datagramConn) SetDeadline(t time.Time) error {
+	c.readDeadline.Set(t)
+	return nil
+}
+
+func (c *datagramConn) SetReadDeadline(t time.Time) error {
+	c.readDeadline.Set(t)
+	return nil
+}
+
+func (c *datagramConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *datagramConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.cancel()
+		c.stream.CancelRead(quic.StreamErrorCode(http3.ErrCodeNoError))
+		c.stream.Close()
+	})
+	return nil
+}
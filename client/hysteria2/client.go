@@ -0,0 +1,104 @@
+// Package hysteria2 provides a Hysteria2 client that depends only on a dialer
+// and a TLS configuration.
+package hysteria2
+
+import (
+	"context"
+	"net"
+	"os"
+	"time"
+
+	"github.com/sagernet/sing-quic/hysteria"
+	"github.com/sagernet/sing-quic/hysteria2"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	aTLS "github.com/sagernet/sing/common/tls"
+)
+
+var _ N.Dialer = (*Client)(nil)
+
+type Options struct {
+	// Dialer dials the UDP connection to the server, N.SystemDialer is used
+	// if nil.
+	Dialer N.Dialer
+	Logger logger.Logger
+	Server M.Socksaddr
+	// ServerPorts are port ranges like "443:453" to hop between every
+	// HopInterval.
+	ServerPorts        []string
+	HopInterval        time.Duration
+	Password           string
+	SalamanderPassword string
+	UpMbps             int
+	DownMbps           int
+	TLSConfig          aTLS.Config
+	DisableUDP         bool
+	BrutalDebug        bool
+}
+
+type Client struct {
+	client *hysteria2.Client
+}
+
+func NewClient(ctx context.Context, options Options) (*Client, error) {
+	if options.TLSConfig == nil {
+		return nil, E.New("missing TLS config")
+	}
+	if options.Dialer == nil {
+		options.Dialer = N.SystemDialer
+	}
+	if options.Logger == nil {
+		options.Logger = logger.NOP()
+	}
+	client, err := hysteria2.NewClient(hysteria2.ClientOptions{
+		Context:            ctx,
+		Dialer:             options.Dialer,
+		Logger:             options.Logger,
+		BrutalDebug:        options.BrutalDebug,
+		ServerAddress:      options.Server,
+		ServerPorts:        options.ServerPorts,
+		HopInterval:        options.HopInterval,
+		SendBPS:            uint64(options.UpMbps * hysteria.MbpsToBps),
+		ReceiveBPS:         uint64(options.DownMbps * hysteria.MbpsToBps),
+		SalamanderPassword: options.SalamanderPassword,
+		Password:           options.Password,
+		TLSConfig:          options.TLSConfig,
+		UDPDisabled:        options.DisableUDP,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Client{client}, nil
+}
+
+func (c *Client) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	switch N.NetworkName(network) {
+	case N.NetworkTCP:
+		return c.client.DialConn(ctx, destination)
+	case N.NetworkUDP:
+		conn, err := c.ListenPacket(ctx, destination)
+		if err != nil {
+			return nil, err
+		}
+		return bufio.NewBindPacketConn(conn, destination), nil
+	default:
+		return nil, E.Extend(N.ErrUnknownNetwork, network)
+	}
+}
+
+func (c *Client) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	return c.client.ListenPacket(ctx)
+}
+
+// CloseWithError closes the current QUIC connection, a new one is created
+// by the next dial.
+func (c *Client) CloseWithError(err error) error {
+	return c.client.CloseWithError(err)
+}
+
+func (c *Client) Close() error {
+	return c.client.CloseWithError(os.ErrClosed)
+}
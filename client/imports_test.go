@@ -0,0 +1,35 @@
+package client
+
+import (
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStandaloneImports(t *testing.T) {
+	t.Parallel()
+	const modulePath = "github.com/sagernet/sing-box"
+	err := filepath.WalkDir(".", func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || !strings.HasSuffix(path, ".go") {
+			return err
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.ImportsOnly)
+		if err != nil {
+			return err
+		}
+		for _, importSpec := range file.Imports {
+			importPath, _ := strconv.Unquote(importSpec.Path.Value)
+			if importPath == modulePath || strings.HasPrefix(importPath, modulePath+"/") {
+				require.True(t, strings.HasPrefix(importPath, modulePath+"/client/"), path, " imports ", importPath)
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,365 @@
+// Package reality implements the REALITY client handshake on top of uTLS.
+package reality
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	mRand "math/rand"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+	"unsafe"
+
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/debug"
+	E "github.com/sagernet/sing/common/exceptions"
+	aTLS "github.com/sagernet/sing/common/tls"
+
+	utls "github.com/metacubex/utls"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/net/http2"
+)
+
+var _ aTLS.ConfigCompat = (*Config)(nil)
+
+type Options struct {
+	ServerName string
+	// PublicKey is the base64 (raw URL encoding) X25519 public key of the
+	// server.
+	PublicKey string
+	// ShortID is the hex encoded short ID, up to 8 bytes.
+	ShortID     string
+	Fingerprint utls.ClientHelloID
+	NextProtos  []string
+	// RootCAs and Time are used to verify the certificate of the target
+	// website when the server does not accept the client, and to complete a
+	// browser-like request to it.
+	RootCAs *x509.CertPool
+	Time    func() time.Time
+}
+
+type Config struct {
+	config      *utls.Config
+	fingerprint utls.ClientHelloID
+	publicKey   []byte
+	shortID     [8]byte
+	rootCAs     *x509.CertPool
+	time        func() time.Time
+}
+
+func NewConfig(options Options) (*Config, error) {
+	if options.ServerName == "" {
+		return nil, E.New("missing server name")
+	}
+	publicKey, err := ParsePublicKey(options.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	shortID, err := ParseShortID(options.ShortID)
+	if err != nil {
+		return nil, err
+	}
+	fingerprint := options.Fingerprint
+	if fingerprint == (utls.ClientHelloID{}) {
+		fingerprint = utls.HelloChrome_Auto
+	}
+	return NewConfigFromUTLS(&utls.Config{
+		ServerName: options.ServerName,
+		NextProtos: options.NextProtos,
+		RootCAs:    options.RootCAs,
+		Time:       options.Time,
+	}, fingerprint, publicKey, shortID, options.RootCAs, options.Time), nil
+}
+
+// NewConfigFromUTLS creates a Config from an existing uTLS config, which is
+// cloned for each handshake.
+func NewConfigFromUTLS(config *utls.Config, fingerprint utls.ClientHelloID, publicKey []byte, shortID [8]byte, rootCAs *x509.CertPool, timeFunc func() time.Time) *Config {
+	return &Config{
+		config:      config,
+		fingerprint: fingerprint,
+		publicKey:   publicKey,
+		shortID:     shortID,
+		rootCAs:     rootCAs,
+		time:        timeFunc,
+	}
+}
+
+func ParsePublicKey(publicKey string) ([]byte, error) {
+	key, err := base64.RawURLEncoding.DecodeString(publicKey)
+	if err != nil {
+		return nil, E.Cause(err, "decode public_key")
+	}
+	if len(key) != 32 {
+		return nil, E.New("invalid public_key")
+	}
+	return key, nil
+}
+
+func ParseShortID(shortID string) ([8]byte, error) {
+	var id [8]byte
+	decodedLen, err := hex.Decode(id[:], []byte(shortID))
+	if err != nil {
+		return id, E.Cause(err, "decode short_id")
+	}
+	if decodedLen > 8 {
+		return id, E.New("invalid short_id")
+	}
+	return id, nil
+}
+
+func (c *Config) ServerName() string {
+	return c.config.ServerName
+}
+
+func (c *Config) SetServerName(serverName string) {
+	c.config.ServerName = serverName
+}
+
+func (c *Config) NextProtos() []string {
+	return c.config.NextProtos
+}
+
+func (c *Config) SetNextProtos(nextProto []string) {
+	c.config.NextProtos = nextProto
+}
+
+func (c *Config) STDConfig() (*aTLS.STDConfig, error) {
+	return nil, E.New("unsupported usage for reality")
+}
+
+func (c *Config) Client(conn net.Conn) (aTLS.Conn, error) {
+	return c.ClientHandshake(context.Background(), conn)
+}
+
+func (c *Config) Clone() aTLS.Config {
+	return &Config{
+		config:      c.config.Clone(),
+		fingerprint: c.fingerprint,
+		publicKey:   c.publicKey,
+		shortID:     c.shortID,
+		rootCAs:     c.rootCAs,
+		time:        c.time,
+	}
+}
+
+func (c *Config) ClientHandshake(ctx context.Context, conn net.Conn) (aTLS.Conn, error) {
+	verifier := &realityVerifier{
+		serverName: c.config.ServerName,
+	}
+	uConfig := c.config.Clone()
+	uConfig.InsecureSkipVerify = true
+	uConfig.SessionTicketsDisabled = true
+	uConfig.VerifyPeerCertificate = verifier.VerifyPeerCertificate
+	uConn := utls.UClient(conn, uConfig, c.fingerprint)
+	verifier.UConn = uConn
+	err := uConn.BuildHandshakeState()
+	if err != nil {
+		return nil, err
+	}
+	for _, extension := range uConn.Extensions {
+		if ce, ok := extension.(*utls.SupportedCurvesExtension); ok {
+			ce.Curves = common.Filter(ce.Curves, func(curveID utls.CurveID) bool {
+				return curveID != utls.X25519MLKEM768
+			})
+		}
+		if ks, ok := extension.(*utls.KeyShareExtension); ok {
+			ks.KeyShares = common.Filter(ks.KeyShares, func(share utls.KeyShare) bool {
+				return share.Group != utls.X25519MLKEM768
+			})
+		}
+	}
+	err = uConn.BuildHandshakeState()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(uConfig.NextProtos) > 0 {
+		for _, extension := range uConn.Extensions {
+			if alpnExtension, isALPN := extension.(*utls.ALPNExtension); isALPN {
+				alpnExtension.AlpnProtocols = uConfig.NextProtos
+				break
+			}
+		}
+	}
+
+	hello := uConn.HandshakeState.Hello
+	hello.SessionId = make([]byte, 32)
+	copy(hello.Raw[39:], hello.SessionId)
+
+	var nowTime time.Time
+	if uConfig.Time != nil {
+		nowTime = uConfig.Time()
+	} else {
+		nowTime = time.Now()
+	}
+	binary.BigEndian.PutUint64(hello.SessionId, uint64(nowTime.Unix()))
+
+	hello.SessionId[0] = 1
+	hello.SessionId[1] = 8
+	hello.SessionId[2] = 1
+	binary.BigEndian.PutUint32(hello.SessionId[4:], uint32(time.Now().Unix()))
+	copy(hello.SessionId[8:], c.shortID[:])
+	if debug.Enabled {
+		fmt.Printf("REALITY hello.sessionId[:16]: %v\n", hello.SessionId[:16])
+	}
+	publicKey, err := ecdh.X25519().NewPublicKey(c.publicKey)
+	if err != nil {
+		return nil, err
+	}
+	keyShareKeys := uConn.HandshakeState.State13.KeyShareKeys
+	if keyShareKeys == nil {
+		return nil, E.New("nil KeyShareKeys")
+	}
+	ecdheKey := keyShareKeys.Ecdhe
+	if ecdheKey == nil {
+		return nil, E.New("nil ecdheKey")
+	}
+	authKey, err := ecdheKey.ECDH(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	if authKey == nil {
+		return nil, E.New("nil auth_key")
+	}
+	verifier.authKey = authKey
+	_, err = hkdf.New(sha256.New, authKey, hello.Random[:20], []byte("REALITY")).Read(authKey)
+	if err != nil {
+		return nil, err
+	}
+	aesBlock, _ := aes.NewCipher(authKey)
+	aesGcmCipher, _ := cipher.NewGCM(aesBlock)
+	aesGcmCipher.Seal(hello.SessionId[:0], hello.Random[20:], hello.SessionId[:16], hello.Raw)
+	copy(hello.Raw[39:], hello.SessionId)
+	if debug.Enabled {
+		fmt.Printf("REALITY hello.sessionId: %v\n", hello.SessionId)
+		fmt.Printf("REALITY uConn.AuthKey: %v\n", authKey)
+	}
+
+	err = uConn.HandshakeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if debug.Enabled {
+		fmt.Printf("REALITY Conn.Verified: %v\n", verifier.verified)
+	}
+
+	if !verifier.verified {
+		go c.fallback(uConn)
+		return nil, E.New("reality verification failed")
+	}
+
+	return &clientConn{uConn}, nil
+}
+
+func (c *Config) fallback(uConn net.Conn) {
+	defer uConn.Close()
+	client := &http.Client{
+		Transport: &http2.Transport{
+			DialTLSContext: func(ctx context.Context, network, addr string, config *tls.Config) (net.Conn, error) {
+				return uConn, nil
+			},
+			TLSClientConfig: &tls.Config{
+				Time:    c.time,
+				RootCAs: c.rootCAs,
+			},
+		},
+	}
+	request, _ := http.NewRequest("GET", "https://"+c.config.ServerName, nil)
+	request.Header.Set("User-Agent", c.fingerprint.Client)
+	request.AddCookie(&http.Cookie{Name: "padding", Value: strings.Repeat("0", mRand.Intn(32)+30)})
+	response, err := client.Do(request)
+	if err != nil {
+		return
+	}
+	_, _ = io.Copy(io.Discard, response.Body)
+	response.Body.Close()
+}
+
+type realityVerifier struct {
+	*utls.UConn
+	serverName string
+	authKey    []byte
+	verified   bool
+}
+
+func (c *realityVerifier) VerifyPeerCertificate(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+	p, _ := reflect.TypeOf(c.Conn).Elem().FieldByName("peerCertificates")
+	certs := *(*([]*x509.Certificate))(unsafe.Pointer(uintptr(unsafe.Pointer(c.Conn)) + p.Offset))
+	if pub, ok := certs[0].PublicKey.(ed25519.PublicKey); ok {
+		h := hmac.New(sha512.New, c.authKey)
+		h.Write(pub)
+		if bytes.Equal(h.Sum(nil), certs[0].Signature) {
+			c.verified = true
+			return nil
+		}
+	}
+	opts := x509.VerifyOptions{
+		DNSName:       c.serverName,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	if _, err := certs[0].Verify(opts); err != nil {
+		return err
+	}
+	return nil
+}
+
+type clientConn struct {
+	*utls.UConn
+}
+
+func (c *clientConn) ConnectionState() tls.ConnectionState {
+	state := c.Conn.ConnectionState()
+	//nolint:staticcheck
+	return tls.ConnectionState{
+		Version:                     state.Version,
+		HandshakeComplete:           state.HandshakeComplete,
+		DidResume:                   state.DidResume,
+		CipherSuite:                 state.CipherSuite,
+		NegotiatedProtocol:          state.NegotiatedProtocol,
+		NegotiatedProtocolIsMutual:  state.NegotiatedProtocolIsMutual,
+		ServerName:                  state.ServerName,
+		PeerCertificates:            state.PeerCertificates,
+		VerifiedChains:              state.VerifiedChains,
+		SignedCertificateTimestamps: state.SignedCertificateTimestamps,
+		OCSPResponse:                state.OCSPResponse,
+		TLSUnique:                   state.TLSUnique,
+	}
+}
+
+func (c *clientConn) Upstream() any {
+	return c.UConn
+}
+
+// Due to low implementation quality, the reality server intercepted half close and caused memory leaks.
+// We fixed it by calling Close() directly.
+func (c *clientConn) CloseWrite() error {
+	return c.Close()
+}
+
+func (c *clientConn) ReaderReplaceable() bool {
+	return true
+}
+
+func (c *clientConn) WriterReplaceable() bool {
+	return true
+}
@@ -0,0 +1,114 @@
+// Package shadowsocks provides a Shadowsocks client, including the 2022
+// methods, that depends only on a dialer.
+package shadowsocks
+
+import (
+	"context"
+	"net"
+
+	"github.com/sagernet/sing-shadowsocks2"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/uot"
+)
+
+var _ N.Dialer = (*Client)(nil)
+
+type Options struct {
+	// Dialer dials the connections to the server, N.SystemDialer is used if
+	// nil.
+	Dialer   N.Dialer
+	Server   M.Socksaddr
+	Method   string
+	Password string
+	// UDPOverTCP relays UDP over TCP with the UoT protocol of the given
+	// version, 0 disables it.
+	UDPOverTCP uint8
+}
+
+type Client struct {
+	dialer    N.Dialer
+	server    M.Socksaddr
+	method    shadowsocks.Method
+	uotClient *uot.Client
+}
+
+func NewClient(ctx context.Context, options Options) (*Client, error) {
+	if options.Dialer == nil {
+		options.Dialer = N.SystemDialer
+	}
+	method, err := shadowsocks.CreateMethod(ctx, options.Method, shadowsocks.MethodOptions{
+		Password: options.Password,
+	})
+	if err != nil {
+		return nil, err
+	}
+	client := &Client{
+		dialer: options.Dialer,
+		server: options.Server,
+		method: method,
+	}
+	if options.UDPOverTCP > 0 {
+		client.uotClient = &uot.Client{
+			Dialer:  (*shadowsocksDialer)(client),
+			Version: options.UDPOverTCP,
+		}
+	}
+	return client, nil
+}
+
+func (c *Client) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	if N.NetworkName(network) == N.NetworkUDP && c.uotClient != nil {
+		return c.uotClient.DialContext(ctx, network, destination)
+	}
+	return (*shadowsocksDialer)(c).DialContext(ctx, network, destination)
+}
+
+func (c *Client) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	if c.uotClient != nil {
+		return c.uotClient.ListenPacket(ctx, destination)
+	}
+	return (*shadowsocksDialer)(c).ListenPacket(ctx, destination)
+}
+
+// DialConn starts a Shadowsocks TCP request over an established connection
+// to the server, e.g. one from a SIP003 plugin.
+func (c *Client) DialConn(conn net.Conn, destination M.Socksaddr) net.Conn {
+	return c.method.DialEarlyConn(conn, destination)
+}
+
+// DialPacketConn wraps an established UDP connection to the server.
+func (c *Client) DialPacketConn(conn net.Conn) N.NetPacketConn {
+	return c.method.DialPacketConn(conn)
+}
+
+type shadowsocksDialer Client
+
+func (c *shadowsocksDialer) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	switch N.NetworkName(network) {
+	case N.NetworkTCP:
+		outConn, err := c.dialer.DialContext(ctx, N.NetworkTCP, c.server)
+		if err != nil {
+			return nil, err
+		}
+		return c.method.DialEarlyConn(outConn, destination), nil
+	case N.NetworkUDP:
+		outConn, err := c.dialer.DialContext(ctx, N.NetworkUDP, c.server)
+		if err != nil {
+			return nil, err
+		}
+		return bufio.NewBindPacketConn(c.method.DialPacketConn(outConn), destination), nil
+	default:
+		return nil, E.Extend(N.ErrUnknownNetwork, network)
+	}
+}
+
+func (c *shadowsocksDialer) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	outConn, err := c.dialer.DialContext(ctx, N.NetworkUDP, c.server)
+	if err != nil {
+		return nil, err
+	}
+	return c.method.DialPacketConn(outConn), nil
+}
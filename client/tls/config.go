@@ -0,0 +1,72 @@
+// Package tls provides TLS configurations for the client packages, which
+// accept any Config of sing, e.g. STDConfig or a REALITY config.
+package tls
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	aTLS "github.com/sagernet/sing/common/tls"
+)
+
+type (
+	Config = aTLS.Config
+	Conn   = aTLS.Conn
+)
+
+var _ Config = (*STDConfig)(nil)
+
+// STDConfig is a Config of crypto/tls.
+type STDConfig struct {
+	config *tls.Config
+}
+
+func NewSTD(config *tls.Config) *STDConfig {
+	return &STDConfig{config}
+}
+
+func (c *STDConfig) ServerName() string {
+	return c.config.ServerName
+}
+
+func (c *STDConfig) SetServerName(serverName string) {
+	c.config.ServerName = serverName
+}
+
+func (c *STDConfig) NextProtos() []string {
+	return c.config.NextProtos
+}
+
+func (c *STDConfig) SetNextProtos(nextProto []string) {
+	c.config.NextProtos = nextProto
+}
+
+func (c *STDConfig) STDConfig() (*aTLS.STDConfig, error) {
+	return c.config, nil
+}
+
+func (c *STDConfig) Client(conn net.Conn) (Conn, error) {
+	return tls.Client(conn, c.config), nil
+}
+
+func (c *STDConfig) Clone() Config {
+	return &STDConfig{c.config.Clone()}
+}
+
+// Dial connects to destination with dialer and performs the TLS handshake.
+func Dial(ctx context.Context, dialer N.Dialer, destination M.Socksaddr, config Config) (Conn, error) {
+	conn, err := dialer.DialContext(ctx, N.NetworkTCP, destination)
+	if err != nil {
+		return nil, err
+	}
+	tlsConn, err := aTLS.ClientHandshake(ctx, conn, config)
+	if err != nil {
+		conn.Close()
+		return nil, E.Cause(err, "TLS handshake")
+	}
+	return tlsConn, nil
+}
@@ -0,0 +1,141 @@
+// Package tuic provides a TUIC client that depends only on a dialer and a
+// TLS configuration.
+package tuic
+
+import (
+	"context"
+	"net"
+	"os"
+	"time"
+
+	"github.com/sagernet/sing-quic/tuic"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	aTLS "github.com/sagernet/sing/common/tls"
+	"github.com/sagernet/sing/common/uot"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+var _ N.Dialer = (*Client)(nil)
+
+type Options struct {
+	// Dialer dials the UDP connection to the server, N.SystemDialer is used
+	// if nil.
+	Dialer   N.Dialer
+	Server   M.Socksaddr
+	UUID     string
+	Password string
+	// CongestionControl is one of cubic (default), new_reno and bbr.
+	CongestionControl string
+	// UDPRelayMode is native (default) or quic.
+	UDPRelayMode string
+	// UDPOverStream relays UDP over TCP streams with the UoT protocol
+	// instead, it conflicts with UDPRelayMode.
+	UDPOverStream    bool
+	ZeroRTTHandshake bool
+	Heartbeat        time.Duration
+	TLSConfig        aTLS.Config
+}
+
+type Client struct {
+	client    *tuic.Client
+	udpStream bool
+}
+
+func NewClient(ctx context.Context, options Options) (*Client, error) {
+	if options.TLSConfig == nil {
+		return nil, E.New("missing TLS config")
+	}
+	if options.Dialer == nil {
+		options.Dialer = N.SystemDialer
+	}
+	userUUID, err := uuid.FromString(options.UUID)
+	if err != nil {
+		return nil, E.Cause(err, "invalid uuid")
+	}
+	var tuicUDPStream bool
+	if options.UDPOverStream && options.UDPRelayMode != "" {
+		return nil, E.New("udp_over_stream is conflict with udp_relay_mode")
+	}
+	switch options.UDPRelayMode {
+	case "native":
+	case "quic":
+		tuicUDPStream = true
+	}
+	client, err := tuic.NewClient(tuic.ClientOptions{
+		Context:           ctx,
+		Dialer:            options.Dialer,
+		ServerAddress:     options.Server,
+		TLSConfig:         options.TLSConfig,
+		UUID:              userUUID,
+		Password:          options.Password,
+		CongestionControl: options.CongestionControl,
+		UDPStream:         tuicUDPStream,
+		ZeroRTTHandshake:  options.ZeroRTTHandshake,
+		Heartbeat:         options.Heartbeat,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		client:    client,
+		udpStream: options.UDPOverStream,
+	}, nil
+}
+
+// UDPOverStream returns whether UDP is relayed over TCP streams.
+func (c *Client) UDPOverStream() bool {
+	return c.udpStream
+}
+
+func (c *Client) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	switch N.NetworkName(network) {
+	case N.NetworkTCP:
+		return c.client.DialConn(ctx, destination)
+	case N.NetworkUDP:
+		if c.udpStream {
+			streamConn, err := c.client.DialConn(ctx, uot.RequestDestination(uot.Version))
+			if err != nil {
+				return nil, err
+			}
+			return uot.NewLazyConn(streamConn, uot.Request{
+				IsConnect:   true,
+				Destination: destination,
+			}), nil
+		}
+		conn, err := c.ListenPacket(ctx, destination)
+		if err != nil {
+			return nil, err
+		}
+		return bufio.NewBindPacketConn(conn, destination), nil
+	default:
+		return nil, E.Extend(N.ErrUnknownNetwork, network)
+	}
+}
+
+func (c *Client) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	if c.udpStream {
+		streamConn, err := c.client.DialConn(ctx, uot.RequestDestination(uot.Version))
+		if err != nil {
+			return nil, err
+		}
+		return uot.NewLazyConn(streamConn, uot.Request{
+			IsConnect:   false,
+			Destination: destination,
+		}), nil
+	}
+	return c.client.ListenPacket(ctx)
+}
+
+// CloseWithError closes the current QUIC connection, a new one is created
+// by the next dial.
+func (c *Client) CloseWithError(err error) error {
+	return c.client.CloseWithError(err)
+}
+
+func (c *Client) Close() error {
+	return c.client.CloseWithError(os.ErrClosed)
+}
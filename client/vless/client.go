@@ -0,0 +1,157 @@
+// Package vless provides a VLESS client that depends only on a dialer and
+// an optional TLS configuration, e.g. a REALITY config of
+// github.com/sagernet/sing-box/client/reality.
+package vless
+
+import (
+	"context"
+	"net"
+
+	"github.com/sagernet/sing-vmess/packetaddr"
+	"github.com/sagernet/sing-vmess/vless"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	aTLS "github.com/sagernet/sing/common/tls"
+)
+
+const (
+	PacketEncodingXUDP       = "xudp"
+	PacketEncodingPacketAddr = "packetaddr"
+	PacketEncodingNone       = "none"
+)
+
+var _ N.Dialer = (*Client)(nil)
+
+type Options struct {
+	// Dialer dials the TCP connection to the server, N.SystemDialer is used
+	// if nil.
+	Dialer N.Dialer
+	Logger logger.Logger
+	Server M.Socksaddr
+	UUID   string
+	// Flow xtls-rprx-vision requires a TLS config, and the with_utls build
+	// tag if it is a REALITY or uTLS config.
+	Flow string
+	// TLSConfig is optional.
+	TLSConfig aTLS.Config
+	// PacketEncoding is one of xudp (default), packetaddr and none.
+	PacketEncoding string
+}
+
+type Client struct {
+	dialer     N.Dialer
+	server     M.Socksaddr
+	tlsConfig  aTLS.Config
+	client     *vless.Client
+	xudp       bool
+	packetAddr bool
+}
+
+func NewClient(options Options) (*Client, error) {
+	if options.Dialer == nil {
+		options.Dialer = N.SystemDialer
+	}
+	if options.Logger == nil {
+		options.Logger = logger.NOP()
+	}
+	client := &Client{
+		dialer:    options.Dialer,
+		server:    options.Server,
+		tlsConfig: options.TLSConfig,
+	}
+	switch options.PacketEncoding {
+	case "", PacketEncodingXUDP:
+		client.xudp = true
+	case PacketEncodingPacketAddr:
+		client.packetAddr = true
+	case PacketEncodingNone:
+	default:
+		return nil, E.New("unknown packet encoding: ", options.PacketEncoding)
+	}
+	var err error
+	client.client, err = vless.NewClient(options.UUID, options.Flow, options.Logger)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+func (c *Client) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	conn, err := c.dialServer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.DialConn(conn, network, destination)
+}
+
+func (c *Client) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	conn, err := c.dialServer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.DialPacketConn(conn, destination)
+}
+
+// DialConn starts a VLESS request over an established connection to the
+// server.
+func (c *Client) DialConn(conn net.Conn, network string, destination M.Socksaddr) (net.Conn, error) {
+	switch N.NetworkName(network) {
+	case N.NetworkTCP:
+		return c.client.DialEarlyConn(conn, destination)
+	case N.NetworkUDP:
+		if c.xudp {
+			return c.client.DialEarlyXUDPPacketConn(conn, destination)
+		} else if c.packetAddr {
+			if destination.IsFqdn() {
+				return nil, E.New("packetaddr: domain destination is not supported")
+			}
+			packetConn, err := c.client.DialEarlyPacketConn(conn, M.Socksaddr{Fqdn: packetaddr.SeqPacketMagicAddress})
+			if err != nil {
+				return nil, err
+			}
+			return bufio.NewBindPacketConn(packetaddr.NewConn(packetConn, destination), destination), nil
+		} else {
+			return c.client.DialEarlyPacketConn(conn, destination)
+		}
+	default:
+		return nil, E.Extend(N.ErrUnknownNetwork, network)
+	}
+}
+
+// DialPacketConn starts a VLESS UDP request over an established connection
+// to the server.
+func (c *Client) DialPacketConn(conn net.Conn, destination M.Socksaddr) (net.PacketConn, error) {
+	if c.xudp {
+		return c.client.DialEarlyXUDPPacketConn(conn, destination)
+	} else if c.packetAddr {
+		if destination.IsFqdn() {
+			return nil, E.New("packetaddr: domain destination is not supported")
+		}
+		packetConn, err := c.client.DialEarlyPacketConn(conn, M.Socksaddr{Fqdn: packetaddr.SeqPacketMagicAddress})
+		if err != nil {
+			return nil, err
+		}
+		return packetaddr.NewConn(packetConn, destination), nil
+	} else {
+		return c.client.DialEarlyPacketConn(conn, destination)
+	}
+}
+
+func (c *Client) dialServer(ctx context.Context) (net.Conn, error) {
+	conn, err := c.dialer.DialContext(ctx, N.NetworkTCP, c.server)
+	if err != nil {
+		return nil, err
+	}
+	if c.tlsConfig == nil {
+		return conn, nil
+	}
+	tlsConn, err := aTLS.ClientHandshake(ctx, conn, c.tlsConfig)
+	if err != nil {
+		conn.Close()
+		return nil, E.Cause(err, "TLS handshake")
+	}
+	return tlsConn, nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/sagernet/sing-box/common/srs"
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+const dataPath = "common/geodata/data"
+
+var ruleSets = map[string]string{
+	"geoip-cn.srs":   "https://raw.githubusercontent.com/SagerNet/sing-geoip/rule-set/geoip-cn.srs",
+	"geosite-cn.srs": "https://raw.githubusercontent.com/SagerNet/sing-geosite/rule-set/geosite-cn.srs",
+}
+
+func main() {
+	for name, url := range ruleSets {
+		err := updateRuleSet(name, url)
+		if err != nil {
+			log.Fatal(E.Cause(err, "update ", name))
+		}
+		log.Info("updated ", name)
+	}
+}
+
+func updateRuleSet(name string, url string) error {
+	response, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return E.New("unexpected status: ", response.Status)
+	}
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	_, err = srs.Read(bytes.NewReader(content), false)
+	if err != nil {
+		return E.Cause(err, "invalid rule-set")
+	}
+	return os.WriteFile(filepath.Join(dataPath, name), content, 0o644)
+}
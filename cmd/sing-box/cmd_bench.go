@@ -0,0 +1,14 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var commandBench = &cobra.Command{
+	Use:   "bench",
+	Short: "Benchmark configuration",
+}
+
+func init() {
+	mainCommand.AddCommand(commandBench)
+}
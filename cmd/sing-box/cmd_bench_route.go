@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/netip"
+	"os"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/sagernet/sing-box"
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/process"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	commandBenchRouteFlagInput     string
+	commandBenchRouteFlagSynthetic int
+	commandBenchRouteFlagRounds    int
+)
+
+var commandBenchRoute = &cobra.Command{
+	Use:   "route",
+	Short: "Benchmark route rule matching",
+	Long: `Benchmark route rule matching.
+
+Connections are read from --input as JSON lines, or generated with random
+destinations if not set. Each line is an object with fields: network, inbound,
+inbound_type, source, destination, domain, protocol, client, user,
+process_path, package_name and destination_addresses.
+
+Rules are matched without sniffing, resolving or searching processes, so
+metadata normally provided by them must be included in the input.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := benchRoute()
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandBenchRoute.Flags().StringVarP(&commandBenchRouteFlagInput, "input", "i", "", "connections file in JSON lines, - for stdin")
+	commandBenchRoute.Flags().IntVar(&commandBenchRouteFlagSynthetic, "synthetic", 10000, "number of generated connections if no input is given")
+	commandBenchRoute.Flags().IntVar(&commandBenchRouteFlagRounds, "rounds", 10, "number of times to match all connections")
+	commandBench.AddCommand(commandBenchRoute)
+}
+
+type benchConnection struct {
+	Network              string       `json:"network,omitempty"`
+	Inbound              string       `json:"inbound,omitempty"`
+	InboundType          string       `json:"inbound_type,omitempty"`
+	Source               string       `json:"source,omitempty"`
+	Destination          string       `json:"destination,omitempty"`
+	Domain               string       `json:"domain,omitempty"`
+	Protocol             string       `json:"protocol,omitempty"`
+	Client               string       `json:"client,omitempty"`
+	User                 string       `json:"user,omitempty"`
+	ProcessPath          string       `json:"process_path,omitempty"`
+	PackageName          string       `json:"package_name,omitempty"`
+	DestinationAddresses []netip.Addr `json:"destination_addresses,omitempty"`
+}
+
+func (c *benchConnection) metadata() (adapter.InboundContext, error) {
+	metadata := adapter.InboundContext{
+		Inbound:              c.Inbound,
+		InboundType:          c.InboundType,
+		Network:              c.Network,
+		Source:               M.ParseSocksaddr(c.Source),
+		Destination:          M.ParseSocksaddr(c.Destination),
+		Domain:               c.Domain,
+		Protocol:             c.Protocol,
+		Client:               c.Client,
+		User:                 c.User,
+		DestinationAddresses: c.DestinationAddresses,
+	}
+	if metadata.Network == "" {
+		metadata.Network = N.NetworkTCP
+	}
+	if !metadata.Destination.IsValid() {
+		return adapter.InboundContext{}, E.New("invalid destination: ", c.Destination)
+	}
+	if c.ProcessPath != "" || c.PackageName != "" {
+		metadata.ProcessInfo = &process.Info{
+			ProcessPath: c.ProcessPath,
+			PackageName: c.PackageName,
+			UserId:      -1,
+		}
+	}
+	if metadata.Destination.IsIPv4() {
+		metadata.IPVersion = 4
+	} else if metadata.Destination.IsIPv6() {
+		metadata.IPVersion = 6
+	}
+	return metadata, nil
+}
+
+type benchRuleStats struct {
+	evaluated int
+	matched   int
+	selected  int
+	cost      time.Duration
+}
+
+func benchRoute() error {
+	var (
+		connections []adapter.InboundContext
+		err         error
+	)
+	if commandBenchRouteFlagInput != "" {
+		connections, err = readBenchConnections(commandBenchRouteFlagInput)
+		if err != nil {
+			return err
+		}
+	} else {
+		connections = generateBenchConnections(commandBenchRouteFlagSynthetic)
+	}
+	if len(connections) == 0 {
+		return E.New("no connections")
+	}
+	if commandBenchRouteFlagRounds <= 0 {
+		return E.New("invalid rounds: ", commandBenchRouteFlagRounds)
+	}
+	heapBefore := readHeapAlloc()
+	options, err := readConfigAndMerge()
+	if err != nil {
+		return err
+	}
+	instance, err := box.New(box.Options{Context: globalCtx, Options: options})
+	if err != nil {
+		return E.Cause(err, "create service")
+	}
+	defer instance.Close()
+	err = instance.PreStart()
+	if err != nil {
+		return E.Cause(err, "start service")
+	}
+	err = instance.PostStart()
+	if err != nil {
+		return E.Cause(err, "start service")
+	}
+	heapLoaded := readHeapAlloc()
+	rules := instance.Router().Rules()
+	ruleStats := make([]benchRuleStats, len(rules))
+	var finalCount int
+
+	// The first pass matches without timing each rule, and is used for the total rate.
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	mallocsBefore := memStats.Mallocs
+	start := time.Now()
+	for round := 0; round < commandBenchRouteFlagRounds; round++ {
+		for i := range connections {
+			benchMatchRule(rules, &connections[i], nil)
+		}
+	}
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&memStats)
+	matchCount := len(connections) * commandBenchRouteFlagRounds
+	allocsPerMatch := float64(memStats.Mallocs-mallocsBefore) / float64(matchCount)
+
+	for i := range connections {
+		selected := benchMatchRule(rules, &connections[i], ruleStats)
+		if selected == -1 {
+			finalCount++
+		} else {
+			ruleStats[selected].selected++
+		}
+	}
+
+	fmt.Println("connections:", len(connections), "rules:", len(rules), "rounds:", commandBenchRouteFlagRounds)
+	fmt.Printf("matched %d connections in %s, %.0f matches/s, %s per match, %.1f allocs per match\n",
+		matchCount, elapsed.Round(time.Microsecond), float64(matchCount)/elapsed.Seconds(), (elapsed / time.Duration(matchCount)).Round(time.Nanosecond), allocsPerMatch)
+	fmt.Println("heap after loading:", formatBenchBytes(heapLoaded-min(heapBefore, heapLoaded)))
+	var totalCost time.Duration
+	for _, stats := range ruleStats {
+		totalCost += stats.cost
+	}
+	fmt.Println()
+	fmt.Printf("%-6s %10s %10s %10s %10s %7s  %s\n", "RULE", "EVALUATED", "MATCHED", "SELECTED", "AVG", "COST", "DESCRIPTION")
+	for i, rule := range rules {
+		stats := ruleStats[i]
+		var average time.Duration
+		if stats.evaluated > 0 {
+			average = stats.cost / time.Duration(stats.evaluated)
+		}
+		var share float64
+		if totalCost > 0 {
+			share = float64(stats.cost) * 100 / float64(totalCost)
+		}
+		description := rule.Action().String()
+		if ruleDescription := rule.String(); ruleDescription != "" {
+			description = ruleDescription + " => " + description
+		}
+		if len(description) > 80 {
+			description = description[:77] + "..."
+		}
+		fmt.Printf("%-6s %10d %10d %10d %10s %6.1f%%  %s\n", "["+strconv.Itoa(i)+"]", stats.evaluated, stats.matched, stats.selected, average, share, description)
+	}
+	fmt.Printf("%-6s %10s %10s %10d\n", "final", "", "", finalCount)
+	return nil
+}
+
+// benchMatchRule follows Router.matchRule, except that non-final actions are not executed.
+// It returns the index of the selected rule, or -1 for final.
+func benchMatchRule(rules []adapter.Rule, connection *adapter.InboundContext, ruleStats []benchRuleStats) int {
+	metadata := *connection
+	for i, rule := range rules {
+		metadata.ResetRuleCache()
+		var matched bool
+		if ruleStats != nil {
+			start := time.Now()
+			matched = rule.Match(&metadata)
+			ruleStats[i].cost += time.Since(start)
+			ruleStats[i].evaluated++
+			if matched {
+				ruleStats[i].matched++
+			}
+		} else {
+			matched = rule.Match(&metadata)
+		}
+		if !matched {
+			continue
+		}
+		switch rule.Action().Type() {
+		case C.RuleActionTypeRoute, C.RuleActionTypeReject, C.RuleActionTypeHijackDNS:
+			return i
+		}
+	}
+	return -1
+}
+
+func readBenchConnections(path string) ([]adapter.InboundContext, error) {
+	var reader io.Reader
+	if path == "-" {
+		reader = os.Stdin
+	} else {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		reader = file
+	}
+	var connections []adapter.InboundContext
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var connection benchConnection
+		err := json.Unmarshal(line, &connection)
+		if err != nil {
+			return nil, E.Cause(err, "parse line ", lineNumber)
+		}
+		metadata, err := connection.metadata()
+		if err != nil {
+			return nil, E.Cause(err, "parse line ", lineNumber)
+		}
+		connections = append(connections, metadata)
+	}
+	return connections, scanner.Err()
+}
+
+// generateBenchConnections generates connections to random domains and addresses,
+// which rarely match any rule, so that the cost of full rule evaluation is shown.
+func generateBenchConnections(count int) []adapter.InboundContext {
+	random := rand.New(rand.NewSource(1))
+	connections := make([]adapter.InboundContext, 0, count)
+	for i := 0; i < count; i++ {
+		var address [4]byte
+		random.Read(address[:])
+		metadata := adapter.InboundContext{
+			Network: N.NetworkTCP,
+			Source:  M.SocksaddrFrom(netip.AddrFrom4([4]byte{192, 168, 1, byte(random.Intn(254) + 1)}), uint16(random.Intn(30000)+30000)),
+		}
+		if i%2 == 0 {
+			metadata.Destination = M.Socksaddr{Fqdn: "host" + strconv.Itoa(random.Intn(1000)) + ".domain" + strconv.Itoa(random.Intn(100000)) + ".com", Port: 443}
+			metadata.DestinationAddresses = []netip.Addr{netip.AddrFrom4(address)}
+		} else {
+			metadata.Destination = M.SocksaddrFrom(netip.AddrFrom4(address), 443)
+			metadata.IPVersion = 4
+		}
+		if i%4 == 3 {
+			metadata.Network = N.NetworkUDP
+		}
+		connections = append(connections, metadata)
+	}
+	return connections
+}
+
+func readHeapAlloc() uint64 {
+	runtime.GC()
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	return memStats.HeapAlloc
+}
+
+func formatBenchBytes(size uint64) string {
+	return fmt.Sprintf("%.1f MiB", float64(size)/1024/1024)
+}
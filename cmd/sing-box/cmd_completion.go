@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+
+	"github.com/spf13/cobra"
+)
+
+func completeInbounds(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completeTags(func(options *option.Options) []string {
+		var tags []string
+		for _, inbound := range options.Inbounds {
+			tags = append(tags, inbound.Tag+"\t"+inbound.Type)
+		}
+		return tags
+	})(cmd, args, toComplete)
+}
+
+func completeDNSServers(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completeTags(func(options *option.Options) []string {
+		if options.DNS == nil {
+			return nil
+		}
+		var tags []string
+		for _, server := range options.DNS.Servers {
+			tags = append(tags, server.Tag+"\t"+server.Type)
+		}
+		return tags
+	})(cmd, args, toComplete)
+}
+
+func completeOutboundsAndInbounds(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completeTags(func(options *option.Options) []string {
+		var tags []string
+		for _, outbound := range options.Outbounds {
+			tags = append(tags, outbound.Tag+"\t"+outbound.Type)
+		}
+		for _, inbound := range options.Inbounds {
+			tags = append(tags, inbound.Tag+"\t"+inbound.Type+" inbound")
+		}
+		return tags
+	})(cmd, args, toComplete)
+}
+
+// completeOutbounds loads outbounds from the Clash API if the configured controller is
+// reachable, so that groups are shown with their current selection, or from the configuration.
+func completeOutbounds(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	options, err := readCompletionConfig(cmd)
+	if err != nil {
+		cobra.CompDebugln(err.Error(), true)
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	tags, err := readClashAPIOutbounds(options)
+	if err != nil {
+		if options.Experimental != nil && options.Experimental.ClashAPI != nil {
+			cobra.CompDebugln(err.Error(), true)
+		}
+		for _, outbound := range options.Outbounds {
+			tags = append(tags, outbound.Tag+"\t"+outbound.Type)
+		}
+		for _, endpoint := range options.Endpoints {
+			tags = append(tags, endpoint.Tag+"\t"+endpoint.Type)
+		}
+	}
+	return filterCompletions(tags, args, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+func completeTags(tagsFunc func(options *option.Options) []string) func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		options, err := readCompletionConfig(cmd)
+		if err != nil {
+			cobra.CompDebugln(err.Error(), true)
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return filterCompletions(tagsFunc(options), args, toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// readCompletionConfig loads the configuration, pre-run hooks are not called for completions
+// and array flags may be parsed twice.
+func readCompletionConfig(cmd *cobra.Command) (*option.Options, error) {
+	configPaths = common.Uniq(configPaths)
+	configDirectories = common.Uniq(configDirectories)
+	preRun(cmd, nil)
+	options, err := readConfigAndMerge()
+	if err != nil {
+		return nil, err
+	}
+	return &options, nil
+}
+
+// filterCompletions removes empty tags, tags already given as arguments and tags not matching the prefix.
+func filterCompletions(tags []string, args []string, toComplete string) []string {
+	var completions []string
+	for _, tag := range tags {
+		name, _, _ := strings.Cut(tag, "\t")
+		if name == "" || !strings.HasPrefix(name, toComplete) {
+			continue
+		}
+		var given bool
+		for _, arg := range args {
+			if arg == name {
+				given = true
+				break
+			}
+		}
+		if !given {
+			completions = append(completions, tag)
+		}
+	}
+	return completions
+}
+
+type clashAPIProxies struct {
+	Proxies map[string]struct {
+		Type string `json:"type"`
+		Now  string `json:"now"`
+	} `json:"proxies"`
+}
+
+func readClashAPIOutbounds(options *option.Options) ([]string, error) {
+	if options.Experimental == nil || options.Experimental.ClashAPI == nil || options.Experimental.ClashAPI.ExternalController == "" {
+		return nil, E.New("Clash API disabled")
+	}
+	clashAPI := options.Experimental.ClashAPI
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	address := clashAPI.ExternalController
+	if strings.HasPrefix(address, ":") {
+		address = "127.0.0.1" + address
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+address+"/proxies", nil)
+	if err != nil {
+		return nil, err
+	}
+	if clashAPI.Secret != "" {
+		request.Header.Set("Authorization", "Bearer "+clashAPI.Secret)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, E.Cause(err, "query Clash API")
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, E.New("query Clash API: ", response.Status)
+	}
+	var proxies clashAPIProxies
+	err = json.NewDecoder(response.Body).Decode(&proxies)
+	if err != nil {
+		return nil, E.Cause(err, "decode Clash API response")
+	}
+	var tags []string
+	for tag, proxy := range proxies.Proxies {
+		// GLOBAL is a virtual group of the Clash API.
+		if tag == "GLOBAL" {
+			continue
+		}
+		description := proxy.Type
+		if proxy.Now != "" {
+			description += ", now " + proxy.Now
+		}
+		tags = append(tags, tag+"\t"+description)
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/common/rw"
+
+	"github.com/spf13/cobra"
+)
+
+var commandConvertFlagOutput string
+
+var commandConvert = &cobra.Command{
+	Use:   "convert",
+	Short: "Convert configurations of other clients",
+}
+
+func init() {
+	commandConvert.PersistentFlags().StringVarP(&commandConvertFlagOutput, "output", "o", "", "Output file, stdout if empty")
+	mainCommand.AddCommand(commandConvert)
+}
+
+func readConvertSource(sourcePath string) ([]byte, error) {
+	if sourcePath == "stdin" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(sourcePath)
+}
+
+func writeConvertedOptions(options *option.Options) error {
+	buffer := new(bytes.Buffer)
+	encoder := json.NewEncoderContext(globalCtx, buffer)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(options)
+	if err != nil {
+		return E.Cause(err, "encode config")
+	}
+	if commandConvertFlagOutput == "" {
+		_, err = os.Stdout.Write(buffer.Bytes())
+		return err
+	}
+	err = rw.MkdirParent(commandConvertFlagOutput)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(commandConvertFlagOutput, buffer.Bytes(), 0o644)
+}
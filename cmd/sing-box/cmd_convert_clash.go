@@ -0,0 +1,37 @@
+package main
+
+import (
+	"github.com/sagernet/sing-box/common/convertor/clash"
+	"github.com/sagernet/sing-box/log"
+
+	"github.com/spf13/cobra"
+)
+
+var commandConvertClash = &cobra.Command{
+	Use:   "clash <config-path>",
+	Short: "Convert Clash configuration",
+	Long:  "Convert a Clash or Clash.Meta configuration, including proxies, proxy groups, rules, rule providers and DNS.\n\nUnsupported features are reported as warnings.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := convertClash(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandConvert.AddCommand(commandConvertClash)
+}
+
+func convertClash(sourcePath string) error {
+	content, err := readConvertSource(sourcePath)
+	if err != nil {
+		return err
+	}
+	options, err := clash.Convert(content, log.StdLogger())
+	if err != nil {
+		return err
+	}
+	return writeConvertedOptions(options)
+}
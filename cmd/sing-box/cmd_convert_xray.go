@@ -0,0 +1,37 @@
+package main
+
+import (
+	"github.com/sagernet/sing-box/common/convertor/xray"
+	"github.com/sagernet/sing-box/log"
+
+	"github.com/spf13/cobra"
+)
+
+var commandConvertXray = &cobra.Command{
+	Use:   "xray <config-path>",
+	Short: "Convert Xray configuration",
+	Long:  "Convert an Xray or V2Ray JSON configuration, including inbounds, outbounds, stream settings, routing and DNS.\n\nUnsupported features are reported as warnings.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := convertXray(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandConvert.AddCommand(commandConvertXray)
+}
+
+func convertXray(sourcePath string) error {
+	content, err := readConvertSource(sourcePath)
+	if err != nil {
+		return err
+	}
+	options, err := xray.Convert(content, log.StdLogger())
+	if err != nil {
+		return err
+	}
+	return writeConvertedOptions(options)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+
+	"github.com/spf13/cobra"
+)
+
+var commandDrainFlagTimeout time.Duration
+
+var commandDrain = &cobra.Command{
+	Use:   "drain [inbound tag...]",
+	Short: "Drain the running instance and wait for it to exit",
+	Long: `Drain the running instance and wait for it to exit.
+
+New connections of the inbounds, or of all inbounds if none is given, are
+rejected, and the instance exits after existing connections are closed or
+the timeout elapsed. The Clash API of the configuration is used.
+
+Sending SIGUSR2 to the instance starts a drain with the drain options of the
+route section.`,
+	ValidArgsFunction: completeInbounds,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := drain(args)
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandDrain.Flags().DurationVarP(&commandDrainFlagTimeout, "timeout", "t", 0, "wait timeout, the drain options of the route section are used by default")
+	mainCommand.AddCommand(commandDrain)
+}
+
+type drainStatus struct {
+	Draining bool `json:"draining"`
+	Finished bool `json:"finished"`
+	Active   int  `json:"active"`
+	Total    int  `json:"total"`
+}
+
+func drain(inbounds []string) error {
+	options, err := readConfigAndMerge()
+	if err != nil {
+		return err
+	}
+	var timeout string
+	if commandDrainFlagTimeout > 0 {
+		timeout = commandDrainFlagTimeout.String()
+	}
+	content, err := json.Marshal(map[string]any{
+		"inbounds": inbounds,
+		"timeout":  timeout,
+	})
+	if err != nil {
+		return err
+	}
+	var status drainStatus
+	err = requestClashAPI(&options, http.MethodPost, "/drain", content, &status)
+	if err != nil {
+		return E.Cause(err, "start drain")
+	}
+	for {
+		if status.Finished {
+			if status.Active > 0 {
+				fmt.Println("drain timed out,", status.Active, "connections closed")
+			} else {
+				fmt.Println("drain finished")
+			}
+			return nil
+		}
+		fmt.Println("draining,", status.Active, "of", status.Total, "connections remaining")
+		time.Sleep(time.Second)
+		err = requestClashAPI(&options, http.MethodGet, "/drain", nil, &status)
+		if err != nil {
+			// the instance exited before the status was polled
+			fmt.Println("drain finished")
+			return nil
+		}
+	}
+}
+
+func requestClashAPI(options *option.Options, method string, path string, content []byte, response any) error {
+	if options.Experimental == nil || options.Experimental.ClashAPI == nil || options.Experimental.ClashAPI.ExternalController == "" {
+		return E.New("Clash API disabled")
+	}
+	clashAPI := options.Experimental.ClashAPI
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	address := clashAPI.ExternalController
+	if strings.HasPrefix(address, ":") {
+		address = "127.0.0.1" + address
+	}
+	request, err := http.NewRequestWithContext(ctx, method, "http://"+address+path, bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	if content != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if clashAPI.Secret != "" {
+		request.Header.Set("Authorization", "Bearer "+clashAPI.Secret)
+	}
+	httpResponse, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return E.Cause(err, "query Clash API")
+	}
+	defer httpResponse.Body.Close()
+	if httpResponse.StatusCode != http.StatusOK {
+		var apiError struct {
+			Message string `json:"message"`
+		}
+		if json.NewDecoder(httpResponse.Body).Decode(&apiError) == nil && apiError.Message != "" {
+			return E.New("query Clash API: ", apiError.Message)
+		}
+		return E.New("query Clash API: ", httpResponse.Status)
+	}
+	return json.NewDecoder(httpResponse.Body).Decode(response)
+}
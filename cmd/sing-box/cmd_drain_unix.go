@@ -0,0 +1,10 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+var drainSignals = []os.Signal{syscall.SIGUSR2}
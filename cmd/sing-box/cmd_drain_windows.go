@@ -0,0 +1,5 @@
+package main
+
+import "os"
+
+var drainSignals []os.Signal
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"os"
+	"strconv"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/common/json/badjson"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/spf13/cobra"
+)
+
+var (
+	commandGenerateUsersFlagCount    int
+	commandGenerateUsersFlagProtocol string
+	commandGenerateUsersFlagMethod   string
+	commandGenerateUsersFlagFlow     string
+	commandGenerateUsersFlagPrefix   string
+	commandGenerateUsersFlagStart    int
+	commandGenerateUsersFlagFormat   string
+	commandGenerateUsersFlagShortID  bool
+)
+
+var commandGenerateUsers = &cobra.Command{
+	Use:   "users",
+	Short: "Generate users for multi-user inbounds",
+	Long: `Generate users for multi-user inbounds.
+
+The JSON output is an inbound fragment whose users can be pasted into the
+inbound or merged with "sing-box users merge". With --short-id, a REALITY
+short ID is generated for each user and listed in "short_id".`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := generateUsers()
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandGenerateUsers.Flags().IntVarP(&commandGenerateUsersFlagCount, "count", "n", 1, "number of users")
+	commandGenerateUsers.Flags().StringVarP(&commandGenerateUsersFlagProtocol, "protocol", "p", "", "inbound type")
+	commandGenerateUsers.Flags().StringVarP(&commandGenerateUsersFlagMethod, "method", "m", "2022-blake3-aes-128-gcm", "shadowsocks method")
+	commandGenerateUsers.Flags().StringVar(&commandGenerateUsersFlagFlow, "flow", "", "VLESS flow")
+	commandGenerateUsers.Flags().StringVar(&commandGenerateUsersFlagPrefix, "prefix", "user", "user name prefix")
+	commandGenerateUsers.Flags().IntVar(&commandGenerateUsersFlagStart, "start", 1, "first user number")
+	commandGenerateUsers.Flags().StringVarP(&commandGenerateUsersFlagFormat, "format", "f", "json", "output format: json, csv")
+	commandGenerateUsers.Flags().BoolVar(&commandGenerateUsersFlagShortID, "short-id", false, "generate a REALITY short ID for each user")
+	commandGenerateUsers.RegisterFlagCompletionFunc("protocol", cobra.FixedCompletions([]string{
+		C.TypeVLESS, C.TypeVMess, C.TypeTrojan, C.TypeShadowsocks, C.TypeTUIC, C.TypeHysteria, C.TypeHysteria2,
+		C.TypeAnyTLS, C.TypeShadowTLS, C.TypeNaive, C.TypeHTTP, C.TypeSOCKS, C.TypeMixed,
+	}, cobra.ShellCompDirectiveNoFileComp))
+	commandGenerateUsers.RegisterFlagCompletionFunc("format", cobra.FixedCompletions([]string{"json", "csv"}, cobra.ShellCompDirectiveNoFileComp))
+	commandGenerate.AddCommand(commandGenerateUsers)
+}
+
+// userField generates the value of a user field, index is the user number.
+type userField struct {
+	name     string
+	generate func(index int) (string, error)
+}
+
+func generateUsers() error {
+	if commandGenerateUsersFlagCount <= 0 {
+		return E.New("invalid count: ", commandGenerateUsersFlagCount)
+	}
+	fields, err := userFields(commandGenerateUsersFlagProtocol)
+	if err != nil {
+		return err
+	}
+	if commandGenerateUsersFlagShortID {
+		fields = append(fields, userField{"short_id", func(int) (string, error) {
+			return randomHex(8)
+		}})
+	}
+	rows := make([][]string, 0, commandGenerateUsersFlagCount)
+	for i := 0; i < commandGenerateUsersFlagCount; i++ {
+		row := make([]string, 0, len(fields))
+		for _, field := range fields {
+			value, err := field.generate(commandGenerateUsersFlagStart + i)
+			if err != nil {
+				return err
+			}
+			row = append(row, value)
+		}
+		rows = append(rows, row)
+	}
+	header := make([]string, 0, len(fields))
+	for _, field := range fields {
+		header = append(header, field.name)
+	}
+	switch commandGenerateUsersFlagFormat {
+	case "json":
+		return writeUsersJSON(header, rows)
+	case "csv":
+		writer := csv.NewWriter(os.Stdout)
+		_ = writer.Write(header)
+		_ = writer.WriteAll(rows)
+		return writer.Error()
+	default:
+		return E.New("unknown format: ", commandGenerateUsersFlagFormat)
+	}
+}
+
+func userFields(protocol string) ([]userField, error) {
+	name := userField{"name", func(index int) (string, error) {
+		return commandGenerateUsersFlagPrefix + strconv.Itoa(index), nil
+	}}
+	uuidField := userField{"uuid", func(int) (string, error) {
+		userUUID, err := uuid.NewV4()
+		if err != nil {
+			return "", err
+		}
+		return userUUID.String(), nil
+	}}
+	password := userField{"password", func(int) (string, error) {
+		return randomBase64(16)
+	}}
+	switch protocol {
+	case C.TypeVLESS:
+		fields := []userField{name, uuidField}
+		if commandGenerateUsersFlagFlow != "" {
+			fields = append(fields, userField{"flow", func(int) (string, error) {
+				return commandGenerateUsersFlagFlow, nil
+			}})
+		}
+		return fields, nil
+	case C.TypeVMess:
+		return []userField{name, uuidField}, nil
+	case C.TypeTUIC:
+		return []userField{name, uuidField, password}, nil
+	case C.TypeTrojan, C.TypeHysteria2, C.TypeAnyTLS, C.TypeShadowTLS:
+		return []userField{name, password}, nil
+	case C.TypeHysteria:
+		return []userField{name, {"auth_str", password.generate}}, nil
+	case C.TypeShadowsocks:
+		keyLength, err := shadowsocksKeyLength(commandGenerateUsersFlagMethod)
+		if err != nil {
+			return nil, err
+		}
+		return []userField{name, {"password", func(int) (string, error) {
+			return randomBase64(keyLength)
+		}}}, nil
+	case C.TypeHTTP, C.TypeSOCKS, C.TypeMixed, C.TypeNaive:
+		return []userField{{"username", name.generate}, password}, nil
+	case "":
+		return nil, E.New("missing protocol")
+	default:
+		return nil, E.New("protocol does not support users: ", protocol)
+	}
+}
+
+// shadowsocksKeyLength returns the key length required by Shadowsocks 2022 methods,
+// legacy methods accept passwords of any length.
+func shadowsocksKeyLength(method string) (int, error) {
+	switch method {
+	case "2022-blake3-aes-128-gcm":
+		return 16, nil
+	case "2022-blake3-aes-256-gcm", "2022-blake3-chacha20-poly1305":
+		return 32, nil
+	case "none":
+		return 0, E.New("method does not support users: ", method)
+	default:
+		if strings.HasPrefix(method, "2022-") {
+			return 0, E.New("unknown method: ", method)
+		}
+		return 16, nil
+	}
+}
+
+func writeUsersJSON(header []string, rows [][]string) error {
+	var (
+		users    []any
+		shortIDs []string
+	)
+	for _, row := range rows {
+		user := new(badjson.JSONObject)
+		for i, value := range row {
+			if header[i] == "short_id" {
+				shortIDs = append(shortIDs, value)
+				continue
+			}
+			user.Put(header[i], value)
+		}
+		users = append(users, user)
+	}
+	output := new(badjson.JSONObject)
+	output.Put("users", users)
+	if len(shortIDs) > 0 {
+		output.Put("short_id", shortIDs)
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(output)
+}
+
+func randomBase64(length int) (string, error) {
+	randomBytes := make([]byte, length)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(randomBytes), nil
+}
+
+func randomHex(length int) (string, error) {
+	randomBytes := make([]byte, length)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(randomBytes), nil
+}
@@ -129,6 +129,13 @@ func mergeTLSOutboundOptions(options *option.OutboundTLSOptions) *option.Outboun
 }
 
 func mergeSSHOutboundOptions(options *option.SSHOutboundOptions) {
+	mergeSSHHostOptions(&options.SSHHostOptions)
+	for i := range options.Jump {
+		mergeSSHHostOptions(&options.Jump[i])
+	}
+}
+
+func mergeSSHHostOptions(options *option.SSHHostOptions) {
 	if options.PrivateKeyPath != "" {
 		if content, err := os.ReadFile(os.ExpandEnv(options.PrivateKeyPath)); err == nil {
 			options.PrivateKey = trimStringArray(strings.Split(string(content), "\n"))
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/sagernet/sing-box/common/srs"
+	"github.com/sagernet/sing-box/log"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagRuleSetBuildDomain        []string
+	flagRuleSetBuildDomainSuffix  []string
+	flagRuleSetBuildDomainKeyword []string
+	flagRuleSetBuildDomainRegex   []string
+	flagRuleSetBuildIPCIDR        []string
+	flagRuleSetBuildSourceIPCIDR  []string
+	flagRuleSetBuildProcessName   []string
+	flagRuleSetBuildProcessPath   []string
+	flagRuleSetBuildPackageName   []string
+)
+
+var commandRuleSetBuild = &cobra.Command{
+	Use:   "build <output-path>",
+	Short: "Build rule-set from items",
+	Long:  "Build rule-set from items, matching if any item matches. The output is in source format if the path ends with .json, otherwise in binary format.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := buildRuleSet(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandRuleSetBuild.Flags().StringArrayVar(&flagRuleSetBuildDomain, "domain", nil, "add domain")
+	commandRuleSetBuild.Flags().StringArrayVar(&flagRuleSetBuildDomainSuffix, "domain-suffix", nil, "add domain suffix")
+	commandRuleSetBuild.Flags().StringArrayVar(&flagRuleSetBuildDomainKeyword, "domain-keyword", nil, "add domain keyword")
+	commandRuleSetBuild.Flags().StringArrayVar(&flagRuleSetBuildDomainRegex, "domain-regex", nil, "add domain regex")
+	commandRuleSetBuild.Flags().StringArrayVar(&flagRuleSetBuildIPCIDR, "ip-cidr", nil, "add IP CIDR")
+	commandRuleSetBuild.Flags().StringArrayVar(&flagRuleSetBuildSourceIPCIDR, "source-ip-cidr", nil, "add source IP CIDR")
+	commandRuleSetBuild.Flags().StringArrayVar(&flagRuleSetBuildProcessName, "process-name", nil, "add process name")
+	commandRuleSetBuild.Flags().StringArrayVar(&flagRuleSetBuildProcessPath, "process-path", nil, "add process path")
+	commandRuleSetBuild.Flags().StringArrayVar(&flagRuleSetBuildPackageName, "package-name", nil, "add package name")
+	commandRuleSet.AddCommand(commandRuleSetBuild)
+}
+
+func buildRuleSet(outputPath string) error {
+	builder := srs.NewBuilder().
+		AddDomain(flagRuleSetBuildDomain...).
+		AddDomainSuffix(flagRuleSetBuildDomainSuffix...).
+		AddDomainKeyword(flagRuleSetBuildDomainKeyword...).
+		AddDomainRegex(flagRuleSetBuildDomainRegex...).
+		AddIPCIDR(flagRuleSetBuildIPCIDR...).
+		AddSourceIPCIDR(flagRuleSetBuildSourceIPCIDR...).
+		AddProcessName(flagRuleSetBuildProcessName...).
+		AddProcessPath(flagRuleSetBuildProcessPath...).
+		AddPackageName(flagRuleSetBuildPackageName...)
+	outputFile, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(outputPath, ".json") {
+		err = builder.WriteSource(outputFile)
+	} else {
+		err = builder.Write(outputFile)
+	}
+	if err != nil {
+		outputFile.Close()
+		os.Remove(outputPath)
+		return err
+	}
+	return outputFile.Close()
+}
@@ -3,9 +3,13 @@ package main
 import (
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/sagernet/sing-box/common/convertor/adguard"
+	"github.com/sagernet/sing-box/common/convertor/dlc"
+	"github.com/sagernet/sing-box/common/convertor/hosts"
+	"github.com/sagernet/sing-box/common/convertor/mmdb"
 	"github.com/sagernet/sing-box/common/srs"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
@@ -17,13 +21,20 @@ import (
 
 var (
 	flagRuleSetConvertType   string
+	flagRuleSetConvertCode   []string
 	flagRuleSetConvertOutput string
 )
 
 var commandRuleSetConvert = &cobra.Command{
 	Use:   "convert [source-path]",
-	Short: "Convert adguard DNS filter to rule-set",
-	Args:  cobra.ExactArgs(1),
+	Short: "Convert adguard DNS filter, hosts file, geosite.dat or mmdb to rule-set",
+	Long: `Convert adguard DNS filter, hosts file, geosite.dat or mmdb to rule-set.
+
+geosite and mmdb sources require --code. A geosite code may be followed by
+@attribute or @!attribute to filter domains, e.g. google@cn. Each code is
+written to geosite-<code>.srs or geoip-<code>.srs next to the source unless
+--output is set.`,
+	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		err := convertRuleSet(args[0])
 		if err != nil {
@@ -34,7 +45,8 @@ var commandRuleSetConvert = &cobra.Command{
 
 func init() {
 	commandRuleSet.AddCommand(commandRuleSetConvert)
-	commandRuleSetConvert.Flags().StringVarP(&flagRuleSetConvertType, "type", "t", "", "Source type, available: adguard")
+	commandRuleSetConvert.Flags().StringVarP(&flagRuleSetConvertType, "type", "t", "", "Source type, available: adguard, hosts, geosite, mmdb")
+	commandRuleSetConvert.Flags().StringSliceVar(&flagRuleSetConvertCode, "code", nil, "Codes to extract from geosite or mmdb sources")
 	commandRuleSetConvert.Flags().StringVarP(&flagRuleSetConvertOutput, "output", "o", flagRuleSetCompileDefaultOutput, "Output file")
 }
 
@@ -55,6 +67,10 @@ func convertRuleSet(sourcePath string) error {
 	switch flagRuleSetConvertType {
 	case "adguard":
 		rules, err = adguard.ToOptions(reader, log.StdLogger())
+	case "hosts":
+		rules, err = hosts.ToOptions(reader, log.StdLogger())
+	case "geosite", "mmdb":
+		return convertRuleSetCodes(sourcePath, reader)
 	case "":
 		return E.New("source type is required")
 	default:
@@ -73,11 +89,58 @@ func convertRuleSet(sourcePath string) error {
 	} else {
 		outputPath = flagRuleSetConvertOutput
 	}
+	return writeConvertedRuleSet(outputPath, rules)
+}
+
+func convertRuleSetCodes(sourcePath string, reader io.Reader) error {
+	if len(flagRuleSetConvertCode) == 0 {
+		return E.New("code is required for source type ", flagRuleSetConvertType)
+	}
+	if flagRuleSetConvertOutput != flagRuleSetCompileDefaultOutput && len(flagRuleSetConvertCode) > 1 {
+		return E.New("output can not be set with multiple codes")
+	}
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	var sites map[string][]dlc.Domain
+	if flagRuleSetConvertType == "geosite" {
+		sites, err = dlc.Read(content)
+		if err != nil {
+			return err
+		}
+	}
+	for _, code := range flagRuleSetConvertCode {
+		var rules []option.HeadlessRule
+		if flagRuleSetConvertType == "geosite" {
+			rules, err = dlc.ToOptions(sites, code)
+		} else {
+			rules, err = mmdb.ToOptions(content, code)
+		}
+		if err != nil {
+			return err
+		}
+		outputPath := flagRuleSetConvertOutput
+		if outputPath == flagRuleSetCompileDefaultOutput {
+			prefix := "geosite-"
+			if flagRuleSetConvertType == "mmdb" {
+				prefix = "geoip-"
+			}
+			outputPath = filepath.Join(filepath.Dir(sourcePath), prefix+strings.ToLower(code)+".srs")
+		}
+		err = writeConvertedRuleSet(outputPath, rules)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeConvertedRuleSet(outputPath string, rules []option.HeadlessRule) error {
 	outputFile, err := os.Create(outputPath)
 	if err != nil {
 		return err
 	}
-	defer outputFile.Close()
 	err = srs.Write(outputFile, option.PlainRuleSet{Rules: rules}, C.RuleSetVersion2)
 	if err != nil {
 		outputFile.Close()
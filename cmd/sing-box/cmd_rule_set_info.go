@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/spf13/cobra"
+)
+
+var flagRuleSetInfoFormat string
+
+var commandRuleSetInfo = &cobra.Command{
+	Use:   "info <rule-set path>",
+	Short: "Print entry counts of the rule-set",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := ruleSetInfo(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandRuleSetInfo.Flags().StringVarP(&flagRuleSetInfoFormat, "format", "f", "", "rule-set format, detected by extension if empty")
+	commandRuleSet.AddCommand(commandRuleSetInfo)
+}
+
+func ruleSetInfo(sourcePath string) error {
+	ruleSet, err := loadRuleSet(sourcePath, flagRuleSetInfoFormat)
+	if err != nil {
+		return err
+	}
+	plainRuleSet, err := ruleSet.Upgrade()
+	if err != nil {
+		return err
+	}
+	var (
+		logicalRules int
+		fieldNames   []string
+		fieldCounts  = make(map[string]int)
+	)
+	var countRule func(ruleOptions option.HeadlessRule)
+	countRule = func(ruleOptions option.HeadlessRule) {
+		if ruleOptions.Type == C.RuleTypeLogical {
+			logicalRules++
+			for _, subRule := range ruleOptions.LogicalOptions.Rules {
+				countRule(subRule)
+			}
+			return
+		}
+		countRuleFields(ruleOptions.DefaultOptions, func(name string, count int) {
+			if _, loaded := fieldCounts[name]; !loaded {
+				fieldNames = append(fieldNames, name)
+			}
+			fieldCounts[name] += count
+		})
+	}
+	for _, ruleOptions := range plainRuleSet.Rules {
+		countRule(ruleOptions)
+	}
+	fmt.Println("version:", ruleSet.Version)
+	fmt.Println("rules:", len(plainRuleSet.Rules))
+	if logicalRules > 0 {
+		fmt.Println("logical rules:", logicalRules)
+	}
+	for _, name := range fieldNames {
+		fmt.Printf("%s: %d\n", name, fieldCounts[name])
+	}
+	return nil
+}
+
+// countRuleFields reports the number of entries of each non-empty field by its JSON name.
+func countRuleFields(options option.DefaultHeadlessRule, f func(name string, count int)) {
+	value := reflect.ValueOf(options)
+	for i := 0; i < value.NumField(); i++ {
+		name, _, _ := strings.Cut(value.Type().Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		field := value.Field(i)
+		var count int
+		switch field.Kind() {
+		case reflect.Slice:
+			count = field.Len()
+		case reflect.Bool:
+			if field.Bool() {
+				count = 1
+			}
+		case reflect.Pointer:
+			if !field.IsNil() {
+				count = 1
+			}
+		}
+		if count > 0 {
+			f(name, count)
+		}
+	}
+}
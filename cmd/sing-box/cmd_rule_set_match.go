@@ -1,11 +1,16 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"fmt"
 	"io"
+	"net/netip"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/srs"
@@ -14,21 +19,32 @@ import (
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing-box/route/rule"
 	E "github.com/sagernet/sing/common/exceptions"
-	F "github.com/sagernet/sing/common/format"
 	"github.com/sagernet/sing/common/json"
 	M "github.com/sagernet/sing/common/metadata"
 
 	"github.com/spf13/cobra"
 )
 
-var flagRuleSetMatchFormat string
+var (
+	flagRuleSetMatchFormat string
+	flagRuleSetMatchDomain string
+	flagRuleSetMatchIP     string
+)
 
 var commandRuleSetMatch = &cobra.Command{
-	Use:   "match <rule-set path> <IP address/domain>",
+	Use:   "match <rule-set path> [IP address/domain]",
 	Short: "Check if an IP address or a domain matches the rule-set",
-	Args:  cobra.ExactArgs(2),
+	Long: `Check if an IP address or a domain matches the rule-set.
+
+--domain and --ip may be combined to match a resolved connection. Without a
+query, entries are read from stdin line by line.`,
+	Args: cobra.RangeArgs(1, 2),
 	Run: func(cmd *cobra.Command, args []string) {
-		err := ruleSetMatch(args[0], args[1])
+		var query string
+		if len(args) > 1 {
+			query = args[1]
+		}
+		err := ruleSetMatch(args[0], query)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -36,11 +52,13 @@ var commandRuleSetMatch = &cobra.Command{
 }
 
 func init() {
-	commandRuleSetMatch.Flags().StringVarP(&flagRuleSetMatchFormat, "format", "f", "source", "rule-set format")
+	commandRuleSetMatch.Flags().StringVarP(&flagRuleSetMatchFormat, "format", "f", "", "rule-set format, detected by extension if empty")
+	commandRuleSetMatch.Flags().StringVar(&flagRuleSetMatchDomain, "domain", "", "domain to match")
+	commandRuleSetMatch.Flags().StringVar(&flagRuleSetMatchIP, "ip", "", "IP address to match")
 	commandRuleSet.AddCommand(commandRuleSetMatch)
 }
 
-func ruleSetMatch(sourcePath string, domain string) error {
+func loadRuleSet(sourcePath string, format string) (option.PlainRuleSetCompat, error) {
 	var (
 		reader io.Reader
 		err    error
@@ -50,56 +68,168 @@ func ruleSetMatch(sourcePath string, domain string) error {
 	} else {
 		reader, err = os.Open(sourcePath)
 		if err != nil {
-			return E.Cause(err, "read rule-set")
+			return option.PlainRuleSetCompat{}, E.Cause(err, "read rule-set")
 		}
 	}
 	content, err := io.ReadAll(reader)
 	if err != nil {
-		return E.Cause(err, "read rule-set")
+		return option.PlainRuleSetCompat{}, E.Cause(err, "read rule-set")
 	}
-	if flagRuleSetMatchFormat == "" {
-		switch filepath.Ext(sourcePath) {
-		case ".json":
-			flagRuleSetMatchFormat = C.RuleSetFormatSource
-		case ".srs":
-			flagRuleSetMatchFormat = C.RuleSetFormatBinary
+	if format == "" {
+		if filepath.Ext(sourcePath) == ".srs" {
+			format = C.RuleSetFormatBinary
+		} else {
+			format = C.RuleSetFormatSource
 		}
 	}
-	var ruleSet option.PlainRuleSetCompat
-	switch flagRuleSetMatchFormat {
+	switch format {
 	case C.RuleSetFormatSource:
-		ruleSet, err = json.UnmarshalExtended[option.PlainRuleSetCompat](content)
-		if err != nil {
-			return err
-		}
+		return json.UnmarshalExtended[option.PlainRuleSetCompat](content)
 	case C.RuleSetFormatBinary:
-		ruleSet, err = srs.Read(bytes.NewReader(content), false)
-		if err != nil {
-			return err
-		}
+		return srs.Read(bytes.NewReader(content), true)
 	default:
-		return E.New("unknown rule-set format: ", flagRuleSetMatchFormat)
+		return option.PlainRuleSetCompat{}, E.New("unknown rule-set format: ", format)
 	}
-	plainRuleSet, err := ruleSet.Upgrade()
+}
+
+func ruleSetMatch(sourcePath string, query string) error {
+	ruleSet, err := loadRuleSet(sourcePath, flagRuleSetMatchFormat)
 	if err != nil {
 		return err
 	}
-	ipAddress := M.ParseAddr(domain)
-	var metadata adapter.InboundContext
-	if ipAddress.IsValid() {
-		metadata.Destination = M.SocksaddrFrom(ipAddress, 0)
-	} else {
-		metadata.Domain = domain
+	plainRuleSet, err := ruleSet.Upgrade()
+	if err != nil {
+		return err
 	}
+	rules := make([]adapter.HeadlessRule, len(plainRuleSet.Rules))
 	for i, ruleOptions := range plainRuleSet.Rules {
-		var currentRule adapter.HeadlessRule
-		currentRule, err = rule.NewHeadlessRule(context.Background(), ruleOptions)
+		rules[i], err = rule.NewHeadlessRule(context.Background(), ruleOptions)
 		if err != nil {
 			return E.Cause(err, "parse rule_set.rules.[", i, "]")
 		}
-		if currentRule.Match(&metadata) {
-			println(F.ToString("match rules.[", i, "]: ", currentRule))
+	}
+	if query != "" || flagRuleSetMatchDomain != "" || flagRuleSetMatchIP != "" {
+		var address netip.Addr
+		if flagRuleSetMatchIP != "" {
+			address, err = netip.ParseAddr(flagRuleSetMatchIP)
+			if err != nil {
+				return E.Cause(err, "parse IP address")
+			}
+		}
+		domain := flagRuleSetMatchDomain
+		if query != "" {
+			if queryAddress := M.ParseAddr(query); queryAddress.IsValid() {
+				address = queryAddress
+			} else {
+				domain = query
+			}
+		}
+		for _, line := range matchRuleSetEntry(plainRuleSet.Rules, rules, domain, address) {
+			fmt.Println(line)
+		}
+		return nil
+	}
+	if sourcePath == "stdin" {
+		return E.New("missing query: stdin is used by the rule-set")
+	}
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		var (
+			domain  string
+			address = M.ParseAddr(entry)
+		)
+		if !address.IsValid() {
+			domain = entry
+		}
+		for _, line := range matchRuleSetEntry(plainRuleSet.Rules, rules, domain, address) {
+			fmt.Println(entry + ": " + line)
+		}
+	}
+	return scanner.Err()
+}
+
+func matchRuleSetEntry(ruleOptions []option.HeadlessRule, rules []adapter.HeadlessRule, domain string, address netip.Addr) []string {
+	var metadata adapter.InboundContext
+	metadata.Domain = domain
+	if address.IsValid() {
+		metadata.Destination = M.SocksaddrFrom(address, 0)
+	}
+	var lines []string
+	for i, currentRule := range rules {
+		metadata.ResetRuleCache()
+		if !currentRule.Match(&metadata) {
+			continue
+		}
+		reasons := explainHeadlessRule(ruleOptions[i], strings.ToLower(domain), address)
+		if len(reasons) == 0 {
+			reasons = []string{currentRule.String()}
+		}
+		lines = append(lines, fmt.Sprint("match rules.[", i, "]: ", strings.Join(reasons, ", ")))
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "no match")
+	}
+	return lines
+}
+
+// explainHeadlessRule lists the domain and IP items of the rule matching the query.
+func explainHeadlessRule(ruleOptions option.HeadlessRule, domain string, address netip.Addr) []string {
+	var reasons []string
+	switch ruleOptions.Type {
+	case C.RuleTypeLogical:
+		if ruleOptions.LogicalOptions.Invert {
+			return nil
+		}
+		for _, subRule := range ruleOptions.LogicalOptions.Rules {
+			reasons = append(reasons, explainHeadlessRule(subRule, domain, address)...)
+		}
+		return reasons
+	}
+	options := ruleOptions.DefaultOptions
+	if options.Invert {
+		return nil
+	}
+	if domain != "" {
+		for _, item := range options.Domain {
+			if item == domain {
+				reasons = append(reasons, "domain="+item)
+			}
+		}
+		for _, item := range options.DomainSuffix {
+			if strings.HasPrefix(item, ".") && strings.HasSuffix(domain, item) ||
+				!strings.HasPrefix(item, ".") && (domain == item || strings.HasSuffix(domain, "."+item)) {
+				reasons = append(reasons, "domain_suffix="+item)
+			}
+		}
+		for _, item := range options.DomainKeyword {
+			if strings.Contains(domain, item) {
+				reasons = append(reasons, "domain_keyword="+item)
+			}
+		}
+		for _, item := range options.DomainRegex {
+			if matched, _ := regexp.MatchString(item, domain); matched {
+				reasons = append(reasons, "domain_regex="+item)
+			}
+		}
+	}
+	if address.IsValid() {
+		for _, item := range options.IPCIDR {
+			prefix, err := netip.ParsePrefix(item)
+			if err != nil {
+				itemAddress, addrErr := netip.ParseAddr(item)
+				if addrErr != nil {
+					continue
+				}
+				prefix = netip.PrefixFrom(itemAddress, itemAddress.BitLen())
+			}
+			if prefix.Contains(address) {
+				reasons = append(reasons, "ip_cidr="+item)
+			}
 		}
 	}
-	return nil
+	return reasons
 }
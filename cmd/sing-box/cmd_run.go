@@ -13,9 +13,12 @@ import (
 	"time"
 
 	"github.com/sagernet/sing-box"
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/systemd"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/json"
 	"github.com/sagernet/sing/common/json/badjson"
@@ -34,7 +37,10 @@ var commandRun = &cobra.Command{
 	},
 }
 
+var commandRunFlagOffline bool
+
 func init() {
+	commandRun.Flags().BoolVar(&commandRunFlagOffline, "offline", false, "start even if remote rule-sets can not be downloaded")
 	mainCommand.AddCommand(commandRun)
 }
 
@@ -134,6 +140,9 @@ func create() (*box.Box, context.CancelFunc, error) {
 		options.Log.DisableColor = true
 	}
 	ctx, cancel := context.WithCancel(globalCtx)
+	if commandRunFlagOffline {
+		ctx = adapter.WithOffline(ctx)
+	}
 	instance, err := box.New(box.Options{
 		Context: ctx,
 		Options: options,
@@ -167,23 +176,56 @@ func create() (*box.Box, context.CancelFunc, error) {
 }
 
 func run() error {
+	serviceHandled, err := runPlatformService()
+	if serviceHandled {
+		return err
+	}
 	osSignals := make(chan os.Signal, 1)
-	signal.Notify(osSignals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	signal.Notify(osSignals, append([]os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP}, drainSignals...)...)
 	defer signal.Stop(osSignals)
+	return runWithSignals(osSignals, nil)
+}
+
+// runWithSignals runs until a signal other than SIGHUP is received or a drain
+// finished, onStarted is called each time the instance is started.
+func runWithSignals(osSignals <-chan os.Signal, onStarted func()) error {
+	var watchdog <-chan time.Time
+	if watchdogInterval := systemd.WatchdogInterval(); watchdogInterval > 0 {
+		watchdogTicker := time.NewTicker(watchdogInterval)
+		defer watchdogTicker.Stop()
+		watchdog = watchdogTicker.C
+	}
 	for {
 		instance, cancel, err := create()
 		if err != nil {
 			return err
 		}
+		if onStarted != nil {
+			onStarted()
+		}
+		err = systemd.Notify(systemd.StateReady)
+		if err != nil {
+			log.Warn(E.Cause(err, "notify systemd"))
+		}
 		runtimeDebug.FreeOSMemory()
 		for {
 			reloadTag := false
 			select {
+			case <-watchdog:
+				_ = systemd.Notify(systemd.StateWatchdog)
+				continue
 			case osSignal := <-osSignals:
+				if common.Contains(drainSignals, osSignal) {
+					err = instance.Drain(nil, 0)
+					if err != nil {
+						log.Error(E.Cause(err, "drain"))
+					}
+					continue
+				}
 				if osSignal == syscall.SIGHUP {
 					err = check()
 					if err != nil {
-						log.Error(E.Cause(err, "reload service"))
+						reloadFailed(instance, err)
 						continue
 					}
 					reloadTag = true
@@ -191,10 +233,16 @@ func run() error {
 			case <-instance.ReloadChan():
 				err = check()
 				if err != nil {
-					log.Error(E.Cause(err, "reload service"))
+					reloadFailed(instance, err)
 					continue
 				}
 				reloadTag = true
+			case <-instance.DrainDone():
+			}
+			if reloadTag {
+				_ = systemd.NotifyReloading()
+			} else {
+				_ = systemd.Notify(systemd.StateStopping)
 			}
 			cancel()
 			closeCtx, closed := context.WithCancel(context.Background())
@@ -212,6 +260,16 @@ func run() error {
 	}
 }
 
+// reloadFailed reports a configuration that failed to reload, the running
+// instance is kept.
+func reloadFailed(instance *box.Box, err error) {
+	log.Error(E.Cause(err, "reload service"))
+	instance.EventBus().Emit(adapter.Event{
+		Type:  adapter.EventReloadFailed,
+		Error: err.Error(),
+	})
+}
+
 func closeMonitor(ctx context.Context) {
 	time.Sleep(C.FatalStopTimeout)
 	select {
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/spf13/cobra"
+)
+
+var commandServiceFlagName string
+
+var commandService = &cobra.Command{
+	Use:   "service",
+	Short: "Manage system service",
+}
+
+func init() {
+	commandService.PersistentFlags().StringVarP(&commandServiceFlagName, "name", "n", "sing-box", "service name")
+}
+
+// serviceArguments builds arguments of the run command, the working directory has been
+// changed by preRun, so that relative paths are resolved against it.
+func serviceArguments() ([]string, error) {
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	arguments := []string{"run", "-D", currentDir}
+	for _, path := range configPaths {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		_, err = os.Stat(absPath)
+		if err != nil {
+			return nil, E.Cause(err, "check configuration")
+		}
+		arguments = append(arguments, "-c", absPath)
+	}
+	for _, directory := range configDirectories {
+		absDirectory, err := filepath.Abs(directory)
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, "-C", absDirectory)
+	}
+	arguments = append(arguments, "--disable-color")
+	return arguments, nil
+}
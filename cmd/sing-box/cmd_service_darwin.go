@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/spf13/cobra"
+)
+
+var commandServiceInstallFlagLogPath string
+
+var commandServiceInstall = &cobra.Command{
+	Use:   "install",
+	Short: "Install and load as launchd daemon",
+	Long: `Install and load as launchd daemon.
+
+A LaunchDaemon plist is written to /Library/LaunchDaemons, running the current
+executable as root with the working directory and configuration paths given by
+-D, -c and -C, resolved to absolute paths. The daemon is started on boot and
+restarted if it exits with an error.
+
+Daemons run as root, so tun and auto_route need no entitlements, but macOS
+lists the executable in Login Items, and a downloaded executable must have the
+quarantine attribute removed (xattr -d com.apple.quarantine) before loading.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := installService()
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var commandServiceUninstall = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Unload and remove launchd daemon",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := uninstallService()
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var commandServiceStart = &cobra.Command{
+	Use:   "start",
+	Short: "Start launchd daemon",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := launchctl("kickstart", "system/"+commandServiceFlagName)
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var commandServiceStop = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop launchd daemon",
+	Long:  "Stop launchd daemon, it is started again on boot.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := launchctl("kill", "SIGTERM", "system/"+commandServiceFlagName)
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var commandServiceStatus = &cobra.Command{
+	Use:   "status",
+	Short: "Show launchd daemon status",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := serviceStatus()
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandServiceInstall.Flags().StringVar(&commandServiceInstallFlagLogPath, "log-path", "", "path of standard output and error, /var/log/<name>.log by default")
+	commandService.AddCommand(commandServiceInstall, commandServiceUninstall, commandServiceStart, commandServiceStop, commandServiceStatus)
+	mainCommand.AddCommand(commandService)
+}
+
+func servicePlistPath() string {
+	return filepath.Join("/Library/LaunchDaemons", commandServiceFlagName+".plist")
+}
+
+func installService() error {
+	executablePath, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	arguments, err := serviceArguments()
+	if err != nil {
+		return err
+	}
+	workingDir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	plistPath := servicePlistPath()
+	_, err = os.Stat(plistPath)
+	if err == nil {
+		return E.New("service already exists: ", plistPath)
+	}
+	logPath := commandServiceInstallFlagLogPath
+	if logPath == "" {
+		logPath = filepath.Join("/var/log", commandServiceFlagName+".log")
+	}
+	content := buildServicePlist(commandServiceFlagName, append([]string{executablePath}, arguments...), workingDir, logPath)
+	err = os.WriteFile(plistPath, content, 0o644)
+	if err != nil {
+		return E.Cause(err, "write plist")
+	}
+	err = launchctl("bootstrap", "system", plistPath)
+	if err != nil {
+		os.Remove(plistPath)
+		return err
+	}
+	log.Info("service installed: ", plistPath)
+	log.Info("logs are written to ", logPath)
+	return nil
+}
+
+func uninstallService() error {
+	plistPath := servicePlistPath()
+	_, err := os.Stat(plistPath)
+	if err != nil {
+		return E.Cause(err, "open service")
+	}
+	err = launchctl("bootout", "system/"+commandServiceFlagName)
+	if err != nil {
+		log.Warn(err)
+	}
+	err = os.Remove(plistPath)
+	if err != nil {
+		return E.Cause(err, "remove plist")
+	}
+	log.Info("service uninstalled: ", plistPath)
+	return nil
+}
+
+func serviceStatus() error {
+	output, err := exec.Command("launchctl", "print", "system/"+commandServiceFlagName).CombinedOutput()
+	if err != nil {
+		return E.New("service not loaded: ", commandServiceFlagName)
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		key, value, found := strings.Cut(strings.TrimSpace(line), " = ")
+		if !found {
+			continue
+		}
+		switch key {
+		case "state", "pid", "last exit code", "program", "stdout path":
+			os.Stdout.WriteString(key + ": " + value + "\n")
+		}
+	}
+	return nil
+}
+
+func launchctl(args ...string) error {
+	output, err := exec.Command("launchctl", args...).CombinedOutput()
+	if err != nil {
+		return E.Cause(err, "launchctl ", strings.Join(args, " "), ": ", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func buildServicePlist(label string, programArguments []string, workingDir string, logPath string) []byte {
+	var buffer bytes.Buffer
+	buffer.WriteString(xml.Header)
+	buffer.WriteString(`<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">` + "\n")
+	buffer.WriteString(`<plist version="1.0">` + "\n<dict>\n")
+	writePlistString := func(key string, value string) {
+		buffer.WriteString("\t<key>" + key + "</key>\n\t<string>")
+		xml.EscapeText(&buffer, []byte(value))
+		buffer.WriteString("</string>\n")
+	}
+	writePlistString("Label", label)
+	buffer.WriteString("\t<key>ProgramArguments</key>\n\t<array>\n")
+	for _, argument := range programArguments {
+		buffer.WriteString("\t\t<string>")
+		xml.EscapeText(&buffer, []byte(argument))
+		buffer.WriteString("</string>\n")
+	}
+	buffer.WriteString("\t</array>\n")
+	writePlistString("WorkingDirectory", workingDir)
+	writePlistString("StandardOutPath", logPath)
+	writePlistString("StandardErrorPath", logPath)
+	buffer.WriteString("\t<key>RunAtLoad</key>\n\t<true/>\n")
+	buffer.WriteString("\t<key>KeepAlive</key>\n\t<dict>\n\t\t<key>SuccessfulExit</key>\n\t\t<false/>\n\t</dict>\n")
+	buffer.WriteString("\t<key>ThrottleInterval</key>\n\t<integer>10</integer>\n")
+	buffer.WriteString("</dict>\n</plist>\n")
+	return buffer.Bytes()
+}
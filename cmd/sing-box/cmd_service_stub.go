@@ -0,0 +1,7 @@
+//go:build !windows
+
+package main
+
+func runPlatformService() (bool, error) {
+	return false, nil
+}
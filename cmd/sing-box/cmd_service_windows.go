@@ -0,0 +1,348 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+var (
+	commandServiceInstallFlagDisplayName string
+	commandServiceInstallFlagDescription string
+	commandServiceInstallFlagManual      bool
+)
+
+var commandServiceInstall = &cobra.Command{
+	Use:   "install",
+	Short: "Install as Windows service",
+	Long: `Install as Windows service.
+
+The service runs the current executable with the working directory and
+configuration paths given by -D, -c and -C, resolved to absolute paths. The
+service is restarted on failure, and start, stop and errors are written to the
+Windows event log.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := installService()
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var commandServiceUninstall = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Stop and remove Windows service",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := uninstallService()
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var commandServiceStart = &cobra.Command{
+	Use:   "start",
+	Short: "Start Windows service",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := startService()
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var commandServiceStop = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop Windows service",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := stopService()
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var commandServiceStatus = &cobra.Command{
+	Use:   "status",
+	Short: "Show Windows service status",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := serviceStatus()
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandServiceInstall.Flags().StringVar(&commandServiceInstallFlagDisplayName, "display-name", "sing-box", "service display name")
+	commandServiceInstall.Flags().StringVar(&commandServiceInstallFlagDescription, "description", "The universal proxy platform", "service description")
+	commandServiceInstall.Flags().BoolVar(&commandServiceInstallFlagManual, "manual", false, "do not start service automatically on boot")
+	commandService.AddCommand(commandServiceInstall, commandServiceUninstall, commandServiceStart, commandServiceStop, commandServiceStatus)
+	mainCommand.AddCommand(commandService)
+}
+
+func installService() error {
+	executablePath, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	arguments, err := serviceArguments()
+	if err != nil {
+		return err
+	}
+	manager, err := mgr.Connect()
+	if err != nil {
+		return E.Cause(err, "connect to service manager")
+	}
+	defer manager.Disconnect()
+	service, err := manager.OpenService(commandServiceFlagName)
+	if err == nil {
+		service.Close()
+		return E.New("service already exists: ", commandServiceFlagName)
+	}
+	startType := uint32(mgr.StartAutomatic)
+	if commandServiceInstallFlagManual {
+		startType = mgr.StartManual
+	}
+	service, err = manager.CreateService(commandServiceFlagName, executablePath, mgr.Config{
+		DisplayName: commandServiceInstallFlagDisplayName,
+		Description: commandServiceInstallFlagDescription,
+		StartType:   startType,
+	}, arguments...)
+	if err != nil {
+		return E.Cause(err, "create service")
+	}
+	defer service.Close()
+	err = service.SetRecoveryActions([]mgr.RecoveryAction{
+		{Type: mgr.ServiceRestart, Delay: 5 * time.Second},
+		{Type: mgr.ServiceRestart, Delay: 10 * time.Second},
+		{Type: mgr.ServiceRestart, Delay: time.Minute},
+	}, uint32((24 * time.Hour).Seconds()))
+	if err != nil {
+		return E.Cause(err, "set recovery actions")
+	}
+	// Exiting with an error is not a crash to the service manager.
+	err = service.SetRecoveryActionsOnNonCrashFailures(true)
+	if err != nil {
+		return E.Cause(err, "set recovery actions")
+	}
+	err = eventlog.InstallAsEventCreate(commandServiceFlagName, eventlog.Error|eventlog.Warning|eventlog.Info)
+	if err != nil {
+		log.Warn(E.Cause(err, "install event log source"))
+	}
+	log.Info("service installed: ", commandServiceFlagName)
+	return nil
+}
+
+func uninstallService() error {
+	manager, err := mgr.Connect()
+	if err != nil {
+		return E.Cause(err, "connect to service manager")
+	}
+	defer manager.Disconnect()
+	service, err := manager.OpenService(commandServiceFlagName)
+	if err != nil {
+		return E.Cause(err, "open service")
+	}
+	defer service.Close()
+	err = controlService(service, svc.Stop, svc.Stopped)
+	if err != nil && !E.IsMulti(err, windows.ERROR_SERVICE_NOT_ACTIVE) {
+		return err
+	}
+	err = service.Delete()
+	if err != nil {
+		return E.Cause(err, "delete service")
+	}
+	_ = eventlog.Remove(commandServiceFlagName)
+	log.Info("service uninstalled: ", commandServiceFlagName)
+	return nil
+}
+
+func startService() error {
+	manager, err := mgr.Connect()
+	if err != nil {
+		return E.Cause(err, "connect to service manager")
+	}
+	defer manager.Disconnect()
+	service, err := manager.OpenService(commandServiceFlagName)
+	if err != nil {
+		return E.Cause(err, "open service")
+	}
+	defer service.Close()
+	err = service.Start()
+	if err != nil {
+		return E.Cause(err, "start service")
+	}
+	return waitService(service, svc.Running)
+}
+
+func stopService() error {
+	manager, err := mgr.Connect()
+	if err != nil {
+		return E.Cause(err, "connect to service manager")
+	}
+	defer manager.Disconnect()
+	service, err := manager.OpenService(commandServiceFlagName)
+	if err != nil {
+		return E.Cause(err, "open service")
+	}
+	defer service.Close()
+	return controlService(service, svc.Stop, svc.Stopped)
+}
+
+func serviceStatus() error {
+	manager, err := mgr.Connect()
+	if err != nil {
+		return E.Cause(err, "connect to service manager")
+	}
+	defer manager.Disconnect()
+	service, err := manager.OpenService(commandServiceFlagName)
+	if err != nil {
+		return E.Cause(err, "open service")
+	}
+	defer service.Close()
+	status, err := service.Query()
+	if err != nil {
+		return E.Cause(err, "query service")
+	}
+	config, err := service.Config()
+	if err != nil {
+		return E.Cause(err, "query service config")
+	}
+	os.Stdout.WriteString("state: " + serviceStateName(status.State) + "\n")
+	if status.ProcessId != 0 {
+		os.Stdout.WriteString("pid: " + F.ToString(status.ProcessId) + "\n")
+	}
+	os.Stdout.WriteString("command: " + config.BinaryPathName + "\n")
+	return nil
+}
+
+func controlService(service *mgr.Service, command svc.Cmd, state svc.State) error {
+	_, err := service.Control(command)
+	if err != nil {
+		return E.Cause(err, "control service")
+	}
+	return waitService(service, state)
+}
+
+func waitService(service *mgr.Service, state svc.State) error {
+	timeout := time.After(C.FatalStopTimeout + 5*time.Second)
+	for {
+		status, err := service.Query()
+		if err != nil {
+			return E.Cause(err, "query service")
+		}
+		if status.State == state {
+			return nil
+		}
+		if status.State == svc.Stopped {
+			return E.New("service stopped with exit code ", status.Win32ExitCode, ", see event log for details")
+		}
+		select {
+		case <-timeout:
+			return E.New("timeout waiting for service state ", serviceStateName(state))
+		case <-time.After(300 * time.Millisecond):
+		}
+	}
+}
+
+func serviceStateName(state svc.State) string {
+	switch state {
+	case svc.Stopped:
+		return "stopped"
+	case svc.StartPending:
+		return "start pending"
+	case svc.StopPending:
+		return "stop pending"
+	case svc.Running:
+		return "running"
+	case svc.ContinuePending:
+		return "continue pending"
+	case svc.PausePending:
+		return "pause pending"
+	case svc.Paused:
+		return "paused"
+	default:
+		return "unknown"
+	}
+}
+
+func runPlatformService() (bool, error) {
+	isService, err := svc.IsWindowsService()
+	if err != nil || !isService {
+		return false, nil
+	}
+	return true, svc.Run("", new(windowsService))
+}
+
+type windowsService struct{}
+
+func (s *windowsService) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	const accepts = svc.AcceptStop | svc.AcceptShutdown | svc.AcceptParamChange
+	var eventLog *eventlog.Log
+	if len(args) > 0 {
+		eventLog, _ = eventlog.Open(args[0])
+	}
+	if eventLog != nil {
+		defer eventLog.Close()
+	}
+	status <- svc.Status{State: svc.StartPending}
+	signals := make(chan os.Signal, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- runWithSignals(signals, func() {
+			status <- svc.Status{State: svc.Running, Accepts: accepts}
+			if eventLog != nil {
+				eventLog.Info(1, "sing-box started")
+			}
+		})
+	}()
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				if eventLog != nil {
+					eventLog.Error(1, err.Error())
+				}
+				return true, 1
+			}
+			if eventLog != nil {
+				eventLog.Info(1, "sing-box stopped")
+			}
+			return false, 0
+		case request := <-requests:
+			switch request.Cmd {
+			case svc.Interrogate:
+				status <- request.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				sendServiceSignal(signals, syscall.SIGTERM)
+			case svc.ParamChange:
+				sendServiceSignal(signals, syscall.SIGHUP)
+			}
+		}
+	}
+}
+
+func sendServiceSignal(signals chan<- os.Signal, signal os.Signal) {
+	select {
+	case signals <- signal:
+	default:
+	}
+}
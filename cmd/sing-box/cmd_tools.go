@@ -5,13 +5,17 @@ import (
 	"os"
 
 	"github.com/sagernet/sing-box"
+	"github.com/sagernet/sing-box/common/dialer"
 	E "github.com/sagernet/sing/common/exceptions"
 	N "github.com/sagernet/sing/common/network"
 
 	"github.com/spf13/cobra"
 )
 
-var commandToolsFlagOutbound string
+var (
+	commandToolsFlagOutbound string
+	commandToolsFlagRoute    bool
+)
 
 var commandTools = &cobra.Command{
 	Use:   "tools",
@@ -20,6 +24,8 @@ var commandTools = &cobra.Command{
 
 func init() {
 	commandTools.PersistentFlags().StringVarP(&commandToolsFlagOutbound, "outbound", "o", "", "Use specified tag instead of default outbound")
+	commandTools.RegisterFlagCompletionFunc("outbound", completeOutbounds)
+	commandTools.PersistentFlags().BoolVarP(&commandToolsFlagRoute, "route", "r", false, "Use route rules instead of default outbound")
 	mainCommand.AddCommand(commandTools)
 }
 
@@ -42,6 +48,17 @@ func createPreStartedClient() (*box.Box, error) {
 }
 
 func createDialer(instance *box.Box, outboundTag string) (N.Dialer, error) {
+	if commandToolsFlagRoute {
+		if outboundTag != "" {
+			return nil, E.New("--route can not be used with an outbound tag")
+		}
+		// Route rules are initialized after pre-start, rule-set references are resolved there.
+		err := instance.PostStart()
+		if err != nil {
+			return nil, E.Cause(err, "start service")
+		}
+		return dialer.NewRouted(globalCtx, instance.Router(), "tools"), nil
+	}
 	if outboundTag == "" {
 		return instance.Outbound().Default(), nil
 	} else {
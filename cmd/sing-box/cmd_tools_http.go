@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	commandHTTPFlagMethod   string
+	commandHTTPFlagInsecure bool
+	commandHTTPFlagTimeout  time.Duration
+)
+
+var commandHTTP = &cobra.Command{
+	Use:   "http <url>",
+	Short: "Show status and timing of an HTTP request",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := probeHTTP(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandHTTP.Flags().StringVarP(&commandHTTPFlagMethod, "method", "X", http.MethodGet, "request method")
+	commandHTTP.Flags().BoolVarP(&commandHTTPFlagInsecure, "insecure", "k", false, "skip certificate verification")
+	commandHTTP.Flags().DurationVar(&commandHTTPFlagTimeout, "timeout", 30*time.Second, "request timeout")
+	commandTools.AddCommand(commandHTTP)
+}
+
+func probeHTTP(urlString string) error {
+	if !strings.Contains(urlString, "://") {
+		urlString = "http://" + urlString
+	}
+	parsedURL, err := url.Parse(urlString)
+	if err != nil {
+		return err
+	}
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+		return E.New("unsupported scheme: ", parsedURL.Scheme)
+	}
+	instance, err := createPreStartedClient()
+	if err != nil {
+		return err
+	}
+	defer instance.Close()
+	dialer, err := createDialer(instance, commandToolsFlagOutbound)
+	if err != nil {
+		return err
+	}
+	var (
+		start                     time.Time
+		connectDone, tlsDone      time.Duration
+		firstByte, requestWritten time.Duration
+	)
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				conn, err := dialer.DialContext(ctx, network, M.ParseSocksaddr(addr))
+				connectDone = time.Since(start)
+				return conn, err
+			},
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: commandHTTPFlagInsecure,
+			},
+			ForceAttemptHTTP2: true,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	defer client.CloseIdleConnections()
+	ctx, cancel := context.WithTimeout(globalCtx, commandHTTPFlagTimeout)
+	defer cancel()
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			tlsDone = time.Since(start)
+		},
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			requestWritten = time.Since(start)
+		},
+		GotFirstResponseByte: func() {
+			firstByte = time.Since(start)
+		},
+	})
+	request, err := http.NewRequestWithContext(ctx, commandHTTPFlagMethod, parsedURL.String(), nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("User-Agent", "curl/7.88.0")
+	start = time.Now()
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	bodySize, err := io.Copy(io.Discard, response.Body)
+	response.Body.Close()
+	if err != nil {
+		return E.Cause(err, "read body")
+	}
+	total := time.Since(start)
+	fmt.Println(response.Proto, response.Status)
+	if location := response.Header.Get("Location"); location != "" {
+		fmt.Println("location:", location)
+	}
+	if server := response.Header.Get("Server"); server != "" {
+		fmt.Println("server:", server)
+	}
+	fmt.Println("body size:", bodySize)
+	if connectDone > 0 {
+		fmt.Println("connect:", connectDone.Round(time.Microsecond))
+	}
+	if tlsDone > 0 {
+		fmt.Println("tls handshake:", tlsDone.Round(time.Microsecond))
+	}
+	if requestWritten > 0 {
+		fmt.Println("request sent:", requestWritten.Round(time.Microsecond))
+	}
+	fmt.Println("first byte:", firstByte.Round(time.Microsecond))
+	fmt.Println("total:", total.Round(time.Microsecond))
+	return nil
+}
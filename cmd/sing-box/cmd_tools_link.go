@@ -0,0 +1,14 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var commandToolsLink = &cobra.Command{
+	Use:   "link",
+	Short: "Convert between share links and configuration",
+}
+
+func init() {
+	commandTools.AddCommand(commandToolsLink)
+}
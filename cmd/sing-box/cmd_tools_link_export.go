@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+
+	"github.com/sagernet/sing-box/common/convertor/link"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/skip2/go-qrcode"
+	"github.com/spf13/cobra"
+)
+
+var (
+	commandToolsLinkExportFlagServer string
+	commandToolsLinkExportFlagQR     bool
+)
+
+var commandToolsLinkExport = &cobra.Command{
+	Use:               "export [tag]...",
+	Short:             "Convert outbounds and inbounds to share links",
+	Long:              "Convert outbounds and inbounds in the configuration to share links.\n\nIf no tags are given, all supported outbounds are exported. Inbounds are exported only when their tag is given, as one link per user.",
+	ValidArgsFunction: completeOutboundsAndInbounds,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := exportLinks(args)
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandToolsLinkExport.Flags().StringVarP(&commandToolsLinkExportFlagServer, "server", "s", "", "Server address used for inbound links")
+	commandToolsLinkExport.Flags().BoolVar(&commandToolsLinkExportFlagQR, "qr", false, "Print links as QR codes")
+	commandToolsLink.AddCommand(commandToolsLinkExport)
+}
+
+func exportLinks(tags []string) error {
+	options, err := readConfigAndMerge()
+	if err != nil {
+		return err
+	}
+	var links []string
+	for _, outbound := range options.Outbounds {
+		if len(tags) == 0 || common.Contains(tags, outbound.Tag) {
+			shareLink, err := link.Export(outbound)
+			if err != nil {
+				if len(tags) > 0 {
+					return E.Cause(err, "export outbound/", outbound.Type, "[", outbound.Tag, "]")
+				}
+				continue
+			}
+			links = append(links, shareLink)
+		}
+	}
+	for _, inbound := range options.Inbounds {
+		if !common.Contains(tags, inbound.Tag) {
+			continue
+		}
+		outbounds, err := link.InboundOutbounds(inbound, commandToolsLinkExportFlagServer)
+		if err != nil {
+			return E.Cause(err, "export inbound/", inbound.Type, "[", inbound.Tag, "]")
+		}
+		for _, outbound := range outbounds {
+			shareLink, err := link.Export(outbound)
+			if err != nil {
+				return E.Cause(err, "export inbound/", inbound.Type, "[", inbound.Tag, "]")
+			}
+			links = append(links, shareLink)
+		}
+	}
+	if len(links) == 0 {
+		return E.New("no exportable outbounds or inbounds found")
+	}
+	for _, shareLink := range links {
+		if commandToolsLinkExportFlagQR {
+			code, err := qrcode.New(shareLink, qrcode.Low)
+			if err != nil {
+				return E.Cause(err, "encode QR code")
+			}
+			os.Stdout.WriteString(code.ToSmallString(false))
+		}
+		os.Stdout.WriteString(shareLink + "\n")
+	}
+	return nil
+}
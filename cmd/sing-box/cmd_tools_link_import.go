@@ -0,0 +1,54 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sagernet/sing-box/common/convertor/link"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+
+	"github.com/spf13/cobra"
+)
+
+var commandToolsLinkImport = &cobra.Command{
+	Use:   "import [link]...",
+	Short: "Convert share links to outbounds",
+	Long:  "Convert share links to outbounds.\n\nLinks are read from stdin if no arguments are given, a base64 encoded subscription is also accepted.",
+	Run: func(cmd *cobra.Command, args []string) {
+		err := importLinks(args)
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandToolsLink.AddCommand(commandToolsLinkImport)
+}
+
+func importLinks(args []string) error {
+	var content []byte
+	if len(args) == 0 {
+		var err error
+		content, err = io.ReadAll(os.Stdin)
+		if err != nil {
+			return E.Cause(err, "read stdin")
+		}
+	} else {
+		content = []byte(strings.Join(args, "\n"))
+	}
+	outbounds, errors := link.ParseList(content)
+	for _, err := range errors {
+		log.Warn(err)
+	}
+	if len(outbounds) == 0 {
+		return E.New("no valid links found")
+	}
+	encoder := json.NewEncoderContext(globalCtx, os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(option.Options{Outbounds: outbounds})
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	commandPingFlagCount    int
+	commandPingFlagInterval time.Duration
+	commandPingFlagTimeout  time.Duration
+	commandUDPingFlagData   string
+	commandUDPingFlagQUIC   bool
+)
+
+var commandTCPing = &cobra.Command{
+	Use:   "tcping <address>",
+	Short: "Measure TCP connect latency",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := probe(args[0], 443, tcping)
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var commandUDPing = &cobra.Command{
+	Use:   "udping <address>",
+	Short: "Measure UDP round trip latency",
+	Long: `Measure UDP round trip latency.
+
+The server must echo the payload back unless --quic is set, in which case a
+QUIC version negotiation is requested, which QUIC servers answer without a
+handshake.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		defaultPort := uint16(7)
+		if commandUDPingFlagQUIC {
+			defaultPort = 443
+		}
+		err := probe(args[0], defaultPort, udping)
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	for _, command := range []*cobra.Command{commandTCPing, commandUDPing} {
+		addProbeFlags(command)
+		commandTools.AddCommand(command)
+	}
+	commandUDPing.Flags().StringVar(&commandUDPingFlagData, "data", "sing-box", "payload of echo requests")
+	commandUDPing.Flags().BoolVar(&commandUDPingFlagQUIC, "quic", false, "probe a QUIC server")
+}
+
+func addProbeFlags(command *cobra.Command) {
+	command.Flags().IntVar(&commandPingFlagCount, "count", 4, "number of probes, 0 to run until interrupted")
+	command.Flags().DurationVar(&commandPingFlagInterval, "interval", time.Second, "interval between probes")
+	command.Flags().DurationVar(&commandPingFlagTimeout, "timeout", 5*time.Second, "timeout of each probe")
+}
+
+type probeFunc func(ctx context.Context, dialer N.Dialer, destination M.Socksaddr) (string, error)
+
+// probe runs probeFunc repeatedly and prints ping-like statistics.
+func probe(address string, defaultPort uint16, f probeFunc) error {
+	destination := M.ParseSocksaddr(address)
+	if destination.Port == 0 {
+		destination.Port = defaultPort
+	}
+	if !destination.IsValid() {
+		return E.New("invalid address: ", address)
+	}
+	instance, err := createPreStartedClient()
+	if err != nil {
+		return err
+	}
+	defer instance.Close()
+	dialer, err := createDialer(instance, commandToolsFlagOutbound)
+	if err != nil {
+		return err
+	}
+	var (
+		sent      int
+		latencies []time.Duration
+	)
+	for sequence := 1; commandPingFlagCount == 0 || sequence <= commandPingFlagCount; sequence++ {
+		if sequence > 1 {
+			select {
+			case <-globalCtx.Done():
+			case <-time.After(commandPingFlagInterval):
+			}
+		}
+		if globalCtx.Err() != nil {
+			break
+		}
+		sent++
+		ctx, cancel := context.WithTimeout(globalCtx, commandPingFlagTimeout)
+		start := time.Now()
+		detail, err := f(ctx, dialer, destination)
+		latency := time.Since(start)
+		cancel()
+		if err != nil {
+			fmt.Printf("%s: seq=%d error: %s\n", destination, sequence, err)
+			continue
+		}
+		latencies = append(latencies, latency)
+		if detail != "" {
+			detail = " " + detail
+		}
+		fmt.Printf("%s: seq=%d time=%s%s\n", destination, sequence, latency.Round(time.Microsecond), detail)
+	}
+	fmt.Println()
+	fmt.Printf("%d probes sent, %d succeeded, %.1f%% loss\n", sent, len(latencies), float64(sent-len(latencies))*100/float64(max(sent, 1)))
+	printLatency(latencies)
+	return nil
+}
+
+func tcping(ctx context.Context, dialer N.Dialer, destination M.Socksaddr) (string, error) {
+	conn, err := dialer.DialContext(ctx, N.NetworkTCP, destination)
+	if err != nil {
+		return "", err
+	}
+	conn.Close()
+	return "", nil
+}
+
+func udping(ctx context.Context, dialer N.Dialer, destination M.Socksaddr) (string, error) {
+	conn, err := dialer.DialContext(ctx, N.NetworkUDP, destination)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	if deadline, loaded := ctx.Deadline(); loaded {
+		_ = conn.SetReadDeadline(deadline)
+	}
+	var (
+		request []byte
+		check   func(response []byte) (string, error)
+	)
+	if commandUDPingFlagQUIC {
+		request, check = quicVersionProbe()
+	} else {
+		request = []byte(commandUDPingFlagData)
+		check = func(response []byte) (string, error) {
+			if !bytes.Equal(response, request) {
+				return "", E.New("unexpected echo response of ", len(response), " bytes")
+			}
+			return "", nil
+		}
+	}
+	_, err = conn.Write(request)
+	if err != nil {
+		return "", err
+	}
+	response := make([]byte, 65535)
+	n, err := conn.Read(response)
+	if err != nil {
+		if E.IsTimeout(err) {
+			return "", E.New("timeout")
+		}
+		return "", err
+	}
+	return check(response[:n])
+}
+
+// quicVersionProbe builds a QUIC long header packet with a reserved version, which servers
+// must answer with a version negotiation packet listing supported versions (RFC 9000 section 6).
+func quicVersionProbe() ([]byte, func(response []byte) (string, error)) {
+	connectionID := make([]byte, 8)
+	common.Must1(rand.Read(connectionID))
+	request := []byte{0xc0, 0x1a, 0x2a, 0x3a, 0x4a, byte(len(connectionID))}
+	request = append(request, connectionID...)
+	request = append(request, 0)
+	// Initial packets must be padded to 1200 bytes to be processed.
+	request = append(request, make([]byte, 1200-len(request))...)
+	return request, func(response []byte) (string, error) {
+		if len(response) < 7 || response[0]&0x80 == 0 || !bytes.Equal(response[1:5], []byte{0, 0, 0, 0}) {
+			return "", E.New("unexpected response of ", len(response), " bytes")
+		}
+		offset := 6 + int(response[5])
+		if offset >= len(response) {
+			return "", E.New("malformed version negotiation")
+		}
+		sourceIDLength := int(response[offset])
+		offset++
+		if offset+sourceIDLength > len(response) || !bytes.Equal(response[offset:offset+sourceIDLength], connectionID) {
+			return "", E.New("connection ID mismatch in version negotiation")
+		}
+		offset += sourceIDLength
+		var versions []string
+		for ; offset+4 <= len(response); offset += 4 {
+			versions = append(versions, formatQUICVersion(response[offset:offset+4]))
+		}
+		return fmt.Sprint("versions=", versions), nil
+	}
+}
+
+func formatQUICVersion(version []byte) string {
+	switch {
+	case bytes.Equal(version, []byte{0, 0, 0, 1}):
+		return "v1"
+	case bytes.Equal(version, []byte{0x6b, 0x33, 0x43, 0xcf}):
+		return "v2"
+	default:
+		return fmt.Sprintf("0x%x", version)
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+
+	"github.com/miekg/dns"
+	"github.com/spf13/cobra"
+)
+
+var (
+	commandResolveFlagServer string
+	commandResolveFlagAPI    string
+	commandResolveFlagSecret string
+)
+
+var commandResolve = &cobra.Command{
+	Use:   "resolve <domain> [type]",
+	Short: "Resolve a domain through the DNS router",
+	Long: `Resolve a domain through the DNS router, showing the matched DNS rules,
+the answering server, the query time and the raw records.
+
+The configuration is loaded unless --api is set, in which case the query is
+sent to the Clash API of a running instance.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		queryType := "A"
+		if len(args) > 1 {
+			queryType = args[1]
+		}
+		err := resolve(args[0], strings.ToUpper(queryType))
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandResolve.Flags().StringVarP(&commandResolveFlagServer, "server", "s", "", "DNS server tag to use instead of DNS rules")
+	commandResolve.RegisterFlagCompletionFunc("server", completeDNSServers)
+	commandResolve.Flags().StringVar(&commandResolveFlagAPI, "api", "", "Clash API address of a running instance, e.g. 127.0.0.1:9090")
+	commandResolve.Flags().StringVar(&commandResolveFlagSecret, "secret", "", "Clash API secret")
+	commandTools.AddCommand(commandResolve)
+}
+
+func resolve(domain string, queryType string) error {
+	qType, loaded := dns.StringToType[queryType]
+	if !loaded {
+		return E.New("unknown query type: ", queryType)
+	}
+	if commandResolveFlagAPI != "" {
+		if commandResolveFlagServer != "" {
+			return E.New("--server is not supported with --api")
+		}
+		return resolveAPI(domain, queryType)
+	}
+	instance, err := createPreStartedClient()
+	if err != nil {
+		return err
+	}
+	defer instance.Close()
+	var options adapter.DNSQueryOptions
+	if commandResolveFlagServer != "" {
+		transport, loaded := instance.DNSTransport().Transport(commandResolveFlagServer)
+		if !loaded {
+			return E.New("DNS server not found: ", commandResolveFlagServer)
+		}
+		options.Transport = transport
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), C.DNSTimeout)
+	defer cancel()
+	var trace adapter.DNSTrace
+	ctx = adapter.WithDNSTrace(ctx, &trace)
+	message := new(dns.Msg)
+	message.SetQuestion(dns.Fqdn(domain), qType)
+	start := time.Now()
+	response, err := instance.DNSRouter().Exchange(ctx, message, options)
+	elapsed := time.Since(start)
+	if err != nil {
+		printResolveTrace(trace, elapsed)
+		return err
+	}
+	fmt.Println(response.String())
+	printResolveTrace(trace, elapsed)
+	return nil
+}
+
+func printResolveTrace(trace adapter.DNSTrace, elapsed time.Duration) {
+	for _, match := range trace.Matches {
+		printResolveRule(match.Index, match.Rule, match.Action)
+	}
+	printResolveServer(trace.Transport, trace.Cached)
+	fmt.Println(";; QUERY TIME:", elapsed.Round(time.Microsecond))
+}
+
+func printResolveRule(index int, rule string, action string) {
+	if rule != "" {
+		fmt.Printf(";; RULE: dns.rules[%d] %s => %s\n", index, rule, action)
+	} else {
+		fmt.Printf(";; RULE: dns.rules[%d] => %s\n", index, action)
+	}
+}
+
+func printResolveServer(server string, cached bool) {
+	if cached {
+		fmt.Println(";; SERVER: cache")
+	} else if server != "" {
+		fmt.Println(";; SERVER:", server)
+	}
+}
+
+type resolveAPIRecord struct {
+	Name string `json:"name"`
+	Type uint16 `json:"type"`
+	TTL  uint32 `json:"TTL"`
+	Data string `json:"data"`
+}
+
+type resolveAPIResponse struct {
+	Message    string             `json:"message"`
+	Status     int                `json:"Status"`
+	Server     string             `json:"Server"`
+	Cached     bool               `json:"Cached"`
+	Elapsed    int64              `json:"Elapsed"`
+	Rules      []resolveAPIRule   `json:"Rules"`
+	Answer     []resolveAPIRecord `json:"Answer"`
+	Authority  []resolveAPIRecord `json:"Authority"`
+	Additional []resolveAPIRecord `json:"Additional"`
+}
+
+type resolveAPIRule struct {
+	Index  int    `json:"index"`
+	Rule   string `json:"rule"`
+	Action string `json:"action"`
+}
+
+func resolveAPI(domain string, queryType string) error {
+	apiURL := commandResolveFlagAPI
+	if !strings.Contains(apiURL, "://") {
+		apiURL = "http://" + apiURL
+	}
+	requestURL, err := url.Parse(apiURL)
+	if err != nil {
+		return E.Cause(err, "parse API address")
+	}
+	requestURL.Path = strings.TrimSuffix(requestURL.Path, "/") + "/dns/query"
+	requestURL.RawQuery = url.Values{"name": {domain}, "type": {queryType}}.Encode()
+	request, err := http.NewRequest(http.MethodGet, requestURL.String(), nil)
+	if err != nil {
+		return err
+	}
+	if commandResolveFlagSecret != "" {
+		request.Header.Set("Authorization", "Bearer "+commandResolveFlagSecret)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	var result resolveAPIResponse
+	err = json.NewDecoder(response.Body).Decode(&result)
+	if err != nil {
+		return E.Cause(err, "decode API response")
+	}
+	if response.StatusCode != http.StatusOK {
+		if result.Message != "" {
+			return E.New(result.Message)
+		}
+		return E.New("unexpected status: ", response.Status)
+	}
+	fmt.Println(";; STATUS:", dns.RcodeToString[result.Status])
+	for _, section := range []struct {
+		name    string
+		records []resolveAPIRecord
+	}{
+		{"ANSWER", result.Answer},
+		{"AUTHORITY", result.Authority},
+		{"ADDITIONAL", result.Additional},
+	} {
+		if len(section.records) == 0 {
+			continue
+		}
+		fmt.Println()
+		fmt.Println(";; " + section.name + " SECTION:")
+		for _, record := range section.records {
+			fmt.Printf("%s\t%d\tIN\t%s\t%s\n", record.Name, record.TTL, dns.TypeToString[record.Type], record.Data)
+		}
+	}
+	fmt.Println()
+	for _, rule := range result.Rules {
+		printResolveRule(rule.Index, rule.Rule, rule.Action)
+	}
+	printResolveServer(result.Server, result.Cached)
+	fmt.Println(";; QUERY TIME:", time.Duration(result.Elapsed)*time.Millisecond)
+	return nil
+}
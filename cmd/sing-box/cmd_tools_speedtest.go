@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	commandSpeedtestFlagDownloadURL string
+	commandSpeedtestFlagUploadURL   string
+	commandSpeedtestFlagLatencyURL  string
+	commandSpeedtestFlagDuration    time.Duration
+	commandSpeedtestFlagSamples     int
+	commandSpeedtestFlagIPerf3      string
+	commandSpeedtestFlagNoDownload  bool
+	commandSpeedtestFlagNoUpload    bool
+)
+
+var commandSpeedtest = &cobra.Command{
+	Use:   "speedtest [outbound tag]...",
+	Short: "Measure latency and throughput through outbounds",
+	Long: `Measure latency and throughput through outbounds.
+
+Each outbound given as an argument is tested in turn, the outbound set by
+--outbound or the default outbound is used if none. HTTP endpoints are used
+unless --iperf3 is set, in which case an iperf3 server is tested over TCP.`,
+	ValidArgsFunction: completeOutbounds,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := speedtest(args)
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandSpeedtest.Flags().StringVar(&commandSpeedtestFlagDownloadURL, "download-url", "https://speed.cloudflare.com/__down?bytes=1000000000", "download test URL")
+	commandSpeedtest.Flags().StringVar(&commandSpeedtestFlagUploadURL, "upload-url", "https://speed.cloudflare.com/__up", "upload test URL, accepting POST requests")
+	commandSpeedtest.Flags().StringVar(&commandSpeedtestFlagLatencyURL, "latency-url", "https://speed.cloudflare.com/__down?bytes=0", "latency test URL")
+	commandSpeedtest.Flags().DurationVarP(&commandSpeedtestFlagDuration, "duration", "t", 10*time.Second, "duration of each throughput test")
+	commandSpeedtest.Flags().IntVar(&commandSpeedtestFlagSamples, "samples", 5, "number of latency samples")
+	commandSpeedtest.Flags().StringVar(&commandSpeedtestFlagIPerf3, "iperf3", "", "iperf3 server address, port 5201 is used if not set")
+	commandSpeedtest.Flags().BoolVar(&commandSpeedtestFlagNoDownload, "no-download", false, "skip download test")
+	commandSpeedtest.Flags().BoolVar(&commandSpeedtestFlagNoUpload, "no-upload", false, "skip upload test")
+	commandTools.AddCommand(commandSpeedtest)
+}
+
+func speedtest(outboundTags []string) error {
+	instance, err := createPreStartedClient()
+	if err != nil {
+		return err
+	}
+	defer instance.Close()
+	if len(outboundTags) == 0 {
+		outboundTags = []string{commandToolsFlagOutbound}
+	}
+	for i, outboundTag := range outboundTags {
+		dialer, err := createDialer(instance, outboundTag)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		if outboundTag == "" {
+			outboundTag = instance.Outbound().Default().Tag()
+		}
+		fmt.Println("outbound:", outboundTag)
+		if commandSpeedtestFlagIPerf3 != "" {
+			err = speedtestIPerf3(dialer)
+		} else {
+			err = speedtestHTTP(dialer)
+		}
+		if err != nil {
+			fmt.Println("error:", err)
+		}
+	}
+	return nil
+}
+
+func speedtestHTTP(dialer N.Dialer) error {
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, M.ParseSocksaddr(addr))
+			},
+			ForceAttemptHTTP2: true,
+		},
+	}
+	defer client.CloseIdleConnections()
+	if commandSpeedtestFlagSamples > 0 {
+		latencies, err := speedtestLatency(client)
+		if err != nil {
+			return E.Cause(err, "latency test")
+		}
+		printLatency(latencies)
+	}
+	if !commandSpeedtestFlagNoDownload {
+		transferred, elapsed, err := speedtestDownload(client)
+		if err != nil {
+			return E.Cause(err, "download test")
+		}
+		fmt.Println("download:", formatThroughput(transferred, elapsed))
+	}
+	if !commandSpeedtestFlagNoUpload {
+		transferred, elapsed, err := speedtestUpload(client)
+		if err != nil {
+			return E.Cause(err, "upload test")
+		}
+		fmt.Println("upload:", formatThroughput(transferred, elapsed))
+	}
+	return nil
+}
+
+// speedtestLatency measures request round trips, the first sample also covers connection setup.
+func speedtestLatency(client *http.Client) ([]time.Duration, error) {
+	latencies := make([]time.Duration, 0, commandSpeedtestFlagSamples)
+	for i := 0; i < commandSpeedtestFlagSamples; i++ {
+		start := time.Now()
+		response, err := client.Get(commandSpeedtestFlagLatencyURL)
+		if err != nil {
+			return nil, err
+		}
+		_, _ = io.Copy(io.Discard, response.Body)
+		response.Body.Close()
+		if response.StatusCode >= 400 {
+			return nil, E.New("unexpected status: ", response.Status)
+		}
+		latencies = append(latencies, time.Since(start))
+	}
+	return latencies, nil
+}
+
+func speedtestDownload(client *http.Client) (int64, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), commandSpeedtestFlagDuration)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, commandSpeedtestFlagDownloadURL, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	start := time.Now()
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return 0, 0, E.New("unexpected status: ", response.Status)
+	}
+	transferred, err := io.Copy(io.Discard, response.Body)
+	elapsed := time.Since(start)
+	if err != nil && ctx.Err() == nil {
+		return 0, 0, err
+	}
+	return transferred, elapsed, nil
+}
+
+func speedtestUpload(client *http.Client) (int64, time.Duration, error) {
+	body := &deadlineReader{deadline: time.Now().Add(commandSpeedtestFlagDuration)}
+	request, err := http.NewRequest(http.MethodPost, commandSpeedtestFlagUploadURL, body)
+	if err != nil {
+		return 0, 0, err
+	}
+	request.Header.Set("Content-Type", "application/octet-stream")
+	start := time.Now()
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, 0, err
+	}
+	elapsed := time.Since(start)
+	_, _ = io.Copy(io.Discard, response.Body)
+	response.Body.Close()
+	if response.StatusCode >= 400 {
+		return 0, 0, E.New("unexpected status: ", response.Status)
+	}
+	return body.n, elapsed, nil
+}
+
+// deadlineReader produces zeros until the deadline.
+type deadlineReader struct {
+	deadline time.Time
+	n        int64
+}
+
+func (r *deadlineReader) Read(p []byte) (int, error) {
+	if time.Now().After(r.deadline) {
+		return 0, io.EOF
+	}
+	clear(p)
+	r.n += int64(len(p))
+	return len(p), nil
+}
+
+func printLatency(latencies []time.Duration) {
+	if len(latencies) == 0 {
+		return
+	}
+	minLatency, maxLatency := latencies[0], latencies[0]
+	var total time.Duration
+	for _, latency := range latencies {
+		minLatency = min(minLatency, latency)
+		maxLatency = max(maxLatency, latency)
+		total += latency
+	}
+	fmt.Println("latency:", "min", minLatency.Round(time.Microsecond), "avg", (total / time.Duration(len(latencies))).Round(time.Microsecond), "max", maxLatency.Round(time.Microsecond))
+}
+
+func formatThroughput(transferred int64, elapsed time.Duration) string {
+	if elapsed <= 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.2f Mbps (%.2f MB in %s)", float64(transferred)*8/elapsed.Seconds()/1e6, float64(transferred)/1e6, elapsed.Round(time.Millisecond))
+}
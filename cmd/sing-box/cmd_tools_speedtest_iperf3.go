@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+// iperf3 control states, see iperf_api.h
+const (
+	iperf3TestStart       = 1
+	iperf3TestRunning     = 2
+	iperf3TestEnd         = 4
+	iperf3ParamExchange   = 9
+	iperf3CreateStreams   = 10
+	iperf3ServerTerminate = 11
+	iperf3ExchangeResults = 13
+	iperf3DisplayResults  = 14
+	iperf3Done            = 16
+	iperf3ServerError     = -2
+	iperf3AccessDenied    = -1
+)
+
+const iperf3BlockSize = 128 * 1024
+
+func speedtestIPerf3(dialer N.Dialer) error {
+	serverAddr := M.ParseSocksaddr(commandSpeedtestFlagIPerf3)
+	if serverAddr.Port == 0 {
+		serverAddr.Port = 5201
+	}
+	if !commandSpeedtestFlagNoUpload {
+		transferred, elapsed, err := iperf3Test(dialer, serverAddr, false)
+		if err != nil {
+			return E.Cause(err, "iperf3 upload test")
+		}
+		fmt.Println("upload:", formatThroughput(transferred, elapsed))
+	}
+	if !commandSpeedtestFlagNoDownload {
+		transferred, elapsed, err := iperf3Test(dialer, serverAddr, true)
+		if err != nil {
+			return E.Cause(err, "iperf3 download test")
+		}
+		fmt.Println("download:", formatThroughput(transferred, elapsed))
+	}
+	return nil
+}
+
+type iperf3Results struct {
+	Streams []struct {
+		Bytes int64 `json:"bytes"`
+	} `json:"streams"`
+}
+
+// iperf3Test runs a single stream TCP test, the server sends when reverse is set.
+func iperf3Test(dialer N.Dialer, serverAddr M.Socksaddr, reverse bool) (int64, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), commandSpeedtestFlagDuration+30*time.Second)
+	defer cancel()
+	control, err := dialer.DialContext(ctx, N.NetworkTCP, serverAddr)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer control.Close()
+	go func() {
+		<-ctx.Done()
+		control.Close()
+	}()
+	cookie := iperf3Cookie()
+	_, err = control.Write(cookie)
+	if err != nil {
+		return 0, 0, err
+	}
+	var (
+		data        net.Conn
+		transferred int64
+		start       time.Time
+		elapsed     time.Duration
+	)
+	defer func() {
+		if data != nil {
+			data.Close()
+		}
+	}()
+	for {
+		state, err := iperf3ReadState(control)
+		if err != nil {
+			return 0, 0, err
+		}
+		switch state {
+		case iperf3ParamExchange:
+			err = iperf3WriteJSON(control, map[string]any{
+				"tcp":            true,
+				"omit":           0,
+				"time":           int(commandSpeedtestFlagDuration.Seconds()),
+				"parallel":       1,
+				"len":            iperf3BlockSize,
+				"reverse":        reverse,
+				"client_version": "3.9",
+			})
+		case iperf3CreateStreams:
+			data, err = dialer.DialContext(ctx, N.NetworkTCP, serverAddr)
+			if err == nil {
+				_, err = data.Write(cookie)
+			}
+		case iperf3TestStart:
+		case iperf3TestRunning:
+			if data == nil {
+				return 0, 0, E.New("unexpected state: test running before streams created")
+			}
+			start = time.Now()
+			transferred, err = iperf3Transfer(data, reverse)
+			elapsed = time.Since(start)
+			if err == nil {
+				err = iperf3WriteState(control, iperf3TestEnd)
+			}
+		case iperf3ExchangeResults:
+			err = iperf3WriteJSON(control, map[string]any{
+				"cpu_util_total":         0,
+				"cpu_util_user":          0,
+				"cpu_util_system":        0,
+				"sender_has_retransmits": -1,
+				"streams": []map[string]any{{
+					"id":          1,
+					"bytes":       transferred,
+					"retransmits": -1,
+					"jitter":      0,
+					"errors":      0,
+					"packets":     0,
+					"start_time":  0,
+					"end_time":    elapsed.Seconds(),
+				}},
+			})
+			if err != nil {
+				break
+			}
+			var serverResults iperf3Results
+			err = iperf3ReadJSON(control, &serverResults)
+			if err == nil && !reverse && len(serverResults.Streams) > 0 {
+				transferred = serverResults.Streams[0].Bytes
+			}
+		case iperf3DisplayResults:
+			return transferred, elapsed, iperf3WriteState(control, iperf3Done)
+		case iperf3AccessDenied:
+			return 0, 0, E.New("access denied, server is busy")
+		case iperf3ServerError:
+			return 0, 0, E.New("server error")
+		case iperf3ServerTerminate:
+			return 0, 0, E.New("server terminated")
+		default:
+			return 0, 0, E.New("unexpected state: ", state)
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+}
+
+func iperf3Transfer(data net.Conn, reverse bool) (int64, error) {
+	deadline := time.Now().Add(commandSpeedtestFlagDuration)
+	buffer := make([]byte, iperf3BlockSize)
+	var transferred int64
+	for time.Now().Before(deadline) {
+		var (
+			n   int
+			err error
+		)
+		if reverse {
+			_ = data.SetReadDeadline(deadline)
+			n, err = data.Read(buffer)
+		} else {
+			_ = data.SetWriteDeadline(deadline)
+			n, err = data.Write(buffer)
+		}
+		transferred += int64(n)
+		if err != nil {
+			if E.IsTimeout(err) {
+				break
+			}
+			return transferred, err
+		}
+	}
+	return transferred, nil
+}
+
+func iperf3Cookie() []byte {
+	const alphabet = "abcdefghijklmnopqrstuvwxyz234567"
+	cookie := make([]byte, 37)
+	common.Must1(rand.Read(cookie[:36]))
+	for i := range cookie[:36] {
+		cookie[i] = alphabet[cookie[i]%byte(len(alphabet))]
+	}
+	cookie[36] = 0
+	return cookie
+}
+
+func iperf3ReadState(conn net.Conn) (int8, error) {
+	var state [1]byte
+	_, err := io.ReadFull(conn, state[:])
+	return int8(state[0]), err
+}
+
+func iperf3WriteState(conn net.Conn, state int8) error {
+	_, err := conn.Write([]byte{byte(state)})
+	return err
+}
+
+func iperf3WriteJSON(conn net.Conn, value any) error {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	message := binary.BigEndian.AppendUint32(nil, uint32(len(content)))
+	_, err = conn.Write(append(message, content...))
+	return err
+}
+
+func iperf3ReadJSON(conn net.Conn, value any) error {
+	var length uint32
+	err := binary.Read(conn, binary.BigEndian, &length)
+	if err != nil {
+		return err
+	}
+	if length > 1<<20 {
+		return E.New("results too large: ", length)
+	}
+	content := make([]byte, length)
+	_, err = io.ReadFull(conn, content)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, value)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"strings"
+	"time"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	commandTLSFlagServerName string
+	commandTLSFlagALPN       []string
+	commandTLSFlagTimeout    time.Duration
+)
+
+var commandTLS = &cobra.Command{
+	Use:   "tls <address>",
+	Short: "Show TLS handshake details of a server",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := probeTLS(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandTLS.Flags().StringVar(&commandTLSFlagServerName, "sni", "", "server name, the host of the address is used if empty")
+	commandTLS.Flags().StringSliceVar(&commandTLSFlagALPN, "alpn", []string{"h2", "http/1.1"}, "ALPN protocols to offer")
+	commandTLS.Flags().DurationVar(&commandTLSFlagTimeout, "timeout", C.TCPTimeout, "handshake timeout")
+	commandTools.AddCommand(commandTLS)
+}
+
+func probeTLS(address string) error {
+	destination := M.ParseSocksaddr(address)
+	if destination.Port == 0 {
+		destination.Port = 443
+	}
+	serverName := commandTLSFlagServerName
+	if serverName == "" {
+		serverName = destination.AddrString()
+	}
+	instance, err := createPreStartedClient()
+	if err != nil {
+		return err
+	}
+	defer instance.Close()
+	dialer, err := createDialer(instance, commandToolsFlagOutbound)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(globalCtx, commandTLSFlagTimeout)
+	defer cancel()
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, N.NetworkTCP, destination)
+	if err != nil {
+		return E.Cause(err, "connect")
+	}
+	defer conn.Close()
+	connectTime := time.Since(start)
+	// Verification is done after the handshake, so that details of untrusted servers are still shown.
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:         serverName,
+		NextProtos:         commandTLSFlagALPN,
+		InsecureSkipVerify: true,
+	})
+	err = tlsConn.HandshakeContext(ctx)
+	if err != nil {
+		return E.Cause(err, "TLS handshake")
+	}
+	handshakeTime := time.Since(start) - connectTime
+	state := tlsConn.ConnectionState()
+	fmt.Println("connect time:", connectTime.Round(time.Microsecond))
+	fmt.Println("handshake time:", handshakeTime.Round(time.Microsecond))
+	fmt.Println("version:", tls.VersionName(state.Version))
+	fmt.Println("cipher suite:", tls.CipherSuiteName(state.CipherSuite))
+	if state.NegotiatedProtocol != "" {
+		fmt.Println("alpn:", state.NegotiatedProtocol)
+	} else {
+		fmt.Println("alpn: none")
+	}
+	fmt.Println("server name:", serverName)
+	if state.DidResume {
+		fmt.Println("resumed: true")
+	}
+	fmt.Println("verification:", verifyTLSState(state, serverName))
+	fmt.Println("certificate chain:")
+	for i, certificate := range state.PeerCertificates {
+		fmt.Printf("  [%d] subject: %s\n", i, certificate.Subject)
+		fmt.Printf("      issuer: %s\n", certificate.Issuer)
+		fmt.Printf("      validity: %s - %s\n", certificate.NotBefore.Format(time.DateOnly), certificate.NotAfter.Format(time.DateOnly))
+		if len(certificate.DNSNames) > 0 {
+			fmt.Printf("      dns names: %s\n", strings.Join(certificate.DNSNames, ", "))
+		}
+		fmt.Printf("      signature: %s, public key: %s\n", certificate.SignatureAlgorithm, certificate.PublicKeyAlgorithm)
+	}
+	return nil
+}
+
+func verifyTLSState(state tls.ConnectionState, serverName string) string {
+	if len(state.PeerCertificates) == 0 {
+		return "no certificate"
+	}
+	intermediates := x509.NewCertPool()
+	for _, certificate := range state.PeerCertificates[1:] {
+		intermediates.AddCert(certificate)
+	}
+	_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		DNSName:       serverName,
+		Intermediates: intermediates,
+	})
+	if err != nil {
+		return err.Error()
+	}
+	return "ok"
+}
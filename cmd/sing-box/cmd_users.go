@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/common/json/badjson"
+
+	"github.com/spf13/cobra"
+)
+
+var commandUsers = &cobra.Command{
+	Use:   "users",
+	Short: "Manage inbound users",
+}
+
+var commandUsersMergeFlagWrite bool
+
+var commandUsersMerge = &cobra.Command{
+	Use:   "merge <inbound tag> <users path>",
+	Short: "Merge users into an inbound of the configuration",
+	Long: `Merge users into an inbound of the configuration.
+
+Users are read from the output of "sing-box generate users", a JSON array of
+users, or CSV with a header line of user fields. Users whose name already exists
+in the inbound are skipped. Listed short IDs are added to REALITY.`,
+	Args: cobra.ExactArgs(2),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return completeInbounds(cmd, args, toComplete)
+		}
+		return nil, cobra.ShellCompDirectiveDefault
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		err := mergeUsers(args[0], args[1])
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandUsersMerge.Flags().BoolVarP(&commandUsersMergeFlagWrite, "write", "w", false, "write result to (source) file instead of stdout")
+	commandUsers.AddCommand(commandUsersMerge)
+	mainCommand.AddCommand(commandUsers)
+}
+
+type usersFile struct {
+	Users   []map[string]any `json:"users"`
+	ShortID []string         `json:"short_id,omitempty"`
+}
+
+func mergeUsers(tag string, sourcePath string) error {
+	var (
+		content []byte
+		err     error
+	)
+	if sourcePath == "stdin" {
+		content, err = io.ReadAll(os.Stdin)
+	} else {
+		content, err = os.ReadFile(sourcePath)
+	}
+	if err != nil {
+		return E.Cause(err, "read users")
+	}
+	users, err := parseUsers(content)
+	if err != nil {
+		return E.Cause(err, "parse users")
+	}
+	optionsList, err := readConfig()
+	if err != nil {
+		return err
+	}
+	for _, optionsEntry := range optionsList {
+		for i := range optionsEntry.options.Inbounds {
+			inbound := &optionsEntry.options.Inbounds[i]
+			if inbound.Tag != tag {
+				continue
+			}
+			err = mergeInboundUsers(inbound, users)
+			if err != nil {
+				return E.Cause(err, "merge users into inbound[", tag, "]")
+			}
+			return writeConfigEntry(optionsEntry, commandUsersMergeFlagWrite)
+		}
+	}
+	return E.New("inbound not found: ", tag)
+}
+
+func parseUsers(content []byte) (*usersFile, error) {
+	content = bytes.TrimSpace(content)
+	if len(content) == 0 {
+		return nil, E.New("empty users")
+	}
+	switch content[0] {
+	case '{':
+		var users usersFile
+		err := json.Unmarshal(content, &users)
+		if err != nil {
+			return nil, err
+		}
+		return &users, nil
+	case '[':
+		var users usersFile
+		err := json.Unmarshal(content, &users.Users)
+		if err != nil {
+			return nil, err
+		}
+		return &users, nil
+	default:
+		records, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		var users usersFile
+		header := records[0]
+		for _, record := range records[1:] {
+			user := make(map[string]any)
+			for i, value := range record {
+				if header[i] == "short_id" {
+					users.ShortID = append(users.ShortID, value)
+					continue
+				}
+				user[header[i]] = value
+			}
+			users.Users = append(users.Users, user)
+		}
+		return &users, nil
+	}
+}
+
+func mergeInboundUsers(inbound *option.Inbound, users *usersFile) error {
+	optionsValue := reflect.ValueOf(inbound.Options)
+	if optionsValue.Kind() != reflect.Pointer || optionsValue.Elem().Kind() != reflect.Struct {
+		return E.New("inbound type does not support users: ", inbound.Type)
+	}
+	usersValue := findJSONField(optionsValue.Elem(), "users")
+	if !usersValue.IsValid() || usersValue.Kind() != reflect.Slice {
+		return E.New("inbound type does not support users: ", inbound.Type)
+	}
+	existingNames := make(map[string]bool)
+	for i := 0; i < usersValue.Len(); i++ {
+		existingContent, err := json.Marshal(usersValue.Index(i).Interface())
+		if err != nil {
+			return err
+		}
+		var existing map[string]any
+		err = json.Unmarshal(existingContent, &existing)
+		if err != nil {
+			return err
+		}
+		existingNames[userName(existing)] = true
+	}
+	var newUsers []map[string]any
+	for _, user := range users.Users {
+		name := userName(user)
+		if existingNames[name] {
+			log.Warn("skip existing user: ", name)
+			continue
+		}
+		existingNames[name] = true
+		newUsers = append(newUsers, user)
+	}
+	if len(newUsers) > 0 {
+		newUsersContent, err := json.Marshal(newUsers)
+		if err != nil {
+			return err
+		}
+		newUsersValue := reflect.New(usersValue.Type())
+		decoder := json.NewDecoder(bytes.NewReader(newUsersContent))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(newUsersValue.Interface())
+		if err != nil {
+			return E.Cause(err, "decode users")
+		}
+		usersValue.Set(reflect.AppendSlice(usersValue, newUsersValue.Elem()))
+	}
+	if len(users.ShortID) > 0 {
+		tlsWrapper, isTLS := inbound.Options.(option.InboundTLSOptionsWrapper)
+		if !isTLS {
+			return E.New("inbound type does not support REALITY: ", inbound.Type)
+		}
+		tlsOptions := tlsWrapper.TakeInboundTLSOptions()
+		if tlsOptions == nil || tlsOptions.Reality == nil || !tlsOptions.Reality.Enabled {
+			return E.New("REALITY is not enabled")
+		}
+		for _, shortID := range users.ShortID {
+			if !common.Contains(tlsOptions.Reality.ShortID, shortID) {
+				tlsOptions.Reality.ShortID = append(tlsOptions.Reality.ShortID, shortID)
+			}
+		}
+		tlsWrapper.ReplaceInboundTLSOptions(tlsOptions)
+	}
+	log.Info("merged ", len(newUsers), " users")
+	return nil
+}
+
+// userName matches keys case-insensitively like the JSON decoder, since auth.User has no JSON tags.
+func userName(user map[string]any) string {
+	for key, value := range user {
+		if strings.EqualFold(key, "name") || strings.EqualFold(key, "username") {
+			name, _ := value.(string)
+			return name
+		}
+	}
+	return ""
+}
+
+// findJSONField finds a field by its JSON name, including fields of embedded structs.
+func findJSONField(value reflect.Value, name string) reflect.Value {
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			fieldValue := findJSONField(value.Field(i), name)
+			if fieldValue.IsValid() {
+				return fieldValue
+			}
+			continue
+		}
+		fieldName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if fieldName == name {
+			return value.Field(i)
+		}
+	}
+	return reflect.Value{}
+}
+
+func writeConfigEntry(optionsEntry *OptionsEntry, write bool) error {
+	options, err := badjson.Omitempty(globalCtx, optionsEntry.options)
+	if err != nil {
+		return err
+	}
+	buffer := new(bytes.Buffer)
+	encoder := json.NewEncoder(buffer)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(options)
+	if err != nil {
+		return E.Cause(err, "encode config")
+	}
+	if !write {
+		os.Stdout.Write(buffer.Bytes())
+		return nil
+	}
+	outputPath, _ := filepath.Abs(optionsEntry.path)
+	err = os.WriteFile(optionsEntry.path, buffer.Bytes(), 0o644)
+	if err != nil {
+		return E.Cause(err, "write output")
+	}
+	os.Stderr.WriteString(outputPath + "\n")
+	return nil
+}
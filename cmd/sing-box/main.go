@@ -5,6 +5,7 @@ package main
 import "github.com/sagernet/sing-box/log"
 
 func main() {
+	defer log.RecoverFatal()
 	if err := mainCommand.Execute(); err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,302 @@
+package acl
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/sagernet/fswatch"
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	"github.com/sagernet/sing/service"
+	"github.com/sagernet/sing/service/filemanager"
+
+	"go4.org/netipx"
+)
+
+var _ adapter.InboundACL = (*ACL)(nil)
+
+// ACL filters inbound sources by allow and deny lists. Deny entries always
+// win, and when any allow entry is configured, other sources are rejected.
+type ACL struct {
+	logger       log.ContextLogger
+	allow        []string
+	deny         []string
+	allowPath    string
+	denyPath     string
+	allowRuleSet []adapter.RuleSet
+	denyRuleSet  []adapter.RuleSet
+	watcher      *fswatch.Watcher
+
+	access       sync.RWMutex
+	allowSet     *netipx.IPSet
+	denySet      *netipx.IPSet
+	runtimeAllow []netip.Prefix
+	runtimeDeny  []netip.Prefix
+}
+
+func New(ctx context.Context, logger log.ContextLogger, options option.InboundACLOptions) (*ACL, error) {
+	acl := &ACL{
+		logger: logger,
+		allow:  options.Allow,
+		deny:   options.Deny,
+	}
+	if options.AllowPath != "" {
+		acl.allowPath, _ = filepath.Abs(filemanager.BasePath(ctx, options.AllowPath))
+	}
+	if options.DenyPath != "" {
+		acl.denyPath, _ = filepath.Abs(filemanager.BasePath(ctx, options.DenyPath))
+	}
+	if len(options.AllowRuleSet) > 0 || len(options.DenyRuleSet) > 0 {
+		router := service.FromContext[adapter.Router](ctx)
+		for _, tag := range options.AllowRuleSet {
+			ruleSet, loaded := router.RuleSet(tag)
+			if !loaded {
+				return nil, E.New("parse acl.allow_rule_set: rule-set not found: ", tag)
+			}
+			acl.allowRuleSet = append(acl.allowRuleSet, ruleSet)
+		}
+		for _, tag := range options.DenyRuleSet {
+			ruleSet, loaded := router.RuleSet(tag)
+			if !loaded {
+				return nil, E.New("parse acl.deny_rule_set: rule-set not found: ", tag)
+			}
+			acl.denyRuleSet = append(acl.denyRuleSet, ruleSet)
+		}
+	}
+	err := acl.reload()
+	if err != nil {
+		return nil, err
+	}
+	var watchPaths []string
+	if acl.allowPath != "" {
+		watchPaths = append(watchPaths, acl.allowPath)
+	}
+	if acl.denyPath != "" {
+		watchPaths = append(watchPaths, acl.denyPath)
+	}
+	if len(watchPaths) > 0 {
+		watcher, err := fswatch.NewWatcher(fswatch.Options{
+			Path:   watchPaths,
+			Logger: logger,
+			Callback: func(_ string) {
+				rErr := acl.reload()
+				if rErr != nil {
+					logger.Error(E.Cause(rErr, "reload acl"))
+				} else {
+					logger.Info("acl reloaded")
+				}
+			},
+		})
+		if err != nil {
+			return nil, E.Cause(err, "fswatch: create fsnotify watcher")
+		}
+		acl.watcher = watcher
+	}
+	return acl, nil
+}
+
+func (a *ACL) Start() error {
+	for _, ruleSet := range a.allowRuleSet {
+		ruleSet.IncRef()
+	}
+	for _, ruleSet := range a.denyRuleSet {
+		ruleSet.IncRef()
+	}
+	if a.watcher != nil {
+		return a.watcher.Start()
+	}
+	return nil
+}
+
+func (a *ACL) Close() error {
+	for _, ruleSet := range a.allowRuleSet {
+		ruleSet.DecRef()
+	}
+	for _, ruleSet := range a.denyRuleSet {
+		ruleSet.DecRef()
+	}
+	if a.watcher != nil {
+		return a.watcher.Close()
+	}
+	return nil
+}
+
+func (a *ACL) reload() error {
+	allowSet, err := buildIPSet(a.allow, a.allowPath)
+	if err != nil {
+		return E.Cause(err, "parse acl allow list")
+	}
+	denySet, err := buildIPSet(a.deny, a.denyPath)
+	if err != nil {
+		return E.Cause(err, "parse acl deny list")
+	}
+	a.access.Lock()
+	a.allowSet = allowSet
+	a.denySet = denySet
+	a.access.Unlock()
+	return nil
+}
+
+func (a *ACL) Allow(source netip.Addr) bool {
+	source = source.Unmap()
+	a.access.RLock()
+	denied := a.denySet.Contains(source) || containsAddr(a.runtimeDeny, source)
+	hasAllow := len(a.allowSet.Prefixes()) > 0 || len(a.runtimeAllow) > 0 || len(a.allowRuleSet) > 0
+	allowed := a.allowSet.Contains(source) || containsAddr(a.runtimeAllow, source)
+	a.access.RUnlock()
+	if denied || len(a.denyRuleSet) > 0 && matchRuleSet(a.denyRuleSet, source) {
+		return false
+	}
+	if !hasAllow || allowed {
+		return true
+	}
+	return matchRuleSet(a.allowRuleSet, source)
+}
+
+func (a *ACL) Entries() adapter.InboundACLEntries {
+	a.access.RLock()
+	defer a.access.RUnlock()
+	return adapter.InboundACLEntries{
+		Allow:        a.allowSet.Prefixes(),
+		Deny:         a.denySet.Prefixes(),
+		RuntimeAllow: append([]netip.Prefix(nil), a.runtimeAllow...),
+		RuntimeDeny:  append([]netip.Prefix(nil), a.runtimeDeny...),
+		AllowRuleSet: ruleSetNames(a.allowRuleSet),
+		DenyRuleSet:  ruleSetNames(a.denyRuleSet),
+	}
+}
+
+func (a *ACL) AddRuntime(allow []netip.Prefix, deny []netip.Prefix) {
+	a.access.Lock()
+	defer a.access.Unlock()
+	a.runtimeAllow = appendPrefixes(a.runtimeAllow, allow)
+	a.runtimeDeny = appendPrefixes(a.runtimeDeny, deny)
+}
+
+func (a *ACL) RemoveRuntime(allow []netip.Prefix, deny []netip.Prefix) {
+	a.access.Lock()
+	defer a.access.Unlock()
+	a.runtimeAllow = removePrefixes(a.runtimeAllow, allow)
+	a.runtimeDeny = removePrefixes(a.runtimeDeny, deny)
+}
+
+func buildIPSet(entries []string, path string) (*netipx.IPSet, error) {
+	var builder netipx.IPSetBuilder
+	for i, entry := range entries {
+		prefix, err := ParsePrefix(entry)
+		if err != nil {
+			return nil, E.Cause(err, "parse [", i, "]")
+		}
+		builder.AddPrefix(prefix)
+	}
+	if path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		var lineNumber int
+		for scanner.Scan() {
+			lineNumber++
+			line := scanner.Text()
+			if index := strings.IndexByte(line, '#'); index >= 0 {
+				line = line[:index]
+			}
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			prefix, err := ParsePrefix(line)
+			if err != nil {
+				return nil, E.Cause(err, path, ":", lineNumber)
+			}
+			builder.AddPrefix(prefix)
+		}
+		if err = scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return builder.IPSet()
+}
+
+// ParsePrefix parses a CIDR prefix or a single address.
+func ParsePrefix(entry string) (netip.Prefix, error) {
+	prefix, err := netip.ParsePrefix(entry)
+	if err == nil {
+		return prefix.Masked(), nil
+	}
+	addr, addrErr := netip.ParseAddr(entry)
+	if addrErr == nil {
+		return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+	}
+	return netip.Prefix{}, err
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchRuleSet(ruleSets []adapter.RuleSet, source netip.Addr) bool {
+	metadata := adapter.InboundContext{
+		Source:            M.SocksaddrFrom(source, 0),
+		IPCIDRMatchSource: true,
+	}
+	for _, ruleSet := range ruleSets {
+		metadata.ResetRuleCache()
+		if ruleSet.Match(&metadata) {
+			return true
+		}
+	}
+	return false
+}
+
+func ruleSetNames(ruleSets []adapter.RuleSet) []string {
+	names := make([]string, 0, len(ruleSets))
+	for _, ruleSet := range ruleSets {
+		names = append(names, ruleSet.Name())
+	}
+	return names
+}
+
+func appendPrefixes(prefixes []netip.Prefix, added []netip.Prefix) []netip.Prefix {
+	for _, prefix := range added {
+		prefix = prefix.Masked()
+		if !containsPrefix(prefixes, prefix) {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return prefixes
+}
+
+func removePrefixes(prefixes []netip.Prefix, removed []netip.Prefix) []netip.Prefix {
+	filtered := prefixes[:0]
+	for _, prefix := range prefixes {
+		if !containsPrefix(removed, prefix) {
+			filtered = append(filtered, prefix)
+		}
+	}
+	return filtered
+}
+
+func containsPrefix(prefixes []netip.Prefix, prefix netip.Prefix) bool {
+	for _, it := range prefixes {
+		if it.Masked() == prefix.Masked() {
+			return true
+		}
+	}
+	return false
+}
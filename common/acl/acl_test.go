@@ -0,0 +1,51 @@
+package acl
+
+import (
+	"context"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestACL(t *testing.T) {
+	t.Parallel()
+	allowPath := filepath.Join(t.TempDir(), "allow.txt")
+	require.NoError(t, os.WriteFile(allowPath, []byte("# office\n198.51.100.0/24\n\n2001:db8::1\n"), 0o644))
+	acl, err := New(context.Background(), log.NewNOPFactory().Logger(), option.InboundACLOptions{
+		Allow:     []string{"192.0.2.0/24"},
+		Deny:      []string{"192.0.2.128/25"},
+		AllowPath: allowPath,
+	})
+	require.NoError(t, err)
+	require.True(t, acl.Allow(netip.MustParseAddr("192.0.2.1")))
+	require.True(t, acl.Allow(netip.MustParseAddr("::ffff:198.51.100.1")))
+	require.True(t, acl.Allow(netip.MustParseAddr("2001:db8::1")))
+	require.False(t, acl.Allow(netip.MustParseAddr("192.0.2.129")))
+	require.False(t, acl.Allow(netip.MustParseAddr("203.0.113.1")))
+
+	acl.AddRuntime([]netip.Prefix{netip.MustParsePrefix("203.0.113.0/24")}, []netip.Prefix{netip.MustParsePrefix("192.0.2.1/32")})
+	require.True(t, acl.Allow(netip.MustParseAddr("203.0.113.1")))
+	require.False(t, acl.Allow(netip.MustParseAddr("192.0.2.1")))
+	acl.RemoveRuntime(nil, []netip.Prefix{netip.MustParsePrefix("192.0.2.1/32")})
+	require.True(t, acl.Allow(netip.MustParseAddr("192.0.2.1")))
+
+	require.NoError(t, os.WriteFile(allowPath, []byte("not a prefix\n"), 0o644))
+	require.Error(t, acl.reload())
+	require.True(t, acl.Allow(netip.MustParseAddr("198.51.100.1")))
+}
+
+func TestACLDenyOnly(t *testing.T) {
+	t.Parallel()
+	acl, err := New(context.Background(), log.NewNOPFactory().Logger(), option.InboundACLOptions{
+		Deny: []string{"192.0.2.1"},
+	})
+	require.NoError(t, err)
+	require.False(t, acl.Allow(netip.MustParseAddr("192.0.2.1")))
+	require.True(t, acl.Allow(netip.MustParseAddr("192.0.2.2")))
+}
@@ -0,0 +1,53 @@
+package acl
+
+import (
+	"sync"
+
+	"github.com/sagernet/sing-box/adapter"
+)
+
+var _ adapter.InboundACLManager = (*Manager)(nil)
+
+type Manager struct {
+	access sync.RWMutex
+	acls   map[string]adapter.InboundACL
+}
+
+func NewManager() *Manager {
+	return &Manager{
+		acls: make(map[string]adapter.InboundACL),
+	}
+}
+
+func (m *Manager) Register(inbound string, acl adapter.InboundACL) {
+	m.access.Lock()
+	defer m.access.Unlock()
+	m.acls[inbound] = acl
+}
+
+// Unregister removes the ACL only if it is still the registered one, so a
+// replaced inbound closing late does not drop the ACL of its successor.
+func (m *Manager) Unregister(inbound string, acl adapter.InboundACL) {
+	m.access.Lock()
+	defer m.access.Unlock()
+	if m.acls[inbound] == acl {
+		delete(m.acls, inbound)
+	}
+}
+
+func (m *Manager) Get(inbound string) (adapter.InboundACL, bool) {
+	m.access.RLock()
+	defer m.access.RUnlock()
+	acl, loaded := m.acls[inbound]
+	return acl, loaded
+}
+
+func (m *Manager) List() map[string]adapter.InboundACL {
+	m.access.RLock()
+	defer m.access.RUnlock()
+	acls := make(map[string]adapter.InboundACL, len(m.acls))
+	for inbound, acl := range m.acls {
+		acls[inbound] = acl
+	}
+	return acls
+}
@@ -0,0 +1,162 @@
+package authprovider
+
+import (
+	std_bufio "bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/sagernet/sing-box/common/masque"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/auth"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	sHTTP "github.com/sagernet/sing/protocol/http"
+	"github.com/sagernet/sing/protocol/socks"
+)
+
+var headerEnd = []byte("\r\n\r\n")
+
+const proxyAuthenticate = `Basic realm="sing-box" charset="UTF-8"`
+
+// HandleHTTPConnectionEx is http.HandleConnectionEx verifying users with
+// authenticator and accepting connect-udp upgrades (RFC 9298) as the first
+// request. With an auth provider, the credentials of the first request are
+// verified, and later requests on the connection must repeat them. The
+// request header must fit in the buffer of reader.
+func HandleHTTPConnectionEx(
+	ctx context.Context,
+	conn net.Conn,
+	reader *std_bufio.Reader,
+	authenticator *Authenticator,
+	handler socks.HandlerEx,
+	source M.Socksaddr,
+	onClose N.CloseHandlerFunc,
+) error {
+	var request *http.Request
+	if authenticator.provider != nil || peekUpgradeRequest(reader) {
+		header, err := peekHTTPHeader(reader)
+		if err == nil {
+			request, err = sHTTP.ReadRequest(std_bufio.NewReader(bytes.NewReader(header)))
+		}
+		if err != nil {
+			// without an auth provider, leave large headers to the HTTP handler
+			if authenticator.provider != nil || !errors.Is(err, std_bufio.ErrBufferFull) {
+				return E.Cause(err, "read http request")
+			}
+		} else if masque.IsUDPRequest(request) {
+			common.Must1(reader.Discard(len(header)))
+			return handleHTTPConnectUDP(ctx, conn, reader, request, authenticator, handler, source, onClose)
+		}
+	}
+	if authenticator.provider == nil {
+		return sHTTP.HandleConnectionEx(ctx, conn, reader, authenticator.static, handler, source, onClose)
+	}
+	username, password, loaded := parseProxyAuthorization(request.Header.Get("Proxy-Authorization"))
+	if !loaded || !authenticator.Verify(ctx, username, password) {
+		err := writeProxyAuthRequired(conn, request)
+		if err != nil {
+			return err
+		}
+		if !loaded {
+			return E.New("http: authentication failed, no Proxy-Authorization header")
+		}
+		return E.New("http: authentication failed, username=", username)
+	}
+	// The request is left in reader and verified again against the
+	// credentials, which also sets the user to the context.
+	return sHTTP.HandleConnectionEx(ctx, conn, reader, auth.NewAuthenticator([]auth.User{{
+		Username: username,
+		Password: password,
+	}}), handler, source, onClose)
+}
+
+func handleHTTPConnectUDP(
+	ctx context.Context,
+	conn net.Conn,
+	reader *std_bufio.Reader,
+	request *http.Request,
+	authenticator *Authenticator,
+	handler socks.HandlerEx,
+	source M.Socksaddr,
+	onClose N.CloseHandlerFunc,
+) error {
+	if authenticator.Enabled() {
+		username, password, loaded := parseProxyAuthorization(request.Header.Get("Proxy-Authorization"))
+		if !loaded || !authenticator.Verify(ctx, username, password) {
+			err := writeProxyAuthRequired(conn, request)
+			if err != nil {
+				return err
+			}
+			if !loaded {
+				return E.New("http: authentication failed, no Proxy-Authorization header")
+			}
+			return E.New("http: authentication failed, username=", username)
+		}
+		ctx = auth.ContextWithUser(ctx, username)
+	}
+	destination, err := masque.ParseUDPTarget(request.URL)
+	if err != nil {
+		return E.Errors(err, common.Error(conn.Write([]byte("HTTP/1.1 400 Bad Request\r\nContent-Length: 0\r\n\r\n"))))
+	}
+	if sourceAddress := sHTTP.SourceAddress(request); sourceAddress.IsValid() {
+		source = sourceAddress
+	}
+	_, err = conn.Write([]byte("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: " + masque.UpgradeToken + "\r\n" + masque.HeaderCapsuleProtocol + ": ?1\r\n\r\n"))
+	if err != nil {
+		return E.Cause(err, "write http response")
+	}
+	handler.NewPacketConnectionEx(ctx, masque.NewStreamPacketConn(conn, reader, destination), source, destination, onClose)
+	return nil
+}
+
+func writeProxyAuthRequired(conn net.Conn, request *http.Request) error {
+	response := &http.Response{
+		StatusCode: http.StatusProxyAuthRequired,
+		Status:     http.StatusText(http.StatusProxyAuthRequired),
+		Proto:      request.Proto,
+		ProtoMajor: request.ProtoMajor,
+		ProtoMinor: request.ProtoMinor,
+		Header: http.Header{
+			"Proxy-Authenticate": []string{proxyAuthenticate},
+		},
+	}
+	return response.Write(conn)
+}
+
+// peekUpgradeRequest checks whether the request may be an HTTP/1.1 Upgrade,
+// which is always a GET request.
+func peekUpgradeRequest(reader *std_bufio.Reader) bool {
+	method, err := reader.Peek(4)
+	return err == nil && string(method) == "GET "
+}
+
+func peekHTTPHeader(reader *std_bufio.Reader) ([]byte, error) {
+	for size := 1; ; size = reader.Buffered() + 1 {
+		content, err := reader.Peek(size)
+		if err != nil {
+			return nil, err
+		}
+		content, _ = reader.Peek(reader.Buffered())
+		index := bytes.Index(content, headerEnd)
+		if index >= 0 {
+			return content[:index+len(headerEnd)], nil
+		}
+	}
+}
+
+// parseProxyAuthorization decodes the credentials like http.HandleConnectionEx,
+// so that both agree on them.
+func parseProxyAuthorization(authorization string) (username string, password string, loaded bool) {
+	if !strings.HasPrefix(authorization, "Basic ") {
+		return
+	}
+	userPassword, _ := base64.URLEncoding.DecodeString(authorization[6:])
+	username, password, loaded = strings.Cut(string(userPassword), ":")
+	return
+}
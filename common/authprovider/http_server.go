@@ -0,0 +1,145 @@
+package authprovider
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/sagernet/sing-box/common/masque"
+	"github.com/sagernet/sing-box/transport/v2rayhttp"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/auth"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/protocol/socks"
+
+	"golang.org/x/net/http2"
+)
+
+// HTTP2ExtendedConnect reports whether extended CONNECT (RFC 8441), which is
+// required by connect-udp over HTTP/2, is enabled. x/net only enables it with
+// GODEBUG=http2xconnect=1, which is checked the same way here.
+var HTTP2ExtendedConnect = strings.Contains(os.Getenv("GODEBUG"), "http2xconnect=1")
+
+// WarnHTTP2ExtendedConnect warns if h2 is offered by TLS ALPN while extended
+// CONNECT is disabled.
+func WarnHTTP2ExtendedConnect(logger logger.Logger, alpn []string) {
+	if !HTTP2ExtendedConnect && common.Contains(alpn, http2.NextProtoTLS) {
+		logger.Warn("connect-udp over HTTP/2 requires the environment variable GODEBUG=http2xconnect=1")
+	}
+}
+
+// HandleHTTP2ConnectionEx serves CONNECT and connect-udp requests of an
+// HTTP/2 connection, for TLS connections negotiated h2. Other requests are
+// rejected.
+func HandleHTTP2ConnectionEx(
+	ctx context.Context,
+	conn net.Conn,
+	authenticator *Authenticator,
+	handler socks.HandlerEx,
+	source M.Socksaddr,
+	onClose N.CloseHandlerFunc,
+) {
+	server := &http2.Server{}
+	server.ServeConn(conn, &http2.ServeConnOpts{
+		Context: ctx,
+		Handler: &HTTPHandler{
+			Authenticator: authenticator,
+			Handler:       handler,
+			Source:        source,
+		},
+	})
+	conn.Close()
+	if onClose != nil {
+		onClose(nil)
+	}
+}
+
+// HTTPHandler serves CONNECT and connect-udp requests of HTTP/2 and HTTP/3.
+type HTTPHandler struct {
+	Authenticator *Authenticator
+	Handler       socks.HandlerEx
+	// Source is the source of all requests, or the remote address of each
+	// request is used if not valid.
+	Source M.Socksaddr
+	// NewPacketConn creates the connection of connect-udp requests, with
+	// DATAGRAM capsules on the request stream if nil.
+	NewPacketConn func(writer http.ResponseWriter, conn net.Conn, destination M.Socksaddr) N.PacketConn
+}
+
+func (h *HTTPHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	ctx := request.Context()
+	source := h.Source
+	if !source.IsValid() {
+		source = M.ParseSocksaddr(request.RemoteAddr).Unwrap()
+	}
+	if h.Authenticator.Enabled() {
+		username, password, loaded := parseProxyAuthorization(request.Header.Get("Proxy-Authorization"))
+		if !loaded || !h.Authenticator.Verify(ctx, username, password) {
+			writer.Header().Set("Proxy-Authenticate", proxyAuthenticate)
+			writer.WriteHeader(http.StatusProxyAuthRequired)
+			if !loaded {
+				h.badRequest(ctx, source, E.New("http: authentication failed, no Proxy-Authorization header"))
+			} else {
+				h.badRequest(ctx, source, E.New("http: authentication failed, username=", username))
+			}
+			return
+		}
+		ctx = auth.ContextWithUser(ctx, username)
+	}
+	var (
+		destination M.Socksaddr
+		isUDP       bool
+	)
+	if masque.IsUDPRequest(request) {
+		var err error
+		destination, err = masque.ParseUDPTarget(request.URL)
+		if err != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			h.badRequest(ctx, source, err)
+			return
+		}
+		isUDP = true
+		writer.Header().Set(masque.HeaderCapsuleProtocol, "?1")
+	} else if request.Method == http.MethodConnect {
+		destination = M.ParseSocksaddr(request.Host).Unwrap()
+		if destination.Port == 0 {
+			destination.Port = 443
+		}
+	} else {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		h.badRequest(ctx, source, E.New("http2: unsupported request: ", request.Method, " ", request.URL))
+		return
+	}
+	writer.WriteHeader(http.StatusOK)
+	writer.(http.Flusher).Flush()
+	done := make(chan struct{})
+	conn := v2rayhttp.NewHTTP2Wrapper(&v2rayhttp.ServerHTTPConn{
+		HTTP2Conn: v2rayhttp.NewHTTPConn(request.Body, writer),
+		Flusher:   writer.(http.Flusher),
+	})
+	onClose := N.OnceClose(func(it error) {
+		close(done)
+	})
+	if isUDP {
+		var packetConn N.PacketConn
+		if h.NewPacketConn != nil {
+			packetConn = h.NewPacketConn(writer, conn, destination)
+		} else {
+			packetConn = masque.NewStreamPacketConn(conn, nil, destination)
+		}
+		h.Handler.NewPacketConnectionEx(ctx, packetConn, source, destination, onClose)
+	} else {
+		h.Handler.NewConnectionEx(ctx, conn, source, destination, onClose)
+	}
+	<-done
+	conn.CloseWrapper()
+}
+
+func (h *HTTPHandler) badRequest(ctx context.Context, source M.Socksaddr, err error) {
+	h.Authenticator.logger.ErrorContext(ctx, E.Cause(err, "process connection from ", source))
+}
@@ -0,0 +1,151 @@
+package authprovider
+
+import (
+	"context"
+	"crypto/sha256"
+	"time"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/auth"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	"github.com/sagernet/sing/contrab/freelru"
+	"github.com/sagernet/sing/contrab/maphash"
+)
+
+const (
+	defaultTimeout          = 10 * time.Second
+	defaultCacheTTL         = 5 * time.Minute
+	defaultNegativeCacheTTL = 30 * time.Second
+	cacheCapacity           = 4096
+)
+
+// Provider verifies credentials of inbound users against an external user
+// database. An error means the database could not be queried, and is not
+// cached.
+type Provider interface {
+	Authenticate(ctx context.Context, username string, password string) (bool, error)
+}
+
+func New(ctx context.Context, logger log.ContextLogger, options option.AuthProviderOptions) (Provider, error) {
+	timeout := time.Duration(options.Timeout)
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	var (
+		provider Provider
+		err      error
+	)
+	switch options.Type {
+	case C.AuthProviderTypeHTTP:
+		provider, err = NewHTTP(ctx, timeout, options.HTTPOptions)
+	case C.AuthProviderTypeRADIUS:
+		provider, err = NewRADIUS(ctx, timeout, options.RADIUSOptions)
+	case C.AuthProviderTypeLDAP:
+		provider, err = NewLDAP(ctx, logger, timeout, options.LDAPOptions)
+	default:
+		err = E.New("unknown auth provider type: ", options.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	negativeCacheTTL := time.Duration(options.NegativeCacheTTL)
+	if negativeCacheTTL == 0 {
+		negativeCacheTTL = defaultNegativeCacheTTL
+	}
+	return &cachedProvider{
+		provider:         provider,
+		cache:            newCache(),
+		cacheTTL:         CacheTTL(options),
+		negativeCacheTTL: negativeCacheTTL,
+	}, nil
+}
+
+// CacheTTL returns the time accepted credentials are cached for.
+func CacheTTL(options option.AuthProviderOptions) time.Duration {
+	if options.CacheTTL == 0 {
+		return defaultCacheTTL
+	}
+	return time.Duration(options.CacheTTL)
+}
+
+type cachedProvider struct {
+	provider         Provider
+	cache            freelru.Cache[[sha256.Size]byte, bool]
+	cacheTTL         time.Duration
+	negativeCacheTTL time.Duration
+}
+
+func (p *cachedProvider) Authenticate(ctx context.Context, username string, password string) (bool, error) {
+	hash := sha256.New()
+	hash.Write([]byte(username))
+	hash.Write([]byte{0})
+	hash.Write([]byte(password))
+	var key [sha256.Size]byte
+	hash.Sum(key[:0])
+	accepted, cached := p.cache.Get(key)
+	if cached {
+		return accepted, nil
+	}
+	accepted, err := p.provider.Authenticate(ctx, username, password)
+	if err != nil {
+		return false, err
+	}
+	if accepted {
+		p.cache.AddWithLifetime(key, true, p.cacheTTL)
+	} else {
+		p.cache.AddWithLifetime(key, false, p.negativeCacheTTL)
+	}
+	return accepted, nil
+}
+
+func newCache() freelru.Cache[[sha256.Size]byte, bool] {
+	return common.Must1(freelru.NewSynced[[sha256.Size]byte, bool](cacheCapacity, maphash.NewHasher[[sha256.Size]byte]().Hash32))
+}
+
+// Authenticator verifies inbound users with the static users first, then
+// with the auth provider if configured.
+type Authenticator struct {
+	logger   logger.ContextLogger
+	static   *auth.Authenticator
+	provider Provider
+}
+
+func NewAuthenticator(ctx context.Context, logger log.ContextLogger, users []auth.User, options *option.AuthProviderOptions) (*Authenticator, error) {
+	authenticator := &Authenticator{
+		logger: logger,
+		static: auth.NewAuthenticator(users),
+	}
+	if options != nil {
+		provider, err := New(ctx, logger, *options)
+		if err != nil {
+			return nil, E.Cause(err, "create auth provider")
+		}
+		authenticator.provider = provider
+	}
+	return authenticator, nil
+}
+
+// Enabled returns whether any user is configured, otherwise authentication
+// is not required.
+func (a *Authenticator) Enabled() bool {
+	return a.static != nil || a.provider != nil
+}
+
+func (a *Authenticator) Verify(ctx context.Context, username string, password string) bool {
+	if a.static != nil && a.static.Verify(username, password) {
+		return true
+	}
+	if a.provider == nil {
+		return false
+	}
+	accepted, err := a.provider.Authenticate(ctx, username, password)
+	if err != nil {
+		a.logger.ErrorContext(ctx, E.Cause(err, "auth provider: verify user ", username))
+		return false
+	}
+	return accepted
+}
@@ -0,0 +1,95 @@
+package authprovider
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/dialer"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+	M "github.com/sagernet/sing/common/metadata"
+	"github.com/sagernet/sing/common/ntp"
+)
+
+var _ Provider = (*HTTPProvider)(nil)
+
+// HTTPProvider posts the credentials as JSON object {"username", "password"}
+// to the URL. A 2xx status accepts the user, 401 and 403 reject it.
+type HTTPProvider struct {
+	url     string
+	headers http.Header
+	client  *http.Client
+}
+
+func NewHTTP(ctx context.Context, timeout time.Duration, options option.HTTPAuthProviderOptions) (*HTTPProvider, error) {
+	if options.URL == "" {
+		return nil, E.New("missing URL")
+	}
+	serverURL, err := url.Parse(options.URL)
+	if err != nil {
+		return nil, E.Cause(err, "parse URL")
+	}
+	outboundDialer, err := dialer.New(ctx, options.DialerOptions, M.IsDomainName(serverURL.Hostname()))
+	if err != nil {
+		return nil, err
+	}
+	return &HTTPProvider{
+		url:     options.URL,
+		headers: options.Headers.Build(),
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				ForceAttemptHTTP2:   true,
+				TLSHandshakeTimeout: C.TCPTimeout,
+				DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+					return outboundDialer.DialContext(ctx, network, M.ParseSocksaddr(addr))
+				},
+				TLSClientConfig: &tls.Config{
+					Time:    ntp.TimeFuncFromContext(ctx),
+					RootCAs: adapter.RootPoolFromContext(ctx),
+				},
+			},
+		},
+	}, nil
+}
+
+func (p *HTTPProvider) Authenticate(ctx context.Context, username string, password string) (bool, error) {
+	content, err := json.Marshal(map[string]string{
+		"username": username,
+		"password": password,
+	})
+	if err != nil {
+		return false, err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(content))
+	if err != nil {
+		return false, err
+	}
+	for key, values := range p.headers {
+		request.Header[key] = values
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := p.client.Do(request)
+	if err != nil {
+		return false, err
+	}
+	io.Copy(io.Discard, response.Body)
+	response.Body.Close()
+	switch {
+	case response.StatusCode >= 200 && response.StatusCode < 300:
+		return true, nil
+	case response.StatusCode == http.StatusUnauthorized, response.StatusCode == http.StatusForbidden:
+		return false, nil
+	default:
+		return false, E.New("unexpected status: ", response.Status)
+	}
+}
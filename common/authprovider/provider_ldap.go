@@ -0,0 +1,252 @@
+package authprovider
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/common/dialer"
+	"github.com/sagernet/sing-box/common/tls"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/cryptobyte/asn1"
+)
+
+const (
+	ldapDefaultPort    = 389
+	ldapDefaultTLSPort = 636
+
+	ldapTagBindRequest    = asn1.Tag(0x60)
+	ldapTagBindResponse   = asn1.Tag(0x61)
+	ldapTagSimpleAuth     = asn1.Tag(0x80)
+	ldapTagUnbindRequest  = 0x42
+	ldapResultSuccess     = 0
+	ldapResultInvalidCred = 49
+	ldapMaxMessageLength  = 1 << 20
+)
+
+var _ Provider = (*LDAPProvider)(nil)
+
+// LDAPProvider verifies users with a simple bind as the DN from the bind_dn
+// template, in which {username} is replaced by the escaped username.
+type LDAPProvider struct {
+	dialer  N.Dialer
+	server  M.Socksaddr
+	bindDN  string
+	timeout time.Duration
+}
+
+func NewLDAP(ctx context.Context, logger log.ContextLogger, timeout time.Duration, options option.LDAPAuthProviderOptions) (*LDAPProvider, error) {
+	server := options.ServerOptions.Build()
+	if !server.IsValid() {
+		return nil, E.New("missing server")
+	}
+	tlsOptions := common.PtrValueOrDefault(options.TLS)
+	if server.Port == 0 {
+		if tlsOptions.Enabled {
+			server.Port = ldapDefaultTLSPort
+		} else {
+			server.Port = ldapDefaultPort
+		}
+	}
+	if !strings.Contains(options.BindDN, "{username}") {
+		return nil, E.New("missing {username} in bind_dn")
+	}
+	outboundDialer, err := dialer.New(ctx, options.DialerOptions, server.IsFqdn())
+	if err != nil {
+		return nil, err
+	}
+	outboundDialer, err = tls.NewDialerFromOptions(ctx, logger, outboundDialer, server.AddrString(), tlsOptions)
+	if err != nil {
+		return nil, err
+	}
+	return &LDAPProvider{
+		dialer:  outboundDialer,
+		server:  server,
+		bindDN:  options.BindDN,
+		timeout: timeout,
+	}, nil
+}
+
+func (p *LDAPProvider) Authenticate(ctx context.Context, username string, password string) (bool, error) {
+	// A simple bind with an empty password is an unauthenticated bind,
+	// which servers accept for any DN.
+	if username == "" || password == "" {
+		return false, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	conn, err := p.dialer.DialContext(ctx, N.NetworkTCP, p.server)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	var builder cryptobyte.Builder
+	builder.AddASN1(asn1.SEQUENCE, func(builder *cryptobyte.Builder) {
+		builder.AddASN1Int64(1)
+		builder.AddASN1(ldapTagBindRequest, func(builder *cryptobyte.Builder) {
+			builder.AddASN1Int64(3)
+			builder.AddASN1OctetString([]byte(strings.ReplaceAll(p.bindDN, "{username}", escapeDN(username))))
+			builder.AddASN1(ldapTagSimpleAuth, func(builder *cryptobyte.Builder) {
+				builder.AddBytes([]byte(password))
+			})
+		})
+	})
+	request, err := builder.Bytes()
+	if err != nil {
+		return false, err
+	}
+	_, err = conn.Write(request)
+	if err != nil {
+		return false, err
+	}
+	resultCode, diagnosticMessage, err := readLDAPBindResponse(bufio.NewReader(conn))
+	if err != nil {
+		return false, E.Cause(err, "read bind response")
+	}
+	conn.Write([]byte{byte(asn1.SEQUENCE), 0x05, byte(asn1.INTEGER), 0x01, 0x02, ldapTagUnbindRequest, 0x00})
+	switch resultCode {
+	case ldapResultSuccess:
+		return true, nil
+	case ldapResultInvalidCred:
+		return false, nil
+	default:
+		return false, E.New("bind failed with result code ", resultCode, ": ", diagnosticMessage)
+	}
+}
+
+func readLDAPBindResponse(reader *bufio.Reader) (int, string, error) {
+	tag, message, err := readBER(reader)
+	if err != nil {
+		return 0, "", err
+	}
+	if tag != byte(asn1.SEQUENCE) {
+		return 0, "", E.New("unexpected tag: ", tag)
+	}
+	var messageID int64
+	message, ok := readBERInteger(message, &messageID)
+	if !ok || messageID != 1 {
+		return 0, "", E.New("unexpected message ID")
+	}
+	tag, response, _, ok := parseBER(message)
+	if !ok || tag != byte(ldapTagBindResponse) {
+		return 0, "", E.New("unexpected protocol operation")
+	}
+	tag, resultCode, response, ok := parseBER(response)
+	if !ok || tag != byte(asn1.ENUM) || len(resultCode) == 0 || len(resultCode) > 4 {
+		return 0, "", E.New("invalid result code")
+	}
+	var code int
+	for _, b := range resultCode {
+		code = code<<8 | int(b)
+	}
+	var diagnosticMessage []byte
+	_, _, response, ok = parseBER(response)
+	if ok {
+		_, diagnosticMessage, _, _ = parseBER(response)
+	}
+	return code, string(diagnosticMessage), nil
+}
+
+// readBER reads an element with BER length encoding, as servers such as
+// Active Directory use non-minimal lengths that cryptobyte rejects.
+func readBER(reader *bufio.Reader) (byte, []byte, error) {
+	tag, err := reader.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	lengthByte, err := reader.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length := int(lengthByte)
+	if lengthByte&0x80 != 0 {
+		lengthLength := int(lengthByte & 0x7f)
+		if lengthLength == 0 || lengthLength > 4 {
+			return 0, nil, E.New("unsupported length")
+		}
+		length = 0
+		for i := 0; i < lengthLength; i++ {
+			lengthByte, err = reader.ReadByte()
+			if err != nil {
+				return 0, nil, err
+			}
+			length = length<<8 | int(lengthByte)
+		}
+	}
+	if length > ldapMaxMessageLength {
+		return 0, nil, E.New("message too large")
+	}
+	content := make([]byte, length)
+	_, err = io.ReadFull(reader, content)
+	if err != nil {
+		return 0, nil, err
+	}
+	return tag, content, nil
+}
+
+func parseBER(data []byte) (tag byte, content []byte, rest []byte, ok bool) {
+	if len(data) < 2 {
+		return
+	}
+	tag = data[0]
+	length := int(data[1])
+	data = data[2:]
+	if length&0x80 != 0 {
+		lengthLength := length & 0x7f
+		if lengthLength == 0 || lengthLength > 4 || len(data) < lengthLength {
+			return
+		}
+		length = 0
+		for _, b := range data[:lengthLength] {
+			length = length<<8 | int(b)
+		}
+		data = data[lengthLength:]
+	}
+	if length > len(data) {
+		return
+	}
+	return tag, data[:length], data[length:], true
+}
+
+func readBERInteger(data []byte, value *int64) ([]byte, bool) {
+	tag, content, rest, ok := parseBER(data)
+	if !ok || tag != byte(asn1.INTEGER) || len(content) == 0 || len(content) > 8 {
+		return nil, false
+	}
+	*value = 0
+	for _, b := range content {
+		*value = *value<<8 | int64(b)
+	}
+	return rest, true
+}
+
+// escapeDN escapes an attribute value in a distinguished name (RFC 4514).
+func escapeDN(value string) string {
+	var builder strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == ',' || c == '+' || c == '"' || c == '\\' || c == '<' || c == '>' || c == ';' || c == '=',
+			c == ' ' && (i == 0 || i == len(value)-1),
+			c == '#' && i == 0:
+			builder.WriteByte('\\')
+			builder.WriteByte(c)
+		case c == 0:
+			builder.WriteString("\\00")
+		default:
+			builder.WriteByte(c)
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,189 @@
+package authprovider
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/binary"
+	"time"
+
+	"github.com/sagernet/sing-box/common/dialer"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+const (
+	radiusCodeAccessRequest = 1
+	radiusCodeAccessAccept  = 2
+	radiusCodeAccessReject  = 3
+
+	radiusAttributeUserName             = 1
+	radiusAttributeUserPassword         = 2
+	radiusAttributeNASIdentifier        = 32
+	radiusAttributeMessageAuthenticator = 80
+
+	radiusDefaultPort = 1812
+	radiusRetries     = 3
+)
+
+var _ Provider = (*RADIUSProvider)(nil)
+
+// RADIUSProvider sends Access-Request packets with PAP credentials (RFC 2865),
+// signed with Message-Authenticator.
+type RADIUSProvider struct {
+	dialer        N.Dialer
+	server        M.Socksaddr
+	secret        []byte
+	nasIdentifier string
+	timeout       time.Duration
+}
+
+func NewRADIUS(ctx context.Context, timeout time.Duration, options option.RADIUSAuthProviderOptions) (*RADIUSProvider, error) {
+	server := options.ServerOptions.Build()
+	if !server.IsValid() {
+		return nil, E.New("missing server")
+	}
+	if server.Port == 0 {
+		server.Port = radiusDefaultPort
+	}
+	if options.Secret == "" {
+		return nil, E.New("missing secret")
+	}
+	outboundDialer, err := dialer.New(ctx, options.DialerOptions, server.IsFqdn())
+	if err != nil {
+		return nil, err
+	}
+	nasIdentifier := options.NASIdentifier
+	if nasIdentifier == "" {
+		nasIdentifier = "sing-box"
+	}
+	return &RADIUSProvider{
+		dialer:        outboundDialer,
+		server:        server,
+		secret:        []byte(options.Secret),
+		nasIdentifier: nasIdentifier,
+		timeout:       timeout,
+	}, nil
+}
+
+func (p *RADIUSProvider) Authenticate(ctx context.Context, username string, password string) (bool, error) {
+	if len(username) > 253 || len(password) > 128 {
+		return false, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	packet, authenticator := p.newAccessRequest(username, password)
+	conn, err := p.dialer.DialContext(ctx, N.NetworkUDP, p.server)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	response := make([]byte, 4096)
+	for i := 0; i < radiusRetries; i++ {
+		_, err = conn.Write(packet)
+		if err != nil {
+			return false, err
+		}
+		retryDeadline := time.Now().Add(p.timeout / radiusRetries)
+		if retryDeadline.After(deadline) || i == radiusRetries-1 {
+			retryDeadline = deadline
+		}
+		conn.SetReadDeadline(retryDeadline)
+		for {
+			var n int
+			n, err = conn.Read(response)
+			if err != nil {
+				break
+			}
+			code, loaded := p.parseResponse(response[:n], packet[1], authenticator)
+			if !loaded {
+				continue
+			}
+			switch code {
+			case radiusCodeAccessAccept:
+				return true, nil
+			case radiusCodeAccessReject:
+				return false, nil
+			default:
+				return false, E.New("unsupported RADIUS response code: ", code)
+			}
+		}
+		if E.IsTimeout(err) && ctx.Err() == nil {
+			continue
+		}
+		return false, err
+	}
+	return false, err
+}
+
+func (p *RADIUSProvider) newAccessRequest(username string, password string) ([]byte, []byte) {
+	var header [20]byte
+	header[0] = radiusCodeAccessRequest
+	rand.Read(header[1:2])
+	authenticator := header[4:20]
+	rand.Read(authenticator)
+	packet := bytes.NewBuffer(header[:])
+	messageAuthenticatorIndex := packet.Len() + 2
+	writeRADIUSAttribute(packet, radiusAttributeMessageAuthenticator, make([]byte, md5.Size))
+	writeRADIUSAttribute(packet, radiusAttributeUserName, []byte(username))
+	writeRADIUSAttribute(packet, radiusAttributeUserPassword, p.hidePassword(password, authenticator))
+	writeRADIUSAttribute(packet, radiusAttributeNASIdentifier, []byte(p.nasIdentifier))
+	content := packet.Bytes()
+	binary.BigEndian.PutUint16(content[2:4], uint16(len(content)))
+	mac := hmac.New(md5.New, p.secret)
+	mac.Write(content)
+	copy(content[messageAuthenticatorIndex:], mac.Sum(nil))
+	return content, append([]byte(nil), authenticator...)
+}
+
+func (p *RADIUSProvider) hidePassword(password string, authenticator []byte) []byte {
+	length := (len(password) + md5.Size - 1) / md5.Size * md5.Size
+	if length == 0 {
+		length = md5.Size
+	}
+	hidden := make([]byte, length)
+	copy(hidden, password)
+	last := authenticator
+	for offset := 0; offset < length; offset += md5.Size {
+		hash := md5.New()
+		hash.Write(p.secret)
+		hash.Write(last)
+		block := hash.Sum(nil)
+		for i := range block {
+			hidden[offset+i] ^= block[i]
+		}
+		last = hidden[offset : offset+md5.Size]
+	}
+	return hidden
+}
+
+func (p *RADIUSProvider) parseResponse(response []byte, identifier byte, requestAuthenticator []byte) (byte, bool) {
+	if len(response) < 20 || response[1] != identifier {
+		return 0, false
+	}
+	length := int(binary.BigEndian.Uint16(response[2:4]))
+	if length < 20 || length > len(response) {
+		return 0, false
+	}
+	response = response[:length]
+	hash := md5.New()
+	hash.Write(response[:4])
+	hash.Write(requestAuthenticator)
+	hash.Write(response[20:])
+	hash.Write(p.secret)
+	if !hmac.Equal(hash.Sum(nil), response[4:20]) {
+		return 0, false
+	}
+	return response[0], true
+}
+
+func writeRADIUSAttribute(buffer *bytes.Buffer, attributeType byte, value []byte) {
+	buffer.WriteByte(attributeType)
+	buffer.WriteByte(byte(len(value) + 2))
+	buffer.Write(value)
+}
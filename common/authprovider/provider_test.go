@@ -0,0 +1,193 @@
+package authprovider
+
+import (
+	"context"
+	"crypto/md5"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/common/json/badoption"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/cryptobyte/asn1"
+)
+
+type testProvider struct {
+	calls atomic.Int32
+}
+
+func (p *testProvider) Authenticate(ctx context.Context, username string, password string) (bool, error) {
+	p.calls.Add(1)
+	return username == "user" && password == "pass", nil
+}
+
+func TestCachedProvider(t *testing.T) {
+	t.Parallel()
+	provider := &testProvider{}
+	cached := &cachedProvider{
+		provider:         provider,
+		cache:            newCache(),
+		cacheTTL:         time.Minute,
+		negativeCacheTTL: 50 * time.Millisecond,
+	}
+	for i := 0; i < 2; i++ {
+		accepted, err := cached.Authenticate(context.Background(), "user", "pass")
+		require.NoError(t, err)
+		require.True(t, accepted)
+		accepted, err = cached.Authenticate(context.Background(), "user", "wrong")
+		require.NoError(t, err)
+		require.False(t, accepted)
+	}
+	require.Equal(t, int32(2), provider.calls.Load())
+	time.Sleep(100 * time.Millisecond)
+	accepted, err := cached.Authenticate(context.Background(), "user", "wrong")
+	require.NoError(t, err)
+	require.False(t, accepted)
+	require.Equal(t, int32(3), provider.calls.Load())
+}
+
+func TestHTTPProvider(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		var credentials map[string]string
+		require.NoError(t, json.NewDecoder(request.Body).Decode(&credentials))
+		switch {
+		case request.Header.Get("Authorization") != "Bearer token":
+			writer.WriteHeader(http.StatusInternalServerError)
+		case credentials["username"] == "user" && credentials["password"] == "pass":
+			writer.WriteHeader(http.StatusNoContent)
+		default:
+			writer.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+	provider, err := NewHTTP(context.Background(), time.Second, option.HTTPAuthProviderOptions{
+		URL:     server.URL,
+		Headers: badoption.HTTPHeader{"Authorization": {"Bearer token"}},
+	})
+	require.NoError(t, err)
+	accepted, err := provider.Authenticate(context.Background(), "user", "pass")
+	require.NoError(t, err)
+	require.True(t, accepted)
+	accepted, err = provider.Authenticate(context.Background(), "user", "wrong")
+	require.NoError(t, err)
+	require.False(t, accepted)
+}
+
+func TestRADIUSProvider(t *testing.T) {
+	t.Parallel()
+	const secret = "secret"
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+	go func() {
+		buffer := make([]byte, 4096)
+		for {
+			n, addr, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			request := buffer[:n]
+			provider := &RADIUSProvider{secret: []byte(secret)}
+			var username, hiddenPassword []byte
+			for attributes := request[20:]; len(attributes) >= 2; attributes = attributes[attributes[1]:] {
+				switch attributes[0] {
+				case radiusAttributeUserName:
+					username = attributes[2:attributes[1]]
+				case radiusAttributeUserPassword:
+					hiddenPassword = attributes[2:attributes[1]]
+				}
+			}
+			// Hiding is its own inverse for a single block.
+			password := provider.hidePassword(string(hiddenPassword), request[4:20])
+			code := byte(radiusCodeAccessReject)
+			if string(username) == "user" && string(password[:4]) == "pass" && password[4] == 0 {
+				code = radiusCodeAccessAccept
+			}
+			response := []byte{code, request[1], 0, 20}
+			hash := md5.New()
+			hash.Write(response)
+			hash.Write(request[4:20])
+			hash.Write([]byte(secret))
+			response = append(response, hash.Sum(nil)...)
+			conn.WriteTo(response, addr)
+		}
+	}()
+	addr := conn.LocalAddr().(*net.UDPAddr)
+	provider, err := NewRADIUS(context.Background(), time.Second, option.RADIUSAuthProviderOptions{
+		ServerOptions: option.ServerOptions{Server: "127.0.0.1", ServerPort: uint16(addr.Port)},
+		Secret:        secret,
+	})
+	require.NoError(t, err)
+	accepted, err := provider.Authenticate(context.Background(), "user", "pass")
+	require.NoError(t, err)
+	require.True(t, accepted)
+	accepted, err = provider.Authenticate(context.Background(), "user", "wrong")
+	require.NoError(t, err)
+	require.False(t, accepted)
+}
+
+func TestLDAPProvider(t *testing.T) {
+	t.Parallel()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			buffer := make([]byte, 4096)
+			n, _ := conn.Read(buffer)
+			var (
+				message, bindRequest cryptobyte.String
+				messageID, version   int64
+				name, password       []byte
+			)
+			input := cryptobyte.String(buffer[:n])
+			ok := input.ReadASN1(&message, asn1.SEQUENCE) &&
+				message.ReadASN1Int64WithTag(&messageID, asn1.INTEGER) &&
+				message.ReadASN1(&bindRequest, ldapTagBindRequest) &&
+				bindRequest.ReadASN1Int64WithTag(&version, asn1.INTEGER) &&
+				bindRequest.ReadASN1Bytes(&name, asn1.OCTET_STRING) &&
+				bindRequest.ReadASN1Bytes(&password, ldapTagSimpleAuth)
+			resultCode := byte(ldapResultInvalidCred)
+			if ok && string(name) == `uid=us\,er,dc=example,dc=org` && string(password) == "pass" {
+				resultCode = ldapResultSuccess
+			}
+			// Long form lengths, as sent by Active Directory.
+			conn.Write([]byte{
+				0x30, 0x84, 0, 0, 0, 0x10,
+				0x02, 0x01, byte(messageID),
+				0x61, 0x84, 0, 0, 0, 0x07,
+				0x0a, 0x01, resultCode,
+				0x04, 0x00,
+				0x04, 0x00,
+			})
+			conn.Close()
+		}
+	}()
+	addr := listener.Addr().(*net.TCPAddr)
+	provider, err := NewLDAP(context.Background(), nil, time.Second, option.LDAPAuthProviderOptions{
+		ServerOptions: option.ServerOptions{Server: "127.0.0.1", ServerPort: uint16(addr.Port)},
+		BindDN:        "uid={username},dc=example,dc=org",
+	})
+	require.NoError(t, err)
+	accepted, err := provider.Authenticate(context.Background(), "us,er", "pass")
+	require.NoError(t, err)
+	require.True(t, accepted)
+	accepted, err = provider.Authenticate(context.Background(), "us,er", "wrong")
+	require.NoError(t, err)
+	require.False(t, accepted)
+	accepted, err = provider.Authenticate(context.Background(), "us,er", "")
+	require.NoError(t, err)
+	require.False(t, accepted)
+}
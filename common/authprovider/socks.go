@@ -0,0 +1,170 @@
+package authprovider
+
+import (
+	std_bufio "bufio"
+	"context"
+	"net"
+
+	"github.com/sagernet/sing-box/common/socksudp"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/auth"
+	"github.com/sagernet/sing/common/buf"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/protocol/socks"
+	"github.com/sagernet/sing/protocol/socks/socks5"
+)
+
+// HandleSOCKSConnectionEx is socks.HandleConnectionEx verifying users with
+// authenticator and serving the UDP extensions of udpServer if not nil.
+// With an auth provider, only SOCKS5 is supported.
+func HandleSOCKSConnectionEx(
+	ctx context.Context, conn net.Conn, reader *std_bufio.Reader,
+	authenticator *Authenticator,
+	udpServer *socksudp.Server,
+	handler socks.HandlerEx,
+	packetListener socks.PacketListener,
+	source M.Socksaddr,
+	onClose N.CloseHandlerFunc,
+) error {
+	if authenticator.provider == nil && udpServer == nil {
+		return socks.HandleConnectionEx(ctx, conn, reader, authenticator.static, handler, packetListener, source, onClose)
+	}
+	versionBytes, err := reader.Peek(1)
+	if err != nil {
+		return err
+	}
+	version := versionBytes[0]
+	if version != socks5.Version {
+		if authenticator.provider == nil {
+			return socks.HandleConnectionEx(ctx, conn, reader, authenticator.static, handler, packetListener, source, onClose)
+		}
+		return E.New("socks", version, ": unsupported with auth provider")
+	}
+	common.Must1(reader.Discard(1))
+	authRequest, err := socks5.ReadAuthRequest0(reader)
+	if err != nil {
+		return err
+	}
+	if !authenticator.Enabled() {
+		if !common.Contains(authRequest.Methods, socks5.AuthTypeNotRequired) {
+			err = socks5.WriteAuthResponse(conn, socks5.AuthResponse{
+				Method: socks5.AuthTypeNoAcceptedMethods,
+			})
+			if err != nil {
+				return err
+			}
+			return E.New("socks5: unsupported authentication methods")
+		}
+		err = socks5.WriteAuthResponse(conn, socks5.AuthResponse{
+			Method: socks5.AuthTypeNotRequired,
+		})
+		if err != nil {
+			return err
+		}
+		return handleSOCKS5Request(ctx, conn, reader, udpServer, handler, packetListener, source, onClose)
+	}
+	if !common.Contains(authRequest.Methods, socks5.AuthTypeUsernamePassword) {
+		err = socks5.WriteAuthResponse(conn, socks5.AuthResponse{
+			Method: socks5.AuthTypeNoAcceptedMethods,
+		})
+		if err != nil {
+			return err
+		}
+		return E.New("socks5: missing username/password authentication method")
+	}
+	err = socks5.WriteAuthResponse(conn, socks5.AuthResponse{
+		Method: socks5.AuthTypeUsernamePassword,
+	})
+	if err != nil {
+		return err
+	}
+	usernamePasswordAuthRequest, err := socks5.ReadUsernamePasswordAuthRequest(reader)
+	if err != nil {
+		return err
+	}
+	response := socks5.UsernamePasswordAuthResponse{}
+	if authenticator.Verify(ctx, usernamePasswordAuthRequest.Username, usernamePasswordAuthRequest.Password) {
+		response.Status = socks5.UsernamePasswordStatusSuccess
+	} else {
+		response.Status = socks5.UsernamePasswordStatusFailure
+	}
+	err = socks5.WriteUsernamePasswordAuthResponse(conn, response)
+	if err != nil {
+		return err
+	}
+	if response.Status != socks5.UsernamePasswordStatusSuccess {
+		return E.New("socks5: authentication failed, username=", usernamePasswordAuthRequest.Username)
+	}
+	ctx = auth.ContextWithUser(ctx, usernamePasswordAuthRequest.Username)
+	return handleSOCKS5Request(ctx, conn, reader, udpServer, handler, packetListener, source, onClose)
+}
+
+func handleSOCKS5Request(
+	ctx context.Context, conn net.Conn, reader *std_bufio.Reader,
+	udpServer *socksudp.Server,
+	handler socks.HandlerEx,
+	packetListener socks.PacketListener,
+	source M.Socksaddr,
+	onClose N.CloseHandlerFunc,
+) error {
+	request, err := socks5.ReadRequest(reader)
+	if err != nil {
+		return err
+	}
+	if udpServer != nil {
+		handled, err := udpServer.HandleRequest(ctx, conn, reader, request, handler, source, onClose)
+		if handled {
+			return err
+		}
+	}
+	switch request.Command {
+	case socks5.CommandConnect:
+		handler.NewConnectionEx(ctx, socks.NewLazyConn(conn, socks5.Version), source, request.Destination, onClose)
+		return nil
+	case socks5.CommandUDPAssociate:
+		var (
+			listenConfig net.ListenConfig
+			udpConn      net.PacketConn
+		)
+		network := M.NetworkFromNetAddr("udp", M.AddrFromNet(conn.LocalAddr()))
+		address := M.SocksaddrFrom(M.AddrFromNet(conn.LocalAddr()), 0).String()
+		if packetListener != nil {
+			udpConn, err = packetListener.ListenPacket(listenConfig, ctx, network, address)
+		} else {
+			udpConn, err = listenConfig.ListenPacket(ctx, network, address)
+		}
+		if err != nil {
+			return E.Cause(err, "socks5: listen udp")
+		}
+		err = socks5.WriteResponse(conn, socks5.Response{
+			ReplyCode: socks5.ReplyCodeSuccess,
+			Bind:      M.SocksaddrFromNet(udpConn.LocalAddr()),
+		})
+		if err != nil {
+			udpConn.Close()
+			return E.Cause(err, "socks5: write response")
+		}
+		var socksPacketConn N.PacketConn = socks.NewAssociatePacketConn(bufio.NewServerPacketConn(udpConn), M.Socksaddr{}, conn)
+		firstPacket := buf.NewPacket()
+		destination, err := socksPacketConn.ReadPacket(firstPacket)
+		if err != nil {
+			firstPacket.Release()
+			socksPacketConn.Close()
+			return E.Cause(err, "socks5: read first packet")
+		}
+		socksPacketConn = bufio.NewCachedPacketConn(socksPacketConn, firstPacket, destination)
+		handler.NewPacketConnectionEx(ctx, socksPacketConn, source, destination, onClose)
+		return nil
+	default:
+		err = socks5.WriteResponse(conn, socks5.Response{
+			ReplyCode: socks5.ReplyCodeUnsupported,
+		})
+		if err != nil {
+			return err
+		}
+		return E.New("socks5: unsupported command ", request.Command)
+	}
+}
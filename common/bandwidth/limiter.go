@@ -0,0 +1,124 @@
+package bandwidth
+
+import (
+	"context"
+	"net"
+
+	"github.com/sagernet/sing/common/buf"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	mbps     = 125000
+	minBurst = 64 * 1024
+)
+
+// Limiter limits the bandwidth shared by all connections wrapped by it, such
+// as the connections of an inbound user. Upload is the direction from the
+// client, download is the direction to the client.
+type Limiter struct {
+	upload   *rate.Limiter
+	download *rate.Limiter
+}
+
+// NewLimiter returns nil if both directions are unlimited.
+func NewLimiter(uploadMbps int, downloadMbps int) *Limiter {
+	if uploadMbps <= 0 && downloadMbps <= 0 {
+		return nil
+	}
+	return &Limiter{
+		upload:   newRateLimiter(uploadMbps),
+		download: newRateLimiter(downloadMbps),
+	}
+}
+
+func newRateLimiter(limitMbps int) *rate.Limiter {
+	if limitMbps <= 0 {
+		return nil
+	}
+	bytesPerSecond := limitMbps * mbps
+	// a burst of 100ms, so that short bursts are not delayed
+	return rate.NewLimiter(rate.Limit(bytesPerSecond), max(bytesPerSecond/10, minBurst))
+}
+
+func waitN(ctx context.Context, limiter *rate.Limiter, n int) error {
+	if limiter == nil {
+		return nil
+	}
+	for n > 0 {
+		chunk := min(n, limiter.Burst())
+		err := limiter.WaitN(ctx, chunk)
+		if err != nil {
+			return err
+		}
+		n -= chunk
+	}
+	return nil
+}
+
+func (l *Limiter) NewConn(ctx context.Context, conn net.Conn) net.Conn {
+	return &limitedConn{Conn: conn, ctx: ctx, limiter: l}
+}
+
+func (l *Limiter) NewPacketConn(ctx context.Context, conn N.PacketConn) N.PacketConn {
+	return &limitedPacketConn{PacketConn: conn, ctx: ctx, limiter: l}
+}
+
+type limitedConn struct {
+	net.Conn
+	ctx     context.Context
+	limiter *Limiter
+}
+
+func (c *limitedConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		waitErr := waitN(c.ctx, c.limiter.upload, n)
+		if err == nil {
+			err = waitErr
+		}
+	}
+	return n, err
+}
+
+func (c *limitedConn) Write(b []byte) (int, error) {
+	err := waitN(c.ctx, c.limiter.download, len(b))
+	if err != nil {
+		return 0, err
+	}
+	return c.Conn.Write(b)
+}
+
+func (c *limitedConn) Upstream() any {
+	return c.Conn
+}
+
+type limitedPacketConn struct {
+	N.PacketConn
+	ctx     context.Context
+	limiter *Limiter
+}
+
+func (c *limitedPacketConn) ReadPacket(buffer *buf.Buffer) (M.Socksaddr, error) {
+	destination, err := c.PacketConn.ReadPacket(buffer)
+	if err != nil {
+		return destination, err
+	}
+	return destination, waitN(c.ctx, c.limiter.upload, buffer.Len())
+}
+
+func (c *limitedPacketConn) WritePacket(buffer *buf.Buffer, destination M.Socksaddr) error {
+	err := waitN(c.ctx, c.limiter.download, buffer.Len())
+	if err != nil {
+		buffer.Release()
+		return err
+	}
+	return c.PacketConn.WritePacket(buffer, destination)
+}
+
+func (c *limitedPacketConn) Upstream() any {
+	return c.PacketConn
+}
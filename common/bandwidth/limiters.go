@@ -0,0 +1,41 @@
+package bandwidth
+
+import "sync"
+
+// Limit is the bandwidth limit of a user in Mbps, zero is unlimited.
+type Limit struct {
+	UploadMbps   int
+	DownloadMbps int
+}
+
+// Limiters holds the limiters of users by ID. Limiters of users with
+// unchanged limits are kept by Update, so that updating users does not
+// reset the bandwidth shared by their connections.
+type Limiters[K comparable] struct {
+	access   sync.RWMutex
+	limits   map[K]Limit
+	limiters map[K]*Limiter
+}
+
+// Update replaces the limits of all users.
+func (l *Limiters[K]) Update(limits map[K]Limit) {
+	l.access.Lock()
+	defer l.access.Unlock()
+	limiters := make(map[K]*Limiter, len(limits))
+	for id, limit := range limits {
+		if currentLimit, loaded := l.limits[id]; loaded && currentLimit == limit {
+			limiters[id] = l.limiters[id]
+		} else {
+			limiters[id] = NewLimiter(limit.UploadMbps, limit.DownloadMbps)
+		}
+	}
+	l.limits = limits
+	l.limiters = limiters
+}
+
+// Load returns the limiter of the user, or nil if it is unlimited.
+func (l *Limiters[K]) Load(id K) *Limiter {
+	l.access.RLock()
+	defer l.access.RUnlock()
+	return l.limiters[id]
+}
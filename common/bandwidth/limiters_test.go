@@ -0,0 +1,34 @@
+package bandwidth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitersUpdate(t *testing.T) {
+	t.Parallel()
+	var limiters Limiters[int]
+	limiters.Update(map[int]Limit{
+		1: {UploadMbps: 10},
+		2: {DownloadMbps: 20},
+		3: {},
+	})
+	first, second := limiters.Load(1), limiters.Load(2)
+	require.NotNil(t, first)
+	require.NotNil(t, second)
+	require.Nil(t, limiters.Load(3))
+
+	limiters.Update(map[int]Limit{
+		1: {UploadMbps: 10},
+		2: {DownloadMbps: 30},
+		4: {UploadMbps: 10},
+	})
+	require.Same(t, first, limiters.Load(1))
+	require.NotSame(t, second, limiters.Load(2))
+	require.NotNil(t, limiters.Load(4))
+	require.NotSame(t, first, limiters.Load(4))
+
+	limiters.Update(map[int]Limit{4: {UploadMbps: 10}})
+	require.Nil(t, limiters.Load(1))
+}
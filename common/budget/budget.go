@@ -0,0 +1,103 @@
+package budget
+
+import (
+	"context"
+	"sync"
+)
+
+// Budget accounts a shared resource. A zero limit only accounts usage.
+type Budget struct {
+	limit    int64
+	access   sync.Mutex
+	used     int64
+	peak     int64
+	rejected uint64
+	released chan struct{}
+}
+
+type Stats struct {
+	Limit    int64
+	Used     int64
+	Peak     int64
+	Rejected uint64
+}
+
+func New(limit int64) *Budget {
+	if limit < 0 {
+		limit = 0
+	}
+	return &Budget{limit: limit}
+}
+
+func (b *Budget) TryAcquire(n int64) bool {
+	b.access.Lock()
+	defer b.access.Unlock()
+	if !b.tryAcquire(n) {
+		b.rejected++
+		return false
+	}
+	return true
+}
+
+// Acquire waits until n is available or ctx is done, applying backpressure
+// to the caller instead of failing as soon as the budget is exhausted.
+func (b *Budget) Acquire(ctx context.Context, n int64) bool {
+	for {
+		b.access.Lock()
+		if b.tryAcquire(n) {
+			b.access.Unlock()
+			return true
+		}
+		if b.released == nil {
+			b.released = make(chan struct{})
+		}
+		released := b.released
+		b.access.Unlock()
+		select {
+		case <-released:
+		case <-ctx.Done():
+			b.access.Lock()
+			b.rejected++
+			b.access.Unlock()
+			return false
+		}
+	}
+}
+
+func (b *Budget) tryAcquire(n int64) bool {
+	if b.limit > 0 && b.used+n > b.limit {
+		return false
+	}
+	b.used += n
+	if b.used > b.peak {
+		b.peak = b.used
+	}
+	return true
+}
+
+func (b *Budget) Release(n int64) {
+	b.access.Lock()
+	defer b.access.Unlock()
+	b.used -= n
+	if b.released != nil {
+		close(b.released)
+		b.released = nil
+	}
+}
+
+func (b *Budget) Used() int64 {
+	b.access.Lock()
+	defer b.access.Unlock()
+	return b.used
+}
+
+func (b *Budget) Stats() Stats {
+	b.access.Lock()
+	defer b.access.Unlock()
+	return Stats{
+		Limit:    b.limit,
+		Used:     b.used,
+		Peak:     b.peak,
+		Rejected: b.rejected,
+	}
+}
@@ -0,0 +1,46 @@
+package budget
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBudget(t *testing.T) {
+	t.Parallel()
+	b := New(2)
+	require.True(t, b.TryAcquire(1))
+	require.True(t, b.TryAcquire(1))
+	require.False(t, b.TryAcquire(1))
+	b.Release(1)
+	require.True(t, b.TryAcquire(1))
+	stats := b.Stats()
+	require.Equal(t, Stats{Limit: 2, Used: 2, Peak: 2, Rejected: 1}, stats)
+}
+
+func TestBudgetAcquire(t *testing.T) {
+	t.Parallel()
+	b := New(1)
+	require.True(t, b.TryAcquire(1))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.False(t, b.Acquire(ctx, 1))
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		b.Release(1)
+	}()
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.True(t, b.Acquire(ctx, 1))
+}
+
+func TestBudgetUnlimited(t *testing.T) {
+	t.Parallel()
+	b := New(0)
+	for i := 0; i < 100; i++ {
+		require.True(t, b.TryAcquire(1024))
+	}
+	require.Equal(t, int64(100*1024), b.Used())
+}
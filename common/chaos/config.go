@@ -0,0 +1,118 @@
+package chaos
+
+import (
+	mRand "math/rand/v2"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+const (
+	// queueSize is the number of chunks or packets in flight per direction.
+	queueSize = 64
+	mbps      = 125000
+)
+
+// Config is the degradation applied to both directions of a connection.
+type Config struct {
+	latency    time.Duration
+	jitter     time.Duration
+	packetLoss float64
+	upload     uint64
+	download   uint64
+}
+
+func NewConfig(options option.ChaosOptions) (*Config, error) {
+	if options.Latency < 0 || options.Jitter < 0 {
+		return nil, E.New("negative latency or jitter")
+	}
+	if options.PacketLoss < 0 || options.PacketLoss > 100 {
+		return nil, E.New("packet_loss must be between 0 and 100")
+	}
+	if options.UpMbps < 0 || options.DownMbps < 0 {
+		return nil, E.New("negative bandwidth")
+	}
+	return &Config{
+		latency:    time.Duration(options.Latency),
+		jitter:     time.Duration(options.Jitter),
+		packetLoss: options.PacketLoss / 100,
+		upload:     uint64(options.UpMbps) * mbps,
+		download:   uint64(options.DownMbps) * mbps,
+	}, nil
+}
+
+func (c *Config) delay() time.Duration {
+	delay := c.latency
+	if c.jitter > 0 {
+		delay += time.Duration(mRand.Int64N(int64(2*c.jitter+1))) - c.jitter
+	}
+	return max(delay, 0)
+}
+
+func (c *Config) drop() bool {
+	return c.packetLoss > 0 && mRand.Float64() < c.packetLoss
+}
+
+// link schedules the delivery of one direction, where chunks are sent one
+// after another at the rate, and delivered after the delay without
+// reordering.
+type link struct {
+	config    *Config
+	rate      uint64
+	access    sync.Mutex
+	sent      time.Time
+	delivered time.Time
+}
+
+func newLink(config *Config, rate uint64) *link {
+	return &link{config: config, rate: rate}
+}
+
+func (l *link) schedule(size int) time.Time {
+	l.access.Lock()
+	defer l.access.Unlock()
+	now := time.Now()
+	sent := now
+	if l.rate > 0 {
+		if l.sent.After(now) {
+			sent = l.sent
+		}
+		sent = sent.Add(time.Duration(uint64(size) * uint64(time.Second) / l.rate))
+		l.sent = sent
+	}
+	delivered := sent.Add(l.config.delay())
+	if delivered.Before(l.delivered) {
+		delivered = l.delivered
+	}
+	l.delivered = delivered
+	return delivered
+}
+
+func (l *link) last() time.Time {
+	l.access.Lock()
+	defer l.access.Unlock()
+	return l.delivered
+}
+
+// wait blocks until the time, or until the connection is closed or the
+// deadline is exceeded.
+func wait(at time.Time, closed <-chan struct{}, deadline <-chan struct{}) error {
+	duration := time.Until(at)
+	if duration <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-closed:
+		return net.ErrClosed
+	case <-deadline:
+		return os.ErrDeadlineExceeded
+	}
+}
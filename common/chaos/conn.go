@@ -0,0 +1,218 @@
+package chaos
+
+import (
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sagernet/sing/common/buf"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/pipe"
+)
+
+// flushTimeout limits the time to deliver written data after close.
+const flushTimeout = 5 * time.Second
+
+var _ net.Conn = (*Conn)(nil)
+
+// Conn delays data in both directions. Written data is delivered after the
+// connection is closed, like the kernel does.
+type Conn struct {
+	net.Conn
+	config       *Config
+	datagram     bool
+	uplink       *link
+	downlink     *link
+	writeAccess  sync.Mutex
+	writeQueue   chan *chunk
+	writeClosed  bool
+	closeWrite   bool
+	writeErr     atomic.Pointer[error]
+	readOnce     sync.Once
+	readQueue    chan *chunk
+	readPending  *chunk
+	readDeadline pipe.Deadline
+	closeOnce    sync.Once
+	closed       chan struct{}
+}
+
+type chunk struct {
+	buffer *buf.Buffer
+	at     time.Time
+	err    error
+}
+
+func NewConn(conn net.Conn, config *Config) *Conn {
+	return newConn(conn, config, false)
+}
+
+// NewDatagramConn is NewConn for connected UDP sockets, where the packet loss
+// is applied and datagrams are not merged.
+func NewDatagramConn(conn net.Conn, config *Config) *Conn {
+	return newConn(conn, config, true)
+}
+
+func newConn(conn net.Conn, config *Config, datagram bool) *Conn {
+	c := &Conn{
+		Conn:         conn,
+		config:       config,
+		datagram:     datagram,
+		uplink:       newLink(config, config.upload),
+		downlink:     newLink(config, config.download),
+		writeQueue:   make(chan *chunk, queueSize),
+		readQueue:    make(chan *chunk, queueSize),
+		readDeadline: pipe.MakeDeadline(),
+		closed:       make(chan struct{}),
+	}
+	go c.loopWrite()
+	return c
+}
+
+func (c *Conn) Write(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	if errPtr := c.writeErr.Load(); errPtr != nil {
+		return 0, *errPtr
+	}
+	if c.datagram && c.config.drop() {
+		return len(b), nil
+	}
+	c.writeAccess.Lock()
+	defer c.writeAccess.Unlock()
+	if c.writeClosed {
+		return 0, net.ErrClosed
+	}
+	buffer := buf.NewSize(len(b))
+	_, _ = buffer.Write(b)
+	select {
+	case c.writeQueue <- &chunk{buffer: buffer, at: c.uplink.schedule(len(b))}:
+		return len(b), nil
+	case <-c.closed:
+		buffer.Release()
+		return 0, net.ErrClosed
+	}
+}
+
+func (c *Conn) loopWrite() {
+	for chunk := range c.writeQueue {
+		if c.writeErr.Load() == nil {
+			_ = wait(chunk.at, nil, nil)
+			_, err := c.Conn.Write(chunk.buffer.Bytes())
+			if err != nil {
+				c.writeErr.Store(&err)
+			}
+		}
+		chunk.buffer.Release()
+	}
+	if c.closeWrite && c.writeErr.Load() == nil {
+		_ = N.CloseWrite(c.Conn)
+	}
+	<-c.closed
+	c.Conn.Close()
+}
+
+func (c *Conn) Read(b []byte) (int, error) {
+	c.readOnce.Do(func() {
+		go c.loopRead()
+	})
+	if c.readPending == nil {
+		select {
+		case c.readPending = <-c.readQueue:
+		case <-c.closed:
+			return 0, net.ErrClosed
+		case <-c.readDeadline.Wait():
+			return 0, os.ErrDeadlineExceeded
+		}
+	}
+	err := wait(c.readPending.at, c.closed, c.readDeadline.Wait())
+	if err != nil {
+		return 0, err
+	}
+	if c.readPending.err != nil {
+		return 0, c.readPending.err
+	}
+	buffer := c.readPending.buffer
+	n := copy(b, buffer.Bytes())
+	buffer.Advance(n)
+	if buffer.IsEmpty() || c.datagram {
+		buffer.Release()
+		c.readPending = nil
+	}
+	return n, nil
+}
+
+func (c *Conn) loopRead() {
+	for {
+		var buffer *buf.Buffer
+		if c.datagram {
+			buffer = buf.NewPacket()
+		} else {
+			buffer = buf.New()
+		}
+		_, err := buffer.ReadOnceFrom(c.Conn)
+		var readChunk *chunk
+		if err != nil {
+			buffer.Release()
+			readChunk = &chunk{at: c.downlink.schedule(0), err: err}
+		} else if c.datagram && c.config.drop() {
+			buffer.Release()
+			continue
+		} else {
+			readChunk = &chunk{buffer: buffer, at: c.downlink.schedule(buffer.Len())}
+		}
+		select {
+		case c.readQueue <- readChunk:
+		case <-c.closed:
+			if readChunk.buffer != nil {
+				readChunk.buffer.Release()
+			}
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (c *Conn) SetDeadline(t time.Time) error {
+	c.readDeadline.Set(t)
+	return c.Conn.SetWriteDeadline(t)
+}
+
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	c.readDeadline.Set(t)
+	return nil
+}
+
+// CloseWrite shuts down the writing side after written data is delivered.
+func (c *Conn) CloseWrite() error {
+	c.writeAccess.Lock()
+	defer c.writeAccess.Unlock()
+	if !c.writeClosed {
+		c.writeClosed = true
+		c.closeWrite = true
+		close(c.writeQueue)
+	}
+	return nil
+}
+
+func (c *Conn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.writeAccess.Lock()
+		if !c.writeClosed {
+			c.writeClosed = true
+			close(c.writeQueue)
+		}
+		c.writeAccess.Unlock()
+		_ = c.Conn.SetWriteDeadline(c.uplink.last().Add(flushTimeout))
+	})
+	return nil
+}
+
+func (c *Conn) Upstream() any {
+	return c.Conn
+}
@@ -0,0 +1,66 @@
+package chaos
+
+import (
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/buf"
+	"github.com/sagernet/sing/common/bufio"
+	"github.com/sagernet/sing/common/json/badoption"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnLatency(t *testing.T) {
+	t.Parallel()
+	config, err := NewConfig(option.ChaosOptions{Latency: badoption.Duration(50 * time.Millisecond)})
+	require.NoError(t, err)
+	client, server := net.Pipe()
+	defer server.Close()
+	conn := NewConn(client, config)
+	start := time.Now()
+	_, err = conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	require.Less(t, time.Since(start), 50*time.Millisecond)
+	message := make([]byte, 5)
+	_, err = io.ReadFull(server, message)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(message))
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	go server.Write([]byte("world"))
+	start = time.Now()
+	_, err = io.ReadFull(conn, message)
+	require.NoError(t, err)
+	require.Equal(t, "world", string(message))
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	_, err = conn.Write([]byte("bye"))
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+	message = make([]byte, 3)
+	_, err = io.ReadFull(server, message)
+	require.NoError(t, err)
+	require.Equal(t, "bye", string(message))
+}
+
+func TestPacketConnLoss(t *testing.T) {
+	t.Parallel()
+	config, err := NewConfig(option.ChaosOptions{PacketLoss: 100})
+	require.NoError(t, err)
+	client, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	server, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer server.Close()
+	conn := NewPacketConn(bufio.NewPacketConn(client), config)
+	defer conn.Close()
+	err = conn.WritePacket(buf.As([]byte("hello")), M.SocksaddrFromNet(server.LocalAddr()))
+	require.NoError(t, err)
+	require.NoError(t, server.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+	_, _, err = server.ReadFrom(make([]byte, 5))
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+}
@@ -0,0 +1,170 @@
+package chaos
+
+import (
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sagernet/sing/common/buf"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/pipe"
+)
+
+var _ N.PacketConn = (*PacketConn)(nil)
+
+// PacketConn drops and delays packets in both directions, packets are also
+// dropped if too many are in flight.
+type PacketConn struct {
+	N.PacketConn
+	config       *Config
+	uplink       *link
+	downlink     *link
+	writeQueue   chan *packet
+	writeErr     atomic.Pointer[error]
+	readOnce     sync.Once
+	readQueue    chan *packet
+	readPending  *packet
+	readDeadline pipe.Deadline
+	closeOnce    sync.Once
+	closed       chan struct{}
+}
+
+type packet struct {
+	buffer      *buf.Buffer
+	destination M.Socksaddr
+	at          time.Time
+	err         error
+}
+
+func NewPacketConn(conn N.PacketConn, config *Config) *PacketConn {
+	c := &PacketConn{
+		PacketConn:   conn,
+		config:       config,
+		uplink:       newLink(config, config.upload),
+		downlink:     newLink(config, config.download),
+		writeQueue:   make(chan *packet, queueSize),
+		readQueue:    make(chan *packet, queueSize),
+		readDeadline: pipe.MakeDeadline(),
+		closed:       make(chan struct{}),
+	}
+	go c.loopWrite()
+	return c
+}
+
+func (c *PacketConn) WritePacket(buffer *buf.Buffer, destination M.Socksaddr) error {
+	if errPtr := c.writeErr.Load(); errPtr != nil {
+		buffer.Release()
+		return *errPtr
+	}
+	if c.config.drop() || len(c.writeQueue) == cap(c.writeQueue) {
+		buffer.Release()
+		return nil
+	}
+	select {
+	case c.writeQueue <- &packet{buffer: buffer, destination: destination, at: c.uplink.schedule(buffer.Len())}:
+		return nil
+	case <-c.closed:
+		buffer.Release()
+		return net.ErrClosed
+	default:
+		buffer.Release()
+		return nil
+	}
+}
+
+func (c *PacketConn) loopWrite() {
+	for {
+		select {
+		case writePacket := <-c.writeQueue:
+			if wait(writePacket.at, c.closed, nil) != nil {
+				writePacket.buffer.Release()
+				return
+			}
+			err := c.PacketConn.WritePacket(writePacket.buffer, writePacket.destination)
+			if err != nil {
+				c.writeErr.Store(&err)
+				return
+			}
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+func (c *PacketConn) ReadPacket(buffer *buf.Buffer) (destination M.Socksaddr, err error) {
+	c.readOnce.Do(func() {
+		go c.loopRead()
+	})
+	if c.readPending == nil {
+		select {
+		case c.readPending = <-c.readQueue:
+		case <-c.closed:
+			return M.Socksaddr{}, net.ErrClosed
+		case <-c.readDeadline.Wait():
+			return M.Socksaddr{}, os.ErrDeadlineExceeded
+		}
+	}
+	err = wait(c.readPending.at, c.closed, c.readDeadline.Wait())
+	if err != nil {
+		return
+	}
+	if c.readPending.err != nil {
+		return M.Socksaddr{}, c.readPending.err
+	}
+	readPacket := c.readPending
+	c.readPending = nil
+	_, err = buffer.Write(readPacket.buffer.Bytes())
+	readPacket.buffer.Release()
+	return readPacket.destination, err
+}
+
+func (c *PacketConn) loopRead() {
+	for {
+		buffer := buf.NewPacket()
+		destination, err := c.PacketConn.ReadPacket(buffer)
+		if err != nil {
+			buffer.Release()
+			select {
+			case c.readQueue <- &packet{at: time.Now(), err: err}:
+			case <-c.closed:
+			}
+			return
+		}
+		if c.config.drop() {
+			buffer.Release()
+			continue
+		}
+		select {
+		case c.readQueue <- &packet{buffer: buffer, destination: destination, at: c.downlink.schedule(buffer.Len())}:
+		case <-c.closed:
+			buffer.Release()
+			return
+		default:
+			buffer.Release()
+		}
+	}
+}
+
+func (c *PacketConn) SetDeadline(t time.Time) error {
+	c.readDeadline.Set(t)
+	return c.PacketConn.SetWriteDeadline(t)
+}
+
+func (c *PacketConn) SetReadDeadline(t time.Time) error {
+	c.readDeadline.Set(t)
+	return nil
+}
+
+func (c *PacketConn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+	return c.PacketConn.Close()
+}
+
+func (c *PacketConn) Upstream() any {
+	return c.PacketConn
+}
@@ -0,0 +1,125 @@
+package clash
+
+import (
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	Port                int                      `yaml:"port,omitempty"`
+	SocksPort           int                      `yaml:"socks-port,omitempty"`
+	MixedPort           int                      `yaml:"mixed-port,omitempty"`
+	RedirPort           int                      `yaml:"redir-port,omitempty"`
+	TProxyPort          int                      `yaml:"tproxy-port,omitempty"`
+	AllowLAN            bool                     `yaml:"allow-lan,omitempty"`
+	BindAddress         string                   `yaml:"bind-address,omitempty"`
+	Mode                string                   `yaml:"mode,omitempty"`
+	LogLevel            string                   `yaml:"log-level,omitempty"`
+	IPv6                *bool                    `yaml:"ipv6,omitempty"`
+	ExternalController  string                   `yaml:"external-controller,omitempty"`
+	ExternalUI          string                   `yaml:"external-ui,omitempty"`
+	Secret              string                   `yaml:"secret,omitempty"`
+	InterfaceName       string                   `yaml:"interface-name,omitempty"`
+	RoutingMark         uint32                   `yaml:"routing-mark,omitempty"`
+	FindProcessMode     string                   `yaml:"find-process-mode,omitempty"`
+	Hosts               yaml.Node                `yaml:"hosts,omitempty"`
+	DNS                 *DNS                     `yaml:"dns,omitempty"`
+	Tun                 *Tun                     `yaml:"tun,omitempty"`
+	Sniffer             *Sniffer                 `yaml:"sniffer,omitempty"`
+	Proxies             []Proxy                  `yaml:"proxies"`
+	ProxyGroups         []ProxyGroup             `yaml:"proxy-groups,omitempty"`
+	ProxyProviders      map[string]ProxyProvider `yaml:"proxy-providers,omitempty"`
+	Rules               []string                 `yaml:"rules,omitempty"`
+	RuleProviders       map[string]RuleProvider  `yaml:"rule-providers,omitempty"`
+	unsupportedSections []string
+}
+
+type Tun struct {
+	Enable              bool     `yaml:"enable"`
+	Stack               string   `yaml:"stack,omitempty"`
+	Device              string   `yaml:"device,omitempty"`
+	MTU                 uint32   `yaml:"mtu,omitempty"`
+	AutoRoute           bool     `yaml:"auto-route,omitempty"`
+	AutoDetectInterface bool     `yaml:"auto-detect-interface,omitempty"`
+	StrictRoute         bool     `yaml:"strict-route,omitempty"`
+	DNSHijack           []string `yaml:"dns-hijack,omitempty"`
+}
+
+type Sniffer struct {
+	Enable bool `yaml:"enable"`
+}
+
+type ProxyProvider struct {
+	Type          string `yaml:"type"`
+	URL           string `yaml:"url,omitempty"`
+	Path          string `yaml:"path,omitempty"`
+	Interval      int    `yaml:"interval,omitempty"`
+	Proxy         string `yaml:"proxy,omitempty"`
+	Filter        string `yaml:"filter,omitempty"`
+	ExcludeFilter string `yaml:"exclude-filter,omitempty"`
+}
+
+type RuleProvider struct {
+	Type     string `yaml:"type"`
+	Behavior string `yaml:"behavior,omitempty"`
+	Format   string `yaml:"format,omitempty"`
+	URL      string `yaml:"url,omitempty"`
+	Path     string `yaml:"path,omitempty"`
+	Interval int    `yaml:"interval,omitempty"`
+	Proxy    string `yaml:"proxy,omitempty"`
+}
+
+var knownSections = map[string]bool{
+	"port": true, "socks-port": true, "mixed-port": true, "redir-port": true, "tproxy-port": true,
+	"allow-lan": true, "bind-address": true, "mode": true, "log-level": true, "ipv6": true,
+	"external-controller": true, "external-ui": true, "secret": true, "interface-name": true,
+	"routing-mark": true, "find-process-mode": true, "hosts": true, "dns": true, "tun": true,
+	"sniffer": true, "proxies": true, "proxy-groups": true, "proxy-providers": true,
+	"rules": true, "rule-providers": true,
+}
+
+func ParseConfig(content []byte) (*Config, error) {
+	var document yaml.Node
+	err := yaml.Unmarshal(content, &document)
+	if err != nil {
+		return nil, E.Cause(err, "decode clash config")
+	}
+	var config Config
+	err = document.Decode(&config)
+	if err != nil {
+		return nil, E.Cause(err, "decode clash config")
+	}
+	if len(document.Content) > 0 && document.Content[0].Kind == yaml.MappingNode {
+		root := document.Content[0]
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			if key := root.Content[i].Value; !knownSections[key] {
+				config.unsupportedSections = append(config.unsupportedSections, key)
+			}
+		}
+	}
+	return &config, nil
+}
+
+// ParseProxies converts the proxies of a Clash configuration into outbounds.
+// Proxies that fail to convert are reported in the returned error slice and skipped.
+func ParseProxies(content []byte) ([]option.Outbound, []error) {
+	config, err := ParseConfig(content)
+	if err != nil {
+		return nil, []error{err}
+	}
+	var (
+		outbounds []option.Outbound
+		errors    []error
+	)
+	for _, proxy := range config.Proxies {
+		outbound, err := proxy.Outbound()
+		if err != nil {
+			errors = append(errors, E.Cause(err, "proxy ", proxy.Name))
+			continue
+		}
+		outbounds = append(outbounds, outbound)
+	}
+	return outbounds, errors
+}
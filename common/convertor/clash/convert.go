@@ -0,0 +1,274 @@
+package clash
+
+import (
+	"net/netip"
+	"sort"
+	"strings"
+	"time"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/json/badoption"
+	"github.com/sagernet/sing/common/logger"
+)
+
+const (
+	tagDirect = "DIRECT"
+	tagReject = "REJECT"
+	tagGlobal = "GLOBAL"
+)
+
+type converter struct {
+	config          *Config
+	logger          logger.Logger
+	options         option.Options
+	route           option.RouteOptions
+	outboundTags    map[string]bool
+	dynamicTags     map[string]string
+	subscriptions   map[string]*option.SubscriptionServiceOptions
+	ruleSets        map[string]bool
+	ipRuleSets      map[string]bool
+	needReject      bool
+	resolveInserted bool
+	mergeable       bool
+}
+
+// Convert translates a Clash or Clash.Meta configuration into sing-box options.
+// Features without a sing-box equivalent are skipped and reported to the logger.
+func Convert(content []byte, logger logger.Logger) (*option.Options, error) {
+	config, err := ParseConfig(content)
+	if err != nil {
+		return nil, err
+	}
+	c := &converter{
+		config:        config,
+		logger:        logger,
+		outboundTags:  make(map[string]bool),
+		dynamicTags:   make(map[string]string),
+		subscriptions: make(map[string]*option.SubscriptionServiceOptions),
+		ruleSets:      make(map[string]bool),
+		ipRuleSets:    make(map[string]bool),
+	}
+	for _, section := range config.unsupportedSections {
+		logger.Warn("ignored unsupported section: ", section)
+	}
+	c.convertLog()
+	c.convertInbounds()
+	c.convertProxies()
+	c.convertProxyProviders()
+	c.convertRuleProviders()
+	c.convertGroups()
+	c.convertDNS()
+	c.convertRules()
+	c.convertExperimental()
+	c.options.Outbounds = append(c.options.Outbounds, option.Outbound{
+		Type:    C.TypeDirect,
+		Tag:     tagDirect,
+		Options: &option.DirectOutboundOptions{},
+	})
+	if c.needReject {
+		c.options.Outbounds = append(c.options.Outbounds, option.Outbound{
+			Type:    C.TypeBlock,
+			Tag:     tagReject,
+			Options: &option.StubOptions{},
+		})
+	}
+	for _, name := range sortedKeys(c.subscriptions) {
+		c.options.Services = append(c.options.Services, option.Service{
+			Type:    C.TypeSubscription,
+			Tag:     name,
+			Options: c.subscriptions[name],
+		})
+	}
+	c.options.Route = &c.route
+	return &c.options, nil
+}
+
+func (c *converter) convertLog() {
+	switch c.config.LogLevel {
+	case "":
+	case "silent":
+		c.options.Log = &option.LogOptions{Disabled: true}
+	case "warning":
+		c.options.Log = &option.LogOptions{Level: "warn"}
+	default:
+		c.options.Log = &option.LogOptions{Level: c.config.LogLevel}
+	}
+}
+
+func (c *converter) listenAddress() *badoption.Addr {
+	listen := netip.AddrFrom4([4]byte{127, 0, 0, 1})
+	if c.config.AllowLAN {
+		listen = netip.IPv6Unspecified()
+		if c.config.BindAddress != "" && c.config.BindAddress != "*" {
+			bindAddress, err := netip.ParseAddr(c.config.BindAddress)
+			if err != nil {
+				c.logger.Warn("ignored invalid bind-address: ", c.config.BindAddress)
+			} else {
+				listen = bindAddress
+			}
+		}
+	}
+	return common.Ptr(badoption.Addr(listen))
+}
+
+func (c *converter) convertInbounds() {
+	listenOptions := func(port int) option.ListenOptions {
+		return option.ListenOptions{
+			Listen:     c.listenAddress(),
+			ListenPort: uint16(port),
+		}
+	}
+	if c.config.MixedPort != 0 {
+		c.options.Inbounds = append(c.options.Inbounds, option.Inbound{
+			Type:    C.TypeMixed,
+			Tag:     "mixed-in",
+			Options: &option.HTTPMixedInboundOptions{ListenOptions: listenOptions(c.config.MixedPort)},
+		})
+	}
+	if c.config.Port != 0 {
+		c.options.Inbounds = append(c.options.Inbounds, option.Inbound{
+			Type:    C.TypeHTTP,
+			Tag:     "http-in",
+			Options: &option.HTTPMixedInboundOptions{ListenOptions: listenOptions(c.config.Port)},
+		})
+	}
+	if c.config.SocksPort != 0 {
+		c.options.Inbounds = append(c.options.Inbounds, option.Inbound{
+			Type:    C.TypeSOCKS,
+			Tag:     "socks-in",
+			Options: &option.SocksInboundOptions{ListenOptions: listenOptions(c.config.SocksPort)},
+		})
+	}
+	if c.config.RedirPort != 0 {
+		c.options.Inbounds = append(c.options.Inbounds, option.Inbound{
+			Type:    C.TypeRedirect,
+			Tag:     "redir-in",
+			Options: &option.RedirectInboundOptions{ListenOptions: listenOptions(c.config.RedirPort)},
+		})
+	}
+	if c.config.TProxyPort != 0 {
+		c.options.Inbounds = append(c.options.Inbounds, option.Inbound{
+			Type:    C.TypeTProxy,
+			Tag:     "tproxy-in",
+			Options: &option.TProxyInboundOptions{ListenOptions: listenOptions(c.config.TProxyPort)},
+		})
+	}
+	if tun := c.config.Tun; tun != nil && tun.Enable {
+		address := badoption.Listable[netip.Prefix]{netip.MustParsePrefix("172.19.0.1/30")}
+		if c.config.IPv6 == nil || *c.config.IPv6 {
+			address = append(address, netip.MustParsePrefix("fdfe:dcba:9876::1/126"))
+		}
+		c.options.Inbounds = append(c.options.Inbounds, option.Inbound{
+			Type: C.TypeTun,
+			Tag:  "tun-in",
+			Options: &option.TunInboundOptions{
+				InterfaceName: tun.Device,
+				MTU:           tun.MTU,
+				Address:       address,
+				AutoRoute:     tun.AutoRoute,
+				StrictRoute:   tun.StrictRoute,
+				Stack:         strings.ToLower(tun.Stack),
+			},
+		})
+		c.route.AutoDetectInterface = tun.AutoDetectInterface
+	}
+	c.route.DefaultInterface = c.config.InterfaceName
+	c.route.DefaultMark = option.FwMark(c.config.RoutingMark)
+	switch c.config.FindProcessMode {
+	case "always":
+		c.route.FindProcess = true
+	case "", "strict", "off":
+	default:
+		c.logger.Warn("ignored unsupported find-process-mode: ", c.config.FindProcessMode)
+	}
+}
+
+func (c *converter) convertProxies() {
+	for _, proxy := range c.config.Proxies {
+		outbound, err := proxy.Outbound()
+		if err != nil {
+			c.logger.Warn("ignored proxy ", proxy.Name, ": ", err)
+			continue
+		}
+		c.outboundTags[outbound.Tag] = true
+		c.options.Outbounds = append(c.options.Outbounds, outbound)
+	}
+}
+
+func (c *converter) convertProxyProviders() {
+	for _, name := range sortedKeys(c.config.ProxyProviders) {
+		provider := c.config.ProxyProviders[name]
+		if provider.Type != "http" {
+			c.logger.Warn("ignored proxy-provider ", name, ": unsupported type: ", provider.Type)
+			continue
+		}
+		subscription := &option.SubscriptionServiceOptions{
+			URL:            provider.URL,
+			DownloadDetour: c.outboundReference(provider.Proxy),
+			UpdateInterval: seconds(provider.Interval),
+		}
+		if provider.Filter != "" {
+			subscription.Include = []string{provider.Filter}
+		}
+		if provider.ExcludeFilter != "" {
+			subscription.Exclude = []string{provider.ExcludeFilter}
+		}
+		c.subscriptions[name] = subscription
+	}
+}
+
+func (c *converter) convertExperimental() {
+	mode := strings.ToLower(c.config.Mode)
+	if c.config.ExternalController == "" {
+		if mode != "" && mode != "rule" {
+			c.logger.Warn("ignored mode ", c.config.Mode, ": external-controller is required to switch modes")
+		}
+		return
+	}
+	var defaultMode string
+	switch mode {
+	case "global":
+		defaultMode = "Global"
+	case "direct":
+		defaultMode = "Direct"
+	}
+	c.options.Experimental = &option.ExperimentalOptions{
+		ClashAPI: &option.ClashAPIOptions{
+			ExternalController: c.config.ExternalController,
+			ExternalUI:         c.config.ExternalUI,
+			Secret:             c.config.Secret,
+			DefaultMode:        defaultMode,
+		},
+		CacheFile: &option.CacheFileOptions{
+			Enabled: true,
+		},
+	}
+}
+
+// outboundReference maps a Clash proxy or group name to an outbound tag usable by static options.
+func (c *converter) outboundReference(name string) string {
+	switch name {
+	case "", tagDirect:
+		return ""
+	}
+	if provider, isDynamic := c.dynamicTags[name]; isDynamic {
+		c.logger.Warn("ignored reference to ", name, ": outbounds of proxy-provider ", provider, " are only available to rules")
+		return ""
+	}
+	return name
+}
+
+func seconds(value int) badoption.Duration {
+	return badoption.Duration(time.Duration(value) * time.Second)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
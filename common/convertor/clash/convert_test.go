@@ -0,0 +1,55 @@
+package clash
+
+import (
+	"testing"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/logger"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvert(t *testing.T) {
+	t.Parallel()
+	content := `
+mixed-port: 7890
+dns:
+  enable: true
+  default-nameserver: [223.5.5.5]
+  nameserver: [https://dns.alidns.com/dns-query]
+  nameserver-policy:
+    "geosite:cn,private": 223.5.5.5
+proxies:
+  - {name: hk, type: ss, server: 1.1.1.1, port: 8388, cipher: aes-128-gcm, password: password}
+proxy-groups:
+  - {name: Proxy, type: select, proxies: [hk, DIRECT, unknown]}
+rules:
+  - DOMAIN-SUFFIX,google.com,Proxy
+  - DOMAIN-KEYWORD,youtube,Proxy
+  - AND,((NETWORK,UDP),(DST-PORT,443)),REJECT
+  - IP-CIDR,10.0.0.0/8,DIRECT
+  - SCRIPT,quic,REJECT
+  - MATCH,Proxy
+`
+	options, err := Convert([]byte(content), logger.NOP())
+	require.NoError(t, err)
+	require.Len(t, options.Inbounds, 1)
+	require.Equal(t, C.TypeMixed, options.Inbounds[0].Type)
+	require.Equal(t, []string{"Proxy", "hk", "DIRECT"}, []string{options.Outbounds[0].Tag, options.Outbounds[1].Tag, options.Outbounds[2].Tag})
+	require.Equal(t, []string{"hk", "DIRECT"}, []string(options.Outbounds[0].Options.(*option.SelectorOutboundOptions).Outbounds))
+	rules := options.Route.Rules
+	require.Len(t, rules, 4)
+	require.Equal(t, []string{"google.com"}, []string(rules[0].DefaultOptions.DomainSuffix))
+	require.Equal(t, []string{"youtube"}, []string(rules[0].DefaultOptions.DomainKeyword))
+	require.Equal(t, "Proxy", rules[0].DefaultOptions.RouteOptions.Outbound)
+	require.Equal(t, C.RuleTypeLogical, rules[1].Type)
+	require.Equal(t, C.RuleActionTypeReject, rules[1].LogicalOptions.Action)
+	require.Equal(t, C.RuleActionTypeResolve, rules[2].DefaultOptions.Action)
+	require.Equal(t, []string{"10.0.0.0/8"}, []string(rules[3].DefaultOptions.IPCIDR))
+	require.Equal(t, "Proxy", options.Route.Final)
+	require.Equal(t, "dns", options.DNS.Final)
+	require.Len(t, options.DNS.Rules, 1)
+	require.Equal(t, []string{"geosite-cn", "geosite-private"}, []string(options.DNS.Rules[0].DefaultOptions.RuleSet))
+	require.Equal(t, "default-dns", options.DNS.Rules[0].DefaultOptions.RouteOptions.Server)
+}
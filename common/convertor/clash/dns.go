@@ -0,0 +1,383 @@
+package clash
+
+import (
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json/badjson"
+	"github.com/sagernet/sing/common/json/badoption"
+
+	mDNS "github.com/miekg/dns"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	dnsTagDefault     = "default-dns"
+	dnsTagMain        = "dns"
+	dnsTagProxyServer = "proxy-server-dns"
+	dnsTagHosts       = "hosts"
+	dnsTagFakeIP      = "fakeip"
+)
+
+type DNS struct {
+	Enable                bool      `yaml:"enable"`
+	IPv6                  *bool     `yaml:"ipv6,omitempty"`
+	Listen                string    `yaml:"listen,omitempty"`
+	UseHosts              *bool     `yaml:"use-hosts,omitempty"`
+	EnhancedMode          string    `yaml:"enhanced-mode,omitempty"`
+	FakeIPRange           string    `yaml:"fake-ip-range,omitempty"`
+	FakeIPFilter          []string  `yaml:"fake-ip-filter,omitempty"`
+	DefaultNameserver     []string  `yaml:"default-nameserver,omitempty"`
+	Nameserver            []string  `yaml:"nameserver,omitempty"`
+	Fallback              []string  `yaml:"fallback,omitempty"`
+	NameserverPolicy      yaml.Node `yaml:"nameserver-policy,omitempty"`
+	ProxyServerNameserver []string  `yaml:"proxy-server-nameserver,omitempty"`
+	DirectNameserver      []string  `yaml:"direct-nameserver,omitempty"`
+}
+
+type dnsConverter struct {
+	*converter
+	options    option.DNSOptions
+	serverTags map[string]string
+	resolver   string
+}
+
+func (c *converter) convertDNS() {
+	dns := c.config.DNS
+	if dns == nil || !dns.Enable {
+		return
+	}
+	d := &dnsConverter{
+		converter:  c,
+		serverTags: make(map[string]string),
+	}
+	if dns.IPv6 != nil && !*dns.IPv6 {
+		d.options.Strategy = option.DomainStrategy(C.DomainStrategyIPv4Only)
+	}
+	if dns.Listen != "" {
+		c.logger.Warn("ignored dns.listen: use the hijack-dns rule action instead")
+	}
+	if len(dns.Fallback) > 0 {
+		c.logger.Warn("ignored dns.fallback: fallback nameservers are not supported")
+	}
+	if len(dns.DirectNameserver) > 0 {
+		c.logger.Warn("ignored dns.direct-nameserver: not supported")
+	}
+	if d.addServer(dnsTagDefault, "default-nameserver", dns.DefaultNameserver) {
+		d.resolver = dnsTagDefault
+	}
+	if !d.addServer(dnsTagMain, "nameserver", dns.Nameserver) {
+		d.options.Servers = append(d.options.Servers, option.DNSServerOptions{
+			Type:    C.DNSTypeLocal,
+			Tag:     dnsTagMain,
+			Options: &option.LocalDNSServerOptions{},
+		})
+	}
+	d.options.Final = dnsTagMain
+	resolver := dnsTagMain
+	if d.addServer(dnsTagProxyServer, "proxy-server-nameserver", dns.ProxyServerNameserver) {
+		resolver = dnsTagProxyServer
+	}
+	c.route.DefaultDomainResolver = &option.DomainResolveOptions{Server: resolver}
+	if dns.UseHosts == nil || *dns.UseHosts {
+		d.convertHosts()
+	}
+	if dns.EnhancedMode == "fake-ip" {
+		d.convertFakeIP()
+	}
+	d.convertPolicy()
+	c.options.DNS = &d.options
+}
+
+func (d *dnsConverter) addServer(tag string, section string, addresses []string) bool {
+	if len(addresses) == 0 {
+		return false
+	}
+	if len(addresses) > 1 {
+		d.logger.Warn("dns.", section, ": only the first nameserver is used")
+	}
+	server, err := d.parseServer(tag, addresses[0])
+	if err != nil {
+		d.logger.Warn("ignored dns.", section, ": ", err)
+		return false
+	}
+	d.options.Servers = append(d.options.Servers, server)
+	d.serverTags[addresses[0]] = tag
+	return true
+}
+
+func (d *dnsConverter) parseServer(tag string, address string) (option.DNSServerOptions, error) {
+	var dialerOptions option.DialerOptions
+	if serverAddress, detour, hasDetour := strings.Cut(address, "#"); hasDetour {
+		address = serverAddress
+		if strings.ContainsAny(detour, "=&") {
+			d.logger.Warn("dns server ", address, ": ignored unsupported parameters: ", detour)
+		} else {
+			dialerOptions.Detour = d.outboundReference(detour)
+		}
+	}
+	switch {
+	case address == "system" || address == "system://":
+		return option.DNSServerOptions{
+			Type:    C.DNSTypeLocal,
+			Tag:     tag,
+			Options: &option.LocalDNSServerOptions{RawLocalDNSServerOptions: option.RawLocalDNSServerOptions{DialerOptions: dialerOptions}},
+		}, nil
+	case strings.HasPrefix(address, "dhcp://"):
+		interfaceName := strings.TrimPrefix(address, "dhcp://")
+		if interfaceName == "system" {
+			interfaceName = ""
+		}
+		return option.DNSServerOptions{
+			Type: C.DNSTypeDHCP,
+			Tag:  tag,
+			Options: &option.DHCPDNSServerOptions{
+				LocalDNSServerOptions: option.LocalDNSServerOptions{RawLocalDNSServerOptions: option.RawLocalDNSServerOptions{DialerOptions: dialerOptions}},
+				Interface:             interfaceName,
+			},
+		}, nil
+	case !strings.Contains(address, "://"):
+		address = "udp://" + address
+	}
+	serverURL, err := url.Parse(address)
+	if err != nil {
+		return option.DNSServerOptions{}, E.Cause(err, "parse dns server ", address)
+	}
+	if serverURL.Hostname() == "" {
+		return option.DNSServerOptions{}, E.New("missing dns server address: ", address)
+	}
+	if _, err = netip.ParseAddr(serverURL.Hostname()); err != nil {
+		if d.resolver == "" {
+			d.logger.Warn("dns server ", address, ": default-nameserver is required to resolve the server address")
+		} else {
+			dialerOptions.DomainResolver = &option.DomainResolveOptions{Server: d.resolver}
+		}
+	}
+	var serverPort uint16
+	if portString := serverURL.Port(); portString != "" {
+		port, err := strconv.ParseUint(portString, 10, 16)
+		if err != nil {
+			return option.DNSServerOptions{}, E.Cause(err, "parse dns server port")
+		}
+		serverPort = uint16(port)
+	}
+	remoteOptions := option.RemoteDNSServerOptions{
+		RawLocalDNSServerOptions: option.RawLocalDNSServerOptions{DialerOptions: dialerOptions},
+		DNSServerAddressOptions: option.DNSServerAddressOptions{
+			Server:     serverURL.Hostname(),
+			ServerPort: serverPort,
+		},
+	}
+	server := option.DNSServerOptions{Tag: tag}
+	switch serverURL.Scheme {
+	case C.DNSTypeUDP, C.DNSTypeTCP:
+		server.Type = serverURL.Scheme
+		server.Options = &remoteOptions
+	case C.DNSTypeTLS, C.DNSTypeQUIC:
+		server.Type = serverURL.Scheme
+		server.Options = &option.RemoteTLSDNSServerOptions{RemoteDNSServerOptions: remoteOptions}
+	case C.DNSTypeHTTPS, C.DNSTypeHTTP3:
+		server.Type = serverURL.Scheme
+		httpsOptions := &option.RemoteHTTPSDNSServerOptions{
+			RemoteTLSDNSServerOptions: option.RemoteTLSDNSServerOptions{RemoteDNSServerOptions: remoteOptions},
+		}
+		if serverURL.Path != "/dns-query" {
+			httpsOptions.Path = serverURL.Path
+		}
+		server.Options = httpsOptions
+	default:
+		return option.DNSServerOptions{}, E.New("unsupported dns server scheme: ", serverURL.Scheme)
+	}
+	return server, nil
+}
+
+func (d *dnsConverter) serverForAddress(address string) (string, error) {
+	if tag, loaded := d.serverTags[address]; loaded {
+		return tag, nil
+	}
+	tag := "dns-" + strconv.Itoa(len(d.options.Servers))
+	server, err := d.parseServer(tag, address)
+	if err != nil {
+		return "", err
+	}
+	d.options.Servers = append(d.options.Servers, server)
+	d.serverTags[address] = tag
+	return tag, nil
+}
+
+func (d *dnsConverter) convertHosts() {
+	hosts := d.config.Hosts
+	if hosts.Kind != yaml.MappingNode || len(hosts.Content) == 0 {
+		return
+	}
+	var (
+		predefined badjson.TypedMap[string, badoption.Listable[netip.Addr]]
+		domains    []string
+	)
+	for i := 0; i+1 < len(hosts.Content); i += 2 {
+		domain := hosts.Content[i].Value
+		var values []string
+		err := hosts.Content[i+1].Decode(&values)
+		if err != nil {
+			var value string
+			err = hosts.Content[i+1].Decode(&value)
+			values = []string{value}
+		}
+		if err != nil || strings.ContainsAny(domain, "*+") {
+			d.logger.Warn("ignored unsupported hosts entry: ", domain)
+			continue
+		}
+		var addresses badoption.Listable[netip.Addr]
+		for _, value := range values {
+			address, err := netip.ParseAddr(value)
+			if err != nil {
+				d.logger.Warn("hosts entry ", domain, ": ignored unsupported value: ", value)
+				continue
+			}
+			addresses = append(addresses, address)
+		}
+		if len(addresses) == 0 {
+			continue
+		}
+		predefined.Put(domain, addresses)
+		domains = append(domains, domain)
+	}
+	if len(domains) == 0 {
+		return
+	}
+	d.options.Servers = append(d.options.Servers, option.DNSServerOptions{
+		Type: C.DNSTypeHosts,
+		Tag:  dnsTagHosts,
+		Options: &option.HostsDNSServerOptions{
+			Predefined: &predefined,
+		},
+	})
+	rule := dnsRouteRule(dnsTagHosts)
+	rule.DefaultOptions.Domain = domains
+	d.options.Rules = append(d.options.Rules, rule)
+}
+
+func (d *dnsConverter) convertFakeIP() {
+	inet4Range := d.config.DNS.FakeIPRange
+	if inet4Range == "" {
+		inet4Range = "198.18.0.1/16"
+	}
+	inet4Prefix, err := netip.ParsePrefix(inet4Range)
+	if err != nil {
+		d.logger.Warn("ignored invalid dns.fake-ip-range: ", inet4Range)
+		inet4Prefix = netip.MustParsePrefix("198.18.0.1/16")
+	}
+	fakeIPOptions := &option.FakeIPDNSServerOptions{
+		Inet4Range: common.Ptr(badoption.Prefix(inet4Prefix)),
+	}
+	if d.options.Strategy != option.DomainStrategy(C.DomainStrategyIPv4Only) {
+		fakeIPOptions.Inet6Range = common.Ptr(badoption.Prefix(netip.MustParsePrefix("fc00::/18")))
+	}
+	d.options.Servers = append(d.options.Servers, option.DNSServerOptions{
+		Type:    C.DNSTypeFakeIP,
+		Tag:     dnsTagFakeIP,
+		Options: fakeIPOptions,
+	})
+	if len(d.config.DNS.FakeIPFilter) > 0 {
+		rule := dnsRouteRule(dnsTagMain)
+		if d.applyDomainPatterns(&rule.DefaultOptions.RawDefaultDNSRule, d.config.DNS.FakeIPFilter) {
+			d.options.Rules = append(d.options.Rules, rule)
+		}
+	}
+	rule := dnsRouteRule(dnsTagFakeIP)
+	rule.DefaultOptions.QueryType = []option.DNSQueryType{option.DNSQueryType(mDNS.TypeA), option.DNSQueryType(mDNS.TypeAAAA)}
+	d.options.Rules = append(d.options.Rules, rule)
+}
+
+func (d *dnsConverter) convertPolicy() {
+	policy := d.config.DNS.NameserverPolicy
+	if policy.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(policy.Content); i += 2 {
+		patterns := strings.Split(policy.Content[i].Value, ",")
+		// `geosite:cn,private` applies the prefix to every item
+		for _, prefix := range []string{"geosite:", "rule-set:"} {
+			if strings.HasPrefix(patterns[0], prefix) {
+				for j := 1; j < len(patterns); j++ {
+					patterns[j] = prefix + strings.TrimSpace(patterns[j])
+				}
+			}
+		}
+		var servers []string
+		err := policy.Content[i+1].Decode(&servers)
+		if err != nil {
+			var server string
+			err = policy.Content[i+1].Decode(&server)
+			servers = []string{server}
+		}
+		if err != nil || len(servers) == 0 {
+			d.logger.Warn("ignored invalid dns.nameserver-policy entry: ", policy.Content[i].Value)
+			continue
+		}
+		if len(servers) > 1 {
+			d.logger.Warn("dns.nameserver-policy ", policy.Content[i].Value, ": only the first nameserver is used")
+		}
+		tag, err := d.serverForAddress(servers[0])
+		if err != nil {
+			d.logger.Warn("ignored dns.nameserver-policy ", policy.Content[i].Value, ": ", err)
+			continue
+		}
+		rule := dnsRouteRule(tag)
+		if d.applyDomainPatterns(&rule.DefaultOptions.RawDefaultDNSRule, patterns) {
+			d.options.Rules = append(d.options.Rules, rule)
+		}
+	}
+}
+
+// applyDomainPatterns converts Clash domain wildcards (`+.example.com`, `*.example.com`, `geosite:cn` and `rule-set:name`).
+func (d *dnsConverter) applyDomainPatterns(rule *option.RawDefaultDNSRule, patterns []string) bool {
+	var matched bool
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		switch {
+		case pattern == "":
+			continue
+		case strings.HasPrefix(pattern, "geosite:"):
+			rule.RuleSet = append(rule.RuleSet, d.geoRuleSet("geosite", strings.TrimPrefix(pattern, "geosite:")))
+		case strings.HasPrefix(pattern, "rule-set:"):
+			tag := strings.TrimPrefix(pattern, "rule-set:")
+			if !d.ruleSets[tag] {
+				d.logger.Warn("ignored domain pattern ", pattern, ": rule-provider not found or unsupported")
+				continue
+			}
+			rule.RuleSet = append(rule.RuleSet, tag)
+		case strings.HasPrefix(pattern, "+."):
+			rule.DomainSuffix = append(rule.DomainSuffix, strings.TrimPrefix(pattern, "+."))
+		case strings.HasPrefix(pattern, ".") && !strings.Contains(pattern, "*"):
+			rule.DomainSuffix = append(rule.DomainSuffix, pattern)
+		case strings.Contains(pattern, "*"):
+			rule.DomainRegex = append(rule.DomainRegex, "^"+strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, `[^.]+`)+"$")
+		default:
+			rule.Domain = append(rule.Domain, pattern)
+		}
+		matched = true
+	}
+	return matched
+}
+
+func dnsRouteRule(server string) option.DNSRule {
+	return option.DNSRule{
+		Type: C.RuleTypeDefault,
+		DefaultOptions: option.DefaultDNSRule{
+			DNSRuleAction: option.DNSRuleAction{
+				Action: C.RuleActionTypeRoute,
+				RouteOptions: option.DNSRouteActionOptions{
+					Server: server,
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,156 @@
+package clash
+
+import (
+	"regexp"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+)
+
+type ProxyGroup struct {
+	Name              string   `yaml:"name"`
+	Type              string   `yaml:"type"`
+	Proxies           []string `yaml:"proxies,omitempty"`
+	Use               []string `yaml:"use,omitempty"`
+	URL               string   `yaml:"url,omitempty"`
+	Interval          int      `yaml:"interval,omitempty"`
+	Tolerance         uint16   `yaml:"tolerance,omitempty"`
+	Filter            string   `yaml:"filter,omitempty"`
+	ExcludeFilter     string   `yaml:"exclude-filter,omitempty"`
+	IncludeAll        bool     `yaml:"include-all,omitempty"`
+	IncludeAllProxies bool     `yaml:"include-all-proxies,omitempty"`
+}
+
+func (g ProxyGroup) outboundType() (string, bool) {
+	switch g.Type {
+	case "select":
+		return C.TypeSelector, true
+	case "url-test", "fallback", "load-balance":
+		return C.TypeURLTest, true
+	default:
+		return "", false
+	}
+}
+
+func (c *converter) convertGroups() {
+	groups := make([]ProxyGroup, 0, len(c.config.ProxyGroups))
+	for _, group := range c.config.ProxyGroups {
+		if _, supported := group.outboundType(); !supported {
+			c.logger.Warn("ignored proxy-group ", group.Name, ": unsupported type: ", group.Type)
+			continue
+		}
+		switch group.Type {
+		case "fallback", "load-balance":
+			c.logger.Warn("proxy-group ", group.Name, ": ", group.Type, " is approximated with urltest")
+		}
+		groups = append(groups, group)
+		if providers := c.groupProviders(group); len(providers) > 0 {
+			c.dynamicTags[group.Name] = providers[0]
+		} else {
+			c.outboundTags[group.Name] = true
+		}
+	}
+	var groupOutbounds []option.Outbound
+	for _, group := range groups {
+		outboundType, _ := group.outboundType()
+		members := c.groupMembers(group)
+		if provider, isDynamic := c.dynamicTags[group.Name]; isDynamic {
+			providers := c.groupProviders(group)
+			if len(providers) > 1 {
+				c.logger.Warn("proxy-group ", group.Name, ": only proxy-provider ", provider, " is used")
+			}
+			subscription := c.subscriptions[provider]
+			groupOptions := option.SubscriptionGroupOptions{
+				Type:      outboundType,
+				Tag:       group.Name,
+				Outbounds: members,
+				URL:       group.URL,
+				Interval:  seconds(group.Interval),
+				Tolerance: group.Tolerance,
+			}
+			if group.Filter != "" {
+				groupOptions.Include = []string{group.Filter}
+			}
+			if group.ExcludeFilter != "" {
+				groupOptions.Exclude = []string{group.ExcludeFilter}
+			}
+			subscription.Groups = append(subscription.Groups, groupOptions)
+			continue
+		}
+		if len(members) == 0 {
+			c.logger.Warn("proxy-group ", group.Name, ": no usable proxies, ", tagDirect, " is used instead")
+			members = []string{tagDirect}
+		}
+		var options any
+		switch outboundType {
+		case C.TypeSelector:
+			options = &option.SelectorOutboundOptions{
+				Outbounds: members,
+			}
+		default:
+			options = &option.URLTestOutboundOptions{
+				Outbounds: members,
+				URL:       group.URL,
+				Interval:  seconds(group.Interval),
+				Tolerance: group.Tolerance,
+			}
+		}
+		groupOutbounds = append(groupOutbounds, option.Outbound{
+			Type:    outboundType,
+			Tag:     group.Name,
+			Options: options,
+		})
+	}
+	c.options.Outbounds = append(groupOutbounds, c.options.Outbounds...)
+}
+
+func (c *converter) groupProviders(group ProxyGroup) []string {
+	var providers []string
+	for _, name := range group.Use {
+		if _, loaded := c.subscriptions[name]; loaded {
+			providers = append(providers, name)
+		} else {
+			c.logger.Warn("proxy-group ", group.Name, ": ignored unavailable proxy-provider ", name)
+		}
+	}
+	return providers
+}
+
+func (c *converter) groupMembers(group ProxyGroup) []string {
+	var members []string
+	for _, name := range group.Proxies {
+		switch {
+		case name == tagDirect:
+		case name == tagReject || name == "REJECT-DROP":
+			c.needReject = true
+			name = tagReject
+		case c.dynamicTags[name] != "":
+			c.logger.Warn("proxy-group ", group.Name, ": ignored ", name, ": outbounds of proxy-provider ", c.dynamicTags[name], " are only available to rules")
+			continue
+		case !c.outboundTags[name]:
+			c.logger.Warn("proxy-group ", group.Name, ": ignored unknown proxy ", name)
+			continue
+		}
+		members = append(members, name)
+	}
+	if group.IncludeAll || group.IncludeAllProxies {
+		var filter, excludeFilter *regexp.Regexp
+		if group.Filter != "" {
+			filter, _ = regexp.Compile(group.Filter)
+		}
+		if group.ExcludeFilter != "" {
+			excludeFilter, _ = regexp.Compile(group.ExcludeFilter)
+		}
+		for _, proxy := range c.config.Proxies {
+			if !c.outboundTags[proxy.Name] || common.Contains(members, proxy.Name) {
+				continue
+			}
+			if filter != nil && !filter.MatchString(proxy.Name) || excludeFilter != nil && excludeFilter.MatchString(proxy.Name) {
+				continue
+			}
+			members = append(members, proxy.Name)
+		}
+	}
+	return members
+}
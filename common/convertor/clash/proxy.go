@@ -0,0 +1,407 @@
+package clash
+
+import (
+	"strconv"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json/badoption"
+)
+
+type Proxy struct {
+	Name              string            `yaml:"name"`
+	Type              string            `yaml:"type"`
+	Server            string            `yaml:"server"`
+	Port              uint16            `yaml:"port"`
+	Ports             string            `yaml:"ports,omitempty"`
+	Username          string            `yaml:"username,omitempty"`
+	Password          string            `yaml:"password,omitempty"`
+	UUID              string            `yaml:"uuid,omitempty"`
+	AlterID           int               `yaml:"alterId,omitempty"`
+	Cipher            string            `yaml:"cipher,omitempty"`
+	UDP               *bool             `yaml:"udp,omitempty"`
+	UDPOverTCP        bool              `yaml:"udp-over-tcp,omitempty"`
+	TLS               bool              `yaml:"tls,omitempty"`
+	SkipCertVerify    bool              `yaml:"skip-cert-verify,omitempty"`
+	ServerName        string            `yaml:"servername,omitempty"`
+	SNI               string            `yaml:"sni,omitempty"`
+	ALPN              []string          `yaml:"alpn,omitempty"`
+	ClientFingerprint string            `yaml:"client-fingerprint,omitempty"`
+	Fingerprint       string            `yaml:"fingerprint,omitempty"`
+	Flow              string            `yaml:"flow,omitempty"`
+	PacketEncoding    string            `yaml:"packet-encoding,omitempty"`
+	Network           string            `yaml:"network,omitempty"`
+	WSOptions         *WSOptions        `yaml:"ws-opts,omitempty"`
+	HTTPOptions       *HTTPOptions      `yaml:"http-opts,omitempty"`
+	H2Options         *H2Options        `yaml:"h2-opts,omitempty"`
+	GRPCOptions       *GRPCOptions      `yaml:"grpc-opts,omitempty"`
+	RealityOptions    *RealityOptions   `yaml:"reality-opts,omitempty"`
+	Plugin            string            `yaml:"plugin,omitempty"`
+	PluginOptions     map[string]any    `yaml:"plugin-opts,omitempty"`
+	Obfs              string            `yaml:"obfs,omitempty"`
+	ObfsPassword      string            `yaml:"obfs-password,omitempty"`
+	Up                string            `yaml:"up,omitempty"`
+	Down              string            `yaml:"down,omitempty"`
+	CongestionControl string            `yaml:"congestion-controller,omitempty"`
+	UDPRelayMode      string            `yaml:"udp-relay-mode,omitempty"`
+	ReduceRTT         bool              `yaml:"reduce-rtt,omitempty"`
+	DisableSNI        bool              `yaml:"disable-sni,omitempty"`
+	Headers           map[string]string `yaml:"headers,omitempty"`
+	DialerProxy       string            `yaml:"dialer-proxy,omitempty"`
+}
+
+type WSOptions struct {
+	Path                string            `yaml:"path,omitempty"`
+	Headers             map[string]string `yaml:"headers,omitempty"`
+	MaxEarlyData        uint32            `yaml:"max-early-data,omitempty"`
+	EarlyDataHeaderName string            `yaml:"early-data-header-name,omitempty"`
+	V2RayHTTPUpgrade    bool              `yaml:"v2ray-http-upgrade,omitempty"`
+}
+
+type HTTPOptions struct {
+	Method  string              `yaml:"method,omitempty"`
+	Path    []string            `yaml:"path,omitempty"`
+	Headers map[string][]string `yaml:"headers,omitempty"`
+}
+
+type H2Options struct {
+	Host []string `yaml:"host,omitempty"`
+	Path string   `yaml:"path,omitempty"`
+}
+
+type GRPCOptions struct {
+	ServiceName string `yaml:"grpc-service-name,omitempty"`
+}
+
+type RealityOptions struct {
+	PublicKey string `yaml:"public-key"`
+	ShortID   string `yaml:"short-id,omitempty"`
+}
+
+// Outbound converts the Clash proxy into a sing-box outbound.
+func (p Proxy) Outbound() (option.Outbound, error) {
+	if p.Name == "" {
+		return option.Outbound{}, E.New("missing proxy name")
+	}
+	var (
+		outboundType string
+		options      any
+		err          error
+	)
+	server := option.ServerOptions{
+		Server:     p.Server,
+		ServerPort: p.Port,
+	}
+	var dialer option.DialerOptions
+	if p.DialerProxy != "" {
+		dialer.Detour = p.DialerProxy
+	}
+	switch p.Type {
+	case "ss":
+		outboundType = C.TypeShadowsocks
+		ssOptions := &option.ShadowsocksOutboundOptions{
+			DialerOptions: dialer,
+			ServerOptions: server,
+			Method:        p.Cipher,
+			Password:      p.Password,
+			Network:       p.network(),
+		}
+		if p.UDPOverTCP {
+			ssOptions.UDPOverTCP = &option.UDPOverTCPOptions{Enabled: true}
+		}
+		ssOptions.Plugin, ssOptions.PluginOptions, err = p.shadowsocksPlugin()
+		options = ssOptions
+	case "vmess":
+		outboundType = C.TypeVMess
+		cipher := p.Cipher
+		if cipher == "" {
+			cipher = "auto"
+		}
+		vmessOptions := &option.VMessOutboundOptions{
+			DialerOptions:  dialer,
+			ServerOptions:  server,
+			UUID:           p.UUID,
+			Security:       cipher,
+			AlterId:        p.AlterID,
+			Network:        p.network(),
+			PacketEncoding: p.PacketEncoding,
+		}
+		vmessOptions.TLS = p.tlsOptions(p.TLS)
+		vmessOptions.Transport, err = p.transportOptions()
+		options = vmessOptions
+	case "vless":
+		outboundType = C.TypeVLESS
+		vlessOptions := &option.VLESSOutboundOptions{
+			DialerOptions: dialer,
+			ServerOptions: server,
+			UUID:          p.UUID,
+			Flow:          p.Flow,
+			Network:       p.network(),
+		}
+		if p.PacketEncoding != "" {
+			vlessOptions.PacketEncoding = &p.PacketEncoding
+		}
+		vlessOptions.TLS = p.tlsOptions(p.TLS)
+		vlessOptions.Transport, err = p.transportOptions()
+		options = vlessOptions
+	case "trojan":
+		outboundType = C.TypeTrojan
+		trojanOptions := &option.TrojanOutboundOptions{
+			DialerOptions: dialer,
+			ServerOptions: server,
+			Password:      p.Password,
+			Network:       p.network(),
+		}
+		trojanOptions.TLS = p.tlsOptions(true)
+		trojanOptions.Transport, err = p.transportOptions()
+		options = trojanOptions
+	case "hysteria2":
+		outboundType = C.TypeHysteria2
+		hysteria2Options := &option.Hysteria2OutboundOptions{
+			DialerOptions: dialer,
+			ServerOptions: server,
+			Password:      p.Password,
+			UpMbps:        parseBandwidth(p.Up),
+			DownMbps:      parseBandwidth(p.Down),
+		}
+		if p.Ports != "" {
+			hysteria2Options.ServerPorts = strings.Split(strings.ReplaceAll(p.Ports, "-", ":"), ",")
+		}
+		if p.Obfs != "" {
+			hysteria2Options.Obfs = &option.Hysteria2Obfs{
+				Type:     p.Obfs,
+				Password: p.ObfsPassword,
+			}
+		}
+		hysteria2Options.TLS = p.tlsOptions(true)
+		options = hysteria2Options
+	case "tuic":
+		outboundType = C.TypeTUIC
+		tuicOptions := &option.TUICOutboundOptions{
+			DialerOptions:     dialer,
+			ServerOptions:     server,
+			UUID:              p.UUID,
+			Password:          p.Password,
+			CongestionControl: p.CongestionControl,
+			UDPRelayMode:      p.UDPRelayMode,
+			ZeroRTTHandshake:  p.ReduceRTT,
+		}
+		tuicOptions.TLS = p.tlsOptions(true)
+		tuicOptions.TLS.DisableSNI = p.DisableSNI
+		options = tuicOptions
+	case "socks5":
+		outboundType = C.TypeSOCKS
+		options = &option.SOCKSOutboundOptions{
+			DialerOptions: dialer,
+			ServerOptions: server,
+			Username:      p.Username,
+			Password:      p.Password,
+			Network:       p.network(),
+		}
+	case "http":
+		outboundType = C.TypeHTTP
+		httpOptions := &option.HTTPOutboundOptions{
+			DialerOptions: dialer,
+			ServerOptions: server,
+			Username:      p.Username,
+			Password:      p.Password,
+		}
+		if len(p.Headers) > 0 {
+			httpOptions.Headers = make(badoption.HTTPHeader)
+			for key, value := range p.Headers {
+				httpOptions.Headers[key] = badoption.Listable[string]{value}
+			}
+		}
+		httpOptions.TLS = p.tlsOptions(p.TLS)
+		options = httpOptions
+	default:
+		return option.Outbound{}, E.New("unsupported proxy type: ", p.Type)
+	}
+	if err != nil {
+		return option.Outbound{}, E.Cause(err, "parse proxy ", p.Name)
+	}
+	return option.Outbound{
+		Type:    outboundType,
+		Tag:     p.Name,
+		Options: options,
+	}, nil
+}
+
+func (p Proxy) network() option.NetworkList {
+	if p.UDP != nil && !*p.UDP {
+		return option.NetworkList("tcp")
+	}
+	return ""
+}
+
+func (p Proxy) tlsOptions(enabled bool) *option.OutboundTLSOptions {
+	if !enabled {
+		return nil
+	}
+	serverName := p.SNI
+	if serverName == "" {
+		serverName = p.ServerName
+	}
+	tlsOptions := &option.OutboundTLSOptions{
+		Enabled:    true,
+		ServerName: serverName,
+		Insecure:   p.SkipCertVerify,
+		ALPN:       p.ALPN,
+	}
+	if p.ClientFingerprint != "" {
+		tlsOptions.UTLS = &option.OutboundUTLSOptions{
+			Enabled:     true,
+			Fingerprint: p.ClientFingerprint,
+		}
+	}
+	if p.RealityOptions != nil {
+		tlsOptions.Reality = &option.OutboundRealityOptions{
+			Enabled:   true,
+			PublicKey: p.RealityOptions.PublicKey,
+			ShortID:   p.RealityOptions.ShortID,
+		}
+		if tlsOptions.UTLS == nil {
+			tlsOptions.UTLS = &option.OutboundUTLSOptions{
+				Enabled:     true,
+				Fingerprint: "chrome",
+			}
+		}
+	}
+	return tlsOptions
+}
+
+func (p Proxy) transportOptions() (*option.V2RayTransportOptions, error) {
+	switch p.Network {
+	case "", "tcp":
+		return nil, nil
+	case "ws":
+		wsOptions := common.PtrValueOrDefault(p.WSOptions)
+		host := wsOptions.Headers["Host"]
+		headers := make(badoption.HTTPHeader)
+		for key, value := range wsOptions.Headers {
+			if key != "Host" {
+				headers[key] = badoption.Listable[string]{value}
+			}
+		}
+		if wsOptions.V2RayHTTPUpgrade {
+			return &option.V2RayTransportOptions{
+				Type: C.V2RayTransportTypeHTTPUpgrade,
+				HTTPUpgradeOptions: option.V2RayHTTPUpgradeOptions{
+					Host:    host,
+					Path:    wsOptions.Path,
+					Headers: headers,
+				},
+			}, nil
+		}
+		return &option.V2RayTransportOptions{
+			Type: C.V2RayTransportTypeWebsocket,
+			WebsocketOptions: option.V2RayWebsocketOptions{
+				Host:                host,
+				Path:                wsOptions.Path,
+				Headers:             headers,
+				MaxEarlyData:        wsOptions.MaxEarlyData,
+				EarlyDataHeaderName: wsOptions.EarlyDataHeaderName,
+			},
+		}, nil
+	case "http":
+		httpOptions := common.PtrValueOrDefault(p.HTTPOptions)
+		var path string
+		if len(httpOptions.Path) > 0 {
+			path = httpOptions.Path[0]
+		}
+		return &option.V2RayTransportOptions{
+			Type: C.V2RayTransportTypeHTTP,
+			HTTPOptions: option.V2RayHTTPOptions{
+				Host:   httpOptions.Headers["Host"],
+				Path:   path,
+				Method: httpOptions.Method,
+			},
+		}, nil
+	case "h2":
+		h2Options := common.PtrValueOrDefault(p.H2Options)
+		return &option.V2RayTransportOptions{
+			Type: C.V2RayTransportTypeHTTP,
+			HTTPOptions: option.V2RayHTTPOptions{
+				Host: h2Options.Host,
+				Path: h2Options.Path,
+			},
+		}, nil
+	case "grpc":
+		return &option.V2RayTransportOptions{
+			Type: C.V2RayTransportTypeGRPC,
+			GRPCOptions: option.V2RayGRPCOptions{
+				ServiceName: common.PtrValueOrDefault(p.GRPCOptions).ServiceName,
+			},
+		}, nil
+	default:
+		return nil, E.New("unsupported network: ", p.Network)
+	}
+}
+
+func (p Proxy) shadowsocksPlugin() (string, string, error) {
+	switch p.Plugin {
+	case "":
+		return "", "", nil
+	case "obfs":
+		pluginOptions := []string{"obfs=" + pluginOption(p.PluginOptions, "mode")}
+		if host := pluginOption(p.PluginOptions, "host"); host != "" {
+			pluginOptions = append(pluginOptions, "obfs-host="+host)
+		}
+		return "obfs-local", strings.Join(pluginOptions, ";"), nil
+	case "v2ray-plugin":
+		pluginOptions := []string{"mode=" + pluginOption(p.PluginOptions, "mode")}
+		if host := pluginOption(p.PluginOptions, "host"); host != "" {
+			pluginOptions = append(pluginOptions, "host="+host)
+		}
+		if path := pluginOption(p.PluginOptions, "path"); path != "" {
+			pluginOptions = append(pluginOptions, "path="+path)
+		}
+		if pluginOption(p.PluginOptions, "tls") == "true" {
+			pluginOptions = append(pluginOptions, "tls")
+		}
+		if pluginOption(p.PluginOptions, "mux") == "true" {
+			pluginOptions = append(pluginOptions, "mux=4")
+		}
+		return "v2ray-plugin", strings.Join(pluginOptions, ";"), nil
+	default:
+		return "", "", E.New("unsupported shadowsocks plugin: ", p.Plugin)
+	}
+}
+
+func pluginOption(options map[string]any, key string) string {
+	value, loaded := options[key]
+	if !loaded {
+		return ""
+	}
+	switch typedValue := value.(type) {
+	case string:
+		return typedValue
+	case bool:
+		return strconv.FormatBool(typedValue)
+	case int:
+		return strconv.Itoa(typedValue)
+	default:
+		return ""
+	}
+}
+
+// parseBandwidth parses Clash bandwidth strings like "100", "100 Mbps" or "1 Gbps" into Mbps.
+func parseBandwidth(value string) int {
+	value = strings.TrimSpace(strings.ToLower(value))
+	if value == "" {
+		return 0
+	}
+	multiplier := 1
+	switch {
+	case strings.HasSuffix(value, "gbps"):
+		value = strings.TrimSuffix(value, "gbps")
+		multiplier = 1000
+	case strings.HasSuffix(value, "mbps"):
+		value = strings.TrimSuffix(value, "mbps")
+	}
+	bandwidth, _ := strconv.Atoi(strings.TrimSpace(value))
+	return bandwidth * multiplier
+}
@@ -0,0 +1,65 @@
+package clash
+
+import (
+	"testing"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProxies(t *testing.T) {
+	t.Parallel()
+	content := `
+proxies:
+  - name: ss
+    type: ss
+    server: example.com
+    port: 8388
+    cipher: aes-128-gcm
+    password: password
+    plugin: obfs
+    plugin-opts:
+      mode: tls
+      host: bing.com
+  - name: vmess
+    type: vmess
+    server: example.com
+    port: 443
+    uuid: b831381d-6324-4d53-ad4f-8cda48b30811
+    alterId: 0
+    cipher: auto
+    tls: true
+    servername: example.com
+    network: ws
+    ws-opts:
+      path: /path
+      headers:
+        Host: cdn.example.com
+  - name: hy2
+    type: hysteria2
+    server: example.com
+    port: 443
+    password: password
+    up: "30 Mbps"
+    down: "1 Gbps"
+  - name: unsupported
+    type: snell
+    server: example.com
+    port: 443
+`
+	outbounds, errors := ParseProxies([]byte(content))
+	require.Len(t, errors, 1)
+	require.Len(t, outbounds, 3)
+	ssOptions := outbounds[0].Options.(*option.ShadowsocksOutboundOptions)
+	require.Equal(t, "obfs-local", ssOptions.Plugin)
+	require.Equal(t, "obfs=tls;obfs-host=bing.com", ssOptions.PluginOptions)
+	require.Equal(t, C.TypeVMess, outbounds[1].Type)
+	vmessOptions := outbounds[1].Options.(*option.VMessOutboundOptions)
+	require.Equal(t, "cdn.example.com", vmessOptions.Transport.WebsocketOptions.Host)
+	require.Equal(t, "example.com", vmessOptions.TLS.ServerName)
+	hysteria2Options := outbounds[2].Options.(*option.Hysteria2OutboundOptions)
+	require.Equal(t, 30, hysteria2Options.UpMbps)
+	require.Equal(t, 1000, hysteria2Options.DownMbps)
+}
@@ -0,0 +1,434 @@
+package clash
+
+import (
+	"strconv"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json/badoption"
+)
+
+const (
+	geositeRuleSetURL = "https://raw.githubusercontent.com/SagerNet/sing-geosite/rule-set/geosite-"
+	geoipRuleSetURL   = "https://raw.githubusercontent.com/SagerNet/sing-geoip/rule-set/geoip-"
+)
+
+func (c *converter) convertRuleProviders() {
+	for _, name := range sortedKeys(c.config.RuleProviders) {
+		provider := c.config.RuleProviders[name]
+		location := provider.URL
+		if provider.Type == "file" {
+			location = provider.Path
+		}
+		var format string
+		switch {
+		case provider.Format == "mrs":
+		case strings.HasSuffix(location, ".srs"):
+			format = C.RuleSetFormatBinary
+		case strings.HasSuffix(location, ".json"):
+			format = C.RuleSetFormatSource
+		}
+		if format == "" {
+			c.logger.Warn("ignored rule-provider ", name, ": only sing-box rule-set files are supported")
+			continue
+		}
+		ruleSet := option.RuleSet{
+			Tag:    name,
+			Format: format,
+		}
+		switch provider.Type {
+		case "http":
+			ruleSet.Type = C.RuleSetTypeRemote
+			ruleSet.RemoteOptions = option.RemoteRuleSet{
+				URL:            provider.URL,
+				DownloadDetour: c.outboundReference(provider.Proxy),
+				UpdateInterval: seconds(provider.Interval),
+			}
+		case "file":
+			ruleSet.Type = C.RuleSetTypeLocal
+			ruleSet.LocalOptions = option.LocalRuleSet{
+				Path: provider.Path,
+			}
+		default:
+			c.logger.Warn("ignored rule-provider ", name, ": unsupported type: ", provider.Type)
+			continue
+		}
+		c.addRuleSet(ruleSet, provider.Behavior == "ipcidr")
+	}
+}
+
+func (c *converter) addRuleSet(ruleSet option.RuleSet, isIP bool) {
+	if c.ruleSets[ruleSet.Tag] {
+		return
+	}
+	c.ruleSets[ruleSet.Tag] = true
+	c.route.RuleSet = append(c.route.RuleSet, ruleSet)
+	if isIP {
+		c.ipRuleSets[ruleSet.Tag] = true
+	}
+}
+
+func (c *converter) geoRuleSet(kind string, name string) string {
+	tag := kind + "-" + strings.ToLower(name)
+	url := geositeRuleSetURL
+	if kind == "geoip" {
+		url = geoipRuleSetURL
+	}
+	c.addRuleSet(option.RuleSet{
+		Type:   C.RuleSetTypeRemote,
+		Tag:    tag,
+		Format: C.RuleSetFormatBinary,
+		RemoteOptions: option.RemoteRuleSet{
+			URL: url + strings.ToLower(name) + ".srs",
+		},
+	}, kind == "geoip")
+	return tag
+}
+
+func (c *converter) convertRules() {
+	if sniffer := c.config.Sniffer; sniffer != nil && sniffer.Enable {
+		c.route.Rules = append(c.route.Rules, actionRule(option.RuleAction{Action: C.RuleActionTypeSniff}))
+	}
+	if tun := c.config.Tun; tun != nil && tun.Enable && len(tun.DNSHijack) > 0 {
+		rule := actionRule(option.RuleAction{Action: C.RuleActionTypeHijackDNS})
+		rule.DefaultOptions.Protocol = []string{C.ProtocolDNS}
+		c.route.Rules = append(c.route.Rules, rule)
+	}
+	if c.config.ExternalController != "" {
+		globalTag := tagGlobal
+		if !c.outboundTags[globalTag] {
+			globalMembers := make([]string, 0, len(c.options.Outbounds)+1)
+			for _, outbound := range c.options.Outbounds {
+				globalMembers = append(globalMembers, outbound.Tag)
+			}
+			globalMembers = append(globalMembers, tagDirect)
+			c.options.Outbounds = append(c.options.Outbounds, option.Outbound{
+				Type: C.TypeSelector,
+				Tag:  globalTag,
+				Options: &option.SelectorOutboundOptions{
+					Outbounds: globalMembers,
+				},
+			})
+		}
+		directRule := actionRule(routeAction(tagDirect))
+		directRule.DefaultOptions.ClashMode = badoption.Listable[string]{"Direct"}
+		globalRule := actionRule(routeAction(globalTag))
+		globalRule.DefaultOptions.ClashMode = badoption.Listable[string]{"Global"}
+		c.route.Rules = append(c.route.Rules, directRule, globalRule)
+	}
+	for _, line := range c.config.Rules {
+		err := c.convertRule(line)
+		if err != nil {
+			c.logger.Warn("ignored rule ", line, ": ", err)
+		}
+	}
+	if c.route.Final == "" {
+		c.route.Final = tagDirect
+	}
+}
+
+func (c *converter) convertRule(line string) error {
+	ruleType, remaining, _ := strings.Cut(strings.TrimSpace(line), ",")
+	ruleType = strings.ToUpper(strings.TrimSpace(ruleType))
+	if ruleType == "MATCH" || ruleType == "FINAL" {
+		target := strings.TrimSpace(remaining)
+		switch target {
+		case tagReject, "REJECT-DROP":
+			c.needReject = true
+			target = tagReject
+		}
+		if provider, isDynamic := c.dynamicTags[target]; isDynamic {
+			return E.New("outbounds of proxy-provider ", provider, " can not be used as the final outbound")
+		}
+		c.route.Final = target
+		return nil
+	}
+	var (
+		payload string
+		params  []string
+	)
+	switch ruleType {
+	case "AND", "OR", "NOT":
+		end := matchParenthesis(remaining)
+		if end < 0 {
+			return E.New("invalid logical rule payload")
+		}
+		payload = remaining[:end+1]
+		params = strings.Split(strings.TrimPrefix(remaining[end+1:], ","), ",")
+	default:
+		params = strings.Split(remaining, ",")
+		payload = strings.TrimSpace(params[0])
+		params = params[1:]
+	}
+	if len(params) == 0 || params[0] == "" {
+		return E.New("missing target")
+	}
+	target := strings.TrimSpace(params[0])
+	var noResolve bool
+	for _, param := range params[1:] {
+		switch strings.TrimSpace(param) {
+		case "no-resolve":
+			noResolve = true
+		case "src":
+			return E.New("unsupported rule parameter: src")
+		}
+	}
+	rule, isIP, err := c.parseMatcher(ruleType, payload)
+	if err != nil {
+		return err
+	}
+	var action option.RuleAction
+	switch target {
+	case tagReject:
+		action = option.RuleAction{Action: C.RuleActionTypeReject}
+	case "REJECT-DROP":
+		action = option.RuleAction{
+			Action:        C.RuleActionTypeReject,
+			RejectOptions: option.RejectActionOptions{Method: C.RuleActionRejectMethodDrop},
+		}
+	case "PASS":
+		return E.New("unsupported target: PASS")
+	default:
+		if target != tagDirect && !c.outboundTags[target] && c.dynamicTags[target] == "" {
+			return E.New("unknown target: ", target)
+		}
+		action = routeAction(target)
+	}
+	if isIP && !noResolve && !c.resolveInserted {
+		c.route.Rules = append(c.route.Rules, actionRule(option.RuleAction{Action: C.RuleActionTypeResolve}))
+		c.resolveInserted = true
+		c.mergeable = false
+	}
+	addressRule := isAddressRuleType(ruleType)
+	if rule.Type == C.RuleTypeLogical {
+		rule.LogicalOptions.RuleAction = action
+	} else {
+		rule.DefaultOptions.RuleAction = action
+		if addressRule && c.mergeable && mergeRule(&c.route.Rules[len(c.route.Rules)-1].DefaultOptions, rule.DefaultOptions) {
+			return nil
+		}
+	}
+	c.route.Rules = append(c.route.Rules, rule)
+	c.mergeable = addressRule
+	return nil
+}
+
+func (c *converter) parseMatcher(ruleType string, payload string) (option.Rule, bool, error) {
+	switch ruleType {
+	case "AND", "OR", "NOT":
+		subRules, err := splitLogicalPayload(payload)
+		if err != nil {
+			return option.Rule{}, false, err
+		}
+		if ruleType == "NOT" && len(subRules) != 1 {
+			return option.Rule{}, false, E.New("NOT rule requires exactly one sub rule")
+		}
+		logicalRule := option.LogicalRule{
+			RawLogicalRule: option.RawLogicalRule{
+				Mode:   C.LogicalTypeAnd,
+				Invert: ruleType == "NOT",
+			},
+		}
+		if ruleType == "OR" {
+			logicalRule.Mode = C.LogicalTypeOr
+		}
+		var isIP bool
+		for _, subRule := range subRules {
+			subType, subPayload, _ := strings.Cut(subRule, ",")
+			subType = strings.ToUpper(strings.TrimSpace(subType))
+			switch subType {
+			case "AND", "OR", "NOT":
+			default:
+				subPayload, _, _ = strings.Cut(subPayload, ",")
+			}
+			rule, subIsIP, err := c.parseMatcher(subType, strings.TrimSpace(subPayload))
+			if err != nil {
+				return option.Rule{}, false, err
+			}
+			isIP = isIP || subIsIP
+			logicalRule.Rules = append(logicalRule.Rules, rule)
+		}
+		return option.Rule{Type: C.RuleTypeLogical, LogicalOptions: logicalRule}, isIP, nil
+	}
+	var (
+		rule option.DefaultRule
+		isIP bool
+	)
+	switch ruleType {
+	case "DOMAIN":
+		rule.Domain = []string{payload}
+	case "DOMAIN-SUFFIX":
+		rule.DomainSuffix = []string{payload}
+	case "DOMAIN-KEYWORD":
+		rule.DomainKeyword = []string{payload}
+	case "DOMAIN-REGEX":
+		rule.DomainRegex = []string{payload}
+	case "GEOSITE":
+		rule.RuleSet = []string{c.geoRuleSet("geosite", payload)}
+	case "GEOIP":
+		isIP = true
+		if strings.EqualFold(payload, "lan") || strings.EqualFold(payload, "private") {
+			rule.IPIsPrivate = true
+		} else {
+			rule.RuleSet = []string{c.geoRuleSet("geoip", payload)}
+		}
+	case "SRC-GEOIP":
+		if strings.EqualFold(payload, "lan") || strings.EqualFold(payload, "private") {
+			rule.SourceIPIsPrivate = true
+		} else {
+			rule.RuleSet = []string{c.geoRuleSet("geoip", payload)}
+			rule.RuleSetIPCIDRMatchSource = true
+		}
+	case "IP-CIDR", "IP-CIDR6":
+		isIP = true
+		rule.IPCIDR = []string{payload}
+	case "SRC-IP-CIDR":
+		rule.SourceIPCIDR = []string{payload}
+	case "DST-PORT":
+		ports, portRanges, err := parsePorts(payload)
+		if err != nil {
+			return option.Rule{}, false, err
+		}
+		rule.Port = ports
+		rule.PortRange = portRanges
+	case "SRC-PORT":
+		ports, portRanges, err := parsePorts(payload)
+		if err != nil {
+			return option.Rule{}, false, err
+		}
+		rule.SourcePort = ports
+		rule.SourcePortRange = portRanges
+	case "IN-NAME":
+		rule.Inbound = []string{payload}
+	case "PROCESS-NAME":
+		rule.ProcessName = []string{payload}
+	case "PROCESS-PATH":
+		rule.ProcessPath = []string{payload}
+	case "PROCESS-PATH-REGEX":
+		rule.ProcessPathRegex = []string{payload}
+	case "NETWORK":
+		rule.Network = []string{strings.ToLower(payload)}
+	case "UID":
+		userID, err := strconv.ParseInt(payload, 10, 32)
+		if err != nil {
+			return option.Rule{}, false, E.Cause(err, "parse uid")
+		}
+		rule.UserID = []int32{int32(userID)}
+	case "RULE-SET":
+		if !c.ruleSets[payload] {
+			return option.Rule{}, false, E.New("rule-provider not found or unsupported: ", payload)
+		}
+		isIP = c.ipRuleSets[payload]
+		rule.RuleSet = []string{payload}
+	default:
+		return option.Rule{}, false, E.New("unsupported rule type: ", ruleType)
+	}
+	return option.Rule{Type: C.RuleTypeDefault, DefaultOptions: rule}, isIP, nil
+}
+
+// isAddressRuleType reports whether the rule only matches the destination address,
+// adjacent rules of these types are merged since sing-box matches them with OR semantics.
+func isAddressRuleType(ruleType string) bool {
+	switch ruleType {
+	case "DOMAIN", "DOMAIN-SUFFIX", "DOMAIN-KEYWORD", "DOMAIN-REGEX", "IP-CIDR", "IP-CIDR6":
+		return true
+	default:
+		return false
+	}
+}
+
+func mergeRule(previous *option.DefaultRule, rule option.DefaultRule) bool {
+	if previous.Action != rule.Action ||
+		previous.RouteOptions.Outbound != rule.RouteOptions.Outbound ||
+		previous.RejectOptions != rule.RejectOptions {
+		return false
+	}
+	previous.Domain = append(previous.Domain, rule.Domain...)
+	previous.DomainSuffix = append(previous.DomainSuffix, rule.DomainSuffix...)
+	previous.DomainKeyword = append(previous.DomainKeyword, rule.DomainKeyword...)
+	previous.DomainRegex = append(previous.DomainRegex, rule.DomainRegex...)
+	previous.IPCIDR = append(previous.IPCIDR, rule.IPCIDR...)
+	return true
+}
+
+func actionRule(action option.RuleAction) option.Rule {
+	return option.Rule{
+		Type: C.RuleTypeDefault,
+		DefaultOptions: option.DefaultRule{
+			RuleAction: action,
+		},
+	}
+}
+
+func routeAction(outbound string) option.RuleAction {
+	return option.RuleAction{
+		Action: C.RuleActionTypeRoute,
+		RouteOptions: option.RouteActionOptions{
+			Outbound: outbound,
+		},
+	}
+}
+
+func parsePorts(payload string) ([]uint16, []string, error) {
+	var (
+		ports      []uint16
+		portRanges []string
+	)
+	for _, portString := range strings.Split(payload, "/") {
+		if start, end, isRange := strings.Cut(portString, "-"); isRange {
+			portRanges = append(portRanges, start+":"+end)
+			continue
+		}
+		port, err := strconv.ParseUint(portString, 10, 16)
+		if err != nil {
+			return nil, nil, E.Cause(err, "parse port")
+		}
+		ports = append(ports, uint16(port))
+	}
+	return ports, portRanges, nil
+}
+
+// matchParenthesis returns the index of the parenthesis closing the one at the start of content.
+func matchParenthesis(content string) int {
+	if !strings.HasPrefix(content, "(") {
+		return -1
+	}
+	var depth int
+	for i, char := range content {
+		switch char {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitLogicalPayload splits `((DOMAIN,a),(NETWORK,UDP))` into its sub rules.
+func splitLogicalPayload(payload string) ([]string, error) {
+	payload = strings.TrimSpace(payload)
+	if !strings.HasPrefix(payload, "(") || !strings.HasSuffix(payload, ")") {
+		return nil, E.New("invalid logical rule payload: ", payload)
+	}
+	payload = payload[1 : len(payload)-1]
+	var subRules []string
+	for payload != "" {
+		payload = strings.TrimLeft(payload, ", ")
+		end := matchParenthesis(payload)
+		if end < 0 {
+			return nil, E.New("invalid logical rule payload: ", payload)
+		}
+		subRules = append(subRules, payload[1:end])
+		payload = payload[end+1:]
+	}
+	if len(subRules) == 0 {
+		return nil, E.New("empty logical rule")
+	}
+	return subRules, nil
+}
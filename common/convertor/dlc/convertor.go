@@ -0,0 +1,153 @@
+// Package dlc reads V2Ray domain list community (geosite.dat / dlc.dat) files.
+package dlc
+
+import (
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type DomainType = uint64
+
+const (
+	DomainTypePlain DomainType = iota
+	DomainTypeRegex
+	DomainTypeDomain
+	DomainTypeFull
+)
+
+type Domain struct {
+	Type       DomainType
+	Value      string
+	Attributes []string
+}
+
+// Read decodes a GeoSiteList message into domains keyed by lower-cased country code.
+func Read(content []byte) (map[string][]Domain, error) {
+	sites := make(map[string][]Domain)
+	err := consumeMessage(content, func(number protowire.Number, value []byte, _ uint64) error {
+		if number != 1 {
+			return nil
+		}
+		var (
+			code    string
+			domains []Domain
+		)
+		err := consumeMessage(value, func(number protowire.Number, value []byte, _ uint64) error {
+			switch number {
+			case 1:
+				code = strings.ToLower(string(value))
+			case 2:
+				domain, err := readDomain(value)
+				if err != nil {
+					return err
+				}
+				domains = append(domains, domain)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		sites[code] = append(sites[code], domains...)
+		return nil
+	})
+	if err != nil {
+		return nil, E.Cause(err, "decode geosite list")
+	}
+	return sites, nil
+}
+
+func readDomain(content []byte) (Domain, error) {
+	var domain Domain
+	err := consumeMessage(content, func(number protowire.Number, value []byte, varint uint64) error {
+		switch number {
+		case 1:
+			domain.Type = varint
+		case 2:
+			domain.Value = string(value)
+		case 3:
+			return consumeMessage(value, func(number protowire.Number, value []byte, _ uint64) error {
+				if number == 1 {
+					domain.Attributes = append(domain.Attributes, strings.ToLower(string(value)))
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	return domain, err
+}
+
+// consumeMessage calls handler for each field, passing bytes fields as value and varint fields as varint.
+func consumeMessage(content []byte, handler func(number protowire.Number, value []byte, varint uint64) error) error {
+	for len(content) > 0 {
+		number, fieldType, n := protowire.ConsumeTag(content)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		content = content[n:]
+		var (
+			value  []byte
+			varint uint64
+		)
+		switch fieldType {
+		case protowire.BytesType:
+			value, n = protowire.ConsumeBytes(content)
+		case protowire.VarintType:
+			varint, n = protowire.ConsumeVarint(content)
+		default:
+			n = protowire.ConsumeFieldValue(number, fieldType, content)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		content = content[n:]
+		err := handler(number, value, varint)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ToOptions converts a code of the decoded list to rules.
+// The code may be followed by `@attribute` or `@!attribute` to filter domains, e.g. `google@cn`.
+func ToOptions(sites map[string][]Domain, code string) ([]option.HeadlessRule, error) {
+	name, attribute, hasAttribute := strings.Cut(strings.ToLower(code), "@")
+	domains, loaded := sites[name]
+	if !loaded {
+		return nil, E.New("code not found: ", name)
+	}
+	attribute, exclude := strings.CutPrefix(attribute, "!")
+	var rule option.DefaultHeadlessRule
+	for _, domain := range domains {
+		if hasAttribute && common.Contains(domain.Attributes, attribute) == exclude {
+			continue
+		}
+		switch domain.Type {
+		case DomainTypePlain:
+			rule.DomainKeyword = append(rule.DomainKeyword, domain.Value)
+		case DomainTypeRegex:
+			rule.DomainRegex = append(rule.DomainRegex, domain.Value)
+		case DomainTypeDomain:
+			rule.DomainSuffix = append(rule.DomainSuffix, domain.Value)
+		case DomainTypeFull:
+			rule.Domain = append(rule.Domain, domain.Value)
+		}
+	}
+	if len(rule.Domain)+len(rule.DomainSuffix)+len(rule.DomainKeyword)+len(rule.DomainRegex) == 0 {
+		return nil, E.New("no domains matched: ", code)
+	}
+	return []option.HeadlessRule{
+		{
+			Type:           C.RuleTypeDefault,
+			DefaultOptions: rule,
+		},
+	}, nil
+}
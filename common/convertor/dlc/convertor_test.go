@@ -0,0 +1,64 @@
+package dlc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestToOptions(t *testing.T) {
+	t.Parallel()
+	domain := func(domainType DomainType, value string, attributes ...string) []byte {
+		var content []byte
+		content = protowire.AppendTag(content, 1, protowire.VarintType)
+		content = protowire.AppendVarint(content, domainType)
+		content = protowire.AppendTag(content, 2, protowire.BytesType)
+		content = protowire.AppendString(content, value)
+		for _, attribute := range attributes {
+			var attributeContent []byte
+			attributeContent = protowire.AppendTag(attributeContent, 1, protowire.BytesType)
+			attributeContent = protowire.AppendString(attributeContent, attribute)
+			attributeContent = protowire.AppendTag(attributeContent, 2, protowire.VarintType)
+			attributeContent = protowire.AppendVarint(attributeContent, 1)
+			content = protowire.AppendTag(content, 3, protowire.BytesType)
+			content = protowire.AppendBytes(content, attributeContent)
+		}
+		return content
+	}
+	var site []byte
+	site = protowire.AppendTag(site, 1, protowire.BytesType)
+	site = protowire.AppendString(site, "GOOGLE")
+	for _, domainContent := range [][]byte{
+		domain(DomainTypeDomain, "google.com"),
+		domain(DomainTypeFull, "www.google.cn", "cn"),
+		domain(DomainTypePlain, "google"),
+		domain(DomainTypeRegex, `^google\.[a-z]+$`),
+	} {
+		site = protowire.AppendTag(site, 2, protowire.BytesType)
+		site = protowire.AppendBytes(site, domainContent)
+	}
+	var content []byte
+	content = protowire.AppendTag(content, 1, protowire.BytesType)
+	content = protowire.AppendBytes(content, site)
+
+	sites, err := Read(content)
+	require.NoError(t, err)
+	require.Len(t, sites["google"], 4)
+	rules, err := ToOptions(sites, "google")
+	require.NoError(t, err)
+	rule := rules[0].DefaultOptions
+	require.Equal(t, []string{"google.com"}, []string(rule.DomainSuffix))
+	require.Equal(t, []string{"www.google.cn"}, []string(rule.Domain))
+	require.Equal(t, []string{"google"}, []string(rule.DomainKeyword))
+	require.Equal(t, []string{`^google\.[a-z]+$`}, []string(rule.DomainRegex))
+	rules, err = ToOptions(sites, "google@cn")
+	require.NoError(t, err)
+	require.Equal(t, []string{"www.google.cn"}, []string(rules[0].DefaultOptions.Domain))
+	require.Empty(t, rules[0].DefaultOptions.DomainSuffix)
+	rules, err = ToOptions(sites, "google@!cn")
+	require.NoError(t, err)
+	require.Empty(t, rules[0].DefaultOptions.Domain)
+	_, err = ToOptions(sites, "facebook")
+	require.Error(t, err)
+}
@@ -0,0 +1,83 @@
+// Package hosts converts hosts-file style blocklists.
+package hosts
+
+import (
+	"bufio"
+	"io"
+	"net/netip"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+)
+
+// reservedNames are the loopback and broadcast names found at the top of most hosts files.
+var reservedNames = map[string]bool{
+	"localhost":             true,
+	"localhost.localdomain": true,
+	"local":                 true,
+	"broadcasthost":         true,
+	"ip6-localhost":         true,
+	"ip6-loopback":          true,
+	"ip6-localnet":          true,
+	"ip6-mcastprefix":       true,
+	"ip6-allnodes":          true,
+	"ip6-allrouters":        true,
+	"ip6-allhosts":          true,
+	"0.0.0.0":               true,
+}
+
+// ToOptions converts lines such as `0.0.0.0 ads.example.com` to an exact domain rule.
+func ToOptions(reader io.Reader, logger logger.Logger) ([]option.HeadlessRule, error) {
+	scanner := bufio.NewScanner(reader)
+	var (
+		domains      []string
+		domainMap    = make(map[string]bool)
+		ignoredLines int
+	)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if _, err := netip.ParseAddr(fields[0]); err != nil || len(fields) < 2 {
+			logger.Debug("ignored unsupported line: ", scanner.Text())
+			ignoredLines++
+			continue
+		}
+		for _, domain := range fields[1:] {
+			domain = strings.ToLower(domain)
+			if reservedNames[domain] || domainMap[domain] {
+				continue
+			}
+			if !M.IsDomainName(domain) {
+				logger.Debug("ignored invalid domain: ", domain)
+				ignoredLines++
+				continue
+			}
+			domainMap[domain] = true
+			domains = append(domains, domain)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if ignoredLines > 0 {
+		logger.Info("parsed rules: ", len(domains), "/", len(domains)+ignoredLines)
+	}
+	if len(domains) == 0 {
+		return nil, E.New("no domains found")
+	}
+	return []option.HeadlessRule{
+		{
+			Type: C.RuleTypeDefault,
+			DefaultOptions: option.DefaultHeadlessRule{
+				Domain: domains,
+			},
+		},
+	}, nil
+}
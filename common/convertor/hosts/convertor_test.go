@@ -0,0 +1,26 @@
+package hosts
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sagernet/sing/common/logger"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestToOptions(t *testing.T) {
+	t.Parallel()
+	content := `# comment
+127.0.0.1 localhost
+::1 localhost ip6-localhost
+0.0.0.0 0.0.0.0
+0.0.0.0 ads.example.com tracker.example.com # inline comment
+127.0.0.1	Ads.Example.com
+invalid line
+`
+	rules, err := ToOptions(strings.NewReader(content), logger.NOP())
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	require.Equal(t, []string{"ads.example.com", "tracker.example.com"}, []string(rules[0].DefaultOptions.Domain))
+}
@@ -0,0 +1,241 @@
+package link
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// Export converts an outbound into a share link.
+func Export(outbound option.Outbound) (string, error) {
+	switch options := outbound.Options.(type) {
+	case *option.VMessOutboundOptions:
+		return exportVMess(outbound.Tag, options)
+	case *option.VLESSOutboundOptions:
+		return exportVLESS(outbound.Tag, options)
+	case *option.TrojanOutboundOptions:
+		return exportTrojan(outbound.Tag, options)
+	case *option.ShadowsocksOutboundOptions:
+		return exportShadowsocks(outbound.Tag, options), nil
+	case *option.Hysteria2OutboundOptions:
+		return exportHysteria2(outbound.Tag, options), nil
+	case *option.TUICOutboundOptions:
+		return exportTUIC(outbound.Tag, options), nil
+	default:
+		return "", E.New("unsupported outbound type: ", outbound.Type)
+	}
+}
+
+func exportURL(scheme string, user *url.Userinfo, server option.ServerOptions, query url.Values, tag string) string {
+	linkURL := url.URL{
+		Scheme:   scheme,
+		User:     user,
+		Host:     net.JoinHostPort(server.Server, strconv.Itoa(int(server.ServerPort))),
+		RawQuery: query.Encode(),
+		Fragment: tag,
+	}
+	return linkURL.String()
+}
+
+func streamFromOptions(tlsOptions *option.OutboundTLSOptions, transport *option.V2RayTransportOptions) (streamOptions, error) {
+	var stream streamOptions
+	if tlsOptions != nil && tlsOptions.Enabled {
+		stream.Security = "tls"
+		stream.ServerName = tlsOptions.ServerName
+		stream.Insecure = tlsOptions.Insecure
+		stream.ALPN = tlsOptions.ALPN
+		if tlsOptions.UTLS != nil && tlsOptions.UTLS.Enabled {
+			stream.Fingerprint = tlsOptions.UTLS.Fingerprint
+		}
+		if tlsOptions.Reality != nil && tlsOptions.Reality.Enabled {
+			stream.Security = "reality"
+			stream.PublicKey = tlsOptions.Reality.PublicKey
+			stream.ShortID = tlsOptions.Reality.ShortID
+		}
+	}
+	if transport == nil {
+		stream.Network = "tcp"
+		return stream, nil
+	}
+	switch transport.Type {
+	case C.V2RayTransportTypeWebsocket:
+		stream.Network = C.V2RayTransportTypeWebsocket
+		stream.Path = transport.WebsocketOptions.Path
+		stream.Host = transport.WebsocketOptions.Host
+		if hostHeader := transport.WebsocketOptions.Headers["Host"]; stream.Host == "" && len(hostHeader) > 0 {
+			stream.Host = hostHeader[0]
+		}
+	case C.V2RayTransportTypeGRPC:
+		stream.Network = C.V2RayTransportTypeGRPC
+		stream.ServiceName = transport.GRPCOptions.ServiceName
+	case C.V2RayTransportTypeHTTPUpgrade:
+		stream.Network = C.V2RayTransportTypeHTTPUpgrade
+		stream.Host = transport.HTTPUpgradeOptions.Host
+		stream.Path = transport.HTTPUpgradeOptions.Path
+	case C.V2RayTransportTypeHTTP:
+		stream.Network = C.V2RayTransportTypeHTTP
+		stream.Host = strings.Join(transport.HTTPOptions.Host, ",")
+		stream.Path = transport.HTTPOptions.Path
+	case C.V2RayTransportTypeQUIC:
+		stream.Network = C.V2RayTransportTypeQUIC
+	default:
+		return streamOptions{}, E.New("unsupported transport type: ", transport.Type)
+	}
+	return stream, nil
+}
+
+func (s streamOptions) Query() url.Values {
+	query := make(url.Values)
+	setQuery(query, "type", s.Network)
+	setQuery(query, "security", s.Security)
+	setQuery(query, "sni", s.ServerName)
+	if s.Insecure {
+		query.Set("allowInsecure", "1")
+	}
+	setQuery(query, "alpn", strings.Join(s.ALPN, ","))
+	setQuery(query, "fp", s.Fingerprint)
+	setQuery(query, "pbk", s.PublicKey)
+	setQuery(query, "sid", s.ShortID)
+	setQuery(query, "host", s.Host)
+	setQuery(query, "path", s.Path)
+	setQuery(query, "serviceName", s.ServiceName)
+	return query
+}
+
+func setQuery(query url.Values, key string, value string) {
+	if value != "" {
+		query.Set(key, value)
+	}
+}
+
+func exportVMess(tag string, options *option.VMessOutboundOptions) (string, error) {
+	stream, err := streamFromOptions(options.TLS, options.Transport)
+	if err != nil {
+		return "", err
+	}
+	content := vmessLink{
+		Version:     "2",
+		Remarks:     tag,
+		Address:     options.Server,
+		Port:        flexibleString(strconv.Itoa(int(options.ServerPort))),
+		ID:          options.UUID,
+		AlterID:     flexibleString(strconv.Itoa(options.AlterId)),
+		Security:    options.Security,
+		Network:     stream.Network,
+		Host:        stream.Host,
+		Path:        stream.Path,
+		TLS:         stream.Security,
+		SNI:         stream.ServerName,
+		ALPN:        strings.Join(stream.ALPN, ","),
+		Fingerprint: stream.Fingerprint,
+	}
+	switch stream.Network {
+	case C.V2RayTransportTypeGRPC:
+		content.Path = stream.ServiceName
+	case C.V2RayTransportTypeHTTP:
+		content.Network = "tcp"
+		content.Type = "http"
+	}
+	if stream.Security == "reality" {
+		return "", E.New("reality is not supported by vmess links")
+	}
+	payload, err := json.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+	return "vmess://" + base64.StdEncoding.EncodeToString(payload), nil
+}
+
+func exportVLESS(tag string, options *option.VLESSOutboundOptions) (string, error) {
+	stream, err := streamFromOptions(options.TLS, options.Transport)
+	if err != nil {
+		return "", err
+	}
+	if stream.Security == "" {
+		stream.Security = "none"
+	}
+	query := stream.Query()
+	query.Set("encryption", "none")
+	setQuery(query, "flow", options.Flow)
+	if options.PacketEncoding != nil {
+		setQuery(query, "packetEncoding", *options.PacketEncoding)
+	}
+	return exportURL("vless", url.User(options.UUID), options.ServerOptions, query, tag), nil
+}
+
+func exportTrojan(tag string, options *option.TrojanOutboundOptions) (string, error) {
+	stream, err := streamFromOptions(options.TLS, options.Transport)
+	if err != nil {
+		return "", err
+	}
+	if stream.Security == "" {
+		stream.Security = "none"
+	}
+	return exportURL("trojan", url.User(options.Password), options.ServerOptions, stream.Query(), tag), nil
+}
+
+func exportShadowsocks(tag string, options *option.ShadowsocksOutboundOptions) string {
+	query := make(url.Values)
+	if options.Plugin != "" {
+		plugin := options.Plugin
+		if options.PluginOptions != "" {
+			plugin += ";" + options.PluginOptions
+		}
+		query.Set("plugin", plugin)
+	}
+	var user *url.Userinfo
+	if strings.HasPrefix(options.Method, "2022-") {
+		// SIP022 keys are base64 already, so the plain form is used
+		user = url.UserPassword(options.Method, options.Password)
+	} else {
+		user = url.User(base64.RawURLEncoding.EncodeToString([]byte(options.Method + ":" + options.Password)))
+	}
+	return exportURL("ss", user, options.ServerOptions, query, tag)
+}
+
+func exportHysteria2(tag string, options *option.Hysteria2OutboundOptions) string {
+	query := make(url.Values)
+	if options.TLS != nil {
+		setQuery(query, "sni", options.TLS.ServerName)
+		if options.TLS.Insecure {
+			query.Set("insecure", "1")
+		}
+		setQuery(query, "alpn", strings.Join(options.TLS.ALPN, ","))
+	}
+	if len(options.ServerPorts) > 0 {
+		query.Set("mport", strings.ReplaceAll(strings.Join(options.ServerPorts, ","), ":", "-"))
+	}
+	if options.Obfs != nil && options.Obfs.Type != "" {
+		query.Set("obfs", options.Obfs.Type)
+		setQuery(query, "obfs-password", options.Obfs.Password)
+	}
+	var user *url.Userinfo
+	if options.Password != "" {
+		user = url.User(options.Password)
+	}
+	return exportURL("hysteria2", user, options.ServerOptions, query, tag)
+}
+
+func exportTUIC(tag string, options *option.TUICOutboundOptions) string {
+	query := make(url.Values)
+	setQuery(query, "congestion_control", options.CongestionControl)
+	setQuery(query, "udp_relay_mode", options.UDPRelayMode)
+	if options.TLS != nil {
+		setQuery(query, "sni", options.TLS.ServerName)
+		if options.TLS.Insecure {
+			query.Set("allow_insecure", "1")
+		}
+		setQuery(query, "alpn", strings.Join(options.TLS.ALPN, ","))
+		if options.TLS.DisableSNI {
+			query.Set("disable_sni", "1")
+		}
+	}
+	return exportURL("tuic", url.UserPassword(options.UUID, options.Password), options.ServerOptions, query, tag)
+}
@@ -0,0 +1,52 @@
+package link
+
+import (
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+)
+
+func parseHysteria2(link string) (option.Outbound, error) {
+	linkURL, err := parseURL(link)
+	if err != nil {
+		return option.Outbound{}, err
+	}
+	query := linkURL.Query()
+	options := option.Hysteria2OutboundOptions{
+		OutboundTLSOptionsContainer: option.OutboundTLSOptionsContainer{
+			TLS: &option.OutboundTLSOptions{
+				Enabled:    true,
+				ServerName: query.Get("sni"),
+				Insecure:   isTrue(query.Get("insecure")),
+				ALPN:       splitList(query.Get("alpn")),
+			},
+		},
+	}
+	server, err := serverOptions(linkURL)
+	if err != nil {
+		return option.Outbound{}, err
+	}
+	options.ServerOptions = server
+	if ports := query.Get("mport"); ports != "" {
+		options.ServerPorts = strings.Split(strings.ReplaceAll(ports, "-", ":"), ",")
+	}
+	if linkURL.User != nil {
+		if password, hasPassword := linkURL.User.Password(); hasPassword {
+			options.Password = linkURL.User.Username() + ":" + password
+		} else {
+			options.Password = linkURL.User.Username()
+		}
+	}
+	if obfsType := query.Get("obfs"); obfsType != "" {
+		options.Obfs = &option.Hysteria2Obfs{
+			Type:     obfsType,
+			Password: query.Get("obfs-password"),
+		}
+	}
+	return option.Outbound{
+		Type:    C.TypeHysteria2,
+		Tag:     linkTag(linkURL),
+		Options: &options,
+	}, nil
+}
@@ -0,0 +1,270 @@
+package link
+
+import (
+	"encoding/base64"
+	"net/netip"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+// InboundOutbounds converts an inbound into the outbounds its users would connect with.
+// server overrides the listen address, which is usually not reachable from clients.
+func InboundOutbounds(inbound option.Inbound, server string) ([]option.Outbound, error) {
+	switch options := inbound.Options.(type) {
+	case *option.VMessInboundOptions:
+		serverOptions, err := inboundServer(options.ListenOptions, server)
+		if err != nil {
+			return nil, err
+		}
+		tlsOptions, err := inboundTLSOptions(options.TLS)
+		if err != nil {
+			return nil, err
+		}
+		outbounds := make([]option.Outbound, 0, len(options.Users))
+		for _, user := range options.Users {
+			outbounds = append(outbounds, option.Outbound{
+				Type: C.TypeVMess,
+				Tag:  inboundTag(inbound.Tag, user.Name),
+				Options: &option.VMessOutboundOptions{
+					ServerOptions: serverOptions,
+					UUID:          user.UUID,
+					Security:      "auto",
+					AlterId:       user.AlterId,
+					OutboundTLSOptionsContainer: option.OutboundTLSOptionsContainer{
+						TLS: tlsOptions,
+					},
+					Transport: options.Transport,
+				},
+			})
+		}
+		return outbounds, nil
+	case *option.VLESSInboundOptions:
+		serverOptions, err := inboundServer(options.ListenOptions, server)
+		if err != nil {
+			return nil, err
+		}
+		tlsOptions, err := inboundTLSOptions(options.TLS)
+		if err != nil {
+			return nil, err
+		}
+		outbounds := make([]option.Outbound, 0, len(options.Users))
+		for _, user := range options.Users {
+			outbounds = append(outbounds, option.Outbound{
+				Type: C.TypeVLESS,
+				Tag:  inboundTag(inbound.Tag, user.Name),
+				Options: &option.VLESSOutboundOptions{
+					ServerOptions: serverOptions,
+					UUID:          user.UUID,
+					Flow:          user.Flow,
+					OutboundTLSOptionsContainer: option.OutboundTLSOptionsContainer{
+						TLS: tlsOptions,
+					},
+					Transport: options.Transport,
+				},
+			})
+		}
+		return outbounds, nil
+	case *option.TrojanInboundOptions:
+		serverOptions, err := inboundServer(options.ListenOptions, server)
+		if err != nil {
+			return nil, err
+		}
+		tlsOptions, err := inboundTLSOptions(options.TLS)
+		if err != nil {
+			return nil, err
+		}
+		outbounds := make([]option.Outbound, 0, len(options.Users))
+		for _, user := range options.Users {
+			outbounds = append(outbounds, option.Outbound{
+				Type: C.TypeTrojan,
+				Tag:  inboundTag(inbound.Tag, user.Name),
+				Options: &option.TrojanOutboundOptions{
+					ServerOptions: serverOptions,
+					Password:      user.Password,
+					OutboundTLSOptionsContainer: option.OutboundTLSOptionsContainer{
+						TLS: tlsOptions,
+					},
+					Transport: options.Transport,
+				},
+			})
+		}
+		return outbounds, nil
+	case *option.ShadowsocksInboundOptions:
+		serverOptions, err := inboundServer(options.ListenOptions, server)
+		if err != nil {
+			return nil, err
+		}
+		if len(options.Users) == 0 {
+			return []option.Outbound{{
+				Type: C.TypeShadowsocks,
+				Tag:  inbound.Tag,
+				Options: &option.ShadowsocksOutboundOptions{
+					ServerOptions: serverOptions,
+					Method:        options.Method,
+					Password:      options.Password,
+				},
+			}}, nil
+		}
+		outbounds := make([]option.Outbound, 0, len(options.Users))
+		for _, user := range options.Users {
+			password := user.Password
+			if options.Password != "" {
+				password = options.Password + ":" + password
+			}
+			outbounds = append(outbounds, option.Outbound{
+				Type: C.TypeShadowsocks,
+				Tag:  inboundTag(inbound.Tag, user.Name),
+				Options: &option.ShadowsocksOutboundOptions{
+					ServerOptions: serverOptions,
+					Method:        options.Method,
+					Password:      password,
+				},
+			})
+		}
+		return outbounds, nil
+	case *option.Hysteria2InboundOptions:
+		serverOptions, err := inboundServer(options.ListenOptions, server)
+		if err != nil {
+			return nil, err
+		}
+		tlsOptions, err := inboundTLSOptions(options.TLS)
+		if err != nil {
+			return nil, err
+		}
+		outbounds := make([]option.Outbound, 0, len(options.Users))
+		for _, user := range options.Users {
+			outbounds = append(outbounds, option.Outbound{
+				Type: C.TypeHysteria2,
+				Tag:  inboundTag(inbound.Tag, user.Name),
+				Options: &option.Hysteria2OutboundOptions{
+					ServerOptions: serverOptions,
+					Password:      user.Password,
+					Obfs:          options.Obfs,
+					OutboundTLSOptionsContainer: option.OutboundTLSOptionsContainer{
+						TLS: tlsOptions,
+					},
+				},
+			})
+		}
+		return outbounds, nil
+	case *option.TUICInboundOptions:
+		serverOptions, err := inboundServer(options.ListenOptions, server)
+		if err != nil {
+			return nil, err
+		}
+		tlsOptions, err := inboundTLSOptions(options.TLS)
+		if err != nil {
+			return nil, err
+		}
+		outbounds := make([]option.Outbound, 0, len(options.Users))
+		for _, user := range options.Users {
+			outbounds = append(outbounds, option.Outbound{
+				Type: C.TypeTUIC,
+				Tag:  inboundTag(inbound.Tag, user.Name),
+				Options: &option.TUICOutboundOptions{
+					ServerOptions:     serverOptions,
+					UUID:              user.UUID,
+					Password:          user.Password,
+					CongestionControl: options.CongestionControl,
+					OutboundTLSOptionsContainer: option.OutboundTLSOptionsContainer{
+						TLS: tlsOptions,
+					},
+				},
+			})
+		}
+		return outbounds, nil
+	default:
+		return nil, E.New("unsupported inbound type: ", inbound.Type)
+	}
+}
+
+// InboundUserLink returns the share link of the named user of an inbound.
+func InboundUserLink(inbound option.Inbound, userName string, server string) (string, error) {
+	multiUserOptions, isMultiUser := inbound.Options.(option.MultiUserInboundOptions)
+	if !isMultiUser {
+		return "", E.New("unsupported inbound type: ", inbound.Type)
+	}
+	users := multiUserOptions.InboundUsers()
+	userIndex := common.Index(users, func(it option.InboundUser) bool {
+		return it.Name == userName
+	})
+	if userIndex == -1 {
+		return "", E.New("user ", userName, " not found")
+	}
+	outbounds, err := InboundOutbounds(inbound, server)
+	if err != nil {
+		return "", err
+	}
+	if len(outbounds) != len(users) {
+		return "", E.New("unsupported inbound type: ", inbound.Type)
+	}
+	return Export(outbounds[userIndex])
+}
+
+func inboundServer(listenOptions option.ListenOptions, server string) (option.ServerOptions, error) {
+	if server == "" && listenOptions.Listen != nil {
+		listenAddr := netip.Addr(*listenOptions.Listen)
+		if !listenAddr.IsUnspecified() {
+			server = listenAddr.String()
+		}
+	}
+	if server == "" {
+		return option.ServerOptions{}, E.New("missing server address")
+	}
+	return option.ServerOptions{
+		Server:     server,
+		ServerPort: listenOptions.ListenPort,
+	}, nil
+}
+
+func inboundTLSOptions(tlsOptions *option.InboundTLSOptions) (*option.OutboundTLSOptions, error) {
+	if tlsOptions == nil || !tlsOptions.Enabled {
+		return nil, nil
+	}
+	outboundOptions := &option.OutboundTLSOptions{
+		Enabled:    true,
+		ServerName: tlsOptions.ServerName,
+		ALPN:       tlsOptions.ALPN,
+	}
+	if tlsOptions.Reality != nil && tlsOptions.Reality.Enabled {
+		privateKey, err := base64.RawURLEncoding.DecodeString(tlsOptions.Reality.PrivateKey)
+		if err != nil {
+			return nil, E.Cause(err, "decode reality private key")
+		}
+		publicKey, err := curve25519.X25519(privateKey, curve25519.Basepoint)
+		if err != nil {
+			return nil, E.Cause(err, "derive reality public key")
+		}
+		if outboundOptions.ServerName == "" && tlsOptions.Reality.Handshake.Server != "" {
+			outboundOptions.ServerName = tlsOptions.Reality.Handshake.Server
+		}
+		outboundOptions.Reality = &option.OutboundRealityOptions{
+			Enabled:   true,
+			PublicKey: base64.RawURLEncoding.EncodeToString(publicKey),
+		}
+		if len(tlsOptions.Reality.ShortID) > 0 {
+			outboundOptions.Reality.ShortID = tlsOptions.Reality.ShortID[0]
+		}
+		outboundOptions.UTLS = &option.OutboundUTLSOptions{
+			Enabled:     true,
+			Fingerprint: "chrome",
+		}
+	}
+	return outboundOptions, nil
+}
+
+func inboundTag(inboundTag string, userName string) string {
+	switch {
+	case inboundTag == "":
+		return userName
+	case userName == "":
+		return inboundTag
+	default:
+		return inboundTag + "-" + userName
+	}
+}
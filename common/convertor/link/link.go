@@ -0,0 +1,136 @@
+package link
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// Parse converts a single share link into an outbound.
+func Parse(link string) (option.Outbound, error) {
+	link = strings.TrimSpace(link)
+	scheme, _, found := strings.Cut(link, "://")
+	if !found {
+		return option.Outbound{}, E.New("invalid link: missing scheme")
+	}
+	switch strings.ToLower(scheme) {
+	case "vmess":
+		return parseVMess(link)
+	case "vless":
+		return parseVLESS(link)
+	case "trojan":
+		return parseTrojan(link)
+	case "ss":
+		return parseShadowsocks(link)
+	case "hysteria2", "hy2":
+		return parseHysteria2(link)
+	case "tuic":
+		return parseTUIC(link)
+	default:
+		return option.Outbound{}, E.New("unsupported link scheme: ", scheme)
+	}
+}
+
+// ParseList converts a newline separated (and optionally base64 encoded) list of share links into outbounds.
+// Lines that fail to parse are reported in the returned error slice and skipped.
+func ParseList(content []byte) ([]option.Outbound, []error) {
+	content = bytes.TrimSpace(content)
+	if !bytes.Contains(content, []byte("://")) {
+		decoded, err := DecodeBase64(string(content))
+		if err == nil {
+			content = decoded
+		}
+	}
+	var (
+		outbounds []option.Outbound
+		errors    []error
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		outbound, err := Parse(line)
+		if err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		outbounds = append(outbounds, outbound)
+	}
+	return outbounds, errors
+}
+
+// DecodeBase64 decodes standard or URL-safe base64 with or without padding.
+func DecodeBase64(content string) ([]byte, error) {
+	content = strings.TrimSpace(content)
+	content = strings.NewReplacer("\r", "", "\n", "", " ", "").Replace(content)
+	if strings.ContainsAny(content, "-_") {
+		return base64.RawURLEncoding.DecodeString(strings.TrimRight(content, "="))
+	}
+	return base64.RawStdEncoding.DecodeString(strings.TrimRight(content, "="))
+}
+
+func parseURL(link string) (*url.URL, error) {
+	linkURL, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+	if linkURL.Hostname() == "" {
+		return nil, E.New("missing server address")
+	}
+	return linkURL, nil
+}
+
+func parsePort(linkURL *url.URL) (uint16, error) {
+	portString := linkURL.Port()
+	if portString == "" {
+		return 443, nil
+	}
+	port, err := strconv.ParseUint(portString, 10, 16)
+	if err != nil {
+		return 0, E.Cause(err, "parse port")
+	}
+	return uint16(port), nil
+}
+
+func serverOptions(linkURL *url.URL) (option.ServerOptions, error) {
+	port, err := parsePort(linkURL)
+	if err != nil {
+		return option.ServerOptions{}, err
+	}
+	return option.ServerOptions{
+		Server:     linkURL.Hostname(),
+		ServerPort: port,
+	}, nil
+}
+
+func linkTag(linkURL *url.URL) string {
+	if linkURL.Fragment != "" {
+		return linkURL.Fragment
+	}
+	return linkURL.Host
+}
+
+func isTrue(value string) bool {
+	switch strings.ToLower(value) {
+	case "1", "true", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
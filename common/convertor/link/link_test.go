@@ -0,0 +1,70 @@
+package link
+
+import (
+	"encoding/base64"
+	"testing"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVLESS(t *testing.T) {
+	t.Parallel()
+	outbound, err := Parse("vless://b831381d-6324-4d53-ad4f-8cda48b30811@example.com:443?security=reality&sni=www.example.org&fp=chrome&pbk=key&sid=6ba85179e30d4fc2&type=grpc&serviceName=grpc&flow=xtls-rprx-vision#node%201")
+	require.NoError(t, err)
+	require.Equal(t, C.TypeVLESS, outbound.Type)
+	require.Equal(t, "node 1", outbound.Tag)
+	options := outbound.Options.(*option.VLESSOutboundOptions)
+	require.Equal(t, "example.com", options.Server)
+	require.Equal(t, uint16(443), options.ServerPort)
+	require.Equal(t, "xtls-rprx-vision", options.Flow)
+	require.True(t, options.TLS.Reality.Enabled)
+	require.Equal(t, "key", options.TLS.Reality.PublicKey)
+	require.Equal(t, C.V2RayTransportTypeGRPC, options.Transport.Type)
+	require.Equal(t, "grpc", options.Transport.GRPCOptions.ServiceName)
+}
+
+func TestParseVMess(t *testing.T) {
+	t.Parallel()
+	payload := `{"v":"2","ps":"vmess node","add":"example.com","port":8443,"id":"b831381d-6324-4d53-ad4f-8cda48b30811","aid":"0","net":"ws","host":"cdn.example.com","path":"/ws","tls":"tls"}`
+	outbound, err := Parse("vmess://" + base64.StdEncoding.EncodeToString([]byte(payload)))
+	require.NoError(t, err)
+	require.Equal(t, "vmess node", outbound.Tag)
+	options := outbound.Options.(*option.VMessOutboundOptions)
+	require.Equal(t, uint16(8443), options.ServerPort)
+	require.Equal(t, "auto", options.Security)
+	require.Equal(t, "cdn.example.com", options.TLS.ServerName)
+	require.Equal(t, "/ws", options.Transport.WebsocketOptions.Path)
+}
+
+func TestParseShadowsocks(t *testing.T) {
+	t.Parallel()
+	userInfo := base64.RawURLEncoding.EncodeToString([]byte("2022-blake3-aes-128-gcm:password"))
+	for _, link := range []string{
+		"ss://" + userInfo + "@example.com:8388#ss",
+		"ss://" + base64.StdEncoding.EncodeToString([]byte("2022-blake3-aes-128-gcm:password@example.com:8388")) + "#ss",
+	} {
+		outbound, err := Parse(link)
+		require.NoError(t, err)
+		require.Equal(t, "ss", outbound.Tag)
+		options := outbound.Options.(*option.ShadowsocksOutboundOptions)
+		require.Equal(t, "2022-blake3-aes-128-gcm", options.Method)
+		require.Equal(t, "password", options.Password)
+		require.Equal(t, uint16(8388), options.ServerPort)
+	}
+}
+
+func TestParseList(t *testing.T) {
+	t.Parallel()
+	content := "trojan://password@example.com:443#trojan\nhysteria2://auth@example.com:443?obfs=salamander&obfs-password=obfs#hy2\nunknown://example.com\n"
+	outbounds, errors := ParseList([]byte(base64.StdEncoding.EncodeToString([]byte(content))))
+	require.Len(t, outbounds, 2)
+	require.Len(t, errors, 1)
+	require.Equal(t, C.TypeTrojan, outbounds[0].Type)
+	require.True(t, outbounds[0].Options.(*option.TrojanOutboundOptions).TLS.Enabled)
+	hysteria2Options := outbounds[1].Options.(*option.Hysteria2OutboundOptions)
+	require.Equal(t, "auth", hysteria2Options.Password)
+	require.Equal(t, "salamander", hysteria2Options.Obfs.Type)
+}
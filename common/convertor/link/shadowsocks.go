@@ -0,0 +1,67 @@
+package link
+
+import (
+	"net/url"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+func parseShadowsocks(link string) (option.Outbound, error) {
+	linkURL, err := url.Parse(link)
+	if err != nil {
+		return option.Outbound{}, err
+	}
+	// legacy format: ss://base64(method:password@host:port)#tag
+	if linkURL.User == nil {
+		decoded, err := DecodeBase64(linkURL.Host)
+		if err != nil {
+			return option.Outbound{}, E.Cause(err, "decode shadowsocks link")
+		}
+		fragment := linkURL.Fragment
+		linkURL, err = url.Parse("ss://" + string(decoded))
+		if err != nil {
+			return option.Outbound{}, err
+		}
+		linkURL.Fragment = fragment
+	}
+	if linkURL.Hostname() == "" {
+		return option.Outbound{}, E.New("missing server address")
+	}
+	server, err := serverOptions(linkURL)
+	if err != nil {
+		return option.Outbound{}, err
+	}
+	var method, password string
+	if userPassword, hasPassword := linkURL.User.Password(); hasPassword {
+		method = linkURL.User.Username()
+		password = userPassword
+	} else {
+		decoded, err := DecodeBase64(linkURL.User.Username())
+		if err != nil {
+			return option.Outbound{}, E.Cause(err, "decode shadowsocks user info")
+		}
+		var found bool
+		method, password, found = strings.Cut(string(decoded), ":")
+		if !found {
+			return option.Outbound{}, E.New("invalid shadowsocks user info")
+		}
+	}
+	options := option.ShadowsocksOutboundOptions{
+		ServerOptions: server,
+		Method:        method,
+		Password:      password,
+	}
+	if plugin := linkURL.Query().Get("plugin"); plugin != "" {
+		pluginName, pluginOptions, _ := strings.Cut(plugin, ";")
+		options.Plugin = pluginName
+		options.PluginOptions = pluginOptions
+	}
+	return option.Outbound{
+		Type:    C.TypeShadowsocks,
+		Tag:     linkTag(linkURL),
+		Options: &options,
+	}, nil
+}
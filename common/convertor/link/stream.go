@@ -0,0 +1,128 @@
+package link
+
+import (
+	"net/url"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json/badoption"
+)
+
+// streamOptions holds the transport and TLS fields shared by V2Ray-style share links.
+type streamOptions struct {
+	Network     string
+	Security    string
+	ServerName  string
+	Insecure    bool
+	ALPN        []string
+	Fingerprint string
+	PublicKey   string
+	ShortID     string
+	Host        string
+	Path        string
+	ServiceName string
+}
+
+func parseStreamQuery(query url.Values) streamOptions {
+	stream := streamOptions{
+		Network:     query.Get("type"),
+		Security:    query.Get("security"),
+		ServerName:  query.Get("sni"),
+		Insecure:    isTrue(query.Get("allowInsecure")) || isTrue(query.Get("insecure")),
+		ALPN:        splitList(query.Get("alpn")),
+		Fingerprint: query.Get("fp"),
+		PublicKey:   query.Get("pbk"),
+		ShortID:     query.Get("sid"),
+		Host:        query.Get("host"),
+		Path:        query.Get("path"),
+		ServiceName: query.Get("serviceName"),
+	}
+	if stream.ServerName == "" {
+		stream.ServerName = query.Get("peer")
+	}
+	return stream
+}
+
+func (s streamOptions) TLSOptions() *option.OutboundTLSOptions {
+	switch s.Security {
+	case "tls", "xtls", "reality":
+	default:
+		return nil
+	}
+	tlsOptions := &option.OutboundTLSOptions{
+		Enabled:    true,
+		ServerName: s.ServerName,
+		Insecure:   s.Insecure,
+		ALPN:       s.ALPN,
+	}
+	if s.Fingerprint != "" {
+		tlsOptions.UTLS = &option.OutboundUTLSOptions{
+			Enabled:     true,
+			Fingerprint: s.Fingerprint,
+		}
+	}
+	if s.Security == "reality" {
+		tlsOptions.Reality = &option.OutboundRealityOptions{
+			Enabled:   true,
+			PublicKey: s.PublicKey,
+			ShortID:   s.ShortID,
+		}
+		if tlsOptions.UTLS == nil {
+			tlsOptions.UTLS = &option.OutboundUTLSOptions{
+				Enabled:     true,
+				Fingerprint: "chrome",
+			}
+		}
+	}
+	return tlsOptions
+}
+
+func (s streamOptions) TransportOptions() (*option.V2RayTransportOptions, error) {
+	switch s.Network {
+	case "", "tcp", "raw":
+		return nil, nil
+	case C.V2RayTransportTypeWebsocket:
+		return &option.V2RayTransportOptions{
+			Type: C.V2RayTransportTypeWebsocket,
+			WebsocketOptions: option.V2RayWebsocketOptions{
+				Host: s.Host,
+				Path: s.Path,
+			},
+		}, nil
+	case C.V2RayTransportTypeGRPC:
+		return &option.V2RayTransportOptions{
+			Type: C.V2RayTransportTypeGRPC,
+			GRPCOptions: option.V2RayGRPCOptions{
+				ServiceName: s.ServiceName,
+			},
+		}, nil
+	case C.V2RayTransportTypeHTTPUpgrade:
+		return &option.V2RayTransportOptions{
+			Type: C.V2RayTransportTypeHTTPUpgrade,
+			HTTPUpgradeOptions: option.V2RayHTTPUpgradeOptions{
+				Host: s.Host,
+				Path: s.Path,
+			},
+		}, nil
+	case C.V2RayTransportTypeHTTP, "h2":
+		var host badoption.Listable[string]
+		if s.Host != "" {
+			host = strings.Split(s.Host, ",")
+		}
+		return &option.V2RayTransportOptions{
+			Type: C.V2RayTransportTypeHTTP,
+			HTTPOptions: option.V2RayHTTPOptions{
+				Host: host,
+				Path: s.Path,
+			},
+		}, nil
+	case C.V2RayTransportTypeQUIC:
+		return &option.V2RayTransportOptions{
+			Type: C.V2RayTransportTypeQUIC,
+		}, nil
+	default:
+		return nil, E.New("unsupported transport type: ", s.Network)
+	}
+}
@@ -0,0 +1,41 @@
+package link
+
+import (
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+func parseTrojan(link string) (option.Outbound, error) {
+	linkURL, err := parseURL(link)
+	if err != nil {
+		return option.Outbound{}, err
+	}
+	if linkURL.User == nil || linkURL.User.Username() == "" {
+		return option.Outbound{}, E.New("missing password")
+	}
+	server, err := serverOptions(linkURL)
+	if err != nil {
+		return option.Outbound{}, err
+	}
+	query := linkURL.Query()
+	options := option.TrojanOutboundOptions{
+		ServerOptions: server,
+		Password:      linkURL.User.Username(),
+	}
+	stream := parseStreamQuery(query)
+	// trojan implies TLS unless explicitly disabled
+	if stream.Security == "" {
+		stream.Security = "tls"
+	}
+	options.TLS = stream.TLSOptions()
+	options.Transport, err = stream.TransportOptions()
+	if err != nil {
+		return option.Outbound{}, err
+	}
+	return option.Outbound{
+		Type:    C.TypeTrojan,
+		Tag:     linkTag(linkURL),
+		Options: &options,
+	}, nil
+}
@@ -0,0 +1,44 @@
+package link
+
+import (
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+func parseTUIC(link string) (option.Outbound, error) {
+	linkURL, err := parseURL(link)
+	if err != nil {
+		return option.Outbound{}, err
+	}
+	if linkURL.User == nil || linkURL.User.Username() == "" {
+		return option.Outbound{}, E.New("missing uuid")
+	}
+	server, err := serverOptions(linkURL)
+	if err != nil {
+		return option.Outbound{}, err
+	}
+	query := linkURL.Query()
+	password, _ := linkURL.User.Password()
+	options := option.TUICOutboundOptions{
+		ServerOptions:     server,
+		UUID:              linkURL.User.Username(),
+		Password:          password,
+		CongestionControl: query.Get("congestion_control"),
+		UDPRelayMode:      query.Get("udp_relay_mode"),
+		OutboundTLSOptionsContainer: option.OutboundTLSOptionsContainer{
+			TLS: &option.OutboundTLSOptions{
+				Enabled:    true,
+				ServerName: query.Get("sni"),
+				Insecure:   isTrue(query.Get("allow_insecure")) || isTrue(query.Get("insecure")),
+				ALPN:       splitList(query.Get("alpn")),
+				DisableSNI: isTrue(query.Get("disable_sni")),
+			},
+		},
+	}
+	return option.Outbound{
+		Type:    C.TypeTUIC,
+		Tag:     linkTag(linkURL),
+		Options: &options,
+	}, nil
+}
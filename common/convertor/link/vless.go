@@ -0,0 +1,41 @@
+package link
+
+import (
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+func parseVLESS(link string) (option.Outbound, error) {
+	linkURL, err := parseURL(link)
+	if err != nil {
+		return option.Outbound{}, err
+	}
+	if linkURL.User == nil || linkURL.User.Username() == "" {
+		return option.Outbound{}, E.New("missing uuid")
+	}
+	server, err := serverOptions(linkURL)
+	if err != nil {
+		return option.Outbound{}, err
+	}
+	query := linkURL.Query()
+	options := option.VLESSOutboundOptions{
+		ServerOptions: server,
+		UUID:          linkURL.User.Username(),
+		Flow:          query.Get("flow"),
+	}
+	if packetEncoding := query.Get("packetEncoding"); packetEncoding != "" {
+		options.PacketEncoding = &packetEncoding
+	}
+	stream := parseStreamQuery(query)
+	options.TLS = stream.TLSOptions()
+	options.Transport, err = stream.TransportOptions()
+	if err != nil {
+		return option.Outbound{}, err
+	}
+	return option.Outbound{
+		Type:    C.TypeVLESS,
+		Tag:     linkTag(linkURL),
+		Options: &options,
+	}, nil
+}
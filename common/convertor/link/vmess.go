@@ -0,0 +1,115 @@
+package link
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// vmessLink is the v2rayN style JSON payload of a vmess:// link.
+type vmessLink struct {
+	Version     flexibleString `json:"v"`
+	Remarks     string         `json:"ps"`
+	Address     string         `json:"add"`
+	Port        flexibleString `json:"port"`
+	ID          string         `json:"id"`
+	AlterID     flexibleString `json:"aid"`
+	Security    string         `json:"scy,omitempty"`
+	Network     string         `json:"net"`
+	Type        string         `json:"type,omitempty"`
+	Host        string         `json:"host,omitempty"`
+	Path        string         `json:"path,omitempty"`
+	TLS         string         `json:"tls,omitempty"`
+	SNI         string         `json:"sni,omitempty"`
+	ALPN        string         `json:"alpn,omitempty"`
+	Fingerprint string         `json:"fp,omitempty"`
+}
+
+type flexibleString string
+
+func (s *flexibleString) UnmarshalJSON(content []byte) error {
+	var stringValue string
+	if json.Unmarshal(content, &stringValue) == nil {
+		*s = flexibleString(stringValue)
+		return nil
+	}
+	var numberValue json.Number
+	err := json.Unmarshal(content, &numberValue)
+	if err != nil {
+		return err
+	}
+	*s = flexibleString(numberValue.String())
+	return nil
+}
+
+func parseVMess(link string) (option.Outbound, error) {
+	_, encoded, _ := strings.Cut(link, "://")
+	payload, err := DecodeBase64(encoded)
+	if err != nil {
+		return option.Outbound{}, E.Cause(err, "decode vmess link")
+	}
+	var content vmessLink
+	err = json.Unmarshal(payload, &content)
+	if err != nil {
+		return option.Outbound{}, E.Cause(err, "decode vmess link")
+	}
+	if content.Address == "" {
+		return option.Outbound{}, E.New("missing server address")
+	}
+	port, err := strconv.ParseUint(string(content.Port), 10, 16)
+	if err != nil {
+		return option.Outbound{}, E.Cause(err, "parse port")
+	}
+	alterID, _ := strconv.Atoi(string(content.AlterID))
+	security := content.Security
+	if security == "" {
+		security = "auto"
+	}
+	options := option.VMessOutboundOptions{
+		ServerOptions: option.ServerOptions{
+			Server:     content.Address,
+			ServerPort: uint16(port),
+		},
+		UUID:     content.ID,
+		Security: security,
+		AlterId:  alterID,
+	}
+	stream := streamOptions{
+		Network:     content.Network,
+		Security:    content.TLS,
+		ServerName:  content.SNI,
+		ALPN:        splitList(content.ALPN),
+		Fingerprint: content.Fingerprint,
+		Host:        content.Host,
+		Path:        content.Path,
+	}
+	switch content.Network {
+	case C.V2RayTransportTypeGRPC:
+		stream.ServiceName = content.Path
+	case "tcp", "":
+		if content.Type == "http" {
+			stream.Network = C.V2RayTransportTypeHTTP
+		}
+	}
+	if stream.ServerName == "" && content.TLS == "tls" {
+		stream.ServerName = content.Host
+	}
+	options.TLS = stream.TLSOptions()
+	options.Transport, err = stream.TransportOptions()
+	if err != nil {
+		return option.Outbound{}, err
+	}
+	tag := content.Remarks
+	if tag == "" {
+		tag = content.Address + ":" + string(content.Port)
+	}
+	return option.Outbound{
+		Type:    C.TypeVMess,
+		Tag:     tag,
+		Options: &options,
+	}, nil
+}
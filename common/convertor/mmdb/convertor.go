@@ -0,0 +1,84 @@
+// Package mmdb extracts country networks from MaxMind DB files.
+package mmdb
+
+import (
+	"net"
+	"net/netip"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/oschwald/maxminddb-golang"
+	"go4.org/netipx"
+)
+
+type countryRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	RegisteredCountry struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"registered_country"`
+}
+
+// ToOptions converts networks of the country code to rules.
+// Both GeoLite2/DB-IP country databases and sing-geoip databases are supported.
+func ToOptions(content []byte, code string) ([]option.HeadlessRule, error) {
+	reader, err := maxminddb.FromBytes(content)
+	if err != nil {
+		return nil, E.Cause(err, "open mmdb")
+	}
+	defer reader.Close()
+	isSingGeoIP := reader.Metadata.DatabaseType == "sing-geoip"
+	var builder netipx.IPSetBuilder
+	networks := reader.Networks(maxminddb.SkipAliasedNetworks)
+	for networks.Next() {
+		var (
+			network    *net.IPNet
+			recordCode string
+		)
+		if isSingGeoIP {
+			network, err = networks.Network(&recordCode)
+		} else {
+			var record countryRecord
+			network, err = networks.Network(&record)
+			recordCode = record.Country.ISOCode
+			if recordCode == "" {
+				recordCode = record.RegisteredCountry.ISOCode
+			}
+		}
+		if err != nil {
+			return nil, E.Cause(err, "read mmdb record")
+		}
+		if !strings.EqualFold(recordCode, code) {
+			continue
+		}
+		prefix, ok := netipx.FromStdIPNet(network)
+		if !ok {
+			continue
+		}
+		builder.AddPrefix(prefix)
+	}
+	if err = networks.Err(); err != nil {
+		return nil, E.Cause(err, "read mmdb")
+	}
+	ipSet, err := builder.IPSet()
+	if err != nil {
+		return nil, err
+	}
+	prefixes := ipSet.Prefixes()
+	if len(prefixes) == 0 {
+		return nil, E.New("code not found: ", code)
+	}
+	return []option.HeadlessRule{
+		{
+			Type: C.RuleTypeDefault,
+			DefaultOptions: option.DefaultHeadlessRule{
+				IPCIDR: common.Map(prefixes, netip.Prefix.String),
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,240 @@
+package xray
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+)
+
+type Config struct {
+	Log       *LogConfig       `json:"log,omitempty"`
+	DNS       *DNSConfig       `json:"dns,omitempty"`
+	FakeDNS   json.RawMessage  `json:"fakedns,omitempty"`
+	Routing   *RoutingConfig   `json:"routing,omitempty"`
+	Inbounds  []InboundConfig  `json:"inbounds,omitempty"`
+	Outbounds []OutboundConfig `json:"outbounds,omitempty"`
+
+	unsupportedSections []string
+}
+
+type LogConfig struct {
+	LogLevel string `json:"loglevel,omitempty"`
+}
+
+type InboundConfig struct {
+	Tag            string          `json:"tag,omitempty"`
+	Listen         string          `json:"listen,omitempty"`
+	Port           StringOrNumber  `json:"port,omitempty"`
+	Protocol       string          `json:"protocol"`
+	Settings       Settings        `json:"settings,omitempty"`
+	StreamSettings *StreamSettings `json:"streamSettings,omitempty"`
+	Sniffing       *Sniffing       `json:"sniffing,omitempty"`
+}
+
+type OutboundConfig struct {
+	Tag            string          `json:"tag,omitempty"`
+	Protocol       string          `json:"protocol"`
+	Settings       Settings        `json:"settings,omitempty"`
+	StreamSettings *StreamSettings `json:"streamSettings,omitempty"`
+	ProxySettings  *ProxySettings  `json:"proxySettings,omitempty"`
+	Mux            *MuxSettings    `json:"mux,omitempty"`
+}
+
+type ProxySettings struct {
+	Tag string `json:"tag,omitempty"`
+}
+
+type MuxSettings struct {
+	Enabled bool `json:"enabled,omitempty"`
+}
+
+type Sniffing struct {
+	Enabled      bool     `json:"enabled,omitempty"`
+	DestOverride []string `json:"destOverride,omitempty"`
+	RouteOnly    bool     `json:"routeOnly,omitempty"`
+}
+
+// Settings is the union of the protocol settings used by inbounds and outbounds.
+type Settings struct {
+	// outbound
+	Vnext   []ServerConfig `json:"vnext,omitempty"`
+	Servers []ServerConfig `json:"servers,omitempty"`
+
+	// inbound
+	Clients        []User     `json:"clients,omitempty"`
+	Decryption     string     `json:"decryption,omitempty"`
+	Fallbacks      []Fallback `json:"fallbacks,omitempty"`
+	Auth           string     `json:"auth,omitempty"`
+	Accounts       []Account  `json:"accounts,omitempty"`
+	UDP            bool       `json:"udp,omitempty"`
+	Address        string     `json:"address,omitempty"`
+	Port           uint16     `json:"port,omitempty"`
+	FollowRedirect bool       `json:"followRedirect,omitempty"`
+
+	// shared
+	Method         string `json:"method,omitempty"`
+	Password       string `json:"password,omitempty"`
+	Network        string `json:"network,omitempty"`
+	DomainStrategy string `json:"domainStrategy,omitempty"`
+}
+
+type ServerConfig struct {
+	Address  string `json:"address"`
+	Port     uint16 `json:"port"`
+	Users    []User `json:"users,omitempty"`
+	Password string `json:"password,omitempty"`
+	Method   string `json:"method,omitempty"`
+	Flow     string `json:"flow,omitempty"`
+	Email    string `json:"email,omitempty"`
+	UoT      bool   `json:"uot,omitempty"`
+}
+
+type User struct {
+	ID         string `json:"id,omitempty"`
+	Flow       string `json:"flow,omitempty"`
+	AlterID    int    `json:"alterId,omitempty"`
+	Security   string `json:"security,omitempty"`
+	Encryption string `json:"encryption,omitempty"`
+	Password   string `json:"password,omitempty"`
+	Method     string `json:"method,omitempty"`
+	Email      string `json:"email,omitempty"`
+	User       string `json:"user,omitempty"`
+	Pass       string `json:"pass,omitempty"`
+}
+
+type Account struct {
+	User string `json:"user"`
+	Pass string `json:"pass"`
+}
+
+type Fallback struct {
+	Dest StringOrNumber `json:"dest"`
+	ALPN string         `json:"alpn,omitempty"`
+	Path string         `json:"path,omitempty"`
+	Name string         `json:"name,omitempty"`
+}
+
+type StreamSettings struct {
+	Network             string               `json:"network,omitempty"`
+	Security            string               `json:"security,omitempty"`
+	TLSSettings         *TLSSettings         `json:"tlsSettings,omitempty"`
+	RealitySettings     *RealitySettings     `json:"realitySettings,omitempty"`
+	TCPSettings         *TCPSettings         `json:"tcpSettings,omitempty"`
+	RawSettings         *TCPSettings         `json:"rawSettings,omitempty"`
+	WSSettings          *WSSettings          `json:"wsSettings,omitempty"`
+	GRPCSettings        *GRPCSettings        `json:"grpcSettings,omitempty"`
+	HTTPUpgradeSettings *HTTPUpgradeSettings `json:"httpupgradeSettings,omitempty"`
+	HTTPSettings        *HTTPSettings        `json:"httpSettings,omitempty"`
+	Sockopt             *Sockopt             `json:"sockopt,omitempty"`
+}
+
+type TLSSettings struct {
+	ServerName    string        `json:"serverName,omitempty"`
+	ALPN          []string      `json:"alpn,omitempty"`
+	AllowInsecure bool          `json:"allowInsecure,omitempty"`
+	Fingerprint   string        `json:"fingerprint,omitempty"`
+	Certificates  []Certificate `json:"certificates,omitempty"`
+}
+
+type Certificate struct {
+	CertificateFile string   `json:"certificateFile,omitempty"`
+	KeyFile         string   `json:"keyFile,omitempty"`
+	Certificate     []string `json:"certificate,omitempty"`
+	Key             []string `json:"key,omitempty"`
+}
+
+type RealitySettings struct {
+	// server
+	Show        bool           `json:"show,omitempty"`
+	Dest        StringOrNumber `json:"dest,omitempty"`
+	Target      StringOrNumber `json:"target,omitempty"`
+	ServerNames []string       `json:"serverNames,omitempty"`
+	PrivateKey  string         `json:"privateKey,omitempty"`
+	ShortIDs    []string       `json:"shortIds,omitempty"`
+	MaxTimeDiff int64          `json:"maxTimeDiff,omitempty"`
+
+	// client
+	ServerName  string `json:"serverName,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	PublicKey   string `json:"publicKey,omitempty"`
+	Password    string `json:"password,omitempty"`
+	ShortID     string `json:"shortId,omitempty"`
+	SpiderX     string `json:"spiderX,omitempty"`
+}
+
+type TCPSettings struct {
+	Header *struct {
+		Type string `json:"type,omitempty"`
+	} `json:"header,omitempty"`
+}
+
+type WSSettings struct {
+	Path    string            `json:"path,omitempty"`
+	Host    string            `json:"host,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+type GRPCSettings struct {
+	ServiceName string `json:"serviceName,omitempty"`
+	MultiMode   bool   `json:"multiMode,omitempty"`
+}
+
+type HTTPUpgradeSettings struct {
+	Path string `json:"path,omitempty"`
+	Host string `json:"host,omitempty"`
+}
+
+type HTTPSettings struct {
+	Host []string `json:"host,omitempty"`
+	Path string   `json:"path,omitempty"`
+}
+
+type Sockopt struct {
+	Mark        uint32         `json:"mark,omitempty"`
+	TCPFastOpen StringOrNumber `json:"tcpFastOpen,omitempty"`
+	TProxy      string         `json:"tproxy,omitempty"`
+	Interface   string         `json:"interface,omitempty"`
+	DialerProxy string         `json:"dialerProxy,omitempty"`
+}
+
+// StringOrNumber accepts JSON strings, numbers and booleans, which Xray uses interchangeably.
+type StringOrNumber string
+
+func (s *StringOrNumber) UnmarshalJSON(content []byte) error {
+	var stringValue string
+	if json.Unmarshal(content, &stringValue) == nil {
+		*s = StringOrNumber(stringValue)
+		return nil
+	}
+	*s = StringOrNumber(strings.Trim(string(content), `"`))
+	return nil
+}
+
+func (s StringOrNumber) Bool() bool {
+	value, _ := strconv.ParseBool(string(s))
+	return value || s == "1"
+}
+
+var knownSections = map[string]bool{
+	"log": true, "dns": true, "fakedns": true, "routing": true, "inbounds": true, "outbounds": true,
+}
+
+func ParseConfig(content []byte) (*Config, error) {
+	config, err := json.UnmarshalExtended[Config](content)
+	if err != nil {
+		return nil, E.Cause(err, "decode xray config")
+	}
+	sections, err := json.UnmarshalExtended[map[string]json.RawMessage](content)
+	if err != nil {
+		return nil, E.Cause(err, "decode xray config")
+	}
+	for section, value := range sections {
+		if !knownSections[section] && !bytes.Equal(bytes.TrimSpace(value), []byte("null")) {
+			config.unsupportedSections = append(config.unsupportedSections, section)
+		}
+	}
+	return &config, nil
+}
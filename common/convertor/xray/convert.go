@@ -0,0 +1,90 @@
+package xray
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/logger"
+	N "github.com/sagernet/sing/common/network"
+)
+
+type converter struct {
+	config          *Config
+	logger          logger.Logger
+	options         option.Options
+	route           option.RouteOptions
+	outboundTags    map[string]bool
+	balancerTags    map[string]bool
+	actionTags      map[string]option.RuleAction
+	ruleSets        map[string]bool
+	sniffRules      []option.Rule
+	resolveInserted bool
+}
+
+// Convert translates an Xray or V2Ray JSON configuration into sing-box options.
+// Features without a sing-box equivalent are skipped and reported to the logger.
+func Convert(content []byte, logger logger.Logger) (*option.Options, error) {
+	config, err := ParseConfig(content)
+	if err != nil {
+		return nil, err
+	}
+	c := &converter{
+		config:       config,
+		logger:       logger,
+		outboundTags: make(map[string]bool),
+		balancerTags: make(map[string]bool),
+		actionTags:   make(map[string]option.RuleAction),
+		ruleSets:     make(map[string]bool),
+	}
+	for _, section := range config.unsupportedSections {
+		logger.Warn("ignored unsupported section: ", section)
+	}
+	c.convertLog()
+	c.convertInbounds()
+	c.convertOutbounds()
+	c.convertBalancers()
+	c.convertDNS()
+	c.convertRules()
+	c.options.Route = &c.route
+	return &c.options, nil
+}
+
+func (c *converter) convertLog() {
+	if c.config.Log == nil {
+		return
+	}
+	switch c.config.Log.LogLevel {
+	case "":
+	case "none":
+		c.options.Log = &option.LogOptions{Disabled: true}
+	case "warning":
+		c.options.Log = &option.LogOptions{Level: "warn"}
+	default:
+		c.options.Log = &option.LogOptions{Level: c.config.Log.LogLevel}
+	}
+}
+
+// networkList converts Xray network lists such as `tcp,udp`, returning empty for both networks.
+func networkList(network string) option.NetworkList {
+	var networks []string
+	for _, name := range strings.Split(network, ",") {
+		switch name = strings.TrimSpace(name); name {
+		case N.NetworkTCP, N.NetworkUDP:
+			networks = append(networks, name)
+		}
+	}
+	if len(networks) != 1 {
+		return ""
+	}
+	return option.NetworkList(networks[0])
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,61 @@
+package xray
+
+import (
+	"testing"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/logger"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvert(t *testing.T) {
+	t.Parallel()
+	content := `{
+  // comments are allowed
+  "inbounds": [{
+    "tag": "vless-in", "port": 443, "protocol": "vless",
+    "settings": {"clients": [{"id": "b831381d-6324-4d53-ad4f-8cda48b30811", "email": "user"}], "decryption": "none"},
+    "streamSettings": {
+      "security": "reality",
+      "realitySettings": {"dest": "example.org:443", "serverNames": ["example.org"], "privateKey": "key", "shortIds": ["0123"]}
+    }
+  }],
+  "outbounds": [
+    {
+      "tag": "proxy", "protocol": "vmess",
+      "settings": {"vnext": [{"address": "example.com", "port": 443, "users": [{"id": "b831381d-6324-4d53-ad4f-8cda48b30811"}]}]},
+      "streamSettings": {"network": "ws", "security": "tls", "wsSettings": {"path": "/ws?ed=2048"}}
+    },
+    {"tag": "direct", "protocol": "freedom"},
+    {"tag": "block", "protocol": "blackhole"}
+  ],
+  "routing": {
+    "domainStrategy": "IPIfNonMatch",
+    "rules": [
+      {"domain": ["domain:ads.com"], "outboundTag": "block"},
+      {"domain": ["full:a.cn"], "ip": ["geoip:cn"], "outboundTag": "direct"},
+      {"port": "1000-2000", "outboundTag": "unknown"}
+    ]
+  }
+}`
+	options, err := Convert([]byte(content), logger.NOP())
+	require.NoError(t, err)
+	require.Len(t, options.Inbounds, 1)
+	tlsOptions := options.Inbounds[0].Options.(*option.VLESSInboundOptions).TLS
+	require.Equal(t, "example.org", tlsOptions.ServerName)
+	require.Equal(t, uint16(443), tlsOptions.Reality.Handshake.ServerPort)
+	require.Len(t, options.Outbounds, 2)
+	transport := options.Outbounds[0].Options.(*option.VMessOutboundOptions).Transport
+	require.Equal(t, "/ws", transport.WebsocketOptions.Path)
+	require.Equal(t, uint32(2048), transport.WebsocketOptions.MaxEarlyData)
+	rules := options.Route.Rules
+	require.Len(t, rules, 3)
+	require.Equal(t, C.RuleActionTypeReject, rules[0].DefaultOptions.Action)
+	require.Equal(t, []string{"ads.com"}, []string(rules[0].DefaultOptions.DomainSuffix))
+	require.Equal(t, C.RuleActionTypeResolve, rules[1].DefaultOptions.Action)
+	require.Equal(t, C.RuleTypeLogical, rules[2].Type)
+	require.Equal(t, "direct", rules[2].LogicalOptions.RouteOptions.Outbound)
+	require.Equal(t, "proxy", options.Route.Final)
+}
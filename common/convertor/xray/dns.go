@@ -0,0 +1,325 @@
+package xray
+
+import (
+	"net"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/common/json/badjson"
+	"github.com/sagernet/sing/common/json/badoption"
+
+	mDNS "github.com/miekg/dns"
+)
+
+const (
+	dnsTagLocal = "local"
+	dnsTagHosts = "hosts"
+)
+
+type DNSConfig struct {
+	Hosts         map[string]json.RawMessage `json:"hosts,omitempty"`
+	Servers       []DNSServerConfig          `json:"servers,omitempty"`
+	QueryStrategy string                     `json:"queryStrategy,omitempty"`
+	ClientIP      string                     `json:"clientIp,omitempty"`
+}
+
+// DNSServerConfig is either a bare address string or an object with domain and IP filters.
+type DNSServerConfig struct {
+	Address   string   `json:"address"`
+	Port      uint16   `json:"port,omitempty"`
+	Domains   []string `json:"domains,omitempty"`
+	ExpectIPs []string `json:"expectIPs,omitempty"`
+	Tag       string   `json:"tag,omitempty"`
+}
+
+type FakeDNSPool struct {
+	IPPool string `json:"ipPool"`
+}
+
+func (s *DNSServerConfig) UnmarshalJSON(content []byte) error {
+	var address string
+	if json.Unmarshal(content, &address) == nil {
+		*s = DNSServerConfig{Address: address}
+		return nil
+	}
+	type _DNSServerConfig DNSServerConfig
+	return json.Unmarshal(content, (*_DNSServerConfig)(s))
+}
+
+type dnsConverter struct {
+	*converter
+	options   option.DNSOptions
+	needLocal bool
+	hasFakeIP bool
+}
+
+func (c *converter) convertDNS() {
+	dns := c.config.DNS
+	if dns == nil {
+		return
+	}
+	d := &dnsConverter{converter: c}
+	switch dns.QueryStrategy {
+	case "", "UseIP":
+	case "UseIPv4":
+		d.options.Strategy = option.DomainStrategy(C.DomainStrategyIPv4Only)
+	case "UseIPv6":
+		d.options.Strategy = option.DomainStrategy(C.DomainStrategyIPv6Only)
+	default:
+		c.logger.Warn("ignored unsupported dns.queryStrategy: ", dns.QueryStrategy)
+	}
+	if dns.ClientIP != "" {
+		clientSubnet, err := netip.ParseAddr(dns.ClientIP)
+		if err != nil {
+			c.logger.Warn("ignored invalid dns.clientIp: ", dns.ClientIP)
+		} else {
+			d.options.ClientSubnet = common.Ptr(badoption.Prefixable(netip.PrefixFrom(clientSubnet, clientSubnet.BitLen())))
+		}
+	}
+	d.convertHosts()
+	var fakeIPRule *option.DNSRule
+	for index, server := range dns.Servers {
+		tag := server.Tag
+		if tag == "" {
+			tag = "dns-" + strconv.Itoa(index)
+		}
+		serverOptions, err := d.parseServer(tag, server)
+		if err != nil {
+			c.logger.Warn("ignored dns server ", server.Address, ": ", err)
+			continue
+		}
+		d.options.Servers = append(d.options.Servers, serverOptions)
+		if len(server.ExpectIPs) > 0 {
+			c.logger.Warn("dns server ", server.Address, ": ignored unsupported expectIPs")
+		}
+		if len(server.Domains) > 0 {
+			matcher, err := c.parseDomains(server.Domains)
+			if err != nil {
+				c.logger.Warn("dns server ", server.Address, ": ignored domains: ", err)
+				continue
+			}
+			rule := dnsRouteRule(tag)
+			rule.DefaultOptions.Domain = matcher.domain
+			rule.DefaultOptions.DomainSuffix = matcher.domainSuffix
+			rule.DefaultOptions.DomainKeyword = matcher.domainKeyword
+			rule.DefaultOptions.DomainRegex = matcher.domainRegex
+			rule.DefaultOptions.RuleSet = matcher.ruleSet
+			d.options.Rules = append(d.options.Rules, rule)
+		} else if d.options.Final == "" {
+			if serverOptions.Type != C.DNSTypeFakeIP {
+				d.options.Final = tag
+			} else if fakeIPRule == nil {
+				// a leading fakedns server answers all A and AAAA queries
+				rule := dnsRouteRule(tag)
+				rule.DefaultOptions.QueryType = []option.DNSQueryType{option.DNSQueryType(mDNS.TypeA), option.DNSQueryType(mDNS.TypeAAAA)}
+				fakeIPRule = &rule
+			}
+		}
+	}
+	if fakeIPRule != nil {
+		d.options.Rules = append(d.options.Rules, *fakeIPRule)
+	}
+	if d.needLocal || d.options.Final == "" {
+		d.options.Servers = append(d.options.Servers, option.DNSServerOptions{
+			Type:    C.DNSTypeLocal,
+			Tag:     dnsTagLocal,
+			Options: &option.LocalDNSServerOptions{},
+		})
+		if d.options.Final == "" {
+			d.options.Final = dnsTagLocal
+		}
+	}
+	c.route.DefaultDomainResolver = &option.DomainResolveOptions{Server: d.options.Final}
+	c.options.DNS = &d.options
+}
+
+func (d *dnsConverter) convertHosts() {
+	var (
+		predefined badjson.TypedMap[string, badoption.Listable[netip.Addr]]
+		domains    []string
+	)
+	for _, domain := range sortedKeys(d.config.DNS.Hosts) {
+		var values []string
+		err := json.Unmarshal(d.config.DNS.Hosts[domain], &values)
+		if err != nil {
+			var value string
+			err = json.Unmarshal(d.config.DNS.Hosts[domain], &value)
+			values = []string{value}
+		}
+		name := strings.TrimPrefix(domain, "full:")
+		if err != nil || strings.Contains(name, ":") {
+			d.logger.Warn("ignored unsupported hosts entry: ", domain)
+			continue
+		}
+		var addresses badoption.Listable[netip.Addr]
+		for _, value := range values {
+			address, err := netip.ParseAddr(value)
+			if err != nil {
+				d.logger.Warn("hosts entry ", domain, ": ignored unsupported value: ", value)
+				continue
+			}
+			addresses = append(addresses, address)
+		}
+		if len(addresses) == 0 {
+			continue
+		}
+		predefined.Put(name, addresses)
+		domains = append(domains, name)
+	}
+	if len(domains) == 0 {
+		return
+	}
+	d.options.Servers = append(d.options.Servers, option.DNSServerOptions{
+		Type: C.DNSTypeHosts,
+		Tag:  dnsTagHosts,
+		Options: &option.HostsDNSServerOptions{
+			Predefined: &predefined,
+		},
+	})
+	rule := dnsRouteRule(dnsTagHosts)
+	rule.DefaultOptions.Domain = domains
+	d.options.Rules = append(d.options.Rules, rule)
+}
+
+func (d *dnsConverter) parseServer(tag string, server DNSServerConfig) (option.DNSServerOptions, error) {
+	address := server.Address
+	switch address {
+	case "localhost":
+		return option.DNSServerOptions{
+			Type:    C.DNSTypeLocal,
+			Tag:     tag,
+			Options: &option.LocalDNSServerOptions{},
+		}, nil
+	case "fakedns":
+		if d.hasFakeIP {
+			return option.DNSServerOptions{}, E.New("duplicate fakedns server")
+		}
+		d.hasFakeIP = true
+		return option.DNSServerOptions{
+			Type:    C.DNSTypeFakeIP,
+			Tag:     tag,
+			Options: d.fakeIPOptions(),
+		}, nil
+	}
+	if !strings.Contains(address, "://") {
+		if server.Port != 0 {
+			address = "udp://" + net.JoinHostPort(address, strconv.Itoa(int(server.Port)))
+		} else if strings.Contains(address, ":") && !strings.HasPrefix(address, "[") {
+			address = "udp://[" + address + "]"
+		} else {
+			address = "udp://" + address
+		}
+	}
+	serverURL, err := url.Parse(address)
+	if err != nil {
+		return option.DNSServerOptions{}, E.Cause(err, "parse dns server")
+	}
+	if serverURL.Hostname() == "" {
+		return option.DNSServerOptions{}, E.New("missing dns server address")
+	}
+	var dialerOptions option.DialerOptions
+	if _, err = netip.ParseAddr(serverURL.Hostname()); err != nil {
+		d.needLocal = true
+		dialerOptions.DomainResolver = &option.DomainResolveOptions{Server: dnsTagLocal}
+	}
+	var serverPort uint16
+	if portString := serverURL.Port(); portString != "" {
+		port, err := strconv.ParseUint(portString, 10, 16)
+		if err != nil {
+			return option.DNSServerOptions{}, E.Cause(err, "parse dns server port")
+		}
+		serverPort = uint16(port)
+	}
+	remoteOptions := option.RemoteDNSServerOptions{
+		RawLocalDNSServerOptions: option.RawLocalDNSServerOptions{DialerOptions: dialerOptions},
+		DNSServerAddressOptions: option.DNSServerAddressOptions{
+			Server:     serverURL.Hostname(),
+			ServerPort: serverPort,
+		},
+	}
+	result := option.DNSServerOptions{Tag: tag}
+	// `+local` servers bypass the routing, which is the default for sing-box DNS servers
+	switch strings.TrimSuffix(serverURL.Scheme, "+local") {
+	case "udp":
+		result.Type = C.DNSTypeUDP
+		result.Options = &remoteOptions
+	case "tcp":
+		result.Type = C.DNSTypeTCP
+		result.Options = &remoteOptions
+	case "quic":
+		result.Type = C.DNSTypeQUIC
+		result.Options = &option.RemoteTLSDNSServerOptions{RemoteDNSServerOptions: remoteOptions}
+	case "https":
+		result.Type = C.DNSTypeHTTPS
+		httpsOptions := &option.RemoteHTTPSDNSServerOptions{
+			RemoteTLSDNSServerOptions: option.RemoteTLSDNSServerOptions{RemoteDNSServerOptions: remoteOptions},
+		}
+		if serverURL.Path != "/dns-query" {
+			httpsOptions.Path = serverURL.Path
+		}
+		result.Options = httpsOptions
+	default:
+		return option.DNSServerOptions{}, E.New("unsupported dns server scheme: ", serverURL.Scheme)
+	}
+	return result, nil
+}
+
+// fakeIPOptions reads the address pools of the top-level fakedns section, which is an object or a list.
+func (d *dnsConverter) fakeIPOptions() *option.FakeIPDNSServerOptions {
+	var pools []FakeDNSPool
+	if len(d.config.FakeDNS) > 0 {
+		err := json.Unmarshal(d.config.FakeDNS, &pools)
+		if err != nil {
+			var pool FakeDNSPool
+			err = json.Unmarshal(d.config.FakeDNS, &pool)
+			if err != nil {
+				d.logger.Warn("ignored invalid fakedns section: ", err)
+			}
+			pools = []FakeDNSPool{pool}
+		}
+	}
+	fakeIPOptions := &option.FakeIPDNSServerOptions{}
+	for _, pool := range pools {
+		if pool.IPPool == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(pool.IPPool)
+		if err != nil {
+			d.logger.Warn("ignored invalid fakedns ipPool: ", pool.IPPool)
+			continue
+		}
+		if prefix.Addr().Is4() {
+			fakeIPOptions.Inet4Range = common.Ptr(badoption.Prefix(prefix))
+		} else {
+			fakeIPOptions.Inet6Range = common.Ptr(badoption.Prefix(prefix))
+		}
+	}
+	if fakeIPOptions.Inet4Range == nil && fakeIPOptions.Inet6Range == nil {
+		fakeIPOptions.Inet4Range = common.Ptr(badoption.Prefix(netip.MustParsePrefix("198.18.0.0/15")))
+		if d.options.Strategy != option.DomainStrategy(C.DomainStrategyIPv4Only) {
+			fakeIPOptions.Inet6Range = common.Ptr(badoption.Prefix(netip.MustParsePrefix("fc00::/18")))
+		}
+	}
+	return fakeIPOptions
+}
+
+func dnsRouteRule(server string) option.DNSRule {
+	return option.DNSRule{
+		Type: C.RuleTypeDefault,
+		DefaultOptions: option.DefaultDNSRule{
+			DNSRuleAction: option.DNSRuleAction{
+				Action: C.RuleActionTypeRoute,
+				RouteOptions: option.DNSRouteActionOptions{
+					Server: server,
+				},
+			},
+		},
+	}
+}
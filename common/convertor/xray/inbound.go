@@ -0,0 +1,276 @@
+package xray
+
+import (
+	"net/netip"
+	"strconv"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/auth"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json/badoption"
+)
+
+func (c *converter) convertInbounds() {
+	for index, inbound := range c.config.Inbounds {
+		if inbound.Tag == "" {
+			inbound.Tag = inbound.Protocol + "-in-" + strconv.Itoa(index)
+		}
+		options, err := c.convertInbound(inbound)
+		if err != nil {
+			c.logger.Warn("ignored inbound ", inbound.Tag, ": ", err)
+			continue
+		}
+		c.options.Inbounds = append(c.options.Inbounds, options)
+		if sniffing := inbound.Sniffing; sniffing != nil && sniffing.Enabled {
+			c.addSniffRule(inbound.Tag, sniffing.DestOverride)
+		}
+	}
+}
+
+func (c *converter) convertInbound(inbound InboundConfig) (option.Inbound, error) {
+	listenOptions, err := inbound.listenOptions()
+	if err != nil {
+		return option.Inbound{}, err
+	}
+	settings := inbound.Settings
+	switch inbound.Protocol {
+	case "socks", "http", "mixed":
+		return c.convertSimpleInbound(inbound, listenOptions)
+	case "dokodemo-door", "tunnel":
+		if settings.FollowRedirect {
+			if inbound.StreamSettings != nil && inbound.StreamSettings.Sockopt != nil && inbound.StreamSettings.Sockopt.TProxy == "tproxy" {
+				return option.Inbound{
+					Type: C.TypeTProxy,
+					Tag:  inbound.Tag,
+					Options: &option.TProxyInboundOptions{
+						ListenOptions: listenOptions,
+						Network:       networkList(settings.Network),
+					},
+				}, nil
+			}
+			return option.Inbound{
+				Type:    C.TypeRedirect,
+				Tag:     inbound.Tag,
+				Options: &option.RedirectInboundOptions{ListenOptions: listenOptions},
+			}, nil
+		}
+		return option.Inbound{
+			Type: C.TypeDirect,
+			Tag:  inbound.Tag,
+			Options: &option.DirectInboundOptions{
+				ListenOptions:   listenOptions,
+				Network:         networkList(settings.Network),
+				OverrideAddress: settings.Address,
+				OverridePort:    settings.Port,
+			},
+		}, nil
+	}
+	tlsOptions, err := inbound.StreamSettings.inboundTLSOptions()
+	if err != nil {
+		return option.Inbound{}, err
+	}
+	transport, err := inbound.StreamSettings.transportOptions()
+	if err != nil {
+		return option.Inbound{}, err
+	}
+	switch inbound.Protocol {
+	case "vless":
+		if settings.Decryption != "" && settings.Decryption != "none" {
+			return option.Inbound{}, E.New("unsupported decryption: ", settings.Decryption)
+		}
+		if len(settings.Fallbacks) > 0 {
+			c.logger.Warn("inbound ", inbound.Tag, ": ignored unsupported vless fallbacks")
+		}
+		users := make([]option.VLESSUser, 0, len(settings.Clients))
+		for index, client := range settings.Clients {
+			users = append(users, option.VLESSUser{
+				Name: client.name(index),
+				UUID: client.ID,
+				Flow: client.Flow,
+			})
+		}
+		return option.Inbound{
+			Type: C.TypeVLESS,
+			Tag:  inbound.Tag,
+			Options: &option.VLESSInboundOptions{
+				ListenOptions:              listenOptions,
+				Users:                      users,
+				InboundTLSOptionsContainer: option.InboundTLSOptionsContainer{TLS: tlsOptions},
+				Transport:                  transport,
+			},
+		}, nil
+	case "vmess":
+		users := make([]option.VMessUser, 0, len(settings.Clients))
+		for index, client := range settings.Clients {
+			users = append(users, option.VMessUser{
+				Name:    client.name(index),
+				UUID:    client.ID,
+				AlterId: client.AlterID,
+			})
+		}
+		return option.Inbound{
+			Type: C.TypeVMess,
+			Tag:  inbound.Tag,
+			Options: &option.VMessInboundOptions{
+				ListenOptions:              listenOptions,
+				Users:                      users,
+				InboundTLSOptionsContainer: option.InboundTLSOptionsContainer{TLS: tlsOptions},
+				Transport:                  transport,
+			},
+		}, nil
+	case "trojan":
+		users := make([]option.TrojanUser, 0, len(settings.Clients))
+		for index, client := range settings.Clients {
+			users = append(users, option.TrojanUser{
+				Name:     client.name(index),
+				Password: client.Password,
+			})
+		}
+		trojanOptions := &option.TrojanInboundOptions{
+			ListenOptions:              listenOptions,
+			Users:                      users,
+			InboundTLSOptionsContainer: option.InboundTLSOptionsContainer{TLS: tlsOptions},
+			Transport:                  transport,
+		}
+		for _, fallback := range settings.Fallbacks {
+			if fallback.Path != "" || fallback.Name != "" {
+				c.logger.Warn("inbound ", inbound.Tag, ": ignored unsupported fallback matching path or name")
+				continue
+			}
+			destination, err := parseDestination(string(fallback.Dest))
+			if err != nil {
+				c.logger.Warn("inbound ", inbound.Tag, ": ignored fallback ", fallback.Dest, ": ", err)
+				continue
+			}
+			if fallback.ALPN == "" {
+				trojanOptions.Fallback = &destination
+				continue
+			}
+			if trojanOptions.FallbackForALPN == nil {
+				trojanOptions.FallbackForALPN = make(map[string]*option.ServerOptions)
+			}
+			trojanOptions.FallbackForALPN[fallback.ALPN] = &destination
+		}
+		return option.Inbound{
+			Type:    C.TypeTrojan,
+			Tag:     inbound.Tag,
+			Options: trojanOptions,
+		}, nil
+	case "shadowsocks":
+		if tlsOptions != nil || transport != nil {
+			return option.Inbound{}, E.New("stream settings are not supported for shadowsocks")
+		}
+		shadowsocksOptions := &option.ShadowsocksInboundOptions{
+			ListenOptions: listenOptions,
+			Network:       networkList(settings.Network),
+			Method:        settings.Method,
+			Password:      settings.Password,
+		}
+		for index, client := range settings.Clients {
+			if client.Method != "" {
+				if shadowsocksOptions.Method == "" {
+					shadowsocksOptions.Method = client.Method
+				} else if client.Method != shadowsocksOptions.Method {
+					return option.Inbound{}, E.New("per-user shadowsocks methods are not supported")
+				}
+			}
+			if len(settings.Clients) == 1 && settings.Password == "" {
+				shadowsocksOptions.Password = client.Password
+				continue
+			}
+			shadowsocksOptions.Users = append(shadowsocksOptions.Users, option.ShadowsocksUser{
+				Name:     client.name(index),
+				Password: client.Password,
+			})
+		}
+		return option.Inbound{
+			Type:    C.TypeShadowsocks,
+			Tag:     inbound.Tag,
+			Options: shadowsocksOptions,
+		}, nil
+	default:
+		return option.Inbound{}, E.New("unsupported protocol: ", inbound.Protocol)
+	}
+}
+
+func (c *converter) convertSimpleInbound(inbound InboundConfig, listenOptions option.ListenOptions) (option.Inbound, error) {
+	if inbound.StreamSettings != nil && inbound.StreamSettings.Security != "" && inbound.StreamSettings.Security != "none" {
+		return option.Inbound{}, E.New("stream settings are not supported for ", inbound.Protocol)
+	}
+	users := common.Map(inbound.Settings.Accounts, func(it Account) auth.User {
+		return auth.User{Username: it.User, Password: it.Pass}
+	})
+	switch inbound.Protocol {
+	case "socks":
+		return option.Inbound{
+			Type: C.TypeSOCKS,
+			Tag:  inbound.Tag,
+			Options: &option.SocksInboundOptions{
+				ListenOptions: listenOptions,
+				Users:         users,
+			},
+		}, nil
+	case "http":
+		return option.Inbound{
+			Type: C.TypeHTTP,
+			Tag:  inbound.Tag,
+			Options: &option.HTTPMixedInboundOptions{
+				ListenOptions: listenOptions,
+				Users:         users,
+			},
+		}, nil
+	default:
+		return option.Inbound{
+			Type: C.TypeMixed,
+			Tag:  inbound.Tag,
+			Options: &option.HTTPMixedInboundOptions{
+				ListenOptions: listenOptions,
+				Users:         users,
+			},
+		}, nil
+	}
+}
+
+func (i InboundConfig) listenOptions() (option.ListenOptions, error) {
+	var listenOptions option.ListenOptions
+	if i.Listen != "" {
+		listen, err := netip.ParseAddr(i.Listen)
+		if err != nil {
+			return listenOptions, E.New("unsupported listen address: ", i.Listen)
+		}
+		listenOptions.Listen = common.Ptr(badoption.Addr(listen))
+	} else {
+		listenOptions.Listen = common.Ptr(badoption.Addr(netip.IPv6Unspecified()))
+	}
+	port, err := strconv.ParseUint(string(i.Port), 10, 16)
+	if err != nil {
+		return listenOptions, E.New("unsupported port: ", i.Port)
+	}
+	listenOptions.ListenPort = uint16(port)
+	return listenOptions, nil
+}
+
+func (u User) name(index int) string {
+	if u.Email != "" {
+		return u.Email
+	}
+	return "user-" + strconv.Itoa(index)
+}
+
+func (c *converter) addSniffRule(inboundTag string, destOverride []string) {
+	var sniffers []string
+	for _, protocol := range destOverride {
+		switch protocol {
+		case C.ProtocolHTTP, C.ProtocolTLS, C.ProtocolQUIC:
+			sniffers = append(sniffers, protocol)
+		}
+	}
+	rule := actionRule(option.RuleAction{
+		Action:       C.RuleActionTypeSniff,
+		SniffOptions: option.RouteActionSniff{Sniffer: sniffers},
+	})
+	rule.DefaultOptions.Inbound = []string{inboundTag}
+	c.sniffRules = append(c.sniffRules, rule)
+}
@@ -0,0 +1,203 @@
+package xray
+
+import (
+	"strconv"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+func (c *converter) convertOutbounds() {
+	for index := range c.config.Outbounds {
+		outbound := &c.config.Outbounds[index]
+		if outbound.Tag == "" {
+			outbound.Tag = outbound.Protocol + "-out-" + strconv.Itoa(index)
+		}
+		switch outbound.Protocol {
+		case "blackhole":
+			c.actionTags[outbound.Tag] = option.RuleAction{Action: C.RuleActionTypeReject}
+			continue
+		case "dns":
+			c.actionTags[outbound.Tag] = option.RuleAction{Action: C.RuleActionTypeHijackDNS}
+			continue
+		}
+		options, err := c.convertOutbound(*outbound)
+		if err != nil {
+			c.logger.Warn("ignored outbound ", outbound.Tag, ": ", err)
+			continue
+		}
+		c.outboundTags[options.Tag] = true
+		c.options.Outbounds = append(c.options.Outbounds, options)
+	}
+	for _, outbound := range c.options.Outbounds {
+		dialerOptions, _ := outbound.Options.(option.DialerOptionsWrapper)
+		if dialerOptions == nil {
+			continue
+		}
+		options := dialerOptions.TakeDialerOptions()
+		if options.Detour != "" && !c.outboundTags[options.Detour] {
+			c.logger.Warn("outbound ", outbound.Tag, ": ignored unknown detour ", options.Detour)
+			options.Detour = ""
+			dialerOptions.ReplaceDialerOptions(options)
+		}
+	}
+}
+
+func (c *converter) convertOutbound(outbound OutboundConfig) (option.Outbound, error) {
+	dialerOptions := outbound.StreamSettings.dialerOptions()
+	if outbound.ProxySettings != nil && outbound.ProxySettings.Tag != "" {
+		dialerOptions.Detour = outbound.ProxySettings.Tag
+	}
+	if outbound.Mux != nil && outbound.Mux.Enabled {
+		c.logger.Warn("outbound ", outbound.Tag, ": ignored unsupported mux settings")
+	}
+	settings := outbound.Settings
+	switch outbound.Protocol {
+	case "freedom", "direct":
+		switch settings.DomainStrategy {
+		case "", "AsIs":
+		default:
+			c.logger.Warn("outbound ", outbound.Tag, ": ignored unsupported domainStrategy: ", settings.DomainStrategy)
+		}
+		return option.Outbound{
+			Type: C.TypeDirect,
+			Tag:  outbound.Tag,
+			Options: &option.DirectOutboundOptions{
+				DialerOptions: dialerOptions,
+			},
+		}, nil
+	}
+	tlsOptions, err := outbound.StreamSettings.outboundTLSOptions()
+	if err != nil {
+		return option.Outbound{}, err
+	}
+	transport, err := outbound.StreamSettings.transportOptions()
+	if err != nil {
+		return option.Outbound{}, err
+	}
+	tlsContainer := option.OutboundTLSOptionsContainer{TLS: tlsOptions}
+	switch outbound.Protocol {
+	case "vless", "vmess":
+		if len(settings.Vnext) == 0 || len(settings.Vnext[0].Users) == 0 {
+			return option.Outbound{}, E.New("missing server")
+		}
+		if len(settings.Vnext) > 1 || len(settings.Vnext[0].Users) > 1 {
+			c.logger.Warn("outbound ", outbound.Tag, ": only the first server and user are used")
+		}
+		server := settings.Vnext[0]
+		user := server.Users[0]
+		serverOptions := option.ServerOptions{Server: server.Address, ServerPort: server.Port}
+		if outbound.Protocol == "vmess" {
+			security := user.Security
+			if security == "" {
+				security = "auto"
+			}
+			return option.Outbound{
+				Type: C.TypeVMess,
+				Tag:  outbound.Tag,
+				Options: &option.VMessOutboundOptions{
+					DialerOptions:               dialerOptions,
+					ServerOptions:               serverOptions,
+					UUID:                        user.ID,
+					Security:                    security,
+					AlterId:                     user.AlterID,
+					OutboundTLSOptionsContainer: tlsContainer,
+					Transport:                   transport,
+				},
+			}, nil
+		}
+		if user.Encryption != "" && user.Encryption != "none" {
+			return option.Outbound{}, E.New("unsupported encryption: ", user.Encryption)
+		}
+		return option.Outbound{
+			Type: C.TypeVLESS,
+			Tag:  outbound.Tag,
+			Options: &option.VLESSOutboundOptions{
+				DialerOptions:               dialerOptions,
+				ServerOptions:               serverOptions,
+				UUID:                        user.ID,
+				Flow:                        user.Flow,
+				OutboundTLSOptionsContainer: tlsContainer,
+				Transport:                   transport,
+			},
+		}, nil
+	}
+	if len(settings.Servers) == 0 {
+		return option.Outbound{}, E.New("missing server")
+	}
+	if len(settings.Servers) > 1 {
+		c.logger.Warn("outbound ", outbound.Tag, ": only the first server is used")
+	}
+	server := settings.Servers[0]
+	serverOptions := option.ServerOptions{Server: server.Address, ServerPort: server.Port}
+	switch outbound.Protocol {
+	case "trojan":
+		return option.Outbound{
+			Type: C.TypeTrojan,
+			Tag:  outbound.Tag,
+			Options: &option.TrojanOutboundOptions{
+				DialerOptions:               dialerOptions,
+				ServerOptions:               serverOptions,
+				Password:                    server.Password,
+				OutboundTLSOptionsContainer: tlsContainer,
+				Transport:                   transport,
+			},
+		}, nil
+	case "shadowsocks":
+		if tlsOptions != nil || transport != nil {
+			return option.Outbound{}, E.New("stream settings are not supported for shadowsocks")
+		}
+		shadowsocksOptions := &option.ShadowsocksOutboundOptions{
+			DialerOptions: dialerOptions,
+			ServerOptions: serverOptions,
+			Method:        server.Method,
+			Password:      server.Password,
+		}
+		if server.UoT {
+			shadowsocksOptions.UDPOverTCP = &option.UDPOverTCPOptions{Enabled: true}
+		}
+		return option.Outbound{
+			Type:    C.TypeShadowsocks,
+			Tag:     outbound.Tag,
+			Options: shadowsocksOptions,
+		}, nil
+	case "socks":
+		if tlsOptions != nil || transport != nil {
+			return option.Outbound{}, E.New("stream settings are not supported for socks")
+		}
+		socksOptions := &option.SOCKSOutboundOptions{
+			DialerOptions: dialerOptions,
+			ServerOptions: serverOptions,
+		}
+		if len(server.Users) > 0 {
+			socksOptions.Username = server.Users[0].User
+			socksOptions.Password = server.Users[0].Pass
+		}
+		return option.Outbound{
+			Type:    C.TypeSOCKS,
+			Tag:     outbound.Tag,
+			Options: socksOptions,
+		}, nil
+	case "http":
+		if transport != nil {
+			return option.Outbound{}, E.New("transport is not supported for http")
+		}
+		httpOptions := &option.HTTPOutboundOptions{
+			DialerOptions:               dialerOptions,
+			ServerOptions:               serverOptions,
+			OutboundTLSOptionsContainer: tlsContainer,
+		}
+		if len(server.Users) > 0 {
+			httpOptions.Username = server.Users[0].User
+			httpOptions.Password = server.Users[0].Pass
+		}
+		return option.Outbound{
+			Type:    C.TypeHTTP,
+			Tag:     outbound.Tag,
+			Options: httpOptions,
+		}, nil
+	default:
+		return option.Outbound{}, E.New("unsupported protocol: ", outbound.Protocol)
+	}
+}
@@ -0,0 +1,323 @@
+package xray
+
+import (
+	"strconv"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+const (
+	geositeRuleSetURL = "https://raw.githubusercontent.com/SagerNet/sing-geosite/rule-set/geosite-"
+	geoipRuleSetURL   = "https://raw.githubusercontent.com/SagerNet/sing-geoip/rule-set/geoip-"
+)
+
+type RoutingConfig struct {
+	DomainStrategy string           `json:"domainStrategy,omitempty"`
+	Rules          []RuleConfig     `json:"rules,omitempty"`
+	Balancers      []BalancerConfig `json:"balancers,omitempty"`
+}
+
+type RuleConfig struct {
+	Domain      []string       `json:"domain,omitempty"`
+	IP          []string       `json:"ip,omitempty"`
+	Port        StringOrNumber `json:"port,omitempty"`
+	SourcePort  StringOrNumber `json:"sourcePort,omitempty"`
+	Network     string         `json:"network,omitempty"`
+	Source      []string       `json:"source,omitempty"`
+	SourceIP    []string       `json:"sourceIP,omitempty"`
+	User        []string       `json:"user,omitempty"`
+	InboundTag  []string       `json:"inboundTag,omitempty"`
+	Protocol    []string       `json:"protocol,omitempty"`
+	Attrs       any            `json:"attrs,omitempty"`
+	OutboundTag string         `json:"outboundTag,omitempty"`
+	BalancerTag string         `json:"balancerTag,omitempty"`
+	RuleTag     string         `json:"ruleTag,omitempty"`
+}
+
+type BalancerConfig struct {
+	Tag         string   `json:"tag"`
+	Selector    []string `json:"selector,omitempty"`
+	FallbackTag string   `json:"fallbackTag,omitempty"`
+	Strategy    *struct {
+		Type string `json:"type,omitempty"`
+	} `json:"strategy,omitempty"`
+}
+
+// domainMatcher holds the sing-box equivalents of Xray domain matchers shared by routing and DNS rules.
+type domainMatcher struct {
+	domain        []string
+	domainSuffix  []string
+	domainKeyword []string
+	domainRegex   []string
+	ruleSet       []string
+}
+
+func (m domainMatcher) isEmpty() bool {
+	return len(m.domain)+len(m.domainSuffix)+len(m.domainKeyword)+len(m.domainRegex)+len(m.ruleSet) == 0
+}
+
+func (c *converter) parseDomains(domains []string) (domainMatcher, error) {
+	var matcher domainMatcher
+	for _, domain := range domains {
+		prefix, value, hasPrefix := strings.Cut(domain, ":")
+		if !hasPrefix {
+			matcher.domainKeyword = append(matcher.domainKeyword, domain)
+			continue
+		}
+		switch prefix {
+		case "domain":
+			matcher.domainSuffix = append(matcher.domainSuffix, value)
+		case "full":
+			matcher.domain = append(matcher.domain, value)
+		case "keyword":
+			matcher.domainKeyword = append(matcher.domainKeyword, value)
+		case "regexp":
+			matcher.domainRegex = append(matcher.domainRegex, value)
+		case "geosite":
+			if strings.Contains(value, "@") {
+				return matcher, E.New("unsupported geosite attribute: ", domain)
+			}
+			matcher.ruleSet = append(matcher.ruleSet, c.geoRuleSet("geosite", value))
+		default:
+			return matcher, E.New("unsupported domain matcher: ", domain)
+		}
+	}
+	return matcher, nil
+}
+
+func (c *converter) geoRuleSet(kind string, name string) string {
+	tag := kind + "-" + strings.ToLower(name)
+	if c.ruleSets[tag] {
+		return tag
+	}
+	c.ruleSets[tag] = true
+	url := geositeRuleSetURL
+	if kind == "geoip" {
+		url = geoipRuleSetURL
+	}
+	c.route.RuleSet = append(c.route.RuleSet, option.RuleSet{
+		Type:   C.RuleSetTypeRemote,
+		Tag:    tag,
+		Format: C.RuleSetFormatBinary,
+		RemoteOptions: option.RemoteRuleSet{
+			URL: url + strings.ToLower(name) + ".srs",
+		},
+	})
+	return tag
+}
+
+func (c *converter) convertBalancers() {
+	if c.config.Routing == nil {
+		return
+	}
+	for _, balancer := range c.config.Routing.Balancers {
+		var members []string
+		for _, outbound := range c.options.Outbounds {
+			for _, prefix := range balancer.Selector {
+				if strings.HasPrefix(outbound.Tag, prefix) {
+					members = append(members, outbound.Tag)
+					break
+				}
+			}
+		}
+		if len(members) == 0 {
+			c.logger.Warn("ignored balancer ", balancer.Tag, ": no outbounds selected")
+			continue
+		}
+		if balancer.Strategy != nil && balancer.Strategy.Type != "" && balancer.Strategy.Type != "leastPing" {
+			c.logger.Warn("balancer ", balancer.Tag, ": ", balancer.Strategy.Type, " strategy is approximated with urltest")
+		}
+		if balancer.FallbackTag != "" {
+			c.logger.Warn("balancer ", balancer.Tag, ": ignored unsupported fallbackTag")
+		}
+		c.balancerTags[balancer.Tag] = true
+		c.options.Outbounds = append(c.options.Outbounds, option.Outbound{
+			Type: C.TypeURLTest,
+			Tag:  balancer.Tag,
+			Options: &option.URLTestOutboundOptions{
+				Outbounds: members,
+			},
+		})
+	}
+}
+
+func (c *converter) convertRules() {
+	c.route.Rules = append(c.route.Rules, c.sniffRules...)
+	var domainStrategy string
+	if c.config.Routing != nil {
+		domainStrategy = c.config.Routing.DomainStrategy
+		for index, rule := range c.config.Routing.Rules {
+			name := rule.RuleTag
+			if name == "" {
+				name = "#" + strconv.Itoa(index)
+			}
+			err := c.convertRule(rule, domainStrategy)
+			if err != nil {
+				c.logger.Warn("ignored rule ", name, ": ", err)
+			}
+		}
+	}
+	if len(c.config.Outbounds) == 0 {
+		return
+	}
+	defaultTag := c.config.Outbounds[0].Tag
+	if action, isAction := c.actionTags[defaultTag]; isAction {
+		c.route.Rules = append(c.route.Rules, actionRule(action))
+	} else if c.outboundTags[defaultTag] {
+		c.route.Final = defaultTag
+	} else if len(c.options.Outbounds) > 0 {
+		c.logger.Warn("default outbound ", defaultTag, " is unavailable, ", c.options.Outbounds[0].Tag, " is used instead")
+	}
+}
+
+func (c *converter) convertRule(rule RuleConfig, domainStrategy string) error {
+	var action option.RuleAction
+	switch {
+	case rule.BalancerTag != "":
+		if !c.balancerTags[rule.BalancerTag] {
+			return E.New("unknown balancer: ", rule.BalancerTag)
+		}
+		action = routeAction(rule.BalancerTag)
+	case rule.OutboundTag != "":
+		if predefined, isAction := c.actionTags[rule.OutboundTag]; isAction {
+			action = predefined
+		} else if c.outboundTags[rule.OutboundTag] {
+			action = routeAction(rule.OutboundTag)
+		} else {
+			return E.New("unknown outbound: ", rule.OutboundTag)
+		}
+	default:
+		return E.New("missing outboundTag")
+	}
+	if rule.Attrs != nil {
+		return E.New("unsupported attrs")
+	}
+	var defaultRule option.RawDefaultRule
+	domains, err := c.parseDomains(rule.Domain)
+	if err != nil {
+		return err
+	}
+	defaultRule.Domain = domains.domain
+	defaultRule.DomainSuffix = domains.domainSuffix
+	defaultRule.DomainKeyword = domains.domainKeyword
+	defaultRule.DomainRegex = domains.domainRegex
+	defaultRule.RuleSet = domains.ruleSet
+	var ipRule option.RawDefaultRule
+	for _, address := range rule.IP {
+		switch {
+		case address == "geoip:private":
+			ipRule.IPIsPrivate = true
+		case strings.HasPrefix(address, "geoip:!"):
+			return E.New("unsupported negated geoip: ", address)
+		case strings.HasPrefix(address, "geoip:"):
+			ipRule.RuleSet = append(ipRule.RuleSet, c.geoRuleSet("geoip", strings.TrimPrefix(address, "geoip:")))
+		case strings.HasPrefix(address, "ext:"):
+			return E.New("unsupported ip matcher: ", address)
+		default:
+			ipRule.IPCIDR = append(ipRule.IPCIDR, address)
+		}
+	}
+	for _, address := range append(rule.Source, rule.SourceIP...) {
+		switch {
+		case address == "geoip:private":
+			defaultRule.SourceIPIsPrivate = true
+		case strings.HasPrefix(address, "geoip:"), strings.HasPrefix(address, "ext:"):
+			return E.New("unsupported source ip matcher: ", address)
+		default:
+			defaultRule.SourceIPCIDR = append(defaultRule.SourceIPCIDR, address)
+		}
+	}
+	defaultRule.Port, defaultRule.PortRange, err = parsePorts(string(rule.Port))
+	if err != nil {
+		return err
+	}
+	defaultRule.SourcePort, defaultRule.SourcePortRange, err = parsePorts(string(rule.SourcePort))
+	if err != nil {
+		return err
+	}
+	if network := networkList(rule.Network); network != "" {
+		defaultRule.Network = network.Build()
+	}
+	defaultRule.Inbound = rule.InboundTag
+	defaultRule.AuthUser = rule.User
+	defaultRule.Protocol = rule.Protocol
+	hasIP := len(rule.IP) > 0
+	if hasIP && domainStrategy != "" && domainStrategy != "AsIs" && !c.resolveInserted {
+		c.route.Rules = append(c.route.Rules, actionRule(option.RuleAction{Action: C.RuleActionTypeResolve}))
+		c.resolveInserted = true
+	}
+	switch {
+	case hasIP && !domains.isEmpty():
+		// sing-box matches domain and IP items with OR, while Xray requires both
+		c.route.Rules = append(c.route.Rules, option.Rule{
+			Type: C.RuleTypeLogical,
+			LogicalOptions: option.LogicalRule{
+				RawLogicalRule: option.RawLogicalRule{
+					Mode: C.LogicalTypeAnd,
+					Rules: []option.Rule{
+						{Type: C.RuleTypeDefault, DefaultOptions: option.DefaultRule{RawDefaultRule: defaultRule}},
+						{Type: C.RuleTypeDefault, DefaultOptions: option.DefaultRule{RawDefaultRule: ipRule}},
+					},
+				},
+				RuleAction: action,
+			},
+		})
+	default:
+		defaultRule.IPIsPrivate = ipRule.IPIsPrivate
+		defaultRule.IPCIDR = ipRule.IPCIDR
+		defaultRule.RuleSet = append(defaultRule.RuleSet, ipRule.RuleSet...)
+		c.route.Rules = append(c.route.Rules, option.Rule{
+			Type: C.RuleTypeDefault,
+			DefaultOptions: option.DefaultRule{
+				RawDefaultRule: defaultRule,
+				RuleAction:     action,
+			},
+		})
+	}
+	return nil
+}
+
+func actionRule(action option.RuleAction) option.Rule {
+	return option.Rule{
+		Type: C.RuleTypeDefault,
+		DefaultOptions: option.DefaultRule{
+			RuleAction: action,
+		},
+	}
+}
+
+func routeAction(outbound string) option.RuleAction {
+	return option.RuleAction{
+		Action: C.RuleActionTypeRoute,
+		RouteOptions: option.RouteActionOptions{
+			Outbound: outbound,
+		},
+	}
+}
+
+// parsePorts parses Xray port lists such as `53,443,1000-2000`.
+func parsePorts(payload string) ([]uint16, []string, error) {
+	var (
+		ports      []uint16
+		portRanges []string
+	)
+	for _, portString := range strings.Split(payload, ",") {
+		portString = strings.TrimSpace(portString)
+		if portString == "" {
+			continue
+		}
+		if start, end, isRange := strings.Cut(portString, "-"); isRange {
+			portRanges = append(portRanges, strings.TrimSpace(start)+":"+strings.TrimSpace(end))
+			continue
+		}
+		port, err := strconv.ParseUint(portString, 10, 16)
+		if err != nil {
+			return nil, nil, E.Cause(err, "parse port")
+		}
+		ports = append(ports, uint16(port))
+	}
+	return ports, portRanges, nil
+}
@@ -0,0 +1,259 @@
+package xray
+
+import (
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json/badoption"
+)
+
+func (s *StreamSettings) transportOptions() (*option.V2RayTransportOptions, error) {
+	if s == nil {
+		return nil, nil
+	}
+	switch s.Network {
+	case "", "tcp", "raw":
+		header := s.TCPSettings
+		if header == nil {
+			header = s.RawSettings
+		}
+		if header != nil && header.Header != nil && header.Header.Type != "" && header.Header.Type != "none" {
+			return nil, E.New("unsupported tcp header type: ", header.Header.Type)
+		}
+		return nil, nil
+	case "ws", "websocket":
+		wsSettings := s.WSSettings
+		if wsSettings == nil {
+			wsSettings = &WSSettings{}
+		}
+		host := wsSettings.Host
+		headers := make(badoption.HTTPHeader)
+		for key, value := range wsSettings.Headers {
+			if strings.EqualFold(key, "Host") {
+				if host == "" {
+					host = value
+				}
+				continue
+			}
+			headers[key] = badoption.Listable[string]{value}
+		}
+		options := option.V2RayWebsocketOptions{
+			Host:    host,
+			Path:    wsSettings.Path,
+			Headers: headers,
+		}
+		if path, query, hasQuery := strings.Cut(wsSettings.Path, "?"); hasQuery {
+			values, err := url.ParseQuery(query)
+			if err == nil && values.Has("ed") {
+				maxEarlyData, err := strconv.ParseUint(values.Get("ed"), 10, 32)
+				if err != nil {
+					return nil, E.Cause(err, "parse websocket early data")
+				}
+				values.Del("ed")
+				options.Path = path
+				if len(values) > 0 {
+					options.Path += "?" + values.Encode()
+				}
+				options.MaxEarlyData = uint32(maxEarlyData)
+				options.EarlyDataHeaderName = "Sec-WebSocket-Protocol"
+			}
+		}
+		return &option.V2RayTransportOptions{
+			Type:             C.V2RayTransportTypeWebsocket,
+			WebsocketOptions: options,
+		}, nil
+	case "grpc", "gun":
+		grpcSettings := s.GRPCSettings
+		if grpcSettings == nil {
+			grpcSettings = &GRPCSettings{}
+		}
+		return &option.V2RayTransportOptions{
+			Type: C.V2RayTransportTypeGRPC,
+			GRPCOptions: option.V2RayGRPCOptions{
+				ServiceName: grpcSettings.ServiceName,
+			},
+		}, nil
+	case "httpupgrade":
+		httpUpgradeSettings := s.HTTPUpgradeSettings
+		if httpUpgradeSettings == nil {
+			httpUpgradeSettings = &HTTPUpgradeSettings{}
+		}
+		return &option.V2RayTransportOptions{
+			Type: C.V2RayTransportTypeHTTPUpgrade,
+			HTTPUpgradeOptions: option.V2RayHTTPUpgradeOptions{
+				Host: httpUpgradeSettings.Host,
+				Path: httpUpgradeSettings.Path,
+			},
+		}, nil
+	case "http", "h2":
+		httpSettings := s.HTTPSettings
+		if httpSettings == nil {
+			httpSettings = &HTTPSettings{}
+		}
+		return &option.V2RayTransportOptions{
+			Type: C.V2RayTransportTypeHTTP,
+			HTTPOptions: option.V2RayHTTPOptions{
+				Host: httpSettings.Host,
+				Path: httpSettings.Path,
+			},
+		}, nil
+	case "quic":
+		return &option.V2RayTransportOptions{
+			Type: C.V2RayTransportTypeQUIC,
+		}, nil
+	default:
+		return nil, E.New("unsupported network: ", s.Network)
+	}
+}
+
+func (s *StreamSettings) outboundTLSOptions() (*option.OutboundTLSOptions, error) {
+	if s == nil {
+		return nil, nil
+	}
+	switch s.Security {
+	case "", "none":
+		return nil, nil
+	case "tls", "xtls":
+		tlsSettings := s.TLSSettings
+		if tlsSettings == nil {
+			tlsSettings = &TLSSettings{}
+		}
+		tlsOptions := &option.OutboundTLSOptions{
+			Enabled:    true,
+			ServerName: tlsSettings.ServerName,
+			Insecure:   tlsSettings.AllowInsecure,
+			ALPN:       tlsSettings.ALPN,
+		}
+		if tlsSettings.Fingerprint != "" {
+			tlsOptions.UTLS = &option.OutboundUTLSOptions{
+				Enabled:     true,
+				Fingerprint: tlsSettings.Fingerprint,
+			}
+		}
+		return tlsOptions, nil
+	case "reality":
+		realitySettings := s.RealitySettings
+		if realitySettings == nil {
+			return nil, E.New("missing realitySettings")
+		}
+		publicKey := realitySettings.PublicKey
+		if publicKey == "" {
+			publicKey = realitySettings.Password
+		}
+		fingerprint := realitySettings.Fingerprint
+		if fingerprint == "" {
+			fingerprint = "chrome"
+		}
+		return &option.OutboundTLSOptions{
+			Enabled:    true,
+			ServerName: realitySettings.ServerName,
+			UTLS: &option.OutboundUTLSOptions{
+				Enabled:     true,
+				Fingerprint: fingerprint,
+			},
+			Reality: &option.OutboundRealityOptions{
+				Enabled:   true,
+				PublicKey: publicKey,
+				ShortID:   realitySettings.ShortID,
+			},
+		}, nil
+	default:
+		return nil, E.New("unsupported security: ", s.Security)
+	}
+}
+
+func (s *StreamSettings) inboundTLSOptions() (*option.InboundTLSOptions, error) {
+	if s == nil {
+		return nil, nil
+	}
+	switch s.Security {
+	case "", "none":
+		return nil, nil
+	case "tls", "xtls":
+		tlsSettings := s.TLSSettings
+		if tlsSettings == nil || len(tlsSettings.Certificates) == 0 {
+			return nil, E.New("missing tls certificates")
+		}
+		if len(tlsSettings.Certificates) > 1 {
+			return nil, E.New("multiple tls certificates are not supported")
+		}
+		certificate := tlsSettings.Certificates[0]
+		return &option.InboundTLSOptions{
+			Enabled:         true,
+			ServerName:      tlsSettings.ServerName,
+			ALPN:            tlsSettings.ALPN,
+			Certificate:     certificate.Certificate,
+			CertificatePath: certificate.CertificateFile,
+			Key:             certificate.Key,
+			KeyPath:         certificate.KeyFile,
+		}, nil
+	case "reality":
+		realitySettings := s.RealitySettings
+		if realitySettings == nil {
+			return nil, E.New("missing realitySettings")
+		}
+		dest := realitySettings.Target
+		if dest == "" {
+			dest = realitySettings.Dest
+		}
+		handshake, err := parseDestination(string(dest))
+		if err != nil {
+			return nil, E.Cause(err, "parse reality target")
+		}
+		if len(realitySettings.ServerNames) == 0 {
+			return nil, E.New("missing reality serverNames")
+		}
+		if len(realitySettings.ServerNames) > 1 {
+			return nil, E.New("multiple reality serverNames are not supported")
+		}
+		return &option.InboundTLSOptions{
+			Enabled:    true,
+			ServerName: realitySettings.ServerNames[0],
+			Reality: &option.InboundRealityOptions{
+				Enabled: true,
+				Handshake: option.InboundRealityHandshakeOptions{
+					ServerOptions: handshake,
+				},
+				PrivateKey:        realitySettings.PrivateKey,
+				ShortID:           realitySettings.ShortIDs,
+				MaxTimeDifference: badoption.Duration(time.Duration(realitySettings.MaxTimeDiff) * time.Millisecond),
+			},
+		}, nil
+	default:
+		return nil, E.New("unsupported security: ", s.Security)
+	}
+}
+
+func (s *StreamSettings) dialerOptions() option.DialerOptions {
+	var dialerOptions option.DialerOptions
+	if s == nil || s.Sockopt == nil {
+		return dialerOptions
+	}
+	dialerOptions.RoutingMark = option.FwMark(s.Sockopt.Mark)
+	dialerOptions.TCPFastOpen = s.Sockopt.TCPFastOpen.Bool()
+	dialerOptions.BindInterface = s.Sockopt.Interface
+	dialerOptions.Detour = s.Sockopt.DialerProxy
+	return dialerOptions
+}
+
+// parseDestination parses Xray destinations, which are either a bare port or host:port.
+func parseDestination(dest string) (option.ServerOptions, error) {
+	if port, err := strconv.ParseUint(dest, 10, 16); err == nil {
+		return option.ServerOptions{Server: "127.0.0.1", ServerPort: uint16(port)}, nil
+	}
+	host, portString, err := net.SplitHostPort(dest)
+	if err != nil {
+		return option.ServerOptions{}, err
+	}
+	port, err := strconv.ParseUint(portString, 10, 16)
+	if err != nil {
+		return option.ServerOptions{}, E.Cause(err, "parse port")
+	}
+	return option.ServerOptions{Server: host, ServerPort: uint16(port)}, nil
+}
@@ -0,0 +1,168 @@
+package credential
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+)
+
+// failureBackoff is how long a rejected credential set is tried after the
+// others.
+const failureBackoff = time.Minute
+
+// Set selects the credential set of an outbound for each connection.
+//
+// Among the sets valid at the time, the one which became valid last is
+// preferred, so that clients switch to a new set at its not_before without
+// a restart. If the server rejects it, the other valid sets are tried and
+// the rejected set is tried last for a while. If no set is valid, for
+// example because of clock skew, all sets are tried.
+type Set[T any] struct {
+	logger   logger.Logger
+	entries  []*entry[T]
+	access   sync.Mutex
+	lastUsed int
+	timeFunc func() time.Time
+}
+
+type entry[T any] struct {
+	index     int
+	value     T
+	notBefore time.Time
+	notAfter  time.Time
+	failedAt  time.Time
+}
+
+// Credentials merges the single credential and the credential sets of
+// options.
+func Credentials(username string, password string, credentials []option.OutboundCredential) ([]option.OutboundCredential, error) {
+	if len(credentials) == 0 {
+		return []option.OutboundCredential{{Username: username, Password: password}}, nil
+	}
+	if username != "" {
+		return nil, E.New("`username` is conflict with `credentials`")
+	}
+	if password != "" {
+		return nil, E.New("`password` is conflict with `credentials`")
+	}
+	return credentials, nil
+}
+
+// New creates a set of the values created from each credential set.
+func New[T any](logger logger.Logger, credentials []option.OutboundCredential, newValue func(credential option.OutboundCredential) (T, error)) (*Set[T], error) {
+	if len(credentials) == 0 {
+		return nil, E.New("missing credentials")
+	}
+	set := &Set[T]{
+		logger:   logger,
+		lastUsed: -1,
+		timeFunc: time.Now,
+	}
+	for index, credential := range credentials {
+		value, err := newValue(credential)
+		if err != nil {
+			return nil, E.Cause(err, "credentials[", index, "]")
+		}
+		item := &entry[T]{
+			index: index,
+			value: value,
+		}
+		if credential.NotBefore != nil {
+			item.notBefore = *credential.NotBefore
+		}
+		if credential.NotAfter != nil {
+			item.notAfter = *credential.NotAfter
+		}
+		if !item.notBefore.IsZero() && !item.notAfter.IsZero() && !item.notAfter.After(item.notBefore) {
+			return nil, E.New("credentials[", index, "]: not_after must be after not_before")
+		}
+		set.entries = append(set.entries, item)
+	}
+	return set, nil
+}
+
+func (e *entry[T]) valid(now time.Time) bool {
+	return (e.notBefore.IsZero() || !now.Before(e.notBefore)) && (e.notAfter.IsZero() || now.Before(e.notAfter))
+}
+
+func (e *entry[T]) rejected(now time.Time) bool {
+	return !e.failedAt.IsZero() && now.Sub(e.failedAt) < failureBackoff
+}
+
+func (s *Set[T]) candidates() []*entry[T] {
+	if len(s.entries) == 1 {
+		return s.entries
+	}
+	now := s.timeFunc()
+	s.access.Lock()
+	defer s.access.Unlock()
+	var candidates []*entry[T]
+	for _, item := range s.entries {
+		if item.valid(now) {
+			candidates = append(candidates, item)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = append(candidates, s.entries...)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if rejectedI, rejectedJ := candidates[i].rejected(now), candidates[j].rejected(now); rejectedI != rejectedJ {
+			return rejectedJ
+		}
+		return candidates[i].notBefore.After(candidates[j].notBefore)
+	})
+	return candidates
+}
+
+func (s *Set[T]) used(item *entry[T]) {
+	if len(s.entries) == 1 {
+		return
+	}
+	s.access.Lock()
+	item.failedAt = time.Time{}
+	switched := s.lastUsed != item.index
+	s.lastUsed = item.index
+	s.access.Unlock()
+	if switched {
+		s.logger.Info("using credentials[", item.index, "]")
+	}
+}
+
+func (s *Set[T]) rejected(item *entry[T], err error) {
+	s.access.Lock()
+	item.failedAt = s.timeFunc()
+	s.access.Unlock()
+	s.logger.Warn("credentials[", item.index, "] rejected: ", err)
+}
+
+// Select returns the value of a new connection, for protocols where the
+// server does not report authentication failures.
+func (s *Set[T]) Select() T {
+	item := s.candidates()[0]
+	s.used(item)
+	return item.value
+}
+
+// Dial calls dial with the values of the candidate credential sets until it
+// succeeds or fails with an error other than an authentication failure.
+func Dial[T any, C any](s *Set[T], isAuthFailure func(err error) bool, dial func(value T) (C, error)) (C, error) {
+	var lastErr error
+	for _, item := range s.candidates() {
+		conn, err := dial(item.value)
+		if err == nil {
+			s.used(item)
+			return conn, nil
+		}
+		if len(s.entries) == 1 || !isAuthFailure(err) {
+			return conn, err
+		}
+		s.rejected(item, err)
+		lastErr = err
+	}
+	var zero C
+	return zero, lastErr
+}
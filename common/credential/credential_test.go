@@ -0,0 +1,83 @@
+package credential
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/stretchr/testify/require"
+)
+
+var errRejected = E.New("rejected")
+
+func isRejected(err error) bool {
+	return err == errRejected
+}
+
+func newTestSet(t *testing.T, now *time.Time) *Set[string] {
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	set, err := New(log.NewNOPFactory().Logger(), []option.OutboundCredential{
+		{Password: "old", NotAfter: common.Ptr(start.Add(2 * time.Hour))},
+		{Password: "new", NotBefore: common.Ptr(start.Add(time.Hour))},
+	}, func(credential option.OutboundCredential) (string, error) {
+		return credential.Password, nil
+	})
+	require.NoError(t, err)
+	*now = start
+	set.timeFunc = func() time.Time { return *now }
+	return set
+}
+
+func TestSetValidity(t *testing.T) {
+	t.Parallel()
+	var now time.Time
+	set := newTestSet(t, &now)
+	start := now
+	require.Equal(t, "old", set.Select())
+	now = start.Add(90 * time.Minute)
+	require.Equal(t, "new", set.Select())
+	now = start.Add(3 * time.Hour)
+	require.Equal(t, "new", set.Select())
+	now = start.Add(-time.Hour)
+	require.Equal(t, "old", set.Select())
+}
+
+func TestSetRetry(t *testing.T) {
+	t.Parallel()
+	var now time.Time
+	set := newTestSet(t, &now)
+	start := now
+	now = start.Add(90 * time.Minute)
+	var tried []string
+	dial := func(value string) (string, error) {
+		tried = append(tried, value)
+		if value == "new" {
+			return "", errRejected
+		}
+		return value, nil
+	}
+	value, err := Dial(set, isRejected, dial)
+	require.NoError(t, err)
+	require.Equal(t, "old", value)
+	require.Equal(t, []string{"new", "old"}, tried)
+	tried = nil
+	_, err = Dial(set, isRejected, dial)
+	require.NoError(t, err)
+	require.Equal(t, []string{"old"}, tried)
+	now = now.Add(failureBackoff)
+	tried = nil
+	_, err = Dial(set, isRejected, dial)
+	require.NoError(t, err)
+	require.Equal(t, []string{"new", "old"}, tried)
+	tried = nil
+	_, err = Dial(set, isRejected, func(value string) (string, error) {
+		tried = append(tried, value)
+		return "", E.New("network unreachable")
+	})
+	require.Error(t, err)
+	require.Len(t, tried, 1)
+}
@@ -0,0 +1,162 @@
+// Package cron parses standard five-field cron expressions.
+package cron
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// Schedule is a parsed `minute hour day-of-month month day-of-week` expression.
+type Schedule struct {
+	minute     uint64
+	hour       uint64
+	dayOfMonth uint64
+	month      uint64
+	dayOfWeek  uint64
+	// anyDay is set when either day field is `*`, so that both must match
+	// instead of either, as in Vixie cron.
+	anyDay bool
+}
+
+type fieldRange struct {
+	min, max int
+}
+
+var (
+	minuteRange     = fieldRange{0, 59}
+	hourRange       = fieldRange{0, 23}
+	dayOfMonthRange = fieldRange{1, 31}
+	monthRange      = fieldRange{1, 12}
+	dayOfWeekRange  = fieldRange{0, 7}
+)
+
+var shortcuts = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse parses an expression such as `30 4 * * 1-5`, `*/15 * * * *` or `@daily`.
+func Parse(expression string) (*Schedule, error) {
+	if shortcut, loaded := shortcuts[strings.ToLower(strings.TrimSpace(expression))]; loaded {
+		expression = shortcut
+	}
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, E.New("invalid cron expression: expected 5 fields, got ", len(fields))
+	}
+	var (
+		schedule Schedule
+		err      error
+	)
+	schedule.minute, err = parseField(fields[0], minuteRange)
+	if err != nil {
+		return nil, E.Cause(err, "parse minute")
+	}
+	schedule.hour, err = parseField(fields[1], hourRange)
+	if err != nil {
+		return nil, E.Cause(err, "parse hour")
+	}
+	schedule.dayOfMonth, err = parseField(fields[2], dayOfMonthRange)
+	if err != nil {
+		return nil, E.Cause(err, "parse day of month")
+	}
+	schedule.month, err = parseField(fields[3], monthRange)
+	if err != nil {
+		return nil, E.Cause(err, "parse month")
+	}
+	schedule.dayOfWeek, err = parseField(fields[4], dayOfWeekRange)
+	if err != nil {
+		return nil, E.Cause(err, "parse day of week")
+	}
+	if schedule.dayOfWeek&(1<<7) != 0 {
+		schedule.dayOfWeek |= 1
+	}
+	schedule.anyDay = strings.HasPrefix(fields[2], "*") || strings.HasPrefix(fields[4], "*")
+	return &schedule, nil
+}
+
+func parseField(field string, valueRange fieldRange) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rangeExpr, stepExpr, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepExpr)
+			if err != nil || step <= 0 {
+				return 0, E.New("invalid step: ", stepExpr)
+			}
+		}
+		var start, end int
+		if rangeExpr == "*" {
+			start, end = valueRange.min, valueRange.max
+		} else {
+			startExpr, endExpr, isRange := strings.Cut(rangeExpr, "-")
+			var err error
+			start, err = strconv.Atoi(startExpr)
+			if err != nil {
+				return 0, E.New("invalid value: ", startExpr)
+			}
+			end = start
+			if isRange {
+				end, err = strconv.Atoi(endExpr)
+				if err != nil {
+					return 0, E.New("invalid value: ", endExpr)
+				}
+			} else if hasStep {
+				end = valueRange.max
+			}
+		}
+		if start < valueRange.min || end > valueRange.max || start > end {
+			return 0, E.New("value out of range: ", item)
+		}
+		for value := start; value <= end; value += step {
+			bits |= 1 << value
+		}
+	}
+	return bits, nil
+}
+
+// Next returns the first time after t matching the schedule, in the location of t.
+// A zero time is returned if nothing matches within five years.
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *Schedule) matchDay(t time.Time) bool {
+	dayOfMonth := s.dayOfMonth&(1<<uint(t.Day())) != 0
+	dayOfWeek := s.dayOfWeek&(1<<uint(t.Weekday())) != 0
+	if s.anyDay {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
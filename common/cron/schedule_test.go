@@ -0,0 +1,38 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduleNext(t *testing.T) {
+	t.Parallel()
+	base := time.Date(2024, time.January, 31, 10, 20, 30, 0, time.UTC)
+	for _, testCase := range []struct {
+		expression string
+		next       time.Time
+	}{
+		{"* * * * *", time.Date(2024, time.January, 31, 10, 21, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, time.January, 31, 10, 30, 0, 0, time.UTC)},
+		{"0 4 * * *", time.Date(2024, time.February, 1, 4, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"30 9 * * 1-5", time.Date(2024, time.February, 1, 9, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, time.February, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * 0", time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+	} {
+		schedule, err := Parse(testCase.expression)
+		require.NoError(t, err, testCase.expression)
+		require.Equal(t, testCase.next, schedule.Next(base), testCase.expression)
+	}
+}
+
+func TestScheduleParseError(t *testing.T) {
+	t.Parallel()
+	for _, expression := range []string{"", "* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err := Parse(expression)
+		require.Error(t, err, expression)
+	}
+}
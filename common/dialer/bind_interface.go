@@ -0,0 +1,108 @@
+package dialer
+
+import (
+	"net"
+	"net/netip"
+	"path"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/control"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+)
+
+// bindInterfaceNonDefault selects any interface except the default one.
+const bindInterfaceNonDefault = "!default"
+
+func isBindInterfacePattern(name string) bool {
+	return name == bindInterfaceNonDefault || strings.ContainsAny(name, "*?[")
+}
+
+// bindInterfaceSelector selects the interface to bind for each connection,
+// so that connections follow the matching interfaces as they come and go.
+type bindInterfaceSelector struct {
+	networkManager  adapter.NetworkManager
+	interfaceFinder control.InterfaceFinder
+	pattern         string
+}
+
+func newBindInterfaceSelector(networkManager adapter.NetworkManager, interfaceFinder control.InterfaceFinder, pattern string) (*bindInterfaceSelector, error) {
+	if pattern == bindInterfaceNonDefault {
+		if networkManager == nil || networkManager.InterfaceMonitor() == nil {
+			return nil, E.New("`bind_interface: ", bindInterfaceNonDefault, "` requires the default interface monitor")
+		}
+	} else if _, err := path.Match(pattern, ""); err != nil {
+		return nil, E.Cause(err, "parse bind_interface pattern: ", pattern)
+	}
+	return &bindInterfaceSelector{
+		networkManager:  networkManager,
+		interfaceFinder: interfaceFinder,
+		pattern:         pattern,
+	}, nil
+}
+
+// Select returns the available interface with the lowest index, matching the
+// pattern and having an address of the destination address family.
+func (s *bindInterfaceSelector) Select(destination netip.Addr) (*control.Interface, error) {
+	var (
+		myInterface  string
+		defaultIndex = -1
+		interfaces   []control.Interface
+	)
+	if s.networkManager != nil && s.networkManager.InterfaceMonitor() != nil {
+		myInterface = s.networkManager.InterfaceMonitor().MyInterface()
+	}
+	if s.pattern == bindInterfaceNonDefault {
+		defaultInterface := s.networkManager.InterfaceMonitor().DefaultInterface()
+		if defaultInterface == nil {
+			return nil, E.New("missing default interface")
+		}
+		defaultIndex = defaultInterface.Index
+		// prefer the physical interfaces reported by the platform
+		interfaces = common.Map(s.networkManager.NetworkInterfaces(), func(it adapter.NetworkInterface) control.Interface {
+			return it.Interface
+		})
+	}
+	if len(interfaces) == 0 {
+		interfaces = s.interfaceFinder.Interfaces()
+	}
+	selected := selectBindInterface(interfaces, s.pattern, destination, defaultIndex, myInterface, isVirtualInterface)
+	if selected == nil {
+		return nil, E.New("no available interface matching bind_interface: ", s.pattern)
+	}
+	return selected, nil
+}
+
+// selectBindInterface selects from interfaces, the interface of sing-box
+// itself is never selected, and `!default` ignores virtual interfaces.
+func selectBindInterface(interfaces []control.Interface, pattern string, destination netip.Addr, defaultIndex int, myInterface string, isVirtual func(name string) bool) *control.Interface {
+	var selected *control.Interface
+	for _, iif := range interfaces {
+		if iif.Flags&net.FlagLoopback != 0 || iif.Name == myInterface || !multiWANAvailable(&iif, destination) {
+			continue
+		}
+		if pattern == bindInterfaceNonDefault {
+			if iif.Index == defaultIndex || isVirtual(iif.Name) {
+				continue
+			}
+		} else if matched, _ := path.Match(pattern, iif.Name); !matched {
+			continue
+		}
+		if selected == nil || iif.Index < selected.Index {
+			selected = common.Ptr(iif)
+		}
+	}
+	return selected
+}
+
+func (s *bindInterfaceSelector) ControlFunc() control.Func {
+	return control.BindToInterfaceFunc(s.interfaceFinder, func(network string, address string) (interfaceName string, interfaceIndex int, err error) {
+		iif, err := s.Select(M.ParseSocksaddr(address).Addr)
+		if err != nil {
+			return "", -1, err
+		}
+		return iif.Name, iif.Index, nil
+	})
+}
@@ -0,0 +1,16 @@
+package dialer
+
+import "strings"
+
+var virtualInterfacePrefixes = []string{"utun", "ipsec", "bridge", "awdl", "llw", "gif", "stf", "anpi", "ap"}
+
+// isVirtualInterface reports whether the interface is a tunnel, bridge or
+// peer-to-peer interface.
+func isVirtualInterface(name string) bool {
+	for _, prefix := range virtualInterfacePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,13 @@
+package dialer
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// isVirtualInterface reports whether the interface is not backed by a
+// device, such as TUN, bridge, veth, WireGuard and PPP interfaces.
+func isVirtualInterface(name string) bool {
+	_, err := os.Stat(filepath.Join("/sys/devices/virtual/net", name))
+	return err == nil
+}
@@ -0,0 +1,7 @@
+//go:build !linux && !darwin
+
+package dialer
+
+func isVirtualInterface(name string) bool {
+	return false
+}
@@ -0,0 +1,69 @@
+package dialer
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/sagernet/sing/common/control"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testInterface(index int, name string, flags net.Flags, addresses ...string) control.Interface {
+	iif := control.Interface{
+		Index: index,
+		Name:  name,
+		Flags: net.FlagUp | net.FlagRunning | flags,
+	}
+	for _, address := range addresses {
+		iif.Addresses = append(iif.Addresses, netip.MustParsePrefix(address))
+	}
+	return iif
+}
+
+func TestSelectBindInterface(t *testing.T) {
+	t.Parallel()
+	interfaces := []control.Interface{
+		testInterface(1, "lo", net.FlagLoopback, "127.0.0.1/8", "::1/128"),
+		testInterface(2, "eth0", 0, "192.168.1.2/24", "2001:db8::2/64"),
+		testInterface(3, "wwan0", 0, "10.0.0.2/8"),
+		testInterface(4, "wwan1", 0, "10.1.0.2/8", "fe80::1/64"),
+		testInterface(5, "tun0", net.FlagPointToPoint, "172.19.0.1/30", "fdfe:dcba:9876::1/126"),
+		testInterface(6, "docker0", 0, "172.17.0.1/16"),
+		{Index: 7, Name: "wwan2", Addresses: []netip.Prefix{netip.MustParsePrefix("10.2.0.2/8")}},
+	}
+	isVirtual := func(name string) bool {
+		return name == "tun0" || name == "docker0"
+	}
+	inet4 := netip.MustParseAddr("1.1.1.1")
+	inet6 := netip.MustParseAddr("2606:4700::1111")
+	for _, testCase := range []struct {
+		name         string
+		pattern      string
+		destination  netip.Addr
+		defaultIndex int
+		myInterface  string
+		selected     string
+	}{
+		{"pattern", "wwan*", inet4, -1, "", "wwan0"},
+		{"pattern address family", "wwan*", inet6, -1, "", ""},
+		{"pattern any family", "wwan[12]", netip.Addr{}, -1, "", "wwan1"},
+		{"pattern excludes loopback", "lo", inet4, -1, "", ""},
+		{"pattern excludes own interface", "tun*", inet4, -1, "tun0", ""},
+		{"pattern includes virtual", "tun*", inet4, -1, "", "tun0"},
+		{"non default", bindInterfaceNonDefault, inet4, 2, "tun0", "wwan0"},
+		{"non default address family", bindInterfaceNonDefault, inet6, 2, "tun0", ""},
+		{"non default excludes virtual", bindInterfaceNonDefault, inet6, 2, "", ""},
+		{"non default next", bindInterfaceNonDefault, inet4, 3, "", "eth0"},
+		{"non default inet6", bindInterfaceNonDefault, inet6, 4, "", "eth0"},
+	} {
+		selected := selectBindInterface(interfaces, testCase.pattern, testCase.destination, testCase.defaultIndex, testCase.myInterface, isVirtual)
+		if testCase.selected == "" {
+			require.Nil(t, selected, testCase.name)
+		} else {
+			require.NotNil(t, selected, testCase.name)
+			require.Equal(t, testCase.selected, selected.Name, testCase.name)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package dialer
+
+import (
+	"context"
+	"net"
+
+	"github.com/sagernet/sing-box/common/chaos"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/bufio"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+var _ N.Dialer = (*ChaosDialer)(nil)
+
+// ChaosDialer degrades connections for testing.
+type ChaosDialer struct {
+	N.Dialer
+	config *chaos.Config
+}
+
+func NewChaos(dialer N.Dialer, options option.ChaosOptions) (*ChaosDialer, error) {
+	config, err := chaos.NewConfig(options)
+	if err != nil {
+		return nil, err
+	}
+	return &ChaosDialer{Dialer: dialer, config: config}, nil
+}
+
+func (d *ChaosDialer) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	conn, err := d.Dialer.DialContext(ctx, network, destination)
+	if err != nil {
+		return nil, err
+	}
+	if N.NetworkName(network) == N.NetworkUDP {
+		return chaos.NewDatagramConn(conn, d.config), nil
+	}
+	return chaos.NewConn(conn, d.config), nil
+}
+
+func (d *ChaosDialer) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	conn, err := d.Dialer.ListenPacket(ctx, destination)
+	if err != nil {
+		return nil, err
+	}
+	return bufio.NewNetPacketConn(chaos.NewPacketConn(bufio.NewPacketConn(conn), d.config)), nil
+}
+
+func (d *ChaosDialer) Upstream() any {
+	return d.Dialer
+}
@@ -13,10 +13,13 @@ import (
 	"github.com/sagernet/sing-box/common/listener"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/experimental/libbox/platform"
+	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-mux"
 	"github.com/sagernet/sing/common"
 	"github.com/sagernet/sing/common/control"
 	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
 	"github.com/sagernet/sing/service"
@@ -44,12 +47,30 @@ type DefaultDialer struct {
 	networkType            []C.InterfaceType
 	fallbackNetworkType    []C.InterfaceType
 	networkFallbackDelay   time.Duration
-	networkLastFallback    common.TypedValue[time.Time]
+	networkLastFallback    *common.TypedValue[time.Time]
+	multiWAN               *multiWAN
+	sourceAddressPool      *sourceAddressPool
+	powerManager           adapter.PowerManager
+	tcpBrutalSendBPS       uint64
 }
 
 func NewDefault(ctx context.Context, options option.DialerOptions) (*DefaultDialer, error) {
 	networkManager := service.FromContext[adapter.NetworkManager](ctx)
 	platformInterface := service.FromContext[platform.Interface](ctx)
+	var tcpCongestionControl control.Func
+	if options.TCPCongestionControl != "" {
+		if !C.IsLinux {
+			return nil, E.New("`tcp_congestion_control` is only supported on Linux")
+		}
+		tcpCongestionControl = listener.TCPCongestionControl(options.TCPCongestionControl)
+	}
+	tcpBrutalSendBPS, err := listener.TCPBrutalSendBPS(options.TCPBrutal, options.TCPCongestionControl)
+	if err != nil {
+		return nil, err
+	}
+	if tcpBrutalSendBPS > 0 && options.TCPFastOpen {
+		return nil, E.New("`tcp_brutal` and `tcp_fast_open` are mutually exclusive")
+	}
 
 	var (
 		dialer                 net.Dialer
@@ -70,7 +91,16 @@ func NewDefault(ctx context.Context, options option.DialerOptions) (*DefaultDial
 		if !(C.IsLinux || C.IsDarwin || C.IsWindows) {
 			return nil, E.New("`bind_interface` is only supported on Linux, macOS and Windows")
 		}
-		bindFunc := control.BindToInterface(interfaceFinder, options.BindInterface, -1)
+		var bindFunc control.Func
+		if isBindInterfacePattern(options.BindInterface) {
+			selector, err := newBindInterfaceSelector(networkManager, interfaceFinder, options.BindInterface)
+			if err != nil {
+				return nil, err
+			}
+			bindFunc = selector.ControlFunc()
+		} else {
+			bindFunc = control.BindToInterface(interfaceFinder, options.BindInterface, -1)
+		}
 		dialer.Control = control.Append(dialer.Control, bindFunc)
 		listener.Control = control.Append(listener.Control, bindFunc)
 	}
@@ -81,8 +111,41 @@ func NewDefault(ctx context.Context, options option.DialerOptions) (*DefaultDial
 		dialer.Control = control.Append(dialer.Control, setMarkWrapper(networkManager, uint32(options.RoutingMark), false))
 		listener.Control = control.Append(listener.Control, setMarkWrapper(networkManager, uint32(options.RoutingMark), false))
 	}
-	disableDefaultBind := options.BindInterface != "" || options.Inet4BindAddress != nil || options.Inet6BindAddress != nil
-	if disableDefaultBind || options.TCPFastOpen {
+	tcpFastOpen := options.TCPFastOpen
+	if options.TCPMultiPath && tcpFastOpen {
+		if logFactory := service.FromContext[log.Factory](ctx); logFactory != nil {
+			logFactory.Logger().Warn("`tcp_fast_open` is not supported with `tcp_multi_path`, fallback to regular connect")
+		}
+		tcpFastOpen = false
+	}
+	var multiWAN *multiWAN
+	if options.MultiWAN != nil {
+		if options.BindInterface != "" {
+			return nil, E.New("`multi_wan` is conflict with `bind_interface`")
+		} else if options.NetworkStrategy != nil || len(options.NetworkType) > 0 || len(options.FallbackNetworkType) > 0 {
+			return nil, E.New("`multi_wan` is conflict with `network_strategy`, `network_type` and `fallback_network_type`")
+		}
+		var err error
+		multiWAN, err = newMultiWAN(interfaceFinder, *options.MultiWAN)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var sourceAddressPool *sourceAddressPool
+	if options.SourceAddressPool != nil {
+		if options.Inet4BindAddress != nil || options.Inet6BindAddress != nil {
+			return nil, E.New("`source_address_pool` is conflict with `inet4_bind_address` and `inet6_bind_address`")
+		} else if multiWAN != nil {
+			return nil, E.New("`source_address_pool` is conflict with `multi_wan`")
+		}
+		var err error
+		sourceAddressPool, err = newSourceAddressPool(*options.SourceAddressPool)
+		if err != nil {
+			return nil, err
+		}
+	}
+	disableDefaultBind := options.BindInterface != "" || options.Inet4BindAddress != nil || options.Inet6BindAddress != nil || multiWAN != nil || sourceAddressPool != nil
+	if disableDefaultBind || tcpFastOpen {
 		if options.NetworkStrategy != nil || len(options.NetworkType) > 0 && options.FallbackNetworkType == nil && options.FallbackDelay == 0 {
 			return nil, E.New("`network_strategy` is conflict with `bind_interface`, `inet4_bind_address`, `inet6_bind_address` and `tcp_fast_open`")
 		}
@@ -180,9 +243,14 @@ func NewDefault(ctx context.Context, options option.DialerOptions) (*DefaultDial
 	}
 	if options.TCPMultiPath {
 		dialer4.SetMultipathTCP(true)
+		dialer6.SetMultipathTCP(true)
+	}
+	if tcpCongestionControl != nil {
+		dialer4.Control = control.Append(dialer4.Control, tcpCongestionControl)
+		dialer6.Control = control.Append(dialer6.Control, tcpCongestionControl)
 	}
-	tcpDialer4 := tfo.Dialer{Dialer: dialer4, DisableTFO: !options.TCPFastOpen}
-	tcpDialer6 := tfo.Dialer{Dialer: dialer6, DisableTFO: !options.TCPFastOpen}
+	tcpDialer4 := tfo.Dialer{Dialer: dialer4, DisableTFO: !tcpFastOpen}
+	tcpDialer6 := tfo.Dialer{Dialer: dialer6, DisableTFO: !tcpFastOpen}
 	return &DefaultDialer{
 		dialer4:                tcpDialer4,
 		dialer6:                tcpDialer6,
@@ -198,6 +266,11 @@ func NewDefault(ctx context.Context, options option.DialerOptions) (*DefaultDial
 		networkType:            networkType,
 		fallbackNetworkType:    fallbackNetworkType,
 		networkFallbackDelay:   networkFallbackDelay,
+		networkLastFallback:    new(common.TypedValue[time.Time]),
+		multiWAN:               multiWAN,
+		sourceAddressPool:      sourceAddressPool,
+		powerManager:           service.FromContext[adapter.PowerManager](ctx),
+		tcpBrutalSendBPS:       tcpBrutalSendBPS,
 	}, nil
 }
 
@@ -208,23 +281,101 @@ func setMarkWrapper(networkManager adapter.NetworkManager, mark uint32, isDefaul
 	return func(network, address string, conn syscall.RawConn) error {
 		if networkManager.AutoRedirectOutputMark() != 0 {
 			if isDefault {
-				return E.New("`route.default_mark` is conflict with `tun.auto_redirect`")
+				return E.New("`route.default_mark` is conflict with `auto_redirect`")
 			} else {
-				return E.New("`routing_mark` is conflict with `tun.auto_redirect`")
+				return E.New("`routing_mark` is conflict with `auto_redirect`")
 			}
 		}
 		return control.RoutingMark(mark)(network, address, conn)
 	}
 }
 
+func (d *DefaultDialer) withControl(controlFunc control.Func) *DefaultDialer {
+	dialer4 := d.dialer4
+	dialer4.Control = control.Append(dialer4.Control, controlFunc)
+	dialer6 := d.dialer6
+	dialer6.Control = control.Append(dialer6.Control, controlFunc)
+	udpDialer4 := d.udpDialer4
+	udpDialer4.Control = control.Append(udpDialer4.Control, controlFunc)
+	udpDialer6 := d.udpDialer6
+	udpDialer6.Control = control.Append(udpDialer6.Control, controlFunc)
+	udpListener := d.udpListener
+	udpListener.Control = control.Append(udpListener.Control, controlFunc)
+	return &DefaultDialer{
+		dialer4:                dialer4,
+		dialer6:                dialer6,
+		udpDialer4:             udpDialer4,
+		udpDialer6:             udpDialer6,
+		udpListener:            udpListener,
+		udpAddr4:               d.udpAddr4,
+		udpAddr6:               d.udpAddr6,
+		netns:                  d.netns,
+		networkManager:         d.networkManager,
+		networkStrategy:        d.networkStrategy,
+		defaultNetworkStrategy: d.defaultNetworkStrategy,
+		networkType:            d.networkType,
+		fallbackNetworkType:    d.fallbackNetworkType,
+		networkFallbackDelay:   d.networkFallbackDelay,
+		networkLastFallback:    d.networkLastFallback,
+		multiWAN:               d.multiWAN,
+		sourceAddressPool:      d.sourceAddressPool,
+		powerManager:           d.powerManager,
+		tcpBrutalSendBPS:       d.tcpBrutalSendBPS,
+	}
+}
+
+// withKeepAlive returns a copy of the dialer with longer TCP keep alive
+// periods, as the dialer applies KeepAlive after the control functions.
+func (d *DefaultDialer) withKeepAlive(idle time.Duration, interval time.Duration) *DefaultDialer {
+	newDialer := d.withControl(control.SetKeepAlivePeriod(idle, interval))
+	newDialer.dialer4.KeepAlive = idle
+	newDialer.dialer6.KeepAlive = idle
+	return newDialer
+}
+
+// withMetadataKeepAlive returns a copy of the dialer with the TCP keep alive
+// override of the matched route options.
+func (d *DefaultDialer) withMetadataKeepAlive(metadata *adapter.InboundContext) *DefaultDialer {
+	if metadata == nil || metadata.TCPKeepAlive == 0 && metadata.TCPKeepAliveInterval == 0 {
+		return d
+	}
+	if metadata.TCPKeepAlive < 0 {
+		newDialer := d.withControl(nil)
+		newDialer.dialer4.KeepAlive = -1
+		newDialer.dialer6.KeepAlive = -1
+		return newDialer
+	}
+	idle := metadata.TCPKeepAlive
+	if idle == 0 {
+		idle = C.TCPKeepAliveInitial
+	}
+	interval := metadata.TCPKeepAliveInterval
+	if interval == 0 {
+		interval = C.TCPKeepAliveInterval
+	}
+	return d.withKeepAlive(idle, interval)
+}
+
 func (d *DefaultDialer) DialContext(ctx context.Context, network string, address M.Socksaddr) (net.Conn, error) {
 	if !address.IsValid() {
 		return nil, E.New("invalid address")
 	} else if address.IsFqdn() {
 		return nil, E.New("domain not resolved")
 	}
+	if d.powerManager != nil && d.powerManager.LowPower() {
+		d = d.withKeepAlive(d.powerManager.KeepAliveInterval(C.TCPKeepAliveInitial), d.powerManager.KeepAliveInterval(C.TCPKeepAliveInterval))
+	}
 	if d.networkStrategy == nil {
-		return trackConn(listener.ListenNetworkNamespace[net.Conn](d.netns, func() (net.Conn, error) {
+		if metadataControl := MetadataControl(d.networkManager, adapter.ContextFrom(ctx)); metadataControl != nil {
+			d = d.withControl(metadataControl)
+		}
+		d = d.withMetadataKeepAlive(adapter.ContextFrom(ctx))
+		if d.multiWAN != nil {
+			return d.dialMultiWAN(ctx, network, address)
+		} else if d.sourceAddressPool != nil {
+			return d.trackConn(d.dialSourceAddressPool(ctx, network, address))
+		}
+		return d.trackConn(listener.ListenNetworkNamespace[net.Conn](d.netns, func() (net.Conn, error) {
 			switch N.NetworkName(network) {
 			case N.NetworkUDP:
 				if !address.IsIPv6() {
@@ -251,6 +402,10 @@ func (d *DefaultDialer) DialParallelInterface(ctx context.Context, network strin
 	if strategy == nil {
 		return d.DialContext(ctx, network, address)
 	}
+	if metadataControl := MetadataControl(d.networkManager, adapter.ContextFrom(ctx)); metadataControl != nil {
+		d = d.withControl(metadataControl)
+	}
+	d = d.withMetadataKeepAlive(adapter.ContextFrom(ctx))
 	if len(interfaceType) == 0 {
 		interfaceType = d.networkType
 	}
@@ -289,25 +444,51 @@ func (d *DefaultDialer) DialParallelInterface(ctx context.Context, network strin
 	if !fastFallback && !isPrimary {
 		d.networkLastFallback.Store(time.Now())
 	}
-	return trackConn(conn, nil)
+	return d.trackConn(conn, nil)
 }
 
 func (d *DefaultDialer) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
 	if d.networkStrategy == nil {
+		if metadataControl := MetadataControl(d.networkManager, adapter.ContextFrom(ctx)); metadataControl != nil {
+			d = d.withControl(metadataControl)
+		}
+		if d.multiWAN != nil {
+			return d.listenMultiWANPacket(ctx, destination)
+		} else if d.sourceAddressPool != nil {
+			return trackPacketConn(d.listenSourceAddressPoolPacket(ctx, destination))
+		}
 		return trackPacketConn(listener.ListenNetworkNamespace[net.PacketConn](d.netns, func() (net.PacketConn, error) {
-			if destination.IsIPv6() {
-				return d.udpListener.ListenPacket(ctx, N.NetworkUDP, d.udpAddr6)
-			} else if destination.IsIPv4() && !destination.Addr.IsUnspecified() {
-				return d.udpListener.ListenPacket(ctx, N.NetworkUDP+"4", d.udpAddr4)
-			} else {
-				return d.udpListener.ListenPacket(ctx, N.NetworkUDP, d.udpAddr4)
+			if metadata := adapter.ContextFrom(ctx); metadata != nil && metadata.UDPLocalPort != 0 {
+				conn, err := listenPacket(ctx, d.udpListener, destination, withPort(d.udpAddr4, metadata.UDPLocalPort), withPort(d.udpAddr6, metadata.UDPLocalPort))
+				if err == nil {
+					return conn, nil
+				}
+				// the port of the restored session is taken, fallback to a random port
 			}
+			return listenPacket(ctx, d.udpListener, destination, d.udpAddr4, d.udpAddr6)
 		}))
 	} else {
 		return d.ListenSerialInterfacePacket(ctx, destination, d.networkStrategy, d.networkType, d.fallbackNetworkType, d.networkFallbackDelay)
 	}
 }
 
+func listenPacket(ctx context.Context, udpListener net.ListenConfig, destination M.Socksaddr, udpAddr4 string, udpAddr6 string) (net.PacketConn, error) {
+	if destination.IsIPv6() {
+		return udpListener.ListenPacket(ctx, N.NetworkUDP, udpAddr6)
+	} else if destination.IsIPv4() && !destination.Addr.IsUnspecified() {
+		return udpListener.ListenPacket(ctx, N.NetworkUDP+"4", udpAddr4)
+	} else {
+		return udpListener.ListenPacket(ctx, N.NetworkUDP, udpAddr4)
+	}
+}
+
+// withPort replaces the port of a listen address, which is empty if no bind
+// address is configured.
+func withPort(address string, port uint16) string {
+	host, _, _ := net.SplitHostPort(address)
+	return net.JoinHostPort(host, F.ToString(port))
+}
+
 func (d *DefaultDialer) DialerForICMPDestination(destination netip.Addr) net.Dialer {
 	if !destination.Is6() {
 		return d.dialer6.Dialer
@@ -323,6 +504,9 @@ func (d *DefaultDialer) ListenSerialInterfacePacket(ctx context.Context, destina
 	if strategy == nil {
 		return d.ListenPacket(ctx, destination)
 	}
+	if metadataControl := MetadataControl(d.networkManager, adapter.ContextFrom(ctx)); metadataControl != nil {
+		d = d.withControl(metadataControl)
+	}
 	if len(interfaceType) == 0 {
 		interfaceType = d.networkType
 	}
@@ -353,6 +537,22 @@ func (d *DefaultDialer) WireGuardControl() control.Func {
 	return d.udpListener.Control
 }
 
+// trackConn enables TCP Brutal on the established connection, as the
+// parameters are reset on connect, and tracks it.
+func (d *DefaultDialer) trackConn(conn net.Conn, err error) (net.Conn, error) {
+	if err != nil {
+		return nil, err
+	}
+	if d.tcpBrutalSendBPS > 0 && N.NetworkName(conn.LocalAddr().Network()) == N.NetworkTCP {
+		err = mux.SetBrutalOptions(conn, d.tcpBrutalSendBPS)
+		if err != nil {
+			conn.Close()
+			return nil, E.Cause(err, "enable tcp brutal")
+		}
+	}
+	return trackConn(conn, nil)
+}
+
 func trackConn(conn net.Conn, err error) (net.Conn, error) {
 	if !conntrack.Enabled || err != nil {
 		return conn, err
@@ -0,0 +1,177 @@
+package dialer
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"net"
+	"net/netip"
+	"sort"
+
+	"github.com/sagernet/sing-box/common/listener"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/control"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+type multiWAN struct {
+	interfaceFinder control.InterfaceFinder
+	strategy        string
+	interfaces      []multiWANInterface
+}
+
+type multiWANInterface struct {
+	name   string
+	weight uint32
+}
+
+type multiWANCandidate struct {
+	*control.Interface
+	score float64
+}
+
+func newMultiWAN(interfaceFinder control.InterfaceFinder, options option.MultiWANOptions) (*multiWAN, error) {
+	if !(C.IsLinux || C.IsDarwin || C.IsWindows) {
+		return nil, E.New("`multi_wan` is only supported on Linux, macOS and Windows")
+	}
+	if len(options.Interfaces) == 0 {
+		return nil, E.New("missing `multi_wan.interfaces`")
+	}
+	strategy := options.Strategy
+	switch strategy {
+	case "":
+		strategy = C.MultiWANStrategyWeighted
+	case C.MultiWANStrategyWeighted, C.MultiWANStrategyHash, C.MultiWANStrategyFailover:
+	default:
+		return nil, E.New("unknown multi_wan strategy: ", strategy)
+	}
+	interfaces := make([]multiWANInterface, 0, len(options.Interfaces))
+	for i, interfaceOptions := range options.Interfaces {
+		if interfaceOptions.Interface == "" {
+			return nil, E.New("missing interface name in `multi_wan.interfaces[", i, "]`")
+		}
+		weight := interfaceOptions.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		interfaces = append(interfaces, multiWANInterface{interfaceOptions.Interface, weight})
+	}
+	return &multiWAN{
+		interfaceFinder: interfaceFinder,
+		strategy:        strategy,
+		interfaces:      interfaces,
+	}, nil
+}
+
+// candidates returns the available interfaces for the destination in the order they should be tried.
+func (m *multiWAN) candidates(destination netip.Addr) []*control.Interface {
+	var candidates []multiWANCandidate
+	for _, multiWANInterface := range m.interfaces {
+		iif, err := m.interfaceFinder.ByName(multiWANInterface.name)
+		if err != nil || !multiWANAvailable(iif, destination) {
+			continue
+		}
+		var score float64
+		switch m.strategy {
+		case C.MultiWANStrategyWeighted:
+			// weighted random order, see Efraimidis and Spirakis
+			score = math.Pow(rand.Float64(), 1/float64(multiWANInterface.weight))
+		case C.MultiWANStrategyHash:
+			// weighted rendezvous hashing keeps destinations on the same interface while it is available
+			score = -float64(multiWANInterface.weight) / math.Log(multiWANHash(destination, multiWANInterface.name))
+		}
+		candidates = append(candidates, multiWANCandidate{iif, score})
+	}
+	if m.strategy != C.MultiWANStrategyFailover {
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].score > candidates[j].score
+		})
+	}
+	interfaces := make([]*control.Interface, 0, len(candidates))
+	for _, candidate := range candidates {
+		interfaces = append(interfaces, candidate.Interface)
+	}
+	return interfaces
+}
+
+func multiWANAvailable(iif *control.Interface, destination netip.Addr) bool {
+	if iif.Flags&net.FlagUp == 0 || iif.Flags&net.FlagRunning == 0 {
+		return false
+	}
+	for _, address := range iif.Addresses {
+		addr := address.Addr()
+		if !destination.IsValid() || destination.Is4() == addr.Is4() && !addr.IsLinkLocalUnicast() {
+			return true
+		}
+	}
+	return false
+}
+
+// multiWANHash maps the destination and interface to (0, 1).
+func multiWANHash(destination netip.Addr, interfaceName string) float64 {
+	hash := fnv.New64a()
+	addressBytes, _ := destination.MarshalBinary()
+	hash.Write(addressBytes)
+	hash.Write([]byte(interfaceName))
+	var sum [8]byte
+	return (float64(binary.BigEndian.Uint64(hash.Sum(sum[:0]))>>11) + 0.5) / (1 << 53)
+}
+
+func (d *DefaultDialer) dialMultiWAN(ctx context.Context, network string, address M.Socksaddr) (net.Conn, error) {
+	candidates := d.multiWAN.candidates(address.Addr)
+	if len(candidates) == 0 {
+		return nil, E.New("no available interface in `multi_wan` for ", address)
+	}
+	var errors []error
+	for _, iif := range candidates {
+		bindFunc := control.BindToInterface(nil, iif.Name, iif.Index)
+		conn, err := listener.ListenNetworkNamespace[net.Conn](d.netns, func() (net.Conn, error) {
+			switch N.NetworkName(network) {
+			case N.NetworkUDP:
+				var udpDialer net.Dialer
+				if !address.IsIPv6() {
+					udpDialer = d.udpDialer4
+				} else {
+					udpDialer = d.udpDialer6
+				}
+				udpDialer.Control = control.Append(udpDialer.Control, bindFunc)
+				return udpDialer.DialContext(ctx, network, address.String())
+			}
+			tcpDialer := d.dialer4
+			if address.IsIPv6() {
+				tcpDialer = d.dialer6
+			}
+			tcpDialer.Control = control.Append(tcpDialer.Control, bindFunc)
+			return DialSlowContext(&tcpDialer, ctx, network, address)
+		})
+		if err == nil {
+			return d.trackConn(conn, nil)
+		}
+		errors = append(errors, E.Cause(err, "dial ", iif.Name))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, E.Errors(errors...)
+}
+
+func (d *DefaultDialer) listenMultiWANPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	var destinationAddr netip.Addr
+	if destination.IsIP() && !destination.Addr.IsUnspecified() {
+		destinationAddr = destination.Addr
+	}
+	candidates := d.multiWAN.candidates(destinationAddr)
+	if len(candidates) == 0 {
+		return nil, E.New("no available interface in `multi_wan` for ", destination)
+	}
+	udpListener := d.udpListener
+	udpListener.Control = control.Append(udpListener.Control, control.BindToInterface(nil, candidates[0].Name, candidates[0].Index))
+	return trackPacketConn(listener.ListenNetworkNamespace[net.PacketConn](d.netns, func() (net.PacketConn, error) {
+		return listenPacket(ctx, udpListener, destination, d.udpAddr4, d.udpAddr6)
+	}))
+}
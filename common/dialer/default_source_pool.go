@@ -0,0 +1,157 @@
+package dialer
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/fnv"
+	"net"
+	"net/netip"
+	"sync/atomic"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/listener"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+// sourceAddressPoolMaxPrefixSize limits the addresses used in each prefix,
+// so that large IPv6 prefixes can be used without overflow.
+const sourceAddressPoolMaxPrefixSize = 1 << 32
+
+type sourceAddressPool struct {
+	strategy string
+	pool4    sourceAddressFamilyPool
+	pool6    sourceAddressFamilyPool
+	counter  atomic.Uint64
+}
+
+type sourceAddressFamilyPool struct {
+	prefixes []netip.Prefix
+	size     uint64
+}
+
+func newSourceAddressPool(options option.SourceAddressPoolOptions) (*sourceAddressPool, error) {
+	if len(options.Addresses) == 0 {
+		return nil, E.New("missing `source_address_pool.addresses`")
+	}
+	strategy := options.Strategy
+	switch strategy {
+	case "":
+		strategy = C.SourceAddressPoolStrategyRoundRobin
+	case C.SourceAddressPoolStrategyRoundRobin, C.SourceAddressPoolStrategyHash, C.SourceAddressPoolStrategyUser:
+	default:
+		return nil, E.New("unknown source_address_pool strategy: ", strategy)
+	}
+	pool := &sourceAddressPool{strategy: strategy}
+	for _, address := range options.Addresses {
+		prefix := address.Build(netip.Prefix{}).Masked()
+		familyPool := &pool.pool4
+		if prefix.Addr().Is6() {
+			familyPool = &pool.pool6
+		}
+		familyPool.prefixes = append(familyPool.prefixes, prefix)
+		familyPool.size += prefixSize(prefix)
+	}
+	return pool, nil
+}
+
+func prefixSize(prefix netip.Prefix) uint64 {
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+	if hostBits >= 32 {
+		return sourceAddressPoolMaxPrefixSize
+	}
+	return 1 << hostBits
+}
+
+// Select returns the source address for the destination, or an invalid
+// address if the pool has no address of the destination address family.
+func (p *sourceAddressPool) Select(ctx context.Context, destination M.Socksaddr) netip.Addr {
+	familyPool := &p.pool4
+	if destination.IsIPv6() || !destination.IsIPv4() && len(p.pool4.prefixes) == 0 {
+		familyPool = &p.pool6
+	}
+	if familyPool.size == 0 {
+		return netip.Addr{}
+	}
+	var index uint64
+	switch p.strategy {
+	case C.SourceAddressPoolStrategyRoundRobin:
+		index = p.counter.Add(1) - 1
+	case C.SourceAddressPoolStrategyHash:
+		index = sourceAddressPoolHash([]byte(destination.AddrString()))
+	case C.SourceAddressPoolStrategyUser:
+		metadata := adapter.ContextFrom(ctx)
+		if metadata != nil && metadata.User != "" {
+			index = sourceAddressPoolHash([]byte(metadata.User))
+		} else if metadata != nil {
+			index = sourceAddressPoolHash(metadata.Source.Addr.AsSlice())
+		}
+	}
+	index %= familyPool.size
+	for _, prefix := range familyPool.prefixes {
+		size := prefixSize(prefix)
+		if index < size {
+			return addrAdd(prefix.Addr(), index)
+		}
+		index -= size
+	}
+	return netip.Addr{}
+}
+
+func sourceAddressPoolHash(key []byte) uint64 {
+	hash := fnv.New64a()
+	hash.Write(key)
+	return hash.Sum64()
+}
+
+func addrAdd(addr netip.Addr, offset uint64) netip.Addr {
+	if addr.Is4() {
+		addrBytes := addr.As4()
+		binary.BigEndian.PutUint32(addrBytes[:], binary.BigEndian.Uint32(addrBytes[:])+uint32(offset))
+		return netip.AddrFrom4(addrBytes)
+	}
+	addrBytes := addr.As16()
+	binary.BigEndian.PutUint64(addrBytes[8:], binary.BigEndian.Uint64(addrBytes[8:])+offset)
+	return netip.AddrFrom16(addrBytes)
+}
+
+func (d *DefaultDialer) dialSourceAddressPool(ctx context.Context, network string, address M.Socksaddr) (net.Conn, error) {
+	sourceAddr := d.sourceAddressPool.Select(ctx, address)
+	return listener.ListenNetworkNamespace[net.Conn](d.netns, func() (net.Conn, error) {
+		switch N.NetworkName(network) {
+		case N.NetworkUDP:
+			udpDialer := d.udpDialer4
+			if address.IsIPv6() {
+				udpDialer = d.udpDialer6
+			}
+			if sourceAddr.IsValid() {
+				udpDialer.LocalAddr = &net.UDPAddr{IP: sourceAddr.AsSlice()}
+			}
+			return udpDialer.DialContext(ctx, network, address.String())
+		}
+		tcpDialer := d.dialer4
+		if address.IsIPv6() {
+			tcpDialer = d.dialer6
+		}
+		if sourceAddr.IsValid() {
+			tcpDialer.LocalAddr = &net.TCPAddr{IP: sourceAddr.AsSlice()}
+		}
+		return DialSlowContext(&tcpDialer, ctx, network, address)
+	})
+}
+
+func (d *DefaultDialer) listenSourceAddressPoolPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	var udpAddr4, udpAddr6 string
+	sourceAddr := d.sourceAddressPool.Select(ctx, destination)
+	if sourceAddr.Is4() {
+		udpAddr4 = M.SocksaddrFrom(sourceAddr, 0).String()
+	} else if sourceAddr.IsValid() {
+		udpAddr6 = M.SocksaddrFrom(sourceAddr, 0).String()
+	}
+	return listener.ListenNetworkNamespace[net.PacketConn](d.netns, func() (net.PacketConn, error) {
+		return listenPacket(ctx, d.udpListener, destination, udpAddr4, udpAddr6)
+	})
+}
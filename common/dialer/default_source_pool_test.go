@@ -0,0 +1,175 @@
+package dialer
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json/badoption"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testSourceAddressPool(t *testing.T, strategy string, prefixes ...string) *sourceAddressPool {
+	t.Helper()
+	var addresses badoption.Listable[*badoption.Prefixable]
+	for _, prefix := range prefixes {
+		address := badoption.Prefixable(netip.MustParsePrefix(prefix))
+		addresses = append(addresses, &address)
+	}
+	pool, err := newSourceAddressPool(option.SourceAddressPoolOptions{
+		Addresses: addresses,
+		Strategy:  strategy,
+	})
+	require.NoError(t, err)
+	return pool
+}
+
+func TestSourceAddressPoolPrefixSize(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		prefix string
+		size   uint64
+	}{
+		{"192.0.2.1/32", 1},
+		{"192.0.2.0/31", 2},
+		{"192.0.2.0/24", 256},
+		{"0.0.0.0/0", sourceAddressPoolMaxPrefixSize},
+		{"2001:db8::1/128", 1},
+		{"2001:db8::/120", 256},
+		{"2001:db8::/97", 1 << 31},
+		{"2001:db8::/96", sourceAddressPoolMaxPrefixSize},
+		{"2001:db8::/64", sourceAddressPoolMaxPrefixSize},
+	} {
+		require.Equal(t, testCase.size, prefixSize(netip.MustParsePrefix(testCase.prefix)), testCase.prefix)
+	}
+}
+
+func TestSourceAddressPoolAddrAdd(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		addr   string
+		offset uint64
+		result string
+	}{
+		{"192.0.2.0", 0, "192.0.2.0"},
+		{"192.0.2.0", 1, "192.0.2.1"},
+		{"192.0.2.0", 255, "192.0.2.255"},
+		{"192.0.2.0", 256, "192.0.3.0"},
+		{"10.0.0.0", 1<<24 - 1, "10.255.255.255"},
+		{"2001:db8::", 0, "2001:db8::"},
+		{"2001:db8::", 1, "2001:db8::1"},
+		{"2001:db8::", 0x10000, "2001:db8::1:0"},
+		{"2001:db8::", 1<<32 - 1, "2001:db8::ffff:ffff"},
+	} {
+		require.Equal(t, netip.MustParseAddr(testCase.result), addrAdd(netip.MustParseAddr(testCase.addr), testCase.offset), testCase.addr, "+", testCase.offset)
+	}
+}
+
+func TestSourceAddressPoolRoundRobin(t *testing.T) {
+	t.Parallel()
+	pool := testSourceAddressPool(t, "", "192.0.2.0/31", "198.51.100.7/32", "2001:db8::/127")
+	destination4 := M.ParseSocksaddrHostPort("203.0.113.1", 443)
+	destination6 := M.ParseSocksaddrHostPort("2001:db8:1::1", 443)
+	for _, testCase := range []struct {
+		destination M.Socksaddr
+		source      string
+	}{
+		{destination4, "192.0.2.0"},
+		{destination4, "192.0.2.1"},
+		{destination4, "198.51.100.7"},
+		{destination4, "192.0.2.0"},
+		{destination6, "2001:db8::"},
+		{destination6, "2001:db8::1"},
+	} {
+		require.Equal(t, netip.MustParseAddr(testCase.source), pool.Select(context.Background(), testCase.destination), testCase.destination)
+	}
+}
+
+func TestSourceAddressPoolFamily(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		prefixes    []string
+		destination M.Socksaddr
+		source      string
+	}{
+		{[]string{"192.0.2.1/32", "2001:db8::1/128"}, M.ParseSocksaddrHostPort("203.0.113.1", 443), "192.0.2.1"},
+		{[]string{"192.0.2.1/32", "2001:db8::1/128"}, M.ParseSocksaddrHostPort("2001:db8:1::1", 443), "2001:db8::1"},
+		{[]string{"192.0.2.1/32", "2001:db8::1/128"}, M.ParseSocksaddrHostPort("example.com", 443), "192.0.2.1"},
+		{[]string{"2001:db8::1/128"}, M.ParseSocksaddrHostPort("example.com", 443), "2001:db8::1"},
+		{[]string{"2001:db8::1/128"}, M.ParseSocksaddrHostPort("203.0.113.1", 443), ""},
+		{[]string{"192.0.2.1/32"}, M.ParseSocksaddrHostPort("2001:db8:1::1", 443), ""},
+	} {
+		pool := testSourceAddressPool(t, C.SourceAddressPoolStrategyRoundRobin, testCase.prefixes...)
+		var source netip.Addr
+		if testCase.source != "" {
+			source = netip.MustParseAddr(testCase.source)
+		}
+		require.Equal(t, source, pool.Select(context.Background(), testCase.destination), testCase.prefixes, " ", testCase.destination)
+	}
+}
+
+func TestSourceAddressPoolHash(t *testing.T) {
+	t.Parallel()
+	pool := testSourceAddressPool(t, C.SourceAddressPoolStrategyHash, "192.0.2.0/24", "198.51.100.0/24")
+	destinations := []M.Socksaddr{
+		M.ParseSocksaddrHostPort("203.0.113.1", 443),
+		M.ParseSocksaddrHostPort("203.0.113.1", 80),
+		M.ParseSocksaddrHostPort("203.0.113.2", 443),
+		M.ParseSocksaddrHostPort("example.com", 443),
+	}
+	for _, destination := range destinations {
+		source := pool.Select(context.Background(), destination)
+		require.True(t, source.IsValid(), destination)
+		require.Equal(t, source, pool.Select(context.Background(), destination), destination)
+		index := sourceAddressPoolHash([]byte(destination.AddrString())) % 512
+		if index < 256 {
+			require.Equal(t, addrAdd(netip.MustParseAddr("192.0.2.0"), index), source, destination)
+		} else {
+			require.Equal(t, addrAdd(netip.MustParseAddr("198.51.100.0"), index-256), source, destination)
+		}
+	}
+	require.Equal(t, pool.Select(context.Background(), destinations[0]), pool.Select(context.Background(), destinations[1]))
+}
+
+func TestSourceAddressPoolUser(t *testing.T) {
+	t.Parallel()
+	pool := testSourceAddressPool(t, C.SourceAddressPoolStrategyUser, "192.0.2.0/24")
+	destination := M.ParseSocksaddrHostPort("203.0.113.1", 443)
+	for _, testCase := range []struct {
+		metadata *adapter.InboundContext
+		source   netip.Addr
+	}{
+		{nil, netip.MustParseAddr("192.0.2.0")},
+		{&adapter.InboundContext{User: "alice"}, addrAdd(netip.MustParseAddr("192.0.2.0"), sourceAddressPoolHash([]byte("alice"))%256)},
+		{&adapter.InboundContext{User: "bob", Source: M.ParseSocksaddrHostPort("10.0.0.1", 1)}, addrAdd(netip.MustParseAddr("192.0.2.0"), sourceAddressPoolHash([]byte("bob"))%256)},
+		{&adapter.InboundContext{Source: M.ParseSocksaddrHostPort("10.0.0.1", 1)}, addrAdd(netip.MustParseAddr("192.0.2.0"), sourceAddressPoolHash([]byte{10, 0, 0, 1})%256)},
+	} {
+		ctx := context.Background()
+		if testCase.metadata != nil {
+			ctx = adapter.WithContext(ctx, testCase.metadata)
+		}
+		require.Equal(t, testCase.source, pool.Select(ctx, destination), testCase.metadata)
+		require.Equal(t, testCase.source, pool.Select(ctx, M.ParseSocksaddrHostPort("198.51.100.1", 443)), testCase.metadata)
+	}
+	require.NotEqual(t, pool.Select(adapter.WithContext(context.Background(), &adapter.InboundContext{User: "alice"}), destination), pool.Select(adapter.WithContext(context.Background(), &adapter.InboundContext{User: "bob"}), destination))
+}
+
+func TestSourceAddressPoolOptions(t *testing.T) {
+	t.Parallel()
+	_, err := newSourceAddressPool(option.SourceAddressPoolOptions{})
+	require.Error(t, err)
+	address := badoption.Prefixable(netip.MustParsePrefix("192.0.2.1/24"))
+	_, err = newSourceAddressPool(option.SourceAddressPoolOptions{
+		Addresses: badoption.Listable[*badoption.Prefixable]{&address},
+		Strategy:  "random",
+	})
+	require.Error(t, err)
+	pool := testSourceAddressPool(t, "", "192.0.2.1/24")
+	require.Equal(t, C.SourceAddressPoolStrategyRoundRobin, pool.strategy)
+	require.Equal(t, netip.MustParseAddr("192.0.2.0"), pool.Select(context.Background(), M.ParseSocksaddrHostPort("203.0.113.1", 443)))
+}
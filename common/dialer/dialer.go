@@ -43,6 +43,9 @@ func NewWithOptions(options Options) (N.Dialer, error) {
 		err    error
 	)
 	if dialOptions.Detour != "" {
+		if dialOptions.TCPBrutal != nil && dialOptions.TCPBrutal.Enabled {
+			return nil, E.New("`tcp_brutal` is not supported with `detour`")
+		}
 		outboundManager := service.FromContext[adapter.OutboundManager](options.Context)
 		if outboundManager == nil {
 			return nil, E.New("missing outbound manager")
@@ -132,6 +135,39 @@ func NewWithOptions(options Options) (N.Dialer, error) {
 			resolveFallbackDelay,
 		)
 	}
+	if dialOptions.Knock != nil {
+		if options.DirectOutbound {
+			return nil, E.New("`knock` is not supported for direct outbound")
+		}
+		dialer, err = NewKnock(options.Context, dialer, *dialOptions.Knock)
+		if err != nil {
+			return nil, E.Cause(err, "create knock dialer")
+		}
+	}
+	if dialOptions.Chaos != nil {
+		if options.DirectOutbound {
+			return nil, E.New("`chaos` is not supported for direct outbound, use the route option instead")
+		}
+		dialer, err = NewChaos(dialer, *dialOptions.Chaos)
+		if err != nil {
+			return nil, E.Cause(err, "create chaos dialer")
+		}
+	}
+	if dialOptions.Padding != nil {
+		if options.DirectOutbound {
+			return nil, E.New("`padding` is not supported for direct outbound")
+		}
+		dialer, err = NewPadding(dialer, *dialOptions.Padding)
+		if err != nil {
+			return nil, E.Cause(err, "create padding dialer")
+		}
+	}
+	if dialOptions.Prewarm > 0 {
+		if options.DirectOutbound {
+			return nil, E.New("`prewarm` is not supported for direct outbound")
+		}
+		dialer = NewPrewarm(options.Context, dialer, dialOptions.Prewarm, time.Duration(dialOptions.PrewarmIdleTimeout))
+	}
 	return dialer, nil
 }
 
@@ -0,0 +1,122 @@
+package dialer
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/common/knock"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/ntp"
+)
+
+const (
+	// knockInterval is less than the default timeout of servers, so that
+	// sources of active clients are kept allowed.
+	knockInterval = 10 * time.Second
+	// knockDelay gives the knock a head start over the connection.
+	knockDelay = 50 * time.Millisecond
+)
+
+var _ N.Dialer = (*KnockDialer)(nil)
+
+// KnockDialer sends a knock packet to the server before dialing, if it has
+// not knocked recently.
+type KnockDialer struct {
+	N.Dialer
+	port          uint16
+	key           []byte
+	sourceAddress netip.Addr
+	timeFunc      func() time.Time
+	access        sync.Mutex
+	knocked       map[M.Socksaddr]time.Time
+}
+
+func NewKnock(ctx context.Context, dialer N.Dialer, options option.OutboundKnockOptions) (*KnockDialer, error) {
+	if options.ServerPort == 0 {
+		return nil, E.New("missing knock server_port")
+	}
+	key, err := knock.ParseKey(options.Key)
+	if err != nil {
+		return nil, err
+	}
+	timeFunc := ntp.TimeFuncFromContext(ctx)
+	if timeFunc == nil {
+		timeFunc = time.Now
+	}
+	return &KnockDialer{
+		Dialer:        dialer,
+		port:          options.ServerPort,
+		key:           key,
+		sourceAddress: options.SourceAddress.Build(netip.Addr{}),
+		timeFunc:      timeFunc,
+		knocked:       make(map[M.Socksaddr]time.Time),
+	}, nil
+}
+
+func (d *KnockDialer) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	err := d.knock(ctx, destination)
+	if err != nil {
+		return nil, err
+	}
+	return d.Dialer.DialContext(ctx, network, destination)
+}
+
+func (d *KnockDialer) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	err := d.knock(ctx, destination)
+	if err != nil {
+		return nil, err
+	}
+	return d.Dialer.ListenPacket(ctx, destination)
+}
+
+func (d *KnockDialer) Upstream() any {
+	return d.Dialer
+}
+
+func (d *KnockDialer) knock(ctx context.Context, destination M.Socksaddr) error {
+	server := M.Socksaddr{Addr: destination.Addr, Fqdn: destination.Fqdn, Port: d.port}
+	d.access.Lock()
+	if time.Since(d.knocked[server]) < knockInterval {
+		d.access.Unlock()
+		return nil
+	}
+	d.knocked[server] = time.Now()
+	d.access.Unlock()
+	err := d.sendKnock(ctx, server)
+	if err != nil {
+		d.access.Lock()
+		delete(d.knocked, server)
+		d.access.Unlock()
+		return E.Cause(err, "knock ", server)
+	}
+	timer := time.NewTimer(knockDelay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (d *KnockDialer) sendKnock(ctx context.Context, server M.Socksaddr) error {
+	conn, err := d.Dialer.DialContext(ctx, N.NetworkUDP, server)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	// the server verifies the source address of knocks, which is only known
+	// locally if the client is not behind NAT
+	sourceAddress := d.sourceAddress
+	if !sourceAddress.IsValid() {
+		sourceAddress = M.AddrFromNet(conn.LocalAddr())
+	}
+	_, err = conn.Write(knock.NewPacket(d.key, sourceAddress, d.timeFunc()))
+	return err
+}
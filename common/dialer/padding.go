@@ -0,0 +1,44 @@
+package dialer
+
+import (
+	"context"
+	"net"
+
+	"github.com/sagernet/sing-box/common/padding"
+	"github.com/sagernet/sing-box/option"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+var _ N.Dialer = (*PaddingDialer)(nil)
+
+// PaddingDialer wraps TCP connections with the padding layer.
+type PaddingDialer struct {
+	N.Dialer
+	config *padding.Config
+}
+
+func NewPadding(dialer N.Dialer, options option.PaddingOptions) (*PaddingDialer, error) {
+	config, err := padding.NewConfig(options)
+	if err != nil {
+		return nil, err
+	}
+	return &PaddingDialer{Dialer: dialer, config: config}, nil
+}
+
+func (d *PaddingDialer) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	conn, err := d.Dialer.DialContext(ctx, network, destination)
+	if err != nil || N.NetworkName(network) != N.NetworkTCP {
+		return conn, err
+	}
+	paddingConn, err := padding.NewClientConn(conn, d.config)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return paddingConn, nil
+}
+
+func (d *PaddingDialer) Upstream() any {
+	return d.Dialer
+}
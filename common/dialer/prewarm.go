@@ -0,0 +1,195 @@
+package dialer
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+const DefaultPrewarmIdleTimeout = time.Minute
+
+var (
+	_ PrewarmPoolDialer = (*PrewarmDialer)(nil)
+	_ ResolveDialer     = (*prewarmResolveDialer)(nil)
+)
+
+type PrewarmDialFunc func(ctx context.Context, destination M.Socksaddr) (net.Conn, error)
+
+type PrewarmPoolDialer interface {
+	N.Dialer
+	Dialer() N.Dialer
+	NewPool(dialFunc PrewarmDialFunc) *PrewarmPool
+}
+
+// PrewarmDialer keeps idle TCP connections ready for each destination it
+// has dialed, so that the next dial does not wait for a handshake.
+type PrewarmDialer struct {
+	ctx         context.Context
+	dialer      N.Dialer
+	size        int
+	idleTimeout time.Duration
+	pool        *PrewarmPool
+}
+
+type prewarmResolveDialer struct {
+	*PrewarmDialer
+	resolveDialer ResolveDialer
+}
+
+func NewPrewarm(ctx context.Context, dialer N.Dialer, size int, idleTimeout time.Duration) N.Dialer {
+	if idleTimeout == 0 {
+		idleTimeout = DefaultPrewarmIdleTimeout
+	}
+	prewarmDialer := &PrewarmDialer{
+		ctx:         ctx,
+		dialer:      dialer,
+		size:        size,
+		idleTimeout: idleTimeout,
+	}
+	prewarmDialer.pool = prewarmDialer.NewPool(func(ctx context.Context, destination M.Socksaddr) (net.Conn, error) {
+		return dialer.DialContext(ctx, N.NetworkTCP, destination)
+	})
+	if resolveDialer, isResolve := dialer.(ResolveDialer); isResolve {
+		return &prewarmResolveDialer{prewarmDialer, resolveDialer}
+	}
+	return prewarmDialer
+}
+
+func (d *PrewarmDialer) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	if N.NetworkName(network) != N.NetworkTCP {
+		return d.dialer.DialContext(ctx, network, destination)
+	}
+	return d.pool.DialContext(ctx, destination)
+}
+
+func (d *PrewarmDialer) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	return d.dialer.ListenPacket(ctx, destination)
+}
+
+// NewPool creates a pool sharing the size and idle timeout of this dialer,
+// for connections that need more than a TCP handshake to become usable.
+func (d *PrewarmDialer) NewPool(dialFunc PrewarmDialFunc) *PrewarmPool {
+	return &PrewarmPool{
+		ctx:         d.ctx,
+		dialFunc:    dialFunc,
+		size:        d.size,
+		idleTimeout: d.idleTimeout,
+		entries:     make(map[M.Socksaddr]*prewarmEntry),
+	}
+}
+
+func (d *PrewarmDialer) Dialer() N.Dialer {
+	return d.dialer
+}
+
+func (d *PrewarmDialer) Upstream() any {
+	return d.dialer
+}
+
+func (d *prewarmResolveDialer) QueryOptions() adapter.DNSQueryOptions {
+	return d.resolveDialer.QueryOptions()
+}
+
+type PrewarmPool struct {
+	ctx         context.Context
+	dialFunc    PrewarmDialFunc
+	size        int
+	idleTimeout time.Duration
+	access      sync.Mutex
+	entries     map[M.Socksaddr]*prewarmEntry
+}
+
+type prewarmEntry struct {
+	idle    []*prewarmConn
+	filling int
+	failed  bool
+}
+
+type prewarmConn struct {
+	conn  net.Conn
+	timer *time.Timer
+}
+
+func (p *PrewarmPool) DialContext(ctx context.Context, destination M.Socksaddr) (net.Conn, error) {
+	if metadata := adapter.ContextFrom(ctx); metadata != nil && (metadata.RoutingMark != 0 || metadata.DSCP != 0 || metadata.TTL != 0) {
+		// per-connection socket options can not be applied to idle connections
+		return p.dialFunc(ctx, destination)
+	}
+	p.access.Lock()
+	entry, loaded := p.entries[destination]
+	if !loaded {
+		entry = &prewarmEntry{}
+		p.entries[destination] = entry
+		context.AfterFunc(p.ctx, func() {
+			p.access.Lock()
+			defer p.access.Unlock()
+			for _, idleConn := range entry.idle {
+				idleConn.timer.Stop()
+				idleConn.conn.Close()
+			}
+			entry.idle = nil
+		})
+	}
+	entry.failed = false
+	var conn net.Conn
+	for conn == nil && len(entry.idle) > 0 {
+		idleConn := entry.idle[0]
+		entry.idle = entry.idle[1:]
+		if idleConn.timer.Stop() {
+			conn = idleConn.conn
+		}
+	}
+	p.fill(destination, entry)
+	p.access.Unlock()
+	if conn != nil {
+		return conn, nil
+	}
+	return p.dialFunc(ctx, destination)
+}
+
+func (p *PrewarmPool) fill(destination M.Socksaddr, entry *prewarmEntry) {
+	if p.ctx.Err() != nil || entry.failed {
+		return
+	}
+	for ; len(entry.idle)+entry.filling < p.size; entry.filling++ {
+		go p.prewarm(destination, entry)
+	}
+}
+
+func (p *PrewarmPool) prewarm(destination M.Socksaddr, entry *prewarmEntry) {
+	ctx, cancel := context.WithTimeout(p.ctx, C.TCPTimeout)
+	conn, err := p.dialFunc(ctx, destination)
+	cancel()
+	p.access.Lock()
+	defer p.access.Unlock()
+	entry.filling--
+	if err != nil {
+		// retry on the next dial instead of hammering an unreachable server
+		entry.failed = true
+		return
+	}
+	if p.ctx.Err() != nil {
+		conn.Close()
+		return
+	}
+	idleConn := &prewarmConn{conn: conn}
+	idleConn.timer = time.AfterFunc(p.idleTimeout, func() {
+		conn.Close()
+		p.access.Lock()
+		defer p.access.Unlock()
+		for i, it := range entry.idle {
+			if it == idleConn {
+				entry.idle = append(entry.idle[:i], entry.idle[i+1:]...)
+				break
+			}
+		}
+		p.fill(destination, entry)
+	})
+	entry.idle = append(entry.idle, idleConn)
+}
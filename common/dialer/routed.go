@@ -0,0 +1,247 @@
+package dialer
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common/buf"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+var _ N.Dialer = (*RoutedDialer)(nil)
+
+// RoutedDialer passes connections to the router as if accepted by an inbound,
+// so that sniffing, route rules and actions apply to them.
+type RoutedDialer struct {
+	ctx     context.Context
+	router  adapter.ConnectionRouterEx
+	inbound string
+}
+
+func NewRouted(ctx context.Context, router adapter.ConnectionRouterEx, inbound string) *RoutedDialer {
+	return &RoutedDialer{
+		ctx:     ctx,
+		router:  router,
+		inbound: inbound,
+	}
+}
+
+func (d *RoutedDialer) metadata(network string, destination M.Socksaddr) adapter.InboundContext {
+	return adapter.InboundContext{
+		Inbound:     d.inbound,
+		InboundType: d.inbound,
+		Network:     network,
+		Source:      M.SocksaddrFrom(netip.IPv4Unspecified(), 0),
+		Destination: destination,
+	}
+}
+
+func (d *RoutedDialer) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	switch N.NetworkName(network) {
+	case N.NetworkTCP:
+		clientConn, serverConn := net.Pipe()
+		handshakeConn := &routedHandshakeConn{Conn: serverConn, done: make(chan error, 1)}
+		go d.router.RouteConnectionEx(d.ctx, handshakeConn, d.metadata(N.NetworkTCP, destination), func(it error) {
+			clientConn.Close()
+		})
+		select {
+		case err := <-handshakeConn.done:
+			if err != nil {
+				clientConn.Close()
+				return nil, err
+			}
+			return clientConn, nil
+		case <-ctx.Done():
+			clientConn.Close()
+			return nil, ctx.Err()
+		}
+	case N.NetworkUDP:
+		packetConn, err := d.ListenPacket(ctx, destination)
+		if err != nil {
+			return nil, err
+		}
+		return &routedPacketConn{routedPacketEnd: packetConn.(*routedPacketEnd), destination: destination}, nil
+	default:
+		return nil, E.Extend(N.ErrUnknownNetwork, network)
+	}
+}
+
+func (d *RoutedDialer) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	clientConn, serverConn := newRoutedPacketPipe()
+	go d.router.RoutePacketConnectionEx(d.ctx, serverConn, d.metadata(N.NetworkUDP, destination), func(it error) {
+		clientConn.Close()
+	})
+	return clientConn, nil
+}
+
+// routedHandshakeConn reports the result of the outbound dial, so that DialContext
+// returns after the connection is established like other dialers. A read before
+// that means the router is sniffing and waits for the client to write first.
+type routedHandshakeConn struct {
+	net.Conn
+	done chan error
+}
+
+func (c *routedHandshakeConn) Read(p []byte) (int, error) {
+	select {
+	case c.done <- nil:
+	default:
+	}
+	return c.Conn.Read(p)
+}
+
+func (c *routedHandshakeConn) HandshakeSuccess() error {
+	select {
+	case c.done <- nil:
+	default:
+	}
+	return nil
+}
+
+func (c *routedHandshakeConn) HandshakeFailure(err error) error {
+	select {
+	case c.done <- err:
+	default:
+	}
+	return nil
+}
+
+type routedPacket struct {
+	content     []byte
+	destination M.Socksaddr
+}
+
+// routedPacketEnd is one end of an in-memory packet pipe. The client end is used as
+// a net.PacketConn, the server end as a N.PacketConn handed to the router.
+type routedPacketEnd struct {
+	in           chan routedPacket
+	out          chan routedPacket
+	done         chan struct{}
+	closeOnce    *sync.Once
+	access       sync.Mutex
+	readDeadline time.Time
+}
+
+func newRoutedPacketPipe() (*routedPacketEnd, *routedPacketEnd) {
+	clientToServer := make(chan routedPacket, 64)
+	serverToClient := make(chan routedPacket, 64)
+	done := make(chan struct{})
+	closeOnce := new(sync.Once)
+	return &routedPacketEnd{in: serverToClient, out: clientToServer, done: done, closeOnce: closeOnce},
+		&routedPacketEnd{in: clientToServer, out: serverToClient, done: done, closeOnce: closeOnce}
+}
+
+func (e *routedPacketEnd) read() (routedPacket, error) {
+	e.access.Lock()
+	readDeadline := e.readDeadline
+	e.access.Unlock()
+	var timeout <-chan time.Time
+	if !readDeadline.IsZero() {
+		timer := time.NewTimer(time.Until(readDeadline))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case packet := <-e.in:
+		return packet, nil
+	case <-e.done:
+		return routedPacket{}, net.ErrClosed
+	case <-timeout:
+		return routedPacket{}, os.ErrDeadlineExceeded
+	}
+}
+
+func (e *routedPacketEnd) write(content []byte, destination M.Socksaddr) error {
+	select {
+	case e.out <- routedPacket{content: content, destination: destination}:
+		return nil
+	case <-e.done:
+		return net.ErrClosed
+	}
+}
+
+func (e *routedPacketEnd) ReadFrom(p []byte) (int, net.Addr, error) {
+	packet, err := e.read()
+	if err != nil {
+		return 0, nil, err
+	}
+	return copy(p, packet.content), packet.destination.UDPAddr(), nil
+}
+
+func (e *routedPacketEnd) WriteTo(p []byte, addr net.Addr) (int, error) {
+	err := e.write(append([]byte(nil), p...), M.SocksaddrFromNet(addr))
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (e *routedPacketEnd) ReadPacket(buffer *buf.Buffer) (M.Socksaddr, error) {
+	packet, err := e.read()
+	if err != nil {
+		return M.Socksaddr{}, err
+	}
+	_, err = buffer.Write(packet.content)
+	return packet.destination, err
+}
+
+func (e *routedPacketEnd) WritePacket(buffer *buf.Buffer, destination M.Socksaddr) error {
+	defer buffer.Release()
+	return e.write(append([]byte(nil), buffer.Bytes()...), destination)
+}
+
+func (e *routedPacketEnd) Close() error {
+	e.closeOnce.Do(func() {
+		close(e.done)
+	})
+	return nil
+}
+
+func (e *routedPacketEnd) LocalAddr() net.Addr {
+	return &net.UDPAddr{IP: net.IPv4zero}
+}
+
+func (e *routedPacketEnd) SetDeadline(t time.Time) error {
+	return e.SetReadDeadline(t)
+}
+
+func (e *routedPacketEnd) SetReadDeadline(t time.Time) error {
+	e.access.Lock()
+	e.readDeadline = t
+	e.access.Unlock()
+	return nil
+}
+
+func (e *routedPacketEnd) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+// routedPacketConn is a connected UDP conn over a routed packet pipe.
+type routedPacketConn struct {
+	*routedPacketEnd
+	destination M.Socksaddr
+}
+
+func (c *routedPacketConn) Read(p []byte) (int, error) {
+	n, _, err := c.ReadFrom(p)
+	return n, err
+}
+
+func (c *routedPacketConn) Write(p []byte) (int, error) {
+	err := c.write(append([]byte(nil), p...), c.destination)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *routedPacketConn) RemoteAddr() net.Addr {
+	return c.destination.UDPAddr()
+}
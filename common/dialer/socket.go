@@ -0,0 +1,51 @@
+package dialer
+
+import (
+	"strings"
+	"syscall"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common/control"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// MetadataControl returns the per-connection socket options requested by metadata,
+// or nil if there is nothing to set.
+func MetadataControl(networkManager adapter.NetworkManager, metadata *adapter.InboundContext) control.Func {
+	if metadata == nil {
+		return nil
+	}
+	var controlFunc control.Func
+	if metadata.RoutingMark != 0 {
+		controlFunc = control.Append(controlFunc, setMarkWrapper(networkManager, metadata.RoutingMark, false))
+	}
+	if metadata.TTL != 0 {
+		ttl := int(metadata.TTL)
+		controlFunc = control.Append(controlFunc, func(network, address string, conn syscall.RawConn) error {
+			return control.Raw(conn, func(fd uintptr) error {
+				err := setTTL(fd, isIPv6Network(network), ttl)
+				if err != nil {
+					return E.Cause(err, "set ttl")
+				}
+				return nil
+			})
+		})
+	}
+	if metadata.DSCP != 0 {
+		trafficClass := int(metadata.DSCP) << 2
+		controlFunc = control.Append(controlFunc, func(network, address string, conn syscall.RawConn) error {
+			return control.Raw(conn, func(fd uintptr) error {
+				err := setTrafficClass(fd, isIPv6Network(network), trafficClass)
+				if err != nil {
+					return E.Cause(err, "set dscp")
+				}
+				return nil
+			})
+		})
+	}
+	return controlFunc
+}
+
+func isIPv6Network(network string) bool {
+	return !strings.HasSuffix(network, "4") && !strings.HasPrefix(network, "ip4")
+}
@@ -0,0 +1,15 @@
+//go:build !unix && !windows
+
+package dialer
+
+import (
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+func setTTL(fd uintptr, isIPv6 bool, ttl int) error {
+	return E.New("unsupported on current platform")
+}
+
+func setTrafficClass(fd uintptr, isIPv6 bool, trafficClass int) error {
+	return E.New("unsupported on current platform")
+}
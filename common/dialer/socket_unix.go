@@ -0,0 +1,28 @@
+//go:build unix
+
+package dialer
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+func setTTL(fd uintptr, isIPv6 bool, ttl int) error {
+	return setIPOption(fd, isIPv6, unix.IP_TTL, unix.IPV6_UNICAST_HOPS, ttl)
+}
+
+func setTrafficClass(fd uintptr, isIPv6 bool, trafficClass int) error {
+	return setIPOption(fd, isIPv6, unix.IP_TOS, unix.IPV6_TCLASS, trafficClass)
+}
+
+func setIPOption(fd uintptr, isIPv6 bool, inet4Option int, inet6Option int, value int) error {
+	if !isIPv6 {
+		return unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, inet4Option, value)
+	}
+	err := unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, inet6Option, value)
+	if err != nil {
+		return err
+	}
+	// IPv4-mapped destinations on dual-stack sockets, not supported by all systems
+	_ = unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, inet4Option, value)
+	return nil
+}
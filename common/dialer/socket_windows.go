@@ -0,0 +1,24 @@
+package dialer
+
+import (
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"golang.org/x/sys/windows"
+)
+
+func setTTL(fd uintptr, isIPv6 bool, ttl int) error {
+	if !isIPv6 {
+		return windows.SetsockoptInt(windows.Handle(fd), windows.IPPROTO_IP, windows.IP_TTL, ttl)
+	}
+	err := windows.SetsockoptInt(windows.Handle(fd), windows.IPPROTO_IPV6, windows.IPV6_UNICAST_HOPS, ttl)
+	if err != nil {
+		return err
+	}
+	// IPv4-mapped destinations on dual-stack sockets
+	_ = windows.SetsockoptInt(windows.Handle(fd), windows.IPPROTO_IP, windows.IP_TTL, ttl)
+	return nil
+}
+
+func setTrafficClass(fd uintptr, isIPv6 bool, trafficClass int) error {
+	return E.New("unsupported on current platform")
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/sagernet/sing/common"
@@ -164,6 +165,30 @@ func (c *slowOpenConn) NeedHandshake() bool {
 	return c.conn.Load() == nil
 }
 
+func (c *slowOpenConn) SyscallConnForRead() syscall.RawConn {
+	conn := c.conn.Load()
+	if conn == nil {
+		select {
+		case <-c.create:
+			if c.err != nil {
+				return nil
+			}
+			conn = c.conn.Load()
+		case <-c.done:
+			return nil
+		}
+	}
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return nil
+	}
+	return rawConn
+}
+
+func (c *slowOpenConn) HandleSyscallReadError(inputErr error) ([]byte, error) {
+	return nil, inputErr
+}
+
 func (c *slowOpenConn) WriteTo(w io.Writer) (n int64, err error) {
 	conn := c.conn.Load()
 	if conn == nil {
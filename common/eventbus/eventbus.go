@@ -0,0 +1,99 @@
+package eventbus
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common"
+)
+
+const DefaultBufferSize = 256
+
+var _ adapter.EventBus = (*Bus)(nil)
+
+type Bus struct {
+	access      sync.RWMutex
+	subscribers []*subscriber
+	subscribed  map[string]int
+	dropped     atomic.Uint64
+}
+
+type subscriber struct {
+	events     chan adapter.Event
+	eventTypes []string
+	closed     bool
+}
+
+func New() *Bus {
+	return &Bus{
+		subscribed: make(map[string]int),
+	}
+}
+
+func (b *Bus) Subscribed(eventType string) bool {
+	b.access.RLock()
+	defer b.access.RUnlock()
+	return b.subscribed[""] > 0 || b.subscribed[eventType] > 0
+}
+
+func (b *Bus) Emit(event adapter.Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	b.access.RLock()
+	defer b.access.RUnlock()
+	for _, it := range b.subscribers {
+		if len(it.eventTypes) > 0 && !common.Contains(it.eventTypes, event.Type) {
+			continue
+		}
+		select {
+		case it.events <- event:
+		default:
+			b.dropped.Add(1)
+		}
+	}
+}
+
+func (b *Bus) Subscribe(bufferSize int, eventTypes ...string) (<-chan adapter.Event, func()) {
+	if bufferSize <= 0 {
+		bufferSize = DefaultBufferSize
+	}
+	it := &subscriber{
+		events:     make(chan adapter.Event, bufferSize),
+		eventTypes: eventTypes,
+	}
+	b.access.Lock()
+	b.subscribers = append(b.subscribers, it)
+	b.updateSubscribed(it, 1)
+	b.access.Unlock()
+	return it.events, func() {
+		b.access.Lock()
+		defer b.access.Unlock()
+		if it.closed {
+			return
+		}
+		it.closed = true
+		b.subscribers = common.Filter(b.subscribers, func(other *subscriber) bool {
+			return other != it
+		})
+		b.updateSubscribed(it, -1)
+		close(it.events)
+	}
+}
+
+func (b *Bus) updateSubscribed(it *subscriber, delta int) {
+	if len(it.eventTypes) == 0 {
+		b.subscribed[""] += delta
+		return
+	}
+	for _, eventType := range it.eventTypes {
+		b.subscribed[eventType] += delta
+	}
+}
+
+// Dropped returns the number of events dropped for slow subscribers.
+func (b *Bus) Dropped() uint64 {
+	return b.dropped.Load()
+}
@@ -0,0 +1,115 @@
+package eventbus
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBusFilter(t *testing.T) {
+	t.Parallel()
+	bus := New()
+	require.False(t, bus.Subscribed(adapter.EventDNSAnswered))
+	events, cancel := bus.Subscribe(4, adapter.EventDNSAnswered)
+	require.True(t, bus.Subscribed(adapter.EventDNSAnswered))
+	require.False(t, bus.Subscribed(adapter.EventConnectionOpen))
+	bus.Emit(adapter.Event{Type: adapter.EventConnectionOpen})
+	bus.Emit(adapter.Event{Type: adapter.EventDNSAnswered, Domain: "example.com"})
+	event := <-events
+	require.Equal(t, "example.com", event.Domain)
+	require.False(t, event.Time.IsZero())
+	cancel()
+	cancel()
+	_, loaded := <-events
+	require.False(t, loaded)
+	require.False(t, bus.Subscribed(adapter.EventDNSAnswered))
+}
+
+func TestBusDrop(t *testing.T) {
+	t.Parallel()
+	bus := New()
+	events, cancel := bus.Subscribe(1)
+	defer cancel()
+	require.True(t, bus.Subscribed(adapter.EventOutboundHealth))
+	bus.Emit(adapter.Event{Type: adapter.EventOutboundHealth})
+	bus.Emit(adapter.Event{Type: adapter.EventOutboundHealth})
+	require.Len(t, events, 1)
+	require.Equal(t, uint64(1), bus.Dropped())
+}
+
+func TestTaskProgress(t *testing.T) {
+	t.Parallel()
+	bus := New()
+	events, cancel := bus.Subscribe(16)
+	defer cancel()
+	task := StartTask(bus, adapter.TaskRuleSet, "geosite")
+	started := <-events
+	require.Equal(t, adapter.EventTaskStarted, started.Type)
+	require.Equal(t, "geosite", started.Tag)
+	now := task.startedAt
+	task.timeFunc = func() time.Time {
+		return now
+	}
+	reader := task.Reader(iotest.OneByteReader(bytes.NewReader(make([]byte, 3))), 3)
+	_, err := io.ReadFull(reader, make([]byte, 2))
+	require.NoError(t, err)
+	progress := <-events
+	require.Equal(t, adapter.EventTaskProgress, progress.Type)
+	require.Equal(t, int64(1), progress.Current)
+	require.Equal(t, int64(3), progress.Total)
+	require.Empty(t, events)
+	now = now.Add(taskProgressInterval)
+	_, err = io.ReadAll(reader)
+	require.NoError(t, err)
+	progress = <-events
+	require.Equal(t, int64(3), progress.Current)
+	now = now.Add(time.Second)
+	task.Finish(nil)
+	finished := <-events
+	require.Equal(t, adapter.EventTaskFinished, finished.Type)
+	require.Equal(t, adapter.TaskStateSucceeded, finished.State)
+	require.Equal(t, int64(3), finished.Current)
+	require.Equal(t, int64(1500), finished.Duration)
+}
+
+func TestTaskFinish(t *testing.T) {
+	t.Parallel()
+	bus := New()
+	events, cancel := bus.Subscribe(16, adapter.EventTaskFinished)
+	defer cancel()
+	StartTask(bus, adapter.TaskFile, "a").Finish(E.New("not found"))
+	event := <-events
+	require.Equal(t, adapter.TaskStateFailed, event.State)
+	require.Equal(t, "not found", event.Error)
+	StartTask(bus, adapter.TaskFile, "b").FinishNotModified()
+	event = <-events
+	require.Equal(t, adapter.TaskStateNotModified, event.State)
+	require.Equal(t, "b", event.Tag)
+	StartTask(nil, adapter.TaskFile, "c").Finish(nil)
+	require.Empty(t, events)
+}
+
+func TestTaskSetProgress(t *testing.T) {
+	t.Parallel()
+	bus := New()
+	events, cancel := bus.Subscribe(16, adapter.EventTaskProgress)
+	defer cancel()
+	task := StartTask(bus, adapter.TaskDrain, "")
+	now := task.startedAt
+	task.timeFunc = func() time.Time {
+		return now
+	}
+	task.Progress(3, 5)
+	progress := <-events
+	require.Equal(t, int64(3), progress.Current)
+	require.Equal(t, int64(5), progress.Total)
+	task.Progress(2, 5)
+	require.Empty(t, events)
+}
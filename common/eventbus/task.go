@@ -0,0 +1,144 @@
+package eventbus
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+)
+
+const taskProgressInterval = 500 * time.Millisecond
+
+// Task emits the events of a background task such as a rule-set download,
+// all methods do nothing if the event bus is nil.
+type Task struct {
+	bus       adapter.EventBus
+	task      string
+	tag       string
+	startedAt time.Time
+	access    sync.Mutex
+	current   int64
+	total     int64
+	emittedAt time.Time
+	timeFunc  func() time.Time
+}
+
+// StartTask emits the task_started event of a task.
+func StartTask(bus adapter.EventBus, task string, tag string) *Task {
+	t := &Task{
+		bus:      bus,
+		task:     task,
+		tag:      tag,
+		timeFunc: time.Now,
+	}
+	t.startedAt = t.timeFunc()
+	t.emit(adapter.EventTaskStarted, nil)
+	return t
+}
+
+// Reader returns a reader that emits task_progress events while reading at
+// most every 500ms, total is the expected size or -1 if unknown.
+func (t *Task) Reader(reader io.Reader, total int64) io.Reader {
+	if t.bus == nil {
+		return reader
+	}
+	t.access.Lock()
+	t.current = 0
+	t.total = max(total, 0)
+	t.access.Unlock()
+	return &taskReader{reader, t}
+}
+
+func (t *Task) progress(n int) {
+	t.access.Lock()
+	t.current += int64(n)
+	now := t.timeFunc()
+	if now.Sub(t.emittedAt) < taskProgressInterval {
+		t.access.Unlock()
+		return
+	}
+	t.emittedAt = now
+	current, total := t.current, t.total
+	t.access.Unlock()
+	t.emit(adapter.EventTaskProgress, func(event *adapter.Event) {
+		event.Current = current
+		event.Total = total
+	})
+}
+
+// Progress sets the progress of a task that does not read a resource, such
+// as the remaining connections of a drain, with the same rate limit.
+func (t *Task) Progress(current int64, total int64) {
+	t.access.Lock()
+	t.current = current
+	t.total = total
+	now := t.timeFunc()
+	if now.Sub(t.emittedAt) < taskProgressInterval {
+		t.access.Unlock()
+		return
+	}
+	t.emittedAt = now
+	t.access.Unlock()
+	t.emit(adapter.EventTaskProgress, func(event *adapter.Event) {
+		event.Current = current
+		event.Total = total
+	})
+}
+
+// Finish emits the task_finished event, failed with err if not nil.
+func (t *Task) Finish(err error) {
+	t.emit(adapter.EventTaskFinished, func(event *adapter.Event) {
+		if err != nil {
+			event.State = adapter.TaskStateFailed
+			event.Error = err.Error()
+		} else {
+			event.State = adapter.TaskStateSucceeded
+			t.access.Lock()
+			event.Current = t.current
+			event.Total = t.total
+			t.access.Unlock()
+		}
+	})
+}
+
+// FinishNotModified emits the task_finished event of a task that found the
+// resource not modified.
+func (t *Task) FinishNotModified() {
+	t.emit(adapter.EventTaskFinished, func(event *adapter.Event) {
+		event.State = adapter.TaskStateNotModified
+	})
+}
+
+func (t *Task) emit(eventType string, build func(event *adapter.Event)) {
+	if t.bus == nil || !t.bus.Subscribed(eventType) {
+		return
+	}
+	now := t.timeFunc()
+	event := adapter.Event{
+		Type: eventType,
+		Time: now,
+		Task: t.task,
+		Tag:  t.tag,
+	}
+	if eventType != adapter.EventTaskStarted {
+		event.Duration = now.Sub(t.startedAt).Milliseconds()
+	}
+	if build != nil {
+		build(&event)
+	}
+	t.bus.Emit(event)
+}
+
+type taskReader struct {
+	io.Reader
+	task *Task
+}
+
+func (r *taskReader) Read(p []byte) (n int, err error) {
+	n, err = r.Reader.Read(p)
+	if n > 0 {
+		r.task.progress(n)
+	}
+	return
+}
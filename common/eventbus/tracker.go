@@ -0,0 +1,218 @@
+package eventbus
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/bufio"
+	N "github.com/sagernet/sing/common/network"
+)
+
+var _ adapter.ConnectionTracker = (*Tracker)(nil)
+
+// Tracker emits connection events of routed connections to the bus.
+type Tracker struct {
+	bus             adapter.EventBus
+	outboundManager adapter.OutboundManager
+}
+
+func NewTracker(bus adapter.EventBus, outboundManager adapter.OutboundManager) *Tracker {
+	return &Tracker{
+		bus:             bus,
+		outboundManager: outboundManager,
+	}
+}
+
+func (t *Tracker) RoutedConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, matchedRule adapter.Rule, matchOutbound adapter.Outbound) net.Conn {
+	event, emitClose := t.routed(ctx, metadata, matchedRule, matchOutbound)
+	if !emitClose {
+		return conn
+	}
+	closer := newCloseEmitter(t.bus, event)
+	return &trackerConn{
+		ExtendedConn: bufio.NewCounterConn(conn, []N.CountFunc{closer.upload}, []N.CountFunc{closer.download}),
+		closer:       closer,
+	}
+}
+
+func (t *Tracker) RoutedPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext, matchedRule adapter.Rule, matchOutbound adapter.Outbound) N.PacketConn {
+	event, emitClose := t.routed(ctx, metadata, matchedRule, matchOutbound)
+	if !emitClose {
+		return conn
+	}
+	closer := newCloseEmitter(t.bus, event)
+	return &trackerPacketConn{
+		PacketConn: bufio.NewCounterPacketConn(conn, []N.CountFunc{closer.upload}, []N.CountFunc{closer.download}),
+		closer:     closer,
+	}
+}
+
+func (t *Tracker) routed(ctx context.Context, metadata adapter.InboundContext, matchedRule adapter.Rule, matchOutbound adapter.Outbound) (adapter.Event, bool) {
+	var (
+		openSubscribed     = t.bus.Subscribed(adapter.EventConnectionOpen)
+		ruleSubscribed     = matchedRule != nil && t.bus.Subscribed(adapter.EventRuleMatched)
+		outboundSubscribed = t.bus.Subscribed(adapter.EventOutboundSelected)
+		closeSubscribed    = t.bus.Subscribed(adapter.EventConnectionClosed)
+	)
+	if !openSubscribed && !ruleSubscribed && !outboundSubscribed && !closeSubscribed {
+		return adapter.Event{}, false
+	}
+	event := adapter.Event{
+		Network:     metadata.Network,
+		Inbound:     metadata.Inbound,
+		InboundType: metadata.InboundType,
+		User:        metadata.User,
+		Protocol:    metadata.Protocol,
+	}
+	if id, loaded := log.IDFromContext(ctx); loaded {
+		event.ConnectionID = id.ID
+	}
+	if metadata.Source.IsValid() {
+		event.Source = metadata.Source.String()
+	}
+	if metadata.Destination.IsValid() {
+		event.Destination = metadata.Destination.String()
+	}
+	if metadata.Destination.Fqdn != "" {
+		event.Domain = metadata.Destination.Fqdn
+	} else {
+		event.Domain = metadata.Domain
+	}
+	if matchedRule != nil {
+		event.Rule = matchedRule.String()
+		event.Action = matchedRule.Action().Type()
+	}
+	event.Outbound, event.Chain = t.resolveChain(matchOutbound)
+	if openSubscribed {
+		t.emit(event, adapter.EventConnectionOpen)
+	}
+	if ruleSubscribed {
+		t.emit(event, adapter.EventRuleMatched)
+	}
+	if outboundSubscribed {
+		t.emit(event, adapter.EventOutboundSelected)
+	}
+	return event, closeSubscribed
+}
+
+func (t *Tracker) resolveChain(matchOutbound adapter.Outbound) (string, []string) {
+	var (
+		chain    []string
+		next     string
+		outbound string
+	)
+	if matchOutbound != nil {
+		next = matchOutbound.Tag()
+	} else if defaultOutbound := t.outboundManager.Default(); defaultOutbound != nil {
+		next = defaultOutbound.Tag()
+	}
+	for next != "" && !common.Contains(chain, next) {
+		detour, loaded := t.outboundManager.Outbound(next)
+		if !loaded {
+			break
+		}
+		chain = append(chain, next)
+		outbound = detour.Tag()
+		group, isGroup := detour.(adapter.OutboundGroup)
+		if !isGroup {
+			break
+		}
+		next = group.Now()
+	}
+	return outbound, chain
+}
+
+func (t *Tracker) emit(event adapter.Event, eventType string) {
+	event.Type = eventType
+	event.Time = time.Now()
+	t.bus.Emit(event)
+}
+
+type closeEmitter struct {
+	bus       adapter.EventBus
+	event     adapter.Event
+	createdAt time.Time
+	uploaded  atomic.Int64
+	received  atomic.Int64
+	closeOnce sync.Once
+}
+
+func newCloseEmitter(bus adapter.EventBus, event adapter.Event) *closeEmitter {
+	return &closeEmitter{
+		bus:       bus,
+		event:     event,
+		createdAt: time.Now(),
+	}
+}
+
+func (e *closeEmitter) upload(n int64) {
+	e.uploaded.Add(n)
+}
+
+func (e *closeEmitter) download(n int64) {
+	e.received.Add(n)
+}
+
+func (e *closeEmitter) close() {
+	e.closeOnce.Do(func() {
+		event := e.event
+		event.Type = adapter.EventConnectionClosed
+		event.Time = time.Now()
+		event.Upload = e.uploaded.Load()
+		event.Download = e.received.Load()
+		event.Duration = event.Time.Sub(e.createdAt).Milliseconds()
+		e.bus.Emit(event)
+	})
+}
+
+type trackerConn struct {
+	N.ExtendedConn
+	closer *closeEmitter
+}
+
+func (c *trackerConn) Close() error {
+	err := c.ExtendedConn.Close()
+	c.closer.close()
+	return err
+}
+
+func (c *trackerConn) Upstream() any {
+	return c.ExtendedConn
+}
+
+func (c *trackerConn) ReaderReplaceable() bool {
+	return true
+}
+
+func (c *trackerConn) WriterReplaceable() bool {
+	return true
+}
+
+type trackerPacketConn struct {
+	N.PacketConn
+	closer *closeEmitter
+}
+
+func (c *trackerPacketConn) Close() error {
+	err := c.PacketConn.Close()
+	c.closer.close()
+	return err
+}
+
+func (c *trackerPacketConn) Upstream() any {
+	return c.PacketConn
+}
+
+func (c *trackerPacketConn) ReaderReplaceable() bool {
+	return true
+}
+
+func (c *trackerPacketConn) WriterReplaceable() bool {
+	return true
+}
@@ -0,0 +1,214 @@
+package fallback
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/sagernet/sing-box/common/dialer"
+	"github.com/sagernet/sing-box/common/pipelistener"
+	"github.com/sagernet/sing-box/common/tls"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"golang.org/x/net/http2"
+)
+
+var defaultIndex = []string{"index.html"}
+
+// Server is a minimal static file server or HTTP reverse proxy, serving
+// fallback connections of TLS inbounds in place of a separate web server.
+type Server struct {
+	ctx        context.Context
+	logger     logger.ContextLogger
+	root       http.FileSystem
+	index      []string
+	proxy      *httputil.ReverseProxy
+	headers    http.Header
+	listener   *pipelistener.Listener
+	httpServer *http.Server
+	h2Server   *http2.Server
+}
+
+func NewServer(ctx context.Context, logger logger.ContextLogger, options option.FallbackHTTPOptions) (*Server, error) {
+	server := &Server{
+		ctx:      ctx,
+		logger:   logger,
+		headers:  options.Headers.Build(),
+		listener: pipelistener.New(16),
+		h2Server: &http2.Server{},
+	}
+	switch {
+	case options.Root != "" && options.Proxy != "":
+		return nil, E.New("`root` is conflict with `proxy`")
+	case options.Root != "":
+		server.root = http.Dir(options.Root)
+		server.index = options.Index
+		if len(server.index) == 0 {
+			server.index = defaultIndex
+		}
+	case options.Proxy != "":
+		proxyURL, err := url.Parse(options.Proxy)
+		if err != nil {
+			return nil, E.Cause(err, "parse proxy URL")
+		}
+		if proxyURL.Scheme != "http" && proxyURL.Scheme != "https" {
+			return nil, E.New("unsupported proxy URL scheme: ", proxyURL.Scheme)
+		}
+		proxyDialer, err := dialer.NewDefault(ctx, option.DialerOptions{})
+		if err != nil {
+			return nil, err
+		}
+		server.proxy = &httputil.ReverseProxy{
+			Rewrite: func(request *httputil.ProxyRequest) {
+				request.SetURL(proxyURL)
+			},
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+					return proxyDialer.DialContext(ctx, network, M.ParseSocksaddr(address))
+				},
+				ForceAttemptHTTP2: true,
+			},
+			ModifyResponse: func(response *http.Response) error {
+				for key, values := range server.headers {
+					response.Header[key] = values
+				}
+				return nil
+			},
+			ErrorHandler: func(writer http.ResponseWriter, request *http.Request, err error) {
+				logger.DebugContext(request.Context(), E.Cause(err, "fallback proxy"))
+				writer.WriteHeader(http.StatusBadGateway)
+			},
+		}
+	default:
+		return nil, E.New("missing `root` or `proxy`")
+	}
+	server.httpServer = &http.Server{
+		Handler:           server,
+		ReadHeaderTimeout: C.TCPTimeout,
+		MaxHeaderBytes:    http.DefaultMaxHeaderBytes,
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
+		ConnContext: func(ctx context.Context, c net.Conn) context.Context {
+			return log.ContextWithNewID(ctx)
+		},
+	}
+	return server, nil
+}
+
+func (s *Server) Start() error {
+	go func() {
+		err := s.httpServer.Serve(s.listener)
+		if err != nil && !E.IsClosed(err) && err != http.ErrServerClosed {
+			s.logger.Error("fallback server serve error: ", err)
+		}
+	}()
+	return nil
+}
+
+func (s *Server) Close() error {
+	return common.Close(common.PtrOrNil(s.httpServer))
+}
+
+// NewConnectionEx serves the connection, with HTTP/2 if negotiated by TLS.
+func (s *Server) NewConnectionEx(ctx context.Context, conn net.Conn, source M.Socksaddr, onClose N.CloseHandlerFunc) {
+	if tlsConn, loaded := common.Cast[tls.Conn](conn); loaded && tlsConn.ConnectionState().NegotiatedProtocol == http2.NextProtoTLS {
+		s.h2Server.ServeConn(conn, &http2.ServeConnOpts{
+			Context: log.ContextWithNewID(s.ctx),
+			Handler: s,
+		})
+		conn.Close()
+		if onClose != nil {
+			onClose(nil)
+		}
+		return
+	}
+	s.listener.Serve(&closeNotifyConn{Conn: conn, onClose: onClose})
+}
+
+func (s *Server) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	s.logger.DebugContext(request.Context(), "fallback request ", request.Method, " ", request.Host, request.URL.Path, " from ", request.RemoteAddr)
+	if s.proxy != nil {
+		s.proxy.ServeHTTP(writer, request)
+		return
+	}
+	for key, values := range s.headers {
+		writer.Header()[key] = values
+	}
+	if request.Method != http.MethodGet && request.Method != http.MethodHead {
+		writer.Header().Set("Allow", "GET, HEAD")
+		http.Error(writer, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	name := path.Clean("/" + request.URL.Path)
+	file, stat, err := s.open(name)
+	if err == nil && stat.IsDir() {
+		file.Close()
+		if !strings.HasSuffix(request.URL.Path, "/") {
+			http.Redirect(writer, request, path.Base(name)+"/", http.StatusMovedPermanently)
+			return
+		}
+		err = os.ErrNotExist
+		for _, index := range s.index {
+			file, stat, err = s.open(path.Join(name, index))
+			if err == nil && !stat.IsDir() {
+				break
+			} else if err == nil {
+				file.Close()
+				err = os.ErrNotExist
+			}
+		}
+	}
+	if err != nil {
+		http.NotFound(writer, request)
+		return
+	}
+	defer file.Close()
+	http.ServeContent(writer, request, stat.Name(), stat.ModTime(), file)
+}
+
+func (s *Server) open(name string) (http.File, os.FileInfo, error) {
+	file, err := s.root.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	return file, stat, nil
+}
+
+type closeNotifyConn struct {
+	net.Conn
+	onClose   N.CloseHandlerFunc
+	closeOnce sync.Once
+}
+
+func (c *closeNotifyConn) Close() error {
+	err := c.Conn.Close()
+	c.closeOnce.Do(func() {
+		if c.onClose != nil {
+			c.onClose(nil)
+		}
+	})
+	return err
+}
+
+func (c *closeNotifyConn) Upstream() any {
+	return c.Conn
+}
@@ -0,0 +1,25 @@
+//go:build with_embedded_geo
+
+package geodata
+
+import (
+	"embed"
+	"path"
+)
+
+//go:embed data
+var embedded embed.FS
+
+const Enabled = true
+
+// Load returns the embedded rule-set with the file name.
+func Load(name string) ([]byte, bool) {
+	if name == "" || name != path.Base(name) {
+		return nil, false
+	}
+	content, err := embedded.ReadFile(path.Join("data", name))
+	if err != nil {
+		return nil, false
+	}
+	return content, true
+}
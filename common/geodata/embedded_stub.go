@@ -0,0 +1,9 @@
+//go:build !with_embedded_geo
+
+package geodata
+
+const Enabled = false
+
+func Load(name string) ([]byte, bool) {
+	return nil, false
+}
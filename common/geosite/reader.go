@@ -2,12 +2,13 @@ package geosite
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/binary"
 	"io"
-	"os"
 	"sync"
 	"sync/atomic"
 
+	"github.com/sagernet/sing-box/common/mmap"
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/varbin"
 )
@@ -19,18 +20,28 @@ type Reader struct {
 	metadataIndex  int64
 	domainIndex    map[string]int
 	domainLength   map[string]int
+	file           *mmap.File
 }
 
 func Open(path string) (*Reader, []string, error) {
-	content, err := os.Open(path)
+	file, err := mmap.Open(path)
 	if err != nil {
 		return nil, nil, err
 	}
-	reader, codes, err := NewReader(content)
+	var (
+		reader *Reader
+		codes  []string
+	)
+	err = file.Access(func(content []byte) error {
+		var readErr error
+		reader, codes, readErr = NewReader(bytes.NewReader(content))
+		return readErr
+	})
 	if err != nil {
-		content.Close()
+		file.Close()
 		return nil, nil, err
 	}
+	reader.file = file
 	return reader, codes, nil
 }
 
@@ -101,7 +112,18 @@ func (r *Reader) readMetadata() error {
 	return nil
 }
 
-func (r *Reader) Read(code string) ([]Item, error) {
+func (r *Reader) Read(code string) (itemList []Item, err error) {
+	if r.file != nil {
+		err = r.file.Access(func(content []byte) error {
+			itemList, err = r.read(code)
+			return err
+		})
+		return
+	}
+	return r.read(code)
+}
+
+func (r *Reader) read(code string) ([]Item, error) {
 	index, exists := r.domainIndex[code]
 	if !exists {
 		return nil, E.New("code ", code, " not exists!")
@@ -123,6 +145,13 @@ func (r *Reader) Upstream() any {
 	return r.reader
 }
 
+func (r *Reader) Close() error {
+	if r.file != nil {
+		return r.file.Close()
+	}
+	return nil
+}
+
 type readCounter struct {
 	io.Reader
 	count int64
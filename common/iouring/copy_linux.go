@@ -0,0 +1,121 @@
+package iouring
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/sagernet/sing/common/buf"
+	N "github.com/sagernet/sing/common/network"
+
+	"golang.org/x/sys/unix"
+)
+
+var aLongTimeAgo = time.Unix(1, 0)
+
+func (r *Ring) Copy(destination *net.TCPConn, source *net.TCPConn, readCounters []N.CountFunc, writeCounters []N.CountFunc) (n int64, err error) {
+	sourceConn, err := source.SyscallConn()
+	if err != nil {
+		return
+	}
+	destinationConn, err := destination.SyscallConn()
+	if err != nil {
+		return
+	}
+	sourceFd, err := dupFd(sourceConn)
+	if err != nil {
+		return
+	}
+	defer unix.Close(sourceFd)
+	destinationFd, err := dupFd(destinationConn)
+	if err != nil {
+		return
+	}
+	defer unix.Close(destinationFd)
+	var c canceler
+	stop := r.watchClose(&c, source, destination, sourceConn, destinationConn)
+	defer stop()
+	buffer := buf.Get(buf.BufferSize)
+	defer buf.Put(buffer)
+	for {
+		var readN int
+		readN, err = r.execute(opRecv, sourceFd, buffer, 0, &c)
+		if err != nil {
+			if errors.Is(err, unix.ECANCELED) {
+				err = net.ErrClosed
+			}
+			return
+		}
+		if readN == 0 {
+			return n, nil
+		}
+		for _, counter := range readCounters {
+			counter(int64(readN))
+		}
+		for written := 0; written < readN; {
+			var writeN int
+			writeN, err = r.execute(opSend, destinationFd, buffer[written:readN], 0, &c)
+			if err != nil {
+				if errors.Is(err, unix.ECANCELED) {
+					err = net.ErrClosed
+				}
+				return
+			}
+			if writeN == 0 {
+				return n, io.ErrShortWrite
+			}
+			written += writeN
+			n += int64(writeN)
+			for _, counter := range writeCounters {
+				counter(int64(writeN))
+			}
+		}
+	}
+}
+
+// watchClose cancels the operations of the relay once either socket is
+// closed through the Go runtime, which the duplicated descriptors in the ring
+// do not observe. The waiters park in the runtime poller, which wakes them on
+// close, and the returned function stops them by expiring their deadlines.
+func (r *Ring) watchClose(c *canceler, source *net.TCPConn, destination *net.TCPConn, sourceConn syscall.RawConn, destinationConn syscall.RawConn) (stop func()) {
+	var (
+		stopped atomic.Bool
+		wg      sync.WaitGroup
+	)
+	wait := func(waitFunc func(func(uintptr) bool) error) {
+		defer wg.Done()
+		err := waitFunc(func(uintptr) bool {
+			return stopped.Load()
+		})
+		if errors.Is(err, net.ErrClosed) && !stopped.Load() {
+			r.cancel(c)
+		}
+	}
+	wg.Add(2)
+	go wait(sourceConn.Read)
+	go wait(destinationConn.Write)
+	return func() {
+		stopped.Store(true)
+		source.SetReadDeadline(aLongTimeAgo)
+		destination.SetWriteDeadline(aLongTimeAgo)
+		wg.Wait()
+		source.SetReadDeadline(time.Time{})
+		destination.SetWriteDeadline(time.Time{})
+	}
+}
+
+// dupFd duplicates the socket so that its descriptor can not be reused
+// while operations are still queued in the ring.
+func dupFd(conn syscall.RawConn) (newFd int, err error) {
+	controlErr := conn.Control(func(fd uintptr) {
+		newFd, err = unix.FcntlInt(fd, unix.F_DUPFD_CLOEXEC, 0)
+	})
+	if controlErr != nil {
+		return -1, controlErr
+	}
+	return
+}
@@ -0,0 +1,180 @@
+package iouring
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/sagernet/sing/common/bufio"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTCPPair(t testing.TB) (*net.TCPConn, *net.TCPConn) {
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer listener.Close()
+	accepted := make(chan *net.TCPConn, 1)
+	go func() {
+		conn, _ := listener.AcceptTCP()
+		accepted <- conn
+	}()
+	client, err := net.DialTCP("tcp", nil, listener.Addr().(*net.TCPAddr))
+	require.NoError(t, err)
+	server := <-accepted
+	require.NotNil(t, server)
+	return client, server
+}
+
+func newTestRing(t testing.TB) *Ring {
+	ring, err := New(0)
+	if err != nil {
+		t.Skip("io_uring unavailable: ", err)
+	}
+	t.Cleanup(func() {
+		ring.Close()
+	})
+	return ring
+}
+
+func TestCopy(t *testing.T) {
+	t.Parallel()
+	ring := newTestRing(t)
+	sourceClient, sourceServer := newTCPPair(t)
+	destinationClient, destinationServer := newTCPPair(t)
+	defer sourceClient.Close()
+	defer destinationServer.Close()
+	payload := make([]byte, 4*1024*1024)
+	_, err := rand.Read(payload)
+	require.NoError(t, err)
+	go func() {
+		sourceClient.Write(payload)
+		sourceClient.CloseWrite()
+	}()
+	received := make(chan []byte, 1)
+	go func() {
+		content, _ := io.ReadAll(destinationServer)
+		received <- content
+	}()
+	var readCount, writeCount int64
+	n, err := ring.Copy(destinationClient, sourceServer, []N.CountFunc{func(n int64) { readCount += n }}, []N.CountFunc{func(n int64) { writeCount += n }})
+	require.NoError(t, err)
+	require.Equal(t, int64(len(payload)), n)
+	require.Equal(t, n, readCount)
+	require.Equal(t, n, writeCount)
+	destinationClient.CloseWrite()
+	require.True(t, bytes.Equal(payload, <-received))
+	sourceServer.Close()
+	destinationClient.Close()
+}
+
+func TestCopyClosed(t *testing.T) {
+	t.Parallel()
+	ring := newTestRing(t)
+	sourceClient, sourceServer := newTCPPair(t)
+	destinationClient, destinationServer := newTCPPair(t)
+	defer sourceClient.Close()
+	defer destinationServer.Close()
+	result := make(chan error, 1)
+	go func() {
+		_, err := ring.Copy(destinationClient, sourceServer, nil, nil)
+		result <- err
+	}()
+	sourceServer.Close()
+	require.ErrorIs(t, <-result, net.ErrClosed)
+	destinationClient.Close()
+}
+
+func TestCopyClosedDestination(t *testing.T) {
+	t.Parallel()
+	ring := newTestRing(t)
+	sourceClient, sourceServer := newTCPPair(t)
+	destinationClient, destinationServer := newTCPPair(t)
+	defer sourceClient.Close()
+	defer destinationServer.Close()
+	result := make(chan error, 1)
+	go func() {
+		_, err := ring.Copy(destinationClient, sourceServer, nil, nil)
+		result <- err
+	}()
+	destinationClient.Close()
+	require.ErrorIs(t, <-result, net.ErrClosed)
+	sourceServer.Close()
+}
+
+func TestCopySmallRing(t *testing.T) {
+	t.Parallel()
+	ring, err := New(2)
+	if err != nil {
+		t.Skip("io_uring unavailable: ", err)
+	}
+	defer ring.Close()
+	const relays = 16
+	payload := make([]byte, 256*1024)
+	_, err = rand.Read(payload)
+	require.NoError(t, err)
+	results := make(chan error, relays)
+	for i := 0; i < relays; i++ {
+		sourceClient, sourceServer := newTCPPair(t)
+		destinationClient, destinationServer := newTCPPair(t)
+		go func() {
+			sourceClient.Write(payload)
+			sourceClient.Close()
+		}()
+		go func() {
+			_, copyErr := ring.Copy(destinationClient, sourceServer, nil, nil)
+			sourceServer.Close()
+			destinationClient.Close()
+			results <- copyErr
+		}()
+		go func() {
+			content, _ := io.ReadAll(destinationServer)
+			destinationServer.Close()
+			if !bytes.Equal(payload, content) {
+				results <- io.ErrUnexpectedEOF
+			}
+		}()
+	}
+	for i := 0; i < relays; i++ {
+		require.NoError(t, <-results)
+	}
+}
+
+func BenchmarkCopy(b *testing.B) {
+	b.Run("standard", func(b *testing.B) {
+		benchmarkCopy(b, func(destination *net.TCPConn, source *net.TCPConn) (int64, error) {
+			return bufio.Copy(destination, source)
+		})
+	})
+	b.Run("io_uring", func(b *testing.B) {
+		ring := newTestRing(b)
+		benchmarkCopy(b, func(destination *net.TCPConn, source *net.TCPConn) (int64, error) {
+			return ring.Copy(destination, source, nil, nil)
+		})
+	})
+}
+
+func benchmarkCopy(b *testing.B, copyFunc func(destination *net.TCPConn, source *net.TCPConn) (int64, error)) {
+	const chunkSize = 64 * 1024
+	sourceClient, sourceServer := newTCPPair(b)
+	destinationClient, destinationServer := newTCPPair(b)
+	defer destinationServer.Close()
+	go copyFunc(destinationClient, sourceServer)
+	go io.Copy(io.Discard, destinationServer)
+	chunk := make([]byte, chunkSize)
+	b.SetBytes(chunkSize)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := sourceClient.Write(chunk)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+	sourceClient.Close()
+	sourceServer.Close()
+	destinationClient.Close()
+}
@@ -0,0 +1,411 @@
+package iouring
+
+import (
+	"net"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
+
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	opNop         = 0
+	opAsyncCancel = 14
+	opLinkTimeout = 15
+	opSend        = 26
+	opRecv        = 27
+
+	sqeIOLink = 1 << 2
+
+	registerEventFd = 4
+
+	offSQRing = 0
+	offCQRing = 0x8000000
+	offSQEs   = 0x10000000
+
+	featSingleMMap = 1 << 0
+
+	sqeSize = 64
+	cqeSize = 16
+
+	userDataWakeup  = 0
+	userDataTimeout = 1
+
+	DefaultEntries = 256
+)
+
+type sqRingOffsets struct {
+	head        uint32
+	tail        uint32
+	ringMask    uint32
+	ringEntries uint32
+	flags       uint32
+	dropped     uint32
+	array       uint32
+	resv1       uint32
+	userAddr    uint64
+}
+
+type cqRingOffsets struct {
+	head        uint32
+	tail        uint32
+	ringMask    uint32
+	ringEntries uint32
+	overflow    uint32
+	cqes        uint32
+	flags       uint32
+	resv1       uint32
+	userAddr    uint64
+}
+
+type params struct {
+	sqEntries    uint32
+	cqEntries    uint32
+	flags        uint32
+	sqThreadCPU  uint32
+	sqThreadIdle uint32
+	features     uint32
+	wqFd         uint32
+	resv         [3]uint32
+	sqOff        sqRingOffsets
+	cqOff        cqRingOffsets
+}
+
+type sqe struct {
+	opcode      uint8
+	flags       uint8
+	ioprio      uint16
+	fd          int32
+	off         uint64
+	addr        uint64
+	len         uint32
+	opFlags     uint32
+	userData    uint64
+	bufIndex    uint16
+	personality uint16
+	spliceFdIn  int32
+	addr3       uint64
+	_           uint64
+}
+
+type timespec struct {
+	sec  int64
+	nsec int64
+}
+
+type operation struct {
+	done    chan int32
+	timeout timespec
+}
+
+// canceler cancels the operations of a relay once either of its sockets is
+// closed through the Go runtime. It is guarded by the access lock of the ring.
+type canceler struct {
+	closed  bool
+	current uint64
+}
+
+// Ring is a shared io_uring instance. Submissions are serialized, and a
+// single reaper goroutine dispatches completions to the waiting operations.
+type Ring struct {
+	fd        int
+	ringMem   []byte
+	cqMem     []byte
+	sqeMem    []byte
+	sqHead    *uint32
+	sqTail    *uint32
+	sqMask    uint32
+	sqArray   unsafe.Pointer
+	sqes      unsafe.Pointer
+	cqHead    *uint32
+	cqTail    *uint32
+	cqMask    uint32
+	cqes      unsafe.Pointer
+	access    sync.Mutex
+	reaped    *sync.Cond
+	nextID    uint64
+	pending   map[uint64]*operation
+	closed    bool
+	eventFile *os.File
+	reaperWg  sync.WaitGroup
+}
+
+func New(entries uint32) (*Ring, error) {
+	if entries == 0 {
+		entries = DefaultEntries
+	}
+	var p params
+	fd, _, errno := unix.Syscall(unix.SYS_IO_URING_SETUP, uintptr(entries), uintptr(unsafe.Pointer(&p)), 0)
+	if errno != 0 {
+		return nil, os.NewSyscallError("io_uring_setup", errno)
+	}
+	r := &Ring{
+		fd:      int(fd),
+		nextID:  userDataTimeout + 1,
+		pending: make(map[uint64]*operation),
+	}
+	r.reaped = sync.NewCond(&r.access)
+	err := r.mmap(&p)
+	if err == nil {
+		err = r.registerEventFd()
+	}
+	if err != nil {
+		r.unmap()
+		return nil, err
+	}
+	r.reaperWg.Add(1)
+	go r.loopCompletion()
+	return r, nil
+}
+
+func (r *Ring) mmap(p *params) error {
+	sqSize := int(p.sqOff.array + p.sqEntries*4)
+	cqSize := int(p.cqOff.cqes + p.cqEntries*cqeSize)
+	if p.features&featSingleMMap != 0 && cqSize > sqSize {
+		sqSize = cqSize
+	}
+	var err error
+	r.ringMem, err = unix.Mmap(r.fd, offSQRing, sqSize, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED|unix.MAP_POPULATE)
+	if err != nil {
+		return E.Cause(err, "mmap submission queue")
+	}
+	if p.features&featSingleMMap != 0 {
+		r.cqMem = r.ringMem
+	} else {
+		r.cqMem, err = unix.Mmap(r.fd, offCQRing, cqSize, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED|unix.MAP_POPULATE)
+		if err != nil {
+			return E.Cause(err, "mmap completion queue")
+		}
+	}
+	r.sqeMem, err = unix.Mmap(r.fd, offSQEs, int(p.sqEntries*sqeSize), unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED|unix.MAP_POPULATE)
+	if err != nil {
+		return E.Cause(err, "mmap submission entries")
+	}
+	sqBase := unsafe.Pointer(&r.ringMem[0])
+	r.sqHead = (*uint32)(unsafe.Add(sqBase, p.sqOff.head))
+	r.sqTail = (*uint32)(unsafe.Add(sqBase, p.sqOff.tail))
+	r.sqMask = *(*uint32)(unsafe.Add(sqBase, p.sqOff.ringMask))
+	r.sqArray = unsafe.Add(sqBase, p.sqOff.array)
+	r.sqes = unsafe.Pointer(&r.sqeMem[0])
+	cqBase := unsafe.Pointer(&r.cqMem[0])
+	r.cqHead = (*uint32)(unsafe.Add(cqBase, p.cqOff.head))
+	r.cqTail = (*uint32)(unsafe.Add(cqBase, p.cqOff.tail))
+	r.cqMask = *(*uint32)(unsafe.Add(cqBase, p.cqOff.ringMask))
+	r.cqes = unsafe.Add(cqBase, p.cqOff.cqes)
+	return nil
+}
+
+// registerEventFd lets completions wake the reaper through the runtime
+// poller instead of a thread blocked in io_uring_enter.
+func (r *Ring) registerEventFd() error {
+	eventFd, err := unix.Eventfd(0, unix.EFD_NONBLOCK|unix.EFD_CLOEXEC)
+	if err != nil {
+		return os.NewSyscallError("eventfd", err)
+	}
+	_, _, errno := unix.Syscall6(unix.SYS_IO_URING_REGISTER, uintptr(r.fd), registerEventFd, uintptr(unsafe.Pointer(&eventFd)), 1, 0, 0)
+	if errno != 0 {
+		unix.Close(eventFd)
+		return os.NewSyscallError("io_uring_register", errno)
+	}
+	r.eventFile = os.NewFile(uintptr(eventFd), "io_uring")
+	return nil
+}
+
+func (r *Ring) unmap() {
+	if r.eventFile != nil {
+		r.eventFile.Close()
+	}
+	if r.sqeMem != nil {
+		unix.Munmap(r.sqeMem)
+	}
+	if r.cqMem != nil && len(r.ringMem) > 0 && &r.cqMem[0] != &r.ringMem[0] {
+		unix.Munmap(r.cqMem)
+	}
+	if r.ringMem != nil {
+		unix.Munmap(r.ringMem)
+	}
+	unix.Close(r.fd)
+}
+
+// Close stops accepting new operations. The ring is released once all
+// in-flight operations have completed.
+func (r *Ring) Close() error {
+	r.access.Lock()
+	if r.closed {
+		r.access.Unlock()
+		return nil
+	}
+	r.closed = true
+	err := r.submit(sqe{opcode: opNop, userData: userDataWakeup})
+	r.access.Unlock()
+	if err != nil {
+		return err
+	}
+	r.reaperWg.Wait()
+	return nil
+}
+
+func (r *Ring) loopCompletion() {
+	defer r.reaperWg.Done()
+	defer r.unmap()
+	var counter [8]byte
+	for {
+		_, err := r.eventFile.Read(counter[:])
+		if err != nil {
+			return
+		}
+		r.access.Lock()
+		r.reap()
+		r.reaped.Broadcast()
+		exit := r.closed && len(r.pending) == 0
+		r.access.Unlock()
+		if exit {
+			return
+		}
+	}
+}
+
+// reap dispatches the available completions and reports whether there were any.
+func (r *Ring) reap() bool {
+	head := atomic.LoadUint32(r.cqHead)
+	tail := atomic.LoadUint32(r.cqTail)
+	if head == tail {
+		return false
+	}
+	for ; head != tail; head++ {
+		cqe := unsafe.Add(r.cqes, uintptr(head&r.cqMask)*cqeSize)
+		userData := *(*uint64)(cqe)
+		result := *(*int32)(unsafe.Add(cqe, 8))
+		if userData <= userDataTimeout {
+			continue
+		}
+		op, loaded := r.pending[userData]
+		if loaded {
+			delete(r.pending, userData)
+			op.done <- result
+		}
+	}
+	atomic.StoreUint32(r.cqHead, head)
+	return true
+}
+
+// submit queues the entries and enters them into the kernel, called with the
+// access lock held. If the kernel is out of completion space, entries stay
+// queued and submit waits for the reaper to drain completions, releasing the
+// lock meanwhile, and the next successful enter submits them too.
+func (r *Ring) submit(entries ...sqe) error {
+	for atomic.LoadUint32(r.sqTail)-atomic.LoadUint32(r.sqHead)+uint32(len(entries)) > r.sqMask+1 {
+		r.reaped.Wait()
+	}
+	tail := atomic.LoadUint32(r.sqTail)
+	for _, entry := range entries {
+		index := tail & r.sqMask
+		*(*sqe)(unsafe.Add(r.sqes, uintptr(index)*sqeSize)) = entry
+		*(*uint32)(unsafe.Add(r.sqArray, uintptr(index)*4)) = index
+		tail++
+	}
+	atomic.StoreUint32(r.sqTail, tail)
+	for {
+		queued := atomic.LoadUint32(r.sqTail) - atomic.LoadUint32(r.sqHead)
+		if queued == 0 {
+			return nil
+		}
+		_, _, errno := unix.Syscall6(unix.SYS_IO_URING_ENTER, uintptr(r.fd), uintptr(queued), 0, 0, 0, 0)
+		switch errno {
+		case 0:
+			r.reap()
+			return nil
+		case unix.EINTR:
+		case unix.EAGAIN, unix.EBUSY:
+			if !r.reap() {
+				r.reaped.Wait()
+			}
+		default:
+			return os.NewSyscallError("io_uring_enter", errno)
+		}
+	}
+}
+
+// execute submits a send or receive on fd and waits for its completion.
+// A linked timeout is attached when timeout is non-zero, in which case
+// expiration is reported as ECANCELED, as is cancellation through c.
+func (r *Ring) execute(opcode uint8, fd int, buffer []byte, timeout time.Duration, c *canceler) (int, error) {
+	op := &operation{done: make(chan int32, 1)}
+	entry := sqe{
+		opcode: opcode,
+		fd:     int32(fd),
+		addr:   uint64(uintptr(unsafe.Pointer(unsafe.SliceData(buffer)))),
+		len:    uint32(len(buffer)),
+	}
+	if opcode == opSend {
+		entry.opFlags = unix.MSG_NOSIGNAL
+	}
+	r.access.Lock()
+	if r.closed {
+		r.access.Unlock()
+		return 0, os.ErrClosed
+	}
+	if c != nil && c.closed {
+		r.access.Unlock()
+		return 0, net.ErrClosed
+	}
+	entry.userData = r.nextID
+	r.nextID++
+	r.pending[entry.userData] = op
+	var err error
+	if timeout > 0 {
+		op.timeout = timespec{sec: int64(timeout / time.Second), nsec: int64(timeout % time.Second)}
+		entry.flags = sqeIOLink
+		err = r.submit(entry, sqe{
+			opcode:   opLinkTimeout,
+			fd:       -1,
+			addr:     uint64(uintptr(unsafe.Pointer(&op.timeout))),
+			len:      1,
+			userData: userDataTimeout,
+		})
+	} else {
+		err = r.submit(entry)
+	}
+	if err != nil {
+		delete(r.pending, entry.userData)
+		r.access.Unlock()
+		return 0, err
+	}
+	if c != nil {
+		c.current = entry.userData
+	}
+	r.access.Unlock()
+	result := <-op.done
+	runtime.KeepAlive(buffer)
+	runtime.KeepAlive(op)
+	if result < 0 {
+		return 0, syscall.Errno(-result)
+	}
+	return int(result), nil
+}
+
+// cancel marks c as closed and cancels its operation in flight, if any.
+func (r *Ring) cancel(c *canceler) error {
+	r.access.Lock()
+	defer r.access.Unlock()
+	c.closed = true
+	if _, loaded := r.pending[c.current]; !loaded {
+		return nil
+	}
+	return r.submit(sqe{opcode: opAsyncCancel, fd: -1, addr: c.current, userData: userDataTimeout})
+}
+
+func (r *Ring) Recv(fd int, buffer []byte, timeout time.Duration) (int, error) {
+	return r.execute(opRecv, fd, buffer, timeout, nil)
+}
+
+func (r *Ring) Send(fd int, buffer []byte, timeout time.Duration) (int, error) {
+	return r.execute(opSend, fd, buffer, timeout, nil)
+}
@@ -0,0 +1,35 @@
+//go:build !linux
+
+package iouring
+
+import (
+	"net"
+	"os"
+	"time"
+
+	N "github.com/sagernet/sing/common/network"
+)
+
+const DefaultEntries = 256
+
+type Ring struct{}
+
+func New(entries uint32) (*Ring, error) {
+	return nil, os.ErrInvalid
+}
+
+func (r *Ring) Close() error {
+	return os.ErrInvalid
+}
+
+func (r *Ring) Recv(fd int, buffer []byte, timeout time.Duration) (int, error) {
+	return 0, os.ErrInvalid
+}
+
+func (r *Ring) Send(fd int, buffer []byte, timeout time.Duration) (int, error) {
+	return 0, os.ErrInvalid
+}
+
+func (r *Ring) Copy(destination *net.TCPConn, source *net.TCPConn, readCounters []N.CountFunc, writeCounters []N.CountFunc) (n int64, err error) {
+	return 0, os.ErrInvalid
+}
@@ -0,0 +1,55 @@
+package knock
+
+import (
+	"encoding/binary"
+	"net/netip"
+
+	"golang.org/x/net/bpf"
+)
+
+const (
+	filterAccept = 0xffffffff
+	filterDrop   = 0
+)
+
+// buildFilter builds a socket filter that accepts packets from addrs only.
+// netOffset is the offset of the IP header in the packet.
+func buildFilter(addrs []netip.Addr, netOffset uint32) ([]bpf.RawInstruction, error) {
+	var inet4Addrs, inet6Addrs []netip.Addr
+	for _, addr := range addrs {
+		addr = addr.Unmap()
+		if addr.Is4() {
+			inet4Addrs = append(inet4Addrs, addr)
+		} else {
+			inet6Addrs = append(inet6Addrs, addr)
+		}
+	}
+	program := []bpf.Instruction{
+		bpf.LoadAbsolute{Off: netOffset, Size: 1},
+		bpf.ALUOpConstant{Op: bpf.ALUOpShiftRight, Val: 4},
+		bpf.JumpIf{Cond: bpf.JumpEqual, Val: 4, SkipTrue: 1},
+		// inet6 filter starts after the inet4 filter
+		bpf.Jump{Skip: uint32(2 + 2*len(inet4Addrs))},
+		bpf.LoadAbsolute{Off: netOffset + 12, Size: 4},
+	}
+	for _, addr := range inet4Addrs {
+		address := addr.As4()
+		program = append(program,
+			bpf.JumpIf{Cond: bpf.JumpEqual, Val: binary.BigEndian.Uint32(address[:]), SkipFalse: 1},
+			bpf.RetConstant{Val: filterAccept},
+		)
+	}
+	program = append(program, bpf.RetConstant{Val: filterDrop})
+	for _, addr := range inet6Addrs {
+		address := addr.As16()
+		for i := 0; i < 4; i++ {
+			program = append(program,
+				bpf.LoadAbsolute{Off: netOffset + 8 + uint32(i*4), Size: 4},
+				bpf.JumpIf{Cond: bpf.JumpEqual, Val: binary.BigEndian.Uint32(address[i*4:]), SkipFalse: uint8(7 - i*2)},
+			)
+		}
+		program = append(program, bpf.RetConstant{Val: filterAccept})
+	}
+	program = append(program, bpf.RetConstant{Val: filterDrop})
+	return bpf.Assemble(program)
+}
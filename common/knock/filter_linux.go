@@ -0,0 +1,45 @@
+package knock
+
+import (
+	"net/netip"
+	"syscall"
+	"unsafe"
+
+	"github.com/sagernet/sing/common/control"
+
+	"golang.org/x/sys/unix"
+)
+
+// skfNetOffset is SKF_NET_OFF, the offset of the network header for
+// absolute loads of socket filters.
+const skfNetOffset = 0xfff00000
+
+const filterSupported = true
+
+func attachFilter(conn syscall.RawConn, addrs []netip.Addr) error {
+	program, err := buildFilter(addrs, skfNetOffset)
+	if err != nil {
+		return err
+	}
+	filter := make([]unix.SockFilter, len(program))
+	for i, instruction := range program {
+		filter[i] = unix.SockFilter{
+			Code: instruction.Op,
+			Jt:   instruction.Jt,
+			Jf:   instruction.Jf,
+			K:    instruction.K,
+		}
+	}
+	return control.Raw(conn, func(fd uintptr) error {
+		return unix.SetsockoptSockFprog(int(fd), unix.SOL_SOCKET, unix.SO_ATTACH_FILTER, &unix.SockFprog{
+			Len:    uint16(len(filter)),
+			Filter: (*unix.SockFilter)(unsafe.Pointer(&filter[0])),
+		})
+	})
+}
+
+func detachFilter(conn syscall.RawConn) error {
+	return control.Raw(conn, func(fd uintptr) error {
+		return unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_DETACH_FILTER, 0)
+	})
+}
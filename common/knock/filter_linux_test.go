@@ -0,0 +1,32 @@
+package knock
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterListener(t *testing.T) {
+	t.Parallel()
+	gate, err := NewGate(context.Background(), log.NewNOPFactory().Logger(), option.InboundKnockOptions{Key: testKey})
+	require.NoError(t, err)
+	defer gate.Close()
+	listenConfig := net.ListenConfig{Control: gate.Control}
+	listener, err := listenConfig.Listen(context.Background(), "tcp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	_, err = net.DialTimeout("tcp4", listener.Addr().String(), 200*time.Millisecond)
+	require.Error(t, err, "handshake completed before knock")
+	source := netip.MustParseAddr("127.0.0.1")
+	require.NoError(t, gate.knock(source, NewPacket(gate.key, source, time.Now())))
+	conn, err := net.DialTimeout("tcp4", listener.Addr().String(), time.Second)
+	require.NoError(t, err)
+	conn.Close()
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package knock
+
+import (
+	"net/netip"
+	"os"
+	"syscall"
+)
+
+const filterSupported = false
+
+func attachFilter(conn syscall.RawConn, addrs []netip.Addr) error {
+	return os.ErrInvalid
+}
+
+func detachFilter(conn syscall.RawConn) error {
+	return os.ErrInvalid
+}
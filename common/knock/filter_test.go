@@ -0,0 +1,58 @@
+package knock
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/bpf"
+)
+
+func testIPHeader(source netip.Addr) []byte {
+	if source.Is4() {
+		header := make([]byte, 20)
+		header[0] = 0x45
+		address := source.As4()
+		copy(header[12:], address[:])
+		return header
+	}
+	header := make([]byte, 40)
+	header[0] = 0x60
+	address := source.As16()
+	copy(header[8:], address[:])
+	return header
+}
+
+func TestFilter(t *testing.T) {
+	t.Parallel()
+	allowed := []netip.Addr{
+		netip.MustParseAddr("192.0.2.1"),
+		netip.MustParseAddr("::ffff:192.0.2.2"),
+		netip.MustParseAddr("2001:db8::1"),
+		netip.MustParseAddr("2001:db8::2"),
+	}
+	for _, addrs := range [][]netip.Addr{nil, allowed} {
+		program, err := buildFilter(addrs, 0)
+		require.NoError(t, err)
+		instructions, allDecoded := bpf.Disassemble(program)
+		require.True(t, allDecoded)
+		vm, err := bpf.NewVM(instructions)
+		require.NoError(t, err)
+		for _, testCase := range []struct {
+			source  string
+			allowed bool
+		}{
+			{"192.0.2.1", true},
+			{"192.0.2.2", true},
+			{"192.0.2.3", false},
+			{"2001:db8::1", true},
+			{"2001:db8::2", true},
+			{"2001:db8::3", false},
+			{"2001:db9::1", false},
+		} {
+			accepted, err := vm.Run(testIPHeader(netip.MustParseAddr(testCase.source)))
+			require.NoError(t, err)
+			require.Equal(t, testCase.allowed && addrs != nil, accepted != 0, testCase.source)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package knock
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/ntp"
+)
+
+const DefaultTimeout = 30 * time.Second
+
+// Gate keeps a listener closed to sources that have not sent a valid knock
+// packet in the timeout. Established TCP connections are not affected, and
+// UDP packets of allowed sources extend the timeout.
+//
+// On Linux, TCP listeners are gated by a socket filter, so that handshakes
+// from other sources are never answered.
+type Gate struct {
+	logger      log.ContextLogger
+	key         []byte
+	timeout     time.Duration
+	timeFunc    func() time.Time
+	access      sync.Mutex
+	allowed     map[netip.Addr]time.Time
+	nonces      map[[nonceLength]byte]time.Time
+	conn        net.PacketConn
+	sockets     []syscall.RawConn
+	filterTimer *time.Timer
+}
+
+func NewGate(ctx context.Context, logger log.ContextLogger, options option.InboundKnockOptions) (*Gate, error) {
+	key, err := ParseKey(options.Key)
+	if err != nil {
+		return nil, err
+	}
+	timeout := time.Duration(options.Timeout)
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	timeFunc := ntp.TimeFuncFromContext(ctx)
+	if timeFunc == nil {
+		timeFunc = time.Now
+	}
+	return &Gate{
+		logger:   logger,
+		key:      key,
+		timeout:  timeout,
+		timeFunc: timeFunc,
+		allowed:  make(map[netip.Addr]time.Time),
+		nonces:   make(map[[nonceLength]byte]time.Time),
+	}, nil
+}
+
+// Start serves knock packets on conn until it is closed.
+func (g *Gate) Start(conn net.PacketConn) {
+	g.conn = conn
+	go g.loopKnock()
+}
+
+func (g *Gate) Close() error {
+	g.access.Lock()
+	g.sockets = nil
+	if g.filterTimer != nil {
+		g.filterTimer.Stop()
+	}
+	g.access.Unlock()
+	return common.Close(g.conn)
+}
+
+func (g *Gate) loopKnock() {
+	buffer := make([]byte, PacketLength+1)
+	for {
+		n, addr, err := g.conn.ReadFrom(buffer)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				g.logger.Error("knock: ", err)
+			}
+			return
+		}
+		source := M.SocksaddrFromNet(addr).Unwrap().Addr
+		err = g.knock(source, buffer[:n])
+		if err != nil {
+			g.logger.Debug("knock: rejected knock from ", source, ": ", err)
+		} else {
+			g.logger.Debug("knock: allowed ", source, " for ", g.timeout)
+		}
+	}
+}
+
+func (g *Gate) knock(source netip.Addr, packet []byte) error {
+	now := g.timeFunc()
+	nonce, err := verifyPacket(g.key, packet, source, now)
+	if err != nil {
+		return err
+	}
+	g.access.Lock()
+	defer g.access.Unlock()
+	g.cleanup(now)
+	if _, loaded := g.nonces[nonce]; loaded {
+		return ErrReplay
+	}
+	g.nonces[nonce] = now.Add(2 * timeWindow)
+	g.allowed[source] = time.Now().Add(g.timeout)
+	g.updateFilters()
+	return nil
+}
+
+func (g *Gate) cleanup(now time.Time) {
+	for nonce, expireAt := range g.nonces {
+		if now.After(expireAt) {
+			delete(g.nonces, nonce)
+		}
+	}
+	systemNow := time.Now()
+	for source, expireAt := range g.allowed {
+		if systemNow.After(expireAt) {
+			delete(g.allowed, source)
+		}
+	}
+}
+
+// Allow reports whether the source has knocked in the timeout, refresh
+// extends the timeout for sources of UDP sessions.
+func (g *Gate) Allow(source netip.Addr, refresh bool) bool {
+	source = source.Unmap()
+	g.access.Lock()
+	defer g.access.Unlock()
+	expireAt, loaded := g.allowed[source]
+	if !loaded {
+		return false
+	}
+	now := time.Now()
+	if now.After(expireAt) {
+		delete(g.allowed, source)
+		return false
+	}
+	if refresh {
+		g.allowed[source] = now.Add(g.timeout)
+	}
+	return true
+}
+
+// Control attaches the filter to TCP listener sockets on Linux.
+func (g *Gate) Control(network, address string, conn syscall.RawConn) error {
+	if !filterSupported || !strings.HasPrefix(network, N.NetworkTCP) {
+		return nil
+	}
+	g.access.Lock()
+	defer g.access.Unlock()
+	err := attachFilter(conn, slices.Collect(maps.Keys(g.allowed)))
+	if err != nil {
+		return E.Cause(err, "attach knock filter")
+	}
+	g.sockets = append(g.sockets, conn)
+	return nil
+}
+
+// updateFilters rebuilds filters with the allowed sources, and schedules the
+// next update when the first of them expires.
+func (g *Gate) updateFilters() {
+	if len(g.sockets) == 0 {
+		return
+	}
+	allowed := slices.Collect(maps.Keys(g.allowed))
+	g.sockets = common.Filter(g.sockets, func(conn syscall.RawConn) bool {
+		err := attachFilter(conn, allowed)
+		if err == nil {
+			return true
+		}
+		if !E.IsClosed(err) {
+			g.logger.Error("knock: update filter: ", err)
+			// fall back to the userspace gate
+			_ = detachFilter(conn)
+		}
+		return false
+	})
+	var nextExpire time.Time
+	for _, expireAt := range g.allowed {
+		if nextExpire.IsZero() || expireAt.Before(nextExpire) {
+			nextExpire = expireAt
+		}
+	}
+	if nextExpire.IsZero() {
+		return
+	}
+	if g.filterTimer == nil {
+		g.filterTimer = time.AfterFunc(time.Until(nextExpire), g.expireFilters)
+	} else {
+		g.filterTimer.Reset(time.Until(nextExpire))
+	}
+}
+
+func (g *Gate) expireFilters() {
+	g.access.Lock()
+	defer g.access.Unlock()
+	g.cleanup(g.timeFunc())
+	g.updateFilters()
+}
+
+func (g *Gate) Listener(listener net.Listener) net.Listener {
+	return &gateListener{Listener: listener, gate: g}
+}
+
+func (g *Gate) PacketConn(conn net.PacketConn) net.PacketConn {
+	return &gatePacketConn{PacketConn: conn, gate: g}
+}
+
+type gateListener struct {
+	net.Listener
+	gate *Gate
+}
+
+func (l *gateListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		source := M.SocksaddrFromNet(conn.RemoteAddr()).Unwrap().Addr
+		if l.gate.Allow(source, false) {
+			if syscallConn, isSyscallConn := conn.(syscall.Conn); filterSupported && isSyscallConn {
+				// accepted sockets inherit the filter of the listener
+				rawConn, err := syscallConn.SyscallConn()
+				if err == nil {
+					_ = detachFilter(rawConn)
+				}
+			}
+			return conn, nil
+		}
+		l.gate.logger.Debug("knock: rejected connection from ", source)
+		if tcpConn, isTCP := conn.(*net.TCPConn); isTCP {
+			// reset instead of a graceful close
+			_ = tcpConn.SetLinger(0)
+		}
+		conn.Close()
+	}
+}
+
+type gatePacketConn struct {
+	net.PacketConn
+	gate *Gate
+}
+
+func (c *gatePacketConn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
+	for {
+		n, addr, err = c.PacketConn.ReadFrom(p)
+		if err != nil || c.gate.Allow(M.SocksaddrFromNet(addr).Unwrap().Addr, true) {
+			return
+		}
+	}
+}
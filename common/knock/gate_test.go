@@ -0,0 +1,52 @@
+package knock
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testKey = "MDEyMzQ1Njc4OWFiY2RlZg=="
+
+func TestPacket(t *testing.T) {
+	t.Parallel()
+	key, err := ParseKey(testKey)
+	require.NoError(t, err)
+	now := time.Now()
+	source := netip.MustParseAddr("192.0.2.1")
+	packet := NewPacket(key, source, now)
+	_, err = verifyPacket(key, packet, source, now)
+	require.NoError(t, err)
+	_, err = verifyPacket(key, packet, netip.MustParseAddr("::ffff:192.0.2.1"), now)
+	require.NoError(t, err)
+	_, err = verifyPacket(key, packet, netip.MustParseAddr("192.0.2.2"), now)
+	require.ErrorIs(t, err, ErrBadPacket)
+	_, err = verifyPacket(key, packet, source, now.Add(time.Minute))
+	require.ErrorIs(t, err, ErrBadTimestamp)
+	packet[len(packet)-1] ^= 1
+	_, err = verifyPacket(key, packet, source, now)
+	require.ErrorIs(t, err, ErrBadPacket)
+	_, err = ParseKey("MDEyMzQ1Njc=")
+	require.Error(t, err)
+}
+
+func TestGate(t *testing.T) {
+	t.Parallel()
+	gate, err := NewGate(context.Background(), log.NewNOPFactory().Logger(), option.InboundKnockOptions{Key: testKey})
+	require.NoError(t, err)
+	source := netip.MustParseAddr("192.0.2.1")
+	require.False(t, gate.Allow(source, false))
+	packet := NewPacket(gate.key, source, time.Now())
+	require.ErrorIs(t, gate.knock(netip.MustParseAddr("192.0.2.2"), packet), ErrBadPacket)
+	require.NoError(t, gate.knock(source, packet))
+	require.True(t, gate.Allow(source, false))
+	require.True(t, gate.Allow(netip.MustParseAddr("::ffff:192.0.2.1"), false))
+	require.False(t, gate.Allow(netip.MustParseAddr("192.0.2.2"), false))
+	require.ErrorIs(t, gate.knock(source, packet), ErrReplay)
+}
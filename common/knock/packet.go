@@ -0,0 +1,83 @@
+package knock
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"hash"
+	"net/netip"
+	"time"
+
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// A knock packet is a single UDP packet authenticated by a shared key:
+//
+//	version (1) | unix time in seconds (8) | random nonce (16) | HMAC-SHA256 (32)
+//
+// The HMAC covers the previous fields and the source address of the packet
+// in 16 bytes, so that captured knocks can not be replayed from another
+// address.
+const (
+	Version      = 1
+	nonceLength  = 16
+	headerLength = 1 + 8 + nonceLength
+	PacketLength = headerLength + sha256.Size
+
+	// timeWindow is the allowed time difference of knocks, which are
+	// remembered for the window to reject replays.
+	timeWindow = 30 * time.Second
+)
+
+var (
+	ErrBadPacket    = E.New("bad knock packet")
+	ErrBadTimestamp = E.New("knock time out of window")
+	ErrReplay       = E.New("replayed knock")
+)
+
+func ParseKey(key string) ([]byte, error) {
+	keyBytes, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, E.Cause(err, "decode knock key")
+	}
+	if len(keyBytes) < 16 {
+		return nil, E.New("knock key too short, at least 16 bytes required")
+	}
+	return keyBytes, nil
+}
+
+func NewPacket(key []byte, source netip.Addr, now time.Time) []byte {
+	packet := make([]byte, PacketLength)
+	packet[0] = Version
+	binary.BigEndian.PutUint64(packet[1:], uint64(now.Unix()))
+	_, _ = rand.Read(packet[9:headerLength])
+	copy(packet[headerLength:], packetMAC(key, packet, source).Sum(nil))
+	return packet
+}
+
+func packetMAC(key []byte, packet []byte, source netip.Addr) hash.Hash {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(packet[:headerLength])
+	address := source.Unmap().As16()
+	mac.Write(address[:])
+	return mac
+}
+
+// verifyPacket returns the nonce of a valid packet.
+func verifyPacket(key []byte, packet []byte, source netip.Addr, now time.Time) ([nonceLength]byte, error) {
+	var nonce [nonceLength]byte
+	if len(packet) != PacketLength || packet[0] != Version {
+		return nonce, ErrBadPacket
+	}
+	if !hmac.Equal(packetMAC(key, packet, source).Sum(nil), packet[headerLength:]) {
+		return nonce, ErrBadPacket
+	}
+	knockTime := time.Unix(int64(binary.BigEndian.Uint64(packet[1:])), 0)
+	if difference := knockTime.Sub(now); difference > timeWindow || difference < -timeWindow {
+		return nonce, ErrBadTimestamp
+	}
+	copy(nonce[:], packet[9:headerLength])
+	return nonce, nil
+}
@@ -9,7 +9,6 @@ import (
 	"errors"
 	"io"
 	"net"
-	"os"
 	"syscall"
 
 	"github.com/sagernet/sing-box/common/badtls"
@@ -45,7 +44,7 @@ func NewConn(ctx context.Context, logger logger.ContextLogger, conn aTLS.Conn, t
 		syscall.Conn
 	}](conn.NetConn())
 	if !isSyscallConn {
-		return nil, os.ErrInvalid
+		return nil, E.Extend(ErrUnsupported, "not a socket")
 	}
 	rawSyscallConn, err := syscallConn.SyscallConn()
 	if err != nil {
@@ -56,7 +55,7 @@ func NewConn(ctx context.Context, logger logger.ContextLogger, conn aTLS.Conn, t
 		return nil, err
 	}
 	if *rawConn.Vers != tls.VersionTLS13 {
-		return nil, os.ErrInvalid
+		return nil, E.Extend(ErrUnsupported, tls.VersionName(*rawConn.Vers))
 	}
 	for rawConn.RawInput.Len() > 0 {
 		err = rawConn.ReadRecord()
@@ -0,0 +1,7 @@
+package ktls
+
+import E "github.com/sagernet/sing/common/exceptions"
+
+// ErrUnsupported is returned by NewConn if kernel TLS is unsupported for the
+// negotiated session, which is left unchanged to continue in userspace.
+var ErrUnsupported = E.New("ktls: unsupported TLS session")
@@ -133,6 +133,19 @@ func (c *Conn) setupKernel(txOffload, rxOffload bool) error {
 	if !support.TLS || !support.TLS_Version13 {
 		return E.New("kernel does not support TLS 1.3")
 	}
+	var txCrypto, rxCrypto kernelCrypto
+	if txOffload {
+		txCrypto = kernelCipher(support, c.rawConn.Out, *c.rawConn.CipherSuite, false)
+		if txCrypto == nil {
+			return E.Extend(ErrUnsupported, "TX cipher suite ", tls.CipherSuiteName(*c.rawConn.CipherSuite))
+		}
+	}
+	if rxOffload {
+		rxCrypto = kernelCipher(support, c.rawConn.In, *c.rawConn.CipherSuite, true)
+		if rxCrypto == nil {
+			return E.Extend(ErrUnsupported, "RX cipher suite ", tls.CipherSuiteName(*c.rawConn.CipherSuite))
+		}
+	}
 	c.rawConn.Out.Lock()
 	defer c.rawConn.Out.Unlock()
 	err = control.Raw(c.rawSyscallConn, func(fd uintptr) error {
@@ -142,11 +155,7 @@ func (c *Conn) setupKernel(txOffload, rxOffload bool) error {
 		return os.NewSyscallError("setsockopt", err)
 	}
 
-	if txOffload {
-		txCrypto := kernelCipher(support, c.rawConn.Out, *c.rawConn.CipherSuite, false)
-		if txCrypto == nil {
-			return E.New("unsupported cipher suite")
-		}
+	if txCrypto != nil {
 		err = control.Raw(c.rawSyscallConn, func(fd uintptr) error {
 			return syscall.SetsockoptString(int(fd), unix.SOL_TLS, TLS_TX, txCrypto.String())
 		})
@@ -165,11 +174,7 @@ func (c *Conn) setupKernel(txOffload, rxOffload bool) error {
 		c.logger.DebugContext(c.ctx, "ktls: kernel TLS TX enabled")
 	}
 
-	if rxOffload {
-		rxCrypto := kernelCipher(support, c.rawConn.In, *c.rawConn.CipherSuite, true)
-		if rxCrypto == nil {
-			return E.New("unsupported cipher suite")
-		}
+	if rxCrypto != nil {
 		err = control.Raw(c.rawSyscallConn, func(fd uintptr) error {
 			return syscall.SetsockoptString(int(fd), unix.SOL_TLS, TLS_RX, rxCrypto.String())
 		})
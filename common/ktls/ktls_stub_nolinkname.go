@@ -10,6 +10,10 @@ import (
 	aTLS "github.com/sagernet/sing/common/tls"
 )
 
+func Load() error {
+	return E.New("kTLS requires build flags `badlinkname` and `-ldflags=-checklinkname=0`, please recompile your binary")
+}
+
 func NewConn(ctx context.Context, logger logger.ContextLogger, conn aTLS.Conn, txOffload, rxOffload bool) (aTLS.Conn, error) {
-	return nil, E.New("kTLS requires build flags `badlinkname` and `-ldflags=-checklinkname=0`, please recompile your binary")
+	return nil, Load()
 }
@@ -10,6 +10,10 @@ import (
 	aTLS "github.com/sagernet/sing/common/tls"
 )
 
+func Load() error {
+	return E.New("kTLS is only supported on Linux")
+}
+
 func NewConn(ctx context.Context, logger logger.ContextLogger, conn aTLS.Conn, txOffload, rxOffload bool) (aTLS.Conn, error) {
-	return nil, E.New("kTLS is only supported on Linux")
+	return nil, Load()
 }
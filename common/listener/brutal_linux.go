@@ -0,0 +1,28 @@
+package listener
+
+import (
+	"os"
+
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"golang.org/x/sys/unix"
+)
+
+// probeBrutal checks that TCP Brutal can be set as the congestion control of
+// a socket, so that missing kernel support fails the start of the inbound
+// instead of each accepted connection.
+func probeBrutal() error {
+	fd, err := unix.Socket(unix.AF_INET6, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		fd, err = unix.Socket(unix.AF_INET, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+		if err != nil {
+			return os.NewSyscallError("socket", err)
+		}
+	}
+	defer unix.Close(fd)
+	err = unix.SetsockoptString(fd, unix.IPPROTO_TCP, unix.TCP_CONGESTION, "brutal")
+	if err != nil {
+		return E.Extend(os.NewSyscallError("setsockopt IPPROTO_TCP TCP_CONGESTION brutal", err), "please make sure you have installed the tcp-brutal kernel module")
+	}
+	return nil
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package listener
+
+func probeBrutal() error {
+	return nil
+}
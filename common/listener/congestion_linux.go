@@ -0,0 +1,26 @@
+package listener
+
+import (
+	"strings"
+	"syscall"
+
+	"github.com/sagernet/sing/common/control"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"golang.org/x/sys/unix"
+)
+
+func TCPCongestionControl(algorithm string) control.Func {
+	return func(network, address string, conn syscall.RawConn) error {
+		if !strings.HasPrefix(network, "tcp") {
+			return nil
+		}
+		return control.Raw(conn, func(fd uintptr) error {
+			err := unix.SetsockoptString(int(fd), unix.IPPROTO_TCP, unix.TCP_CONGESTION, algorithm)
+			if err != nil {
+				return E.Cause(err, "set tcp congestion control ", algorithm)
+			}
+			return nil
+		})
+	}
+}
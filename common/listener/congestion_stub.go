@@ -0,0 +1,16 @@
+//go:build !linux
+
+package listener
+
+import (
+	"syscall"
+
+	"github.com/sagernet/sing/common/control"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+func TCPCongestionControl(algorithm string) control.Func {
+	return func(network, address string, conn syscall.RawConn) error {
+		return E.New("`tcp_congestion_control` is only supported on Linux")
+	}
+}
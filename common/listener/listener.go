@@ -9,6 +9,10 @@ import (
 	"sync/atomic"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/acl"
+	"github.com/sagernet/sing-box/common/knock"
+	"github.com/sagernet/sing-box/common/padding"
+	"github.com/sagernet/sing-box/common/portmap"
 	"github.com/sagernet/sing-box/common/settings"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing/common"
@@ -23,6 +27,7 @@ import (
 type Listener struct {
 	ctx                      context.Context
 	logger                   logger.ContextLogger
+	tag                      string
 	network                  []string
 	listenOptions            option.ListenOptions
 	connHandler              adapter.ConnectionHandlerEx
@@ -35,17 +40,26 @@ type Listener struct {
 	tproxy                   bool
 
 	tcpListener          net.Listener
+	tcpWorkers           *workerListener
 	systemProxy          settings.SystemProxy
+	portMappers          []*portmap.Mapper
 	udpConn              *net.UDPConn
 	udpAddr              M.Socksaddr
+	acl                  *acl.ACL
+	knock                *knock.Gate
+	padding              *padding.Config
+	brutalSendBPS        uint64
 	packetOutbound       chan *N.PacketBuffer
 	packetOutboundClosed chan struct{}
 	shutdown             atomic.Bool
+	tcpDrained           atomic.Bool
+	unregisterDrain      func()
 }
 
 type Options struct {
 	Context                  context.Context
 	Logger                   logger.ContextLogger
+	Tag                      string
 	Network                  []string
 	Listen                   option.ListenOptions
 	ConnectionHandler        adapter.ConnectionHandlerEx
@@ -64,6 +78,7 @@ func New(
 	return &Listener{
 		ctx:                      options.Context,
 		logger:                   options.Logger,
+		tag:                      options.Tag,
 		network:                  options.Network,
 		listenOptions:            options.Listen,
 		connHandler:              options.ConnectionHandler,
@@ -83,7 +98,11 @@ func (l *Listener) Start() error {
 		if err != nil {
 			return err
 		}
-		go l.loopTCPIn()
+		if l.tcpWorkers != nil {
+			l.startTCPWorkers(l.tcpWorkers)
+		} else {
+			go l.loopTCPIn(l.tcpListener)
+		}
 	}
 	if common.Contains(l.network, N.NetworkUDP) {
 		_, err := l.ListenUDP()
@@ -92,9 +111,16 @@ func (l *Listener) Start() error {
 		}
 		l.packetOutboundClosed = make(chan struct{})
 		l.packetOutbound = make(chan *N.PacketBuffer, 64)
-		go l.loopUDPIn()
-		if !l.disablePacketOutput {
-			go l.loopUDPOut()
+		if l.listenOptions.UDPBatch {
+			go l.loopUDPInBatch()
+			if !l.disablePacketOutput {
+				go l.loopUDPOutBatch()
+			}
+		} else {
+			go l.loopUDPIn()
+			if !l.disablePacketOutput {
+				go l.loopUDPOut()
+			}
 		}
 	}
 	if l.setSystemProxy {
@@ -121,14 +147,41 @@ func (l *Listener) Start() error {
 
 func (l *Listener) Close() error {
 	l.shutdown.Store(true)
+	if l.unregisterDrain != nil {
+		l.unregisterDrain()
+	}
 	var err error
 	if l.systemProxy != nil && l.systemProxy.IsEnabled() {
 		err = l.systemProxy.Disable()
 	}
+	for _, mapper := range l.portMappers {
+		err = E.Append(err, mapper.Close(), func(err error) error {
+			return E.Cause(err, "close port mapping")
+		})
+	}
 	return E.Errors(err, common.Close(
 		l.tcpListener,
 		common.PtrOrNil(l.udpConn),
-	))
+		common.PtrOrNil(l.knock),
+	), l.closeACL())
+}
+
+func (l *Listener) startPortMapping(network string, listenAddr net.Addr) error {
+	if !l.listenOptions.PortMapping {
+		return nil
+	}
+	mapper, err := portmap.New(portmap.Options{
+		Context: l.ctx,
+		Logger:  l.logger,
+		Network: network,
+		Listen:  M.SocksaddrFromNet(listenAddr).AddrPort(),
+	})
+	if err != nil {
+		return E.Cause(err, "create port mapping")
+	}
+	mapper.Start()
+	l.portMappers = append(l.portMappers, mapper)
+	return nil
 }
 
 func (l *Listener) TCPListener() net.Listener {
@@ -0,0 +1,108 @@
+package listener
+
+import (
+	"net"
+	"net/netip"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/acl"
+	M "github.com/sagernet/sing/common/metadata"
+	"github.com/sagernet/sing/service"
+)
+
+// startACL starts the source ACL shared by the TCP and UDP listeners.
+func (l *Listener) startACL() error {
+	if l.listenOptions.ACL == nil || l.acl != nil {
+		return nil
+	}
+	inboundACL, err := acl.New(l.ctx, l.logger, *l.listenOptions.ACL)
+	if err != nil {
+		return err
+	}
+	err = inboundACL.Start()
+	if err != nil {
+		return err
+	}
+	l.acl = inboundACL
+	if l.tag != "" {
+		aclManager := service.FromContext[adapter.InboundACLManager](l.ctx)
+		if aclManager != nil {
+			aclManager.Register(l.tag, inboundACL)
+		}
+	}
+	return nil
+}
+
+func (l *Listener) closeACL() error {
+	if l.acl == nil {
+		return nil
+	}
+	if l.tag != "" {
+		aclManager := service.FromContext[adapter.InboundACLManager](l.ctx)
+		if aclManager != nil {
+			aclManager.Unregister(l.tag, l.acl)
+		}
+	}
+	return l.acl.Close()
+}
+
+// allowPacket reports whether a UDP packet from source passes the ACL and
+// the knock gate.
+func (l *Listener) allowPacket(source netip.Addr) bool {
+	if l.acl != nil && !l.acl.Allow(source) {
+		return false
+	}
+	return l.knock == nil || l.knock.Allow(source, true)
+}
+
+func (l *Listener) aclListener(listener net.Listener) net.Listener {
+	if l.acl == nil {
+		return listener
+	}
+	return &aclListener{Listener: listener, listener: l}
+}
+
+func (l *Listener) aclPacketConn(conn net.PacketConn) net.PacketConn {
+	if l.acl == nil {
+		return conn
+	}
+	return &aclPacketConn{PacketConn: conn, acl: l.acl}
+}
+
+type aclListener struct {
+	net.Listener
+	listener *Listener
+}
+
+func (l *aclListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		source := M.SocksaddrFromNet(conn.RemoteAddr()).Unwrap().Addr
+		if l.listener.acl.Allow(source) {
+			return conn, nil
+		}
+		l.listener.logger.Debug("acl: rejected connection from ", source)
+		if tcpConn, isTCP := conn.(*net.TCPConn); isTCP {
+			// reset instead of a graceful close
+			_ = tcpConn.SetLinger(0)
+		}
+		conn.Close()
+	}
+}
+
+type aclPacketConn struct {
+	net.PacketConn
+	acl *acl.ACL
+}
+
+func (c *aclPacketConn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
+	for {
+		n, addr, err = c.PacketConn.ReadFrom(p)
+		if err != nil || c.acl.Allow(M.SocksaddrFromNet(addr).Unwrap().Addr) {
+			return
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package listener
+
+import (
+	"net"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-mux"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// TCPBrutalSendBPS returns the send rate of TCP Brutal, or zero if it is
+// disabled.
+func TCPBrutalSendBPS(options *option.TCPBrutalOptions, congestionControl string) (uint64, error) {
+	if options == nil || !options.Enabled {
+		return 0, nil
+	}
+	if !mux.BrutalAvailable {
+		return 0, E.New("`tcp_brutal` is only supported on Linux")
+	}
+	if congestionControl != "" {
+		return 0, E.New("`tcp_brutal` and `tcp_congestion_control` are mutually exclusive")
+	}
+	sendBPS := uint64(options.SendMbps * C.MbpsToBps)
+	if sendBPS < mux.BrutalMinSpeedBPS {
+		return 0, E.New("tcp_brutal: invalid send speed")
+	}
+	return sendBPS, nil
+}
+
+func (l *Listener) createBrutal() error {
+	sendBPS, err := TCPBrutalSendBPS(l.listenOptions.TCPBrutal, l.listenOptions.TCPCongestionControl)
+	if err != nil {
+		return err
+	}
+	if sendBPS > 0 {
+		err = probeBrutal()
+		if err != nil {
+			return E.Cause(err, "tcp_brutal")
+		}
+	}
+	l.brutalSendBPS = sendBPS
+	return nil
+}
+
+func (l *Listener) brutalListener(listener net.Listener) net.Listener {
+	if l.brutalSendBPS == 0 {
+		return listener
+	}
+	return &brutalListener{Listener: listener, listener: l}
+}
+
+// brutalListener enables TCP Brutal on accepted connections, as the
+// parameters are reset when the connection is established.
+type brutalListener struct {
+	net.Listener
+	listener *Listener
+}
+
+func (l *brutalListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		err = mux.SetBrutalOptions(conn, l.listener.brutalSendBPS)
+		if err == nil {
+			return conn, nil
+		}
+		l.listener.logger.Error(E.Cause(err, "enable tcp brutal for connection from ", conn.RemoteAddr()))
+		conn.Close()
+	}
+}
@@ -0,0 +1,30 @@
+package listener
+
+import (
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/service"
+)
+
+// registerDrain closes the TCP listener when the inbound is drained, so that
+// load balancers stop sending new connections. UDP sockets are kept open, as
+// they are shared by existing sessions.
+func (l *Listener) registerDrain() {
+	if l.tag == "" {
+		return
+	}
+	router := service.FromContext[adapter.Router](l.ctx)
+	if router == nil {
+		return
+	}
+	l.unregisterDrain = router.RegisterDrainListener(l.tag, &drainCloser{l})
+}
+
+type drainCloser struct {
+	listener *Listener
+}
+
+func (c *drainCloser) Close() error {
+	c.listener.tcpDrained.Store(true)
+	c.listener.logger.Info("tcp server closed for drain")
+	return c.listener.tcpListener.Close()
+}
@@ -0,0 +1,77 @@
+package listener
+
+import (
+	"net"
+	"net/netip"
+	"syscall"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/knock"
+	"github.com/sagernet/sing/common/control"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/service"
+)
+
+// startKnock starts the knock gate shared by the TCP and UDP listeners.
+func (l *Listener) startKnock() error {
+	if l.listenOptions.Knock == nil || l.knock != nil {
+		return nil
+	}
+	if l.listenOptions.Knock.ListenPort == 0 {
+		return E.New("missing knock listen_port")
+	}
+	gate, err := knock.NewGate(l.ctx, l.logger, *l.listenOptions.Knock)
+	if err != nil {
+		return err
+	}
+	bindAddr := M.SocksaddrFrom(l.listenOptions.Listen.Build(netip.AddrFrom4([4]byte{127, 0, 0, 1})), l.listenOptions.Knock.ListenPort)
+	var listenConfig net.ListenConfig
+	if l.listenOptions.BindInterface != "" {
+		listenConfig.Control = control.Append(listenConfig.Control, control.BindToInterface(service.FromContext[adapter.NetworkManager](l.ctx).InterfaceFinder(), l.listenOptions.BindInterface, -1))
+	}
+	if l.listenOptions.RoutingMark != 0 {
+		listenConfig.Control = control.Append(listenConfig.Control, control.RoutingMark(uint32(l.listenOptions.RoutingMark)))
+	}
+	packetConn, err := ListenNetworkNamespace[net.PacketConn](l.listenOptions.NetNs, func() (net.PacketConn, error) {
+		return listenConfig.ListenPacket(l.ctx, M.NetworkFromNetAddr(N.NetworkUDP, bindAddr.Addr), bindAddr.String())
+	})
+	if err != nil {
+		return E.Cause(err, "listen knock")
+	}
+	l.logger.Info("knock server started at ", packetConn.LocalAddr())
+	gate.Start(packetConn)
+	l.knock = gate
+	return nil
+}
+
+// attachKnockFilter attaches the knock filter to a listener passed by systemd.
+func (l *Listener) attachKnockFilter(listener net.Listener) error {
+	if l.knock == nil {
+		return nil
+	}
+	syscallConn, isSyscallConn := listener.(syscall.Conn)
+	if !isSyscallConn {
+		return nil
+	}
+	rawConn, err := syscallConn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	return l.knock.Control(listener.Addr().Network(), listener.Addr().String(), rawConn)
+}
+
+func (l *Listener) gateListener(listener net.Listener) net.Listener {
+	if l.knock == nil {
+		return listener
+	}
+	return l.knock.Listener(listener)
+}
+
+func (l *Listener) gatePacketConn(conn net.PacketConn) net.PacketConn {
+	if l.knock == nil {
+		return conn
+	}
+	return l.knock.PacketConn(conn)
+}
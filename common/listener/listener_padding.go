@@ -0,0 +1,40 @@
+package listener
+
+import (
+	"net"
+
+	"github.com/sagernet/sing-box/common/padding"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+func (l *Listener) createPadding() error {
+	if l.listenOptions.Padding == nil {
+		return nil
+	}
+	config, err := padding.NewConfig(*l.listenOptions.Padding)
+	if err != nil {
+		return E.Cause(err, "create padding")
+	}
+	l.padding = config
+	return nil
+}
+
+func (l *Listener) paddingListener(listener net.Listener) net.Listener {
+	if l.padding == nil {
+		return listener
+	}
+	return &paddingListener{Listener: listener, config: l.padding}
+}
+
+type paddingListener struct {
+	net.Listener
+	config *padding.Config
+}
+
+func (l *paddingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return padding.NewServerConn(conn, l.config), nil
+}
@@ -1,6 +1,7 @@
 package listener
 
 import (
+	"context"
 	"net"
 	"net/netip"
 	"strings"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/redir"
+	"github.com/sagernet/sing-box/common/systemd"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing/common/control"
@@ -25,8 +27,44 @@ func (l *Listener) ListenTCP() (net.Listener, error) {
 	if l.listenOptions.ProxyProtocol || l.listenOptions.ProxyProtocolAcceptNoHeader {
 		return nil, E.New("Proxy Protocol is deprecated and removed in sing-box 1.6.0")
 	}
-	var err error
+	err := l.startACL()
+	if err != nil {
+		return nil, err
+	}
+	err = l.startKnock()
+	if err != nil {
+		return nil, err
+	}
+	err = l.createPadding()
+	if err != nil {
+		return nil, err
+	}
+	err = l.createBrutal()
+	if err != nil {
+		return nil, err
+	}
 	bindAddr := M.SocksaddrFrom(l.listenOptions.Listen.Build(netip.AddrFrom4([4]byte{127, 0, 0, 1})), l.listenOptions.ListenPort)
+	if l.listenOptions.NetNs == "" {
+		tcpListener, err := systemd.Listener(bindAddr)
+		if err != nil {
+			return nil, E.Cause(err, "use socket passed by systemd")
+		}
+		if tcpListener != nil {
+			if l.listenOptions.TCPWorkers > 1 {
+				tcpListener.Close()
+				return nil, E.New("`tcp_workers` is not supported with socket activation")
+			}
+			err = l.attachKnockFilter(tcpListener)
+			if err != nil {
+				tcpListener.Close()
+				return nil, err
+			}
+			l.logger.Info("tcp server started at ", tcpListener.Addr(), " (socket activation)")
+			l.tcpListener = l.wrapTCPListener(tcpListener)
+			l.registerDrain()
+			return l.tcpListener, l.startPortMapping(N.NetworkTCP, tcpListener.Addr())
+		}
+	}
 	var listenConfig net.ListenConfig
 	if l.listenOptions.BindInterface != "" {
 		listenConfig.Control = control.Append(listenConfig.Control, control.BindToInterface(service.FromContext[adapter.NetworkManager](l.ctx).InterfaceFinder(), l.listenOptions.BindInterface, -1))
@@ -37,6 +75,9 @@ func (l *Listener) ListenTCP() (net.Listener, error) {
 	if l.listenOptions.ReuseAddr {
 		listenConfig.Control = control.Append(listenConfig.Control, control.ReuseAddr())
 	}
+	if l.knock != nil {
+		listenConfig.Control = control.Append(listenConfig.Control, l.knock.Control)
+	}
 	if l.listenOptions.TCPKeepAlive >= 0 {
 		keepIdle := time.Duration(l.listenOptions.TCPKeepAlive)
 		if keepIdle == 0 {
@@ -53,8 +94,17 @@ func (l *Listener) ListenTCP() (net.Listener, error) {
 		}
 	}
 	if l.listenOptions.TCPMultiPath {
+		if l.listenOptions.TCPFastOpen {
+			l.logger.Warn("`tcp_fast_open` is not supported with `tcp_multi_path`, fallback to regular listen")
+		}
 		listenConfig.SetMultipathTCP(true)
 	}
+	if l.listenOptions.TCPCongestionControl != "" {
+		if !C.IsLinux {
+			return nil, E.New("`tcp_congestion_control` is only supported on Linux")
+		}
+		listenConfig.Control = control.Append(listenConfig.Control, TCPCongestionControl(l.listenOptions.TCPCongestionControl))
+	}
 	if l.tproxy {
 		listenConfig.Control = control.Append(listenConfig.Control, func(network, address string, conn syscall.RawConn) error {
 			return control.Raw(conn, func(fd uintptr) error {
@@ -62,8 +112,30 @@ func (l *Listener) ListenTCP() (net.Listener, error) {
 			})
 		})
 	}
-	tcpListener, err := ListenNetworkNamespace[net.Listener](l.listenOptions.NetNs, func() (net.Listener, error) {
-		if l.listenOptions.TCPFastOpen {
+	if l.listenOptions.TCPWorkerCPUAffinity && l.listenOptions.TCPWorkers < 2 {
+		return nil, E.New("`tcp_worker_cpu_affinity` requires `tcp_workers`")
+	}
+	var tcpListener net.Listener
+	if l.listenOptions.TCPWorkers > 1 {
+		tcpListener, err = l.listenTCPWorkers(listenConfig, bindAddr)
+	} else {
+		tcpListener, err = l.listenTCP(listenConfig, bindAddr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	l.logger.Info("tcp server started at ", tcpListener.Addr())
+	if workers, isWorkers := tcpListener.(*workerListener); isWorkers {
+		l.tcpWorkers = workers
+	}
+	l.tcpListener = l.wrapTCPListener(tcpListener)
+	l.registerDrain()
+	return l.tcpListener, l.startPortMapping(N.NetworkTCP, tcpListener.Addr())
+}
+
+func (l *Listener) listenTCP(listenConfig net.ListenConfig, bindAddr M.Socksaddr) (net.Listener, error) {
+	return ListenNetworkNamespace[net.Listener](l.listenOptions.NetNs, func() (net.Listener, error) {
+		if l.listenOptions.TCPFastOpen && !l.listenOptions.TCPMultiPath {
 			var tfoConfig tfo.ListenConfig
 			tfoConfig.ListenConfig = listenConfig
 			return tfoConfig.Listen(l.ctx, M.NetworkFromNetAddr(N.NetworkTCP, bindAddr.Addr), bindAddr.String())
@@ -71,16 +143,9 @@ func (l *Listener) ListenTCP() (net.Listener, error) {
 			return listenConfig.Listen(l.ctx, M.NetworkFromNetAddr(N.NetworkTCP, bindAddr.Addr), bindAddr.String())
 		}
 	})
-	if err != nil {
-		return nil, err
-	}
-	l.logger.Info("tcp server started at ", tcpListener.Addr())
-	l.tcpListener = tcpListener
-	return tcpListener, err
 }
 
-func (l *Listener) loopTCPIn() {
-	tcpListener := l.tcpListener
+func (l *Listener) loopTCPIn(tcpListener net.Listener) {
 	var metadata adapter.InboundContext
 	for {
 		conn, err := tcpListener.Accept()
@@ -90,7 +155,7 @@ func (l *Listener) loopTCPIn() {
 				l.logger.Error(err)
 				continue
 			}
-			if l.shutdown.Load() && E.IsClosed(err) {
+			if (l.shutdown.Load() || l.tcpDrained.Load()) && E.IsClosed(err) {
 				return
 			}
 			l.tcpListener.Close()
@@ -105,6 +170,11 @@ func (l *Listener) loopTCPIn() {
 		metadata.OriginDestination = M.SocksaddrFromNet(conn.LocalAddr()).Unwrap()
 		ctx := log.ContextWithNewID(l.ctx)
 		l.logger.InfoContext(ctx, "inbound connection from ", metadata.Source)
-		go l.connHandler.NewConnectionEx(ctx, conn, metadata, nil)
+		go l.newConnection(ctx, conn, metadata)
 	}
 }
+
+func (l *Listener) newConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) {
+	defer log.RecoverFatal()
+	l.connHandler.NewConnectionEx(ctx, conn, metadata, nil)
+}
@@ -0,0 +1,158 @@
+package listener
+
+import (
+	"net"
+	"runtime"
+	"sync"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing/common/control"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+)
+
+// listenTCPWorkers opens a socket for each worker on the same port with
+// SO_REUSEPORT, so that the kernel distributes new connections between them.
+func (l *Listener) listenTCPWorkers(listenConfig net.ListenConfig, bindAddr M.Socksaddr) (net.Listener, error) {
+	if !C.IsLinux {
+		return nil, E.New("`tcp_workers` is only supported on Linux")
+	}
+	workers := l.listenOptions.TCPWorkers
+	var cpus []int
+	if l.listenOptions.TCPWorkerCPUAffinity {
+		var err error
+		cpus, err = allowedCPUs()
+		if err != nil {
+			return nil, E.Cause(err, "get allowed CPUs")
+		}
+	}
+	listenConfig.Control = control.Append(listenConfig.Control, control.ReuseAddr())
+	listeners := make([]net.Listener, 0, workers)
+	workerCPUs := make([]int, 0, workers)
+	for index := 0; index < workers; index++ {
+		workerConfig := listenConfig
+		cpu := -1
+		if len(cpus) > 0 {
+			cpu = cpus[index%len(cpus)]
+			workerConfig.Control = control.Append(workerConfig.Control, incomingCPU(cpu))
+		}
+		listener, err := l.listenTCP(workerConfig, bindAddr)
+		if err != nil {
+			for _, listener = range listeners {
+				listener.Close()
+			}
+			return nil, E.Cause(err, "listen worker ", index)
+		}
+		if index == 0 {
+			// use the same port for all workers if allocated by the system
+			bindAddr = M.SocksaddrFromNet(listener.Addr())
+		}
+		listeners = append(listeners, listener)
+		workerCPUs = append(workerCPUs, cpu)
+	}
+	return newWorkerListener(listeners, workerCPUs), nil
+}
+
+// wrapTCPListener applies the ACL, knock gate, brutal and padding wrappers.
+func (l *Listener) wrapTCPListener(listener net.Listener) net.Listener {
+	return l.paddingListener(l.brutalListener(l.gateListener(l.aclListener(listener))))
+}
+
+// startTCPWorkers runs an accept loop for the socket of each worker, which
+// hands accepted connections to the handler directly.
+func (l *Listener) startTCPWorkers(listener *workerListener) {
+	for index, workerListener := range listener.listeners {
+		go l.loopTCPWorker(l.wrapTCPListener(workerListener), listener.cpus[index])
+	}
+}
+
+func (l *Listener) loopTCPWorker(listener net.Listener, cpu int) {
+	if cpu >= 0 {
+		runtime.LockOSThread()
+		err := setThreadAffinity(cpu)
+		if err != nil {
+			l.logger.Error(E.Cause(err, "set affinity of worker to CPU ", cpu))
+		}
+	}
+	l.loopTCPIn(listener)
+}
+
+type workerAccept struct {
+	conn net.Conn
+	err  error
+}
+
+// workerListener accepts from the sockets of all workers in parallel, for
+// users of the listener that call Accept instead of the accept loops of
+// workers.
+type workerListener struct {
+	listeners  []net.Listener
+	cpus       []int
+	accept     chan workerAccept
+	done       chan struct{}
+	acceptOnce sync.Once
+	closeOnce  sync.Once
+}
+
+func newWorkerListener(listeners []net.Listener, cpus []int) *workerListener {
+	return &workerListener{
+		listeners: listeners,
+		cpus:      cpus,
+		accept:    make(chan workerAccept),
+		done:      make(chan struct{}),
+	}
+}
+
+func (l *workerListener) loopAccept(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if !l.send(workerAccept{conn: conn, err: err}) {
+			if conn != nil {
+				conn.Close()
+			}
+			return
+		}
+		//nolint:staticcheck
+		if netError, isNetError := err.(net.Error); err != nil && !(isNetError && netError.Temporary()) {
+			return
+		}
+	}
+}
+
+func (l *workerListener) send(result workerAccept) bool {
+	select {
+	case l.accept <- result:
+		return true
+	case <-l.done:
+		return false
+	}
+}
+
+func (l *workerListener) Accept() (net.Conn, error) {
+	l.acceptOnce.Do(func() {
+		for _, listener := range l.listeners {
+			go l.loopAccept(listener)
+		}
+	})
+	select {
+	case result := <-l.accept:
+		return result.conn, result.err
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *workerListener) Close() error {
+	var err error
+	l.closeOnce.Do(func() {
+		close(l.done)
+		for _, listener := range l.listeners {
+			err = E.Errors(err, listener.Close())
+		}
+	})
+	return err
+}
+
+func (l *workerListener) Addr() net.Addr {
+	return l.listeners[0].Addr()
+}
@@ -10,6 +10,8 @@ import (
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/redir"
+	"github.com/sagernet/sing-box/common/systemd"
+	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing/common/buf"
 	"github.com/sagernet/sing/common/control"
 	E "github.com/sagernet/sing/common/exceptions"
@@ -19,7 +21,35 @@ import (
 )
 
 func (l *Listener) ListenUDP() (net.PacketConn, error) {
+	if l.listenOptions.UDPBatch && !C.IsLinux {
+		return nil, E.New("`udp_batch` is only supported on Linux")
+	}
+	err := l.startACL()
+	if err != nil {
+		return nil, err
+	}
+	err = l.startKnock()
+	if err != nil {
+		return nil, err
+	}
 	bindAddr := M.SocksaddrFrom(l.listenOptions.Listen.Build(netip.AddrFrom4([4]byte{127, 0, 0, 1})), l.listenOptions.ListenPort)
+	if l.listenOptions.NetNs == "" {
+		udpConn, err := systemd.PacketConn(bindAddr)
+		if err != nil {
+			return nil, E.Cause(err, "use socket passed by systemd")
+		}
+		if udpConn != nil {
+			systemdConn, isUDPConn := udpConn.(*net.UDPConn)
+			if !isUDPConn {
+				udpConn.Close()
+				return nil, E.New("socket passed by systemd is not a UDP socket: ", udpConn.LocalAddr().Network())
+			}
+			l.udpConn = systemdConn
+			l.udpAddr = bindAddr
+			l.logger.Info("udp server started at ", udpConn.LocalAddr(), " (socket activation)")
+			return l.gatePacketConn(l.aclPacketConn(udpConn)), l.startPortMapping(N.NetworkUDP, udpConn.LocalAddr())
+		}
+	}
 	var listenConfig net.ListenConfig
 	if l.listenOptions.BindInterface != "" {
 		listenConfig.Control = control.Append(listenConfig.Control, control.BindToInterface(service.FromContext[adapter.NetworkManager](l.ctx).InterfaceFinder(), l.listenOptions.BindInterface, -1))
@@ -55,7 +85,7 @@ func (l *Listener) ListenUDP() (net.PacketConn, error) {
 	l.udpConn = udpConn.(*net.UDPConn)
 	l.udpAddr = bindAddr
 	l.logger.Info("udp server started at ", udpConn.LocalAddr())
-	return udpConn, err
+	return l.gatePacketConn(l.aclPacketConn(udpConn)), l.startPortMapping(N.NetworkUDP, udpConn.LocalAddr())
 }
 
 func (l *Listener) DialContext(dialer net.Dialer, ctx context.Context, network string, address string) (net.Conn, error) {
@@ -125,6 +155,12 @@ func (l *Listener) loopUDPIn() {
 				l.logger.Error("udp listener closed: ", err)
 				return
 			}
+			if !l.allowPacket(addr.Addr()) {
+				if l.threadUnsafePacketWriter {
+					buffer.Release()
+				}
+				continue
+			}
 			buffer.Truncate(n)
 			l.oobPacketHandler.NewPacketEx(buffer, oob[:oobN], M.SocksaddrFromNetIP(addr).Unwrap())
 		}
@@ -147,6 +183,12 @@ func (l *Listener) loopUDPIn() {
 				l.logger.Error("udp listener closed: ", err)
 				return
 			}
+			if !l.allowPacket(addr.Addr()) {
+				if l.threadUnsafePacketWriter {
+					buffer.Release()
+				}
+				continue
+			}
 			buffer.Truncate(n)
 			l.packetHandler.NewPacketEx(buffer, M.SocksaddrFromNetIP(addr).Unwrap())
 		}
@@ -0,0 +1,169 @@
+package listener
+
+import (
+	"net"
+	"net/netip"
+
+	"github.com/sagernet/sing/common/buf"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const udpBatchSize = 64
+
+type udpBatchConn interface {
+	ReadBatch(ms []ipv4.Message, flags int) (int, error)
+	WriteBatch(ms []ipv4.Message, flags int) (int, error)
+}
+
+func newUDPBatchConn(udpConn *net.UDPConn) (udpBatchConn, bool) {
+	if M.AddrFromNet(udpConn.LocalAddr()).Is4() {
+		return ipv4.NewPacketConn(udpConn), false
+	} else {
+		return ipv6.NewPacketConn(udpConn), true
+	}
+}
+
+func (l *Listener) loopUDPInBatch() {
+	defer close(l.packetOutboundClosed)
+	batchConn, _ := newUDPBatchConn(l.udpConn)
+	messages := make([]ipv4.Message, udpBatchSize)
+	buffers := make([]*buf.Buffer, udpBatchSize)
+	var oobs [][]byte
+	if l.oobPacketHandler != nil {
+		oobs = make([][]byte, udpBatchSize)
+		for i := range oobs {
+			oobs[i] = make([]byte, 1024)
+		}
+	}
+	if !l.threadUnsafePacketWriter {
+		for i := range buffers {
+			buffers[i] = buf.NewPacket()
+			buffers[i].IncRef()
+		}
+		defer func() {
+			for _, buffer := range buffers {
+				buffer.DecRef()
+				buffer.Release()
+			}
+		}()
+	}
+	for {
+		for i := range messages {
+			if l.threadUnsafePacketWriter {
+				if buffers[i] == nil {
+					buffers[i] = buf.NewPacket()
+				}
+			} else {
+				buffers[i].Reset()
+			}
+			messages[i].Buffers = [][]byte{buffers[i].FreeBytes()}
+			if oobs != nil {
+				messages[i].OOB = oobs[i]
+			}
+		}
+		n, err := batchConn.ReadBatch(messages, 0)
+		if err != nil {
+			if l.threadUnsafePacketWriter {
+				for i, buffer := range buffers {
+					buffer.Release()
+					buffers[i] = nil
+				}
+			}
+			if l.shutdown.Load() && E.IsClosed(err) {
+				return
+			}
+			l.udpConn.Close()
+			l.logger.Error("udp listener closed: ", err)
+			return
+		}
+		for i := 0; i < n; i++ {
+			buffer := buffers[i]
+			if l.threadUnsafePacketWriter {
+				buffers[i] = nil
+			}
+			source := M.SocksaddrFromNet(messages[i].Addr).Unwrap()
+			if !l.allowPacket(source.Addr) {
+				if l.threadUnsafePacketWriter {
+					buffer.Release()
+				}
+				continue
+			}
+			buffer.Truncate(messages[i].N)
+			if oobs != nil {
+				l.oobPacketHandler.NewPacketEx(buffer, messages[i].OOB[:messages[i].NN], source)
+			} else {
+				l.packetHandler.NewPacketEx(buffer, source)
+			}
+		}
+	}
+}
+
+func (l *Listener) loopUDPOutBatch() {
+	batchConn, isIPv6 := newUDPBatchConn(l.udpConn)
+	messages := make([]ipv4.Message, 0, udpBatchSize)
+	packets := make([]*N.PacketBuffer, 0, udpBatchSize)
+	for {
+		select {
+		case packet := <-l.packetOutbound:
+			packets = append(packets, packet)
+		fetch:
+			for len(packets) < udpBatchSize {
+				select {
+				case packet = <-l.packetOutbound:
+					packets = append(packets, packet)
+				default:
+					break fetch
+				}
+			}
+			for _, packet := range packets {
+				destination := packet.Destination.AddrPort()
+				if isIPv6 {
+					destination = netip.AddrPortFrom(netip.AddrFrom16(destination.Addr().As16()), destination.Port())
+				}
+				messages = append(messages, ipv4.Message{
+					Buffers: [][]byte{packet.Buffer.Bytes()},
+					Addr:    net.UDPAddrFromAddrPort(destination),
+				})
+			}
+			pending := messages
+			for len(pending) > 0 {
+				n, err := batchConn.WriteBatch(pending, 0)
+				if err != nil {
+					if l.shutdown.Load() && E.IsClosed(err) {
+						break
+					}
+					l.logger.Error("udp listener write back: ", pending[0].Addr, ": ", err)
+					n = 1
+				}
+				pending = pending[n:]
+			}
+			for i, packet := range packets {
+				packet.Buffer.Release()
+				N.PutPacketBuffer(packet)
+				packets[i] = nil
+				messages[i] = ipv4.Message{}
+			}
+			packets = packets[:0]
+			messages = messages[:0]
+			if l.shutdown.Load() {
+				return
+			}
+			continue
+		case <-l.packetOutboundClosed:
+		}
+		for {
+			select {
+			case packet := <-l.packetOutbound:
+				packet.Buffer.Release()
+				N.PutPacketBuffer(packet)
+			default:
+				return
+			}
+		}
+	}
+}
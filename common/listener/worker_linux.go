@@ -0,0 +1,40 @@
+package listener
+
+import (
+	"syscall"
+
+	"github.com/sagernet/sing/common/control"
+
+	"golang.org/x/sys/unix"
+)
+
+func allowedCPUs() ([]int, error) {
+	var cpuSet unix.CPUSet
+	err := unix.SchedGetaffinity(0, &cpuSet)
+	if err != nil {
+		return nil, err
+	}
+	var cpus []int
+	for cpu := 0; cpu < len(cpuSet)*64; cpu++ {
+		if cpuSet.IsSet(cpu) {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
+
+// incomingCPU makes the kernel prefer the socket for connections received on
+// the CPU in the SO_REUSEPORT group.
+func incomingCPU(cpu int) control.Func {
+	return func(network, address string, conn syscall.RawConn) error {
+		return control.Raw(conn, func(fd uintptr) error {
+			return unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_INCOMING_CPU, cpu)
+		})
+	}
+}
+
+func setThreadAffinity(cpu int) error {
+	var cpuSet unix.CPUSet
+	cpuSet.Set(cpu)
+	return unix.SchedSetaffinity(0, &cpuSet)
+}
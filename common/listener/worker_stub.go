@@ -0,0 +1,21 @@
+//go:build !linux
+
+package listener
+
+import (
+	"os"
+
+	"github.com/sagernet/sing/common/control"
+)
+
+func allowedCPUs() ([]int, error) {
+	return nil, os.ErrInvalid
+}
+
+func incomingCPU(cpu int) control.Func {
+	return nil
+}
+
+func setThreadAffinity(cpu int) error {
+	return os.ErrInvalid
+}
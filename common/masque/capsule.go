@@ -0,0 +1,60 @@
+package masque
+
+import (
+	"io"
+
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// capsuleTypeDatagram is the DATAGRAM capsule of HTTP Datagrams (RFC 9297),
+// carrying UDP payloads with context ID 0.
+const capsuleTypeDatagram = 0x00
+
+// readVarInt reads a variable-length integer of QUIC (RFC 9000, Section 16).
+func readVarInt(reader io.ByteReader) (uint64, error) {
+	first, err := reader.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	length := 1 << (first >> 6)
+	value := uint64(first & 0x3f)
+	for i := 1; i < length; i++ {
+		next, err := reader.ReadByte()
+		if err != nil {
+			return 0, E.Cause(io.ErrUnexpectedEOF, "read varint")
+		}
+		value = value<<8 | uint64(next)
+	}
+	return value, nil
+}
+
+func varIntLen(value uint64) int {
+	switch {
+	case value <= 63:
+		return 1
+	case value <= 16383:
+		return 2
+	case value <= 1073741823:
+		return 4
+	default:
+		return 8
+	}
+}
+
+// putVarInt writes value to the start of b, which must have room for it.
+func putVarInt(b []byte, value uint64) int {
+	length := varIntLen(value)
+	for i := length - 1; i >= 0; i-- {
+		b[i] = byte(value)
+		value >>= 8
+	}
+	switch length {
+	case 2:
+		b[0] |= 0x40
+	case 4:
+		b[0] |= 0x80
+	case 8:
+		b[0] |= 0xc0
+	}
+	return length
+}
@@ -0,0 +1,101 @@
+package masque
+
+import (
+	std_bufio "bufio"
+	"io"
+	"net"
+
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/buf"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+var _ N.PacketConn = (*StreamPacketConn)(nil)
+
+// StreamPacketConn carries UDP payloads to and from the target in DATAGRAM
+// capsules on the request stream. Other capsules are skipped.
+type StreamPacketConn struct {
+	net.Conn
+	reader      *std_bufio.Reader
+	destination M.Socksaddr
+}
+
+// NewStreamPacketConn reads capsules from reader, or from conn if nil, and
+// writes capsules to conn.
+func NewStreamPacketConn(conn net.Conn, reader io.Reader, destination M.Socksaddr) *StreamPacketConn {
+	if reader == nil {
+		reader = conn
+	}
+	bufReader, isBufReader := reader.(*std_bufio.Reader)
+	if !isBufReader {
+		bufReader = std_bufio.NewReader(reader)
+	}
+	return &StreamPacketConn{
+		Conn:        conn,
+		reader:      bufReader,
+		destination: destination,
+	}
+}
+
+func (c *StreamPacketConn) ReadPacket(buffer *buf.Buffer) (destination M.Socksaddr, err error) {
+	for {
+		var capsuleType, length uint64
+		capsuleType, err = readVarInt(c.reader)
+		if err != nil {
+			return
+		}
+		length, err = readVarInt(c.reader)
+		if err != nil {
+			return
+		}
+		if capsuleType != capsuleTypeDatagram {
+			_, err = c.reader.Discard(int(length))
+			if err != nil {
+				return
+			}
+			continue
+		}
+		var contextID uint64
+		contextID, err = readVarInt(c.reader)
+		if err != nil {
+			return
+		}
+		if length < uint64(varIntLen(contextID)) {
+			return M.Socksaddr{}, E.New("masque: invalid datagram capsule")
+		}
+		payloadLength := int(length) - varIntLen(contextID)
+		if contextID != 0 || payloadLength > buffer.FreeLen() {
+			_, err = c.reader.Discard(payloadLength)
+			if err != nil {
+				return
+			}
+			continue
+		}
+		_, err = buffer.ReadFullFrom(c.reader, payloadLength)
+		if err != nil {
+			return
+		}
+		return c.destination, nil
+	}
+}
+
+func (c *StreamPacketConn) WritePacket(buffer *buf.Buffer, destination M.Socksaddr) error {
+	defer buffer.Release()
+	// context ID 0 takes one byte
+	length := uint64(buffer.Len() + 1)
+	header := buffer.ExtendHeader(1 + varIntLen(length) + 1)
+	header[0] = capsuleTypeDatagram
+	n := putVarInt(header[1:], length)
+	header[1+n] = 0
+	return common.Error(c.Conn.Write(buffer.Bytes()))
+}
+
+func (c *StreamPacketConn) FrontHeadroom() int {
+	return 1 + 8 + 1
+}
+
+func (c *StreamPacketConn) Upstream() any {
+	return c.Conn
+}
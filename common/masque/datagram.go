@@ -0,0 +1,136 @@
+package masque
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing/common/buf"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/pipe"
+)
+
+const datagramQueueSize = 64
+
+// DatagramStream is a request stream of HTTP/3 with HTTP Datagrams (RFC 9297).
+type DatagramStream interface {
+	SendDatagram(b []byte) error
+	ReceiveDatagram(ctx context.Context) ([]byte, error)
+}
+
+var _ N.PacketConn = (*DatagramPacketConn)(nil)
+
+// DatagramPacketConn carries UDP payloads to and from the target in HTTP
+// Datagrams with context ID 0. The connection is closed with the request
+// stream, capsules on the stream are ignored.
+type DatagramPacketConn struct {
+	net.Conn
+	stream       DatagramStream
+	destination  M.Socksaddr
+	ctx          context.Context
+	cancel       context.CancelFunc
+	datagrams    chan []byte
+	readDeadline pipe.Deadline
+	closeOnce    sync.Once
+}
+
+// NewDatagramPacketConn returns a connection of stream, with conn reading
+// and closing the request stream.
+func NewDatagramPacketConn(ctx context.Context, stream DatagramStream, conn net.Conn, destination M.Socksaddr) *DatagramPacketConn {
+	ctx, cancel := context.WithCancel(ctx)
+	c := &DatagramPacketConn{
+		Conn:         conn,
+		stream:       stream,
+		destination:  destination,
+		ctx:          ctx,
+		cancel:       cancel,
+		datagrams:    make(chan []byte, datagramQueueSize),
+		readDeadline: pipe.MakeDeadline(),
+	}
+	go c.loopReceive()
+	go c.waitStream()
+	return c
+}
+
+func (c *DatagramPacketConn) loopReceive() {
+	for {
+		datagram, err := c.stream.ReceiveDatagram(c.ctx)
+		if err != nil {
+			c.Close()
+			return
+		}
+		select {
+		case c.datagrams <- datagram:
+		default:
+		}
+	}
+}
+
+func (c *DatagramPacketConn) waitStream() {
+	_, _ = io.Copy(io.Discard, c.Conn)
+	c.Close()
+}
+
+func (c *DatagramPacketConn) ReadPacket(buffer *buf.Buffer) (destination M.Socksaddr, err error) {
+	for {
+		var datagram []byte
+		select {
+		case datagram = <-c.datagrams:
+		case <-c.ctx.Done():
+			return M.Socksaddr{}, net.ErrClosed
+		case <-c.readDeadline.Wait():
+			return M.Socksaddr{}, os.ErrDeadlineExceeded
+		}
+		reader := buf.As(datagram)
+		contextID, err := readVarInt(reader)
+		if err != nil || contextID != 0 {
+			continue
+		}
+		_, err = buffer.Write(reader.Bytes())
+		if err != nil {
+			return M.Socksaddr{}, err
+		}
+		return c.destination, nil
+	}
+}
+
+func (c *DatagramPacketConn) WritePacket(buffer *buf.Buffer, destination M.Socksaddr) error {
+	defer buffer.Release()
+	buffer.ExtendHeader(1)[0] = 0
+	return c.stream.SendDatagram(buffer.Bytes())
+}
+
+func (c *DatagramPacketConn) FrontHeadroom() int {
+	return 1
+}
+
+func (c *DatagramPacketConn) SetDeadline(t time.Time) error {
+	c.readDeadline.Set(t)
+	return nil
+}
+
+func (c *DatagramPacketConn) SetReadDeadline(t time.Time) error {
+	c.readDeadline.Set(t)
+	return nil
+}
+
+func (c *DatagramPacketConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *DatagramPacketConn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		c.cancel()
+		err = c.Conn.Close()
+	})
+	return err
+}
+
+func (c *DatagramPacketConn) Upstream() any {
+	return c.Conn
+}
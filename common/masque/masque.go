@@ -0,0 +1,72 @@
+package masque
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+)
+
+// UpgradeToken is the HTTP Upgrade Token of UDP proxying over HTTP (RFC 9298).
+const UpgradeToken = "connect-udp"
+
+const (
+	// DefaultURIPrefix is the prefix of the default URI template
+	// /.well-known/masque/udp/{target_host}/{target_port}/
+	DefaultURIPrefix = "/.well-known/masque/udp/"
+
+	HeaderCapsuleProtocol = "Capsule-Protocol"
+)
+
+// IsUDPRequest checks for an HTTP/1.1 Upgrade request, or an extended CONNECT
+// request of HTTP/2 and HTTP/3.
+func IsUDPRequest(request *http.Request) bool {
+	switch request.ProtoMajor {
+	case 1:
+		return request.Method == http.MethodGet && headerContainsToken(request.Header, "Connection", "upgrade") && headerContainsToken(request.Header, "Upgrade", UpgradeToken)
+	case 2:
+		return request.Method == http.MethodConnect && request.Header.Get(":protocol") == UpgradeToken
+	default:
+		return request.Method == http.MethodConnect && request.Proto == UpgradeToken
+	}
+}
+
+// ParseUDPTarget parses the target of the default URI template, in the path
+// or in the target of HTTP/1.1 requests in absolute form.
+func ParseUDPTarget(requestURL *url.URL) (M.Socksaddr, error) {
+	path := requestURL.EscapedPath()
+	if !strings.HasPrefix(path, DefaultURIPrefix) {
+		return M.Socksaddr{}, E.New("masque: unknown URI template: ", path)
+	}
+	parts := strings.Split(strings.TrimSuffix(strings.TrimPrefix(path, DefaultURIPrefix), "/"), "/")
+	if len(parts) != 2 {
+		return M.Socksaddr{}, E.New("masque: invalid target: ", path)
+	}
+	host, err := url.PathUnescape(parts[0])
+	if err != nil {
+		return M.Socksaddr{}, E.Cause(err, "masque: invalid target host")
+	}
+	port, err := strconv.ParseUint(parts[1], 10, 16)
+	if err != nil || port == 0 {
+		return M.Socksaddr{}, E.New("masque: invalid target port: ", parts[1])
+	}
+	destination := M.ParseSocksaddrHostPort(host, uint16(port))
+	if !destination.IsValid() {
+		return M.Socksaddr{}, E.New("masque: invalid target host: ", host)
+	}
+	return destination.Unwrap(), nil
+}
+
+func headerContainsToken(header http.Header, name string, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, element := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(element), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
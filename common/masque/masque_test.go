@@ -0,0 +1,48 @@
+package masque
+
+import (
+	"bytes"
+	"net/url"
+	"testing"
+
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUDPTarget(t *testing.T) {
+	t.Parallel()
+	for path, destination := range map[string]string{
+		"/.well-known/masque/udp/192.0.2.6/443/":         "192.0.2.6:443",
+		"/.well-known/masque/udp/2001%3Adb8%3A%3A42/53/": "[2001:db8::42]:53",
+		"/.well-known/masque/udp/example.com/53/":        "example.com:53",
+	} {
+		requestURL, err := url.Parse(path)
+		require.NoError(t, err)
+		target, err := ParseUDPTarget(requestURL)
+		require.NoError(t, err)
+		require.Equal(t, M.ParseSocksaddr(destination), target)
+	}
+	for _, path := range []string{
+		"/masque/udp/192.0.2.6/443/",
+		"/.well-known/masque/udp/192.0.2.6/",
+		"/.well-known/masque/udp/192.0.2.6/0/",
+	} {
+		requestURL, err := url.Parse(path)
+		require.NoError(t, err)
+		_, err = ParseUDPTarget(requestURL)
+		require.Error(t, err)
+	}
+}
+
+func TestVarInt(t *testing.T) {
+	t.Parallel()
+	for _, value := range []uint64{0, 63, 64, 16383, 16384, 1073741823, 1073741824, 1<<62 - 1} {
+		var b [8]byte
+		n := putVarInt(b[:], value)
+		require.Equal(t, varIntLen(value), n)
+		decoded, err := readVarInt(bytes.NewReader(b[:n]))
+		require.NoError(t, err)
+		require.Equal(t, value, decoded)
+	}
+}
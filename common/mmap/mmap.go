@@ -0,0 +1,87 @@
+package mmap
+
+import (
+	"os"
+	"runtime/debug"
+	"sync"
+
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+var (
+	access sync.Mutex
+	files  = make(map[string]*File)
+)
+
+// File is a read-only mapping of a file. Mappings of unchanged files are
+// shared between all openers and released when the last one is closed.
+type File struct {
+	path    string
+	info    os.FileInfo
+	content []byte
+	refs    int
+}
+
+func Open(path string) (*File, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	access.Lock()
+	defer access.Unlock()
+	file, loaded := files[path]
+	if loaded && os.SameFile(file.info, info) && file.info.Size() == info.Size() && file.info.ModTime().Equal(info.ModTime()) {
+		file.refs++
+		return file, nil
+	}
+	content, err := mapFile(path, info.Size())
+	if err != nil {
+		return nil, err
+	}
+	file = &File{
+		path:    path,
+		info:    info,
+		content: content,
+		refs:    1,
+	}
+	// a changed file replaces the shared entry, older mappings stay valid for their holders
+	files[path] = file
+	return file, nil
+}
+
+func (f *File) Info() os.FileInfo {
+	return f.info
+}
+
+// Access calls accessFunc with the mapped content. Faults caused by the
+// file being truncated while it is mapped are returned as errors.
+func (f *File) Access(accessFunc func(content []byte) error) (err error) {
+	defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			if _, isFault := recovered.(interface{ Addr() uintptr }); !isFault {
+				panic(recovered)
+			}
+			err = E.New("access mapped file ", f.path, ": ", recovered)
+		}
+	}()
+	return accessFunc(f.content)
+}
+
+func (f *File) Close() error {
+	access.Lock()
+	defer access.Unlock()
+	if f.refs == 0 {
+		return os.ErrClosed
+	}
+	f.refs--
+	if f.refs > 0 {
+		return nil
+	}
+	if files[f.path] == f {
+		delete(files, f.path)
+	}
+	content := f.content
+	f.content = nil
+	return unmapFile(content)
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package mmap
+
+import "os"
+
+func mapFile(path string, size int64) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+func unmapFile(content []byte) error {
+	return nil
+}
@@ -0,0 +1,32 @@
+//go:build unix
+
+package mmap
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+func mapFile(path string, size int64) ([]byte, error) {
+	if size == 0 {
+		return nil, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	content, err := unix.Mmap(int(file.Fd()), 0, int(size), unix.PROT_READ, unix.MAP_SHARED)
+	if err != nil {
+		return nil, os.NewSyscallError("mmap", err)
+	}
+	return content, nil
+}
+
+func unmapFile(content []byte) error {
+	if content == nil {
+		return nil
+	}
+	return unix.Munmap(content)
+}
@@ -3,11 +3,13 @@ package mux
 import (
 	"context"
 	"net"
+	"sync"
 
 	"github.com/sagernet/sing-box/adapter"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing-mux"
+	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/logger"
 	M "github.com/sagernet/sing/common/metadata"
@@ -57,3 +59,80 @@ func (d *clientDialer) DialContext(ctx context.Context, network string, destinat
 func (d *clientDialer) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
 	return d.Dialer.ListenPacket(adapter.OverrideContext(ctx), destination)
 }
+
+// OutboundClient selects the multiplex client of an outbound for each
+// connection following the multiplex route options of the connection, clients
+// which are not configured in the outbound are created on first use.
+type OutboundClient struct {
+	dialer  N.Dialer
+	logger  logger.Logger
+	options option.OutboundMultiplexOptions
+	client  *Client
+	access  sync.Mutex
+	created map[bool]*Client
+}
+
+func NewOutboundClient(dialer N.Dialer, logger logger.Logger, options option.OutboundMultiplexOptions) (*OutboundClient, error) {
+	client, err := NewClientWithOptions(dialer, logger, options)
+	if err != nil {
+		return nil, err
+	}
+	return &OutboundClient{
+		dialer:  dialer,
+		logger:  logger,
+		options: options,
+		client:  client,
+		created: make(map[bool]*Client),
+	}, nil
+}
+
+// Client returns the multiplex client for the connection, or nil if the
+// connection should be dialed directly.
+func (c *OutboundClient) Client(ctx context.Context) (*Client, error) {
+	metadata := adapter.ContextFrom(ctx)
+	if metadata == nil || metadata.Multiplex == "" && !metadata.MultiplexPadding {
+		return c.client, nil
+	} else if metadata.Multiplex == C.MultiplexDisabled {
+		return nil, nil
+	}
+	padding := metadata.MultiplexPadding || c.options.Padding
+	if c.client != nil && c.options.Padding == padding {
+		return c.client, nil
+	}
+	c.access.Lock()
+	defer c.access.Unlock()
+	client, loaded := c.created[padding]
+	if loaded {
+		return client, nil
+	}
+	options := c.options
+	options.Enabled = true
+	options.Padding = padding
+	client, err := NewClientWithOptions(c.dialer, c.logger, options)
+	if err != nil {
+		return nil, E.Cause(err, "create multiplex client")
+	}
+	c.created[padding] = client
+	return client, nil
+}
+
+func (c *OutboundClient) Reset() {
+	if c.client != nil {
+		c.client.Reset()
+	}
+	c.access.Lock()
+	defer c.access.Unlock()
+	for _, client := range c.created {
+		client.Reset()
+	}
+}
+
+func (c *OutboundClient) Close() error {
+	c.access.Lock()
+	defer c.access.Unlock()
+	closers := []any{common.PtrOrNil(c.client)}
+	for _, client := range c.created {
+		closers = append(closers, client)
+	}
+	return common.Close(closers...)
+}
@@ -0,0 +1,241 @@
+package pac
+
+import (
+	std_bufio "bufio"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+const (
+	DefaultPath = "/proxy.pac"
+	WPADPath    = "/wpad.dat"
+	ContentType = "application/x-ns-proxy-autoconfig"
+)
+
+// Server serves the PAC file of an HTTP proxy inbound to requests in origin
+// form, which are not proxy requests.
+type Server struct {
+	router          adapter.Router
+	logger          log.ContextLogger
+	path            string
+	proxy           string
+	https           bool
+	bypassOutbounds []string
+	bypass          adapter.ProxyBypass
+}
+
+// NewServer creates the PAC server of options, or returns nil if not enabled.
+func NewServer(router adapter.Router, logger log.ContextLogger, options *option.HTTPPACOptions, https bool) (*Server, error) {
+	if options == nil || !options.Enabled {
+		return nil, nil
+	}
+	server := &Server{
+		router:          router,
+		logger:          logger,
+		path:            options.Path,
+		proxy:           options.Proxy,
+		https:           https,
+		bypassOutbounds: options.BypassOutbound,
+		bypass: adapter.ProxyBypass{
+			Domain:       options.BypassDomain,
+			DomainSuffix: options.BypassDomainSuffix,
+		},
+	}
+	if server.path == "" {
+		server.path = DefaultPath
+	} else if !strings.HasPrefix(server.path, "/") {
+		return nil, E.New("pac: path must start with /")
+	}
+	if server.proxy != "" {
+		_, _, err := net.SplitHostPort(server.proxy)
+		if err != nil {
+			return nil, E.Cause(err, "pac: parse proxy")
+		}
+	}
+	for _, prefixString := range options.BypassIPCIDR {
+		prefix, err := netip.ParsePrefix(prefixString)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(prefixString)
+			if addrErr != nil {
+				return nil, E.Cause(err, "pac: parse bypass_ip_cidr")
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		server.bypass.IPCIDR = append(server.bypass.IPCIDR, prefix)
+	}
+	return server, nil
+}
+
+// Match checks whether the buffered request is a GET or HEAD request of the
+// PAC file, other requests are left to the proxy handler.
+func (s *Server) Match(reader *std_bufio.Reader) bool {
+	header, err := reader.Peek(6)
+	if err != nil || !bytes.HasPrefix(header, []byte("GET /")) && !bytes.HasPrefix(header, []byte("HEAD /")) {
+		return false
+	}
+	var requestLine []byte
+	for size := len(header) + 1; ; size = reader.Buffered() + 1 {
+		content, _ := reader.Peek(reader.Buffered())
+		index := bytes.IndexByte(content, '\n')
+		if index >= 0 {
+			requestLine = content[:index]
+			break
+		}
+		_, err = reader.Peek(size)
+		if err != nil {
+			return false
+		}
+	}
+	fields := strings.Fields(string(requestLine))
+	if len(fields) != 3 {
+		return false
+	}
+	path, _, _ := strings.Cut(fields[1], "?")
+	return path == s.path || path == WPADPath
+}
+
+// HandleConnectionEx serves the request matched by Match and closes the
+// connection, errors are returned like of the proxy handlers.
+func (s *Server) HandleConnectionEx(ctx context.Context, conn net.Conn, reader *std_bufio.Reader, onClose N.CloseHandlerFunc) error {
+	request, err := http.ReadRequest(reader)
+	if err != nil {
+		return E.Cause(err, "pac: read request")
+	}
+	response := &http.Response{
+		Proto:      request.Proto,
+		ProtoMajor: request.ProtoMajor,
+		ProtoMinor: request.ProtoMinor,
+		Header:     make(http.Header),
+		Close:      true,
+	}
+	proxy := s.proxy
+	if proxy == "" {
+		proxy = M.SocksaddrFromNet(conn.LocalAddr()).Unwrap().String()
+	}
+	content := []byte(s.Generate(proxy))
+	s.logger.DebugContext(ctx, "pac request ", request.URL.Path, " from ", conn.RemoteAddr())
+	response.StatusCode = http.StatusOK
+	response.Status = http.StatusText(http.StatusOK)
+	response.Header.Set("Content-Type", ContentType)
+	response.Header.Set("Cache-Control", "no-cache")
+	response.Header.Set("Content-Length", strconv.Itoa(len(content)))
+	response.ContentLength = int64(len(content))
+	if request.Method != http.MethodHead {
+		response.Body = io.NopCloser(bytes.NewReader(content))
+	}
+	err = response.Write(conn)
+	if err != nil {
+		return E.Cause(err, "pac: write response")
+	}
+	conn.Close()
+	if onClose != nil {
+		onClose(nil)
+	}
+	return nil
+}
+
+// Bypass returns the configured bypass lists merged with the route rules
+// routed to the bypass outbounds.
+func (s *Server) Bypass() adapter.ProxyBypass {
+	bypass := adapter.ProxyBypass{
+		Domain:       append([]string(nil), s.bypass.Domain...),
+		DomainSuffix: append([]string(nil), s.bypass.DomainSuffix...),
+		IPCIDR:       append([]netip.Prefix(nil), s.bypass.IPCIDR...),
+	}
+	if len(s.bypassOutbounds) == 0 {
+		return bypass
+	}
+	for _, rule := range s.router.Rules() {
+		bypassRule, isBypassRule := rule.(adapter.ProxyBypassRule)
+		if !isBypassRule {
+			continue
+		}
+		ruleBypass, loaded := bypassRule.ProxyBypass()
+		if !loaded || !common.Contains(s.bypassOutbounds, ruleBypass.Outbound) {
+			continue
+		}
+		bypass.Domain = append(bypass.Domain, ruleBypass.Domain...)
+		bypass.DomainSuffix = append(bypass.DomainSuffix, ruleBypass.DomainSuffix...)
+		bypass.IPCIDR = append(bypass.IPCIDR, ruleBypass.IPCIDR...)
+	}
+	return bypass
+}
+
+// Generate generates the PAC file using proxy as the proxy address.
+func (s *Server) Generate(proxy string) string {
+	bypass := s.Bypass()
+	proxyType := "PROXY"
+	if s.https {
+		proxyType = "HTTPS"
+	}
+	var builder strings.Builder
+	builder.WriteString("var domains = ")
+	builder.Write(common.Must1(json.Marshal(common.Map(bypass.Domain, strings.ToLower))))
+	builder.WriteString(";\nvar domainSuffixes = ")
+	builder.Write(common.Must1(json.Marshal(common.Map(bypass.DomainSuffix, strings.ToLower))))
+	builder.WriteString(";\nvar ipv4Networks = [")
+	for i, prefix := range common.Filter(bypass.IPCIDR, func(it netip.Prefix) bool {
+		return it.Addr().Is4()
+	}) {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+		prefix = prefix.Masked()
+		mask := net.CIDRMask(prefix.Bits(), 32)
+		builder.WriteString("[\"")
+		builder.WriteString(prefix.Addr().String())
+		builder.WriteString("\", \"")
+		builder.WriteString(net.IP(mask).String())
+		builder.WriteString("\"]")
+	}
+	builder.WriteString("];\nvar proxy = ")
+	builder.Write(common.Must1(json.Marshal(proxyType + " " + proxy)))
+	builder.WriteString(";\n")
+	builder.WriteString(findProxyForURL)
+	return builder.String()
+}
+
+const findProxyForURL = `
+function FindProxyForURL(url, host) {
+  host = host.toLowerCase();
+  if (isPlainHostName(host) || host === "localhost") {
+    return "DIRECT";
+  }
+  if (domains.indexOf(host) !== -1) {
+    return "DIRECT";
+  }
+  for (var i = 0; i < domainSuffixes.length; i++) {
+    var suffix = domainSuffixes[i];
+    if (suffix.charAt(0) === ".") {
+      if (dnsDomainIs(host, suffix)) {
+        return "DIRECT";
+      }
+    } else if (host === suffix || dnsDomainIs(host, "." + suffix)) {
+      return "DIRECT";
+    }
+  }
+  if (/^\d+\.\d+\.\d+\.\d+$/.test(host)) {
+    for (var j = 0; j < ipv4Networks.length; j++) {
+      if (isInNet(host, ipv4Networks[j][0], ipv4Networks[j][1])) {
+        return "DIRECT";
+      }
+    }
+  }
+  return proxy;
+}
+`
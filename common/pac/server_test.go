@@ -0,0 +1,43 @@
+package pac
+
+import (
+	std_bufio "bufio"
+	"strings"
+	"testing"
+
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerMatch(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer(nil, log.NewNOPFactory().Logger(), &option.HTTPPACOptions{Enabled: true}, false)
+	require.NoError(t, err)
+	for request, matched := range map[string]bool{
+		"GET /proxy.pac HTTP/1.1\r\nHost: proxy\r\n\r\n":                                    true,
+		"HEAD /wpad.dat?x=1 HTTP/1.1\r\n\r\n":                                               true,
+		"GET /other HTTP/1.1\r\n\r\n":                                                       false,
+		"GET http://example.com/proxy.pac HTTP/1.1\r\n\r\n":                                 false,
+		"GET /.well-known/masque/udp/example.com/443/ HTTP/1.1\r\nUpgrade: connect-udp\r\n": false,
+		"CONNECT example.com:443 HTTP/1.1\r\n\r\n":                                          false,
+	} {
+		require.Equal(t, matched, server.Match(std_bufio.NewReader(strings.NewReader(request))), request)
+	}
+}
+
+func TestServerGenerate(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer(nil, log.NewNOPFactory().Logger(), &option.HTTPPACOptions{
+		Enabled:            true,
+		BypassDomainSuffix: []string{"Example.org"},
+		BypassIPCIDR:       []string{"10.0.0.0/8", "192.168.1.1", "fd00::/8"},
+	}, true)
+	require.NoError(t, err)
+	content := server.Generate("127.0.0.1:8080")
+	require.Contains(t, content, `var domains = [];`)
+	require.Contains(t, content, `var domainSuffixes = ["example.org"];`)
+	require.Contains(t, content, `var ipv4Networks = [["10.0.0.0", "255.0.0.0"], ["192.168.1.1", "255.255.255.255"]];`)
+	require.Contains(t, content, `var proxy = "HTTPS 127.0.0.1:8080";`)
+}
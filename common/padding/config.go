@@ -0,0 +1,126 @@
+package padding
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"slices"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+const (
+	MinPacketSize = 32
+	maxPacketSize = 65535
+
+	maxPacketSizes   = 16
+	minDummyInterval = 100 * time.Millisecond
+)
+
+// DefaultPacketSizes are proposed by clients without packet sizes configured.
+var DefaultPacketSizes = []uint16{128, 256, 512, 1024, 1400}
+
+// Config is the shaping of one direction, a zero value only frames data.
+type Config struct {
+	key           [sha256.Size]byte
+	packetSizes   []uint16
+	dummyInterval time.Duration
+	burstSize     uint32
+	burstInterval time.Duration
+}
+
+func NewConfig(options option.PaddingOptions) (*Config, error) {
+	config := &Config{
+		key:           sha256.Sum256([]byte(options.Password)),
+		packetSizes:   slices.Clone(options.PacketSizes),
+		dummyInterval: time.Duration(options.DummyInterval),
+		burstSize:     options.BurstSize,
+		burstInterval: time.Duration(options.BurstInterval),
+	}
+	err := config.validate()
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (c *Config) validate() error {
+	if len(c.packetSizes) > maxPacketSizes {
+		return E.New("too many packet sizes, at most ", maxPacketSizes, " allowed")
+	}
+	for _, size := range c.packetSizes {
+		if size < MinPacketSize {
+			return E.New("packet size ", size, " too small, at least ", MinPacketSize, " required")
+		}
+	}
+	slices.Sort(c.packetSizes)
+	c.packetSizes = slices.Compact(c.packetSizes)
+	if c.dummyInterval != 0 && c.dummyInterval < minDummyInterval {
+		return E.New("dummy interval too short, at least ", minDummyInterval, " required")
+	}
+	if (c.burstSize == 0) != (c.burstInterval == 0) {
+		return E.New("burst size and burst interval must be set together")
+	}
+	return nil
+}
+
+// negotiate returns the shaping of the server, where options not configured
+// on the server are taken from the client proposal.
+func (c *Config) negotiate(proposal *Config) *Config {
+	config := *c
+	if len(config.packetSizes) == 0 {
+		config.packetSizes = proposal.packetSizes
+	}
+	if config.dummyInterval == 0 {
+		config.dummyInterval = proposal.dummyInterval
+	}
+	if config.burstSize == 0 {
+		config.burstSize = proposal.burstSize
+		config.burstInterval = proposal.burstInterval
+	}
+	return &config
+}
+
+func (c *Config) clientProposal() *Config {
+	if len(c.packetSizes) > 0 {
+		return c
+	}
+	config := *c
+	config.packetSizes = DefaultPacketSizes
+	return &config
+}
+
+// marshalProposal encodes the shaping in the hello:
+//
+//	count (1) | packet sizes (2 * count) | dummy interval in ms (4) | burst size (4) | burst interval in ms (4)
+func (c *Config) marshalProposal() []byte {
+	proposal := make([]byte, 1+2*len(c.packetSizes)+12)
+	proposal[0] = byte(len(c.packetSizes))
+	offset := 1
+	for _, size := range c.packetSizes {
+		binary.BigEndian.PutUint16(proposal[offset:], size)
+		offset += 2
+	}
+	binary.BigEndian.PutUint32(proposal[offset:], uint32(c.dummyInterval.Milliseconds()))
+	binary.BigEndian.PutUint32(proposal[offset+4:], c.burstSize)
+	binary.BigEndian.PutUint32(proposal[offset+8:], uint32(c.burstInterval.Milliseconds()))
+	return proposal
+}
+
+func unmarshalProposal(sizes []byte, content []byte) (*Config, error) {
+	config := &Config{
+		packetSizes:   make([]uint16, 0, len(sizes)/2),
+		dummyInterval: time.Duration(binary.BigEndian.Uint32(content)) * time.Millisecond,
+		burstSize:     binary.BigEndian.Uint32(content[4:]),
+		burstInterval: time.Duration(binary.BigEndian.Uint32(content[8:])) * time.Millisecond,
+	}
+	for i := 0; i < len(sizes); i += 2 {
+		config.packetSizes = append(config.packetSizes, binary.BigEndian.Uint16(sizes[i:]))
+	}
+	err := config.validate()
+	if err != nil {
+		return nil, E.Cause(err, "bad proposal")
+	}
+	return config, nil
+}
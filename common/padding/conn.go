@@ -0,0 +1,323 @@
+package padding
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	mRand "math/rand/v2"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing/common/buf"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// The client starts with a hello, followed by frames in both directions,
+// everything after the nonce is encrypted with AES-CTR keyed per direction:
+//
+//	hello: nonce (16) | version (1) | padding length (2) | proposal | padding
+//	frame: data length (2) | padding length (2) | data | padding
+const (
+	Version           = 1
+	nonceLength       = 16
+	frameHeaderLength = 4
+	maxHelloPadding   = 512
+)
+
+var _ net.Conn = (*Conn)(nil)
+
+type Conn struct {
+	net.Conn
+	localConfig      *Config
+	config           *Config
+	handshakeAccess  sync.Mutex
+	handshakeDone    bool
+	handshakeErr     error
+	readStream       cipher.Stream
+	dataRemaining    int
+	paddingRemaining int
+	writeAccess      sync.Mutex
+	writeStream      cipher.Stream
+	lastWrite        time.Time
+	burstStart       time.Time
+	burstWritten     uint32
+	closeOnce        sync.Once
+	closed           chan struct{}
+}
+
+func NewClientConn(conn net.Conn, config *Config) (*Conn, error) {
+	proposal := config.clientProposal()
+	var nonce [nonceLength]byte
+	_, _ = rand.Read(nonce[:])
+	c := &Conn{
+		Conn:          conn,
+		config:        proposal,
+		handshakeDone: true,
+		readStream:    newStream(config.key, nonce, "server"),
+		writeStream:   newStream(config.key, nonce, "client"),
+		closed:        make(chan struct{}),
+	}
+	proposalContent := proposal.marshalProposal()
+	paddingLength := mRand.IntN(maxHelloPadding)
+	hello := make([]byte, nonceLength+3+len(proposalContent)+paddingLength)
+	copy(hello, nonce[:])
+	hello[nonceLength] = Version
+	binary.BigEndian.PutUint16(hello[nonceLength+1:], uint16(paddingLength))
+	copy(hello[nonceLength+3:], proposalContent)
+	c.writeStream.XORKeyStream(hello[nonceLength:], hello[nonceLength:])
+	_, err := conn.Write(hello)
+	if err != nil {
+		return nil, E.Cause(err, "write padding hello")
+	}
+	c.lastWrite = time.Now()
+	c.startDummy()
+	return c, nil
+}
+
+// NewServerConn returns a conn that reads the hello on first use.
+func NewServerConn(conn net.Conn, config *Config) *Conn {
+	return &Conn{
+		Conn:        conn,
+		localConfig: config,
+		closed:      make(chan struct{}),
+	}
+}
+
+func newStream(key [sha256.Size]byte, nonce [nonceLength]byte, label string) cipher.Stream {
+	mac := hmac.New(sha256.New, key[:])
+	mac.Write([]byte(label))
+	mac.Write(nonce[:])
+	block, _ := aes.NewCipher(mac.Sum(nil))
+	return cipher.NewCTR(block, make([]byte, aes.BlockSize))
+}
+
+func (c *Conn) handshake() error {
+	c.handshakeAccess.Lock()
+	defer c.handshakeAccess.Unlock()
+	if c.handshakeDone {
+		return c.handshakeErr
+	}
+	c.handshakeDone = true
+	c.handshakeErr = c.readHello()
+	if c.handshakeErr == nil {
+		c.startDummy()
+	}
+	return c.handshakeErr
+}
+
+func (c *Conn) readHello() error {
+	header := make([]byte, nonceLength+4)
+	_, err := io.ReadFull(c.Conn, header)
+	if err != nil {
+		return E.Cause(err, "read padding hello")
+	}
+	var nonce [nonceLength]byte
+	copy(nonce[:], header)
+	c.readStream = newStream(c.localConfig.key, nonce, "client")
+	c.writeStream = newStream(c.localConfig.key, nonce, "server")
+	c.readStream.XORKeyStream(header[nonceLength:], header[nonceLength:])
+	if header[nonceLength] != Version {
+		return E.New("bad padding hello: unknown version ", header[nonceLength])
+	}
+	paddingLength := int(binary.BigEndian.Uint16(header[nonceLength+1:]))
+	sizeCount := int(header[nonceLength+3])
+	if sizeCount > maxPacketSizes || paddingLength > maxHelloPadding {
+		return E.New("bad padding hello")
+	}
+	content := make([]byte, 2*sizeCount+12+paddingLength)
+	_, err = io.ReadFull(c.Conn, content)
+	if err != nil {
+		return E.Cause(err, "read padding hello")
+	}
+	c.readStream.XORKeyStream(content, content)
+	proposal, err := unmarshalProposal(content[:2*sizeCount], content[2*sizeCount:])
+	if err != nil {
+		return E.Cause(err, "bad padding hello")
+	}
+	c.config = c.localConfig.negotiate(proposal)
+	return nil
+}
+
+func (c *Conn) Read(p []byte) (n int, err error) {
+	err = c.handshake()
+	if err != nil {
+		return
+	}
+	if len(p) == 0 {
+		return
+	}
+	for {
+		if c.dataRemaining > 0 {
+			if len(p) > c.dataRemaining {
+				p = p[:c.dataRemaining]
+			}
+			n, err = c.Conn.Read(p)
+			c.readStream.XORKeyStream(p[:n], p[:n])
+			c.dataRemaining -= n
+			if err == io.EOF && c.dataRemaining > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return
+		}
+		if c.paddingRemaining > 0 {
+			err = c.discardPadding()
+			if err != nil {
+				return
+			}
+		}
+		var header [frameHeaderLength]byte
+		_, err = io.ReadFull(c.Conn, header[:])
+		if err != nil {
+			return
+		}
+		c.readStream.XORKeyStream(header[:], header[:])
+		c.dataRemaining = int(binary.BigEndian.Uint16(header[:]))
+		c.paddingRemaining = int(binary.BigEndian.Uint16(header[2:]))
+		if c.dataRemaining == 0 && c.paddingRemaining > 0 {
+			// dummy frame
+			err = c.discardPadding()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *Conn) discardPadding() error {
+	var scratch [1024]byte
+	for c.paddingRemaining > 0 {
+		n := min(c.paddingRemaining, len(scratch))
+		_, err := io.ReadFull(c.Conn, scratch[:n])
+		if err != nil {
+			return err
+		}
+		c.readStream.XORKeyStream(scratch[:n], scratch[:n])
+		c.paddingRemaining -= n
+	}
+	return nil
+}
+
+func (c *Conn) Write(p []byte) (n int, err error) {
+	err = c.handshake()
+	if err != nil {
+		return
+	}
+	c.writeAccess.Lock()
+	defer c.writeAccess.Unlock()
+	maxPayload := maxPacketSize - frameHeaderLength
+	if len(c.config.packetSizes) > 0 {
+		maxPayload = int(c.config.packetSizes[len(c.config.packetSizes)-1]) - frameHeaderLength
+	}
+	for len(p) > 0 {
+		chunk := p[:min(len(p), maxPayload)]
+		err = c.writeFrame(chunk, c.frameSize(len(chunk)))
+		if err != nil {
+			return
+		}
+		n += len(chunk)
+		p = p[len(chunk):]
+	}
+	return
+}
+
+// frameSize returns the smallest packet size that fits the data.
+func (c *Conn) frameSize(dataLength int) int {
+	frameLength := frameHeaderLength + dataLength
+	for _, size := range c.config.packetSizes {
+		if int(size) >= frameLength {
+			return int(size)
+		}
+	}
+	return frameLength
+}
+
+func (c *Conn) writeFrame(data []byte, frameSize int) error {
+	buffer := buf.NewSize(frameSize)
+	defer buffer.Release()
+	frame := buffer.Extend(frameSize)
+	binary.BigEndian.PutUint16(frame, uint16(len(data)))
+	binary.BigEndian.PutUint16(frame[2:], uint16(frameSize-frameHeaderLength-len(data)))
+	copy(frame[frameHeaderLength:], data)
+	clear(frame[frameHeaderLength+len(data):])
+	c.writeStream.XORKeyStream(frame, frame)
+	err := c.waitBurst(frameSize)
+	if err != nil {
+		return err
+	}
+	_, err = c.Conn.Write(frame)
+	c.lastWrite = time.Now()
+	return err
+}
+
+// waitBurst delays the write to the next burst if it exceeds the burst size.
+func (c *Conn) waitBurst(frameSize int) error {
+	if c.config.burstSize == 0 {
+		return nil
+	}
+	now := time.Now()
+	if now.Sub(c.burstStart) >= c.config.burstInterval {
+		c.burstStart = now
+		c.burstWritten = 0
+	} else if c.burstWritten > 0 && c.burstWritten+uint32(frameSize) > c.config.burstSize {
+		timer := time.NewTimer(c.config.burstInterval - now.Sub(c.burstStart))
+		select {
+		case <-c.closed:
+			timer.Stop()
+			return net.ErrClosed
+		case <-timer.C:
+		}
+		c.burstStart = time.Now()
+		c.burstWritten = 0
+	}
+	c.burstWritten += uint32(frameSize)
+	return nil
+}
+
+func (c *Conn) startDummy() {
+	if c.config.dummyInterval > 0 {
+		go c.loopDummy()
+	}
+}
+
+// loopDummy sends dummy frames at random intervals around the dummy interval
+// while the conn is idle.
+func (c *Conn) loopDummy() {
+	interval := c.config.dummyInterval
+	for {
+		timer := time.NewTimer(interval/2 + mRand.N(interval))
+		select {
+		case <-c.closed:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		c.writeAccess.Lock()
+		var err error
+		if time.Since(c.lastWrite) >= interval/2 {
+			err = c.writeFrame(nil, c.dummySize())
+		}
+		c.writeAccess.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (c *Conn) dummySize() int {
+	if len(c.config.packetSizes) == 0 {
+		return MinPacketSize + mRand.IntN(256)
+	}
+	return int(c.config.packetSizes[mRand.IntN(len(c.config.packetSizes))])
+}
+
+func (c *Conn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+	return c.Conn.Close()
+}
@@ -0,0 +1,72 @@
+package padding
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json/badoption"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConn(t *testing.T) {
+	t.Parallel()
+	clientConfig, err := NewConfig(option.PaddingOptions{Password: "password", PacketSizes: badoption.Listable[uint16]{512, 64}})
+	require.NoError(t, err)
+	serverConfig, err := NewConfig(option.PaddingOptions{Password: "password"})
+	require.NoError(t, err)
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	serverConn := NewServerConn(server, serverConfig)
+	clientDone := make(chan error, 1)
+	var clientConn *Conn
+	go func() {
+		var clientErr error
+		clientConn, clientErr = NewClientConn(client, clientConfig)
+		clientDone <- clientErr
+	}()
+	message := make([]byte, 2000)
+	_, _ = rand.Read(message)
+	serverDone := make(chan error, 1)
+	go func() {
+		_, serverErr := serverConn.Write(message)
+		serverDone <- serverErr
+	}()
+	require.NoError(t, <-clientDone)
+	received := make([]byte, len(message))
+	_, err = io.ReadFull(clientConn, received)
+	require.NoError(t, err)
+	require.True(t, bytes.Equal(message, received))
+	go io.Copy(io.Discard, clientConn)
+	require.NoError(t, <-serverDone)
+	require.Equal(t, []uint16{64, 512}, serverConn.config.packetSizes)
+}
+
+func TestConnBadPassword(t *testing.T) {
+	t.Parallel()
+	clientConfig, err := NewConfig(option.PaddingOptions{Password: "password"})
+	require.NoError(t, err)
+	serverConfig, err := NewConfig(option.PaddingOptions{Password: "wrong"})
+	require.NoError(t, err)
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	go func() {
+		_, _ = NewClientConn(client, clientConfig)
+	}()
+	_, err = NewServerConn(server, serverConfig).Read(make([]byte, 1))
+	require.Error(t, err)
+}
+
+func TestConfig(t *testing.T) {
+	t.Parallel()
+	_, err := NewConfig(option.PaddingOptions{PacketSizes: badoption.Listable[uint16]{16}})
+	require.Error(t, err)
+	_, err = NewConfig(option.PaddingOptions{BurstSize: 1024})
+	require.Error(t, err)
+}
@@ -0,0 +1,55 @@
+package portmap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// defaultGateway reads the IPv4 default gateway from /proc/net/route,
+// the route on the default interface is preferred if it is known.
+func defaultGateway(networkManager adapter.NetworkManager) (netip.Addr, error) {
+	file, err := os.Open("/proc/net/route")
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	defer file.Close()
+	var defaultInterface string
+	if networkInterface := networkManager.DefaultNetworkInterface(); networkInterface != nil {
+		defaultInterface = networkInterface.Name
+	}
+	var gateway netip.Addr
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[1] != "00000000" {
+			continue
+		}
+		gatewayBytes, err := hex.DecodeString(fields[2])
+		if err != nil || len(gatewayBytes) != 4 {
+			continue
+		}
+		var address [4]byte
+		binary.LittleEndian.PutUint32(address[:], binary.BigEndian.Uint32(gatewayBytes))
+		routeGateway := netip.AddrFrom4(address)
+		if routeGateway.IsUnspecified() {
+			continue
+		}
+		if defaultInterface == "" || fields[0] == defaultInterface {
+			return routeGateway, nil
+		}
+		if !gateway.IsValid() {
+			gateway = routeGateway
+		}
+	}
+	if !gateway.IsValid() {
+		return netip.Addr{}, E.New("no IPv4 default route found")
+	}
+	return gateway, nil
+}
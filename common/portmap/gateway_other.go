@@ -0,0 +1,25 @@
+//go:build !linux
+
+package portmap
+
+import (
+	"net/netip"
+
+	"github.com/sagernet/sing-box/adapter"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// defaultGateway assumes the gateway is the first host in the subnet of the default interface,
+// as is the case for most home routers.
+func defaultGateway(networkManager adapter.NetworkManager) (netip.Addr, error) {
+	networkInterface := networkManager.DefaultNetworkInterface()
+	if networkInterface == nil {
+		return netip.Addr{}, E.New("missing default interface")
+	}
+	for _, prefix := range networkInterface.Addresses {
+		if prefix.Addr().Is4() && prefix.Bits() < 31 {
+			return prefix.Masked().Addr().Next(), nil
+		}
+	}
+	return netip.Addr{}, E.New("missing IPv4 address on default interface ", networkInterface.Name)
+}
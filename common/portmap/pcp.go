@@ -0,0 +1,205 @@
+package portmap
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"net"
+	"net/netip"
+	"time"
+
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+const (
+	pcpPort              = 5351
+	pcpVersion           = 2
+	natPMPVersion        = 0
+	pcpOpcodeMap         = 1
+	pcpResultSuccess     = 0
+	pcpResultUnsupported = 1
+	pcpInitialTimeout    = 250 * time.Millisecond
+	pcpMaxAttempts       = 4
+)
+
+var _ client = (*pcpClient)(nil)
+
+// pcpClient requests mappings with PCP (RFC 6887), and falls back to NAT-PMP (RFC 6886)
+// if the gateway only speaks the older protocol.
+type pcpClient struct {
+	dialer  N.Dialer
+	gateway netip.Addr
+	natPMP  bool
+	nonce   [12]byte
+}
+
+func newPCPClient(dialer N.Dialer, gateway netip.Addr) *pcpClient {
+	client := &pcpClient{
+		dialer:  dialer,
+		gateway: gateway,
+	}
+	rand.Read(client.nonce[:])
+	return client
+}
+
+func (c *pcpClient) Protocol() string {
+	if c.natPMP {
+		return ProtocolNATPMP
+	}
+	return ProtocolPCP
+}
+
+func (c *pcpClient) Gateway() netip.Addr {
+	return c.gateway
+}
+
+func (c *pcpClient) Map(ctx context.Context, network string, internalPort uint16, externalPort uint16, lifetime time.Duration) (netip.AddrPort, time.Duration, error) {
+	if !c.natPMP {
+		externalAddress, grantedLifetime, err := c.mapPCP(ctx, network, internalPort, externalPort, lifetime)
+		if err != errUnsupportedVersion {
+			return externalAddress, grantedLifetime, err
+		}
+		c.natPMP = true
+	}
+	return c.mapNATPMP(ctx, network, internalPort, externalPort, lifetime)
+}
+
+func (c *pcpClient) Unmap(ctx context.Context, network string, internalPort uint16, externalPort uint16) error {
+	var err error
+	if c.natPMP {
+		_, _, err = c.mapNATPMP(ctx, network, internalPort, 0, 0)
+	} else {
+		_, _, err = c.mapPCP(ctx, network, internalPort, externalPort, 0)
+	}
+	return err
+}
+
+var errUnsupportedVersion = E.New("unsupported version")
+
+func (c *pcpClient) mapPCP(ctx context.Context, network string, internalPort uint16, externalPort uint16, lifetime time.Duration) (netip.AddrPort, time.Duration, error) {
+	conn, err := c.dialer.DialContext(ctx, N.NetworkUDP, M.SocksaddrFrom(c.gateway, pcpPort))
+	if err != nil {
+		return netip.AddrPort{}, 0, err
+	}
+	defer conn.Close()
+	clientAddress := M.SocksaddrFromNet(conn.LocalAddr()).Addr.As16()
+	request := make([]byte, 60)
+	request[0] = pcpVersion
+	request[1] = pcpOpcodeMap
+	binary.BigEndian.PutUint32(request[4:], uint32(lifetime/time.Second))
+	copy(request[8:24], clientAddress[:])
+	copy(request[24:36], c.nonce[:])
+	request[36] = ipProtocol(network)
+	binary.BigEndian.PutUint16(request[40:], internalPort)
+	binary.BigEndian.PutUint16(request[42:], externalPort)
+	anyAddress := netip.AddrFrom4([4]byte{}).As16()
+	// IPv4-mapped unspecified address for any external IPv4 address
+	anyAddress[10], anyAddress[11] = 0xff, 0xff
+	copy(request[44:60], anyAddress[:])
+	response, err := exchange(ctx, conn, request, func(response []byte) bool {
+		if len(response) >= 2 && response[0] == natPMPVersion {
+			return true
+		}
+		return len(response) >= 60 && response[1] == 0x80|pcpOpcodeMap && [12]byte(response[24:36]) == c.nonce
+	})
+	if err != nil {
+		return netip.AddrPort{}, 0, err
+	}
+	if response[0] == natPMPVersion {
+		return netip.AddrPort{}, 0, errUnsupportedVersion
+	}
+	resultCode := response[3]
+	if resultCode == pcpResultUnsupported {
+		return netip.AddrPort{}, 0, errUnsupportedVersion
+	} else if resultCode != pcpResultSuccess {
+		return netip.AddrPort{}, 0, E.New("PCP result code ", resultCode)
+	}
+	grantedLifetime := time.Duration(binary.BigEndian.Uint32(response[4:])) * time.Second
+	externalAddress := netip.AddrFrom16([16]byte(response[44:60])).Unmap()
+	return netip.AddrPortFrom(externalAddress, binary.BigEndian.Uint16(response[42:])), grantedLifetime, nil
+}
+
+func (c *pcpClient) mapNATPMP(ctx context.Context, network string, internalPort uint16, externalPort uint16, lifetime time.Duration) (netip.AddrPort, time.Duration, error) {
+	conn, err := c.dialer.DialContext(ctx, N.NetworkUDP, M.SocksaddrFrom(c.gateway, pcpPort))
+	if err != nil {
+		return netip.AddrPort{}, 0, err
+	}
+	defer conn.Close()
+	var opcode byte = 2
+	if network == N.NetworkUDP {
+		opcode = 1
+	}
+	request := make([]byte, 12)
+	request[1] = opcode
+	binary.BigEndian.PutUint16(request[4:], internalPort)
+	binary.BigEndian.PutUint16(request[6:], externalPort)
+	binary.BigEndian.PutUint32(request[8:], uint32(lifetime/time.Second))
+	response, err := exchange(ctx, conn, request, func(response []byte) bool {
+		return len(response) >= 16 && response[0] == natPMPVersion && response[1] == 0x80|opcode
+	})
+	if err != nil {
+		return netip.AddrPort{}, 0, err
+	}
+	resultCode := binary.BigEndian.Uint16(response[2:])
+	if resultCode != 0 {
+		return netip.AddrPort{}, 0, E.New("NAT-PMP result code ", resultCode)
+	}
+	if lifetime == 0 {
+		return netip.AddrPort{}, 0, nil
+	}
+	mappedPort := binary.BigEndian.Uint16(response[10:])
+	grantedLifetime := time.Duration(binary.BigEndian.Uint32(response[12:])) * time.Second
+	response, err = exchange(ctx, conn, []byte{natPMPVersion, 0}, func(response []byte) bool {
+		return len(response) >= 12 && response[0] == natPMPVersion && response[1] == 0x80
+	})
+	if err != nil {
+		return netip.AddrPort{}, 0, E.Cause(err, "request external address")
+	}
+	if resultCode = binary.BigEndian.Uint16(response[2:]); resultCode != 0 {
+		return netip.AddrPort{}, 0, E.New("NAT-PMP result code ", resultCode)
+	}
+	return netip.AddrPortFrom(netip.AddrFrom4([4]byte(response[8:12])), mappedPort), grantedLifetime, nil
+}
+
+// exchange sends the request with exponential retransmission until a matching response is received.
+func exchange(ctx context.Context, conn net.Conn, request []byte, match func(response []byte) bool) ([]byte, error) {
+	buffer := make([]byte, 1100)
+	timeout := pcpInitialTimeout
+	for attempt := 0; attempt < pcpMaxAttempts; attempt++ {
+		_, err := conn.Write(request)
+		if err != nil {
+			return nil, err
+		}
+		deadline := time.Now().Add(timeout)
+		if ctxDeadline, loaded := ctx.Deadline(); loaded && ctxDeadline.Before(deadline) {
+			deadline = ctxDeadline
+		}
+		conn.SetReadDeadline(deadline)
+		for {
+			n, err := conn.Read(buffer)
+			if err != nil {
+				if netError, isNetError := err.(net.Error); isNetError && netError.Timeout() {
+					break
+				}
+				return nil, err
+			}
+			if match(buffer[:n]) {
+				return buffer[:n], nil
+			}
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		timeout *= 2
+	}
+	return nil, E.New("no response from gateway")
+}
+
+func ipProtocol(network string) byte {
+	if network == N.NetworkUDP {
+		return 17
+	}
+	return 6
+}
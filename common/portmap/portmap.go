@@ -0,0 +1,232 @@
+package portmap
+
+import (
+	"context"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common/control"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/service"
+)
+
+const (
+	ProtocolPCP    = "pcp"
+	ProtocolNATPMP = "nat-pmp"
+	ProtocolUPnP   = "upnp"
+)
+
+const (
+	requestLifetime  = 2 * time.Hour
+	requestTimeout   = 10 * time.Second
+	minRetryInterval = 30 * time.Second
+	maxRetryInterval = 10 * time.Minute
+)
+
+type client interface {
+	Protocol() string
+	Gateway() netip.Addr
+	Map(ctx context.Context, network string, internalPort uint16, externalPort uint16, lifetime time.Duration) (netip.AddrPort, time.Duration, error)
+	Unmap(ctx context.Context, network string, internalPort uint16, externalPort uint16) error
+}
+
+type Options struct {
+	Context context.Context
+	Logger  logger.ContextLogger
+	Network string
+	Listen  netip.AddrPort
+}
+
+var _ adapter.PortMapper = (*Mapper)(nil)
+
+// Mapper requests a port mapping for a listener from the gateway with PCP, NAT-PMP or UPnP IGD,
+// and keeps it renewed until closed.
+type Mapper struct {
+	ctx            context.Context
+	cancel         context.CancelFunc
+	logger         logger.ContextLogger
+	network        string
+	listen         netip.AddrPort
+	networkManager adapter.NetworkManager
+	dialer         N.Dialer
+	client         client
+	access         sync.RWMutex
+	mapping        adapter.PortMapping
+	done           chan struct{}
+}
+
+func New(options Options) (*Mapper, error) {
+	listenAddr := options.Listen.Addr()
+	if listenAddr.IsLoopback() {
+		return nil, E.New("port mapping requires a listen address reachable from the gateway")
+	}
+	if !listenAddr.Is4() && !listenAddr.IsUnspecified() {
+		return nil, E.New("port mapping is only supported for IPv4")
+	}
+	networkManager := service.FromContext[adapter.NetworkManager](options.Context)
+	ctx, cancel := context.WithCancel(options.Context)
+	return &Mapper{
+		ctx:            ctx,
+		cancel:         cancel,
+		logger:         options.Logger,
+		network:        options.Network,
+		listen:         options.Listen,
+		networkManager: networkManager,
+		dialer:         newSystemDialer(networkManager),
+		mapping: adapter.PortMapping{
+			Network: options.Network,
+			Listen:  options.Listen,
+		},
+		done: make(chan struct{}),
+	}, nil
+}
+
+// newSystemDialer creates a dialer that bypasses tun like the direct outbound,
+// requests must reach the gateway of the physical network.
+func newSystemDialer(networkManager adapter.NetworkManager) N.Dialer {
+	var controlFunc control.Func
+	defaultOptions := networkManager.DefaultOptions()
+	if defaultOptions.BindInterface != "" {
+		controlFunc = control.BindToInterface(networkManager.InterfaceFinder(), defaultOptions.BindInterface, -1)
+	} else if networkManager.AutoDetectInterface() {
+		controlFunc = networkManager.AutoDetectInterfaceFunc()
+	}
+	if defaultOptions.RoutingMark != 0 {
+		controlFunc = control.Append(controlFunc, control.RoutingMark(defaultOptions.RoutingMark))
+	}
+	controlFunc = control.Append(controlFunc, networkManager.AutoRedirectOutputMarkFunc())
+	systemDialer := &N.DefaultDialer{}
+	systemDialer.Dialer.Control = controlFunc
+	systemDialer.ListenConfig.Control = controlFunc
+	return systemDialer
+}
+
+func (m *Mapper) Start() {
+	m.networkManager.RegisterPortMapper(m)
+	go m.loopUpdate()
+}
+
+func (m *Mapper) PortMapping() adapter.PortMapping {
+	m.access.RLock()
+	defer m.access.RUnlock()
+	return m.mapping
+}
+
+func (m *Mapper) loopUpdate() {
+	defer close(m.done)
+	retryInterval := minRetryInterval
+	for {
+		lifetime, err := m.update()
+		var wait time.Duration
+		if err != nil {
+			if m.ctx.Err() != nil {
+				return
+			}
+			m.logger.Warn(E.Cause(err, "request ", m.network, " port mapping for ", m.listen))
+			wait = retryInterval
+			retryInterval = min(retryInterval*2, maxRetryInterval)
+		} else {
+			wait = lifetime / 2
+			retryInterval = minRetryInterval
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-m.ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+func (m *Mapper) update() (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(m.ctx, requestTimeout)
+	defer cancel()
+	m.access.RLock()
+	externalPort := m.mapping.ExternalAddress.Port()
+	m.access.RUnlock()
+	if externalPort == 0 {
+		externalPort = m.listen.Port()
+	}
+	var (
+		externalAddress netip.AddrPort
+		lifetime        time.Duration
+		err             error
+	)
+	if m.client != nil {
+		externalAddress, lifetime, err = m.client.Map(ctx, m.network, m.listen.Port(), externalPort, requestLifetime)
+		if err != nil {
+			m.logger.Debug(E.Cause(err, "renew port mapping with ", m.client.Protocol()))
+			m.client = nil
+		}
+	}
+	if m.client == nil {
+		var errors []error
+		for _, candidate := range m.newClients() {
+			externalAddress, lifetime, err = candidate.Map(ctx, m.network, m.listen.Port(), externalPort, requestLifetime)
+			if err == nil {
+				m.client = candidate
+				break
+			}
+			errors = append(errors, E.Cause(err, candidate.Protocol()))
+		}
+		if m.client == nil {
+			err = E.Errors(errors...)
+			m.access.Lock()
+			m.mapping = adapter.PortMapping{
+				Network: m.network,
+				Listen:  m.listen,
+				Error:   err.Error(),
+			}
+			m.access.Unlock()
+			return 0, err
+		}
+	}
+	m.access.Lock()
+	changed := m.mapping.ExternalAddress != externalAddress || m.mapping.Protocol != m.client.Protocol()
+	m.mapping = adapter.PortMapping{
+		Network:         m.network,
+		Listen:          m.listen,
+		Protocol:        m.client.Protocol(),
+		Gateway:         m.client.Gateway(),
+		ExternalAddress: externalAddress,
+		Expire:          time.Now().Add(lifetime),
+	}
+	m.access.Unlock()
+	if changed {
+		m.logger.Info("mapped ", m.network, " port ", m.listen.Port(), " to ", externalAddress, " with ", m.client.Protocol(), " on ", m.client.Gateway())
+	}
+	return lifetime, nil
+}
+
+func (m *Mapper) newClients() []client {
+	var clients []client
+	gateway, err := defaultGateway(m.networkManager)
+	if err != nil {
+		m.logger.Debug(E.Cause(err, "find default gateway"))
+	} else {
+		clients = append(clients, newPCPClient(m.dialer, gateway))
+	}
+	clients = append(clients, newUPnPClient(m.dialer, gateway))
+	return clients
+}
+
+func (m *Mapper) Close() error {
+	m.networkManager.UnregisterPortMapper(m)
+	m.cancel()
+	<-m.done
+	if m.client == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	err := m.client.Unmap(ctx, m.network, m.listen.Port(), m.mapping.ExternalAddress.Port())
+	if err != nil {
+		return E.Cause(err, "delete port mapping")
+	}
+	return nil
+}
@@ -0,0 +1,337 @@
+package portmap
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+const (
+	ssdpAddress       = "239.255.255.250:1900"
+	ssdpSearchTarget  = "urn:schemas-upnp-org:device:InternetGatewayDevice:1"
+	ssdpSearchTimeout = 3 * time.Second
+
+	upnpErrorConflict            = 718
+	upnpErrorOnlyPermanentLeases = 725
+	upnpPermanentLeaseInterval   = 20 * time.Minute
+)
+
+var _ client = (*upnpClient)(nil)
+
+// upnpClient requests mappings from the WANIPConnection or WANPPPConnection service of an UPnP internet gateway device.
+type upnpClient struct {
+	dialer      N.Dialer
+	gateway     netip.Addr
+	httpClient  *http.Client
+	controlURL  string
+	serviceType string
+	localAddr   netip.Addr
+}
+
+func newUPnPClient(dialer N.Dialer, gateway netip.Addr) *upnpClient {
+	return &upnpClient{
+		dialer:  dialer,
+		gateway: gateway,
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+					return dialer.DialContext(ctx, network, M.ParseSocksaddr(addr))
+				},
+			},
+			Timeout: requestTimeout,
+		},
+	}
+}
+
+func (c *upnpClient) Protocol() string {
+	return ProtocolUPnP
+}
+
+func (c *upnpClient) Gateway() netip.Addr {
+	return c.gateway
+}
+
+func (c *upnpClient) Map(ctx context.Context, network string, internalPort uint16, externalPort uint16, lifetime time.Duration) (netip.AddrPort, time.Duration, error) {
+	if c.controlURL == "" {
+		err := c.discover(ctx)
+		if err != nil {
+			return netip.AddrPort{}, 0, err
+		}
+	}
+	var err error
+	for attempt := 0; attempt < 3; attempt++ {
+		err = c.addPortMapping(ctx, network, internalPort, externalPort, lifetime)
+		if upnpErr, isUPnPErr := err.(*upnpError); isUPnPErr {
+			switch upnpErr.code {
+			case upnpErrorOnlyPermanentLeases:
+				lifetime = 0
+				continue
+			case upnpErrorConflict:
+				externalPort = uint16(1024 + rand.Intn(65535-1024))
+				continue
+			}
+		}
+		break
+	}
+	if err != nil {
+		return netip.AddrPort{}, 0, err
+	}
+	response, err := c.call(ctx, "GetExternalIPAddress", nil)
+	if err != nil {
+		return netip.AddrPort{}, 0, E.Cause(err, "request external address")
+	}
+	externalAddress, err := netip.ParseAddr(response["NewExternalIPAddress"])
+	if err != nil {
+		return netip.AddrPort{}, 0, E.Cause(err, "parse external address")
+	}
+	if lifetime == 0 {
+		lifetime = upnpPermanentLeaseInterval * 2
+	}
+	return netip.AddrPortFrom(externalAddress, externalPort), lifetime, nil
+}
+
+func (c *upnpClient) Unmap(ctx context.Context, network string, internalPort uint16, externalPort uint16) error {
+	_, err := c.call(ctx, "DeletePortMapping", [][2]string{
+		{"NewRemoteHost", ""},
+		{"NewExternalPort", strconv.Itoa(int(externalPort))},
+		{"NewProtocol", strings.ToUpper(network)},
+	})
+	return err
+}
+
+func (c *upnpClient) addPortMapping(ctx context.Context, network string, internalPort uint16, externalPort uint16, lifetime time.Duration) error {
+	_, err := c.call(ctx, "AddPortMapping", [][2]string{
+		{"NewRemoteHost", ""},
+		{"NewExternalPort", strconv.Itoa(int(externalPort))},
+		{"NewProtocol", strings.ToUpper(network)},
+		{"NewInternalPort", strconv.Itoa(int(internalPort))},
+		{"NewInternalClient", c.localAddr.String()},
+		{"NewEnabled", "1"},
+		{"NewPortMappingDescription", "sing-box"},
+		{"NewLeaseDuration", strconv.Itoa(int(lifetime / time.Second))},
+	})
+	return err
+}
+
+func (c *upnpClient) discover(ctx context.Context) error {
+	location, err := c.search(ctx)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, location.String(), nil)
+	if err != nil {
+		return err
+	}
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return E.Cause(err, "fetch device description")
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return E.New("fetch device description: unexpected status: ", response.Status)
+	}
+	var description upnpDescription
+	err = xml.NewDecoder(response.Body).Decode(&description)
+	if err != nil {
+		return E.Cause(err, "decode device description")
+	}
+	service, loaded := description.Device.findService()
+	if !loaded {
+		return E.New("missing WAN connection service in device description")
+	}
+	baseURL := location
+	if description.URLBase != "" {
+		baseURL, err = url.Parse(description.URLBase)
+		if err != nil {
+			return E.Cause(err, "parse URLBase")
+		}
+	}
+	controlURL, err := baseURL.Parse(service.ControlURL)
+	if err != nil {
+		return E.Cause(err, "parse control URL")
+	}
+	conn, err := c.dialer.DialContext(ctx, N.NetworkUDP, M.ParseSocksaddr(controlURL.Host))
+	if err != nil {
+		return err
+	}
+	c.localAddr = M.SocksaddrFromNet(conn.LocalAddr()).Addr
+	conn.Close()
+	if gatewayAddr := M.ParseSocksaddr(controlURL.Host).Addr; gatewayAddr.IsValid() {
+		c.gateway = gatewayAddr
+	}
+	c.controlURL = controlURL.String()
+	c.serviceType = service.ServiceType
+	return nil
+}
+
+// search finds the location of the gateway device description with SSDP,
+// the device at the default gateway is preferred if it is known.
+func (c *upnpClient) search(ctx context.Context) (*url.URL, error) {
+	destination := M.ParseSocksaddr(ssdpAddress)
+	conn, err := c.dialer.ListenPacket(ctx, destination)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	request := "M-SEARCH * HTTP/1.1\r\n" +
+		"HOST: " + ssdpAddress + "\r\n" +
+		"ST: " + ssdpSearchTarget + "\r\n" +
+		"MAN: \"ssdp:discover\"\r\n" +
+		"MX: 2\r\n\r\n"
+	_, err = conn.WriteTo([]byte(request), destination.UDPAddr())
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(ssdpSearchTimeout)
+	if ctxDeadline, loaded := ctx.Deadline(); loaded && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	conn.SetReadDeadline(deadline)
+	var location *url.URL
+	buffer := make([]byte, 2048)
+	for {
+		n, _, err := conn.ReadFrom(buffer)
+		if err != nil {
+			break
+		}
+		response, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(buffer[:n])), nil)
+		if err != nil {
+			continue
+		}
+		response.Body.Close()
+		responseLocation, err := url.Parse(response.Header.Get("Location"))
+		if err != nil || responseLocation.Host == "" {
+			continue
+		}
+		if !c.gateway.IsValid() || M.ParseSocksaddr(responseLocation.Host).Addr == c.gateway {
+			return responseLocation, nil
+		}
+		if location == nil {
+			location = responseLocation
+		}
+	}
+	if location == nil {
+		return nil, E.New("no UPnP internet gateway device found")
+	}
+	return location, nil
+}
+
+func (c *upnpClient) call(ctx context.Context, action string, arguments [][2]string) (map[string]string, error) {
+	var body bytes.Buffer
+	body.WriteString(`<?xml version="1.0"?><s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body>`)
+	body.WriteString(`<u:` + action + ` xmlns:u="` + c.serviceType + `">`)
+	for _, argument := range arguments {
+		body.WriteString("<" + argument[0] + ">")
+		xml.EscapeText(&body, []byte(argument[1]))
+		body.WriteString("</" + argument[0] + ">")
+	}
+	body.WriteString(`</u:` + action + `></s:Body></s:Envelope>`)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.controlURL, &body)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	request.Header.Set("SOAPAction", `"`+c.serviceType+"#"+action+`"`)
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	content, err := io.ReadAll(io.LimitReader(response.Body, 64*1024))
+	if err != nil {
+		return nil, err
+	}
+	values := parseSOAPValues(content)
+	if response.StatusCode != http.StatusOK {
+		code, _ := strconv.Atoi(values["errorCode"])
+		return nil, &upnpError{action, code, values["errorDescription"], response.Status}
+	}
+	return values, nil
+}
+
+// parseSOAPValues collects the text of all leaf elements by local name.
+func parseSOAPValues(content []byte) map[string]string {
+	values := make(map[string]string)
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	var (
+		name string
+		text []byte
+	)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return values
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			name = element.Name.Local
+			text = text[:0]
+		case xml.CharData:
+			text = append(text, element...)
+		case xml.EndElement:
+			if name == element.Name.Local {
+				values[name] = strings.TrimSpace(string(text))
+			}
+			name = ""
+		}
+	}
+}
+
+type upnpError struct {
+	action      string
+	code        int
+	description string
+	status      string
+}
+
+func (e *upnpError) Error() string {
+	if e.code == 0 {
+		return e.action + ": unexpected status: " + e.status
+	}
+	return e.action + ": UPnP error " + strconv.Itoa(e.code) + ": " + e.description
+}
+
+type upnpDescription struct {
+	URLBase string     `xml:"URLBase"`
+	Device  upnpDevice `xml:"device"`
+}
+
+type upnpDevice struct {
+	Services []upnpService `xml:"serviceList>service"`
+	Devices  []upnpDevice  `xml:"deviceList>device"`
+}
+
+type upnpService struct {
+	ServiceType string `xml:"serviceType"`
+	ControlURL  string `xml:"controlURL"`
+}
+
+func (d *upnpDevice) findService() (upnpService, bool) {
+	for _, service := range d.Services {
+		if strings.HasPrefix(service.ServiceType, "urn:schemas-upnp-org:service:WANIPConnection:") ||
+			strings.HasPrefix(service.ServiceType, "urn:schemas-upnp-org:service:WANPPPConnection:") {
+			return service, true
+		}
+	}
+	for i := range d.Devices {
+		service, loaded := d.Devices[i].findService()
+		if loaded {
+			return service, true
+		}
+	}
+	return upnpService{}, false
+}
@@ -0,0 +1,192 @@
+package power
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/experimental/libbox/platform"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/x/list"
+	"github.com/sagernet/sing/service"
+)
+
+const (
+	DefaultKeepAliveMultiplier = 3
+	DefaultTimerWindow         = 5 * time.Minute
+
+	// systemStateInterval is the interval to read the power state from the
+	// operating system if not reported by the platform.
+	systemStateInterval = time.Minute
+)
+
+var _ adapter.PowerManager = (*Manager)(nil)
+
+type Manager struct {
+	ctx                 context.Context
+	cancel              context.CancelFunc
+	logger              log.ContextLogger
+	enabled             bool
+	always              bool
+	keepAliveMultiplier time.Duration
+	timerWindow         time.Duration
+	access              sync.Mutex
+	state               adapter.PowerState
+	lowPower            atomic.Bool
+	normal              chan struct{}
+	platformReported    bool
+	callbacks           list.List[adapter.PowerCallback]
+}
+
+func NewManager(ctx context.Context, logger log.ContextLogger, options option.PowerSavingOptions) (*Manager, error) {
+	if !options.Enabled && (options.Always || options.KeepAliveMultiplier != 0 || options.TimerWindow != 0) {
+		return nil, E.New("power_saving is not enabled")
+	}
+	keepAliveMultiplier := options.KeepAliveMultiplier
+	if keepAliveMultiplier == 0 {
+		keepAliveMultiplier = DefaultKeepAliveMultiplier
+	}
+	timerWindow := time.Duration(options.TimerWindow)
+	if timerWindow == 0 {
+		timerWindow = DefaultTimerWindow
+	} else if timerWindow < time.Second {
+		return nil, E.New("timer_window must be at least 1s")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	normal := make(chan struct{})
+	close(normal)
+	return &Manager{
+		ctx:                 ctx,
+		cancel:              cancel,
+		logger:              logger,
+		enabled:             options.Enabled,
+		always:              options.Always,
+		keepAliveMultiplier: time.Duration(keepAliveMultiplier),
+		timerWindow:         timerWindow,
+		normal:              normal,
+	}, nil
+}
+
+func (m *Manager) Name() string {
+	return "power-manager"
+}
+
+func (m *Manager) Start(stage adapter.StartStage) error {
+	if stage != adapter.StartStateStart || !m.enabled {
+		return nil
+	}
+	m.access.Lock()
+	m.update()
+	m.access.Unlock()
+	if m.always || service.FromContext[platform.Interface](m.ctx) != nil || !systemStateSupported {
+		return nil
+	}
+	go m.loopSystemState()
+	return nil
+}
+
+func (m *Manager) Close() error {
+	m.cancel()
+	return nil
+}
+
+func (m *Manager) LowPower() bool {
+	return m.lowPower.Load()
+}
+
+// UpdatePowerState is called by the platform, after which the state is no
+// longer read from the operating system.
+func (m *Manager) UpdatePowerState(state adapter.PowerState) {
+	m.access.Lock()
+	defer m.access.Unlock()
+	m.platformReported = true
+	m.state = state
+	m.update()
+}
+
+func (m *Manager) updateSystemState(state adapter.PowerState) {
+	m.access.Lock()
+	defer m.access.Unlock()
+	if m.platformReported {
+		return
+	}
+	m.state = state
+	m.update()
+}
+
+func (m *Manager) loopSystemState() {
+	ticker := time.NewTicker(systemStateInterval)
+	defer ticker.Stop()
+	for {
+		state, err := readSystemState()
+		if err != nil {
+			m.logger.Warn("read power state: ", err)
+			return
+		}
+		m.updateSystemState(state)
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Manager) update() {
+	lowPower := m.enabled && (m.always || m.state.OnBattery || m.state.PowerSaveMode || m.state.Idle)
+	if lowPower == m.lowPower.Load() {
+		return
+	}
+	m.lowPower.Store(lowPower)
+	if lowPower {
+		m.normal = make(chan struct{})
+		m.logger.Info("entered low power mode")
+	} else {
+		close(m.normal)
+		m.logger.Info("exited low power mode")
+	}
+	for element := m.callbacks.Front(); element != nil; element = element.Next() {
+		element.Value(lowPower)
+	}
+}
+
+func (m *Manager) KeepAliveInterval(interval time.Duration) time.Duration {
+	if !m.LowPower() {
+		return interval
+	}
+	return interval * m.keepAliveMultiplier
+}
+
+func (m *Manager) WaitWindow(ctx context.Context) {
+	m.access.Lock()
+	lowPower, normal := m.lowPower.Load(), m.normal
+	m.access.Unlock()
+	if !lowPower {
+		return
+	}
+	now := time.Now()
+	timer := time.NewTimer(now.Truncate(m.timerWindow).Add(m.timerWindow).Sub(now))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-normal:
+	case <-ctx.Done():
+	case <-m.ctx.Done():
+	}
+}
+
+func (m *Manager) RegisterCallback(callback adapter.PowerCallback) *list.Element[adapter.PowerCallback] {
+	m.access.Lock()
+	defer m.access.Unlock()
+	return m.callbacks.PushBack(callback)
+}
+
+func (m *Manager) UnregisterCallback(element *list.Element[adapter.PowerCallback]) {
+	m.access.Lock()
+	defer m.access.Unlock()
+	m.callbacks.Remove(element)
+}
@@ -0,0 +1,48 @@
+package power
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+)
+
+const systemStateSupported = true
+
+const powerSupplyPath = "/sys/class/power_supply"
+
+// readSystemState reports on battery if there is a battery and no external
+// power supply is online.
+func readSystemState() (adapter.PowerState, error) {
+	entries, err := os.ReadDir(powerSupplyPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return adapter.PowerState{}, nil
+		}
+		return adapter.PowerState{}, err
+	}
+	var hasBattery, hasOnline bool
+	for _, entry := range entries {
+		supplyType := readSupplyFile(entry.Name(), "type")
+		switch supplyType {
+		case "Battery":
+			if readSupplyFile(entry.Name(), "scope") != "Device" {
+				hasBattery = true
+			}
+		case "Mains", "USB", "USB_C", "USB_PD", "Wireless":
+			if readSupplyFile(entry.Name(), "online") == "1" {
+				hasOnline = true
+			}
+		}
+	}
+	return adapter.PowerState{OnBattery: hasBattery && !hasOnline}, nil
+}
+
+func readSupplyFile(name string, file string) string {
+	content, err := os.ReadFile(filepath.Join(powerSupplyPath, name, file))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
@@ -0,0 +1,15 @@
+//go:build !linux && !windows
+
+package power
+
+import (
+	"os"
+
+	"github.com/sagernet/sing-box/adapter"
+)
+
+const systemStateSupported = false
+
+func readSystemState() (adapter.PowerState, error) {
+	return adapter.PowerState{}, os.ErrInvalid
+}
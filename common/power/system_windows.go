@@ -0,0 +1,47 @@
+package power
+
+import (
+	"syscall"
+	"unsafe"
+
+	"github.com/sagernet/sing-box/adapter"
+
+	"golang.org/x/sys/windows"
+)
+
+const systemStateSupported = true
+
+var (
+	modkernel32              = windows.NewLazySystemDLL("kernel32.dll")
+	procGetSystemPowerStatus = modkernel32.NewProc("GetSystemPowerStatus")
+)
+
+type systemPowerStatus struct {
+	ACLineStatus        uint8
+	BatteryFlag         uint8
+	BatteryLifePercent  uint8
+	SystemStatusFlag    uint8
+	BatteryLifeTime     uint32
+	BatteryFullLifeTime uint32
+}
+
+const (
+	acLineOffline    = 0
+	batteryNoBattery = 128
+	batterySaverOn   = 1
+)
+
+func readSystemState() (adapter.PowerState, error) {
+	var status systemPowerStatus
+	r1, _, e1 := syscall.SyscallN(procGetSystemPowerStatus.Addr(), uintptr(unsafe.Pointer(&status)))
+	if r1 == 0 {
+		if e1 != 0 {
+			return adapter.PowerState{}, error(e1)
+		}
+		return adapter.PowerState{}, syscall.EINVAL
+	}
+	return adapter.PowerState{
+		OnBattery:     status.ACLineStatus == acLineOffline && status.BatteryFlag&batteryNoBattery == 0,
+		PowerSaveMode: status.SystemStatusFlag == batterySaverOn,
+	}, nil
+}
@@ -0,0 +1,69 @@
+package privilege
+
+import (
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+)
+
+// Drop switches the process to the configured user and group. It is called after all
+// listeners and interfaces are created, and does nothing if already switched, so that
+// the configuration can be reloaded. With keep_reload_capabilities, the reload capabilities
+// are kept with the configured ones, so that the listeners and interfaces can be created
+// again on reload.
+func Drop(logger logger.Logger, options option.PrivilegeOptions, reloadCapabilities []string) error {
+	if options.User == "" && options.Group == "" {
+		if len(options.KeepCapabilities) > 0 {
+			return E.New("keep_capabilities requires user")
+		}
+		if options.KeepReloadCapabilities {
+			return E.New("keep_reload_capabilities requires user")
+		}
+		return nil
+	}
+	if !options.KeepReloadCapabilities {
+		reloadCapabilities = nil
+	}
+	return drop(logger, options, reloadCapabilities)
+}
+
+// ReloadCapabilities returns the Linux capabilities needed to create the tun and
+// system WireGuard interfaces and the listeners on ports below 1024 of the options.
+func ReloadCapabilities(options option.Options) []string {
+	if !C.IsLinux {
+		return nil
+	}
+	var netAdmin, netBindService bool
+	for _, inbound := range options.Inbounds {
+		if inbound.Type == C.TypeTun {
+			netAdmin = true
+		}
+		if listenWrapper, isListen := inbound.Options.(option.ListenOptionsWrapper); isListen {
+			listenPort := listenWrapper.TakeListenOptions().ListenPort
+			if listenPort != 0 && listenPort < 1024 {
+				netBindService = true
+			}
+		}
+	}
+	for _, endpoint := range options.Endpoints {
+		wireGuardOptions, isWireGuard := endpoint.Options.(*option.WireGuardEndpointOptions)
+		if !isWireGuard {
+			continue
+		}
+		if wireGuardOptions.System {
+			netAdmin = true
+		}
+		if wireGuardOptions.ListenPort != 0 && wireGuardOptions.ListenPort < 1024 {
+			netBindService = true
+		}
+	}
+	var capabilities []string
+	if netAdmin {
+		capabilities = append(capabilities, "net_admin")
+	}
+	if netBindService {
+		capabilities = append(capabilities, "net_bind_service")
+	}
+	return capabilities
+}
@@ -0,0 +1,64 @@
+package privilege
+
+import (
+	"strings"
+	"syscall"
+	"unsafe"
+
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"golang.org/x/sys/unix"
+)
+
+var capabilityNames = map[string]uint32{
+	"NET_ADMIN":        unix.CAP_NET_ADMIN,
+	"NET_RAW":          unix.CAP_NET_RAW,
+	"NET_BIND_SERVICE": unix.CAP_NET_BIND_SERVICE,
+	"NET_BROADCAST":    unix.CAP_NET_BROADCAST,
+	"SYS_ADMIN":        unix.CAP_SYS_ADMIN,
+	"SYS_PTRACE":       unix.CAP_SYS_PTRACE,
+	"DAC_READ_SEARCH":  unix.CAP_DAC_READ_SEARCH,
+}
+
+func parseCapabilities(names []string) ([]uint32, error) {
+	var capabilities []uint32
+	for _, name := range names {
+		capability, loaded := capabilityNames[strings.TrimPrefix(strings.ToUpper(name), "CAP_")]
+		if !loaded {
+			return nil, E.New("unknown capability: ", name)
+		}
+		capabilities = append(capabilities, capability)
+	}
+	return capabilities, nil
+}
+
+// keepCapabilities sets PR_SET_KEEPCAPS, which is per thread, so it must be applied to
+// all threads, which is only possible without cgo.
+func keepCapabilities(keep bool) error {
+	var value uintptr
+	if keep {
+		value = 1
+	}
+	_, _, errno := syscall.AllThreadsSyscall(unix.SYS_PRCTL, unix.PR_SET_KEEPCAPS, value, 0)
+	if errno == syscall.ENOTSUP {
+		return E.New("keep_capabilities is unavailable in builds with cgo enabled")
+	} else if errno != 0 {
+		return E.Cause(errno, "prctl PR_SET_KEEPCAPS")
+	}
+	return nil
+}
+
+func setCapabilities(capabilities []uint32) error {
+	header := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	var data [2]unix.CapUserData
+	for _, capability := range capabilities {
+		data[capability/32].Effective |= 1 << (capability % 32)
+		data[capability/32].Permitted |= 1 << (capability % 32)
+		data[capability/32].Inheritable |= 1 << (capability % 32)
+	}
+	_, _, errno := syscall.AllThreadsSyscall(unix.SYS_CAPSET, uintptr(unsafe.Pointer(&header)), uintptr(unsafe.Pointer(&data[0])), 0)
+	if errno != 0 {
+		return E.Cause(errno, "capset")
+	}
+	return keepCapabilities(false)
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package privilege
+
+import (
+	"runtime"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+)
+
+func drop(logger logger.Logger, options option.PrivilegeOptions, reloadCapabilities []string) error {
+	return E.New("privilege dropping is unsupported on ", runtime.GOOS)
+}
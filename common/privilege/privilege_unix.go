@@ -0,0 +1,113 @@
+//go:build unix
+
+package privilege
+
+import (
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+)
+
+func drop(logger logger.Logger, options option.PrivilegeOptions, reloadCapabilities []string) error {
+	uid, gid, err := lookupIDs(options.User, options.Group)
+	if err != nil {
+		return err
+	}
+	capabilities, err := parseCapabilities(options.KeepCapabilities)
+	if err != nil {
+		return err
+	}
+	reload, err := parseCapabilities(reloadCapabilities)
+	if err != nil {
+		return err
+	}
+	if os.Getuid() == uid && os.Getgid() == gid {
+		return nil
+	}
+	if os.Geteuid() != 0 {
+		return E.New("not running as root")
+	}
+	if uid != 0 && len(capabilities)+len(reload) > 0 {
+		err = keepCapabilities(true)
+		if err != nil {
+			return err
+		}
+	}
+	err = syscall.Setgroups([]int{})
+	if err != nil {
+		return E.Cause(err, "setgroups")
+	}
+	err = syscall.Setgid(gid)
+	if err != nil {
+		return E.Cause(err, "setgid")
+	}
+	err = syscall.Setuid(uid)
+	if err != nil {
+		return E.Cause(err, "setuid")
+	}
+	if uid == 0 {
+		return nil
+	}
+	capabilities = append(capabilities, reload...)
+	if len(capabilities) > 0 {
+		err = setCapabilities(capabilities)
+		if err != nil {
+			return err
+		}
+	}
+	if len(reload) > 0 {
+		logger.Info("capabilities kept for reload: ", strings.Join(reloadCapabilities, ", "))
+	}
+	if syscall.Setuid(0) == nil {
+		return E.New("root privileges are still regainable")
+	}
+	return nil
+}
+
+// lookupIDs resolves names or numeric ids, the group defaults to the primary group of the user.
+func lookupIDs(userName string, groupName string) (uid int, gid int, err error) {
+	uid = os.Getuid()
+	gid = -1
+	if userName != "" {
+		var userInfo *user.User
+		userInfo, err = user.Lookup(userName)
+		if err != nil {
+			userInfo, err = user.LookupId(userName)
+		}
+		if err != nil {
+			return 0, 0, E.Cause(err, "lookup user: ", userName)
+		}
+		uid, err = strconv.Atoi(userInfo.Uid)
+		if err != nil {
+			return 0, 0, E.Cause(err, "parse uid: ", userInfo.Uid)
+		}
+		gid, err = strconv.Atoi(userInfo.Gid)
+		if err != nil {
+			return 0, 0, E.Cause(err, "parse gid: ", userInfo.Gid)
+		}
+	}
+	if groupName != "" {
+		var groupInfo *user.Group
+		groupInfo, err = user.LookupGroup(groupName)
+		if err != nil {
+			groupInfo, err = user.LookupGroupId(groupName)
+		}
+		if err != nil {
+			return 0, 0, E.Cause(err, "lookup group: ", groupName)
+		}
+		gid, err = strconv.Atoi(groupInfo.Gid)
+		if err != nil {
+			return 0, 0, E.Cause(err, "parse gid: ", groupInfo.Gid)
+		}
+	}
+	if gid == -1 {
+		gid = os.Getgid()
+	}
+	return uid, gid, nil
+}
@@ -0,0 +1,20 @@
+//go:build unix && !linux
+
+package privilege
+
+import E "github.com/sagernet/sing/common/exceptions"
+
+func parseCapabilities(names []string) ([]uint32, error) {
+	if len(names) > 0 {
+		return nil, E.New("keep_capabilities is only supported on Linux")
+	}
+	return nil, nil
+}
+
+func keepCapabilities(keep bool) error {
+	return nil
+}
+
+func setCapabilities(capabilities []uint32) error {
+	return nil
+}
@@ -0,0 +1,81 @@
+package quicmtu
+
+import (
+	"net"
+	"syscall"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+const (
+	// DefaultInitialMTU is the initial packet size of quic-go.
+	DefaultInitialMTU = 1280
+	minInitialMTU     = 1200
+	maxInitialMTU     = 1452
+)
+
+// Validate checks the options for a QUIC protocol, initialMTU reports whether
+// the protocol supports configuring the initial MTU.
+func Validate(options option.QUICMTUOptions, initialMTU bool) error {
+	if options.InitialMTU > 0 {
+		if !initialMTU {
+			return E.New("`initial_mtu` is not supported")
+		}
+		if options.InitialMTU < minInitialMTU || options.InitialMTU > maxInitialMTU {
+			return E.New("`initial_mtu` must be between ", minInitialMTU, " and ", maxInitialMTU)
+		}
+	}
+	if options.MaxDatagramSize > 0 {
+		currentInitialMTU := options.InitialMTU
+		if currentInitialMTU == 0 {
+			currentInitialMTU = DefaultInitialMTU
+		}
+		if options.MaxDatagramSize < currentInitialMTU {
+			return E.New("`max_datagram_size` must not be less than the initial MTU ", currentInitialMTU)
+		}
+	}
+	return nil
+}
+
+// NewPacketConn wraps the UDP socket passed to QUIC.
+//
+// To disable path MTU discovery, the socket is hidden from quic-go so that it
+// does not set the DF bit, which path MTU discovery requires. To limit the
+// datagram size, probe packets larger than the limit are dropped, so that path
+// MTU discovery never goes beyond it. Both disable the batch send and receive
+// optimizations of quic-go.
+func NewPacketConn(conn net.PacketConn, options option.QUICMTUOptions) net.PacketConn {
+	if !options.DisableMTUDiscovery && options.MaxDatagramSize == 0 {
+		return conn
+	}
+	limitedConn := &packetConn{PacketConn: conn, maxDatagramSize: int(options.MaxDatagramSize)}
+	if !options.DisableMTUDiscovery {
+		if syscallConn, isSyscallConn := conn.(syscall.Conn); isSyscallConn {
+			return &syscallPacketConn{limitedConn, syscallConn}
+		}
+	}
+	return limitedConn
+}
+
+type packetConn struct {
+	net.PacketConn
+	maxDatagramSize int
+}
+
+func (c *packetConn) WriteTo(p []byte, addr net.Addr) (n int, err error) {
+	if c.maxDatagramSize > 0 && len(p) > c.maxDatagramSize {
+		// dropped as by a link with a smaller MTU
+		return len(p), nil
+	}
+	return c.PacketConn.WriteTo(p, addr)
+}
+
+type syscallPacketConn struct {
+	*packetConn
+	syscallConn syscall.Conn
+}
+
+func (c *syscallPacketConn) SyscallConn() (syscall.RawConn, error) {
+	return c.syscallConn.SyscallConn()
+}
@@ -0,0 +1,41 @@
+package redir
+
+import (
+	"net/netip"
+)
+
+const (
+	DefaultAutoRedirectInputMark  = 0x2025
+	DefaultAutoRedirectOutputMark = 0x2024
+	DefaultIPRoute2TableIndex     = 2100
+	DefaultIPRoute2RuleIndex      = 8900
+)
+
+// reservedPrefixes is the list of destinations never redirected to the proxy.
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("224.0.0.0/4"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("::1/128"),
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fe80::/10"),
+	netip.MustParsePrefix("ff00::/8"),
+}
+
+type AutoRedirectOptions struct {
+	Name               string
+	TProxy             bool
+	Port               uint16
+	Network            []string
+	InputMark          uint32
+	OutputMark         uint32
+	IPRoute2TableIndex int
+	IPRoute2RuleIndex  int
+	ExcludeAddress     []netip.Prefix
+}
@@ -0,0 +1,473 @@
+package redir
+
+import (
+	"net"
+	"os"
+
+	"github.com/sagernet/netlink"
+	"github.com/sagernet/nftables"
+	"github.com/sagernet/nftables/binaryutil"
+	"github.com/sagernet/nftables/expr"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	N "github.com/sagernet/sing/common/network"
+
+	"go4.org/netipx"
+	"golang.org/x/sys/unix"
+)
+
+type AutoRedirect struct {
+	options   AutoRedirectOptions
+	tableName string
+	rules     []*netlink.Rule
+	routes    []*netlink.Route
+}
+
+func NewAutoRedirect(options AutoRedirectOptions) (*AutoRedirect, error) {
+	if options.OutputMark == 0 {
+		options.OutputMark = DefaultAutoRedirectOutputMark
+	}
+	if options.TProxy {
+		if options.InputMark == 0 {
+			options.InputMark = DefaultAutoRedirectInputMark
+		}
+		if options.InputMark == options.OutputMark {
+			return nil, E.New("`auto_redirect_input_mark` and `auto_redirect_output_mark` must be different")
+		}
+		if options.IPRoute2TableIndex == 0 {
+			options.IPRoute2TableIndex = DefaultIPRoute2TableIndex
+		}
+		if options.IPRoute2RuleIndex == 0 {
+			options.IPRoute2RuleIndex = DefaultIPRoute2RuleIndex
+		}
+	} else {
+		options.Network = []string{N.NetworkTCP}
+	}
+	return &AutoRedirect{
+		options:   options,
+		tableName: "sing-box-" + options.Name,
+	}, nil
+}
+
+func (r *AutoRedirect) Start() error {
+	nft, err := nftables.New()
+	if err != nil {
+		return E.Cause(err, "open nftables")
+	}
+	defer nft.CloseLasting()
+	// remove rules left over by an unclean exit
+	r.cleanupTable(nft)
+	table := nft.AddTable(&nftables.Table{
+		Name:   r.tableName,
+		Family: nftables.TableFamilyINet,
+	})
+	excludeSet4, err := r.createExcludeSet(nft, table, 1, "exclude_address", nftables.TableFamilyIPv4)
+	if err != nil {
+		return err
+	}
+	excludeSet6, err := r.createExcludeSet(nft, table, 2, "exclude_address6", nftables.TableFamilyIPv6)
+	if err != nil {
+		return err
+	}
+	excludeSets := []*nftables.Set{excludeSet4, excludeSet6}
+	if r.options.TProxy {
+		r.setupTProxy(nft, table, excludeSets)
+	} else {
+		r.setupRedirect(nft, table, excludeSets)
+	}
+	err = nft.Flush()
+	if err != nil {
+		return E.Cause(err, "install nftables rules")
+	}
+	if r.options.TProxy {
+		err = r.setupRoute()
+		if err != nil {
+			r.Close()
+			return E.Cause(err, "install iproute2 rules")
+		}
+	}
+	return nil
+}
+
+func (r *AutoRedirect) Close() error {
+	for _, rule := range r.rules {
+		netlink.RuleDel(rule)
+	}
+	r.rules = nil
+	for _, route := range r.routes {
+		netlink.RouteDel(route)
+	}
+	r.routes = nil
+	nft, err := nftables.New()
+	if err != nil {
+		return err
+	}
+	defer nft.CloseLasting()
+	r.cleanupTable(nft)
+	return nil
+}
+
+func (r *AutoRedirect) cleanupTable(nft *nftables.Conn) {
+	table, err := nft.ListTableOfFamily(r.tableName, nftables.TableFamilyINet)
+	if err != nil {
+		return
+	}
+	nft.DelTable(table)
+	_ = nft.Flush()
+}
+
+func (r *AutoRedirect) setupRedirect(nft *nftables.Conn, table *nftables.Table, excludeSets []*nftables.Set) {
+	chainPreRouting := nft.AddChain(&nftables.Chain{
+		Name:     "prerouting",
+		Table:    table,
+		Hooknum:  nftables.ChainHookPrerouting,
+		Priority: nftables.ChainPriorityNATDest,
+		Type:     nftables.ChainTypeNAT,
+	})
+	chainOutput := nft.AddChain(&nftables.Chain{
+		Name:     "output",
+		Table:    table,
+		Hooknum:  nftables.ChainHookOutput,
+		Priority: nftables.ChainPriorityNATDest,
+		Type:     nftables.ChainTypeNAT,
+	})
+	r.addOutputMarkReturn(nft, table, chainOutput)
+	for _, chain := range []*nftables.Chain{chainPreRouting, chainOutput} {
+		r.addExcludeRules(nft, table, chain, excludeSets)
+		nft.AddRule(&nftables.Rule{
+			Table: table,
+			Chain: chain,
+			Exprs: append(
+				nftablesL4ProtoExprs(unix.IPPROTO_TCP),
+				&expr.Counter{},
+				&expr.Immediate{
+					Register: 1,
+					Data:     binaryutil.BigEndian.PutUint16(r.options.Port),
+				},
+				&expr.Redir{
+					RegisterProtoMin: 1,
+					Flags:            unix.NF_NAT_RANGE_PROTO_SPECIFIED,
+				},
+			),
+		})
+	}
+}
+
+func (r *AutoRedirect) setupTProxy(nft *nftables.Conn, table *nftables.Table, excludeSets []*nftables.Set) {
+	chainPreRouting := nft.AddChain(&nftables.Chain{
+		Name:     "prerouting",
+		Table:    table,
+		Hooknum:  nftables.ChainHookPrerouting,
+		Priority: nftables.ChainPriorityMangle,
+		Type:     nftables.ChainTypeFilter,
+	})
+	r.addExcludeRules(nft, table, chainPreRouting, excludeSets)
+	l4Protos := r.l4Protos()
+	// divert packets of connections already owned by a transparent socket
+	for _, l4Proto := range l4Protos {
+		exprs := nftablesL4ProtoExprs(l4Proto)
+		exprs = append(exprs,
+			&expr.Socket{
+				Key:      expr.SocketKeyTransparent,
+				Register: 1,
+			},
+			&expr.Cmp{
+				Op:       expr.CmpOpEq,
+				Register: 1,
+				Data:     []byte{1},
+			},
+		)
+		exprs = append(exprs, nftablesSetMarkExprs(r.options.InputMark)...)
+		exprs = append(exprs, &expr.Verdict{Kind: expr.VerdictAccept})
+		nft.AddRule(&nftables.Rule{
+			Table: table,
+			Chain: chainPreRouting,
+			Exprs: exprs,
+		})
+	}
+	for _, l4Proto := range l4Protos {
+		exprs := nftablesL4ProtoExprs(l4Proto)
+		exprs = append(exprs,
+			&expr.Counter{},
+			&expr.Immediate{
+				Register: 1,
+				Data:     binaryutil.BigEndian.PutUint16(r.options.Port),
+			},
+			&expr.TProxy{
+				Family:      unix.NFPROTO_UNSPEC,
+				TableFamily: byte(nftables.TableFamilyINet),
+				RegPort:     1,
+			},
+		)
+		exprs = append(exprs, nftablesSetMarkExprs(r.options.InputMark)...)
+		exprs = append(exprs, &expr.Verdict{Kind: expr.VerdictAccept})
+		nft.AddRule(&nftables.Rule{
+			Table: table,
+			Chain: chainPreRouting,
+			Exprs: exprs,
+		})
+	}
+
+	// route locally generated packets back to prerouting through the loopback
+	chainOutput := nft.AddChain(&nftables.Chain{
+		Name:     "output",
+		Table:    table,
+		Hooknum:  nftables.ChainHookOutput,
+		Priority: nftables.ChainPriorityMangle,
+		Type:     nftables.ChainTypeRoute,
+	})
+	r.addOutputMarkReturn(nft, table, chainOutput)
+	// replies from transparent sockets carry the original destination as source
+	nft.AddRule(&nftables.Rule{
+		Table: table,
+		Chain: chainOutput,
+		Exprs: []expr.Any{
+			&expr.Fib{
+				Register:       1,
+				FlagSADDR:      true,
+				ResultADDRTYPE: true,
+			},
+			&expr.Cmp{
+				Op:       expr.CmpOpNeq,
+				Register: 1,
+				Data:     binaryutil.NativeEndian.PutUint32(unix.RTN_LOCAL),
+			},
+			&expr.Verdict{Kind: expr.VerdictReturn},
+		},
+	})
+	r.addExcludeRules(nft, table, chainOutput, excludeSets)
+	for _, l4Proto := range l4Protos {
+		exprs := nftablesL4ProtoExprs(l4Proto)
+		exprs = append(exprs, &expr.Counter{})
+		exprs = append(exprs, nftablesSetMarkExprs(r.options.InputMark)...)
+		nft.AddRule(&nftables.Rule{
+			Table: table,
+			Chain: chainOutput,
+			Exprs: exprs,
+		})
+	}
+}
+
+func (r *AutoRedirect) setupRoute() error {
+	loopback, err := netlink.LinkByName("lo")
+	if err != nil {
+		return E.Cause(err, "find loopback interface")
+	}
+	families := []int{unix.AF_INET}
+	if _, err = os.Stat("/proc/net/if_inet6"); err == nil {
+		families = append(families, unix.AF_INET6)
+	}
+	for _, family := range families {
+		var destination *net.IPNet
+		if family == unix.AF_INET {
+			destination = &net.IPNet{IP: net.IPv4zero.To4(), Mask: net.CIDRMask(0, 32)}
+		} else {
+			destination = &net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, 128)}
+		}
+		route := &netlink.Route{
+			LinkIndex: loopback.Attrs().Index,
+			Scope:     netlink.SCOPE_HOST,
+			Dst:       destination,
+			Table:     r.options.IPRoute2TableIndex,
+			Type:      unix.RTN_LOCAL,
+		}
+		err = netlink.RouteReplace(route)
+		if err != nil {
+			return E.Cause(err, "add route")
+		}
+		r.routes = append(r.routes, route)
+		rule := netlink.NewRule()
+		rule.Priority = r.options.IPRoute2RuleIndex
+		rule.Family = family
+		rule.Table = r.options.IPRoute2TableIndex
+		rule.Mark = r.options.InputMark
+		rule.MarkSet = true
+		netlink.RuleDel(rule)
+		err = netlink.RuleAdd(rule)
+		if err != nil {
+			return E.Cause(err, "add rule")
+		}
+		r.rules = append(r.rules, rule)
+	}
+	return nil
+}
+
+func (r *AutoRedirect) l4Protos() []byte {
+	var l4Protos []byte
+	if common.Contains(r.options.Network, N.NetworkTCP) {
+		l4Protos = append(l4Protos, unix.IPPROTO_TCP)
+	}
+	if common.Contains(r.options.Network, N.NetworkUDP) {
+		l4Protos = append(l4Protos, unix.IPPROTO_UDP)
+	}
+	return l4Protos
+}
+
+func (r *AutoRedirect) addOutputMarkReturn(nft *nftables.Conn, table *nftables.Table, chain *nftables.Chain) {
+	nft.AddRule(&nftables.Rule{
+		Table: table,
+		Chain: chain,
+		Exprs: []expr.Any{
+			&expr.Meta{
+				Key:      expr.MetaKeyMARK,
+				Register: 1,
+			},
+			&expr.Cmp{
+				Op:       expr.CmpOpEq,
+				Register: 1,
+				Data:     binaryutil.NativeEndian.PutUint32(r.options.OutputMark),
+			},
+			&expr.Verdict{Kind: expr.VerdictReturn},
+		},
+	})
+}
+
+func (r *AutoRedirect) addExcludeRules(nft *nftables.Conn, table *nftables.Table, chain *nftables.Chain, excludeSets []*nftables.Set) {
+	nft.AddRule(&nftables.Rule{
+		Table: table,
+		Chain: chain,
+		Exprs: []expr.Any{
+			&expr.Fib{
+				Register:       1,
+				FlagDADDR:      true,
+				ResultADDRTYPE: true,
+			},
+			&expr.Cmp{
+				Op:       expr.CmpOpEq,
+				Register: 1,
+				Data:     binaryutil.NativeEndian.PutUint32(unix.RTN_LOCAL),
+			},
+			&expr.Verdict{Kind: expr.VerdictReturn},
+		},
+	})
+	for _, excludeSet := range excludeSets {
+		nft.AddRule(&nftables.Rule{
+			Table: table,
+			Chain: chain,
+			Exprs: append(
+				nftablesDestinationIPSetExprs(excludeSet),
+				&expr.Verdict{Kind: expr.VerdictReturn},
+			),
+		})
+	}
+}
+
+func (r *AutoRedirect) createExcludeSet(nft *nftables.Conn, table *nftables.Table, id uint32, name string, family nftables.TableFamily) (*nftables.Set, error) {
+	var builder netipx.IPSetBuilder
+	for _, prefix := range reservedPrefixes {
+		builder.AddPrefix(prefix)
+	}
+	for _, prefix := range r.options.ExcludeAddress {
+		builder.AddPrefix(prefix)
+	}
+	ipSet, err := builder.IPSet()
+	if err != nil {
+		return nil, err
+	}
+	var setElements []nftables.SetElement
+	for _, ipRange := range ipSet.Ranges() {
+		if (family == nftables.TableFamilyIPv4) != ipRange.From().Is4() {
+			continue
+		}
+		setElements = append(setElements, nftables.SetElement{
+			Key: ipRange.From().AsSlice(),
+		})
+		// an interval reaching the end of the address space has no end element
+		endAddr := ipRange.To().Next()
+		if endAddr.IsValid() {
+			setElements = append(setElements, nftables.SetElement{
+				Key:         endAddr.AsSlice(),
+				IntervalEnd: true,
+			})
+		}
+	}
+	keyType := nftables.TypeIPAddr
+	if family == nftables.TableFamilyIPv6 {
+		keyType = nftables.TypeIP6Addr
+	}
+	excludeSet := &nftables.Set{
+		Table:    table,
+		ID:       id,
+		Name:     name,
+		Interval: true,
+		KeyType:  keyType,
+	}
+	err = nft.AddSet(excludeSet, setElements)
+	if err != nil {
+		return nil, E.Cause(err, "create set ", name)
+	}
+	return excludeSet, nil
+}
+
+func nftablesL4ProtoExprs(l4Proto byte) []expr.Any {
+	return []expr.Any{
+		&expr.Meta{
+			Key:      expr.MetaKeyL4PROTO,
+			Register: 1,
+		},
+		&expr.Cmp{
+			Op:       expr.CmpOpEq,
+			Register: 1,
+			Data:     []byte{l4Proto},
+		},
+	}
+}
+
+func nftablesSetMarkExprs(mark uint32) []expr.Any {
+	return []expr.Any{
+		&expr.Immediate{
+			Register: 1,
+			Data:     binaryutil.NativeEndian.PutUint32(mark),
+		},
+		&expr.Meta{
+			Key:            expr.MetaKeyMARK,
+			Register:       1,
+			SourceRegister: true,
+		},
+	}
+}
+
+func nftablesDestinationIPSetExprs(set *nftables.Set) []expr.Any {
+	exprs := []expr.Any{
+		&expr.Meta{
+			Key:      expr.MetaKeyNFPROTO,
+			Register: 1,
+		},
+	}
+	if set.KeyType == nftables.TypeIPAddr {
+		exprs = append(exprs,
+			&expr.Cmp{
+				Op:       expr.CmpOpEq,
+				Register: 1,
+				Data:     []byte{unix.NFPROTO_IPV4},
+			},
+			&expr.Payload{
+				OperationType: expr.PayloadLoad,
+				DestRegister:  1,
+				Base:          expr.PayloadBaseNetworkHeader,
+				Offset:        16,
+				Len:           4,
+			},
+		)
+	} else {
+		exprs = append(exprs,
+			&expr.Cmp{
+				Op:       expr.CmpOpEq,
+				Register: 1,
+				Data:     []byte{unix.NFPROTO_IPV6},
+			},
+			&expr.Payload{
+				OperationType: expr.PayloadLoad,
+				DestRegister:  1,
+				Base:          expr.PayloadBaseNetworkHeader,
+				Offset:        24,
+				Len:           16,
+			},
+		)
+	}
+	return append(exprs, &expr.Lookup{
+		SourceRegister: 1,
+		SetID:          set.ID,
+		SetName:        set.Name,
+	})
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package redir
+
+import (
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+type AutoRedirect struct{}
+
+func NewAutoRedirect(options AutoRedirectOptions) (*AutoRedirect, error) {
+	return nil, E.New("`auto_redirect` is only supported on Linux")
+}
+
+func (r *AutoRedirect) Start() error {
+	return nil
+}
+
+func (r *AutoRedirect) Close() error {
+	return nil
+}
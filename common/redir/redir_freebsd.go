@@ -0,0 +1,72 @@
+package redir
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"syscall"
+	"unsafe"
+
+	M "github.com/sagernet/sing/common/metadata"
+)
+
+const (
+	PF_OUT      = 0x2
+	DIOCNATLOOK = 0xc04c4417
+)
+
+// GetOriginalDestination looks up the state of pf rdr rules first, and falls
+// back to the local address of the connection, which is the original
+// destination with ipfw fwd and pf divert-to rules.
+func GetOriginalDestination(conn net.Conn) (destination netip.AddrPort, err error) {
+	localAddr := M.AddrPortFromNet(conn.LocalAddr())
+	remoteAddr := M.AddrPortFromNet(conn.RemoteAddr())
+	destination, err = lookupNAT(remoteAddr, localAddr)
+	if err != nil {
+		if errors.Is(err, syscall.ENOENT) || errors.Is(err, syscall.EACCES) || errors.Is(err, syscall.EPERM) {
+			return netip.AddrPortFrom(localAddr.Addr().Unmap(), localAddr.Port()), nil
+		}
+		return netip.AddrPort{}, err
+	}
+	return
+}
+
+func lookupNAT(source netip.AddrPort, destination netip.AddrPort) (netip.AddrPort, error) {
+	fd, err := syscall.Open("/dev/pf", syscall.O_RDONLY, 0)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	defer syscall.Close(fd)
+	nl := struct {
+		saddr, daddr, rsaddr, rdaddr [16]byte
+		sport, dport, rsport, rdport [2]byte
+		af, proto, direction         uint8
+		_                            uint8
+	}{
+		proto:     syscall.IPPROTO_TCP,
+		direction: PF_OUT,
+	}
+	sourceAddr, destinationAddr := source.Addr().Unmap(), destination.Addr().Unmap()
+	if sourceAddr.Is4() {
+		sourceBytes, destinationBytes := sourceAddr.As4(), destinationAddr.As4()
+		copy(nl.saddr[:], sourceBytes[:])
+		copy(nl.daddr[:], destinationBytes[:])
+		nl.af = syscall.AF_INET
+	} else {
+		nl.saddr = sourceAddr.As16()
+		nl.daddr = destinationAddr.As16()
+		nl.af = syscall.AF_INET6
+	}
+	nl.sport[0], nl.sport[1] = byte(source.Port()>>8), byte(source.Port())
+	nl.dport[0], nl.dport[1] = byte(destination.Port()>>8), byte(destination.Port())
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), DIOCNATLOOK, uintptr(unsafe.Pointer(&nl))); errno != 0 {
+		return netip.AddrPort{}, errno
+	}
+	var addr netip.Addr
+	if nl.af == syscall.AF_INET {
+		addr = netip.AddrFrom4([4]byte(nl.rdaddr[:4]))
+	} else {
+		addr = netip.AddrFrom16(nl.rdaddr)
+	}
+	return netip.AddrPortFrom(addr, uint16(nl.rdport[0])<<8|uint16(nl.rdport[1])), nil
+}
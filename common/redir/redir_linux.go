@@ -6,6 +6,7 @@ import (
 	"net/netip"
 	"os"
 	"syscall"
+	"unsafe"
 
 	"github.com/sagernet/sing/common"
 	"github.com/sagernet/sing/common/control"
@@ -30,9 +31,9 @@ func GetOriginalDestination(conn net.Conn) (destination netip.AddrPort, err erro
 			if err != nil {
 				return err
 			}
-			var port [2]byte
-			binary.BigEndian.PutUint16(port[:], raw.Addr.Port)
-			destination = netip.AddrPortFrom(M.AddrFromIP(raw.Addr.Addr[:]), binary.LittleEndian.Uint16(port[:]))
+			// the port is in network byte order regardless of the host
+			port := (*[2]byte)(unsafe.Pointer(&raw.Addr.Port))
+			destination = netip.AddrPortFrom(M.AddrFromIP(raw.Addr.Addr[:]), binary.BigEndian.Uint16(port[:]))
 		}
 		return nil
 	})
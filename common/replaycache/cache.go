@@ -0,0 +1,61 @@
+package replaycache
+
+import (
+	"context"
+	"time"
+
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/replay"
+)
+
+// Cache remembers request IDs of inbounds, such as salts and authentication
+// IDs, to reject replays in addition to the filter of the protocol.
+type Cache interface {
+	Start() error
+	// Check reports whether the ID is not seen in the TTL, and remembers it.
+	Check(ctx context.Context, id []byte) bool
+	Close() error
+}
+
+func New(ctx context.Context, logger log.ContextLogger, options option.ReplayProtectionOptions, defaultTTL time.Duration) (Cache, error) {
+	ttl := time.Duration(options.TTL)
+	if ttl == 0 {
+		ttl = defaultTTL
+	} else if ttl < defaultTTL {
+		return nil, E.New("replay protection ttl must not be less than ", defaultTTL)
+	}
+	switch {
+	case options.Redis != nil && options.Gossip != nil:
+		return nil, E.New("redis and gossip must not be combined")
+	case options.Redis != nil:
+		return NewRedis(ctx, logger, *options.Redis, ttl)
+	case options.Gossip != nil:
+		return NewGossip(ctx, logger, *options.Gossip, ttl)
+	default:
+		return NewMemory(ttl), nil
+	}
+}
+
+var _ Cache = (*Memory)(nil)
+
+type Memory struct {
+	filter replay.Filter
+}
+
+func NewMemory(ttl time.Duration) *Memory {
+	return &Memory{replay.NewSimple(ttl)}
+}
+
+func (c *Memory) Start() error {
+	return nil
+}
+
+func (c *Memory) Check(ctx context.Context, id []byte) bool {
+	return c.filter.Check(id)
+}
+
+func (c *Memory) Close() error {
+	return nil
+}
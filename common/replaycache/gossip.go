@@ -0,0 +1,162 @@
+package replaycache
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/sagernet/sing-box/common/listener"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+// A gossip packet announces an ID seen by a server to its peers:
+//
+//	version (1) | unix time in seconds (8) | ID length (1) | ID | truncated HMAC-SHA256 of the previous fields (16)
+const (
+	gossipVersion   = 1
+	gossipMACLength = 16
+	maxIDLength     = 255
+)
+
+var _ Cache = (*Gossip)(nil)
+
+// Gossip shares IDs between servers by sending them to peers, replays sent to
+// two servers in the network latency between them are not detected.
+type Gossip struct {
+	*Memory
+	logger     log.ContextLogger
+	listener   *listener.Listener
+	packetConn net.PacketConn
+	peers      []*net.UDPAddr
+	key        []byte
+	ttl        time.Duration
+}
+
+func NewGossip(ctx context.Context, logger log.ContextLogger, options option.ReplayGossipOptions, ttl time.Duration) (*Gossip, error) {
+	if options.ListenPort == 0 {
+		return nil, E.New("missing gossip listen_port")
+	}
+	key, err := base64.StdEncoding.DecodeString(options.Key)
+	if err != nil {
+		return nil, E.Cause(err, "decode gossip key")
+	}
+	if len(key) < 16 {
+		return nil, E.New("gossip key too short, at least 16 bytes required")
+	}
+	peers := make([]*net.UDPAddr, 0, len(options.Peers))
+	for _, peer := range options.Peers {
+		peerAddr := M.ParseSocksaddr(peer)
+		if !peerAddr.IsIP() || peerAddr.Port == 0 {
+			return nil, E.New("invalid gossip peer: ", peer, ", IP address and port required")
+		}
+		peers = append(peers, peerAddr.UDPAddr())
+	}
+	return &Gossip{
+		Memory: NewMemory(ttl),
+		logger: logger,
+		listener: listener.New(listener.Options{
+			Context: ctx,
+			Logger:  logger,
+			Network: []string{N.NetworkUDP},
+			Listen: option.ListenOptions{
+				Listen:     options.Listen,
+				ListenPort: options.ListenPort,
+			},
+		}),
+		peers: peers,
+		key:   key,
+		ttl:   ttl,
+	}, nil
+}
+
+func (c *Gossip) Start() error {
+	packetConn, err := c.listener.ListenUDP()
+	if err != nil {
+		return E.Cause(err, "listen gossip")
+	}
+	c.packetConn = packetConn
+	go c.loopGossip()
+	return nil
+}
+
+func (c *Gossip) Check(ctx context.Context, id []byte) bool {
+	if !c.Memory.Check(ctx, id) {
+		return false
+	}
+	if len(id) <= maxIDLength && len(c.peers) > 0 && c.packetConn != nil {
+		packet := c.newPacket(id, time.Now())
+		for _, peer := range c.peers {
+			_, err := c.packetConn.WriteTo(packet, peer)
+			if err != nil {
+				c.logger.DebugContext(ctx, "gossip: write to ", peer, ": ", err)
+			}
+		}
+	}
+	return true
+}
+
+func (c *Gossip) newPacket(id []byte, now time.Time) []byte {
+	packet := make([]byte, 10+len(id), 10+len(id)+gossipMACLength)
+	packet[0] = gossipVersion
+	binary.BigEndian.PutUint64(packet[1:], uint64(now.Unix()))
+	packet[9] = byte(len(id))
+	copy(packet[10:], id)
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write(packet)
+	return mac.Sum(packet)[:len(packet)+gossipMACLength]
+}
+
+// parsePacket returns the ID of a valid packet.
+func (c *Gossip) parsePacket(packet []byte, now time.Time) ([]byte, error) {
+	if len(packet) < 10+gossipMACLength || packet[0] != gossipVersion {
+		return nil, E.New("bad gossip packet")
+	}
+	idLength := int(packet[9])
+	if len(packet) != 10+idLength+gossipMACLength {
+		return nil, E.New("bad gossip packet")
+	}
+	content := packet[:10+idLength]
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write(content)
+	if !hmac.Equal(mac.Sum(nil)[:gossipMACLength], packet[len(content):]) {
+		return nil, E.New("bad gossip packet")
+	}
+	seenAt := time.Unix(int64(binary.BigEndian.Uint64(packet[1:])), 0)
+	if difference := now.Sub(seenAt); difference > c.ttl || difference < -c.ttl {
+		return nil, E.New("expired gossip packet")
+	}
+	return content[10:], nil
+}
+
+func (c *Gossip) loopGossip() {
+	buffer := make([]byte, 10+maxIDLength+gossipMACLength)
+	for {
+		n, addr, err := c.packetConn.ReadFrom(buffer)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				c.logger.Error("gossip: ", err)
+			}
+			return
+		}
+		id, err := c.parsePacket(buffer[:n], time.Now())
+		if err != nil {
+			c.logger.Debug("gossip: ", err, " from ", addr)
+			continue
+		}
+		c.Memory.Check(context.Background(), id)
+	}
+}
+
+func (c *Gossip) Close() error {
+	return common.Close(common.PtrOrNil(c.listener))
+}
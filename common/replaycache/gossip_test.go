@@ -0,0 +1,24 @@
+package replaycache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGossipPacket(t *testing.T) {
+	t.Parallel()
+	gossip := &Gossip{key: []byte("0123456789abcdef"), ttl: time.Minute}
+	id := []byte("0123456789abcdef0123456789abcdef")
+	now := time.Now()
+	packet := gossip.newPacket(id, now)
+	parsedID, err := gossip.parsePacket(packet, now)
+	require.NoError(t, err)
+	require.Equal(t, id, parsedID)
+	_, err = gossip.parsePacket(packet, now.Add(2*time.Minute))
+	require.Error(t, err)
+	packet[10] ^= 1
+	_, err = gossip.parsePacket(packet, now)
+	require.Error(t, err)
+}
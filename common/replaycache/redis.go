@@ -0,0 +1,205 @@
+package replaycache
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/common/dialer"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+const (
+	DefaultKeyPrefix = "sing-box:replay:"
+
+	redisTimeout  = 5 * time.Second
+	redisMaxIdle  = 4
+	redisNilReply = "$-1"
+)
+
+var _ Cache = (*Redis)(nil)
+
+// Redis shares IDs between servers with SET NX, IDs are accepted if the
+// server is unavailable.
+type Redis struct {
+	logger    log.ContextLogger
+	dialer    N.Dialer
+	server    M.Socksaddr
+	username  string
+	password  string
+	db        int
+	keyPrefix string
+	ttl       time.Duration
+	access    sync.Mutex
+	idleConns []*redisConn
+	closed    bool
+}
+
+type redisConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func NewRedis(ctx context.Context, logger log.ContextLogger, options option.ReplayRedisOptions, ttl time.Duration) (*Redis, error) {
+	if options.Server == "" {
+		return nil, E.New("missing redis server")
+	}
+	outboundDialer, err := dialer.New(ctx, options.DialerOptions, options.ServerIsDomain())
+	if err != nil {
+		return nil, err
+	}
+	server := options.Build()
+	if server.Port == 0 {
+		server.Port = 6379
+	}
+	keyPrefix := options.KeyPrefix
+	if keyPrefix == "" {
+		keyPrefix = DefaultKeyPrefix
+	}
+	return &Redis{
+		logger:    logger,
+		dialer:    outboundDialer,
+		server:    server,
+		username:  options.Username,
+		password:  options.Password,
+		db:        options.DB,
+		keyPrefix: keyPrefix,
+		ttl:       ttl,
+	}, nil
+}
+
+func (c *Redis) Start() error {
+	return nil
+}
+
+func (c *Redis) Check(ctx context.Context, id []byte) bool {
+	reply, err := c.exchange(ctx, "SET", c.keyPrefix+hex.EncodeToString(id), "1", "NX", "PX", strconv.FormatInt(c.ttl.Milliseconds(), 10))
+	if err != nil {
+		c.logger.ErrorContext(ctx, E.Cause(err, "check replay with redis"))
+		return true
+	}
+	return reply != redisNilReply
+}
+
+func (c *Redis) exchange(ctx context.Context, args ...string) (string, error) {
+	conn, err := c.getConn(ctx)
+	if err != nil {
+		return "", err
+	}
+	reply, err := conn.exchange(args...)
+	if err != nil {
+		conn.Close()
+		return "", err
+	}
+	c.putConn(conn)
+	return reply, nil
+}
+
+func (c *Redis) getConn(ctx context.Context) (*redisConn, error) {
+	c.access.Lock()
+	if len(c.idleConns) > 0 {
+		conn := c.idleConns[len(c.idleConns)-1]
+		c.idleConns = c.idleConns[:len(c.idleConns)-1]
+		c.access.Unlock()
+		return conn, nil
+	}
+	c.access.Unlock()
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+	rawConn, err := c.dialer.DialContext(ctx, N.NetworkTCP, c.server)
+	if err != nil {
+		return nil, err
+	}
+	conn := &redisConn{Conn: rawConn, reader: bufio.NewReader(rawConn)}
+	if c.password != "" {
+		if c.username != "" {
+			_, err = conn.exchange("AUTH", c.username, c.password)
+		} else {
+			_, err = conn.exchange("AUTH", c.password)
+		}
+		if err != nil {
+			conn.Close()
+			return nil, E.Cause(err, "authenticate")
+		}
+	}
+	if c.db != 0 {
+		_, err = conn.exchange("SELECT", strconv.Itoa(c.db))
+		if err != nil {
+			conn.Close()
+			return nil, E.Cause(err, "select database")
+		}
+	}
+	return conn, nil
+}
+
+func (c *Redis) putConn(conn *redisConn) {
+	c.access.Lock()
+	defer c.access.Unlock()
+	if c.closed || len(c.idleConns) >= redisMaxIdle {
+		conn.Close()
+		return
+	}
+	c.idleConns = append(c.idleConns, conn)
+}
+
+func (c *Redis) Close() error {
+	c.access.Lock()
+	defer c.access.Unlock()
+	c.closed = true
+	for _, conn := range c.idleConns {
+		conn.Close()
+	}
+	c.idleConns = nil
+	return nil
+}
+
+// exchange sends a command and returns the first line of the reply.
+func (c *redisConn) exchange(args ...string) (string, error) {
+	err := c.SetDeadline(time.Now().Add(redisTimeout))
+	if err != nil {
+		return "", err
+	}
+	var command strings.Builder
+	command.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		command.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n")
+	}
+	_, err = c.Write([]byte(command.String()))
+	if err != nil {
+		return "", err
+	}
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return "", E.New("bad redis reply")
+	}
+	switch line[0] {
+	case '-':
+		return "", E.New("redis: ", line[1:])
+	case '$':
+		if line == redisNilReply {
+			return line, nil
+		}
+		length, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return "", E.New("bad redis reply: ", line)
+		}
+		_, err = c.reader.Discard(length + 2)
+		if err != nil {
+			return "", err
+		}
+	}
+	return line, nil
+}
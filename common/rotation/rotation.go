@@ -0,0 +1,113 @@
+package rotation
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"math/rand"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// Selector selects one of the configured values, such as paths or hosts of a
+// transport, for each connection.
+//
+// Without an interval, clients select a random value for each connection and
+// servers accept all values. With an interval, both sides derive the value of
+// the current epoch from the shared key, and servers also accept the values
+// of the adjacent epochs to tolerate clock skew.
+type Selector struct {
+	values   []string
+	label    string
+	interval time.Duration
+	key      []byte
+	timeFunc func() time.Time
+}
+
+// New creates a selector of values, label separates the schedules of selectors
+// sharing the same rotation options. It returns nil if there are no values.
+func New(values []string, label string, options *option.V2RayTransportRotationOptions) (*Selector, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	for i, value := range values {
+		if common.Contains(values[:i], value) {
+			return nil, E.New("duplicate ", label, ": ", value)
+		}
+	}
+	selector := &Selector{
+		values:   values,
+		label:    label,
+		timeFunc: time.Now,
+	}
+	if options != nil {
+		if options.Interval < 0 {
+			return nil, E.New("invalid rotation interval: ", options.Interval)
+		}
+		selector.interval = time.Duration(options.Interval)
+		selector.key = []byte(options.Key)
+	}
+	return selector, nil
+}
+
+func (s *Selector) epochIndex(epoch int64) int {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(s.label))
+	mac.Write(binary.BigEndian.AppendUint64(nil, uint64(epoch)))
+	return int(binary.BigEndian.Uint64(mac.Sum(nil)) % uint64(len(s.values)))
+}
+
+func (s *Selector) epoch() int64 {
+	return s.timeFunc().UnixNano() / int64(s.interval)
+}
+
+// Values returns all configured values.
+func (s *Selector) Values() []string {
+	return s.values
+}
+
+// Select returns the value of a new connection.
+func (s *Selector) Select() string {
+	if len(s.values) == 1 {
+		return s.values[0]
+	}
+	if s.interval == 0 {
+		return s.values[rand.Intn(len(s.values))]
+	}
+	return s.values[s.epochIndex(s.epoch())]
+}
+
+// Accepted returns the values currently accepted from clients.
+func (s *Selector) Accepted() []string {
+	if len(s.values) == 1 || s.interval == 0 {
+		return s.values
+	}
+	epoch := s.epoch()
+	accepted := make([]string, 0, 3)
+	for _, index := range []int{s.epochIndex(epoch), s.epochIndex(epoch - 1), s.epochIndex(epoch + 1)} {
+		value := s.values[index]
+		if !common.Contains(accepted, value) {
+			accepted = append(accepted, value)
+		}
+	}
+	return accepted
+}
+
+// Contains returns whether the value is currently accepted.
+func (s *Selector) Contains(value string) bool {
+	return common.Contains(s.Accepted(), value)
+}
+
+// Values merges a single value and a list of values of options.
+func Values(value string, values []string, name string) ([]string, error) {
+	if value == "" {
+		return values, nil
+	}
+	if len(values) > 0 {
+		return nil, E.New("`", name, "` is conflict with `", name, "s`")
+	}
+	return []string{value}, nil
+}
@@ -0,0 +1,61 @@
+package rotation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json/badoption"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectorEpoch(t *testing.T) {
+	t.Parallel()
+	values := []string{"/a", "/b", "/c", "/d", "/e"}
+	options := &option.V2RayTransportRotationOptions{Interval: badoption.Duration(time.Hour), Key: "key"}
+	client, err := New(values, "path", options)
+	require.NoError(t, err)
+	server, err := New(values, "path", options)
+	require.NoError(t, err)
+	now := time.Date(2024, time.January, 1, 0, 30, 0, 0, time.UTC)
+	server.timeFunc = func() time.Time { return now }
+	selected := make(map[string]bool)
+	for hours := 0; hours < 24; hours++ {
+		clientTime := now.Add(time.Duration(hours) * time.Hour)
+		client.timeFunc = func() time.Time { return clientTime }
+		value := client.Select()
+		require.Equal(t, value, client.Select())
+		selected[value] = true
+		if hours <= 1 {
+			require.True(t, server.Contains(value))
+		}
+	}
+	require.Greater(t, len(selected), 1)
+	require.LessOrEqual(t, len(server.Accepted()), 3)
+}
+
+func TestSelectorRandom(t *testing.T) {
+	t.Parallel()
+	values := []string{"a.example.org", "b.example.org"}
+	selector, err := New(values, "host", nil)
+	require.NoError(t, err)
+	require.Equal(t, values, selector.Accepted())
+	for i := 0; i < 16; i++ {
+		require.Contains(t, values, selector.Select())
+	}
+}
+
+func TestSelectorOptions(t *testing.T) {
+	t.Parallel()
+	selector, err := New(nil, "path", nil)
+	require.NoError(t, err)
+	require.Nil(t, selector)
+	_, err = New([]string{"/a", "/a"}, "path", nil)
+	require.Error(t, err)
+	_, err = Values("/a", []string{"/b"}, "path")
+	require.Error(t, err)
+	values, err := Values("/a", nil, "path")
+	require.NoError(t, err)
+	require.Equal(t, []string{"/a"}, values)
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"io"
+	"os"
 
 	"github.com/sagernet/sing-box/adapter"
 	C "github.com/sagernet/sing-box/constant"
@@ -14,6 +15,10 @@ import (
 )
 
 func HTTPHost(_ context.Context, metadata *adapter.InboundContext, reader io.Reader) error {
+	// request methods are upper case tokens
+	if method, loaded := peekByte(reader); loaded && (method < 'A' || method > 'Z') {
+		return os.ErrInvalid
+	}
 	request, err := http.ReadRequest(std_bufio.NewReader(reader))
 	if err != nil {
 		if errors.Is(err, io.ErrUnexpectedEOF) {
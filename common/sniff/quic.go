@@ -21,6 +21,11 @@ import (
 )
 
 func QUICClientHello(ctx context.Context, metadata *adapter.InboundContext, packet []byte) error {
+	// client hellos are sent in long header packets, reject others before
+	// allocating the parser, as the stream sniffers do
+	if len(packet) == 0 || packet[0]&0xc0 != 0xc0 {
+		return os.ErrInvalid
+	}
 	reader := bytes.NewReader(packet)
 	typeByte, err := reader.ReadByte()
 	if err != nil {
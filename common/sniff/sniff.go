@@ -10,7 +10,6 @@ import (
 
 	"github.com/sagernet/sing-box/adapter"
 	C "github.com/sagernet/sing-box/constant"
-	"github.com/sagernet/sing/common"
 	"github.com/sagernet/sing/common/buf"
 	E "github.com/sagernet/sing/common/exceptions"
 )
@@ -20,6 +19,11 @@ type (
 	PacketSniffer = func(ctx context.Context, metadata *adapter.InboundContext, packet []byte) error
 )
 
+const recordTypeHandshake = 0x16
+
+// MaxBufferSize is the maximum size of the buffer of PeekStream.
+const MaxBufferSize = 64 * 1024
+
 var ErrNeedMoreData = E.New("need more data")
 
 func Skip(metadata *adapter.InboundContext) bool {
@@ -38,12 +42,23 @@ func Skip(metadata *adapter.InboundContext) bool {
 	return false
 }
 
+// PeekStream reads from conn until a sniffer succeeds, all sniffers reject the
+// payload, buffer is full or timeout is reached. All sniffers share a single
+// view of the buffered payload in each pass.
 func PeekStream(ctx context.Context, metadata *adapter.InboundContext, conn net.Conn, buffers []*buf.Buffer, buffer *buf.Buffer, timeout time.Duration, sniffers ...StreamSniffer) error {
 	if timeout == 0 {
 		timeout = C.ReadPayloadTimeout
 	}
 	deadline := time.Now().Add(timeout)
-	var sniffError error
+	var (
+		sniffError error
+		reader     bytes.Reader
+		joined     []byte
+	)
+	if len(buffers) > 0 {
+		joined = buf.Get(buf.UDPBufferSize)[:0]
+		defer buf.Put(joined)
+	}
 	for i := 0; ; i++ {
 		err := conn.SetReadDeadline(deadline)
 		if err != nil {
@@ -57,12 +72,19 @@ func PeekStream(ctx context.Context, metadata *adapter.InboundContext, conn net.
 			}
 			return E.Cause(err, "read payload")
 		}
+		payload := buffer.Bytes()
+		if len(buffers) > 0 {
+			joined = joined[:0]
+			for _, cached := range buffers {
+				joined = append(joined, cached.Bytes()...)
+			}
+			joined = append(joined, payload...)
+			payload = joined
+		}
 		sniffError = nil
 		for _, sniffer := range sniffers {
-			reader := io.MultiReader(common.Map(append(buffers, buffer), func(it *buf.Buffer) io.Reader {
-				return bytes.NewReader(it.Bytes())
-			})...)
-			err = sniffer(ctx, metadata, reader)
+			reader.Reset(payload)
+			err = sniffer(ctx, metadata, &reader)
 			if err == nil {
 				return nil
 			}
@@ -75,6 +97,18 @@ func PeekStream(ctx context.Context, metadata *adapter.InboundContext, conn net.
 	return sniffError
 }
 
+// peekByte returns the first byte of a sniffer input without consuming it,
+// so that sniffers can reject foreign protocols before allocating parsers.
+func peekByte(reader io.Reader) (byte, bool) {
+	readerAt, isReaderAt := reader.(io.ReaderAt)
+	if !isReaderAt {
+		return 0, false
+	}
+	var first [1]byte
+	n, _ := readerAt.ReadAt(first[:], 0)
+	return first[0], n == 1
+}
+
 func PeekPacket(ctx context.Context, metadata *adapter.InboundContext, packet []byte, sniffers ...PacketSniffer) error {
 	var sniffError []error
 	for _, sniffer := range sniffers {
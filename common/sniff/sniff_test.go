@@ -0,0 +1,121 @@
+package sniff_test
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/sniff"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing/common/buf"
+
+	"github.com/stretchr/testify/require"
+)
+
+var defaultStreamSniffers = []sniff.StreamSniffer{
+	sniff.TLSClientHello,
+	sniff.HTTPHost,
+	sniff.StreamDomainNameQuery,
+	sniff.BitTorrent,
+	sniff.SSH,
+	sniff.RDP,
+}
+
+type payloadConn struct {
+	net.Conn
+	payload []byte
+}
+
+func (c *payloadConn) Read(p []byte) (int, error) {
+	if len(c.payload) == 0 {
+		return 0, os.ErrDeadlineExceeded
+	}
+	n := copy(p, c.payload)
+	c.payload = c.payload[n:]
+	return n, nil
+}
+
+func (c *payloadConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+func clientHello(t testing.TB) []byte {
+	client, server := net.Pipe()
+	defer server.Close()
+	go func() {
+		tls.Client(client, &tls.Config{ServerName: "example.com"}).Handshake()
+		client.Close()
+	}()
+	payload := make([]byte, 4096)
+	n, err := server.Read(payload)
+	require.NoError(t, err)
+	return payload[:n]
+}
+
+func TestPeekStream(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name     string
+		payload  []byte
+		protocol string
+		host     string
+	}{
+		{"tls", clientHello(t), C.ProtocolTLS, "example.com"},
+		{"http", []byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"), C.ProtocolHTTP, "example.com"},
+		{"ssh", []byte("SSH-2.0-OpenSSH_9.6\r\n"), C.ProtocolSSH, ""},
+	} {
+		var metadata adapter.InboundContext
+		buffer := buf.NewPacket()
+		err := sniff.PeekStream(context.Background(), &metadata, &payloadConn{payload: testCase.payload}, nil, buffer, 0, defaultStreamSniffers...)
+		buffer.Release()
+		require.NoError(t, err, testCase.name)
+		require.Equal(t, testCase.protocol, metadata.Protocol, testCase.name)
+		require.Equal(t, testCase.host, metadata.SniffHost, testCase.name)
+	}
+}
+
+func TestPeekStreamUnknown(t *testing.T) {
+	t.Parallel()
+	var metadata adapter.InboundContext
+	buffer := buf.NewPacket()
+	defer buffer.Release()
+	err := sniff.PeekStream(context.Background(), &metadata, &payloadConn{payload: []byte{0xff, 0x00, 0x01, 0x02}}, nil, buffer, 0, defaultStreamSniffers...)
+	require.Error(t, err)
+	require.Empty(t, metadata.Protocol)
+}
+
+func BenchmarkPeekStream(b *testing.B) {
+	for _, testCase := range []struct {
+		name    string
+		payload []byte
+	}{
+		{"tls", clientHello(b)},
+		{"http", []byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n")},
+		{"unknown", make([]byte, 1024)},
+	} {
+		b.Run(testCase.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var metadata adapter.InboundContext
+				buffer := buf.NewPacket()
+				sniff.PeekStream(context.Background(), &metadata, &payloadConn{payload: testCase.payload}, nil, buffer, 0, defaultStreamSniffers...)
+				buffer.Release()
+			}
+		})
+	}
+}
+
+func TestQUICClientHelloRejectShortHeader(t *testing.T) {
+	// short header packets of established connections are rejected without allocations
+	packet := make([]byte, 1200)
+	packet[0] = 0x40
+	var metadata adapter.InboundContext
+	allocs := testing.AllocsPerRun(100, func() {
+		require.ErrorIs(t, sniff.QUICClientHello(context.Background(), &metadata, packet), os.ErrInvalid)
+	})
+	require.Zero(t, allocs)
+}
@@ -13,6 +13,9 @@ import (
 
 func SSH(_ context.Context, metadata *adapter.InboundContext, reader io.Reader) error {
 	const sshPrefix = "SSH-2.0-"
+	if prefix, loaded := peekByte(reader); loaded && prefix != sshPrefix[0] {
+		return os.ErrInvalid
+	}
 	bReader := bufio.NewReader(reader)
 	prefix, err := bReader.Peek(len(sshPrefix))
 	if string(prefix[:]) != sshPrefix[:len(prefix)] {
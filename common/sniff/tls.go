@@ -5,6 +5,7 @@ import (
 	"crypto/tls"
 	"errors"
 	"io"
+	"os"
 
 	"github.com/sagernet/sing-box/adapter"
 	C "github.com/sagernet/sing-box/constant"
@@ -12,22 +13,39 @@ import (
 	E "github.com/sagernet/sing/common/exceptions"
 )
 
+// errClientHelloCaptured aborts the handshake as soon as the client hello is
+// parsed, instead of letting crypto/tls continue with key exchange.
+var errClientHelloCaptured = E.New("client hello captured")
+
 func TLSClientHello(ctx context.Context, metadata *adapter.InboundContext, reader io.Reader) error {
+	clientHello, err := ReadTLSClientHello(ctx, reader)
+	if err != nil {
+		return err
+	}
+	metadata.Protocol = C.ProtocolTLS
+	metadata.SniffHost = clientHello.ServerName
+	return nil
+}
+
+// ReadTLSClientHello parses the client hello of a TLS connection, returning
+// ErrNeedMoreData if the reader ends before the client hello is complete.
+func ReadTLSClientHello(ctx context.Context, reader io.Reader) (*tls.ClientHelloInfo, error) {
+	if header, loaded := peekByte(reader); loaded && header != recordTypeHandshake {
+		return nil, os.ErrInvalid
+	}
 	var clientHello *tls.ClientHelloInfo
 	err := tls.Server(bufio.NewReadOnlyConn(reader), &tls.Config{
 		GetConfigForClient: func(argHello *tls.ClientHelloInfo) (*tls.Config, error) {
 			clientHello = argHello
-			return nil, nil
+			return nil, errClientHelloCaptured
 		},
 	}).HandshakeContext(ctx)
 	if clientHello != nil {
-		metadata.Protocol = C.ProtocolTLS
-		metadata.SniffHost = clientHello.ServerName
-		return nil
+		return clientHello, nil
 	}
 	if errors.Is(err, io.ErrUnexpectedEOF) {
-		return E.Cause1(ErrNeedMoreData, err)
+		return nil, E.Cause1(ErrNeedMoreData, err)
 	} else {
-		return err
+		return nil, err
 	}
 }
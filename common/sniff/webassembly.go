@@ -0,0 +1,45 @@
+package sniff
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/sagernet/sing-box/adapter"
+)
+
+// WASMStream returns a stream sniffer calling the sniffer of module with
+// all buffered payload.
+func WASMStream(module adapter.WASMModule) StreamSniffer {
+	return func(ctx context.Context, metadata *adapter.InboundContext, reader io.Reader) error {
+		payload, err := io.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+		return wasmSniff(ctx, module, metadata, payload, false)
+	}
+}
+
+// WASMPacket returns a packet sniffer calling the sniffer of module.
+func WASMPacket(module adapter.WASMModule) PacketSniffer {
+	return func(ctx context.Context, metadata *adapter.InboundContext, packet []byte) error {
+		return wasmSniff(ctx, module, metadata, packet, true)
+	}
+}
+
+func wasmSniff(ctx context.Context, module adapter.WASMModule, metadata *adapter.InboundContext, payload []byte, isPacket bool) error {
+	result, err := module.Sniff(ctx, payload, isPacket)
+	if err != nil {
+		return err
+	}
+	if result == nil {
+		return os.ErrInvalid
+	}
+	if result.NeedMoreData {
+		return ErrNeedMoreData
+	}
+	metadata.Protocol = result.Protocol
+	metadata.Domain = result.Domain
+	metadata.Client = result.Client
+	return nil
+}
@@ -0,0 +1,266 @@
+package socksudp
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/buf"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/pipe"
+)
+
+const relayQueueSize = 64
+
+// relay serves UDP associations of all clients on the port of the TCP
+// listener. Associations are bound to the first packet from the address of
+// their control connection whose source matches the DST.ADDR and DST.PORT
+// in the request, preferring those that declared the port.
+type relay struct {
+	ctx     context.Context
+	logger  logger.ContextLogger
+	conn    net.PacketConn
+	port    uint16
+	access  sync.Mutex
+	bound   map[netip.AddrPort]*relayConn
+	pending map[netip.Addr][]*relayConn
+}
+
+func newRelay(ctx context.Context, logger logger.ContextLogger, conn net.PacketConn) *relay {
+	return &relay{
+		ctx:     ctx,
+		logger:  logger,
+		conn:    conn,
+		port:    M.SocksaddrFromNet(conn.LocalAddr()).Port,
+		bound:   make(map[netip.AddrPort]*relayConn),
+		pending: make(map[netip.Addr][]*relayConn),
+	}
+}
+
+func (r *relay) newConn(control net.Conn, expected M.Socksaddr) *relayConn {
+	conn := &relayConn{
+		relay:        r,
+		control:      control,
+		clientAddr:   M.AddrFromNet(control.RemoteAddr()).Unmap(),
+		expected:     expected.Unwrap(),
+		packets:      make(chan *buf.Buffer, relayQueueSize),
+		readDeadline: pipe.MakeDeadline(),
+		done:         make(chan struct{}),
+	}
+	r.access.Lock()
+	r.pending[conn.clientAddr] = append(r.pending[conn.clientAddr], conn)
+	r.access.Unlock()
+	go conn.waitControl()
+	return conn
+}
+
+func (r *relay) loopRead() {
+	for {
+		buffer := buf.NewPacket()
+		n, addr, err := r.conn.ReadFrom(buffer.FreeBytes())
+		if err != nil {
+			buffer.Release()
+			if !E.IsClosed(err) {
+				r.logger.ErrorContext(r.ctx, E.Cause(err, "read udp relay"))
+			}
+			return
+		}
+		buffer.Truncate(n)
+		source := M.AddrPortFromNet(addr)
+		source = netip.AddrPortFrom(source.Addr().Unmap(), source.Port())
+		conn := r.lookup(source)
+		if conn == nil {
+			buffer.Release()
+			continue
+		}
+		select {
+		case conn.packets <- buffer:
+		default:
+			buffer.Release()
+		}
+	}
+}
+
+func (r *relay) lookup(source netip.AddrPort) *relayConn {
+	r.access.Lock()
+	defer r.access.Unlock()
+	if conn, loaded := r.bound[source]; loaded {
+		return conn
+	}
+	pending := r.pending[source.Addr()]
+	if len(pending) == 0 {
+		return nil
+	}
+	index := -1
+	for i, conn := range pending {
+		if !conn.matches(source) {
+			continue
+		}
+		if conn.expected.Port == source.Port() {
+			index = i
+			break
+		}
+		if index == -1 {
+			index = i
+		}
+	}
+	if index == -1 {
+		return nil
+	}
+	conn := pending[index]
+	r.removePending(conn)
+	conn.client.Store(&source)
+	r.bound[source] = conn
+	return conn
+}
+
+func (r *relay) removePending(conn *relayConn) {
+	pending := r.pending[conn.clientAddr]
+	for i, pendingConn := range pending {
+		if pendingConn == conn {
+			pending = append(pending[:i], pending[i+1:]...)
+			break
+		}
+	}
+	if len(pending) == 0 {
+		delete(r.pending, conn.clientAddr)
+	} else {
+		r.pending[conn.clientAddr] = pending
+	}
+}
+
+func (r *relay) remove(conn *relayConn) {
+	r.access.Lock()
+	defer r.access.Unlock()
+	if client := conn.client.Load(); client != nil {
+		if r.bound[*client] == conn {
+			delete(r.bound, *client)
+		}
+	} else {
+		r.removePending(conn)
+	}
+}
+
+func (r *relay) Close() error {
+	return r.conn.Close()
+}
+
+var _ N.PacketConn = (*relayConn)(nil)
+
+type relayConn struct {
+	relay        *relay
+	control      net.Conn
+	clientAddr   netip.Addr
+	expected     M.Socksaddr
+	client       atomic.Pointer[netip.AddrPort]
+	packets      chan *buf.Buffer
+	readDeadline pipe.Deadline
+	closeOnce    sync.Once
+	done         chan struct{}
+}
+
+// matches reports whether a packet from source may bind the association.
+// Zero DST.ADDR and DST.PORT fields in the request match any address and
+// port, a domain DST.ADDR matches nothing.
+func (c *relayConn) matches(source netip.AddrPort) bool {
+	if !c.expected.IsIP() {
+		return false
+	}
+	if !c.expected.Addr.IsUnspecified() && c.expected.Addr.Unmap() != source.Addr() {
+		return false
+	}
+	return c.expected.Port == 0 || c.expected.Port == source.Port()
+}
+
+// waitControl closes the association when the control connection is closed,
+// as required by RFC 1928.
+func (c *relayConn) waitControl() {
+	_, _ = io.Copy(io.Discard, c.control)
+	c.Close()
+}
+
+func (c *relayConn) ReadPacket(buffer *buf.Buffer) (destination M.Socksaddr, err error) {
+	for {
+		var packet *buf.Buffer
+		select {
+		case packet = <-c.packets:
+		case <-c.done:
+			return M.Socksaddr{}, net.ErrClosed
+		case <-c.readDeadline.Wait():
+			return M.Socksaddr{}, os.ErrDeadlineExceeded
+		}
+		// fragmentation is not supported, drop fragments as permitted by RFC 1928
+		if packet.Len() < headerLength || packet.Byte(2) != 0 {
+			packet.Release()
+			continue
+		}
+		packet.Advance(headerLength)
+		destination, err = M.SocksaddrSerializer.ReadAddrPort(packet)
+		if err != nil {
+			packet.Release()
+			continue
+		}
+		_, err = buffer.Write(packet.Bytes())
+		packet.Release()
+		return
+	}
+}
+
+func (c *relayConn) WritePacket(buffer *buf.Buffer, destination M.Socksaddr) error {
+	defer buffer.Release()
+	client := c.client.Load()
+	if client == nil {
+		return E.New("socks5: udp association not bound")
+	}
+	header := buf.With(buffer.ExtendHeader(headerLength + M.SocksaddrSerializer.AddrPortLen(destination)))
+	common.Must(header.WriteZeroN(headerLength))
+	err := M.SocksaddrSerializer.WriteAddrPort(header, destination)
+	if err != nil {
+		return err
+	}
+	return common.Error(c.relay.conn.WriteTo(buffer.Bytes(), net.UDPAddrFromAddrPort(*client)))
+}
+
+func (c *relayConn) FrontHeadroom() int {
+	return headerLength + M.MaxSocksaddrLength
+}
+
+func (c *relayConn) LocalAddr() net.Addr {
+	return c.relay.conn.LocalAddr()
+}
+
+func (c *relayConn) RemoteAddr() net.Addr {
+	return c.control.RemoteAddr()
+}
+
+func (c *relayConn) SetDeadline(t time.Time) error {
+	c.readDeadline.Set(t)
+	return nil
+}
+
+func (c *relayConn) SetReadDeadline(t time.Time) error {
+	c.readDeadline.Set(t)
+	return nil
+}
+
+func (c *relayConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *relayConn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.relay.remove(c)
+		c.control.Close()
+	})
+	return nil
+}
@@ -0,0 +1,75 @@
+package socksudp
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/stretchr/testify/require"
+)
+
+type controlConn struct {
+	net.Conn
+	remoteAddr net.Addr
+}
+
+func (c *controlConn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+func newTestRelayConn(t *testing.T, relay *relay, client string, expected string) *relayConn {
+	t.Helper()
+	conn, peer := net.Pipe()
+	t.Cleanup(func() { peer.Close() })
+	control := &controlConn{Conn: conn, remoteAddr: M.ParseSocksaddr(client).TCPAddr()}
+	relayConn := relay.newConn(control, M.ParseSocksaddr(expected))
+	t.Cleanup(func() { relayConn.Close() })
+	return relayConn
+}
+
+func TestRelayLookup(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name     string
+		expected string
+		source   string
+		bound    bool
+	}{
+		{"exact", "192.168.1.2:5000", "192.168.1.2:5000", true},
+		{"other port", "192.168.1.2:5000", "192.168.1.2:5001", false},
+		{"unspecified address", "0.0.0.0:5000", "192.168.1.2:5000", true},
+		{"unspecified address other port", "0.0.0.0:5000", "192.168.1.2:5001", false},
+		{"unspecified", "0.0.0.0:0", "192.168.1.2:5001", true},
+		{"zero port", "192.168.1.2:0", "192.168.1.2:5001", true},
+		{"other address", "192.168.1.3:5000", "192.168.1.2:5000", false},
+		{"domain", "example.com:5000", "192.168.1.2:5000", false},
+		{"other client", "0.0.0.0:0", "192.168.1.3:5000", false},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			relay := newRelay(context.Background(), logger.NOP(), &net.UDPConn{})
+			conn := newTestRelayConn(t, relay, "192.168.1.2:40000", testCase.expected)
+			bound := relay.lookup(netip.MustParseAddrPort(testCase.source))
+			if testCase.bound {
+				require.Equal(t, conn, bound)
+			} else {
+				require.Nil(t, bound)
+			}
+		})
+	}
+}
+
+func TestRelayLookupPrefersDeclaredPort(t *testing.T) {
+	t.Parallel()
+	relay := newRelay(context.Background(), logger.NOP(), &net.UDPConn{})
+	anyConn := newTestRelayConn(t, relay, "192.168.1.2:40000", "0.0.0.0:0")
+	portConn := newTestRelayConn(t, relay, "192.168.1.2:40001", "0.0.0.0:5000")
+	require.Equal(t, portConn, relay.lookup(netip.MustParseAddrPort("192.168.1.2:5000")))
+	require.Equal(t, anyConn, relay.lookup(netip.MustParseAddrPort("192.168.1.2:5001")))
+	require.Nil(t, relay.lookup(netip.MustParseAddrPort("192.168.1.2:5002")))
+	require.Equal(t, portConn, relay.lookup(netip.MustParseAddrPort("192.168.1.2:5000")))
+}
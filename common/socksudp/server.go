@@ -0,0 +1,157 @@
+package socksudp
+
+import (
+	std_bufio "bufio"
+	"context"
+	"net"
+	"net/netip"
+
+	"github.com/sagernet/sing-box/common/listener"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/buf"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/protocol/socks"
+	"github.com/sagernet/sing/protocol/socks/socks5"
+)
+
+// CommandUDPInTCP is the UDP tunnel extension of gost, where packets are
+// carried in the TCP connection.
+const CommandUDPInTCP byte = 0xF3
+
+// Server handles the UDP extensions of SOCKS5 inbounds.
+type Server struct {
+	ctx           context.Context
+	logger        logger.ContextLogger
+	listener      *listener.Listener
+	sharedPort    bool
+	advertiseAddr netip.Addr
+	advertisePort uint16
+	udpInTCP      bool
+	relay         *relay
+}
+
+// NewServer returns nil if no extension is enabled.
+func NewServer(ctx context.Context, logger logger.ContextLogger, listener *listener.Listener, relayOptions *option.SOCKSUDPRelayOptions, udpInTCP bool) (*Server, error) {
+	if relayOptions == nil && !udpInTCP {
+		return nil, nil
+	}
+	server := &Server{
+		ctx:      ctx,
+		logger:   logger,
+		listener: listener,
+		udpInTCP: udpInTCP,
+	}
+	if relayOptions != nil {
+		if relayOptions.AdvertisePort != 0 && !relayOptions.SharedPort {
+			return nil, E.New("`udp_relay.advertise_port` requires `shared_port`")
+		}
+		server.sharedPort = relayOptions.SharedPort
+		if relayOptions.AdvertiseAddress != nil {
+			server.advertiseAddr = relayOptions.AdvertiseAddress.Build(netip.Addr{})
+		}
+		server.advertisePort = relayOptions.AdvertisePort
+	}
+	return server, nil
+}
+
+func (s *Server) Start() error {
+	if !s.sharedPort {
+		return nil
+	}
+	listenAddr := M.SocksaddrFromNet(s.listener.TCPListener().Addr())
+	udpConn, err := s.listener.ListenPacket(net.ListenConfig{}, s.ctx, M.NetworkFromNetAddr(N.NetworkUDP, listenAddr.Addr), listenAddr.String())
+	if err != nil {
+		return E.Cause(err, "listen UDP relay")
+	}
+	s.relay = newRelay(s.ctx, s.logger, udpConn)
+	s.logger.Info("udp relay started at ", udpConn.LocalAddr())
+	go s.relay.loopRead()
+	return nil
+}
+
+func (s *Server) Close() error {
+	if s.relay == nil {
+		return nil
+	}
+	return s.relay.Close()
+}
+
+// HandleRequest handles the request if an extension is enabled for the command.
+func (s *Server) HandleRequest(ctx context.Context, conn net.Conn, reader *std_bufio.Reader, request socks5.Request, handler socks.HandlerEx, source M.Socksaddr, onClose N.CloseHandlerFunc) (bool, error) {
+	switch request.Command {
+	case socks5.CommandUDPAssociate:
+		if !s.sharedPort && !s.advertiseAddr.IsValid() {
+			return false, nil
+		}
+		return true, s.handleAssociate(ctx, conn, request, handler, source, onClose)
+	case CommandUDPInTCP:
+		if !s.udpInTCP {
+			return false, nil
+		}
+		return true, s.handleUDPInTCP(ctx, conn, reader, handler, source, onClose)
+	default:
+		return false, nil
+	}
+}
+
+func (s *Server) handleAssociate(ctx context.Context, conn net.Conn, request socks5.Request, handler socks.HandlerEx, source M.Socksaddr, onClose N.CloseHandlerFunc) error {
+	var (
+		packetConn N.PacketConn
+		bind       M.Socksaddr
+	)
+	if s.relay != nil {
+		packetConn = s.relay.newConn(conn, request.Destination)
+		bind = M.SocksaddrFrom(M.AddrFromNet(conn.LocalAddr()).Unmap(), s.relay.port)
+	} else {
+		network := M.NetworkFromNetAddr(N.NetworkUDP, M.AddrFromNet(conn.LocalAddr()))
+		address := M.SocksaddrFrom(M.AddrFromNet(conn.LocalAddr()), 0).String()
+		udpConn, err := s.listener.ListenPacket(net.ListenConfig{}, ctx, network, address)
+		if err != nil {
+			return E.Cause(err, "socks5: listen udp")
+		}
+		packetConn = socks.NewAssociatePacketConn(bufio.NewServerPacketConn(udpConn), M.Socksaddr{}, conn)
+		bind = M.SocksaddrFromNet(udpConn.LocalAddr())
+	}
+	if s.advertiseAddr.IsValid() {
+		bind.Addr = s.advertiseAddr
+	}
+	if s.advertisePort != 0 {
+		bind.Port = s.advertisePort
+	}
+	err := socks5.WriteResponse(conn, socks5.Response{
+		ReplyCode: socks5.ReplyCodeSuccess,
+		Bind:      bind,
+	})
+	if err != nil {
+		packetConn.Close()
+		return E.Cause(err, "socks5: write response")
+	}
+	return newPacketConnection(ctx, packetConn, handler, source, onClose)
+}
+
+func (s *Server) handleUDPInTCP(ctx context.Context, conn net.Conn, reader *std_bufio.Reader, handler socks.HandlerEx, source M.Socksaddr, onClose N.CloseHandlerFunc) error {
+	err := socks5.WriteResponse(conn, socks5.Response{
+		ReplyCode: socks5.ReplyCodeSuccess,
+		Bind:      M.SocksaddrFromNet(conn.LocalAddr()),
+	})
+	if err != nil {
+		return E.Cause(err, "socks5: write response")
+	}
+	return newPacketConnection(ctx, newTCPPacketConn(conn, reader), handler, source, onClose)
+}
+
+func newPacketConnection(ctx context.Context, packetConn N.PacketConn, handler socks.HandlerEx, source M.Socksaddr, onClose N.CloseHandlerFunc) error {
+	firstPacket := buf.NewPacket()
+	destination, err := packetConn.ReadPacket(firstPacket)
+	if err != nil {
+		firstPacket.Release()
+		packetConn.Close()
+		return E.Cause(err, "socks5: read first packet")
+	}
+	handler.NewPacketConnectionEx(ctx, bufio.NewCachedPacketConn(packetConn, firstPacket, destination), source, destination, onClose)
+	return nil
+}
@@ -0,0 +1,77 @@
+package socksudp
+
+import (
+	std_bufio "bufio"
+	"encoding/binary"
+	"io"
+	"net"
+
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/buf"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+// Packets in the TCP connection have the SOCKS5 UDP header, with the length
+// of data in the RSV field:
+//
+//	+-----+------+------+----------+----------+----------+
+//	| LEN | FRAG | ATYP | DST.ADDR | DST.PORT |   DATA   |
+//	+-----+------+------+----------+----------+----------+
+//	|  2  |  1   |  1   | Variable |    2     | Variable |
+//	+-----+------+------+----------+----------+----------+
+const headerLength = 3
+
+var _ N.PacketConn = (*tcpPacketConn)(nil)
+
+type tcpPacketConn struct {
+	net.Conn
+	reader *std_bufio.Reader
+}
+
+func newTCPPacketConn(conn net.Conn, reader *std_bufio.Reader) *tcpPacketConn {
+	return &tcpPacketConn{Conn: conn, reader: reader}
+}
+
+func (c *tcpPacketConn) ReadPacket(buffer *buf.Buffer) (destination M.Socksaddr, err error) {
+	var header [headerLength]byte
+	_, err = io.ReadFull(c.reader, header[:])
+	if err != nil {
+		return
+	}
+	if header[2] != 0 {
+		return M.Socksaddr{}, E.New("socks5: fragmented packet")
+	}
+	destination, err = M.SocksaddrSerializer.ReadAddrPort(c.reader)
+	if err != nil {
+		return
+	}
+	length := int(binary.BigEndian.Uint16(header[:2]))
+	if length > buffer.FreeLen() {
+		return M.Socksaddr{}, E.New("socks5: packet too large: ", length)
+	}
+	_, err = buffer.ReadFullFrom(c.reader, length)
+	return
+}
+
+func (c *tcpPacketConn) WritePacket(buffer *buf.Buffer, destination M.Socksaddr) error {
+	defer buffer.Release()
+	dataLength := buffer.Len()
+	header := buf.With(buffer.ExtendHeader(headerLength + M.SocksaddrSerializer.AddrPortLen(destination)))
+	binary.BigEndian.PutUint16(header.Extend(2), uint16(dataLength))
+	common.Must(header.WriteByte(0))
+	err := M.SocksaddrSerializer.WriteAddrPort(header, destination)
+	if err != nil {
+		return err
+	}
+	return common.Error(c.Conn.Write(buffer.Bytes()))
+}
+
+func (c *tcpPacketConn) FrontHeadroom() int {
+	return headerLength + M.MaxSocksaddrLength
+}
+
+func (c *tcpPacketConn) Upstream() any {
+	return c.Conn
+}
@@ -0,0 +1,38 @@
+package socksudp
+
+import (
+	std_bufio "bufio"
+	"net"
+	"testing"
+
+	"github.com/sagernet/sing/common/buf"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTCPPacketConn(t *testing.T) {
+	t.Parallel()
+	clientConn, serverConn := net.Pipe()
+	client := newTCPPacketConn(clientConn, std_bufio.NewReader(clientConn))
+	server := newTCPPacketConn(serverConn, std_bufio.NewReader(serverConn))
+	defer client.Close()
+	defer server.Close()
+	destination := M.ParseSocksaddr("example.com:53")
+	go func() {
+		for _, payload := range []string{"hello", "world"} {
+			buffer := buf.NewPacket()
+			buffer.Resize(client.FrontHeadroom(), 0)
+			buffer.WriteString(payload)
+			client.WritePacket(buffer, destination)
+		}
+	}()
+	for _, payload := range []string{"hello", "world"} {
+		buffer := buf.NewPacket()
+		readDestination, err := server.ReadPacket(buffer)
+		require.NoError(t, err)
+		require.Equal(t, destination, readDestination)
+		require.Equal(t, payload, string(buffer.Bytes()))
+		buffer.Release()
+	}
+}
@@ -0,0 +1,169 @@
+package srs
+
+import (
+	"bytes"
+	"io"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json"
+)
+
+// Builder constructs a rule-set in memory. Items of different kinds are
+// added to separate rules, so the rule-set matches if any item matches,
+// e.g. a domain or a process name.
+type Builder struct {
+	destination option.DefaultHeadlessRule
+	source      option.DefaultHeadlessRule
+	port        option.DefaultHeadlessRule
+	process     option.DefaultHeadlessRule
+	rules       []option.HeadlessRule
+}
+
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+func (b *Builder) AddDomain(domain ...string) *Builder {
+	b.destination.Domain = append(b.destination.Domain, domain...)
+	return b
+}
+
+func (b *Builder) AddDomainSuffix(domainSuffix ...string) *Builder {
+	b.destination.DomainSuffix = append(b.destination.DomainSuffix, domainSuffix...)
+	return b
+}
+
+func (b *Builder) AddDomainKeyword(domainKeyword ...string) *Builder {
+	b.destination.DomainKeyword = append(b.destination.DomainKeyword, domainKeyword...)
+	return b
+}
+
+func (b *Builder) AddDomainRegex(domainRegex ...string) *Builder {
+	b.destination.DomainRegex = append(b.destination.DomainRegex, domainRegex...)
+	return b
+}
+
+func (b *Builder) AddIPCIDR(ipCIDR ...string) *Builder {
+	b.destination.IPCIDR = append(b.destination.IPCIDR, ipCIDR...)
+	return b
+}
+
+func (b *Builder) AddSourceIPCIDR(sourceIPCIDR ...string) *Builder {
+	b.source.SourceIPCIDR = append(b.source.SourceIPCIDR, sourceIPCIDR...)
+	return b
+}
+
+func (b *Builder) AddPort(port ...uint16) *Builder {
+	b.port.Port = append(b.port.Port, port...)
+	return b
+}
+
+func (b *Builder) AddPortRange(portRange ...string) *Builder {
+	b.port.PortRange = append(b.port.PortRange, portRange...)
+	return b
+}
+
+func (b *Builder) AddProcessName(processName ...string) *Builder {
+	b.process.ProcessName = append(b.process.ProcessName, processName...)
+	return b
+}
+
+func (b *Builder) AddProcessPath(processPath ...string) *Builder {
+	b.process.ProcessPath = append(b.process.ProcessPath, processPath...)
+	return b
+}
+
+func (b *Builder) AddPackageName(packageName ...string) *Builder {
+	b.process.PackageName = append(b.process.PackageName, packageName...)
+	return b
+}
+
+// AddRule adds a rule as is, for items not covered by the other methods.
+func (b *Builder) AddRule(rule ...option.HeadlessRule) *Builder {
+	b.rules = append(b.rules, rule...)
+	return b
+}
+
+func (b *Builder) Build() option.PlainRuleSet {
+	var ruleSet option.PlainRuleSet
+	for _, rule := range []option.DefaultHeadlessRule{b.destination, b.source, b.port, b.process} {
+		if rule.IsValid() {
+			ruleSet.Rules = append(ruleSet.Rules, option.HeadlessRule{
+				Type:           C.RuleTypeDefault,
+				DefaultOptions: rule,
+			})
+		}
+	}
+	ruleSet.Rules = append(ruleSet.Rules, b.rules...)
+	return ruleSet
+}
+
+// Write compiles the rule-set to binary format, with the lowest version that
+// supports its items.
+func (b *Builder) Write(writer io.Writer) error {
+	ruleSet := b.Build()
+	return Write(writer, ruleSet, DowngradeVersion(C.RuleSetVersionCurrent, ruleSet))
+}
+
+// WriteSource writes the rule-set in source format.
+func (b *Builder) WriteSource(writer io.Writer) error {
+	ruleSet := b.Build()
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(option.PlainRuleSetCompat{
+		Version: DowngradeVersion(C.RuleSetVersionCurrent, ruleSet),
+		Options: ruleSet,
+	})
+}
+
+// DowngradeVersion lowers version down to RuleSetVersion2 if the rule-set
+// does not use items introduced later, so older clients can read it.
+func DowngradeVersion(version uint8, ruleSet option.PlainRuleSet) uint8 {
+	if version == C.RuleSetVersion4 && !hasDefaultRule(ruleSet.Rules, func(rule option.DefaultHeadlessRule) bool {
+		return rule.NetworkInterfaceAddress != nil && rule.NetworkInterfaceAddress.Size() > 0 ||
+			len(rule.DefaultInterfaceAddress) > 0
+	}) {
+		version = C.RuleSetVersion3
+	}
+	if version == C.RuleSetVersion3 && !hasDefaultRule(ruleSet.Rules, func(rule option.DefaultHeadlessRule) bool {
+		return len(rule.NetworkType) > 0 || rule.NetworkIsExpensive || rule.NetworkIsConstrained
+	}) {
+		version = C.RuleSetVersion2
+	}
+	return version
+}
+
+func hasDefaultRule(rules []option.HeadlessRule, cond func(rule option.DefaultHeadlessRule) bool) bool {
+	for _, rule := range rules {
+		switch rule.Type {
+		case C.RuleTypeDefault:
+			if cond(rule.DefaultOptions) {
+				return true
+			}
+		case C.RuleTypeLogical:
+			if hasDefaultRule(rule.LogicalOptions.Rules, cond) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Decode reads a rule-set in binary or source format, detected by the magic
+// bytes, and upgrades it to the current version.
+func Decode(content []byte) (option.PlainRuleSet, error) {
+	var (
+		ruleSet option.PlainRuleSetCompat
+		err     error
+	)
+	if bytes.HasPrefix(content, MagicBytes[:]) {
+		ruleSet, err = Read(bytes.NewReader(content), false)
+	} else {
+		ruleSet, err = json.UnmarshalExtended[option.PlainRuleSetCompat](content)
+	}
+	if err != nil {
+		return option.PlainRuleSet{}, err
+	}
+	return ruleSet.Upgrade()
+}
@@ -0,0 +1,101 @@
+package systemd
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+
+	M "github.com/sagernet/sing/common/metadata"
+)
+
+// listenFDsStart is SD_LISTEN_FDS_START.
+const listenFDsStart = 3
+
+// listenFiles parses sockets passed by socket activation. The files are kept open,
+// listeners are created from duplicates, so that sockets are reused after reload.
+var listenFiles = sync.OnceValue(func() []*os.File {
+	if os.Getenv("LISTEN_PID") != strconv.Itoa(os.Getpid()) {
+		return nil
+	}
+	listenFDs, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || listenFDs <= 0 {
+		return nil
+	}
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+	files := make([]*os.File, 0, listenFDs)
+	for fd := listenFDsStart; fd < listenFDsStart+listenFDs; fd++ {
+		syscall.CloseOnExec(fd)
+		files = append(files, os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd)))
+	}
+	return files
+})
+
+// Listener returns a passed stream socket listening on the address, or nil if not found.
+func Listener(address M.Socksaddr) (net.Listener, error) {
+	for _, file := range listenFiles() {
+		if socketType(file) != syscall.SOCK_STREAM {
+			continue
+		}
+		listener, err := net.FileListener(file)
+		if err != nil {
+			return nil, err
+		}
+		if matchAddress(M.SocksaddrFromNet(listener.Addr()), address) {
+			return listener, nil
+		}
+		listener.Close()
+	}
+	return nil, nil
+}
+
+// PacketConn returns a passed datagram socket bound to the address, or nil if not found.
+func PacketConn(address M.Socksaddr) (net.PacketConn, error) {
+	for _, file := range listenFiles() {
+		if socketType(file) != syscall.SOCK_DGRAM {
+			continue
+		}
+		packetConn, err := net.FilePacketConn(file)
+		if err != nil {
+			return nil, err
+		}
+		if matchAddress(M.SocksaddrFromNet(packetConn.LocalAddr()), address) {
+			return packetConn, nil
+		}
+		packetConn.Close()
+	}
+	return nil, nil
+}
+
+// socketType avoids File.Fd, which may set the shared socket to blocking mode.
+func socketType(file *os.File) int {
+	rawConn, err := file.SyscallConn()
+	if err != nil {
+		return -1
+	}
+	socketType := -1
+	_ = rawConn.Control(func(fd uintptr) {
+		value, err := syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_TYPE)
+		if err == nil {
+			socketType = value
+		}
+	})
+	return socketType
+}
+
+// matchAddress treats unspecified addresses of both families as equal, since
+// ListenStream= with only a port binds to [::] by default.
+func matchAddress(socketAddress M.Socksaddr, address M.Socksaddr) bool {
+	if socketAddress.Port != address.Port {
+		return false
+	}
+	socketAddr := socketAddress.Addr.Unmap()
+	addr := address.Addr.Unmap()
+	if socketAddr.IsUnspecified() && addr.IsUnspecified() {
+		return true
+	}
+	return socketAddr == addr
+}
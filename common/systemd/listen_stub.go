@@ -0,0 +1,17 @@
+//go:build !linux
+
+package systemd
+
+import (
+	"net"
+
+	M "github.com/sagernet/sing/common/metadata"
+)
+
+func Listener(address M.Socksaddr) (net.Listener, error) {
+	return nil, nil
+}
+
+func PacketConn(address M.Socksaddr) (net.PacketConn, error) {
+	return nil, nil
+}
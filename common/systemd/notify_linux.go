@@ -0,0 +1,57 @@
+package systemd
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	StateReady    = "READY=1"
+	StateStopping = "STOPPING=1"
+	StateWatchdog = "WATCHDOG=1"
+)
+
+// Notify sends a state to the service manager, it does nothing if not started by systemd
+// with NotifyAccess set.
+func Notify(state string) error {
+	socketPath := os.Getenv("NOTIFY_SOCKET")
+	if socketPath == "" {
+		return nil
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// NotifyReloading reports a reload in progress, systemd requires the monotonic
+// timestamp for services of Type=notify-reload.
+func NotifyReloading() error {
+	var now unix.Timespec
+	err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &now)
+	if err != nil {
+		return err
+	}
+	return Notify("RELOADING=1\nMONOTONIC_USEC=" + strconv.FormatInt(now.Nano()/int64(time.Microsecond), 10))
+}
+
+// WatchdogInterval returns the interval to send StateWatchdog at, half of WatchdogSec,
+// or zero if the watchdog is disabled.
+func WatchdogInterval() time.Duration {
+	watchdogPID := os.Getenv("WATCHDOG_PID")
+	if watchdogPID != "" && watchdogPID != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	watchdogUSec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || watchdogUSec <= 0 {
+		return 0
+	}
+	return time.Duration(watchdogUSec) * time.Microsecond / 2
+}
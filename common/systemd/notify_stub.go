@@ -0,0 +1,23 @@
+//go:build !linux
+
+package systemd
+
+import "time"
+
+const (
+	StateReady    = "READY=1"
+	StateStopping = "STOPPING=1"
+	StateWatchdog = "WATCHDOG=1"
+)
+
+func Notify(state string) error {
+	return nil
+}
+
+func NotifyReloading() error {
+	return nil
+}
+
+func WatchdogInterval() time.Duration {
+	return 0
+}
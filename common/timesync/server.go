@@ -0,0 +1,99 @@
+package timesync
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/sagernet/sing/common/ntp"
+)
+
+const (
+	packetLength = 48
+
+	modeClient = 3
+	modeServer = 4
+
+	// maxStratum marks the server as unsynchronized.
+	maxStratum = 16
+)
+
+var ntpEpoch = time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func (s *Service) loopServe() {
+	buffer := make([]byte, 512)
+	for {
+		n, addr, err := s.packetConn.ReadFrom(buffer)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				s.logger.Error("serve NTP: ", err)
+			}
+			return
+		}
+		receiveTime := s.TimeFunc()()
+		if n < packetLength || buffer[0]&0x07 != modeClient {
+			continue
+		}
+		response := s.newResponse(buffer[:packetLength], receiveTime)
+		_, err = s.packetConn.WriteTo(response, addr)
+		if err != nil {
+			s.logger.Debug("write NTP response to ", addr, ": ", err)
+		}
+	}
+}
+
+// newResponse answers a client request with the synchronized clock, as a
+// server one stratum below the upstream server. The leap indicator reports
+// not synchronized until the first update succeeds.
+func (s *Service) newResponse(request []byte, receiveTime time.Time) []byte {
+	upstream, syncTime := s.Synchronized()
+	response := make([]byte, packetLength)
+	version := request[0] >> 3 & 0x07
+	if upstream == nil {
+		response[0] = ntp.LeapNotInSync<<6 | version<<3 | modeServer
+		response[1] = maxStratum
+	} else {
+		response[0] = uint8(upstream.Leap)<<6 | version<<3 | modeServer
+		response[1] = min(upstream.Stratum+1, maxStratum-1)
+	}
+	// poll interval of the client
+	response[2] = request[2]
+	// precision of 2^-20 seconds, about a microsecond
+	response[3] = 0xec
+	if upstream != nil {
+		binary.BigEndian.PutUint32(response[4:], toShortTime(upstream.RootDelay+upstream.RTT))
+		binary.BigEndian.PutUint32(response[8:], toShortTime(upstream.RootDispersion+upstream.MinError))
+		binary.BigEndian.PutUint32(response[12:], s.referenceID())
+		binary.BigEndian.PutUint64(response[16:], toTime(syncTime))
+	}
+	// the transmit time of the client is the origin time
+	copy(response[24:32], request[40:48])
+	binary.BigEndian.PutUint64(response[32:], toTime(receiveTime))
+	binary.BigEndian.PutUint64(response[40:], toTime(s.TimeFunc()()))
+	return response
+}
+
+// referenceID is the IPv4 address of the upstream server, or the first four
+// bytes of the MD5 hash of other addresses as in RFC 5905.
+func (s *Service) referenceID() uint32 {
+	if s.server.Addr.Is4() {
+		return binary.BigEndian.Uint32(s.server.Addr.AsSlice())
+	}
+	hash := md5.Sum([]byte(s.server.AddrString()))
+	return binary.BigEndian.Uint32(hash[:4])
+}
+
+func toTime(t time.Time) uint64 {
+	duration := t.Sub(ntpEpoch)
+	seconds := uint64(duration / time.Second)
+	fraction := uint64(duration%time.Second) << 32 / uint64(time.Second)
+	return seconds<<32 | fraction
+}
+
+func toShortTime(duration time.Duration) uint32 {
+	seconds := uint32(duration / time.Second)
+	fraction := uint32(uint64(duration%time.Second) << 16 / uint64(time.Second))
+	return seconds<<16 | fraction
+}
@@ -0,0 +1,193 @@
+package timesync
+
+import (
+	"context"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/listener"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/ntp"
+	"github.com/sagernet/sing/common/x/list"
+	"github.com/sagernet/sing/service"
+	"github.com/sagernet/sing/service/pause"
+)
+
+var _ ntp.TimeService = (*Service)(nil)
+
+type Options struct {
+	Context       context.Context
+	Dialer        N.Dialer
+	Logger        log.ContextLogger
+	Server        M.Socksaddr
+	Interval      time.Duration
+	WriteToSystem bool
+	// Listen enables the NTP server if ListenPort is not zero.
+	Listen option.ListenOptions
+}
+
+// Service synchronizes time with an NTP server like the service of sing,
+// and serves the synchronized time to NTP clients if listening.
+type Service struct {
+	ctx           context.Context
+	cancel        common.ContextCancelCauseFunc
+	dialer        N.Dialer
+	logger        log.ContextLogger
+	server        M.Socksaddr
+	writeToSystem bool
+	interval      time.Duration
+	ticker        *time.Ticker
+	pause         pause.Manager
+	pauseCallback *list.Element[pause.Callback]
+	power         adapter.PowerManager
+	listener      *listener.Listener
+	packetConn    net.PacketConn
+	access        sync.RWMutex
+	clockOffset   time.Duration
+	response      *ntp.Response
+	syncTime      time.Time
+}
+
+func NewService(options Options) *Service {
+	ctx, cancel := common.ContextWithCancelCause(options.Context)
+	destination := options.Server
+	if !destination.IsValid() {
+		destination = M.Socksaddr{
+			Fqdn: "time.apple.com",
+		}
+	}
+	if destination.Port == 0 {
+		destination.Port = 123
+	}
+	interval := options.Interval
+	if interval <= 0 {
+		interval = 30 * time.Minute
+	}
+	timeService := &Service{
+		ctx:           ctx,
+		cancel:        cancel,
+		dialer:        options.Dialer,
+		logger:        options.Logger,
+		server:        destination,
+		writeToSystem: options.WriteToSystem,
+		interval:      interval,
+		pause:         service.FromContext[pause.Manager](ctx),
+		power:         service.FromContext[adapter.PowerManager](ctx),
+	}
+	if options.Listen.ListenPort != 0 {
+		timeService.listener = listener.New(listener.Options{
+			Context: ctx,
+			Logger:  options.Logger,
+			Network: []string{N.NetworkUDP},
+			Listen:  options.Listen,
+		})
+	}
+	return timeService
+}
+
+func (s *Service) Start() error {
+	if s.listener != nil {
+		packetConn, err := s.listener.ListenUDP()
+		if err != nil {
+			return E.Cause(err, "listen NTP server")
+		}
+		s.packetConn = packetConn
+		go s.loopServe()
+	}
+	err := s.update()
+	if err != nil {
+		s.logger.Error(E.Cause(err, "initialize time"))
+	} else {
+		s.logger.Info("updated time: ", s.TimeFunc()().Local().Format(ntp.TimeLayout))
+	}
+	s.ticker = time.NewTicker(s.interval)
+	go s.loopUpdate()
+	if s.pause != nil {
+		s.pauseCallback = pause.RegisterTicker(s.pause, s.ticker, s.interval, s.updateOnce)
+	}
+	return nil
+}
+
+func (s *Service) Close() error {
+	if s.ticker != nil {
+		s.ticker.Stop()
+	}
+	if s.pauseCallback != nil {
+		s.pause.UnregisterCallback(s.pauseCallback)
+	}
+	s.cancel(os.ErrClosed)
+	return common.Close(common.PtrOrNil(s.listener))
+}
+
+func (s *Service) TimeFunc() func() time.Time {
+	return func() time.Time {
+		s.access.RLock()
+		defer s.access.RUnlock()
+		return time.Now().Add(s.clockOffset)
+	}
+}
+
+// Synchronized returns the last response of the upstream server and the
+// synchronized time it was received at, or nil if not synchronized yet.
+func (s *Service) Synchronized() (*ntp.Response, time.Time) {
+	s.access.RLock()
+	defer s.access.RUnlock()
+	return s.response, s.syncTime
+}
+
+func (s *Service) loopUpdate() {
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-s.ticker.C:
+		}
+		if s.power != nil {
+			s.power.WaitWindow(s.ctx)
+		}
+		s.updateOnce()
+	}
+}
+
+func (s *Service) updateOnce() {
+	err := s.update()
+	if err == nil {
+		s.logger.Info("updated time: ", s.TimeFunc()().Local().Format(ntp.TimeLayout))
+	} else {
+		s.logger.Error("update time: ", err)
+	}
+}
+
+func (s *Service) update() error {
+	response, err := ntp.Exchange(s.ctx, s.dialer, s.server)
+	if err != nil {
+		return err
+	}
+	err = response.Validate()
+	if err != nil {
+		return E.Cause(err, "invalid response from ", s.server)
+	}
+	s.access.Lock()
+	defer s.access.Unlock()
+	s.clockOffset = response.ClockOffset
+	s.response = response
+	s.syncTime = time.Now().Add(s.clockOffset)
+	if s.writeToSystem {
+		err = ntp.SetSystemTime(s.syncTime)
+		if err != nil {
+			s.logger.Error("write time to system: ", err)
+		} else {
+			// the system clock is synchronized now
+			s.clockOffset = 0
+		}
+	}
+	return nil
+}
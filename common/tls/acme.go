@@ -6,12 +6,15 @@ import (
 	"context"
 	"crypto/tls"
 	"strings"
+	"sync"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/eventbus"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/logger"
+	"github.com/sagernet/sing/service"
 
 	"github.com/caddyserver/certmagic"
 	"github.com/libdns/alidns"
@@ -99,6 +102,33 @@ func startACME(ctx context.Context, logger logger.Logger, options option.Inbound
 		Storage:           storage,
 		Logger:            zapLogger,
 	}
+	if eventBus := service.FromContext[adapter.EventBus](ctx); eventBus != nil {
+		var tasks sync.Map
+		config.OnEvent = func(ctx context.Context, event string, data map[string]any) error {
+			identifier, _ := data["identifier"].(string)
+			switch event {
+			case "cert_obtaining":
+				tasks.Store(identifier, eventbus.StartTask(eventBus, adapter.TaskCertificate, identifier))
+			case "cert_obtained":
+				if task, loaded := tasks.LoadAndDelete(identifier); loaded {
+					task.(*eventbus.Task).Finish(nil)
+				}
+				eventBus.Emit(adapter.Event{
+					Type:   adapter.EventCertificateRenewed,
+					Domain: identifier,
+				})
+			case "cert_failed":
+				if task, loaded := tasks.LoadAndDelete(identifier); loaded {
+					err, _ := data["error"].(error)
+					if err == nil {
+						err = E.New("unknown error")
+					}
+					task.(*eventbus.Task).Finish(err)
+				}
+			}
+			return nil
+		}
+	}
 	acmeConfig := certmagic.ACMEIssuer{
 		CA:                      acmeServer,
 		Email:                   options.Email,
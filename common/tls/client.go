@@ -61,6 +61,11 @@ func NewClientWithOptions(options ClientOptions) (Config, error) {
 	if options.Options.KernelRx {
 		options.Logger.Warn("enabling kTLS RX will definitely reduce performance, please checkout https://sing-box.sagernet.org/configuration/shared/tls/#kernel_rx")
 	}
+	var err error
+	options.Options, err = applyFronting(options.Logger, options.Options)
+	if err != nil {
+		return nil, err
+	}
 	if options.Options.Reality != nil && options.Options.Reality.Enabled {
 		return NewRealityClient(options.Context, options.Logger, options.ServerAddress, options.Options)
 	} else if options.Options.UTLS != nil && options.Options.UTLS.Enabled {
@@ -96,6 +101,9 @@ type defaultDialer struct {
 }
 
 func NewDialer(dialer N.Dialer, config Config) Dialer {
+	if prewarmDialer, isPrewarm := dialer.(prewarmPoolDialer); isPrewarm {
+		return newPrewarmDialer(prewarmDialer, config)
+	}
 	return &defaultDialer{dialer, config}
 }
 
@@ -0,0 +1,54 @@
+package tls
+
+import (
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+)
+
+// applyFronting checks the domain fronting and fake SNI options, and returns
+// the options with the fronting domain as the server name, which is sent in
+// SNI and verified.
+func applyFronting(logger logger.ContextLogger, options option.OutboundTLSOptions) (option.OutboundTLSOptions, error) {
+	if options.FrontingDomain == "" && options.FakeSNI == "" {
+		return options, nil
+	}
+	var name string
+	if options.FrontingDomain != "" {
+		if options.FakeSNI != "" {
+			return options, E.New("`fronting_domain` is conflict with `fake_sni`")
+		}
+		name = "fronting_domain"
+	} else {
+		name = "fake_sni"
+	}
+	if options.DisableSNI {
+		return options, E.New("`", name, "` is conflict with `disable_sni`")
+	}
+	if options.ECH != nil && options.ECH.Enabled {
+		return options, E.New("`", name, "` is unsupported with ECH")
+	}
+	if options.Reality != nil && options.Reality.Enabled {
+		return options, E.New("`", name, "` is unsupported in reality")
+	}
+	if options.ServerName == "" {
+		return options, E.New("`", name, "` requires `server_name`")
+	}
+	if options.FrontingDomain != "" {
+		logger.Warn("domain fronting enabled: SNI ", options.FrontingDomain, " differs from host ", options.ServerName, ", which is rejected by many CDNs")
+		options.ServerName = options.FrontingDomain
+		options.FrontingDomain = ""
+	} else {
+		logger.Warn("fake SNI enabled: SNI ", options.FakeSNI, " is sent, and the certificate is verified for ", options.ServerName)
+	}
+	return options, nil
+}
+
+// FrontedHost returns the host of HTTP requests in the TLS connection if
+// domain fronting is enabled, or an empty string.
+func FrontedHost(options *option.OutboundTLSOptions) string {
+	if options == nil || !options.Enabled || options.FrontingDomain == "" {
+		return ""
+	}
+	return options.ServerName
+}
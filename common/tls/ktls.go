@@ -2,6 +2,7 @@ package tls
 
 import (
 	"context"
+	"errors"
 	"net"
 
 	"github.com/sagernet/sing-box/common/ktls"
@@ -23,6 +24,10 @@ func (w *KTLSClientConfig) ClientHandshake(ctx context.Context, conn net.Conn) (
 	}
 	kConn, err := ktls.NewConn(ctx, w.logger, tlsConn, w.kernelTx, w.kernelRx)
 	if err != nil {
+		if errors.Is(err, ktls.ErrUnsupported) {
+			w.logger.DebugContext(ctx, err, ", continue in userspace")
+			return tlsConn, nil
+		}
 		tlsConn.Close()
 		return nil, E.Cause(err, "initialize kernel TLS")
 	}
@@ -51,6 +56,10 @@ func (w *KTlSServerConfig) ServerHandshake(ctx context.Context, conn net.Conn) (
 	}
 	kConn, err := ktls.NewConn(ctx, w.logger, tlsConn, w.kernelTx, w.kernelRx)
 	if err != nil {
+		if errors.Is(err, ktls.ErrUnsupported) {
+			w.logger.DebugContext(ctx, err, ", continue in userspace")
+			return tlsConn, nil
+		}
 		tlsConn.Close()
 		return nil, E.Cause(err, "initialize kernel TLS")
 	}
@@ -65,3 +74,14 @@ func (w *KTlSServerConfig) Clone() Config {
 		w.kernelRx,
 	}
 }
+
+// kTLSAvailable checks if kernel TLS is available on this system, kTLS is
+// disabled with a warning if not.
+func kTLSAvailable(logger logger.ContextLogger) bool {
+	err := ktls.Load()
+	if err != nil {
+		logger.Warn("kernel TLS disabled: ", err)
+		return false
+	}
+	return true
+}
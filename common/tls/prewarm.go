@@ -0,0 +1,45 @@
+package tls
+
+import (
+	"context"
+	"net"
+	"os"
+
+	"github.com/sagernet/sing-box/common/dialer"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+type prewarmPoolDialer = dialer.PrewarmPoolDialer
+
+// prewarmDialer keeps finished TLS handshakes in the pool instead of bare
+// TCP connections.
+type prewarmDialer struct {
+	*defaultDialer
+	pool *dialer.PrewarmPool
+}
+
+func newPrewarmDialer(upstream prewarmPoolDialer, config Config) Dialer {
+	tlsDialer := &defaultDialer{upstream.Dialer(), config}
+	return &prewarmDialer{
+		defaultDialer: tlsDialer,
+		pool: upstream.NewPool(func(ctx context.Context, destination M.Socksaddr) (net.Conn, error) {
+			return tlsDialer.DialTLSContext(ctx, destination)
+		}),
+	}
+}
+
+func (d *prewarmDialer) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	if N.NetworkName(network) != N.NetworkTCP {
+		return nil, os.ErrInvalid
+	}
+	return d.DialTLSContext(ctx, destination)
+}
+
+func (d *prewarmDialer) DialTLSContext(ctx context.Context, destination M.Socksaddr) (Conn, error) {
+	conn, err := d.pool.DialContext(ctx, destination)
+	if err != nil {
+		return nil, err
+	}
+	return conn.(Conn), nil
+}
@@ -80,8 +80,12 @@ func NewRealityServer(ctx context.Context, logger log.ContextLogger, options opt
 
 	tlsConfig.SessionTicketsDisabled = true
 	tlsConfig.Log = func(format string, v ...any) {
-		if logger != nil {
-			logger.Trace(fmt.Sprintf(format, v...))
+		if logger == nil {
+			return
+		}
+		logger.Trace(fmt.Sprintf(format, v...))
+		if format == realityClientTimeFormat && tlsConfig.MaxTimeDiff > 0 {
+			checkRealityClientTime(logger, tlsConfig.Time, tlsConfig.MaxTimeDiff, v...)
 		}
 	}
 	tlsConfig.Type = N.NetworkTCP
@@ -131,6 +135,9 @@ func NewRealityServer(ctx context.Context, logger log.ContextLogger, options opt
 		if !C.IsLinux {
 			return nil, E.New("kTLS is only supported on Linux")
 		}
+		if !kTLSAvailable(logger) {
+			return config, nil
+		}
 		config = &KTlSServerConfig{
 			ServerConfig: config,
 			logger:       logger,
@@ -233,3 +240,29 @@ func (c *realityConnWrapper) ReaderReplaceable() bool {
 func (c *realityConnWrapper) WriterReplaceable() bool {
 	return true
 }
+
+// realityClientTimeFormat is the log message of the library with the time of
+// the client, which is the only way to know why authentication failed.
+const realityClientTimeFormat = "REALITY remoteAddr: %v hs.c.ClientTime: %v"
+
+func checkRealityClientTime(logger log.ContextLogger, timeFunc func() time.Time, maxTimeDifference time.Duration, v ...any) {
+	if len(v) != 2 {
+		return
+	}
+	clientTime, isTime := v[1].(time.Time)
+	if !isTime {
+		return
+	}
+	var now time.Time
+	if timeFunc != nil {
+		now = timeFunc()
+	} else {
+		now = time.Now()
+	}
+	difference := clientTime.Sub(now)
+	if difference > maxTimeDifference {
+		logger.Warn("REALITY client ", v[0], " rejected: client clock is ", difference, " ahead of server, exceeding max_time_difference of ", maxTimeDifference)
+	} else if difference < -maxTimeDifference {
+		logger.Warn("REALITY client ", v[0], " rejected: client clock is ", -difference, " behind server, exceeding max_time_difference of ", maxTimeDifference)
+	}
+}
@@ -88,12 +88,14 @@ func NewSTDClient(ctx context.Context, logger logger.ContextLogger, serverAddres
 	var tlsConfig tls.Config
 	tlsConfig.Time = ntp.TimeFuncFromContext(ctx)
 	tlsConfig.RootCAs = adapter.RootPoolFromContext(ctx)
-	if !options.DisableSNI {
+	if options.FakeSNI != "" {
+		tlsConfig.ServerName = options.FakeSNI
+	} else if !options.DisableSNI {
 		tlsConfig.ServerName = serverName
 	}
 	if options.Insecure {
 		tlsConfig.InsecureSkipVerify = options.Insecure
-	} else if options.DisableSNI {
+	} else if options.DisableSNI || options.FakeSNI != "" {
 		tlsConfig.InsecureSkipVerify = true
 		tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
 			verifyOptions := x509.VerifyOptions{
@@ -210,6 +212,9 @@ func NewSTDClient(ctx context.Context, logger logger.ContextLogger, serverAddres
 		if !C.IsLinux {
 			return nil, E.New("kTLS is only supported on Linux")
 		}
+		if !kTLSAvailable(logger) {
+			return config, nil
+		}
 		config = &KTLSClientConfig{
 			Config:   config,
 			logger:   logger,
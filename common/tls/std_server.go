@@ -18,10 +18,13 @@ import (
 	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/ntp"
+	"github.com/sagernet/sing/service"
 )
 
 var errInsecureUnused = E.New("tls: insecure unused")
 
+const certificateExpiringThreshold = 14 * 24 * time.Hour
+
 type STDServerConfig struct {
 	access                sync.RWMutex
 	config                *tls.Config
@@ -34,6 +37,8 @@ type STDServerConfig struct {
 	clientCertificatePath []string
 	echKeyPath            string
 	watcher               *fswatch.Watcher
+	eventBus              adapter.EventBus
+	expiryDone            chan struct{}
 }
 
 func (c *STDServerConfig) ServerName() string {
@@ -98,10 +103,52 @@ func (c *STDServerConfig) Start() error {
 		if err != nil {
 			c.logger.Warn("create fsnotify watcher: ", err)
 		}
+		if c.eventBus != nil && c.certificate != nil {
+			c.expiryDone = make(chan struct{})
+			go c.loopCheckExpiry()
+		}
 		return nil
 	}
 }
 
+// loopCheckExpiry emits a certificate_expiring event daily once the
+// certificate expires in less than certificateExpiringThreshold.
+func (c *STDServerConfig) loopCheckExpiry() {
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+	for {
+		c.checkExpiry()
+		select {
+		case <-c.expiryDone:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *STDServerConfig) checkExpiry() {
+	c.access.RLock()
+	certificates := c.config.Certificates
+	c.access.RUnlock()
+	for _, certificate := range certificates {
+		leaf := certificate.Leaf
+		if leaf == nil || time.Until(leaf.NotAfter) > certificateExpiringThreshold {
+			continue
+		}
+		domain := leaf.Subject.CommonName
+		if len(leaf.DNSNames) > 0 {
+			domain = leaf.DNSNames[0]
+		}
+		expireAt := leaf.NotAfter
+		c.logger.Warn("TLS certificate for ", domain, " expires at ", expireAt.Format(time.RFC3339))
+		c.eventBus.Emit(adapter.Event{
+			Type:     adapter.EventCertificateExpiring,
+			Domain:   domain,
+			ExpireAt: &expireAt,
+		})
+	}
+}
+
 func (c *STDServerConfig) startWatcher() error {
 	var watchPath []string
 	if c.certificatePath != "" {
@@ -206,6 +253,9 @@ func (c *STDServerConfig) Close() error {
 	if c.acmeService != nil {
 		return c.acmeService.Close()
 	}
+	if c.expiryDone != nil {
+		close(c.expiryDone)
+	}
 	if c.watcher != nil {
 		return c.watcher.Close()
 	}
@@ -367,6 +417,7 @@ func NewSTDServer(ctx context.Context, logger log.ContextLogger, options option.
 		clientCertificatePath: options.ClientCertificatePath,
 		keyPath:               options.KeyPath,
 		echKeyPath:            echKeyPath,
+		eventBus:              service.FromContext[adapter.EventBus](ctx),
 	}
 	serverConfig.config.GetConfigForClient = func(info *tls.ClientHelloInfo) (*tls.Config, error) {
 		serverConfig.access.Lock()
@@ -378,6 +429,9 @@ func NewSTDServer(ctx context.Context, logger log.ContextLogger, options option.
 		if !C.IsLinux {
 			return nil, E.New("kTLS is only supported on Linux")
 		}
+		if !kTLSAvailable(logger) {
+			return config, nil
+		}
 		config = &KTlSServerConfig{
 			ServerConfig: config,
 			logger:       logger,
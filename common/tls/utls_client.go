@@ -156,13 +156,15 @@ func NewUTLSClient(ctx context.Context, logger logger.ContextLogger, serverAddre
 	var tlsConfig utls.Config
 	tlsConfig.Time = ntp.TimeFuncFromContext(ctx)
 	tlsConfig.RootCAs = adapter.RootPoolFromContext(ctx)
-	if !options.DisableSNI {
+	if options.FakeSNI != "" {
+		tlsConfig.ServerName = options.FakeSNI
+	} else if !options.DisableSNI {
 		tlsConfig.ServerName = serverName
 	}
 	if options.Insecure {
 		tlsConfig.InsecureSkipVerify = options.Insecure
-	} else if options.DisableSNI {
-		if options.Reality != nil && options.Reality.Enabled {
+	} else if options.DisableSNI || options.FakeSNI != "" {
+		if options.DisableSNI && options.Reality != nil && options.Reality.Enabled {
 			return nil, E.New("disable_sni is unsupported in reality")
 		}
 		tlsConfig.InsecureServerNameToVerify = serverName
@@ -269,6 +271,9 @@ func NewUTLSClient(ctx context.Context, logger logger.ContextLogger, serverAddre
 		if !C.IsLinux {
 			return nil, E.New("kTLS is only supported on Linux")
 		}
+		if !kTLSAvailable(logger) {
+			return config, nil
+		}
 		config = &KTLSClientConfig{
 			Config:   config,
 			logger:   logger,
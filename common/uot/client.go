@@ -0,0 +1,77 @@
+package uot
+
+import (
+	"context"
+	"net"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/uot"
+)
+
+// Client selects the UDP transport of an outbound by the `udp_over_tcp`
+// route option, or the UoT options of the outbound if not set.
+type Client struct {
+	dialer  N.Dialer
+	version uint8
+	connect bool
+}
+
+func NewClient(dialer N.Dialer, options option.UDPOverTCPOptions) *Client {
+	client := &Client{
+		dialer:  dialer,
+		connect: options.Connect,
+	}
+	if options.Enabled {
+		client.version = options.Version
+		if client.version == 0 {
+			client.version = uot.Version
+		}
+	}
+	return client
+}
+
+// Version returns the UoT version for the connection, or zero for native UDP.
+func (c *Client) Version(ctx context.Context) uint8 {
+	metadata := adapter.ContextFrom(ctx)
+	if metadata == nil {
+		return c.version
+	}
+	switch metadata.UDPOverTCP {
+	case C.UDPOverTCPNative:
+		return 0
+	case C.UDPOverTCPV1:
+		return uot.LegacyVersion
+	case C.UDPOverTCPV2:
+		return uot.Version
+	default:
+		return c.version
+	}
+}
+
+func (c *Client) DialContext(ctx context.Context, version uint8, destination M.Socksaddr) (net.Conn, error) {
+	client := uot.Client{Dialer: c.dialer, Version: version}
+	return client.DialContext(ctx, N.NetworkUDP, destination)
+}
+
+// ListenPacket uses the connect mode of UoT v2 if enabled in the outbound,
+// which fixes the destination of the session.
+func (c *Client) ListenPacket(ctx context.Context, version uint8, destination M.Socksaddr) (net.PacketConn, error) {
+	client := uot.Client{Dialer: c.dialer, Version: version}
+	if !c.connect || version != uot.Version {
+		return client.ListenPacket(ctx, destination)
+	}
+	tcpConn, err := c.dialer.DialContext(ctx, N.NetworkTCP, uot.RequestDestination(version))
+	if err != nil {
+		return nil, err
+	}
+	uConn, err := client.DialEarlyConn(tcpConn, true, destination)
+	if err != nil {
+		tcpConn.Close()
+		return nil, err
+	}
+	return uConn, nil
+}
@@ -0,0 +1,7 @@
+package constant
+
+const (
+	AuthProviderTypeHTTP   = "http"
+	AuthProviderTypeRADIUS = "radius"
+	AuthProviderTypeLDAP   = "ldap"
+)
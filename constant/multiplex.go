@@ -0,0 +1,6 @@
+package constant
+
+const (
+	MultiplexEnabled  = "enabled"
+	MultiplexDisabled = "disabled"
+)
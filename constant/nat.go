@@ -0,0 +1,7 @@
+package constant
+
+const (
+	NATEndpointIndependent     = "endpoint_independent"
+	NATAddressDependent        = "address_dependent"
+	NATAddressAndPortDependent = "address_and_port_dependent"
+)
@@ -58,3 +58,15 @@ func (s NetworkStrategy) String() string {
 	}
 	return name
 }
+
+const (
+	MultiWANStrategyWeighted = "weighted"
+	MultiWANStrategyHash     = "hash"
+	MultiWANStrategyFailover = "failover"
+)
+
+const (
+	SourceAddressPoolStrategyRoundRobin = "round_robin"
+	SourceAddressPoolStrategyHash       = "hash"
+	SourceAddressPoolStrategyUser       = "user"
+)
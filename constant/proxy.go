@@ -28,6 +28,17 @@ const (
 	TypeDERP         = "derp"
 	TypeResolved     = "resolved"
 	TypeSSMAPI       = "ssm-api"
+	TypeSubscription = "subscription"
+	TypeUpdater      = "updater"
+	TypeWebhook      = "webhook"
+	TypeScheduler    = "scheduler"
+	TypeEBPF         = "ebpf"
+	TypeDHCPServer   = "dhcp-server"
+	TypePlugin       = "plugin"
+	TypeHTTP3        = "http3"
+	TypePortal       = "portal"
+	TypeBridge       = "bridge"
+	TypeSNIMux       = "sni-mux"
 )
 
 const (
@@ -84,6 +95,16 @@ func ProxyDisplayName(proxyType string) string {
 		return "Hysteria2"
 	case TypeAnyTLS:
 		return "AnyTLS"
+	case TypePlugin:
+		return "Plugin"
+	case TypeHTTP3:
+		return "HTTP/3"
+	case TypePortal:
+		return "Portal"
+	case TypeBridge:
+		return "Bridge"
+	case TypeSNIMux:
+		return "SNI Mux"
 	case TypeSelector:
 		return "Selector"
 	case TypeURLTest:
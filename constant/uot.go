@@ -0,0 +1,7 @@
+package constant
+
+const (
+	UDPOverTCPNative = "native"
+	UDPOverTCPV1     = "v1"
+	UDPOverTCPV2     = "v2"
+)
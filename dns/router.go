@@ -39,6 +39,7 @@ type Router struct {
 	defaultDomainStrategy C.DomainStrategy
 	dnsReverseMapping     freelru.Cache[netip.Addr, string]
 	platformInterface     platform.Interface
+	eventBus              adapter.EventBus
 }
 
 func NewRouter(ctx context.Context, logFactory log.Factory, options option.DNSOptions) *Router {
@@ -47,6 +48,7 @@ func NewRouter(ctx context.Context, logFactory log.Factory, options option.DNSOp
 		logger:                logFactory.NewLogger("dns"),
 		transport:             service.FromContext[adapter.DNSTransportManager](ctx),
 		outbound:              service.FromContext[adapter.OutboundManager](ctx),
+		eventBus:              service.FromContext[adapter.EventBus](ctx),
 		rules:                 make([]adapter.DNSRule, 0, len(options.Rules)),
 		defaultDomainStrategy: C.DomainStrategy(options.Strategy),
 	}
@@ -144,6 +146,13 @@ func (r *Router) matchDNS(ctx context.Context, allowFakeIP bool, ruleIndex int,
 			} else {
 				r.logger.DebugContext(ctx, "match[", displayRuleIndex, "] => ", currentRule.Action())
 			}
+			if trace := adapter.DNSTraceFromContext(ctx); trace != nil {
+				trace.Matches = append(trace.Matches, adapter.DNSTraceMatch{
+					Index:  currentRuleIndex,
+					Rule:   ruleDescription,
+					Action: currentRule.Action().String(),
+				})
+			}
 			switch action := currentRule.Action().(type) {
 			case *R.RuleActionDNSRoute:
 				transport, loaded := r.transport.Transport(action.Server)
@@ -218,6 +227,10 @@ func (r *Router) Exchange(ctx context.Context, message *mDNS.Msg, options adapte
 		err       error
 	)
 	response, cached := r.client.ExchangeCache(ctx, message)
+	trace := adapter.DNSTraceFromContext(ctx)
+	if cached && trace != nil {
+		trace.Cached = true
+	}
 	if !cached {
 		var metadata *adapter.InboundContext
 		ctx, metadata = adapter.ExtendContext(ctx)
@@ -243,6 +256,9 @@ func (r *Router) Exchange(ctx context.Context, message *mDNS.Msg, options adapte
 			if options.Strategy == C.DomainStrategyAsIS {
 				options.Strategy = r.defaultDomainStrategy
 			}
+			if trace != nil {
+				trace.Transport = transport.Tag()
+			}
 			response, err = r.client.Exchange(ctx, transport, message, options, nil)
 		} else {
 			var (
@@ -284,6 +300,9 @@ func (r *Router) Exchange(ctx context.Context, message *mDNS.Msg, options adapte
 				if dnsOptions.Strategy == C.DomainStrategyAsIS {
 					dnsOptions.Strategy = r.defaultDomainStrategy
 				}
+				if trace != nil {
+					trace.Transport = transport.Tag()
+				}
 				response, err = r.client.Exchange(dnsCtx, transport, message, dnsOptions, responseCheck)
 				var rejected bool
 				if err != nil {
@@ -307,8 +326,10 @@ func (r *Router) Exchange(ctx context.Context, message *mDNS.Msg, options adapte
 		}
 	}
 	if err != nil {
+		r.emitExchange(ctx, message.Question[0], transport, nil, err)
 		return nil, err
 	}
+	r.emitExchange(ctx, message.Question[0], transport, response, nil)
 	if r.dnsReverseMapping != nil && len(message.Question) > 0 && response != nil && len(response.Answer) > 0 {
 		if transport == nil || transport.Type() != C.DNSTypeFakeIP {
 			for _, answer := range response.Answer {
@@ -327,6 +348,7 @@ func (r *Router) Exchange(ctx context.Context, message *mDNS.Msg, options adapte
 func (r *Router) Lookup(ctx context.Context, domain string, options adapter.DNSQueryOptions) ([]netip.Addr, error) {
 	var (
 		responseAddrs []netip.Addr
+		transportTag  string
 		cached        bool
 		err           error
 	)
@@ -350,8 +372,11 @@ func (r *Router) Lookup(ctx context.Context, domain string, options adapter.DNSQ
 	responseAddrs, cached = r.client.LookupCache(domain, options.Strategy)
 	if cached {
 		if len(responseAddrs) == 0 {
-			return nil, E.New("lookup ", domain, ": empty result (cached)")
+			err = E.New("lookup ", domain, ": empty result (cached)")
+			r.emitLookup(ctx, domain, "", nil, err)
+			return nil, err
 		}
+		r.emitLookup(ctx, domain, "", responseAddrs, nil)
 		return responseAddrs, nil
 	}
 	r.logger.DebugContext(ctx, "lookup domain ", domain)
@@ -371,6 +396,7 @@ func (r *Router) Lookup(ctx context.Context, domain string, options adapter.DNSQ
 		if options.Strategy == C.DomainStrategyAsIS {
 			options.Strategy = r.defaultDomainStrategy
 		}
+		transportTag = transport.Tag()
 		responseAddrs, err = r.client.Lookup(ctx, transport, domain, options, nil)
 	} else {
 		var (
@@ -413,6 +439,7 @@ func (r *Router) Lookup(ctx context.Context, domain string, options adapter.DNSQ
 			if dnsOptions.Strategy == C.DomainStrategyAsIS {
 				dnsOptions.Strategy = r.defaultDomainStrategy
 			}
+			transportTag = transport.Tag()
 			responseAddrs, err = r.client.Lookup(dnsCtx, transport, domain, dnsOptions, responseCheck)
 			if responseCheck == nil || err == nil {
 				break
@@ -425,9 +452,53 @@ response:
 	if len(responseAddrs) > 0 {
 		r.logger.InfoContext(ctx, "lookup succeed for ", domain, ": ", strings.Join(F.MapToString(responseAddrs), " "))
 	}
+	r.emitLookup(ctx, domain, transportTag, responseAddrs, err)
 	return responseAddrs, err
 }
 
+func (r *Router) emitExchange(ctx context.Context, question mDNS.Question, transport adapter.DNSTransport, response *mDNS.Msg, err error) {
+	if r.eventBus == nil || !r.eventBus.Subscribed(adapter.EventDNSAnswered) {
+		return
+	}
+	event := r.newAnsweredEvent(ctx, question.Name, err)
+	event.QueryType = mDNS.Type(question.Qtype).String()
+	if transport != nil {
+		event.Transport = transport.Tag()
+	}
+	if response != nil {
+		event.RCode = mDNS.RcodeToString[response.Rcode]
+		for _, answer := range response.Answer {
+			event.Answers = append(event.Answers, strings.TrimPrefix(answer.String(), answer.Header().String()))
+		}
+	}
+	r.eventBus.Emit(event)
+}
+
+func (r *Router) emitLookup(ctx context.Context, domain string, transportTag string, responseAddrs []netip.Addr, err error) {
+	if r.eventBus == nil || !r.eventBus.Subscribed(adapter.EventDNSAnswered) {
+		return
+	}
+	event := r.newAnsweredEvent(ctx, domain, err)
+	event.Transport = transportTag
+	event.Answers = F.MapToString(responseAddrs)
+	r.eventBus.Emit(event)
+}
+
+func (r *Router) newAnsweredEvent(ctx context.Context, domain string, err error) adapter.Event {
+	event := adapter.Event{
+		Type:   adapter.EventDNSAnswered,
+		Time:   time.Now(),
+		Domain: FqdnToDomain(domain),
+	}
+	if id, loaded := log.IDFromContext(ctx); loaded {
+		event.ConnectionID = id.ID
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	return event
+}
+
 func isAddressQuery(message *mDNS.Msg) bool {
 	for _, question := range message.Question {
 		if question.Qtype == mDNS.TypeA || question.Qtype == mDNS.TypeAAAA || question.Qtype == mDNS.TypeHTTPS {
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"io"
 	"net"
+	"net/netip"
 	"runtime"
 	"strings"
 	"sync"
@@ -21,6 +22,7 @@ import (
 	"github.com/sagernet/sing/common/buf"
 	"github.com/sagernet/sing/common/control"
 	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json/badoption"
 	"github.com/sagernet/sing/common/logger"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
@@ -47,8 +49,12 @@ type Transport struct {
 	networkManager    adapter.NetworkManager
 	interfaceName     string
 	interfaceCallback *list.Element[tun.DefaultInterfaceUpdateCallback]
+	networkCallback   *list.Element[tun.NetworkUpdateCallback]
+	serverOverride    map[netip.Addr]M.Socksaddr
+	excludeServer     []netip.Prefix
 	transportLock     sync.RWMutex
 	updatedAt         time.Time
+	queriedInterface  *control.Interface
 	servers           []M.Socksaddr
 	search            []string
 	ndots             int
@@ -60,6 +66,21 @@ func NewTransport(ctx context.Context, logger log.ContextLogger, tag string, opt
 	if err != nil {
 		return nil, err
 	}
+	serverOverride := make(map[netip.Addr]M.Socksaddr)
+	for server, override := range options.ServerOverride {
+		serverAddr, err := netip.ParseAddr(server)
+		if err != nil {
+			return nil, E.Cause(err, "parse server_override: ", server)
+		}
+		overrideAddr := M.ParseSocksaddr(override)
+		if overrideAddr.Port == 0 {
+			overrideAddr.Port = 53
+		}
+		if !overrideAddr.IsIP() {
+			return nil, E.New("invalid server_override for ", server, ": ", override, ", IP address required")
+		}
+		serverOverride[serverAddr.Unmap()] = overrideAddr
+	}
 	return &Transport{
 		TransportAdapter: dns.NewTransportAdapterWithLocalOptions(C.DNSTypeDHCP, tag, options.LocalDNSServerOptions),
 		ctx:              ctx,
@@ -67,8 +88,12 @@ func NewTransport(ctx context.Context, logger log.ContextLogger, tag string, opt
 		logger:           logger,
 		networkManager:   service.FromContext[adapter.NetworkManager](ctx),
 		interfaceName:    options.Interface,
-		ndots:            1,
-		attempts:         2,
+		serverOverride:   serverOverride,
+		excludeServer: common.Map(options.ExcludeServer, func(it badoption.Prefixable) netip.Prefix {
+			return netip.Prefix(it)
+		}),
+		ndots:    1,
+		attempts: 2,
 	}, nil
 }
 
@@ -90,6 +115,8 @@ func (t *Transport) Start(stage adapter.StartStage) error {
 	}
 	if t.interfaceName == "" {
 		t.interfaceCallback = t.networkManager.InterfaceMonitor().RegisterCallback(t.interfaceUpdated)
+	} else if t.networkManager.NetworkMonitor() != nil {
+		t.networkCallback = t.networkManager.NetworkMonitor().RegisterCallback(t.networkUpdated)
 	}
 	go func() {
 		_, err := t.Fetch()
@@ -104,6 +131,9 @@ func (t *Transport) Close() error {
 	if t.interfaceCallback != nil {
 		t.networkManager.InterfaceMonitor().UnregisterCallback(t.interfaceCallback)
 	}
+	if t.networkCallback != nil {
+		t.networkManager.NetworkMonitor().UnregisterCallback(t.networkCallback)
+	}
 	return nil
 }
 
@@ -159,10 +189,26 @@ func (t *Transport) fetchInterface() (*control.Interface, error) {
 		}
 		return defaultInterface, nil
 	} else {
+		iface, err := t.networkManager.InterfaceFinder().ByName(t.interfaceName)
+		if err == nil {
+			return iface, nil
+		}
+		// interfaces like VLAN subinterfaces may be created after start
+		updateErr := t.networkManager.UpdateInterfaces()
+		if updateErr != nil {
+			return nil, E.Errors(err, updateErr)
+		}
 		return t.networkManager.InterfaceFinder().ByName(t.interfaceName)
 	}
 }
 
+// interfaceChanged reports whether the network of the interface changed
+// since servers were queried on it.
+func (t *Transport) interfaceChanged(iface *control.Interface) bool {
+	queried := t.queriedInterface
+	return queried == nil || queried.Index != iface.Index || queried.Flags != iface.Flags || !slices.Equal(queried.Addresses, iface.Addresses)
+}
+
 func (t *Transport) updateServers() error {
 	iface, err := t.fetchInterface()
 	if err != nil {
@@ -179,17 +225,47 @@ func (t *Transport) updateServers() error {
 		return E.New("dhcp: empty DNS servers response")
 	} else {
 		t.updatedAt = time.Now()
+		t.queriedInterface = iface
 		return nil
 	}
 }
 
 func (t *Transport) interfaceUpdated(defaultInterface *control.Interface, flags int) {
+	t.transportLock.Lock()
+	defer t.transportLock.Unlock()
 	err := t.updateServers()
 	if err != nil {
 		t.logger.Error("update servers: ", err)
 	}
 }
 
+// networkUpdated queries servers again if the specified interface changed,
+// the servers are kept while the interface is missing or down.
+func (t *Transport) networkUpdated() {
+	err := t.networkManager.UpdateInterfaces()
+	if err != nil {
+		t.logger.Warn("update interfaces: ", err)
+	}
+	iface, err := t.fetchInterface()
+	if err != nil {
+		return
+	}
+	t.transportLock.Lock()
+	defer t.transportLock.Unlock()
+	if !t.interfaceChanged(iface) {
+		return
+	}
+	t.queriedInterface = iface
+	if iface.Flags&net.FlagRunning == 0 {
+		// wait for the link to come up
+		return
+	}
+	err = t.updateServers()
+	if err != nil {
+		t.logger.Error("update servers: ", err)
+	}
+}
+
 func (t *Transport) fetchServers0(ctx context.Context, iface *control.Interface) error {
 	var listener net.ListenConfig
 	listener.Control = control.Append(listener.Control, control.BindToInterface(t.networkManager.InterfaceFinder(), iface.Name, iface.Index))
@@ -278,9 +354,21 @@ func (t *Transport) recreateServers(iface *control.Interface, dhcpPacket *dhcpv4
 	} else if dhcpPacket.DomainName() != "" {
 		t.search = []string{dhcpPacket.DomainName()}
 	}
-	serverAddrs := common.Map(dhcpPacket.DNS(), func(it net.IP) M.Socksaddr {
-		return M.SocksaddrFrom(M.AddrFromIP(it), 53)
-	})
+	var serverAddrs []M.Socksaddr
+	for _, serverIP := range dhcpPacket.DNS() {
+		serverAddr := M.AddrFromIP(serverIP)
+		if common.Any(t.excludeServer, func(it netip.Prefix) bool {
+			return it.Contains(serverAddr)
+		}) {
+			t.logger.Debug("dhcp: excluded DNS server ", serverAddr, " from ", iface.Name)
+			continue
+		}
+		if override, loaded := t.serverOverride[serverAddr]; loaded {
+			serverAddrs = append(serverAddrs, override)
+		} else {
+			serverAddrs = append(serverAddrs, M.SocksaddrFrom(serverAddr, 53))
+		}
+	}
 	if len(serverAddrs) > 0 && !slices.Equal(t.servers, serverAddrs) {
 		t.logger.Info("dhcp: updated DNS servers from ", iface.Name, ": [", strings.Join(common.Map(serverAddrs, M.Socksaddr.String), ","), "], search: [", strings.Join(t.search, ","), "]")
 	}
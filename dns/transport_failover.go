@@ -0,0 +1,144 @@
+package dns
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	mDNS "github.com/miekg/dns"
+)
+
+const (
+	failoverCooldown    = 30 * time.Second
+	failoverMaxCooldown = 5 * time.Minute
+)
+
+var _ adapter.DNSTransport = (*FailoverTransport)(nil)
+
+// FailoverTransport exchanges through the first healthy of several transports
+// which only differ in their detour. A detour failing an exchange is skipped
+// for a cooldown doubling on each failure, and is tried again as the last
+// resort or when the cooldown expires.
+type FailoverTransport struct {
+	TransportAdapter
+	logger     log.ContextLogger
+	detours    []string
+	transports []adapter.DNSTransport
+	access     sync.Mutex
+	states     []failoverState
+	active     int
+	timeFunc   func() time.Time
+}
+
+type failoverState struct {
+	cooldown time.Duration
+	until    time.Time
+}
+
+func NewFailoverTransport(logger log.ContextLogger, transportType string, tag string, detours []string, constructor func(detour string) (adapter.DNSTransport, error)) (*FailoverTransport, error) {
+	transports := make([]adapter.DNSTransport, 0, len(detours))
+	for _, detour := range detours {
+		transport, err := constructor(detour)
+		if err != nil {
+			return nil, E.Cause(err, "detour ", detour)
+		}
+		transports = append(transports, transport)
+	}
+	return &FailoverTransport{
+		TransportAdapter: NewTransportAdapter(transportType, tag, transports[0].Dependencies()),
+		logger:           logger,
+		detours:          detours,
+		transports:       transports,
+		states:           make([]failoverState, len(transports)),
+		timeFunc:         time.Now,
+	}, nil
+}
+
+func (t *FailoverTransport) Start(stage adapter.StartStage) error {
+	for i, transport := range t.transports {
+		err := transport.Start(stage)
+		if err != nil {
+			return E.Cause(err, "detour ", t.detours[i])
+		}
+	}
+	return nil
+}
+
+func (t *FailoverTransport) Close() error {
+	return common.Close(common.Map(t.transports, func(it adapter.DNSTransport) any {
+		return it
+	})...)
+}
+
+func (t *FailoverTransport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	order := t.order()
+	var errors []error
+	for i, index := range order {
+		exchangeCtx, cancel := ctx, context.CancelFunc(func() {})
+		if deadline, hasDeadline := ctx.Deadline(); hasDeadline && i < len(order)-1 {
+			// leave time for the remaining detours if this one hangs
+			exchangeCtx, cancel = context.WithTimeout(ctx, time.Until(deadline)/time.Duration(len(order)-i))
+		}
+		response, err := t.transports[index].Exchange(exchangeCtx, message)
+		cancel()
+		if err == nil {
+			t.succeed(ctx, index)
+			return response, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		t.fail(ctx, index, err)
+		errors = append(errors, E.Cause(err, "detour ", t.detours[index]))
+	}
+	return nil, E.Errors(errors...)
+}
+
+// order returns the indexes of transports to try, healthy ones first.
+func (t *FailoverTransport) order() []int {
+	t.access.Lock()
+	defer t.access.Unlock()
+	now := t.timeFunc()
+	order := make([]int, 0, len(t.states))
+	for index, state := range t.states {
+		if !now.Before(state.until) {
+			order = append(order, index)
+		}
+	}
+	for index, state := range t.states {
+		if now.Before(state.until) {
+			order = append(order, index)
+		}
+	}
+	return order
+}
+
+func (t *FailoverTransport) succeed(ctx context.Context, index int) {
+	t.access.Lock()
+	t.states[index] = failoverState{}
+	previous := t.active
+	t.active = index
+	t.access.Unlock()
+	if previous != index {
+		t.logger.InfoContext(ctx, "shift to detour ", t.detours[index], " from ", t.detours[previous])
+	}
+}
+
+func (t *FailoverTransport) fail(ctx context.Context, index int, err error) {
+	t.access.Lock()
+	state := &t.states[index]
+	if state.cooldown == 0 {
+		state.cooldown = failoverCooldown
+	} else if !t.timeFunc().Before(state.until) {
+		state.cooldown = min(state.cooldown*2, failoverMaxCooldown)
+	}
+	state.until = t.timeFunc().Add(state.cooldown)
+	cooldown := state.cooldown
+	t.access.Unlock()
+	t.logger.WarnContext(ctx, "detour ", t.detours[index], " skipped for ", cooldown, ": ", err)
+}
@@ -0,0 +1,109 @@
+package dns
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	mDNS "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+type failoverTestTransport struct {
+	TransportAdapter
+	failing   bool
+	exchanged int
+}
+
+func (t *failoverTestTransport) Start(stage adapter.StartStage) error {
+	return nil
+}
+
+func (t *failoverTestTransport) Close() error {
+	return nil
+}
+
+func (t *failoverTestTransport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	t.exchanged++
+	if t.failing {
+		return nil, E.New("detour failed")
+	}
+	return new(mDNS.Msg).SetReply(message), nil
+}
+
+func newFailoverTestTransport(t *testing.T, detours ...string) (*FailoverTransport, map[string]*failoverTestTransport, *time.Time) {
+	transports := make(map[string]*failoverTestTransport)
+	failover, err := NewFailoverTransport(log.NewNOPFactory().Logger(), C.DNSTypeUDP, "test", detours, func(detour string) (adapter.DNSTransport, error) {
+		transport := &failoverTestTransport{TransportAdapter: NewTransportAdapter(C.DNSTypeUDP, "test", nil)}
+		transports[detour] = transport
+		return transport, nil
+	})
+	require.NoError(t, err)
+	now := time.Unix(0, 0)
+	failover.timeFunc = func() time.Time {
+		return now
+	}
+	return failover, transports, &now
+}
+
+func TestFailoverTransportOrder(t *testing.T) {
+	t.Parallel()
+	failover, transports, _ := newFailoverTestTransport(t, "a", "b", "c")
+	message := new(mDNS.Msg).SetQuestion("example.org.", mDNS.TypeA)
+	_, err := failover.Exchange(context.Background(), message)
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 0, 0}, []int{transports["a"].exchanged, transports["b"].exchanged, transports["c"].exchanged})
+
+	transports["a"].failing = true
+	transports["b"].failing = true
+	_, err = failover.Exchange(context.Background(), message)
+	require.NoError(t, err)
+	require.Equal(t, []int{2, 1, 1}, []int{transports["a"].exchanged, transports["b"].exchanged, transports["c"].exchanged})
+	require.Equal(t, 2, failover.active)
+	require.Equal(t, []int{2, 0, 1}, failover.order())
+
+	transports["c"].failing = true
+	_, err = failover.Exchange(context.Background(), message)
+	require.Error(t, err)
+}
+
+func TestFailoverTransportCooldown(t *testing.T) {
+	t.Parallel()
+	failover, transports, now := newFailoverTestTransport(t, "a", "b")
+	message := new(mDNS.Msg).SetQuestion("example.org.", mDNS.TypeA)
+	transports["a"].failing = true
+	_, err := failover.Exchange(context.Background(), message)
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 0}, failover.order())
+
+	*now = now.Add(failoverCooldown - time.Second)
+	require.Equal(t, []int{1, 0}, failover.order())
+	*now = now.Add(time.Second)
+	require.Equal(t, []int{0, 1}, failover.order())
+
+	transports["a"].failing = false
+	_, err = failover.Exchange(context.Background(), message)
+	require.NoError(t, err)
+	require.Equal(t, 0, failover.active)
+	require.Equal(t, failoverState{}, failover.states[0])
+}
+
+func TestFailoverTransportBackoff(t *testing.T) {
+	t.Parallel()
+	failover, _, now := newFailoverTestTransport(t, "a", "b")
+	expected := []time.Duration{failoverCooldown, 2 * failoverCooldown, 4 * failoverCooldown, 8 * failoverCooldown, failoverMaxCooldown, failoverMaxCooldown}
+	for _, cooldown := range expected {
+		failover.fail(context.Background(), 0, E.New("detour failed"))
+		require.Equal(t, cooldown, failover.states[0].cooldown)
+		require.Equal(t, now.Add(cooldown), failover.states[0].until)
+		// failures of concurrent exchanges within the cooldown do not double it
+		failover.fail(context.Background(), 0, E.New("detour failed"))
+		require.Equal(t, cooldown, failover.states[0].cooldown)
+		*now = failover.states[0].until
+	}
+}
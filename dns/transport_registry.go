@@ -20,10 +20,28 @@ func RegisterTransport[Options any](registry *TransportRegistry, transportType s
 		if rawOptions != nil {
 			options = rawOptions.(*Options)
 		}
+		if detourOptions, isDetour := any(options).(detourListOptions); isDetour && options != nil {
+			detours, err := detourOptions.DetourList()
+			if err != nil {
+				return nil, err
+			}
+			if len(detours) > 0 {
+				return NewFailoverTransport(logger, transportType, tag, detours, func(detour string) (adapter.DNSTransport, error) {
+					detourOptions := *options
+					any(&detourOptions).(detourListOptions).SetDetour(detour)
+					return constructor(ctx, logger, tag, detourOptions)
+				})
+			}
+		}
 		return constructor(ctx, logger, tag, common.PtrValueOrDefault(options))
 	})
 }
 
+type detourListOptions interface {
+	DetourList() ([]string, error)
+	SetDetour(detour string)
+}
+
 var _ adapter.DNSTransportRegistry = (*TransportRegistry)(nil)
 
 type (
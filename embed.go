@@ -0,0 +1,302 @@
+package box
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/dialer"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"golang.org/x/net/proxy"
+)
+
+// The embedding API consists of Create, the Option constructors in this
+// file, Error and the StartContext, CloseContext, Reload, Dialer,
+// RoundTripper, EventBus, SubscribeEvents and SetRuleSet methods. It is kept
+// compatible across releases: options and methods may be added, but existing
+// ones keep their signatures and behavior. The Options struct and New remain
+// available for existing callers, but new fields are only exposed through
+// functional options.
+//
+// Custom protocols, services and rule items are registered to the
+// registries of include.NewRegistries, whose context is then passed to
+// WithContext.
+//
+// To use a single protocol without routing, the clients under client/ can be
+// imported instead, see package github.com/sagernet/sing-box/client.
+
+const (
+	OpDecode = "decode"
+	OpCreate = "create"
+	OpStart  = "start"
+	OpClose  = "close"
+	OpReload = "reload"
+)
+
+// EmbedInbound is the inbound tag and type of connections from Dialer and
+// RoundTripper, for route rules to match them.
+const EmbedInbound = "embed"
+
+// ErrClosed is returned when closing an instance that is already closed.
+var ErrClosed = os.ErrClosed
+
+// Error is returned by the embedding API, Op is the failed operation.
+type Error struct {
+	Op  string
+	Err error
+}
+
+func (e *Error) Error() string {
+	return e.Op + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func wrapError(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Op: op, Err: err}
+}
+
+// Option configures an instance created by Create.
+type Option func(options *Options)
+
+// ServiceFactory creates a service started and closed together with the
+// instance. ctx carries the instance services, such as adapter.Router and
+// log.Factory.
+type ServiceFactory func(ctx context.Context) (adapter.LifecycleService, error)
+
+// StartHook is called after the instance is started, an error closes the
+// instance and fails the start.
+type StartHook func(instance *Box) error
+
+// CloseHook is called before the instance is closed.
+type CloseHook func(instance *Box)
+
+// WithContext sets the parent context, which must carry the protocol
+// registries, for example from include.Context.
+func WithContext(ctx context.Context) Option {
+	return func(options *Options) {
+		options.Context = ctx
+	}
+}
+
+func WithOptions(boxOptions option.Options) Option {
+	return func(options *Options) {
+		options.Options = boxOptions
+		options.config = nil
+	}
+}
+
+// WithConfig sets the configuration as JSON content, decoded with the
+// registries of the context.
+func WithConfig(content []byte) Option {
+	return func(options *Options) {
+		options.config = content
+	}
+}
+
+// WithLogWriter sets the writer of log messages when no log output is
+// configured.
+func WithLogWriter(writer io.Writer) Option {
+	return func(options *Options) {
+		options.logWriter = writer
+	}
+}
+
+func WithService(factory ServiceFactory) Option {
+	return func(options *Options) {
+		options.services = append(options.services, factory)
+	}
+}
+
+func WithStartHook(hook StartHook) Option {
+	return func(options *Options) {
+		options.startHooks = append(options.startHooks, hook)
+	}
+}
+
+func WithCloseHook(hook CloseHook) Option {
+	return func(options *Options) {
+		options.closeHooks = append(options.closeHooks, hook)
+	}
+}
+
+func Create(options ...Option) (*Box, error) {
+	var boxOptions Options
+	for _, option := range options {
+		option(&boxOptions)
+	}
+	return create(boxOptions)
+}
+
+func create(options Options) (*Box, error) {
+	if options.config != nil {
+		ctx := options.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		decoded, err := json.UnmarshalExtendedContext[option.Options](ctx, options.config)
+		if err != nil {
+			return nil, wrapError(OpDecode, err)
+		}
+		options.Options = decoded
+	}
+	instance, err := New(options)
+	if err != nil {
+		return nil, wrapError(OpCreate, err)
+	}
+	return instance, nil
+}
+
+// StartContext starts the instance. If ctx is done first, the start is
+// aborted and the instance closed.
+func (s *Box) StartContext(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Start()
+	}()
+	select {
+	case err := <-done:
+		return wrapError(OpStart, err)
+	case <-ctx.Done():
+		s.cancel()
+		if <-done == nil {
+			s.Close()
+		}
+		return wrapError(OpStart, ctx.Err())
+	}
+}
+
+// CloseContext closes the instance. If ctx is done first, it returns
+// while the close continues in the background.
+func (s *Box) CloseContext(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Close()
+	}()
+	select {
+	case err := <-done:
+		return wrapError(OpClose, err)
+	case <-ctx.Done():
+		return wrapError(OpClose, ctx.Err())
+	}
+}
+
+// Reload creates an instance from the options of this one with options
+// applied on top, then closes this instance and starts the new one, like
+// SIGHUP does for the command line. All components are recreated, except
+// for the event bus.
+//
+// Each instance has its own service registry, so if the new instance can
+// not be created, this instance keeps running untouched. If it fails to
+// start, an instance with the previous options is started again and
+// returned along with the error.
+func (s *Box) Reload(ctx context.Context, options ...Option) (*Box, error) {
+	boxOptions := s.options.clone()
+	for _, option := range options {
+		option(&boxOptions)
+	}
+	boxOptions.eventBus = s.eventBus
+	instance, err := create(boxOptions)
+	if err != nil {
+		return nil, wrapError(OpReload, err)
+	}
+	err = s.CloseContext(ctx)
+	if err != nil && !errors.Is(err, ErrClosed) {
+		instance.Close()
+		return nil, wrapError(OpReload, err)
+	}
+	err = instance.StartContext(ctx)
+	if err != nil {
+		err = wrapError(OpReload, err)
+		previousOptions := s.options.clone()
+		previousOptions.eventBus = s.eventBus
+		previous, rollbackErr := create(previousOptions)
+		if rollbackErr == nil {
+			rollbackErr = previous.Start()
+		}
+		if rollbackErr != nil {
+			return nil, E.Errors(err, E.Cause(rollbackErr, "restore previous instance"))
+		}
+		return previous, err
+	}
+	return instance, nil
+}
+
+func (o Options) clone() Options {
+	o.services = append([]ServiceFactory(nil), o.services...)
+	o.startHooks = append([]StartHook(nil), o.startHooks...)
+	o.closeHooks = append([]CloseHook(nil), o.closeHooks...)
+	return o
+}
+
+// SetRuleSet replaces the rules of a local or inline rule-set, e.g. one
+// built with srs.Builder. The rules are kept until the file of a local
+// rule-set changes or the instance is reloaded.
+func (s *Box) SetRuleSet(tag string, ruleSet option.PlainRuleSet) error {
+	current, loaded := s.router.RuleSet(tag)
+	if !loaded {
+		return E.New("rule-set not found: ", tag)
+	}
+	mutableRuleSet, isMutable := current.(adapter.MutableRuleSet)
+	if !isMutable {
+		return E.New("rule-set ", tag, " is not a local or inline rule-set")
+	}
+	return mutableRuleSet.SetRules(ruleSet.Rules)
+}
+
+// Dialer returns a dialer passing connections through routing as if accepted
+// by an inbound, so that sniffing, route rules and detours apply to them.
+func (s *Box) Dialer() proxy.ContextDialer {
+	return &routedContextDialer{dialer.NewRouted(s.ctx, s.router, EmbedInbound)}
+}
+
+// RoundTripper returns an HTTP transport whose connections are dialed by
+// Dialer. Environment proxy settings are ignored.
+func (s *Box) RoundTripper() http.RoundTripper {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = s.Dialer().DialContext
+	return transport
+}
+
+// SubscribeEvents calls handler in order for events of eventTypes, or all
+// events if none is given, until cancel is called. The subscription is kept
+// across Reload. Events are dropped while handler does not keep up.
+func (s *Box) SubscribeEvents(handler func(event adapter.Event), eventTypes ...string) (cancel func()) {
+	events, cancel := s.eventBus.Subscribe(0, eventTypes...)
+	go func() {
+		for event := range events {
+			handler(event)
+		}
+	}()
+	return cancel
+}
+
+type routedContextDialer struct {
+	dialer *dialer.RoutedDialer
+}
+
+func (d *routedContextDialer) Dial(network string, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+func (d *routedContextDialer) DialContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	destination := M.ParseSocksaddr(address)
+	if !destination.IsValid() || destination.Port == 0 {
+		return nil, E.New("invalid address: ", address)
+	}
+	return d.dialer.DialContext(ctx, network, destination)
+}
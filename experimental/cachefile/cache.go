@@ -30,6 +30,10 @@ var (
 		string(bucketMode),
 		string(bucketRuleSet),
 		string(bucketRDRC),
+		string(bucketSubscription),
+		string(bucketUDPSession),
+		string(bucketWireGuardPeer),
+		string(bucketSSHHostKey),
 	}
 
 	cacheIDDefault = []byte("default")
@@ -44,6 +48,7 @@ type CacheFile struct {
 	storeFakeIP       bool
 	storeRDRC         bool
 	rdrcTimeout       time.Duration
+	storeUDPSession   bool
 	DB                *bbolt.DB
 	saveMetadataTimer *time.Timer
 	saveFakeIPAccess  sync.RWMutex
@@ -80,16 +85,17 @@ func New(ctx context.Context, options option.CacheFileOptions) *CacheFile {
 		}
 	}
 	return &CacheFile{
-		ctx:          ctx,
-		path:         filemanager.BasePath(ctx, path),
-		cacheID:      cacheIDBytes,
-		storeFakeIP:  options.StoreFakeIP,
-		storeRDRC:    options.StoreRDRC,
-		rdrcTimeout:  rdrcTimeout,
-		saveDomain:   make(map[netip.Addr]string),
-		saveAddress4: make(map[string]netip.Addr),
-		saveAddress6: make(map[string]netip.Addr),
-		saveRDRC:     make(map[saveRDRCCacheKey]bool),
+		ctx:             ctx,
+		path:            filemanager.BasePath(ctx, path),
+		cacheID:         cacheIDBytes,
+		storeFakeIP:     options.StoreFakeIP,
+		storeRDRC:       options.StoreRDRC,
+		rdrcTimeout:     rdrcTimeout,
+		storeUDPSession: options.StoreUDPSession,
+		saveDomain:      make(map[netip.Addr]string),
+		saveAddress4:    make(map[string]netip.Addr),
+		saveAddress6:    make(map[string]netip.Addr),
+		saveRDRC:        make(map[saveRDRCCacheKey]bool),
 	}
 }
 
@@ -0,0 +1,32 @@
+package cachefile
+
+import (
+	"bytes"
+
+	"github.com/sagernet/bbolt"
+)
+
+var bucketSSHHostKey = []byte("ssh_host_key")
+
+func (c *CacheFile) LoadSSHHostKey(host string) []byte {
+	var hostKey []byte
+	c.DB.View(func(t *bbolt.Tx) error {
+		bucket := c.bucket(t, bucketSSHHostKey)
+		if bucket == nil {
+			return nil
+		}
+		hostKey = bytes.Clone(bucket.Get([]byte(host)))
+		return nil
+	})
+	return hostKey
+}
+
+func (c *CacheFile) SaveSSHHostKey(host string, hostKey []byte) error {
+	return c.DB.Batch(func(t *bbolt.Tx) error {
+		bucket, err := c.createBucket(t, bucketSSHHostKey)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(host), hostKey)
+	})
+}
@@ -0,0 +1,43 @@
+package cachefile
+
+import (
+	"os"
+
+	"github.com/sagernet/bbolt"
+	"github.com/sagernet/sing-box/adapter"
+)
+
+var bucketSubscription = []byte("subscription")
+
+func (c *CacheFile) LoadSubscription(tag string) *adapter.SavedBinary {
+	var savedSubscription adapter.SavedBinary
+	err := c.DB.View(func(t *bbolt.Tx) error {
+		bucket := c.bucket(t, bucketSubscription)
+		if bucket == nil {
+			return os.ErrNotExist
+		}
+		subscriptionBinary := bucket.Get([]byte(tag))
+		if len(subscriptionBinary) == 0 {
+			return os.ErrInvalid
+		}
+		return savedSubscription.UnmarshalBinary(subscriptionBinary)
+	})
+	if err != nil {
+		return nil
+	}
+	return &savedSubscription
+}
+
+func (c *CacheFile) SaveSubscription(tag string, subscription *adapter.SavedBinary) error {
+	return c.DB.Batch(func(t *bbolt.Tx) error {
+		bucket, err := c.createBucket(t, bucketSubscription)
+		if err != nil {
+			return err
+		}
+		subscriptionBinary, err := subscription.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(tag), subscriptionBinary)
+	})
+}
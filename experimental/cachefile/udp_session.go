@@ -0,0 +1,185 @@
+package cachefile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/sagernet/bbolt"
+	"github.com/sagernet/sing-box/adapter"
+	M "github.com/sagernet/sing/common/metadata"
+	"github.com/sagernet/sing/common/varbin"
+)
+
+var (
+	bucketUDPSession    = []byte("udp_session")
+	bucketWireGuardPeer = []byte("wireguard_peer")
+)
+
+func (c *CacheFile) StoreUDPSession() bool {
+	return c.storeUDPSession
+}
+
+func (c *CacheFile) LoadUDPSessions() []adapter.SavedUDPSession {
+	var sessions []adapter.SavedUDPSession
+	c.DB.Update(func(t *bbolt.Tx) error {
+		bucket := c.bucket(t, bucketUDPSession)
+		if bucket == nil {
+			return nil
+		}
+		bucket.ForEach(func(key, value []byte) error {
+			session, err := unmarshalUDPSession(value)
+			if err == nil {
+				sessions = append(sessions, session)
+			}
+			return nil
+		})
+		if c.cacheID == nil {
+			return t.DeleteBucket(bucketUDPSession)
+		}
+		return t.Bucket(c.cacheID).DeleteBucket(bucketUDPSession)
+	})
+	return sessions
+}
+
+func (c *CacheFile) SaveUDPSessions(sessions []adapter.SavedUDPSession) error {
+	return c.DB.Batch(func(t *bbolt.Tx) error {
+		bucket, err := c.createBucket(t, bucketUDPSession)
+		if err != nil {
+			return err
+		}
+		for _, session := range sessions {
+			sessionBinary, err := marshalUDPSession(session)
+			if err != nil {
+				return err
+			}
+			err = bucket.Put([]byte(session.Inbound+"\x00"+session.Source.String()+"\x00"+session.Destination.String()), sessionBinary)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func marshalUDPSession(session adapter.SavedUDPSession) ([]byte, error) {
+	var buffer bytes.Buffer
+	err := binary.Write(&buffer, binary.BigEndian, uint8(1))
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range []string{session.Inbound, session.Source.String(), session.Destination.String(), session.Outbound} {
+		err = varbin.Write(&buffer, binary.BigEndian, []byte(value))
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = binary.Write(&buffer, binary.BigEndian, session.LocalPort)
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Write(&buffer, binary.BigEndian, session.LastActive.UnixNano())
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Write(&buffer, binary.BigEndian, int64(session.Timeout))
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func unmarshalUDPSession(data []byte) (adapter.SavedUDPSession, error) {
+	var session adapter.SavedUDPSession
+	reader := bytes.NewReader(data)
+	var version uint8
+	err := binary.Read(reader, binary.BigEndian, &version)
+	if err != nil {
+		return session, err
+	}
+	values := make([]string, 4)
+	for i := range values {
+		var value []byte
+		err = varbin.Read(reader, binary.BigEndian, &value)
+		if err != nil {
+			return session, err
+		}
+		values[i] = string(value)
+	}
+	session.Inbound = values[0]
+	session.Source = M.ParseSocksaddr(values[1])
+	session.Destination = M.ParseSocksaddr(values[2])
+	session.Outbound = values[3]
+	err = binary.Read(reader, binary.BigEndian, &session.LocalPort)
+	if err != nil {
+		return session, err
+	}
+	var lastActive, timeout int64
+	err = binary.Read(reader, binary.BigEndian, &lastActive)
+	if err != nil {
+		return session, err
+	}
+	err = binary.Read(reader, binary.BigEndian, &timeout)
+	if err != nil {
+		return session, err
+	}
+	session.LastActive = time.Unix(0, lastActive)
+	session.Timeout = time.Duration(timeout)
+	return session, nil
+}
+
+func (c *CacheFile) LoadWireGuardPeerEndpoints(tag string) map[string]netip.AddrPort {
+	endpoints := make(map[string]netip.AddrPort)
+	c.DB.View(func(t *bbolt.Tx) error {
+		bucket := c.bucket(t, bucketWireGuardPeer)
+		if bucket == nil {
+			return nil
+		}
+		prefix := []byte(tag + "\x00")
+		cursor := bucket.Cursor()
+		for key, value := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, value = cursor.Next() {
+			var endpoint netip.AddrPort
+			if endpoint.UnmarshalBinary(value) == nil {
+				endpoints[string(key[len(prefix):])] = endpoint
+			}
+		}
+		return nil
+	})
+	return endpoints
+}
+
+func (c *CacheFile) SaveWireGuardPeerEndpoints(tag string, endpoints map[string]netip.AddrPort) error {
+	return c.DB.Batch(func(t *bbolt.Tx) error {
+		bucket, err := c.createBucket(t, bucketWireGuardPeer)
+		if err != nil {
+			return err
+		}
+		prefix := tag + "\x00"
+		var staleKeys [][]byte
+		bucket.ForEach(func(key, value []byte) error {
+			if strings.HasPrefix(string(key), prefix) {
+				staleKeys = append(staleKeys, bytes.Clone(key))
+			}
+			return nil
+		})
+		for _, key := range staleKeys {
+			err = bucket.Delete(key)
+			if err != nil {
+				return err
+			}
+		}
+		for publicKey, endpoint := range endpoints {
+			endpointBinary, err := endpoint.MarshalBinary()
+			if err != nil {
+				return err
+			}
+			err = bucket.Put([]byte(prefix+publicKey), endpointBinary)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -35,15 +35,18 @@ func CalculateClashModeList(options option.Options) []string {
 	predefinedOrder := []string{
 		"Rule", "Global", "Direct",
 	}
+	// custom modes are listed first in the configured order
+	customModes := common.FilterNotDefault(common.Uniq(common.PtrValueOrDefault(common.PtrValueOrDefault(options.Experimental).ClashAPI).Modes))
 	var newClashModes []string
 	for _, mode := range clashModes {
-		if !common.Contains(predefinedOrder, mode) {
+		if !common.Contains(predefinedOrder, mode) && !common.Contains(customModes, mode) {
 			newClashModes = append(newClashModes, mode)
 		}
 	}
 	sort.Strings(newClashModes)
+	newClashModes = append(customModes, newClashModes...)
 	for _, mode := range predefinedOrder {
-		if common.Contains(clashModes, mode) {
+		if common.Contains(clashModes, mode) && !common.Contains(customModes, mode) {
 			newClashModes = append(newClashModes, mode)
 		}
 	}
@@ -55,9 +58,7 @@ func extraClashModeFromRule(rules []option.Rule) []string {
 	for _, rule := range rules {
 		switch rule.Type {
 		case C.RuleTypeDefault:
-			if rule.DefaultOptions.ClashMode != "" {
-				clashMode = append(clashMode, rule.DefaultOptions.ClashMode)
-			}
+			clashMode = append(clashMode, rule.DefaultOptions.ClashMode...)
 		case C.RuleTypeLogical:
 			clashMode = append(clashMode, extraClashModeFromRule(rule.LogicalOptions.Rules)...)
 		}
@@ -70,9 +71,7 @@ func extraClashModeFromDNSRule(rules []option.DNSRule) []string {
 	for _, rule := range rules {
 		switch rule.Type {
 		case C.RuleTypeDefault:
-			if rule.DefaultOptions.ClashMode != "" {
-				clashMode = append(clashMode, rule.DefaultOptions.ClashMode)
-			}
+			clashMode = append(clashMode, rule.DefaultOptions.ClashMode...)
 		case C.RuleTypeLogical:
 			clashMode = append(clashMode, extraClashModeFromDNSRule(rule.LogicalOptions.Rules)...)
 		}
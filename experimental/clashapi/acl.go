@@ -0,0 +1,130 @@
+package clashapi
+
+import (
+	"net/http"
+	"net/netip"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/acl"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+func aclRouter(aclManager adapter.InboundACLManager) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/", getACLs(aclManager))
+	r.Route("/{inbound}", func(r chi.Router) {
+		r.Get("/", getACL(aclManager))
+		r.Post("/", addACL(aclManager))
+		r.Delete("/", removeACL(aclManager))
+	})
+	return r
+}
+
+func getACLs(aclManager adapter.InboundACLManager) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		acls := render.M{}
+		if aclManager != nil {
+			for inbound, inboundACL := range aclManager.List() {
+				acls[inbound] = inboundACL.Entries()
+			}
+		}
+		render.JSON(w, r, render.M{
+			"acls": acls,
+		})
+	}
+}
+
+func getACL(aclManager adapter.InboundACLManager) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		inboundACL, loaded := findACL(w, r, aclManager)
+		if !loaded {
+			return
+		}
+		render.JSON(w, r, inboundACL.Entries())
+	}
+}
+
+// addACL adds entries to the runtime lists of an inbound ACL, which are kept
+// until the inbound is restarted.
+func addACL(aclManager adapter.InboundACLManager) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		inboundACL, loaded := findACL(w, r, aclManager)
+		if !loaded {
+			return
+		}
+		allow, deny, loaded := decodeACLEntries(w, r)
+		if !loaded {
+			return
+		}
+		inboundACL.AddRuntime(allow, deny)
+		render.JSON(w, r, inboundACL.Entries())
+	}
+}
+
+func removeACL(aclManager adapter.InboundACLManager) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		inboundACL, loaded := findACL(w, r, aclManager)
+		if !loaded {
+			return
+		}
+		allow, deny, loaded := decodeACLEntries(w, r)
+		if !loaded {
+			return
+		}
+		inboundACL.RemoveRuntime(allow, deny)
+		render.JSON(w, r, inboundACL.Entries())
+	}
+}
+
+func findACL(w http.ResponseWriter, r *http.Request, aclManager adapter.InboundACLManager) (adapter.InboundACL, bool) {
+	var (
+		inboundACL adapter.InboundACL
+		loaded     bool
+	)
+	if aclManager != nil {
+		inboundACL, loaded = aclManager.Get(getEscapeParam(r, "inbound"))
+	}
+	if !loaded {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, ErrNotFound)
+	}
+	return inboundACL, loaded
+}
+
+func decodeACLEntries(w http.ResponseWriter, r *http.Request) ([]netip.Prefix, []netip.Prefix, bool) {
+	var body struct {
+		Allow []string `json:"allow"`
+		Deny  []string `json:"deny"`
+	}
+	err := render.DecodeJSON(r.Body, &body)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, ErrBadRequest)
+		return nil, nil, false
+	}
+	allow, err := parseACLEntries(body.Allow)
+	if err == nil {
+		var deny []netip.Prefix
+		deny, err = parseACLEntries(body.Deny)
+		if err == nil {
+			return allow, deny, true
+		}
+	}
+	render.Status(r, http.StatusBadRequest)
+	render.JSON(w, r, newError(err.Error()))
+	return nil, nil, false
+}
+
+func parseACLEntries(entries []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, entry := range entries {
+		prefix, err := acl.ParsePrefix(entry)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, nil
+}
@@ -0,0 +1,39 @@
+package clashapi
+
+import (
+	"net/http"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+func budgetRouter(router adapter.Router) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/", getBudgets(router))
+	return r
+}
+
+func getBudgets(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, r, render.M{
+			"budgets": common.Map(router.Budgets(), budgetInfo),
+		})
+	}
+}
+
+func budgetInfo(stats adapter.BudgetStats) render.M {
+	info := render.M{
+		"subsystem": stats.Subsystem,
+		"limit":     stats.Limit,
+		"used":      stats.Used,
+		"peak":      stats.Peak,
+		"rejected":  stats.Rejected,
+	}
+	if stats.Key != "" {
+		info["key"] = stats.Key
+	}
+	return info
+}
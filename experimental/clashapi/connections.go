@@ -2,6 +2,7 @@ package clashapi
 
 import (
 	"bytes"
+	"context"
 	"net/http"
 	"strconv"
 	"time"
@@ -17,10 +18,12 @@ import (
 	"github.com/gofrs/uuid/v5"
 )
 
-func connectionRouter(router adapter.Router, trafficManager *trafficontrol.Manager) http.Handler {
+func connectionRouter(ctx context.Context, router adapter.Router, trafficManager *trafficontrol.Manager, dumpPath string) http.Handler {
 	r := chi.NewRouter()
 	r.Get("/", getConnections(trafficManager))
 	r.Delete("/", closeAllConnections(router, trafficManager))
+	r.Get("/dump", getConnectionDump(router, trafficManager))
+	r.Post("/dump", writeConnectionDump(ctx, router, trafficManager, dumpPath))
 	r.Delete("/{id}", closeConnection(trafficManager))
 	return r
 }
@@ -99,3 +102,30 @@ func closeAllConnections(router adapter.Router, trafficManager *trafficontrol.Ma
 		render.NoContent(w, r)
 	}
 }
+
+func getConnectionDump(router adapter.Router, trafficManager *trafficontrol.Manager) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, r, trafficManager.Dump("api", router.Budgets()))
+	}
+}
+
+// writeConnectionDump writes the dump to the configured path, so that it
+// is kept with the log for later debugging.
+func writeConnectionDump(ctx context.Context, router adapter.Router, trafficManager *trafficontrol.Manager, dumpPath string) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if dumpPath == "" {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError("connection_dump_path not configured"))
+			return
+		}
+		err := trafficManager.WriteDump(ctx, dumpPath, "api", router.Budgets())
+		if err != nil {
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		render.JSON(w, r, render.M{
+			"path": dumpPath,
+		})
+	}
+}
@@ -3,6 +3,7 @@ package clashapi
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"github.com/sagernet/sing-box/adapter"
 	C "github.com/sagernet/sing-box/constant"
@@ -37,19 +38,29 @@ func queryDNS(router adapter.DNSRouter) func(w http.ResponseWriter, r *http.Requ
 		ctx, cancel := context.WithTimeout(context.Background(), C.DNSTimeout)
 		defer cancel()
 
+		var trace adapter.DNSTrace
+		ctx = adapter.WithDNSTrace(ctx, &trace)
 		msg := dns.Msg{}
 		msg.SetQuestion(dns.Fqdn(name), qType)
+		start := time.Now()
 		resp, err := router.Exchange(ctx, &msg, adapter.DNSQueryOptions{})
+		elapsed := time.Since(start)
 		if err != nil {
 			render.Status(r, http.StatusInternalServerError)
 			render.JSON(w, r, newError(err.Error()))
 			return
 		}
 
+		server := trace.Transport
+		if server == "" {
+			server = "internal"
+		}
 		responseData := render.M{
 			"Status":   resp.Rcode,
 			"Question": resp.Question,
-			"Server":   "internal",
+			"Server":   server,
+			"Cached":   trace.Cached,
+			"Elapsed":  elapsed.Milliseconds(),
 			"TC":       resp.Truncated,
 			"RD":       resp.RecursionDesired,
 			"RA":       resp.RecursionAvailable,
@@ -67,6 +78,16 @@ func queryDNS(router adapter.DNSRouter) func(w http.ResponseWriter, r *http.Requ
 			}
 		}
 
+		if len(trace.Matches) > 0 {
+			responseData["Rules"] = common.Map(trace.Matches, func(it adapter.DNSTraceMatch) render.M {
+				return render.M{
+					"index":  it.Index,
+					"rule":   it.Rule,
+					"action": it.Action,
+				}
+			})
+		}
+
 		if len(resp.Answer) > 0 {
 			responseData["Answer"] = common.Map(resp.Answer, rr2Json)
 		}
@@ -0,0 +1,67 @@
+package clashapi
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common/json/badoption"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+func drainRouter(router adapter.Router) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/", getDrain(router))
+	r.Post("/", startDrain(router))
+	return r
+}
+
+func getDrain(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, r, drainInfo(router.DrainStatus()))
+	}
+}
+
+// startDrain rejects new connections of the inbounds and exits the instance
+// after existing ones are closed or the timeout elapsed, the drain options
+// are used for empty fields.
+func startDrain(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Inbounds []string           `json:"inbounds"`
+			Timeout  badoption.Duration `json:"timeout"`
+		}
+		if r.ContentLength != 0 {
+			err := render.DecodeJSON(r.Body, &body)
+			if err != nil {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, ErrBadRequest)
+				return
+			}
+		}
+		err := router.Drain(body.Inbounds, time.Duration(body.Timeout))
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		render.JSON(w, r, drainInfo(router.DrainStatus()))
+	}
+}
+
+func drainInfo(status adapter.DrainStatus) render.M {
+	info := render.M{
+		"draining": status.Draining,
+	}
+	if status.Draining {
+		info["finished"] = status.Finished
+		info["inbounds"] = status.Inbounds
+		info["active"] = status.Active
+		info["total"] = status.Total
+		info["started_at"] = status.StartedAt.Format(time.RFC3339)
+		info["deadline"] = status.Deadline.Format(time.RFC3339)
+	}
+	return info
+}
@@ -0,0 +1,83 @@
+package clashapi
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/json"
+
+	"github.com/go-chi/render"
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+)
+
+func getEvents(eventBus adapter.EventBus) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if eventBus == nil {
+			render.Status(r, http.StatusNoContent)
+			return
+		}
+		var eventTypes []string
+		if typesText := r.URL.Query().Get("types"); typesText != "" {
+			eventTypes = common.Filter(strings.Split(typesText, ","), func(it string) bool {
+				return it != ""
+			})
+		}
+		streamEvents(w, r, eventBus, eventTypes, nil)
+	}
+}
+
+// streamEvents writes initial and then events of eventTypes from the event
+// bus as JSON lines, or as WebSocket text messages if requested.
+func streamEvents(w http.ResponseWriter, r *http.Request, eventBus adapter.EventBus, eventTypes []string, initial []adapter.Event) {
+	var (
+		conn net.Conn
+		err  error
+	)
+	if r.Header.Get("Upgrade") == "websocket" {
+		conn, _, _, err = ws.UpgradeHTTP(r, w)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+	}
+	events, cancel := eventBus.Subscribe(0, eventTypes...)
+	defer cancel()
+	if conn == nil {
+		w.Header().Set("Content-Type", "application/json")
+		render.Status(r, http.StatusOK)
+		w.(http.Flusher).Flush()
+	}
+	buf := &bytes.Buffer{}
+	for {
+		var event adapter.Event
+		if len(initial) > 0 {
+			event = initial[0]
+			initial = initial[1:]
+		} else {
+			select {
+			case <-r.Context().Done():
+				return
+			case event = <-events:
+			}
+		}
+		buf.Reset()
+		err = json.NewEncoder(buf).Encode(event)
+		if err != nil {
+			return
+		}
+		if conn == nil {
+			_, err = w.Write(buf.Bytes())
+			w.(http.Flusher).Flush()
+		} else {
+			err = wsutil.WriteServerText(conn, buf.Bytes())
+		}
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package clashapi
+
+import (
+	"net/http"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+func portMappingRouter(networkManager adapter.NetworkManager) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/", getPortMappings(networkManager))
+	return r
+}
+
+func getPortMappings(networkManager adapter.NetworkManager) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, r, render.M{
+			"mappings": common.Map(networkManager.PortMappings(), portMappingInfo),
+		})
+	}
+}
+
+func portMappingInfo(mapping adapter.PortMapping) render.M {
+	info := render.M{
+		"network": mapping.Network,
+		"listen":  mapping.Listen.String(),
+	}
+	if mapping.Error != "" {
+		info["error"] = mapping.Error
+	}
+	if mapping.ExternalAddress.IsValid() {
+		info["protocol"] = mapping.Protocol
+		info["gateway"] = mapping.Gateway.String()
+		info["external_address"] = mapping.ExternalAddress.String()
+		info["expire"] = mapping.Expire
+	}
+	return info
+}
@@ -3,50 +3,134 @@ package clashapi
 import (
 	"context"
 	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/urltest"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/batch"
+	"github.com/sagernet/sing/common/json/badjson"
+	"github.com/sagernet/sing/service"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 )
 
-func proxyProviderRouter() http.Handler {
+func proxyProviderRouter(server *Server) http.Handler {
 	r := chi.NewRouter()
-	r.Get("/", getProviders)
+	r.Get("/", getProviders(server))
 
 	r.Route("/{name}", func(r chi.Router) {
-		r.Use(parseProviderName, findProviderByName)
-		r.Get("/", getProvider)
+		r.Use(parseProviderName, findProviderByName(server))
+		r.Get("/", getProvider(server))
 		r.Put("/", updateProvider)
-		r.Get("/healthcheck", healthCheckProvider)
+		r.Get("/healthcheck", healthCheckProvider(server))
 	})
 	return r
 }
 
-func getProviders(w http.ResponseWriter, r *http.Request) {
-	render.JSON(w, r, render.M{
-		"providers": render.M{},
-	})
+func subscriptions(server *Server) []adapter.Subscription {
+	serviceManager := service.FromContext[adapter.ServiceManager](server.ctx)
+	if serviceManager == nil {
+		return nil
+	}
+	var subscriptionList []adapter.Subscription
+	for _, it := range serviceManager.Services() {
+		if subscription, isSubscription := it.(adapter.Subscription); isSubscription {
+			subscriptionList = append(subscriptionList, subscription)
+		}
+	}
+	return subscriptionList
 }
 
-func getProvider(w http.ResponseWriter, r *http.Request) {
-	/*provider := r.Context().Value(CtxKeyProvider).(provider.ProxyProvider)
-	render.JSON(w, r, provider)*/
-	render.NoContent(w, r)
+func providerInfo(server *Server, subscription adapter.Subscription) *badjson.JSONObject {
+	var info badjson.JSONObject
+	info.Put("name", subscription.Tag())
+	info.Put("type", "Proxy")
+	info.Put("vehicleType", "HTTP")
+	proxies := make([]*badjson.JSONObject, 0)
+	for _, tag := range subscription.Outbounds() {
+		detour, loaded := server.outbound.Outbound(tag)
+		if !loaded {
+			continue
+		}
+		proxies = append(proxies, proxyInfo(server, detour))
+	}
+	info.Put("proxies", proxies)
+	info.Put("updatedAt", subscription.UpdatedAt())
+	if subscriptionInfo := subscription.Info(); subscriptionInfo != nil {
+		info.Put("subscriptionInfo", subscriptionInfo)
+	}
+	if err := subscription.LastError(); err != nil {
+		info.Put("error", err.Error())
+	}
+	return &info
+}
+
+func getProviders(server *Server) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		providerMap := render.M{}
+		for _, subscription := range subscriptions(server) {
+			providerMap[subscription.Tag()] = providerInfo(server, subscription)
+		}
+		render.JSON(w, r, render.M{
+			"providers": providerMap,
+		})
+	}
+}
+
+func getProvider(server *Server) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		subscription := r.Context().Value(CtxKeyProvider).(adapter.Subscription)
+		render.JSON(w, r, providerInfo(server, subscription))
+	}
 }
 
 func updateProvider(w http.ResponseWriter, r *http.Request) {
-	/*provider := r.Context().Value(CtxKeyProvider).(provider.ProxyProvider)
-	if err := provider.Update(); err != nil {
+	subscription := r.Context().Value(CtxKeyProvider).(adapter.Subscription)
+	err := subscription.Update(r.Context())
+	if err != nil {
 		render.Status(r, http.StatusServiceUnavailable)
 		render.JSON(w, r, newError(err.Error()))
 		return
-	}*/
+	}
 	render.NoContent(w, r)
 }
 
-func healthCheckProvider(w http.ResponseWriter, r *http.Request) {
-	/*provider := r.Context().Value(CtxKeyProvider).(provider.ProxyProvider)
-	provider.HealthCheck()*/
-	render.NoContent(w, r)
+func healthCheckProvider(server *Server) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		subscription := r.Context().Value(CtxKeyProvider).(adapter.Subscription)
+		ctx, cancel := context.WithTimeout(r.Context(), C.TCPTimeout)
+		defer cancel()
+		b, _ := batch.New(ctx, batch.WithConcurrencyNum[any](10))
+		var access sync.Mutex
+		result := make(map[string]uint16)
+		for _, tag := range subscription.Outbounds() {
+			detour, loaded := server.outbound.Outbound(tag)
+			if !loaded {
+				continue
+			}
+			b.Go(tag, func() (any, error) {
+				delay, err := urltest.URLTest(ctx, "", detour)
+				if err != nil {
+					server.urlTestHistory.DeleteURLTestHistory(tag)
+				} else {
+					server.urlTestHistory.StoreURLTestHistory(tag, &adapter.URLTestHistory{
+						Time:  time.Now(),
+						Delay: delay,
+					})
+					access.Lock()
+					result[tag] = delay
+					access.Unlock()
+				}
+				return nil, nil
+			})
+		}
+		b.Wait()
+		render.JSON(w, r, result)
+	}
 }
 
 func parseProviderName(next http.Handler) http.Handler {
@@ -57,18 +141,20 @@ func parseProviderName(next http.Handler) http.Handler {
 	})
 }
 
-func findProviderByName(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		/*name := r.Context().Value(CtxKeyProviderName).(string)
-		providers := tunnel.ProxyProviders()
-		provider, exist := providers[name]
-		if !exist {*/
-		render.Status(r, http.StatusNotFound)
-		render.JSON(w, r, ErrNotFound)
-		//return
-		//}
-
-		// ctx := context.WithValue(r.Context(), CtxKeyProvider, provider)
-		// next.ServeHTTP(w, r.WithContext(ctx))
-	})
+func findProviderByName(server *Server) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			name := r.Context().Value(CtxKeyProviderName).(string)
+			subscription := common.Find(subscriptions(server), func(it adapter.Subscription) bool {
+				return it.Tag() == name
+			})
+			if subscription == nil {
+				render.Status(r, http.StatusNotFound)
+				render.JSON(w, r, ErrNotFound)
+				return
+			}
+			ctx := context.WithValue(r.Context(), CtxKeyProvider, subscription)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
 }
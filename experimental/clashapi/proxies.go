@@ -80,6 +80,14 @@ func proxyInfo(server *Server, detour adapter.Outbound) *badjson.JSONObject {
 		info.Put("now", group.Now())
 		info.Put("all", group.All())
 	}
+	if lazyOutbound, isLazy := detour.(adapter.LazyOutbound); isLazy {
+		if state, err := lazyOutbound.LazyState(); state != "" {
+			info.Put("lazy", state)
+			if err != nil {
+				info.Put("lazy_error", err.Error())
+			}
+		}
+	}
 	return &info
 }
 
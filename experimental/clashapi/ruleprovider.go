@@ -2,10 +2,12 @@ package clashapi
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"strings"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/srs"
 	C "github.com/sagernet/sing-box/constant"
 	F "github.com/sagernet/sing/common/format"
 	"github.com/sagernet/sing/common/json/badjson"
@@ -60,8 +62,36 @@ func getRuleProvider(w http.ResponseWriter, r *http.Request) {
 	render.JSON(w, r, ruleSetInfo(ruleSet))
 }
 
+// updateRuleProvider updates the rule-set, or replaces the rules of a local
+// or inline rule-set with the rule-set in binary or source format in the
+// request body.
 func updateRuleProvider(w http.ResponseWriter, r *http.Request) {
 	ruleSet := r.Context().Value(CtxKeyProvider).(adapter.RuleSet)
+	content, err := io.ReadAll(r.Body)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, newError(err.Error()))
+		return
+	}
+	if len(content) > 0 {
+		mutableRuleSet, isMutable := ruleSet.(adapter.MutableRuleSet)
+		if !isMutable {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError("rule-set is not a local or inline rule-set"))
+			return
+		}
+		plainRuleSet, err := srs.Decode(content)
+		if err == nil {
+			err = mutableRuleSet.SetRules(plainRuleSet.Rules)
+		}
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		render.NoContent(w, r)
+		return
+	}
 	if err := ruleSet.Update(r.Context()); err != nil {
 		render.Status(r, http.StatusInternalServerError)
 		render.JSON(w, r, newError(err.Error()))
@@ -50,6 +50,7 @@ type Server struct {
 	httpServer     *http.Server
 	trafficManager *trafficontrol.Manager
 	urlTestHistory adapter.URLTestHistoryStorage
+	taskHistory    *taskHistory
 	logDebug       bool
 
 	mode           string
@@ -60,6 +61,9 @@ type Server struct {
 	externalUI               string
 	externalUIDownloadURL    string
 	externalUIDownloadDetour string
+
+	connectionDumpPath  string
+	unregisterFatalHook func()
 }
 
 func NewServer(ctx context.Context, logFactory log.ObservableFactory, options option.ClashAPIOptions) (adapter.ClashServer, error) {
@@ -83,6 +87,11 @@ func NewServer(ctx context.Context, logFactory log.ObservableFactory, options op
 		externalUIDownloadURL:    options.ExternalUIDownloadURL,
 		externalUIDownloadDetour: options.ExternalUIDownloadDetour,
 	}
+	if options.ConnectionDumpPath != "" {
+		s.connectionDumpPath = filemanager.BasePath(ctx, os.ExpandEnv(options.ConnectionDumpPath))
+	}
+	eventBus := service.FromContext[adapter.EventBus](ctx)
+	s.taskHistory = newTaskHistory(eventBus)
 	s.urlTestHistory = service.FromContext[adapter.URLTestHistoryStorage](ctx)
 	if s.urlTestHistory == nil {
 		s.urlTestHistory = urltest.NewHistoryStorage()
@@ -117,17 +126,27 @@ func NewServer(ctx context.Context, logFactory log.ObservableFactory, options op
 		r.Get("/", hello(options.ExternalUI != ""))
 		r.Get("/logs", getLogs(logFactory))
 		r.Get("/traffic", traffic(trafficManager))
+		r.Get("/events", getEvents(eventBus))
 		r.Get("/version", version)
 		r.Mount("/configs", configRouter(s, logFactory))
 		r.Mount("/proxies", proxyRouter(s, s.router))
 		r.Mount("/rules", ruleRouter(s.router))
-		r.Mount("/connections", connectionRouter(s.router, trafficManager))
-		r.Mount("/providers/proxies", proxyProviderRouter())
+		r.Mount("/connections", connectionRouter(s.ctx, s.router, trafficManager, s.connectionDumpPath))
+		r.Mount("/providers/proxies", proxyProviderRouter(s))
 		r.Mount("/providers/rules", ruleProviderRouter(s.router))
 		r.Mount("/script", scriptRouter())
 		r.Mount("/profile", profileRouter())
 		r.Mount("/cache", cacheRouter(ctx))
 		r.Mount("/dns", dnsRouter(s.dnsRouter))
+		r.Mount("/tun", tunRouter(ctx, service.FromContext[adapter.InboundManager](ctx)))
+		r.Mount("/acl", aclRouter(service.FromContext[adapter.InboundACLManager](ctx)))
+		r.Mount("/portmap", portMappingRouter(service.FromContext[adapter.NetworkManager](ctx)))
+		r.Mount("/udp_sessions", udpSessionRouter(service.FromContext[adapter.ConnectionManager](ctx)))
+		r.Mount("/budgets", budgetRouter(s.router))
+		r.Mount("/tasks", taskRouter(eventBus, s.taskHistory))
+		r.Mount("/users", userRouter(s.router))
+		r.Mount("/drain", drainRouter(s.router))
+		r.Mount("/wireguard", wireGuardRouter(ctx, service.FromContext[adapter.EndpointManager](ctx)))
 		if service.FromContext[platform.Interface](ctx) == nil {
 			r.Mount("/restart", restartRouter(s.ctx, logFactory))
 		}
@@ -151,6 +170,10 @@ func (s *Server) Name() string {
 func (s *Server) Start(stage adapter.StartStage) error {
 	switch stage {
 	case adapter.StartStateStart:
+		s.trafficManager.Start()
+		if s.connectionDumpPath != "" {
+			s.unregisterFatalHook = log.RegisterFatalHook(s.dumpConnectionsOnFatal)
+		}
 		cacheFile := service.FromContext[adapter.CacheFile](s.ctx)
 		if cacheFile != nil {
 			mode := cacheFile.LoadMode()
@@ -192,13 +215,26 @@ func (s *Server) Start(stage adapter.StartStage) error {
 }
 
 func (s *Server) Close() error {
+	if s.unregisterFatalHook != nil {
+		s.unregisterFatalHook()
+	}
 	return common.Close(
 		common.PtrOrNil(s.httpServer),
 		s.trafficManager,
 		s.urlTestHistory,
+		s.taskHistory,
 	)
 }
 
+func (s *Server) dumpConnectionsOnFatal(message string) {
+	err := s.trafficManager.WriteDump(s.ctx, s.connectionDumpPath, "fatal: "+message, s.router.Budgets())
+	if err != nil {
+		s.logger.Error(E.Cause(err, "dump connections"))
+	} else {
+		s.logger.Info("connections dumped to ", s.connectionDumpPath)
+	}
+}
+
 func (s *Server) Mode() string {
 	return s.mode
 }
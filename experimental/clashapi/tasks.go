@@ -0,0 +1,92 @@
+package clashapi
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/sagernet/sing-box/adapter"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+var taskEventTypes = []string{adapter.EventTaskStarted, adapter.EventTaskProgress, adapter.EventTaskFinished}
+
+// taskHistory keeps the last event of each background task, so that clients
+// connecting later see tasks that are running or failed.
+type taskHistory struct {
+	access sync.Mutex
+	tasks  map[[2]string]adapter.Event
+	cancel func()
+}
+
+func newTaskHistory(eventBus adapter.EventBus) *taskHistory {
+	history := &taskHistory{
+		tasks: make(map[[2]string]adapter.Event),
+	}
+	if eventBus == nil {
+		return history
+	}
+	events, cancel := eventBus.Subscribe(16, taskEventTypes...)
+	done := make(chan struct{})
+	history.cancel = func() {
+		cancel()
+		close(done)
+	}
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case event := <-events:
+				history.access.Lock()
+				history.tasks[[2]string{event.Task, event.Tag}] = event
+				history.access.Unlock()
+			}
+		}
+	}()
+	return history
+}
+
+func (h *taskHistory) List() []adapter.Event {
+	h.access.Lock()
+	tasks := make([]adapter.Event, 0, len(h.tasks))
+	for _, event := range h.tasks {
+		tasks = append(tasks, event)
+	}
+	h.access.Unlock()
+	slices.SortFunc(tasks, func(a, b adapter.Event) int {
+		if a.Task != b.Task {
+			return strings.Compare(a.Task, b.Task)
+		}
+		return strings.Compare(a.Tag, b.Tag)
+	})
+	return tasks
+}
+
+func (h *taskHistory) Close() error {
+	if h.cancel != nil {
+		h.cancel()
+	}
+	return nil
+}
+
+func taskRouter(eventBus adapter.EventBus, history *taskHistory) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/", getTasks(eventBus, history))
+	return r
+}
+
+func getTasks(eventBus adapter.EventBus, history *taskHistory) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if eventBus != nil && (r.Header.Get("Upgrade") == "websocket" || r.URL.Query().Get("stream") == "true") {
+			streamEvents(w, r, eventBus, taskEventTypes, history.List())
+			return
+		}
+		render.JSON(w, r, render.M{
+			"tasks": history.List(),
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package trafficontrol
+
+import (
+	"context"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/sing/common/json"
+	M "github.com/sagernet/sing/common/metadata"
+	"github.com/sagernet/sing/service/filemanager"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// Dump is the full connection table with runtime state for post-mortem
+// debugging.
+type Dump struct {
+	Time              time.Time        `json:"time"`
+	Reason            string           `json:"reason"`
+	Version           string           `json:"version"`
+	Goroutines        int              `json:"goroutines"`
+	Memory            DumpMemory       `json:"memory"`
+	UploadTotal       int64            `json:"upload_total"`
+	DownloadTotal     int64            `json:"download_total"`
+	Budgets           []DumpBudget     `json:"budgets"`
+	Connections       []DumpConnection `json:"connections"`
+	ClosedConnections []DumpConnection `json:"closed_connections"`
+}
+
+type DumpMemory struct {
+	RSS        uint64 `json:"rss"`
+	HeapInuse  uint64 `json:"heap_inuse"`
+	HeapIdle   uint64 `json:"heap_idle"`
+	StackInuse uint64 `json:"stack_inuse"`
+	Sys        uint64 `json:"sys"`
+	NumGC      uint32 `json:"num_gc"`
+}
+
+// DumpBudget is the usage of a buffer or connection budget of the router.
+type DumpBudget struct {
+	Subsystem string `json:"subsystem"`
+	Key       string `json:"key,omitempty"`
+	Limit     int64  `json:"limit"`
+	Used      int64  `json:"used"`
+	Peak      int64  `json:"peak"`
+	Rejected  uint64 `json:"rejected"`
+}
+
+// DumpBuffer is the data queued in the kernel buffers of the inbound socket,
+// received but not read yet, and sent but not acknowledged yet.
+type DumpBuffer struct {
+	ReadQueue  int `json:"read_queue"`
+	WriteQueue int `json:"write_queue"`
+}
+
+type DumpConnection struct {
+	ID             string      `json:"id"`
+	Network        string      `json:"network"`
+	Inbound        string      `json:"inbound,omitempty"`
+	InboundType    string      `json:"inbound_type"`
+	Source         string      `json:"source"`
+	Destination    string      `json:"destination"`
+	Domain         string      `json:"domain,omitempty"`
+	Addresses      []string    `json:"destination_addresses,omitempty"`
+	Protocol       string      `json:"protocol,omitempty"`
+	Client         string      `json:"client,omitempty"`
+	User           string      `json:"user,omitempty"`
+	Process        string      `json:"process,omitempty"`
+	Rule           string      `json:"rule"`
+	Chains         []string    `json:"chains"`
+	Outbound       string      `json:"outbound"`
+	OutboundType   string      `json:"outbound_type"`
+	Upload         int64       `json:"upload"`
+	Download       int64       `json:"download"`
+	Buffer         *DumpBuffer `json:"buffer,omitempty"`
+	CreatedAt      time.Time   `json:"created_at"`
+	ClosedAt       *time.Time  `json:"closed_at,omitempty"`
+	LastUploadAt   *time.Time  `json:"last_upload_at,omitempty"`
+	LastDownloadAt *time.Time  `json:"last_download_at,omitempty"`
+}
+
+func (m *Manager) Dump(reason string, budgets []adapter.BudgetStats) *Dump {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	m.updateMemory()
+	dump := &Dump{
+		Time:       time.Now(),
+		Reason:     reason,
+		Version:    C.Version,
+		Goroutines: runtime.NumGoroutine(),
+		Memory: DumpMemory{
+			RSS:        m.memory,
+			HeapInuse:  memStats.HeapInuse,
+			HeapIdle:   memStats.HeapIdle,
+			StackInuse: memStats.StackInuse,
+			Sys:        memStats.Sys,
+			NumGC:      memStats.NumGC,
+		},
+		UploadTotal:       m.uploadTotal.Load(),
+		DownloadTotal:     m.downloadTotal.Load(),
+		Budgets:           []DumpBudget{},
+		Connections:       []DumpConnection{},
+		ClosedConnections: []DumpConnection{},
+	}
+	for _, stats := range budgets {
+		dump.Budgets = append(dump.Budgets, DumpBudget(stats))
+	}
+	m.connections.Range(func(_ uuid.UUID, tracker Tracker) bool {
+		connection := newDumpConnection(tracker.Metadata())
+		if tcpConn, isTCPConn := tracker.(*TCPConn); isTCPConn {
+			connection.Buffer = socketBuffer(tcpConn.ExtendedConn)
+		}
+		dump.Connections = append(dump.Connections, connection)
+		return true
+	})
+	for _, metadata := range m.ClosedConnections() {
+		dump.ClosedConnections = append(dump.ClosedConnections, newDumpConnection(metadata))
+	}
+	return dump
+}
+
+// WriteDump writes the dump to the path, replacing an existing file.
+func (m *Manager) WriteDump(ctx context.Context, path string, reason string, budgets []adapter.BudgetStats) error {
+	err := filemanager.MkdirAll(ctx, filepath.Dir(path), 0o755)
+	if err != nil {
+		return err
+	}
+	file, err := filemanager.Create(ctx, path)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(m.Dump(reason, budgets))
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func newDumpConnection(t TrackerMetadata) DumpConnection {
+	connection := DumpConnection{
+		ID:           t.ID.String(),
+		Network:      t.Metadata.Network,
+		Inbound:      t.Metadata.Inbound,
+		InboundType:  t.Metadata.InboundType,
+		Source:       t.Metadata.Source.String(),
+		Destination:  t.Metadata.Destination.String(),
+		Domain:       t.Metadata.Domain,
+		Protocol:     t.Metadata.Protocol,
+		Client:       t.Metadata.Client,
+		User:         t.Metadata.User,
+		Chains:       t.Chain,
+		Outbound:     t.Outbound,
+		OutboundType: t.OutboundType,
+		Upload:       t.Upload.Load(),
+		Download:     t.Download.Load(),
+		CreatedAt:    t.CreatedAt,
+	}
+	if t.Metadata.RouteOriginalDestination.IsValid() {
+		connection.Destination = t.Metadata.RouteOriginalDestination.String() + " => " + connection.Destination
+	}
+	for _, address := range t.Metadata.DestinationAddresses {
+		connection.Addresses = append(connection.Addresses, M.SocksaddrFrom(address, t.Metadata.Destination.Port).String())
+	}
+	if t.Metadata.ProcessInfo != nil {
+		if t.Metadata.ProcessInfo.ProcessPath != "" {
+			connection.Process = t.Metadata.ProcessInfo.ProcessPath
+		} else if t.Metadata.ProcessInfo.PackageName != "" {
+			connection.Process = t.Metadata.ProcessInfo.PackageName
+		}
+	}
+	if t.Rule != nil {
+		connection.Rule = F.ToString(t.Rule, " => ", t.Rule.Action())
+	} else {
+		connection.Rule = "final"
+	}
+	if !t.ClosedAt.IsZero() {
+		connection.ClosedAt = &t.ClosedAt
+	}
+	connection.LastUploadAt = unixNanoTime(t.LastUpload.Load())
+	connection.LastDownloadAt = unixNanoTime(t.LastDownload.Load())
+	return connection
+}
+
+func unixNanoTime(nanos int64) *time.Time {
+	if nanos == 0 {
+		return nil
+	}
+	timestamp := time.Unix(0, nanos)
+	return &timestamp
+}
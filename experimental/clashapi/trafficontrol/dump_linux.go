@@ -0,0 +1,35 @@
+package trafficontrol
+
+import (
+	"syscall"
+
+	"github.com/sagernet/sing/common"
+
+	"golang.org/x/sys/unix"
+)
+
+func socketBuffer(conn any) *DumpBuffer {
+	syscallConn, isSyscallConn := common.Cast[syscall.Conn](conn)
+	if !isSyscallConn {
+		return nil
+	}
+	rawConn, err := syscallConn.SyscallConn()
+	if err != nil {
+		return nil
+	}
+	var (
+		buffer     DumpBuffer
+		controlErr error
+	)
+	err = rawConn.Control(func(fd uintptr) {
+		buffer.ReadQueue, controlErr = unix.IoctlGetInt(int(fd), unix.SIOCINQ)
+		if controlErr != nil {
+			return
+		}
+		buffer.WriteQueue, controlErr = unix.IoctlGetInt(int(fd), unix.SIOCOUTQ)
+	})
+	if err != nil || controlErr != nil {
+		return nil
+	}
+	return &buffer
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package trafficontrol
+
+func socketBuffer(conn any) *DumpBuffer {
+	return nil
+}
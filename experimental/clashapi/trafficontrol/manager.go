@@ -11,29 +11,75 @@ import (
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing/common"
 	"github.com/sagernet/sing/common/json"
-	"github.com/sagernet/sing/common/x/list"
 
 	"github.com/gofrs/uuid/v5"
 )
 
+const (
+	closedConnectionsCapacity = 1000
+	coarseClockInterval       = time.Second
+)
+
 type Manager struct {
 	uploadTotal   atomic.Int64
 	downloadTotal atomic.Int64
 
-	connections             compatible.Map[uuid.UUID, Tracker]
-	closedConnectionsAccess sync.Mutex
-	closedConnections       list.List[TrackerMetadata]
+	connections            compatible.Map[uuid.UUID, Tracker]
+	closedAccess           sync.Mutex
+	closedConnectionsIndex uint64
+	closedConnections      [closedConnectionsCapacity]*TrackerMetadata
+
+	usageAccess        sync.Mutex
+	closedPackageUsage map[string]Usage
+	closedUserUsage    map[string]Usage
 
 	pid    int32
 	memory uint64
+
+	clock     atomic.Int64
+	clockDone chan struct{}
+	closeOnce sync.Once
 }
 
 func NewManager() *Manager {
-	return &Manager{
-		pid: int32(os.Getpid()),
+	manager := &Manager{
+		pid:       int32(os.Getpid()),
+		clockDone: make(chan struct{}),
+	}
+	manager.clock.Store(time.Now().UnixNano())
+	return manager
+}
+
+func (m *Manager) Start() {
+	go m.loopClock()
+}
+
+// loopClock updates the coarse clock recording the last activity of
+// connections, so that reads and writes do not read the time themselves.
+func (m *Manager) loopClock() {
+	ticker := time.NewTicker(coarseClockInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			m.clock.Store(now.UnixNano())
+		case <-m.clockDone:
+			return
+		}
 	}
 }
 
+func (m *Manager) now() int64 {
+	return m.clock.Load()
+}
+
+func (m *Manager) Close() error {
+	m.closeOnce.Do(func() {
+		close(m.clockDone)
+	})
+	return nil
+}
+
 func (m *Manager) Join(c Tracker) {
 	m.connections.Store(c.Metadata().ID, c)
 }
@@ -43,12 +89,11 @@ func (m *Manager) Leave(c Tracker) {
 	_, loaded := m.connections.LoadAndDelete(metadata.ID)
 	if loaded {
 		metadata.ClosedAt = time.Now()
-		m.closedConnectionsAccess.Lock()
-		defer m.closedConnectionsAccess.Unlock()
-		if m.closedConnections.Len() >= 1000 {
-			m.closedConnections.PopFront()
-		}
-		m.closedConnections.PushBack(metadata)
+		m.closedAccess.Lock()
+		m.closedConnections[m.closedConnectionsIndex%closedConnectionsCapacity] = &metadata
+		m.closedConnectionsIndex++
+		m.closedAccess.Unlock()
+		m.leaveUsage(metadata)
 	}
 }
 
@@ -78,9 +123,18 @@ func (m *Manager) Connections() []TrackerMetadata {
 }
 
 func (m *Manager) ClosedConnections() []TrackerMetadata {
-	m.closedConnectionsAccess.Lock()
-	defer m.closedConnectionsAccess.Unlock()
-	return m.closedConnections.Array()
+	m.closedAccess.Lock()
+	defer m.closedAccess.Unlock()
+	end := m.closedConnectionsIndex
+	var start uint64
+	if end > closedConnectionsCapacity {
+		start = end - closedConnectionsCapacity
+	}
+	connections := make([]TrackerMetadata, 0, end-start)
+	for index := start; index < end; index++ {
+		connections = append(connections, *m.closedConnections[index%closedConnectionsCapacity])
+	}
+	return connections
 }
 
 func (m *Manager) Connection(id uuid.UUID) Tracker {
@@ -23,6 +23,8 @@ type TrackerMetadata struct {
 	ClosedAt     time.Time
 	Upload       *atomic.Int64
 	Download     *atomic.Int64
+	LastUpload   *atomic.Int64
+	LastDownload *atomic.Int64
 	Chain        []string
 	Rule         adapter.Rule
 	Outbound     string
@@ -155,12 +157,16 @@ func NewTCPTracker(conn net.Conn, manager *Manager, metadata adapter.InboundCont
 	}
 	upload := new(atomic.Int64)
 	download := new(atomic.Int64)
+	lastUpload := new(atomic.Int64)
+	lastDownload := new(atomic.Int64)
 	tracker := &TCPConn{
 		ExtendedConn: bufio.NewCounterConn(conn, []N.CountFunc{func(n int64) {
 			upload.Add(n)
+			lastUpload.Store(manager.now())
 			manager.PushUploaded(n)
 		}}, []N.CountFunc{func(n int64) {
 			download.Add(n)
+			lastDownload.Store(manager.now())
 			manager.PushDownloaded(n)
 		}}),
 		metadata: TrackerMetadata{
@@ -169,6 +175,8 @@ func NewTCPTracker(conn net.Conn, manager *Manager, metadata adapter.InboundCont
 			CreatedAt:    time.Now(),
 			Upload:       upload,
 			Download:     download,
+			LastUpload:   lastUpload,
+			LastDownload: lastDownload,
 			Chain:        common.Reverse(chain),
 			Rule:         matchRule,
 			Outbound:     outbound,
@@ -236,12 +244,16 @@ func NewUDPTracker(conn N.PacketConn, manager *Manager, metadata adapter.Inbound
 	}
 	upload := new(atomic.Int64)
 	download := new(atomic.Int64)
+	lastUpload := new(atomic.Int64)
+	lastDownload := new(atomic.Int64)
 	trackerConn := &UDPConn{
 		PacketConn: bufio.NewCounterPacketConn(conn, []N.CountFunc{func(n int64) {
 			upload.Add(n)
+			lastUpload.Store(manager.now())
 			manager.PushUploaded(n)
 		}}, []N.CountFunc{func(n int64) {
 			download.Add(n)
+			lastDownload.Store(manager.now())
 			manager.PushDownloaded(n)
 		}}),
 		metadata: TrackerMetadata{
@@ -250,6 +262,8 @@ func NewUDPTracker(conn N.PacketConn, manager *Manager, metadata adapter.Inbound
 			CreatedAt:    time.Now(),
 			Upload:       upload,
 			Download:     download,
+			LastUpload:   lastUpload,
+			LastDownload: lastDownload,
 			Chain:        common.Reverse(chain),
 			Rule:         matchRule,
 			Outbound:     outbound,
@@ -0,0 +1,96 @@
+package trafficontrol
+
+import (
+	"github.com/sagernet/sing-box/adapter"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// Usage is the traffic of connections grouped by their owner, including
+// closed connections since the manager was created.
+type Usage struct {
+	Upload      int64
+	Download    int64
+	Connections int32
+}
+
+// PackageUsage returns usage grouped by the package name of the source
+// process, or its path if the package name is unknown.
+func (m *Manager) PackageUsage() map[string]Usage {
+	return m.usage(&m.closedPackageUsage, UsagePackage)
+}
+
+// UserUsage returns usage grouped by the authenticated inbound user, or the
+// user name of the source process if not authenticated.
+func (m *Manager) UserUsage() map[string]Usage {
+	return m.usage(&m.closedUserUsage, UsageUser)
+}
+
+func (m *Manager) usage(closedUsage *map[string]Usage, keyFunc func(metadata adapter.InboundContext) string) map[string]Usage {
+	m.usageAccess.Lock()
+	usage := make(map[string]Usage, len(*closedUsage))
+	for key, value := range *closedUsage {
+		usage[key] = value
+	}
+	m.usageAccess.Unlock()
+	m.connections.Range(func(_ uuid.UUID, value Tracker) bool {
+		metadata := value.Metadata()
+		key := keyFunc(metadata.Metadata)
+		if key == "" {
+			return true
+		}
+		current := usage[key]
+		current.Upload += metadata.Upload.Load()
+		current.Download += metadata.Download.Load()
+		current.Connections++
+		usage[key] = current
+		return true
+	})
+	return usage
+}
+
+func (m *Manager) leaveUsage(metadata TrackerMetadata) {
+	packageKey := UsagePackage(metadata.Metadata)
+	userKey := UsageUser(metadata.Metadata)
+	if packageKey == "" && userKey == "" {
+		return
+	}
+	upload, download := metadata.Upload.Load(), metadata.Download.Load()
+	m.usageAccess.Lock()
+	defer m.usageAccess.Unlock()
+	addUsage(&m.closedPackageUsage, packageKey, upload, download)
+	addUsage(&m.closedUserUsage, userKey, upload, download)
+}
+
+func addUsage(usage *map[string]Usage, key string, upload int64, download int64) {
+	if key == "" {
+		return
+	}
+	if *usage == nil {
+		*usage = make(map[string]Usage)
+	}
+	current := (*usage)[key]
+	current.Upload += upload
+	current.Download += download
+	(*usage)[key] = current
+}
+
+func UsagePackage(metadata adapter.InboundContext) string {
+	if metadata.ProcessInfo == nil {
+		return ""
+	}
+	if metadata.ProcessInfo.PackageName != "" {
+		return metadata.ProcessInfo.PackageName
+	}
+	return metadata.ProcessInfo.ProcessPath
+}
+
+func UsageUser(metadata adapter.InboundContext) string {
+	if metadata.User != "" {
+		return metadata.User
+	}
+	if metadata.ProcessInfo == nil {
+		return ""
+	}
+	return metadata.ProcessInfo.User
+}
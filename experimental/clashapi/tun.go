@@ -0,0 +1,103 @@
+package clashapi
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/common/json/badjson"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+func tunRouter(ctx context.Context, inboundManager adapter.InboundManager) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/", getTunInbounds(ctx, inboundManager))
+	r.Get("/{tag}", getTunInbound(ctx, inboundManager))
+	r.Put("/{tag}", updateTunInbound(ctx, inboundManager))
+	return r
+}
+
+func findTunInbound(inboundManager adapter.InboundManager, r *http.Request) (adapter.TunInbound, bool) {
+	inbound, loaded := inboundManager.Get(getEscapeParam(r, "tag"))
+	if !loaded {
+		return nil, false
+	}
+	tunInbound, isTun := inbound.(adapter.TunInbound)
+	return tunInbound, isTun
+}
+
+func getTunInbounds(ctx context.Context, inboundManager adapter.InboundManager) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var tunMap badjson.JSONObject
+		for _, inbound := range inboundManager.Inbounds() {
+			tunInbound, isTun := inbound.(adapter.TunInbound)
+			if !isTun {
+				continue
+			}
+			tunMap.Put(tunInbound.Tag(), tunInbound.RouteOptions())
+		}
+		var responseMap badjson.JSONObject
+		responseMap.Put("tun", &tunMap)
+		response, err := json.MarshalContext(ctx, &responseMap)
+		if err != nil {
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		w.Write(response)
+	}
+}
+
+func getTunInbound(ctx context.Context, inboundManager adapter.InboundManager) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tunInbound, loaded := findTunInbound(inboundManager, r)
+		if !loaded {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, ErrNotFound)
+			return
+		}
+		response, err := json.MarshalContext(ctx, tunInbound.RouteOptions())
+		if err != nil {
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		w.Write(response)
+	}
+}
+
+func updateTunInbound(ctx context.Context, inboundManager adapter.InboundManager) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tunInbound, loaded := findTunInbound(inboundManager, r)
+		if !loaded {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, ErrNotFound)
+			return
+		}
+		content, err := io.ReadAll(r.Body)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, ErrBadRequest)
+			return
+		}
+		var routeOptions option.TunRouteOptions
+		err = json.UnmarshalContextDisallowUnknownFields(ctx, content, &routeOptions)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		err = tunInbound.UpdateRouteOptions(routeOptions)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		render.NoContent(w, r)
+	}
+}
@@ -0,0 +1,37 @@
+package clashapi
+
+import (
+	"net/http"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+func udpSessionRouter(connectionManager adapter.ConnectionManager) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/", getUDPSessions(connectionManager))
+	return r
+}
+
+func getUDPSessions(connectionManager adapter.ConnectionManager) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, r, render.M{
+			"tables": common.Map(connectionManager.UDPSessionTables(), udpSessionTableInfo),
+		})
+	}
+}
+
+func udpSessionTableInfo(table adapter.UDPSessionTableStats) render.M {
+	info := render.M{
+		"active":  table.Active,
+		"limit":   table.Limit,
+		"evicted": table.Evicted,
+	}
+	if table.Inbound != "" {
+		info["inbound"] = table.Inbound
+	}
+	return info
+}
@@ -0,0 +1,126 @@
+package clashapi
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+func userRouter(router adapter.Router) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/", getUsers(router))
+	r.Put("/{inbound}", setUser(router))
+	r.Patch("/{inbound}/{name}", updateUser(router))
+	r.Delete("/{inbound}/{name}", removeUser(router))
+	return r
+}
+
+func getUsers(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, r, render.M{
+			"users": common.Map(router.Users(), userInfo),
+		})
+	}
+}
+
+func userInfo(status adapter.UserStatus) render.M {
+	info := render.M{
+		"inbound":     status.Inbound,
+		"name":        status.Name,
+		"enabled":     status.Enabled,
+		"connections": status.Connections,
+	}
+	if !status.ExpireAt.IsZero() {
+		info["expire_at"] = status.ExpireAt.Format(time.RFC3339)
+	}
+	return info
+}
+
+// updateUser changes the enabled and expire_at fields of a user, an empty
+// expire_at removes the expiry.
+func updateUser(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Enabled  *bool   `json:"enabled"`
+			ExpireAt *string `json:"expire_at"`
+		}
+		err := render.DecodeJSON(r.Body, &body)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, ErrBadRequest)
+			return
+		}
+		update := adapter.UserStatusUpdate{
+			Enabled: body.Enabled,
+		}
+		if body.ExpireAt != nil {
+			var expireAt time.Time
+			if *body.ExpireAt != "" {
+				expireAt, err = time.Parse(time.RFC3339, *body.ExpireAt)
+				if err != nil {
+					render.Status(r, http.StatusBadRequest)
+					render.JSON(w, r, newError(err.Error()))
+					return
+				}
+			}
+			update.ExpireAt = &expireAt
+		}
+		err = router.UpdateUser(getEscapeParam(r, "inbound"), getEscapeParam(r, "name"), update)
+		if err != nil {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		render.NoContent(w, r)
+	}
+}
+
+// setUser adds or replaces a user of a running inbound, the body is the user
+// in the format of the inbound options. The share link of the user is
+// returned if it can be built, with the server query overriding the listen
+// address.
+func setUser(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		content, err := io.ReadAll(r.Body)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, ErrBadRequest)
+			return
+		}
+		tag := getEscapeParam(r, "inbound")
+		user, err := router.SetInboundUser(tag, content)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		response := render.M{
+			"name": user.Name,
+		}
+		shareLink, err := router.InboundUserLink(tag, user.Name, r.URL.Query().Get("server"))
+		if err != nil {
+			response["link_error"] = err.Error()
+		} else {
+			response["link"] = shareLink
+		}
+		render.JSON(w, r, response)
+	}
+}
+
+func removeUser(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := router.RemoveInboundUser(getEscapeParam(r, "inbound"), getEscapeParam(r, "name"))
+		if err != nil {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		render.NoContent(w, r)
+	}
+}
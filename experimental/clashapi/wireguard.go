@@ -0,0 +1,120 @@
+package clashapi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/json"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+func wireGuardRouter(ctx context.Context, endpointManager adapter.EndpointManager) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/{tag}/peers", getWireGuardPeers(endpointManager))
+	r.Put("/{tag}/peers", setWireGuardPeer(ctx, endpointManager))
+	r.Delete("/{tag}/peers/{public_key}", removeWireGuardPeer(endpointManager))
+	return r
+}
+
+func findWireGuardEndpoint(endpointManager adapter.EndpointManager, r *http.Request) (adapter.WireGuardEndpoint, bool) {
+	endpoint, loaded := endpointManager.Get(getEscapeParam(r, "tag"))
+	if !loaded {
+		return nil, false
+	}
+	wireGuardEndpoint, isWireGuard := endpoint.(adapter.WireGuardEndpoint)
+	return wireGuardEndpoint, isWireGuard
+}
+
+func getWireGuardPeers(endpointManager adapter.EndpointManager) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		wireGuardEndpoint, loaded := findWireGuardEndpoint(endpointManager, r)
+		if !loaded {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, ErrNotFound)
+			return
+		}
+		render.JSON(w, r, render.M{
+			"peers": common.Map(wireGuardEndpoint.Peers(), wireGuardPeerInfo),
+		})
+	}
+}
+
+func wireGuardPeerInfo(status adapter.WireGuardPeerStatus) render.M {
+	info := render.M{
+		"public_key":  status.PublicKey,
+		"allowed_ips": status.AllowedIPs,
+		"rx_bytes":    status.ReceivedBytes,
+		"tx_bytes":    status.SentBytes,
+	}
+	if status.Name != "" {
+		info["name"] = status.Name
+	}
+	if status.Endpoint != "" {
+		info["endpoint"] = status.Endpoint
+	}
+	if status.PersistentKeepaliveInterval > 0 {
+		info["persistent_keepalive_interval"] = status.PersistentKeepaliveInterval
+	}
+	if !status.LastHandshake.IsZero() {
+		info["last_handshake"] = status.LastHandshake.Format(time.RFC3339)
+	}
+	return info
+}
+
+// setWireGuardPeer adds or replaces a peer of a running endpoint, the body is
+// the peer in the format of the endpoint options.
+func setWireGuardPeer(ctx context.Context, endpointManager adapter.EndpointManager) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		wireGuardEndpoint, loaded := findWireGuardEndpoint(endpointManager, r)
+		if !loaded {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, ErrNotFound)
+			return
+		}
+		content, err := io.ReadAll(r.Body)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, ErrBadRequest)
+			return
+		}
+		var peer option.WireGuardPeer
+		err = json.UnmarshalContextDisallowUnknownFields(ctx, content, &peer)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		err = wireGuardEndpoint.SetPeer(peer)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		render.NoContent(w, r)
+	}
+}
+
+func removeWireGuardPeer(endpointManager adapter.EndpointManager) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		wireGuardEndpoint, loaded := findWireGuardEndpoint(endpointManager, r)
+		if !loaded {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, ErrNotFound)
+			return
+		}
+		err := wireGuardEndpoint.RemovePeer(getEscapeParam(r, "public_key"))
+		if err != nil {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		render.NoContent(w, r)
+	}
+}
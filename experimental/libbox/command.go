@@ -17,4 +17,8 @@ const (
 	CommandConnections
 	CommandCloseConnection
 	CommandGetDeprecatedNotes
+	CommandUsage
+	CommandSetQuota
+	CommandSetInboundUser
+	CommandRemoveInboundUser
 )
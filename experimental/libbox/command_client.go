@@ -32,6 +32,8 @@ type CommandClientHandler interface {
 	InitializeClashMode(modeList StringIterator, currentMode string)
 	UpdateClashMode(newMode string)
 	WriteConnections(message *Connections)
+	WriteUsage(message *UsageMessage)
+	QuotaReached(event *QuotaEvent)
 }
 
 func NewStandaloneCommandClient() *CommandClient {
@@ -141,6 +143,13 @@ func (c *CommandClient) Connect() error {
 		}
 		c.handler.Connected()
 		go c.handleConnectionsConn(conn)
+	case CommandUsage:
+		err = binary.Write(conn, binary.BigEndian, c.options.StatusInterval)
+		if err != nil {
+			return E.Cause(err, "write interval")
+		}
+		c.handler.Connected()
+		go c.handleUsageConn(conn)
 	}
 	return nil
 }
@@ -184,6 +184,11 @@ type Connection struct {
 	Outbound      string
 	OutboundType  string
 	ChainList     []string
+	ProcessID     int32
+	ProcessPath   string
+	PackageName   string
+	ProcessUserID int32
+	ProcessUser   string
 }
 
 func (c *Connection) Chain() StringIterator {
@@ -263,6 +268,14 @@ func newConnection(connections map[uuid.UUID]*Connection, metadata trafficontrol
 		Outbound:      metadata.Outbound,
 		OutboundType:  metadata.OutboundType,
 		ChainList:     metadata.Chain,
+		ProcessUserID: -1,
+	}
+	if processInfo := metadata.Metadata.ProcessInfo; processInfo != nil {
+		connection.ProcessID = int32(processInfo.ProcessID)
+		connection.ProcessPath = processInfo.ProcessPath
+		connection.PackageName = processInfo.PackageName
+		connection.ProcessUserID = processInfo.UserId
+		connection.ProcessUser = processInfo.User
 	}
 	connections[metadata.ID] = &connection
 	return connection
@@ -108,7 +108,7 @@ func readGroups(reader io.Reader) (OutboundGroupIterator, error) {
 
 func writeGroups(writer io.Writer, boxService *BoxService) error {
 	historyStorage := service.PtrFromContext[urltest.HistoryStorage](boxService.ctx)
-	cacheFile := service.FromContext[adapter.CacheFile](boxService.ctx)
+	cacheFile := service.FromContext[adapter.CacheFile](boxService.instance.Context())
 	outbounds := boxService.instance.Outbound().Outbounds()
 	var iGroups []adapter.OutboundGroup
 	for _, it := range outbounds {
@@ -187,7 +187,7 @@ func (s *CommandServer) handleSetGroupExpand(conn net.Conn) error {
 	if serviceNow == nil {
 		return writeError(conn, E.New("service not ready"))
 	}
-	cacheFile := service.FromContext[adapter.CacheFile](serviceNow.ctx)
+	cacheFile := service.FromContext[adapter.CacheFile](serviceNow.instance.Context())
 	if cacheFile != nil {
 		err = cacheFile.StoreGroupExpand(groupTag, isExpand)
 		if err != nil {
@@ -0,0 +1,149 @@
+package libbox
+
+import (
+	"bufio"
+	"net"
+
+	"github.com/sagernet/sing/common/binary"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/varbin"
+)
+
+// InboundUser is a user added to a running inbound. Link is the share link
+// of the user, or LinkError the reason it could not be built.
+type InboundUser struct {
+	Name      string
+	Link      string
+	LinkError string
+}
+
+// SetInboundUser adds or replaces a user of a running vmess, vless, trojan,
+// shadowsocks, hysteria2 or tuic inbound. userJSON is the user in the format
+// of the inbound options, and server overrides the listen address in the
+// returned share link.
+func (c *CommandClient) SetInboundUser(inbound string, userJSON string, server string) (*InboundUser, error) {
+	conn, err := c.directConnect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	err = binary.Write(conn, binary.BigEndian, uint8(CommandSetInboundUser))
+	if err != nil {
+		return nil, err
+	}
+	writer := bufio.NewWriter(conn)
+	err = varbin.Write(writer, binary.BigEndian, inbound)
+	if err != nil {
+		return nil, err
+	}
+	err = varbin.Write(writer, binary.BigEndian, userJSON)
+	if err != nil {
+		return nil, err
+	}
+	err = varbin.Write(writer, binary.BigEndian, server)
+	if err != nil {
+		return nil, err
+	}
+	err = writer.Flush()
+	if err != nil {
+		return nil, err
+	}
+	reader := bufio.NewReader(conn)
+	err = readError(reader)
+	if err != nil {
+		return nil, err
+	}
+	var user InboundUser
+	err = varbin.Read(reader, binary.BigEndian, &user)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (s *CommandServer) handleSetInboundUser(conn net.Conn) error {
+	reader := bufio.NewReader(conn)
+	inbound, err := varbin.ReadValue[string](reader, binary.BigEndian)
+	if err != nil {
+		return E.Cause(err, "read inbound")
+	}
+	userJSON, err := varbin.ReadValue[string](reader, binary.BigEndian)
+	if err != nil {
+		return E.Cause(err, "read user")
+	}
+	server, err := varbin.ReadValue[string](reader, binary.BigEndian)
+	if err != nil {
+		return E.Cause(err, "read server")
+	}
+	service := s.service
+	if service == nil {
+		return writeError(conn, E.New("service not ready"))
+	}
+	router := service.instance.Router()
+	inboundUser, err := router.SetInboundUser(inbound, []byte(userJSON))
+	if err != nil {
+		return writeError(conn, err)
+	}
+	user := InboundUser{
+		Name: inboundUser.Name,
+	}
+	user.Link, err = router.InboundUserLink(inbound, inboundUser.Name, server)
+	if err != nil {
+		user.LinkError = err.Error()
+	}
+	writer := bufio.NewWriter(conn)
+	err = writeError(writer, nil)
+	if err != nil {
+		return err
+	}
+	err = varbin.Write(writer, binary.BigEndian, user)
+	if err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+// RemoveInboundUser removes a user of a running inbound and closes its
+// connections.
+func (c *CommandClient) RemoveInboundUser(inbound string, name string) error {
+	conn, err := c.directConnect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = binary.Write(conn, binary.BigEndian, uint8(CommandRemoveInboundUser))
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(conn)
+	err = varbin.Write(writer, binary.BigEndian, inbound)
+	if err != nil {
+		return err
+	}
+	err = varbin.Write(writer, binary.BigEndian, name)
+	if err != nil {
+		return err
+	}
+	err = writer.Flush()
+	if err != nil {
+		return err
+	}
+	return readError(conn)
+}
+
+func (s *CommandServer) handleRemoveInboundUser(conn net.Conn) error {
+	reader := bufio.NewReader(conn)
+	inbound, err := varbin.ReadValue[string](reader, binary.BigEndian)
+	if err != nil {
+		return E.Cause(err, "read inbound")
+	}
+	name, err := varbin.ReadValue[string](reader, binary.BigEndian)
+	if err != nil {
+		return E.Cause(err, "read name")
+	}
+	service := s.service
+	if service == nil {
+		return writeError(conn, E.New("service not ready"))
+	}
+	return writeError(conn, service.instance.Router().RemoveInboundUser(inbound, name))
+}
@@ -35,6 +35,9 @@ type CommandServer struct {
 	logReset      chan struct{}
 
 	closedConnections []Connection
+
+	quotaAccess sync.Mutex
+	quotas      map[usageKey]*usageQuota
 }
 
 type CommandServerHandler interface {
@@ -63,6 +66,7 @@ func (s *CommandServer) SetService(newService *BoxService) {
 		newService.clashServer.(*clashapi.Server).SetModeUpdateHook(s.modeUpdate)
 	}
 	s.service = newService
+	s.resetQuotas()
 	s.notifyURLTestUpdate()
 }
 
@@ -176,6 +180,14 @@ func (s *CommandServer) handleConnection(conn net.Conn) error {
 		return s.handleCloseConnection(conn)
 	case CommandGetDeprecatedNotes:
 		return s.handleGetDeprecatedNotes(conn)
+	case CommandUsage:
+		return s.handleUsageConn(conn)
+	case CommandSetQuota:
+		return s.handleSetQuota(conn)
+	case CommandSetInboundUser:
+		return s.handleSetInboundUser(conn)
+	case CommandRemoveInboundUser:
+		return s.handleRemoveInboundUser(conn)
 	default:
 		return E.New("unknown command: ", command)
 	}
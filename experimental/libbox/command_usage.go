@@ -0,0 +1,291 @@
+package libbox
+
+import (
+	"bufio"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/experimental/clashapi"
+	"github.com/sagernet/sing-box/experimental/clashapi/trafficontrol"
+	"github.com/sagernet/sing/common/binary"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/varbin"
+)
+
+const (
+	UsageKindPackage int32 = iota
+	UsageKindUser
+)
+
+// Usage is the traffic of a package (or process path if the package name is
+// unknown) or a user (the authenticated inbound user, or the user of the
+// source process), including closed connections since the service started.
+type Usage struct {
+	Name          string
+	Connections   int32
+	Uplink        int64
+	Downlink      int64
+	UplinkTotal   int64
+	DownlinkTotal int64
+}
+
+type UsageIterator interface {
+	Next() *Usage
+	HasNext() bool
+}
+
+type QuotaEvent struct {
+	Kind  int32
+	Name  string
+	Limit int64
+	Used  int64
+}
+
+type usageMessage struct {
+	Packages []Usage
+	Users    []Usage
+	Quotas   []QuotaEvent
+}
+
+type UsageMessage struct {
+	packages []Usage
+	users    []Usage
+}
+
+// Packages returns the usage of packages, sorted by total traffic.
+func (m *UsageMessage) Packages() UsageIterator {
+	return newPtrIterator(m.packages)
+}
+
+// Users returns the usage of users, sorted by total traffic.
+func (m *UsageMessage) Users() UsageIterator {
+	return newPtrIterator(m.users)
+}
+
+type usageKey struct {
+	kind int32
+	name string
+}
+
+type usageQuota struct {
+	limit   int64
+	reached bool
+}
+
+func (c *CommandClient) handleUsageConn(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		var message usageMessage
+		err := varbin.Read(reader, binary.BigEndian, &message)
+		if err != nil {
+			c.handler.Disconnected(err.Error())
+			return
+		}
+		c.handler.WriteUsage(&UsageMessage{
+			packages: message.Packages,
+			users:    message.Users,
+		})
+		for _, event := range message.Quotas {
+			c.handler.QuotaReached(&event)
+		}
+	}
+}
+
+// SetQuota sets the traffic quota of a package or user, QuotaReached of the
+// handler of usage clients is called once the total traffic reaches it.
+// A non-positive limit removes the quota.
+func (c *CommandClient) SetQuota(kind int32, name string, limit int64) error {
+	conn, err := c.directConnect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = binary.Write(conn, binary.BigEndian, uint8(CommandSetQuota))
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(conn)
+	err = binary.Write(writer, binary.BigEndian, kind)
+	if err != nil {
+		return err
+	}
+	err = varbin.Write(writer, binary.BigEndian, name)
+	if err != nil {
+		return err
+	}
+	err = binary.Write(writer, binary.BigEndian, limit)
+	if err != nil {
+		return err
+	}
+	err = writer.Flush()
+	if err != nil {
+		return err
+	}
+	return readError(conn)
+}
+
+func (s *CommandServer) handleSetQuota(conn net.Conn) error {
+	reader := bufio.NewReader(conn)
+	var kind int32
+	err := binary.Read(reader, binary.BigEndian, &kind)
+	if err != nil {
+		return E.Cause(err, "read kind")
+	}
+	name, err := varbin.ReadValue[string](reader, binary.BigEndian)
+	if err != nil {
+		return E.Cause(err, "read name")
+	}
+	var limit int64
+	err = binary.Read(reader, binary.BigEndian, &limit)
+	if err != nil {
+		return E.Cause(err, "read limit")
+	}
+	if kind != UsageKindPackage && kind != UsageKindUser {
+		return writeError(conn, E.New("unknown usage kind: ", kind))
+	}
+	key := usageKey{kind, name}
+	s.quotaAccess.Lock()
+	if limit <= 0 {
+		delete(s.quotas, key)
+	} else {
+		if s.quotas == nil {
+			s.quotas = make(map[usageKey]*usageQuota)
+		}
+		s.quotas[key] = &usageQuota{limit: limit}
+	}
+	s.quotaAccess.Unlock()
+	return writeError(conn, nil)
+}
+
+func (s *CommandServer) resetQuotas() {
+	s.quotaAccess.Lock()
+	defer s.quotaAccess.Unlock()
+	for _, quota := range s.quotas {
+		quota.reached = false
+	}
+}
+
+func (s *CommandServer) checkQuotas(kind int32, usageList []Usage) []QuotaEvent {
+	s.quotaAccess.Lock()
+	defer s.quotaAccess.Unlock()
+	var events []QuotaEvent
+	for _, usage := range usageList {
+		quota, loaded := s.quotas[usageKey{kind, usage.Name}]
+		if !loaded || quota.reached {
+			continue
+		}
+		used := usage.UplinkTotal + usage.DownlinkTotal
+		if used < quota.limit {
+			continue
+		}
+		quota.reached = true
+		events = append(events, QuotaEvent{
+			Kind:  kind,
+			Name:  usage.Name,
+			Limit: quota.limit,
+			Used:  used,
+		})
+	}
+	return events
+}
+
+func (s *CommandServer) handleUsageConn(conn net.Conn) error {
+	var interval int64
+	err := binary.Read(conn, binary.BigEndian, &interval)
+	if err != nil {
+		return E.Cause(err, "read interval")
+	}
+	ticker := time.NewTicker(time.Duration(interval))
+	defer ticker.Stop()
+	ctx := connKeepAlive(conn)
+	var (
+		trafficManager *trafficontrol.Manager
+		eventBus       adapter.EventBus
+	)
+	for {
+		service := s.service
+		if service != nil {
+			trafficManager = service.clashServer.(*clashapi.Server).TrafficManager()
+			eventBus = service.instance.EventBus()
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	lastUsage := make(map[usageKey]Usage)
+	writer := bufio.NewWriter(conn)
+	for {
+		var message usageMessage
+		message.Packages = newUsageList(lastUsage, UsageKindPackage, trafficManager.PackageUsage())
+		message.Users = newUsageList(lastUsage, UsageKindUser, trafficManager.UserUsage())
+		message.Quotas = append(s.checkQuotas(UsageKindPackage, message.Packages), s.checkQuotas(UsageKindUser, message.Users)...)
+		for _, quotaEvent := range message.Quotas {
+			event := adapter.Event{
+				Type:  adapter.EventQuotaExceeded,
+				Limit: quotaEvent.Limit,
+				Used:  quotaEvent.Used,
+			}
+			if quotaEvent.Kind == UsageKindPackage {
+				event.Package = quotaEvent.Name
+			} else {
+				event.User = quotaEvent.Name
+			}
+			eventBus.Emit(event)
+		}
+		err = varbin.Write(writer, binary.BigEndian, message)
+		if err != nil {
+			return err
+		}
+		err = writer.Flush()
+		if err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func newUsageList(lastUsage map[usageKey]Usage, kind int32, usageMap map[string]trafficontrol.Usage) []Usage {
+	usageList := make([]Usage, 0, len(usageMap))
+	for name, current := range usageMap {
+		key := usageKey{kind, name}
+		usage := Usage{
+			Name:          name,
+			Connections:   current.Connections,
+			UplinkTotal:   current.Upload,
+			DownlinkTotal: current.Download,
+		}
+		last, loaded := lastUsage[key]
+		if loaded {
+			usage.Uplink = max(usage.UplinkTotal-last.UplinkTotal, 0)
+			usage.Downlink = max(usage.DownlinkTotal-last.DownlinkTotal, 0)
+		} else {
+			usage.Uplink = usage.UplinkTotal
+			usage.Downlink = usage.DownlinkTotal
+		}
+		lastUsage[key] = usage
+		usageList = append(usageList, usage)
+	}
+	slices.SortFunc(usageList, func(x, y Usage) int {
+		xTraffic := x.UplinkTotal + x.DownlinkTotal
+		yTraffic := y.UplinkTotal + y.DownlinkTotal
+		if xTraffic < yTraffic {
+			return 1
+		} else if xTraffic > yTraffic {
+			return -1
+		} else {
+			return strings.Compare(x.Name, y.Name)
+		}
+	})
+	return usageList
+}
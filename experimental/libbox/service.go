@@ -72,8 +72,8 @@ func NewService(configContent string, platformInterface PlatformInterface) (*Box
 		cancel:                cancel,
 		instance:              instance,
 		urlTestHistoryStorage: urlTestHistoryStorage,
-		pauseManager:          service.FromContext[pause.Manager](ctx),
-		clashServer:           service.FromContext[adapter.ClashServer](ctx),
+		pauseManager:          service.FromContext[pause.Manager](instance.Context()),
+		clashServer:           service.FromContext[adapter.ClashServer](instance.Context()),
 	}, nil
 }
 
@@ -110,6 +110,24 @@ func (s *BoxService) Close() error {
 	}
 }
 
+// Reload replaces the running instance with one created from configContent,
+// without touching the configuration file. An invalid configuration leaves
+// the running instance untouched, and if the new instance fails to start,
+// the one restarted with the previous configuration is used.
+func (s *BoxService) Reload(configContent string) error {
+	options, err := parseConfig(s.ctx, configContent)
+	if err != nil {
+		return err
+	}
+	instance, err := s.instance.Reload(s.ctx, box.WithOptions(options))
+	if instance != nil {
+		s.instance = instance
+		s.pauseManager = service.FromContext[pause.Manager](instance.Context())
+		s.clashServer = service.FromContext[adapter.ClashServer](instance.Context())
+	}
+	return err
+}
+
 func (s *BoxService) NeedWIFIState() bool {
 	return s.instance.Router().NeedWIFIState()
 }
@@ -172,6 +190,17 @@ func (w *platformInterfaceWrapper) OpenTun(options *tun.Options, platformOptions
 	return tun.New(*options)
 }
 
+func (w *platformInterfaceWrapper) UpdateTunRouteOptions(options *tun.Options, platformOptions option.TunPlatformOptions) error {
+	if len(options.IncludeUID) > 0 || len(options.ExcludeUID) > 0 {
+		return E.New("platform: unsupported uid options")
+	}
+	routeRanges, err := options.BuildAutoRouteRanges(true)
+	if err != nil {
+		return err
+	}
+	return w.iif.UpdateTunRouteOptions(&tunOptions{options, routeRanges, platformOptions})
+}
+
 func (w *platformInterfaceWrapper) CreateDefaultInterfaceMonitor(logger logger.Logger) tun.DefaultInterfaceMonitor {
 	return &platformDefaultInterfaceMonitor{
 		platformInterfaceWrapper: w,
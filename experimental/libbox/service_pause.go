@@ -3,7 +3,9 @@ package libbox
 import (
 	"time"
 
+	"github.com/sagernet/sing-box/adapter"
 	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing/service"
 )
 
 type iOSPauseFields struct {
@@ -27,6 +29,20 @@ func (s *BoxService) Wake() {
 	}
 }
 
+// UpdatePowerState reports the power state of the device, which is used by
+// `power_saving` instead of reading it from the operating system.
+func (s *BoxService) UpdatePowerState(onBattery bool, powerSaveMode bool, idle bool) {
+	powerManager := service.FromContext[adapter.PowerManager](s.instance.Context())
+	if powerManager == nil {
+		return
+	}
+	powerManager.UpdatePowerState(adapter.PowerState{
+		OnBattery:     onBattery,
+		PowerSaveMode: powerSaveMode,
+		Idle:          idle,
+	})
+}
+
 func (s *BoxService) ResetNetwork() {
 	s.instance.Router().ResetNetwork()
 }
@@ -0,0 +1,103 @@
+package wasm
+
+import (
+	"github.com/sagernet/sing-box/adapter"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+)
+
+// The guest ABI: a module exports its linear memory as `memory`, and
+// `alloc(size i32) i32` returning a buffer the host copies input into. If
+// `dealloc(ptr i32, size i32)` is exported, the host calls it after each
+// call, since instances are reused.
+//
+// A sniffer exports `sniff(ptr i32, len i32, is_packet i32) i64` and returns
+// sniffStatusNotMatched, sniffStatusNeedMoreData, or the JSON encoded
+// sniffResult as ptr<<32 | len.
+//
+// A rule evaluator exports functions `(ptr i32, len i32) i32`, receiving
+// the JSON encoded ruleMetadata and returning non-zero on match.
+const (
+	exportMemory  = "memory"
+	exportAlloc   = "alloc"
+	exportDealloc = "dealloc"
+	exportSniff   = "sniff"
+
+	DefaultRuleFunction = "match"
+
+	sniffStatusNotMatched   = 0
+	sniffStatusNeedMoreData = -1
+)
+
+type sniffResult struct {
+	Protocol string `json:"protocol"`
+	Domain   string `json:"domain,omitempty"`
+	Client   string `json:"client,omitempty"`
+}
+
+func decodeSniffResult(status int64, read func(offset uint32, length uint32) ([]byte, bool)) (*adapter.WASMSniffResult, error) {
+	switch status {
+	case sniffStatusNotMatched:
+		return nil, nil
+	case sniffStatusNeedMoreData:
+		return &adapter.WASMSniffResult{NeedMoreData: true}, nil
+	}
+	content, loaded := read(uint32(uint64(status)>>32), uint32(status))
+	if !loaded {
+		return nil, E.New("sniff result out of memory range")
+	}
+	var result sniffResult
+	err := json.Unmarshal(content, &result)
+	if err != nil {
+		return nil, E.Cause(err, "decode sniff result")
+	}
+	if result.Protocol == "" {
+		return nil, E.New("missing protocol in sniff result")
+	}
+	return &adapter.WASMSniffResult{
+		Protocol: result.Protocol,
+		Domain:   result.Domain,
+		Client:   result.Client,
+	}, nil
+}
+
+type ruleMetadata struct {
+	Network     string `json:"network,omitempty"`
+	Inbound     string `json:"inbound,omitempty"`
+	InboundType string `json:"inbound_type,omitempty"`
+	User        string `json:"user,omitempty"`
+	Source      string `json:"source,omitempty"`
+	Destination string `json:"destination,omitempty"`
+	Domain      string `json:"domain,omitempty"`
+	Protocol    string `json:"protocol,omitempty"`
+	Client      string `json:"client,omitempty"`
+	ProcessPath string `json:"process_path,omitempty"`
+	PackageName string `json:"package_name,omitempty"`
+}
+
+func encodeRuleMetadata(metadata *adapter.InboundContext) ([]byte, error) {
+	message := ruleMetadata{
+		Network:     metadata.Network,
+		Inbound:     metadata.Inbound,
+		InboundType: metadata.InboundType,
+		User:        metadata.User,
+		Protocol:    metadata.Protocol,
+		Client:      metadata.Client,
+	}
+	if metadata.Source.IsValid() {
+		message.Source = metadata.Source.String()
+	}
+	if metadata.Destination.IsValid() {
+		message.Destination = metadata.Destination.String()
+	}
+	if metadata.Destination.Fqdn != "" {
+		message.Domain = metadata.Destination.Fqdn
+	} else {
+		message.Domain = metadata.Domain
+	}
+	if metadata.ProcessInfo != nil {
+		message.ProcessPath = metadata.ProcessInfo.ProcessPath
+		message.PackageName = metadata.ProcessInfo.PackageName
+	}
+	return json.Marshal(message)
+}
@@ -0,0 +1,49 @@
+package wasm
+
+import (
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common/json"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeSniffResult(t *testing.T) {
+	t.Parallel()
+	memory := append(make([]byte, 8), `{"protocol":"custom","domain":"example.com"}`...)
+	read := func(offset uint32, length uint32) ([]byte, bool) {
+		if uint64(offset)+uint64(length) > uint64(len(memory)) {
+			return nil, false
+		}
+		return memory[offset : offset+length], true
+	}
+	result, err := decodeSniffResult(sniffStatusNotMatched, read)
+	require.NoError(t, err)
+	require.Nil(t, result)
+	result, err = decodeSniffResult(sniffStatusNeedMoreData, read)
+	require.NoError(t, err)
+	require.True(t, result.NeedMoreData)
+	result, err = decodeSniffResult(int64(8)<<32|int64(len(memory)-8), read)
+	require.NoError(t, err)
+	require.Equal(t, "custom", result.Protocol)
+	require.Equal(t, "example.com", result.Domain)
+	_, err = decodeSniffResult(int64(8)<<32|int64(len(memory)), read)
+	require.Error(t, err)
+}
+
+func TestEncodeRuleMetadata(t *testing.T) {
+	t.Parallel()
+	content, err := encodeRuleMetadata(&adapter.InboundContext{
+		Network:     "tcp",
+		Inbound:     "in",
+		Destination: M.ParseSocksaddrHostPort("example.com", 443),
+	})
+	require.NoError(t, err)
+	var message ruleMetadata
+	require.NoError(t, json.Unmarshal(content, &message))
+	require.Equal(t, "tcp", message.Network)
+	require.Equal(t, "example.com:443", message.Destination)
+	require.Equal(t, "example.com", message.Domain)
+}
@@ -0,0 +1,70 @@
+//go:build with_wasm
+
+package wasm
+
+import (
+	"context"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/experimental"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+func init() {
+	experimental.RegisterWASMManagerConstructor(NewManager)
+}
+
+var _ adapter.WASMManager = (*Manager)(nil)
+
+type Manager struct {
+	modules map[string]*Module
+}
+
+func NewManager(ctx context.Context, logger log.ContextLogger, options option.WASMOptions) (adapter.WASMManager, error) {
+	manager := &Manager{
+		modules: make(map[string]*Module),
+	}
+	for i, moduleOptions := range options.Modules {
+		if moduleOptions.Tag == "" {
+			manager.Close()
+			return nil, E.New("missing tag of module[", i, "]")
+		}
+		if _, loaded := manager.modules[moduleOptions.Tag]; loaded {
+			manager.Close()
+			return nil, E.New("duplicate module tag: ", moduleOptions.Tag)
+		}
+		module, err := NewModule(ctx, moduleOptions)
+		if err != nil {
+			manager.Close()
+			return nil, E.Cause(err, "load module[", moduleOptions.Tag, "]")
+		}
+		manager.modules[moduleOptions.Tag] = module
+	}
+	return manager, nil
+}
+
+func (m *Manager) Name() string {
+	return "wasm manager"
+}
+
+func (m *Manager) Start(stage adapter.StartStage) error {
+	return nil
+}
+
+func (m *Manager) Close() error {
+	var errors []error
+	for _, module := range m.modules {
+		errors = append(errors, module.Close())
+	}
+	return E.Errors(errors...)
+}
+
+func (m *Manager) Module(tag string) (adapter.WASMModule, bool) {
+	module, loaded := m.modules[tag]
+	if !loaded {
+		return nil, false
+	}
+	return module, true
+}
@@ -0,0 +1,211 @@
+//go:build with_wasm
+
+package wasm
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/service/filemanager"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+const (
+	DefaultMemoryLimit = 16 * 1024 * 1024
+	DefaultTimeout     = 20 * time.Millisecond
+
+	memoryPageSize = 64 * 1024
+)
+
+var _ adapter.WASMModule = (*Module)(nil)
+
+// Module runs a compiled module in a sandbox without filesystem, network or
+// environment access. Linear memory is limited by the runtime, and each call
+// is interrupted when its timeout is reached. Instances are not safe for
+// concurrent calls, so idle ones are pooled, and an instance is dropped
+// after a failed call since its state is unknown.
+type Module struct {
+	ctx       context.Context
+	tag       string
+	timeout   time.Duration
+	runtime   wazero.Runtime
+	compiled  wazero.CompiledModule
+	instances chan api.Module
+}
+
+func NewModule(ctx context.Context, options option.WASMModuleOptions) (*Module, error) {
+	if options.Path == "" {
+		return nil, E.New("missing path")
+	}
+	content, err := os.ReadFile(filemanager.BasePath(ctx, os.ExpandEnv(options.Path)))
+	if err != nil {
+		return nil, E.Cause(err, "read module")
+	}
+	memoryLimit := uint64(DefaultMemoryLimit)
+	if options.MemoryLimit != nil {
+		memoryLimit = options.MemoryLimit.Value()
+	}
+	memoryPages := memoryLimit / memoryPageSize
+	if memoryPages == 0 {
+		memoryPages = 1
+	} else if memoryPages > 65536 {
+		memoryPages = 65536
+	}
+	timeout := time.Duration(options.Timeout)
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	moduleRuntime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(uint32(memoryPages)).
+		WithCloseOnContextDone(true))
+	_, err = wasi_snapshot_preview1.Instantiate(ctx, moduleRuntime)
+	if err != nil {
+		moduleRuntime.Close(ctx)
+		return nil, E.Cause(err, "instantiate wasi")
+	}
+	compiled, err := moduleRuntime.CompileModule(ctx, content)
+	if err != nil {
+		moduleRuntime.Close(ctx)
+		return nil, E.Cause(err, "compile module")
+	}
+	module := &Module{
+		ctx:       ctx,
+		tag:       options.Tag,
+		timeout:   timeout,
+		runtime:   moduleRuntime,
+		compiled:  compiled,
+		instances: make(chan api.Module, runtime.NumCPU()),
+	}
+	instance, err := module.instantiate()
+	if err != nil {
+		moduleRuntime.Close(ctx)
+		return nil, err
+	}
+	if instance.ExportedMemory(exportMemory) == nil {
+		moduleRuntime.Close(ctx)
+		return nil, E.New("missing exported memory")
+	}
+	if instance.ExportedFunction(exportAlloc) == nil {
+		moduleRuntime.Close(ctx)
+		return nil, E.New("missing exported function: ", exportAlloc)
+	}
+	module.put(instance)
+	return module, nil
+}
+
+func (m *Module) Tag() string {
+	return m.tag
+}
+
+func (m *Module) Close() error {
+	return m.runtime.Close(m.ctx)
+}
+
+func (m *Module) Sniff(ctx context.Context, payload []byte, isPacket bool) (*adapter.WASMSniffResult, error) {
+	var packetFlag uint64
+	if isPacket {
+		packetFlag = 1
+	}
+	var result *adapter.WASMSniffResult
+	err := m.call(ctx, exportSniff, payload, []uint64{packetFlag}, func(memory api.Memory, results []uint64) error {
+		var err error
+		result, err = decodeSniffResult(int64(results[0]), memory.Read)
+		return err
+	})
+	return result, err
+}
+
+func (m *Module) Match(ctx context.Context, function string, metadata *adapter.InboundContext) (bool, error) {
+	if function == "" {
+		function = DefaultRuleFunction
+	}
+	input, err := encodeRuleMetadata(metadata)
+	if err != nil {
+		return false, err
+	}
+	var matched bool
+	err = m.call(ctx, function, input, nil, func(memory api.Memory, results []uint64) error {
+		matched = uint32(results[0]) != 0
+		return nil
+	})
+	return matched, err
+}
+
+func (m *Module) call(ctx context.Context, function string, input []byte, arguments []uint64, handle func(memory api.Memory, results []uint64) error) error {
+	instance, err := m.get()
+	if err != nil {
+		return err
+	}
+	exportedFunction := instance.ExportedFunction(function)
+	if exportedFunction == nil {
+		m.put(instance)
+		return E.New("missing exported function: ", function)
+	}
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+	allocResults, err := instance.ExportedFunction(exportAlloc).Call(ctx, uint64(len(input)))
+	if err != nil {
+		instance.Close(m.ctx)
+		return E.Cause(err, "call ", exportAlloc)
+	}
+	inputPtr := uint32(allocResults[0])
+	memory := instance.ExportedMemory(exportMemory)
+	if !memory.Write(inputPtr, input) {
+		instance.Close(m.ctx)
+		return E.New("input out of memory range")
+	}
+	results, err := exportedFunction.Call(ctx, append([]uint64{uint64(inputPtr), uint64(len(input))}, arguments...)...)
+	if err != nil {
+		instance.Close(m.ctx)
+		return E.Cause(err, "call ", function)
+	}
+	if len(results) == 0 {
+		instance.Close(m.ctx)
+		return E.New("missing result of ", function)
+	}
+	err = handle(memory, results)
+	if dealloc := instance.ExportedFunction(exportDealloc); dealloc != nil {
+		_, deallocErr := dealloc.Call(ctx, uint64(inputPtr), uint64(len(input)))
+		if deallocErr != nil {
+			instance.Close(m.ctx)
+			return E.Errors(err, E.Cause(deallocErr, "call ", exportDealloc))
+		}
+	}
+	m.put(instance)
+	return err
+}
+
+func (m *Module) instantiate() (api.Module, error) {
+	instance, err := m.runtime.InstantiateModule(m.ctx, m.compiled, wazero.NewModuleConfig().
+		WithName("").
+		WithStartFunctions("_initialize"))
+	if err != nil {
+		return nil, E.Cause(err, "instantiate module")
+	}
+	return instance, nil
+}
+
+func (m *Module) get() (api.Module, error) {
+	select {
+	case instance := <-m.instances:
+		return instance, nil
+	default:
+		return m.instantiate()
+	}
+}
+
+func (m *Module) put(instance api.Module) {
+	select {
+	case m.instances <- instance:
+	default:
+		instance.Close(m.ctx)
+	}
+}
@@ -0,0 +1,25 @@
+package experimental
+
+import (
+	"context"
+	"os"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+)
+
+type WASMManagerConstructor = func(ctx context.Context, logger log.ContextLogger, options option.WASMOptions) (adapter.WASMManager, error)
+
+var wasmManagerConstructor WASMManagerConstructor
+
+func RegisterWASMManagerConstructor(constructor WASMManagerConstructor) {
+	wasmManagerConstructor = constructor
+}
+
+func NewWASMManager(ctx context.Context, logger log.ContextLogger, options option.WASMOptions) (adapter.WASMManager, error) {
+	if wasmManagerConstructor == nil {
+		return nil, os.ErrInvalid
+	}
+	return wasmManagerConstructor(ctx, logger, options)
+}
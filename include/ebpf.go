@@ -0,0 +1,12 @@
+//go:build with_ebpf
+
+package include
+
+import (
+	"github.com/sagernet/sing-box/adapter/service"
+	"github.com/sagernet/sing-box/service/ebpf"
+)
+
+func registerEBPFService(registry *service.Registry) {
+	ebpf.RegisterService(registry)
+}
@@ -0,0 +1,20 @@
+//go:build !with_ebpf
+
+package include
+
+import (
+	"context"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/adapter/service"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+func registerEBPFService(registry *service.Registry) {
+	service.Register[option.EBPFServiceOptions](registry, C.TypeEBPF, func(ctx context.Context, logger log.ContextLogger, tag string, options option.EBPFServiceOptions) (adapter.Service, error) {
+		return nil, E.New(`eBPF is not included in this build, rebuild with -tags with_ebpf`)
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/sagernet/sing-box/adapter/outbound"
 	"github.com/sagernet/sing-box/dns"
 	"github.com/sagernet/sing-box/dns/transport/quic"
+	"github.com/sagernet/sing-box/protocol/http3"
 	"github.com/sagernet/sing-box/protocol/hysteria"
 	"github.com/sagernet/sing-box/protocol/hysteria2"
 	_ "github.com/sagernet/sing-box/protocol/naive/quic"
@@ -18,12 +19,14 @@ func registerQUICInbounds(registry *inbound.Registry) {
 	hysteria.RegisterInbound(registry)
 	tuic.RegisterInbound(registry)
 	hysteria2.RegisterInbound(registry)
+	http3.RegisterInbound(registry)
 }
 
 func registerQUICOutbounds(registry *outbound.Registry) {
 	hysteria.RegisterOutbound(registry)
 	tuic.RegisterOutbound(registry)
 	hysteria2.RegisterOutbound(registry)
+	http3.RegisterOutbound(registry)
 }
 
 func registerQUICTransports(registry *dns.TransportRegistry) {
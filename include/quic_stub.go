@@ -44,6 +44,9 @@ func registerQUICInbounds(registry *inbound.Registry) {
 	inbound.Register[option.Hysteria2InboundOptions](registry, C.TypeHysteria2, func(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.Hysteria2InboundOptions) (adapter.Inbound, error) {
 		return nil, C.ErrQUICNotIncluded
 	})
+	inbound.Register[option.HTTP3InboundOptions](registry, C.TypeHTTP3, func(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.HTTP3InboundOptions) (adapter.Inbound, error) {
+		return nil, C.ErrQUICNotIncluded
+	})
 	naive.ConfigureHTTP3ListenerFunc = func(listener *listener.Listener, handler http.Handler, tlsConfig tls.ServerConfig, logger logger.Logger) (io.Closer, error) {
 		return nil, C.ErrQUICNotIncluded
 	}
@@ -59,6 +62,9 @@ func registerQUICOutbounds(registry *outbound.Registry) {
 	outbound.Register[option.Hysteria2OutboundOptions](registry, C.TypeHysteria2, func(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.Hysteria2OutboundOptions) (adapter.Outbound, error) {
 		return nil, C.ErrQUICNotIncluded
 	})
+	outbound.Register[option.HTTP3OutboundOptions](registry, C.TypeHTTP3, func(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.HTTP3OutboundOptions) (adapter.Outbound, error) {
+		return nil, C.ErrQUICNotIncluded
+	})
 }
 
 func registerQUICTransports(registry *dns.TransportRegistry) {
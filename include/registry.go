@@ -25,9 +25,12 @@ import (
 	"github.com/sagernet/sing-box/protocol/http"
 	"github.com/sagernet/sing-box/protocol/mixed"
 	"github.com/sagernet/sing-box/protocol/naive"
+	"github.com/sagernet/sing-box/protocol/plugin"
 	"github.com/sagernet/sing-box/protocol/redirect"
+	"github.com/sagernet/sing-box/protocol/reverse"
 	"github.com/sagernet/sing-box/protocol/shadowsocks"
 	"github.com/sagernet/sing-box/protocol/shadowtls"
+	"github.com/sagernet/sing-box/protocol/snimux"
 	"github.com/sagernet/sing-box/protocol/socks"
 	"github.com/sagernet/sing-box/protocol/ssh"
 	"github.com/sagernet/sing-box/protocol/tor"
@@ -35,13 +38,57 @@ import (
 	"github.com/sagernet/sing-box/protocol/tun"
 	"github.com/sagernet/sing-box/protocol/vless"
 	"github.com/sagernet/sing-box/protocol/vmess"
+	"github.com/sagernet/sing-box/route/rule"
+	"github.com/sagernet/sing-box/service/dhcpserver"
 	"github.com/sagernet/sing-box/service/resolved"
+	"github.com/sagernet/sing-box/service/scheduler"
 	"github.com/sagernet/sing-box/service/ssmapi"
+	"github.com/sagernet/sing-box/service/subscription"
+	"github.com/sagernet/sing-box/service/updater"
+	"github.com/sagernet/sing-box/service/webhook"
 	E "github.com/sagernet/sing/common/exceptions"
+	singService "github.com/sagernet/sing/service"
 )
 
 func Context(ctx context.Context) context.Context {
-	return box.Context(ctx, InboundRegistry(), OutboundRegistry(), EndpointRegistry(), DNSTransportRegistry(), ServiceRegistry())
+	return NewRegistries().Context(ctx)
+}
+
+// Registries holds the registries of all included types. Embedders can
+// register their own implementations to them before creating the context.
+type Registries struct {
+	Inbound      *inbound.Registry
+	Outbound     *outbound.Registry
+	Endpoint     *endpoint.Registry
+	DNSTransport *dns.TransportRegistry
+	Service      *service.Registry
+	RuleItem     *rule.ItemRegistry
+}
+
+func NewRegistries() *Registries {
+	return &Registries{
+		Inbound:      InboundRegistry(),
+		Outbound:     OutboundRegistry(),
+		Endpoint:     EndpointRegistry(),
+		DNSTransport: DNSTransportRegistry(),
+		Service:      ServiceRegistry(),
+		RuleItem:     RuleItemRegistry(),
+	}
+}
+
+func (r *Registries) Context(ctx context.Context) context.Context {
+	if singService.FromContext[adapter.RuleItemRegistry](ctx) == nil {
+		ctx = singService.ContextWith[adapter.RuleItemRegistry](ctx, r.RuleItem)
+	}
+	return box.Context(ctx, r.Inbound, r.Outbound, r.Endpoint, r.DNSTransport, r.Service)
+}
+
+func RuleItemRegistry() *rule.ItemRegistry {
+	registry := rule.NewItemRegistry()
+
+	rule.RegisterWASMItem(registry)
+
+	return registry
 }
 
 func InboundRegistry() *inbound.Registry {
@@ -63,6 +110,9 @@ func InboundRegistry() *inbound.Registry {
 	shadowtls.RegisterInbound(registry)
 	vless.RegisterInbound(registry)
 	anytls.RegisterInbound(registry)
+	plugin.RegisterInbound(registry)
+	reverse.RegisterBridge(registry)
+	snimux.RegisterInbound(registry)
 
 	registerQUICInbounds(registry)
 	registerStubForRemovedInbounds(registry)
@@ -92,6 +142,7 @@ func OutboundRegistry() *outbound.Registry {
 	shadowtls.RegisterOutbound(registry)
 	vless.RegisterOutbound(registry)
 	anytls.RegisterOutbound(registry)
+	plugin.RegisterOutbound(registry)
 
 	registerQUICOutbounds(registry)
 	registerWireGuardOutbound(registry)
@@ -105,6 +156,7 @@ func EndpointRegistry() *endpoint.Registry {
 
 	registerWireGuardEndpoint(registry)
 	registerTailscaleEndpoint(registry)
+	reverse.RegisterPortal(registry)
 
 	return registry
 }
@@ -131,10 +183,16 @@ func DNSTransportRegistry() *dns.TransportRegistry {
 func ServiceRegistry() *service.Registry {
 	registry := service.NewRegistry()
 
+	dhcpserver.RegisterService(registry)
 	resolved.RegisterService(registry)
 	ssmapi.RegisterService(registry)
+	subscription.RegisterService(registry)
+	scheduler.RegisterService(registry)
+	updater.RegisterService(registry)
+	webhook.RegisterService(registry)
 
 	registerDERPService(registry)
+	registerEBPFService(registry)
 
 	return registry
 }
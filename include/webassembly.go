@@ -0,0 +1,5 @@
+//go:build with_wasm
+
+package include
+
+import _ "github.com/sagernet/sing-box/experimental/wasm"
@@ -0,0 +1,19 @@
+//go:build !with_wasm
+
+package include
+
+import (
+	"context"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/experimental"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+func init() {
+	experimental.RegisterWASMManagerConstructor(func(ctx context.Context, logger log.ContextLogger, options option.WASMOptions) (adapter.WASMManager, error) {
+		return nil, E.New(`WebAssembly is not included in this build, rebuild with -tags with_wasm`)
+	})
+}
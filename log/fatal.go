@@ -0,0 +1,54 @@
+package log
+
+import (
+	"sync"
+	"sync/atomic"
+
+	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/sing/common/x/list"
+)
+
+var (
+	fatalAccess  sync.Mutex
+	fatalHooks   list.List[func(message string)]
+	fatalRunning atomic.Bool
+)
+
+// RegisterFatalHook registers a hook called with the message before the
+// process exits on a fatal or panic log or a panic recovered by RecoverFatal,
+// e.g. to save state for post-mortem debugging.
+func RegisterFatalHook(hook func(message string)) (unregister func()) {
+	fatalAccess.Lock()
+	element := fatalHooks.PushBack(hook)
+	fatalAccess.Unlock()
+	return func() {
+		fatalAccess.Lock()
+		fatalHooks.Remove(element)
+		fatalAccess.Unlock()
+	}
+}
+
+func runFatalHooks(message string) {
+	// hooks that log fatal errors would run again otherwise
+	if fatalRunning.Swap(true) {
+		return
+	}
+	fatalAccess.Lock()
+	var hooks []func(message string)
+	for element := fatalHooks.Front(); element != nil; element = element.Next() {
+		hooks = append(hooks, element.Value)
+	}
+	fatalAccess.Unlock()
+	for _, hook := range hooks {
+		hook(message)
+	}
+}
+
+// RecoverFatal runs the fatal hooks if the goroutine panics, then panics
+// again with the same value. It must be called by defer.
+func RecoverFatal() {
+	if err := recover(); err != nil {
+		runFatalHooks(F.ToString("panic: ", err))
+		panic(err)
+	}
+}
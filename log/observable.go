@@ -21,7 +21,7 @@ type defaultFactory struct {
 	formatter         Formatter
 	platformFormatter Formatter
 	writer            io.Writer
-	file              *os.File
+	file              *logFile
 	filePath          string
 	platformWriter    PlatformWriter
 	needObservable    bool
@@ -63,12 +63,12 @@ func NewDefaultFactory(
 
 func (f *defaultFactory) Start() error {
 	if f.filePath != "" {
-		logFile, err := filemanager.OpenFile(f.ctx, f.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		file, err := filemanager.OpenFile(f.ctx, f.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
 		if err != nil {
 			return err
 		}
-		f.writer = logFile
-		f.file = logFile
+		f.file = &logFile{file: file}
+		f.writer = f.file
 	}
 	return nil
 }
@@ -135,20 +135,24 @@ func (l *observableLogger) Log(ctx context.Context, level Level, args []any) {
 	if l.needObservable {
 		message, messageSimple := l.formatter.FormatWithSimple(ctx, level, l.tag, msgStr, nowTime)
 		if level == LevelPanic {
+			runFatalHooks(msgStr)
 			panic(message)
 		}
 		l.writer.Write([]byte(message))
 		if level == LevelFatal {
+			runFatalHooks(msgStr)
 			os.Exit(1)
 		}
 		l.subscriber.Emit(Entry{level, messageSimple})
 	} else {
 		message := l.formatter.Format(ctx, level, l.tag, msgStr, nowTime)
 		if level == LevelPanic {
+			runFatalHooks(msgStr)
 			panic(message)
 		}
 		l.writer.Write([]byte(message))
 		if level == LevelFatal {
+			runFatalHooks(msgStr)
 			os.Exit(1)
 		}
 	}
@@ -0,0 +1,96 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/service/filemanager"
+)
+
+// RotatableFactory is implemented by factories that can rotate their log
+// file.
+type RotatableFactory interface {
+	Factory
+	Rotatable() bool
+	// Rotate renames the log file with the current time as suffix and
+	// reopens it, keeping at most maxBackups renamed files if positive.
+	Rotate(maxBackups int) error
+}
+
+const rotateTimeLayout = "20060102-150405"
+
+var _ RotatableFactory = (*defaultFactory)(nil)
+
+// logFile serializes writes with rotations of the log file.
+type logFile struct {
+	access sync.Mutex
+	file   *os.File
+}
+
+func (f *logFile) Write(p []byte) (n int, err error) {
+	f.access.Lock()
+	defer f.access.Unlock()
+	return f.file.Write(p)
+}
+
+func (f *logFile) Close() error {
+	f.access.Lock()
+	defer f.access.Unlock()
+	return f.file.Close()
+}
+
+func (f *defaultFactory) Rotatable() bool {
+	return f.file != nil
+}
+
+func (f *defaultFactory) Rotate(maxBackups int) error {
+	if f.file == nil {
+		return E.New("log output is not a file")
+	}
+	path := f.file.file.Name()
+	backupPath := path + "." + time.Now().Format(rotateTimeLayout)
+	f.file.access.Lock()
+	err := os.Rename(path, backupPath)
+	if err != nil {
+		f.file.access.Unlock()
+		return E.Cause(err, "rename log file")
+	}
+	newFile, err := filemanager.OpenFile(f.ctx, f.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		f.file.access.Unlock()
+		return E.Cause(err, "reopen log file")
+	}
+	oldFile := f.file.file
+	f.file.file = newFile
+	f.file.access.Unlock()
+	oldFile.Close()
+	if maxBackups > 0 {
+		return removeBackups(path, maxBackups)
+	}
+	return nil
+}
+
+func removeBackups(path string, maxBackups int) error {
+	backups, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return err
+	}
+	backups = slices.DeleteFunc(backups, func(it string) bool {
+		_, err := time.Parse(rotateTimeLayout, strings.TrimPrefix(it, path+"."))
+		return err != nil
+	})
+	if len(backups) <= maxBackups {
+		return nil
+	}
+	// the time layout sorts in chronological order
+	slices.Sort(backups)
+	for _, backup := range backups[:len(backups)-maxBackups] {
+		err = E.Errors(err, os.Remove(backup))
+	}
+	return err
+}
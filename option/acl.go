@@ -0,0 +1,12 @@
+package option
+
+import "github.com/sagernet/sing/common/json/badoption"
+
+type InboundACLOptions struct {
+	Allow        badoption.Listable[string] `json:"allow,omitempty"`
+	Deny         badoption.Listable[string] `json:"deny,omitempty"`
+	AllowPath    string                     `json:"allow_path,omitempty"`
+	DenyPath     string                     `json:"deny_path,omitempty"`
+	AllowRuleSet badoption.Listable[string] `json:"allow_rule_set,omitempty"`
+	DenyRuleSet  badoption.Listable[string] `json:"deny_rule_set,omitempty"`
+}
@@ -0,0 +1,77 @@
+package option
+
+import (
+	C "github.com/sagernet/sing-box/constant"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/common/json/badjson"
+	"github.com/sagernet/sing/common/json/badoption"
+)
+
+type _AuthProviderOptions struct {
+	Type             string                    `json:"type"`
+	CacheTTL         badoption.Duration        `json:"cache_ttl,omitempty"`
+	NegativeCacheTTL badoption.Duration        `json:"negative_cache_ttl,omitempty"`
+	Timeout          badoption.Duration        `json:"timeout,omitempty"`
+	HTTPOptions      HTTPAuthProviderOptions   `json:"-"`
+	RADIUSOptions    RADIUSAuthProviderOptions `json:"-"`
+	LDAPOptions      LDAPAuthProviderOptions   `json:"-"`
+}
+
+type AuthProviderOptions _AuthProviderOptions
+
+func (o AuthProviderOptions) MarshalJSON() ([]byte, error) {
+	var v any
+	switch o.Type {
+	case C.AuthProviderTypeHTTP:
+		v = o.HTTPOptions
+	case C.AuthProviderTypeRADIUS:
+		v = o.RADIUSOptions
+	case C.AuthProviderTypeLDAP:
+		v = o.LDAPOptions
+	default:
+		return nil, E.New("unknown auth provider type: ", o.Type)
+	}
+	return badjson.MarshallObjects((_AuthProviderOptions)(o), v)
+}
+
+func (o *AuthProviderOptions) UnmarshalJSON(bytes []byte) error {
+	err := json.Unmarshal(bytes, (*_AuthProviderOptions)(o))
+	if err != nil {
+		return err
+	}
+	var v any
+	switch o.Type {
+	case C.AuthProviderTypeHTTP:
+		v = &o.HTTPOptions
+	case C.AuthProviderTypeRADIUS:
+		v = &o.RADIUSOptions
+	case C.AuthProviderTypeLDAP:
+		v = &o.LDAPOptions
+	case "":
+		return E.New("missing auth provider type")
+	default:
+		return E.New("unknown auth provider type: ", o.Type)
+	}
+	return badjson.UnmarshallExcluded(bytes, (*_AuthProviderOptions)(o), v)
+}
+
+type HTTPAuthProviderOptions struct {
+	DialerOptions
+	URL     string               `json:"url"`
+	Headers badoption.HTTPHeader `json:"headers,omitempty"`
+}
+
+type RADIUSAuthProviderOptions struct {
+	DialerOptions
+	ServerOptions
+	Secret        string `json:"secret"`
+	NASIdentifier string `json:"nas_identifier,omitempty"`
+}
+
+type LDAPAuthProviderOptions struct {
+	DialerOptions
+	ServerOptions
+	OutboundTLSOptionsContainer
+	BindDN string `json:"bind_dn"`
+}
@@ -0,0 +1,11 @@
+package option
+
+import "github.com/sagernet/sing/common/json/badoption"
+
+type ChaosOptions struct {
+	Latency    badoption.Duration `json:"latency,omitempty"`
+	Jitter     badoption.Duration `json:"jitter,omitempty"`
+	PacketLoss float64            `json:"packet_loss,omitempty"`
+	UpMbps     int                `json:"up_mbps,omitempty"`
+	DownMbps   int                `json:"down_mbps,omitempty"`
+}
@@ -0,0 +1,12 @@
+package option
+
+import "time"
+
+// OutboundCredential is a credential set of an outbound, used between
+// not_before and not_after.
+type OutboundCredential struct {
+	Username  string     `json:"username,omitempty"`
+	Password  string     `json:"password,omitempty"`
+	NotBefore *time.Time `json:"not_before,omitempty"`
+	NotAfter  *time.Time `json:"not_after,omitempty"`
+}
@@ -0,0 +1,25 @@
+package option
+
+import (
+	"net/netip"
+
+	"github.com/sagernet/sing/common/json/badoption"
+)
+
+type DHCPServerServiceOptions struct {
+	Interface           string                           `json:"interface"`
+	Inet4Address        *badoption.Prefix                `json:"inet4_address,omitempty"`
+	Inet4RangeStart     *badoption.Addr                  `json:"inet4_range_start,omitempty"`
+	Inet4RangeEnd       *badoption.Addr                  `json:"inet4_range_end,omitempty"`
+	LeaseTime           badoption.Duration               `json:"lease_time,omitempty"`
+	DNS                 badoption.Listable[netip.Addr]   `json:"dns,omitempty"`
+	DomainName          string                           `json:"domain_name,omitempty"`
+	StaticLease         []DHCPStaticLease                `json:"static_lease,omitempty"`
+	RouterAdvertisement bool                             `json:"router_advertisement,omitempty"`
+	Inet6Prefix         badoption.Listable[netip.Prefix] `json:"inet6_prefix,omitempty"`
+}
+
+type DHCPStaticLease struct {
+	MAC     string     `json:"mac"`
+	Address netip.Addr `json:"address"`
+}
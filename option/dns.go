@@ -381,9 +381,24 @@ type LocalDNSServerOptions struct {
 type RemoteDNSServerOptions struct {
 	RawLocalDNSServerOptions
 	DNSServerAddressOptions
-	LegacyAddressResolver      string             `json:"-"`
-	LegacyAddressStrategy      DomainStrategy     `json:"-"`
-	LegacyAddressFallbackDelay badoption.Duration `json:"-"`
+	Detours                    badoption.Listable[string] `json:"detours,omitempty"`
+	LegacyAddressResolver      string                     `json:"-"`
+	LegacyAddressStrategy      DomainStrategy             `json:"-"`
+	LegacyAddressFallbackDelay badoption.Duration         `json:"-"`
+}
+
+// DetourList returns the failover detours of the server.
+func (o *RemoteDNSServerOptions) DetourList() ([]string, error) {
+	if len(o.Detours) > 0 && o.Detour != "" {
+		return nil, E.New("`detour` and `detours` can not be set at the same time")
+	}
+	return o.Detours, nil
+}
+
+// SetDetour replaces the failover detours of the server with detour.
+func (o *RemoteDNSServerOptions) SetDetour(detour string) {
+	o.Detour = detour
+	o.Detours = nil
 }
 
 type RemoteTLSDNSServerOptions struct {
@@ -405,5 +420,7 @@ type FakeIPDNSServerOptions struct {
 
 type DHCPDNSServerOptions struct {
 	LocalDNSServerOptions
-	Interface string `json:"interface,omitempty"`
+	Interface      string                                   `json:"interface,omitempty"`
+	ServerOverride map[string]string                        `json:"server_override,omitempty"`
+	ExcludeServer  badoption.Listable[badoption.Prefixable] `json:"exclude_server,omitempty"`
 }
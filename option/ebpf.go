@@ -0,0 +1,10 @@
+package option
+
+import "github.com/sagernet/sing/common/json/badoption"
+
+type EBPFServiceOptions struct {
+	CgroupPath    string                     `json:"cgroup_path,omitempty"`
+	LocalRedirect bool                       `json:"local_redirect,omitempty"`
+	BypassCgroup  badoption.Listable[string] `json:"bypass_cgroup,omitempty"`
+	BypassMark    FwMark                     `json:"bypass_mark,omitempty"`
+}
@@ -3,11 +3,18 @@ package option
 import "github.com/sagernet/sing/common/json/badoption"
 
 type ExperimentalOptions struct {
-	CacheFile *CacheFileOptions `json:"cache_file,omitempty"`
-	ClashAPI  *ClashAPIOptions  `json:"clash_api,omitempty"`
-	V2RayAPI  *V2RayAPIOptions  `json:"v2ray_api,omitempty"`
-	Debug     *DebugOptions     `json:"debug,omitempty"`
-	URLTestUnifiedDelay bool    `json:"urltest_unified_delay,omitempty"`
+	CacheFile           *CacheFileOptions `json:"cache_file,omitempty"`
+	ClashAPI            *ClashAPIOptions  `json:"clash_api,omitempty"`
+	V2RayAPI            *V2RayAPIOptions  `json:"v2ray_api,omitempty"`
+	Debug               *DebugOptions     `json:"debug,omitempty"`
+	IOURing             *IOURingOptions   `json:"io_uring,omitempty"`
+	WASM                *WASMOptions      `json:"wasm,omitempty"`
+	URLTestUnifiedDelay bool              `json:"urltest_unified_delay,omitempty"`
+}
+
+type IOURingOptions struct {
+	Enabled bool   `json:"enabled,omitempty"`
+	Entries uint32 `json:"entries,omitempty"`
 }
 
 type CacheFileOptions struct {
@@ -17,6 +24,8 @@ type CacheFileOptions struct {
 	StoreFakeIP bool               `json:"store_fakeip,omitempty"`
 	StoreRDRC   bool               `json:"store_rdrc,omitempty"`
 	RDRCTimeout badoption.Duration `json:"rdrc_timeout,omitempty"`
+
+	StoreUDPSession bool `json:"store_udp_session,omitempty"`
 }
 
 type ClashAPIOptions struct {
@@ -26,9 +35,11 @@ type ClashAPIOptions struct {
 	ExternalUIDownloadDetour         string                     `json:"external_ui_download_detour,omitempty"`
 	Secret                           string                     `json:"secret,omitempty"`
 	DefaultMode                      string                     `json:"default_mode,omitempty"`
+	Modes                            badoption.Listable[string] `json:"modes,omitempty"`
 	ModeList                         []string                   `json:"-"`
 	AccessControlAllowOrigin         badoption.Listable[string] `json:"access_control_allow_origin,omitempty"`
 	AccessControlAllowPrivateNetwork bool                       `json:"access_control_allow_private_network,omitempty"`
+	ConnectionDumpPath               string                     `json:"connection_dump_path,omitempty"`
 
 	// Deprecated: migrated to global cache file
 	CacheFile string `json:"cache_file,omitempty"`
@@ -0,0 +1,10 @@
+package option
+
+import "github.com/sagernet/sing/common/json/badoption"
+
+type FallbackHTTPOptions struct {
+	Root    string                     `json:"root,omitempty"`
+	Index   badoption.Listable[string] `json:"index,omitempty"`
+	Proxy   string                     `json:"proxy,omitempty"`
+	Headers badoption.HTTPHeader       `json:"headers,omitempty"`
+}
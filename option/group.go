@@ -9,18 +9,28 @@ type SelectorOutboundOptions struct {
 }
 
 type URLTestOutboundOptions struct {
-	Outbounds                 []string           `json:"outbounds"`
-	URL                       string             `json:"url,omitempty"`
-	Interval                  badoption.Duration `json:"interval,omitempty"`
-	Tolerance                 uint16             `json:"tolerance,omitempty"`
-	IdleTimeout               badoption.Duration `json:"idle_timeout,omitempty"`
-	InterruptExistConnections bool               `json:"interrupt_exist_connections,omitempty"`
+	Outbounds                 []string            `json:"outbounds"`
+	URL                       string              `json:"url,omitempty"`
+	Interval                  badoption.Duration  `json:"interval,omitempty"`
+	Tolerance                 uint16              `json:"tolerance,omitempty"`
+	IdleTimeout               badoption.Duration  `json:"idle_timeout,omitempty"`
+	SLA                       *OutboundSLAOptions `json:"sla,omitempty"`
+	InterruptExistConnections bool                `json:"interrupt_exist_connections,omitempty"`
 }
 
 type FallbackOutboundOptions struct {
-	Outbounds                 []string           `json:"outbounds"`
-	URL                       string             `json:"url,omitempty"`
-	Interval                  badoption.Duration `json:"interval,omitempty"`
-	IdleTimeout               badoption.Duration `json:"idle_timeout,omitempty"`
-	InterruptExistConnections bool               `json:"interrupt_exist_connections,omitempty"`
+	Outbounds                 []string            `json:"outbounds"`
+	URL                       string              `json:"url,omitempty"`
+	Interval                  badoption.Duration  `json:"interval,omitempty"`
+	IdleTimeout               badoption.Duration  `json:"idle_timeout,omitempty"`
+	SLA                       *OutboundSLAOptions `json:"sla,omitempty"`
+	InterruptExistConnections bool                `json:"interrupt_exist_connections,omitempty"`
+}
+
+type OutboundSLAOptions struct {
+	MaxFailures   uint16             `json:"max_failures,omitempty"`
+	MaxP95Latency badoption.Duration `json:"max_p95_latency,omitempty"`
+	LatencyWindow uint16             `json:"latency_window,omitempty"`
+	Quarantine    badoption.Duration `json:"quarantine,omitempty"`
+	MaxQuarantine badoption.Duration `json:"max_quarantine,omitempty"`
 }
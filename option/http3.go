@@ -0,0 +1,26 @@
+package option
+
+import (
+	"github.com/sagernet/sing/common/auth"
+	"github.com/sagernet/sing/common/json/badoption"
+)
+
+type HTTP3InboundOptions struct {
+	ListenOptions
+	Users            []auth.User                  `json:"users,omitempty"`
+	UserStatus       map[string]UserStatusOptions `json:"user_status,omitempty"`
+	AuthProvider     *AuthProviderOptions         `json:"auth_provider,omitempty"`
+	ZeroRTTHandshake bool                         `json:"zero_rtt_handshake,omitempty"`
+	InboundTLSOptionsContainer
+}
+
+type HTTP3OutboundOptions struct {
+	DialerOptions
+	ServerOptions
+	Username         string               `json:"username,omitempty"`
+	Password         string               `json:"password,omitempty"`
+	Headers          badoption.HTTPHeader `json:"headers,omitempty"`
+	ZeroRTTHandshake bool                 `json:"zero_rtt_handshake,omitempty"`
+	Network          NetworkList          `json:"network,omitempty"`
+	OutboundTLSOptionsContainer
+}
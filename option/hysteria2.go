@@ -12,12 +12,14 @@ import (
 
 type Hysteria2InboundOptions struct {
 	ListenOptions
-	UpMbps                int             `json:"up_mbps,omitempty"`
-	DownMbps              int             `json:"down_mbps,omitempty"`
-	Obfs                  *Hysteria2Obfs  `json:"obfs,omitempty"`
-	Users                 []Hysteria2User `json:"users,omitempty"`
-	IgnoreClientBandwidth bool            `json:"ignore_client_bandwidth,omitempty"`
+	UpMbps                int                  `json:"up_mbps,omitempty"`
+	DownMbps              int                  `json:"down_mbps,omitempty"`
+	Obfs                  *Hysteria2Obfs       `json:"obfs,omitempty"`
+	Users                 []Hysteria2User      `json:"users,omitempty"`
+	IgnoreClientBandwidth bool                 `json:"ignore_client_bandwidth,omitempty"`
+	AuthProvider          *AuthProviderOptions `json:"auth_provider,omitempty"`
 	InboundTLSOptionsContainer
+	QUICMTUOptions
 	Masquerade  *Hysteria2Masquerade `json:"masquerade,omitempty"`
 	BrutalDebug bool                 `json:"brutal_debug,omitempty"`
 }
@@ -30,6 +32,9 @@ type Hysteria2Obfs struct {
 type Hysteria2User struct {
 	Name     string `json:"name,omitempty"`
 	Password string `json:"password,omitempty"`
+	UpMbps   int    `json:"up_mbps,omitempty"`
+	DownMbps int    `json:"down_mbps,omitempty"`
+	UserStatusOptions
 }
 
 type _Hysteria2Masquerade struct {
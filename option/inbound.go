@@ -59,19 +59,28 @@ type InboundOptions struct {
 }
 
 type ListenOptions struct {
-	Listen               *badoption.Addr    `json:"listen,omitempty"`
-	ListenPort           uint16             `json:"listen_port,omitempty"`
-	BindInterface        string             `json:"bind_interface,omitempty"`
-	RoutingMark          FwMark             `json:"routing_mark,omitempty"`
-	ReuseAddr            bool               `json:"reuse_addr,omitempty"`
-	NetNs                string             `json:"netns,omitempty"`
-	TCPKeepAlive         badoption.Duration `json:"tcp_keep_alive,omitempty"`
-	TCPKeepAliveInterval badoption.Duration `json:"tcp_keep_alive_interval,omitempty"`
-	TCPFastOpen          bool               `json:"tcp_fast_open,omitempty"`
-	TCPMultiPath         bool               `json:"tcp_multi_path,omitempty"`
-	UDPFragment          *bool              `json:"udp_fragment,omitempty"`
-	UDPFragmentDefault   bool               `json:"-"`
-	UDPTimeout           UDPTimeoutCompat   `json:"udp_timeout,omitempty"`
+	Listen               *badoption.Addr      `json:"listen,omitempty"`
+	ListenPort           uint16               `json:"listen_port,omitempty"`
+	BindInterface        string               `json:"bind_interface,omitempty"`
+	RoutingMark          FwMark               `json:"routing_mark,omitempty"`
+	ReuseAddr            bool                 `json:"reuse_addr,omitempty"`
+	NetNs                string               `json:"netns,omitempty"`
+	TCPKeepAlive         badoption.Duration   `json:"tcp_keep_alive,omitempty"`
+	TCPKeepAliveInterval badoption.Duration   `json:"tcp_keep_alive_interval,omitempty"`
+	TCPFastOpen          bool                 `json:"tcp_fast_open,omitempty"`
+	TCPMultiPath         bool                 `json:"tcp_multi_path,omitempty"`
+	TCPCongestionControl string               `json:"tcp_congestion_control,omitempty"`
+	TCPBrutal            *TCPBrutalOptions    `json:"tcp_brutal,omitempty"`
+	TCPWorkers           int                  `json:"tcp_workers,omitempty"`
+	TCPWorkerCPUAffinity bool                 `json:"tcp_worker_cpu_affinity,omitempty"`
+	UDPFragment          *bool                `json:"udp_fragment,omitempty"`
+	UDPFragmentDefault   bool                 `json:"-"`
+	UDPTimeout           UDPTimeoutCompat     `json:"udp_timeout,omitempty"`
+	UDPBatch             bool                 `json:"udp_batch,omitempty"`
+	PortMapping          bool                 `json:"port_mapping,omitempty"`
+	Knock                *InboundKnockOptions `json:"knock,omitempty"`
+	ACL                  *InboundACLOptions   `json:"acl,omitempty"`
+	Padding              *PaddingOptions      `json:"padding,omitempty"`
 
 	// Deprecated: removed
 	ProxyProtocol bool `json:"proxy_protocol,omitempty"`
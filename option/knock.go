@@ -0,0 +1,15 @@
+package option
+
+import "github.com/sagernet/sing/common/json/badoption"
+
+type InboundKnockOptions struct {
+	ListenPort uint16             `json:"listen_port"`
+	Key        string             `json:"key"`
+	Timeout    badoption.Duration `json:"timeout,omitempty"`
+}
+
+type OutboundKnockOptions struct {
+	ServerPort    uint16          `json:"server_port"`
+	Key           string          `json:"key"`
+	SourceAddress *badoption.Addr `json:"source_address,omitempty"`
+}
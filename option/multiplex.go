@@ -16,6 +16,11 @@ type OutboundMultiplexOptions struct {
 	Brutal         *BrutalOptions `json:"brutal,omitempty"`
 }
 
+type TCPBrutalOptions struct {
+	Enabled  bool `json:"enabled,omitempty"`
+	SendMbps int  `json:"send_mbps,omitempty"`
+}
+
 type BrutalOptions struct {
 	Enabled  bool `json:"enabled,omitempty"`
 	UpMbps   int  `json:"up_mbps,omitempty"`
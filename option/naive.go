@@ -4,7 +4,9 @@ import "github.com/sagernet/sing/common/auth"
 
 type NaiveInboundOptions struct {
 	ListenOptions
-	Users   []auth.User `json:"users,omitempty"`
-	Network NetworkList `json:"network,omitempty"`
+	Users        []auth.User                  `json:"users,omitempty"`
+	UserStatus   map[string]UserStatusOptions `json:"user_status,omitempty"`
+	AuthProvider *AuthProviderOptions         `json:"auth_provider,omitempty"`
+	Network      NetworkList                  `json:"network,omitempty"`
 	InboundTLSOptionsContainer
 }
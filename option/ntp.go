@@ -6,6 +6,8 @@ type NTPOptions struct {
 	Enabled       bool               `json:"enabled,omitempty"`
 	Interval      badoption.Duration `json:"interval,omitempty"`
 	WriteToSystem bool               `json:"write_to_system,omitempty"`
+	Listen        *badoption.Addr    `json:"listen,omitempty"`
+	ListenPort    uint16             `json:"listen_port,omitempty"`
 	ServerOptions
 	DialerOptions
 }
@@ -20,6 +20,8 @@ type _Options struct {
 	Outbounds    []Outbound           `json:"outbounds,omitempty"`
 	Route        *RouteOptions        `json:"route,omitempty"`
 	Services     []Service            `json:"services,omitempty"`
+	Privilege    *PrivilegeOptions    `json:"privilege,omitempty"`
+	PowerSaving  *PowerSavingOptions  `json:"power_saving,omitempty"`
 	Experimental *ExperimentalOptions `json:"experimental,omitempty"`
 }
 
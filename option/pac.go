@@ -0,0 +1,13 @@
+package option
+
+import "github.com/sagernet/sing/common/json/badoption"
+
+type HTTPPACOptions struct {
+	Enabled            bool                       `json:"enabled,omitempty"`
+	Path               string                     `json:"path,omitempty"`
+	Proxy              string                     `json:"proxy,omitempty"`
+	BypassOutbound     badoption.Listable[string] `json:"bypass_outbound,omitempty"`
+	BypassDomain       badoption.Listable[string] `json:"bypass_domain,omitempty"`
+	BypassDomainSuffix badoption.Listable[string] `json:"bypass_domain_suffix,omitempty"`
+	BypassIPCIDR       badoption.Listable[string] `json:"bypass_ip_cidr,omitempty"`
+}
@@ -0,0 +1,11 @@
+package option
+
+import "github.com/sagernet/sing/common/json/badoption"
+
+type PaddingOptions struct {
+	Password      string                     `json:"password,omitempty"`
+	PacketSizes   badoption.Listable[uint16] `json:"packet_sizes,omitempty"`
+	DummyInterval badoption.Duration         `json:"dummy_interval,omitempty"`
+	BurstSize     uint32                     `json:"burst_size,omitempty"`
+	BurstInterval badoption.Duration         `json:"burst_interval,omitempty"`
+}
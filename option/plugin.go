@@ -0,0 +1,23 @@
+package option
+
+import (
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/common/json/badoption"
+)
+
+type PluginOptions struct {
+	Plugin  string                     `json:"plugin"`
+	Path    string                     `json:"path"`
+	Args    badoption.Listable[string] `json:"args,omitempty"`
+	Env     map[string]string          `json:"env,omitempty"`
+	Options json.RawMessage            `json:"options,omitempty"`
+}
+
+type PluginInboundOptions struct {
+	PluginOptions
+}
+
+type PluginOutboundOptions struct {
+	PluginOptions
+	Network NetworkList `json:"network,omitempty"`
+}
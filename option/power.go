@@ -0,0 +1,10 @@
+package option
+
+import "github.com/sagernet/sing/common/json/badoption"
+
+type PowerSavingOptions struct {
+	Enabled             bool               `json:"enabled,omitempty"`
+	Always              bool               `json:"always,omitempty"`
+	KeepAliveMultiplier uint32             `json:"keep_alive_multiplier,omitempty"`
+	TimerWindow         badoption.Duration `json:"timer_window,omitempty"`
+}
@@ -0,0 +1,10 @@
+package option
+
+import "github.com/sagernet/sing/common/json/badoption"
+
+type PrivilegeOptions struct {
+	User                   string                     `json:"user,omitempty"`
+	Group                  string                     `json:"group,omitempty"`
+	KeepCapabilities       badoption.Listable[string] `json:"keep_capabilities,omitempty"`
+	KeepReloadCapabilities bool                       `json:"keep_reload_capabilities,omitempty"`
+}
@@ -0,0 +1,7 @@
+package option
+
+type QUICMTUOptions struct {
+	InitialMTU          uint16 `json:"initial_mtu,omitempty"`
+	DisableMTUDiscovery bool   `json:"disable_mtu_discovery,omitempty"`
+	MaxDatagramSize     uint16 `json:"max_datagram_size,omitempty"`
+}
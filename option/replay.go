@@ -0,0 +1,25 @@
+package option
+
+import "github.com/sagernet/sing/common/json/badoption"
+
+type ReplayProtectionOptions struct {
+	TTL    badoption.Duration   `json:"ttl,omitempty"`
+	Redis  *ReplayRedisOptions  `json:"redis,omitempty"`
+	Gossip *ReplayGossipOptions `json:"gossip,omitempty"`
+}
+
+type ReplayRedisOptions struct {
+	DialerOptions
+	ServerOptions
+	Username  string `json:"username,omitempty"`
+	Password  string `json:"password,omitempty"`
+	DB        int    `json:"db,omitempty"`
+	KeyPrefix string `json:"key_prefix,omitempty"`
+}
+
+type ReplayGossipOptions struct {
+	Listen     *badoption.Addr            `json:"listen,omitempty"`
+	ListenPort uint16                     `json:"listen_port"`
+	Peers      badoption.Listable[string] `json:"peers"`
+	Key        string                     `json:"key"`
+}
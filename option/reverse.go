@@ -0,0 +1,21 @@
+package option
+
+type PortalEndpointOptions struct {
+	ListenOptions
+	Password string `json:"password,omitempty"`
+	InboundTLSOptionsContainer
+}
+
+type BridgeInboundOptions struct {
+	DialerOptions
+	ServerOptions
+	Password    string                 `json:"password,omitempty"`
+	Connections int                    `json:"connections,omitempty"`
+	Services    []BridgeServiceOptions `json:"services,omitempty"`
+	OutboundTLSOptionsContainer
+}
+
+type BridgeServiceOptions struct {
+	Network NetworkList `json:"network,omitempty"`
+	ServerOptions
+}
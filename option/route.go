@@ -1,6 +1,9 @@
 package option
 
-import "github.com/sagernet/sing/common/json/badoption"
+import (
+	"github.com/sagernet/sing/common/byteformats"
+	"github.com/sagernet/sing/common/json/badoption"
+)
 
 type RouteOptions struct {
 	GeoIP                      *GeoIPOptions                     `json:"geoip,omitempty"`
@@ -18,6 +21,44 @@ type RouteOptions struct {
 	DefaultNetworkType         badoption.Listable[InterfaceType] `json:"default_network_type,omitempty"`
 	DefaultFallbackNetworkType badoption.Listable[InterfaceType] `json:"default_fallback_network_type,omitempty"`
 	DefaultFallbackDelay       badoption.Duration                `json:"default_fallback_delay,omitempty"`
+	UDPSession                 *UDPSessionOptions                `json:"udp_session,omitempty"`
+	Budget                     *BudgetOptions                    `json:"budget,omitempty"`
+	Sniff                      *RouteSniffOptions                `json:"sniff,omitempty"`
+	Drain                      *DrainOptions                     `json:"drain,omitempty"`
+}
+
+// DrainOptions are used by drains started without explicit inbounds or
+// timeout, such as by the drain signal.
+type DrainOptions struct {
+	Inbounds badoption.Listable[string] `json:"inbounds,omitempty"`
+	Timeout  badoption.Duration         `json:"timeout,omitempty"`
+}
+
+type RouteSniffOptions struct {
+	RouteActionSniff
+	Inbounds map[string]RouteActionSniff `json:"inbounds,omitempty"`
+}
+
+type UDPSessionOptions struct {
+	MaxSessions        int                           `json:"max_sessions,omitempty"`
+	InboundMaxSessions map[string]int                `json:"inbound_max_sessions,omitempty"`
+	Timeout            badoption.Duration            `json:"timeout,omitempty"`
+	ProtocolTimeouts   map[string]badoption.Duration `json:"protocol_timeouts,omitempty"`
+	QUICAffinity       bool                          `json:"quic_affinity,omitempty"`
+}
+
+type BudgetOptions struct {
+	InboundConnections int                             `json:"inbound_connections,omitempty"`
+	InboundSniffBuffer *byteformats.MemoryBytes        `json:"inbound_sniff_buffer,omitempty"`
+	UserConnections    int                             `json:"user_connections,omitempty"`
+	SniffBuffer        *byteformats.MemoryBytes        `json:"sniff_buffer,omitempty"`
+	Inbounds           map[string]InboundBudgetOptions `json:"inbounds,omitempty"`
+	WaitTimeout        badoption.Duration              `json:"wait_timeout,omitempty"`
+}
+
+type InboundBudgetOptions struct {
+	Connections int                      `json:"connections,omitempty"`
+	SniffBuffer *byteformats.MemoryBytes `json:"sniff_buffer,omitempty"`
 }
 
 type GeoIPOptions struct {
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing/common/byteformats"
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/json"
 	"github.com/sagernet/sing/common/json/badjson"
@@ -155,10 +156,27 @@ type RawRouteOptionsActionOptions struct {
 	UDPDisableDomainUnmapping bool               `json:"udp_disable_domain_unmapping,omitempty"`
 	UDPConnect                bool               `json:"udp_connect,omitempty"`
 	UDPTimeout                badoption.Duration `json:"udp_timeout,omitempty"`
+	UDPNATMapping             string             `json:"udp_nat_mapping,omitempty"`
+	UDPNATFiltering           string             `json:"udp_nat_filtering,omitempty"`
+	UDPOverTCP                string             `json:"udp_over_tcp,omitempty"`
+
+	Multiplex        string `json:"multiplex,omitempty"`
+	MultiplexPadding bool   `json:"multiplex_padding,omitempty"`
 
 	TLSFragment              bool               `json:"tls_fragment,omitempty"`
 	TLSFragmentFallbackDelay badoption.Duration `json:"tls_fragment_fallback_delay,omitempty"`
 	TLSRecordFragment        bool               `json:"tls_record_fragment,omitempty"`
+
+	RoutingMark FwMark `json:"routing_mark,omitempty"`
+	DSCP        uint8  `json:"dscp,omitempty"`
+
+	TCPKeepAlive         badoption.Duration `json:"tcp_keep_alive,omitempty"`
+	TCPKeepAliveInterval badoption.Duration `json:"tcp_keep_alive_interval,omitempty"`
+	TCPIdleTimeout       badoption.Duration `json:"tcp_idle_timeout,omitempty"`
+	TCPDisableHalfClose  bool               `json:"tcp_disable_half_close,omitempty"`
+	TCPHalfCloseTimeout  badoption.Duration `json:"tcp_half_close_timeout,omitempty"`
+
+	Chaos *ChaosOptions `json:"chaos,omitempty"`
 }
 
 type RouteOptionsActionOptions RawRouteOptionsActionOptions
@@ -293,8 +311,9 @@ func (r *RejectActionOptions) UnmarshalJSON(bytes []byte) error {
 }
 
 type RouteActionSniff struct {
-	Sniffer badoption.Listable[string] `json:"sniffer,omitempty"`
-	Timeout badoption.Duration         `json:"timeout,omitempty"`
+	Sniffer    badoption.Listable[string] `json:"sniffer,omitempty"`
+	Timeout    badoption.Duration         `json:"timeout,omitempty"`
+	BufferSize *byteformats.MemoryBytes   `json:"buffer_size,omitempty"`
 }
 
 type RouteActionResolve struct {
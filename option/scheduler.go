@@ -0,0 +1,30 @@
+package option
+
+import "github.com/sagernet/sing/common/json/badoption"
+
+type SchedulerServiceOptions struct {
+	Tasks []SchedulerTaskOptions `json:"tasks"`
+}
+
+type SchedulerTaskOptions struct {
+	Name     string `json:"name,omitempty"`
+	Schedule string `json:"schedule"`
+	Action   string `json:"action"`
+
+	// select
+	Selector string `json:"selector,omitempty"`
+	Outbound string `json:"outbound,omitempty"`
+
+	// update_subscription and update_rule_set
+	Subscription badoption.Listable[string] `json:"subscription,omitempty"`
+	RuleSet      badoption.Listable[string] `json:"rule_set,omitempty"`
+
+	// rotate_log
+	MaxBackups int `json:"max_backups,omitempty"`
+
+	// health_report
+	URL     string               `json:"url,omitempty"`
+	Headers badoption.HTTPHeader `json:"headers,omitempty"`
+	Detour  string               `json:"detour,omitempty"`
+	Timeout badoption.Duration   `json:"timeout,omitempty"`
+}
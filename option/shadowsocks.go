@@ -2,18 +2,20 @@ package option
 
 type ShadowsocksInboundOptions struct {
 	ListenOptions
-	Network      NetworkList              `json:"network,omitempty"`
-	Method       string                   `json:"method"`
-	Password     string                   `json:"password,omitempty"`
-	Users        []ShadowsocksUser        `json:"users,omitempty"`
-	Destinations []ShadowsocksDestination `json:"destinations,omitempty"`
-	Multiplex    *InboundMultiplexOptions `json:"multiplex,omitempty"`
-	Managed      bool                     `json:"managed,omitempty"`
+	Network          NetworkList              `json:"network,omitempty"`
+	Method           string                   `json:"method"`
+	Password         string                   `json:"password,omitempty"`
+	Users            []ShadowsocksUser        `json:"users,omitempty"`
+	Destinations     []ShadowsocksDestination `json:"destinations,omitempty"`
+	Multiplex        *InboundMultiplexOptions `json:"multiplex,omitempty"`
+	Managed          bool                     `json:"managed,omitempty"`
+	ReplayProtection *ReplayProtectionOptions `json:"replay_protection,omitempty"`
 }
 
 type ShadowsocksUser struct {
 	Name     string `json:"name"`
 	Password string `json:"password"`
+	UserStatusOptions
 }
 
 type ShadowsocksDestination struct {
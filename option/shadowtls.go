@@ -65,11 +65,14 @@ func (w *WildcardSNI) UnmarshalJSON(bytes []byte) error {
 type ShadowTLSUser struct {
 	Name     string `json:"name,omitempty"`
 	Password string `json:"password,omitempty"`
+	Detour   string `json:"detour,omitempty"`
+	UserStatusOptions
 }
 
 type ShadowTLSHandshakeOptions struct {
 	ServerOptions
 	DialerOptions
+	StrictMode *bool `json:"strict_mode,omitempty"`
 }
 
 type ShadowTLSOutboundOptions struct {
@@ -7,33 +7,50 @@ import (
 
 type SocksInboundOptions struct {
 	ListenOptions
-	Users          []auth.User           `json:"users,omitempty"`
-	DomainResolver *DomainResolveOptions `json:"domain_resolver,omitempty"`
+	Users          []auth.User                  `json:"users,omitempty"`
+	UserStatus     map[string]UserStatusOptions `json:"user_status,omitempty"`
+	AuthProvider   *AuthProviderOptions         `json:"auth_provider,omitempty"`
+	DomainResolver *DomainResolveOptions        `json:"domain_resolver,omitempty"`
+	UDPRelay       *SOCKSUDPRelayOptions        `json:"udp_relay,omitempty"`
+	UDPInTCP       bool                         `json:"udp_in_tcp,omitempty"`
+}
+
+type SOCKSUDPRelayOptions struct {
+	SharedPort       bool            `json:"shared_port,omitempty"`
+	AdvertiseAddress *badoption.Addr `json:"advertise_address,omitempty"`
+	AdvertisePort    uint16          `json:"advertise_port,omitempty"`
 }
 
 type HTTPMixedInboundOptions struct {
 	ListenOptions
-	Users          []auth.User           `json:"users,omitempty"`
-	DomainResolver *DomainResolveOptions `json:"domain_resolver,omitempty"`
-	SetSystemProxy bool                  `json:"set_system_proxy,omitempty"`
+	Users          []auth.User                  `json:"users,omitempty"`
+	UserStatus     map[string]UserStatusOptions `json:"user_status,omitempty"`
+	AuthProvider   *AuthProviderOptions         `json:"auth_provider,omitempty"`
+	DomainResolver *DomainResolveOptions        `json:"domain_resolver,omitempty"`
+	UDPRelay       *SOCKSUDPRelayOptions        `json:"udp_relay,omitempty"`
+	UDPInTCP       bool                         `json:"udp_in_tcp,omitempty"`
+	SetSystemProxy bool                         `json:"set_system_proxy,omitempty"`
+	PAC            *HTTPPACOptions              `json:"pac,omitempty"`
 	InboundTLSOptionsContainer
 }
 
 type SOCKSOutboundOptions struct {
 	DialerOptions
 	ServerOptions
-	Version    string             `json:"version,omitempty"`
-	Username   string             `json:"username,omitempty"`
-	Password   string             `json:"password,omitempty"`
-	Network    NetworkList        `json:"network,omitempty"`
-	UDPOverTCP *UDPOverTCPOptions `json:"udp_over_tcp,omitempty"`
+	Version     string               `json:"version,omitempty"`
+	Username    string               `json:"username,omitempty"`
+	Password    string               `json:"password,omitempty"`
+	Credentials []OutboundCredential `json:"credentials,omitempty"`
+	Network     NetworkList          `json:"network,omitempty"`
+	UDPOverTCP  *UDPOverTCPOptions   `json:"udp_over_tcp,omitempty"`
 }
 
 type HTTPOutboundOptions struct {
 	DialerOptions
 	ServerOptions
-	Username string `json:"username,omitempty"`
-	Password string `json:"password,omitempty"`
+	Username    string               `json:"username,omitempty"`
+	Password    string               `json:"password,omitempty"`
+	Credentials []OutboundCredential `json:"credentials,omitempty"`
 	OutboundTLSOptionsContainer
 	Path    string               `json:"path,omitempty"`
 	Headers badoption.HTTPHeader `json:"headers,omitempty"`
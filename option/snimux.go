@@ -0,0 +1,20 @@
+package option
+
+import "github.com/sagernet/sing/common/json/badoption"
+
+type SNIMuxInboundOptions struct {
+	ListenOptions
+	Rules    []SNIMuxRule  `json:"rules,omitempty"`
+	Fallback *SNIMuxTarget `json:"fallback,omitempty"`
+}
+
+type SNIMuxRule struct {
+	ServerName badoption.Listable[string] `json:"server_name,omitempty"`
+	ALPN       badoption.Listable[string] `json:"alpn,omitempty"`
+	SNIMuxTarget
+}
+
+type SNIMuxTarget struct {
+	Inbound string `json:"inbound,omitempty"`
+	ServerOptions
+}
@@ -4,13 +4,24 @@ import "github.com/sagernet/sing/common/json/badoption"
 
 type SSHOutboundOptions struct {
 	DialerOptions
+	SSHHostOptions
+	Jump              []SSHHostOptions           `json:"jump,omitempty"`
+	KnownHosts        badoption.Listable[string] `json:"known_hosts,omitempty"`
+	HostKeyTOFU       bool                       `json:"host_key_tofu,omitempty"`
+	KeepAliveInterval badoption.Duration         `json:"keep_alive_interval,omitempty"`
+	KeepAliveCountMax int                        `json:"keep_alive_count_max,omitempty"`
+	ClientVersion     string                     `json:"client_version,omitempty"`
+}
+
+type SSHHostOptions struct {
 	ServerOptions
 	User                 string                     `json:"user,omitempty"`
 	Password             string                     `json:"password,omitempty"`
 	PrivateKey           badoption.Listable[string] `json:"private_key,omitempty"`
 	PrivateKeyPath       string                     `json:"private_key_path,omitempty"`
 	PrivateKeyPassphrase string                     `json:"private_key_passphrase,omitempty"`
+	Agent                bool                       `json:"agent,omitempty"`
+	AgentPath            string                     `json:"agent_path,omitempty"`
 	HostKey              badoption.Listable[string] `json:"host_key,omitempty"`
 	HostKeyAlgorithms    badoption.Listable[string] `json:"host_key_algorithms,omitempty"`
-	ClientVersion        string                     `json:"client_version,omitempty"`
 }
@@ -0,0 +1,28 @@
+package option
+
+import "github.com/sagernet/sing/common/json/badoption"
+
+type SubscriptionServiceOptions struct {
+	URL            string                     `json:"url"`
+	UserAgent      string                     `json:"user_agent,omitempty"`
+	DownloadDetour string                     `json:"download_detour,omitempty"`
+	UpdateInterval badoption.Duration         `json:"update_interval,omitempty"`
+	TagPrefix      string                     `json:"tag_prefix,omitempty"`
+	Include        badoption.Listable[string] `json:"include,omitempty"`
+	Exclude        badoption.Listable[string] `json:"exclude,omitempty"`
+	Groups         []SubscriptionGroupOptions `json:"groups,omitempty"`
+}
+
+type SubscriptionGroupOptions struct {
+	Type                      string                     `json:"type"`
+	Tag                       string                     `json:"tag"`
+	Include                   badoption.Listable[string] `json:"include,omitempty"`
+	Exclude                   badoption.Listable[string] `json:"exclude,omitempty"`
+	Outbounds                 badoption.Listable[string] `json:"outbounds,omitempty"`
+	Default                   string                     `json:"default,omitempty"`
+	URL                       string                     `json:"url,omitempty"`
+	Interval                  badoption.Duration         `json:"interval,omitempty"`
+	Tolerance                 uint16                     `json:"tolerance,omitempty"`
+	IdleTimeout               badoption.Duration         `json:"idle_timeout,omitempty"`
+	InterruptExistConnections bool                       `json:"interrupt_exist_connections,omitempty"`
+}
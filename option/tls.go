@@ -98,6 +98,8 @@ type OutboundTLSOptions struct {
 	Enabled                    bool                                `json:"enabled,omitempty"`
 	DisableSNI                 bool                                `json:"disable_sni,omitempty"`
 	ServerName                 string                              `json:"server_name,omitempty"`
+	FrontingDomain             string                              `json:"fronting_domain,omitempty"`
+	FakeSNI                    string                              `json:"fake_sni,omitempty"`
 	Insecure                   bool                                `json:"insecure,omitempty"`
 	ALPN                       badoption.Listable[string]          `json:"alpn,omitempty"`
 	MinVersion                 string                              `json:"min_version,omitempty"`
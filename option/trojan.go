@@ -6,6 +6,7 @@ type TrojanInboundOptions struct {
 	InboundTLSOptionsContainer
 	Fallback        *ServerOptions            `json:"fallback,omitempty"`
 	FallbackForALPN map[string]*ServerOptions `json:"fallback_for_alpn,omitempty"`
+	FallbackHTTP    *FallbackHTTPOptions      `json:"fallback_http,omitempty"`
 	Multiplex       *InboundMultiplexOptions  `json:"multiplex,omitempty"`
 	Transport       *V2RayTransportOptions    `json:"transport,omitempty"`
 }
@@ -13,13 +14,15 @@ type TrojanInboundOptions struct {
 type TrojanUser struct {
 	Name     string `json:"name"`
 	Password string `json:"password"`
+	UserStatusOptions
 }
 
 type TrojanOutboundOptions struct {
 	DialerOptions
 	ServerOptions
-	Password string      `json:"password"`
-	Network  NetworkList `json:"network,omitempty"`
+	Password    string               `json:"password"`
+	Credentials []OutboundCredential `json:"credentials,omitempty"`
+	Network     NetworkList          `json:"network,omitempty"`
 	OutboundTLSOptionsContainer
 	Multiplex *OutboundMultiplexOptions `json:"multiplex,omitempty"`
 	Transport *V2RayTransportOptions    `json:"transport,omitempty"`
@@ -2,6 +2,7 @@ package option
 
 import (
 	"net/netip"
+	"slices"
 	"strconv"
 
 	E "github.com/sagernet/sing/common/exceptions"
@@ -37,6 +38,8 @@ type TunInboundOptions struct {
 	ExcludePackage         badoption.Listable[string]       `json:"exclude_package,omitempty"`
 	UDPTimeout             UDPTimeoutCompat                 `json:"udp_timeout,omitempty"`
 	Stack                  string                           `json:"stack,omitempty"`
+	Offload                bool                             `json:"offload,omitempty"`
+	TTL                    uint8                            `json:"ttl,omitempty"`
 	Platform               *TunPlatformOptions              `json:"platform,omitempty"`
 	InboundOptions
 
@@ -58,6 +61,31 @@ type TunInboundOptions struct {
 	EndpointIndependentNat bool `json:"endpoint_independent_nat,omitempty"`
 }
 
+type TunRouteOptions struct {
+	RouteAddress        badoption.Listable[netip.Prefix] `json:"route_address,omitempty"`
+	RouteExcludeAddress badoption.Listable[netip.Prefix] `json:"route_exclude_address,omitempty"`
+	IncludeInterface    badoption.Listable[string]       `json:"include_interface,omitempty"`
+	ExcludeInterface    badoption.Listable[string]       `json:"exclude_interface,omitempty"`
+	IncludeUID          badoption.Listable[uint32]       `json:"include_uid,omitempty"`
+	IncludeUIDRange     badoption.Listable[string]       `json:"include_uid_range,omitempty"`
+	ExcludeUID          badoption.Listable[uint32]       `json:"exclude_uid,omitempty"`
+	ExcludeUIDRange     badoption.Listable[string]       `json:"exclude_uid_range,omitempty"`
+	IncludePackage      badoption.Listable[string]       `json:"include_package,omitempty"`
+	ExcludePackage      badoption.Listable[string]       `json:"exclude_package,omitempty"`
+}
+
+// EqualRules reports whether the interface, UID and package lists are the same.
+func (o TunRouteOptions) EqualRules(other TunRouteOptions) bool {
+	return slices.Equal(o.IncludeInterface, other.IncludeInterface) &&
+		slices.Equal(o.ExcludeInterface, other.ExcludeInterface) &&
+		slices.Equal(o.IncludeUID, other.IncludeUID) &&
+		slices.Equal(o.IncludeUIDRange, other.IncludeUIDRange) &&
+		slices.Equal(o.ExcludeUID, other.ExcludeUID) &&
+		slices.Equal(o.ExcludeUIDRange, other.ExcludeUIDRange) &&
+		slices.Equal(o.IncludePackage, other.IncludePackage) &&
+		slices.Equal(o.ExcludePackage, other.ExcludePackage)
+}
+
 type FwMark uint32
 
 func (f FwMark) MarshalJSON() ([]byte, error) {
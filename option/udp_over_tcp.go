@@ -8,13 +8,14 @@ import (
 type _UDPOverTCPOptions struct {
 	Enabled bool  `json:"enabled,omitempty"`
 	Version uint8 `json:"version,omitempty"`
+	Connect bool  `json:"connect,omitempty"`
 }
 
 type UDPOverTCPOptions _UDPOverTCPOptions
 
 func (o UDPOverTCPOptions) MarshalJSON() ([]byte, error) {
-	switch o.Version {
-	case 0, uot.Version:
+	switch {
+	case !o.Connect && (o.Version == 0 || o.Version == uot.Version):
 		return json.Marshal(o.Enabled)
 	default:
 		return json.Marshal(_UDPOverTCPOptions(o))
@@ -0,0 +1,53 @@
+package option
+
+import (
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/common/json/badoption"
+)
+
+type UpdaterServiceOptions struct {
+	DownloadDetour string                                    `json:"download_detour,omitempty"`
+	UserAgent      string                                    `json:"user_agent,omitempty"`
+	Schedule       string                                    `json:"schedule,omitempty"`
+	UpdateInterval badoption.Duration                        `json:"update_interval,omitempty"`
+	Files          []UpdaterFileOptions                      `json:"files,omitempty"`
+	RuleSet        badoption.Listable[UpdaterRuleSetOptions] `json:"rule_set,omitempty"`
+}
+
+type UpdaterFileOptions struct {
+	URL      string `json:"url"`
+	Path     string `json:"path"`
+	Schedule string `json:"schedule,omitempty"`
+	UpdaterVerifyOptions
+}
+
+type UpdaterVerifyOptions struct {
+	SHA256       string `json:"sha256,omitempty"`
+	ChecksumURL  string `json:"checksum_url,omitempty"`
+	SignatureURL string `json:"signature_url,omitempty"`
+	PublicKey    string `json:"public_key,omitempty"`
+}
+
+type _UpdaterRuleSetOptions struct {
+	Tag string `json:"tag"`
+	UpdaterVerifyOptions
+}
+
+// UpdaterRuleSetOptions is a rule-set tag, or an object with the tag and its verification options.
+type UpdaterRuleSetOptions _UpdaterRuleSetOptions
+
+func (o UpdaterRuleSetOptions) MarshalJSON() ([]byte, error) {
+	if o.UpdaterVerifyOptions == (UpdaterVerifyOptions{}) {
+		return json.Marshal(o.Tag)
+	}
+	return json.Marshal((_UpdaterRuleSetOptions)(o))
+}
+
+func (o *UpdaterRuleSetOptions) UnmarshalJSON(content []byte) error {
+	var tag string
+	if json.Unmarshal(content, &tag) == nil {
+		*o = UpdaterRuleSetOptions{Tag: tag}
+		return nil
+	}
+	return json.UnmarshalDisallowUnknownFields(content, (*_UpdaterRuleSetOptions)(o))
+}
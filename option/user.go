@@ -0,0 +1,122 @@
+package option
+
+import (
+	"time"
+
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/auth"
+)
+
+// UserStatusOptions are the fields shared by users of multi-user inbounds.
+// Disabled or expired users are rejected after authentication, and their
+// connections are closed at expiry.
+type UserStatusOptions struct {
+	Enabled  *bool      `json:"enabled,omitempty"`
+	ExpireAt *time.Time `json:"expire_at,omitempty"`
+}
+
+// InboundUser is a user of a multi-user inbound, named as in the user field
+// of route rules.
+type InboundUser struct {
+	Name string
+	UserStatusOptions
+}
+
+// MultiUserInboundOptions is implemented by options of inbounds with users.
+type MultiUserInboundOptions interface {
+	InboundUsers() []InboundUser
+}
+
+func authInboundUsers(users []auth.User, userStatus map[string]UserStatusOptions) []InboundUser {
+	return common.Map(users, func(it auth.User) InboundUser {
+		return InboundUser{it.Username, userStatus[it.Username]}
+	})
+}
+
+func (o SocksInboundOptions) InboundUsers() []InboundUser {
+	return authInboundUsers(o.Users, o.UserStatus)
+}
+
+func (o HTTPMixedInboundOptions) InboundUsers() []InboundUser {
+	return authInboundUsers(o.Users, o.UserStatus)
+}
+
+func (o NaiveInboundOptions) InboundUsers() []InboundUser {
+	return authInboundUsers(o.Users, o.UserStatus)
+}
+
+func (o HTTP3InboundOptions) InboundUsers() []InboundUser {
+	return authInboundUsers(o.Users, o.UserStatus)
+}
+
+func (u HysteriaUser) InboundUser() InboundUser {
+	return InboundUser{u.Name, u.UserStatusOptions}
+}
+
+func (o HysteriaInboundOptions) InboundUsers() []InboundUser {
+	return common.Map(o.Users, HysteriaUser.InboundUser)
+}
+
+func (u Hysteria2User) InboundUser() InboundUser {
+	return InboundUser{u.Name, u.UserStatusOptions}
+}
+
+func (o Hysteria2InboundOptions) InboundUsers() []InboundUser {
+	return common.Map(o.Users, Hysteria2User.InboundUser)
+}
+
+func (u ShadowsocksUser) InboundUser() InboundUser {
+	return InboundUser{u.Name, u.UserStatusOptions}
+}
+
+func (o ShadowsocksInboundOptions) InboundUsers() []InboundUser {
+	return common.Map(o.Users, ShadowsocksUser.InboundUser)
+}
+
+func (u ShadowTLSUser) InboundUser() InboundUser {
+	return InboundUser{u.Name, u.UserStatusOptions}
+}
+
+func (o ShadowTLSInboundOptions) InboundUsers() []InboundUser {
+	return common.Map(o.Users, ShadowTLSUser.InboundUser)
+}
+
+func (u TrojanUser) InboundUser() InboundUser {
+	return InboundUser{u.Name, u.UserStatusOptions}
+}
+
+func (o TrojanInboundOptions) InboundUsers() []InboundUser {
+	return common.Map(o.Users, TrojanUser.InboundUser)
+}
+
+func (u VLESSUser) InboundUser() InboundUser {
+	return InboundUser{u.Name, u.UserStatusOptions}
+}
+
+func (o VLESSInboundOptions) InboundUsers() []InboundUser {
+	return common.Map(o.Users, VLESSUser.InboundUser)
+}
+
+func (u VMessUser) InboundUser() InboundUser {
+	return InboundUser{u.Name, u.UserStatusOptions}
+}
+
+func (o VMessInboundOptions) InboundUsers() []InboundUser {
+	return common.Map(o.Users, VMessUser.InboundUser)
+}
+
+func (u TUICUser) InboundUser() InboundUser {
+	return InboundUser{u.Name, u.UserStatusOptions}
+}
+
+func (o TUICInboundOptions) InboundUsers() []InboundUser {
+	return common.Map(o.Users, TUICUser.InboundUser)
+}
+
+func (u AnyTLSUser) InboundUser() InboundUser {
+	return InboundUser{u.Name, u.UserStatusOptions}
+}
+
+func (o AnyTLSInboundOptions) InboundUsers() []InboundUser {
+	return common.Map(o.Users, AnyTLSUser.InboundUser)
+}
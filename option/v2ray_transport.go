@@ -77,25 +77,40 @@ type V2RayHTTPOptions struct {
 }
 
 type V2RayWebsocketOptions struct {
-	Host                string               `json:"host,omitempty"`
-	Path                string               `json:"path,omitempty"`
-	Headers             badoption.HTTPHeader `json:"headers,omitempty"`
-	MaxEarlyData        uint32               `json:"max_early_data,omitempty"`
-	EarlyDataHeaderName string               `json:"early_data_header_name,omitempty"`
+	Host                string                         `json:"host,omitempty"`
+	Hosts               badoption.Listable[string]     `json:"hosts,omitempty"`
+	Path                string                         `json:"path,omitempty"`
+	Paths               badoption.Listable[string]     `json:"paths,omitempty"`
+	Rotation            *V2RayTransportRotationOptions `json:"rotation,omitempty"`
+	Headers             badoption.HTTPHeader           `json:"headers,omitempty"`
+	MaxEarlyData        uint32                         `json:"max_early_data,omitempty"`
+	EarlyDataHeaderName string                         `json:"early_data_header_name,omitempty"`
 }
 
-type V2RayQUICOptions struct{}
+type V2RayQUICOptions struct {
+	QUICMTUOptions
+}
 
 type V2RayGRPCOptions struct {
-	ServiceName         string             `json:"service_name,omitempty"`
-	IdleTimeout         badoption.Duration `json:"idle_timeout,omitempty"`
-	PingTimeout         badoption.Duration `json:"ping_timeout,omitempty"`
-	PermitWithoutStream bool               `json:"permit_without_stream,omitempty"`
-	ForceLite           bool               `json:"-"` // for test
+	ServiceName         string                         `json:"service_name,omitempty"`
+	ServiceNames        badoption.Listable[string]     `json:"service_names,omitempty"`
+	Rotation            *V2RayTransportRotationOptions `json:"rotation,omitempty"`
+	IdleTimeout         badoption.Duration             `json:"idle_timeout,omitempty"`
+	PingTimeout         badoption.Duration             `json:"ping_timeout,omitempty"`
+	PermitWithoutStream bool                           `json:"permit_without_stream,omitempty"`
+	ForceLite           bool                           `json:"-"` // for test
 }
 
 type V2RayHTTPUpgradeOptions struct {
-	Host    string               `json:"host,omitempty"`
-	Path    string               `json:"path,omitempty"`
-	Headers badoption.HTTPHeader `json:"headers,omitempty"`
+	Host     string                         `json:"host,omitempty"`
+	Hosts    badoption.Listable[string]     `json:"hosts,omitempty"`
+	Path     string                         `json:"path,omitempty"`
+	Paths    badoption.Listable[string]     `json:"paths,omitempty"`
+	Rotation *V2RayTransportRotationOptions `json:"rotation,omitempty"`
+	Headers  badoption.HTTPHeader           `json:"headers,omitempty"`
+}
+
+type V2RayTransportRotationOptions struct {
+	Interval badoption.Duration `json:"interval,omitempty"`
+	Key      string             `json:"key,omitempty"`
 }
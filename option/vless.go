@@ -4,14 +4,17 @@ type VLESSInboundOptions struct {
 	ListenOptions
 	Users []VLESSUser `json:"users,omitempty"`
 	InboundTLSOptionsContainer
-	Multiplex *InboundMultiplexOptions `json:"multiplex,omitempty"`
-	Transport *V2RayTransportOptions   `json:"transport,omitempty"`
+	Fallback     *ServerOptions           `json:"fallback,omitempty"`
+	FallbackHTTP *FallbackHTTPOptions     `json:"fallback_http,omitempty"`
+	Multiplex    *InboundMultiplexOptions `json:"multiplex,omitempty"`
+	Transport    *V2RayTransportOptions   `json:"transport,omitempty"`
 }
 
 type VLESSUser struct {
 	Name string `json:"name"`
 	UUID string `json:"uuid"`
 	Flow string `json:"flow,omitempty"`
+	UserStatusOptions
 }
 
 type VLESSOutboundOptions struct {
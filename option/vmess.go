@@ -1,17 +1,22 @@
 package option
 
+import "github.com/sagernet/sing/common/json/badoption"
+
 type VMessInboundOptions struct {
 	ListenOptions
 	Users []VMessUser `json:"users,omitempty"`
 	InboundTLSOptionsContainer
-	Multiplex *InboundMultiplexOptions `json:"multiplex,omitempty"`
-	Transport *V2RayTransportOptions   `json:"transport,omitempty"`
+	Multiplex         *InboundMultiplexOptions `json:"multiplex,omitempty"`
+	Transport         *V2RayTransportOptions   `json:"transport,omitempty"`
+	MaxTimeDifference badoption.Duration       `json:"max_time_difference,omitempty"`
+	ReplayProtection  *ReplayProtectionOptions `json:"replay_protection,omitempty"`
 }
 
 type VMessUser struct {
 	Name    string `json:"name"`
 	UUID    string `json:"uuid"`
 	AlterId int    `json:"alterId,omitempty"`
+	UserStatusOptions
 }
 
 type VMessOutboundOptions struct {
@@ -0,0 +1,22 @@
+package option
+
+import (
+	"github.com/sagernet/sing/common/byteformats"
+	"github.com/sagernet/sing/common/json/badoption"
+)
+
+type WASMOptions struct {
+	Modules []WASMModuleOptions `json:"modules,omitempty"`
+}
+
+type WASMModuleOptions struct {
+	Tag         string                   `json:"tag"`
+	Path        string                   `json:"path"`
+	MemoryLimit *byteformats.MemoryBytes `json:"memory_limit,omitempty"`
+	Timeout     badoption.Duration       `json:"timeout,omitempty"`
+}
+
+type WASMRuleItemOptions struct {
+	Module   string `json:"module"`
+	Function string `json:"function,omitempty"`
+}
@@ -0,0 +1,14 @@
+package option
+
+import "github.com/sagernet/sing/common/json/badoption"
+
+type WebhookServiceOptions struct {
+	Detour        string                     `json:"detour,omitempty"`
+	URL           string                     `json:"url"`
+	Headers       badoption.HTTPHeader       `json:"headers,omitempty"`
+	Events        badoption.Listable[string] `json:"events,omitempty"`
+	Template      string                     `json:"template,omitempty"`
+	Timeout       badoption.Duration         `json:"timeout,omitempty"`
+	MaxRetries    uint32                     `json:"max_retries,omitempty"`
+	RetryInterval badoption.Duration         `json:"retry_interval,omitempty"`
+}
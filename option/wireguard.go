@@ -16,10 +16,12 @@ type WireGuardEndpointOptions struct {
 	Peers      []WireGuardPeer                  `json:"peers,omitempty"`
 	UDPTimeout badoption.Duration               `json:"udp_timeout,omitempty"`
 	Workers    int                              `json:"workers,omitempty"`
+	Lazy       bool                             `json:"lazy,omitempty"`
 	DialerOptions
 }
 
 type WireGuardPeer struct {
+	Name                        string                           `json:"name,omitempty"`
 	Address                     string                           `json:"address,omitempty"`
 	Port                        uint16                           `json:"port,omitempty"`
 	PublicKey                   string                           `json:"public_key,omitempty"`
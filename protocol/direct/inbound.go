@@ -57,10 +57,11 @@ func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLo
 	} else {
 		udpTimeout = C.UDPTimeout
 	}
-	inbound.udpNat = udpnat.New(inbound, inbound.preparePacketConnection, udpTimeout, false)
+	inbound.udpNat = udpnat.New(inbound, inbound.preparePacketConnection, udpTimeout, true)
 	inbound.listener = listener.New(listener.Options{
 		Context:           ctx,
 		Logger:            logger,
+		Tag:               tag,
 		Network:           options.Network.Build(),
 		Listen:            options.ListenOptions,
 		ConnectionHandler: inbound,
@@ -42,6 +42,7 @@ type Fallback struct {
 	link                         string
 	interval                     time.Duration
 	idleTimeout                  time.Duration
+	sla                          *option.OutboundSLAOptions
 	group                        *FallbackGroup
 	interruptExternalConnections bool
 }
@@ -58,6 +59,7 @@ func NewFallback(ctx context.Context, router adapter.Router, logger log.ContextL
 		link:                         options.URL,
 		interval:                     time.Duration(options.Interval),
 		idleTimeout:                  time.Duration(options.IdleTimeout),
+		sla:                          options.SLA,
 		interruptExternalConnections: options.InterruptExistConnections,
 	}
 	if len(outbound.tags) == 0 {
@@ -75,7 +77,7 @@ func (s *Fallback) Start() error {
 		}
 		outbounds = append(outbounds, detour)
 	}
-	group, err := NewFallbackGroup(s.ctx, s.outbound, s.logger, outbounds, s.link, s.interval, s.idleTimeout, s.interruptExternalConnections)
+	group, err := NewFallbackGroup(s.ctx, s.outbound, s.logger, s.Tag(), outbounds, s.link, s.interval, s.idleTimeout, s.sla, s.interruptExternalConnections)
 	if err != nil {
 		return err
 	}
@@ -181,12 +183,15 @@ type FallbackGroup struct {
 	outbound                     adapter.OutboundManager
 	pause                        pause.Manager
 	pauseCallback                *list.Element[pause.Callback]
+	power                        adapter.PowerManager
 	logger                       log.Logger
 	outbounds                    []adapter.Outbound
 	link                         string
 	interval                     time.Duration
 	idleTimeout                  time.Duration
 	history                      adapter.URLTestHistoryStorage
+	health                       *healthNotifier
+	sla                          *slaTracker
 	checking                     atomic.Bool
 	selectedOutboundTCP          adapter.Outbound
 	selectedOutboundUDP          adapter.Outbound
@@ -199,7 +204,7 @@ type FallbackGroup struct {
 	lastActive                   common.TypedValue[time.Time]
 }
 
-func NewFallbackGroup(ctx context.Context, outboundManager adapter.OutboundManager, logger log.Logger, outbounds []adapter.Outbound, link string, interval time.Duration, idleTimeout time.Duration, interruptExternalConnections bool) (*FallbackGroup, error) {
+func NewFallbackGroup(ctx context.Context, outboundManager adapter.OutboundManager, logger log.Logger, tag string, outbounds []adapter.Outbound, link string, interval time.Duration, idleTimeout time.Duration, slaOptions *option.OutboundSLAOptions, interruptExternalConnections bool) (*FallbackGroup, error) {
 	if interval == 0 {
 		interval = C.DefaultURLTestInterval
 	}
@@ -217,7 +222,7 @@ func NewFallbackGroup(ctx context.Context, outboundManager adapter.OutboundManag
 	} else {
 		history = urltest.NewHistoryStorage()
 	}
-	return &FallbackGroup{
+	group := &FallbackGroup{
 		ctx:                          ctx,
 		outbound:                     outboundManager,
 		logger:                       logger,
@@ -226,11 +231,19 @@ func NewFallbackGroup(ctx context.Context, outboundManager adapter.OutboundManag
 		interval:                     interval,
 		idleTimeout:                  idleTimeout,
 		history:                      history,
+		health:                       newHealthNotifier(ctx, tag),
 		close:                        make(chan struct{}),
 		pause:                        service.FromContext[pause.Manager](ctx),
+		power:                        service.FromContext[adapter.PowerManager](ctx),
 		interruptGroup:               interrupt.NewGroup(),
 		interruptExternalConnections: interruptExternalConnections,
-	}, nil
+	}
+	var err error
+	group.sla, err = newSLATracker(slaOptions, group.reprobe)
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
 }
 
 func (g *FallbackGroup) PostStart() {
@@ -259,6 +272,7 @@ func (g *FallbackGroup) Touch() {
 func (g *FallbackGroup) Close() error {
 	g.access.Lock()
 	defer g.access.Unlock()
+	g.sla.Close()
 	if g.ticker == nil {
 		return nil
 	}
@@ -306,10 +320,20 @@ func (g *FallbackGroup) loopCheck() {
 			g.access.Unlock()
 			return
 		}
+		if g.power != nil {
+			g.power.WaitWindow(g.ctx)
+		}
 		g.CheckOutbounds(false)
 	}
 }
 
+func (g *FallbackGroup) reprobe() {
+	if g.power != nil {
+		g.power.WaitWindow(g.ctx)
+	}
+	g.CheckOutbounds(false)
+}
+
 func (g *FallbackGroup) CheckOutbounds(force bool) {
 	_, _ = g.urlTest(g.ctx, force)
 }
@@ -333,6 +357,9 @@ func (g *FallbackGroup) urlTest(ctx context.Context, force bool) (map[string]uin
 		if checked[realTag] {
 			continue
 		}
+		if !force && !g.sla.probe(realTag) {
+			continue
+		}
 		history := g.history.LoadURLTestHistory(realTag)
 		if !force && history != nil && time.Since(history.Time) < g.interval {
 			continue
@@ -346,12 +373,20 @@ func (g *FallbackGroup) urlTest(ctx context.Context, force bool) (map[string]uin
 			testCtx, cancel := context.WithTimeout(g.ctx, C.TCPTimeout)
 			defer cancel()
 			t, err := urltest.URLTest(testCtx, g.link, p)
-			if err != nil {
+			var tolerated bool
+			if g.sla != nil {
+				tolerated, err = g.sla.update(realTag, t, err)
+			}
+			if tolerated {
+				g.logger.Debug("outbound ", tag, " unavailable within SLA: ", err)
+			} else if err != nil {
 				g.logger.Debug("outbound ", tag, " unavailable: ", err)
 				g.history.DeleteURLTestHistory(realTag)
+				g.health.update(realTag, 0, err)
 			} else {
 				g.logger.Debug("outbound ", tag, " available: ", t, "ms")
 				g.history.StoreURLTestHistory(realTag, &adapter.URLTestHistory{Time: time.Now(), Delay: t})
+				g.health.update(realTag, t, nil)
 				resultAccess.Lock()
 				result[tag] = t
 				resultAccess.Unlock()
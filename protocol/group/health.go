@@ -0,0 +1,53 @@
+package group
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/service"
+)
+
+// healthNotifier emits outbound health events of a group when the result
+// of an URL test changes the state of an outbound, or on its first test.
+type healthNotifier struct {
+	eventBus adapter.EventBus
+	group    string
+	access   sync.Mutex
+	healthy  map[string]bool
+}
+
+func newHealthNotifier(ctx context.Context, group string) *healthNotifier {
+	return &healthNotifier{
+		eventBus: service.FromContext[adapter.EventBus](ctx),
+		group:    group,
+		healthy:  make(map[string]bool),
+	}
+}
+
+func (n *healthNotifier) update(outbound string, delay uint16, err error) {
+	if n.eventBus == nil {
+		return
+	}
+	healthy := err == nil
+	n.access.Lock()
+	previous, loaded := n.healthy[outbound]
+	n.healthy[outbound] = healthy
+	n.access.Unlock()
+	if loaded && previous == healthy || !n.eventBus.Subscribed(adapter.EventOutboundHealth) {
+		return
+	}
+	event := adapter.Event{
+		Type:     adapter.EventOutboundHealth,
+		Group:    n.group,
+		Outbound: outbound,
+	}
+	if healthy {
+		event.State = adapter.OutboundStateHealthy
+		event.Delay = delay
+	} else {
+		event.State = adapter.OutboundStateUnhealthy
+		event.Error = err.Error()
+	}
+	n.eventBus.Emit(event)
+}
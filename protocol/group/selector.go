@@ -127,6 +127,14 @@ func (s *Selector) SelectOutbound(tag string) bool {
 	if s.selected.Swap(detour) == detour {
 		return true
 	}
+	if lazyOutbound, isLazy := detour.(adapter.LazyOutbound); isLazy {
+		go func() {
+			err := lazyOutbound.Initialize()
+			if err != nil {
+				s.logger.Error(E.Cause(err, "initialize outbound/", lazyOutbound.Type(), "[", lazyOutbound.Tag(), "]"))
+			}
+		}()
+	}
 	if s.Tag() != "" {
 		cacheFile := service.FromContext[adapter.CacheFile](s.ctx)
 		if cacheFile != nil {
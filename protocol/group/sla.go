@@ -0,0 +1,179 @@
+package group
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+const (
+	slaDefaultMaxFailures   = 3
+	slaDefaultWindow        = 10
+	slaMinSamples           = 5
+	slaDefaultQuarantine    = time.Minute
+	slaDefaultMaxQuarantine = 30 * time.Minute
+)
+
+// slaTracker quarantines outbounds of a group which fail consecutive tests
+// or exceed the p95 latency of recent tests, and re-probes them with
+// exponential backoff when the quarantine expires.
+type slaTracker struct {
+	maxFailures   int
+	maxLatency    uint16
+	window        int
+	quarantine    time.Duration
+	maxQuarantine time.Duration
+	reprobe       func()
+	access        sync.Mutex
+	states        map[string]*slaState
+	timeFunc      func() time.Time
+	closed        bool
+}
+
+type slaState struct {
+	failures int
+	samples  []uint16
+	backoff  time.Duration
+	until    time.Time
+	timer    *time.Timer
+}
+
+func newSLATracker(options *option.OutboundSLAOptions, reprobe func()) (*slaTracker, error) {
+	if options == nil {
+		return nil, nil
+	}
+	tracker := &slaTracker{
+		maxFailures:   int(options.MaxFailures),
+		window:        int(options.LatencyWindow),
+		quarantine:    time.Duration(options.Quarantine),
+		maxQuarantine: time.Duration(options.MaxQuarantine),
+		reprobe:       reprobe,
+		states:        make(map[string]*slaState),
+		timeFunc:      time.Now,
+	}
+	if options.MaxP95Latency > 0 {
+		maxLatency := time.Duration(options.MaxP95Latency).Milliseconds()
+		if maxLatency == 0 || maxLatency > 65535 {
+			return nil, E.New("sla: max_p95_latency must be between 1ms and 65535ms")
+		}
+		tracker.maxLatency = uint16(maxLatency)
+	}
+	if tracker.maxFailures == 0 {
+		tracker.maxFailures = slaDefaultMaxFailures
+	}
+	if tracker.window == 0 {
+		tracker.window = slaDefaultWindow
+	}
+	if tracker.quarantine == 0 {
+		tracker.quarantine = slaDefaultQuarantine
+	}
+	if tracker.maxQuarantine == 0 {
+		tracker.maxQuarantine = max(slaDefaultMaxQuarantine, tracker.quarantine)
+	} else if tracker.maxQuarantine < tracker.quarantine {
+		return nil, E.New("sla: max_quarantine must be greater or equal than quarantine")
+	}
+	return tracker, nil
+}
+
+// probe reports whether the outbound should be tested, which is false while
+// it is quarantined.
+func (t *slaTracker) probe(tag string) bool {
+	if t == nil {
+		return true
+	}
+	t.access.Lock()
+	defer t.access.Unlock()
+	state := t.states[tag]
+	return state == nil || !t.timeFunc().Before(state.until)
+}
+
+// update records the result of a test. The outbound stays available if
+// tolerated is true, otherwise it is unavailable with the returned error if
+// not nil.
+func (t *slaTracker) update(tag string, delay uint16, err error) (tolerated bool, quarantineErr error) {
+	t.access.Lock()
+	defer t.access.Unlock()
+	state := t.states[tag]
+	if state == nil {
+		state = &slaState{}
+		t.states[tag] = state
+	}
+	probation := !state.until.IsZero()
+	if err != nil {
+		if probation {
+			return false, t.quarantineLocked(state, E.Cause(err, "re-probe"))
+		}
+		state.failures++
+		if state.failures < t.maxFailures {
+			return true, err
+		}
+		return false, t.quarantineLocked(state, E.Cause(err, state.failures, " consecutive failures"))
+	}
+	state.failures = 0
+	if t.maxLatency > 0 {
+		if probation {
+			if delay > t.maxLatency {
+				return false, t.quarantineLocked(state, E.New("latency ", delay, "ms exceeds ", t.maxLatency, "ms"))
+			}
+		} else {
+			state.samples = append(state.samples, delay)
+			if len(state.samples) > t.window {
+				state.samples = state.samples[len(state.samples)-t.window:]
+			}
+			if len(state.samples) >= min(slaMinSamples, t.window) {
+				latency := percentile95(state.samples)
+				if latency > t.maxLatency {
+					return false, t.quarantineLocked(state, E.New("p95 latency ", latency, "ms exceeds ", t.maxLatency, "ms"))
+				}
+			}
+		}
+	}
+	if probation {
+		state.samples = state.samples[:0]
+		state.backoff = 0
+		state.until = time.Time{}
+	}
+	return false, nil
+}
+
+func (t *slaTracker) quarantineLocked(state *slaState, reason error) error {
+	if state.backoff == 0 {
+		state.backoff = t.quarantine
+	} else {
+		state.backoff = min(state.backoff*2, t.maxQuarantine)
+	}
+	state.failures = 0
+	state.samples = state.samples[:0]
+	state.until = t.timeFunc().Add(state.backoff)
+	if state.timer != nil {
+		state.timer.Stop()
+	}
+	if !t.closed && t.reprobe != nil {
+		state.timer = time.AfterFunc(state.backoff, t.reprobe)
+	}
+	return E.Cause(reason, "quarantined for ", state.backoff)
+}
+
+func (t *slaTracker) Close() error {
+	if t == nil {
+		return nil
+	}
+	t.access.Lock()
+	defer t.access.Unlock()
+	t.closed = true
+	for _, state := range t.states {
+		if state.timer != nil {
+			state.timer.Stop()
+		}
+	}
+	return nil
+}
+
+func percentile95(samples []uint16) uint16 {
+	sorted := slices.Clone(samples)
+	slices.Sort(sorted)
+	return sorted[(len(sorted)*95+99)/100-1]
+}
@@ -0,0 +1,103 @@
+package group
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json/badoption"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSLATracker(t *testing.T, options option.OutboundSLAOptions) (*slaTracker, *time.Time) {
+	tracker, err := newSLATracker(&options, nil)
+	require.NoError(t, err)
+	now := time.Unix(0, 0)
+	tracker.timeFunc = func() time.Time {
+		return now
+	}
+	return tracker, &now
+}
+
+func TestSLATrackerFailures(t *testing.T) {
+	t.Parallel()
+	tracker, now := newTestSLATracker(t, option.OutboundSLAOptions{})
+	testErr := E.New("test failed")
+	for i := 1; i < slaDefaultMaxFailures; i++ {
+		tolerated, err := tracker.update("a", 0, testErr)
+		require.True(t, tolerated)
+		require.Error(t, err)
+	}
+	tolerated, err := tracker.update("a", 0, testErr)
+	require.False(t, tolerated)
+	require.ErrorContains(t, err, "quarantined for 1m0s")
+	require.False(t, tracker.probe("a"))
+	require.True(t, tracker.probe("b"))
+
+	*now = now.Add(slaDefaultQuarantine)
+	require.True(t, tracker.probe("a"))
+	tolerated, err = tracker.update("a", 100, nil)
+	require.False(t, tolerated)
+	require.NoError(t, err)
+	require.True(t, tracker.probe("a"))
+	require.Zero(t, tracker.states["a"].backoff)
+}
+
+func TestSLATrackerBackoff(t *testing.T) {
+	t.Parallel()
+	tracker, now := newTestSLATracker(t, option.OutboundSLAOptions{
+		MaxFailures:   1,
+		Quarantine:    badoption.Duration(time.Minute),
+		MaxQuarantine: badoption.Duration(5 * time.Minute),
+	})
+	testErr := E.New("test failed")
+	for _, backoff := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute} {
+		require.True(t, tracker.probe("a"))
+		_, err := tracker.update("a", 0, testErr)
+		require.Error(t, err)
+		require.Equal(t, backoff, tracker.states["a"].backoff)
+		require.Equal(t, now.Add(backoff), tracker.states["a"].until)
+		*now = now.Add(backoff - time.Second)
+		require.False(t, tracker.probe("a"))
+		*now = now.Add(time.Second)
+	}
+}
+
+func TestSLATrackerLatency(t *testing.T) {
+	t.Parallel()
+	tracker, now := newTestSLATracker(t, option.OutboundSLAOptions{
+		MaxP95Latency: badoption.Duration(200 * time.Millisecond),
+		LatencyWindow: 5,
+	})
+	for i := 0; i < slaMinSamples-1; i++ {
+		_, err := tracker.update("a", 1000, nil)
+		require.NoError(t, err)
+	}
+	_, err := tracker.update("a", 100, nil)
+	require.ErrorContains(t, err, "p95 latency 1000ms exceeds 200ms")
+	require.False(t, tracker.probe("a"))
+
+	// a slow re-probe extends the quarantine, a fast one lifts it
+	*now = tracker.states["a"].until
+	_, err = tracker.update("a", 300, nil)
+	require.ErrorContains(t, err, "latency 300ms exceeds 200ms")
+	require.Equal(t, 2*slaDefaultQuarantine, tracker.states["a"].backoff)
+	*now = tracker.states["a"].until
+	_, err = tracker.update("a", 100, nil)
+	require.NoError(t, err)
+	require.True(t, tracker.probe("a"))
+	require.Empty(t, tracker.states["a"].samples)
+}
+
+func TestPercentile95(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, uint16(5), percentile95([]uint16{5}))
+	require.Equal(t, uint16(10), percentile95([]uint16{1, 10, 2, 3}))
+	samples := make([]uint16, 100)
+	for i := range samples {
+		samples[i] = uint16(100 - i)
+	}
+	require.Equal(t, uint16(95), percentile95(samples))
+}
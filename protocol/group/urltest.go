@@ -43,6 +43,7 @@ type URLTest struct {
 	interval                     time.Duration
 	tolerance                    uint16
 	idleTimeout                  time.Duration
+	sla                          *option.OutboundSLAOptions
 	group                        *URLTestGroup
 	interruptExternalConnections bool
 }
@@ -60,6 +61,7 @@ func NewURLTest(ctx context.Context, router adapter.Router, logger log.ContextLo
 		interval:                     time.Duration(options.Interval),
 		tolerance:                    options.Tolerance,
 		idleTimeout:                  time.Duration(options.IdleTimeout),
+		sla:                          options.SLA,
 		interruptExternalConnections: options.InterruptExistConnections,
 	}
 	if len(outbound.tags) == 0 {
@@ -77,7 +79,7 @@ func (s *URLTest) Start() error {
 		}
 		outbounds = append(outbounds, detour)
 	}
-	group, err := NewURLTestGroup(s.ctx, s.outbound, s.logger, outbounds, s.link, s.interval, s.tolerance, s.idleTimeout, s.interruptExternalConnections)
+	group, err := NewURLTestGroup(s.ctx, s.outbound, s.logger, s.Tag(), outbounds, s.link, s.interval, s.tolerance, s.idleTimeout, s.sla, s.interruptExternalConnections)
 	if err != nil {
 		return err
 	}
@@ -192,6 +194,7 @@ type URLTestGroup struct {
 	outbound                     adapter.OutboundManager
 	pause                        pause.Manager
 	pauseCallback                *list.Element[pause.Callback]
+	power                        adapter.PowerManager
 	logger                       log.Logger
 	outbounds                    []adapter.Outbound
 	link                         string
@@ -199,6 +202,8 @@ type URLTestGroup struct {
 	tolerance                    uint16
 	idleTimeout                  time.Duration
 	history                      adapter.URLTestHistoryStorage
+	health                       *healthNotifier
+	sla                          *slaTracker
 	checking                     atomic.Bool
 	selectedOutboundTCP          adapter.Outbound
 	selectedOutboundUDP          adapter.Outbound
@@ -211,7 +216,7 @@ type URLTestGroup struct {
 	lastActive                   common.TypedValue[time.Time]
 }
 
-func NewURLTestGroup(ctx context.Context, outboundManager adapter.OutboundManager, logger log.Logger, outbounds []adapter.Outbound, link string, interval time.Duration, tolerance uint16, idleTimeout time.Duration, interruptExternalConnections bool) (*URLTestGroup, error) {
+func NewURLTestGroup(ctx context.Context, outboundManager adapter.OutboundManager, logger log.Logger, tag string, outbounds []adapter.Outbound, link string, interval time.Duration, tolerance uint16, idleTimeout time.Duration, slaOptions *option.OutboundSLAOptions, interruptExternalConnections bool) (*URLTestGroup, error) {
 	if interval == 0 {
 		interval = C.DefaultURLTestInterval
 	}
@@ -232,7 +237,7 @@ func NewURLTestGroup(ctx context.Context, outboundManager adapter.OutboundManage
 	} else {
 		history = urltest.NewHistoryStorage()
 	}
-	return &URLTestGroup{
+	group := &URLTestGroup{
 		ctx:                          ctx,
 		outbound:                     outboundManager,
 		logger:                       logger,
@@ -242,11 +247,19 @@ func NewURLTestGroup(ctx context.Context, outboundManager adapter.OutboundManage
 		tolerance:                    tolerance,
 		idleTimeout:                  idleTimeout,
 		history:                      history,
+		health:                       newHealthNotifier(ctx, tag),
 		close:                        make(chan struct{}),
 		pause:                        service.FromContext[pause.Manager](ctx),
+		power:                        service.FromContext[adapter.PowerManager](ctx),
 		interruptGroup:               interrupt.NewGroup(),
 		interruptExternalConnections: interruptExternalConnections,
-	}, nil
+	}
+	var err error
+	group.sla, err = newSLATracker(slaOptions, group.reprobe)
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
 }
 
 func (g *URLTestGroup) PostStart() {
@@ -275,6 +288,7 @@ func (g *URLTestGroup) Touch() {
 func (g *URLTestGroup) Close() error {
 	g.access.Lock()
 	defer g.access.Unlock()
+	g.sla.Close()
 	if g.ticker == nil {
 		return nil
 	}
@@ -348,10 +362,20 @@ func (g *URLTestGroup) loopCheck() {
 			g.access.Unlock()
 			return
 		}
+		if g.power != nil {
+			g.power.WaitWindow(g.ctx)
+		}
 		g.CheckOutbounds(false)
 	}
 }
 
+func (g *URLTestGroup) reprobe() {
+	if g.power != nil {
+		g.power.WaitWindow(g.ctx)
+	}
+	g.CheckOutbounds(false)
+}
+
 func (g *URLTestGroup) CheckOutbounds(force bool) {
 	_, _ = g.urlTest(g.ctx, force)
 }
@@ -375,6 +399,9 @@ func (g *URLTestGroup) urlTest(ctx context.Context, force bool) (map[string]uint
 		if checked[realTag] {
 			continue
 		}
+		if !force && !g.sla.probe(realTag) {
+			continue
+		}
 		history := g.history.LoadURLTestHistory(realTag)
 		if !force && history != nil && time.Since(history.Time) < g.interval {
 			continue
@@ -388,15 +415,23 @@ func (g *URLTestGroup) urlTest(ctx context.Context, force bool) (map[string]uint
 			testCtx, cancel := context.WithTimeout(g.ctx, C.TCPTimeout)
 			defer cancel()
 			t, err := urltest.URLTest(testCtx, g.link, p)
-			if err != nil {
+			var tolerated bool
+			if g.sla != nil {
+				tolerated, err = g.sla.update(realTag, t, err)
+			}
+			if tolerated {
+				g.logger.Debug("outbound ", tag, " unavailable within SLA: ", err)
+			} else if err != nil {
 				g.logger.Debug("outbound ", tag, " unavailable: ", err)
 				g.history.DeleteURLTestHistory(realTag)
+				g.health.update(realTag, 0, err)
 			} else {
 				g.logger.Debug("outbound ", tag, " available: ", t, "ms")
 				g.history.StoreURLTestHistory(realTag, &adapter.URLTestHistory{
 					Time:  time.Now(),
 					Delay: t,
 				})
+				g.health.update(realTag, t, nil)
 				resultAccess.Lock()
 				result[tag] = t
 				resultAccess.Unlock()
@@ -7,7 +7,9 @@ import (
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/adapter/inbound"
+	"github.com/sagernet/sing-box/common/authprovider"
 	"github.com/sagernet/sing-box/common/listener"
+	"github.com/sagernet/sing-box/common/pac"
 	"github.com/sagernet/sing-box/common/tls"
 	"github.com/sagernet/sing-box/common/uot"
 	C "github.com/sagernet/sing-box/constant"
@@ -17,7 +19,8 @@ import (
 	"github.com/sagernet/sing/common/auth"
 	E "github.com/sagernet/sing/common/exceptions"
 	N "github.com/sagernet/sing/common/network"
-	"github.com/sagernet/sing/protocol/http"
+
+	"golang.org/x/net/http2"
 )
 
 func RegisterInbound(registry *inbound.Registry) {
@@ -31,16 +34,25 @@ type Inbound struct {
 	router        adapter.ConnectionRouterEx
 	logger        log.ContextLogger
 	listener      *listener.Listener
-	authenticator *auth.Authenticator
+	authenticator *authprovider.Authenticator
+	pacServer     *pac.Server
 	tlsConfig     tls.ServerConfig
 }
 
 func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.HTTPMixedInboundOptions) (adapter.Inbound, error) {
 	inbound := &Inbound{
-		Adapter:       inbound.NewAdapter(C.TypeHTTP, tag),
-		router:        uot.NewRouter(router, logger),
-		logger:        logger,
-		authenticator: auth.NewAuthenticator(options.Users),
+		Adapter: inbound.NewAdapter(C.TypeHTTP, tag),
+		router:  uot.NewRouter(router, logger),
+		logger:  logger,
+	}
+	authenticator, err := authprovider.NewAuthenticator(ctx, logger, options.Users, options.AuthProvider)
+	if err != nil {
+		return nil, err
+	}
+	inbound.authenticator = authenticator
+	inbound.pacServer, err = pac.NewServer(router, logger, options.PAC, options.TLS != nil && options.TLS.Enabled)
+	if err != nil {
+		return nil, err
 	}
 	if options.TLS != nil {
 		tlsConfig, err := tls.NewServerWithOptions(tls.ServerOptions{
@@ -53,10 +65,14 @@ func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLo
 			return nil, err
 		}
 		inbound.tlsConfig = tlsConfig
+		if options.TLS.Enabled {
+			authprovider.WarnHTTP2ExtendedConnect(logger, options.TLS.ALPN)
+		}
 	}
 	inbound.listener = listener.New(listener.Options{
 		Context:           ctx,
 		Logger:            logger,
+		Tag:               tag,
 		Network:           []string{N.NetworkTCP},
 		Listen:            options.ListenOptions,
 		ConnectionHandler: inbound,
@@ -95,8 +111,18 @@ func (h *Inbound) NewConnectionEx(ctx context.Context, conn net.Conn, metadata a
 			return
 		}
 		conn = tlsConn
+		if tlsConn.ConnectionState().NegotiatedProtocol == http2.NextProtoTLS {
+			authprovider.HandleHTTP2ConnectionEx(ctx, conn, h.authenticator, adapter.NewUpstreamHandlerEx(metadata, h.newUserConnection, h.streamUserPacketConnection), metadata.Source, onClose)
+			return
+		}
+	}
+	reader := std_bufio.NewReader(conn)
+	var err error
+	if h.pacServer != nil && h.pacServer.Match(reader) {
+		err = h.pacServer.HandleConnectionEx(ctx, conn, reader, onClose)
+	} else {
+		err = authprovider.HandleHTTPConnectionEx(ctx, conn, reader, h.authenticator, adapter.NewUpstreamHandlerEx(metadata, h.newUserConnection, h.streamUserPacketConnection), metadata.Source, onClose)
 	}
-	err := http.HandleConnectionEx(ctx, conn, std_bufio.NewReader(conn), h.authenticator, adapter.NewUpstreamHandlerEx(metadata, h.newUserConnection, h.streamUserPacketConnection), metadata.Source, onClose)
 	if err != nil {
 		N.CloseOnHandshakeFailure(conn, onClose, err)
 		h.logger.ErrorContext(ctx, E.Cause(err, "process connection from ", metadata.Source))
@@ -4,9 +4,11 @@ import (
 	"context"
 	"net"
 	"os"
+	"strings"
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/adapter/outbound"
+	"github.com/sagernet/sing-box/common/credential"
 	"github.com/sagernet/sing-box/common/dialer"
 	"github.com/sagernet/sing-box/common/tls"
 	C "github.com/sagernet/sing-box/constant"
@@ -26,10 +28,14 @@ func RegisterOutbound(registry *outbound.Registry) {
 type Outbound struct {
 	outbound.Adapter
 	logger logger.ContextLogger
-	client *sHTTP.Client
+	client *credential.Set[*sHTTP.Client]
 }
 
 func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.HTTPOutboundOptions) (adapter.Outbound, error) {
+	credentials, err := credential.Credentials(options.Username, options.Password, options.Credentials)
+	if err != nil {
+		return nil, err
+	}
 	outboundDialer, err := dialer.New(ctx, options.DialerOptions, options.ServerIsDomain())
 	if err != nil {
 		return nil, err
@@ -38,17 +44,23 @@ func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextL
 	if err != nil {
 		return nil, err
 	}
-	return &Outbound{
-		Adapter: outbound.NewAdapterWithDialerOptions(C.TypeHTTP, tag, []string{N.NetworkTCP}, options.DialerOptions),
-		logger:  logger,
-		client: sHTTP.NewClient(sHTTP.Options{
+	clients, err := credential.New(logger, credentials, func(credential option.OutboundCredential) (*sHTTP.Client, error) {
+		return sHTTP.NewClient(sHTTP.Options{
 			Dialer:   detour,
 			Server:   options.ServerOptions.Build(),
-			Username: options.Username,
-			Password: options.Password,
+			Username: credential.Username,
+			Password: credential.Password,
 			Path:     options.Path,
 			Headers:  options.Headers.Build(),
-		}),
+		}), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Outbound{
+		Adapter: outbound.NewAdapterWithDialerOptions(C.TypeHTTP, tag, []string{N.NetworkTCP}, options.DialerOptions),
+		logger:  logger,
+		client:  clients,
 	}, nil
 }
 
@@ -57,9 +69,15 @@ func (h *Outbound) DialContext(ctx context.Context, network string, destination
 	metadata.Outbound = h.Tag()
 	metadata.Destination = destination
 	h.logger.InfoContext(ctx, "outbound connection to ", destination)
-	return h.client.DialContext(ctx, network, destination)
+	return credential.Dial(h.client, isHTTPAuthFailure, func(client *sHTTP.Client) (net.Conn, error) {
+		return client.DialContext(ctx, network, destination)
+	})
 }
 
 func (h *Outbound) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
 	return nil, os.ErrInvalid
 }
+
+func isHTTPAuthFailure(err error) bool {
+	return strings.Contains(err.Error(), "authentication required")
+}
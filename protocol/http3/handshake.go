@@ -0,0 +1,32 @@
+package http3
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/sagernet/quic-go"
+)
+
+type handshakeContextKey struct{}
+
+func contextWithHandshake(ctx context.Context, conn *quic.Conn) context.Context {
+	return context.WithValue(ctx, handshakeContextKey{}, conn.HandshakeComplete())
+}
+
+// handshakeHandler holds requests received in 0-RTT data until the handshake
+// completes, so that replayed requests are never proxied.
+type handshakeHandler struct {
+	http.Handler
+}
+
+func (h *handshakeHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	handshakeComplete, _ := request.Context().Value(handshakeContextKey{}).(<-chan struct{})
+	if handshakeComplete != nil {
+		select {
+		case <-handshakeComplete:
+		case <-request.Context().Done():
+			return
+		}
+	}
+	h.Handler.ServeHTTP(writer, request)
+}
@@ -0,0 +1,46 @@
+package http3
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newHandshakeRequest(ctx context.Context, handshakeComplete <-chan struct{}) *http.Request {
+	ctx = context.WithValue(ctx, handshakeContextKey{}, handshakeComplete)
+	return httptest.NewRequest(http.MethodConnect, "https://example.com:443", nil).WithContext(ctx)
+}
+
+func TestHandshakeHandlerWaitsForHandshake(t *testing.T) {
+	t.Parallel()
+	served := make(chan struct{})
+	handler := &handshakeHandler{http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		close(served)
+	})}
+	handshakeComplete := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(httptest.NewRecorder(), newHandshakeRequest(context.Background(), handshakeComplete))
+		close(done)
+	}()
+	select {
+	case <-served:
+		t.Fatal("request served before handshake completed")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(handshakeComplete)
+	<-done
+	<-served
+}
+
+func TestHandshakeHandlerDropsReplayedRequest(t *testing.T) {
+	t.Parallel()
+	handler := &handshakeHandler{http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		t.Error("replayed request served")
+	})}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	handler.ServeHTTP(httptest.NewRecorder(), newHandshakeRequest(ctx, make(chan struct{})))
+}
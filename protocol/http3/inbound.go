@@ -0,0 +1,194 @@
+package http3
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/sagernet/quic-go"
+	"github.com/sagernet/quic-go/http3"
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/adapter/inbound"
+	"github.com/sagernet/sing-box/common/authprovider"
+	"github.com/sagernet/sing-box/common/listener"
+	"github.com/sagernet/sing-box/common/masque"
+	"github.com/sagernet/sing-box/common/tls"
+	"github.com/sagernet/sing-box/common/uot"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-quic"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/auth"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+func RegisterInbound(registry *inbound.Registry) {
+	inbound.Register[option.HTTP3InboundOptions](registry, C.TypeHTTP3, NewInbound)
+}
+
+type Inbound struct {
+	inbound.Adapter
+	ctx           context.Context
+	router        adapter.ConnectionRouterEx
+	logger        log.ContextLogger
+	listener      *listener.Listener
+	authenticator *authprovider.Authenticator
+	tlsConfig     tls.ServerConfig
+	zeroRTT       bool
+	quicListener  qtls.EarlyListener
+	server        *http3.Server
+}
+
+func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.HTTP3InboundOptions) (adapter.Inbound, error) {
+	if options.TLS == nil || !options.TLS.Enabled {
+		return nil, C.ErrTLSRequired
+	}
+	tlsConfig, err := tls.NewServer(ctx, logger, common.PtrValueOrDefault(options.TLS))
+	if err != nil {
+		return nil, err
+	}
+	authenticator, err := authprovider.NewAuthenticator(ctx, logger, options.Users, options.AuthProvider)
+	if err != nil {
+		return nil, err
+	}
+	inbound := &Inbound{
+		Adapter: inbound.NewAdapter(C.TypeHTTP3, tag),
+		ctx:     ctx,
+		router:  uot.NewRouter(router, logger),
+		logger:  logger,
+		listener: listener.New(listener.Options{
+			Context: ctx,
+			Logger:  logger,
+			Tag:     tag,
+			Listen:  options.ListenOptions,
+		}),
+		authenticator: authenticator,
+		tlsConfig:     tlsConfig,
+		zeroRTT:       options.ZeroRTTHandshake,
+	}
+	return inbound, nil
+}
+
+func (h *Inbound) Start(stage adapter.StartStage) error {
+	if stage != adapter.StartStateStart {
+		return nil
+	}
+	err := h.tlsConfig.Start()
+	if err != nil {
+		return E.Cause(err, "create TLS config")
+	}
+	err = qtls.ConfigureHTTP3(h.tlsConfig)
+	if err != nil {
+		return err
+	}
+	udpConn, err := h.listener.ListenUDP()
+	if err != nil {
+		return err
+	}
+	quicListener, err := qtls.ListenEarly(udpConn, h.tlsConfig, &quic.Config{
+		EnableDatagrams:    true,
+		MaxIncomingStreams: 1 << 60,
+		Allow0RTT:          h.zeroRTT,
+	})
+	if err != nil {
+		udpConn.Close()
+		return err
+	}
+	var metadata adapter.InboundContext
+	//nolint:staticcheck
+	metadata.InboundDetour = h.listener.ListenOptions().Detour
+	//nolint:staticcheck
+	metadata.InboundOptions = h.listener.ListenOptions().InboundOptions
+	metadata.OriginDestination = h.listener.UDPAddr()
+	h.quicListener = quicListener
+	var handler http.Handler = &authprovider.HTTPHandler{
+		Authenticator: h.authenticator,
+		Handler:       adapter.NewUpstreamHandlerEx(metadata, h.newUserConnection, h.newUserPacketConnection),
+		NewPacketConn: h.newPacketConn,
+	}
+	h.server = &http3.Server{
+		EnableDatagrams: true,
+		Handler:         handler,
+	}
+	if h.zeroRTT {
+		h.server.Handler = &handshakeHandler{handler}
+		h.server.ConnContext = contextWithHandshake
+	}
+	go func() {
+		sErr := h.server.ServeListener(quicListener)
+		udpConn.Close()
+		if sErr != nil && !E.IsClosedOrCanceled(sErr) {
+			h.logger.Error("http3 server closed: ", sErr)
+		}
+	}()
+	return nil
+}
+
+func (h *Inbound) Close() error {
+	return common.Close(
+		h.quicListener,
+		common.PtrOrNil(h.server),
+		h.listener,
+		h.tlsConfig,
+	)
+}
+
+func (h *Inbound) newPacketConn(writer http.ResponseWriter, conn net.Conn, destination M.Socksaddr) N.PacketConn {
+	stream := writer.(http3.HTTPStreamer).HTTPStream()
+	return masque.NewDatagramPacketConn(h.ctx, stream, &streamConn{conn, stream}, destination)
+}
+
+func (h *Inbound) newUserConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
+	ctx = log.ContextWithNewID(ctx)
+	metadata.Inbound = h.Tag()
+	metadata.InboundType = h.Type()
+	h.logger.InfoContext(ctx, "inbound connection from ", metadata.Source)
+	user, loaded := auth.UserFromContext[string](ctx)
+	if !loaded {
+		h.logger.InfoContext(ctx, "inbound connection to ", metadata.Destination)
+		h.router.RouteConnectionEx(ctx, conn, metadata, onClose)
+		return
+	}
+	metadata.User = user
+	h.logger.InfoContext(ctx, "[", user, "] inbound connection to ", metadata.Destination)
+	h.router.RouteConnectionEx(ctx, conn, metadata, onClose)
+}
+
+func (h *Inbound) newUserPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
+	ctx = log.ContextWithNewID(ctx)
+	metadata.Inbound = h.Tag()
+	metadata.InboundType = h.Type()
+	h.logger.InfoContext(ctx, "inbound packet connection from ", metadata.Source)
+	user, loaded := auth.UserFromContext[string](ctx)
+	if !loaded {
+		h.logger.InfoContext(ctx, "inbound packet connection to ", metadata.Destination)
+		h.router.RoutePacketConnectionEx(ctx, conn, metadata, onClose)
+		return
+	}
+	metadata.User = user
+	h.logger.InfoContext(ctx, "[", user, "] inbound packet connection to ", metadata.Destination)
+	h.router.RoutePacketConnectionEx(ctx, conn, metadata, onClose)
+}
+
+// streamConn reads and closes the hijacked request stream of a connect-udp
+// request, instead of the response writer.
+type streamConn struct {
+	net.Conn
+	stream *http3.Stream
+}
+
+func (c *streamConn) Read(p []byte) (n int, err error) {
+	return c.stream.Read(p)
+}
+
+func (c *streamConn) Write(p []byte) (n int, err error) {
+	return c.stream.Write(p)
+}
+
+func (c *streamConn) Close() error {
+	c.stream.CancelRead(quic.StreamErrorCode(http3.ErrCodeNoError))
+	return c.stream.Close()
+}
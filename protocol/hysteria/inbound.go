@@ -49,6 +49,7 @@ func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLo
 		listener: listener.New(listener.Options{
 			Context: ctx,
 			Logger:  logger,
+			Tag:     tag,
 			Listen:  options.ListenOptions,
 		}),
 		tlsConfig: tlsConfig,
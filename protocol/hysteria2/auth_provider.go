@@ -0,0 +1,87 @@
+package hysteria2
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/sagernet/quic-go/http3"
+)
+
+// constants of the authentication request of the hysteria2 protocol
+const (
+	authRequestHost   = "hysteria"
+	authRequestPath   = "/auth"
+	authRequestHeader = "Hysteria-Auth"
+)
+
+// providerUser is a user accepted by the auth provider, with a negative ID
+// that does not collide with IDs of configured users.
+type providerUser struct {
+	id       int
+	name     string
+	password string
+}
+
+type reauthKey struct{}
+
+// authProviderHandler handles authentication requests with passwords unknown
+// to the service, which are passed to the masquerade handler by the service.
+// A password accepted by the auth provider is added to the service until the
+// cache TTL expires, and the request is passed to the session again.
+type authProviderHandler Inbound
+
+func (h *authProviderHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	inbound := (*Inbound)(h)
+	if request.Method == http.MethodPost && request.Host == authRequestHost && request.URL.Path == authRequestPath && request.Context().Value(reauthKey{}) == nil {
+		if auth := request.Header.Get(authRequestHeader); auth != "" && inbound.verifyProviderUser(request.Context(), auth) {
+			server, loaded := request.Context().Value(http3.ServerContextKey).(*http3.Server)
+			if loaded && server.Handler != nil {
+				server.Handler.ServeHTTP(writer, request.WithContext(context.WithValue(request.Context(), reauthKey{}, true)))
+				return
+			}
+		}
+	}
+	if inbound.masqueradeHandler != nil {
+		inbound.masqueradeHandler.ServeHTTP(writer, request)
+	} else {
+		http.NotFound(writer, request)
+	}
+}
+
+// verifyProviderUser verifies the auth string with the auth provider, as
+// `username:password` if it contains a colon, or the password otherwise.
+func (h *Inbound) verifyProviderUser(ctx context.Context, auth string) bool {
+	username, password, loaded := strings.Cut(auth, ":")
+	if !loaded {
+		username, password = "", auth
+	}
+	accepted, err := h.authProvider.Authenticate(ctx, username, password)
+	if err != nil {
+		h.logger.ErrorContext(ctx, E.Cause(err, "auth provider: verify user ", username))
+		return false
+	}
+	if !accepted {
+		return false
+	}
+	h.userAccess.Lock()
+	h.nextProviderID--
+	h.providerUsers = append(h.providerUsers, providerUser{h.nextProviderID, username, auth})
+	h.updateServiceLocked()
+	h.userAccess.Unlock()
+	time.AfterFunc(h.authProviderTTL, func() {
+		h.userAccess.Lock()
+		defer h.userAccess.Unlock()
+		for i, user := range h.providerUsers {
+			if user.password == auth {
+				h.providerUsers = append(h.providerUsers[:i:i], h.providerUsers[i+1:]...)
+				h.updateServiceLocked()
+				return
+			}
+		}
+	})
+	return true
+}
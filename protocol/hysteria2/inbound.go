@@ -6,11 +6,15 @@ import (
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/adapter/inbound"
+	"github.com/sagernet/sing-box/common/authprovider"
+	"github.com/sagernet/sing-box/common/bandwidth"
 	"github.com/sagernet/sing-box/common/listener"
+	"github.com/sagernet/sing-box/common/quicmtu"
 	"github.com/sagernet/sing-box/common/tls"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
@@ -28,14 +32,27 @@ func RegisterInbound(registry *inbound.Registry) {
 	inbound.Register[option.Hysteria2InboundOptions](registry, C.TypeHysteria2, NewInbound)
 }
 
+var _ adapter.ManagedUserInbound = (*Inbound)(nil)
+
 type Inbound struct {
 	inbound.Adapter
-	router       adapter.Router
-	logger       log.ContextLogger
-	listener     *listener.Listener
-	tlsConfig    tls.ServerConfig
-	service      *hysteria2.Service[int]
-	userNameList []string
+	router            adapter.Router
+	logger            log.ContextLogger
+	listener          *listener.Listener
+	tlsConfig         tls.ServerConfig
+	service           *hysteria2.Service[int]
+	users             *inbound.UserList[option.Hysteria2User]
+	options           option.Hysteria2InboundOptions
+	masqueradeHandler http.Handler
+	authProvider      authprovider.Provider
+	authProviderTTL   time.Duration
+	userAccess        sync.Mutex
+	staticIDs         []int
+	staticUsers       []option.Hysteria2User
+	providerUsers     []providerUser
+	nextProviderID    int
+	userNames         map[int]string
+	limiters          bandwidth.Limiters[int]
 }
 
 func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.Hysteria2InboundOptions) (adapter.Inbound, error) {
@@ -43,6 +60,10 @@ func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLo
 	if options.TLS == nil || !options.TLS.Enabled {
 		return nil, C.ErrTLSRequired
 	}
+	err := quicmtu.Validate(options.QUICMTUOptions, false)
+	if err != nil {
+		return nil, err
+	}
 	tlsConfig, err := tls.NewServer(ctx, logger, common.PtrValueOrDefault(options.TLS))
 	if err != nil {
 		return nil, err
@@ -103,9 +124,21 @@ func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLo
 		listener: listener.New(listener.Options{
 			Context: ctx,
 			Logger:  logger,
+			Tag:     tag,
 			Listen:  options.ListenOptions,
 		}),
-		tlsConfig: tlsConfig,
+		tlsConfig:         tlsConfig,
+		users:             inbound.NewUserList(option.Hysteria2User.InboundUser),
+		options:           options,
+		masqueradeHandler: masqueradeHandler,
+	}
+	if options.AuthProvider != nil {
+		inbound.authProvider, err = authprovider.New(ctx, logger, *options.AuthProvider)
+		if err != nil {
+			return nil, E.Cause(err, "create auth provider")
+		}
+		inbound.authProviderTTL = authprovider.CacheTTL(*options.AuthProvider)
+		masqueradeHandler = (*authProviderHandler)(inbound)
 	}
 	var udpTimeout time.Duration
 	if options.UDPTimeout != 0 {
@@ -129,20 +162,56 @@ func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLo
 	if err != nil {
 		return nil, err
 	}
-	userList := make([]int, 0, len(options.Users))
-	userNameList := make([]string, 0, len(options.Users))
-	userPasswordList := make([]string, 0, len(options.Users))
-	for index, user := range options.Users {
-		userList = append(userList, index)
-		userNameList = append(userNameList, user.Name)
-		userPasswordList = append(userPasswordList, user.Password)
-	}
-	service.UpdateUsers(userList, userPasswordList)
 	inbound.service = service
-	inbound.userNameList = userNameList
+	err = inbound.users.Initialize(options.Users, func(ids []int, users []option.Hysteria2User) error {
+		inbound.userAccess.Lock()
+		defer inbound.userAccess.Unlock()
+		inbound.staticIDs = ids
+		inbound.staticUsers = users
+		inbound.updateServiceLocked()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 	return inbound, nil
 }
 
+// updateServiceLocked updates the service with the configured users followed
+// by the users accepted by the auth provider.
+func (h *Inbound) updateServiceLocked() {
+	userCount := len(h.staticUsers) + len(h.providerUsers)
+	userIDs := make([]int, 0, userCount)
+	passwords := make([]string, 0, userCount)
+	userNames := make(map[int]string, userCount)
+	limits := make(map[int]bandwidth.Limit, len(h.staticUsers))
+	for i, user := range h.staticUsers {
+		userID := h.staticIDs[i]
+		userIDs = append(userIDs, userID)
+		passwords = append(passwords, user.Password)
+		userNames[userID] = user.Name
+		limits[userID] = bandwidth.Limit{UploadMbps: user.UpMbps, DownloadMbps: user.DownMbps}
+	}
+	for _, user := range h.providerUsers {
+		userIDs = append(userIDs, user.id)
+		passwords = append(passwords, user.password)
+		userNames[user.id] = user.name
+	}
+	h.service.UpdateUsers(userIDs, passwords)
+	h.userNames = userNames
+	h.limiters.Update(limits)
+}
+
+func (h *Inbound) user(ctx context.Context) (string, *bandwidth.Limiter) {
+	userID, loaded := auth.UserFromContext[int](ctx)
+	if !loaded {
+		return "", nil
+	}
+	h.userAccess.Lock()
+	defer h.userAccess.Unlock()
+	return h.userNames[userID], h.limiters.Load(userID)
+}
+
 func (h *Inbound) NewConnectionEx(ctx context.Context, conn net.Conn, source M.Socksaddr, destination M.Socksaddr, onClose N.CloseHandlerFunc) {
 	ctx = log.ContextWithNewID(ctx)
 	var metadata adapter.InboundContext
@@ -156,8 +225,11 @@ func (h *Inbound) NewConnectionEx(ctx context.Context, conn net.Conn, source M.S
 	metadata.Source = source
 	metadata.Destination = destination
 	h.logger.InfoContext(ctx, "inbound connection from ", metadata.Source)
-	userID, _ := auth.UserFromContext[int](ctx)
-	if userName := h.userNameList[userID]; userName != "" {
+	userName, limiter := h.user(ctx)
+	if limiter != nil {
+		conn = limiter.NewConn(ctx, conn)
+	}
+	if userName != "" {
 		metadata.User = userName
 		h.logger.InfoContext(ctx, "[", userName, "] inbound connection to ", metadata.Destination)
 	} else {
@@ -179,8 +251,11 @@ func (h *Inbound) NewPacketConnectionEx(ctx context.Context, conn N.PacketConn,
 	metadata.Source = source
 	metadata.Destination = destination
 	h.logger.InfoContext(ctx, "inbound packet connection from ", metadata.Source)
-	userID, _ := auth.UserFromContext[int](ctx)
-	if userName := h.userNameList[userID]; userName != "" {
+	userName, limiter := h.user(ctx)
+	if limiter != nil {
+		conn = limiter.NewPacketConn(ctx, conn)
+	}
+	if userName != "" {
 		metadata.User = userName
 		h.logger.InfoContext(ctx, "[", userName, "] inbound packet connection to ", metadata.Destination)
 	} else {
@@ -203,7 +278,7 @@ func (h *Inbound) Start(stage adapter.StartStage) error {
 	if err != nil {
 		return err
 	}
-	return h.service.Start(packetConn)
+	return h.service.Start(quicmtu.NewPacketConn(packetConn, h.options.QUICMTUOptions))
 }
 
 func (h *Inbound) Close() error {
@@ -213,3 +288,17 @@ func (h *Inbound) Close() error {
 		common.PtrOrNil(h.service),
 	)
 }
+
+func (h *Inbound) InboundOptions() option.Inbound {
+	options := h.options
+	options.Users = h.users.Users()
+	return option.Inbound{Type: h.Type(), Tag: h.Tag(), Options: &options}
+}
+
+func (h *Inbound) SetUser(content []byte) (option.InboundUser, error) {
+	return h.users.SetUser(content)
+}
+
+func (h *Inbound) RemoveUser(name string) error {
+	return h.users.RemoveUser(name)
+}
@@ -3,21 +3,19 @@ package hysteria2
 import (
 	"context"
 	"net"
-	"os"
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/adapter/outbound"
+	"github.com/sagernet/sing-box/client/hysteria2"
 	"github.com/sagernet/sing-box/common/dialer"
 	"github.com/sagernet/sing-box/common/tls"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing-box/protocol/tuic"
-	"github.com/sagernet/sing-quic/hysteria"
-	"github.com/sagernet/sing-quic/hysteria2"
+	qHysteria2 "github.com/sagernet/sing-quic/hysteria2"
 	"github.com/sagernet/sing/common"
-	"github.com/sagernet/sing/common/bufio"
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/logger"
 	M "github.com/sagernet/sing/common/metadata"
@@ -54,7 +52,7 @@ func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextL
 			return nil, E.New("missing obfs password")
 		}
 		switch options.Obfs.Type {
-		case hysteria2.ObfsTypeSalamander:
+		case qHysteria2.ObfsTypeSalamander:
 			salamanderPassword = options.Obfs.Password
 		default:
 			return nil, E.New("unknown obfs type: ", options.Obfs.Type)
@@ -65,20 +63,19 @@ func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextL
 		return nil, err
 	}
 	networkList := options.Network.Build()
-	client, err := hysteria2.NewClient(hysteria2.ClientOptions{
-		Context:            ctx,
+	client, err := hysteria2.NewClient(ctx, hysteria2.Options{
 		Dialer:             outboundDialer,
 		Logger:             logger,
-		BrutalDebug:        options.BrutalDebug,
-		ServerAddress:      options.ServerOptions.Build(),
+		Server:             options.ServerOptions.Build(),
 		ServerPorts:        options.ServerPorts,
 		HopInterval:        time.Duration(options.HopInterval),
-		SendBPS:            uint64(options.UpMbps * hysteria.MbpsToBps),
-		ReceiveBPS:         uint64(options.DownMbps * hysteria.MbpsToBps),
-		SalamanderPassword: salamanderPassword,
 		Password:           options.Password,
+		SalamanderPassword: salamanderPassword,
+		UpMbps:             options.UpMbps,
+		DownMbps:           options.DownMbps,
 		TLSConfig:          tlsConfig,
-		UDPDisabled:        !common.Contains(networkList, N.NetworkUDP),
+		DisableUDP:         !common.Contains(networkList, N.NetworkUDP),
+		BrutalDebug:        options.BrutalDebug,
 	})
 	if err != nil {
 		return nil, err
@@ -94,21 +91,17 @@ func (h *Outbound) DialContext(ctx context.Context, network string, destination
 	switch N.NetworkName(network) {
 	case N.NetworkTCP:
 		h.logger.InfoContext(ctx, "outbound connection to ", destination)
-		return h.client.DialConn(ctx, destination)
 	case N.NetworkUDP:
-		conn, err := h.ListenPacket(ctx, destination)
-		if err != nil {
-			return nil, err
-		}
-		return bufio.NewBindPacketConn(conn, destination), nil
+		h.logger.InfoContext(ctx, "outbound packet connection to ", destination)
 	default:
 		return nil, E.New("unsupported network: ", network)
 	}
+	return h.client.DialContext(ctx, network, destination)
 }
 
 func (h *Outbound) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
 	h.logger.InfoContext(ctx, "outbound packet connection to ", destination)
-	return h.client.ListenPacket(ctx)
+	return h.client.ListenPacket(ctx, destination)
 }
 
 func (h *Outbound) InterfaceUpdated() {
@@ -116,5 +109,5 @@ func (h *Outbound) InterfaceUpdated() {
 }
 
 func (h *Outbound) Close() error {
-	return h.client.CloseWithError(os.ErrClosed)
+	return h.client.Close()
 }
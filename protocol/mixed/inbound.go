@@ -7,7 +7,10 @@ import (
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/adapter/inbound"
+	"github.com/sagernet/sing-box/common/authprovider"
 	"github.com/sagernet/sing-box/common/listener"
+	"github.com/sagernet/sing-box/common/pac"
+	"github.com/sagernet/sing-box/common/socksudp"
 	"github.com/sagernet/sing-box/common/tls"
 	"github.com/sagernet/sing-box/common/uot"
 	C "github.com/sagernet/sing-box/constant"
@@ -17,10 +20,10 @@ import (
 	"github.com/sagernet/sing/common/auth"
 	E "github.com/sagernet/sing/common/exceptions"
 	N "github.com/sagernet/sing/common/network"
-	"github.com/sagernet/sing/protocol/http"
-	"github.com/sagernet/sing/protocol/socks"
 	"github.com/sagernet/sing/protocol/socks/socks4"
 	"github.com/sagernet/sing/protocol/socks/socks5"
+
+	"golang.org/x/net/http2"
 )
 
 func RegisterInbound(registry *inbound.Registry) {
@@ -34,16 +37,26 @@ type Inbound struct {
 	router        adapter.ConnectionRouterEx
 	logger        log.ContextLogger
 	listener      *listener.Listener
-	authenticator *auth.Authenticator
+	authenticator *authprovider.Authenticator
+	udpServer     *socksudp.Server
+	pacServer     *pac.Server
 	tlsConfig     tls.ServerConfig
 }
 
 func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.HTTPMixedInboundOptions) (adapter.Inbound, error) {
 	inbound := &Inbound{
-		Adapter:       inbound.NewAdapter(C.TypeMixed, tag),
-		router:        uot.NewRouter(router, logger),
-		logger:        logger,
-		authenticator: auth.NewAuthenticator(options.Users),
+		Adapter: inbound.NewAdapter(C.TypeMixed, tag),
+		router:  uot.NewRouter(router, logger),
+		logger:  logger,
+	}
+	authenticator, err := authprovider.NewAuthenticator(ctx, logger, options.Users, options.AuthProvider)
+	if err != nil {
+		return nil, err
+	}
+	inbound.authenticator = authenticator
+	inbound.pacServer, err = pac.NewServer(router, logger, options.PAC, options.TLS != nil && options.TLS.Enabled)
+	if err != nil {
+		return nil, err
 	}
 	if options.TLS != nil {
 		tlsConfig, err := tls.NewServerWithOptions(tls.ServerOptions{
@@ -56,16 +69,24 @@ func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLo
 			return nil, err
 		}
 		inbound.tlsConfig = tlsConfig
+		if options.TLS.Enabled {
+			authprovider.WarnHTTP2ExtendedConnect(logger, options.TLS.ALPN)
+		}
 	}
 	inbound.listener = listener.New(listener.Options{
 		Context:           ctx,
 		Logger:            logger,
+		Tag:               tag,
 		Network:           []string{N.NetworkTCP},
 		Listen:            options.ListenOptions,
 		ConnectionHandler: inbound,
 		SetSystemProxy:    options.SetSystemProxy,
 		SystemProxySOCKS:  true,
 	})
+	inbound.udpServer, err = socksudp.NewServer(ctx, logger, inbound.listener, options.UDPRelay, options.UDPInTCP)
+	if err != nil {
+		return nil, err
+	}
 	return inbound, nil
 }
 
@@ -79,10 +100,20 @@ func (h *Inbound) Start(stage adapter.StartStage) error {
 			return E.Cause(err, "create TLS config")
 		}
 	}
-	return h.listener.Start()
+	err := h.listener.Start()
+	if err != nil {
+		return err
+	}
+	if h.udpServer != nil {
+		return h.udpServer.Start()
+	}
+	return nil
 }
 
 func (h *Inbound) Close() error {
+	if h.udpServer != nil {
+		h.udpServer.Close()
+	}
 	return common.Close(
 		h.listener,
 		h.tlsConfig,
@@ -108,6 +139,10 @@ func (h *Inbound) newConnection(ctx context.Context, conn net.Conn, metadata ada
 			return E.Cause(err, "TLS handshake")
 		}
 		conn = tlsConn
+		if tlsConn.ConnectionState().NegotiatedProtocol == http2.NextProtoTLS {
+			authprovider.HandleHTTP2ConnectionEx(ctx, conn, h.authenticator, adapter.NewUpstreamHandlerEx(metadata, h.newUserConnection, h.streamUserPacketConnection), metadata.Source, onClose)
+			return nil
+		}
 	}
 	reader := std_bufio.NewReader(conn)
 	headerBytes, err := reader.Peek(1)
@@ -116,9 +151,12 @@ func (h *Inbound) newConnection(ctx context.Context, conn net.Conn, metadata ada
 	}
 	switch headerBytes[0] {
 	case socks4.Version, socks5.Version:
-		return socks.HandleConnectionEx(ctx, conn, reader, h.authenticator, adapter.NewUpstreamHandlerEx(metadata, h.newUserConnection, h.streamUserPacketConnection), h.listener, metadata.Source, onClose)
+		return authprovider.HandleSOCKSConnectionEx(ctx, conn, reader, h.authenticator, h.udpServer, adapter.NewUpstreamHandlerEx(metadata, h.newUserConnection, h.streamUserPacketConnection), h.listener, metadata.Source, onClose)
 	default:
-		return http.HandleConnectionEx(ctx, conn, reader, h.authenticator, adapter.NewUpstreamHandlerEx(metadata, h.newUserConnection, h.streamUserPacketConnection), metadata.Source, onClose)
+		if h.pacServer != nil && h.pacServer.Match(reader) {
+			return h.pacServer.HandleConnectionEx(ctx, conn, reader, onClose)
+		}
+		return authprovider.HandleHTTPConnectionEx(ctx, conn, reader, h.authenticator, adapter.NewUpstreamHandlerEx(metadata, h.newUserConnection, h.streamUserPacketConnection), metadata.Source, onClose)
 	}
 }
 
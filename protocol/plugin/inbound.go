@@ -0,0 +1,132 @@
+package plugin
+
+import (
+	std_bufio "bufio"
+	"context"
+	"net"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/adapter/inbound"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/auth"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/protocol/socks"
+)
+
+func RegisterInbound(registry *inbound.Registry) {
+	inbound.Register[option.PluginInboundOptions](registry, C.TypePlugin, NewInbound)
+}
+
+var _ adapter.Inbound = (*Inbound)(nil)
+
+// Inbound runs a plugin subprocess that accepts clients itself and hands
+// their connections over to a loopback SOCKS5 server authenticated with
+// generated credentials.
+type Inbound struct {
+	inbound.Adapter
+	ctx           context.Context
+	router        adapter.ConnectionRouterEx
+	logger        logger.ContextLogger
+	process       *process
+	username      string
+	password      string
+	authenticator *auth.Authenticator
+	tcpListener   *net.TCPListener
+}
+
+func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.PluginInboundOptions) (adapter.Inbound, error) {
+	pluginProcess, err := newProcess(logger, tag, roleInbound, options.PluginOptions)
+	if err != nil {
+		return nil, err
+	}
+	username := newCredential()
+	password := newCredential()
+	return &Inbound{
+		Adapter:       inbound.NewAdapter(C.TypePlugin, tag),
+		ctx:           ctx,
+		router:        router,
+		logger:        logger,
+		process:       pluginProcess,
+		username:      username,
+		password:      password,
+		authenticator: auth.NewAuthenticator([]auth.User{{Username: username, Password: password}}),
+	}, nil
+}
+
+func (h *Inbound) Start(stage adapter.StartStage) error {
+	if stage != adapter.StartStateStart {
+		return nil
+	}
+	tcpListener, err := net.ListenTCP("tcp", &net.TCPAddr{
+		IP: net.IPv4(127, 0, 0, 1),
+	})
+	if err != nil {
+		return err
+	}
+	h.tcpListener = tcpListener
+	go h.loopAccept()
+	_, err = h.process.start(h.ctx,
+		envSocksAddress+"="+tcpListener.Addr().String(),
+		envSocksUsername+"="+h.username,
+		envSocksPassword+"="+h.password,
+	)
+	if err != nil {
+		return err
+	}
+	h.logger.Debug("plugin ", h.process.options.Plugin, " ready")
+	return nil
+}
+
+func (h *Inbound) Close() error {
+	return E.Errors(
+		common.Close(common.PtrOrNil(h.tcpListener)),
+		h.process.close(),
+	)
+}
+
+func (h *Inbound) loopAccept() {
+	for {
+		conn, err := h.tcpListener.AcceptTCP()
+		if err != nil {
+			return
+		}
+		go h.newConnection(log.ContextWithNewID(h.ctx), conn)
+	}
+}
+
+func (h *Inbound) newConnection(ctx context.Context, conn *net.TCPConn) {
+	err := socks.HandleConnectionEx(ctx, conn, std_bufio.NewReader(conn), h.authenticator, h, nil, M.SocksaddrFromNet(conn.RemoteAddr()), nil)
+	if err != nil {
+		if E.IsClosedOrCanceled(err) {
+			h.logger.DebugContext(ctx, "connection closed: ", err)
+		} else {
+			h.logger.ErrorContext(ctx, E.Cause(err, "process plugin connection"))
+		}
+	}
+}
+
+func (h *Inbound) NewConnectionEx(ctx context.Context, conn net.Conn, source M.Socksaddr, destination M.Socksaddr, onClose N.CloseHandlerFunc) {
+	var metadata adapter.InboundContext
+	metadata.Inbound = h.Tag()
+	metadata.InboundType = h.Type()
+	metadata.Source = source
+	metadata.Destination = destination
+	h.logger.InfoContext(ctx, "inbound connection to ", destination)
+	h.router.RouteConnectionEx(ctx, conn, metadata, onClose)
+}
+
+func (h *Inbound) NewPacketConnectionEx(ctx context.Context, conn N.PacketConn, source M.Socksaddr, destination M.Socksaddr, onClose N.CloseHandlerFunc) {
+	var metadata adapter.InboundContext
+	metadata.Inbound = h.Tag()
+	metadata.InboundType = h.Type()
+	metadata.Source = source
+	metadata.Destination = destination
+	h.logger.InfoContext(ctx, "inbound packet connection to ", destination)
+	h.router.RoutePacketConnectionEx(ctx, conn, metadata, onClose)
+}
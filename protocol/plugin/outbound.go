@@ -0,0 +1,98 @@
+package plugin
+
+import (
+	"context"
+	"net"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/adapter/outbound"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/protocol/socks"
+)
+
+func RegisterOutbound(registry *outbound.Registry) {
+	outbound.Register[option.PluginOutboundOptions](registry, C.TypePlugin, NewOutbound)
+}
+
+var _ adapter.Outbound = (*Outbound)(nil)
+
+// Outbound forwards connections to a plugin subprocess, which serves them
+// on a loopback SOCKS5 server authenticated with generated credentials.
+type Outbound struct {
+	outbound.Adapter
+	ctx      context.Context
+	logger   logger.ContextLogger
+	process  *process
+	username string
+	password string
+	client   *socks.Client
+}
+
+func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.PluginOutboundOptions) (adapter.Outbound, error) {
+	pluginProcess, err := newProcess(logger, tag, roleOutbound, options.PluginOptions)
+	if err != nil {
+		return nil, err
+	}
+	return &Outbound{
+		Adapter:  outbound.NewAdapter(C.TypePlugin, tag, options.Network.Build(), nil),
+		ctx:      ctx,
+		logger:   logger,
+		process:  pluginProcess,
+		username: newCredential(),
+		password: newCredential(),
+	}, nil
+}
+
+func (h *Outbound) Start(stage adapter.StartStage) error {
+	if stage != adapter.StartStateStart {
+		return nil
+	}
+	message, err := h.process.start(h.ctx,
+		envSocksUsername+"="+h.username,
+		envSocksPassword+"="+h.password,
+	)
+	if err != nil {
+		return err
+	}
+	socksAddress := M.ParseSocksaddr(message.SocksAddress)
+	if !socksAddress.IsValid() {
+		h.process.close()
+		return E.New("plugin reported invalid socks address: ", message.SocksAddress)
+	}
+	h.logger.Debug("plugin ", h.process.options.Plugin, " ready at ", socksAddress)
+	h.client = socks.NewClient(N.SystemDialer, socksAddress, socks.Version5, h.username, h.password)
+	return nil
+}
+
+func (h *Outbound) Close() error {
+	return h.process.close()
+}
+
+func (h *Outbound) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	ctx, metadata := adapter.ExtendContext(ctx)
+	metadata.Outbound = h.Tag()
+	metadata.Destination = destination
+	switch N.NetworkName(network) {
+	case N.NetworkTCP:
+		h.logger.InfoContext(ctx, "outbound connection to ", destination)
+	case N.NetworkUDP:
+		h.logger.InfoContext(ctx, "outbound packet connection to ", destination)
+	default:
+		return nil, E.Extend(N.ErrUnknownNetwork, network)
+	}
+	return h.client.DialContext(ctx, network, destination)
+}
+
+func (h *Outbound) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	ctx, metadata := adapter.ExtendContext(ctx)
+	metadata.Outbound = h.Tag()
+	metadata.Destination = destination
+	h.logger.InfoContext(ctx, "outbound packet connection to ", destination)
+	return h.client.ListenPacket(ctx, destination)
+}
@@ -0,0 +1,189 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/common/logger"
+)
+
+// ProtocolVersion is the version of the plugin protocol, a plugin must
+// report it in its ready message.
+const ProtocolVersion = 1
+
+const (
+	roleInbound  = "inbound"
+	roleOutbound = "outbound"
+
+	envProtocolVersion = "SING_BOX_PLUGIN_PROTOCOL_VERSION"
+	envRole            = "SING_BOX_PLUGIN_ROLE"
+	envType            = "SING_BOX_PLUGIN_TYPE"
+	envTag             = "SING_BOX_PLUGIN_TAG"
+	envOptions         = "SING_BOX_PLUGIN_OPTIONS"
+	envSocksAddress    = "SING_BOX_PLUGIN_SOCKS_ADDRESS"
+	envSocksUsername   = "SING_BOX_PLUGIN_SOCKS_USERNAME"
+	envSocksPassword   = "SING_BOX_PLUGIN_SOCKS_PASSWORD"
+
+	processCloseTimeout = 5 * time.Second
+)
+
+// readyMessage is the first line a plugin writes to stdout once it is
+// ready to serve, or the error that prevented it from starting.
+type readyMessage struct {
+	Version      int    `json:"version"`
+	SocksAddress string `json:"socks_address,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// process runs a plugin as a subprocess. Options are passed through the
+// environment, the ready message is read from stdout, and everything else
+// the plugin writes is forwarded to the logger. Closing stdin asks the
+// plugin to exit.
+type process struct {
+	logger  logger.ContextLogger
+	tag     string
+	role    string
+	options option.PluginOptions
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	done    chan struct{}
+	closed  atomic.Bool
+}
+
+func newProcess(logger logger.ContextLogger, tag string, role string, options option.PluginOptions) (*process, error) {
+	if options.Plugin == "" {
+		return nil, E.New("missing plugin type")
+	}
+	if options.Path == "" {
+		return nil, E.New("missing plugin path")
+	}
+	return &process{
+		logger:  logger,
+		tag:     tag,
+		role:    role,
+		options: options,
+	}, nil
+}
+
+func newCredential() string {
+	var credential [16]byte
+	rand.Read(credential[:])
+	return hex.EncodeToString(credential[:])
+}
+
+func (p *process) start(ctx context.Context, env ...string) (*readyMessage, error) {
+	cmd := exec.Command(os.ExpandEnv(p.options.Path), p.options.Args...)
+	cmd.Env = os.Environ()
+	for key, value := range p.options.Env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	pluginOptions := string(p.options.Options)
+	if pluginOptions == "" {
+		pluginOptions = "{}"
+	}
+	cmd.Env = append(cmd.Env,
+		envProtocolVersion+"="+F.ToString(ProtocolVersion),
+		envRole+"="+p.role,
+		envType+"="+p.options.Plugin,
+		envTag+"="+p.tag,
+		envOptions+"="+pluginOptions,
+	)
+	cmd.Env = append(cmd.Env, env...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	err = cmd.Start()
+	if err != nil {
+		return nil, E.Cause(err, "start plugin")
+	}
+	p.cmd = cmd
+	p.stdin = stdin
+	p.done = make(chan struct{})
+	readyChan := make(chan readyMessage, 1)
+	go p.loopOutput(stderr)
+	go func() {
+		reader := bufio.NewReader(stdout)
+		line, _ := reader.ReadBytes('\n')
+		var message readyMessage
+		err := json.Unmarshal(line, &message)
+		if err != nil {
+			message.Error = E.Cause(err, "decode ready message: ", strings.TrimSpace(string(line))).Error()
+		}
+		readyChan <- message
+		p.loopOutput(reader)
+	}()
+	go func() {
+		err := cmd.Wait()
+		close(p.done)
+		if !p.closed.Load() {
+			p.logger.Error(E.Cause(err, "plugin exited unexpectedly"))
+		}
+	}()
+	timer := time.NewTimer(C.StartTimeout)
+	defer timer.Stop()
+	var message readyMessage
+	select {
+	case message = <-readyChan:
+	case <-p.done:
+		return nil, E.New("plugin exited before ready")
+	case <-timer.C:
+		p.close()
+		return nil, E.New("plugin did not become ready in ", C.StartTimeout)
+	case <-ctx.Done():
+		p.close()
+		return nil, ctx.Err()
+	}
+	if message.Error != "" {
+		p.close()
+		return nil, E.New("plugin failed to start: ", message.Error)
+	}
+	if message.Version != ProtocolVersion {
+		p.close()
+		return nil, E.New("unsupported plugin protocol version: ", message.Version)
+	}
+	return &message, nil
+}
+
+func (p *process) loopOutput(reader io.Reader) {
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		p.logger.Info(scanner.Text())
+	}
+}
+
+func (p *process) close() error {
+	if p.cmd == nil || !p.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	p.stdin.Close()
+	select {
+	case <-p.done:
+		return nil
+	case <-time.After(processCloseTimeout):
+	}
+	err := p.cmd.Process.Kill()
+	<-p.done
+	return err
+}
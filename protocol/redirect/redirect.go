@@ -11,8 +11,11 @@ import (
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/service"
 )
 
 func RegisterRedirect(registry *inbound.Registry) {
@@ -21,24 +24,57 @@ func RegisterRedirect(registry *inbound.Registry) {
 
 type Redirect struct {
 	inbound.Adapter
-	router   adapter.Router
-	logger   log.ContextLogger
-	listener *listener.Listener
+	router       adapter.Router
+	logger       log.ContextLogger
+	listener     *listener.Listener
+	listenPort   uint16
+	autoRedirect *redir.AutoRedirect
 }
 
 func NewRedirect(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.RedirectInboundOptions) (adapter.Inbound, error) {
 	redirect := &Redirect{
-		Adapter: inbound.NewAdapter(C.TypeRedirect, tag),
-		router:  router,
-		logger:  logger,
+		Adapter:    inbound.NewAdapter(C.TypeRedirect, tag),
+		router:     router,
+		logger:     logger,
+		listenPort: options.ListenPort,
 	}
 	redirect.listener = listener.New(listener.Options{
 		Context:           ctx,
 		Logger:            logger,
+		Tag:               tag,
 		Network:           []string{N.NetworkTCP},
 		Listen:            options.ListenOptions,
 		ConnectionHandler: redirect,
 	})
+	if options.AutoRedirect {
+		if common.Any(service.FromContext[adapter.InboundManager](ctx).Inbounds(), func(it adapter.Inbound) bool {
+			redirectInbound, isRedirect := it.(*Redirect)
+			return isRedirect && redirectInbound.Tag() != tag && redirectInbound.autoRedirect != nil
+		}) {
+			return nil, E.New("only one redirect inbound with `auto_redirect` can be configured")
+		}
+		if options.ListenPort == 0 {
+			return nil, E.New("`listen_port` is required by `auto_redirect`")
+		}
+		outputMark := uint32(options.AutoRedirectOutputMark)
+		if outputMark == 0 {
+			outputMark = redir.DefaultAutoRedirectOutputMark
+		}
+		var err error
+		redirect.autoRedirect, err = redir.NewAutoRedirect(redir.AutoRedirectOptions{
+			Name:           "redirect",
+			Port:           options.ListenPort,
+			OutputMark:     outputMark,
+			ExcludeAddress: options.RouteExcludeAddress,
+		})
+		if err != nil {
+			return nil, E.Cause(err, "initialize auto-redirect")
+		}
+		err = service.FromContext[adapter.NetworkManager](ctx).RegisterAutoRedirectOutputMark(outputMark)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return redirect, nil
 }
 
@@ -46,11 +82,25 @@ func (h *Redirect) Start(stage adapter.StartStage) error {
 	if stage != adapter.StartStateStart {
 		return nil
 	}
-	return h.listener.Start()
+	err := h.listener.Start()
+	if err != nil {
+		return err
+	}
+	if h.autoRedirect != nil {
+		err = h.autoRedirect.Start()
+		if err != nil {
+			return E.Cause(err, "start auto-redirect")
+		}
+	}
+	return nil
 }
 
 func (h *Redirect) Close() error {
-	return h.listener.Close()
+	var err error
+	if h.autoRedirect != nil {
+		err = h.autoRedirect.Close()
+	}
+	return E.Errors(err, h.listener.Close())
 }
 
 func (h *Redirect) NewConnectionEx(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
@@ -60,9 +110,16 @@ func (h *Redirect) NewConnectionEx(ctx context.Context, conn net.Conn, metadata
 		h.logger.ErrorContext(ctx, "process connection from ", conn.RemoteAddr(), ": get redirect destination: ", err)
 		return
 	}
+	metadata.Destination = M.SocksaddrFromNetIP(destination).Unwrap()
+	// the local address is used as the destination on FreeBSD, which is the
+	// listener itself for connections not redirected
+	if metadata.Destination.Port == h.listenPort && metadata.Destination == M.SocksaddrFromNet(conn.LocalAddr()).Unwrap() {
+		conn.Close()
+		h.logger.ErrorContext(ctx, "process connection from ", conn.RemoteAddr(), ": connection is not redirected")
+		return
+	}
 	metadata.Inbound = h.Tag()
 	metadata.InboundType = h.Type()
-	metadata.Destination = M.SocksaddrFromNetIP(destination)
 	h.logger.InfoContext(ctx, "inbound connection to ", metadata.Destination)
 	h.router.RouteConnectionEx(ctx, conn, metadata, onClose)
 }
@@ -16,9 +16,11 @@ import (
 	"github.com/sagernet/sing/common"
 	"github.com/sagernet/sing/common/buf"
 	"github.com/sagernet/sing/common/control"
+	E "github.com/sagernet/sing/common/exceptions"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
 	"github.com/sagernet/sing/common/udpnat2"
+	"github.com/sagernet/sing/service"
 )
 
 func RegisterTProxy(registry *inbound.Registry) {
@@ -27,11 +29,12 @@ func RegisterTProxy(registry *inbound.Registry) {
 
 type TProxy struct {
 	inbound.Adapter
-	ctx      context.Context
-	router   adapter.Router
-	logger   log.ContextLogger
-	listener *listener.Listener
-	udpNat   *udpnat.Service
+	ctx          context.Context
+	router       adapter.Router
+	logger       log.ContextLogger
+	listener     *listener.Listener
+	udpNat       *udpnat.Service
+	autoRedirect *redir.AutoRedirect
 }
 
 func NewTProxy(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.TProxyInboundOptions) (adapter.Inbound, error) {
@@ -47,16 +50,51 @@ func NewTProxy(ctx context.Context, router adapter.Router, logger log.ContextLog
 	} else {
 		udpTimeout = C.UDPTimeout
 	}
-	tproxy.udpNat = udpnat.New(tproxy, tproxy.preparePacketConnection, udpTimeout, false)
+	tproxy.udpNat = udpnat.New(tproxy, tproxy.preparePacketConnection, udpTimeout, true)
 	tproxy.listener = listener.New(listener.Options{
 		Context:           ctx,
 		Logger:            logger,
+		Tag:               tag,
 		Network:           options.Network.Build(),
 		Listen:            options.ListenOptions,
 		ConnectionHandler: tproxy,
 		OOBPacketHandler:  tproxy,
 		TProxy:            true,
 	})
+	if options.AutoRedirect {
+		if common.Any(service.FromContext[adapter.InboundManager](ctx).Inbounds(), func(it adapter.Inbound) bool {
+			tproxyInbound, isTProxy := it.(*TProxy)
+			return isTProxy && tproxyInbound.Tag() != tag && tproxyInbound.autoRedirect != nil
+		}) {
+			return nil, E.New("only one tproxy inbound with `auto_redirect` can be configured")
+		}
+		if options.ListenPort == 0 {
+			return nil, E.New("`listen_port` is required by `auto_redirect`")
+		}
+		outputMark := uint32(options.AutoRedirectOutputMark)
+		if outputMark == 0 {
+			outputMark = redir.DefaultAutoRedirectOutputMark
+		}
+		var err error
+		tproxy.autoRedirect, err = redir.NewAutoRedirect(redir.AutoRedirectOptions{
+			Name:               "tproxy",
+			TProxy:             true,
+			Port:               options.ListenPort,
+			Network:            options.Network.Build(),
+			InputMark:          uint32(options.AutoRedirectInputMark),
+			OutputMark:         outputMark,
+			IPRoute2TableIndex: options.IPRoute2TableIndex,
+			IPRoute2RuleIndex:  options.IPRoute2RuleIndex,
+			ExcludeAddress:     options.RouteExcludeAddress,
+		})
+		if err != nil {
+			return nil, E.Cause(err, "initialize auto-redirect")
+		}
+		err = service.FromContext[adapter.NetworkManager](ctx).RegisterAutoRedirectOutputMark(outputMark)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return tproxy, nil
 }
 
@@ -64,11 +102,25 @@ func (t *TProxy) Start(stage adapter.StartStage) error {
 	if stage != adapter.StartStateStart {
 		return nil
 	}
-	return t.listener.Start()
+	err := t.listener.Start()
+	if err != nil {
+		return err
+	}
+	if t.autoRedirect != nil {
+		err = t.autoRedirect.Start()
+		if err != nil {
+			return E.Cause(err, "start auto-redirect")
+		}
+	}
+	return nil
 }
 
 func (t *TProxy) Close() error {
-	return t.listener.Close()
+	var err error
+	if t.autoRedirect != nil {
+		err = t.autoRedirect.Close()
+	}
+	return E.Errors(err, t.listener.Close())
 }
 
 func (t *TProxy) NewConnectionEx(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
@@ -97,7 +149,7 @@ func (t *TProxy) NewPacketEx(buffer *buf.Buffer, oob []byte, source M.Socksaddr)
 		t.logger.Warn("process packet from ", source, ": get tproxy destination: ", err)
 		return
 	}
-	t.udpNat.NewPacket([][]byte{buffer.Bytes()}, source, M.SocksaddrFromNetIP(destination), nil)
+	t.udpNat.NewPacket([][]byte{buffer.Bytes()}, source, M.SocksaddrFromNetIP(destination).Unwrap(), nil)
 }
 
 func (t *TProxy) preparePacketConnection(source M.Socksaddr, destination M.Socksaddr, userData any) (bool, context.Context, N.PacketWriter, N.CloseHandlerFunc) {
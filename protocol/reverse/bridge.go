@@ -0,0 +1,234 @@
+package reverse
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/adapter/inbound"
+	"github.com/sagernet/sing-box/common/dialer"
+	"github.com/sagernet/sing-box/common/tls"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/transport/reverse"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/uot"
+	"github.com/sagernet/smux"
+)
+
+const (
+	bridgeMinRetryInterval = time.Second
+	bridgeMaxRetryInterval = time.Minute
+)
+
+func RegisterBridge(registry *inbound.Registry) {
+	inbound.Register[option.BridgeInboundOptions](registry, C.TypeBridge, NewBridge)
+}
+
+// Bridge keeps control connections to a portal, and routes connections
+// opened by the portal as inbound connections.
+type Bridge struct {
+	inbound.Adapter
+	ctx         context.Context
+	cancel      context.CancelFunc
+	router      adapter.Router
+	logger      log.ContextLogger
+	dialer      N.Dialer
+	serverAddr  M.Socksaddr
+	key         reverse.Key
+	connections int
+	services    []reverse.Service
+	access      sync.Mutex
+	sessions    map[*smux.Session]struct{}
+	done        sync.WaitGroup
+}
+
+func NewBridge(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.BridgeInboundOptions) (adapter.Inbound, error) {
+	if options.Password == "" {
+		return nil, E.New("missing password")
+	}
+	if options.Connections < 0 {
+		return nil, E.New("invalid connections: ", options.Connections)
+	}
+	if len(options.Services) == 0 {
+		return nil, E.New("missing services")
+	}
+	var services []reverse.Service
+	for i, service := range options.Services {
+		destination := service.ServerOptions.Build()
+		if !destination.IsValid() || destination.Port == 0 {
+			return nil, E.New("missing server or server_port for services[", i, "]")
+		}
+		services = append(services, reverse.Service{
+			Network:     service.Network.Build(),
+			Destination: destination,
+		})
+	}
+	outboundDialer, err := dialer.New(ctx, options.DialerOptions, options.ServerIsDomain())
+	if err != nil {
+		return nil, err
+	}
+	if options.TLS != nil && options.TLS.Enabled {
+		tlsConfig, err := tls.NewClient(ctx, logger, options.Server, common.PtrValueOrDefault(options.TLS))
+		if err != nil {
+			return nil, err
+		}
+		outboundDialer = tls.NewDialer(outboundDialer, tlsConfig)
+	}
+	connections := options.Connections
+	if connections == 0 {
+		connections = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	return &Bridge{
+		Adapter:     inbound.NewAdapter(C.TypeBridge, tag),
+		ctx:         ctx,
+		cancel:      cancel,
+		router:      router,
+		logger:      logger,
+		dialer:      outboundDialer,
+		serverAddr:  options.ServerOptions.Build(),
+		key:         reverse.NewKey(options.Password),
+		connections: connections,
+		services:    services,
+		sessions:    make(map[*smux.Session]struct{}),
+	}, nil
+}
+
+func (b *Bridge) Start(stage adapter.StartStage) error {
+	if stage != adapter.StartStatePostStart {
+		return nil
+	}
+	for i := 0; i < b.connections; i++ {
+		b.done.Add(1)
+		go b.loopConnection()
+	}
+	return nil
+}
+
+func (b *Bridge) Close() error {
+	b.cancel()
+	b.access.Lock()
+	for session := range b.sessions {
+		session.Close()
+	}
+	b.access.Unlock()
+	b.done.Wait()
+	return nil
+}
+
+func (b *Bridge) loopConnection() {
+	defer b.done.Done()
+	retryInterval := bridgeMinRetryInterval
+	for {
+		connected, err := b.connect()
+		if b.ctx.Err() != nil {
+			return
+		}
+		if connected {
+			retryInterval = bridgeMinRetryInterval
+			b.logger.Error(E.Cause(err, "connection to portal closed"))
+		} else {
+			b.logger.Error(E.Cause(err, "connect to portal"), ", retrying in ", retryInterval)
+		}
+		select {
+		case <-b.ctx.Done():
+			return
+		case <-time.After(retryInterval):
+		}
+		if !connected {
+			retryInterval = min(retryInterval*2, bridgeMaxRetryInterval)
+		}
+	}
+}
+
+func (b *Bridge) connect() (connected bool, err error) {
+	conn, err := b.dialer.DialContext(b.ctx, N.NetworkTCP, b.serverAddr)
+	if err != nil {
+		return
+	}
+	_ = conn.SetDeadline(time.Now().Add(C.TCPTimeout))
+	err = reverse.ClientHandshake(conn, b.key)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	_ = conn.SetDeadline(time.Time{})
+	session, err := smux.Server(conn, reverse.SmuxConfig())
+	if err != nil {
+		conn.Close()
+		return
+	}
+	b.access.Lock()
+	if b.ctx.Err() != nil {
+		b.access.Unlock()
+		session.Close()
+		return false, b.ctx.Err()
+	}
+	b.sessions[session] = struct{}{}
+	b.access.Unlock()
+	defer func() {
+		b.access.Lock()
+		delete(b.sessions, session)
+		b.access.Unlock()
+		session.Close()
+	}()
+	connected = true
+	source := M.SocksaddrFromNet(conn.RemoteAddr()).Unwrap()
+	b.logger.Info("connected to portal ", b.serverAddr)
+	for {
+		var stream *smux.Stream
+		stream, err = session.AcceptStream()
+		if err != nil {
+			return
+		}
+		go b.newStream(stream, source)
+	}
+}
+
+func (b *Bridge) newStream(stream *smux.Stream, source M.Socksaddr) {
+	ctx := log.ContextWithNewID(b.ctx)
+	_ = stream.SetReadDeadline(time.Now().Add(C.TCPTimeout))
+	command, destination, err := reverse.ReadStreamRequest(stream)
+	if err != nil {
+		stream.Close()
+		b.logger.ErrorContext(ctx, E.Cause(err, "read stream request"))
+		return
+	}
+	_ = stream.SetReadDeadline(time.Time{})
+	var network string
+	switch command {
+	case reverse.CommandTCP:
+		network = N.NetworkTCP
+	case reverse.CommandUDP:
+		network = N.NetworkUDP
+	default:
+		stream.Close()
+		b.logger.ErrorContext(ctx, E.New("unknown command: ", command))
+		return
+	}
+	if !common.Any(b.services, func(it reverse.Service) bool {
+		return it.Match(network, destination)
+	}) {
+		stream.Close()
+		b.logger.ErrorContext(ctx, E.New("rejected ", network, " connection to ", destination, ": not in services"))
+		return
+	}
+	var metadata adapter.InboundContext
+	metadata.Inbound = b.Tag()
+	metadata.InboundType = b.Type()
+	metadata.Source = source
+	metadata.Destination = destination
+	if command == reverse.CommandTCP {
+		b.logger.InfoContext(ctx, "inbound connection to ", destination)
+		b.router.RouteConnectionEx(ctx, stream, metadata, nil)
+	} else {
+		b.logger.InfoContext(ctx, "inbound packet connection to ", destination)
+		b.router.RoutePacketConnectionEx(ctx, uot.NewConn(stream, uot.Request{Destination: destination}), metadata, nil)
+	}
+}
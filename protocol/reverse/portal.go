@@ -0,0 +1,196 @@
+package reverse
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/adapter/endpoint"
+	"github.com/sagernet/sing-box/common/listener"
+	"github.com/sagernet/sing-box/common/tls"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/transport/reverse"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/uot"
+	"github.com/sagernet/smux"
+)
+
+func RegisterPortal(registry *endpoint.Registry) {
+	endpoint.Register[option.PortalEndpointOptions](registry, C.TypePortal, NewPortal)
+}
+
+// Portal accepts control connections from bridges, and sends outbound
+// connections through them.
+type Portal struct {
+	endpoint.Adapter
+	logger    log.ContextLogger
+	listener  *listener.Listener
+	tlsConfig tls.ServerConfig
+	key       reverse.Key
+	access    sync.Mutex
+	sessions  []*smux.Session
+}
+
+func NewPortal(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.PortalEndpointOptions) (adapter.Endpoint, error) {
+	if options.Password == "" {
+		return nil, E.New("missing password")
+	}
+	portal := &Portal{
+		Adapter: endpoint.NewAdapter(C.TypePortal, tag, []string{N.NetworkTCP, N.NetworkUDP}, nil),
+		logger:  logger,
+		key:     reverse.NewKey(options.Password),
+	}
+	if options.TLS != nil && options.TLS.Enabled {
+		tlsConfig, err := tls.NewServer(ctx, logger, common.PtrValueOrDefault(options.TLS))
+		if err != nil {
+			return nil, err
+		}
+		portal.tlsConfig = tlsConfig
+	}
+	portal.listener = listener.New(listener.Options{
+		Context:           ctx,
+		Logger:            logger,
+		Tag:               tag,
+		Network:           []string{N.NetworkTCP},
+		Listen:            options.ListenOptions,
+		ConnectionHandler: (*portalListener)(portal),
+	})
+	return portal, nil
+}
+
+func (p *Portal) Start(stage adapter.StartStage) error {
+	if stage != adapter.StartStateStart {
+		return nil
+	}
+	if p.tlsConfig != nil {
+		err := p.tlsConfig.Start()
+		if err != nil {
+			return E.Cause(err, "create TLS config")
+		}
+	}
+	return p.listener.Start()
+}
+
+func (p *Portal) Close() error {
+	p.access.Lock()
+	sessions := p.sessions
+	p.sessions = nil
+	p.access.Unlock()
+	for _, session := range sessions {
+		session.Close()
+	}
+	return common.Close(
+		p.listener,
+		p.tlsConfig,
+	)
+}
+
+// portalListener handles bridge connections, it is not implemented by Portal
+// since the router passes connections to outbounds implementing the handler.
+type portalListener Portal
+
+func (l *portalListener) NewConnectionEx(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
+	p := (*Portal)(l)
+	err := p.newConnection(ctx, conn, metadata.Source)
+	if err != nil {
+		N.CloseOnHandshakeFailure(conn, onClose, err)
+		p.logger.ErrorContext(ctx, E.Cause(err, "process bridge connection from ", metadata.Source))
+		return
+	}
+	if onClose != nil {
+		onClose(nil)
+	}
+}
+
+func (p *Portal) newConnection(ctx context.Context, conn net.Conn, source M.Socksaddr) error {
+	if p.tlsConfig != nil {
+		tlsConn, err := tls.ServerHandshake(ctx, conn, p.tlsConfig)
+		if err != nil {
+			return E.Cause(err, "TLS handshake")
+		}
+		conn = tlsConn
+	}
+	_ = conn.SetDeadline(time.Now().Add(C.TCPTimeout))
+	err := reverse.ServerHandshake(conn, p.key)
+	if err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(time.Time{})
+	session, err := smux.Client(conn, reverse.SmuxConfig())
+	if err != nil {
+		return err
+	}
+	p.access.Lock()
+	p.sessions = append(p.sessions, session)
+	p.access.Unlock()
+	p.logger.InfoContext(ctx, "bridge connected from ", source)
+	<-session.CloseChan()
+	p.access.Lock()
+	p.sessions = common.Filter(p.sessions, func(it *smux.Session) bool {
+		return it != session
+	})
+	p.access.Unlock()
+	p.logger.InfoContext(ctx, "bridge disconnected from ", source)
+	return nil
+}
+
+// openStream opens a stream on the bridge connection with the fewest streams.
+func (p *Portal) openStream(command byte, destination M.Socksaddr) (net.Conn, error) {
+	p.access.Lock()
+	var selected *smux.Session
+	for _, session := range p.sessions {
+		if session.IsClosed() {
+			continue
+		}
+		if selected == nil || session.NumStreams() < selected.NumStreams() {
+			selected = session
+		}
+	}
+	p.access.Unlock()
+	if selected == nil {
+		return nil, E.New("no bridge connected")
+	}
+	stream, err := selected.OpenStream()
+	if err != nil {
+		return nil, err
+	}
+	err = reverse.WriteStreamRequest(stream, command, destination)
+	if err != nil {
+		stream.Close()
+		return nil, err
+	}
+	return stream, nil
+}
+
+func (p *Portal) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	switch N.NetworkName(network) {
+	case N.NetworkTCP:
+		p.logger.InfoContext(ctx, "outbound connection to ", destination)
+		return p.openStream(reverse.CommandTCP, destination)
+	case N.NetworkUDP:
+		p.logger.InfoContext(ctx, "outbound packet connection to ", destination)
+		stream, err := p.openStream(reverse.CommandUDP, destination)
+		if err != nil {
+			return nil, err
+		}
+		return uot.NewConn(stream, uot.Request{Destination: destination}), nil
+	default:
+		return nil, E.Extend(N.ErrUnknownNetwork, network)
+	}
+}
+
+func (p *Portal) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	p.logger.InfoContext(ctx, "outbound packet connection to ", destination)
+	stream, err := p.openStream(reverse.CommandUDP, destination)
+	if err != nil {
+		return nil, err
+	}
+	return uot.NewConn(stream, uot.Request{Destination: destination}), nil
+}
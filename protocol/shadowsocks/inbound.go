@@ -53,6 +53,7 @@ type Inbound struct {
 	logger   logger.ContextLogger
 	listener *listener.Listener
 	service  shadowsocks.Service
+	replay   *replayProtection
 }
 
 func newInbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.ShadowsocksInboundOptions) (*Inbound, error) {
@@ -83,9 +84,17 @@ func newInbound(ctx context.Context, router adapter.Router, logger log.ContextLo
 	default:
 		err = E.New("unsupported method: ", options.Method)
 	}
+	if err != nil {
+		return nil, err
+	}
+	inbound.replay, err = newReplayProtection(ctx, logger, options)
+	if err != nil {
+		return nil, err
+	}
 	inbound.listener = listener.New(listener.Options{
 		Context:                  ctx,
 		Logger:                   logger,
+		Tag:                      tag,
 		Network:                  options.Network.Build(),
 		Listen:                   options.ListenOptions,
 		ConnectionHandler:        inbound,
@@ -99,15 +108,24 @@ func (h *Inbound) Start(stage adapter.StartStage) error {
 	if stage != adapter.StartStateStart {
 		return nil
 	}
+	if h.replay != nil {
+		err := h.replay.Start()
+		if err != nil {
+			return err
+		}
+	}
 	return h.listener.Start()
 }
 
 func (h *Inbound) Close() error {
-	return h.listener.Close()
+	return common.Close(h.listener, common.PtrOrNil(h.replay))
 }
 
 //nolint:staticcheck
 func (h *Inbound) NewConnectionEx(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
+	if h.replay != nil {
+		ctx, conn = h.replay.newConn(ctx, conn)
+	}
 	err := h.service.NewConnection(ctx, conn, adapter.UpstreamMetadata(metadata))
 	N.CloseOnHandshakeFailure(conn, onClose, err)
 	if err != nil {
@@ -128,6 +146,12 @@ func (h *Inbound) NewPacketEx(buffer *buf.Buffer, source M.Socksaddr) {
 }
 
 func (h *Inbound) newConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	if h.replay != nil {
+		err := h.replay.check(ctx)
+		if err != nil {
+			return err
+		}
+	}
 	h.logger.InfoContext(ctx, "inbound connection to ", metadata.Destination)
 	metadata.Inbound = h.Tag()
 	metadata.InboundType = h.Type()
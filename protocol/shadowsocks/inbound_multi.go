@@ -31,6 +31,7 @@ import (
 var (
 	_ adapter.TCPInjectableInbound = (*MultiInbound)(nil)
 	_ adapter.ManagedSSMServer     = (*MultiInbound)(nil)
+	_ adapter.ManagedUserInbound   = (*MultiInbound)(nil)
 )
 
 type MultiInbound struct {
@@ -40,7 +41,9 @@ type MultiInbound struct {
 	logger   logger.ContextLogger
 	listener *listener.Listener
 	service  shadowsocks.MultiService[int]
-	users    []option.ShadowsocksUser
+	replay   *replayProtection
+	users    *inbound.UserList[option.ShadowsocksUser]
+	options  option.ShadowsocksInboundOptions
 	tracker  adapter.SSMTracker
 }
 
@@ -50,6 +53,8 @@ func newMultiInbound(ctx context.Context, router adapter.Router, logger log.Cont
 		ctx:     ctx,
 		router:  uot.NewRouter(router, logger),
 		logger:  logger,
+		users:   inbound.NewUserList(option.ShadowsocksUser.InboundUser),
+		options: options,
 	}
 	var err error
 	inbound.router, err = mux.NewRouterWithOptions(inbound.router, logger, common.PtrValueOrDefault(options.Multiplex))
@@ -83,21 +88,23 @@ func newMultiInbound(ctx context.Context, router adapter.Router, logger log.Cont
 	if err != nil {
 		return nil, err
 	}
-	if len(options.Users) > 0 {
-		err = service.UpdateUsersWithPasswords(common.MapIndexed(options.Users, func(index int, user option.ShadowsocksUser) int {
-			return index
-		}), common.Map(options.Users, func(user option.ShadowsocksUser) string {
+	err = inbound.users.Initialize(options.Users, func(ids []int, users []option.ShadowsocksUser) error {
+		return service.UpdateUsersWithPasswords(ids, common.Map(users, func(user option.ShadowsocksUser) string {
 			return user.Password
 		}))
-		if err != nil {
-			return nil, err
-		}
+	})
+	if err != nil {
+		return nil, err
 	}
 	inbound.service = service
-	inbound.users = options.Users
+	inbound.replay, err = newReplayProtection(ctx, logger, options)
+	if err != nil {
+		return nil, err
+	}
 	inbound.listener = listener.New(listener.Options{
 		Context:                  ctx,
 		Logger:                   logger,
+		Tag:                      tag,
 		Network:                  options.Network.Build(),
 		Listen:                   options.ListenOptions,
 		ConnectionHandler:        inbound,
@@ -111,11 +118,17 @@ func (h *MultiInbound) Start(stage adapter.StartStage) error {
 	if stage != adapter.StartStateStart {
 		return nil
 	}
+	if h.replay != nil {
+		err := h.replay.Start()
+		if err != nil {
+			return err
+		}
+	}
 	return h.listener.Start()
 }
 
 func (h *MultiInbound) Close() error {
-	return h.listener.Close()
+	return common.Close(h.listener, common.PtrOrNil(h.replay))
 }
 
 func (h *MultiInbound) SetTracker(tracker adapter.SSMTracker) {
@@ -123,22 +136,36 @@ func (h *MultiInbound) SetTracker(tracker adapter.SSMTracker) {
 }
 
 func (h *MultiInbound) UpdateUsers(users []string, uPSKs []string) error {
-	err := h.service.UpdateUsersWithPasswords(common.MapIndexed(users, func(index int, user string) int {
-		return index
-	}), uPSKs)
-	if err != nil {
-		return err
+	if len(users) != len(uPSKs) {
+		return E.New("mismatched users and passwords")
 	}
-	h.users = common.Map(users, func(user string) option.ShadowsocksUser {
+	return h.users.SetUsers(common.MapIndexed(users, func(index int, user string) option.ShadowsocksUser {
 		return option.ShadowsocksUser{
-			Name: user,
+			Name:     user,
+			Password: uPSKs[index],
 		}
-	})
-	return nil
+	}))
+}
+
+func (h *MultiInbound) InboundOptions() option.Inbound {
+	options := h.options
+	options.Users = h.users.Users()
+	return option.Inbound{Type: h.Type(), Tag: h.Tag(), Options: &options}
+}
+
+func (h *MultiInbound) SetUser(content []byte) (option.InboundUser, error) {
+	return h.users.SetUser(content)
+}
+
+func (h *MultiInbound) RemoveUser(name string) error {
+	return h.users.RemoveUser(name)
 }
 
 //nolint:staticcheck
 func (h *MultiInbound) NewConnectionEx(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
+	if h.replay != nil {
+		ctx, conn = h.replay.newConn(ctx, conn)
+	}
 	err := h.service.NewConnection(ctx, conn, adapter.UpstreamMetadata(metadata))
 	N.CloseOnHandshakeFailure(conn, onClose, err)
 	if err != nil {
@@ -159,13 +186,19 @@ func (h *MultiInbound) NewPacketEx(buffer *buf.Buffer, source M.Socksaddr) {
 }
 
 func (h *MultiInbound) newConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
-	userIndex, loaded := auth.UserFromContext[int](ctx)
+	if h.replay != nil {
+		err := h.replay.check(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	userID, loaded := auth.UserFromContext[int](ctx)
 	if !loaded {
 		return os.ErrInvalid
 	}
-	user := h.users[userIndex].Name
+	user := h.users.Name(userID)
 	if user == "" {
-		user = F.ToString(userIndex)
+		user = F.ToString(userID)
 	} else {
 		metadata.User = user
 	}
@@ -183,13 +216,13 @@ func (h *MultiInbound) newConnection(ctx context.Context, conn net.Conn, metadat
 }
 
 func (h *MultiInbound) newPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
-	userIndex, loaded := auth.UserFromContext[int](ctx)
+	userID, loaded := auth.UserFromContext[int](ctx)
 	if !loaded {
 		return os.ErrInvalid
 	}
-	user := h.users[userIndex].Name
+	user := h.users.Name(userID)
 	if user == "" {
-		user = F.ToString(userIndex)
+		user = F.ToString(userID)
 	} else {
 		metadata.User = user
 	}
@@ -34,6 +34,7 @@ type RelayInbound struct {
 	logger       logger.ContextLogger
 	listener     *listener.Listener
 	service      *shadowaead_2022.RelayService[int]
+	replay       *replayProtection
 	destinations []option.ShadowsocksDestination
 }
 
@@ -74,9 +75,14 @@ func newRelayInbound(ctx context.Context, router adapter.Router, logger log.Cont
 		return nil, err
 	}
 	inbound.service = service
+	inbound.replay, err = newReplayProtection(ctx, logger, options)
+	if err != nil {
+		return nil, err
+	}
 	inbound.listener = listener.New(listener.Options{
 		Context:                  ctx,
 		Logger:                   logger,
+		Tag:                      tag,
 		Network:                  options.Network.Build(),
 		Listen:                   options.ListenOptions,
 		ConnectionHandler:        inbound,
@@ -90,15 +96,24 @@ func (h *RelayInbound) Start(stage adapter.StartStage) error {
 	if stage != adapter.StartStateStart {
 		return nil
 	}
+	if h.replay != nil {
+		err := h.replay.Start()
+		if err != nil {
+			return err
+		}
+	}
 	return h.listener.Start()
 }
 
 func (h *RelayInbound) Close() error {
-	return h.listener.Close()
+	return common.Close(h.listener, common.PtrOrNil(h.replay))
 }
 
 //nolint:staticcheck
 func (h *RelayInbound) NewConnectionEx(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
+	if h.replay != nil {
+		ctx, conn = h.replay.newConn(ctx, conn)
+	}
 	err := h.service.NewConnection(ctx, conn, adapter.UpstreamMetadata(metadata))
 	N.CloseOnHandshakeFailure(conn, onClose, err)
 	if err != nil {
@@ -119,6 +134,12 @@ func (h *RelayInbound) NewPacketEx(buffer *buf.Buffer, source M.Socksaddr) {
 }
 
 func (h *RelayInbound) newConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	if h.replay != nil {
+		err := h.replay.check(ctx)
+		if err != nil {
+			return err
+		}
+	}
 	destinationIndex, loaded := auth.UserFromContext[int](ctx)
 	if !loaded {
 		return os.ErrInvalid
@@ -6,20 +6,18 @@ import (
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/adapter/outbound"
+	"github.com/sagernet/sing-box/client/shadowsocks"
 	"github.com/sagernet/sing-box/common/dialer"
 	"github.com/sagernet/sing-box/common/mux"
+	"github.com/sagernet/sing-box/common/uot"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing-box/transport/sip003"
-	"github.com/sagernet/sing-shadowsocks2"
 	"github.com/sagernet/sing/common"
-	"github.com/sagernet/sing/common/bufio"
-	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/logger"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
-	"github.com/sagernet/sing/common/uot"
 )
 
 func RegisterOutbound(registry *outbound.Registry) {
@@ -29,51 +27,48 @@ func RegisterOutbound(registry *outbound.Registry) {
 type Outbound struct {
 	outbound.Adapter
 	logger          logger.ContextLogger
-	dialer          N.Dialer
-	method          shadowsocks.Method
-	serverAddr      M.Socksaddr
+	client          *shadowsocks.Client
 	plugin          sip003.Plugin
 	uotClient       *uot.Client
-	multiplexDialer *mux.Client
+	multiplexDialer *mux.OutboundClient
 }
 
 func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.ShadowsocksOutboundOptions) (adapter.Outbound, error) {
-	method, err := shadowsocks.CreateMethod(ctx, options.Method, shadowsocks.MethodOptions{
-		Password: options.Password,
-	})
+	outboundDialer, err := dialer.New(ctx, options.DialerOptions, options.ServerIsDomain())
 	if err != nil {
 		return nil, err
 	}
-	outboundDialer, err := dialer.New(ctx, options.DialerOptions, options.ServerIsDomain())
+	serverAddr := options.ServerOptions.Build()
+	client, err := shadowsocks.NewClient(ctx, shadowsocks.Options{
+		Dialer:   outboundDialer,
+		Server:   serverAddr,
+		Method:   options.Method,
+		Password: options.Password,
+	})
 	if err != nil {
 		return nil, err
 	}
 	outbound := &Outbound{
-		Adapter:    outbound.NewAdapterWithDialerOptions(C.TypeShadowsocks, tag, options.Network.Build(), options.DialerOptions),
-		logger:     logger,
-		dialer:     outboundDialer,
-		method:     method,
-		serverAddr: options.ServerOptions.Build(),
+		Adapter: outbound.NewAdapterWithDialerOptions(C.TypeShadowsocks, tag, options.Network.Build(), options.DialerOptions),
+		logger:  logger,
+		client:  client,
 	}
 	if options.Plugin != "" {
-		outbound.plugin, err = sip003.CreatePlugin(ctx, options.Plugin, options.PluginOptions, router, outbound.dialer, outbound.serverAddr)
+		outbound.plugin, err = sip003.CreatePlugin(ctx, options.Plugin, options.PluginOptions, router, outboundDialer, serverAddr)
 		if err != nil {
 			return nil, err
 		}
 	}
 	uotOptions := common.PtrValueOrDefault(options.UDPOverTCP)
+	var multiplexOptions option.OutboundMultiplexOptions
 	if !uotOptions.Enabled {
-		outbound.multiplexDialer, err = mux.NewClientWithOptions((*shadowsocksDialer)(outbound), logger, common.PtrValueOrDefault(options.Multiplex))
-		if err != nil {
-			return nil, err
-		}
+		multiplexOptions = common.PtrValueOrDefault(options.Multiplex)
 	}
-	if uotOptions.Enabled {
-		outbound.uotClient = &uot.Client{
-			Dialer:  (*shadowsocksDialer)(outbound),
-			Version: uotOptions.Version,
-		}
+	outbound.multiplexDialer, err = mux.NewOutboundClient((*shadowsocksDialer)(outbound), logger, multiplexOptions)
+	if err != nil {
+		return nil, err
 	}
+	outbound.uotClient = uot.NewClient((*shadowsocksDialer)(outbound), uotOptions)
 	return outbound, nil
 }
 
@@ -81,14 +76,18 @@ func (h *Outbound) DialContext(ctx context.Context, network string, destination
 	ctx, metadata := adapter.ExtendContext(ctx)
 	metadata.Outbound = h.Tag()
 	metadata.Destination = destination
-	if h.multiplexDialer == nil {
+	multiplexDialer, err := h.multiplexDialer.Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if multiplexDialer == nil {
 		switch N.NetworkName(network) {
 		case N.NetworkTCP:
 			h.logger.InfoContext(ctx, "outbound connection to ", destination)
 		case N.NetworkUDP:
-			if h.uotClient != nil {
+			if version := h.uotClient.Version(ctx); version != 0 {
 				h.logger.InfoContext(ctx, "outbound UoT connect packet connection to ", destination)
-				return h.uotClient.DialContext(ctx, network, destination)
+				return h.uotClient.DialContext(ctx, version, destination)
 			} else {
 				h.logger.InfoContext(ctx, "outbound packet connection to ", destination)
 			}
@@ -101,7 +100,7 @@ func (h *Outbound) DialContext(ctx context.Context, network string, destination
 		case N.NetworkUDP:
 			h.logger.InfoContext(ctx, "outbound multiplex packet connection to ", destination)
 		}
-		return h.multiplexDialer.DialContext(ctx, network, destination)
+		return multiplexDialer.DialContext(ctx, network, destination)
 	}
 }
 
@@ -109,10 +108,14 @@ func (h *Outbound) ListenPacket(ctx context.Context, destination M.Socksaddr) (n
 	ctx, metadata := adapter.ExtendContext(ctx)
 	metadata.Outbound = h.Tag()
 	metadata.Destination = destination
-	if h.multiplexDialer == nil {
-		if h.uotClient != nil {
+	multiplexDialer, err := h.multiplexDialer.Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if multiplexDialer == nil {
+		if version := h.uotClient.Version(ctx); version != 0 {
 			h.logger.InfoContext(ctx, "outbound UoT packet connection to ", destination)
-			return h.uotClient.ListenPacket(ctx, destination)
+			return h.uotClient.ListenPacket(ctx, version, destination)
 		} else {
 			h.logger.InfoContext(ctx, "outbound packet connection to ", destination)
 		}
@@ -120,18 +123,16 @@ func (h *Outbound) ListenPacket(ctx context.Context, destination M.Socksaddr) (n
 		return (*shadowsocksDialer)(h).ListenPacket(ctx, destination)
 	} else {
 		h.logger.InfoContext(ctx, "outbound multiplex packet connection to ", destination)
-		return h.multiplexDialer.ListenPacket(ctx, destination)
+		return multiplexDialer.ListenPacket(ctx, destination)
 	}
 }
 
 func (h *Outbound) InterfaceUpdated() {
-	if h.multiplexDialer != nil {
-		h.multiplexDialer.Reset()
-	}
+	h.multiplexDialer.Reset()
 }
 
 func (h *Outbound) Close() error {
-	return common.Close(common.PtrOrNil(h.multiplexDialer))
+	return common.Close(h.multiplexDialer)
 }
 
 var _ N.Dialer = (*shadowsocksDialer)(nil)
@@ -142,37 +143,19 @@ func (h *shadowsocksDialer) DialContext(ctx context.Context, network string, des
 	ctx, metadata := adapter.ExtendContext(ctx)
 	metadata.Outbound = h.Tag()
 	metadata.Destination = destination
-	switch N.NetworkName(network) {
-	case N.NetworkTCP:
-		var outConn net.Conn
-		var err error
-		if h.plugin != nil {
-			outConn, err = h.plugin.DialContext(ctx)
-		} else {
-			outConn, err = h.dialer.DialContext(ctx, N.NetworkTCP, h.serverAddr)
-		}
+	if N.NetworkName(network) == N.NetworkTCP && h.plugin != nil {
+		outConn, err := h.plugin.DialContext(ctx)
 		if err != nil {
 			return nil, err
 		}
-		return h.method.DialEarlyConn(outConn, destination), nil
-	case N.NetworkUDP:
-		outConn, err := h.dialer.DialContext(ctx, N.NetworkUDP, h.serverAddr)
-		if err != nil {
-			return nil, err
-		}
-		return bufio.NewBindPacketConn(h.method.DialPacketConn(outConn), destination), nil
-	default:
-		return nil, E.Extend(N.ErrUnknownNetwork, network)
+		return h.client.DialConn(outConn, destination), nil
 	}
+	return h.client.DialContext(ctx, network, destination)
 }
 
 func (h *shadowsocksDialer) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
 	ctx, metadata := adapter.ExtendContext(ctx)
 	metadata.Outbound = h.Tag()
 	metadata.Destination = destination
-	outConn, err := h.dialer.DialContext(ctx, N.NetworkUDP, h.serverAddr)
-	if err != nil {
-		return nil, err
-	}
-	return h.method.DialPacketConn(outConn), nil
+	return h.client.ListenPacket(ctx, destination)
 }
@@ -0,0 +1,90 @@
+package shadowsocks
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/sagernet/sing-box/common/replaycache"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-shadowsocks/shadowaead_2022"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// replayTTL is the window of the salt filter of the service.
+const replayTTL = 60 * time.Second
+
+var ErrReplay = E.New("replayed request")
+
+// replayProtection checks request salts of TCP connections with a replay
+// cache, which may be shared between servers.
+type replayProtection struct {
+	cache      replaycache.Cache
+	saltLength int
+}
+
+func newReplayProtection(ctx context.Context, logger log.ContextLogger, options option.ShadowsocksInboundOptions) (*replayProtection, error) {
+	if options.ReplayProtection == nil {
+		return nil, nil
+	}
+	if !common.Contains(shadowaead_2022.List, options.Method) {
+		return nil, E.New("replay_protection is only supported for shadowsocks 2022 methods")
+	}
+	cache, err := replaycache.New(ctx, logger, *options.ReplayProtection, replayTTL)
+	if err != nil {
+		return nil, E.Cause(err, "create replay protection")
+	}
+	saltLength := 32
+	if options.Method == "2022-blake3-aes-128-gcm" {
+		saltLength = 16
+	}
+	return &replayProtection{cache, saltLength}, nil
+}
+
+func (p *replayProtection) Start() error {
+	return p.cache.Start()
+}
+
+func (p *replayProtection) Close() error {
+	return p.cache.Close()
+}
+
+func (p *replayProtection) newConn(ctx context.Context, conn net.Conn) (context.Context, net.Conn) {
+	recordConn := &saltConn{Conn: conn, salt: make([]byte, p.saltLength)}
+	return context.WithValue(ctx, saltKey{}, recordConn), recordConn
+}
+
+// check is called after the request is authenticated by the service.
+func (p *replayProtection) check(ctx context.Context) error {
+	conn, loaded := ctx.Value(saltKey{}).(*saltConn)
+	if !loaded || conn.n < len(conn.salt) {
+		return nil
+	}
+	if !p.cache.Check(ctx, conn.salt) {
+		return ErrReplay
+	}
+	return nil
+}
+
+type saltKey struct{}
+
+// saltConn records the request salt at the start of the connection.
+type saltConn struct {
+	net.Conn
+	salt []byte
+	n    int
+}
+
+func (c *saltConn) Read(p []byte) (n int, err error) {
+	n, err = c.Conn.Read(p)
+	if c.n < len(c.salt) {
+		c.n += copy(c.salt[c.n:], p[:n])
+	}
+	return
+}
+
+func (c *saltConn) Upstream() any {
+	return c.Conn
+}
@@ -2,22 +2,28 @@ package shadowtls
 
 import (
 	"context"
+	"io"
 	"net"
+	"strings"
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/adapter/inbound"
 	"github.com/sagernet/sing-box/common/dialer"
 	"github.com/sagernet/sing-box/common/listener"
+	"github.com/sagernet/sing-box/common/sniff"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing-shadowtls"
 	"github.com/sagernet/sing/common"
 	"github.com/sagernet/sing/common/auth"
+	"github.com/sagernet/sing/common/buf"
+	"github.com/sagernet/sing/common/bufio"
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/logger"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/service"
 )
 
 func RegisterInbound(registry *inbound.Registry) {
@@ -26,63 +32,92 @@ func RegisterInbound(registry *inbound.Registry) {
 
 type Inbound struct {
 	inbound.Adapter
-	router   adapter.Router
-	logger   logger.ContextLogger
-	listener *listener.Listener
-	service  *shadowtls.Service
+	ctx                    context.Context
+	router                 adapter.Router
+	logger                 logger.ContextLogger
+	listener               *listener.Listener
+	service                *shadowtls.Service
+	handshakeForServerName []serverNameService
+	userDetour             map[string]string
+}
+
+// serverNameService is the service of a handshake server selected by the
+// server name of the client hello.
+type serverNameService struct {
+	serverName string
+	service    *shadowtls.Service
 }
 
 func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.ShadowTLSInboundOptions) (adapter.Inbound, error) {
 	inbound := &Inbound{
-		Adapter: inbound.NewAdapter(C.TypeShadowTLS, tag),
-		router:  router,
-		logger:  logger,
+		Adapter:    inbound.NewAdapter(C.TypeShadowTLS, tag),
+		ctx:        ctx,
+		router:     router,
+		logger:     logger,
+		userDetour: make(map[string]string),
 	}
 
 	if options.Version == 0 {
 		options.Version = 1
 	}
 
-	var handshakeForServerName map[string]shadowtls.HandshakeConfig
-	if options.Version > 1 {
-		handshakeForServerName = make(map[string]shadowtls.HandshakeConfig)
-		if options.HandshakeForServerName != nil {
-			for _, entry := range options.HandshakeForServerName.Entries() {
-				handshakeDialer, err := dialer.New(ctx, entry.Value.DialerOptions, entry.Value.ServerIsDomain())
-				if err != nil {
-					return nil, err
-				}
-				handshakeForServerName[entry.Key] = shadowtls.HandshakeConfig{
-					Server: entry.Value.ServerOptions.Build(),
-					Dialer: handshakeDialer,
-				}
+	users := common.Map(options.Users, func(it option.ShadowTLSUser) shadowtls.User {
+		return shadowtls.User{Name: it.Name, Password: it.Password}
+	})
+	for _, user := range options.Users {
+		if user.Detour == "" {
+			continue
+		}
+		if options.Version != 3 {
+			return nil, E.New("user detour is only supported in protocol version 3")
+		}
+		if user.Name == "" {
+			return nil, E.New("missing name for user with detour")
+		}
+		inbound.userDetour[user.Name] = user.Detour
+	}
+	newService := func(handshake option.ShadowTLSHandshakeOptions, serverIsDomain bool, wildcardSNI option.WildcardSNI) (*shadowtls.Service, error) {
+		handshakeDialer, err := dialer.New(ctx, handshake.DialerOptions, serverIsDomain)
+		if err != nil {
+			return nil, err
+		}
+		strictMode := options.StrictMode
+		if handshake.StrictMode != nil {
+			strictMode = *handshake.StrictMode
+		}
+		return shadowtls.NewService(shadowtls.ServiceConfig{
+			Version:  options.Version,
+			Password: options.Password,
+			Users:    users,
+			Handshake: shadowtls.HandshakeConfig{
+				Server: handshake.ServerOptions.Build(),
+				Dialer: handshakeDialer,
+			},
+			StrictMode:  strictMode,
+			WildcardSNI: shadowtls.WildcardSNI(wildcardSNI),
+			Handler:     (*inboundHandler)(inbound),
+			Logger:      logger,
+		})
+	}
+	if options.Version > 1 && options.HandshakeForServerName != nil {
+		// server names are matched here instead of by the service to
+		// support wildcards and per-server options
+		for _, entry := range options.HandshakeForServerName.Entries() {
+			service, err := newService(entry.Value, entry.Value.ServerIsDomain(), option.ShadowTLSWildcardSNIOff)
+			if err != nil {
+				return nil, E.Cause(err, "handshake for server name ", entry.Key)
 			}
+			inbound.handshakeForServerName = append(inbound.handshakeForServerName, serverNameService{
+				serverName: strings.ToLower(entry.Key),
+				service:    service,
+			})
 		}
 	}
 	serverIsDomain := options.Handshake.ServerIsDomain()
 	if options.WildcardSNI != option.ShadowTLSWildcardSNIOff {
 		serverIsDomain = true
 	}
-	handshakeDialer, err := dialer.New(ctx, options.Handshake.DialerOptions, serverIsDomain)
-	if err != nil {
-		return nil, err
-	}
-	service, err := shadowtls.NewService(shadowtls.ServiceConfig{
-		Version:  options.Version,
-		Password: options.Password,
-		Users: common.Map(options.Users, func(it option.ShadowTLSUser) shadowtls.User {
-			return (shadowtls.User)(it)
-		}),
-		Handshake: shadowtls.HandshakeConfig{
-			Server: options.Handshake.ServerOptions.Build(),
-			Dialer: handshakeDialer,
-		},
-		HandshakeForServerName: handshakeForServerName,
-		StrictMode:             options.StrictMode,
-		WildcardSNI:            shadowtls.WildcardSNI(options.WildcardSNI),
-		Handler:                (*inboundHandler)(inbound),
-		Logger:                 logger,
-	})
+	service, err := newService(options.Handshake, serverIsDomain, options.WildcardSNI)
 	if err != nil {
 		return nil, err
 	}
@@ -90,6 +125,7 @@ func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLo
 	inbound.listener = listener.New(listener.Options{
 		Context:           ctx,
 		Logger:            logger,
+		Tag:               tag,
 		Network:           []string{N.NetworkTCP},
 		Listen:            options.ListenOptions,
 		ConnectionHandler: inbound,
@@ -98,10 +134,22 @@ func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLo
 }
 
 func (h *Inbound) Start(stage adapter.StartStage) error {
-	if stage != adapter.StartStateStart {
-		return nil
+	switch stage {
+	case adapter.StartStateStart:
+		return h.listener.Start()
+	case adapter.StartStatePostStart:
+		inboundManager := service.FromContext[adapter.InboundManager](h.ctx)
+		for userName, detour := range h.userDetour {
+			detourInbound, loaded := inboundManager.Get(detour)
+			if !loaded {
+				return E.New("detour of user ", userName, " not found: ", detour)
+			}
+			if _, isInjectable := detourInbound.(adapter.TCPInjectableInbound); !isInjectable {
+				return E.New("detour of user ", userName, " is not TCP injectable: ", detour)
+			}
+		}
 	}
-	return h.listener.Start()
+	return nil
 }
 
 func (h *Inbound) Close() error {
@@ -109,7 +157,30 @@ func (h *Inbound) Close() error {
 }
 
 func (h *Inbound) NewConnectionEx(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
-	err := h.service.NewConnection(adapter.WithContext(log.ContextWithNewID(ctx), &metadata), conn, metadata.Source, metadata.Destination, onClose)
+	service := h.service
+	if len(h.handshakeForServerName) > 0 {
+		var serverName string
+		buffer := buf.NewPacket()
+		err := sniff.PeekStream(ctx, &metadata, conn, nil, buffer, C.TCPTimeout, func(ctx context.Context, metadata *adapter.InboundContext, reader io.Reader) error {
+			clientHello, err := sniff.ReadTLSClientHello(ctx, reader)
+			if err != nil {
+				return err
+			}
+			serverName = clientHello.ServerName
+			return nil
+		})
+		if !buffer.IsEmpty() {
+			conn = bufio.NewCachedConn(conn, buffer)
+		} else {
+			buffer.Release()
+		}
+		if err != nil {
+			h.logger.DebugContext(ctx, E.Cause(err, "read client hello from ", metadata.Source))
+		} else if serverNameService := h.serviceForServerName(serverName); serverNameService != nil {
+			service = serverNameService
+		}
+	}
+	err := service.NewConnection(adapter.WithContext(log.ContextWithNewID(ctx), &metadata), conn, metadata.Source, metadata.Destination, onClose)
 	N.CloseOnHandshakeFailure(conn, onClose, err)
 	if err != nil {
 		if E.IsClosedOrCanceled(err) {
@@ -120,6 +191,24 @@ func (h *Inbound) NewConnectionEx(ctx context.Context, conn net.Conn, metadata a
 	}
 }
 
+// serviceForServerName returns the service of the exactly matched server name,
+// or else the first matched wildcard.
+func (h *Inbound) serviceForServerName(serverName string) *shadowtls.Service {
+	serverName = strings.ToLower(serverName)
+	for _, it := range h.handshakeForServerName {
+		if it.serverName == serverName {
+			return it.service
+		}
+	}
+	for _, it := range h.handshakeForServerName {
+		if suffix, isWildcard := strings.CutPrefix(it.serverName, "*"); isWildcard && strings.HasPrefix(suffix, ".") &&
+			strings.HasSuffix(serverName, suffix) && len(serverName) > len(suffix) {
+			return it.service
+		}
+	}
+	return nil
+}
+
 type inboundHandler Inbound
 
 func (h *inboundHandler) NewConnectionEx(ctx context.Context, conn net.Conn, source M.Socksaddr, destination M.Socksaddr, onClose N.CloseHandlerFunc) {
@@ -134,6 +223,10 @@ func (h *inboundHandler) NewConnectionEx(ctx context.Context, conn net.Conn, sou
 	metadata.Destination = destination
 	if userName, _ := auth.UserFromContext[string](ctx); userName != "" {
 		metadata.User = userName
+		if detour, loaded := h.userDetour[userName]; loaded {
+			//nolint:staticcheck
+			metadata.InboundDetour = detour
+		}
 		h.logger.InfoContext(ctx, "[", userName, "] inbound connection to ", metadata.Destination)
 	} else {
 		h.logger.InfoContext(ctx, "inbound connection to ", metadata.Destination)
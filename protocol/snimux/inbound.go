@@ -0,0 +1,219 @@
+package snimux
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/adapter/inbound"
+	"github.com/sagernet/sing-box/common/listener"
+	"github.com/sagernet/sing-box/common/sniff"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/buf"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/service"
+)
+
+func RegisterInbound(registry *inbound.Registry) {
+	inbound.Register[option.SNIMuxInboundOptions](registry, C.TypeSNIMux, NewInbound)
+}
+
+var _ adapter.TCPInjectableInbound = (*Inbound)(nil)
+
+// Inbound dispatches TLS connections by the server name and ALPN of the
+// client hello to other inbounds or addresses, without terminating TLS.
+type Inbound struct {
+	inbound.Adapter
+	ctx      context.Context
+	router   adapter.ConnectionRouterEx
+	logger   log.ContextLogger
+	listener *listener.Listener
+	rules    []rule
+	fallback *target
+}
+
+type rule struct {
+	serverName []string
+	alpn       []string
+	target     target
+}
+
+type target struct {
+	inbound string
+	server  M.Socksaddr
+}
+
+func newTarget(options option.SNIMuxTarget) (target, error) {
+	if options.Inbound != "" {
+		if options.Server != "" {
+			return target{}, E.New("`inbound` is conflict with `server`")
+		}
+		return target{inbound: options.Inbound}, nil
+	}
+	server := options.ServerOptions.Build()
+	if !server.IsValid() || server.Port == 0 {
+		return target{}, E.New("missing `inbound` or `server` and `server_port`")
+	}
+	return target{server: server}, nil
+}
+
+func (t target) String() string {
+	if t.inbound != "" {
+		return "inbound/" + t.inbound
+	}
+	return t.server.String()
+}
+
+func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.SNIMuxInboundOptions) (adapter.Inbound, error) {
+	inbound := &Inbound{
+		Adapter: inbound.NewAdapter(C.TypeSNIMux, tag),
+		ctx:     ctx,
+		router:  router,
+		logger:  logger,
+	}
+	if len(options.Rules) == 0 && options.Fallback == nil {
+		return nil, E.New("missing rules")
+	}
+	for i, ruleOptions := range options.Rules {
+		ruleTarget, err := newTarget(ruleOptions.SNIMuxTarget)
+		if err != nil {
+			return nil, E.Cause(err, "parse rule[", i, "]")
+		}
+		inbound.rules = append(inbound.rules, rule{
+			serverName: common.Map(ruleOptions.ServerName, strings.ToLower),
+			alpn:       ruleOptions.ALPN,
+			target:     ruleTarget,
+		})
+	}
+	if options.Fallback != nil {
+		fallbackTarget, err := newTarget(*options.Fallback)
+		if err != nil {
+			return nil, E.Cause(err, "parse fallback")
+		}
+		inbound.fallback = &fallbackTarget
+	}
+	inbound.listener = listener.New(listener.Options{
+		Context:           ctx,
+		Logger:            logger,
+		Tag:               tag,
+		Network:           []string{N.NetworkTCP},
+		Listen:            options.ListenOptions,
+		ConnectionHandler: inbound,
+	})
+	return inbound, nil
+}
+
+func (h *Inbound) Start(stage adapter.StartStage) error {
+	switch stage {
+	case adapter.StartStateStart:
+		return h.listener.Start()
+	case adapter.StartStatePostStart:
+		inboundManager := service.FromContext[adapter.InboundManager](h.ctx)
+		targets := common.Map(h.rules, func(it rule) target {
+			return it.target
+		})
+		if h.fallback != nil {
+			targets = append(targets, *h.fallback)
+		}
+		for _, it := range targets {
+			if it.inbound == "" {
+				continue
+			}
+			detour, loaded := inboundManager.Get(it.inbound)
+			if !loaded {
+				return E.New("inbound not found: ", it.inbound)
+			}
+			if _, isInjectable := detour.(adapter.TCPInjectableInbound); !isInjectable {
+				return E.New("inbound is not TCP injectable: ", it.inbound)
+			}
+		}
+	}
+	return nil
+}
+
+func (h *Inbound) Close() error {
+	return h.listener.Close()
+}
+
+func (h *Inbound) NewConnectionEx(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
+	var (
+		serverName string
+		alpn       []string
+	)
+	buffer := buf.NewPacket()
+	err := sniff.PeekStream(ctx, &metadata, conn, nil, buffer, C.TCPTimeout, func(ctx context.Context, metadata *adapter.InboundContext, reader io.Reader) error {
+		clientHello, err := sniff.ReadTLSClientHello(ctx, reader)
+		if err != nil {
+			return err
+		}
+		serverName = clientHello.ServerName
+		alpn = clientHello.SupportedProtos
+		return nil
+	})
+	if !buffer.IsEmpty() {
+		conn = bufio.NewCachedConn(conn, buffer)
+	} else {
+		buffer.Release()
+	}
+	var matched *target
+	if err != nil {
+		h.logger.DebugContext(ctx, E.Cause(err, "read client hello from ", metadata.Source))
+	} else {
+		matched = h.match(serverName, alpn)
+	}
+	if matched == nil {
+		if h.fallback == nil {
+			N.CloseOnHandshakeFailure(conn, onClose, E.New("no matching rule"))
+			h.logger.DebugContext(ctx, "process connection from ", metadata.Source, ": no matching rule for server name: ", serverName)
+			return
+		}
+		matched = h.fallback
+	}
+	metadata.Inbound = h.Tag()
+	metadata.InboundType = h.Type()
+	metadata.SniffHost = serverName
+	if matched.inbound != "" {
+		//nolint:staticcheck
+		metadata.InboundDetour = matched.inbound
+	} else {
+		metadata.Destination = matched.server
+	}
+	h.logger.InfoContext(ctx, "dispatch connection with server name ", serverName, " to ", matched)
+	h.router.RouteConnectionEx(ctx, conn, metadata, onClose)
+}
+
+func (h *Inbound) match(serverName string, alpn []string) *target {
+	serverName = strings.ToLower(serverName)
+	for i := range h.rules {
+		it := &h.rules[i]
+		if len(it.serverName) > 0 && !common.Any(it.serverName, func(pattern string) bool {
+			return matchServerName(pattern, serverName)
+		}) {
+			continue
+		}
+		if len(it.alpn) > 0 && !common.Any(alpn, func(protocol string) bool {
+			return common.Contains(it.alpn, protocol)
+		}) {
+			continue
+		}
+		return &it.target
+	}
+	return nil
+}
+
+// matchServerName matches the server name exactly, or any subdomain for
+// patterns starting with "*.".
+func matchServerName(pattern string, serverName string) bool {
+	if suffix, isWildcard := strings.CutPrefix(pattern, "*"); isWildcard && strings.HasPrefix(suffix, ".") {
+		return strings.HasSuffix(serverName, suffix) && len(serverName) > len(suffix)
+	}
+	return pattern == serverName
+}
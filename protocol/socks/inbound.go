@@ -7,7 +7,9 @@ import (
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/adapter/inbound"
+	"github.com/sagernet/sing-box/common/authprovider"
 	"github.com/sagernet/sing-box/common/listener"
+	"github.com/sagernet/sing-box/common/socksudp"
 	"github.com/sagernet/sing-box/common/uot"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
@@ -16,7 +18,6 @@ import (
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/logger"
 	N "github.com/sagernet/sing/common/network"
-	"github.com/sagernet/sing/protocol/socks"
 )
 
 func RegisterInbound(registry *inbound.Registry) {
@@ -30,23 +31,33 @@ type Inbound struct {
 	router        adapter.ConnectionRouterEx
 	logger        logger.ContextLogger
 	listener      *listener.Listener
-	authenticator *auth.Authenticator
+	authenticator *authprovider.Authenticator
+	udpServer     *socksudp.Server
 }
 
 func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.SocksInboundOptions) (adapter.Inbound, error) {
 	inbound := &Inbound{
-		Adapter:       inbound.NewAdapter(C.TypeSOCKS, tag),
-		router:        uot.NewRouter(router, logger),
-		logger:        logger,
-		authenticator: auth.NewAuthenticator(options.Users),
+		Adapter: inbound.NewAdapter(C.TypeSOCKS, tag),
+		router:  uot.NewRouter(router, logger),
+		logger:  logger,
 	}
+	authenticator, err := authprovider.NewAuthenticator(ctx, logger, options.Users, options.AuthProvider)
+	if err != nil {
+		return nil, err
+	}
+	inbound.authenticator = authenticator
 	inbound.listener = listener.New(listener.Options{
 		Context:           ctx,
 		Logger:            logger,
+		Tag:               tag,
 		Network:           []string{N.NetworkTCP},
 		Listen:            options.ListenOptions,
 		ConnectionHandler: inbound,
 	})
+	inbound.udpServer, err = socksudp.NewServer(ctx, logger, inbound.listener, options.UDPRelay, options.UDPInTCP)
+	if err != nil {
+		return nil, err
+	}
 	return inbound, nil
 }
 
@@ -54,15 +65,25 @@ func (h *Inbound) Start(stage adapter.StartStage) error {
 	if stage != adapter.StartStateStart {
 		return nil
 	}
-	return h.listener.Start()
+	err := h.listener.Start()
+	if err != nil {
+		return err
+	}
+	if h.udpServer != nil {
+		return h.udpServer.Start()
+	}
+	return nil
 }
 
 func (h *Inbound) Close() error {
+	if h.udpServer != nil {
+		h.udpServer.Close()
+	}
 	return h.listener.Close()
 }
 
 func (h *Inbound) NewConnectionEx(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
-	err := socks.HandleConnectionEx(ctx, conn, std_bufio.NewReader(conn), h.authenticator, adapter.NewUpstreamHandlerEx(metadata, h.newUserConnection, h.streamUserPacketConnection), h.listener, metadata.Source, onClose)
+	err := authprovider.HandleSOCKSConnectionEx(ctx, conn, std_bufio.NewReader(conn), h.authenticator, h.udpServer, adapter.NewUpstreamHandlerEx(metadata, h.newUserConnection, h.streamUserPacketConnection), h.listener, metadata.Source, onClose)
 	N.CloseOnHandshakeFailure(conn, onClose, err)
 	if err != nil {
 		if E.IsClosedOrCanceled(err) {
@@ -3,10 +3,13 @@ package socks
 import (
 	"context"
 	"net"
+	"strings"
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/adapter/outbound"
+	"github.com/sagernet/sing-box/common/credential"
 	"github.com/sagernet/sing-box/common/dialer"
+	"github.com/sagernet/sing-box/common/uot"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
@@ -15,7 +18,6 @@ import (
 	"github.com/sagernet/sing/common/logger"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
-	"github.com/sagernet/sing/common/uot"
 	"github.com/sagernet/sing/protocol/socks"
 	"github.com/sagernet/sing/service"
 )
@@ -30,7 +32,7 @@ type Outbound struct {
 	outbound.Adapter
 	dnsRouter adapter.DNSRouter
 	logger    logger.ContextLogger
-	client    *socks.Client
+	client    *socksClient
 	resolve   bool
 	uotClient *uot.Client
 }
@@ -46,24 +48,29 @@ func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextL
 	if err != nil {
 		return nil, err
 	}
+	credentials, err := credential.Credentials(options.Username, options.Password, options.Credentials)
+	if err != nil {
+		return nil, err
+	}
 	outboundDialer, err := dialer.New(ctx, options.DialerOptions, options.ServerIsDomain())
 	if err != nil {
 		return nil, err
 	}
+	serverAddr := options.ServerOptions.Build()
+	clients, err := credential.New(logger, credentials, func(credential option.OutboundCredential) (*socks.Client, error) {
+		return socks.NewClient(outboundDialer, serverAddr, version, credential.Username, credential.Password), nil
+	})
+	if err != nil {
+		return nil, err
+	}
 	outbound := &Outbound{
 		Adapter:   outbound.NewAdapterWithDialerOptions(C.TypeSOCKS, tag, options.Network.Build(), options.DialerOptions),
 		dnsRouter: service.FromContext[adapter.DNSRouter](ctx),
 		logger:    logger,
-		client:    socks.NewClient(outboundDialer, options.ServerOptions.Build(), version, options.Username, options.Password),
+		client:    &socksClient{clients},
 		resolve:   version == socks.Version4,
 	}
-	uotOptions := common.PtrValueOrDefault(options.UDPOverTCP)
-	if uotOptions.Enabled {
-		outbound.uotClient = &uot.Client{
-			Dialer:  outbound.client,
-			Version: uotOptions.Version,
-		}
-	}
+	outbound.uotClient = uot.NewClient(outbound.client, common.PtrValueOrDefault(options.UDPOverTCP))
 	return outbound, nil
 }
 
@@ -75,9 +82,9 @@ func (h *Outbound) DialContext(ctx context.Context, network string, destination
 	case N.NetworkTCP:
 		h.logger.InfoContext(ctx, "outbound connection to ", destination)
 	case N.NetworkUDP:
-		if h.uotClient != nil {
+		if version := h.uotClient.Version(ctx); version != 0 {
 			h.logger.InfoContext(ctx, "outbound UoT connect packet connection to ", destination)
-			return h.uotClient.DialContext(ctx, network, destination)
+			return h.uotClient.DialContext(ctx, version, destination)
 		}
 		h.logger.InfoContext(ctx, "outbound packet connection to ", destination)
 	default:
@@ -97,9 +104,9 @@ func (h *Outbound) ListenPacket(ctx context.Context, destination M.Socksaddr) (n
 	ctx, metadata := adapter.ExtendContext(ctx)
 	metadata.Outbound = h.Tag()
 	metadata.Destination = destination
-	if h.uotClient != nil {
+	if version := h.uotClient.Version(ctx); version != 0 {
 		h.logger.InfoContext(ctx, "outbound UoT packet connection to ", destination)
-		return h.uotClient.ListenPacket(ctx, destination)
+		return h.uotClient.ListenPacket(ctx, version, destination)
 	}
 	if h.resolve && destination.IsFqdn() {
 		destinationAddresses, err := h.dnsRouter.Lookup(ctx, destination.Fqdn, adapter.DNSQueryOptions{})
@@ -115,3 +122,25 @@ func (h *Outbound) ListenPacket(ctx context.Context, destination M.Socksaddr) (n
 	h.logger.InfoContext(ctx, "outbound packet connection to ", destination)
 	return h.client.ListenPacket(ctx, destination)
 }
+
+// socksClient dials with the clients of the credential sets, retrying with
+// the next set if the server rejects the username or password.
+type socksClient struct {
+	clients *credential.Set[*socks.Client]
+}
+
+func isSOCKSAuthFailure(err error) bool {
+	return strings.Contains(err.Error(), "incorrect user name or password")
+}
+
+func (c *socksClient) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	return credential.Dial(c.clients, isSOCKSAuthFailure, func(client *socks.Client) (net.Conn, error) {
+		return client.DialContext(ctx, network, destination)
+	})
+}
+
+func (c *socksClient) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	return credential.Dial(c.clients, isSOCKSAuthFailure, func(client *socks.Client) (net.PacketConn, error) {
+		return client.ListenPacket(ctx, destination)
+	})
+}
@@ -0,0 +1,146 @@
+package ssh
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// sshHost is a jump host or the server of the outbound.
+type sshHost struct {
+	serverAddr        M.Socksaddr
+	user              string
+	password          string
+	signer            ssh.Signer
+	agentPath         string
+	agentKey          ssh.PublicKey
+	hostKey           []ssh.PublicKey
+	hostKeyAlgorithms []string
+}
+
+func newHost(options option.SSHHostOptions) (*sshHost, error) {
+	host := &sshHost{
+		serverAddr:        options.ServerOptions.Build(),
+		user:              options.User,
+		password:          options.Password,
+		hostKeyAlgorithms: options.HostKeyAlgorithms,
+	}
+	if host.serverAddr.Port == 0 {
+		host.serverAddr.Port = 22
+	}
+	if host.user == "" {
+		host.user = "root"
+	}
+	if options.Agent {
+		host.agentPath = options.AgentPath
+		if host.agentPath == "" {
+			host.agentPath = os.Getenv("SSH_AUTH_SOCK")
+			if host.agentPath == "" {
+				return nil, E.New("missing ssh agent path: SSH_AUTH_SOCK is not set")
+			}
+		} else {
+			host.agentPath = os.ExpandEnv(host.agentPath)
+		}
+	} else if options.AgentPath != "" {
+		return nil, E.New("`agent_path` requires `agent`")
+	}
+	if len(options.PrivateKey) > 0 || options.PrivateKeyPath != "" {
+		var privateKey []byte
+		if len(options.PrivateKey) > 0 {
+			privateKey = []byte(strings.Join(options.PrivateKey, "\n"))
+		} else {
+			var err error
+			privateKey, err = os.ReadFile(os.ExpandEnv(options.PrivateKeyPath))
+			if err != nil {
+				return nil, E.Cause(err, "read private key")
+			}
+		}
+		var signer ssh.Signer
+		var err error
+		if options.PrivateKeyPassphrase == "" {
+			signer, err = ssh.ParsePrivateKey(privateKey)
+		} else {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(privateKey, []byte(options.PrivateKeyPassphrase))
+		}
+		var passphraseMissingErr *ssh.PassphraseMissingError
+		if errors.As(err, &passphraseMissingErr) {
+			// the key is used by the agent if it is loaded there
+			if host.agentPath == "" || passphraseMissingErr.PublicKey == nil {
+				return nil, E.New("private key is encrypted, `private_key_passphrase` or `agent` required")
+			}
+			host.agentKey = passphraseMissingErr.PublicKey
+		} else if err != nil {
+			return nil, E.Cause(err, "parse private key")
+		} else {
+			host.signer = signer
+		}
+	}
+	for _, hostKey := range options.HostKey {
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(hostKey))
+		if err != nil {
+			return nil, E.Cause(err, "parse host key ", hostKey)
+		}
+		host.hostKey = append(host.hostKey, key)
+	}
+	return host, nil
+}
+
+// authMethods returns the auth methods and the connection to the agent, which
+// must be kept open until authenticated.
+func (h *sshHost) authMethods() ([]ssh.AuthMethod, io.Closer, error) {
+	var (
+		authMethods []ssh.AuthMethod
+		signers     []ssh.Signer
+		agentConn   net.Conn
+	)
+	if h.agentPath != "" {
+		var err error
+		agentConn, err = net.Dial("unix", h.agentPath)
+		if err != nil {
+			return nil, nil, E.Cause(err, "connect to ssh agent")
+		}
+		agentSigners, err := agent.NewClient(agentConn).Signers()
+		if err != nil {
+			agentConn.Close()
+			return nil, nil, E.Cause(err, "list ssh agent keys")
+		}
+		if h.agentKey != nil {
+			agentKey := h.agentKey.Marshal()
+			for _, signer := range agentSigners {
+				if bytes.Equal(signer.PublicKey().Marshal(), agentKey) {
+					signers = append(signers, signer)
+				}
+			}
+			if len(signers) == 0 {
+				agentConn.Close()
+				return nil, nil, E.New("encrypted private key is not loaded in ssh agent")
+			}
+		} else {
+			signers = append(signers, agentSigners...)
+		}
+	}
+	if h.signer != nil {
+		signers = append(signers, h.signer)
+	}
+	// methods of the same type after the first are not tried
+	if len(signers) > 0 {
+		authMethods = append(authMethods, ssh.PublicKeys(signers...))
+	}
+	if h.password != "" {
+		authMethods = append(authMethods, ssh.Password(h.password))
+	}
+	if agentConn == nil {
+		return authMethods, nil, nil
+	}
+	return authMethods, agentConn, nil
+}
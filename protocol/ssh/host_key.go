@@ -0,0 +1,97 @@
+package ssh
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+
+	"github.com/sagernet/sing-box/adapter"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/service"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// verifyHostKey checks the host key against the pinned keys, then known_hosts,
+// then keys trusted on first use. Any key is accepted if none is configured.
+func (s *Outbound) verifyHostKey(host *sshHost, key ssh.PublicKey) error {
+	address := host.serverAddr.String()
+	if len(host.hostKey) > 0 {
+		serverKey := key.Marshal()
+		for _, hostKey := range host.hostKey {
+			if bytes.Equal(serverKey, hostKey.Marshal()) {
+				s.trustHostKey(address, key)
+				return nil
+			}
+		}
+		return E.New("host key mismatch, server send ", key.Type(), " ", base64.StdEncoding.EncodeToString(serverKey))
+	}
+	if s.knownHosts != nil {
+		err := s.knownHosts(address, host.serverAddr, key)
+		if err == nil {
+			s.trustHostKey(address, key)
+			return nil
+		}
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) || len(keyErr.Want) > 0 {
+			return E.Cause(err, "verify host key of ", address)
+		}
+	} else if !s.hostKeyTOFU {
+		return nil
+	}
+	if !s.hostKeyTOFU {
+		return E.New("unknown host ", address, ", server send ", key.Type(), " ", ssh.FingerprintSHA256(key))
+	}
+	hostname := knownhosts.Normalize(address)
+	trustedKey := s.loadHostKey(hostname)
+	if trustedKey == nil {
+		s.logger.Info("trust host key of ", hostname, " on first use: ", key.Type(), " ", ssh.FingerprintSHA256(key))
+		s.storeHostKey(hostname, key.Marshal())
+		return nil
+	}
+	if !bytes.Equal(trustedKey, key.Marshal()) {
+		return E.New("host key of ", hostname, " changed, server send ", key.Type(), " ", ssh.FingerprintSHA256(key), ", set `host_key` to accept it")
+	}
+	return nil
+}
+
+// trustHostKey replaces the key trusted on first use with the verified key,
+// so that a changed key accepted by `host_key` is kept after it is removed.
+func (s *Outbound) trustHostKey(address string, key ssh.PublicKey) {
+	if !s.hostKeyTOFU {
+		return
+	}
+	host := knownhosts.Normalize(address)
+	serverKey := key.Marshal()
+	if !bytes.Equal(s.loadHostKey(host), serverKey) {
+		s.storeHostKey(host, serverKey)
+	}
+}
+
+func (s *Outbound) loadHostKey(host string) []byte {
+	if hostKey, loaded := s.trustedHostKeys[host]; loaded {
+		return hostKey
+	}
+	cacheFile := service.FromContext[adapter.CacheFile](s.ctx)
+	if cacheFile == nil {
+		return nil
+	}
+	hostKey := cacheFile.LoadSSHHostKey(host)
+	if hostKey != nil {
+		s.trustedHostKeys[host] = hostKey
+	}
+	return hostKey
+}
+
+func (s *Outbound) storeHostKey(host string, hostKey []byte) {
+	s.trustedHostKeys[host] = hostKey
+	cacheFile := service.FromContext[adapter.CacheFile](s.ctx)
+	if cacheFile == nil {
+		return
+	}
+	err := cacheFile.SaveSSHHostKey(host, hostKey)
+	if err != nil {
+		s.logger.Error(E.Cause(err, "save host key of ", host))
+	}
+}
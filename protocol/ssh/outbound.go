@@ -1,14 +1,11 @@
 package ssh
 
 import (
-	"bytes"
 	"context"
-	"encoding/base64"
 	"math/rand"
 	"net"
 	"os"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
 
@@ -25,6 +22,7 @@ import (
 	N "github.com/sagernet/sing/common/network"
 
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
 )
 
 func RegisterOutbound(registry *outbound.Registry) {
@@ -38,19 +36,24 @@ type Outbound struct {
 	ctx               context.Context
 	logger            logger.ContextLogger
 	dialer            N.Dialer
-	serverAddr        M.Socksaddr
-	user              string
-	hostKey           []ssh.PublicKey
-	hostKeyAlgorithms []string
+	hosts             []*sshHost
+	knownHosts        ssh.HostKeyCallback
+	hostKeyTOFU       bool
+	trustedHostKeys   map[string][]byte
+	keepAliveInterval time.Duration
+	keepAliveCountMax int
 	clientVersion     string
-	authMethod        []ssh.AuthMethod
 	clientAccess      sync.Mutex
 	clientConn        net.Conn
 	client            *ssh.Client
 }
 
 func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.SSHOutboundOptions) (adapter.Outbound, error) {
-	outboundDialer, err := dialer.New(ctx, options.DialerOptions, options.ServerIsDomain())
+	serverIsDomain := options.ServerIsDomain()
+	if len(options.Jump) > 0 {
+		serverIsDomain = options.Jump[0].ServerIsDomain()
+	}
+	outboundDialer, err := dialer.New(ctx, options.DialerOptions, serverIsDomain)
 	if err != nil {
 		return nil, err
 	}
@@ -59,53 +62,41 @@ func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextL
 		ctx:               ctx,
 		logger:            logger,
 		dialer:            outboundDialer,
-		serverAddr:        options.ServerOptions.Build(),
-		user:              options.User,
-		hostKeyAlgorithms: options.HostKeyAlgorithms,
+		hostKeyTOFU:       options.HostKeyTOFU,
+		trustedHostKeys:   make(map[string][]byte),
+		keepAliveInterval: time.Duration(options.KeepAliveInterval),
+		keepAliveCountMax: options.KeepAliveCountMax,
 		clientVersion:     options.ClientVersion,
 	}
-	if outbound.serverAddr.Port == 0 {
-		outbound.serverAddr.Port = 22
-	}
-	if outbound.user == "" {
-		outbound.user = "root"
-	}
 	if outbound.clientVersion == "" {
 		outbound.clientVersion = randomVersion()
 	}
-	if options.Password != "" {
-		outbound.authMethod = append(outbound.authMethod, ssh.Password(options.Password))
+	if outbound.keepAliveCountMax == 0 {
+		outbound.keepAliveCountMax = 3
 	}
-	if len(options.PrivateKey) > 0 || options.PrivateKeyPath != "" {
-		var privateKey []byte
-		if len(options.PrivateKey) > 0 {
-			privateKey = []byte(strings.Join(options.PrivateKey, "\n"))
-		} else {
-			var err error
-			privateKey, err = os.ReadFile(os.ExpandEnv(options.PrivateKeyPath))
-			if err != nil {
-				return nil, E.Cause(err, "read private key")
-			}
-		}
-		var signer ssh.Signer
-		var err error
-		if options.PrivateKeyPassphrase == "" {
-			signer, err = ssh.ParsePrivateKey(privateKey)
-		} else {
-			signer, err = ssh.ParsePrivateKeyWithPassphrase(privateKey, []byte(options.PrivateKeyPassphrase))
+	for i, jumpOptions := range options.Jump {
+		if jumpOptions.Server == "" {
+			return nil, E.New("missing server for jump host ", i)
 		}
+		host, err := newHost(jumpOptions)
 		if err != nil {
-			return nil, E.Cause(err, "parse private key")
+			return nil, E.Cause(err, "jump host ", i)
 		}
-		outbound.authMethod = append(outbound.authMethod, ssh.PublicKeys(signer))
+		outbound.hosts = append(outbound.hosts, host)
 	}
-	if len(options.HostKey) > 0 {
-		for _, hostKey := range options.HostKey {
-			key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(hostKey))
-			if err != nil {
-				return nil, E.New("parse host key ", key)
-			}
-			outbound.hostKey = append(outbound.hostKey, key)
+	host, err := newHost(options.SSHHostOptions)
+	if err != nil {
+		return nil, err
+	}
+	outbound.hosts = append(outbound.hosts, host)
+	if len(options.KnownHosts) > 0 {
+		knownHostsPaths := make([]string, 0, len(options.KnownHosts))
+		for _, path := range options.KnownHosts {
+			knownHostsPaths = append(knownHostsPaths, os.ExpandEnv(path))
+		}
+		outbound.knownHosts, err = knownhosts.New(knownHostsPaths...)
+		if err != nil {
+			return nil, E.Cause(err, "read known hosts")
 		}
 	}
 	return outbound, nil
@@ -133,51 +124,109 @@ func (s *Outbound) connect() (*ssh.Client, error) {
 		return s.client, nil
 	}
 
-	conn, err := s.dialer.DialContext(s.ctx, N.NetworkTCP, s.serverAddr)
+	conn, err := s.dialer.DialContext(s.ctx, N.NetworkTCP, s.hosts[0].serverAddr)
 	if err != nil {
 		return nil, err
 	}
-	config := &ssh.ClientConfig{
-		User:              s.user,
-		Auth:              s.authMethod,
-		ClientVersion:     s.clientVersion,
-		HostKeyAlgorithms: s.hostKeyAlgorithms,
-		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
-			if len(s.hostKey) == 0 {
-				return nil
-			}
-			serverKey := key.Marshal()
-			for _, hostKey := range s.hostKey {
-				if bytes.Equal(serverKey, hostKey.Marshal()) {
-					return nil
-				}
+	var client *ssh.Client
+	hostConn := conn
+	for i, host := range s.hosts {
+		if i > 0 {
+			hostConn, err = client.Dial(N.NetworkTCP, host.serverAddr.String())
+			if err != nil {
+				conn.Close()
+				return nil, E.Cause(err, "connect to ", host.serverAddr, " through jump host")
 			}
-			return E.New("host key mismatch, server send ", key.Type(), " ", base64.StdEncoding.EncodeToString(serverKey))
-		},
-	}
-	clientConn, chans, reqs, err := ssh.NewClientConn(conn, s.serverAddr.Addr.String(), config)
-	if err != nil {
-		conn.Close()
-		return nil, E.Cause(err, "connect to ssh server")
+		}
+		client, err = s.handshake(hostConn, host)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
 	}
 
-	client := ssh.NewClient(clientConn, chans, reqs)
-
 	s.clientConn = conn
 	s.client = client
 
+	done := make(chan struct{})
 	go func() {
 		client.Wait()
+		close(done)
 		conn.Close()
 		s.clientAccess.Lock()
 		s.client = nil
 		s.clientConn = nil
 		s.clientAccess.Unlock()
 	}()
+	if s.keepAliveInterval > 0 {
+		go s.keepAlive(client, conn, done)
+	}
 
 	return client, nil
 }
 
+func (s *Outbound) handshake(conn net.Conn, host *sshHost) (*ssh.Client, error) {
+	authMethods, agentConn, err := host.authMethods()
+	if err != nil {
+		return nil, err
+	}
+	if agentConn != nil {
+		defer agentConn.Close()
+	}
+	config := &ssh.ClientConfig{
+		User:              host.user,
+		Auth:              authMethods,
+		ClientVersion:     s.clientVersion,
+		HostKeyAlgorithms: host.hostKeyAlgorithms,
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			return s.verifyHostKey(host, key)
+		},
+	}
+	clientConn, chans, reqs, err := ssh.NewClientConn(conn, host.serverAddr.String(), config)
+	if err != nil {
+		return nil, E.Cause(err, "connect to ssh server ", host.serverAddr)
+	}
+	return ssh.NewClient(clientConn, chans, reqs), nil
+}
+
+// keepAlive closes the connection if the server does not reply to
+// keepAliveCountMax keep alive requests in a row.
+func (s *Outbound) keepAlive(client *ssh.Client, conn net.Conn, done <-chan struct{}) {
+	ticker := time.NewTicker(s.keepAliveInterval)
+	defer ticker.Stop()
+	reply := make(chan struct{}, 1)
+	var (
+		pending bool
+		missed  int
+	)
+	for {
+		select {
+		case <-done:
+			return
+		case <-reply:
+			pending = false
+			missed = 0
+		case <-ticker.C:
+			if pending {
+				missed++
+				if missed >= s.keepAliveCountMax {
+					s.logger.Error("ssh server did not reply to ", missed, " keep alive requests, closing connection")
+					conn.Close()
+					return
+				}
+				continue
+			}
+			pending = true
+			go func() {
+				_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+				if err == nil {
+					reply <- struct{}{}
+				}
+			}()
+		}
+	}
+}
+
 func (s *Outbound) InterfaceUpdated() {
 	common.Close(s.clientConn)
 }
@@ -26,6 +26,8 @@ import (
 	"github.com/cretz/bine/tor"
 )
 
+var _ adapter.LazyOutbound = (*Outbound)(nil)
+
 func RegisterOutbound(registry *outbound.Registry) {
 	outbound.Register[option.TorOutboundOptions](registry, C.TypeTor, NewOutbound)
 }
@@ -40,6 +42,7 @@ type Outbound struct {
 	events      chan control.Event
 	instance    *tor.Tor
 	socksClient *socks.Client
+	lazy        *outbound.Lazy
 }
 
 func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.TorOutboundOptions) (adapter.Outbound, error) {
@@ -79,17 +82,26 @@ func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextL
 	if err != nil {
 		return nil, err
 	}
-	return &Outbound{
+	torOutbound := &Outbound{
 		Adapter:   outbound.NewAdapterWithDialerOptions(C.TypeTor, tag, []string{N.NetworkTCP}, options.DialerOptions),
 		ctx:       ctx,
 		logger:    logger,
 		proxy:     NewProxyListener(ctx, logger, outboundDialer),
 		startConf: &startConf,
 		options:   options.Options,
-	}, nil
+	}
+	torOutbound.lazy = outbound.NewLazy(options.Lazy, torOutbound.startInstance)
+	return torOutbound, nil
 }
 
 func (t *Outbound) Start() error {
+	if t.lazy.Enabled() {
+		return nil
+	}
+	return t.startInstance()
+}
+
+func (t *Outbound) startInstance() error {
 	err := t.start()
 	if err != nil {
 		t.Close()
@@ -97,6 +109,14 @@ func (t *Outbound) Start() error {
 	return err
 }
 
+func (t *Outbound) LazyState() (state string, err error) {
+	return t.lazy.LazyState()
+}
+
+func (t *Outbound) Initialize() error {
+	return t.lazy.Initialize()
+}
+
 var torLogEvents = []control.EventCode{
 	control.EventCodeLogDebug,
 	control.EventCodeLogErr,
@@ -203,6 +223,10 @@ func (t *Outbound) Close() error {
 }
 
 func (t *Outbound) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	err := t.lazy.Initialize()
+	if err != nil {
+		return nil, E.Cause(err, "initialize tor")
+	}
 	t.logger.InfoContext(ctx, "outbound connection to ", destination)
 	return t.socksClient.DialContext(ctx, network, destination)
 }
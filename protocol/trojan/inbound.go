@@ -7,6 +7,7 @@ import (
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/adapter/inbound"
+	"github.com/sagernet/sing-box/common/fallback"
 	"github.com/sagernet/sing-box/common/listener"
 	"github.com/sagernet/sing-box/common/mux"
 	"github.com/sagernet/sing-box/common/tls"
@@ -27,7 +28,10 @@ func RegisterInbound(registry *inbound.Registry) {
 	inbound.Register[option.TrojanInboundOptions](registry, C.TypeTrojan, NewInbound)
 }
 
-var _ adapter.TCPInjectableInbound = (*Inbound)(nil)
+var (
+	_ adapter.TCPInjectableInbound = (*Inbound)(nil)
+	_ adapter.ManagedUserInbound   = (*Inbound)(nil)
+)
 
 type Inbound struct {
 	inbound.Adapter
@@ -35,10 +39,12 @@ type Inbound struct {
 	logger                   log.ContextLogger
 	listener                 *listener.Listener
 	service                  *trojan.Service[int]
-	users                    []option.TrojanUser
+	users                    *inbound.UserList[option.TrojanUser]
+	options                  option.TrojanInboundOptions
 	tlsConfig                tls.ServerConfig
 	fallbackAddr             M.Socksaddr
 	fallbackAddrTLSNextProto map[string]M.Socksaddr
+	fallbackServer           *fallback.Server
 	transport                adapter.V2RayServerTransport
 }
 
@@ -47,7 +53,8 @@ func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLo
 		Adapter: inbound.NewAdapter(C.TypeTrojan, tag),
 		router:  router,
 		logger:  logger,
-		users:   options.Users,
+		users:   inbound.NewUserList(option.TrojanUser.InboundUser),
+		options: options,
 	}
 	if options.TLS != nil {
 		tlsConfig, err := tls.NewServerWithOptions(tls.ServerOptions{
@@ -63,13 +70,23 @@ func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLo
 		inbound.tlsConfig = tlsConfig
 	}
 	var fallbackHandler N.TCPConnectionHandlerEx
-	if options.Fallback != nil && options.Fallback.Server != "" || len(options.FallbackForALPN) > 0 {
+	if options.Fallback != nil && options.Fallback.Server != "" || len(options.FallbackForALPN) > 0 || options.FallbackHTTP != nil {
 		if options.Fallback != nil && options.Fallback.Server != "" {
+			if options.FallbackHTTP != nil {
+				return nil, E.New("`fallback` is conflict with `fallback_http`")
+			}
 			inbound.fallbackAddr = options.Fallback.Build()
 			if !inbound.fallbackAddr.IsValid() {
 				return nil, E.New("invalid fallback address: ", inbound.fallbackAddr)
 			}
 		}
+		if options.FallbackHTTP != nil {
+			fallbackServer, err := fallback.NewServer(ctx, logger, *options.FallbackHTTP)
+			if err != nil {
+				return nil, E.Cause(err, "create fallback HTTP server")
+			}
+			inbound.fallbackServer = fallbackServer
+		}
 		if len(options.FallbackForALPN) > 0 {
 			if inbound.tlsConfig == nil {
 				return nil, E.New("fallback for ALPN is not supported without TLS")
@@ -87,11 +104,11 @@ func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLo
 		fallbackHandler = adapter.NewUpstreamContextHandlerEx(inbound.fallbackConnection, nil)
 	}
 	service := trojan.NewService[int](adapter.NewUpstreamContextHandlerEx(inbound.newConnection, inbound.newPacketConnection), fallbackHandler, logger)
-	err := service.UpdateUsers(common.MapIndexed(options.Users, func(index int, it option.TrojanUser) int {
-		return index
-	}), common.Map(options.Users, func(it option.TrojanUser) string {
-		return it.Password
-	}))
+	err := inbound.users.Initialize(options.Users, func(ids []int, users []option.TrojanUser) error {
+		return service.UpdateUsers(ids, common.Map(users, func(it option.TrojanUser) string {
+			return it.Password
+		}))
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -109,6 +126,7 @@ func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLo
 	inbound.listener = listener.New(listener.Options{
 		Context:           ctx,
 		Logger:            logger,
+		Tag:               tag,
 		Network:           []string{N.NetworkTCP},
 		Listen:            options.ListenOptions,
 		ConnectionHandler: inbound,
@@ -126,6 +144,12 @@ func (h *Inbound) Start(stage adapter.StartStage) error {
 			return E.Cause(err, "create TLS config")
 		}
 	}
+	if h.fallbackServer != nil {
+		err := h.fallbackServer.Start()
+		if err != nil {
+			return err
+		}
+	}
 	if h.transport == nil {
 		return h.listener.Start()
 	}
@@ -161,9 +185,24 @@ func (h *Inbound) Close() error {
 		h.listener,
 		h.tlsConfig,
 		h.transport,
+		common.PtrOrNil(h.fallbackServer),
 	)
 }
 
+func (h *Inbound) InboundOptions() option.Inbound {
+	options := h.options
+	options.Users = h.users.Users()
+	return option.Inbound{Type: h.Type(), Tag: h.Tag(), Options: &options}
+}
+
+func (h *Inbound) SetUser(content []byte) (option.InboundUser, error) {
+	return h.users.SetUser(content)
+}
+
+func (h *Inbound) RemoveUser(name string) error {
+	return h.users.RemoveUser(name)
+}
+
 func (h *Inbound) NewConnectionEx(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
 	if h.tlsConfig != nil && h.transport == nil {
 		tlsConn, err := tls.ServerHandshake(ctx, conn, h.tlsConfig)
@@ -184,14 +223,14 @@ func (h *Inbound) NewConnectionEx(ctx context.Context, conn net.Conn, metadata a
 func (h *Inbound) newConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
 	metadata.Inbound = h.Tag()
 	metadata.InboundType = h.Type()
-	userIndex, loaded := auth.UserFromContext[int](ctx)
+	userID, loaded := auth.UserFromContext[int](ctx)
 	if !loaded {
 		N.CloseOnHandshakeFailure(conn, onClose, os.ErrInvalid)
 		return
 	}
-	user := h.users[userIndex].Name
+	user := h.users.Name(userID)
 	if user == "" {
-		user = F.ToString(userIndex)
+		user = F.ToString(userID)
 	} else {
 		metadata.User = user
 	}
@@ -202,14 +241,14 @@ func (h *Inbound) newConnection(ctx context.Context, conn net.Conn, metadata ada
 func (h *Inbound) newPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
 	metadata.Inbound = h.Tag()
 	metadata.InboundType = h.Type()
-	userIndex, loaded := auth.UserFromContext[int](ctx)
+	userID, loaded := auth.UserFromContext[int](ctx)
 	if !loaded {
 		N.CloseOnHandshakeFailure(conn, onClose, os.ErrInvalid)
 		return
 	}
-	user := h.users[userIndex].Name
+	user := h.users.Name(userID)
 	if user == "" {
-		user = F.ToString(userIndex)
+		user = F.ToString(userID)
 	} else {
 		metadata.User = user
 	}
@@ -232,6 +271,11 @@ func (h *Inbound) fallbackConnection(ctx context.Context, conn net.Conn, metadat
 		}
 	}
 	if !fallbackAddr.IsValid() {
+		if h.fallbackServer != nil {
+			h.logger.InfoContext(ctx, "fallback connection from ", metadata.Source, " to built-in HTTP server")
+			h.fallbackServer.NewConnectionEx(ctx, conn, metadata.Source, onClose)
+			return
+		}
 		if !h.fallbackAddr.IsValid() {
 			h.logger.DebugContext(ctx, "process connection from ", metadata.Source, ": fallback disabled by default")
 			N.CloseOnHandshakeFailure(conn, onClose, os.ErrInvalid)
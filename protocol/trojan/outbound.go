@@ -6,6 +6,7 @@ import (
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/adapter/outbound"
+	"github.com/sagernet/sing-box/common/credential"
 	"github.com/sagernet/sing-box/common/dialer"
 	"github.com/sagernet/sing-box/common/mux"
 	"github.com/sagernet/sing-box/common/tls"
@@ -31,14 +32,27 @@ type Outbound struct {
 	logger          logger.ContextLogger
 	dialer          N.Dialer
 	serverAddr      M.Socksaddr
-	key             [56]byte
-	multiplexDialer *mux.Client
+	keys            *credential.Set[[56]byte]
+	multiplexDialer *mux.OutboundClient
 	tlsConfig       tls.Config
 	tlsDialer       tls.Dialer
 	transport       adapter.V2RayClientTransport
 }
 
 func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.TrojanOutboundOptions) (adapter.Outbound, error) {
+	credentials, err := credential.Credentials("", options.Password, options.Credentials)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := credential.New(logger, credentials, func(credential option.OutboundCredential) ([56]byte, error) {
+		if credential.Username != "" {
+			return [56]byte{}, E.New("username is not supported")
+		}
+		return trojan.Key(credential.Password), nil
+	})
+	if err != nil {
+		return nil, err
+	}
 	outboundDialer, err := dialer.New(ctx, options.DialerOptions, options.ServerIsDomain())
 	if err != nil {
 		return nil, err
@@ -48,7 +62,7 @@ func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextL
 		logger:     logger,
 		dialer:     outboundDialer,
 		serverAddr: options.ServerOptions.Build(),
-		key:        trojan.Key(options.Password),
+		keys:       keys,
 	}
 	if options.TLS != nil {
 		outbound.tlsConfig, err = tls.NewClientWithOptions(tls.ClientOptions{
@@ -64,13 +78,17 @@ func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextL
 		}
 		outbound.tlsDialer = tls.NewDialer(outboundDialer, outbound.tlsConfig)
 	}
+	transportOptions, err := v2ray.FrontedTransportOptions(logger, options.TLS, options.Transport)
+	if err != nil {
+		return nil, err
+	}
 	if options.Transport != nil {
-		outbound.transport, err = v2ray.NewClientTransport(ctx, outbound.dialer, outbound.serverAddr, common.PtrValueOrDefault(options.Transport), outbound.tlsConfig)
+		outbound.transport, err = v2ray.NewClientTransport(ctx, outbound.dialer, outbound.serverAddr, transportOptions, outbound.tlsConfig)
 		if err != nil {
 			return nil, E.Cause(err, "create client transport: ", options.Transport.Type)
 		}
 	}
-	outbound.multiplexDialer, err = mux.NewClientWithOptions((*trojanDialer)(outbound), logger, common.PtrValueOrDefault(options.Multiplex))
+	outbound.multiplexDialer, err = mux.NewOutboundClient((*trojanDialer)(outbound), logger, common.PtrValueOrDefault(options.Multiplex))
 	if err != nil {
 		return nil, err
 	}
@@ -78,7 +96,11 @@ func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextL
 }
 
 func (h *Outbound) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
-	if h.multiplexDialer == nil {
+	multiplexDialer, err := h.multiplexDialer.Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if multiplexDialer == nil {
 		switch N.NetworkName(network) {
 		case N.NetworkTCP:
 			h.logger.InfoContext(ctx, "outbound connection to ", destination)
@@ -93,17 +115,21 @@ func (h *Outbound) DialContext(ctx context.Context, network string, destination
 		case N.NetworkUDP:
 			h.logger.InfoContext(ctx, "outbound multiplex packet connection to ", destination)
 		}
-		return h.multiplexDialer.DialContext(ctx, network, destination)
+		return multiplexDialer.DialContext(ctx, network, destination)
 	}
 }
 
 func (h *Outbound) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
-	if h.multiplexDialer == nil {
+	multiplexDialer, err := h.multiplexDialer.Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if multiplexDialer == nil {
 		h.logger.InfoContext(ctx, "outbound packet connection to ", destination)
 		return (*trojanDialer)(h).ListenPacket(ctx, destination)
 	} else {
 		h.logger.InfoContext(ctx, "outbound multiplex packet connection to ", destination)
-		return h.multiplexDialer.ListenPacket(ctx, destination)
+		return multiplexDialer.ListenPacket(ctx, destination)
 	}
 }
 
@@ -111,13 +137,11 @@ func (h *Outbound) InterfaceUpdated() {
 	if h.transport != nil {
 		h.transport.Close()
 	}
-	if h.multiplexDialer != nil {
-		h.multiplexDialer.Reset()
-	}
+	h.multiplexDialer.Reset()
 }
 
 func (h *Outbound) Close() error {
-	return common.Close(common.PtrOrNil(h.multiplexDialer), h.transport)
+	return common.Close(h.multiplexDialer, h.transport)
 }
 
 type trojanDialer Outbound
@@ -139,11 +163,12 @@ func (h *trojanDialer) DialContext(ctx context.Context, network string, destinat
 		common.Close(conn)
 		return nil, err
 	}
+	key := h.keys.Select()
 	switch N.NetworkName(network) {
 	case N.NetworkTCP:
-		return trojan.NewClientConn(conn, h.key, destination), nil
+		return trojan.NewClientConn(conn, key, destination), nil
 	case N.NetworkUDP:
-		return bufio.NewBindPacketConn(trojan.NewClientPacketConn(conn, h.key), destination), nil
+		return bufio.NewBindPacketConn(trojan.NewClientPacketConn(conn, key), destination), nil
 	default:
 		return nil, E.Extend(N.ErrUnknownNetwork, network)
 	}
@@ -7,7 +7,9 @@ import (
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/adapter/inbound"
+	"github.com/sagernet/sing-box/common/bandwidth"
 	"github.com/sagernet/sing-box/common/listener"
+	"github.com/sagernet/sing-box/common/quicmtu"
 	"github.com/sagernet/sing-box/common/tls"
 	"github.com/sagernet/sing-box/common/uot"
 	C "github.com/sagernet/sing-box/constant"
@@ -27,14 +29,18 @@ func RegisterInbound(registry *inbound.Registry) {
 	inbound.Register[option.TUICInboundOptions](registry, C.TypeTUIC, NewInbound)
 }
 
+var _ adapter.ManagedUserInbound = (*Inbound)(nil)
+
 type Inbound struct {
 	inbound.Adapter
-	router       adapter.ConnectionRouterEx
-	logger       log.ContextLogger
-	listener     *listener.Listener
-	tlsConfig    tls.ServerConfig
-	server       *tuic.Service[int]
-	userNameList []string
+	router    adapter.ConnectionRouterEx
+	logger    log.ContextLogger
+	listener  *listener.Listener
+	tlsConfig tls.ServerConfig
+	server    *tuic.Service[int]
+	users     *inbound.UserList[option.TUICUser]
+	options   option.TUICInboundOptions
+	limiters  bandwidth.Limiters[int]
 }
 
 func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.TUICInboundOptions) (adapter.Inbound, error) {
@@ -42,6 +48,10 @@ func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLo
 	if options.TLS == nil || !options.TLS.Enabled {
 		return nil, C.ErrTLSRequired
 	}
+	err := quicmtu.Validate(options.QUICMTUOptions, false)
+	if err != nil {
+		return nil, err
+	}
 	tlsConfig, err := tls.NewServer(ctx, logger, common.PtrValueOrDefault(options.TLS))
 	if err != nil {
 		return nil, err
@@ -53,9 +63,12 @@ func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLo
 		listener: listener.New(listener.Options{
 			Context: ctx,
 			Logger:  logger,
+			Tag:     tag,
 			Listen:  options.ListenOptions,
 		}),
 		tlsConfig: tlsConfig,
+		users:     inbound.NewUserList(option.TUICUser.InboundUser),
+		options:   options,
 	}
 	var udpTimeout time.Duration
 	if options.UDPTimeout != 0 {
@@ -77,26 +90,30 @@ func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLo
 	if err != nil {
 		return nil, err
 	}
-	var userList []int
-	var userNameList []string
-	var userUUIDList [][16]byte
-	var userPasswordList []string
-	for index, user := range options.Users {
-		if user.UUID == "" {
-			return nil, E.New("missing uuid for user ", index)
+	err = inbound.users.Initialize(options.Users, func(ids []int, users []option.TUICUser) error {
+		userUUIDList := make([][16]byte, 0, len(users))
+		userPasswordList := make([]string, 0, len(users))
+		limits := make(map[int]bandwidth.Limit, len(users))
+		for index, user := range users {
+			if user.UUID == "" {
+				return E.New("missing uuid for user ", index)
+			}
+			userUUID, err := uuid.FromString(user.UUID)
+			if err != nil {
+				return E.Cause(err, "invalid uuid for user ", index)
+			}
+			userUUIDList = append(userUUIDList, userUUID)
+			userPasswordList = append(userPasswordList, user.Password)
+			limits[ids[index]] = bandwidth.Limit{UploadMbps: user.UpMbps, DownloadMbps: user.DownMbps}
 		}
-		userUUID, err := uuid.FromString(user.UUID)
-		if err != nil {
-			return nil, E.Cause(err, "invalid uuid for user ", index)
-		}
-		userList = append(userList, index)
-		userNameList = append(userNameList, user.Name)
-		userUUIDList = append(userUUIDList, userUUID)
-		userPasswordList = append(userPasswordList, user.Password)
+		service.UpdateUsers(ids, userUUIDList, userPasswordList)
+		inbound.limiters.Update(limits)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	service.UpdateUsers(userList, userUUIDList, userPasswordList)
 	inbound.server = service
-	inbound.userNameList = userNameList
 	return inbound, nil
 }
 
@@ -114,7 +131,10 @@ func (h *Inbound) NewConnectionEx(ctx context.Context, conn net.Conn, source M.S
 	metadata.Destination = destination
 	h.logger.InfoContext(ctx, "inbound connection from ", metadata.Source)
 	userID, _ := auth.UserFromContext[int](ctx)
-	if userName := h.userNameList[userID]; userName != "" {
+	if limiter := h.limiters.Load(userID); limiter != nil {
+		conn = limiter.NewConn(ctx, conn)
+	}
+	if userName := h.users.Name(userID); userName != "" {
 		metadata.User = userName
 		h.logger.InfoContext(ctx, "[", userName, "] inbound connection to ", metadata.Destination)
 	} else {
@@ -137,7 +157,10 @@ func (h *Inbound) NewPacketConnectionEx(ctx context.Context, conn N.PacketConn,
 	metadata.Destination = destination
 	h.logger.InfoContext(ctx, "inbound packet connection from ", metadata.Source)
 	userID, _ := auth.UserFromContext[int](ctx)
-	if userName := h.userNameList[userID]; userName != "" {
+	if limiter := h.limiters.Load(userID); limiter != nil {
+		conn = limiter.NewPacketConn(ctx, conn)
+	}
+	if userName := h.users.Name(userID); userName != "" {
 		metadata.User = userName
 		h.logger.InfoContext(ctx, "[", userName, "] inbound packet connection to ", metadata.Destination)
 	} else {
@@ -160,7 +183,7 @@ func (h *Inbound) Start(stage adapter.StartStage) error {
 	if err != nil {
 		return err
 	}
-	return h.server.Start(packetConn)
+	return h.server.Start(quicmtu.NewPacketConn(packetConn, h.options.QUICMTUOptions))
 }
 
 func (h *Inbound) Close() error {
@@ -170,3 +193,17 @@ func (h *Inbound) Close() error {
 		common.PtrOrNil(h.server),
 	)
 }
+
+func (h *Inbound) InboundOptions() option.Inbound {
+	options := h.options
+	options.Users = h.users.Users()
+	return option.Inbound{Type: h.Type(), Tag: h.Tag(), Options: &options}
+}
+
+func (h *Inbound) SetUser(content []byte) (option.InboundUser, error) {
+	return h.users.SetUser(content)
+}
+
+func (h *Inbound) RemoveUser(name string) error {
+	return h.users.RemoveUser(name)
+}
@@ -3,26 +3,21 @@ package tuic
 import (
 	"context"
 	"net"
-	"os"
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/adapter/outbound"
+	"github.com/sagernet/sing-box/client/tuic"
 	"github.com/sagernet/sing-box/common/dialer"
 	"github.com/sagernet/sing-box/common/tls"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
-	"github.com/sagernet/sing-quic/tuic"
 	"github.com/sagernet/sing/common"
-	"github.com/sagernet/sing/common/bufio"
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/logger"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
-	"github.com/sagernet/sing/common/uot"
-
-	"github.com/gofrs/uuid/v5"
 )
 
 func RegisterOutbound(registry *outbound.Registry) {
@@ -33,9 +28,8 @@ var _ adapter.InterfaceUpdateListener = (*Outbound)(nil)
 
 type Outbound struct {
 	outbound.Adapter
-	logger    logger.ContextLogger
-	client    *tuic.Client
-	udpStream bool
+	logger logger.ContextLogger
+	client *tuic.Client
 }
 
 func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.TUICOutboundOptions) (adapter.Outbound, error) {
@@ -47,43 +41,29 @@ func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextL
 	if err != nil {
 		return nil, err
 	}
-	userUUID, err := uuid.FromString(options.UUID)
-	if err != nil {
-		return nil, E.Cause(err, "invalid uuid")
-	}
-	var tuicUDPStream bool
-	if options.UDPOverStream && options.UDPRelayMode != "" {
-		return nil, E.New("udp_over_stream is conflict with udp_relay_mode")
-	}
-	switch options.UDPRelayMode {
-	case "native":
-	case "quic":
-		tuicUDPStream = true
-	}
 	outboundDialer, err := dialer.New(ctx, options.DialerOptions, options.ServerIsDomain())
 	if err != nil {
 		return nil, err
 	}
-	client, err := tuic.NewClient(tuic.ClientOptions{
-		Context:           ctx,
+	client, err := tuic.NewClient(ctx, tuic.Options{
 		Dialer:            outboundDialer,
-		ServerAddress:     options.ServerOptions.Build(),
-		TLSConfig:         tlsConfig,
-		UUID:              userUUID,
+		Server:            options.ServerOptions.Build(),
+		UUID:              options.UUID,
 		Password:          options.Password,
 		CongestionControl: options.CongestionControl,
-		UDPStream:         tuicUDPStream,
+		UDPRelayMode:      options.UDPRelayMode,
+		UDPOverStream:     options.UDPOverStream,
 		ZeroRTTHandshake:  options.ZeroRTTHandshake,
 		Heartbeat:         time.Duration(options.Heartbeat),
+		TLSConfig:         tlsConfig,
 	})
 	if err != nil {
 		return nil, err
 	}
 	return &Outbound{
-		Adapter:   outbound.NewAdapterWithDialerOptions(C.TypeTUIC, tag, options.Network.Build(), options.DialerOptions),
-		logger:    logger,
-		client:    client,
-		udpStream: options.UDPOverStream,
+		Adapter: outbound.NewAdapterWithDialerOptions(C.TypeTUIC, tag, options.Network.Build(), options.DialerOptions),
+		logger:  logger,
+		client:  client,
 	}, nil
 }
 
@@ -91,45 +71,25 @@ func (h *Outbound) DialContext(ctx context.Context, network string, destination
 	switch N.NetworkName(network) {
 	case N.NetworkTCP:
 		h.logger.InfoContext(ctx, "outbound connection to ", destination)
-		return h.client.DialConn(ctx, destination)
 	case N.NetworkUDP:
-		if h.udpStream {
+		if h.client.UDPOverStream() {
 			h.logger.InfoContext(ctx, "outbound stream packet connection to ", destination)
-			streamConn, err := h.client.DialConn(ctx, uot.RequestDestination(uot.Version))
-			if err != nil {
-				return nil, err
-			}
-			return uot.NewLazyConn(streamConn, uot.Request{
-				IsConnect:   true,
-				Destination: destination,
-			}), nil
 		} else {
-			conn, err := h.ListenPacket(ctx, destination)
-			if err != nil {
-				return nil, err
-			}
-			return bufio.NewBindPacketConn(conn, destination), nil
+			h.logger.InfoContext(ctx, "outbound packet connection to ", destination)
 		}
 	default:
 		return nil, E.New("unsupported network: ", network)
 	}
+	return h.client.DialContext(ctx, network, destination)
 }
 
 func (h *Outbound) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
-	if h.udpStream {
+	if h.client.UDPOverStream() {
 		h.logger.InfoContext(ctx, "outbound stream packet connection to ", destination)
-		streamConn, err := h.client.DialConn(ctx, uot.RequestDestination(uot.Version))
-		if err != nil {
-			return nil, err
-		}
-		return uot.NewLazyConn(streamConn, uot.Request{
-			IsConnect:   false,
-			Destination: destination,
-		}), nil
 	} else {
 		h.logger.InfoContext(ctx, "outbound packet connection to ", destination)
-		return h.client.ListenPacket(ctx)
 	}
+	return h.client.ListenPacket(ctx, destination)
 }
 
 func (h *Outbound) InterfaceUpdated() {
@@ -137,5 +97,5 @@ func (h *Outbound) InterfaceUpdated() {
 }
 
 func (h *Outbound) Close() error {
-	return h.client.CloseWithError(os.ErrClosed)
+	return h.client.Close()
 }
@@ -8,6 +8,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
@@ -47,6 +48,7 @@ type Inbound struct {
 	tunOptions                  tun.Options
 	udpTimeout                  time.Duration
 	stack                       string
+	offload                     bool
 	tunIf                       tun.Tun
 	tunStack                    tun.Stack
 	platformInterface           platform.Interface
@@ -58,6 +60,9 @@ type Inbound struct {
 	routeExcludeRuleSetCallback []*list.Element[adapter.RuleSetUpdateCallback]
 	routeAddressSet             []*netipx.IPSet
 	routeExcludeAddressSet      []*netipx.IPSet
+	routeAccess                 sync.Mutex
+	routeOptions                option.TunRouteOptions
+	ttl                         uint8
 }
 
 func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.TunInboundOptions) (adapter.Inbound, error) {
@@ -133,9 +138,21 @@ func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLo
 
 	platformInterface := service.FromContext[platform.Interface](ctx)
 	tunMTU := options.MTU
-	enableGSO := C.IsLinux && options.Stack == "gvisor" && platformInterface == nil && tunMTU > 0 && tunMTU < 49152
+	if options.Offload {
+		if !C.IsLinux || platformInterface != nil {
+			return nil, E.New("`offload` is only supported on Linux")
+		}
+		if tunMTU >= 49152 {
+			return nil, E.New("`offload` requires `mtu` less than 49152")
+		}
+	}
+	enableGSO := C.IsLinux && platformInterface == nil && (options.Offload || options.Stack == "gvisor" && tunMTU > 0 && tunMTU < 49152)
 	if tunMTU == 0 {
-		if platformInterface != nil && platformInterface.UnderNetworkExtension() {
+		if options.Offload {
+			// Segmentation offloads pass TCP segments up to 64 KiB regardless of the MTU,
+			// while the MTU must stay below the offload size limit.
+			tunMTU = 9000
+		} else if platformInterface != nil && platformInterface.UnderNetworkExtension() {
 			// In Network Extension, when MTU exceeds 4064 (4096-UTUN_IF_HEADROOM_SIZE), the performance of tun will drop significantly, which may be a system bug.
 			tunMTU = 4064
 		} else if C.IsAndroid {
@@ -151,8 +168,8 @@ func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLo
 	} else {
 		udpTimeout = C.UDPTimeout
 	}
-	var err error
 	includeUID := uidToRange(options.IncludeUID)
+	var err error
 	if len(options.IncludeUIDRange) > 0 {
 		includeUID, err = parseRange(includeUID, options.IncludeUIDRange)
 		if err != nil {
@@ -167,13 +184,28 @@ func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLo
 		}
 	}
 
-	tableIndex := options.IPRoute2TableIndex
-	if tableIndex == 0 {
-		tableIndex = tun.DefaultIPRoute2TableIndex
+	var tunInbounds []*Inbound
+	for _, it := range service.FromContext[adapter.InboundManager](ctx).Inbounds() {
+		tunInbound, isTun := it.(*Inbound)
+		if isTun && tunInbound.tag != tag {
+			tunInbounds = append(tunInbounds, tunInbound)
+		}
 	}
-	ruleIndex := options.IPRoute2RuleIndex
-	if ruleIndex == 0 {
-		ruleIndex = tun.DefaultIPRoute2RuleIndex
+	if platformInterface != nil && len(tunInbounds) > 0 {
+		return nil, E.New("multiple tun inbounds are not supported on this platform")
+	}
+	if options.AutoRedirect && common.Any(tunInbounds, func(it *Inbound) bool {
+		return it.autoRedirect != nil
+	}) {
+		return nil, E.New("only one tun inbound with `auto_redirect` can be configured")
+	}
+	err = checkAddressOverlap(tunInbounds, address)
+	if err != nil {
+		return nil, err
+	}
+	tableIndex, ruleIndex, err := allocateRouteIndex(tunInbounds, options)
+	if err != nil {
+		return nil, err
 	}
 	inputMark := uint32(options.AutoRedirectInputMark)
 	if inputMark == 0 {
@@ -222,8 +254,22 @@ func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLo
 		},
 		udpTimeout:        udpTimeout,
 		stack:             options.Stack,
+		offload:           options.Offload,
 		platformInterface: platformInterface,
 		platformOptions:   common.PtrValueOrDefault(options.Platform),
+		ttl:               options.TTL,
+		routeOptions: option.TunRouteOptions{
+			RouteAddress:        routeAddress,
+			RouteExcludeAddress: routeExcludeAddress,
+			IncludeInterface:    options.IncludeInterface,
+			ExcludeInterface:    options.ExcludeInterface,
+			IncludeUID:          options.IncludeUID,
+			IncludeUIDRange:     options.IncludeUIDRange,
+			ExcludeUID:          options.ExcludeUID,
+			ExcludeUIDRange:     options.ExcludeUIDRange,
+			IncludePackage:      options.IncludePackage,
+			ExcludePackage:      options.ExcludePackage,
+		},
 	}
 	for _, routeAddressSet := range options.RouteAddressSet {
 		ruleSet, loaded := router.RuleSet(routeAddressSet)
@@ -270,6 +316,67 @@ func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLo
 	return inbound, nil
 }
 
+// allocateRouteIndex picks the iproute2 table and rule index not used by other tun inbounds
+// with auto_route, each inbound occupies rules from the start index to the start index plus 10.
+func checkAddressOverlap(tunInbounds []*Inbound, address []netip.Prefix) error {
+	for _, prefix := range address {
+		for _, tunInbound := range tunInbounds {
+			otherAddress := tunInbound.tunOptions.Inet4Address
+			if prefix.Addr().Is6() {
+				otherAddress = tunInbound.tunOptions.Inet6Address
+			}
+			if otherPrefix := common.Find(otherAddress, prefix.Overlaps); otherPrefix.IsValid() {
+				return E.New("address ", prefix, " overlaps with ", otherPrefix, " of inbound/tun[", tunInbound.tag, "]")
+			}
+		}
+	}
+	return nil
+}
+
+func allocateRouteIndex(tunInbounds []*Inbound, options option.TunInboundOptions) (tableIndex int, ruleIndex int, err error) {
+	tableIndex = options.IPRoute2TableIndex
+	ruleIndex = options.IPRoute2RuleIndex
+	if !options.AutoRoute {
+		if tableIndex == 0 {
+			tableIndex = tun.DefaultIPRoute2TableIndex
+		}
+		if ruleIndex == 0 {
+			ruleIndex = tun.DefaultIPRoute2RuleIndex
+		}
+		return
+	}
+	tunInbounds = common.Filter(tunInbounds, func(it *Inbound) bool {
+		return it.tunOptions.AutoRoute
+	})
+	tableUsed := func(index int) *Inbound {
+		return common.Find(tunInbounds, func(it *Inbound) bool {
+			return it.tunOptions.IPRoute2TableIndex == index
+		})
+	}
+	ruleUsed := func(index int) *Inbound {
+		return common.Find(tunInbounds, func(it *Inbound) bool {
+			return index <= it.tunOptions.IPRoute2RuleIndex+10 && it.tunOptions.IPRoute2RuleIndex <= index+10
+		})
+	}
+	if tableIndex == 0 {
+		tableIndex = tun.DefaultIPRoute2TableIndex
+		for tableUsed(tableIndex) != nil {
+			tableIndex++
+		}
+	} else if conflictInbound := tableUsed(tableIndex); conflictInbound != nil {
+		return 0, 0, E.New("iproute2_table_index ", tableIndex, " is already used by inbound/tun[", conflictInbound.tag, "]")
+	}
+	if ruleIndex == 0 {
+		ruleIndex = tun.DefaultIPRoute2RuleIndex
+		for ruleUsed(ruleIndex) != nil {
+			ruleIndex += 100
+		}
+	} else if conflictInbound := ruleUsed(ruleIndex); conflictInbound != nil {
+		return 0, 0, E.New("iproute2_rule_index ", ruleIndex, " overlaps with inbound/tun[", conflictInbound.tag, "]")
+	}
+	return
+}
+
 func uidToRange(uidList badoption.Listable[uint32]) []ranges.Range[uint32] {
 	return common.Map(uidList, func(uid uint32) ranges.Range[uint32] {
 		return ranges.NewSingle(uid)
@@ -352,24 +459,7 @@ func (t *Inbound) Start(stage adapter.StartStage) error {
 		monitor := taskmonitor.New(t.logger, C.StartTimeout)
 		tunOptions := t.tunOptions
 		if t.autoRedirect == nil && !(runtime.GOOS == "android" && t.platformInterface != nil) {
-			for _, ipSet := range t.routeAddressSet {
-				for _, prefix := range ipSet.Prefixes() {
-					if prefix.Addr().Is4() {
-						tunOptions.Inet4RouteAddress = append(tunOptions.Inet4RouteAddress, prefix)
-					} else {
-						tunOptions.Inet6RouteAddress = append(tunOptions.Inet6RouteAddress, prefix)
-					}
-				}
-			}
-			for _, ipSet := range t.routeExcludeAddressSet {
-				for _, prefix := range ipSet.Prefixes() {
-					if prefix.Addr().Is4() {
-						tunOptions.Inet4RouteExcludeAddress = append(tunOptions.Inet4RouteExcludeAddress, prefix)
-					} else {
-						tunOptions.Inet6RouteExcludeAddress = append(tunOptions.Inet6RouteExcludeAddress, prefix)
-					}
-				}
-			}
+			appendRouteAddressSet(&tunOptions, t.routeAddressSet, t.routeExcludeAddressSet)
 		}
 		monitor.Start("open interface")
 		if t.platformInterface != nil {
@@ -385,6 +475,13 @@ func (t *Inbound) Start(stage adapter.StartStage) error {
 		if err != nil {
 			return E.Cause(err, "configure tun interface")
 		}
+		if t.offload {
+			if linuxTUN, isLinuxTUN := tunInterface.(tun.LinuxTUN); isLinuxTUN && linuxTUN.BatchSize() > 1 {
+				t.logger.Debug("segmentation offload enabled")
+			} else {
+				t.logger.Warn("segmentation offload is not supported by the kernel, fallback to plain packets")
+			}
+		}
 		t.logger.Trace("creating stack")
 		t.tunIf = tunInterface
 		var (
@@ -439,6 +536,90 @@ func (t *Inbound) Start(stage adapter.StartStage) error {
 	return nil
 }
 
+func appendRouteAddressSet(tunOptions *tun.Options, routeAddressSet []*netipx.IPSet, routeExcludeAddressSet []*netipx.IPSet) {
+	for _, ipSet := range routeAddressSet {
+		for _, prefix := range ipSet.Prefixes() {
+			if prefix.Addr().Is4() {
+				tunOptions.Inet4RouteAddress = append(tunOptions.Inet4RouteAddress, prefix)
+			} else {
+				tunOptions.Inet6RouteAddress = append(tunOptions.Inet6RouteAddress, prefix)
+			}
+		}
+	}
+	for _, ipSet := range routeExcludeAddressSet {
+		for _, prefix := range ipSet.Prefixes() {
+			if prefix.Addr().Is4() {
+				tunOptions.Inet4RouteExcludeAddress = append(tunOptions.Inet4RouteExcludeAddress, prefix)
+			} else {
+				tunOptions.Inet6RouteExcludeAddress = append(tunOptions.Inet6RouteExcludeAddress, prefix)
+			}
+		}
+	}
+}
+
+func (t *Inbound) RouteOptions() option.TunRouteOptions {
+	t.routeAccess.Lock()
+	defer t.routeAccess.Unlock()
+	return t.routeOptions
+}
+
+// UpdateRouteOptions replaces route addresses of auto_route without recreating the
+// interface, and interface, UID and package lists with the platform interface.
+func (t *Inbound) UpdateRouteOptions(routeOptions option.TunRouteOptions) error {
+	t.routeAccess.Lock()
+	defer t.routeAccess.Unlock()
+	if !t.tunOptions.AutoRoute {
+		return E.New("`auto_route` is not enabled")
+	} else if t.autoRedirect != nil {
+		return E.New("updating route options is not supported with `auto_redirect`")
+	} else if t.tunIf == nil {
+		return E.New("tun interface is not started")
+	} else if t.platformInterface == nil && !t.routeOptions.EqualRules(routeOptions) {
+		// iproute2 rules, which match interfaces and UIDs, are only created when the interface is started
+		return E.New("updating interface, UID and package lists is only supported on graphical clients")
+	}
+	newOptions := t.tunOptions
+	newOptions.Inet4RouteAddress = common.Filter(routeOptions.RouteAddress, func(it netip.Prefix) bool {
+		return it.Addr().Is4()
+	})
+	newOptions.Inet6RouteAddress = common.Filter(routeOptions.RouteAddress, func(it netip.Prefix) bool {
+		return it.Addr().Is6()
+	})
+	newOptions.Inet4RouteExcludeAddress = common.Filter(routeOptions.RouteExcludeAddress, func(it netip.Prefix) bool {
+		return it.Addr().Is4()
+	})
+	newOptions.Inet6RouteExcludeAddress = common.Filter(routeOptions.RouteExcludeAddress, func(it netip.Prefix) bool {
+		return it.Addr().Is6()
+	})
+	var err error
+	if t.platformInterface != nil {
+		newOptions.IncludeInterface = routeOptions.IncludeInterface
+		newOptions.ExcludeInterface = routeOptions.ExcludeInterface
+		newOptions.IncludeUID, err = parseRange(uidToRange(routeOptions.IncludeUID), routeOptions.IncludeUIDRange)
+		if err != nil {
+			return E.Cause(err, "parse include_uid_range")
+		}
+		newOptions.ExcludeUID, err = parseRange(uidToRange(routeOptions.ExcludeUID), routeOptions.ExcludeUIDRange)
+		if err != nil {
+			return E.Cause(err, "parse exclude_uid_range")
+		}
+		newOptions.IncludePackage = routeOptions.IncludePackage
+		newOptions.ExcludePackage = routeOptions.ExcludePackage
+		err = t.platformInterface.UpdateTunRouteOptions(&newOptions, t.platformOptions)
+	} else {
+		tunOptions := newOptions
+		appendRouteAddressSet(&tunOptions, common.FlatMap(t.routeRuleSet, adapter.RuleSet.ExtractIPSet), common.FlatMap(t.routeExcludeRuleSet, adapter.RuleSet.ExtractIPSet))
+		err = t.tunIf.UpdateRouteOptions(tunOptions)
+	}
+	if err != nil {
+		return E.Cause(err, "update route options")
+	}
+	t.tunOptions = newOptions
+	t.routeOptions = routeOptions
+	t.logger.Info("route options updated")
+	return nil
+}
+
 func (t *Inbound) updateRouteAddressSet(it adapter.RuleSet) {
 	t.routeAddressSet = common.FlatMap(t.routeRuleSet, adapter.RuleSet.ExtractIPSet)
 	t.routeExcludeAddressSet = common.FlatMap(t.routeExcludeRuleSet, adapter.RuleSet.ExtractIPSet)
@@ -470,6 +651,7 @@ func (t *Inbound) PrepareConnection(network string, source M.Socksaddr, destinat
 		Source:         source,
 		Destination:    destination,
 		InboundOptions: t.inboundOptions,
+		TTL:            t.ttl,
 	}, routeContext, timeout)
 	if err != nil {
 		if !rule.IsRejected(err) {
@@ -488,6 +670,7 @@ func (t *Inbound) NewConnectionEx(ctx context.Context, conn net.Conn, source M.S
 	metadata.Destination = destination
 	//nolint:staticcheck
 	metadata.InboundOptions = t.inboundOptions
+	metadata.TTL = t.ttl
 	t.logger.InfoContext(ctx, "inbound connection from ", metadata.Source)
 	t.logger.InfoContext(ctx, "inbound connection to ", metadata.Destination)
 	t.router.RouteConnectionEx(ctx, conn, metadata, onClose)
@@ -502,6 +685,7 @@ func (t *Inbound) NewPacketConnectionEx(ctx context.Context, conn N.PacketConn,
 	metadata.Destination = destination
 	//nolint:staticcheck
 	metadata.InboundOptions = t.inboundOptions
+	metadata.TTL = t.ttl
 	t.logger.InfoContext(ctx, "inbound packet connection from ", metadata.Source)
 	t.logger.InfoContext(ctx, "inbound packet connection to ", metadata.Destination)
 	t.router.RoutePacketConnectionEx(ctx, conn, metadata, onClose)
@@ -518,6 +702,7 @@ func (t *autoRedirectHandler) NewConnectionEx(ctx context.Context, conn net.Conn
 	metadata.Destination = destination
 	//nolint:staticcheck
 	metadata.InboundOptions = t.inboundOptions
+	metadata.TTL = t.ttl
 	t.logger.InfoContext(ctx, "inbound redirect connection from ", metadata.Source)
 	t.logger.InfoContext(ctx, "inbound connection to ", metadata.Destination)
 	t.router.RouteConnectionEx(ctx, conn, metadata, onClose)
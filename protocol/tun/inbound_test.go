@@ -0,0 +1,146 @@
+package tun
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-tun"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testRouteInbound(tag string, autoRoute bool, tableIndex int, ruleIndex int) *Inbound {
+	return &Inbound{
+		tag: tag,
+		tunOptions: tun.Options{
+			AutoRoute:          autoRoute,
+			IPRoute2TableIndex: tableIndex,
+			IPRoute2RuleIndex:  ruleIndex,
+		},
+	}
+}
+
+func TestAllocateRouteIndex(t *testing.T) {
+	t.Parallel()
+	first := testRouteInbound("first", true, tun.DefaultIPRoute2TableIndex, tun.DefaultIPRoute2RuleIndex)
+	second := testRouteInbound("second", true, tun.DefaultIPRoute2TableIndex+1, tun.DefaultIPRoute2RuleIndex+100)
+	manual := testRouteInbound("manual", false, tun.DefaultIPRoute2TableIndex, tun.DefaultIPRoute2RuleIndex)
+	for _, testCase := range []struct {
+		name       string
+		inbounds   []*Inbound
+		options    option.TunInboundOptions
+		tableIndex int
+		ruleIndex  int
+		err        bool
+	}{
+		{
+			name:       "default",
+			options:    option.TunInboundOptions{AutoRoute: true},
+			tableIndex: tun.DefaultIPRoute2TableIndex,
+			ruleIndex:  tun.DefaultIPRoute2RuleIndex,
+		},
+		{
+			name:       "default without auto_route",
+			inbounds:   []*Inbound{first},
+			tableIndex: tun.DefaultIPRoute2TableIndex,
+			ruleIndex:  tun.DefaultIPRoute2RuleIndex,
+		},
+		{
+			name:       "next free index",
+			inbounds:   []*Inbound{first},
+			options:    option.TunInboundOptions{AutoRoute: true},
+			tableIndex: tun.DefaultIPRoute2TableIndex + 1,
+			ruleIndex:  tun.DefaultIPRoute2RuleIndex + 100,
+		},
+		{
+			name:       "skip used indexes",
+			inbounds:   []*Inbound{first, second},
+			options:    option.TunInboundOptions{AutoRoute: true},
+			tableIndex: tun.DefaultIPRoute2TableIndex + 2,
+			ruleIndex:  tun.DefaultIPRoute2RuleIndex + 200,
+		},
+		{
+			name:       "ignore inbounds without auto_route",
+			inbounds:   []*Inbound{manual},
+			options:    option.TunInboundOptions{AutoRoute: true},
+			tableIndex: tun.DefaultIPRoute2TableIndex,
+			ruleIndex:  tun.DefaultIPRoute2RuleIndex,
+		},
+		{
+			name:       "explicit indexes",
+			inbounds:   []*Inbound{first},
+			options:    option.TunInboundOptions{AutoRoute: true, IPRoute2TableIndex: 100, IPRoute2RuleIndex: tun.DefaultIPRoute2RuleIndex + 11},
+			tableIndex: 100,
+			ruleIndex:  tun.DefaultIPRoute2RuleIndex + 11,
+		},
+		{
+			name:     "explicit table index conflict",
+			inbounds: []*Inbound{first},
+			options:  option.TunInboundOptions{AutoRoute: true, IPRoute2TableIndex: tun.DefaultIPRoute2TableIndex},
+			err:      true,
+		},
+		{
+			name:     "explicit rule index conflict",
+			inbounds: []*Inbound{first},
+			options:  option.TunInboundOptions{AutoRoute: true, IPRoute2RuleIndex: tun.DefaultIPRoute2RuleIndex},
+			err:      true,
+		},
+		{
+			name:     "rule window overlap above",
+			inbounds: []*Inbound{first},
+			options:  option.TunInboundOptions{AutoRoute: true, IPRoute2RuleIndex: tun.DefaultIPRoute2RuleIndex + 10},
+			err:      true,
+		},
+		{
+			name:     "rule window overlap below",
+			inbounds: []*Inbound{first},
+			options:  option.TunInboundOptions{AutoRoute: true, IPRoute2RuleIndex: tun.DefaultIPRoute2RuleIndex - 10},
+			err:      true,
+		},
+		{
+			name:       "rule window below",
+			inbounds:   []*Inbound{first},
+			options:    option.TunInboundOptions{AutoRoute: true, IPRoute2RuleIndex: tun.DefaultIPRoute2RuleIndex - 11},
+			tableIndex: tun.DefaultIPRoute2TableIndex + 1,
+			ruleIndex:  tun.DefaultIPRoute2RuleIndex - 11,
+		},
+	} {
+		tableIndex, ruleIndex, err := allocateRouteIndex(testCase.inbounds, testCase.options)
+		if testCase.err {
+			require.Error(t, err, testCase.name)
+			continue
+		}
+		require.NoError(t, err, testCase.name)
+		require.Equal(t, testCase.tableIndex, tableIndex, testCase.name)
+		require.Equal(t, testCase.ruleIndex, ruleIndex, testCase.name)
+	}
+}
+
+func TestCheckAddressOverlap(t *testing.T) {
+	t.Parallel()
+	inbounds := []*Inbound{{
+		tag: "first",
+		tunOptions: tun.Options{
+			Inet4Address: []netip.Prefix{netip.MustParsePrefix("172.18.0.1/30")},
+			Inet6Address: []netip.Prefix{netip.MustParsePrefix("fdfe:dcba:9876::1/126")},
+		},
+	}}
+	for _, testCase := range []struct {
+		address string
+		err     bool
+	}{
+		{"172.19.0.1/30", false},
+		{"fdfe:dcba:9877::1/126", false},
+		{"172.18.0.2/32", true},
+		{"172.16.0.1/12", true},
+		{"fdfe:dcba:9876::2/128", true},
+	} {
+		err := checkAddressOverlap(inbounds, []netip.Prefix{netip.MustParsePrefix(testCase.address)})
+		if testCase.err {
+			require.Error(t, err, testCase.address)
+		} else {
+			require.NoError(t, err, testCase.address)
+		}
+	}
+}
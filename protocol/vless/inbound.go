@@ -2,11 +2,14 @@ package vless
 
 import (
 	"context"
+	"io"
 	"net"
 	"os"
+	"sync/atomic"
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/adapter/inbound"
+	"github.com/sagernet/sing-box/common/fallback"
 	"github.com/sagernet/sing-box/common/listener"
 	"github.com/sagernet/sing-box/common/mux"
 	"github.com/sagernet/sing-box/common/tls"
@@ -19,19 +22,25 @@ import (
 	"github.com/sagernet/sing-vmess/vless"
 	"github.com/sagernet/sing/common"
 	"github.com/sagernet/sing/common/auth"
+	"github.com/sagernet/sing/common/buf"
 	"github.com/sagernet/sing/common/bufio"
 	E "github.com/sagernet/sing/common/exceptions"
 	F "github.com/sagernet/sing/common/format"
 	"github.com/sagernet/sing/common/logger"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
+
+	"github.com/gofrs/uuid/v5"
 )
 
 func RegisterInbound(registry *inbound.Registry) {
 	inbound.Register[option.VLESSInboundOptions](registry, C.TypeVLESS, NewInbound)
 }
 
-var _ adapter.TCPInjectableInbound = (*Inbound)(nil)
+var (
+	_ adapter.TCPInjectableInbound = (*Inbound)(nil)
+	_ adapter.ManagedUserInbound   = (*Inbound)(nil)
+)
 
 type Inbound struct {
 	inbound.Adapter
@@ -39,10 +48,15 @@ type Inbound struct {
 	router    adapter.ConnectionRouterEx
 	logger    logger.ContextLogger
 	listener  *listener.Listener
-	users     []option.VLESSUser
+	users     *inbound.UserList[option.VLESSUser]
+	options   option.VLESSInboundOptions
 	service   *vless.Service[int]
 	tlsConfig tls.ServerConfig
 	transport adapter.V2RayServerTransport
+
+	fallbackAddr   M.Socksaddr
+	fallbackServer *fallback.Server
+	userIDs        atomic.Pointer[map[[16]byte]struct{}]
 }
 
 func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.VLESSInboundOptions) (adapter.Inbound, error) {
@@ -51,7 +65,8 @@ func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLo
 		ctx:     ctx,
 		router:  uot.NewRouter(router, logger),
 		logger:  logger,
-		users:   options.Users,
+		users:   inbound.NewUserList(option.VLESSUser.InboundUser),
+		options: options,
 	}
 	var err error
 	inbound.router, err = mux.NewRouterWithOptions(inbound.router, logger, common.PtrValueOrDefault(options.Multiplex))
@@ -59,24 +74,45 @@ func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLo
 		return nil, err
 	}
 	service := vless.NewService[int](logger, adapter.NewUpstreamContextHandlerEx(inbound.newConnectionEx, inbound.newPacketConnectionEx))
-	service.UpdateUsers(common.MapIndexed(inbound.users, func(index int, _ option.VLESSUser) int {
-		return index
-	}), common.Map(inbound.users, func(it option.VLESSUser) string {
-		return it.UUID
-	}), common.Map(inbound.users, func(it option.VLESSUser) string {
-		return it.Flow
-	}))
+	kTLSCompatible := common.PtrValueOrDefault(options.Transport).Type == "" &&
+		!common.PtrValueOrDefault(options.Multiplex).Enabled &&
+		common.All(options.Users, func(it option.VLESSUser) bool {
+			return it.Flow == ""
+		})
+	tlsOptions := common.PtrValueOrDefault(options.TLS)
+	kTLSEnabled := kTLSCompatible && (tlsOptions.KernelTx || tlsOptions.KernelRx)
+	err = inbound.users.Initialize(options.Users, func(ids []int, users []option.VLESSUser) error {
+		if kTLSEnabled && common.Any(users, func(it option.VLESSUser) bool {
+			return it.Flow != ""
+		}) {
+			return E.New("flow is not supported by inbounds started with kTLS")
+		}
+		userIDs := make(map[[16]byte]struct{}, len(users))
+		for _, user := range users {
+			userID, err := uuid.FromString(user.UUID)
+			if err != nil {
+				userID = uuid.NewV5(uuid.Nil, user.UUID)
+			}
+			userIDs[userID] = struct{}{}
+		}
+		inbound.userIDs.Store(&userIDs)
+		service.UpdateUsers(ids, common.Map(users, func(it option.VLESSUser) string {
+			return it.UUID
+		}), common.Map(users, func(it option.VLESSUser) string {
+			return it.Flow
+		}))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 	inbound.service = service
 	if options.TLS != nil {
 		inbound.tlsConfig, err = tls.NewServerWithOptions(tls.ServerOptions{
-			Context: ctx,
-			Logger:  logger,
-			Options: common.PtrValueOrDefault(options.TLS),
-			KTLSCompatible: common.PtrValueOrDefault(options.Transport).Type == "" &&
-				!common.PtrValueOrDefault(options.Multiplex).Enabled &&
-				common.All(options.Users, func(it option.VLESSUser) bool {
-					return it.Flow == ""
-				}),
+			Context:        ctx,
+			Logger:         logger,
+			Options:        tlsOptions,
+			KTLSCompatible: kTLSCompatible,
 		})
 		if err != nil {
 			return nil, err
@@ -88,9 +124,24 @@ func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLo
 			return nil, E.Cause(err, "create server transport: ", options.Transport.Type)
 		}
 	}
+	if options.Fallback != nil && options.Fallback.Server != "" {
+		if options.FallbackHTTP != nil {
+			return nil, E.New("`fallback` is conflict with `fallback_http`")
+		}
+		inbound.fallbackAddr = options.Fallback.Build()
+		if !inbound.fallbackAddr.IsValid() {
+			return nil, E.New("invalid fallback address: ", inbound.fallbackAddr)
+		}
+	} else if options.FallbackHTTP != nil {
+		inbound.fallbackServer, err = fallback.NewServer(ctx, logger, *options.FallbackHTTP)
+		if err != nil {
+			return nil, E.Cause(err, "create fallback HTTP server")
+		}
+	}
 	inbound.listener = listener.New(listener.Options{
 		Context:           ctx,
 		Logger:            logger,
+		Tag:               tag,
 		Network:           []string{N.NetworkTCP},
 		Listen:            options.ListenOptions,
 		ConnectionHandler: inbound,
@@ -108,6 +159,12 @@ func (h *Inbound) Start(stage adapter.StartStage) error {
 			return err
 		}
 	}
+	if h.fallbackServer != nil {
+		err := h.fallbackServer.Start()
+		if err != nil {
+			return err
+		}
+	}
 	if h.transport == nil {
 		return h.listener.Start()
 	}
@@ -144,9 +201,24 @@ func (h *Inbound) Close() error {
 		h.listener,
 		h.tlsConfig,
 		h.transport,
+		common.PtrOrNil(h.fallbackServer),
 	)
 }
 
+func (h *Inbound) InboundOptions() option.Inbound {
+	options := h.options
+	options.Users = h.users.Users()
+	return option.Inbound{Type: h.Type(), Tag: h.Tag(), Options: &options}
+}
+
+func (h *Inbound) SetUser(content []byte) (option.InboundUser, error) {
+	return h.users.SetUser(content)
+}
+
+func (h *Inbound) RemoveUser(name string) error {
+	return h.users.RemoveUser(name)
+}
+
 func (h *Inbound) NewConnectionEx(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
 	if h.tlsConfig != nil && h.transport == nil {
 		tlsConn, err := tls.ServerHandshake(ctx, conn, h.tlsConfig)
@@ -157,6 +229,23 @@ func (h *Inbound) NewConnectionEx(ctx context.Context, conn net.Conn, metadata a
 		}
 		conn = tlsConn
 	}
+	if h.fallbackAddr.IsValid() || h.fallbackServer != nil {
+		var (
+			fallbackErr error
+			cached      bool
+		)
+		conn, cached, fallbackErr = h.readRequestForFallback(conn)
+		if fallbackErr != nil {
+			if !cached {
+				N.CloseOnHandshakeFailure(conn, onClose, fallbackErr)
+				h.logger.ErrorContext(ctx, E.Cause(fallbackErr, "process connection from ", metadata.Source))
+				return
+			}
+			h.logger.DebugContext(ctx, E.Cause(fallbackErr, "process connection from ", metadata.Source))
+			h.fallbackConnection(ctx, conn, metadata, onClose)
+			return
+		}
+	}
 	err := h.service.NewConnection(adapter.WithContext(ctx, &metadata), conn, metadata.Source, onClose)
 	if err != nil {
 		N.CloseOnHandshakeFailure(conn, onClose, err)
@@ -167,14 +256,14 @@ func (h *Inbound) NewConnectionEx(ctx context.Context, conn net.Conn, metadata a
 func (h *Inbound) newConnectionEx(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
 	metadata.Inbound = h.Tag()
 	metadata.InboundType = h.Type()
-	userIndex, loaded := auth.UserFromContext[int](ctx)
+	userID, loaded := auth.UserFromContext[int](ctx)
 	if !loaded {
 		N.CloseOnHandshakeFailure(conn, onClose, os.ErrInvalid)
 		return
 	}
-	user := h.users[userIndex].Name
+	user := h.users.Name(userID)
 	if user == "" {
-		user = F.ToString(userIndex)
+		user = F.ToString(userID)
 	} else {
 		metadata.User = user
 	}
@@ -185,14 +274,14 @@ func (h *Inbound) newConnectionEx(ctx context.Context, conn net.Conn, metadata a
 func (h *Inbound) newPacketConnectionEx(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
 	metadata.Inbound = h.Tag()
 	metadata.InboundType = h.Type()
-	userIndex, loaded := auth.UserFromContext[int](ctx)
+	userID, loaded := auth.UserFromContext[int](ctx)
 	if !loaded {
 		N.CloseOnHandshakeFailure(conn, onClose, os.ErrInvalid)
 		return
 	}
-	user := h.users[userIndex].Name
+	user := h.users.Name(userID)
 	if user == "" {
-		user = F.ToString(userIndex)
+		user = F.ToString(userID)
 	} else {
 		metadata.User = user
 	}
@@ -206,6 +295,42 @@ func (h *Inbound) newPacketConnectionEx(ctx context.Context, conn N.PacketConn,
 	h.router.RoutePacketConnectionEx(ctx, conn, metadata, onClose)
 }
 
+// readRequestForFallback reads the request to check the user, and returns the
+// connection with the request cached. The connection should fall back if the
+// error is not nil, or be closed if nothing was cached.
+func (h *Inbound) readRequestForFallback(conn net.Conn) (net.Conn, bool, error) {
+	cache := buf.New()
+	request, err := vless.ReadRequest(io.TeeReader(conn, cache))
+	if cache.IsEmpty() {
+		cache.Release()
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		return conn, false, err
+	}
+	conn = bufio.NewCachedConn(conn, cache)
+	if err != nil {
+		return conn, true, err
+	}
+	if _, loaded := (*h.userIDs.Load())[request.UUID]; !loaded {
+		return conn, true, E.New("unknown UUID: ", uuid.FromBytesOrNil(request.UUID[:]))
+	}
+	return conn, true, nil
+}
+
+func (h *Inbound) fallbackConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
+	if h.fallbackServer != nil {
+		h.logger.InfoContext(ctx, "fallback connection from ", metadata.Source, " to built-in HTTP server")
+		h.fallbackServer.NewConnectionEx(ctx, conn, metadata.Source, onClose)
+		return
+	}
+	metadata.Inbound = h.Tag()
+	metadata.InboundType = h.Type()
+	metadata.Destination = h.fallbackAddr
+	h.logger.InfoContext(ctx, "fallback connection to ", h.fallbackAddr)
+	h.router.RouteConnectionEx(ctx, conn, metadata, onClose)
+}
+
 var _ adapter.V2RayServerTransportHandler = (*inboundTransportHandler)(nil)
 
 type inboundTransportHandler Inbound
@@ -6,6 +6,7 @@ import (
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/adapter/outbound"
+	"github.com/sagernet/sing-box/client/vless"
 	"github.com/sagernet/sing-box/common/dialer"
 	"github.com/sagernet/sing-box/common/mux"
 	"github.com/sagernet/sing-box/common/tls"
@@ -13,10 +14,7 @@ import (
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing-box/transport/v2ray"
-	"github.com/sagernet/sing-vmess/packetaddr"
-	"github.com/sagernet/sing-vmess/vless"
 	"github.com/sagernet/sing/common"
-	"github.com/sagernet/sing/common/bufio"
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/logger"
 	M "github.com/sagernet/sing/common/metadata"
@@ -33,12 +31,10 @@ type Outbound struct {
 	dialer          N.Dialer
 	client          *vless.Client
 	serverAddr      M.Socksaddr
-	multiplexDialer *mux.Client
+	multiplexDialer *mux.OutboundClient
 	tlsConfig       tls.Config
 	tlsDialer       tls.Dialer
 	transport       adapter.V2RayClientTransport
-	packetAddr      bool
-	xudp            bool
 }
 
 func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.VLESSOutboundOptions) (adapter.Outbound, error) {
@@ -67,30 +63,34 @@ func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextL
 		}
 		outbound.tlsDialer = tls.NewDialer(outboundDialer, outbound.tlsConfig)
 	}
+	transportOptions, err := v2ray.FrontedTransportOptions(logger, options.TLS, options.Transport)
+	if err != nil {
+		return nil, err
+	}
 	if options.Transport != nil {
-		outbound.transport, err = v2ray.NewClientTransport(ctx, outbound.dialer, outbound.serverAddr, common.PtrValueOrDefault(options.Transport), outbound.tlsConfig)
+		outbound.transport, err = v2ray.NewClientTransport(ctx, outbound.dialer, outbound.serverAddr, transportOptions, outbound.tlsConfig)
 		if err != nil {
 			return nil, E.Cause(err, "create client transport: ", options.Transport.Type)
 		}
 	}
-	if options.PacketEncoding == nil {
-		outbound.xudp = true
-	} else {
-		switch *options.PacketEncoding {
-		case "":
-		case "packetaddr":
-			outbound.packetAddr = true
-		case "xudp":
-			outbound.xudp = true
-		default:
-			return nil, E.New("unknown packet encoding: ", options.PacketEncoding)
+	packetEncoding := vless.PacketEncodingXUDP
+	if options.PacketEncoding != nil {
+		packetEncoding = *options.PacketEncoding
+		if packetEncoding == "" {
+			packetEncoding = vless.PacketEncodingNone
 		}
 	}
-	outbound.client, err = vless.NewClient(options.UUID, options.Flow, logger)
+	outbound.client, err = vless.NewClient(vless.Options{
+		Logger:         logger,
+		Server:         outbound.serverAddr,
+		UUID:           options.UUID,
+		Flow:           options.Flow,
+		PacketEncoding: packetEncoding,
+	})
 	if err != nil {
 		return nil, err
 	}
-	outbound.multiplexDialer, err = mux.NewClientWithOptions((*vlessDialer)(outbound), logger, common.PtrValueOrDefault(options.Multiplex))
+	outbound.multiplexDialer, err = mux.NewOutboundClient((*vlessDialer)(outbound), logger, common.PtrValueOrDefault(options.Multiplex))
 	if err != nil {
 		return nil, err
 	}
@@ -98,7 +98,11 @@ func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextL
 }
 
 func (h *Outbound) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
-	if h.multiplexDialer == nil {
+	multiplexDialer, err := h.multiplexDialer.Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if multiplexDialer == nil {
 		switch N.NetworkName(network) {
 		case N.NetworkTCP:
 			h.logger.InfoContext(ctx, "outbound connection to ", destination)
@@ -113,17 +117,21 @@ func (h *Outbound) DialContext(ctx context.Context, network string, destination
 		case N.NetworkUDP:
 			h.logger.InfoContext(ctx, "outbound multiplex packet connection to ", destination)
 		}
-		return h.multiplexDialer.DialContext(ctx, network, destination)
+		return multiplexDialer.DialContext(ctx, network, destination)
 	}
 }
 
 func (h *Outbound) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
-	if h.multiplexDialer == nil {
+	multiplexDialer, err := h.multiplexDialer.Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if multiplexDialer == nil {
 		h.logger.InfoContext(ctx, "outbound packet connection to ", destination)
 		return (*vlessDialer)(h).ListenPacket(ctx, destination)
 	} else {
 		h.logger.InfoContext(ctx, "outbound multiplex packet connection to ", destination)
-		return h.multiplexDialer.ListenPacket(ctx, destination)
+		return multiplexDialer.ListenPacket(ctx, destination)
 	}
 }
 
@@ -131,13 +139,11 @@ func (h *Outbound) InterfaceUpdated() {
 	if h.transport != nil {
 		h.transport.Close()
 	}
-	if h.multiplexDialer != nil {
-		h.multiplexDialer.Reset()
-	}
+	h.multiplexDialer.Reset()
 }
 
 func (h *Outbound) Close() error {
-	return common.Close(common.PtrOrNil(h.multiplexDialer), h.transport)
+	return common.Close(h.multiplexDialer, h.transport)
 }
 
 type vlessDialer Outbound
@@ -161,26 +167,10 @@ func (h *vlessDialer) DialContext(ctx context.Context, network string, destinati
 	switch N.NetworkName(network) {
 	case N.NetworkTCP:
 		h.logger.InfoContext(ctx, "outbound connection to ", destination)
-		return h.client.DialEarlyConn(conn, destination)
 	case N.NetworkUDP:
 		h.logger.InfoContext(ctx, "outbound packet connection to ", destination)
-		if h.xudp {
-			return h.client.DialEarlyXUDPPacketConn(conn, destination)
-		} else if h.packetAddr {
-			if destination.IsFqdn() {
-				return nil, E.New("packetaddr: domain destination is not supported")
-			}
-			packetConn, err := h.client.DialEarlyPacketConn(conn, M.Socksaddr{Fqdn: packetaddr.SeqPacketMagicAddress})
-			if err != nil {
-				return nil, err
-			}
-			return bufio.NewBindPacketConn(packetaddr.NewConn(packetConn, destination), destination), nil
-		} else {
-			return h.client.DialEarlyPacketConn(conn, destination)
-		}
-	default:
-		return nil, E.Extend(N.ErrUnknownNetwork, network)
 	}
+	return h.client.DialConn(conn, network, destination)
 }
 
 func (h *vlessDialer) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
@@ -201,18 +191,5 @@ func (h *vlessDialer) ListenPacket(ctx context.Context, destination M.Socksaddr)
 		common.Close(conn)
 		return nil, err
 	}
-	if h.xudp {
-		return h.client.DialEarlyXUDPPacketConn(conn, destination)
-	} else if h.packetAddr {
-		if destination.IsFqdn() {
-			return nil, E.New("packetaddr: domain destination is not supported")
-		}
-		conn, err := h.client.DialEarlyPacketConn(conn, M.Socksaddr{Fqdn: packetaddr.SeqPacketMagicAddress})
-		if err != nil {
-			return nil, err
-		}
-		return packetaddr.NewConn(conn, destination), nil
-	} else {
-		return h.client.DialEarlyPacketConn(conn, destination)
-	}
+	return h.client.DialPacketConn(conn, destination)
 }
@@ -2,13 +2,18 @@ package vmess
 
 import (
 	"context"
+	"crypto/cipher"
+	"errors"
 	"net"
 	"os"
+	"sync/atomic"
+	"time"
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/adapter/inbound"
 	"github.com/sagernet/sing-box/common/listener"
 	"github.com/sagernet/sing-box/common/mux"
+	"github.com/sagernet/sing-box/common/replaycache"
 	"github.com/sagernet/sing-box/common/tls"
 	"github.com/sagernet/sing-box/common/uot"
 	C "github.com/sagernet/sing-box/constant"
@@ -32,7 +37,10 @@ func RegisterInbound(registry *inbound.Registry) {
 	inbound.Register[option.VMessInboundOptions](registry, C.TypeVMess, NewInbound)
 }
 
-var _ adapter.TCPInjectableInbound = (*Inbound)(nil)
+var (
+	_ adapter.TCPInjectableInbound = (*Inbound)(nil)
+	_ adapter.ManagedUserInbound   = (*Inbound)(nil)
+)
 
 type Inbound struct {
 	inbound.Adapter
@@ -41,9 +49,15 @@ type Inbound struct {
 	logger    logger.ContextLogger
 	listener  *listener.Listener
 	service   *vmess.Service[int]
-	users     []option.VMessUser
+	users     *inbound.UserList[option.VMessUser]
+	options   option.VMessInboundOptions
 	tlsConfig tls.ServerConfig
 	transport adapter.V2RayServerTransport
+
+	timeFunc          func() time.Time
+	maxTimeDifference time.Duration
+	authIDCiphers     atomic.Pointer[map[int]cipher.Block]
+	replayCache       replaycache.Cache
 }
 
 func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.VMessInboundOptions) (adapter.Inbound, error) {
@@ -52,8 +66,13 @@ func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLo
 		ctx:     ctx,
 		router:  uot.NewRouter(router, logger),
 		logger:  logger,
-		users:   options.Users,
+		users:   inbound.NewUserList(option.VMessUser.InboundUser),
+		options: options,
 	}
+	if options.MaxTimeDifference < 0 || time.Duration(options.MaxTimeDifference) > maxTimeDifference {
+		return nil, E.New("max_time_difference must be between 0 and ", maxTimeDifference)
+	}
+	inbound.maxTimeDifference = time.Duration(options.MaxTimeDifference)
 	var err error
 	inbound.router, err = mux.NewRouterWithOptions(inbound.router, logger, common.PtrValueOrDefault(options.Multiplex))
 	if err != nil {
@@ -62,22 +81,40 @@ func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLo
 	var serviceOptions []vmess.ServiceOption
 	if timeFunc := ntp.TimeFuncFromContext(ctx); timeFunc != nil {
 		serviceOptions = append(serviceOptions, vmess.ServiceWithTimeFunc(timeFunc))
+		inbound.timeFunc = timeFunc
+	} else {
+		inbound.timeFunc = time.Now
 	}
 	if options.Transport != nil && options.Transport.Type != "" {
 		serviceOptions = append(serviceOptions, vmess.ServiceWithDisableHeaderProtection())
 	}
 	service := vmess.NewService[int](adapter.NewUpstreamContextHandlerEx(inbound.newConnectionEx, inbound.newPacketConnectionEx), serviceOptions...)
 	inbound.service = service
-	err = service.UpdateUsers(common.MapIndexed(options.Users, func(index int, it option.VMessUser) int {
-		return index
-	}), common.Map(options.Users, func(it option.VMessUser) string {
-		return it.UUID
-	}), common.Map(options.Users, func(it option.VMessUser) int {
-		return it.AlterId
-	}))
+	err = inbound.users.Initialize(options.Users, func(ids []int, users []option.VMessUser) error {
+		authIDCiphers, err := newAuthIDCiphers(ids, users)
+		if err != nil {
+			return err
+		}
+		err = service.UpdateUsers(ids, common.Map(users, func(it option.VMessUser) string {
+			return it.UUID
+		}), common.Map(users, func(it option.VMessUser) int {
+			return it.AlterId
+		}))
+		if err != nil {
+			return err
+		}
+		inbound.authIDCiphers.Store(&authIDCiphers)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	if options.ReplayProtection != nil {
+		inbound.replayCache, err = replaycache.New(ctx, logger, *options.ReplayProtection, maxTimeDifference)
+		if err != nil {
+			return nil, E.Cause(err, "create replay protection")
+		}
+	}
 	if options.TLS != nil {
 		inbound.tlsConfig, err = tls.NewServer(ctx, logger, common.PtrValueOrDefault(options.TLS))
 		if err != nil {
@@ -93,6 +130,7 @@ func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLo
 	inbound.listener = listener.New(listener.Options{
 		Context:           ctx,
 		Logger:            logger,
+		Tag:               tag,
 		Network:           []string{N.NetworkTCP},
 		Listen:            options.ListenOptions,
 		ConnectionHandler: inbound,
@@ -108,6 +146,12 @@ func (h *Inbound) Start(stage adapter.StartStage) error {
 	if err != nil {
 		return err
 	}
+	if h.replayCache != nil {
+		err = h.replayCache.Start()
+		if err != nil {
+			return err
+		}
+	}
 	if h.tlsConfig != nil {
 		err = h.tlsConfig.Start()
 		if err != nil {
@@ -150,9 +194,24 @@ func (h *Inbound) Close() error {
 		h.listener,
 		h.tlsConfig,
 		h.transport,
+		h.replayCache,
 	)
 }
 
+func (h *Inbound) InboundOptions() option.Inbound {
+	options := h.options
+	options.Users = h.users.Users()
+	return option.Inbound{Type: h.Type(), Tag: h.Tag(), Options: &options}
+}
+
+func (h *Inbound) SetUser(content []byte) (option.InboundUser, error) {
+	return h.users.SetUser(content)
+}
+
+func (h *Inbound) RemoveUser(name string) error {
+	return h.users.RemoveUser(name)
+}
+
 func (h *Inbound) NewConnectionEx(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
 	if h.tlsConfig != nil && h.transport == nil {
 		tlsConn, err := tls.ServerHandshake(ctx, conn, h.tlsConfig)
@@ -163,8 +222,15 @@ func (h *Inbound) NewConnectionEx(ctx context.Context, conn net.Conn, metadata a
 		}
 		conn = tlsConn
 	}
-	err := h.service.NewConnection(adapter.WithContext(ctx, &metadata), conn, metadata.Source, onClose)
+	authConn := &authIDConn{Conn: conn}
+	if h.maxTimeDifference > 0 || h.replayCache != nil {
+		ctx = contextWithAuthID(ctx, authConn)
+	}
+	err := h.service.NewConnection(adapter.WithContext(ctx, &metadata), authConn, metadata.Source, onClose)
 	if err != nil {
+		if errors.Is(err, vmess.ErrBadTimestamp) {
+			err = h.badTimestampError(authConn)
+		}
 		N.CloseOnHandshakeFailure(conn, onClose, err)
 		h.logger.ErrorContext(ctx, E.Cause(err, "process connection from ", metadata.Source))
 	}
@@ -173,17 +239,23 @@ func (h *Inbound) NewConnectionEx(ctx context.Context, conn net.Conn, metadata a
 func (h *Inbound) newConnectionEx(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
 	metadata.Inbound = h.Tag()
 	metadata.InboundType = h.Type()
-	userIndex, loaded := auth.UserFromContext[int](ctx)
+	userID, loaded := auth.UserFromContext[int](ctx)
 	if !loaded {
 		N.CloseOnHandshakeFailure(conn, onClose, os.ErrInvalid)
 		return
 	}
-	user := h.users[userIndex].Name
+	user := h.users.Name(userID)
 	if user == "" {
-		user = F.ToString(userIndex)
+		user = F.ToString(userID)
 	} else {
 		metadata.User = user
 	}
+	err := h.checkRequest(ctx, userID)
+	if err != nil {
+		N.CloseOnHandshakeFailure(conn, onClose, err)
+		h.logger.ErrorContext(ctx, E.Cause(err, "[", user, "] process connection from ", metadata.Source))
+		return
+	}
 	h.logger.InfoContext(ctx, "[", user, "] inbound connection to ", metadata.Destination)
 	h.router.RouteConnectionEx(ctx, conn, metadata, onClose)
 }
@@ -191,17 +263,23 @@ func (h *Inbound) newConnectionEx(ctx context.Context, conn net.Conn, metadata a
 func (h *Inbound) newPacketConnectionEx(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
 	metadata.Inbound = h.Tag()
 	metadata.InboundType = h.Type()
-	userIndex, loaded := auth.UserFromContext[int](ctx)
+	userID, loaded := auth.UserFromContext[int](ctx)
 	if !loaded {
 		N.CloseOnHandshakeFailure(conn, onClose, os.ErrInvalid)
 		return
 	}
-	user := h.users[userIndex].Name
+	user := h.users.Name(userID)
 	if user == "" {
-		user = F.ToString(userIndex)
+		user = F.ToString(userID)
 	} else {
 		metadata.User = user
 	}
+	err := h.checkRequest(ctx, userID)
+	if err != nil {
+		N.CloseOnHandshakeFailure(conn, onClose, err)
+		h.logger.ErrorContext(ctx, E.Cause(err, "[", user, "] process connection from ", metadata.Source))
+		return
+	}
 	if metadata.Destination.Fqdn == packetaddr.SeqPacketMagicAddress {
 		metadata.Destination = M.Socksaddr{}
 		conn = packetaddr.NewConn(bufio.NewNetPacketConn(conn), metadata.Destination)
@@ -212,6 +290,56 @@ func (h *Inbound) newPacketConnectionEx(ctx context.Context, conn N.PacketConn,
 	h.router.RoutePacketConnectionEx(ctx, conn, metadata, onClose)
 }
 
+// badTimestampError explains the rejection of the service with the time of
+// the client, which is known if it is a user of the inbound.
+func (h *Inbound) badTimestampError(conn *authIDConn) error {
+	authIDCiphers := h.authIDCiphers.Load()
+	if authIDCiphers == nil || conn.n < len(conn.authID) {
+		return vmess.ErrBadTimestamp
+	}
+	for _, block := range *authIDCiphers {
+		userTime, loaded := clientTime(block, conn.authID)
+		if loaded {
+			// the service checks timestamps against the system clock
+			return timeDifferenceError(userTime, time.Now(), maxTimeDifference)
+		}
+	}
+	return vmess.ErrBadTimestamp
+}
+
+// checkRequest enforces max_time_difference against the synchronized time
+// and checks the authentication ID with the replay cache, connections of
+// legacy users are not checked.
+func (h *Inbound) checkRequest(ctx context.Context, userID int) error {
+	if h.maxTimeDifference == 0 && h.replayCache == nil {
+		return nil
+	}
+	conn := authIDFromContext(ctx)
+	authIDCiphers := h.authIDCiphers.Load()
+	if conn == nil || authIDCiphers == nil {
+		return nil
+	}
+	block, loaded := (*authIDCiphers)[userID]
+	if !loaded {
+		return nil
+	}
+	userTime, loaded := clientTime(block, conn.authID)
+	if !loaded {
+		return nil
+	}
+	if h.maxTimeDifference > 0 {
+		now := h.timeFunc()
+		difference := userTime.Sub(now)
+		if difference > h.maxTimeDifference || difference < -h.maxTimeDifference {
+			return timeDifferenceError(userTime, now, h.maxTimeDifference)
+		}
+	}
+	if h.replayCache != nil && !h.replayCache.Check(ctx, conn.authID[:]) {
+		return vmess.ErrReplay
+	}
+	return nil
+}
+
 var _ adapter.V2RayServerTransportHandler = (*inboundTransportHandler)(nil)
 
 type inboundTransportHandler Inbound
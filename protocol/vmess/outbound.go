@@ -33,7 +33,7 @@ type Outbound struct {
 	dialer          N.Dialer
 	client          *vmess.Client
 	serverAddr      M.Socksaddr
-	multiplexDialer *mux.Client
+	multiplexDialer *mux.OutboundClient
 	tlsConfig       tls.Config
 	tlsDialer       tls.Dialer
 	transport       adapter.V2RayClientTransport
@@ -59,13 +59,17 @@ func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextL
 		}
 		outbound.tlsDialer = tls.NewDialer(outboundDialer, outbound.tlsConfig)
 	}
+	transportOptions, err := v2ray.FrontedTransportOptions(logger, options.TLS, options.Transport)
+	if err != nil {
+		return nil, err
+	}
 	if options.Transport != nil {
-		outbound.transport, err = v2ray.NewClientTransport(ctx, outbound.dialer, outbound.serverAddr, common.PtrValueOrDefault(options.Transport), outbound.tlsConfig)
+		outbound.transport, err = v2ray.NewClientTransport(ctx, outbound.dialer, outbound.serverAddr, transportOptions, outbound.tlsConfig)
 		if err != nil {
 			return nil, E.Cause(err, "create client transport: ", options.Transport.Type)
 		}
 	}
-	outbound.multiplexDialer, err = mux.NewClientWithOptions((*vmessDialer)(outbound), logger, common.PtrValueOrDefault(options.Multiplex))
+	outbound.multiplexDialer, err = mux.NewOutboundClient((*vmessDialer)(outbound), logger, common.PtrValueOrDefault(options.Multiplex))
 	if err != nil {
 		return nil, err
 	}
@@ -107,17 +111,19 @@ func (h *Outbound) InterfaceUpdated() {
 	if h.transport != nil {
 		h.transport.Close()
 	}
-	if h.multiplexDialer != nil {
-		h.multiplexDialer.Reset()
-	}
+	h.multiplexDialer.Reset()
 }
 
 func (h *Outbound) Close() error {
-	return common.Close(common.PtrOrNil(h.multiplexDialer), h.transport)
+	return common.Close(h.multiplexDialer, h.transport)
 }
 
 func (h *Outbound) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
-	if h.multiplexDialer == nil {
+	multiplexDialer, err := h.multiplexDialer.Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if multiplexDialer == nil {
 		switch N.NetworkName(network) {
 		case N.NetworkTCP:
 			h.logger.InfoContext(ctx, "outbound connection to ", destination)
@@ -132,17 +138,21 @@ func (h *Outbound) DialContext(ctx context.Context, network string, destination
 		case N.NetworkUDP:
 			h.logger.InfoContext(ctx, "outbound multiplex packet connection to ", destination)
 		}
-		return h.multiplexDialer.DialContext(ctx, network, destination)
+		return multiplexDialer.DialContext(ctx, network, destination)
 	}
 }
 
 func (h *Outbound) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
-	if h.multiplexDialer == nil {
+	multiplexDialer, err := h.multiplexDialer.Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if multiplexDialer == nil {
 		h.logger.InfoContext(ctx, "outbound packet connection to ", destination)
 		return (*vmessDialer)(h).ListenPacket(ctx, destination)
 	} else {
 		h.logger.InfoContext(ctx, "outbound multiplex packet connection to ", destination)
-		return h.multiplexDialer.ListenPacket(ctx, destination)
+		return multiplexDialer.ListenPacket(ctx, destination)
 	}
 }
 
@@ -0,0 +1,91 @@
+package vmess
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"hash/crc32"
+	"net"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-vmess"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// maxTimeDifference is the tolerance of the service, timestamps out of it
+// are rejected before the user is known to the inbound.
+const maxTimeDifference = vmess.CacheDurationSeconds * time.Second
+
+// authIDConn records the encrypted authentication ID at the start of the
+// request, which contains the time of the client.
+type authIDConn struct {
+	net.Conn
+	authID [16]byte
+	n      int
+}
+
+func (c *authIDConn) Read(p []byte) (n int, err error) {
+	n, err = c.Conn.Read(p)
+	if c.n < len(c.authID) {
+		c.n += copy(c.authID[c.n:], p[:n])
+	}
+	return
+}
+
+func (c *authIDConn) Upstream() any {
+	return c.Conn
+}
+
+type authIDKey struct{}
+
+func contextWithAuthID(ctx context.Context, conn *authIDConn) context.Context {
+	return context.WithValue(ctx, authIDKey{}, conn)
+}
+
+func authIDFromContext(ctx context.Context) *authIDConn {
+	conn, _ := ctx.Value(authIDKey{}).(*authIDConn)
+	return conn
+}
+
+// newAuthIDCiphers creates the authentication ID ciphers of users in the
+// same way as the service.
+func newAuthIDCiphers(ids []int, users []option.VMessUser) (map[int]cipher.Block, error) {
+	ciphers := make(map[int]cipher.Block, len(users))
+	for i, user := range users {
+		userUUID, err := uuid.FromString(user.UUID)
+		if err != nil {
+			userUUID = uuid.NewV5(uuid.Nil, user.UUID)
+		}
+		userKey := vmess.Key(userUUID)
+		block, err := aes.NewCipher(vmess.KDF(userKey[:], vmess.KDFSaltConstAuthIDEncryptionKey)[:16])
+		if err != nil {
+			return nil, err
+		}
+		ciphers[ids[i]] = block
+	}
+	return ciphers, nil
+}
+
+// clientTime decrypts the time of the client from the authentication ID,
+// ok is false if the ID is not encrypted by the user of the cipher.
+func clientTime(block cipher.Block, authID [16]byte) (clientTime time.Time, ok bool) {
+	var decoded [16]byte
+	block.Decrypt(decoded[:], authID[:])
+	if crc32.ChecksumIEEE(decoded[:12]) != binary.BigEndian.Uint32(decoded[12:]) {
+		return
+	}
+	return time.Unix(int64(binary.BigEndian.Uint64(decoded[:])), 0), true
+}
+
+func timeDifferenceError(clientTime time.Time, now time.Time, tolerance time.Duration) error {
+	// the time of the client is in seconds
+	difference := clientTime.Sub(now).Round(time.Second)
+	if difference > 0 {
+		return E.New("client clock is ", difference, " ahead of server, exceeding the allowed time difference of ", tolerance, ", check the system time of both sides")
+	}
+	return E.New("client clock is ", -difference, " behind server, exceeding the allowed time difference of ", tolerance, ", check the system time of both sides")
+}
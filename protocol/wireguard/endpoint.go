@@ -8,6 +8,7 @@ import (
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/adapter/endpoint"
+	"github.com/sagernet/sing-box/adapter/outbound"
 	"github.com/sagernet/sing-box/common/dialer"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
@@ -24,7 +25,11 @@ import (
 	"github.com/sagernet/sing/service"
 )
 
-var _ adapter.OutboundWithPreferredRoutes = (*Endpoint)(nil)
+var (
+	_ adapter.OutboundWithPreferredRoutes = (*Endpoint)(nil)
+	_ adapter.LazyOutbound                = (*Endpoint)(nil)
+	_ adapter.WireGuardEndpoint           = (*Endpoint)(nil)
+)
 
 func RegisterEndpoint(registry *endpoint.Registry) {
 	endpoint.Register[option.WireGuardEndpointOptions](registry, C.TypeWireGuard, NewEndpoint)
@@ -38,6 +43,8 @@ type Endpoint struct {
 	logger         logger.ContextLogger
 	localAddresses []netip.Prefix
 	endpoint       *wireguard.Endpoint
+	lazy           *outbound.Lazy
+	cacheFile      adapter.CacheFile
 }
 
 func NewEndpoint(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.WireGuardEndpointOptions) (adapter.Endpoint, error) {
@@ -52,6 +59,12 @@ func NewEndpoint(ctx context.Context, router adapter.Router, logger log.ContextL
 	if options.Detour != "" && options.ListenPort != 0 {
 		return nil, E.New("`listen_port` is conflict with `detour`")
 	}
+	if options.System && options.Lazy {
+		return nil, E.New("`lazy` is conflict with `system`")
+	}
+	if options.ListenPort != 0 && options.Lazy {
+		return nil, E.New("`lazy` is conflict with `listen_port`")
+	}
 	outboundDialer, err := dialer.NewWithOptions(dialer.Options{
 		Context: ctx,
 		Options: options.DialerOptions,
@@ -93,39 +106,109 @@ func NewEndpoint(ctx context.Context, router adapter.Router, logger log.ContextL
 			}
 			return endpointAddresses[0], nil
 		},
-		Peers: common.Map(options.Peers, func(it option.WireGuardPeer) wireguard.PeerOptions {
-			return wireguard.PeerOptions{
-				Endpoint:                    M.ParseSocksaddrHostPort(it.Address, it.Port),
-				PublicKey:                   it.PublicKey,
-				PreSharedKey:                it.PreSharedKey,
-				AllowedIPs:                  it.AllowedIPs,
-				PersistentKeepaliveInterval: it.PersistentKeepaliveInterval,
-				Reserved:                    it.Reserved,
-			}
-		}),
+		Peers:   common.Map(options.Peers, newPeerOptions),
 		Workers: options.Workers,
 	})
 	if err != nil {
 		return nil, err
 	}
 	ep.endpoint = wgEndpoint
+	ep.lazy = outbound.NewLazy(options.Lazy, func() error {
+		err := wgEndpoint.Start(false)
+		if err != nil {
+			return err
+		}
+		err = wgEndpoint.Start(true)
+		if err != nil {
+			return err
+		}
+		ep.restoreRoamingEndpoints()
+		return nil
+	})
 	return ep, nil
 }
 
+func newPeerOptions(peer option.WireGuardPeer) wireguard.PeerOptions {
+	return wireguard.PeerOptions{
+		Name:                        peer.Name,
+		Endpoint:                    M.ParseSocksaddrHostPort(peer.Address, peer.Port),
+		PublicKey:                   peer.PublicKey,
+		PreSharedKey:                peer.PreSharedKey,
+		AllowedIPs:                  peer.AllowedIPs,
+		PersistentKeepaliveInterval: peer.PersistentKeepaliveInterval,
+		Reserved:                    peer.Reserved,
+	}
+}
+
 func (w *Endpoint) Start(stage adapter.StartStage) error {
+	if w.lazy.Enabled() {
+		return nil
+	}
 	switch stage {
 	case adapter.StartStateStart:
 		return w.endpoint.Start(false)
 	case adapter.StartStatePostStart:
-		return w.endpoint.Start(true)
+		err := w.endpoint.Start(true)
+		if err != nil {
+			return err
+		}
+		w.restoreRoamingEndpoints()
 	}
 	return nil
 }
 
+// restoreRoamingEndpoints restores endpoints of roaming peers saved before
+// reload.
+func (w *Endpoint) restoreRoamingEndpoints() {
+	cacheFile := service.FromContext[adapter.CacheFile](w.ctx)
+	if cacheFile == nil || !cacheFile.StoreUDPSession() {
+		return
+	}
+	w.cacheFile = cacheFile
+	endpoints := cacheFile.LoadWireGuardPeerEndpoints(w.Tag())
+	if len(endpoints) == 0 {
+		return
+	}
+	err := w.endpoint.RestoreRoamingEndpoints(endpoints)
+	if err != nil {
+		w.logger.Error(E.Cause(err, "restore peer endpoints"))
+		return
+	}
+	w.logger.Info("restored endpoints of ", len(endpoints), " peers")
+}
+
+func (w *Endpoint) LazyState() (state string, err error) {
+	return w.lazy.LazyState()
+}
+
+func (w *Endpoint) Initialize() error {
+	return w.lazy.Initialize()
+}
+
 func (w *Endpoint) Close() error {
+	if w.cacheFile != nil {
+		if endpoints := w.endpoint.RoamingEndpoints(); endpoints != nil {
+			err := w.cacheFile.SaveWireGuardPeerEndpoints(w.Tag(), endpoints)
+			if err != nil {
+				w.logger.Error(E.Cause(err, "save peer endpoints"))
+			}
+		}
+	}
 	return w.endpoint.Close()
 }
 
+func (w *Endpoint) Peers() []adapter.WireGuardPeerStatus {
+	return w.endpoint.Peers()
+}
+
+func (w *Endpoint) SetPeer(peer option.WireGuardPeer) error {
+	return w.endpoint.SetPeer(newPeerOptions(peer))
+}
+
+func (w *Endpoint) RemovePeer(publicKey string) error {
+	return w.endpoint.RemovePeer(publicKey)
+}
+
 func (w *Endpoint) PrepareConnection(network string, source M.Socksaddr, destination M.Socksaddr, routeContext tun.DirectRouteContext, timeout time.Duration) (tun.DirectRouteDestination, error) {
 	var ipVersion uint8
 	if !destination.IsIPv6() {
@@ -140,6 +223,7 @@ func (w *Endpoint) PrepareConnection(network string, source M.Socksaddr, destina
 		Network:     network,
 		Source:      source,
 		Destination: destination,
+		User:        w.endpoint.PeerName(source.Addr),
 	}, routeContext, timeout)
 	if err != nil {
 		if !rule.IsRejected(err) {
@@ -154,6 +238,7 @@ func (w *Endpoint) NewConnectionEx(ctx context.Context, conn net.Conn, source M.
 	metadata.Inbound = w.Tag()
 	metadata.InboundType = w.Type()
 	metadata.Source = source
+	metadata.User = w.endpoint.PeerName(source.Addr)
 	for _, localPrefix := range w.localAddresses {
 		if localPrefix.Contains(destination.Addr) {
 			metadata.OriginDestination = destination
@@ -176,6 +261,7 @@ func (w *Endpoint) NewPacketConnectionEx(ctx context.Context, conn N.PacketConn,
 	metadata.Inbound = w.Tag()
 	metadata.InboundType = w.Type()
 	metadata.Source = source
+	metadata.User = w.endpoint.PeerName(source.Addr)
 	metadata.Destination = destination
 	for _, localPrefix := range w.localAddresses {
 		if localPrefix.Contains(destination.Addr) {
@@ -194,6 +280,10 @@ func (w *Endpoint) NewPacketConnectionEx(ctx context.Context, conn N.PacketConn,
 }
 
 func (w *Endpoint) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	err := w.lazy.Initialize()
+	if err != nil {
+		return nil, E.Cause(err, "initialize wireguard")
+	}
 	switch network {
 	case N.NetworkTCP:
 		w.logger.InfoContext(ctx, "outbound connection to ", destination)
@@ -213,6 +303,10 @@ func (w *Endpoint) DialContext(ctx context.Context, network string, destination
 }
 
 func (w *Endpoint) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	err := w.lazy.Initialize()
+	if err != nil {
+		return nil, E.Cause(err, "initialize wireguard")
+	}
 	w.logger.InfoContext(ctx, "outbound packet connection to ", destination)
 	if destination.IsFqdn() {
 		destinationAddresses, err := w.dnsRouter.Lookup(ctx, destination.Fqdn, adapter.DNSQueryOptions{})
@@ -237,5 +331,9 @@ func (w *Endpoint) PreferredAddress(address netip.Addr) bool {
 }
 
 func (w *Endpoint) NewDirectRouteConnection(metadata adapter.InboundContext, routeContext tun.DirectRouteContext, timeout time.Duration) (tun.DirectRouteDestination, error) {
+	err := w.lazy.Initialize()
+	if err != nil {
+		return nil, E.Cause(err, "initialize wireguard")
+	}
 	return w.endpoint.NewDirectRouteConnection(metadata, routeContext, timeout)
 }
@@ -0,0 +1,191 @@
+package route
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/budget"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/buf"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+const sniffBufferSize = int64(buf.UDPBufferSize)
+
+// budgetManager accounts routed connections per inbound and user, and the
+// buffers held while sniffing, so that a single abusive inbound or user can
+// not exhaust the memory of the whole instance.
+type budgetManager struct {
+	options     option.BudgetOptions
+	waitTimeout time.Duration
+	sniffBuffer *budget.Budget
+	access      sync.Mutex
+	inbounds    map[string]*inboundBudget
+	users       map[string]*userBudget
+}
+
+type inboundBudget struct {
+	connections *budget.Budget
+	sniffBuffer *budget.Budget
+}
+
+// userBudget is dropped once no connection of the user holds or waits for it.
+type userBudget struct {
+	connections *budget.Budget
+	references  int
+}
+
+func newBudgetManager(options option.BudgetOptions) *budgetManager {
+	return &budgetManager{
+		options:     options,
+		waitTimeout: time.Duration(options.WaitTimeout),
+		sniffBuffer: budget.New(int64(options.SniffBuffer.Value())),
+		inbounds:    make(map[string]*inboundBudget),
+		users:       make(map[string]*userBudget),
+	}
+}
+
+func (m *budgetManager) inbound(tag string) *inboundBudget {
+	m.access.Lock()
+	defer m.access.Unlock()
+	inbound, loaded := m.inbounds[tag]
+	if !loaded {
+		connections := int64(m.options.InboundConnections)
+		sniffBuffer := int64(m.options.InboundSniffBuffer.Value())
+		if inboundOptions, hasOptions := m.options.Inbounds[tag]; hasOptions {
+			if inboundOptions.Connections != 0 {
+				connections = int64(inboundOptions.Connections)
+			}
+			if inboundOptions.SniffBuffer != nil {
+				sniffBuffer = int64(inboundOptions.SniffBuffer.Value())
+			}
+		}
+		inbound = &inboundBudget{
+			connections: budget.New(connections),
+			sniffBuffer: budget.New(sniffBuffer),
+		}
+		m.inbounds[tag] = inbound
+	}
+	return inbound
+}
+
+func (m *budgetManager) acquire(ctx context.Context, b *budget.Budget, n int64) bool {
+	if m.waitTimeout == 0 {
+		return b.TryAcquire(n)
+	}
+	ctx, cancel := context.WithTimeout(ctx, m.waitTimeout)
+	defer cancel()
+	return b.Acquire(ctx, n)
+}
+
+// acquireConnection admits a routed connection, returning a release function
+// that is safe to call more than once.
+func (m *budgetManager) acquireConnection(ctx context.Context, metadata *adapter.InboundContext) (func(), error) {
+	inbound := m.inbound(metadata.Inbound)
+	if !m.acquire(ctx, inbound.connections, 1) {
+		return nil, E.New("connection budget exhausted for inbound ", metadata.Inbound)
+	}
+	user := metadata.User
+	var userConnections *userBudget
+	if user != "" && m.options.UserConnections > 0 {
+		m.access.Lock()
+		userConnections = m.users[user]
+		if userConnections == nil {
+			userConnections = &userBudget{connections: budget.New(int64(m.options.UserConnections))}
+			m.users[user] = userConnections
+		}
+		userConnections.references++
+		m.access.Unlock()
+		if !m.acquire(ctx, userConnections.connections, 1) {
+			m.dereferenceUser(user, userConnections)
+			inbound.connections.Release(1)
+			return nil, E.New("connection budget exhausted for user ", user)
+		}
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			inbound.connections.Release(1)
+			if userConnections != nil {
+				userConnections.connections.Release(1)
+				m.dereferenceUser(user, userConnections)
+			}
+		})
+	}, nil
+}
+
+func (m *budgetManager) dereferenceUser(user string, userConnections *userBudget) {
+	m.access.Lock()
+	defer m.access.Unlock()
+	userConnections.references--
+	if userConnections.references == 0 {
+		delete(m.users, user)
+	}
+}
+
+// acquireBuffer reserves n bytes for sniffing from both the inbound and the
+// global sniff buffer budget.
+func (m *budgetManager) acquireBuffer(ctx context.Context, inboundTag string, n int64) bool {
+	inbound := m.inbound(inboundTag)
+	if !m.acquire(ctx, inbound.sniffBuffer, n) {
+		return false
+	}
+	if !m.acquire(ctx, m.sniffBuffer, n) {
+		inbound.sniffBuffer.Release(n)
+		return false
+	}
+	return true
+}
+
+func (m *budgetManager) releaseBuffer(inboundTag string, n int64) {
+	m.inbound(inboundTag).sniffBuffer.Release(n)
+	m.sniffBuffer.Release(n)
+}
+
+func (m *budgetManager) stats() []adapter.BudgetStats {
+	m.access.Lock()
+	inbounds := make(map[string]*inboundBudget, len(m.inbounds))
+	for tag, inbound := range m.inbounds {
+		inbounds[tag] = inbound
+	}
+	users := make(map[string]*budget.Budget, len(m.users))
+	for user, userConnections := range m.users {
+		users[user] = userConnections.connections
+	}
+	m.access.Unlock()
+	budgets := []adapter.BudgetStats{newBudgetStats(adapter.BudgetSniffBuffer, "", m.sniffBuffer)}
+	for _, tag := range sortedKeys(inbounds) {
+		budgets = append(budgets,
+			newBudgetStats(adapter.BudgetInboundConnections, tag, inbounds[tag].connections),
+			newBudgetStats(adapter.BudgetInboundSniffBuffer, tag, inbounds[tag].sniffBuffer),
+		)
+	}
+	for _, user := range sortedKeys(users) {
+		budgets = append(budgets, newBudgetStats(adapter.BudgetUserConnections, user, users[user]))
+	}
+	return budgets
+}
+
+func newBudgetStats(subsystem string, key string, b *budget.Budget) adapter.BudgetStats {
+	stats := b.Stats()
+	return adapter.BudgetStats{
+		Subsystem: subsystem,
+		Key:       key,
+		Limit:     stats.Limit,
+		Used:      stats.Used,
+		Peak:      stats.Peak,
+		Rejected:  stats.Rejected,
+	}
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -13,9 +13,13 @@ import (
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/chaos"
 	"github.com/sagernet/sing-box/common/dialer"
+	"github.com/sagernet/sing-box/common/iouring"
 	"github.com/sagernet/sing-box/common/tlsfragment"
 	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing/common"
 	"github.com/sagernet/sing/common/buf"
 	"github.com/sagernet/sing/common/bufio"
@@ -25,36 +29,111 @@ import (
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
 	"github.com/sagernet/sing/common/x/list"
+
+	"golang.org/x/sys/cpu"
 )
 
 var _ adapter.ConnectionManager = (*ConnectionManager)(nil)
 
+// connectionShards spreads connection tracking over independent locks, so
+// that opening and closing connections does not contend on a single mutex.
+const connectionShards = 64
+
 type ConnectionManager struct {
-	logger      logger.ContextLogger
+	ctx                 context.Context
+	logger              logger.ContextLogger
+	ioURingOptions      option.IOURingOptions
+	ioRing              *iouring.Ring
+	nextShard           atomic.Uint32
+	shards              [connectionShards]connectionShard
+	udpSessions         *udpSessionTable
+	inboundUDPSessions  map[string]*udpSessionTable
+	udpTimeout          time.Duration
+	udpProtocolTimeouts map[string]time.Duration
+	quicAffinity        *quicAffinityTable
+	cacheFile           adapter.CacheFile
+	restoredUDPAccess   sync.Mutex
+	restoredUDPSessions map[string]adapter.SavedUDPSession
+}
+
+type connectionShard struct {
 	access      sync.Mutex
 	connections list.List[io.Closer]
+	_           cpu.CacheLinePad
 }
 
-func NewConnectionManager(logger logger.ContextLogger) *ConnectionManager {
-	return &ConnectionManager{
-		logger: logger,
+func NewConnectionManager(ctx context.Context, logger logger.ContextLogger, ioURingOptions option.IOURingOptions, udpSessionOptions option.UDPSessionOptions) (*ConnectionManager, error) {
+	udpSessions, inboundUDPSessions, err := newUDPSessionTables(udpSessionOptions)
+	if err != nil {
+		return nil, E.Cause(err, "parse udp_session")
+	}
+	udpProtocolTimeouts, err := newUDPProtocolTimeouts(udpSessionOptions)
+	if err != nil {
+		return nil, E.Cause(err, "parse udp_session")
 	}
+	manager := &ConnectionManager{
+		ctx:                 ctx,
+		logger:              logger,
+		ioURingOptions:      ioURingOptions,
+		udpSessions:         udpSessions,
+		inboundUDPSessions:  inboundUDPSessions,
+		udpTimeout:          time.Duration(udpSessionOptions.Timeout),
+		udpProtocolTimeouts: udpProtocolTimeouts,
+	}
+	if udpSessionOptions.QUICAffinity {
+		manager.quicAffinity = newQUICAffinityTable()
+	}
+	return manager, nil
 }
 
 func (m *ConnectionManager) Start(stage adapter.StartStage) error {
+	if stage != adapter.StartStateStart {
+		return nil
+	}
+	m.loadUDPSessions()
+	if !m.ioURingOptions.Enabled {
+		return nil
+	}
+	if !C.IsLinux {
+		return E.New("`io_uring` is only supported on Linux")
+	}
+	ioRing, err := iouring.New(m.ioURingOptions.Entries)
+	if err != nil {
+		return E.Cause(err, "initialize io_uring")
+	}
+	m.ioRing = ioRing
+	m.logger.Warn("experimental io_uring relay enabled")
 	return nil
 }
 
 func (m *ConnectionManager) Close() error {
-	m.access.Lock()
-	defer m.access.Unlock()
-	for element := m.connections.Front(); element != nil; element = element.Next() {
-		common.Close(element.Value)
+	for i := range m.shards {
+		shard := &m.shards[i]
+		shard.access.Lock()
+		for element := shard.connections.Front(); element != nil; element = element.Next() {
+			common.Close(element.Value)
+		}
+		shard.connections.Init()
+		shard.access.Unlock()
+	}
+	if m.ioRing != nil {
+		return m.ioRing.Close()
 	}
-	m.connections.Init()
 	return nil
 }
 
+func (m *ConnectionManager) track(conn io.Closer, onClose N.CloseHandlerFunc) N.CloseHandlerFunc {
+	shard := &m.shards[m.nextShard.Add(1)%connectionShards]
+	shard.access.Lock()
+	element := shard.connections.PushBack(conn)
+	shard.access.Unlock()
+	return N.AppendClose(onClose, func(it error) {
+		shard.access.Lock()
+		defer shard.access.Unlock()
+		shard.connections.Remove(element)
+	})
+}
+
 func (m *ConnectionManager) NewConnection(ctx context.Context, this N.Dialer, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
 	ctx = adapter.WithContext(ctx, &metadata)
 	var (
@@ -90,17 +169,22 @@ func (m *ConnectionManager) NewConnection(ctx context.Context, this N.Dialer, co
 		m.logger.ErrorContext(ctx, err)
 		return
 	}
+	if metadata.Chaos != nil {
+		remoteConn = chaos.NewConn(remoteConn, metadata.Chaos)
+	}
 	if metadata.TLSFragment || metadata.TLSRecordFragment {
 		remoteConn = tf.NewConn(remoteConn, ctx, metadata.TLSFragment, metadata.TLSRecordFragment, metadata.TLSFragmentFallbackDelay)
 	}
-	m.access.Lock()
-	element := m.connections.PushBack(conn)
-	m.access.Unlock()
-	onClose = N.AppendClose(onClose, func(it error) {
-		m.access.Lock()
-		defer m.access.Unlock()
-		m.connections.Remove(element)
-	})
+	if metadata.TCPKeepAlive != 0 || metadata.TCPKeepAliveInterval > 0 {
+		err = setKeepAlive(conn, metadata.TCPKeepAlive, metadata.TCPKeepAliveInterval)
+		if err != nil {
+			m.logger.WarnContext(ctx, E.Cause(err, "set keep alive"))
+		}
+	}
+	if metadata.TCPIdleTimeout > 0 {
+		conn = newIdleConn(conn, metadata.TCPIdleTimeout)
+	}
+	onClose = m.track(conn, onClose)
 	var done atomic.Bool
 	go m.connectionCopy(ctx, conn, remoteConn, false, &done, onClose)
 	go m.connectionCopy(ctx, remoteConn, conn, true, &done, onClose)
@@ -108,12 +192,21 @@ func (m *ConnectionManager) NewConnection(ctx context.Context, this N.Dialer, co
 
 func (m *ConnectionManager) NewPacketConnection(ctx context.Context, this N.Dialer, conn N.PacketConn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
 	ctx = adapter.WithContext(ctx, &metadata)
+	if m.quicAffinity != nil && len(metadata.QUICShortHeader) > 0 {
+		if session := m.quicAffinity.lookup(&metadata); session != nil {
+			m.migrateQUICSession(ctx, session, conn, &metadata, onClose)
+			return
+		}
+	}
 	var (
 		remotePacketConn   net.PacketConn
 		remoteConn         net.Conn
 		destinationAddress netip.Addr
 		err                error
 	)
+	if m.restoredUDPSessions != nil {
+		m.restoreUDPSession(this, &metadata)
+	}
 	if metadata.UDPConnect {
 		parallelDialer, isParallelDialer := this.(dialer.ParallelInterfaceDialer)
 		if len(metadata.DestinationAddresses) > 0 {
@@ -176,6 +269,7 @@ func (m *ConnectionManager) NewPacketConnection(ctx context.Context, this N.Dial
 		m.logger.ErrorContext(ctx, "report handshake success: ", err)
 		return
 	}
+	savedSession := m.newSavedUDPSession(this, &metadata, remotePacketConn)
 	if destinationAddress.IsValid() {
 		var originDestination M.Socksaddr
 		if metadata.RouteOriginalDestination.IsValid() {
@@ -195,35 +289,52 @@ func (m *ConnectionManager) NewPacketConnection(ctx context.Context, this N.Dial
 	} else if metadata.RouteOriginalDestination.IsValid() && metadata.RouteOriginalDestination != metadata.Destination {
 		remotePacketConn = bufio.NewDestinationNATPacketConn(bufio.NewPacketConn(remotePacketConn), metadata.Destination, metadata.RouteOriginalDestination)
 	}
-	var udpTimeout time.Duration
-	if metadata.UDPTimeout > 0 {
-		udpTimeout = metadata.UDPTimeout
-	} else {
-		protocol := metadata.Protocol
-		if protocol == "" {
-			protocol = C.PortProtocols[metadata.Destination.Port]
-		}
-		if protocol != "" {
-			udpTimeout = C.ProtocolTimeouts[protocol]
+	if udpTimeout := m.packetTimeout(&metadata); udpTimeout > 0 {
+		ctx, conn = canceler.NewPacketConn(ctx, conn, udpTimeout)
+	}
+	var destination N.PacketConn = bufio.NewPacketConn(remotePacketConn)
+	if metadata.Chaos != nil {
+		destination = chaos.NewPacketConn(destination, metadata.Chaos)
+	}
+	if metadata.UDPNATMapping != "" && metadata.UDPNATMapping != C.NATEndpointIndependent {
+		natDestination := metadata.Destination
+		if metadata.RouteOriginalDestination.IsValid() {
+			natDestination = metadata.RouteOriginalDestination
 		}
+		destination = newNATMappingPacketConn(ctx, m.logger, this, &metadata, destination, natDestination)
 	}
-	if udpTimeout > 0 {
-		ctx, conn = canceler.NewPacketConn(ctx, conn, udpTimeout)
+	if metadata.UDPNATFiltering != "" && metadata.UDPNATFiltering != C.NATEndpointIndependent {
+		destination = newNATFilterPacketConn(destination, metadata.UDPNATFiltering)
+	}
+	conn, onClose = m.trackUDPSession(ctx, conn, &metadata, savedSession, onClose)
+	onClose = m.track(conn, onClose)
+	if m.quicAffinity != nil && metadata.Protocol == C.ProtocolQUIC {
+		affinityConn := m.quicAffinity.newConn(ctx, m.logger, conn, destination, &metadata, onClose)
+		conn, onClose = affinityConn, affinityConn.handleClose
 	}
-	destination := bufio.NewPacketConn(remotePacketConn)
-	m.access.Lock()
-	element := m.connections.PushBack(conn)
-	m.access.Unlock()
-	onClose = N.AppendClose(onClose, func(it error) {
-		m.access.Lock()
-		defer m.access.Unlock()
-		m.connections.Remove(element)
-	})
 	var done atomic.Bool
 	go m.packetConnectionCopy(ctx, conn, destination, false, &done, onClose)
 	go m.packetConnectionCopy(ctx, destination, conn, true, &done, onClose)
 }
 
+func (m *ConnectionManager) packetTimeout(metadata *adapter.InboundContext) time.Duration {
+	if metadata.UDPTimeout > 0 {
+		return metadata.UDPTimeout
+	}
+	protocol := metadata.Protocol
+	if protocol == "" {
+		protocol = C.PortProtocols[metadata.Destination.Port]
+	}
+	var udpTimeout time.Duration
+	if protocol != "" {
+		udpTimeout = m.udpProtocolTimeouts[protocol]
+	}
+	if udpTimeout == 0 {
+		udpTimeout = m.udpTimeout
+	}
+	return udpTimeout
+}
+
 func (m *ConnectionManager) preConnectionCopy(ctx context.Context, source net.Conn, destination net.Conn, direction bool, done *atomic.Bool, onClose N.CloseHandlerFunc) {
 	readHandshake := N.NeedHandshakeForRead(source)
 	writeHandshake := N.NeedHandshakeForWrite(destination)
@@ -254,6 +365,7 @@ func (m *ConnectionManager) preConnectionCopy(ctx context.Context, source net.Co
 }
 
 func (m *ConnectionManager) connectionCopy(ctx context.Context, source net.Conn, destination net.Conn, direction bool, done *atomic.Bool, onClose N.CloseHandlerFunc) {
+	defer log.RecoverFatal()
 	var (
 		sourceReader      io.Reader = source
 		destinationWriter io.Writer = destination
@@ -294,13 +406,25 @@ func (m *ConnectionManager) connectionCopy(ctx context.Context, source net.Conn,
 
 	m.preConnectionCopy(ctx, source, destination, direction, done, onClose)
 
-	_, err := bufio.CopyWithCounters(destinationWriter, sourceReader, source, readCounters, writeCounters, bufio.DefaultIncreaseBufferAfter, bufio.DefaultBatchSize)
+	var err error
+	sourceTCPConn, isSourceTCP := sourceReader.(*net.TCPConn)
+	destinationTCPConn, isDestinationTCP := destinationWriter.(*net.TCPConn)
+	if m.ioRing != nil && isSourceTCP && isDestinationTCP {
+		_, err = m.ioRing.Copy(destinationTCPConn, sourceTCPConn, readCounters, writeCounters)
+	} else {
+		_, err = bufio.CopyWithCounters(destinationWriter, sourceReader, source, readCounters, writeCounters, bufio.DefaultIncreaseBufferAfter, bufio.DefaultBatchSize)
+	}
+	metadata := adapter.ContextFrom(ctx)
 	if err != nil {
 		common.Close(source, destination)
-	} else if duplexDst, isDuplex := destination.(N.WriteCloser); isDuplex {
+	} else if duplexDst, isDuplex := destination.(N.WriteCloser); isDuplex && !metadata.TCPDisableHalfClose {
 		err = duplexDst.CloseWrite()
 		if err != nil {
 			common.Close(source, destination)
+		} else if metadata.TCPHalfCloseTimeout > 0 {
+			time.AfterFunc(metadata.TCPHalfCloseTimeout, func() {
+				common.Close(source, destination)
+			})
 		}
 	} else {
 		destination.Close()
@@ -370,6 +494,7 @@ func (m *ConnectionManager) connectionCopyEarlyWrite(source net.Conn, destinatio
 }
 
 func (m *ConnectionManager) packetConnectionCopy(ctx context.Context, source N.PacketReader, destination N.PacketWriter, direction bool, done *atomic.Bool, onClose N.CloseHandlerFunc) {
+	defer log.RecoverFatal()
 	_, err := bufio.CopyPacket(destination, source)
 	if !direction {
 		if err == nil {
@@ -0,0 +1,259 @@
+package route
+
+import (
+	"context"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing/common/buf"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+func natBehaviorKey(behavior string, address M.Socksaddr) M.Socksaddr {
+	if behavior == C.NATAddressDependent {
+		return M.Socksaddr{Addr: address.Addr, Fqdn: address.Fqdn}
+	}
+	return address
+}
+
+var _ N.PacketConn = (*natFilterPacketConn)(nil)
+
+// natFilterPacketConn drops packets from remote endpoints that the client has not sent to.
+type natFilterPacketConn struct {
+	upstream  N.PacketConn
+	filtering string
+	access    sync.RWMutex
+	endpoints map[M.Socksaddr]bool
+}
+
+func newNATFilterPacketConn(conn N.PacketConn, filtering string) *natFilterPacketConn {
+	return &natFilterPacketConn{
+		upstream:  conn,
+		filtering: filtering,
+		endpoints: make(map[M.Socksaddr]bool),
+	}
+}
+
+func (c *natFilterPacketConn) ReadPacket(buffer *buf.Buffer) (destination M.Socksaddr, err error) {
+	start := buffer.Start()
+	for {
+		destination, err = c.upstream.ReadPacket(buffer)
+		if err != nil {
+			return
+		}
+		c.access.RLock()
+		allowed := c.endpoints[natBehaviorKey(c.filtering, destination)]
+		c.access.RUnlock()
+		if allowed {
+			return
+		}
+		buffer.Resize(start, 0)
+	}
+}
+
+func (c *natFilterPacketConn) WritePacket(buffer *buf.Buffer, destination M.Socksaddr) error {
+	key := natBehaviorKey(c.filtering, destination)
+	c.access.RLock()
+	loaded := c.endpoints[key]
+	c.access.RUnlock()
+	if !loaded {
+		c.access.Lock()
+		c.endpoints[key] = true
+		c.access.Unlock()
+	}
+	return c.upstream.WritePacket(buffer, destination)
+}
+
+func (c *natFilterPacketConn) Close() error {
+	return c.upstream.Close()
+}
+
+func (c *natFilterPacketConn) LocalAddr() net.Addr {
+	return c.upstream.LocalAddr()
+}
+
+func (c *natFilterPacketConn) SetDeadline(t time.Time) error {
+	return c.upstream.SetDeadline(t)
+}
+
+func (c *natFilterPacketConn) SetReadDeadline(t time.Time) error {
+	return c.upstream.SetReadDeadline(t)
+}
+
+func (c *natFilterPacketConn) SetWriteDeadline(t time.Time) error {
+	return c.upstream.SetWriteDeadline(t)
+}
+
+func (c *natFilterPacketConn) Upstream() any {
+	return c.upstream
+}
+
+var _ N.PacketConn = (*natMappingPacketConn)(nil)
+
+// natMappingPacketConn opens a separate outbound socket for each remote endpoint,
+// so that the mapped source port differs between destinations.
+type natMappingPacketConn struct {
+	ctx       context.Context
+	logger    logger.ContextLogger
+	dialer    N.Dialer
+	mapping   string
+	connect   bool
+	primary   N.PacketConn
+	access    sync.Mutex
+	conns     map[M.Socksaddr]N.PacketConn
+	packets   chan *natPacket
+	readErr   chan error
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type natPacket struct {
+	buffer      *buf.Buffer
+	destination M.Socksaddr
+}
+
+func newNATMappingPacketConn(ctx context.Context, logger logger.ContextLogger, dialer N.Dialer, metadata *adapter.InboundContext, conn N.PacketConn, destination M.Socksaddr) *natMappingPacketConn {
+	mappingConn := &natMappingPacketConn{
+		ctx:     ctx,
+		logger:  logger,
+		dialer:  dialer,
+		mapping: metadata.UDPNATMapping,
+		connect: metadata.UDPConnect,
+		conns:   make(map[M.Socksaddr]N.PacketConn),
+		packets: make(chan *natPacket, 64),
+		primary: conn,
+		readErr: make(chan error, 1),
+		done:    make(chan struct{}),
+	}
+	key := natBehaviorKey(mappingConn.mapping, destination)
+	mappingConn.conns[key] = conn
+	go mappingConn.loopRead(key, conn, true)
+	return mappingConn
+}
+
+func (c *natMappingPacketConn) loopRead(key M.Socksaddr, conn N.PacketConn, primary bool) {
+	for {
+		buffer := buf.NewPacket()
+		destination, err := conn.ReadPacket(buffer)
+		if err != nil {
+			buffer.Release()
+			c.access.Lock()
+			if c.conns[key] == conn {
+				delete(c.conns, key)
+			}
+			c.access.Unlock()
+			conn.Close()
+			if primary {
+				select {
+				case c.readErr <- err:
+				default:
+				}
+			} else if !E.IsClosedOrCanceled(err) {
+				c.logger.DebugContext(c.ctx, "close nat mapping to ", key, ": ", err)
+			}
+			return
+		}
+		select {
+		case c.packets <- &natPacket{buffer, destination}:
+		case <-c.done:
+			buffer.Release()
+			return
+		}
+	}
+}
+
+func (c *natMappingPacketConn) ReadPacket(buffer *buf.Buffer) (destination M.Socksaddr, err error) {
+	select {
+	case packet := <-c.packets:
+		_, err = buffer.Write(packet.buffer.Bytes())
+		packet.buffer.Release()
+		return packet.destination, err
+	case err = <-c.readErr:
+		return
+	case <-c.done:
+		return M.Socksaddr{}, net.ErrClosed
+	}
+}
+
+func (c *natMappingPacketConn) WritePacket(buffer *buf.Buffer, destination M.Socksaddr) error {
+	key := natBehaviorKey(c.mapping, destination)
+	c.access.Lock()
+	conn, loaded := c.conns[key]
+	if !loaded {
+		select {
+		case <-c.done:
+			c.access.Unlock()
+			buffer.Release()
+			return net.ErrClosed
+		default:
+		}
+		var err error
+		conn, err = c.newConn(destination)
+		if err != nil {
+			c.access.Unlock()
+			buffer.Release()
+			return E.Cause(err, "open nat mapping to ", destination)
+		}
+		c.conns[key] = conn
+		go c.loopRead(key, conn, false)
+	}
+	c.access.Unlock()
+	return conn.WritePacket(buffer, destination)
+}
+
+func (c *natMappingPacketConn) newConn(destination M.Socksaddr) (N.PacketConn, error) {
+	if c.connect {
+		conn, err := c.dialer.DialContext(c.ctx, N.NetworkUDP, destination)
+		if err != nil {
+			return nil, err
+		}
+		return bufio.NewPacketConn(bufio.NewUnbindPacketConn(conn)), nil
+	}
+	packetConn, err := c.dialer.ListenPacket(c.ctx, destination)
+	if err != nil {
+		return nil, err
+	}
+	return bufio.NewPacketConn(packetConn), nil
+}
+
+func (c *natMappingPacketConn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+	c.access.Lock()
+	conns := c.conns
+	c.conns = make(map[M.Socksaddr]N.PacketConn)
+	c.access.Unlock()
+	var errs []error
+	for _, conn := range conns {
+		errs = append(errs, conn.Close())
+	}
+	return E.Errors(errs...)
+}
+
+func (c *natMappingPacketConn) LocalAddr() net.Addr {
+	return c.primary.LocalAddr()
+}
+
+func (c *natMappingPacketConn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+func (c *natMappingPacketConn) SetReadDeadline(t time.Time) error {
+	if t.IsZero() {
+		return nil
+	}
+	return os.ErrInvalid
+}
+
+func (c *natMappingPacketConn) SetWriteDeadline(t time.Time) error {
+	return os.ErrInvalid
+}
@@ -0,0 +1,398 @@
+package route
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/netip"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common/buf"
+	"github.com/sagernet/sing/common/canceler"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+const (
+	quicMaxConnectionIDLength = 20
+	// shorter connection IDs are not tracked, as they may collide
+	quicMinConnectionIDLength = 4
+	quicMaxSessionIDs         = 8
+)
+
+// quicShortHeader returns the start of a packet that looks like a QUIC short
+// header packet, which is enough to look up any connection ID.
+func quicShortHeader(packet []byte) []byte {
+	if len(packet) < 1+quicMinConnectionIDLength || packet[0]&0xc0 != 0x40 {
+		return nil
+	}
+	return bytes.Clone(packet[:min(len(packet), 1+quicMaxConnectionIDLength)])
+}
+
+// quicLongHeaderSourceID returns the source connection ID of a QUIC long header packet.
+func quicLongHeaderSourceID(packet []byte) []byte {
+	if len(packet) < 7 || packet[0]&0x80 == 0 {
+		return nil
+	}
+	destinationLength := int(packet[5])
+	if destinationLength > quicMaxConnectionIDLength || len(packet) < 7+destinationLength {
+		return nil
+	}
+	sourceLength := int(packet[6+destinationLength])
+	if sourceLength > quicMaxConnectionIDLength || len(packet) < 7+destinationLength+sourceLength {
+		return nil
+	}
+	return packet[7+destinationLength : 7+destinationLength+sourceLength]
+}
+
+// quicAffinityTable maps connection IDs chosen by QUIC servers to sessions,
+// so that packets of a migrated client, which come from a new source port,
+// continue the existing session instead of starting a new one.
+type quicAffinityTable struct {
+	access   sync.Mutex
+	sessions map[*quicAffinityConn]struct{}
+	ids      map[string]*quicAffinityConn
+	lengths  map[int]int
+}
+
+func newQUICAffinityTable() *quicAffinityTable {
+	return &quicAffinityTable{
+		sessions: make(map[*quicAffinityConn]struct{}),
+		ids:      make(map[string]*quicAffinityConn),
+		lengths:  make(map[int]int),
+	}
+}
+
+func (t *quicAffinityTable) newConn(ctx context.Context, logger logger.ContextLogger, conn N.PacketConn, remote N.PacketWriter, metadata *adapter.InboundContext, onClose N.CloseHandlerFunc) *quicAffinityConn {
+	primary := &quicAffinityClient{conn: conn, onClose: onClose}
+	session := &quicAffinityConn{
+		ctx:         ctx,
+		logger:      logger,
+		table:       t,
+		inbound:     metadata.Inbound,
+		user:        metadata.User,
+		source:      metadata.Source.Addr.Unmap(),
+		destination: metadata.Destination,
+		remote:      remote,
+		primary:     primary,
+		clients:     []*quicAffinityClient{primary},
+		current:     primary,
+		done:        make(chan struct{}),
+	}
+	t.access.Lock()
+	t.sessions[session] = struct{}{}
+	t.access.Unlock()
+	return session
+}
+
+// lookup finds the session by the connection ID of the packet. Clients
+// should switch to a connection ID unknown to us when migrating, so the only
+// session from the same source address to the same destination of the
+// inbound and user is used otherwise, which covers NAT rebinding.
+func (t *quicAffinityTable) lookup(metadata *adapter.InboundContext) *quicAffinityConn {
+	header := metadata.QUICShortHeader
+	t.access.Lock()
+	defer t.access.Unlock()
+	for length := range t.lengths {
+		if len(header) < 1+length {
+			continue
+		}
+		session := t.ids[string(header[1:1+length])]
+		if session != nil && session.inbound == metadata.Inbound && session.user == metadata.User {
+			return session
+		}
+	}
+	var candidate *quicAffinityConn
+	for session := range t.sessions {
+		if !session.matches(metadata) {
+			continue
+		}
+		if candidate != nil {
+			return nil
+		}
+		candidate = session
+	}
+	return candidate
+}
+
+func (t *quicAffinityTable) add(session *quicAffinityConn, id []byte) {
+	key := string(id)
+	t.access.Lock()
+	defer t.access.Unlock()
+	if _, loaded := t.ids[key]; loaded {
+		return
+	}
+	t.ids[key] = session
+	t.lengths[len(id)]++
+}
+
+func (t *quicAffinityTable) remove(session *quicAffinityConn, ids []string) {
+	t.access.Lock()
+	defer t.access.Unlock()
+	delete(t.sessions, session)
+	for _, key := range ids {
+		if t.ids[key] != session {
+			continue
+		}
+		delete(t.ids, key)
+		t.lengths[len(key)]--
+		if t.lengths[len(key)] == 0 {
+			delete(t.lengths, len(key))
+		}
+	}
+}
+
+var _ N.PacketConn = (*quicAffinityConn)(nil)
+
+// quicAffinityConn is the client side of a QUIC session. Connections of
+// migrated clients are attached to it until they are idle, and packets from
+// the server are sent to the client that sent the latest packet, like a NAT
+// rebinding.
+type quicAffinityConn struct {
+	ctx         context.Context
+	logger      logger.ContextLogger
+	table       *quicAffinityTable
+	inbound     string
+	user        string
+	source      netip.Addr
+	destination M.Socksaddr
+	remote      N.PacketWriter
+	primary     *quicAffinityClient
+	access      sync.RWMutex
+	clients     []*quicAffinityClient
+	current     *quicAffinityClient
+	closed      bool
+	done        chan struct{}
+
+	idAccess sync.Mutex
+	idLength int
+	ids      []string
+}
+
+type quicAffinityClient struct {
+	conn    N.PacketConn
+	onClose N.CloseHandlerFunc
+}
+
+// matches reports whether the connection may be a rebound client of the
+// session without a known connection ID, which requires the same source
+// address. Sessions with destinations overridden by sniffing never match, as
+// packets of migrated clients are not sniffed.
+func (c *quicAffinityConn) matches(metadata *adapter.InboundContext) bool {
+	return c.inbound == metadata.Inbound && c.user == metadata.User && c.source.IsValid() &&
+		c.source == metadata.Source.Addr.Unmap() && c.destination == metadata.Destination
+}
+
+// learn records a connection ID of the server, taken from the source ID of
+// its long header packets, or from the destination ID of client packets once
+// the length is known.
+func (c *quicAffinityConn) learn(packet []byte, fromServer bool) {
+	if len(packet) == 0 {
+		return
+	}
+	var id []byte
+	c.idAccess.Lock()
+	defer c.idAccess.Unlock()
+	if fromServer {
+		id = quicLongHeaderSourceID(packet)
+		if len(id) < quicMinConnectionIDLength {
+			return
+		}
+		c.idLength = len(id)
+	} else {
+		if c.idLength == 0 || packet[0]&0xc0 != 0x40 || len(packet) < 1+c.idLength {
+			return
+		}
+		id = packet[1 : 1+c.idLength]
+	}
+	if len(c.ids) >= quicMaxSessionIDs {
+		return
+	}
+	for _, key := range c.ids {
+		if key == string(id) {
+			return
+		}
+	}
+	c.ids = append(c.ids, string(id))
+	c.table.add(c, id)
+}
+
+func (c *quicAffinityConn) received(client *quicAffinityClient, packet []byte) {
+	c.learn(packet, false)
+	c.access.RLock()
+	isCurrent := c.current == client
+	c.access.RUnlock()
+	if !isCurrent {
+		c.access.Lock()
+		if !c.closed {
+			c.current = client
+		}
+		c.access.Unlock()
+	}
+}
+
+func (c *quicAffinityConn) attach(conn N.PacketConn, onClose N.CloseHandlerFunc) bool {
+	client := &quicAffinityClient{conn: conn, onClose: onClose}
+	c.access.Lock()
+	if c.closed {
+		c.access.Unlock()
+		return false
+	}
+	c.clients = append(c.clients, client)
+	c.current = client
+	c.access.Unlock()
+	go c.loopClient(client)
+	return true
+}
+
+// detach removes an idle or closed client, and closes the session if no
+// client is left.
+func (c *quicAffinityConn) detach(client *quicAffinityClient, err error) {
+	c.access.Lock()
+	index := slices.Index(c.clients, client)
+	if index == -1 {
+		c.access.Unlock()
+		return
+	}
+	c.clients = slices.Delete(c.clients, index, index+1)
+	if c.current == client && len(c.clients) > 0 {
+		c.current = c.clients[len(c.clients)-1]
+	}
+	isEmpty := len(c.clients) == 0
+	c.access.Unlock()
+	client.conn.Close()
+	client.onClose(err)
+	if isEmpty {
+		c.Close()
+	}
+}
+
+func (c *quicAffinityConn) loopClient(client *quicAffinityClient) {
+	for {
+		buffer := buf.NewPacket()
+		destination, err := client.conn.ReadPacket(buffer)
+		if err != nil {
+			buffer.Release()
+			c.detach(client, err)
+			return
+		}
+		c.received(client, buffer.Bytes())
+		err = c.remote.WritePacket(buffer, destination)
+		if err != nil {
+			c.logger.DebugContext(c.ctx, "packet upload closed: ", err)
+			c.detach(client, err)
+			c.Close()
+			return
+		}
+	}
+}
+
+func (c *quicAffinityConn) isAttached(client *quicAffinityClient) bool {
+	c.access.RLock()
+	defer c.access.RUnlock()
+	return slices.Contains(c.clients, client)
+}
+
+// ReadPacket reads from the client of the first connection, and waits for the
+// session to be closed if other clients are still attached after it is idle.
+func (c *quicAffinityConn) ReadPacket(buffer *buf.Buffer) (destination M.Socksaddr, err error) {
+	if c.isAttached(c.primary) {
+		destination, err = c.primary.conn.ReadPacket(buffer)
+		if err == nil {
+			c.received(c.primary, buffer.Bytes())
+			return
+		}
+		c.detach(c.primary, err)
+	}
+	<-c.done
+	return M.Socksaddr{}, net.ErrClosed
+}
+
+func (c *quicAffinityConn) WritePacket(buffer *buf.Buffer, destination M.Socksaddr) error {
+	c.learn(buffer.Bytes(), true)
+	c.access.RLock()
+	client := c.current
+	c.access.RUnlock()
+	err := client.conn.WritePacket(buffer, destination)
+	if err != nil && !c.isClosed() {
+		// the client may have just been detached
+		return nil
+	}
+	return err
+}
+
+// handleClose calls the close handlers of all attached clients.
+func (c *quicAffinityConn) handleClose(err error) {
+	c.access.Lock()
+	clients := c.clients
+	c.clients = nil
+	c.access.Unlock()
+	for _, client := range clients {
+		client.onClose(err)
+	}
+}
+
+func (c *quicAffinityConn) isClosed() bool {
+	c.access.RLock()
+	defer c.access.RUnlock()
+	return c.closed
+}
+
+func (c *quicAffinityConn) Close() error {
+	c.access.Lock()
+	if c.closed {
+		c.access.Unlock()
+		return nil
+	}
+	c.closed = true
+	close(c.done)
+	clients := c.clients
+	c.access.Unlock()
+	c.idAccess.Lock()
+	ids := c.ids
+	c.ids = nil
+	c.idAccess.Unlock()
+	c.table.remove(c, ids)
+	for _, client := range clients {
+		client.conn.Close()
+	}
+	return nil
+}
+
+func (c *quicAffinityConn) LocalAddr() net.Addr {
+	return c.primary.conn.LocalAddr()
+}
+
+func (c *quicAffinityConn) SetDeadline(t time.Time) error {
+	return os.ErrInvalid
+}
+
+func (c *quicAffinityConn) SetReadDeadline(t time.Time) error {
+	return os.ErrInvalid
+}
+
+func (c *quicAffinityConn) SetWriteDeadline(t time.Time) error {
+	return os.ErrInvalid
+}
+
+// migrateQUICSession attaches the connection of a migrated client to the
+// QUIC session, which is tracked like a new session except for the outbound
+// connection.
+func (m *ConnectionManager) migrateQUICSession(ctx context.Context, session *quicAffinityConn, conn N.PacketConn, metadata *adapter.InboundContext, onClose N.CloseHandlerFunc) {
+	if udpTimeout := m.packetTimeout(metadata); udpTimeout > 0 {
+		ctx, conn = canceler.NewPacketConn(ctx, conn, udpTimeout)
+	}
+	conn, onClose = m.trackUDPSession(ctx, conn, metadata, nil, onClose)
+	onClose = m.track(conn, onClose)
+	if !session.attach(conn, onClose) {
+		N.CloseOnHandshakeFailure(conn, onClose, E.New("migrate QUIC session: session closed"))
+		return
+	}
+	m.logger.DebugContext(ctx, "migrated QUIC session")
+}
@@ -0,0 +1,83 @@
+package route
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/stretchr/testify/require"
+)
+
+func quicTestMetadata(source string, header []byte) *adapter.InboundContext {
+	return &adapter.InboundContext{
+		Inbound:         "tun-in",
+		User:            "user",
+		Source:          M.ParseSocksaddr(source),
+		Destination:     M.ParseSocksaddr("1.1.1.1:443"),
+		QUICShortHeader: header,
+	}
+}
+
+func quicTestSession(table *quicAffinityTable, metadata *adapter.InboundContext) *quicAffinityConn {
+	return table.newConn(context.Background(), logger.NOP(), nil, nil, metadata, nil)
+}
+
+func TestQUICShortHeader(t *testing.T) {
+	t.Parallel()
+	require.Nil(t, quicShortHeader([]byte{0x40, 1, 2}))
+	require.Nil(t, quicShortHeader([]byte{0xc0, 1, 2, 3, 4}))
+	packet := make([]byte, 64)
+	packet[0] = 0x41
+	require.Len(t, quicShortHeader(packet), 1+quicMaxConnectionIDLength)
+}
+
+func TestQUICLongHeaderSourceID(t *testing.T) {
+	t.Parallel()
+	packet := []byte{0xc0, 0, 0, 0, 1, 2, 0xa, 0xb, 4, 1, 2, 3, 4, 0}
+	require.Equal(t, []byte{1, 2, 3, 4}, quicLongHeaderSourceID(packet))
+	require.Nil(t, quicLongHeaderSourceID(packet[:10]))
+	require.Nil(t, quicLongHeaderSourceID([]byte{0x40, 0, 0, 0, 1, 0, 0}))
+}
+
+func TestQUICAffinityLookupByConnectionID(t *testing.T) {
+	t.Parallel()
+	table := newQUICAffinityTable()
+	header := []byte{0x40, 1, 2, 3, 4, 5, 6, 7, 8}
+	session := quicTestSession(table, quicTestMetadata("10.0.0.1:1000", nil))
+	other := quicTestSession(table, quicTestMetadata("10.0.0.1:1001", nil))
+	table.add(session, header[1:9])
+	require.Same(t, session, table.lookup(quicTestMetadata("10.0.0.2:2000", header)))
+
+	metadata := quicTestMetadata("10.0.0.2:2000", header)
+	metadata.User = "other"
+	require.Nil(t, table.lookup(metadata))
+
+	table.remove(session, []string{string(header[1:9])})
+	require.Nil(t, table.lookup(quicTestMetadata("10.0.0.2:2000", header)))
+	require.Same(t, other, table.lookup(quicTestMetadata("10.0.0.1:2000", header)))
+}
+
+func TestQUICAffinityLookupFallback(t *testing.T) {
+	t.Parallel()
+	table := newQUICAffinityTable()
+	header := []byte{0x40, 1, 2, 3, 4, 5, 6, 7, 8}
+	session := quicTestSession(table, quicTestMetadata("10.0.0.1:1000", nil))
+	quicTestSession(table, quicTestMetadata("10.0.0.2:1000", nil))
+
+	// a rebound port of the same client
+	require.Same(t, session, table.lookup(quicTestMetadata("[::ffff:10.0.0.1]:1001", header)))
+
+	// another client to the same destination
+	require.Nil(t, table.lookup(quicTestMetadata("10.0.0.3:1000", header)))
+
+	metadata := quicTestMetadata("10.0.0.1:1001", header)
+	metadata.Destination = M.ParseSocksaddr("1.0.0.1:443")
+	require.Nil(t, table.lookup(metadata))
+
+	// ambiguous sessions of the same client
+	quicTestSession(table, quicTestMetadata("10.0.0.1:1002", nil))
+	require.Nil(t, table.lookup(quicTestMetadata("10.0.0.1:1003", header)))
+}
@@ -0,0 +1,101 @@
+package route
+
+import (
+	"net"
+	"sync/atomic"
+	"time"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing/common"
+	N "github.com/sagernet/sing/common/network"
+)
+
+// setKeepAlive applies the TCP keep alive override of the route options to
+// the inbound connection, negative idle disables keep alive.
+func setKeepAlive(conn net.Conn, idle time.Duration, interval time.Duration) error {
+	tcpConn, isTCP := common.Cast[*net.TCPConn](conn)
+	if !isTCP {
+		return nil
+	}
+	if idle < 0 {
+		return tcpConn.SetKeepAlive(false)
+	}
+	if idle == 0 {
+		idle = C.TCPKeepAliveInitial
+	}
+	if interval == 0 {
+		interval = C.TCPKeepAliveInterval
+	}
+	return tcpConn.SetKeepAliveConfig(net.KeepAliveConfig{
+		Enable:   true,
+		Idle:     idle,
+		Interval: interval,
+	})
+}
+
+var _ N.WriteCloser = (*idleConn)(nil)
+
+// idleConn closes the connection after no data is read or written for the
+// timeout.
+type idleConn struct {
+	net.Conn
+	timeout    time.Duration
+	lastActive atomic.Int64
+	closed     atomic.Bool
+}
+
+func newIdleConn(conn net.Conn, timeout time.Duration) *idleConn {
+	c := &idleConn{
+		Conn:    conn,
+		timeout: timeout,
+	}
+	c.lastActive.Store(time.Now().UnixNano())
+	time.AfterFunc(timeout, c.check)
+	return c
+}
+
+func (c *idleConn) check() {
+	if c.closed.Load() {
+		return
+	}
+	idle := time.Since(time.Unix(0, c.lastActive.Load()))
+	if idle >= c.timeout {
+		c.Close()
+		return
+	}
+	time.AfterFunc(c.timeout-idle, c.check)
+}
+
+func (c *idleConn) Read(b []byte) (n int, err error) {
+	n, err = c.Conn.Read(b)
+	if n > 0 {
+		c.lastActive.Store(time.Now().UnixNano())
+	}
+	return
+}
+
+func (c *idleConn) Write(b []byte) (n int, err error) {
+	n, err = c.Conn.Write(b)
+	if n > 0 {
+		c.lastActive.Store(time.Now().UnixNano())
+	}
+	return
+}
+
+// CloseWrite closes the connection if the upstream does not support half
+// close, as the copy waits for the peer otherwise.
+func (c *idleConn) CloseWrite() error {
+	if writeCloser, isWriteCloser := common.Cast[N.WriteCloser](c.Conn); isWriteCloser {
+		return writeCloser.CloseWrite()
+	}
+	return c.Close()
+}
+
+func (c *idleConn) Close() error {
+	c.closed.Store(true)
+	return c.Conn.Close()
+}
+
+func (c *idleConn) Upstream() any {
+	return c.Conn
+}
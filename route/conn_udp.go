@@ -0,0 +1,196 @@
+package route
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/x/list"
+)
+
+// udpSessionRefreshInterval bounds how often activity moves a session to the
+// back of its tables, so that busy sessions do not contend on table locks.
+const udpSessionRefreshInterval = time.Second
+
+const (
+	udpSessionTableGlobal = iota
+	udpSessionTableInbound
+	udpSessionTableCount
+)
+
+var udpSessionProtocols = []string{
+	C.ProtocolDNS,
+	C.ProtocolNTP,
+	C.ProtocolSTUN,
+	C.ProtocolQUIC,
+	C.ProtocolDTLS,
+	C.ProtocolBitTorrent,
+}
+
+// udpSessionTable keeps UDP sessions in least recently used order and
+// evicts the oldest one when a new session exceeds the limit.
+type udpSessionTable struct {
+	index    int
+	inbound  string
+	limit    int
+	access   sync.Mutex
+	sessions list.List[*udpSession]
+	evicted  atomic.Uint64
+}
+
+type udpSession struct {
+	conn        N.PacketConn
+	saved       *adapter.SavedUDPSession
+	lastRefresh atomic.Int64
+	tables      [udpSessionTableCount]*udpSessionTable
+	elements    [udpSessionTableCount]*list.Element[*udpSession]
+}
+
+func newUDPSessionTables(options option.UDPSessionOptions) (*udpSessionTable, map[string]*udpSessionTable, error) {
+	if options.MaxSessions < 0 {
+		return nil, nil, E.New("invalid `max_sessions`: ", options.MaxSessions)
+	}
+	globalTable := &udpSessionTable{
+		index: udpSessionTableGlobal,
+		limit: options.MaxSessions,
+	}
+	inboundTables := make(map[string]*udpSessionTable)
+	for inbound, limit := range options.InboundMaxSessions {
+		if limit <= 0 {
+			return nil, nil, E.New("invalid `inbound_max_sessions` for inbound ", inbound, ": ", limit)
+		}
+		inboundTables[inbound] = &udpSessionTable{
+			index:   udpSessionTableInbound,
+			inbound: inbound,
+			limit:   limit,
+		}
+	}
+	return globalTable, inboundTables, nil
+}
+
+func newUDPProtocolTimeouts(options option.UDPSessionOptions) (map[string]time.Duration, error) {
+	protocolTimeouts := make(map[string]time.Duration, len(C.ProtocolTimeouts)+len(options.ProtocolTimeouts))
+	for protocol, timeout := range C.ProtocolTimeouts {
+		protocolTimeouts[protocol] = timeout
+	}
+	for protocol, timeout := range options.ProtocolTimeouts {
+		if !common.Contains(udpSessionProtocols, protocol) {
+			return nil, E.New("unknown protocol in `protocol_timeouts`: ", protocol)
+		}
+		if timeout <= 0 {
+			return nil, E.New("invalid timeout for protocol ", protocol, ": ", timeout)
+		}
+		protocolTimeouts[protocol] = time.Duration(timeout)
+	}
+	return protocolTimeouts, nil
+}
+
+func (t *udpSessionTable) add(session *udpSession) *udpSession {
+	t.access.Lock()
+	defer t.access.Unlock()
+	var evicted *udpSession
+	if t.limit > 0 && t.sessions.Len() >= t.limit {
+		evicted = t.sessions.Remove(t.sessions.Front())
+		evicted.elements[t.index] = nil
+		t.evicted.Add(1)
+	}
+	session.tables[t.index] = t
+	session.elements[t.index] = t.sessions.PushBack(session)
+	return evicted
+}
+
+func (t *udpSessionTable) stats() adapter.UDPSessionTableStats {
+	t.access.Lock()
+	defer t.access.Unlock()
+	return adapter.UDPSessionTableStats{
+		Inbound: t.inbound,
+		Limit:   t.limit,
+		Active:  t.sessions.Len(),
+		Evicted: t.evicted.Load(),
+	}
+}
+
+func (s *udpSession) refresh(n int64) {
+	now := time.Now().UnixNano()
+	lastRefresh := s.lastRefresh.Load()
+	if now-lastRefresh < int64(udpSessionRefreshInterval) || !s.lastRefresh.CompareAndSwap(lastRefresh, now) {
+		return
+	}
+	for index, table := range s.tables {
+		if table == nil {
+			continue
+		}
+		table.access.Lock()
+		if element := s.elements[index]; element != nil {
+			table.sessions.MoveToBack(element)
+		}
+		table.access.Unlock()
+	}
+}
+
+func (s *udpSession) remove() {
+	for index, table := range s.tables {
+		if table == nil {
+			continue
+		}
+		table.access.Lock()
+		if element := s.elements[index]; element != nil {
+			table.sessions.Remove(element)
+			s.elements[index] = nil
+		}
+		table.access.Unlock()
+	}
+}
+
+// trackUDPSession adds the session to the global and inbound tables, closing
+// the least recently used sessions of tables that are full. Sessions are not
+// tracked if no table has a limit and the session is not saved for reload.
+func (m *ConnectionManager) trackUDPSession(ctx context.Context, conn N.PacketConn, metadata *adapter.InboundContext, saved *adapter.SavedUDPSession, onClose N.CloseHandlerFunc) (N.PacketConn, N.CloseHandlerFunc) {
+	inboundTable, hasInboundTable := m.inboundUDPSessions[metadata.Inbound]
+	if m.udpSessions.limit == 0 && !hasInboundTable && saved == nil {
+		return conn, onClose
+	}
+	session := &udpSession{conn: conn, saved: saved}
+	session.lastRefresh.Store(time.Now().UnixNano())
+	tables := []*udpSessionTable{m.udpSessions}
+	if hasInboundTable {
+		tables = append(tables, inboundTable)
+	}
+	for _, table := range tables {
+		evicted := table.add(session)
+		if evicted == nil {
+			continue
+		}
+		if table.inbound == "" {
+			m.logger.DebugContext(ctx, "udp session limit reached, evict least recently used session")
+		} else {
+			m.logger.DebugContext(ctx, "udp session limit reached for inbound ", table.inbound, ", evict least recently used session")
+		}
+		evicted.conn.Close()
+	}
+	refreshCounters := []N.CountFunc{session.refresh}
+	return bufio.NewCounterPacketConn(conn, refreshCounters, refreshCounters), N.AppendClose(onClose, func(it error) {
+		session.remove()
+	})
+}
+
+func (m *ConnectionManager) UDPSessionTables() []adapter.UDPSessionTableStats {
+	tables := make([]adapter.UDPSessionTableStats, 0, 1+len(m.inboundUDPSessions))
+	tables = append(tables, m.udpSessions.stats())
+	for _, table := range m.inboundUDPSessions {
+		tables = append(tables, table.stats())
+	}
+	sort.Slice(tables[1:], func(i, j int) bool {
+		return tables[i+1].Inbound < tables[j+1].Inbound
+	})
+	return tables
+}
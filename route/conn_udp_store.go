@@ -0,0 +1,121 @@
+package route
+
+import (
+	"net"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/service"
+)
+
+func udpSessionKey(inbound string, source M.Socksaddr, destination M.Socksaddr) string {
+	return inbound + "\x00" + source.String() + "\x00" + destination.String()
+}
+
+// loadUDPSessions loads UDP sessions saved by the previous instance, which
+// are restored when the client sends to the same destination again.
+func (m *ConnectionManager) loadUDPSessions() {
+	cacheFile := service.FromContext[adapter.CacheFile](m.ctx)
+	if cacheFile == nil || !cacheFile.StoreUDPSession() {
+		return
+	}
+	m.cacheFile = cacheFile
+	now := time.Now()
+	restoredSessions := make(map[string]adapter.SavedUDPSession)
+	for _, session := range cacheFile.LoadUDPSessions() {
+		if now.Sub(session.LastActive) > session.Timeout {
+			continue
+		}
+		restoredSessions[udpSessionKey(session.Inbound, session.Source, session.Destination)] = session
+	}
+	if len(restoredSessions) > 0 {
+		m.logger.Info("loaded ", len(restoredSessions), " udp sessions")
+	}
+	m.restoredUDPSessions = restoredSessions
+}
+
+// restoreUDPSession requests the outbound local port of the saved session for
+// the new session, if both are routed to the same direct outbound.
+func (m *ConnectionManager) restoreUDPSession(this N.Dialer, metadata *adapter.InboundContext) {
+	outbound, isOutbound := this.(adapter.Outbound)
+	if !isOutbound || outbound.Type() != C.TypeDirect {
+		return
+	}
+	key := udpSessionKey(metadata.Inbound, metadata.Source, metadata.Destination)
+	m.restoredUDPAccess.Lock()
+	session, loaded := m.restoredUDPSessions[key]
+	if loaded {
+		delete(m.restoredUDPSessions, key)
+	}
+	m.restoredUDPAccess.Unlock()
+	if !loaded || session.Outbound != outbound.Tag() || time.Since(session.LastActive) > session.Timeout {
+		return
+	}
+	metadata.UDPLocalPort = session.LocalPort
+}
+
+func (m *ConnectionManager) newSavedUDPSession(this N.Dialer, metadata *adapter.InboundContext, remotePacketConn net.PacketConn) *adapter.SavedUDPSession {
+	if m.cacheFile == nil {
+		return nil
+	}
+	outbound, isOutbound := this.(adapter.Outbound)
+	if !isOutbound || outbound.Type() != C.TypeDirect {
+		return nil
+	}
+	localAddr := M.SocksaddrFromNet(remotePacketConn.LocalAddr())
+	if localAddr.Port == 0 {
+		return nil
+	}
+	timeout := m.packetTimeout(metadata)
+	if timeout == 0 {
+		timeout = C.UDPTimeout
+	}
+	if metadata.UDPLocalPort != 0 {
+		if localAddr.Port == metadata.UDPLocalPort {
+			m.logger.Debug("restored udp session from ", metadata.Source, " to ", metadata.Destination, " at local port ", localAddr.Port)
+		} else {
+			m.logger.Debug("restore udp session from ", metadata.Source, " to ", metadata.Destination, ": local port ", metadata.UDPLocalPort, " is not available")
+		}
+	}
+	return &adapter.SavedUDPSession{
+		Inbound:     metadata.Inbound,
+		Source:      metadata.Source,
+		Destination: metadata.Destination,
+		Outbound:    outbound.Tag(),
+		LocalPort:   localAddr.Port,
+		Timeout:     timeout,
+	}
+}
+
+// SaveUDPSessions saves active UDP sessions to the cache file, so that the
+// next instance can restore them. It is called before the instance is
+// closed.
+func (m *ConnectionManager) SaveUDPSessions() {
+	if m.cacheFile == nil {
+		return
+	}
+	var sessions []adapter.SavedUDPSession
+	m.udpSessions.access.Lock()
+	for element := m.udpSessions.sessions.Front(); element != nil; element = element.Next() {
+		session := element.Value
+		if session.saved == nil {
+			continue
+		}
+		savedSession := *session.saved
+		savedSession.LastActive = time.Unix(0, session.lastRefresh.Load())
+		sessions = append(sessions, savedSession)
+	}
+	m.udpSessions.access.Unlock()
+	if len(sessions) == 0 {
+		return
+	}
+	err := m.cacheFile.SaveUDPSessions(sessions)
+	if err != nil {
+		m.logger.Error("save udp sessions: ", err)
+		return
+	}
+	m.logger.Info("saved ", len(sessions), " udp sessions")
+}
@@ -0,0 +1,224 @@
+package route
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/eventbus"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	R "github.com/sagernet/sing-box/route/rule"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+const (
+	defaultDrainTimeout = time.Minute
+	drainLogInterval    = 5 * time.Second
+)
+
+// drainManager counts the routed connections of each inbound, so that a
+// drain can close the listeners of the drained inbounds, reject new
+// connections of them and wait for the existing ones.
+type drainManager struct {
+	logger    log.ContextLogger
+	options   option.DrainOptions
+	access    sync.Mutex
+	active    map[string]int
+	listeners map[string][]*drainListener
+	status    adapter.DrainStatus
+	inbounds  map[string]bool
+	changed   chan struct{}
+	done      chan struct{}
+}
+
+type drainListener struct {
+	io.Closer
+}
+
+func newDrainManager(logger log.ContextLogger, options option.DrainOptions) *drainManager {
+	return &drainManager{
+		logger:    logger,
+		options:   options,
+		active:    make(map[string]int),
+		listeners: make(map[string][]*drainListener),
+		changed:   make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+}
+
+func (m *drainManager) registerListener(inbound string, listener io.Closer) func() {
+	m.access.Lock()
+	defer m.access.Unlock()
+	if m.isDraining(inbound) {
+		m.closeListener(inbound, listener)
+		return func() {}
+	}
+	registered := &drainListener{listener}
+	m.listeners[inbound] = append(m.listeners[inbound], registered)
+	return func() {
+		m.access.Lock()
+		defer m.access.Unlock()
+		m.listeners[inbound] = common.Filter(m.listeners[inbound], func(it *drainListener) bool {
+			return it != registered
+		})
+		if len(m.listeners[inbound]) == 0 {
+			delete(m.listeners, inbound)
+		}
+	}
+}
+
+func (m *drainManager) closeListener(inbound string, listener io.Closer) {
+	err := listener.Close()
+	if err != nil {
+		m.logger.Warn(E.Cause(err, "close listener of inbound ", inbound))
+	}
+}
+
+// acquire counts a connection of the inbound, it fails if the inbound is
+// draining.
+func (m *drainManager) acquire(inbound string) (func(), error) {
+	m.access.Lock()
+	defer m.access.Unlock()
+	if m.isDraining(inbound) {
+		return nil, &R.RejectedError{Cause: E.New("inbound ", inbound, " is draining")}
+	}
+	m.active[inbound]++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.release(inbound)
+		})
+	}, nil
+}
+
+func (m *drainManager) release(inbound string) {
+	m.access.Lock()
+	m.active[inbound]--
+	if m.active[inbound] <= 0 {
+		delete(m.active, inbound)
+	}
+	draining := m.status.Draining
+	m.access.Unlock()
+	if draining {
+		select {
+		case m.changed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (m *drainManager) isDraining(inbound string) bool {
+	return m.status.Draining && (m.inbounds == nil || m.inbounds[inbound])
+}
+
+func (m *drainManager) drain(eventBus adapter.EventBus, inbounds []string, timeout time.Duration) error {
+	if len(inbounds) == 0 {
+		inbounds = m.options.Inbounds
+	}
+	if timeout == 0 {
+		timeout = time.Duration(m.options.Timeout)
+	}
+	if timeout == 0 {
+		timeout = defaultDrainTimeout
+	}
+	m.access.Lock()
+	if m.status.Draining {
+		m.access.Unlock()
+		return E.New("already draining")
+	}
+	if len(inbounds) > 0 {
+		m.inbounds = make(map[string]bool, len(inbounds))
+		for _, inbound := range inbounds {
+			m.inbounds[inbound] = true
+		}
+	}
+	now := time.Now()
+	m.status = adapter.DrainStatus{
+		Draining:  true,
+		Inbounds:  common.Uniq(inbounds),
+		StartedAt: now,
+		Deadline:  now.Add(timeout),
+	}
+	m.status.Total = m.remaining()
+	m.status.Active = m.status.Total
+	for inbound, listeners := range m.listeners {
+		if !m.isDraining(inbound) {
+			continue
+		}
+		for _, listener := range listeners {
+			m.closeListener(inbound, listener)
+		}
+		delete(m.listeners, inbound)
+	}
+	m.access.Unlock()
+	if len(inbounds) > 0 {
+		m.logger.Info("draining inbounds ", inbounds, ", ", m.status.Total, " connections, timeout ", timeout)
+	} else {
+		m.logger.Info("draining all inbounds, ", m.status.Total, " connections, timeout ", timeout)
+	}
+	go m.wait(eventbus.StartTask(eventBus, adapter.TaskDrain, ""), timeout)
+	return nil
+}
+
+// remaining returns the routed connections of drained inbounds, the lock
+// must be held.
+func (m *drainManager) remaining() int {
+	var count int
+	for inbound, active := range m.active {
+		if m.inbounds == nil || m.inbounds[inbound] {
+			count += active
+		}
+	}
+	return count
+}
+
+func (m *drainManager) wait(task *eventbus.Task, timeout time.Duration) {
+	defer close(m.done)
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(drainLogInterval)
+	defer ticker.Stop()
+	for {
+		m.access.Lock()
+		remaining := m.remaining()
+		m.status.Active = remaining
+		total := m.status.Total
+		m.access.Unlock()
+		task.Progress(int64(remaining), int64(total))
+		if remaining == 0 {
+			m.finish()
+			m.logger.Info("drain finished")
+			task.Finish(nil)
+			return
+		}
+		select {
+		case <-m.changed:
+		case <-ticker.C:
+			m.logger.Info("draining, ", remaining, " connections remaining")
+		case <-deadline.C:
+			m.finish()
+			m.logger.Warn("drain timed out, ", remaining, " connections remaining")
+			task.Finish(E.New("timed out, ", remaining, " connections remaining"))
+			return
+		}
+	}
+}
+
+func (m *drainManager) finish() {
+	m.access.Lock()
+	m.status.Finished = true
+	m.access.Unlock()
+}
+
+func (m *drainManager) statusSnapshot() adapter.DrainStatus {
+	m.access.Lock()
+	defer m.access.Unlock()
+	status := m.status
+	if status.Draining && !status.Finished {
+		status.Active = m.remaining()
+	}
+	return status
+}
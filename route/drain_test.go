@@ -0,0 +1,43 @@
+package route
+
+import (
+	"testing"
+
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testCloser struct {
+	closed bool
+}
+
+func (c *testCloser) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestDrainClosesListeners(t *testing.T) {
+	t.Parallel()
+	manager := newDrainManager(log.NewNOPFactory().Logger(), option.DrainOptions{})
+	drained := &testCloser{}
+	other := &testCloser{}
+	unregistered := &testCloser{}
+	manager.registerListener("drained", drained)
+	manager.registerListener("other", other)
+	manager.registerListener("drained", unregistered)()
+	require.NoError(t, manager.drain(nil, []string{"drained"}, 0))
+	<-manager.done
+	require.True(t, drained.closed)
+	require.False(t, other.closed)
+	require.False(t, unregistered.closed)
+	late := &testCloser{}
+	manager.registerListener("drained", late)
+	require.True(t, late.closed)
+	_, err := manager.acquire("drained")
+	require.Error(t, err)
+	release, err := manager.acquire("other")
+	require.NoError(t, err)
+	release()
+}
@@ -0,0 +1,199 @@
+package route
+
+import (
+	"context"
+	"encoding/binary"
+	"sync/atomic"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/urltest"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-tun"
+	"github.com/sagernet/sing/common/buf"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	"github.com/sagernet/sing/service"
+)
+
+var _ tun.DirectRouteDestination = (*icmpReplyDestination)(nil)
+
+// icmpReplyDestination answers ICMP echo requests for outbounds that cannot relay ICMP,
+// each reply is delayed by the measured latency of the outbound.
+type icmpReplyDestination struct {
+	ctx          context.Context
+	logger       logger.ContextLogger
+	routeContext tun.DirectRouteContext
+	timeout      time.Duration
+	ready        chan struct{}
+	delay        time.Duration
+	err          error
+	closed       atomic.Bool
+}
+
+func (r *Router) newICMPReplyDestination(metadata adapter.InboundContext, outbound adapter.Outbound, routeContext tun.DirectRouteContext, timeout time.Duration) *icmpReplyDestination {
+	ctx := log.ContextWithNewID(r.ctx)
+	destination := &icmpReplyDestination{
+		ctx:          ctx,
+		logger:       r.logger,
+		routeContext: routeContext,
+		timeout:      timeout,
+		ready:        make(chan struct{}),
+	}
+	r.logger.InfoContext(ctx, "reply ", metadata.Network, " connection from ", metadata.Source.AddrString(), " to ", metadata.Destination.AddrString(), " with latency of outbound/", outbound.Type(), "[", outbound.Tag(), "]")
+	go func() {
+		destination.delay, destination.err = r.outboundDelay(ctx, outbound)
+		if destination.err != nil {
+			r.logger.DebugContext(ctx, "measure latency of outbound/", outbound.Type(), "[", outbound.Tag(), "]: ", destination.err)
+		}
+		close(destination.ready)
+	}()
+	return destination
+}
+
+func (r *Router) outboundDelay(ctx context.Context, outbound adapter.Outbound) (time.Duration, error) {
+	for {
+		group, isGroup := outbound.(adapter.OutboundGroup)
+		if !isGroup {
+			break
+		}
+		selected, loaded := r.outbound.Outbound(group.Now())
+		if !loaded {
+			break
+		}
+		outbound = selected
+	}
+	var history adapter.URLTestHistoryStorage
+	if historyFromCtx := service.PtrFromContext[urltest.HistoryStorage](r.ctx); historyFromCtx != nil {
+		history = historyFromCtx
+	} else if clashServer := service.FromContext[adapter.ClashServer](r.ctx); clashServer != nil {
+		history = clashServer.HistoryStorage()
+	}
+	if history != nil {
+		testHistory := history.LoadURLTestHistory(outbound.Tag())
+		if testHistory != nil && time.Since(testHistory.Time) < C.DefaultURLTestInterval {
+			return time.Duration(testHistory.Delay) * time.Millisecond, nil
+		}
+	}
+	testCtx, cancel := context.WithTimeout(ctx, C.TCPTimeout)
+	defer cancel()
+	delay, err := urltest.URLTest(testCtx, "", outbound)
+	if err != nil {
+		return 0, err
+	}
+	if history != nil {
+		history.StoreURLTestHistory(outbound.Tag(), &adapter.URLTestHistory{
+			Time:  time.Now(),
+			Delay: delay,
+		})
+	}
+	return time.Duration(delay) * time.Millisecond, nil
+}
+
+func (d *icmpReplyDestination) WritePacket(packet *buf.Buffer) error {
+	err := makeICMPEchoReply(packet.Bytes())
+	if err != nil {
+		packet.Release()
+		return err
+	}
+	requestTime := time.Now()
+	go func() {
+		defer packet.Release()
+		select {
+		case <-d.ready:
+		case <-time.After(d.timeout):
+			return
+		}
+		if d.err != nil {
+			return
+		}
+		if wait := time.Until(requestTime.Add(d.delay)); wait > 0 {
+			time.Sleep(wait)
+		}
+		if d.closed.Load() {
+			return
+		}
+		writeErr := d.routeContext.WritePacket(packet.Bytes())
+		if writeErr != nil {
+			d.logger.ErrorContext(d.ctx, E.Cause(writeErr, "write ICMP echo reply"))
+		}
+	}()
+	return nil
+}
+
+func (d *icmpReplyDestination) Close() error {
+	d.closed.Store(true)
+	return nil
+}
+
+func (d *icmpReplyDestination) IsClosed() bool {
+	return d.closed.Load()
+}
+
+// makeICMPEchoReply rewrites an ICMP or ICMPv6 echo request packet into the echo reply in place.
+func makeICMPEchoReply(packet []byte) error {
+	if len(packet) == 0 {
+		return E.New("empty packet")
+	}
+	switch packet[0] >> 4 {
+	case 4:
+		headerLen := int(packet[0]&0x0f) * 4
+		if headerLen < 20 || len(packet) < headerLen+8 || packet[9] != 1 {
+			return E.New("invalid ICMPv4 packet")
+		}
+		icmpHdr := packet[headerLen:]
+		if icmpHdr[0] != 8 {
+			return E.New("not an ICMPv4 echo request")
+		}
+		swapAddress(packet[12:16], packet[16:20])
+		packet[8] = 64
+		icmpHdr[0] = 0
+		binary.BigEndian.PutUint16(icmpHdr[2:], 0)
+		binary.BigEndian.PutUint16(icmpHdr[2:], checksum(icmpHdr, 0))
+		binary.BigEndian.PutUint16(packet[10:], 0)
+		binary.BigEndian.PutUint16(packet[10:], checksum(packet[:headerLen], 0))
+	case 6:
+		if len(packet) < 48 || packet[6] != 58 {
+			return E.New("invalid ICMPv6 packet")
+		}
+		icmpHdr := packet[40:]
+		if icmpHdr[0] != 128 {
+			return E.New("not an ICMPv6 echo request")
+		}
+		swapAddress(packet[8:24], packet[24:40])
+		packet[7] = 64
+		icmpHdr[0] = 129
+		binary.BigEndian.PutUint16(icmpHdr[2:], 0)
+		pseudoHeaderSum := checksumAdd(packet[8:40], uint32(len(icmpHdr))+58)
+		binary.BigEndian.PutUint16(icmpHdr[2:], checksum(icmpHdr, pseudoHeaderSum))
+	default:
+		return E.New("unknown IP version")
+	}
+	return nil
+}
+
+func swapAddress(source []byte, destination []byte) {
+	for i := range source {
+		source[i], destination[i] = destination[i], source[i]
+	}
+}
+
+func checksumAdd(data []byte, initial uint32) uint32 {
+	sum := initial
+	for i := 0; i+1 < len(data); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(data[i:]))
+	}
+	if len(data)%2 == 1 {
+		sum += uint32(data[len(data)-1]) << 8
+	}
+	return sum
+}
+
+func checksum(data []byte, initial uint32) uint16 {
+	sum := checksumAdd(data, initial)
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
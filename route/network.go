@@ -8,6 +8,7 @@ import (
 	"os"
 	"runtime"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -50,6 +51,8 @@ type NetworkManager struct {
 	inbound                adapter.InboundManager
 	outbound               adapter.OutboundManager
 	wifiState              adapter.WIFIState
+	portMapperAccess       sync.Mutex
+	portMappers            []adapter.PortMapper
 	started                bool
 }
 
@@ -343,8 +346,8 @@ func (r *NetworkManager) DefaultOptions() adapter.NetworkOptions {
 }
 
 func (r *NetworkManager) RegisterAutoRedirectOutputMark(mark uint32) error {
-	if r.autoRedirectOutputMark > 0 {
-		return E.New("only one auto-redirect can be configured")
+	if r.autoRedirectOutputMark > 0 && r.autoRedirectOutputMark != mark {
+		return E.New("only one auto-redirect output mark can be configured")
 	}
 	r.autoRedirectOutputMark = mark
 	return nil
@@ -363,6 +366,26 @@ func (r *NetworkManager) AutoRedirectOutputMarkFunc() control.Func {
 	}
 }
 
+func (r *NetworkManager) RegisterPortMapper(mapper adapter.PortMapper) {
+	r.portMapperAccess.Lock()
+	defer r.portMapperAccess.Unlock()
+	r.portMappers = append(r.portMappers, mapper)
+}
+
+func (r *NetworkManager) UnregisterPortMapper(mapper adapter.PortMapper) {
+	r.portMapperAccess.Lock()
+	defer r.portMapperAccess.Unlock()
+	r.portMappers = common.Filter(r.portMappers, func(it adapter.PortMapper) bool {
+		return it != mapper
+	})
+}
+
+func (r *NetworkManager) PortMappings() []adapter.PortMapping {
+	r.portMapperAccess.Lock()
+	defer r.portMapperAccess.Unlock()
+	return common.Map(r.portMappers, adapter.PortMapper.PortMapping)
+}
+
 func (r *NetworkManager) NetworkMonitor() tun.NetworkUpdateMonitor {
 	return r.networkMonitor
 }
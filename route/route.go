@@ -60,7 +60,7 @@ func (r *Router) RouteConnectionEx(ctx context.Context, conn net.Conn, metadata
 	}
 }
 
-func (r *Router) routeConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) error {
+func (r *Router) routeConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) (err error) {
 	//nolint:staticcheck
 	if metadata.InboundDetour != "" {
 		if metadata.LastInbound == metadata.InboundDetour {
@@ -81,6 +81,33 @@ func (r *Router) routeConnection(ctx context.Context, conn net.Conn, metadata ad
 		return nil
 	}
 	conntrack.KillerCheck()
+	releaseDrain, err := r.drain.acquire(metadata.Inbound)
+	if err != nil {
+		return err
+	}
+	releaseUser, err := r.users.acquire(&metadata, conn)
+	if err != nil {
+		releaseDrain()
+		return err
+	}
+	releaseBudget, err := r.budget.acquireConnection(ctx, &metadata)
+	if err != nil {
+		releaseUser()
+		releaseDrain()
+		return err
+	}
+	defer func() {
+		if err != nil {
+			releaseBudget()
+			releaseUser()
+			releaseDrain()
+		}
+	}()
+	onClose = N.AppendClose(onClose, func(it error) {
+		releaseBudget()
+		releaseUser()
+		releaseDrain()
+	})
 	metadata.Network = N.NetworkTCP
 	switch metadata.Destination.Fqdn {
 	case mux.Destination.Fqdn:
@@ -182,7 +209,7 @@ func (r *Router) RoutePacketConnectionEx(ctx context.Context, conn N.PacketConn,
 	}
 }
 
-func (r *Router) routePacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) error {
+func (r *Router) routePacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) (err error) {
 	//nolint:staticcheck
 	if metadata.InboundDetour != "" {
 		if metadata.LastInbound == metadata.InboundDetour {
@@ -203,6 +230,33 @@ func (r *Router) routePacketConnection(ctx context.Context, conn N.PacketConn, m
 		return nil
 	}
 	conntrack.KillerCheck()
+	releaseDrain, err := r.drain.acquire(metadata.Inbound)
+	if err != nil {
+		return err
+	}
+	releaseUser, err := r.users.acquire(&metadata, conn)
+	if err != nil {
+		releaseDrain()
+		return err
+	}
+	releaseBudget, err := r.budget.acquireConnection(ctx, &metadata)
+	if err != nil {
+		releaseUser()
+		releaseDrain()
+		return err
+	}
+	defer func() {
+		if err != nil {
+			releaseBudget()
+			releaseUser()
+			releaseDrain()
+		}
+	}()
+	onClose = N.AppendClose(onClose, func(it error) {
+		releaseBudget()
+		releaseUser()
+		releaseDrain()
+	})
 
 	// TODO: move to UoT
 	metadata.Network = N.NetworkUDP
@@ -249,6 +303,9 @@ func (r *Router) routePacketConnection(ctx context.Context, conn N.PacketConn, m
 		}
 		selectedOutbound = defaultOutbound
 	}
+	if metadata.Protocol == "" && len(packetBuffers) > 0 {
+		metadata.QUICShortHeader = quicShortHeader(packetBuffers[0].Buffer.Bytes())
+	}
 	for _, buffer := range packetBuffers {
 		conn = bufio.NewCachedPacketConn(conn, buffer.Buffer, buffer.Destination)
 		N.PutPacketBuffer(buffer)
@@ -296,6 +353,9 @@ func (r *Router) PreMatch(metadata adapter.InboundContext, routeContext tun.Dire
 				return nil, E.New("outbound not found: ", action.Outbound)
 			}
 			if !common.Contains(outbound.Network(), metadata.Network) {
+				if metadata.Network == N.NetworkICMP {
+					return r.newICMPReplyDestination(metadata, outbound, routeContext, timeout), nil
+				}
 				return nil, E.New(metadata.Network, " is not supported by outbound: ", action.Outbound)
 			}
 			directRouteOutbound = outbound.(adapter.DirectRouteOutbound)
@@ -307,7 +367,7 @@ func (r *Router) PreMatch(metadata adapter.InboundContext, routeContext tun.Dire
 		}
 		defaultOutbound := r.outbound.Default()
 		if !common.Contains(defaultOutbound.Network(), metadata.Network) {
-			return nil, E.New(metadata.Network, " is not supported by default outbound: ", defaultOutbound.Tag())
+			return r.newICMPReplyDestination(metadata, defaultOutbound, routeContext, timeout), nil
 		}
 		directRouteOutbound = defaultOutbound.(adapter.DirectRouteOutbound)
 	}
@@ -532,6 +592,21 @@ match:
 			if routeOptions.UDPTimeout > 0 {
 				metadata.UDPTimeout = routeOptions.UDPTimeout
 			}
+			if routeOptions.UDPNATMapping != "" {
+				metadata.UDPNATMapping = routeOptions.UDPNATMapping
+			}
+			if routeOptions.UDPNATFiltering != "" {
+				metadata.UDPNATFiltering = routeOptions.UDPNATFiltering
+			}
+			if routeOptions.UDPOverTCP != "" {
+				metadata.UDPOverTCP = routeOptions.UDPOverTCP
+			}
+			if routeOptions.Multiplex != "" {
+				metadata.Multiplex = routeOptions.Multiplex
+			}
+			if routeOptions.MultiplexPadding {
+				metadata.MultiplexPadding = true
+			}
 			if routeOptions.TLSFragment {
 				metadata.TLSFragment = true
 				metadata.TLSFragmentFallbackDelay = routeOptions.TLSFragmentFallbackDelay
@@ -539,6 +614,32 @@ match:
 			if routeOptions.TLSRecordFragment {
 				metadata.TLSRecordFragment = true
 			}
+			if routeOptions.RoutingMark != 0 {
+				metadata.RoutingMark = routeOptions.RoutingMark
+			}
+			if routeOptions.DSCP != 0 {
+				metadata.DSCP = routeOptions.DSCP
+			}
+			if routeOptions.TCPKeepAlive != 0 {
+				metadata.TCPKeepAlive = routeOptions.TCPKeepAlive
+			}
+			if routeOptions.TCPKeepAliveInterval > 0 {
+				metadata.TCPKeepAliveInterval = routeOptions.TCPKeepAliveInterval
+			}
+			if routeOptions.TCPIdleTimeout > 0 {
+				metadata.TCPIdleTimeout = routeOptions.TCPIdleTimeout
+			}
+			if routeOptions.TCPDisableHalfClose {
+				metadata.TCPDisableHalfClose = true
+				metadata.TCPHalfCloseTimeout = 0
+			}
+			if routeOptions.TCPHalfCloseTimeout > 0 {
+				metadata.TCPHalfCloseTimeout = routeOptions.TCPHalfCloseTimeout
+				metadata.TCPDisableHalfClose = false
+			}
+			if routeOptions.Chaos != nil {
+				metadata.Chaos = routeOptions.Chaos
+			}
 		}
 		switch action := currentRule.Action().(type) {
 		case *R.RuleActionSniff:
@@ -580,9 +681,20 @@ func (r *Router) actionSniff(
 	ctx context.Context, metadata *adapter.InboundContext, action *R.RuleActionSniff,
 	inputConn net.Conn, inputPacketConn N.PacketConn, inputBuffers []*buf.Buffer, inputPacketBuffers []*N.PacketBuffer,
 ) (buffer *buf.Buffer, packetBuffers []*N.PacketBuffer, fatalErr error) {
+	if inboundSniff, loaded := r.inboundSniff[metadata.Inbound]; loaded {
+		action = action.WithDefaults(inboundSniff)
+	} else {
+		action = action.WithDefaults(r.sniffDefaults)
+	}
+	if action.Timeout == 0 {
+		action.Timeout = C.ReadPayloadTimeout
+	}
 	if sniff.Skip(metadata) {
 		r.logger.DebugContext(ctx, "sniff skipped due to port considered as server-first")
 		return
+	} else if observedTimeout, serverFirst := r.serverFirst.Get(serverFirstKey{metadata.Source.Addr, metadata.Destination}); serverFirst && inputConn != nil && action.Timeout <= observedTimeout {
+		r.logger.DebugContext(ctx, "sniff skipped due to destination observed as server-first")
+		return
 	} else if metadata.Protocol != "" {
 		r.logger.DebugContext(ctx, "duplicate sniff skipped")
 		return
@@ -607,7 +719,16 @@ func (r *Router) actionSniff(
 				sniff.RDP,
 			}
 		}
-		sniffBuffer := buf.NewPacket()
+		bufferSize := sniffBufferSize
+		if action.BufferSize > 0 {
+			bufferSize = int64(action.BufferSize)
+		}
+		if !r.budget.acquireBuffer(ctx, metadata.Inbound, bufferSize) {
+			r.logger.DebugContext(ctx, "sniff skipped due to buffer budget")
+			return
+		}
+		defer r.budget.releaseBuffer(metadata.Inbound, bufferSize)
+		sniffBuffer := buf.NewSize(int(bufferSize))
 		err := sniff.PeekStream(
 			ctx,
 			metadata,
@@ -619,6 +740,10 @@ func (r *Router) actionSniff(
 		)
 		metadata.SnifferNames = action.SnifferNames
 		metadata.SniffError = err
+		if err != nil && sniffBuffer.IsEmpty() && E.IsTimeout(err) {
+			// the client sent nothing within the timeout, do not stall the next connection again
+			r.serverFirst.Add(serverFirstKey{metadata.Source.Addr, metadata.Destination}, action.Timeout)
+		}
 		if err == nil {
 			if metadata.SniffHost != "" && metadata.Client != "" {
 				r.logger.DebugContext(ctx, "sniffed protocol: ", metadata.Protocol, ", domain: ", metadata.SniffHost, ", client: ", metadata.Client)
@@ -665,7 +790,15 @@ func (r *Router) actionSniff(
 				sniff.NTP,
 			}
 		}
-		var err error
+		var (
+			err            error
+			bufferReserved int64
+		)
+		defer func() {
+			if bufferReserved > 0 {
+				r.budget.releaseBuffer(metadata.Inbound, bufferReserved)
+			}
+		}()
 		for _, packetBuffer := range inputPacketBuffers {
 			if quicMoreData() {
 				err = sniff.PeekPacket(
@@ -692,17 +825,19 @@ func (r *Router) actionSniff(
 		}
 		packetBuffers = inputPacketBuffers
 		for {
+			if !r.budget.acquireBuffer(ctx, metadata.Inbound, sniffBufferSize) {
+				r.logger.DebugContext(ctx, "packet sniff skipped due to buffer budget")
+				err = E.New("sniff buffer budget exhausted")
+				goto finally
+			}
+			bufferReserved += sniffBufferSize
 			var (
 				sniffBuffer = buf.NewPacket()
 				destination M.Socksaddr
 				done        = make(chan struct{})
 			)
 			go func() {
-				sniffTimeout := C.ReadPayloadTimeout
-				if action.Timeout > 0 {
-					sniffTimeout = action.Timeout
-				}
-				inputPacketConn.SetReadDeadline(time.Now().Add(sniffTimeout))
+				inputPacketConn.SetReadDeadline(time.Now().Add(action.Timeout))
 				destination, err = inputPacketConn.ReadPacket(sniffBuffer)
 				inputPacketConn.SetReadDeadline(time.Time{})
 				close(done)
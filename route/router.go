@@ -2,10 +2,13 @@ package route
 
 import (
 	"context"
+	"io"
 	"os"
 	"runtime"
+	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/convertor/link"
 	"github.com/sagernet/sing-box/common/process"
 	"github.com/sagernet/sing-box/common/taskmonitor"
 	C "github.com/sagernet/sing-box/constant"
@@ -13,8 +16,10 @@ import (
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
 	R "github.com/sagernet/sing-box/route/rule"
+	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/task"
+	"github.com/sagernet/sing/contrab/freelru"
 	"github.com/sagernet/sing/service"
 	"github.com/sagernet/sing/service/pause"
 )
@@ -41,6 +46,13 @@ type Router struct {
 	needWIFIState     bool
 	started           bool
 	reloadChan        chan<- struct{}
+	budget            *budgetManager
+	users             *userManager
+	drain             *drainManager
+	serverFirst       freelru.Cache[serverFirstKey, time.Duration]
+	sniffOptions      option.RouteSniffOptions
+	sniffDefaults     *R.RuleActionSniff
+	inboundSniff      map[string]*R.RuleActionSniff
 }
 
 func NewRouter(ctx context.Context, logFactory log.Factory, options option.RouteOptions, dnsOptions option.DNSOptions, reloadChan chan<- struct{}) *Router {
@@ -60,10 +72,28 @@ func NewRouter(ctx context.Context, logFactory log.Factory, options option.Route
 		platformInterface: service.FromContext[platform.Interface](ctx),
 		needWIFIState:     hasRule(options.Rules, isWIFIRule) || hasDNSRule(dnsOptions.Rules, isWIFIDNSRule),
 		reloadChan:        reloadChan,
+		budget:            newBudgetManager(common.PtrValueOrDefault(options.Budget)),
+		users:             newUserManager(logFactory.NewLogger("user")),
+		drain:             newDrainManager(logFactory.NewLogger("drain"), common.PtrValueOrDefault(options.Drain)),
+		serverFirst:       newServerFirstCache(),
+		sniffOptions:      common.PtrValueOrDefault(options.Sniff),
 	}
 }
 
 func (r *Router) Initialize(rules []option.Rule, ruleSets []option.RuleSet) error {
+	sniffDefaults, err := R.NewRuleActionSniff(r.ctx, r.sniffOptions.RouteActionSniff)
+	if err != nil {
+		return E.Cause(err, "parse sniff options")
+	}
+	r.sniffDefaults = sniffDefaults
+	r.inboundSniff = make(map[string]*R.RuleActionSniff, len(r.sniffOptions.Inbounds))
+	for tag, options := range r.sniffOptions.Inbounds {
+		inboundSniff, err := R.NewRuleActionSniff(r.ctx, options)
+		if err != nil {
+			return E.Cause(err, "parse sniff options for inbound ", tag)
+		}
+		r.inboundSniff[tag] = inboundSniff.WithDefaults(sniffDefaults)
+	}
 	for i, options := range rules {
 		rule, err := R.NewRule(r.ctx, r.logger, options, false)
 		if err != nil {
@@ -175,6 +205,7 @@ func (r *Router) Start(stage adapter.StartStage) error {
 func (r *Router) Close() error {
 	monitor := taskmonitor.New(r.logger, C.StopTimeout)
 	var err error
+	r.users.close()
 	for i, rule := range r.rules {
 		monitor.Start("close rule[", i, "]")
 		err = E.Append(err, rule.Close(), func(err error) error {
@@ -218,6 +249,98 @@ func (r *Router) ResetNetwork() {
 	//r.dns.ResetNetwork()
 }
 
+func (r *Router) Budgets() []adapter.BudgetStats {
+	return r.budget.stats()
+}
+
+// RegisterUsers sets the users of a multi-user inbound, whose status is
+// checked for its connections.
+func (r *Router) RegisterUsers(inbound string, users []option.InboundUser) {
+	r.users.register(inbound, users)
+}
+
+func (r *Router) Users() []adapter.UserStatus {
+	return r.users.status()
+}
+
+func (r *Router) UpdateUser(inbound string, name string, update adapter.UserStatusUpdate) error {
+	return r.users.update(inbound, name, update)
+}
+
+// SetInboundUser adds or replaces a user of a running inbound from its JSON
+// in the user format of the inbound.
+func (r *Router) SetInboundUser(inbound string, content []byte) (option.InboundUser, error) {
+	managedInbound, err := r.managedUserInbound(inbound)
+	if err != nil {
+		return option.InboundUser{}, err
+	}
+	user, err := managedInbound.SetUser(content)
+	if err != nil {
+		return option.InboundUser{}, err
+	}
+	r.users.register(inbound, []option.InboundUser{user})
+	return user, nil
+}
+
+// RemoveInboundUser removes a user of a running inbound and closes its
+// connections.
+func (r *Router) RemoveInboundUser(inbound string, name string) error {
+	managedInbound, err := r.managedUserInbound(inbound)
+	if err != nil {
+		return err
+	}
+	err = managedInbound.RemoveUser(name)
+	if err != nil {
+		return err
+	}
+	r.users.unregister(inbound, name)
+	return nil
+}
+
+// InboundUserLink returns the share link of a user of a running inbound,
+// server overrides the listen address of the inbound.
+func (r *Router) InboundUserLink(inbound string, name string, server string) (string, error) {
+	managedInbound, err := r.managedUserInbound(inbound)
+	if err != nil {
+		return "", err
+	}
+	return link.InboundUserLink(managedInbound.InboundOptions(), name, server)
+}
+
+func (r *Router) Drain(inbounds []string, timeout time.Duration) error {
+	for _, tag := range inbounds {
+		if _, loaded := r.inbound.Get(tag); !loaded {
+			return E.New("inbound not found: ", tag)
+		}
+	}
+	return r.drain.drain(service.FromContext[adapter.EventBus](r.ctx), inbounds, timeout)
+}
+
+func (r *Router) DrainStatus() adapter.DrainStatus {
+	return r.drain.statusSnapshot()
+}
+
+func (r *Router) RegisterDrainListener(inbound string, listener io.Closer) func() {
+	return r.drain.registerListener(inbound, listener)
+}
+
+// DrainDone is closed when a drain finished or timed out.
+func (r *Router) DrainDone() <-chan struct{} {
+	return r.drain.done
+}
+
+func (r *Router) managedUserInbound(tag string) (adapter.ManagedUserInbound, error) {
+	inbound, loaded := r.inbound.Get(tag)
+	if !loaded {
+		return nil, E.New("inbound not found: ", tag)
+	}
+	managedInbound, isManaged := inbound.(adapter.ManagedUserInbound)
+	if !isManaged {
+		return nil, E.New("users of ", inbound.Type(), " inbound cannot be changed at runtime")
+	}
+	return managedInbound, nil
+}
+
 func (r *Router) Reload() {
 	if r.platformInterface == nil {
 		select {
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/chaos"
 	"github.com/sagernet/sing-box/common/dialer"
 	"github.com/sagernet/sing-box/common/sniff"
 	C "github.com/sagernet/sing-box/constant"
@@ -20,6 +21,7 @@ import (
 	"github.com/sagernet/sing/common/logger"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/service"
 
 	"github.com/miekg/dns"
 )
@@ -29,6 +31,30 @@ func NewRuleAction(ctx context.Context, logger logger.ContextLogger, action opti
 	case "":
 		return nil, nil
 	case C.RuleActionTypeRoute:
+		err := checkNATBehavior(action.RouteOptions.UDPNATMapping, action.RouteOptions.UDPNATFiltering)
+		if err != nil {
+			return nil, err
+		}
+		err = checkUDPOverTCP(action.RouteOptions.UDPOverTCP)
+		if err != nil {
+			return nil, err
+		}
+		err = checkMultiplex(action.RouteOptions.Multiplex, action.RouteOptions.MultiplexPadding)
+		if err != nil {
+			return nil, err
+		}
+		err = checkSocketOptions(action.RouteOptions.RoutingMark, action.RouteOptions.DSCP)
+		if err != nil {
+			return nil, err
+		}
+		err = checkTCPOptions(action.RouteOptions.RawRouteOptionsActionOptions)
+		if err != nil {
+			return nil, err
+		}
+		chaosConfig, err := newChaosConfig(action.RouteOptions.Chaos)
+		if err != nil {
+			return nil, err
+		}
 		return &RuleActionRoute{
 			Outbound: action.RouteOptions.Outbound,
 			RuleActionRouteOptions: RuleActionRouteOptions{
@@ -38,12 +64,50 @@ func NewRuleAction(ctx context.Context, logger logger.ContextLogger, action opti
 				FallbackDelay:             time.Duration(action.RouteOptions.FallbackDelay),
 				UDPDisableDomainUnmapping: action.RouteOptions.UDPDisableDomainUnmapping,
 				UDPConnect:                action.RouteOptions.UDPConnect,
+				UDPTimeout:                time.Duration(action.RouteOptions.UDPTimeout),
+				UDPNATMapping:             action.RouteOptions.UDPNATMapping,
+				UDPNATFiltering:           action.RouteOptions.UDPNATFiltering,
+				UDPOverTCP:                action.RouteOptions.UDPOverTCP,
+				Multiplex:                 action.RouteOptions.Multiplex,
+				MultiplexPadding:          action.RouteOptions.MultiplexPadding,
 				TLSFragment:               action.RouteOptions.TLSFragment,
 				TLSFragmentFallbackDelay:  time.Duration(action.RouteOptions.TLSFragmentFallbackDelay),
 				TLSRecordFragment:         action.RouteOptions.TLSRecordFragment,
+				RoutingMark:               uint32(action.RouteOptions.RoutingMark),
+				DSCP:                      action.RouteOptions.DSCP,
+				TCPKeepAlive:              time.Duration(action.RouteOptions.TCPKeepAlive),
+				TCPKeepAliveInterval:      time.Duration(action.RouteOptions.TCPKeepAliveInterval),
+				TCPIdleTimeout:            time.Duration(action.RouteOptions.TCPIdleTimeout),
+				TCPDisableHalfClose:       action.RouteOptions.TCPDisableHalfClose,
+				TCPHalfCloseTimeout:       time.Duration(action.RouteOptions.TCPHalfCloseTimeout),
+				Chaos:                     chaosConfig,
 			},
 		}, nil
 	case C.RuleActionTypeRouteOptions:
+		err := checkNATBehavior(action.RouteOptionsOptions.UDPNATMapping, action.RouteOptionsOptions.UDPNATFiltering)
+		if err != nil {
+			return nil, err
+		}
+		err = checkUDPOverTCP(action.RouteOptionsOptions.UDPOverTCP)
+		if err != nil {
+			return nil, err
+		}
+		err = checkMultiplex(action.RouteOptionsOptions.Multiplex, action.RouteOptionsOptions.MultiplexPadding)
+		if err != nil {
+			return nil, err
+		}
+		err = checkSocketOptions(action.RouteOptionsOptions.RoutingMark, action.RouteOptionsOptions.DSCP)
+		if err != nil {
+			return nil, err
+		}
+		err = checkTCPOptions(option.RawRouteOptionsActionOptions(action.RouteOptionsOptions))
+		if err != nil {
+			return nil, err
+		}
+		chaosConfig, err := newChaosConfig(action.RouteOptionsOptions.Chaos)
+		if err != nil {
+			return nil, err
+		}
 		return &RuleActionRouteOptions{
 			OverrideAddress:           M.ParseSocksaddrHostPort(action.RouteOptionsOptions.OverrideAddress, 0),
 			OverridePort:              action.RouteOptionsOptions.OverridePort,
@@ -52,9 +116,22 @@ func NewRuleAction(ctx context.Context, logger logger.ContextLogger, action opti
 			UDPDisableDomainUnmapping: action.RouteOptionsOptions.UDPDisableDomainUnmapping,
 			UDPConnect:                action.RouteOptionsOptions.UDPConnect,
 			UDPTimeout:                time.Duration(action.RouteOptionsOptions.UDPTimeout),
+			UDPNATMapping:             action.RouteOptionsOptions.UDPNATMapping,
+			UDPNATFiltering:           action.RouteOptionsOptions.UDPNATFiltering,
+			UDPOverTCP:                action.RouteOptionsOptions.UDPOverTCP,
+			Multiplex:                 action.RouteOptionsOptions.Multiplex,
+			MultiplexPadding:          action.RouteOptionsOptions.MultiplexPadding,
 			TLSFragment:               action.RouteOptionsOptions.TLSFragment,
 			TLSFragmentFallbackDelay:  time.Duration(action.RouteOptionsOptions.TLSFragmentFallbackDelay),
 			TLSRecordFragment:         action.RouteOptionsOptions.TLSRecordFragment,
+			RoutingMark:               uint32(action.RouteOptionsOptions.RoutingMark),
+			DSCP:                      action.RouteOptionsOptions.DSCP,
+			TCPKeepAlive:              time.Duration(action.RouteOptionsOptions.TCPKeepAlive),
+			TCPKeepAliveInterval:      time.Duration(action.RouteOptionsOptions.TCPKeepAliveInterval),
+			TCPIdleTimeout:            time.Duration(action.RouteOptionsOptions.TCPIdleTimeout),
+			TCPDisableHalfClose:       action.RouteOptionsOptions.TCPDisableHalfClose,
+			TCPHalfCloseTimeout:       time.Duration(action.RouteOptionsOptions.TCPHalfCloseTimeout),
+			Chaos:                     chaosConfig,
 		}, nil
 	case C.RuleActionTypeDirect:
 		directDialer, err := dialer.New(ctx, option.DialerOptions(action.DirectOptions), false)
@@ -85,11 +162,7 @@ func NewRuleAction(ctx context.Context, logger logger.ContextLogger, action opti
 	case C.RuleActionTypeHijackDNS:
 		return &RuleActionHijackDNS{}, nil
 	case C.RuleActionTypeSniff:
-		sniffAction := &RuleActionSniff{
-			SnifferNames: action.SniffOptions.Sniffer,
-			Timeout:      time.Duration(action.SniffOptions.Timeout),
-		}
-		return sniffAction, sniffAction.build()
+		return NewRuleActionSniff(ctx, action.SniffOptions)
 	case C.RuleActionTypeResolve:
 		return &RuleActionResolve{
 			Server:       action.ResolveOptions.Server,
@@ -168,9 +241,103 @@ type RuleActionRouteOptions struct {
 	UDPDisableDomainUnmapping bool
 	UDPConnect                bool
 	UDPTimeout                time.Duration
+	UDPNATMapping             string
+	UDPNATFiltering           string
+	UDPOverTCP                string
+	Multiplex                 string
+	MultiplexPadding          bool
 	TLSFragment               bool
 	TLSFragmentFallbackDelay  time.Duration
 	TLSRecordFragment         bool
+	RoutingMark               uint32
+	DSCP                      uint8
+	TCPKeepAlive              time.Duration
+	TCPKeepAliveInterval      time.Duration
+	TCPIdleTimeout            time.Duration
+	TCPDisableHalfClose       bool
+	TCPHalfCloseTimeout       time.Duration
+	Chaos                     *chaos.Config
+}
+
+func checkNATBehavior(mapping string, filtering string) error {
+	for _, behavior := range []struct {
+		name  string
+		value string
+	}{
+		{"udp_nat_mapping", mapping},
+		{"udp_nat_filtering", filtering},
+	} {
+		switch behavior.value {
+		case "", C.NATEndpointIndependent, C.NATAddressDependent, C.NATAddressAndPortDependent:
+		default:
+			return E.New("unknown ", behavior.name, ": ", behavior.value)
+		}
+	}
+	return nil
+}
+
+func checkUDPOverTCP(mode string) error {
+	switch mode {
+	case "", C.UDPOverTCPNative, C.UDPOverTCPV1, C.UDPOverTCPV2:
+		return nil
+	default:
+		return E.New("unknown udp_over_tcp: ", mode)
+	}
+}
+
+func checkMultiplex(mode string, padding bool) error {
+	switch mode {
+	case "", C.MultiplexEnabled:
+	case C.MultiplexDisabled:
+		if padding {
+			return E.New("`multiplex_padding` requires multiplex")
+		}
+	default:
+		return E.New("unknown multiplex: ", mode)
+	}
+	return nil
+}
+
+func newChaosConfig(options *option.ChaosOptions) (*chaos.Config, error) {
+	if options == nil {
+		return nil, nil
+	}
+	config, err := chaos.NewConfig(*options)
+	if err != nil {
+		return nil, E.Cause(err, "chaos")
+	}
+	return config, nil
+}
+
+func checkSocketOptions(routingMark option.FwMark, dscp uint8) error {
+	if routingMark != 0 && !C.IsLinux {
+		return E.New("`routing_mark` is only supported on Linux")
+	}
+	if dscp > 63 {
+		return E.New("invalid dscp: ", dscp)
+	} else if dscp > 0 && C.IsWindows {
+		return E.New("`dscp` is not supported on Windows")
+	}
+	return nil
+}
+
+func checkTCPOptions(options option.RawRouteOptionsActionOptions) error {
+	if options.TCPKeepAliveInterval < 0 {
+		return E.New("invalid tcp_keep_alive_interval: ", options.TCPKeepAliveInterval)
+	}
+	if options.TCPKeepAlive < 0 && options.TCPKeepAliveInterval > 0 {
+		return E.New("`tcp_keep_alive_interval` requires TCP keep alive")
+	}
+	if options.TCPIdleTimeout < 0 {
+		return E.New("invalid tcp_idle_timeout: ", options.TCPIdleTimeout)
+	}
+	if options.TCPHalfCloseTimeout < 0 {
+		return E.New("invalid tcp_half_close_timeout: ", options.TCPHalfCloseTimeout)
+	}
+	if options.TCPDisableHalfClose && options.TCPHalfCloseTimeout > 0 {
+		return E.New("`tcp_disable_half_close` and `tcp_half_close_timeout` are mutually exclusive")
+	}
+	return nil
 }
 
 func (r *RuleActionRouteOptions) Type() string {
@@ -210,6 +377,21 @@ func (r *RuleActionRouteOptions) Descriptions() []string {
 	if r.UDPTimeout > 0 {
 		descriptions = append(descriptions, "udp-timeout")
 	}
+	if r.UDPNATMapping != "" {
+		descriptions = append(descriptions, F.ToString("udp-nat-mapping=", r.UDPNATMapping))
+	}
+	if r.UDPNATFiltering != "" {
+		descriptions = append(descriptions, F.ToString("udp-nat-filtering=", r.UDPNATFiltering))
+	}
+	if r.UDPOverTCP != "" {
+		descriptions = append(descriptions, F.ToString("udp-over-tcp=", r.UDPOverTCP))
+	}
+	if r.Multiplex != "" {
+		descriptions = append(descriptions, F.ToString("multiplex=", r.Multiplex))
+	}
+	if r.MultiplexPadding {
+		descriptions = append(descriptions, "multiplex-padding")
+	}
 	if r.TLSFragment {
 		descriptions = append(descriptions, "tls-fragment")
 	}
@@ -219,6 +401,32 @@ func (r *RuleActionRouteOptions) Descriptions() []string {
 	if r.TLSRecordFragment {
 		descriptions = append(descriptions, "tls-record-fragment")
 	}
+	if r.RoutingMark != 0 {
+		descriptions = append(descriptions, F.ToString("routing-mark=", r.RoutingMark))
+	}
+	if r.DSCP != 0 {
+		descriptions = append(descriptions, F.ToString("dscp=", r.DSCP))
+	}
+	if r.TCPKeepAlive > 0 {
+		descriptions = append(descriptions, F.ToString("tcp-keep-alive=", r.TCPKeepAlive.String()))
+	} else if r.TCPKeepAlive < 0 {
+		descriptions = append(descriptions, "tcp-disable-keep-alive")
+	}
+	if r.TCPKeepAliveInterval > 0 {
+		descriptions = append(descriptions, F.ToString("tcp-keep-alive-interval=", r.TCPKeepAliveInterval.String()))
+	}
+	if r.TCPIdleTimeout > 0 {
+		descriptions = append(descriptions, F.ToString("tcp-idle-timeout=", r.TCPIdleTimeout.String()))
+	}
+	if r.TCPDisableHalfClose {
+		descriptions = append(descriptions, "tcp-disable-half-close")
+	}
+	if r.TCPHalfCloseTimeout > 0 {
+		descriptions = append(descriptions, F.ToString("tcp-half-close-timeout=", r.TCPHalfCloseTimeout.String()))
+	}
+	if r.Chaos != nil {
+		descriptions = append(descriptions, "chaos")
+	}
 	return descriptions
 }
 
@@ -366,15 +574,46 @@ type RuleActionSniff struct {
 	StreamSniffers []sniff.StreamSniffer
 	PacketSniffers []sniff.PacketSniffer
 	Timeout        time.Duration
+	BufferSize     int
 	// Deprecated
 	OverrideDestination bool
 }
 
+func NewRuleActionSniff(ctx context.Context, options option.RouteActionSniff) (*RuleActionSniff, error) {
+	if options.BufferSize.Value() > sniff.MaxBufferSize {
+		return nil, E.New("sniff buffer size must be less than or equal to 64 KiB")
+	}
+	action := &RuleActionSniff{
+		SnifferNames: options.Sniffer,
+		Timeout:      time.Duration(options.Timeout),
+		BufferSize:   int(options.BufferSize.Value()),
+	}
+	return action, action.build(ctx)
+}
+
+// WithDefaults returns a copy of the action with unset sniffers, timeout and
+// buffer size taken from defaults.
+func (r *RuleActionSniff) WithDefaults(defaults *RuleActionSniff) *RuleActionSniff {
+	action := *r
+	if len(action.SnifferNames) == 0 {
+		action.SnifferNames = defaults.SnifferNames
+		action.StreamSniffers = defaults.StreamSniffers
+		action.PacketSniffers = defaults.PacketSniffers
+	}
+	if action.Timeout == 0 {
+		action.Timeout = defaults.Timeout
+	}
+	if action.BufferSize == 0 {
+		action.BufferSize = defaults.BufferSize
+	}
+	return &action
+}
+
 func (r *RuleActionSniff) Type() string {
 	return C.RuleActionTypeSniff
 }
 
-func (r *RuleActionSniff) build() error {
+func (r *RuleActionSniff) build(ctx context.Context) error {
 	for _, name := range r.SnifferNames {
 		switch name {
 		case C.ProtocolTLS:
@@ -401,7 +640,18 @@ func (r *RuleActionSniff) build() error {
 		case C.ProtocolNTP:
 			r.PacketSniffers = append(r.PacketSniffers, sniff.NTP)
 		default:
-			return E.New("unknown sniffer: ", name)
+			var (
+				module adapter.WASMModule
+				loaded bool
+			)
+			if wasmManager := service.FromContext[adapter.WASMManager](ctx); wasmManager != nil {
+				module, loaded = wasmManager.Module(name)
+			}
+			if !loaded {
+				return E.New("unknown sniffer: ", name)
+			}
+			r.StreamSniffers = append(r.StreamSniffers, sniff.WASMStream(module))
+			r.PacketSniffers = append(r.PacketSniffers, sniff.WASMPacket(module))
 		}
 	}
 	return nil
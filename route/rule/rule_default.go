@@ -2,6 +2,8 @@ package rule
 
 import (
 	"context"
+	"net/netip"
+	"reflect"
 
 	"github.com/sagernet/sing-box/adapter"
 	C "github.com/sagernet/sing-box/constant"
@@ -42,10 +44,14 @@ func NewRule(ctx context.Context, logger log.ContextLogger, options option.Rule,
 	}
 }
 
-var _ adapter.Rule = (*DefaultRule)(nil)
+var (
+	_ adapter.Rule            = (*DefaultRule)(nil)
+	_ adapter.ProxyBypassRule = (*DefaultRule)(nil)
+)
 
 type DefaultRule struct {
 	abstractDefaultRule
+	proxyBypass *adapter.ProxyBypass
 }
 
 type RuleItem interface {
@@ -59,7 +65,7 @@ func NewDefaultRule(ctx context.Context, logger log.ContextLogger, options optio
 		return nil, E.Cause(err, "action")
 	}
 	rule := &DefaultRule{
-		abstractDefaultRule{
+		abstractDefaultRule: abstractDefaultRule{
 			invert: options.Invert,
 			action: action,
 		},
@@ -216,7 +222,7 @@ func NewDefaultRule(ctx context.Context, logger log.ContextLogger, options optio
 		rule.items = append(rule.items, item)
 		rule.allItems = append(rule.allItems, item)
 	}
-	if options.ClashMode != "" {
+	if len(options.ClashMode) > 0 {
 		item := NewClashModeItem(ctx, options.ClashMode)
 		rule.items = append(rule.items, item)
 		rule.allItems = append(rule.allItems, item)
@@ -266,6 +272,14 @@ func NewDefaultRule(ctx context.Context, logger log.ContextLogger, options optio
 		rule.items = append(rule.items, item)
 		rule.allItems = append(rule.allItems, item)
 	}
+	if len(options.Custom) > 0 {
+		items, err := newCustomItems(ctx, logger, options.Custom)
+		if err != nil {
+			return nil, err
+		}
+		rule.items = append(rule.items, items...)
+		rule.allItems = append(rule.allItems, items...)
+	}
 	if len(options.RuleSet) > 0 {
 		var matchSource bool
 		if options.RuleSetIPCIDRMatchSource {
@@ -280,9 +294,49 @@ func NewDefaultRule(ctx context.Context, logger log.ContextLogger, options optio
 		rule.items = append(rule.items, item)
 		rule.allItems = append(rule.allItems, item)
 	}
+	rule.proxyBypass = newProxyBypass(options)
 	return rule, nil
 }
 
+// newProxyBypass returns the proxy bypass list of a rule which only has
+// domain, domain_suffix and ip_cidr items and routes to an outbound.
+func newProxyBypass(options option.DefaultRule) *adapter.ProxyBypass {
+	if options.Invert || options.Action != "" && options.Action != C.RuleActionTypeRoute || options.RouteOptions.Outbound == "" {
+		return nil
+	}
+	conditions := options.RawDefaultRule
+	conditions.Domain = nil
+	conditions.DomainSuffix = nil
+	conditions.IPCIDR = nil
+	if !reflect.DeepEqual(conditions, option.RawDefaultRule{}) {
+		return nil
+	}
+	proxyBypass := &adapter.ProxyBypass{
+		Outbound:     options.RouteOptions.Outbound,
+		Domain:       options.Domain,
+		DomainSuffix: options.DomainSuffix,
+	}
+	for _, prefixString := range options.IPCIDR {
+		prefix, err := netip.ParsePrefix(prefixString)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(prefixString)
+			if addrErr != nil {
+				return nil
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		proxyBypass.IPCIDR = append(proxyBypass.IPCIDR, prefix)
+	}
+	return proxyBypass
+}
+
+func (r *DefaultRule) ProxyBypass() (adapter.ProxyBypass, bool) {
+	if r.proxyBypass == nil {
+		return adapter.ProxyBypass{}, false
+	}
+	return *r.proxyBypass, true
+}
+
 var _ adapter.Rule = (*LogicalRule)(nil)
 
 type LogicalRule struct {
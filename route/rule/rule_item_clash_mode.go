@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common"
 	"github.com/sagernet/sing/service"
 )
 
@@ -13,13 +14,13 @@ var _ RuleItem = (*ClashModeItem)(nil)
 type ClashModeItem struct {
 	ctx         context.Context
 	clashServer adapter.ClashServer
-	mode        string
+	modes       []string
 }
 
-func NewClashModeItem(ctx context.Context, mode string) *ClashModeItem {
+func NewClashModeItem(ctx context.Context, modes []string) *ClashModeItem {
 	return &ClashModeItem{
-		ctx:  ctx,
-		mode: mode,
+		ctx:   ctx,
+		modes: modes,
 	}
 }
 
@@ -32,9 +33,15 @@ func (r *ClashModeItem) Match(metadata *adapter.InboundContext) bool {
 	if r.clashServer == nil {
 		return false
 	}
-	return strings.EqualFold(r.clashServer.Mode(), r.mode)
+	currentMode := r.clashServer.Mode()
+	return common.Any(r.modes, func(mode string) bool {
+		return strings.EqualFold(currentMode, mode)
+	})
 }
 
 func (r *ClashModeItem) String() string {
-	return "clash_mode=" + r.mode
+	if len(r.modes) == 1 {
+		return "clash_mode=" + r.modes[0]
+	}
+	return "clash_mode=[" + strings.Join(r.modes, " ") + "]"
 }
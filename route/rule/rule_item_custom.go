@@ -0,0 +1,82 @@
+package rule
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/service"
+)
+
+type ItemConstructorFunc[T any] func(ctx context.Context, logger log.ContextLogger, options T) (RuleItem, error)
+
+// RegisterItem registers a rule item type, configured in the `custom` field
+// of rules with its options decoded as T.
+func RegisterItem[Options any](registry *ItemRegistry, itemType string, constructor ItemConstructorFunc[Options]) {
+	registry.register(itemType, func(ctx context.Context, logger log.ContextLogger, rawOptions json.RawMessage) (RuleItem, error) {
+		var options Options
+		if len(rawOptions) > 0 {
+			err := json.UnmarshalContext(ctx, rawOptions, &options)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return constructor(ctx, logger, options)
+	})
+}
+
+var _ adapter.RuleItemRegistry = (*ItemRegistry)(nil)
+
+type itemConstructorFunc func(ctx context.Context, logger log.ContextLogger, rawOptions json.RawMessage) (RuleItem, error)
+
+type ItemRegistry struct {
+	access       sync.Mutex
+	constructors map[string]itemConstructorFunc
+}
+
+func NewItemRegistry() *ItemRegistry {
+	return &ItemRegistry{
+		constructors: make(map[string]itemConstructorFunc),
+	}
+}
+
+func (r *ItemRegistry) CreateRuleItem(ctx context.Context, logger log.ContextLogger, itemType string, rawOptions json.RawMessage) (adapter.HeadlessRule, error) {
+	r.access.Lock()
+	constructor, loaded := r.constructors[itemType]
+	r.access.Unlock()
+	if !loaded {
+		return nil, E.New("rule item type not found: ", itemType)
+	}
+	return constructor(ctx, logger, rawOptions)
+}
+
+func (r *ItemRegistry) register(itemType string, constructor itemConstructorFunc) {
+	r.access.Lock()
+	defer r.access.Unlock()
+	r.constructors[itemType] = constructor
+}
+
+func newCustomItems(ctx context.Context, logger log.ContextLogger, options map[string]json.RawMessage) ([]RuleItem, error) {
+	registry := service.FromContext[adapter.RuleItemRegistry](ctx)
+	if registry == nil {
+		return nil, E.New("missing rule item registry in context")
+	}
+	itemTypes := make([]string, 0, len(options))
+	for itemType := range options {
+		itemTypes = append(itemTypes, itemType)
+	}
+	sort.Strings(itemTypes)
+	items := make([]RuleItem, 0, len(itemTypes))
+	for _, itemType := range itemTypes {
+		item, err := registry.CreateRuleItem(ctx, logger, itemType, options[itemType])
+		if err != nil {
+			return nil, E.Cause(err, "custom rule item: ", itemType)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
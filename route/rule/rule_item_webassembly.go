@@ -0,0 +1,63 @@
+package rule
+
+import (
+	"context"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/service"
+)
+
+func RegisterWASMItem(registry *ItemRegistry) {
+	RegisterItem[option.WASMRuleItemOptions](registry, "wasm", NewWASMItem)
+}
+
+var _ RuleItem = (*WASMItem)(nil)
+
+// WASMItem matches by calling a rule evaluation function of a module
+// declared in experimental.wasm, errors of the call are logged and do not
+// match.
+type WASMItem struct {
+	ctx      context.Context
+	logger   log.ContextLogger
+	module   adapter.WASMModule
+	function string
+}
+
+func NewWASMItem(ctx context.Context, logger log.ContextLogger, options option.WASMRuleItemOptions) (RuleItem, error) {
+	if options.Module == "" {
+		return nil, E.New("missing module")
+	}
+	manager := service.FromContext[adapter.WASMManager](ctx)
+	if manager == nil {
+		return nil, E.New("wasm module not found: ", options.Module)
+	}
+	module, loaded := manager.Module(options.Module)
+	if !loaded {
+		return nil, E.New("wasm module not found: ", options.Module)
+	}
+	return &WASMItem{
+		ctx:      ctx,
+		logger:   logger,
+		module:   module,
+		function: options.Function,
+	}, nil
+}
+
+func (r *WASMItem) Match(metadata *adapter.InboundContext) bool {
+	matched, err := r.module.Match(r.ctx, r.function, metadata)
+	if err != nil {
+		r.logger.Error(E.Cause(err, "match wasm rule item ", r.String()))
+		return false
+	}
+	return matched
+}
+
+func (r *WASMItem) String() string {
+	if r.function == "" {
+		return "wasm=" + r.module.Tag()
+	}
+	return "wasm=" + r.module.Tag() + "." + r.function
+}
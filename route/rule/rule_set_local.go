@@ -1,9 +1,8 @@
 package rule
 
 import (
+	"bytes"
 	"context"
-	"io"
-	"os"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -12,6 +11,7 @@ import (
 
 	"github.com/sagernet/fswatch"
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/mmap"
 	"github.com/sagernet/sing-box/common/srs"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
@@ -26,7 +26,7 @@ import (
 	"go4.org/netipx"
 )
 
-var _ adapter.RuleSet = (*LocalRuleSet)(nil)
+var _ adapter.MutableRuleSet = (*LocalRuleSet)(nil)
 
 type LocalRuleSet struct {
 	ctx        context.Context
@@ -124,31 +124,27 @@ func (s *LocalRuleSet) StartContext(ctx context.Context, startContext *adapter.H
 }
 
 func (s *LocalRuleSet) reloadFile(path string) error {
-	file, err := os.Open(path)
+	file, err := mmap.Open(path)
 	if err != nil {
 		return err
 	}
-	fileInfo, _ := file.Stat()
-	s.lastUpdated = fileInfo.ModTime()
+	defer file.Close()
+	s.lastUpdated = file.Info().ModTime()
 	var ruleSet option.PlainRuleSetCompat
-	switch s.fileFormat {
-	case C.RuleSetFormatSource, "":
-		content, err := io.ReadAll(file)
-		if err != nil {
-			return err
-		}
-		ruleSet, err = json.UnmarshalExtended[option.PlainRuleSetCompat](content)
-		if err != nil {
-			return err
+	err = file.Access(func(content []byte) error {
+		var decodeErr error
+		switch s.fileFormat {
+		case C.RuleSetFormatSource, "":
+			ruleSet, decodeErr = json.UnmarshalExtended[option.PlainRuleSetCompat](content)
+		case C.RuleSetFormatBinary:
+			ruleSet, decodeErr = srs.Read(bytes.NewReader(content), false)
+		default:
+			decodeErr = E.New("unknown rule-set format: ", s.fileFormat)
 		}
-
-	case C.RuleSetFormatBinary:
-		ruleSet, err = srs.Read(file, false)
-		if err != nil {
-			return err
-		}
-	default:
-		return E.New("unknown rule-set format: ", s.fileFormat)
+		return decodeErr
+	})
+	if err != nil {
+		return err
 	}
 	plainRuleSet, err := ruleSet.Upgrade()
 	if err != nil {
@@ -157,6 +153,16 @@ func (s *LocalRuleSet) reloadFile(path string) error {
 	return s.reloadRules(plainRuleSet.Rules)
 }
 
+// SetRules replaces the rules, until the file of a local rule-set changes.
+func (s *LocalRuleSet) SetRules(rules []option.HeadlessRule) error {
+	err := s.reloadRules(rules)
+	if err != nil {
+		return err
+	}
+	s.lastUpdated = time.Now()
+	return nil
+}
+
 func (s *LocalRuleSet) reloadRules(headlessRules []option.HeadlessRule) error {
 	rules := make([]adapter.HeadlessRule, len(headlessRules))
 	var err error
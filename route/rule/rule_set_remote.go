@@ -7,6 +7,8 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/url"
+	"path"
 	"runtime"
 	"strings"
 	"sync"
@@ -14,6 +16,8 @@ import (
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/eventbus"
+	"github.com/sagernet/sing-box/common/geodata"
 	"github.com/sagernet/sing-box/common/srs"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
@@ -32,7 +36,9 @@ import (
 	"go4.org/netipx"
 )
 
-var _ adapter.RuleSet = (*RemoteRuleSet)(nil)
+var _ adapter.ManagedRuleSet = (*RemoteRuleSet)(nil)
+
+const remoteRuleSetRetryInterval = time.Minute
 
 type RemoteRuleSet struct {
 	ctx            context.Context
@@ -50,8 +56,12 @@ type RemoteRuleSet struct {
 	updateTicker   *time.Ticker
 	cacheFile      adapter.CacheFile
 	pauseManager   pause.Manager
+	powerManager   adapter.PowerManager
+	eventBus       adapter.EventBus
 	callbacks      list.List[adapter.RuleSetUpdateCallback]
 	refs           atomic.Int32
+	managed        bool
+	verify         adapter.RuleSetVerifyFunc
 }
 
 func NewRemoteRuleSet(ctx context.Context, logger logger.ContextLogger, options option.RuleSet) *RemoteRuleSet {
@@ -70,6 +80,8 @@ func NewRemoteRuleSet(ctx context.Context, logger logger.ContextLogger, options
 		options:        options,
 		updateInterval: updateInterval,
 		pauseManager:   service.FromContext[pause.Manager](ctx),
+		powerManager:   service.FromContext[adapter.PowerManager](ctx),
+		eventBus:       service.FromContext[adapter.EventBus](ctx),
 	}
 }
 
@@ -95,13 +107,19 @@ func (s *RemoteRuleSet) Update(ctx context.Context) error {
 	}
 	err := s.fetch(ctx, nil)
 	if err != nil {
-		s.logger.Error("fetch rule-set ", s.options.Tag, ": ", err)
-	} else if s.refs.Load() == 0 {
+		return err
+	}
+	if s.refs.Load() == 0 {
 		s.rules = nil
 	}
 	return nil
 }
 
+func (s *RemoteRuleSet) SetManaged(verify adapter.RuleSetVerifyFunc) {
+	s.managed = true
+	s.verify = verify
+}
+
 func (s *RemoteRuleSet) UpdatedAt() time.Time {
 	return s.lastUpdated
 }
@@ -136,15 +154,54 @@ func (s *RemoteRuleSet) StartContext(ctx context.Context, startContext *adapter.
 	if s.lastUpdated.IsZero() {
 		err := s.fetch(ctx, startContext)
 		if err != nil {
-			return E.Cause(err, "initial rule-set: ", s.options.Tag)
+			err = s.loadFallback(err)
+			if err != nil {
+				return E.Cause(err, "initial rule-set: ", s.options.Tag)
+			}
 		}
 	}
-	s.updateTicker = time.NewTicker(s.updateInterval)
+	if !s.managed {
+		s.updateTicker = time.NewTicker(s.updateInterval)
+	}
+	return nil
+}
+
+// loadFallback loads the embedded rule-set, or starts with an empty rule-set
+// in offline mode, if the initial download failed.
+func (s *RemoteRuleSet) loadFallback(fetchErr error) error {
+	var names []string
+	if remoteURL, err := url.Parse(s.options.RemoteOptions.URL); err == nil {
+		names = append(names, path.Base(remoteURL.Path))
+	}
+	switch s.options.Format {
+	case C.RuleSetFormatSource:
+		names = append(names, s.options.Tag+".json")
+	case C.RuleSetFormatBinary:
+		names = append(names, s.options.Tag+".srs")
+	}
+	for _, name := range names {
+		content, loaded := geodata.Load(name)
+		if !loaded {
+			continue
+		}
+		err := s.loadBytes(content)
+		if err != nil {
+			return E.Cause(err, "load embedded rule-set ", name)
+		}
+		s.logger.Warn("initial rule-set ", s.options.Tag, ": ", fetchErr, ", using embedded ", name)
+		return nil
+	}
+	if !adapter.IsOffline(s.ctx) {
+		return fetchErr
+	}
+	s.logger.Warn("initial rule-set ", s.options.Tag, ": ", fetchErr, ", starting with an empty rule-set")
 	return nil
 }
 
 func (s *RemoteRuleSet) PostStart() error {
-	go s.loopUpdate()
+	if !s.managed {
+		go s.loopUpdate()
+	}
 	return nil
 }
 
@@ -240,12 +297,24 @@ func (s *RemoteRuleSet) loopUpdate() {
 			s.rules = nil
 		}
 	}
+	// retry the initial download more frequently when started with fallback data
+	for s.lastUpdated.IsZero() {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(min(s.updateInterval, remoteRuleSetRetryInterval)):
+			s.updateOnce()
+		}
+	}
 	for {
 		runtime.GC()
 		select {
 		case <-s.ctx.Done():
 			return
 		case <-s.updateTicker.C:
+			if s.powerManager != nil {
+				s.powerManager.WaitWindow(s.ctx)
+			}
 			s.updateOnce()
 		}
 	}
@@ -261,6 +330,17 @@ func (s *RemoteRuleSet) updateOnce() {
 }
 
 func (s *RemoteRuleSet) fetch(ctx context.Context, startContext *adapter.HTTPStartContext) error {
+	task := eventbus.StartTask(s.eventBus, adapter.TaskRuleSet, s.options.Tag)
+	notModified, err := s.fetch0(ctx, startContext, task)
+	if notModified {
+		task.FinishNotModified()
+	} else {
+		task.Finish(err)
+	}
+	return err
+}
+
+func (s *RemoteRuleSet) fetch0(ctx context.Context, startContext *adapter.HTTPStartContext, task *eventbus.Task) (bool, error) {
 	s.logger.Debug("updating rule-set ", s.options.Tag, " from URL: ", s.options.RemoteOptions.URL)
 	var httpClient *http.Client
 	if startContext != nil {
@@ -282,14 +362,14 @@ func (s *RemoteRuleSet) fetch(ctx context.Context, startContext *adapter.HTTPSta
 	}
 	request, err := http.NewRequest("GET", s.options.RemoteOptions.URL, nil)
 	if err != nil {
-		return err
+		return false, err
 	}
 	if s.lastEtag != "" {
 		request.Header.Set("If-None-Match", s.lastEtag)
 	}
 	response, err := httpClient.Do(request.WithContext(ctx))
 	if err != nil {
-		return err
+		return false, err
 	}
 	switch response.StatusCode {
 	case http.StatusOK:
@@ -302,24 +382,31 @@ func (s *RemoteRuleSet) fetch(ctx context.Context, startContext *adapter.HTTPSta
 				err = s.cacheFile.SaveRuleSet(s.options.Tag, savedRuleSet)
 				if err != nil {
 					s.logger.Error("save rule-set updated time: ", err)
-					return nil
+					return true, nil
 				}
 			}
 		}
 		s.logger.Info("update rule-set ", s.options.Tag, ": not modified")
-		return nil
+		return true, nil
 	default:
-		return E.New("unexpected status: ", response.Status)
+		return false, E.New("unexpected status: ", response.Status)
 	}
-	content, err := io.ReadAll(response.Body)
+	content, err := io.ReadAll(task.Reader(response.Body, response.ContentLength))
 	if err != nil {
 		response.Body.Close()
-		return err
+		return false, err
+	}
+	if s.verify != nil {
+		err = s.verify(ctx, s.options.RemoteOptions.URL, content)
+		if err != nil {
+			response.Body.Close()
+			return false, E.Cause(err, "verify")
+		}
 	}
 	err = s.loadBytes(content)
 	if err != nil {
 		response.Body.Close()
-		return err
+		return false, err
 	}
 	response.Body.Close()
 	eTagHeader := response.Header.Get("Etag")
@@ -338,7 +425,7 @@ func (s *RemoteRuleSet) fetch(ctx context.Context, startContext *adapter.HTTPSta
 		}
 	}
 	s.logger.Info("updated rule-set ", s.options.Tag)
-	return nil
+	return false, nil
 }
 
 func (s *RemoteRuleSet) Close() error {
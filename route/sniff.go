@@ -0,0 +1,34 @@
+package route
+
+import (
+	"net/netip"
+	"time"
+
+	"github.com/sagernet/sing/common"
+	M "github.com/sagernet/sing/common/metadata"
+	"github.com/sagernet/sing/contrab/freelru"
+	"github.com/sagernet/sing/contrab/maphash"
+)
+
+const (
+	serverFirstCacheSize     = 4096
+	serverFirstCacheLifetime = 10 * time.Minute
+)
+
+// serverFirstKey keys the server-first cache by the client too, so that a
+// client holding back its payload can not disable sniffing of the destination
+// for others.
+type serverFirstKey struct {
+	source      netip.Addr
+	destination M.Socksaddr
+}
+
+// newServerFirstCache remembers destinations whose clients sent nothing within
+// the sniff timeout, so that later connections of the same clients to them
+// skip sniffing instead of stalling for the timeout again. Connections sniffed
+// with a longer timeout than the observed one are still sniffed.
+func newServerFirstCache() freelru.Cache[serverFirstKey, time.Duration] {
+	cache := common.Must1(freelru.NewSynced[serverFirstKey, time.Duration](serverFirstCacheSize, maphash.NewHasher[serverFirstKey]().Hash32))
+	cache.SetLifetime(serverFirstCacheLifetime)
+	return cache
+}
@@ -0,0 +1,225 @@
+package route
+
+import (
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// userManager enforces the enabled and expire_at fields of inbound users:
+// connections of disabled or expired users are rejected, and routed ones
+// are closed when the user is disabled or expires.
+type userManager struct {
+	logger log.ContextLogger
+	access sync.Mutex
+	users  map[userKey]*userEntry
+	closed bool
+}
+
+type userKey struct {
+	inbound string
+	name    string
+}
+
+type userEntry struct {
+	enabled     bool
+	expireAt    time.Time
+	timer       *time.Timer
+	connections map[*userConnection]struct{}
+}
+
+type userConnection struct {
+	io.Closer
+}
+
+func newUserManager(logger log.ContextLogger) *userManager {
+	return &userManager{
+		logger: logger,
+		users:  make(map[userKey]*userEntry),
+	}
+}
+
+// register sets the status of users, keeping the tracked connections of
+// users that are already registered.
+func (m *userManager) register(inbound string, users []option.InboundUser) {
+	m.access.Lock()
+	var connections []*userConnection
+	now := time.Now()
+	for _, user := range users {
+		key := userKey{inbound, user.Name}
+		entry := &userEntry{
+			enabled:     user.Enabled == nil || *user.Enabled,
+			connections: make(map[*userConnection]struct{}),
+		}
+		if user.ExpireAt != nil {
+			entry.expireAt = *user.ExpireAt
+		}
+		if previous, loaded := m.users[key]; loaded {
+			if previous.timer != nil {
+				previous.timer.Stop()
+			}
+			entry.connections = previous.connections
+			if entry.check(user.Name, now) != nil {
+				connections = append(connections, entry.takeConnections()...)
+			}
+		}
+		m.users[key] = entry
+		m.scheduleExpiry(key, entry)
+	}
+	m.access.Unlock()
+	closeUserConnections(connections)
+}
+
+// unregister removes a user and closes its connections.
+func (m *userManager) unregister(inbound string, name string) {
+	m.access.Lock()
+	key := userKey{inbound, name}
+	entry, loaded := m.users[key]
+	if !loaded {
+		m.access.Unlock()
+		return
+	}
+	if entry.timer != nil {
+		entry.timer.Stop()
+	}
+	delete(m.users, key)
+	connections := entry.takeConnections()
+	m.access.Unlock()
+	closeUserConnections(connections)
+}
+
+func (m *userManager) scheduleExpiry(key userKey, entry *userEntry) {
+	if entry.timer != nil {
+		entry.timer.Stop()
+		entry.timer = nil
+	}
+	if entry.expireAt.IsZero() || !entry.enabled {
+		return
+	}
+	entry.timer = time.AfterFunc(time.Until(entry.expireAt), func() {
+		m.access.Lock()
+		if m.users[key] != entry || m.closed {
+			m.access.Unlock()
+			return
+		}
+		connections := entry.takeConnections()
+		m.access.Unlock()
+		m.logger.Info("user ", key.name, " of inbound/", key.inbound, " expired, closing ", len(connections), " connections")
+		closeUserConnections(connections)
+	})
+}
+
+func (e *userEntry) check(name string, now time.Time) error {
+	if !e.enabled {
+		return E.New("user ", name, " disabled")
+	}
+	if !e.expireAt.IsZero() && !now.Before(e.expireAt) {
+		return E.New("user ", name, " expired")
+	}
+	return nil
+}
+
+func (e *userEntry) takeConnections() []*userConnection {
+	connections := make([]*userConnection, 0, len(e.connections))
+	for connection := range e.connections {
+		connections = append(connections, connection)
+	}
+	clear(e.connections)
+	return connections
+}
+
+// closeUserConnections is called without the lock held, as closing calls
+// the release functions of the connections.
+func closeUserConnections(connections []*userConnection) {
+	for _, connection := range connections {
+		connection.Close()
+	}
+}
+
+// acquire rejects connections of disabled or expired users, and tracks
+// connections of known users until the returned function is called.
+func (m *userManager) acquire(metadata *adapter.InboundContext, conn io.Closer) (func(), error) {
+	if metadata.User == "" {
+		return func() {}, nil
+	}
+	m.access.Lock()
+	defer m.access.Unlock()
+	entry, loaded := m.users[userKey{metadata.Inbound, metadata.User}]
+	if !loaded {
+		return func() {}, nil
+	}
+	err := entry.check(metadata.User, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	connection := &userConnection{conn}
+	entry.connections[connection] = struct{}{}
+	return func() {
+		m.access.Lock()
+		defer m.access.Unlock()
+		delete(entry.connections, connection)
+	}, nil
+}
+
+func (m *userManager) update(inbound string, name string, update adapter.UserStatusUpdate) error {
+	m.access.Lock()
+	key := userKey{inbound, name}
+	entry, loaded := m.users[key]
+	if !loaded {
+		m.access.Unlock()
+		return E.New("user ", name, " not found in inbound ", inbound)
+	}
+	if update.Enabled != nil {
+		entry.enabled = *update.Enabled
+	}
+	if update.ExpireAt != nil {
+		entry.expireAt = *update.ExpireAt
+	}
+	var connections []*userConnection
+	if entry.check(name, time.Now()) != nil {
+		connections = entry.takeConnections()
+	}
+	m.scheduleExpiry(key, entry)
+	m.access.Unlock()
+	closeUserConnections(connections)
+	return nil
+}
+
+func (m *userManager) status() []adapter.UserStatus {
+	m.access.Lock()
+	defer m.access.Unlock()
+	users := make([]adapter.UserStatus, 0, len(m.users))
+	for key, entry := range m.users {
+		users = append(users, adapter.UserStatus{
+			Inbound:     key.inbound,
+			Name:        key.name,
+			Enabled:     entry.enabled,
+			ExpireAt:    entry.expireAt,
+			Connections: len(entry.connections),
+		})
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if users[i].Inbound != users[j].Inbound {
+			return users[i].Inbound < users[j].Inbound
+		}
+		return users[i].Name < users[j].Name
+	})
+	return users
+}
+
+func (m *userManager) close() {
+	m.access.Lock()
+	defer m.access.Unlock()
+	m.closed = true
+	for _, entry := range m.users {
+		if entry.timer != nil {
+			entry.timer.Stop()
+		}
+	}
+}
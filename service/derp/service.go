@@ -104,6 +104,7 @@ func NewService(ctx context.Context, logger log.ContextLogger, tag string, optio
 		stunListener = listener.New(listener.Options{
 			Context: ctx,
 			Logger:  logger,
+			Tag:     tag,
 			Network: []string{N.NetworkUDP},
 			Listen:  options.STUN.ListenOptions,
 		})
@@ -116,6 +117,7 @@ func NewService(ctx context.Context, logger log.ContextLogger, tag string, optio
 		listener: listener.New(listener.Options{
 			Context: ctx,
 			Logger:  logger,
+			Tag:     tag,
 			Network: []string{N.NetworkTCP},
 			Listen:  options.ListenOptions,
 		}),
@@ -0,0 +1,102 @@
+package dhcpserver
+
+import (
+	"net"
+	"net/netip"
+
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/insomniacslk/dhcp/dhcpv4"
+)
+
+func (s *Service) handleDHCPv4(conn net.PacketConn, peer net.Addr, request *dhcpv4.DHCPv4) {
+	if request.OpCode != dhcpv4.OpcodeBootRequest {
+		return
+	}
+	hardwareAddr := request.ClientHWAddr.String()
+	var reply *dhcpv4.DHCPv4
+	var err error
+	switch request.MessageType() {
+	case dhcpv4.MessageTypeDiscover:
+		address, loaded := s.pool.offer(hardwareAddr, M.AddrFromIP(request.RequestedIPAddress()))
+		if !loaded {
+			s.logger.Warn("DHCPv4 address pool exhausted, ignore DISCOVER from ", hardwareAddr)
+			return
+		}
+		reply, err = s.newDHCPv4Reply(request, dhcpv4.MessageTypeOffer, address)
+	case dhcpv4.MessageTypeRequest:
+		serverID := request.ServerIdentifier()
+		if serverID != nil && M.AddrFromIP(serverID) != s.serverAddress.Addr() {
+			// the client selected another server
+			s.pool.release(hardwareAddr, netip.Addr{})
+			return
+		}
+		address := M.AddrFromIP(request.RequestedIPAddress())
+		if !address.IsValid() {
+			address = M.AddrFromIP(request.ClientIPAddr)
+		}
+		if address.IsValid() && !address.IsUnspecified() && s.pool.commit(hardwareAddr, address, s.leaseTime) {
+			s.logger.Info("DHCPv4 lease ", address, " to ", hardwareAddr, hostnameSuffix(request))
+			reply, err = s.newDHCPv4Reply(request, dhcpv4.MessageTypeAck, address)
+		} else {
+			s.logger.Debug("DHCPv4 reject request for ", address, " from ", hardwareAddr)
+			reply, err = dhcpv4.NewReplyFromRequest(request,
+				dhcpv4.WithMessageType(dhcpv4.MessageTypeNak),
+				dhcpv4.WithServerIP(s.serverAddress.Addr().AsSlice()),
+				dhcpv4.WithOption(dhcpv4.OptServerIdentifier(s.serverAddress.Addr().AsSlice())),
+			)
+			peer = &net.UDPAddr{IP: net.IPv4bcast, Port: dhcpv4.ClientPort}
+		}
+	case dhcpv4.MessageTypeRelease, dhcpv4.MessageTypeDecline:
+		s.pool.release(hardwareAddr, netip.Addr{})
+		return
+	case dhcpv4.MessageTypeInform:
+		reply, err = s.newDHCPv4Reply(request, dhcpv4.MessageTypeAck, netip.Addr{})
+	default:
+		return
+	}
+	if err != nil {
+		s.logger.Error(E.Cause(err, "create DHCPv4 reply"))
+		return
+	}
+	_, err = conn.WriteTo(reply.ToBytes(), peer)
+	if err != nil {
+		s.logger.Error(E.Cause(err, "write DHCPv4 reply"))
+	}
+}
+
+func (s *Service) newDHCPv4Reply(request *dhcpv4.DHCPv4, messageType dhcpv4.MessageType, address netip.Addr) (*dhcpv4.DHCPv4, error) {
+	serverAddress := s.serverAddress.Addr().AsSlice()
+	modifiers := []dhcpv4.Modifier{
+		dhcpv4.WithMessageType(messageType),
+		dhcpv4.WithServerIP(serverAddress),
+		dhcpv4.WithOption(dhcpv4.OptServerIdentifier(serverAddress)),
+		dhcpv4.WithNetmask(net.CIDRMask(s.serverAddress.Bits(), 32)),
+		dhcpv4.WithRouter(serverAddress),
+	}
+	if address.IsValid() {
+		modifiers = append(modifiers,
+			dhcpv4.WithYourIP(address.AsSlice()),
+			dhcpv4.WithLeaseTime(uint32(s.leaseTime.Seconds())),
+		)
+	}
+	if len(s.dns4) > 0 {
+		modifiers = append(modifiers, dhcpv4.WithDNS(common.Map(s.dns4, func(it netip.Addr) net.IP {
+			return it.AsSlice()
+		})...))
+	}
+	if s.options.DomainName != "" {
+		modifiers = append(modifiers, dhcpv4.WithOption(dhcpv4.OptDomainName(s.options.DomainName)))
+	}
+	return dhcpv4.NewReplyFromRequest(request, modifiers...)
+}
+
+func hostnameSuffix(request *dhcpv4.DHCPv4) string {
+	hostname := request.HostName()
+	if hostname == "" {
+		return ""
+	}
+	return " (" + hostname + ")"
+}
@@ -0,0 +1,51 @@
+package dhcpserver
+
+import (
+	"net"
+	"net/netip"
+
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/insomniacslk/dhcp/dhcpv6"
+	"github.com/insomniacslk/dhcp/iana"
+)
+
+// handleDHCPv6 answers stateless DHCPv6 information requests, addresses are configured by SLAAC.
+func (s *Service) handleDHCPv6(conn net.PacketConn, peer net.Addr, request dhcpv6.DHCPv6) {
+	message, err := request.GetInnerMessage()
+	if err != nil || message.Type() != dhcpv6.MessageTypeInformationRequest {
+		return
+	}
+	modifiers := []dhcpv6.Modifier{
+		dhcpv6.WithServerID(&dhcpv6.DUIDLL{
+			HWType:        iana.HWTypeEthernet,
+			LinkLayerAddr: s.iface.HardwareAddr,
+		}),
+	}
+	if len(s.dns6) > 0 {
+		modifiers = append(modifiers, dhcpv6.WithDNS(common.Map(s.dns6, func(it netip.Addr) net.IP {
+			return it.AsSlice()
+		})...))
+	}
+	if s.options.DomainName != "" {
+		modifiers = append(modifiers, dhcpv6.WithDomainSearchList(s.options.DomainName))
+	}
+	reply, err := dhcpv6.NewReplyFromMessage(message, modifiers...)
+	if err != nil {
+		s.logger.Error(E.Cause(err, "create DHCPv6 reply"))
+		return
+	}
+	var response dhcpv6.DHCPv6 = reply
+	if request.IsRelay() {
+		response, err = dhcpv6.NewRelayReplFromRelayForw(request.(*dhcpv6.RelayMessage), reply)
+		if err != nil {
+			s.logger.Error(E.Cause(err, "create DHCPv6 relay reply"))
+			return
+		}
+	}
+	_, err = conn.WriteTo(response.ToBytes(), peer)
+	if err != nil {
+		s.logger.Error(E.Cause(err, "write DHCPv6 reply"))
+	}
+}
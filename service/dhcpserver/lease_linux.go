@@ -0,0 +1,123 @@
+package dhcpserver
+
+import (
+	"net/netip"
+	"sync"
+	"time"
+)
+
+const offerTimeout = time.Minute
+
+type lease struct {
+	address netip.Addr
+	expire  time.Time
+}
+
+type leasePool struct {
+	access        sync.Mutex
+	rangeStart    netip.Addr
+	rangeEnd      netip.Addr
+	serverAddress netip.Addr
+	leases        map[string]*lease
+	owners        map[netip.Addr]string
+	static        map[string]netip.Addr
+	staticOwners  map[netip.Addr]string
+}
+
+func newLeasePool(serverAddress netip.Prefix, rangeStart netip.Addr, rangeEnd netip.Addr, static map[string]netip.Addr) *leasePool {
+	pool := &leasePool{
+		rangeStart:    rangeStart,
+		rangeEnd:      rangeEnd,
+		serverAddress: serverAddress.Addr(),
+		leases:        make(map[string]*lease),
+		owners:        make(map[netip.Addr]string),
+		static:        static,
+		staticOwners:  make(map[netip.Addr]string),
+	}
+	for hardwareAddr, address := range static {
+		pool.staticOwners[address] = hardwareAddr
+	}
+	return pool
+}
+
+// offer picks an address for the client and reserves it until the client requests it.
+func (p *leasePool) offer(hardwareAddr string, requested netip.Addr) (netip.Addr, bool) {
+	p.access.Lock()
+	defer p.access.Unlock()
+	if address, loaded := p.static[hardwareAddr]; loaded {
+		return address, true
+	}
+	now := time.Now()
+	if current, loaded := p.leases[hardwareAddr]; loaded {
+		if current.expire.Before(now) {
+			current.expire = now.Add(offerTimeout)
+		}
+		return current.address, true
+	}
+	if requested.IsValid() && p.inRange(requested) && p.available(hardwareAddr, requested, now) {
+		p.store(hardwareAddr, requested, now.Add(offerTimeout))
+		return requested, true
+	}
+	for address := p.rangeStart; address.IsValid() && address.Compare(p.rangeEnd) <= 0; address = address.Next() {
+		if p.available(hardwareAddr, address, now) {
+			p.store(hardwareAddr, address, now.Add(offerTimeout))
+			return address, true
+		}
+	}
+	return netip.Addr{}, false
+}
+
+// commit binds the address to the client for the lease time.
+func (p *leasePool) commit(hardwareAddr string, address netip.Addr, leaseTime time.Duration) bool {
+	p.access.Lock()
+	defer p.access.Unlock()
+	if staticAddress, loaded := p.static[hardwareAddr]; loaded {
+		return staticAddress == address
+	}
+	now := time.Now()
+	if !p.inRange(address) || !p.available(hardwareAddr, address, now) {
+		return false
+	}
+	p.store(hardwareAddr, address, now.Add(leaseTime))
+	return true
+}
+
+func (p *leasePool) release(hardwareAddr string, address netip.Addr) {
+	p.access.Lock()
+	defer p.access.Unlock()
+	current, loaded := p.leases[hardwareAddr]
+	if !loaded || (address.IsValid() && current.address != address) {
+		return
+	}
+	delete(p.leases, hardwareAddr)
+	delete(p.owners, current.address)
+}
+
+func (p *leasePool) inRange(address netip.Addr) bool {
+	return address.Compare(p.rangeStart) >= 0 && address.Compare(p.rangeEnd) <= 0
+}
+
+func (p *leasePool) available(hardwareAddr string, address netip.Addr, now time.Time) bool {
+	if address == p.serverAddress {
+		return false
+	}
+	if owner, loaded := p.staticOwners[address]; loaded && owner != hardwareAddr {
+		return false
+	}
+	owner, loaded := p.owners[address]
+	if !loaded || owner == hardwareAddr {
+		return true
+	}
+	return p.leases[owner].expire.Before(now)
+}
+
+func (p *leasePool) store(hardwareAddr string, address netip.Addr, expire time.Time) {
+	if current, loaded := p.leases[hardwareAddr]; loaded && current.address != address {
+		delete(p.owners, current.address)
+	}
+	if owner, loaded := p.owners[address]; loaded && owner != hardwareAddr {
+		delete(p.leases, owner)
+	}
+	p.leases[hardwareAddr] = &lease{address: address, expire: expire}
+	p.owners[address] = hardwareAddr
+}
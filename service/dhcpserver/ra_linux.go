@@ -0,0 +1,210 @@
+package dhcpserver
+
+import (
+	"context"
+	"encoding/binary"
+	"math/rand"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"golang.org/x/net/ipv6"
+)
+
+// Router advertisement constants from RFC 4861 section 6.2.1 and 10.
+const (
+	maxRtrAdvInterval          = 600 * time.Second
+	minRtrAdvInterval          = 200 * time.Second
+	maxInitialRtrAdvertisement = 3
+	maxInitialRtrAdvInterval   = 16 * time.Second
+	routerLifetime             = 1800
+	prefixValidLifetime        = 86400
+	prefixPreferredLifetime    = 14400
+	dnsLifetime                = 1800
+)
+
+const (
+	icmpTypeRouterSolicitation  = 133
+	icmpTypeRouterAdvertisement = 134
+)
+
+var allNodesAddress = &net.IPAddr{IP: net.ParseIP("ff02::1")}
+
+type routerAdvertiser struct {
+	ctx         context.Context
+	logger      log.ContextLogger
+	iface       *net.Interface
+	prefixes    []netip.Prefix
+	dns         []netip.Addr
+	domainName  string
+	otherConfig bool
+	conn        *ipv6.PacketConn
+	writeAccess sync.Mutex
+	done        chan struct{}
+	closeOnce   sync.Once
+}
+
+func newRouterAdvertiser(ctx context.Context, logger log.ContextLogger, iface *net.Interface, prefixes []netip.Prefix, dns []netip.Addr, domainName string, otherConfig bool) *routerAdvertiser {
+	return &routerAdvertiser{
+		ctx:         ctx,
+		logger:      logger,
+		iface:       iface,
+		prefixes:    prefixes,
+		dns:         dns,
+		domainName:  domainName,
+		otherConfig: otherConfig,
+		done:        make(chan struct{}),
+	}
+}
+
+func (a *routerAdvertiser) Start() error {
+	packetConn, err := net.ListenPacket("ip6:ipv6-icmp", "::")
+	if err != nil {
+		return err
+	}
+	conn := ipv6.NewPacketConn(packetConn)
+	var filter ipv6.ICMPFilter
+	filter.SetAll(true)
+	filter.Accept(ipv6.ICMPTypeRouterSolicitation)
+	for _, setup := range []func() error{
+		func() error { return conn.SetICMPFilter(&filter) },
+		func() error { return conn.SetControlMessage(ipv6.FlagInterface, true) },
+		func() error { return conn.SetMulticastInterface(a.iface) },
+		func() error { return conn.SetMulticastHopLimit(255) },
+		func() error { return conn.SetHopLimit(255) },
+		func() error { return conn.SetMulticastLoopback(false) },
+		func() error { return conn.JoinGroup(a.iface, &net.IPAddr{IP: net.ParseIP("ff02::2")}) },
+	} {
+		err = setup()
+		if err != nil {
+			conn.Close()
+			return err
+		}
+	}
+	a.conn = conn
+	go a.loopRead()
+	go a.loopAdvertise()
+	return nil
+}
+
+func (a *routerAdvertiser) loopRead() {
+	buffer := make([]byte, 1500)
+	for {
+		n, controlMessage, source, err := a.conn.ReadFrom(buffer)
+		if err != nil {
+			select {
+			case <-a.done:
+			default:
+				a.logger.Error(E.Cause(err, "read router solicitation"))
+			}
+			return
+		}
+		if n < 8 || buffer[0] != icmpTypeRouterSolicitation || controlMessage == nil || controlMessage.IfIndex != a.iface.Index {
+			continue
+		}
+		destination := allNodesAddress
+		if sourceAddr, isIPAddr := source.(*net.IPAddr); isIPAddr && !sourceAddr.IP.IsUnspecified() {
+			destination = sourceAddr
+		}
+		a.logger.Debug("reply router solicitation from ", source)
+		err = a.advertise(destination, routerLifetime)
+		if err != nil {
+			a.logger.Error(E.Cause(err, "send router advertisement"))
+		}
+	}
+}
+
+func (a *routerAdvertiser) loopAdvertise() {
+	for count := 0; ; count++ {
+		err := a.advertise(allNodesAddress, routerLifetime)
+		if err != nil {
+			a.logger.Error(E.Cause(err, "send router advertisement"))
+		}
+		interval := minRtrAdvInterval + time.Duration(rand.Int63n(int64(maxRtrAdvInterval-minRtrAdvInterval)))
+		if count < maxInitialRtrAdvertisement && interval > maxInitialRtrAdvInterval {
+			interval = maxInitialRtrAdvInterval
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+		case <-a.done:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+func (a *routerAdvertiser) advertise(destination *net.IPAddr, lifetime uint16) error {
+	a.writeAccess.Lock()
+	defer a.writeAccess.Unlock()
+	_, err := a.conn.WriteTo(a.buildAdvertisement(lifetime), &ipv6.ControlMessage{IfIndex: a.iface.Index}, destination)
+	return err
+}
+
+// buildAdvertisement encodes the router advertisement message, the checksum is filled by the kernel.
+func (a *routerAdvertiser) buildAdvertisement(lifetime uint16) []byte {
+	message := make([]byte, 16, 128)
+	message[0] = icmpTypeRouterAdvertisement
+	message[4] = 64
+	if a.otherConfig {
+		message[5] |= 0x40
+	}
+	binary.BigEndian.PutUint16(message[6:], lifetime)
+	if len(a.iface.HardwareAddr) == 6 {
+		message = append(message, 1, 1)
+		message = append(message, a.iface.HardwareAddr...)
+	}
+	if a.iface.MTU > 0 {
+		message = append(message, 5, 1, 0, 0)
+		message = binary.BigEndian.AppendUint32(message, uint32(a.iface.MTU))
+	}
+	for _, prefix := range a.prefixes {
+		message = append(message, 3, 4, byte(prefix.Bits()), 0xc0)
+		message = binary.BigEndian.AppendUint32(message, prefixValidLifetime)
+		message = binary.BigEndian.AppendUint32(message, prefixPreferredLifetime)
+		message = append(message, 0, 0, 0, 0)
+		prefixAddress := prefix.Masked().Addr().As16()
+		message = append(message, prefixAddress[:]...)
+	}
+	if len(a.dns) > 0 {
+		message = append(message, 25, byte(1+2*len(a.dns)), 0, 0)
+		message = binary.BigEndian.AppendUint32(message, dnsLifetime)
+		for _, address := range a.dns {
+			dnsAddress := address.As16()
+			message = append(message, dnsAddress[:]...)
+		}
+	}
+	if a.domainName != "" {
+		var domain []byte
+		for _, label := range strings.Split(strings.TrimSuffix(a.domainName, "."), ".") {
+			domain = append(domain, byte(len(label)))
+			domain = append(domain, label...)
+		}
+		domain = append(domain, 0)
+		optionLength := (8 + len(domain) + 7) / 8
+		message = append(message, 31, byte(optionLength), 0, 0)
+		message = binary.BigEndian.AppendUint32(message, dnsLifetime)
+		message = append(message, domain...)
+		message = append(message, make([]byte, optionLength*8-8-len(domain))...)
+	}
+	return message
+}
+
+func (a *routerAdvertiser) Close() error {
+	var err error
+	a.closeOnce.Do(func() {
+		close(a.done)
+		if a.conn == nil {
+			return
+		}
+		// withdraw the default router from clients, see RFC 4861 section 6.2.5
+		err = a.advertise(allNodesAddress, 0)
+		err = E.Errors(err, a.conn.Close())
+	})
+	return err
+}
@@ -0,0 +1,213 @@
+package dhcpserver
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	boxService "github.com/sagernet/sing-box/adapter/service"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/insomniacslk/dhcp/dhcpv4/server4"
+	"github.com/insomniacslk/dhcp/dhcpv6/server6"
+)
+
+const defaultLeaseTime = 12 * time.Hour
+
+func RegisterService(registry *boxService.Registry) {
+	boxService.Register[option.DHCPServerServiceOptions](registry, C.TypeDHCPServer, NewService)
+}
+
+type Service struct {
+	boxService.Adapter
+	ctx           context.Context
+	logger        log.ContextLogger
+	options       option.DHCPServerServiceOptions
+	leaseTime     time.Duration
+	staticLease   map[string]netip.Addr
+	iface         *net.Interface
+	serverAddress netip.Prefix
+	dns4          []netip.Addr
+	dns6          []netip.Addr
+	pool          *leasePool
+	server4       *server4.Server
+	server6       *server6.Server
+	advertiser    *routerAdvertiser
+}
+
+func NewService(ctx context.Context, logger log.ContextLogger, tag string, options option.DHCPServerServiceOptions) (adapter.Service, error) {
+	if options.Interface == "" {
+		return nil, E.New("missing interface")
+	}
+	if (options.Inet4RangeStart == nil) != (options.Inet4RangeEnd == nil) {
+		return nil, E.New("both inet4_range_start and inet4_range_end must be set")
+	}
+	leaseTime := time.Duration(options.LeaseTime)
+	if leaseTime == 0 {
+		leaseTime = defaultLeaseTime
+	}
+	staticLease := make(map[string]netip.Addr)
+	for _, lease := range options.StaticLease {
+		hardwareAddr, err := net.ParseMAC(lease.MAC)
+		if err != nil {
+			return nil, E.Cause(err, "parse static lease MAC address")
+		}
+		if !lease.Address.Is4() {
+			return nil, E.New("invalid static lease address for ", lease.MAC, ": ", lease.Address)
+		}
+		staticLease[hardwareAddr.String()] = lease.Address
+	}
+	for _, prefix := range options.Inet6Prefix {
+		if !prefix.Addr().Is6() || prefix.Bits() != 64 {
+			return nil, E.New("invalid inet6_prefix ", prefix, ": only /64 IPv6 prefixes are supported")
+		}
+	}
+	return &Service{
+		Adapter:     boxService.NewAdapter(C.TypeDHCPServer, tag),
+		ctx:         ctx,
+		logger:      logger,
+		options:     options,
+		leaseTime:   leaseTime,
+		staticLease: staticLease,
+	}, nil
+}
+
+func (s *Service) Start(stage adapter.StartStage) error {
+	if stage != adapter.StartStateStart {
+		return nil
+	}
+	iface, err := net.InterfaceByName(s.options.Interface)
+	if err != nil {
+		return E.Cause(err, "find interface ", s.options.Interface)
+	}
+	s.iface = iface
+	addresses, err := interfaceAddresses(iface)
+	if err != nil {
+		return err
+	}
+	if s.options.Inet4Address != nil {
+		s.serverAddress = s.options.Inet4Address.Build(netip.Prefix{})
+	} else {
+		for _, address := range addresses {
+			if address.Addr().Is4() {
+				s.serverAddress = address
+				break
+			}
+		}
+	}
+	if !s.serverAddress.IsValid() || !s.serverAddress.Addr().Is4() {
+		return E.New("missing IPv4 address on interface ", iface.Name)
+	}
+	var rangeStart, rangeEnd netip.Addr
+	if s.options.Inet4RangeStart != nil {
+		rangeStart = s.options.Inet4RangeStart.Build(netip.Addr{})
+		rangeEnd = s.options.Inet4RangeEnd.Build(netip.Addr{})
+		if !s.serverAddress.Contains(rangeStart) || !s.serverAddress.Contains(rangeEnd) || rangeStart.Compare(rangeEnd) > 0 {
+			return E.New("invalid inet4 range ", rangeStart, "-", rangeEnd, " for ", s.serverAddress)
+		}
+	} else {
+		rangeStart = s.serverAddress.Masked().Addr().Next()
+		rangeEnd = lastAddress(s.serverAddress).Prev()
+		if !rangeStart.IsValid() || rangeStart.Compare(rangeEnd) > 0 {
+			return E.New("inet4 address ", s.serverAddress, " leaves no addresses to lease")
+		}
+	}
+	for _, address := range s.options.DNS {
+		if address.Is4() {
+			s.dns4 = append(s.dns4, address)
+		} else {
+			s.dns6 = append(s.dns6, address)
+		}
+	}
+	if len(s.options.DNS) == 0 {
+		s.dns4 = []netip.Addr{s.serverAddress.Addr()}
+		for _, address := range addresses {
+			if address.Addr().Is6() && address.Addr().IsLinkLocalUnicast() {
+				s.dns6 = []netip.Addr{address.Addr()}
+				break
+			}
+		}
+	}
+	s.pool = newLeasePool(s.serverAddress, rangeStart, rangeEnd, s.staticLease)
+	s.server4, err = server4.NewServer(iface.Name, nil, s.handleDHCPv4)
+	if err != nil {
+		return E.Cause(err, "listen DHCPv4")
+	}
+	go s.serve(s.server4.Serve, "DHCPv4")
+	s.logger.Info("DHCPv4 server started at ", iface.Name, ", leasing ", rangeStart, "-", rangeEnd)
+	if s.options.RouterAdvertisement {
+		prefixes := s.options.Inet6Prefix
+		if len(prefixes) == 0 {
+			for _, address := range addresses {
+				if address.Addr().Is6() && address.Addr().IsGlobalUnicast() && address.Bits() == 64 {
+					prefixes = append(prefixes, address.Masked())
+				}
+			}
+		}
+		if len(prefixes) == 0 {
+			s.logger.Warn("no /64 IPv6 prefix found on interface ", iface.Name, ", router advertisements will contain no prefix")
+		}
+		stateless := len(s.dns6) > 0 || s.options.DomainName != ""
+		if stateless {
+			s.server6, err = server6.NewServer(iface.Name, nil, s.handleDHCPv6)
+			if err != nil {
+				return E.Cause(err, "listen DHCPv6")
+			}
+			go s.serve(s.server6.Serve, "DHCPv6")
+		}
+		s.advertiser = newRouterAdvertiser(s.ctx, s.logger, iface, prefixes, s.dns6, s.options.DomainName, stateless)
+		err = s.advertiser.Start()
+		if err != nil {
+			return E.Cause(err, "start router advertisement")
+		}
+		s.logger.Info("router advertisement started at ", iface.Name)
+	}
+	return nil
+}
+
+func (s *Service) serve(serveFunc func() error, name string) {
+	err := serveFunc()
+	if err != nil && !E.IsClosed(err) {
+		s.logger.Error(E.Cause(err, "serve ", name))
+	}
+}
+
+func (s *Service) Close() error {
+	return common.Close(
+		common.PtrOrNil(s.advertiser),
+		common.PtrOrNil(s.server4),
+		common.PtrOrNil(s.server6),
+	)
+}
+
+func interfaceAddresses(iface *net.Interface) ([]netip.Prefix, error) {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, E.Cause(err, "list addresses of interface ", iface.Name)
+	}
+	var prefixes []netip.Prefix
+	for _, addr := range addrs {
+		ipNet, isIPNet := addr.(*net.IPNet)
+		if !isIPNet {
+			continue
+		}
+		address, _ := netip.AddrFromSlice(ipNet.IP)
+		ones, _ := ipNet.Mask.Size()
+		prefixes = append(prefixes, netip.PrefixFrom(address.Unmap(), ones))
+	}
+	return prefixes, nil
+}
+
+func lastAddress(prefix netip.Prefix) netip.Addr {
+	address := prefix.Masked().Addr().As4()
+	for i := prefix.Bits(); i < 32; i++ {
+		address[i/8] |= 1 << (7 - i%8)
+	}
+	return netip.AddrFrom4(address)
+}
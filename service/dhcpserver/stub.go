@@ -0,0 +1,20 @@
+//go:build !linux
+
+package dhcpserver
+
+import (
+	"context"
+
+	"github.com/sagernet/sing-box/adapter"
+	boxService "github.com/sagernet/sing-box/adapter/service"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+func RegisterService(registry *boxService.Registry) {
+	boxService.Register[option.DHCPServerServiceOptions](registry, C.TypeDHCPServer, func(ctx context.Context, logger log.ContextLogger, tag string, options option.DHCPServerServiceOptions) (adapter.Service, error) {
+		return nil, E.New("DHCP server service is only supported on Linux")
+	})
+}
@@ -0,0 +1,126 @@
+package ebpf
+
+import (
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"golang.org/x/sys/unix"
+)
+
+// Offsets of struct bpf_sock_ops, struct sk_msg_md and struct bpf_sock in uapi/linux/bpf.h.
+const (
+	sockOpsOp         = 0
+	sockOpsFamily     = 20
+	sockOpsRemoteIP4  = 24
+	sockOpsLocalIP4   = 28
+	sockOpsRemotePort = 64
+	sockOpsLocalPort  = 68
+
+	skMsgRemoteIP4  = 20
+	skMsgLocalIP4   = 24
+	skMsgRemotePort = 60
+	skMsgLocalPort  = 64
+
+	sockMark = 16
+)
+
+const (
+	sockOpsActiveEstablished  = 4
+	sockOpsPassiveEstablished = 5
+	skPass                    = 1
+	flagIngress               = 1
+)
+
+// sockKeySize is the size of the key of local sockets: local address, remote address,
+// local port and remote port. Ports are stored in host byte order, remote_port in the
+// context is in network byte order.
+const sockKeySize = 16
+
+func newSockMap() (*ebpf.Map, error) {
+	return ebpf.NewMap(&ebpf.MapSpec{
+		Name:       "sing_box_sock",
+		Type:       ebpf.SockHash,
+		KeySize:    sockKeySize,
+		ValueSize:  4,
+		MaxEntries: 65535,
+	})
+}
+
+// newSockOpsProgram adds established IPv4 TCP sockets whose both ends have the same
+// address to the map.
+func newSockOpsProgram(sockMap *ebpf.Map) (*ebpf.Program, error) {
+	return ebpf.NewProgram(&ebpf.ProgramSpec{
+		Name:    "sing_box_sockops",
+		Type:    ebpf.SockOps,
+		License: "GPL",
+		Instructions: asm.Instructions{
+			asm.Mov.Reg(asm.R6, asm.R1),
+			asm.LoadMem(asm.R2, asm.R6, sockOpsOp, asm.Word),
+			asm.JEq.Imm(asm.R2, sockOpsActiveEstablished, "established"),
+			asm.JEq.Imm(asm.R2, sockOpsPassiveEstablished, "established"),
+			asm.Ja.Label("exit"),
+			asm.LoadMem(asm.R2, asm.R6, sockOpsFamily, asm.Word).WithSymbol("established"),
+			asm.JNE.Imm(asm.R2, unix.AF_INET, "exit"),
+			asm.LoadMem(asm.R2, asm.R6, sockOpsLocalIP4, asm.Word),
+			asm.LoadMem(asm.R3, asm.R6, sockOpsRemoteIP4, asm.Word),
+			asm.JNE.Reg(asm.R2, asm.R3, "exit"),
+			asm.StoreMem(asm.RFP, -16, asm.R2, asm.Word),
+			asm.StoreMem(asm.RFP, -12, asm.R3, asm.Word),
+			asm.LoadMem(asm.R2, asm.R6, sockOpsLocalPort, asm.Word),
+			asm.StoreMem(asm.RFP, -8, asm.R2, asm.Word),
+			asm.LoadMem(asm.R2, asm.R6, sockOpsRemotePort, asm.Word),
+			asm.HostTo(asm.BE, asm.R2, asm.Word),
+			asm.StoreMem(asm.RFP, -4, asm.R2, asm.Word),
+			asm.Mov.Reg(asm.R1, asm.R6),
+			asm.LoadMapPtr(asm.R2, sockMap.FD()),
+			asm.Mov.Reg(asm.R3, asm.RFP),
+			asm.Add.Imm(asm.R3, -16),
+			asm.Mov.Imm(asm.R4, 0),
+			asm.FnSockHashUpdate.Call(),
+			asm.Mov.Imm(asm.R0, 1).WithSymbol("exit"),
+			asm.Return(),
+		},
+	})
+}
+
+// newSkMsgProgram redirects messages to the receive queue of the peer socket if it is in
+// the map, otherwise messages pass through the network stack.
+func newSkMsgProgram(sockMap *ebpf.Map) (*ebpf.Program, error) {
+	return ebpf.NewProgram(&ebpf.ProgramSpec{
+		Name:    "sing_box_sk_msg",
+		Type:    ebpf.SkMsg,
+		License: "GPL",
+		Instructions: asm.Instructions{
+			asm.LoadMem(asm.R2, asm.R1, skMsgRemoteIP4, asm.Word),
+			asm.StoreMem(asm.RFP, -16, asm.R2, asm.Word),
+			asm.LoadMem(asm.R2, asm.R1, skMsgLocalIP4, asm.Word),
+			asm.StoreMem(asm.RFP, -12, asm.R2, asm.Word),
+			asm.LoadMem(asm.R2, asm.R1, skMsgRemotePort, asm.Word),
+			asm.HostTo(asm.BE, asm.R2, asm.Word),
+			asm.StoreMem(asm.RFP, -8, asm.R2, asm.Word),
+			asm.LoadMem(asm.R2, asm.R1, skMsgLocalPort, asm.Word),
+			asm.StoreMem(asm.RFP, -4, asm.R2, asm.Word),
+			asm.LoadMapPtr(asm.R2, sockMap.FD()),
+			asm.Mov.Reg(asm.R3, asm.RFP),
+			asm.Add.Imm(asm.R3, -16),
+			asm.Mov.Imm(asm.R4, flagIngress),
+			asm.FnMsgRedirectHash.Call(),
+			asm.Mov.Imm(asm.R0, skPass),
+			asm.Return(),
+		},
+	})
+}
+
+// newMarkProgram sets the mark of sockets created in the attached cgroup.
+func newMarkProgram(mark uint32) (*ebpf.Program, error) {
+	return ebpf.NewProgram(&ebpf.ProgramSpec{
+		Name:    "sing_box_mark",
+		Type:    ebpf.CGroupSock,
+		License: "GPL",
+		Instructions: asm.Instructions{
+			asm.Mov.Imm32(asm.R2, int32(mark)),
+			asm.StoreMem(asm.R1, sockMark, asm.R2, asm.Word),
+			asm.Mov.Imm(asm.R0, 1),
+			asm.Return(),
+		},
+	})
+}
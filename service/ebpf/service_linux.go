@@ -0,0 +1,184 @@
+package ebpf
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	boxService "github.com/sagernet/sing-box/adapter/service"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/service"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/rlimit"
+)
+
+func RegisterService(registry *boxService.Registry) {
+	boxService.Register[option.EBPFServiceOptions](registry, C.TypeEBPF, NewService)
+}
+
+type Service struct {
+	boxService.Adapter
+	logger         log.ContextLogger
+	networkManager adapter.NetworkManager
+	options        option.EBPFServiceOptions
+	sockMap        *ebpf.Map
+	sockOps        *ebpf.Program
+	skMsg          *ebpf.Program
+	skMsgAttached  bool
+	mark           *ebpf.Program
+	links          []link.Link
+}
+
+func NewService(ctx context.Context, logger log.ContextLogger, tag string, options option.EBPFServiceOptions) (adapter.Service, error) {
+	if !options.LocalRedirect && len(options.BypassCgroup) == 0 {
+		return nil, E.New("missing local_redirect or bypass_cgroup")
+	}
+	return &Service{
+		Adapter:        boxService.NewAdapter(C.TypeEBPF, tag),
+		logger:         logger,
+		networkManager: service.FromContext[adapter.NetworkManager](ctx),
+		options:        options,
+	}, nil
+}
+
+func (s *Service) Start(stage adapter.StartStage) error {
+	if stage != adapter.StartStateStart {
+		return nil
+	}
+	err := rlimit.RemoveMemlock()
+	if err != nil {
+		return E.Cause(err, "remove memlock limit")
+	}
+	cgroupRoot, err := cgroupMountPoint()
+	if err != nil {
+		return err
+	}
+	if s.options.LocalRedirect {
+		err = s.startLocalRedirect(cgroupPath(cgroupRoot, s.options.CgroupPath))
+		if err != nil {
+			return E.Cause(err, "start local redirect")
+		}
+		s.logger.Info("local redirect enabled")
+	}
+	if len(s.options.BypassCgroup) > 0 {
+		mark := uint32(s.options.BypassMark)
+		if mark == 0 {
+			mark = s.networkManager.AutoRedirectOutputMark()
+		}
+		if mark == 0 {
+			return E.New("missing bypass_mark")
+		}
+		s.mark, err = newMarkProgram(mark)
+		if err != nil {
+			return E.Cause(err, "load bypass program")
+		}
+		for _, bypassCgroup := range s.options.BypassCgroup {
+			var cgroupLink link.Link
+			cgroupLink, err = link.AttachCgroup(link.CgroupOptions{
+				Path:    cgroupPath(cgroupRoot, bypassCgroup),
+				Attach:  ebpf.AttachCGroupInetSockCreate,
+				Program: s.mark,
+			})
+			if err != nil {
+				return E.Cause(err, "attach bypass program to cgroup ", bypassCgroup)
+			}
+			s.links = append(s.links, cgroupLink)
+		}
+		s.logger.Info("bypass enabled for ", len(s.options.BypassCgroup), " cgroups")
+	}
+	return nil
+}
+
+func (s *Service) startLocalRedirect(cgroup string) error {
+	var err error
+	s.sockMap, err = newSockMap()
+	if err != nil {
+		return E.Cause(err, "create sockhash")
+	}
+	s.sockOps, err = newSockOpsProgram(s.sockMap)
+	if err != nil {
+		return E.Cause(err, "load sockops program")
+	}
+	s.skMsg, err = newSkMsgProgram(s.sockMap)
+	if err != nil {
+		return E.Cause(err, "load sk_msg program")
+	}
+	err = link.RawAttachProgram(link.RawAttachProgramOptions{
+		Target:  s.sockMap.FD(),
+		Program: s.skMsg,
+		Attach:  ebpf.AttachSkMsgVerdict,
+	})
+	if err != nil {
+		return E.Cause(err, "attach sk_msg program")
+	}
+	s.skMsgAttached = true
+	cgroupLink, err := link.AttachCgroup(link.CgroupOptions{
+		Path:    cgroup,
+		Attach:  ebpf.AttachCGroupSockOps,
+		Program: s.sockOps,
+	})
+	if err != nil {
+		return E.Cause(err, "attach sockops program to cgroup ", cgroup)
+	}
+	s.links = append(s.links, cgroupLink)
+	return nil
+}
+
+func (s *Service) Close() error {
+	var err error
+	for _, cgroupLink := range s.links {
+		err = E.Append(err, cgroupLink.Close(), func(err error) error {
+			return E.Cause(err, "detach cgroup program")
+		})
+	}
+	if s.skMsgAttached {
+		err = E.Append(err, link.RawDetachProgram(link.RawDetachProgramOptions{
+			Target:  s.sockMap.FD(),
+			Program: s.skMsg,
+			Attach:  ebpf.AttachSkMsgVerdict,
+		}), func(err error) error {
+			return E.Cause(err, "detach sk_msg program")
+		})
+	}
+	return E.Append(err, common.Close(
+		common.PtrOrNil(s.sockOps),
+		common.PtrOrNil(s.skMsg),
+		common.PtrOrNil(s.mark),
+		common.PtrOrNil(s.sockMap),
+	), func(err error) error {
+		return E.Cause(err, "close programs")
+	})
+}
+
+// cgroupMountPoint finds the cgroup v2 hierarchy, which is /sys/fs/cgroup/unified in hybrid mode.
+func cgroupMountPoint() (string, error) {
+	mountsFile, err := os.Open("/proc/self/mounts")
+	if err != nil {
+		return "", err
+	}
+	defer mountsFile.Close()
+	scanner := bufio.NewScanner(mountsFile)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 3 && fields[2] == "cgroup2" {
+			return fields[1], nil
+		}
+	}
+	return "", E.New("cgroup v2 is not mounted")
+}
+
+func cgroupPath(cgroupRoot string, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(cgroupRoot, path)
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+
+package ebpf
+
+import (
+	"context"
+
+	"github.com/sagernet/sing-box/adapter"
+	boxService "github.com/sagernet/sing-box/adapter/service"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+func RegisterService(registry *boxService.Registry) {
+	boxService.Register[option.EBPFServiceOptions](registry, C.TypeEBPF, func(ctx context.Context, logger log.ContextLogger, tag string, options option.EBPFServiceOptions) (adapter.Service, error) {
+		return nil, E.New("eBPF service is only supported on Linux")
+	})
+}
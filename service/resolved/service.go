@@ -73,6 +73,7 @@ func NewService(ctx context.Context, logger log.ContextLogger, tag string, optio
 	inbound.listener = listener.New(listener.Options{
 		Context:                  ctx,
 		Logger:                   logger,
+		Tag:                      tag,
 		Network:                  []string{N.NetworkTCP, N.NetworkUDP},
 		Listen:                   options.ListenOptions,
 		ConnectionHandler:        inbound,
@@ -0,0 +1,208 @@
+package scheduler
+
+import (
+	"context"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/protocol/group"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/service"
+)
+
+type selectAction struct {
+	outbound adapter.OutboundManager
+	selector string
+	target   string
+}
+
+func newSelectAction(ctx context.Context, options option.SchedulerTaskOptions) (*selectAction, error) {
+	if options.Selector == "" {
+		return nil, E.New("missing selector")
+	}
+	if options.Outbound == "" {
+		return nil, E.New("missing outbound")
+	}
+	return &selectAction{
+		outbound: service.FromContext[adapter.OutboundManager](ctx),
+		selector: options.Selector,
+		target:   options.Outbound,
+	}, nil
+}
+
+func (a *selectAction) start(ctx context.Context) error {
+	_, err := a.loadSelector()
+	return err
+}
+
+func (a *selectAction) run(ctx context.Context) error {
+	selector, err := a.loadSelector()
+	if err != nil {
+		return err
+	}
+	if !selector.SelectOutbound(a.target) {
+		return E.New("outbound not found in selector ", a.selector, ": ", a.target)
+	}
+	return nil
+}
+
+// loadSelector looks up the selector on each run, as it may be replaced by
+// subscriptions.
+func (a *selectAction) loadSelector() (*group.Selector, error) {
+	outbound, loaded := a.outbound.Outbound(a.selector)
+	if !loaded {
+		return nil, E.New("selector not found: ", a.selector)
+	}
+	selector, isSelector := outbound.(*group.Selector)
+	if !isSelector {
+		return nil, E.New("outbound ", a.selector, " is not a selector")
+	}
+	return selector, nil
+}
+
+func (a *selectAction) close() {
+}
+
+type updateSubscriptionAction struct {
+	serviceManager adapter.ServiceManager
+	tags           []string
+}
+
+func newUpdateSubscriptionAction(ctx context.Context, options option.SchedulerTaskOptions) (*updateSubscriptionAction, error) {
+	return &updateSubscriptionAction{
+		serviceManager: service.FromContext[adapter.ServiceManager](ctx),
+		tags:           options.Subscription,
+	}, nil
+}
+
+func (a *updateSubscriptionAction) start(ctx context.Context) error {
+	_, err := a.subscriptions()
+	return err
+}
+
+func (a *updateSubscriptionAction) run(ctx context.Context) error {
+	subscriptions, err := a.subscriptions()
+	if err != nil {
+		return err
+	}
+	for _, subscription := range subscriptions {
+		err = E.Append(err, subscription.Update(ctx), func(err error) error {
+			return E.Cause(err, "update subscription ", subscription.Tag())
+		})
+	}
+	return err
+}
+
+// subscriptions returns the configured subscriptions, or all of them if
+// none is configured.
+func (a *updateSubscriptionAction) subscriptions() ([]adapter.Subscription, error) {
+	var subscriptions []adapter.Subscription
+	if len(a.tags) == 0 {
+		for _, it := range a.serviceManager.Services() {
+			if subscription, isSubscription := it.(adapter.Subscription); isSubscription {
+				subscriptions = append(subscriptions, subscription)
+			}
+		}
+		return subscriptions, nil
+	}
+	for _, tag := range a.tags {
+		it, loaded := a.serviceManager.Get(tag)
+		if !loaded {
+			return nil, E.New("subscription not found: ", tag)
+		}
+		subscription, isSubscription := it.(adapter.Subscription)
+		if !isSubscription {
+			return nil, E.New("service ", tag, " is not a subscription")
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	return subscriptions, nil
+}
+
+func (a *updateSubscriptionAction) close() {
+}
+
+type updateRuleSetAction struct {
+	router adapter.Router
+	tags   []string
+}
+
+func newUpdateRuleSetAction(ctx context.Context, options option.SchedulerTaskOptions) (*updateRuleSetAction, error) {
+	return &updateRuleSetAction{
+		router: service.FromContext[adapter.Router](ctx),
+		tags:   options.RuleSet,
+	}, nil
+}
+
+func (a *updateRuleSetAction) start(ctx context.Context) error {
+	for _, tag := range a.tags {
+		ruleSet, loaded := a.router.RuleSet(tag)
+		if !loaded {
+			return E.New("rule-set not found: ", tag)
+		}
+		if ruleSet.Type() != C.RuleSetTypeRemote {
+			return E.New("rule-set ", tag, " is not a remote rule-set")
+		}
+	}
+	return nil
+}
+
+// run updates the configured rule-sets, or all remote rule-sets if none is
+// configured.
+func (a *updateRuleSetAction) run(ctx context.Context) error {
+	var err error
+	if len(a.tags) == 0 {
+		for _, ruleSet := range a.router.RuleSets() {
+			if ruleSet.Type() == C.RuleSetTypeRemote {
+				err = E.Append(err, ruleSet.Update(ctx), func(err error) error {
+					return E.Cause(err, "update rule-set ", ruleSet.Name())
+				})
+			}
+		}
+		return err
+	}
+	for _, tag := range a.tags {
+		ruleSet, loaded := a.router.RuleSet(tag)
+		if !loaded {
+			continue
+		}
+		err = E.Append(err, ruleSet.Update(ctx), func(err error) error {
+			return E.Cause(err, "update rule-set ", tag)
+		})
+	}
+	return err
+}
+
+func (a *updateRuleSetAction) close() {
+}
+
+type rotateLogAction struct {
+	logFactory log.Factory
+	maxBackups int
+}
+
+func newRotateLogAction(ctx context.Context, options option.SchedulerTaskOptions) (*rotateLogAction, error) {
+	if options.MaxBackups < 0 {
+		return nil, E.New("invalid max_backups: ", options.MaxBackups)
+	}
+	return &rotateLogAction{
+		logFactory: service.FromContext[log.Factory](ctx),
+		maxBackups: options.MaxBackups,
+	}, nil
+}
+
+func (a *rotateLogAction) start(ctx context.Context) error {
+	if factory, isRotatable := a.logFactory.(log.RotatableFactory); !isRotatable || !factory.Rotatable() {
+		return E.New("log output is not a file")
+	}
+	return nil
+}
+
+func (a *rotateLogAction) run(ctx context.Context) error {
+	return a.logFactory.(log.RotatableFactory).Rotate(a.maxBackups)
+}
+
+func (a *rotateLogAction) close() {
+}
@@ -0,0 +1,207 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/urltest"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json/badoption"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/ntp"
+	"github.com/sagernet/sing/service"
+)
+
+// healthReportAction posts the state of outbound groups and subscriptions
+// to a webhook.
+type healthReportAction struct {
+	ctx            context.Context
+	outbound       adapter.OutboundManager
+	serviceManager adapter.ServiceManager
+	options        option.SchedulerTaskOptions
+	httpClient     *http.Client
+}
+
+type healthReport struct {
+	Time          time.Time            `json:"time"`
+	Version       string               `json:"version"`
+	Goroutines    int                  `json:"goroutines"`
+	Memory        uint64               `json:"memory"`
+	Groups        []groupReport        `json:"groups"`
+	Subscriptions []subscriptionReport `json:"subscriptions,omitempty"`
+}
+
+type groupReport struct {
+	Tag       string           `json:"tag"`
+	Type      string           `json:"type"`
+	Now       string           `json:"now"`
+	Outbounds []outboundReport `json:"outbounds"`
+}
+
+type outboundReport struct {
+	Tag      string     `json:"tag"`
+	Delay    uint16     `json:"delay,omitempty"`
+	TestedAt *time.Time `json:"tested_at,omitempty"`
+}
+
+type subscriptionReport struct {
+	Tag       string     `json:"tag"`
+	Outbounds int        `json:"outbounds"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+func newHealthReportAction(ctx context.Context, options option.SchedulerTaskOptions) (*healthReportAction, error) {
+	if options.URL == "" {
+		return nil, E.New("missing url")
+	}
+	reportURL, err := url.Parse(options.URL)
+	if err != nil {
+		return nil, E.Cause(err, "parse url")
+	}
+	if reportURL.Scheme != "http" && reportURL.Scheme != "https" {
+		return nil, E.New("unsupported url scheme: ", reportURL.Scheme)
+	}
+	if options.Timeout == 0 {
+		options.Timeout = badoption.Duration(C.TCPTimeout)
+	}
+	return &healthReportAction{
+		ctx:            ctx,
+		outbound:       service.FromContext[adapter.OutboundManager](ctx),
+		serviceManager: service.FromContext[adapter.ServiceManager](ctx),
+		options:        options,
+	}, nil
+}
+
+func (a *healthReportAction) start(ctx context.Context) error {
+	var dialer N.Dialer
+	if a.options.Detour != "" {
+		outbound, loaded := a.outbound.Outbound(a.options.Detour)
+		if !loaded {
+			return E.New("detour outbound not found: ", a.options.Detour)
+		}
+		dialer = outbound
+	} else {
+		dialer = a.outbound.Default()
+	}
+	a.httpClient = &http.Client{
+		Transport: &http.Transport{
+			ForceAttemptHTTP2:   true,
+			TLSHandshakeTimeout: C.TCPTimeout,
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, M.ParseSocksaddr(addr))
+			},
+			TLSClientConfig: &tls.Config{
+				Time:    ntp.TimeFuncFromContext(a.ctx),
+				RootCAs: adapter.RootPoolFromContext(a.ctx),
+			},
+		},
+		Timeout: time.Duration(a.options.Timeout),
+	}
+	return nil
+}
+
+func (a *healthReportAction) run(ctx context.Context) error {
+	body, err := json.Marshal(a.buildReport())
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, a.options.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "sing-box "+C.Version)
+	for name, values := range a.options.Headers.Build() {
+		request.Header[name] = values
+	}
+	response, err := a.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 4096))
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return E.New("unexpected status: ", strings.TrimSpace(response.Status))
+	}
+	return nil
+}
+
+func (a *healthReportAction) buildReport() healthReport {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	report := healthReport{
+		Time:       time.Now(),
+		Version:    C.Version,
+		Goroutines: runtime.NumGoroutine(),
+		Memory:     memStats.HeapInuse + memStats.StackInuse,
+		Groups:     []groupReport{},
+	}
+	// the history of the Clash API is registered after services are created
+	var history adapter.URLTestHistoryStorage
+	if historyFromCtx := service.PtrFromContext[urltest.HistoryStorage](a.ctx); historyFromCtx != nil {
+		history = historyFromCtx
+	} else if clashServer := service.FromContext[adapter.ClashServer](a.ctx); clashServer != nil {
+		history = clashServer.HistoryStorage()
+	}
+	for _, outbound := range a.outbound.Outbounds() {
+		outboundGroup, isGroup := outbound.(adapter.OutboundGroup)
+		if !isGroup {
+			continue
+		}
+		group := groupReport{
+			Tag:       outboundGroup.Tag(),
+			Type:      outboundGroup.Type(),
+			Now:       outboundGroup.Now(),
+			Outbounds: []outboundReport{},
+		}
+		for _, tag := range outboundGroup.All() {
+			member := outboundReport{Tag: tag}
+			if history != nil {
+				if testHistory := history.LoadURLTestHistory(tag); testHistory != nil {
+					member.Delay = testHistory.Delay
+					member.TestedAt = &testHistory.Time
+				}
+			}
+			group.Outbounds = append(group.Outbounds, member)
+		}
+		report.Groups = append(report.Groups, group)
+	}
+	for _, it := range a.serviceManager.Services() {
+		subscription, isSubscription := it.(adapter.Subscription)
+		if !isSubscription {
+			continue
+		}
+		entry := subscriptionReport{
+			Tag:       subscription.Tag(),
+			Outbounds: len(subscription.Outbounds()),
+		}
+		if updatedAt := subscription.UpdatedAt(); !updatedAt.IsZero() {
+			entry.UpdatedAt = &updatedAt
+		}
+		if err := subscription.LastError(); err != nil {
+			entry.Error = err.Error()
+		}
+		report.Subscriptions = append(report.Subscriptions, entry)
+	}
+	return report
+}
+
+func (a *healthReportAction) close() {
+	if a.httpClient != nil {
+		a.httpClient.CloseIdleConnections()
+	}
+}
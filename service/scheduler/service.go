@@ -0,0 +1,162 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	boxService "github.com/sagernet/sing-box/adapter/service"
+	"github.com/sagernet/sing-box/common/cron"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+)
+
+const (
+	ActionSelect             = "select"
+	ActionUpdateSubscription = "update_subscription"
+	ActionUpdateRuleSet      = "update_rule_set"
+	ActionRotateLog          = "rotate_log"
+	ActionHealthReport       = "health_report"
+)
+
+func RegisterService(registry *boxService.Registry) {
+	boxService.Register[option.SchedulerServiceOptions](registry, C.TypeScheduler, NewService)
+}
+
+type Service struct {
+	boxService.Adapter
+	ctx    context.Context
+	cancel context.CancelFunc
+	logger log.ContextLogger
+	tasks  []*task
+}
+
+// task runs an action each time its schedule matches, runs are skipped
+// while the previous one is still running.
+type task struct {
+	name     string
+	schedule *cron.Schedule
+	action   action
+}
+
+type action interface {
+	start(ctx context.Context) error
+	run(ctx context.Context) error
+	close()
+}
+
+func NewService(ctx context.Context, logger log.ContextLogger, tag string, options option.SchedulerServiceOptions) (adapter.Service, error) {
+	if len(options.Tasks) == 0 {
+		return nil, E.New("missing tasks")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	scheduler := &Service{
+		Adapter: boxService.NewAdapter(C.TypeScheduler, tag),
+		ctx:     ctx,
+		cancel:  cancel,
+		logger:  logger,
+	}
+	for i, taskOptions := range options.Tasks {
+		schedulerTask, err := newTask(ctx, i, taskOptions)
+		if err != nil {
+			cancel()
+			return nil, E.Cause(err, "parse tasks[", i, "]")
+		}
+		scheduler.tasks = append(scheduler.tasks, schedulerTask)
+	}
+	return scheduler, nil
+}
+
+func newTask(ctx context.Context, index int, options option.SchedulerTaskOptions) (*task, error) {
+	if options.Schedule == "" {
+		return nil, E.New("missing schedule")
+	}
+	schedule, err := cron.Parse(options.Schedule)
+	if err != nil {
+		return nil, E.Cause(err, "parse schedule")
+	}
+	var taskAction action
+	switch options.Action {
+	case ActionSelect:
+		taskAction, err = newSelectAction(ctx, options)
+	case ActionUpdateSubscription:
+		taskAction, err = newUpdateSubscriptionAction(ctx, options)
+	case ActionUpdateRuleSet:
+		taskAction, err = newUpdateRuleSetAction(ctx, options)
+	case ActionRotateLog:
+		taskAction, err = newRotateLogAction(ctx, options)
+	case ActionHealthReport:
+		taskAction, err = newHealthReportAction(ctx, options)
+	case "":
+		return nil, E.New("missing action")
+	default:
+		return nil, E.New("unknown action: ", options.Action)
+	}
+	if err != nil {
+		return nil, err
+	}
+	name := options.Name
+	if name == "" {
+		name = F.ToString(options.Action, "[", index, "]")
+	}
+	return &task{
+		name:     name,
+		schedule: schedule,
+		action:   taskAction,
+	}, nil
+}
+
+func (s *Service) Start(stage adapter.StartStage) error {
+	switch stage {
+	case adapter.StartStateStart:
+		for _, schedulerTask := range s.tasks {
+			err := schedulerTask.action.start(s.ctx)
+			if err != nil {
+				return E.Cause(err, "start task ", schedulerTask.name)
+			}
+		}
+	case adapter.StartStatePostStart:
+		for _, schedulerTask := range s.tasks {
+			go s.loopTask(schedulerTask)
+		}
+	}
+	return nil
+}
+
+func (s *Service) Close() error {
+	s.cancel()
+	for _, schedulerTask := range s.tasks {
+		schedulerTask.action.close()
+	}
+	return nil
+}
+
+func (s *Service) loopTask(schedulerTask *task) {
+	for {
+		nextTime := schedulerTask.schedule.Next(time.Now())
+		if nextTime.IsZero() {
+			s.logger.Warn("schedule of task ", schedulerTask.name, " never matches, stopped")
+			return
+		}
+		timer := time.NewTimer(time.Until(nextTime))
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		s.logger.Debug("run task ", schedulerTask.name)
+		err := schedulerTask.action.run(s.ctx)
+		if err != nil {
+			if E.IsClosedOrCanceled(err) && s.ctx.Err() != nil {
+				return
+			}
+			s.logger.Error(E.Cause(err, "run task ", schedulerTask.name))
+		} else {
+			s.logger.Info("task ", schedulerTask.name, " finished")
+		}
+	}
+}
@@ -47,6 +47,7 @@ func NewService(ctx context.Context, logger log.ContextLogger, tag string, optio
 		listener: listener.New(listener.Options{
 			Context: ctx,
 			Logger:  logger,
+			Tag:     tag,
 			Network: []string{N.NetworkTCP},
 			Listen:  options.ListenOptions,
 		}),
@@ -0,0 +1,98 @@
+package subscription
+
+import (
+	"regexp"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+type groupFilter struct {
+	options option.SubscriptionGroupOptions
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+func newGroupFilter(options option.SubscriptionGroupOptions) (*groupFilter, error) {
+	if options.Tag == "" {
+		return nil, E.New("missing tag")
+	}
+	switch options.Type {
+	case C.TypeSelector, C.TypeURLTest:
+	case "":
+		return nil, E.New("missing type")
+	default:
+		return nil, E.New("unsupported group type: ", options.Type)
+	}
+	include, err := compileFilters(options.Include)
+	if err != nil {
+		return nil, E.Cause(err, "parse include")
+	}
+	exclude, err := compileFilters(options.Exclude)
+	if err != nil {
+		return nil, E.Cause(err, "parse exclude")
+	}
+	return &groupFilter{
+		options: options,
+		include: include,
+		exclude: exclude,
+	}, nil
+}
+
+// Build returns the outbound type and options of the group for the given subscription outbound tags.
+func (g *groupFilter) Build(outboundTags []string) (string, any) {
+	members := common.Filter(outboundTags, func(it string) bool {
+		return matchFilters(it, g.include, g.exclude)
+	})
+	members = append(members, g.options.Outbounds...)
+	if len(members) == 0 {
+		// keep the group usable by routes even if the filters matched nothing
+		members = append(members, outboundTags...)
+	}
+	switch g.options.Type {
+	case C.TypeURLTest:
+		return C.TypeURLTest, &option.URLTestOutboundOptions{
+			Outbounds:                 members,
+			URL:                       g.options.URL,
+			Interval:                  g.options.Interval,
+			Tolerance:                 g.options.Tolerance,
+			IdleTimeout:               g.options.IdleTimeout,
+			InterruptExistConnections: g.options.InterruptExistConnections,
+		}
+	default:
+		var defaultTag string
+		if common.Contains(members, g.options.Default) {
+			defaultTag = g.options.Default
+		}
+		return C.TypeSelector, &option.SelectorOutboundOptions{
+			Outbounds:                 members,
+			Default:                   defaultTag,
+			InterruptExistConnections: g.options.InterruptExistConnections,
+		}
+	}
+}
+
+func compileFilters(expressions []string) ([]*regexp.Regexp, error) {
+	filters := make([]*regexp.Regexp, 0, len(expressions))
+	for _, expression := range expressions {
+		filter, err := regexp.Compile(expression)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+	return filters, nil
+}
+
+func matchFilters(tag string, include []*regexp.Regexp, exclude []*regexp.Regexp) bool {
+	if len(include) > 0 && !common.Any(include, func(it *regexp.Regexp) bool {
+		return it.MatchString(tag)
+	}) {
+		return false
+	}
+	return !common.Any(exclude, func(it *regexp.Regexp) bool {
+		return it.MatchString(tag)
+	})
+}
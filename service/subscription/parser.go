@@ -0,0 +1,75 @@
+package subscription
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/convertor/clash"
+	"github.com/sagernet/sing-box/common/convertor/link"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+)
+
+type jsonSubscription struct {
+	Outbounds []option.Outbound `json:"outbounds"`
+}
+
+// ParseContent detects the subscription format (sing-box JSON, Clash YAML or a share link list)
+// and converts it into outbounds. Entries that fail to convert are returned as errors.
+func ParseContent(ctx context.Context, content []byte) ([]option.Outbound, []error) {
+	content = bytes.TrimSpace(content)
+	switch {
+	case len(content) == 0:
+		return nil, []error{E.New("empty subscription")}
+	case content[0] == '{':
+		var subscription jsonSubscription
+		err := json.UnmarshalContext(ctx, content, &subscription)
+		if err != nil {
+			return nil, []error{E.Cause(err, "decode sing-box subscription")}
+		}
+		return common.Filter(subscription.Outbounds, func(it option.Outbound) bool {
+			switch it.Type {
+			case C.TypeDirect, C.TypeBlock, C.TypeDNS, C.TypeSelector, C.TypeURLTest, C.TypeFallback:
+				return false
+			default:
+				return true
+			}
+		}), nil
+	case bytes.Contains(content, []byte("proxies:")):
+		return clash.ParseProxies(content)
+	default:
+		return link.ParseList(content)
+	}
+}
+
+// ParseInfo parses the subscription-userinfo header, e.g. `upload=1; download=2; total=3; expire=4`.
+func ParseInfo(header string) *adapter.SubscriptionInfo {
+	if header == "" {
+		return nil
+	}
+	var info adapter.SubscriptionInfo
+	for _, field := range strings.Split(header, ";") {
+		key, value, found := strings.Cut(strings.TrimSpace(field), "=")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "upload":
+			info.Upload, _ = strconv.ParseUint(value, 10, 64)
+		case "download":
+			info.Download, _ = strconv.ParseUint(value, 10, 64)
+		case "total":
+			info.Total, _ = strconv.ParseUint(value, 10, 64)
+		case "expire":
+			info.Expire, _ = strconv.ParseInt(value, 10, 64)
+		}
+	}
+	return &info
+}
@@ -0,0 +1,426 @@
+package subscription
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	boxService "github.com/sagernet/sing-box/adapter/service"
+	"github.com/sagernet/sing-box/common/eventbus"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/ntp"
+	"github.com/sagernet/sing/service"
+	"github.com/sagernet/sing/service/pause"
+)
+
+func RegisterService(registry *boxService.Registry) {
+	boxService.Register[option.SubscriptionServiceOptions](registry, C.TypeSubscription, NewService)
+}
+
+var _ adapter.Subscription = (*Service)(nil)
+
+type Service struct {
+	boxService.Adapter
+	ctx            context.Context
+	cancel         context.CancelFunc
+	logger         log.ContextLogger
+	logFactory     log.Factory
+	router         adapter.Router
+	outbound       adapter.OutboundManager
+	cacheFile      adapter.CacheFile
+	pauseManager   pause.Manager
+	powerManager   adapter.PowerManager
+	eventBus       adapter.EventBus
+	options        option.SubscriptionServiceOptions
+	updateInterval time.Duration
+	include        []*regexp.Regexp
+	exclude        []*regexp.Regexp
+	groups         []*groupFilter
+	dialer         N.Dialer
+	updateTicker   *time.Ticker
+	updateAccess   sync.Mutex
+	access         sync.RWMutex
+	outboundTags   []string
+	groupTags      []string
+	lastUpdated    time.Time
+	lastEtag       string
+	lastError      error
+	info           *adapter.SubscriptionInfo
+}
+
+func NewService(ctx context.Context, logger log.ContextLogger, tag string, options option.SubscriptionServiceOptions) (adapter.Service, error) {
+	if options.URL == "" {
+		return nil, E.New("missing url")
+	}
+	var updateInterval time.Duration
+	if options.UpdateInterval > 0 {
+		updateInterval = time.Duration(options.UpdateInterval)
+	} else {
+		updateInterval = 24 * time.Hour
+	}
+	include, err := compileFilters(options.Include)
+	if err != nil {
+		return nil, E.Cause(err, "parse include")
+	}
+	exclude, err := compileFilters(options.Exclude)
+	if err != nil {
+		return nil, E.Cause(err, "parse exclude")
+	}
+	groups := make([]*groupFilter, 0, len(options.Groups))
+	for i, groupOptions := range options.Groups {
+		group, err := newGroupFilter(groupOptions)
+		if err != nil {
+			return nil, E.Cause(err, "parse groups[", i, "]")
+		}
+		groups = append(groups, group)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	return &Service{
+		Adapter:        boxService.NewAdapter(C.TypeSubscription, tag),
+		ctx:            ctx,
+		cancel:         cancel,
+		logger:         logger,
+		logFactory:     service.FromContext[log.Factory](ctx),
+		router:         service.FromContext[adapter.Router](ctx),
+		outbound:       service.FromContext[adapter.OutboundManager](ctx),
+		pauseManager:   service.FromContext[pause.Manager](ctx),
+		powerManager:   service.FromContext[adapter.PowerManager](ctx),
+		eventBus:       service.FromContext[adapter.EventBus](ctx),
+		options:        options,
+		updateInterval: updateInterval,
+		include:        include,
+		exclude:        exclude,
+		groups:         groups,
+	}, nil
+}
+
+func (s *Service) Start(stage adapter.StartStage) error {
+	switch stage {
+	case adapter.StartStateStart:
+		if s.options.DownloadDetour != "" {
+			outbound, loaded := s.outbound.Outbound(s.options.DownloadDetour)
+			if !loaded {
+				return E.New("download detour not found: ", s.options.DownloadDetour)
+			}
+			s.dialer = outbound
+		} else {
+			s.dialer = s.outbound.Default()
+		}
+		s.cacheFile = service.FromContext[adapter.CacheFile](s.ctx)
+		if s.cacheFile != nil {
+			if savedSubscription := s.cacheFile.LoadSubscription(s.Tag()); savedSubscription != nil {
+				err := s.loadBytes(savedSubscription.Content)
+				if err != nil {
+					s.logger.Error(E.Cause(err, "restore cached subscription"))
+				} else {
+					s.lastUpdated = savedSubscription.LastUpdated
+					s.lastEtag = savedSubscription.LastEtag
+				}
+			}
+		}
+		if s.lastUpdated.IsZero() {
+			err := s.fetch(s.ctx)
+			if err != nil {
+				// start without outbounds, the update loop retries
+				s.logger.Warn(E.Cause(err, "initial subscription"))
+			}
+		}
+		s.updateTicker = time.NewTicker(s.updateInterval)
+	case adapter.StartStatePostStart:
+		go s.loopUpdate()
+	}
+	return nil
+}
+
+func (s *Service) Close() error {
+	s.cancel()
+	if s.updateTicker != nil {
+		s.updateTicker.Stop()
+	}
+	return nil
+}
+
+func (s *Service) Outbounds() []string {
+	s.access.RLock()
+	defer s.access.RUnlock()
+	return s.outboundTags
+}
+
+func (s *Service) Groups() []string {
+	s.access.RLock()
+	defer s.access.RUnlock()
+	return s.groupTags
+}
+
+func (s *Service) UpdatedAt() time.Time {
+	s.access.RLock()
+	defer s.access.RUnlock()
+	return s.lastUpdated
+}
+
+func (s *Service) LastError() error {
+	s.access.RLock()
+	defer s.access.RUnlock()
+	return s.lastError
+}
+
+func (s *Service) Info() *adapter.SubscriptionInfo {
+	s.access.RLock()
+	defer s.access.RUnlock()
+	return s.info
+}
+
+func (s *Service) Update(ctx context.Context) error {
+	if s.updateTicker != nil {
+		s.updateTicker.Reset(s.updateInterval)
+	}
+	return s.fetch(ctx)
+}
+
+func (s *Service) loopUpdate() {
+	if time.Since(s.UpdatedAt()) > s.updateInterval {
+		s.updateOnce()
+	}
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-s.updateTicker.C:
+			s.pauseManager.WaitActive()
+			if s.powerManager != nil {
+				s.powerManager.WaitWindow(s.ctx)
+			}
+			s.updateOnce()
+		}
+	}
+}
+
+func (s *Service) updateOnce() {
+	err := s.fetch(s.ctx)
+	if err != nil {
+		s.logger.Error("update subscription: ", err)
+	}
+}
+
+func (s *Service) fetch(ctx context.Context) error {
+	s.updateAccess.Lock()
+	defer s.updateAccess.Unlock()
+	task := eventbus.StartTask(s.eventBus, adapter.TaskSubscription, s.Tag())
+	notModified, err := s.fetch0(ctx, task)
+	if notModified {
+		task.FinishNotModified()
+	} else {
+		task.Finish(err)
+	}
+	s.access.Lock()
+	s.lastError = err
+	s.access.Unlock()
+	return err
+}
+
+func (s *Service) fetch0(ctx context.Context, task *eventbus.Task) (bool, error) {
+	s.logger.Debug("updating subscription from URL: ", s.options.URL)
+	httpClient := &http.Client{
+		Transport: &http.Transport{
+			ForceAttemptHTTP2:   true,
+			TLSHandshakeTimeout: C.TCPTimeout,
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return s.dialer.DialContext(ctx, network, M.ParseSocksaddr(addr))
+			},
+			TLSClientConfig: &tls.Config{
+				Time:    ntp.TimeFuncFromContext(s.ctx),
+				RootCAs: adapter.RootPoolFromContext(s.ctx),
+			},
+		},
+	}
+	defer httpClient.CloseIdleConnections()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, s.options.URL, nil)
+	if err != nil {
+		return false, err
+	}
+	if s.options.UserAgent != "" {
+		request.Header.Set("User-Agent", s.options.UserAgent)
+	} else {
+		request.Header.Set("User-Agent", "sing-box "+C.Version)
+	}
+	if s.lastEtag != "" {
+		request.Header.Set("If-None-Match", s.lastEtag)
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return false, err
+	}
+	defer response.Body.Close()
+	info := ParseInfo(response.Header.Get("subscription-userinfo"))
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		s.access.Lock()
+		s.lastUpdated = time.Now()
+		if info != nil {
+			s.info = info
+		}
+		s.access.Unlock()
+		s.saveCache(nil)
+		s.logger.Info("update subscription: not modified")
+		return true, nil
+	default:
+		return false, E.New("unexpected status: ", response.Status)
+	}
+	content, err := io.ReadAll(task.Reader(response.Body, response.ContentLength))
+	if err != nil {
+		return false, err
+	}
+	err = s.loadBytes(content)
+	if err != nil {
+		return false, err
+	}
+	s.access.Lock()
+	if eTagHeader := response.Header.Get("Etag"); eTagHeader != "" {
+		s.lastEtag = eTagHeader
+	}
+	s.lastUpdated = time.Now()
+	s.info = info
+	s.access.Unlock()
+	s.saveCache(content)
+	s.logger.Info("updated subscription: ", len(s.Outbounds()), " outbounds")
+	return false, nil
+}
+
+func (s *Service) saveCache(content []byte) {
+	if s.cacheFile == nil {
+		return
+	}
+	if content == nil {
+		savedSubscription := s.cacheFile.LoadSubscription(s.Tag())
+		if savedSubscription == nil {
+			return
+		}
+		content = savedSubscription.Content
+	}
+	s.access.RLock()
+	savedSubscription := &adapter.SavedBinary{
+		Content:     content,
+		LastUpdated: s.lastUpdated,
+		LastEtag:    s.lastEtag,
+	}
+	s.access.RUnlock()
+	err := s.cacheFile.SaveSubscription(s.Tag(), savedSubscription)
+	if err != nil {
+		s.logger.Error("save subscription cache: ", err)
+	}
+}
+
+func (s *Service) loadBytes(content []byte) error {
+	outbounds, errors := ParseContent(s.ctx, content)
+	for _, err := range errors {
+		s.logger.Warn("skip subscription entry: ", err)
+	}
+	outbounds = common.Filter(outbounds, func(it option.Outbound) bool {
+		return matchFilters(it.Tag, s.include, s.exclude)
+	})
+	if len(outbounds) == 0 {
+		return E.New("no outbounds available in subscription")
+	}
+	return s.apply(outbounds)
+}
+
+// apply replaces the outbounds and groups created by the previous update.
+// New outbounds and groups are created before stale ones are removed, so that
+// routes referencing group tags never observe a missing outbound.
+func (s *Service) apply(outbounds []option.Outbound) error {
+	s.access.RLock()
+	previousTags := s.outboundTags
+	s.access.RUnlock()
+	ownedTags := make(map[string]bool)
+	for _, tag := range previousTags {
+		ownedTags[tag] = true
+	}
+	for _, group := range s.groups {
+		ownedTags[group.options.Tag] = true
+	}
+	usedTags := make(map[string]bool)
+	outboundTags := make([]string, 0, len(outbounds))
+	for _, outboundOptions := range outbounds {
+		tag := s.options.TagPrefix + outboundOptions.Tag
+		for i := 2; usedTags[tag] || (!ownedTags[tag] && s.outboundExists(tag)); i++ {
+			tag = F.ToString(s.options.TagPrefix, outboundOptions.Tag, " (", i, ")")
+		}
+		err := s.outbound.Create(
+			adapter.WithContext(s.ctx, &adapter.InboundContext{
+				Outbound: tag,
+			}),
+			s.router,
+			s.newLogger(F.ToString("outbound/", outboundOptions.Type, "[", tag, "]")),
+			tag,
+			outboundOptions.Type,
+			outboundOptions.Options,
+		)
+		if err != nil {
+			s.logger.Warn("skip subscription entry: ", E.Cause(err, "initialize outbound[", tag, "]"))
+			continue
+		}
+		usedTags[tag] = true
+		outboundTags = append(outboundTags, tag)
+	}
+	if len(outboundTags) == 0 {
+		return E.New("no outbounds available in subscription")
+	}
+	groupTags := make([]string, 0, len(s.groups))
+	for _, group := range s.groups {
+		groupType, groupOptions := group.Build(outboundTags)
+		err := s.outbound.Create(
+			adapter.WithContext(s.ctx, &adapter.InboundContext{
+				Outbound: group.options.Tag,
+			}),
+			s.router,
+			s.newLogger(F.ToString("outbound/", groupType, "[", group.options.Tag, "]")),
+			group.options.Tag,
+			groupType,
+			groupOptions,
+		)
+		if err != nil {
+			return E.Cause(err, "initialize group[", group.options.Tag, "]")
+		}
+		groupTags = append(groupTags, group.options.Tag)
+	}
+	for _, tag := range previousTags {
+		if usedTags[tag] {
+			continue
+		}
+		err := s.outbound.Remove(tag)
+		if err != nil {
+			s.logger.Warn("remove stale outbound[", tag, "]: ", err)
+		}
+	}
+	s.access.Lock()
+	s.outboundTags = outboundTags
+	s.groupTags = groupTags
+	s.access.Unlock()
+	return nil
+}
+
+func (s *Service) outboundExists(tag string) bool {
+	_, loaded := s.outbound.Outbound(tag)
+	return loaded
+}
+
+func (s *Service) newLogger(tag string) log.ContextLogger {
+	if s.logFactory == nil {
+		return s.logger
+	}
+	return s.logFactory.NewLogger(tag)
+}
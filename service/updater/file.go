@@ -0,0 +1,167 @@
+package updater
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/eventbus"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/service/filemanager"
+)
+
+type file struct {
+	service      *Service
+	options      option.UpdaterFileOptions
+	path         string
+	schedule     schedule
+	verifier     *verifier
+	updateAccess sync.Mutex
+}
+
+func newFile(ctx context.Context, service *Service, options option.UpdaterFileOptions, defaultSchedule schedule) (*file, error) {
+	if options.URL == "" {
+		return nil, E.New("missing url")
+	}
+	if options.Path == "" {
+		return nil, E.New("missing path")
+	}
+	updateFile := &file{
+		service:  service,
+		options:  options,
+		path:     filemanager.BasePath(ctx, os.ExpandEnv(options.Path)),
+		schedule: defaultSchedule,
+	}
+	if options.Schedule != "" {
+		fileSchedule, err := parseSchedule(options.Schedule, 0)
+		if err != nil {
+			return nil, err
+		}
+		updateFile.schedule = fileSchedule
+	}
+	updateVerifier, err := newVerifier(service, options.UpdaterVerifyOptions)
+	if err != nil {
+		return nil, err
+	}
+	updateFile.verifier = updateVerifier
+	return updateFile, nil
+}
+
+// stale reports whether the file is missing or older than the update interval.
+func (f *file) stale() bool {
+	fileInfo, err := os.Stat(f.path)
+	if err != nil {
+		return true
+	}
+	return f.schedule.cron == nil && time.Since(fileInfo.ModTime()) > f.schedule.interval
+}
+
+func (f *file) update() {
+	err := f.fetch(f.service.ctx)
+	if err != nil {
+		f.service.logger.Error("update ", f.path, ": ", err)
+	}
+}
+
+func (f *file) fetch(ctx context.Context) error {
+	f.updateAccess.Lock()
+	defer f.updateAccess.Unlock()
+	task := eventbus.StartTask(f.service.eventBus, adapter.TaskFile, f.path)
+	notModified, err := f.fetch0(ctx, task)
+	if notModified {
+		task.FinishNotModified()
+	} else {
+		task.Finish(err)
+	}
+	return err
+}
+
+func (f *file) fetch0(ctx context.Context, task *eventbus.Task) (bool, error) {
+	f.service.logger.Debug("updating ", f.path, " from URL: ", f.options.URL)
+	request, err := f.service.newRequest(ctx, f.options.URL)
+	if err != nil {
+		return false, err
+	}
+	fileInfo, statErr := os.Stat(f.path)
+	if statErr == nil {
+		request.Header.Set("If-Modified-Since", fileInfo.ModTime().UTC().Format(http.TimeFormat))
+	}
+	response, err := f.service.httpClient.Do(request)
+	if err != nil {
+		return false, err
+	}
+	defer response.Body.Close()
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		f.service.logger.Info("update ", f.path, ": not modified")
+		return true, nil
+	default:
+		return false, E.New("unexpected status: ", response.Status)
+	}
+	content, err := io.ReadAll(task.Reader(response.Body, response.ContentLength))
+	if err != nil {
+		return false, err
+	}
+	err = f.verifier.verify(ctx, f.options.URL, content)
+	if err != nil {
+		return false, E.Cause(err, "verify")
+	}
+	if statErr == nil {
+		currentContent, readErr := os.ReadFile(f.path)
+		if readErr == nil && bytes.Equal(currentContent, content) {
+			f.service.logger.Info("update ", f.path, ": not modified")
+			return true, os.Chtimes(f.path, time.Now(), time.Now())
+		}
+	}
+	err = writeFileAtomic(ctx, f.path, content)
+	if err != nil {
+		return false, err
+	}
+	f.service.logger.Info("updated ", f.path)
+	return false, nil
+}
+
+// writeFileAtomic replaces path by renaming a synced temporary file in the same directory,
+// so that watchers such as local rule-sets never observe a partially written file.
+func writeFileAtomic(ctx context.Context, path string, content []byte) error {
+	directory := filepath.Dir(path)
+	err := filemanager.MkdirAll(ctx, directory, 0o755)
+	if err != nil {
+		return err
+	}
+	tempFile, err := os.CreateTemp(directory, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tempPath := tempFile.Name()
+	_, err = tempFile.Write(content)
+	if err == nil {
+		err = tempFile.Sync()
+	}
+	closeErr := tempFile.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tempPath, 0o644)
+	}
+	if err == nil {
+		err = filemanager.Chown(ctx, tempPath)
+	}
+	if err == nil {
+		err = os.Rename(tempPath, path)
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	return nil
+}
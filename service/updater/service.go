@@ -0,0 +1,278 @@
+package updater
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	boxService "github.com/sagernet/sing-box/adapter/service"
+	"github.com/sagernet/sing-box/common/cron"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/ntp"
+	"github.com/sagernet/sing/service"
+	"github.com/sagernet/sing/service/pause"
+)
+
+func RegisterService(registry *boxService.Registry) {
+	boxService.Register[option.UpdaterServiceOptions](registry, C.TypeUpdater, NewService)
+}
+
+type Service struct {
+	boxService.Adapter
+	ctx          context.Context
+	cancel       context.CancelFunc
+	logger       log.ContextLogger
+	router       adapter.Router
+	outbound     adapter.OutboundManager
+	pauseManager pause.Manager
+	powerManager adapter.PowerManager
+	eventBus     adapter.EventBus
+	options      option.UpdaterServiceOptions
+	schedule     schedule
+	files        []*file
+	ruleSets     []*ruleSet
+	httpClient   *http.Client
+}
+
+type ruleSet struct {
+	tag      string
+	verifier *verifier
+	ruleSet  adapter.ManagedRuleSet
+}
+
+// remoteRuleSetRetryInterval is the retry interval of rule-sets started without downloaded content.
+const remoteRuleSetRetryInterval = time.Minute
+
+// schedule is either a cron expression or a fixed interval.
+type schedule struct {
+	cron     *cron.Schedule
+	interval time.Duration
+}
+
+func (s schedule) next(now time.Time) time.Time {
+	if s.cron != nil {
+		return s.cron.Next(now)
+	}
+	return now.Add(s.interval)
+}
+
+func NewService(ctx context.Context, logger log.ContextLogger, tag string, options option.UpdaterServiceOptions) (adapter.Service, error) {
+	if len(options.Files) == 0 && len(options.RuleSet) == 0 {
+		return nil, E.New("missing files or rule_set")
+	}
+	defaultSchedule, err := parseSchedule(options.Schedule, time.Duration(options.UpdateInterval))
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	updater := &Service{
+		Adapter:      boxService.NewAdapter(C.TypeUpdater, tag),
+		ctx:          ctx,
+		cancel:       cancel,
+		logger:       logger,
+		router:       service.FromContext[adapter.Router](ctx),
+		outbound:     service.FromContext[adapter.OutboundManager](ctx),
+		pauseManager: service.FromContext[pause.Manager](ctx),
+		powerManager: service.FromContext[adapter.PowerManager](ctx),
+		eventBus:     service.FromContext[adapter.EventBus](ctx),
+		options:      options,
+		schedule:     defaultSchedule,
+	}
+	for i, fileOptions := range options.Files {
+		updateFile, err := newFile(ctx, updater, fileOptions, defaultSchedule)
+		if err != nil {
+			cancel()
+			return nil, E.Cause(err, "parse files[", i, "]")
+		}
+		updater.files = append(updater.files, updateFile)
+	}
+	for i, ruleSetOptions := range options.RuleSet {
+		if ruleSetOptions.Tag == "" {
+			cancel()
+			return nil, E.New("parse rule_set[", i, "]: missing tag")
+		}
+		updateVerifier, err := newVerifier(updater, ruleSetOptions.UpdaterVerifyOptions)
+		if err != nil {
+			cancel()
+			return nil, E.Cause(err, "parse rule_set[", i, "]")
+		}
+		updater.ruleSets = append(updater.ruleSets, &ruleSet{
+			tag:      ruleSetOptions.Tag,
+			verifier: updateVerifier,
+		})
+	}
+	return updater, nil
+}
+
+func parseSchedule(expression string, interval time.Duration) (schedule, error) {
+	if expression != "" {
+		cronSchedule, err := cron.Parse(expression)
+		if err != nil {
+			return schedule{}, E.Cause(err, "parse schedule")
+		}
+		return schedule{cron: cronSchedule}, nil
+	}
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+	return schedule{interval: interval}, nil
+}
+
+func (s *Service) Start(stage adapter.StartStage) error {
+	switch stage {
+	case adapter.StartStateInitialize:
+		// rule-sets download their initial content when the router starts,
+		// so that verification and scheduling are taken over before it.
+		if s.options.DownloadDetour != "" {
+			_, loaded := s.outbound.Outbound(s.options.DownloadDetour)
+			if !loaded {
+				return E.New("download detour not found: ", s.options.DownloadDetour)
+			}
+		}
+		s.httpClient = &http.Client{
+			Transport: &http.Transport{
+				ForceAttemptHTTP2:   true,
+				TLSHandshakeTimeout: C.TCPTimeout,
+				DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+					return s.dialer().DialContext(ctx, network, M.ParseSocksaddr(addr))
+				},
+				TLSClientConfig: &tls.Config{
+					Time:    ntp.TimeFuncFromContext(s.ctx),
+					RootCAs: adapter.RootPoolFromContext(s.ctx),
+				},
+			},
+		}
+		for _, updateRuleSet := range s.ruleSets {
+			routerRuleSet, loaded := s.router.RuleSet(updateRuleSet.tag)
+			if !loaded {
+				return E.New("rule-set not found: ", updateRuleSet.tag)
+			}
+			managedRuleSet, isManaged := routerRuleSet.(adapter.ManagedRuleSet)
+			if !isManaged || routerRuleSet.Type() != C.RuleSetTypeRemote {
+				return E.New("rule-set ", updateRuleSet.tag, " is not a remote rule-set")
+			}
+			managedRuleSet.SetManaged(updateRuleSet.verifier.verify)
+			updateRuleSet.ruleSet = managedRuleSet
+		}
+	case adapter.StartStatePostStart:
+		for _, updateFile := range s.files {
+			go func(updateFile *file) {
+				if updateFile.stale() {
+					updateFile.update()
+				}
+				s.loopUpdate(updateFile.schedule, updateFile.update)
+			}(updateFile)
+		}
+		if len(s.ruleSets) > 0 {
+			go s.loopRuleSets()
+		}
+	}
+	return nil
+}
+
+func (s *Service) dialer() N.Dialer {
+	if s.options.DownloadDetour != "" {
+		outbound, loaded := s.outbound.Outbound(s.options.DownloadDetour)
+		if loaded {
+			return outbound
+		}
+	}
+	return s.outbound.Default()
+}
+
+func (s *Service) Close() error {
+	s.cancel()
+	if s.httpClient != nil {
+		s.httpClient.CloseIdleConnections()
+	}
+	return nil
+}
+
+func (s *Service) loopUpdate(updateSchedule schedule, update func()) {
+	for {
+		nextTime := updateSchedule.next(time.Now())
+		if nextTime.IsZero() {
+			s.logger.Warn("schedule never matches, stopped")
+			return
+		}
+		timer := time.NewTimer(time.Until(nextTime))
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		s.pauseManager.WaitActive()
+		if s.powerManager != nil {
+			s.powerManager.WaitWindow(s.ctx)
+		}
+		update()
+	}
+}
+
+func (s *Service) loopRuleSets() {
+	for _, updateRuleSet := range s.ruleSets {
+		if updateRuleSet.stale(s.schedule) {
+			updateRuleSet.update(s)
+		}
+	}
+	// retry rule-sets started with embedded or empty content more frequently
+	retryInterval := remoteRuleSetRetryInterval
+	if s.schedule.cron == nil {
+		retryInterval = min(s.schedule.interval, retryInterval)
+	}
+	for common.Any(s.ruleSets, func(it *ruleSet) bool { return it.ruleSet.UpdatedAt().IsZero() }) {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(retryInterval):
+		}
+		for _, updateRuleSet := range s.ruleSets {
+			if updateRuleSet.ruleSet.UpdatedAt().IsZero() {
+				updateRuleSet.update(s)
+			}
+		}
+	}
+	s.loopUpdate(s.schedule, s.updateRuleSets)
+}
+
+func (s *Service) updateRuleSets() {
+	for _, updateRuleSet := range s.ruleSets {
+		updateRuleSet.update(s)
+	}
+}
+
+// stale reports whether the rule-set was never downloaded or is older than the update interval.
+func (r *ruleSet) stale(updateSchedule schedule) bool {
+	updatedAt := r.ruleSet.UpdatedAt()
+	return updatedAt.IsZero() || updateSchedule.cron == nil && time.Since(updatedAt) > updateSchedule.interval
+}
+
+func (r *ruleSet) update(s *Service) {
+	err := r.ruleSet.Update(s.ctx)
+	if err != nil {
+		s.logger.Error(E.Cause(err, "update rule-set ", r.tag))
+	}
+}
+
+func (s *Service) newRequest(ctx context.Context, url string) (*http.Request, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.options.UserAgent != "" {
+		request.Header.Set("User-Agent", s.options.UserAgent)
+	} else {
+		request.Header.Set("User-Agent", "sing-box "+C.Version)
+	}
+	return request, nil
+}
@@ -0,0 +1,132 @@
+package updater
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// verifier checks downloaded files and rule-sets against a pinned digest,
+// a published checksum and an Ed25519 signature.
+type verifier struct {
+	service   *Service
+	options   option.UpdaterVerifyOptions
+	checksum  []byte
+	publicKey ed25519.PublicKey
+}
+
+func newVerifier(service *Service, options option.UpdaterVerifyOptions) (*verifier, error) {
+	updateVerifier := &verifier{
+		service: service,
+		options: options,
+	}
+	if options.SHA256 != "" {
+		checksum, err := hex.DecodeString(options.SHA256)
+		if err != nil || len(checksum) != sha256.Size {
+			return nil, E.New("invalid sha256: ", options.SHA256)
+		}
+		updateVerifier.checksum = checksum
+	}
+	if options.PublicKey != "" || options.SignatureURL != "" {
+		if options.PublicKey == "" {
+			return nil, E.New("missing public_key")
+		}
+		if options.SignatureURL == "" {
+			return nil, E.New("missing signature_url")
+		}
+		publicKey, err := base64.StdEncoding.DecodeString(options.PublicKey)
+		if err != nil || len(publicKey) != ed25519.PublicKeySize {
+			return nil, E.New("invalid ed25519 public key")
+		}
+		updateVerifier.publicKey = publicKey
+	}
+	return updateVerifier, nil
+}
+
+// verify checks content downloaded from url.
+func (v *verifier) verify(ctx context.Context, url string, content []byte) error {
+	checksum := sha256.Sum256(content)
+	if v.checksum != nil && !bytes.Equal(checksum[:], v.checksum) {
+		return E.New("sha256 mismatch")
+	}
+	if v.options.ChecksumURL != "" {
+		checksumContent, err := v.download(ctx, v.options.ChecksumURL)
+		if err != nil {
+			return E.Cause(err, "download checksum")
+		}
+		expected, err := parseChecksum(checksumContent, filepath.Base(url))
+		if err != nil {
+			return E.Cause(err, "parse checksum")
+		}
+		if !bytes.Equal(checksum[:], expected) {
+			return E.New("sha256 mismatch")
+		}
+	}
+	if v.publicKey != nil {
+		signature, err := v.download(ctx, v.options.SignatureURL)
+		if err != nil {
+			return E.Cause(err, "download signature")
+		}
+		if len(signature) != ed25519.SignatureSize {
+			signature, err = base64.StdEncoding.DecodeString(string(bytes.TrimSpace(signature)))
+			if err != nil {
+				return E.Cause(err, "decode signature")
+			}
+		}
+		if !ed25519.Verify(v.publicKey, content, signature) {
+			return E.New("invalid signature")
+		}
+	}
+	return nil
+}
+
+func (v *verifier) download(ctx context.Context, url string) ([]byte, error) {
+	request, err := v.service.newRequest(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	response, err := v.service.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, E.New("unexpected status: ", response.Status)
+	}
+	return io.ReadAll(response.Body)
+}
+
+// parseChecksum reads a bare hex digest or `sha256sum` output, preferring the line naming fileName.
+func parseChecksum(content []byte, fileName string) ([]byte, error) {
+	var firstDigest string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if firstDigest == "" {
+			firstDigest = fields[0]
+		}
+		if len(fields) > 1 && strings.TrimPrefix(fields[1], "*") == fileName {
+			firstDigest = fields[0]
+			break
+		}
+	}
+	checksum, err := hex.DecodeString(firstDigest)
+	if err != nil || len(checksum) != sha256.Size {
+		return nil, E.New("invalid sha256: ", firstDigest)
+	}
+	return checksum, nil
+}
@@ -0,0 +1,75 @@
+package updater
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifier(t *testing.T) {
+	t.Parallel()
+	content := []byte("rule-set content")
+	checksum := sha256.Sum256(content)
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	signature := ed25519.Sign(privateKey, content)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/SHA256SUMS":
+			w.Write([]byte("0000000000000000000000000000000000000000000000000000000000000000  other.srs\n" + hex.EncodeToString(checksum[:]) + "  geosite-cn.srs\n"))
+		case "/geosite-cn.srs.sig":
+			w.Write([]byte(base64.StdEncoding.EncodeToString(signature)))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	service := &Service{httpClient: server.Client()}
+	contentURL := server.URL + "/geosite-cn.srs"
+	for _, testCase := range []struct {
+		name    string
+		options option.UpdaterVerifyOptions
+		content []byte
+		valid   bool
+	}{
+		{"none", option.UpdaterVerifyOptions{}, []byte("any"), true},
+		{"sha256", option.UpdaterVerifyOptions{SHA256: hex.EncodeToString(checksum[:])}, content, true},
+		{"sha256 mismatch", option.UpdaterVerifyOptions{SHA256: hex.EncodeToString(checksum[:])}, []byte("other"), false},
+		{"checksum url", option.UpdaterVerifyOptions{ChecksumURL: server.URL + "/SHA256SUMS"}, content, true},
+		{"checksum url mismatch", option.UpdaterVerifyOptions{ChecksumURL: server.URL + "/SHA256SUMS"}, []byte("other"), false},
+		{"signature", option.UpdaterVerifyOptions{SignatureURL: contentURL + ".sig", PublicKey: base64.StdEncoding.EncodeToString(publicKey)}, content, true},
+		{"signature mismatch", option.UpdaterVerifyOptions{SignatureURL: contentURL + ".sig", PublicKey: base64.StdEncoding.EncodeToString(publicKey)}, []byte("other"), false},
+		{"signature missing", option.UpdaterVerifyOptions{SignatureURL: server.URL + "/missing.sig", PublicKey: base64.StdEncoding.EncodeToString(publicKey)}, content, false},
+	} {
+		updateVerifier, err := newVerifier(service, testCase.options)
+		require.NoError(t, err, testCase.name)
+		err = updateVerifier.verify(context.Background(), contentURL, testCase.content)
+		if testCase.valid {
+			require.NoError(t, err, testCase.name)
+		} else {
+			require.Error(t, err, testCase.name)
+		}
+	}
+}
+
+func TestVerifierOptions(t *testing.T) {
+	t.Parallel()
+	for _, options := range []option.UpdaterVerifyOptions{
+		{SHA256: "00"},
+		{SignatureURL: "https://example.org/file.sig"},
+		{PublicKey: base64.StdEncoding.EncodeToString(make([]byte, ed25519.PublicKeySize))},
+		{SignatureURL: "https://example.org/file.sig", PublicKey: "invalid"},
+	} {
+		_, err := newVerifier(&Service{}, options)
+		require.Error(t, err, options)
+	}
+}
@@ -0,0 +1,311 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	boxService "github.com/sagernet/sing-box/adapter/service"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/sing/common/json/badoption"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/ntp"
+	"github.com/sagernet/sing/service"
+)
+
+const (
+	EventOutboundDown = "outbound_down"
+	EventOutboundUp   = "outbound_up"
+)
+
+var defaultEvents = []string{
+	EventOutboundDown,
+	EventOutboundUp,
+	adapter.EventCertificateRenewed,
+	adapter.EventCertificateExpiring,
+	adapter.EventQuotaExceeded,
+	adapter.EventBanApplied,
+	adapter.EventReloadFailed,
+}
+
+func RegisterService(registry *boxService.Registry) {
+	boxService.Register[option.WebhookServiceOptions](registry, C.TypeWebhook, NewService)
+}
+
+type Service struct {
+	boxService.Adapter
+	ctx         context.Context
+	cancel      context.CancelFunc
+	logger      log.ContextLogger
+	outbound    adapter.OutboundManager
+	eventBus    adapter.EventBus
+	options     option.WebhookServiceOptions
+	events      []string
+	template    *template.Template
+	httpClient  *http.Client
+	unsubscribe func()
+	// healthy is the last known state of outbounds in groups, only
+	// accessed by the loop.
+	healthy map[healthKey]bool
+}
+
+type healthKey struct {
+	group    string
+	outbound string
+}
+
+// notification is the template data and the default body of requests,
+// Type is an event type of the webhook rather than of the event bus.
+type notification struct {
+	adapter.Event
+	Message string `json:"message"`
+}
+
+func NewService(ctx context.Context, logger log.ContextLogger, tag string, options option.WebhookServiceOptions) (adapter.Service, error) {
+	if options.URL == "" {
+		return nil, E.New("missing url")
+	}
+	webhookURL, err := url.Parse(options.URL)
+	if err != nil {
+		return nil, E.Cause(err, "parse url")
+	}
+	if webhookURL.Scheme != "http" && webhookURL.Scheme != "https" {
+		return nil, E.New("unsupported url scheme: ", webhookURL.Scheme)
+	}
+	events := []string(options.Events)
+	if len(events) == 0 {
+		events = defaultEvents
+	}
+	for _, eventType := range events {
+		if !common.Contains(defaultEvents, eventType) {
+			return nil, E.New("unknown event: ", eventType)
+		}
+	}
+	var bodyTemplate *template.Template
+	if options.Template != "" {
+		bodyTemplate, err = template.New("webhook").Funcs(template.FuncMap{
+			"json": func(value any) (string, error) {
+				content, err := json.Marshal(value)
+				return string(content), err
+			},
+		}).Parse(options.Template)
+		if err != nil {
+			return nil, E.Cause(err, "parse template")
+		}
+	}
+	if options.MaxRetries == 0 {
+		options.MaxRetries = 3
+	}
+	if options.RetryInterval == 0 {
+		options.RetryInterval = badoption.Duration(10 * time.Second)
+	}
+	if options.Timeout == 0 {
+		options.Timeout = badoption.Duration(C.TCPTimeout)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	return &Service{
+		Adapter:  boxService.NewAdapter(C.TypeWebhook, tag),
+		ctx:      ctx,
+		cancel:   cancel,
+		logger:   logger,
+		outbound: service.FromContext[adapter.OutboundManager](ctx),
+		eventBus: service.FromContext[adapter.EventBus](ctx),
+		options:  options,
+		events:   events,
+		template: bodyTemplate,
+		healthy:  make(map[healthKey]bool),
+	}, nil
+}
+
+func (s *Service) Start(stage adapter.StartStage) error {
+	if stage != adapter.StartStateStart {
+		return nil
+	}
+	var dialer N.Dialer
+	if s.options.Detour != "" {
+		outbound, loaded := s.outbound.Outbound(s.options.Detour)
+		if !loaded {
+			return E.New("detour outbound not found: ", s.options.Detour)
+		}
+		dialer = outbound
+	} else {
+		dialer = s.outbound.Default()
+	}
+	s.httpClient = &http.Client{
+		Transport: &http.Transport{
+			ForceAttemptHTTP2:   true,
+			TLSHandshakeTimeout: C.TCPTimeout,
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, M.ParseSocksaddr(addr))
+			},
+			TLSClientConfig: &tls.Config{
+				Time:    ntp.TimeFuncFromContext(s.ctx),
+				RootCAs: adapter.RootPoolFromContext(s.ctx),
+			},
+		},
+		Timeout: time.Duration(s.options.Timeout),
+	}
+	eventTypes := make([]string, 0, len(s.events))
+	for _, eventType := range s.events {
+		if eventType == EventOutboundDown || eventType == EventOutboundUp {
+			eventType = adapter.EventOutboundHealth
+		}
+		if !common.Contains(eventTypes, eventType) {
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+	events, unsubscribe := s.eventBus.Subscribe(0, eventTypes...)
+	s.unsubscribe = unsubscribe
+	go s.loop(events)
+	return nil
+}
+
+func (s *Service) Close() error {
+	s.cancel()
+	if s.unsubscribe != nil {
+		s.unsubscribe()
+	}
+	if s.httpClient != nil {
+		s.httpClient.CloseIdleConnections()
+	}
+	return nil
+}
+
+// loop sends notifications in order, retries delay the following ones.
+func (s *Service) loop(events <-chan adapter.Event) {
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case event, loaded := <-events:
+			if !loaded {
+				return
+			}
+			notification, send := s.newNotification(event)
+			if send {
+				s.send(notification)
+			}
+		}
+	}
+}
+
+func (s *Service) newNotification(event adapter.Event) (notification, bool) {
+	it := notification{Event: event}
+	switch event.Type {
+	case adapter.EventOutboundHealth:
+		key := healthKey{event.Group, event.Outbound}
+		healthy := event.State == adapter.OutboundStateHealthy
+		previous, loaded := s.healthy[key]
+		s.healthy[key] = healthy
+		// the first test of a healthy outbound is not a recovery
+		if healthy && (!loaded || previous) || !healthy && loaded && !previous {
+			return it, false
+		}
+		if healthy {
+			it.Type = EventOutboundUp
+			it.Message = F.ToString("outbound ", event.Outbound, " in group ", event.Group, " is up")
+		} else {
+			it.Type = EventOutboundDown
+			it.Message = F.ToString("outbound ", event.Outbound, " in group ", event.Group, " is down: ", event.Error)
+		}
+	case adapter.EventCertificateRenewed:
+		it.Message = F.ToString("certificate for ", event.Domain, " renewed")
+	case adapter.EventCertificateExpiring:
+		it.Message = F.ToString("certificate for ", event.Domain, " expires at ", event.ExpireAt.Format(time.RFC3339))
+	case adapter.EventQuotaExceeded:
+		name := event.User
+		if event.Package != "" {
+			name = event.Package
+		}
+		it.Message = F.ToString(name, " exceeded quota: ", event.Used, " of ", event.Limit, " bytes used")
+	case adapter.EventBanApplied:
+		it.Message = F.ToString("source ", event.Source, " banned")
+		if event.Inbound != "" {
+			it.Message += " on inbound " + event.Inbound
+		}
+		if event.Error != "" {
+			it.Message += ": " + event.Error
+		}
+	case adapter.EventReloadFailed:
+		it.Message = "reload failed: " + event.Error
+	default:
+		return it, false
+	}
+	return it, common.Contains(s.events, it.Type)
+}
+
+func (s *Service) send(notification notification) {
+	body, err := s.render(notification)
+	if err != nil {
+		s.logger.Error(E.Cause(err, "render ", notification.Type, " notification"))
+		return
+	}
+	for attempt := uint32(0); ; attempt++ {
+		err = s.post(body)
+		if err == nil {
+			s.logger.Debug("sent ", notification.Type, " notification")
+			return
+		}
+		if attempt >= s.options.MaxRetries {
+			s.logger.Error(E.Cause(err, "send ", notification.Type, " notification"))
+			return
+		}
+		s.logger.Warn(E.Cause(err, "send ", notification.Type, " notification, retrying"))
+		timer := time.NewTimer(time.Duration(s.options.RetryInterval))
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+func (s *Service) render(notification notification) ([]byte, error) {
+	if s.template == nil {
+		return json.Marshal(notification)
+	}
+	var buffer bytes.Buffer
+	err := s.template.Execute(&buffer, notification)
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func (s *Service) post(body []byte) error {
+	request, err := http.NewRequestWithContext(s.ctx, http.MethodPost, s.options.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "sing-box "+C.Version)
+	for name, values := range s.options.Headers.Build() {
+		request.Header[name] = values
+	}
+	response, err := s.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 4096))
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return E.New("unexpected status: ", strings.TrimSpace(response.Status))
+	}
+	return nil
+}
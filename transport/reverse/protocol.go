@@ -0,0 +1,170 @@
+package reverse
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"io"
+	"net"
+
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/buf"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	"github.com/sagernet/smux"
+)
+
+// A bridge and the portal authenticate each other on the control connection
+// by challenge-response, keyed with SHA256(PASSWORD):
+//
+//	bridge: VERSION(1) | BRIDGE_NONCE(32)
+//	portal: PORTAL_NONCE(32) | HMAC-SHA256(KEY, "portal" | BRIDGE_NONCE | PORTAL_NONCE)
+//	bridge: HMAC-SHA256(KEY, "bridge" | PORTAL_NONCE | BRIDGE_NONCE)
+//	portal: STATUS(1)
+//
+// The connection is then multiplexed with smux, the portal opens a stream for
+// each proxied connection with
+//
+//	COMMAND(1) | DESTINATION(SOCKS address)
+//
+// followed by the payload, or UDP over TCP packets for CommandUDP.
+const (
+	Version    = 1
+	CommandTCP = 1
+	CommandUDP = 2
+)
+
+const (
+	statusOK         = 0
+	statusAuthFailed = 1
+	keyLength        = sha256.Size
+	nonceLength      = 32
+	macLength        = sha256.Size
+)
+
+var (
+	ErrAuthFailed       = E.New("authentication failed")
+	ErrPortalAuthFailed = E.New("portal authentication failed")
+)
+
+type Key [keyLength]byte
+
+func NewKey(password string) Key {
+	return sha256.Sum256([]byte(password))
+}
+
+func (k Key) mac(label string, nonces ...[]byte) []byte {
+	hash := hmac.New(sha256.New, k[:])
+	hash.Write([]byte(label))
+	for _, nonce := range nonces {
+		hash.Write(nonce)
+	}
+	return hash.Sum(nil)
+}
+
+func ClientHandshake(conn net.Conn, key Key) error {
+	request := buf.NewSize(1 + nonceLength)
+	defer request.Release()
+	common.Must(request.WriteByte(Version))
+	bridgeNonce := request.Extend(nonceLength)
+	_, err := rand.Read(bridgeNonce)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Write(request.Bytes())
+	if err != nil {
+		return E.Cause(err, "write handshake request")
+	}
+	var challenge [nonceLength + macLength]byte
+	_, err = io.ReadFull(conn, challenge[:])
+	if err != nil {
+		return E.Cause(err, "read handshake challenge")
+	}
+	portalNonce := challenge[:nonceLength]
+	if !hmac.Equal(challenge[nonceLength:], key.mac("portal", bridgeNonce, portalNonce)) {
+		return ErrPortalAuthFailed
+	}
+	_, err = conn.Write(key.mac("bridge", portalNonce, bridgeNonce))
+	if err != nil {
+		return E.Cause(err, "write handshake response")
+	}
+	var status [1]byte
+	_, err = io.ReadFull(conn, status[:])
+	if err != nil {
+		return E.Cause(err, "read handshake status")
+	}
+	switch status[0] {
+	case statusOK:
+		return nil
+	case statusAuthFailed:
+		return ErrAuthFailed
+	default:
+		return E.New("unknown handshake status: ", status[0])
+	}
+}
+
+func ServerHandshake(conn net.Conn, key Key) error {
+	var request [1 + nonceLength]byte
+	_, err := io.ReadFull(conn, request[:])
+	if err != nil {
+		return E.Cause(err, "read handshake request")
+	}
+	if request[0] != Version {
+		return E.New("unknown version: ", request[0])
+	}
+	bridgeNonce := request[1:]
+	challenge := buf.NewSize(nonceLength + macLength)
+	defer challenge.Release()
+	portalNonce := challenge.Extend(nonceLength)
+	_, err = rand.Read(portalNonce)
+	if err != nil {
+		return err
+	}
+	common.Must1(challenge.Write(key.mac("portal", bridgeNonce, portalNonce)))
+	_, err = conn.Write(challenge.Bytes())
+	if err != nil {
+		return E.Cause(err, "write handshake challenge")
+	}
+	var response [macLength]byte
+	_, err = io.ReadFull(conn, response[:])
+	if err != nil {
+		return E.Cause(err, "read handshake response")
+	}
+	if !hmac.Equal(response[:], key.mac("bridge", portalNonce, bridgeNonce)) {
+		_, _ = conn.Write([]byte{statusAuthFailed})
+		return ErrAuthFailed
+	}
+	_, err = conn.Write([]byte{statusOK})
+	if err != nil {
+		return E.Cause(err, "write handshake status")
+	}
+	return nil
+}
+
+func WriteStreamRequest(conn net.Conn, command byte, destination M.Socksaddr) error {
+	request := buf.NewSize(1 + M.SocksaddrSerializer.AddrPortLen(destination))
+	defer request.Release()
+	common.Must(request.WriteByte(command))
+	err := M.SocksaddrSerializer.WriteAddrPort(request, destination)
+	if err != nil {
+		return err
+	}
+	return common.Error(conn.Write(request.Bytes()))
+}
+
+func ReadStreamRequest(reader io.Reader) (command byte, destination M.Socksaddr, err error) {
+	var commandBytes [1]byte
+	_, err = io.ReadFull(reader, commandBytes[:])
+	if err != nil {
+		return
+	}
+	command = commandBytes[0]
+	destination, err = M.SocksaddrSerializer.ReadAddrPort(reader)
+	return
+}
+
+// SmuxConfig keeps the keep-alive of smux enabled, so that dead control
+// connections are detected by both sides.
+func SmuxConfig() *smux.Config {
+	return smux.DefaultConfig()
+}
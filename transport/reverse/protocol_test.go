@@ -0,0 +1,116 @@
+package reverse
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/netip"
+	"testing"
+
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+func handshake(t *testing.T, bridgeConn net.Conn, portalConn net.Conn, bridgeKey Key, portalKey Key) (bridgeErr error, portalErr error) {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() {
+		err := ServerHandshake(portalConn, portalKey)
+		portalConn.Close()
+		done <- err
+	}()
+	bridgeErr = ClientHandshake(bridgeConn, bridgeKey)
+	bridgeConn.Close()
+	portalErr = <-done
+	return
+}
+
+func TestHandshake(t *testing.T) {
+	t.Parallel()
+	bridgeConn, portalConn := net.Pipe()
+	bridgeErr, portalErr := handshake(t, bridgeConn, portalConn, NewKey("password"), NewKey("password"))
+	require.NoError(t, bridgeErr)
+	require.NoError(t, portalErr)
+}
+
+func TestHandshakeWrongPassword(t *testing.T) {
+	t.Parallel()
+	bridgeConn, portalConn := net.Pipe()
+	bridgeErr, portalErr := handshake(t, bridgeConn, portalConn, NewKey("password"), NewKey("other"))
+	require.ErrorIs(t, bridgeErr, ErrPortalAuthFailed)
+	require.Error(t, portalErr)
+}
+
+// recordConn records the data written by the bridge.
+type recordConn struct {
+	net.Conn
+	written bytes.Buffer
+}
+
+func (c *recordConn) Write(p []byte) (int, error) {
+	c.written.Write(p)
+	return c.Conn.Write(p)
+}
+
+func TestHandshakeReplay(t *testing.T) {
+	t.Parallel()
+	key := NewKey("password")
+	bridgeConn, portalConn := net.Pipe()
+	recorded := &recordConn{Conn: bridgeConn}
+	bridgeErr, portalErr := handshake(t, recorded, portalConn, key, key)
+	require.NoError(t, bridgeErr)
+	require.NoError(t, portalErr)
+
+	attackerConn, portalConn := net.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- ServerHandshake(portalConn, key)
+		portalConn.Close()
+	}()
+	request := recorded.written.Next(1 + nonceLength)
+	response := recorded.written.Next(macLength)
+	_, err := attackerConn.Write(request)
+	require.NoError(t, err)
+	_, err = io.ReadFull(attackerConn, make([]byte, nonceLength+macLength))
+	require.NoError(t, err)
+	_, err = attackerConn.Write(response)
+	require.NoError(t, err)
+	var status [1]byte
+	_, err = io.ReadFull(attackerConn, status[:])
+	require.NoError(t, err)
+	require.Equal(t, byte(statusAuthFailed), status[0])
+	require.ErrorIs(t, <-done, ErrAuthFailed)
+	attackerConn.Close()
+}
+
+func TestServiceMatch(t *testing.T) {
+	t.Parallel()
+	ipService := Service{
+		Network:     []string{N.NetworkTCP},
+		Destination: M.ParseSocksaddrHostPort("127.0.0.1", 8080),
+	}
+	domainService := Service{
+		Network:     []string{N.NetworkTCP, N.NetworkUDP},
+		Destination: M.ParseSocksaddrHostPort("nas.lan", 53),
+	}
+	for _, testCase := range []struct {
+		service     Service
+		network     string
+		destination M.Socksaddr
+		match       bool
+	}{
+		{ipService, N.NetworkTCP, M.ParseSocksaddrHostPort("127.0.0.1", 8080), true},
+		{ipService, N.NetworkTCP, M.SocksaddrFrom(netip.MustParseAddr("::ffff:127.0.0.1"), 8080), true},
+		{ipService, N.NetworkUDP, M.ParseSocksaddrHostPort("127.0.0.1", 8080), false},
+		{ipService, N.NetworkTCP, M.ParseSocksaddrHostPort("127.0.0.1", 22), false},
+		{ipService, N.NetworkTCP, M.ParseSocksaddrHostPort("192.168.1.1", 8080), false},
+		{ipService, N.NetworkTCP, M.ParseSocksaddrHostPort("localhost", 8080), false},
+		{domainService, N.NetworkUDP, M.ParseSocksaddrHostPort("NAS.lan", 53), true},
+		{domainService, N.NetworkTCP, M.ParseSocksaddrHostPort("router.lan", 53), false},
+		{domainService, N.NetworkTCP, M.ParseSocksaddrHostPort("192.168.1.2", 53), false},
+	} {
+		require.Equal(t, testCase.match, testCase.service.Match(testCase.network, testCase.destination), testCase.network, " ", testCase.destination)
+	}
+}
@@ -0,0 +1,26 @@
+package reverse
+
+import (
+	"strings"
+
+	"github.com/sagernet/sing/common"
+	M "github.com/sagernet/sing/common/metadata"
+)
+
+// Service is a destination exposed by a bridge, the portal can not open
+// streams to other destinations.
+type Service struct {
+	Network     []string
+	Destination M.Socksaddr
+}
+
+func (s Service) Match(network string, destination M.Socksaddr) bool {
+	if !common.Contains(s.Network, network) || destination.Port != s.Destination.Port {
+		return false
+	}
+	destination = destination.Unwrap()
+	if s.Destination.IsFqdn() {
+		return destination.IsFqdn() && strings.EqualFold(destination.Fqdn, s.Destination.Fqdn)
+	}
+	return destination.IsIP() && destination.Addr == s.Destination.Addr
+}
@@ -0,0 +1,40 @@
+package v2ray
+
+import (
+	"github.com/sagernet/sing-box/common/tls"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+)
+
+// FrontedTransportOptions returns the transport options of an outbound, with
+// the host of HTTP based transports set to the server name if domain
+// fronting is enabled and the host is not set.
+func FrontedTransportOptions(logger logger.ContextLogger, tlsOptions *option.OutboundTLSOptions, transportOptions *option.V2RayTransportOptions) (option.V2RayTransportOptions, error) {
+	options := common.PtrValueOrDefault(transportOptions)
+	host := tls.FrontedHost(tlsOptions)
+	if host == "" {
+		return options, nil
+	}
+	switch options.Type {
+	case "":
+		logger.Warn("domain fronting without an HTTP based transport only changes the SNI")
+	case C.V2RayTransportTypeHTTP:
+		if len(options.HTTPOptions.Host) == 0 {
+			options.HTTPOptions.Host = []string{host}
+		}
+	case C.V2RayTransportTypeWebsocket:
+		if options.WebsocketOptions.Host == "" && len(options.WebsocketOptions.Hosts) == 0 && options.WebsocketOptions.Headers.Build().Get("Host") == "" {
+			options.WebsocketOptions.Host = host
+		}
+	case C.V2RayTransportTypeHTTPUpgrade:
+		if options.HTTPUpgradeOptions.Host == "" && len(options.HTTPUpgradeOptions.Hosts) == 0 && options.HTTPUpgradeOptions.Headers.Build().Get("Host") == "" {
+			options.HTTPUpgradeOptions.Host = host
+		}
+	default:
+		return options, E.New("domain fronting is unsupported in ", options.Type, " transport")
+	}
+	return options, nil
+}
@@ -19,5 +19,5 @@ func NewGRPCServer(ctx context.Context, logger logger.ContextLogger, options opt
 }
 
 func NewGRPCClient(ctx context.Context, dialer N.Dialer, serverAddr M.Socksaddr, options option.V2RayGRPCOptions, tlsConfig tls.Config) (adapter.V2RayClientTransport, error) {
-	return v2raygrpclite.NewClient(ctx, dialer, serverAddr, options, tlsConfig), nil
+	return v2raygrpclite.NewClient(ctx, dialer, serverAddr, options, tlsConfig)
 }
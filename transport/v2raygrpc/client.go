@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/rotation"
 	"github.com/sagernet/sing-box/common/tls"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing/common"
@@ -28,13 +29,17 @@ type Client struct {
 	ctx         context.Context
 	dialer      N.Dialer
 	serverAddr  string
-	serviceName string
+	serviceName *rotation.Selector
 	dialOptions []grpc.DialOption
 	conn        atomic.Pointer[grpc.ClientConn]
 	connAccess  sync.Mutex
 }
 
 func NewClient(ctx context.Context, dialer N.Dialer, serverAddr M.Socksaddr, options option.V2RayGRPCOptions, tlsConfig tls.Config) (adapter.V2RayClientTransport, error) {
+	serviceName, err := newServiceNameSelector(options)
+	if err != nil {
+		return nil, err
+	}
 	var dialOptions []grpc.DialOption
 	if tlsConfig != nil {
 		if len(tlsConfig.NextProtos()) == 0 {
@@ -69,7 +74,7 @@ func NewClient(ctx context.Context, dialer N.Dialer, serverAddr M.Socksaddr, opt
 		ctx:         ctx,
 		dialer:      dialer,
 		serverAddr:  serverAddr.String(),
-		serviceName: options.ServiceName,
+		serviceName: serviceName,
 		dialOptions: dialOptions,
 	}, nil
 }
@@ -101,7 +106,7 @@ func (c *Client) DialContext(ctx context.Context) (net.Conn, error) {
 	}
 	client := NewGunServiceClient(clientConn).(GunServiceCustomNameClient)
 	ctx, cancel := common.ContextWithCancelCause(ctx)
-	stream, err := client.TunCustomName(ctx, c.serviceName)
+	stream, err := client.TunCustomName(ctx, c.serviceName.Select())
 	if err != nil {
 		cancel(err)
 		return nil, err
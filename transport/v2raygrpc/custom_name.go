@@ -3,9 +3,23 @@ package v2raygrpc
 import (
 	"context"
 
+	"github.com/sagernet/sing-box/common/rotation"
+	"github.com/sagernet/sing-box/option"
+
 	"google.golang.org/grpc"
 )
 
+func newServiceNameSelector(options option.V2RayGRPCOptions) (*rotation.Selector, error) {
+	serviceNames, err := rotation.Values(options.ServiceName, options.ServiceNames, "service_name")
+	if err != nil {
+		return nil, err
+	}
+	if len(serviceNames) == 0 {
+		serviceNames = []string{""}
+	}
+	return rotation.New(serviceNames, "service_name", options.Rotation)
+}
+
 type GunService interface {
 	Context() context.Context
 	Send(*Hunk) error
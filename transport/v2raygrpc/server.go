@@ -8,10 +8,12 @@ import (
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/rotation"
 	"github.com/sagernet/sing-box/common/tls"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/logger"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
@@ -26,13 +28,18 @@ import (
 var _ adapter.V2RayServerTransport = (*Server)(nil)
 
 type Server struct {
-	ctx     context.Context
-	logger  logger.ContextLogger
-	handler adapter.V2RayServerTransportHandler
-	server  *grpc.Server
+	ctx         context.Context
+	logger      logger.ContextLogger
+	handler     adapter.V2RayServerTransportHandler
+	server      *grpc.Server
+	serviceName *rotation.Selector
 }
 
 func NewServer(ctx context.Context, logger logger.ContextLogger, options option.V2RayGRPCOptions, tlsConfig tls.ServerConfig, handler adapter.V2RayServerTransportHandler) (*Server, error) {
+	serviceName, err := newServiceNameSelector(options)
+	if err != nil {
+		return nil, err
+	}
 	var serverOptions []grpc.ServerOption
 	if tlsConfig != nil {
 		if !common.Contains(tlsConfig.NextProtos(), http2.NextProtoTLS) {
@@ -46,12 +53,22 @@ func NewServer(ctx context.Context, logger logger.ContextLogger, options option.
 			Timeout: time.Duration(options.PingTimeout),
 		}))
 	}
-	server := &Server{ctx, logger, handler, grpc.NewServer(serverOptions...)}
-	RegisterGunServiceCustomNameServer(server.server, server, options.ServiceName)
+	server := &Server{ctx, logger, handler, grpc.NewServer(serverOptions...), serviceName}
+	for _, name := range serviceName.Values() {
+		RegisterGunServiceCustomNameServer(server.server, server, name)
+	}
 	return server, nil
 }
 
 func (s *Server) Tun(server GunService_TunServer) error {
+	if method, loaded := grpc.Method(server.Context()); loaded {
+		name := strings.TrimSuffix(strings.TrimPrefix(method, "/"), "/Tun")
+		if !s.serviceName.Contains(name) {
+			err := E.New("bad service name: ", name)
+			s.logger.ErrorContext(server.Context(), err)
+			return err
+		}
+	}
 	conn := NewGRPCConn(server)
 	var source M.Socksaddr
 	if remotePeer, loaded := peer.FromContext(server.Context()); loaded {
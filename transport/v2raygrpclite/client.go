@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/rotation"
 	"github.com/sagernet/sing-box/common/tls"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing-box/transport/v2rayhttp"
@@ -32,11 +33,23 @@ type Client struct {
 	serverAddr M.Socksaddr
 	transport  *http2.Transport
 	options    option.V2RayGRPCOptions
-	url        *url.URL
+	urls       map[string]*url.URL
+	names      *rotation.Selector
 	host       string
 }
 
-func NewClient(ctx context.Context, dialer N.Dialer, serverAddr M.Socksaddr, options option.V2RayGRPCOptions, tlsConfig tls.Config) adapter.V2RayClientTransport {
+func NewClient(ctx context.Context, dialer N.Dialer, serverAddr M.Socksaddr, options option.V2RayGRPCOptions, tlsConfig tls.Config) (adapter.V2RayClientTransport, error) {
+	serviceNames, err := rotation.Values(options.ServiceName, options.ServiceNames, "service_name")
+	if err != nil {
+		return nil, err
+	}
+	if len(serviceNames) == 0 {
+		serviceNames = []string{""}
+	}
+	names, err := rotation.New(serviceNames, "service_name", options.Rotation)
+	if err != nil {
+		return nil, err
+	}
 	var host string
 	if tlsConfig != nil && tlsConfig.ServerName() != "" {
 		host = M.ParseSocksaddrHostPort(tlsConfig.ServerName(), serverAddr.Port).String()
@@ -52,13 +65,17 @@ func NewClient(ctx context.Context, dialer N.Dialer, serverAddr M.Socksaddr, opt
 			PingTimeout:        time.Duration(options.PingTimeout),
 			DisableCompression: true,
 		},
-		url: &url.URL{
+		urls:  make(map[string]*url.URL, len(serviceNames)),
+		names: names,
+		host:  host,
+	}
+	for _, serviceName := range serviceNames {
+		client.urls[serviceName] = &url.URL{
 			Scheme:  "https",
 			Host:    serverAddr.String(),
-			Path:    "/" + options.ServiceName + "/Tun",
-			RawPath: "/" + url.PathEscape(options.ServiceName) + "/Tun",
-		},
-		host: host,
+			Path:    "/" + serviceName + "/Tun",
+			RawPath: "/" + url.PathEscape(serviceName) + "/Tun",
+		}
 	}
 	if tlsConfig == nil {
 		client.transport.DialTLSContext = func(ctx context.Context, network, addr string, cfg *tls.STDConfig) (net.Conn, error) {
@@ -74,7 +91,7 @@ func NewClient(ctx context.Context, dialer N.Dialer, serverAddr M.Socksaddr, opt
 		}
 	}
 
-	return client
+	return client, nil
 }
 
 func (c *Client) DialContext(ctx context.Context) (net.Conn, error) {
@@ -82,7 +99,7 @@ func (c *Client) DialContext(ctx context.Context) (net.Conn, error) {
 	request := &http.Request{
 		Method: http.MethodPost,
 		Body:   pipeInReader,
-		URL:    c.url,
+		URL:    c.urls[c.names.Select()],
 		Header: defaultClientHeader,
 		Host:   c.host,
 	}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/rotation"
 	"github.com/sagernet/sing-box/common/tls"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
@@ -34,15 +35,26 @@ type Server struct {
 	httpServer *http.Server
 	h2Server   *http2.Server
 	h2cHandler http.Handler
-	path       string
+	names      *rotation.Selector
 }
 
 func NewServer(ctx context.Context, logger logger.ContextLogger, options option.V2RayGRPCOptions, tlsConfig tls.ServerConfig, handler adapter.V2RayServerTransportHandler) (*Server, error) {
+	serviceNames, err := rotation.Values(options.ServiceName, options.ServiceNames, "service_name")
+	if err != nil {
+		return nil, err
+	}
+	if len(serviceNames) == 0 {
+		serviceNames = []string{""}
+	}
+	names, err := rotation.New(serviceNames, "service_name", options.Rotation)
+	if err != nil {
+		return nil, err
+	}
 	server := &Server{
 		tlsConfig: tlsConfig,
 		logger:    logger,
 		handler:   handler,
-		path:      "/" + options.ServiceName + "/Tun",
+		names:     names,
 		h2Server: &http2.Server{
 			IdleTimeout: time.Duration(options.IdleTimeout),
 		},
@@ -65,7 +77,8 @@ func (s *Server) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
 		s.h2cHandler.ServeHTTP(writer, request)
 		return
 	}
-	if request.URL.Path != s.path {
+	serviceName, isTun := strings.CutSuffix(strings.TrimPrefix(request.URL.Path, "/"), "/Tun")
+	if !isTun || !s.names.Contains(serviceName) {
 		s.invalidRequest(writer, request, http.StatusNotFound, E.New("bad path: ", request.URL.Path))
 		return
 	}
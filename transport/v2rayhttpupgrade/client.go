@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/rotation"
 	"github.com/sagernet/sing-box/common/tls"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing/common/buf"
@@ -22,11 +23,12 @@ import (
 var _ adapter.V2RayClientTransport = (*Client)(nil)
 
 type Client struct {
-	dialer     N.Dialer
-	serverAddr M.Socksaddr
-	requestURL url.URL
-	headers    http.Header
-	host       string
+	dialer      N.Dialer
+	serverAddr  M.Socksaddr
+	requestURLs map[string]url.URL
+	paths       *rotation.Selector
+	headers     http.Header
+	hosts       *rotation.Selector
 }
 
 func NewClient(ctx context.Context, dialer N.Dialer, serverAddr M.Socksaddr, options option.V2RayHTTPUpgradeOptions, tlsConfig tls.Config) (*Client, error) {
@@ -36,13 +38,16 @@ func NewClient(ctx context.Context, dialer N.Dialer, serverAddr M.Socksaddr, opt
 		}
 		dialer = tls.NewDialer(dialer, tlsConfig)
 	}
-	var host string
-	if options.Host != "" {
-		host = options.Host
-	} else if tlsConfig != nil && tlsConfig.ServerName() != "" {
-		host = tlsConfig.ServerName()
-	} else {
-		host = serverAddr.String()
+	hostList, err := rotation.Values(options.Host, options.Hosts, "host")
+	if err != nil {
+		return nil, err
+	}
+	if len(hostList) == 0 {
+		if tlsConfig != nil && tlsConfig.ServerName() != "" {
+			hostList = []string{tlsConfig.ServerName()}
+		} else {
+			hostList = []string{serverAddr.String()}
+		}
 	}
 	var requestURL url.URL
 	if tlsConfig == nil {
@@ -51,25 +56,45 @@ func NewClient(ctx context.Context, dialer N.Dialer, serverAddr M.Socksaddr, opt
 		requestURL.Scheme = "https"
 	}
 	requestURL.Host = serverAddr.String()
-	requestURL.Path = options.Path
-	err := sHTTP.URLSetPath(&requestURL, options.Path)
+	pathList, err := rotation.Values(options.Path, options.Paths, "path")
 	if err != nil {
-		return nil, E.Cause(err, "parse path")
+		return nil, err
 	}
-	if !strings.HasPrefix(requestURL.Path, "/") {
-		requestURL.Path = "/" + requestURL.Path
+	if len(pathList) == 0 {
+		pathList = []string{""}
+	}
+	requestURLs := make(map[string]url.URL, len(pathList))
+	for _, path := range pathList {
+		pathURL := requestURL
+		pathURL.Path = path
+		err = sHTTP.URLSetPath(&pathURL, path)
+		if err != nil {
+			return nil, E.Cause(err, "parse path")
+		}
+		if !strings.HasPrefix(pathURL.Path, "/") {
+			pathURL.Path = "/" + pathURL.Path
+		}
+		requestURLs[path] = pathURL
 	}
 	headers := make(http.Header)
 	for key, value := range options.Headers {
 		headers[key] = value
 	}
-	return &Client{
-		dialer:     dialer,
-		serverAddr: serverAddr,
-		requestURL: requestURL,
-		headers:    headers,
-		host:       host,
-	}, nil
+	client := &Client{
+		dialer:      dialer,
+		serverAddr:  serverAddr,
+		requestURLs: requestURLs,
+		headers:     headers,
+	}
+	client.paths, err = rotation.New(pathList, "path", options.Rotation)
+	if err != nil {
+		return nil, err
+	}
+	client.hosts, err = rotation.New(hostList, "host", options.Rotation)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
 }
 
 func (c *Client) DialContext(ctx context.Context) (net.Conn, error) {
@@ -77,11 +102,12 @@ func (c *Client) DialContext(ctx context.Context) (net.Conn, error) {
 	if err != nil {
 		return nil, err
 	}
+	requestURL := c.requestURLs[c.paths.Select()]
 	request := &http.Request{
 		Method: http.MethodGet,
-		URL:    &c.requestURL,
+		URL:    &requestURL,
 		Header: c.headers.Clone(),
-		Host:   c.host,
+		Host:   c.hosts.Select(),
 	}
 	request.Header.Set("Connection", "Upgrade")
 	request.Header.Set("Upgrade", "websocket")
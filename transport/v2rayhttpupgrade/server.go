@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/rotation"
 	"github.com/sagernet/sing-box/common/tls"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
@@ -30,8 +31,8 @@ type Server struct {
 	tlsConfig  tls.ServerConfig
 	handler    adapter.V2RayServerTransportHandler
 	httpServer *http.Server
-	host       string
-	path       string
+	hosts      *rotation.Selector
+	paths      *rotation.Selector
 	headers    http.Header
 }
 
@@ -41,12 +42,31 @@ func NewServer(ctx context.Context, logger logger.ContextLogger, options option.
 		logger:    logger,
 		tlsConfig: tlsConfig,
 		handler:   handler,
-		host:      options.Host,
-		path:      options.Path,
 		headers:   options.Headers.Build(),
 	}
-	if !strings.HasPrefix(server.path, "/") {
-		server.path = "/" + server.path
+	hostList, err := rotation.Values(options.Host, options.Hosts, "host")
+	if err != nil {
+		return nil, err
+	}
+	server.hosts, err = rotation.New(hostList, "host", options.Rotation)
+	if err != nil {
+		return nil, err
+	}
+	pathList, err := rotation.Values(options.Path, options.Paths, "path")
+	if err != nil {
+		return nil, err
+	}
+	if len(pathList) == 0 {
+		pathList = []string{"/"}
+	}
+	for i, path := range pathList {
+		if !strings.HasPrefix(path, "/") {
+			pathList[i] = "/" + path
+		}
+	}
+	server.paths, err = rotation.New(pathList, "path", options.Rotation)
+	if err != nil {
+		return nil, err
 	}
 	server.httpServer = &http.Server{
 		Handler:           server,
@@ -69,11 +89,11 @@ type httpFlusher interface {
 
 func (s *Server) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
 	host := request.Host
-	if len(s.host) > 0 && host != s.host {
+	if s.hosts != nil && !s.hosts.Contains(host) {
 		s.invalidRequest(writer, request, http.StatusBadRequest, E.New("bad host: ", host))
 		return
 	}
-	if request.URL.Path != s.path {
+	if !s.paths.Contains(request.URL.Path) {
 		s.invalidRequest(writer, request, http.StatusNotFound, E.New("bad path: ", request.URL.Path))
 		return
 	}
@@ -10,6 +10,7 @@ import (
 	"github.com/sagernet/quic-go"
 	"github.com/sagernet/quic-go/http3"
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/quicmtu"
 	"github.com/sagernet/sing-box/common/tls"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
@@ -28,14 +29,20 @@ type Client struct {
 	serverAddr M.Socksaddr
 	tlsConfig  tls.Config
 	quicConfig *quic.Config
+	mtuOptions option.QUICMTUOptions
 	connAccess sync.Mutex
 	conn       common.TypedValue[*quic.Conn]
 	rawConn    net.Conn
 }
 
 func NewClient(ctx context.Context, dialer N.Dialer, serverAddr M.Socksaddr, options option.V2RayQUICOptions, tlsConfig tls.Config) (adapter.V2RayClientTransport, error) {
+	err := quicmtu.Validate(options.QUICMTUOptions, true)
+	if err != nil {
+		return nil, err
+	}
 	quicConfig := &quic.Config{
-		DisablePathMTUDiscovery: !C.IsLinux && !C.IsWindows,
+		InitialPacketSize:       options.InitialMTU,
+		DisablePathMTUDiscovery: !C.IsLinux && !C.IsWindows || options.DisableMTUDiscovery,
 	}
 	if len(tlsConfig.NextProtos()) == 0 {
 		tlsConfig.SetNextProtos([]string{http3.NextProtoH3})
@@ -46,6 +53,7 @@ func NewClient(ctx context.Context, dialer N.Dialer, serverAddr M.Socksaddr, opt
 		serverAddr: serverAddr,
 		tlsConfig:  tlsConfig,
 		quicConfig: quicConfig,
+		mtuOptions: options.QUICMTUOptions,
 	}, nil
 }
 
@@ -72,7 +80,7 @@ func (c *Client) offerNew() (*quic.Conn, error) {
 	if err != nil {
 		return nil, err
 	}
-	packetConn := bufio.NewUnbindPacketConn(udpConn)
+	packetConn := quicmtu.NewPacketConn(bufio.NewUnbindPacketConn(udpConn), c.mtuOptions)
 	quicConn, err := qtls.Dial(c.ctx, packetConn, udpConn.RemoteAddr(), c.tlsConfig, c.quicConfig)
 	if err != nil {
 		packetConn.Close()
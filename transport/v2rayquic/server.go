@@ -10,6 +10,7 @@ import (
 	"github.com/sagernet/quic-go"
 	"github.com/sagernet/quic-go/http3"
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/quicmtu"
 	"github.com/sagernet/sing-box/common/tls"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
@@ -28,14 +29,20 @@ type Server struct {
 	logger       logger.ContextLogger
 	tlsConfig    tls.ServerConfig
 	quicConfig   *quic.Config
+	mtuOptions   option.QUICMTUOptions
 	handler      adapter.V2RayServerTransportHandler
 	udpListener  net.PacketConn
 	quicListener qtls.Listener
 }
 
 func NewServer(ctx context.Context, logger logger.ContextLogger, options option.V2RayQUICOptions, tlsConfig tls.ServerConfig, handler adapter.V2RayServerTransportHandler) (adapter.V2RayServerTransport, error) {
+	err := quicmtu.Validate(options.QUICMTUOptions, true)
+	if err != nil {
+		return nil, err
+	}
 	quicConfig := &quic.Config{
-		DisablePathMTUDiscovery: !C.IsLinux && !C.IsWindows,
+		InitialPacketSize:       options.InitialMTU,
+		DisablePathMTUDiscovery: !C.IsLinux && !C.IsWindows || options.DisableMTUDiscovery,
 	}
 	if len(tlsConfig.NextProtos()) == 0 {
 		tlsConfig.SetNextProtos([]string{http3.NextProtoH3})
@@ -45,6 +52,7 @@ func NewServer(ctx context.Context, logger logger.ContextLogger, options option.
 		logger:     logger,
 		tlsConfig:  tlsConfig,
 		quicConfig: quicConfig,
+		mtuOptions: options.QUICMTUOptions,
 		handler:    handler,
 	}
 	return server, nil
@@ -59,7 +67,7 @@ func (s *Server) Serve(listener net.Listener) error {
 }
 
 func (s *Server) ServePacket(listener net.PacketConn) error {
-	quicListener, err := qtls.Listen(listener, s.tlsConfig, s.quicConfig)
+	quicListener, err := qtls.Listen(quicmtu.NewPacketConn(listener, s.mtuOptions), s.tlsConfig, s.quicConfig)
 	if err != nil {
 		return err
 	}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/rotation"
 	"github.com/sagernet/sing-box/common/tls"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
@@ -28,6 +29,9 @@ type Client struct {
 	dialer              N.Dialer
 	serverAddr          M.Socksaddr
 	requestURL          url.URL
+	requestURLs         map[string]url.URL
+	paths               *rotation.Selector
+	hosts               *rotation.Selector
 	headers             http.Header
 	maxEarlyData        uint32
 	earlyDataHeaderName string
@@ -46,35 +50,70 @@ func NewClient(ctx context.Context, dialer N.Dialer, serverAddr M.Socksaddr, opt
 	} else {
 		requestURL.Scheme = "wss"
 	}
-	if options.Host == "" {
+	hostList, err := rotation.Values(options.Host, options.Hosts, "host")
+	if err != nil {
+		return nil, err
+	}
+	if len(hostList) == 0 {
 		requestURL.Host = serverAddr.String()
-	} else {
-		requestURL.Host = options.Host
 	}
-	requestURL.Path = options.Path
-	err := sHTTP.URLSetPath(&requestURL, options.Path)
+	pathList, err := rotation.Values(options.Path, options.Paths, "path")
 	if err != nil {
-		return nil, E.Cause(err, "parse path")
+		return nil, err
 	}
-	if !strings.HasPrefix(requestURL.Path, "/") {
-		requestURL.Path = "/" + requestURL.Path
+	if len(pathList) == 0 {
+		pathList = []string{""}
+	}
+	requestURLs := make(map[string]url.URL, len(pathList))
+	for _, path := range pathList {
+		pathURL := requestURL
+		pathURL.Path = path
+		err = sHTTP.URLSetPath(&pathURL, path)
+		if err != nil {
+			return nil, E.Cause(err, "parse path")
+		}
+		if !strings.HasPrefix(pathURL.Path, "/") {
+			pathURL.Path = "/" + pathURL.Path
+		}
+		requestURLs[path] = pathURL
 	}
 	headers := options.Headers.Build()
 	if host := headers.Get("Host"); host != "" {
 		headers.Del("Host")
 		requestURL.Host = host
+		hostList = nil
 	}
 	if headers.Get("User-Agent") == "" {
 		headers.Set("User-Agent", "Go-http-client/1.1")
 	}
-	return &Client{
-		dialer,
-		serverAddr,
-		requestURL,
-		headers,
-		options.MaxEarlyData,
-		options.EarlyDataHeaderName,
-	}, nil
+	client := &Client{
+		dialer:              dialer,
+		serverAddr:          serverAddr,
+		requestURL:          requestURL,
+		requestURLs:         requestURLs,
+		headers:             headers,
+		maxEarlyData:        options.MaxEarlyData,
+		earlyDataHeaderName: options.EarlyDataHeaderName,
+	}
+	client.paths, err = rotation.New(pathList, "path", options.Rotation)
+	if err != nil {
+		return nil, err
+	}
+	client.hosts, err = rotation.New(hostList, "host", options.Rotation)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+func (c *Client) newRequestURL() url.URL {
+	requestURL := c.requestURLs[c.paths.Select()]
+	if c.hosts != nil {
+		requestURL.Host = c.hosts.Select()
+	} else {
+		requestURL.Host = c.requestURL.Host
+	}
+	return requestURL
 }
 
 func (c *Client) dialContext(ctx context.Context, requestURL *url.URL, headers http.Header) (*WebsocketConn, error) {
@@ -111,14 +150,15 @@ func (c *Client) dialContext(ctx context.Context, requestURL *url.URL, headers h
 }
 
 func (c *Client) DialContext(ctx context.Context) (net.Conn, error) {
+	requestURL := c.newRequestURL()
 	if c.maxEarlyData <= 0 {
-		conn, err := c.dialContext(ctx, &c.requestURL, c.headers)
+		conn, err := c.dialContext(ctx, &requestURL, c.headers)
 		if err != nil {
 			return nil, err
 		}
 		return conn, nil
 	} else {
-		return &EarlyWebsocketConn{Client: c, ctx: ctx, create: make(chan struct{})}, nil
+		return &EarlyWebsocketConn{Client: c, ctx: ctx, requestURL: requestURL, create: make(chan struct{})}, nil
 	}
 }
 
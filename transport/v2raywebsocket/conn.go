@@ -6,6 +6,7 @@ import (
 	"errors"
 	"io"
 	"net"
+	"net/url"
 	"os"
 	"sync"
 	"sync/atomic"
@@ -135,11 +136,12 @@ func (c *WebsocketConn) Upstream() any {
 
 type EarlyWebsocketConn struct {
 	*Client
-	ctx    context.Context
-	conn   atomic.Pointer[WebsocketConn]
-	access sync.Mutex
-	create chan struct{}
-	err    error
+	ctx        context.Context
+	requestURL url.URL
+	conn       atomic.Pointer[WebsocketConn]
+	access     sync.Mutex
+	create     chan struct{}
+	err        error
 }
 
 func (c *EarlyWebsocketConn) Read(b []byte) (n int, err error) {
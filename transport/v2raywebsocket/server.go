@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/rotation"
 	"github.com/sagernet/sing-box/common/tls"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
@@ -34,7 +35,8 @@ type Server struct {
 	tlsConfig           tls.ServerConfig
 	handler             adapter.V2RayServerTransportHandler
 	httpServer          *http.Server
-	path                string
+	paths               *rotation.Selector
+	hosts               *rotation.Selector
 	maxEarlyData        uint32
 	earlyDataHeaderName string
 	upgrader            ws.HTTPUpgrader
@@ -46,7 +48,6 @@ func NewServer(ctx context.Context, logger logger.ContextLogger, options option.
 		logger:              logger,
 		tlsConfig:           tlsConfig,
 		handler:             handler,
-		path:                options.Path,
 		maxEarlyData:        options.MaxEarlyData,
 		earlyDataHeaderName: options.EarlyDataHeaderName,
 		upgrader: ws.HTTPUpgrader{
@@ -54,8 +55,26 @@ func NewServer(ctx context.Context, logger logger.ContextLogger, options option.
 			Header:  options.Headers.Build(),
 		},
 	}
-	if !strings.HasPrefix(server.path, "/") {
-		server.path = "/" + server.path
+	pathList, err := rotation.Values(options.Path, options.Paths, "path")
+	if err != nil {
+		return nil, err
+	}
+	if len(pathList) == 0 {
+		pathList = []string{"/"}
+	}
+	for i, path := range pathList {
+		if !strings.HasPrefix(path, "/") {
+			pathList[i] = "/" + path
+		}
+	}
+	server.paths, err = rotation.New(pathList, "path", options.Rotation)
+	if err != nil {
+		return nil, err
+	}
+	// unlike `host`, which is only sent by clients, `hosts` is also verified by the server
+	server.hosts, err = rotation.New(options.Hosts, "host", options.Rotation)
+	if err != nil {
+		return nil, err
 	}
 	server.httpServer = &http.Server{
 		Handler:           server,
@@ -72,8 +91,13 @@ func NewServer(ctx context.Context, logger logger.ContextLogger, options option.
 }
 
 func (s *Server) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if s.hosts != nil && !s.hosts.Contains(request.Host) {
+		s.invalidRequest(writer, request, http.StatusBadRequest, E.New("bad host: ", request.Host))
+		return
+	}
+	paths := s.paths.Accepted()
 	if s.maxEarlyData == 0 || s.earlyDataHeaderName != "" {
-		if request.URL.Path != s.path {
+		if !common.Contains(paths, request.URL.Path) {
 			s.invalidRequest(writer, request, http.StatusNotFound, E.New("bad path: ", request.URL.Path))
 			return
 		}
@@ -84,18 +108,20 @@ func (s *Server) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
 		conn      net.Conn
 	)
 	if s.earlyDataHeaderName == "" {
-		if strings.HasPrefix(request.URL.RequestURI(), s.path) {
-			earlyDataStr := request.URL.RequestURI()[len(s.path):]
+		var path string
+		for _, it := range paths {
+			if strings.HasPrefix(request.URL.RequestURI(), it) && len(it) > len(path) {
+				path = it
+			}
+		}
+		if path != "" {
+			earlyDataStr := request.URL.RequestURI()[len(path):]
 			earlyData, err = base64.RawURLEncoding.DecodeString(earlyDataStr)
 		} else {
 			s.invalidRequest(writer, request, http.StatusNotFound, E.New("bad path: ", request.URL.Path))
 			return
 		}
 	} else {
-		if request.URL.Path != s.path {
-			s.invalidRequest(writer, request, http.StatusNotFound, E.New("bad path: ", request.URL.Path))
-			return
-		}
 		earlyDataStr := request.Header.Get(s.earlyDataHeaderName)
 		if earlyDataStr != "" {
 			earlyData, err = base64.RawURLEncoding.DecodeString(earlyDataStr)
@@ -5,11 +5,15 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"fmt"
+	"math"
 	"net"
 	"net/netip"
 	"os"
 	"reflect"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 	"unsafe"
 
@@ -31,15 +35,21 @@ import (
 
 type Endpoint struct {
 	options        EndpointOptions
+	peerAccess     sync.RWMutex
 	peers          []peerConfig
+	peerNames      map[*device.Peer]string
 	ipcConf        string
 	allowedAddress []netip.Prefix
 	tunDevice      Device
 	natDevice      NatDevice
+	bind           conn.Bind
+	connected      bool
 	device         *device.Device
 	allowedIPs     *device.AllowedIPs
 	pause          pause.Manager
 	pauseCallback  *list.Element[pause.Callback]
+	power          adapter.PowerManager
+	powerCallback  *list.Element[adapter.PowerCallback]
 }
 
 func NewEndpoint(options EndpointOptions) (*Endpoint, error) {
@@ -57,35 +67,9 @@ func NewEndpoint(options EndpointOptions) (*Endpoint, error) {
 	}
 	var peers []peerConfig
 	for peerIndex, rawPeer := range options.Peers {
-		peer := peerConfig{
-			allowedIPs: rawPeer.AllowedIPs,
-			keepalive:  rawPeer.PersistentKeepaliveInterval,
-		}
-		if rawPeer.Endpoint.Addr.IsValid() {
-			peer.endpoint = rawPeer.Endpoint.AddrPort()
-		} else if rawPeer.Endpoint.IsFqdn() {
-			peer.destination = rawPeer.Endpoint
-		}
-		publicKeyBytes, err := base64.StdEncoding.DecodeString(rawPeer.PublicKey)
+		peer, err := newPeerConfig(rawPeer)
 		if err != nil {
-			return nil, E.Cause(err, "decode public key for peer ", peerIndex)
-		}
-		peer.publicKeyHex = hex.EncodeToString(publicKeyBytes)
-		if rawPeer.PreSharedKey != "" {
-			preSharedKeyBytes, err := base64.StdEncoding.DecodeString(rawPeer.PreSharedKey)
-			if err != nil {
-				return nil, E.Cause(err, "decode pre shared key for peer ", peerIndex)
-			}
-			peer.preSharedKeyHex = hex.EncodeToString(preSharedKeyBytes)
-		}
-		if len(rawPeer.AllowedIPs) == 0 {
-			return nil, E.New("missing allowed ips for peer ", peerIndex)
-		}
-		if len(rawPeer.Reserved) > 0 {
-			if len(rawPeer.Reserved) != 3 {
-				return nil, E.New("invalid reserved value for peer ", peerIndex, ", required 3 bytes, got ", len(peer.reserved))
-			}
-			copy(peer.reserved[:], rawPeer.Reserved[:])
+			return nil, E.Cause(err, "parse peer[", peerIndex, "]")
 		}
 		peers = append(peers, peer)
 	}
@@ -134,6 +118,8 @@ func NewEndpoint(options EndpointOptions) (*Endpoint, error) {
 }
 
 func (e *Endpoint) Start(resolve bool) error {
+	e.peerAccess.Lock()
+	defer e.peerAccess.Unlock()
 	if common.Any(e.peers, func(peer peerConfig) bool {
 		return !peer.endpoint.IsValid() && peer.destination.IsFqdn()
 	}) {
@@ -153,64 +139,78 @@ func (e *Endpoint) Start(resolve bool) error {
 	} else if resolve {
 		return nil
 	}
-	var bind conn.Bind
-	wgListener, isWgListener := common.Cast[dialer.WireGuardListener](e.options.Dialer)
-	if isWgListener {
-		bind = conn.NewStdNetBind(wgListener.WireGuardControl())
-	} else {
-		var (
-			isConnect   bool
-			connectAddr netip.AddrPort
-			reserved    [3]uint8
-		)
-		if len(e.peers) == 1 && e.peers[0].endpoint.IsValid() {
-			isConnect = true
-			connectAddr = e.peers[0].endpoint
-			reserved = e.peers[0].reserved
+	// the device is kept if the configuration fails, so that a retry of a
+	// lazy endpoint does not start the TUN device again
+	if e.device == nil {
+		var bind conn.Bind
+		wgListener, isWgListener := common.Cast[dialer.WireGuardListener](e.options.Dialer)
+		if isWgListener {
+			bind = conn.NewStdNetBind(wgListener.WireGuardControl())
+		} else {
+			var (
+				isConnect   bool
+				connectAddr netip.AddrPort
+				reserved    [3]uint8
+			)
+			if len(e.peers) == 1 && e.peers[0].endpoint.IsValid() {
+				isConnect = true
+				connectAddr = e.peers[0].endpoint
+				reserved = e.peers[0].reserved
+			}
+			bind = NewClientBind(e.options.Context, e.options.Logger, e.options.Dialer, isConnect, connectAddr, reserved)
+			e.connected = isConnect
 		}
-		bind = NewClientBind(e.options.Context, e.options.Logger, e.options.Dialer, isConnect, connectAddr, reserved)
-	}
-	if isWgListener || len(e.peers) > 1 {
-		for _, peer := range e.peers {
-			if peer.reserved != [3]uint8{} {
-				bind.SetReservedForEndpoint(peer.endpoint, peer.reserved)
+		if isWgListener || len(e.peers) > 1 {
+			for _, peer := range e.peers {
+				if peer.reserved != [3]uint8{} {
+					bind.SetReservedForEndpoint(peer.endpoint, peer.reserved)
+				}
 			}
 		}
+		err := e.tunDevice.Start()
+		if err != nil {
+			return err
+		}
+		logger := &device.Logger{
+			Verbosef: func(format string, args ...interface{}) {
+				e.options.Logger.Debug(fmt.Sprintf(strings.ToLower(format), args...))
+			},
+			Errorf: func(format string, args ...interface{}) {
+				e.options.Logger.Error(fmt.Sprintf(strings.ToLower(format), args...))
+			},
+		}
+		var deviceInput Device
+		if e.natDevice != nil {
+			deviceInput = e.natDevice
+		} else {
+			deviceInput = e.tunDevice
+		}
+		wgDevice := device.NewDevice(e.options.Context, deviceInput, bind, logger, e.options.Workers)
+		e.tunDevice.SetDevice(wgDevice)
+		e.device = wgDevice
+		e.bind = bind
 	}
-	err := e.tunDevice.Start()
-	if err != nil {
-		return err
-	}
-	logger := &device.Logger{
-		Verbosef: func(format string, args ...interface{}) {
-			e.options.Logger.Debug(fmt.Sprintf(strings.ToLower(format), args...))
-		},
-		Errorf: func(format string, args ...interface{}) {
-			e.options.Logger.Error(fmt.Sprintf(strings.ToLower(format), args...))
-		},
-	}
-	var deviceInput Device
-	if e.natDevice != nil {
-		deviceInput = e.natDevice
-	} else {
-		deviceInput = e.tunDevice
-	}
-	wgDevice := device.NewDevice(e.options.Context, deviceInput, bind, logger, e.options.Workers)
-	e.tunDevice.SetDevice(wgDevice)
 	ipcConf := e.ipcConf
 	for _, peer := range e.peers {
 		ipcConf += peer.GenerateIpcLines()
 	}
-	err = wgDevice.IpcSet(ipcConf)
+	err := e.device.IpcSet(ipcConf)
 	if err != nil {
 		return E.Cause(err, "setup wireguard: \n", ipcConf)
 	}
-	e.device = wgDevice
 	e.pause = service.FromContext[pause.Manager](e.options.Context)
 	if e.pause != nil {
 		e.pauseCallback = e.pause.RegisterCallback(e.onPauseUpdated)
 	}
-	e.allowedIPs = (*device.AllowedIPs)(unsafe.Pointer(reflect.Indirect(reflect.ValueOf(wgDevice)).FieldByName("allowedips").UnsafeAddr()))
+	e.power = service.FromContext[adapter.PowerManager](e.options.Context)
+	if e.power != nil {
+		e.powerCallback = e.power.RegisterCallback(e.onPowerUpdated)
+		if e.power.LowPower() {
+			e.updateKeepaliveLocked(true)
+		}
+	}
+	e.allowedIPs = (*device.AllowedIPs)(unsafe.Pointer(reflect.Indirect(reflect.ValueOf(e.device)).FieldByName("allowedips").UnsafeAddr()))
+	e.updatePeerNamesLocked()
 	return nil
 }
 
@@ -235,6 +235,9 @@ func (e *Endpoint) Close() error {
 	if e.pauseCallback != nil {
 		e.pause.UnregisterCallback(e.pauseCallback)
 	}
+	if e.powerCallback != nil {
+		e.power.UnregisterCallback(e.powerCallback)
+	}
 	return nil
 }
 
@@ -245,6 +248,216 @@ func (e *Endpoint) Lookup(address netip.Addr) *device.Peer {
 	return e.allowedIPs.Lookup(address.AsSlice())
 }
 
+// PeerName returns the name of the peer whose allowed IPs contain the address.
+func (e *Endpoint) PeerName(address netip.Addr) string {
+	peer := e.Lookup(address)
+	if peer == nil {
+		return ""
+	}
+	e.peerAccess.RLock()
+	defer e.peerAccess.RUnlock()
+	return e.peerNames[peer]
+}
+
+func (e *Endpoint) updatePeerNamesLocked() {
+	peerNames := make(map[*device.Peer]string)
+	for _, peer := range e.peers {
+		if peer.name == "" {
+			continue
+		}
+		if devicePeer := e.device.LookupPeer(peer.publicKey); devicePeer != nil {
+			peerNames[devicePeer] = peer.name
+		}
+	}
+	e.peerNames = peerNames
+}
+
+// SetPeer adds the peer, or replaces the peer with the same public key.
+// Allowed IPs outside those of the initial peers are not routed to a system
+// interface.
+func (e *Endpoint) SetPeer(options PeerOptions) error {
+	peer, err := newPeerConfig(options)
+	if err != nil {
+		return err
+	}
+	if !peer.endpoint.IsValid() && peer.destination.IsFqdn() {
+		destinationAddress, err := e.options.ResolvePeer(peer.destination.Fqdn)
+		if err != nil {
+			return E.Cause(err, "resolve endpoint domain: ", peer.destination)
+		}
+		peer.endpoint = netip.AddrPortFrom(destinationAddress, peer.destination.Port)
+	}
+	e.peerAccess.Lock()
+	defer e.peerAccess.Unlock()
+	peerIndex := slices.IndexFunc(e.peers, func(it peerConfig) bool {
+		return it.publicKey == peer.publicKey
+	})
+	if e.connected && (peerIndex == -1 || peer.endpoint != e.peers[peerIndex].endpoint) {
+		return E.New("endpoint is connected to a single peer, other peers cannot be added")
+	}
+	if peerIndex == -1 {
+		e.peers = append(e.peers, peer)
+	} else {
+		e.peers[peerIndex] = peer
+	}
+	if e.device == nil {
+		return nil
+	}
+	if peer.reserved != [3]uint8{} && peer.endpoint.IsValid() {
+		e.bind.SetReservedForEndpoint(peer.endpoint, peer.reserved)
+	}
+	err = e.device.IpcSet(strings.TrimPrefix(peer.GenerateIpcLines(), "\n"))
+	if err != nil {
+		return E.Cause(err, "set peer")
+	}
+	if e.power != nil && e.power.LowPower() {
+		e.updateKeepaliveLocked(true)
+	}
+	e.updatePeerNamesLocked()
+	return nil
+}
+
+func (e *Endpoint) RemovePeer(publicKey string) error {
+	key, err := parseKey(publicKey)
+	if err != nil {
+		return E.Cause(err, "decode public key")
+	}
+	e.peerAccess.Lock()
+	defer e.peerAccess.Unlock()
+	peerIndex := slices.IndexFunc(e.peers, func(it peerConfig) bool {
+		return it.publicKey == key
+	})
+	if peerIndex == -1 {
+		return E.New("peer not found: ", publicKey)
+	}
+	e.peers = slices.Delete(e.peers, peerIndex, peerIndex+1)
+	if e.device == nil {
+		return nil
+	}
+	err = e.device.IpcSet("public_key=" + hex.EncodeToString(key[:]) + "\nremove=true")
+	if err != nil {
+		return E.Cause(err, "remove peer")
+	}
+	e.updatePeerNamesLocked()
+	return nil
+}
+
+// RoamingEndpoints returns the endpoints learned from peers without a
+// configured endpoint, by public keys in base64.
+func (e *Endpoint) RoamingEndpoints() map[string]netip.AddrPort {
+	e.peerAccess.RLock()
+	defer e.peerAccess.RUnlock()
+	if e.device == nil {
+		return nil
+	}
+	ipcConf, err := e.device.IpcGet()
+	if err != nil {
+		return nil
+	}
+	deviceStatus := parsePeerStatus(ipcConf)
+	endpoints := make(map[string]netip.AddrPort)
+	for _, peer := range e.peers {
+		if peer.endpoint.IsValid() {
+			continue
+		}
+		endpoint, err := netip.ParseAddrPort(deviceStatus[hex.EncodeToString(peer.publicKey[:])]["endpoint"])
+		if err != nil {
+			continue
+		}
+		endpoints[base64.StdEncoding.EncodeToString(peer.publicKey[:])] = endpoint
+	}
+	return endpoints
+}
+
+// RestoreRoamingEndpoints sets the endpoints saved by RoamingEndpoints to
+// peers without a configured endpoint, so that packets can be sent to them
+// before they send packets again.
+func (e *Endpoint) RestoreRoamingEndpoints(endpoints map[string]netip.AddrPort) error {
+	e.peerAccess.RLock()
+	defer e.peerAccess.RUnlock()
+	if e.device == nil {
+		return nil
+	}
+	var ipcConf string
+	for _, peer := range e.peers {
+		if peer.endpoint.IsValid() {
+			continue
+		}
+		endpoint, loaded := endpoints[base64.StdEncoding.EncodeToString(peer.publicKey[:])]
+		if !loaded {
+			continue
+		}
+		ipcConf += "\npublic_key=" + hex.EncodeToString(peer.publicKey[:]) + "\nupdate_only=true\nendpoint=" + endpoint.String()
+	}
+	if ipcConf == "" {
+		return nil
+	}
+	return e.device.IpcSet(strings.TrimPrefix(ipcConf, "\n"))
+}
+
+// Peers returns the configured peers with statistics of the device.
+func (e *Endpoint) Peers() []adapter.WireGuardPeerStatus {
+	e.peerAccess.RLock()
+	defer e.peerAccess.RUnlock()
+	var deviceStatus map[string]map[string]string
+	if e.device != nil {
+		ipcConf, err := e.device.IpcGet()
+		if err != nil {
+			e.options.Logger.Error(E.Cause(err, "get peer status"))
+		} else {
+			deviceStatus = parsePeerStatus(ipcConf)
+		}
+	}
+	statusList := make([]adapter.WireGuardPeerStatus, 0, len(e.peers))
+	for _, peer := range e.peers {
+		status := adapter.WireGuardPeerStatus{
+			Name:                        peer.name,
+			PublicKey:                   base64.StdEncoding.EncodeToString(peer.publicKey[:]),
+			AllowedIPs:                  peer.allowedIPs,
+			PersistentKeepaliveInterval: peer.keepalive,
+		}
+		if peer.endpoint.IsValid() {
+			status.Endpoint = peer.endpoint.String()
+		} else if peer.destination.IsValid() {
+			status.Endpoint = peer.destination.String()
+		}
+		if peerStatus, loaded := deviceStatus[hex.EncodeToString(peer.publicKey[:])]; loaded {
+			if endpoint := peerStatus["endpoint"]; endpoint != "" {
+				status.Endpoint = endpoint
+			}
+			handshakeSec, _ := strconv.ParseInt(peerStatus["last_handshake_time_sec"], 10, 64)
+			handshakeNsec, _ := strconv.ParseInt(peerStatus["last_handshake_time_nsec"], 10, 64)
+			if handshakeSec != 0 || handshakeNsec != 0 {
+				status.LastHandshake = time.Unix(handshakeSec, handshakeNsec)
+			}
+			status.ReceivedBytes, _ = strconv.ParseUint(peerStatus["rx_bytes"], 10, 64)
+			status.SentBytes, _ = strconv.ParseUint(peerStatus["tx_bytes"], 10, 64)
+		}
+		statusList = append(statusList, status)
+	}
+	return statusList
+}
+
+// parsePeerStatus parses the output of the UAPI get operation by peer public
+// keys in hex.
+func parsePeerStatus(ipcConf string) map[string]map[string]string {
+	peerStatus := make(map[string]map[string]string)
+	var currentPeer map[string]string
+	for _, line := range strings.Split(ipcConf, "\n") {
+		key, value, loaded := strings.Cut(line, "=")
+		if !loaded {
+			continue
+		}
+		if key == "public_key" {
+			currentPeer = make(map[string]string)
+			peerStatus[value] = currentPeer
+		} else if currentPeer != nil {
+			currentPeer[key] = value
+		}
+	}
+	return peerStatus
+}
+
 func (e *Endpoint) NewDirectRouteConnection(metadata adapter.InboundContext, routeContext tun.DirectRouteContext, timeout time.Duration) (tun.DirectRouteDestination, error) {
 	if e.natDevice == nil {
 		return nil, os.ErrInvalid
@@ -261,18 +474,96 @@ func (e *Endpoint) onPauseUpdated(event int) {
 	}
 }
 
+// onPowerUpdated lengthens persistent keepalive intervals of peers in the low
+// power mode.
+func (e *Endpoint) onPowerUpdated(lowPower bool) {
+	e.peerAccess.RLock()
+	defer e.peerAccess.RUnlock()
+	e.updateKeepaliveLocked(lowPower)
+}
+
+func (e *Endpoint) updateKeepaliveLocked(lowPower bool) {
+	var ipcConf string
+	for _, peer := range e.peers {
+		if peer.keepalive == 0 {
+			continue
+		}
+		keepalive := time.Duration(peer.keepalive) * time.Second
+		if lowPower {
+			keepalive = e.power.KeepAliveInterval(keepalive)
+		}
+		ipcConf += "\npublic_key=" + hex.EncodeToString(peer.publicKey[:]) + "\nupdate_only=true\npersistent_keepalive_interval=" + F.ToString(min(int64(keepalive/time.Second), math.MaxUint16))
+	}
+	if ipcConf == "" {
+		return
+	}
+	err := e.device.IpcSet(strings.TrimPrefix(ipcConf, "\n"))
+	if err != nil {
+		e.options.Logger.Error(E.Cause(err, "update persistent keepalive"))
+	}
+}
+
 type peerConfig struct {
+	name            string
 	destination     M.Socksaddr
 	endpoint        netip.AddrPort
-	publicKeyHex    string
+	publicKey       device.NoisePublicKey
 	preSharedKeyHex string
 	allowedIPs      []netip.Prefix
 	keepalive       uint16
 	reserved        [3]uint8
 }
 
+func newPeerConfig(options PeerOptions) (peerConfig, error) {
+	peer := peerConfig{
+		name:       options.Name,
+		allowedIPs: options.AllowedIPs,
+		keepalive:  options.PersistentKeepaliveInterval,
+	}
+	if options.Endpoint.Addr.IsValid() {
+		peer.endpoint = options.Endpoint.AddrPort()
+	} else if options.Endpoint.IsFqdn() {
+		peer.destination = options.Endpoint
+	}
+	var err error
+	peer.publicKey, err = parseKey(options.PublicKey)
+	if err != nil {
+		return peerConfig{}, E.Cause(err, "decode public key")
+	}
+	if options.PreSharedKey != "" {
+		preSharedKeyBytes, err := base64.StdEncoding.DecodeString(options.PreSharedKey)
+		if err != nil {
+			return peerConfig{}, E.Cause(err, "decode pre shared key")
+		}
+		peer.preSharedKeyHex = hex.EncodeToString(preSharedKeyBytes)
+	}
+	if len(options.AllowedIPs) == 0 {
+		return peerConfig{}, E.New("missing allowed ips")
+	}
+	if len(options.Reserved) > 0 {
+		if len(options.Reserved) != 3 {
+			return peerConfig{}, E.New("invalid reserved value, required 3 bytes, got ", len(options.Reserved))
+		}
+		copy(peer.reserved[:], options.Reserved[:])
+	}
+	return peer, nil
+}
+
+func parseKey(key string) (device.NoisePublicKey, error) {
+	var publicKey device.NoisePublicKey
+	keyBytes, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return publicKey, err
+	}
+	if len(keyBytes) != len(publicKey) {
+		return publicKey, E.New("invalid key length: ", len(keyBytes))
+	}
+	copy(publicKey[:], keyBytes)
+	return publicKey, nil
+}
+
 func (c peerConfig) GenerateIpcLines() string {
-	ipcLines := "\npublic_key=" + c.publicKeyHex
+	ipcLines := "\npublic_key=" + hex.EncodeToString(c.publicKey[:]) + "\nreplace_allowed_ips=true"
 	if c.endpoint.IsValid() {
 		ipcLines += "\nendpoint=" + c.endpoint.String()
 	}
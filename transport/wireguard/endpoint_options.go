@@ -30,6 +30,7 @@ type EndpointOptions struct {
 }
 
 type PeerOptions struct {
+	Name                        string
 	Endpoint                    M.Socksaddr
 	PublicKey                   string
 	PreSharedKey                string